@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/spf13/cobra"
+)
+
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Manage alternative names of local models",
+	Long: `Give local models short or alternative names. An alias can be used wherever
+a local model name is expected, such as remove, share, decrypt and scrub.
+
+Aliases always point to a model, never to another alias. A model renamed with
+silmaril rename keeps its aliases and its old name becomes one of them.
+
+Examples:
+  silmaril alias set my-llama meta-llama/Llama-3.1-8B
+  silmaril alias remove my-llama
+  silmaril alias list`,
+}
+
+var aliasSetCmd = &cobra.Command{
+	Use:   "set <alias> <model-name>",
+	Short: "Let a local model be referred to by another name",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runAliasSet,
+}
+
+var aliasRemoveCmd = &cobra.Command{
+	Use:   "remove <alias>",
+	Short: "Delete an alias, keeping the model",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runAliasRemove,
+}
+
+var aliasListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the aliases of local models",
+	Args:  cobra.NoArgs,
+	RunE:  runAliasList,
+}
+
+func init() {
+	rootCmd.AddCommand(aliasCmd)
+	aliasCmd.AddCommand(aliasSetCmd, aliasRemoveCmd, aliasListCmd)
+}
+
+func runAliasSet(cmd *cobra.Command, args []string) error {
+	for i, arg := range args {
+		name, err := modelname.Canonical(arg)
+		if err != nil {
+			return err
+		}
+		args[i] = name
+	}
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	model, err := newDaemonClient().SetAlias(args[0], args[1])
+	if err != nil {
+		return fmt.Errorf("failed to set alias: %w", err)
+	}
+	fmt.Printf("✓ %s now refers to %s\n", args[0], model)
+	return nil
+}
+
+func runAliasRemove(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	if err := newDaemonClient().RemoveAlias(args[0]); err != nil {
+		return fmt.Errorf("failed to remove alias: %w", err)
+	}
+	fmt.Printf("✓ Removed alias %s\n", args[0])
+	return nil
+}
+
+func runAliasList(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	aliases, err := newDaemonClient().ListAliases()
+	if err != nil {
+		return fmt.Errorf("failed to list aliases: %w", err)
+	}
+	if len(aliases) == 0 {
+		fmt.Println("No aliases.")
+		return nil
+	}
+
+	for _, alias := range aliases {
+		name, _ := alias["alias"].(string)
+		model, _ := alias["model"].(string)
+		fmt.Printf("  %s -> %s\n", name, model)
+	}
+	return nil
+}
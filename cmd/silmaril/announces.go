@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var announcesCmd = &cobra.Command{
+	Use:   "announces",
+	Short: "Show how the torrents of seeded models are announced on the DHT",
+	Long: `Show the last DHT announce of the torrent of every model the daemon seeds
+or published, and how many DHT nodes stored this node as its peer.
+
+Besides listing models in the catalog, the daemon announces each torrent by
+its infohash to the DHT nodes closest to it, so peers looking the torrent up
+find this node even while the catalog is stale or unreachable. Announces are
+repeated every network.dht_announce_interval_minutes, and after 5 minutes
+when no node accepted one:
+
+  silmaril config set network.dht_announce_interval_minutes 15`,
+	Args: cobra.NoArgs,
+	RunE: runAnnounces,
+}
+
+func init() {
+	rootCmd.AddCommand(announcesCmd)
+}
+
+func runAnnounces(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	announces, err := newDaemonClient().GetDHTAnnounces()
+	if err != nil {
+		return fmt.Errorf("failed to get DHT announces: %w", err)
+	}
+	if len(announces) == 0 {
+		fmt.Println("No models announced yet.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tNODES\tPEERS\tLAST ANNOUNCED\tSTATUS")
+	for _, a := range announces {
+		last := "never"
+		if a.LastSuccess != nil {
+			last = a.LastSuccess.Local().Format("2006-01-02 15:04")
+		}
+		status := "ok"
+		if a.Error != "" {
+			status = fmt.Sprintf("failed %d times: %s", a.Failures, a.Error)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", a.Model, a.Nodes, a.Peers, last, status)
+	}
+	return w.Flush()
+}
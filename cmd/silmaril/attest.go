@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/silmaril/silmaril/internal/attest"
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/version"
+	"github.com/spf13/cobra"
+)
+
+var attestCmd = &cobra.Command{
+	Use:   "attest <model-name>",
+	Short: "Write a signed attestation of the files of a model",
+	Long: `Write a signed attestation of a local model for compliance and audit
+tooling: every file with its size and SHA256, the model's license, torrent
+infohash and provenance, your publisher key and when it was attested.
+
+The attestation is an in-toto Statement signed with your publisher key in a
+DSSE envelope, the format of in-toto and SLSA attestations. Files without a
+checksum in the manifest are hashed first.
+
+Examples:
+  silmaril attest meta-llama/Llama-3.1-8B
+  silmaril attest meta-llama/Llama-3.1-8B -o llama.intoto.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAttest,
+}
+
+var attestVerifyCmd = &cobra.Command{
+	Use:   "verify <attestation-file>",
+	Short: "Verify an attestation and the files it names",
+	Long: `Verify the signature of an attestation, written by Silmaril or another
+in-toto tool signing with ed25519 keys, and print what it attests. With
+--model or --dir the files are hashed and checked against it.
+
+Anyone can sign an attestation, so check the publisher key printed, or pass
+the expected one with --publisher to refuse attestations of anyone else.
+
+Examples:
+  silmaril attest verify llama.intoto.json --publisher 3b6a27bc...
+  silmaril attest verify llama.intoto.json --model meta-llama/Llama-3.1-8B
+  silmaril attest verify model.intoto.json --dir ./Llama-3.1-8B`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAttestVerify,
+}
+
+func init() {
+	rootCmd.AddCommand(attestCmd)
+	attestCmd.AddCommand(attestVerifyCmd)
+
+	attestCmd.Flags().StringP("output", "o", "", "File to write (default <model>.intoto.json in the current directory, - for stdout)")
+	attestVerifyCmd.Flags().String("publisher", "", "Only accept attestations signed with this publisher key")
+	attestVerifyCmd.Flags().String("model", "", "Check the files of this local model against the attestation")
+	attestVerifyCmd.Flags().String("dir", "", "Check the files of this directory against the attestation")
+}
+
+func runAttest(cmd *cobra.Command, args []string) error {
+	modelName, err := modelname.Canonical(args[0])
+	if err != nil {
+		return err
+	}
+	output, _ := cmd.Flags().GetString("output")
+	if output == "" {
+		output = path.Base(modelName) + ".intoto.json"
+	}
+
+	paths, err := storage.NewPaths()
+	if err != nil {
+		return fmt.Errorf("failed to initialize paths: %w", err)
+	}
+	modelPath, err := paths.ModelDir(modelName)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(modelPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("model %s not found locally, download it with 'silmaril get %s'", modelName, modelName)
+	}
+	if storage.IsPartial(modelPath) {
+		return fmt.Errorf("model %s is still downloading", modelName)
+	}
+	if storage.IsQuarantined(modelPath) {
+		return fmt.Errorf("model %s is in quarantine, see 'silmaril quarantine'", modelName)
+	}
+
+	registry, err := models.NewRegistry(paths)
+	if err != nil {
+		return fmt.Errorf("failed to load registry: %w", err)
+	}
+	manifest, err := registry.GetManifest(modelName)
+	if err != nil {
+		return err
+	}
+	if manifest.Encryption != nil {
+		return fmt.Errorf("model %s is encrypted, attestations of encrypted models are not supported", modelName)
+	}
+
+	privateKey, err := discovery.LoadOrCreatePublisherKey(filepath.Join(config.Get().Security.KeysDir, "publisher.key"))
+	if err != nil {
+		return err
+	}
+	publisher := hex.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var infoHash string
+	if m, err := metainfo.ParseMagnetUri(manifest.MagnetURI); err == nil {
+		infoHash = m.InfoHash.HexString()
+	}
+	statement, err := attest.Build(ctx, storage.ResolveModelDir(modelPath), manifest, infoHash, publisher, "silmaril/"+version.Get().Version)
+	if err != nil {
+		return fmt.Errorf("failed to attest %s: %w", modelName, err)
+	}
+	envelope, err := attest.Sign(statement, privateKey)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal attestation: %w", err)
+	}
+
+	if output == "-" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(output, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write attestation: %w", err)
+	}
+	fmt.Printf("✓ Attested %s (%d files, %.2f GB)\n", modelName, len(statement.Subject), float64(statement.Predicate.TotalSize)/(1024*1024*1024))
+	fmt.Printf("  File:      %s\n", output)
+	fmt.Printf("  Publisher: %s\n", publisher)
+	fmt.Printf("\nVerify it with 'silmaril attest verify %s --publisher %s'\n", filepath.Base(output), publisher)
+	return nil
+}
+
+func runAttestVerify(cmd *cobra.Command, args []string) error {
+	publisher, _ := cmd.Flags().GetString("publisher")
+	modelName, _ := cmd.Flags().GetString("model")
+	dir, _ := cmd.Flags().GetString("dir")
+	if modelName != "" && dir != "" {
+		return fmt.Errorf("--model and --dir can't be used together")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read attestation: %w", err)
+	}
+	statement, signers, err := attest.Open(data)
+	if err != nil {
+		return err
+	}
+	if publisher != "" && !signedBy(signers, publisher) {
+		return fmt.Errorf("attestation is signed by %s, not %s", strings.Join(signers, ", "), publisher)
+	}
+
+	fmt.Printf("✓ Signature valid, signed by %s\n", strings.Join(signers, ", "))
+	if p := statement.Predicate; statement.PredicateType == attest.PredicateType {
+		fmt.Printf("  Model:     %s\n", p.Model)
+		if p.License != "" {
+			fmt.Printf("  License:   %s\n", p.License)
+		}
+		if p.InfoHash != "" {
+			fmt.Printf("  InfoHash:  %s\n", p.InfoHash)
+		}
+		if p.Provenance != nil && p.Provenance.UpstreamURL != "" {
+			fmt.Printf("  Upstream:  %s", p.Provenance.UpstreamURL)
+			if p.Provenance.UpstreamRevision != "" {
+				fmt.Printf(" @ %s", p.Provenance.UpstreamRevision)
+			}
+			fmt.Println()
+		}
+		fmt.Printf("  Attested:  %s by %s\n", p.Attested.Local().Format("2006-01-02 15:04"), p.Builder)
+		if p.Publisher != "" && !signedBy(signers, p.Publisher) {
+			fmt.Printf("  ⚠️  Names publisher %s, which didn't sign it\n", p.Publisher)
+		}
+	} else {
+		fmt.Printf("  Predicate: %s\n", statement.PredicateType)
+	}
+	fmt.Printf("  Files:     %d\n", len(statement.Subject))
+
+	if modelName != "" {
+		paths, err := storage.NewPaths()
+		if err != nil {
+			return fmt.Errorf("failed to initialize paths: %w", err)
+		}
+		modelPath, err := paths.ModelDir(modelName)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(modelPath); err != nil {
+			return fmt.Errorf("model %s not found locally", modelName)
+		}
+		dir = storage.ResolveModelDir(modelPath)
+	}
+	if dir == "" {
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("\nChecking the files in %s\n", dir)
+	mismatched, err := statement.Check(ctx, dir, func(file string) {
+		fmt.Printf("  %s\n", file)
+	})
+	if err != nil {
+		return err
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("%d files don't match the attestation: %s", len(mismatched), strings.Join(mismatched, ", "))
+	}
+	fmt.Println("✓ All files match the attestation")
+	return nil
+}
+
+// signedBy reports whether publisher is one of the signers
+func signedBy(signers []string, publisher string) bool {
+	return slices.ContainsFunc(signers, func(signer string) bool { return strings.EqualFold(signer, publisher) })
+}
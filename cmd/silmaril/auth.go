@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/hfauth"
+	"github.com/silmaril/silmaril/internal/proxy"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// How long checking a token with the hub may take
+const whoamiTimeout = 30 * time.Second
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage credentials for model hubs",
+}
+
+var authHFCmd = &cobra.Command{
+	Use:   "hf",
+	Short: "Manage the HuggingFace access token",
+	Long: `Manage the HuggingFace access token the daemon clones and searches gated
+and private models with.
+
+The token is stored in the keys directory, encrypted with a key kept in the
+OS keyring (the macOS Keychain, or the Secret Service through secret-tool on
+Linux) where one is available, and readable only by you otherwise. The
+daemon reads it for every request and only sends it to the HuggingFace Hub,
+or the hub set in hf_proxy.upstream. HF_TOKEN overrides the stored token.`,
+}
+
+var authHFLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Store a HuggingFace access token",
+	Long: `Check a HuggingFace access token with the hub and store it. The token
+is read from --token, from the terminal without echoing it, or from stdin.
+Create a token at https://huggingface.co/settings/tokens, a read token is
+enough to clone gated models whose terms you accepted on the hub.
+
+Examples:
+  silmaril auth hf login
+  silmaril auth hf login --token hf_...
+  echo "$TOKEN" | silmaril auth hf login`,
+	Args: cobra.NoArgs,
+	RunE: runAuthHFLogin,
+}
+
+var authHFLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the stored HuggingFace access token",
+	Args:  cobra.NoArgs,
+	RunE:  runAuthHFLogout,
+}
+
+var authHFWhoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the HuggingFace account of the token in use",
+	Long: `Check the token in use, HF_TOKEN or the stored one, with the hub and
+show the account and organizations it belongs to and its role.
+
+Examples:
+  silmaril auth hf whoami`,
+	Args: cobra.NoArgs,
+	RunE: runAuthHFWhoami,
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authHFCmd)
+	authHFCmd.AddCommand(authHFLoginCmd)
+	authHFCmd.AddCommand(authHFLogoutCmd)
+	authHFCmd.AddCommand(authHFWhoamiCmd)
+
+	authHFLoginCmd.Flags().String("token", "", "Access token, read from the terminal or stdin if not given")
+	authHFLoginCmd.Flags().Bool("skip-check", false, "Store the token without checking it with the hub")
+}
+
+// hfTokenStore returns the token store of the configured keys directory
+func hfTokenStore() *hfauth.Store {
+	return hfauth.NewStore(config.Get().Security.KeysDir)
+}
+
+// whoami checks token with the configured hub through the git proxy, which
+// clones go through too
+func whoami(token string) (*hfauth.User, error) {
+	cfg := config.Get()
+	hubProxy, err := proxy.FromConfig(cfg, proxy.Git)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := cfg.HFProxy.Upstream
+	if endpoint == "" {
+		endpoint = "https://huggingface.co"
+	}
+	return hfauth.Whoami(hubProxy.HTTPClient(whoamiTimeout), endpoint, token)
+}
+
+// readToken reads a token from the terminal without echoing it, or from
+// the first line of stdin
+func readToken() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Print("HuggingFace token (https://huggingface.co/settings/tokens): ")
+		token, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read token: %w", err)
+		}
+		return string(token), nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+	return line, nil
+}
+
+func runAuthHFLogin(cmd *cobra.Command, args []string) error {
+	token, _ := cmd.Flags().GetString("token")
+	if token == "" {
+		var err error
+		if token, err = readToken(); err != nil {
+			return err
+		}
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return errors.New("no token given")
+	}
+
+	if skip, _ := cmd.Flags().GetBool("skip-check"); !skip {
+		user, err := whoami(token)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Token belongs to %s\n", user.Name)
+	}
+
+	encrypted, err := hfTokenStore().Save(token)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		fmt.Println("✅ Token stored, encrypted with a key in the OS keyring")
+	} else {
+		fmt.Println("✅ Token stored, readable only by you (no OS keyring found to encrypt it)")
+	}
+	if os.Getenv("HF_TOKEN") != "" {
+		fmt.Println("Note: HF_TOKEN is set and is used instead of the stored token.")
+	}
+	return nil
+}
+
+func runAuthHFLogout(cmd *cobra.Command, args []string) error {
+	if err := hfTokenStore().Delete(); err != nil {
+		return err
+	}
+	fmt.Println("✅ Stored HuggingFace token removed")
+	if os.Getenv("HF_TOKEN") != "" {
+		fmt.Println("Note: HF_TOKEN is still set and is used for HuggingFace requests.")
+	}
+	return nil
+}
+
+func runAuthHFWhoami(cmd *cobra.Command, args []string) error {
+	token, source, err := hfTokenStore().Token()
+	if err != nil {
+		return err
+	}
+	user, err := whoami(token)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Logged in to HuggingFace as %s\n", user.Name)
+	if user.Fullname != "" {
+		fmt.Printf("  Name: %s\n", user.Fullname)
+	}
+	if len(user.Orgs) > 0 {
+		orgs := make([]string, len(user.Orgs))
+		for i, org := range user.Orgs {
+			orgs[i] = org.Name
+		}
+		fmt.Printf("  Organizations: %s\n", strings.Join(orgs, ", "))
+	}
+	if access := user.Auth.AccessToken; access.DisplayName != "" || access.Role != "" {
+		fmt.Printf("  Token: %s (%s)\n", access.DisplayName, access.Role)
+	}
+	if source == hfauth.SourceEnv {
+		fmt.Println("  Source: HF_TOKEN")
+	} else {
+		fmt.Println("  Source: stored token")
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/silmaril/silmaril/internal/api/client"
+	"github.com/spf13/cobra"
+)
+
+var blocklistCmd = &cobra.Command{
+	Use:   "blocklist",
+	Short: "Manage the models this node refuses",
+	Long: `Manage the model blocklist. The daemon doesn't download, seed or show in
+discovery the models whose infohash or name is on it.
+
+Entries are an infohash, a model name or a pattern of names such as
+badorg/*. Besides the local entries, the daemon follows the feeds listed in
+security.model_blocklist_urls, one entry per line:
+
+  security:
+    model_blocklist_urls:
+      - https://example.com/silmaril-blocklist.txt`,
+}
+
+var blocklistAddCmd = &cobra.Command{
+	Use:   "add [infohash|pattern]",
+	Short: "Refuse a model or the models matching a pattern",
+	Long: `Add an infohash, a model name or a pattern of names to the local
+blocklist. Torrents of the models it blocks are stopped, their files are
+kept. Adding entries needs an admin token.
+
+Examples:
+  silmaril blocklist add badorg/leaked-model --reason "leaked weights"
+  silmaril blocklist add 'badorg/*'
+  silmaril blocklist add 3b6a27bcceb6a42d62a3a8d02a6f0d73653215`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBlocklistAdd,
+}
+
+var blocklistRemoveCmd = &cobra.Command{
+	Use:   "remove [infohash|pattern]",
+	Short: "Remove an entry from the local blocklist",
+	Long: `Remove an entry from the local blocklist. Models it blocked aren't
+downloaded or seeded again on their own. Entries of feeds can't be removed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBlocklistRemove,
+}
+
+var blocklistListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the local entries and the feeds of the blocklist",
+	Args:  cobra.NoArgs,
+	RunE:  runBlocklistList,
+}
+
+var blocklistUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Download the blocklist feeds again",
+	Args:  cobra.NoArgs,
+	RunE:  runBlocklistUpdate,
+}
+
+func init() {
+	rootCmd.AddCommand(blocklistCmd)
+	blocklistCmd.AddCommand(blocklistAddCmd)
+	blocklistCmd.AddCommand(blocklistRemoveCmd)
+	blocklistCmd.AddCommand(blocklistListCmd)
+	blocklistCmd.AddCommand(blocklistUpdateCmd)
+	blocklistAddCmd.Flags().String("reason", "", "Why the model is refused")
+}
+
+func runBlocklistAdd(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	reason, _ := cmd.Flags().GetString("reason")
+	entry, err := newDaemonClient().BlockModel(args[0], reason)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to the blocklist: %w", args[0], err)
+	}
+	fmt.Printf("✓ Blocked %s\n", entry.Pattern)
+	return nil
+}
+
+func runBlocklistRemove(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	if err := newDaemonClient().UnblockModel(args[0]); err != nil {
+		return fmt.Errorf("failed to remove %s from the blocklist: %w", args[0], err)
+	}
+	fmt.Printf("✓ Removed %s from the blocklist\n", args[0])
+	return nil
+}
+
+func runBlocklistList(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	list, err := newDaemonClient().GetBlocklist()
+	if err != nil {
+		return fmt.Errorf("failed to get the blocklist: %w", err)
+	}
+	return printBlocklist(list)
+}
+
+func runBlocklistUpdate(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	fmt.Println("Downloading blocklist feeds...")
+	list, err := newDaemonClient().ReloadBlocklist()
+	if err != nil {
+		return fmt.Errorf("failed to update the blocklist: %w", err)
+	}
+	return printBlocklist(list)
+}
+
+// printBlocklist shows the local entries and the state of each feed
+func printBlocklist(list *client.ModelBlocklist) error {
+	if len(list.Entries) == 0 {
+		fmt.Println("No local entries.")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ENTRY\tADDED\tREASON")
+		for _, entry := range list.Entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", entry.Pattern, entry.AddedAt.Local().Format("2006-01-02 15:04"), entry.Reason)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(list.Feeds) > 0 {
+		fmt.Println()
+		fmt.Printf("%d feed(s):\n", len(list.Feeds))
+		for _, feed := range list.Feeds {
+			updated := "not downloaded yet"
+			if feed.Updated != nil {
+				updated = "updated " + feed.Updated.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Printf("  %s (%d entries, %s)\n", feed.URL, feed.Entries, updated)
+			if feed.SkippedLines > 0 {
+				fmt.Printf("    Skipped %d lines that didn't parse\n", feed.SkippedLines)
+			}
+			if feed.Error != "" {
+				fmt.Printf("    Last update failed: %s\n", feed.Error)
+			}
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%d entries blocked in total\n", list.Total)
+	return nil
+}
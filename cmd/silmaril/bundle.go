@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/silmaril/silmaril/internal/bundle"
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Pack models into signed archives for networks without internet",
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create <model-name>",
+	Short: "Pack a model into a signed bundle",
+	Long: `Pack a local model into a single tar archive holding its files, its
+manifest and its torrent, signed with your publisher key. Carry the bundle to
+a network without internet access and install it with 'silmaril bundle
+install'; no catalog or DHT is needed on either side.
+
+Files without a checksum in the manifest are hashed first.
+
+Examples:
+  silmaril bundle create meta-llama/Llama-3.1-8B
+  silmaril bundle create meta-llama/Llama-3.1-8B -o /mnt/usb/llama.tar`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBundleCreate,
+}
+
+var bundleInstallCmd = &cobra.Command{
+	Use:   "install <bundle-file>",
+	Short: "Verify a bundle and install its model",
+	Long: `Verify the signature of a bundle and the checksum of every file in it,
+then install the model into the models directory and seed it. Nothing is
+installed if the bundle was tampered with.
+
+Anyone can sign a bundle, so check the publisher key printed by the install,
+or pass the expected one with --publisher to refuse bundles of anyone else.
+'silmaril subscriptions list' prints the key of a node.
+
+Examples:
+  silmaril bundle install llama.tar
+  silmaril bundle install llama.tar --publisher 3b6a27bc...
+  silmaril bundle install llama.tar --name acme/llama --seed=false`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBundleInstall,
+}
+
+func init() {
+	rootCmd.AddCommand(bundleCmd)
+	bundleCmd.AddCommand(bundleCreateCmd)
+	bundleCmd.AddCommand(bundleInstallCmd)
+
+	bundleCreateCmd.Flags().StringP("output", "o", "", "File to write (default <model>.tar in the current directory)")
+	bundleInstallCmd.Flags().String("name", "", "Model name to install as (default the bundled name)")
+	bundleInstallCmd.Flags().String("publisher", "", "Only accept bundles signed with this publisher key")
+	bundleInstallCmd.Flags().Bool("seed", true, "Seed the model once installed")
+}
+
+func runBundleCreate(cmd *cobra.Command, args []string) error {
+	modelName, err := modelname.Canonical(args[0])
+	if err != nil {
+		return err
+	}
+	output, _ := cmd.Flags().GetString("output")
+	if output == "" {
+		output = path.Base(modelName) + ".tar"
+	}
+
+	paths, err := storage.NewPaths()
+	if err != nil {
+		return fmt.Errorf("failed to initialize paths: %w", err)
+	}
+	modelPath, err := paths.ModelDir(modelName)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(modelPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("model %s not found locally, download it with 'silmaril get %s'", modelName, modelName)
+	}
+	if storage.IsPartial(modelPath) {
+		return fmt.Errorf("model %s is still downloading", modelName)
+	}
+
+	registry, err := models.NewRegistry(paths)
+	if err != nil {
+		return fmt.Errorf("failed to load registry: %w", err)
+	}
+	manifest, err := registry.GetManifest(modelName)
+	if err != nil {
+		return err
+	}
+	if manifest.Encryption != nil {
+		return fmt.Errorf("model %s is encrypted, bundles of encrypted models are not supported", modelName)
+	}
+
+	// The daemon knows the torrent of the model, running or not
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+	torrentData, err := newDaemonClient().ExportTorrent(modelName)
+	if err != nil {
+		return fmt.Errorf("failed to get the torrent of %s: %w", modelName, err)
+	}
+
+	privateKey, err := discovery.LoadOrCreatePublisherKey(filepath.Join(config.Get().Security.KeysDir, "publisher.key"))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	fmt.Printf("Bundling %s into %s\n", modelName, output)
+	index, err := bundle.Write(ctx, file, storage.ResolveModelDir(modelPath), manifest, torrentData, privateKey, func(name string, size int64) {
+		fmt.Printf("  %s (%.2f MB)\n", name, float64(size)/(1024*1024))
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+
+	fmt.Printf("✓ Bundled %s\n", modelName)
+	fmt.Printf("  InfoHash:  %s\n", index.InfoHash)
+	fmt.Printf("  Publisher: %s\n", index.Publisher)
+	fmt.Printf("\nInstall it with 'silmaril bundle install %s --publisher %s'\n", filepath.Base(output), index.Publisher)
+	return nil
+}
+
+func runBundleInstall(cmd *cobra.Command, args []string) error {
+	name, _ := cmd.Flags().GetString("name")
+	publisher, _ := cmd.Flags().GetString("publisher")
+	seed, _ := cmd.Flags().GetBool("seed")
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
+
+	br, err := bundle.Open(file)
+	if err != nil {
+		return err
+	}
+	if publisher != "" && !strings.EqualFold(publisher, br.Index.Publisher) {
+		return fmt.Errorf("bundle is signed by %s, not %s", br.Index.Publisher, publisher)
+	}
+	fmt.Printf("Bundle of %s signed by %s\n", br.Index.Name, br.Index.Publisher)
+	if name == "" {
+		name = br.Index.Name
+	}
+	if name, err = modelname.Canonical(name); err != nil {
+		return err
+	}
+
+	paths, err := storage.NewPaths()
+	if err != nil {
+		return fmt.Errorf("failed to initialize paths: %w", err)
+	}
+	modelPath, err := paths.ModelDir(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(modelPath); err == nil && !storage.IsPartial(modelPath) {
+		return fmt.Errorf("model %s already exists, remove it first with 'silmaril remove %s --purge'", name, name)
+	}
+	if err := storage.MarkPartial(modelPath); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("Installing %s\n", name)
+	if err := br.Extract(ctx, modelPath, func(file string, size int64) {
+		fmt.Printf("  %s (%.2f MB, verified)\n", file, float64(size)/(1024*1024))
+	}); err != nil {
+		return fmt.Errorf("failed to install bundle: %w", err)
+	}
+
+	br.Manifest.Name = name
+	if err := models.WriteManifest(modelPath, br.Manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := storage.ClearPartial(modelPath); err != nil {
+		return err
+	}
+
+	// Registered through the daemon, which keeps the torrent and seeds it
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+	result, err := newDaemonClient().ImportTorrent(br.Torrent, name, seed)
+	if err != nil {
+		return fmt.Errorf("failed to register %s: %w", name, err)
+	}
+
+	fmt.Printf("✓ Installed %s\n", name)
+	fmt.Printf("  InfoHash: %s\n", br.Index.InfoHash)
+	if status, _ := result["status"].(string); status == "seeding" {
+		fmt.Println("  Seeding to peers on this network")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/silmaril/silmaril/internal/api/client"
+	"github.com/spf13/cobra"
+)
+
+var cancelCmd = &cobra.Command{
+	Use:   "cancel <transfer-id|model-name>",
+	Short: "Cancel a download",
+	Long: `Cancel a running or paused download.
+
+The download stops and its torrent is dropped. Files that were already
+downloaded are kept so a later 'silmaril get' can continue from them, but the
+model is not listed by 'silmaril list' until the download completes. Use
+--purge to delete the partial files and free the disk space.
+
+Examples:
+  silmaril cancel meta-llama/Llama-3.1-8B
+  silmaril cancel 1b4e28ba-2fa1-11d2-883f-0016d3cca427 --purge`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCancel,
+}
+
+func init() {
+	rootCmd.AddCommand(cancelCmd)
+	cancelCmd.Flags().Bool("purge", false, "Delete the partially downloaded files")
+}
+
+func runCancel(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	purge, _ := cmd.Flags().GetBool("purge")
+
+	apiClient := newDaemonClient()
+	transferID, err := resolveDownload(apiClient, args[0])
+	if err != nil {
+		return err
+	}
+
+	if !purge {
+		if err := apiClient.CancelTransfer(transferID); err != nil {
+			return fmt.Errorf("failed to cancel download: %w", err)
+		}
+		fmt.Printf("✓ Cancelled download %s\n", args[0])
+		fmt.Println("Partial files were kept, run with --purge to delete them.")
+		return nil
+	}
+
+	freed, err := apiClient.PurgeTransfer(transferID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel download: %w", err)
+	}
+	fmt.Printf("✓ Cancelled download %s and freed %.2f GB\n", args[0], float64(freed)/(1024*1024*1024))
+	return nil
+}
+
+// resolveDownload returns the ID of the unfinished download matching a
+// transfer ID or model name
+func resolveDownload(apiClient *client.Client, target string) (string, error) {
+	transfers, err := apiClient.ListTransfers("")
+	if err != nil {
+		return "", fmt.Errorf("failed to list transfers: %w", err)
+	}
+
+	for _, transfer := range transfers {
+		if id, _ := transfer["id"].(string); id == target {
+			return id, nil
+		}
+	}
+
+	for _, transfer := range transfers {
+		name, _ := transfer["model_name"].(string)
+		kind, _ := transfer["type"].(string)
+		status, _ := transfer["status"].(string)
+		if name != target || kind != "download" {
+			continue
+		}
+		if status == "pending" || status == "active" || status == "paused" {
+			id, _ := transfer["id"].(string)
+			return id, nil
+		}
+	}
+
+	return "", fmt.Errorf("no unfinished download found for %s", target)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Largest catalog snapshot read from a URL
+const maxCatalogSnapshot = 32 << 20
+
+var catalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "Export and import snapshots of the catalog",
+}
+
+var catalogExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Write a snapshot of the catalog",
+	Long: `Write a snapshot of the public catalog known to the daemon, by default to
+catalog.json, or to stdout with -. Communities can serve snapshots over HTTP
+so new nodes discover models before their DHT node finds the catalog.
+
+Examples:
+  silmaril catalog export
+  silmaril catalog export /var/www/silmaril/catalog.json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCatalogExport,
+}
+
+var catalogImportCmd = &cobra.Command{
+	Use:   "import <file|url>",
+	Short: "Merge a catalog snapshot into the catalog",
+	Long: `Merge a catalog snapshot, from a file or an http(s) URL, into the public
+catalog of the daemon, as if a peer had sent it. Entries are only as
+trustworthy as whoever serves the snapshot, so import snapshots from sources
+you trust.
+
+Examples:
+  silmaril catalog import catalog.json
+  silmaril catalog import https://models.example.org/catalog.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCatalogImport,
+}
+
+func init() {
+	rootCmd.AddCommand(catalogCmd)
+	catalogCmd.AddCommand(catalogExportCmd)
+	catalogCmd.AddCommand(catalogImportCmd)
+}
+
+func runCatalogExport(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	output := "catalog.json"
+	if len(args) > 0 {
+		output = args[0]
+	}
+
+	data, err := newDaemonClient().ExportCatalog()
+	if err != nil {
+		return fmt.Errorf("failed to export catalog: %w", err)
+	}
+	if output == "-" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write catalog snapshot: %w", err)
+	}
+
+	fmt.Printf("✓ Wrote a snapshot of the catalog to %s\n", output)
+	fmt.Println("Import it elsewhere with 'silmaril catalog import'")
+	return nil
+}
+
+func runCatalogImport(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	data, err := readCatalogSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+
+	result, err := newDaemonClient().ImportCatalog(data)
+	if err != nil {
+		return fmt.Errorf("failed to import catalog: %w", err)
+	}
+
+	if !result.Changed {
+		fmt.Printf("✓ The catalog already has everything in the snapshot (%d models)\n", result.Models)
+		return nil
+	}
+	fmt.Printf("✓ Imported a snapshot of %d models, the catalog now has %d\n", result.Models, result.Total)
+	fmt.Println("Find models with 'silmaril discover'")
+	return nil
+}
+
+// readCatalogSnapshot reads a catalog snapshot from a file or an http(s) URL
+func readCatalogSnapshot(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read catalog snapshot: %w", err)
+		}
+		return data, nil
+	}
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch catalog snapshot: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch catalog snapshot: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCatalogSnapshot+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch catalog snapshot: %w", err)
+	}
+	if len(data) > maxCatalogSnapshot {
+		return nil, fmt.Errorf("catalog snapshot is larger than %d MB", maxCatalogSnapshot>>20)
+	}
+	return data, nil
+}
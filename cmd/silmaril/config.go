@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show or change the running daemon configuration",
+	Long: `Show or change the configuration of the running daemon.
+
+Rate limits, the catalog refresh interval and the log level are applied right
+away. Other settings are accepted but only take effect after a daemon restart.
+Changes made here are not written to the config file and take precedence over
+it until the daemon restarts. Edits to the config file are picked up by the
+daemon automatically.
+
+Examples:
+  silmaril config get
+  silmaril config get network.upload_rate_limit
+  silmaril config set network.upload_rate_limit 1048576
+  silmaril config set daemon.log_level info
+  silmaril config show --effective
+  silmaril config validate`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Show the running configuration",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting of the running daemon",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runConfigSet,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the settings that differ from the defaults and their source",
+	Long: `Show the settings of the running daemon that don't have their default
+value, and where each value comes from: the config file (file), a SILMARIL_
+environment variable (env), a daemon flag (flag), the active profile (profile)
+or 'silmaril config set' (runtime). With --effective every setting is shown,
+defaults included.
+
+Examples:
+  silmaril config show
+  silmaril config show --effective`,
+	Args: cobra.NoArgs,
+	RunE: runConfigShow,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check a config file for mistakes",
+	Long: `Check a config file for unknown keys, values of the wrong type and values
+out of range, and print the line and column of each mistake. Without a file
+the config file in use is checked. The daemon runs the same checks when it
+starts and refuses to start with an invalid config file.
+
+Examples:
+  silmaril config validate
+  silmaril config validate ~/.config/silmaril/config.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd, configSetCmd, configShowCmd, configValidateCmd)
+
+	configShowCmd.Flags().Bool("effective", false, "Show every setting, defaults included")
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	result, err := apiClient.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+
+	settings, _ := result["settings"].(map[string]interface{})
+	if len(args) == 1 {
+		value, ok := settings[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown config key %q", args[0])
+		}
+		fmt.Println(formatConfigValue(value))
+		return nil
+	}
+
+	live := make(map[string]bool)
+	if keys, ok := result["live_keys"].([]interface{}); ok {
+		for _, key := range keys {
+			live[fmt.Sprint(key)] = true
+		}
+	}
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		marker := " "
+		if live[key] {
+			marker = "*"
+		}
+		fmt.Printf("%s %s = %s\n", marker, key, formatConfigValue(settings[key]))
+	}
+	fmt.Println("\n* can be changed without restarting the daemon")
+
+	if pending, ok := result["pending_restart"].([]interface{}); ok && len(pending) > 0 {
+		fmt.Printf("Restart the daemon to apply: %v\n", pending)
+	}
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	// Values are parsed as JSON so numbers, booleans and lists keep their
+	// type, anything else is sent as a string
+	var value interface{}
+	if err := json.Unmarshal([]byte(args[1]), &value); err != nil {
+		value = args[1]
+	}
+
+	apiClient := newDaemonClient()
+	result, err := apiClient.UpdateConfig(map[string]interface{}{args[0]: value})
+	if err != nil {
+		return err
+	}
+
+	if restart, ok := result["restart_required"].([]interface{}); ok && len(restart) > 0 {
+		fmt.Printf("✓ Set %s, restart the daemon to apply it\n", args[0])
+		return nil
+	}
+	fmt.Printf("✓ Set %s\n", args[0])
+	return nil
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	effective, _ := cmd.Flags().GetBool("effective")
+
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	result, err := apiClient.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+
+	settings, _ := result["settings"].(map[string]interface{})
+	sources, _ := result["sources"].(map[string]interface{})
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		source := fmt.Sprint(sources[key])
+		if effective || source != config.SourceDefault {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	if len(keys) == 0 {
+		fmt.Println("All settings have their default value")
+		return nil
+	}
+	for _, key := range keys {
+		fmt.Printf("%s = %s (%s)\n", key, formatConfigValue(settings[key]), sources[key])
+	}
+	return nil
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	path := config.GetViper().ConfigFileUsed()
+	if len(args) == 1 {
+		path = args[0]
+	}
+	if path == "" {
+		return fmt.Errorf("no config file in use, pass the file to check")
+	}
+
+	warnings, err := config.ValidateFile(path)
+	for _, warning := range warnings {
+		fmt.Printf("warning: %s\n", warning.Error())
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ %s is valid\n", path)
+	return nil
+}
+
+// formatConfigValue prints config values the way they are written in YAML
+func formatConfigValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		if v == float64(int64(v)) {
+			return fmt.Sprintf("%d", int64(v))
+		}
+		return fmt.Sprintf("%g", v)
+	case []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
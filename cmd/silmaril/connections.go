@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var connectionsCmd = &cobra.Command{
+	Use:   "connections",
+	Short: "Show the peer connections of each model against the limits",
+	Long: `Show how many peers the daemon is connected to for every model it seeds or
+downloads, and the share of the connection limit each model was given.
+
+network.max_connections caps the connections of all models together. It is
+divided between the models by the peers they know: a quiet swarm keeps a few
+connections and the busy ones split the rest, each up to
+network.max_connections_per_torrent. Every connected peer interested in a
+model is uploaded to, so a model's connections are its upload slots:
+
+  silmaril config set network.max_connections 200
+  silmaril config set network.max_connections_per_torrent 80
+
+network.max_half_open and network.max_half_open_per_torrent limit the
+connection attempts in progress and apply when the daemon restarts.`,
+	Args: cobra.NoArgs,
+	RunE: runConnections,
+}
+
+func init() {
+	rootCmd.AddCommand(connectionsCmd)
+}
+
+func runConnections(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	stats, err := newDaemonClient().GetConnectionStats()
+	if err != nil {
+		return fmt.Errorf("failed to get connection stats: %w", err)
+	}
+
+	limits := stats.Limits
+	fmt.Printf("Connected to %d of at most %d peers, up to %d per model\n", stats.Connected, limits.MaxConnections, limits.PerTorrent)
+	fmt.Printf("Connecting to %d of at most %d peers, up to %d per model\n\n", stats.HalfOpen, limits.HalfOpen, limits.HalfOpenPerTorrent)
+	if len(stats.Torrents) == 0 {
+		fmt.Println("No models seeding or downloading.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tCONNECTED\tCONNECTING\tKNOWN\tLIMIT")
+	for _, t := range stats.Torrents {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", t.Model, t.Connected, t.HalfOpen, t.Known, t.Limit)
+	}
+	return w.Flush()
+}
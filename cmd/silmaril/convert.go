@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/spf13/cobra"
+)
+
+var (
+	convertVariants []string
+	convertPublish  bool
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert [model-name]",
+	Short: "Convert a local model into derived variants such as GGUF quantizations",
+	Long: `Convert a local safetensors model into derived models with the converter
+set in convert.command, or show the recent conversions. convert.command can
+only be set in the config file and is read when the daemon starts.
+
+The converter is run once per variant with the model directory, an output
+directory and the variant as arguments, for example a script running
+llama.cpp's convert_hf_to_gguf.py and llama-quantize. Each variant becomes a
+model named <model>-<variant>-GGUF whose manifest links it to the model it
+was made from. With convert.enabled set, downloaded models matching
+convert.models are converted into convert.variants automatically.
+
+Examples:
+  silmaril convert                                     # Show recent conversions
+  silmaril convert meta-llama/Llama-3.1-8B             # Make the configured variants
+  silmaril convert meta-llama/Llama-3.1-8B -v Q4_K_M -v Q8_0 --publish`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConvert,
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.Flags().StringArrayVarP(&convertVariants, "variant", "v", nil, "Variant to make, repeatable (default: convert.variants)")
+	convertCmd.Flags().BoolVar(&convertPublish, "publish", false, "Publish the derived models to the network")
+}
+
+func runConvert(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+
+	if len(args) == 1 {
+		modelName, err := modelname.Canonical(args[0])
+		if err != nil {
+			return err
+		}
+		conversions, err := apiClient.ConvertModel(modelName, convertVariants, convertPublish)
+		if err != nil {
+			return fmt.Errorf("failed to convert: %w", err)
+		}
+		for _, conversion := range conversions {
+			derived, _ := conversion["derived"].(string)
+			fmt.Printf("✓ Queued %s\n", derived)
+		}
+		fmt.Println("Follow the conversions with: silmaril convert")
+		return nil
+	}
+
+	conversions, err := apiClient.ListConversions()
+	if err != nil {
+		return fmt.Errorf("failed to list conversions: %w", err)
+	}
+	if len(conversions) == 0 {
+		fmt.Println("No conversions.")
+		return nil
+	}
+
+	for _, conversion := range conversions {
+		derived, _ := conversion["derived"].(string)
+		model, _ := conversion["model"].(string)
+		status, _ := conversion["status"].(string)
+		fmt.Printf("  %s (from %s)\n", derived, model)
+		fmt.Printf("    Status: %s", status)
+		if completed, err := time.Parse(time.RFC3339Nano, fmt.Sprint(conversion["completed_at"])); err == nil {
+			fmt.Printf(" | %s", completed.Local().Format("2006-01-02 15:04"))
+		}
+		if job, _ := conversion["publish_job"].(string); job != "" {
+			fmt.Printf(" | Publish job %s", job)
+		}
+		fmt.Println()
+		if errMsg, _ := conversion["error"].(string); errMsg != "" {
+			fmt.Printf("    Error: %s\n", errMsg)
+		}
+	}
+	return nil
+}
@@ -3,7 +3,12 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -11,6 +16,8 @@ import (
 	"github.com/silmaril/silmaril/internal/api/client"
 	"github.com/silmaril/silmaril/internal/config"
 	"github.com/silmaril/silmaril/internal/daemon"
+	"github.com/silmaril/silmaril/internal/proxy"
+	"github.com/silmaril/silmaril/internal/storage"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -33,82 +40,240 @@ The daemon will:
 - Maintain persistent DHT connections
 - Continue seeding downloaded models
 - Handle download/upload operations
-- Provide an HTTP API on port 8737 (configurable)`,
+- Provide an HTTP API on port 8737 (configurable)
+
+By default the daemon is started in the background. Use --foreground to run
+it in the current process, for example under systemd or another service
+manager; on Windows 'silmaril daemon service install' registers it as a
+service. Only one daemon can run per Silmaril directory; it holds a lock on
+~/.silmaril/daemon/daemon.pid while running.
+
+Pieces verified before are remembered, so a restart only hashes pieces
+the daemon knows nothing about and seeding resumes within seconds. If
+model files may have changed while the daemon was stopped, --recheck
+hashes every piece of the restored models again before they are seeded.
+
+Examples:
+  silmaril daemon start
+  silmaril daemon start --foreground
+  silmaril daemon start --recheck`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		port, _ := cmd.Flags().GetInt("port")
+		foreground, _ := cmd.Flags().GetBool("foreground")
+		hfProxy, _ := cmd.Flags().GetBool("hf-proxy")
+		recheck, _ := cmd.Flags().GetBool("recheck")
 		
-		if port == 0 {
-			port = viper.GetInt("daemon.port")
-			if port == 0 {
-				port = 8737 // Default port
-			}
+		port = daemonPort(port)
+
+		// Check if a daemon already holds the PID file
+		pidPath := daemon.DefaultPIDFilePath()
+		if pid, err := daemon.RunningPID(pidPath); err == nil && pid > 0 {
+			fmt.Printf("Daemon is already running (PID %d)\n", pid)
+			return nil
+		}
+
+		// The files of an unfinished migration are split between two places
+		if m, err := storage.ReadMigration(storage.GetBaseDir()); err != nil {
+			return err
+		} else if m != nil {
+			return fmt.Errorf("the base directory is being migrated to %s, run 'silmaril storage migrate %s' to finish", m.To, m.To)
 		}
 
 		// Check if something is already running on this port
-		apiClient := client.NewClient(fmt.Sprintf("http://127.0.0.1:%d", port))
+		apiClient := localDaemonClient(port)
 		if err := apiClient.Health(); err == nil {
 			fmt.Printf("Daemon is already running on port %d\n", port)
 			return nil
 		}
 
+		// Refuse to run with a config file that has mistakes in it
+		warnings, err := config.Validate()
+		for _, warning := range warnings {
+			fmt.Printf("[Config] Warning: %s\n", warning.Error())
+		}
+		if err != nil {
+			return fmt.Errorf("invalid config file:\n%w", err)
+		}
+
+		if !foreground {
+			return startDetachedDaemon(port, hfProxy, recheck, apiClient)
+		}
+
+		// Take the single-instance lock before touching any daemon state
+		pidFile, err := daemon.AcquirePIDFile(pidPath)
+		if err != nil {
+			return fmt.Errorf("failed to start daemon: %w", err)
+		}
+		defer pidFile.Release()
+
+		// Capture daemon output so it can be read with 'silmaril daemon logs'
+		logs, err := daemon.CaptureOutput(filepath.Join(storage.GetBaseDir(), "daemon", "logs"))
+		if err != nil {
+			fmt.Printf("Warning: could not capture daemon logs: %v\n", err)
+		}
+		
+		// A --port other than the configured one overrides it
+		if port != config.Get().Daemon.Port {
+			if err := config.SetFlag("daemon.port", port); err != nil {
+				return fmt.Errorf("invalid --port: %w", err)
+			}
+		}
+
+		// Serve the HuggingFace Hub proxy regardless of the config file
+		if hfProxy {
+			if err := config.SetFlag("hf_proxy.enabled", true); err != nil {
+				return fmt.Errorf("failed to enable HuggingFace proxy: %w", err)
+			}
+		}
+
+		// Hash the restored torrents again instead of trusting their pieces
+		if recheck {
+			if err := config.SetFlag("torrent.recheck_on_start", true); err != nil {
+				return fmt.Errorf("failed to enable recheck: %w", err)
+			}
+		}
+		
 		// Create daemon
 		cfg := config.Get()
 		d, err := daemon.New(cfg)
 		if err != nil {
 			return fmt.Errorf("failed to create daemon: %w", err)
 		}
+		d.SetLogBuffer(logs)
 
 		// Setup API routes and set them on the daemon
 		routes := api.SetupRoutes(d)
 		d.SetAPIHandler(routes)
+		d.SetHFProxyHandler(api.SetupHFProxyRoutes(d))
+
+		// Under the Windows service manager it starts and stops the daemon
+		if daemon.RunningAsService() {
+			return daemon.RunService(d, port)
+		}
 		
 		// Start the daemon (this starts all components and the API server)
 		if err := d.Start(port); err != nil {
 			return fmt.Errorf("failed to start daemon: %w", err)
 		}
 		
-		// Wait for interrupt signal
+		// Wait for an interrupt signal or a shutdown request through the API
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-		<-sigChan
-		
-		fmt.Println("\nShutting down daemon...")
-		return d.Shutdown()
+		select {
+		case <-sigChan:
+			fmt.Println("\nShutting down daemon...")
+			return d.Shutdown()
+		case <-d.Done():
+			return nil
+		}
 	},
 }
 
+// startDetachedDaemon runs 'daemon start --foreground' as a background
+// process and waits until its API is reachable
+func startDetachedDaemon(port int, hfProxy, recheck bool, apiClient *client.Client) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate silmaril executable: %w", err)
+	}
+
+	args := []string{"daemon", "start", "--foreground", "--port", strconv.Itoa(port)}
+	if cfgFile != "" {
+		args = append(args, "--config", cfgFile)
+	}
+	if profile != "" {
+		args = append(args, "--profile", profile)
+	}
+	if hfProxy {
+		args = append(args, "--hf-proxy")
+	}
+	if recheck {
+		args = append(args, "--recheck")
+	}
+
+	// Output goes to the daemon log file, see 'silmaril daemon logs'
+	child := exec.Command(executable, args...)
+	child.SysProcAttr = daemon.DetachedProcAttr()
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("failed to start daemon process: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- child.Wait()
+	}()
+
+	fmt.Printf("Starting daemon (PID %d)...\n", child.Process.Pid)
+	deadline := time.After(60 * time.Second)
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-exited:
+			if err == nil {
+				err = fmt.Errorf("exited before becoming ready")
+			}
+			return fmt.Errorf("daemon failed to start: %w (see %s)", err,
+				filepath.Join(storage.GetBaseDir(), "daemon", "logs", "daemon.log"))
+		case <-deadline:
+			return fmt.Errorf("daemon did not become ready within 60s, check 'silmaril daemon logs'")
+		case <-ticker.C:
+			if err := apiClient.Health(); err == nil {
+				fmt.Printf("Daemon started on port %d (PID %d)\n", port, child.Process.Pid)
+				return nil
+			}
+		}
+	}
+}
+
 var daemonStopCmd = &cobra.Command{
 	Use:   "stop",
 	Short: "Stop the Silmaril daemon",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		port, _ := cmd.Flags().GetInt("port")
-		if port == 0 {
-			port = viper.GetInt("daemon.port")
-			if port == 0 {
-				port = 8737 // Default port
-			}
+		port = daemonPort(port)
+
+		pidPath := daemon.DefaultPIDFilePath()
+		pid, err := daemon.RunningPID(pidPath)
+		if err != nil {
+			fmt.Printf("Warning: could not read PID file: %v\n", err)
 		}
 
-		// Check if daemon is running by trying the API
-		apiClient := client.NewClient(fmt.Sprintf("http://127.0.0.1:%d", port))
-		if err := apiClient.Health(); err != nil {
+		// Prefer a graceful shutdown via the API, fall back to signalling the
+		// process that holds the PID file
+		apiClient := localDaemonClient(port)
+		if err := apiClient.Health(); err == nil {
+			if err := apiClient.Shutdown(); err != nil {
+				// Without an admin token the daemon is stopped like any
+				// process of the user
+				if !isAuthError(err) || pid <= 0 {
+					return fmt.Errorf("failed to stop daemon: %w", err)
+				}
+				if err := daemon.TerminateProcess(pid); err != nil {
+					return fmt.Errorf("failed to stop daemon: %w", err)
+				}
+			}
+		} else if pid > 0 {
+			if err := daemon.TerminateProcess(pid); err != nil {
+				return fmt.Errorf("failed to stop daemon: %w", err)
+			}
+		} else {
 			fmt.Println("Daemon is not running")
 			return nil
 		}
 
-		// Shutdown via API
-		if err := apiClient.Shutdown(); err != nil {
-			return fmt.Errorf("failed to stop daemon: %w", err)
-		}
-
 		fmt.Println("Daemon shutdown initiated")
 		
-		// Wait briefly to confirm shutdown
-		time.Sleep(2 * time.Second)
-		if err := apiClient.Health(); err != nil {
-			// Daemon is no longer responding, it has stopped
-			fmt.Println("Daemon stopped successfully")
-			return nil
+		// Wait until the daemon releases its lock and stops answering
+		deadline := time.Now().Add(30 * time.Second)
+		for time.Now().Before(deadline) {
+			time.Sleep(500 * time.Millisecond)
+			running, _ := daemon.RunningPID(pidPath)
+			if running == 0 && apiClient.Health() != nil {
+				fmt.Println("Daemon stopped successfully")
+				return nil
+			}
 		}
 
 		return fmt.Errorf("daemon did not stop within timeout")
@@ -118,18 +283,28 @@ var daemonStopCmd = &cobra.Command{
 var daemonStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Check daemon status",
+	Long: `Show the status of the running daemon.
+
+With --verbose the health of each component is shown too: the torrent
+client, DHT bootstrapping, the catalog with its sequence number and when it
+was last published, free space of the models directory and when the state
+was last saved, along with the version the daemon was built from.
+
+Examples:
+  silmaril daemon status
+  silmaril daemon status --verbose`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		port, _ := cmd.Flags().GetInt("port")
-		if port == 0 {
-			port = viper.GetInt("daemon.port")
-			if port == 0 {
-				port = 8737 // Default port
-			}
-		}
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		port = daemonPort(port)
 
 		// Check daemon via API
-		apiClient := client.NewClient(fmt.Sprintf("http://127.0.0.1:%d", port))
+		apiClient := localDaemonClient(port)
 		if err := apiClient.Health(); err != nil {
+			if pid, _ := daemon.RunningPID(daemon.DefaultPIDFilePath()); pid > 0 {
+				fmt.Printf("Daemon process is running (PID %d) but not responding on port %d\n", pid, port)
+				return nil
+			}
 			fmt.Printf("Daemon is not running on port %d\n", port)
 			return nil
 		}
@@ -144,28 +319,169 @@ var daemonStatusCmd = &cobra.Command{
 		fmt.Printf("Daemon Status (port %d):\n", port)
 		fmt.Printf("  PID: %v\n", status["pid"])
 		fmt.Printf("  Uptime: %v\n", status["uptime"])
+		if mode, ok := status["mode"].(string); ok {
+			fmt.Printf("  Mode: %s\n", mode)
+		}
 		fmt.Printf("  Active Transfers: %v\n", status["active_transfers"])
 		fmt.Printf("  Total Peers: %v\n", status["total_peers"])
 		fmt.Printf("  DHT Nodes: %v\n", status["dht_nodes"])
+		if addrs, ok := status["listen_addrs"].([]interface{}); ok && len(addrs) > 0 {
+			fmt.Printf("  Listening On: %v\n", addrs)
+		}
+		printPorts(status["ports"])
+		printFamilyCounts("Peers by Family", status["peers_by_family"])
+		printFamilyCounts("DHT Nodes by Family", status["dht_nodes_by_family"])
+		printBootstrapState(status["dht_bootstrap"])
+		printPeerHints(status["peer_hints"])
+		printComponents(status, verbose)
 
 		return nil
 	},
 }
 
+var daemonLogsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show daemon logs",
+	Long: `Show recent output of the running daemon.
+
+The daemon keeps its most recent log lines in memory and writes all output to
+~/.silmaril/daemon/logs/daemon.log, which is rotated as it grows.
+
+Examples:
+  silmaril daemon logs              # Show buffered log lines
+  silmaril daemon logs -n 50        # Show the last 50 lines
+  silmaril daemon logs --since 10m  # Show lines from the last 10 minutes
+  silmaril daemon logs -f           # Keep printing new lines`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		port, _ := cmd.Flags().GetInt("port")
+		port = daemonPort(port)
+		follow, _ := cmd.Flags().GetBool("follow")
+		tail, _ := cmd.Flags().GetInt("tail")
+		since, _ := cmd.Flags().GetString("since")
+
+		apiClient := localDaemonClient(port)
+		if err := apiClient.Health(); err != nil {
+			fmt.Printf("Daemon is not running on port %d\n", port)
+			return nil
+		}
+
+		lines, lastSeq, err := apiClient.GetLogs(since, tail)
+		if err != nil {
+			return fmt.Errorf("failed to get logs: %w", err)
+		}
+		printLogLines(lines)
+
+		if !follow {
+			return nil
+		}
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-sigChan:
+				return nil
+			case <-ticker.C:
+				lines, seq, err := apiClient.GetLogs(strconv.FormatInt(lastSeq, 10), 0)
+				if err != nil {
+					return fmt.Errorf("lost connection to daemon: %w", err)
+				}
+				printLogLines(lines)
+				lastSeq = seq
+			}
+		}
+	},
+}
+
+var daemonEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show daemon events",
+	Long: `Show notable events of the running daemon, such as updates of subscribed
+models being found, downloaded and installed.
+
+Examples:
+  silmaril daemon events     # Show recent events
+  silmaril daemon events -f  # Keep printing new events`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		port, _ := cmd.Flags().GetInt("port")
+		port = daemonPort(port)
+		follow, _ := cmd.Flags().GetBool("follow")
+
+		apiClient := localDaemonClient(port)
+		if err := apiClient.Health(); err != nil {
+			fmt.Printf("Daemon is not running on port %d\n", port)
+			return nil
+		}
+
+		events, lastSeq, err := apiClient.GetEvents(0)
+		if err != nil {
+			return fmt.Errorf("failed to get events: %w", err)
+		}
+		printEvents(events)
+
+		if !follow {
+			return nil
+		}
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-sigChan:
+				return nil
+			case <-ticker.C:
+				events, seq, err := apiClient.GetEvents(lastSeq)
+				if err != nil {
+					return fmt.Errorf("lost connection to daemon: %w", err)
+				}
+				printEvents(events)
+				lastSeq = seq
+			}
+		}
+	},
+}
+
+// printEvents prints events returned by the daemon API
+func printEvents(events []map[string]interface{}) {
+	for _, event := range events {
+		eventTime, _ := event["time"].(string)
+		if t, err := time.Parse(time.RFC3339Nano, eventTime); err == nil {
+			eventTime = t.Local().Format("2006-01-02 15:04:05")
+		}
+		eventType, _ := event["type"].(string)
+		model, _ := event["model"].(string)
+		message, _ := event["message"].(string)
+		if model != "" {
+			fmt.Printf("%s %-16s %s: %s\n", eventTime, eventType, model, message)
+		} else {
+			fmt.Printf("%s %-16s %s\n", eventTime, eventType, message)
+		}
+	}
+}
+
+// printLogLines prints log lines returned by the daemon API
+func printLogLines(lines []map[string]interface{}) {
+	for _, line := range lines {
+		text, _ := line["text"].(string)
+		fmt.Println(text)
+	}
+}
+
 var daemonRestartCmd = &cobra.Command{
 	Use:   "restart",
 	Short: "Restart the Silmaril daemon",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		port, _ := cmd.Flags().GetInt("port")
-		if port == 0 {
-			port = viper.GetInt("daemon.port")
-			if port == 0 {
-				port = 8737 // Default port
-			}
-		}
+		port = daemonPort(port)
 
 		// Check if daemon is running
-		apiClient := client.NewClient(fmt.Sprintf("http://127.0.0.1:%d", port))
+		apiClient := localDaemonClient(port)
 		if err := apiClient.Health(); err == nil {
 			// Daemon is running, stop it first
 			fmt.Println("Stopping daemon...")
@@ -183,22 +499,213 @@ var daemonRestartCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(daemonCmd)
-	daemonCmd.AddCommand(daemonStartCmd, daemonStopCmd, daemonStatusCmd, daemonRestartCmd)
+	daemonCmd.AddCommand(daemonStartCmd, daemonStopCmd, daemonStatusCmd, daemonRestartCmd, daemonLogsCmd, daemonEventsCmd)
 	
 	// Flags for daemon start
 	daemonStartCmd.Flags().Int("port", 0, "API port (default: 8737)")
+	daemonStartCmd.Flags().Bool("foreground", false, "Run the daemon in the current process instead of in the background")
+	daemonStartCmd.Flags().Bool("hf-proxy", false, "Serve the HuggingFace Hub proxy (see hf_proxy in the config)")
+	daemonStartCmd.Flags().Bool("recheck", false, "Hash every piece of the restored models again instead of trusting the pieces verified before")
 	
 	// Flags for other commands
 	daemonStopCmd.Flags().Int("port", 0, "API port (default: 8737)")
 	daemonStatusCmd.Flags().Int("port", 0, "API port (default: 8737)")
+	daemonStatusCmd.Flags().BoolP("verbose", "v", false, "Show the health of each component and the daemon version")
 	daemonRestartCmd.Flags().Int("port", 0, "API port (default: 8737)")
+	daemonRestartCmd.Flags().Bool("foreground", false, "Run the daemon in the current process instead of in the background")
+	daemonRestartCmd.Flags().Bool("hf-proxy", false, "Serve the HuggingFace Hub proxy (see hf_proxy in the config)")
+	daemonRestartCmd.Flags().Bool("recheck", false, "Hash every piece of the restored models again instead of trusting the pieces verified before")
+	daemonLogsCmd.Flags().Int("port", 0, "API port (default: 8737)")
+	daemonLogsCmd.Flags().BoolP("follow", "f", false, "Keep printing new log lines")
+	daemonLogsCmd.Flags().IntP("tail", "n", 0, "Only show the last n lines")
+	daemonLogsCmd.Flags().String("since", "", "Only show lines newer than a duration (e.g. 10m)")
+	daemonEventsCmd.Flags().Int("port", 0, "API port (default: 8737)")
+	daemonEventsCmd.Flags().BoolP("follow", "f", false, "Keep printing new events")
 }
 
 // Helper function to get daemon URL with the specified or default port
 func getDaemonURL() string {
-	port := viper.GetInt("daemon.port")
-	if port == 0 {
-		port = 8737
+	return fmt.Sprintf("http://127.0.0.1:%d", daemonPort(0))
+}
+
+// daemonPort returns the API port to use: the --port flag if given, else
+// daemon.port from the configuration or the default
+func daemonPort(flag int) int {
+	if flag > 0 {
+		return flag
+	}
+	if port := viper.GetInt("daemon.port"); port > 0 {
+		return port
+	}
+	return config.DefaultDaemonPort
+}
+
+// daemonSocket returns the local socket to reach the daemon through, empty
+// to use its port. A Unix socket is only used once the daemon created it,
+// unless the daemon serves the API on nothing else.
+func daemonSocket() string {
+	socket := config.ExpandPath(viper.GetString("daemon.socket"))
+	if socket == "" || runtime.GOOS == "windows" || viper.GetBool("daemon.socket_only") {
+		return socket
+	}
+	if _, err := os.Stat(socket); err != nil {
+		return ""
+	}
+	return socket
+}
+
+// localDaemonClient returns an API client for the daemon on this machine
+// listening on port
+func localDaemonClient(port int) *client.Client {
+	c := client.NewClient(fmt.Sprintf("http://127.0.0.1:%d", port))
+	c.SetToken(apiToken())
+	if socket := daemonSocket(); socket != "" {
+		c.SetSocket(socket)
+	}
+	return c
+}
+
+// newDaemonClient returns an API client for the daemon, through its local
+// socket if it has one, otherwise using the api proxy if one is configured
+func newDaemonClient() *client.Client {
+	c := client.NewClient(getDaemonURL())
+	c.SetToken(apiToken())
+	if socket := daemonSocket(); socket != "" {
+		c.SetSocket(socket)
+		return c
+	}
+	p, err := proxy.FromConfig(config.Get(), proxy.API)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring proxy setting: %v\n", err)
+		return c
+	}
+	if p != nil {
+		c.SetTransport(p.Transport())
+	}
+	return c
+}
+
+// printFamilyCounts prints per address family counts from the daemon status
+func printFamilyCounts(label string, value interface{}) {
+	counts, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	fmt.Printf("  %s: IPv4 %v, IPv6 %v\n", label, counts["ipv4"], counts["ipv6"])
+}
+
+// printBootstrapState prints the DHT bootstrap state from the daemon status
+func printBootstrapState(value interface{}) {
+	state, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	fmt.Printf("  DHT Bootstrap: %v", state["status"])
+	if source, ok := state["source"].(string); ok && source != "" {
+		fmt.Printf(" (via %s)", source)
+	}
+	fmt.Println()
+	if lastError, ok := state["last_error"].(string); ok && lastError != "" {
+		fmt.Printf("    Last Error: %s (%v failed attempts)\n", lastError, state["consecutive_failures"])
+	}
+	if next, ok := state["next_attempt"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, next); err == nil {
+			fmt.Printf("    Next Attempt: in %s\n", time.Until(t).Round(time.Second))
+		}
+	}
+}
+
+// printComponents prints the health of each component and the build of the
+// daemon if verbose, or else the components that are not ok
+func printComponents(status map[string]interface{}, verbose bool) {
+	components, ok := status["components"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if !verbose {
+		var attention []string
+		for _, name := range daemon.StatusComponents {
+			component, _ := components[name].(map[string]interface{})
+			if s, _ := component["status"].(string); s != "" && s != daemon.HealthOK && s != daemon.HealthDisabled {
+				attention = append(attention, fmt.Sprintf("%s %s", name, s))
+			}
+		}
+		if len(attention) > 0 {
+			fmt.Printf("  Needs Attention: %s (see --verbose)\n", strings.Join(attention, ", "))
+		}
+		return
+	}
+
+	if build, ok := status["build"].(map[string]interface{}); ok {
+		fmt.Printf("  Version: %v", build["version"])
+		if commit, ok := build["commit"].(string); ok && commit != "" {
+			fmt.Printf(" (commit %s", commit)
+			if modified, _ := build["modified"].(bool); modified {
+				fmt.Print(", modified")
+			}
+			fmt.Print(")")
+		}
+		fmt.Printf(", %v\n", build["go_version"])
+	}
+	if uptime, ok := status["uptime_info"].(map[string]interface{}); ok {
+		if started, err := time.Parse(time.RFC3339Nano, fmt.Sprint(uptime["started_at"])); err == nil {
+			fmt.Printf("  Started: %s\n", started.Local().Format("2006-01-02 15:04:05"))
+		}
+	}
+	fmt.Println("  Components:")
+	for _, name := range daemon.StatusComponents {
+		component, ok := components[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fmt.Printf("    %-15s %-9v %v\n", name, component["status"], component["message"])
+	}
+}
+
+// printPeerHints prints the peers found through network.peer_hints, if set
+func printPeerHints(value interface{}) {
+	hints, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if names, ok := hints["hints"].([]interface{}); !ok || len(names) == 0 {
+		return
+	}
+	fmt.Printf("  Peer Hints: %v\n", hints["hints"])
+	if peers, ok := hints["peers"].([]interface{}); ok && len(peers) > 0 {
+		fmt.Printf("    Peers: %v\n", peers)
+	} else {
+		fmt.Println("    Peers: none")
 	}
-	return fmt.Sprintf("http://127.0.0.1:%d", port)
-}
\ No newline at end of file
+	if lastError, ok := hints["error"].(string); ok && lastError != "" {
+		fmt.Printf("    Last Error: %s\n", lastError)
+	}
+}
+
+// printPorts prints the ports the daemon listens on
+func printPorts(value interface{}) {
+	ports, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	var parts []string
+	if api, ok := ports["api"].(float64); ok {
+		parts = append(parts, fmt.Sprintf("API %.0f", api))
+	}
+	if socket, ok := ports["socket"].(string); ok {
+		parts = append(parts, "socket "+socket)
+	}
+	if peer, ok := ports["peer"].(float64); ok {
+		parts = append(parts, fmt.Sprintf("peers %.0f", peer))
+	}
+	if dht, ok := ports["dht"].([]interface{}); ok && len(dht) > 0 {
+		parts = append(parts, fmt.Sprintf("DHT %v", dht[0]))
+	}
+	if hfProxy, ok := ports["hf_proxy"].(float64); ok {
+		parts = append(parts, fmt.Sprintf("HF proxy %.0f", hfProxy))
+	}
+	if len(parts) > 0 {
+		fmt.Printf("  Ports: %s\n", strings.Join(parts, ", "))
+	}
+}
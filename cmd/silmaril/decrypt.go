@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt [model-name]",
+	Short: "Decrypt a downloaded encrypted model",
+	Long: `Give the daemon the key of an encrypted model, or the token for its key
+endpoint, and decrypt the model once it is downloaded.
+
+Encrypted models are seeded as downloaded, their decrypted files are placed
+in the models directory. Follow the decryption with: silmaril daemon logs -f
+
+Examples:
+  silmaril decrypt org/gated-model --key <key>
+  silmaril decrypt org/gated-model --key-token <token>`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDecrypt,
+}
+
+var (
+	decryptKey      string
+	decryptKeyToken string
+)
+
+func init() {
+	rootCmd.AddCommand(decryptCmd)
+
+	decryptCmd.Flags().StringVar(&decryptKey, "key", "", "key of the model, as given by its publisher")
+	decryptCmd.Flags().StringVar(&decryptKeyToken, "key-token", "", "token for the key endpoint of the model")
+}
+
+func runDecrypt(cmd *cobra.Command, args []string) error {
+	if decryptKey == "" && decryptKeyToken == "" {
+		return fmt.Errorf("--key or --key-token is required")
+	}
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	decrypting, err := apiClient.SetModelKey(args[0], decryptKey, decryptKeyToken)
+	if err != nil {
+		return fmt.Errorf("failed to set key: %w", err)
+	}
+
+	if decrypting {
+		fmt.Printf("✓ Decrypting %s, follow it with: silmaril daemon logs -f\n", args[0])
+	} else {
+		fmt.Printf("✓ Key stored, %s is decrypted once downloaded\n", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <model-a> <model-b>",
+	Short: "Compare the files of two local models",
+	Long: `Compare the file lists of two local models, such as two versions of a
+model, by their manifests. Files only in the second model are added, files
+only in the first are removed, and files in both are changed when their
+size or SHA256 differs.
+
+Examples:
+  silmaril diff org/model-v1 org/model-v2
+  silmaril diff llama-old llama --json`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().Bool("json", false, "Print the differences as JSON")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	diff, err := apiClient.DiffModels(args[0], args[1])
+	if err != nil {
+		return fmt.Errorf("failed to compare models: %w", err)
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode differences: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n", diffSide(diff, "a"), diffSide(diff, "b"))
+
+	added, _ := diff["added"].([]interface{})
+	removed, _ := diff["removed"].([]interface{})
+	changed, _ := diff["changed"].([]interface{})
+	for _, f := range removed {
+		file, _ := f.(map[string]interface{})
+		path, _ := file["path"].(string)
+		size, _ := file["size"].(float64)
+		fmt.Printf("- %s (%s)\n", path, formatProgressBytes(int64(size)))
+	}
+	for _, f := range added {
+		file, _ := f.(map[string]interface{})
+		path, _ := file["path"].(string)
+		size, _ := file["size"].(float64)
+		fmt.Printf("+ %s (%s)\n", path, formatProgressBytes(int64(size)))
+	}
+	for _, f := range changed {
+		file, _ := f.(map[string]interface{})
+		path, _ := file["path"].(string)
+		sizeA, _ := file["size_a"].(float64)
+		sizeB, _ := file["size_b"].(float64)
+		if sizeA == sizeB {
+			fmt.Printf("~ %s (%s, content changed)\n", path, formatProgressBytes(int64(sizeB)))
+		} else {
+			fmt.Printf("~ %s (%s -> %s)\n", path, formatProgressBytes(int64(sizeA)), formatProgressBytes(int64(sizeB)))
+		}
+	}
+
+	unchanged, _ := diff["unchanged"].(float64)
+	delta, _ := diff["size_delta"].(float64)
+	sign := "+"
+	if delta < 0 {
+		sign, delta = "-", -delta
+	}
+	fmt.Printf("\n%d added, %d removed, %d changed, %.0f unchanged (%s%s)\n",
+		len(added), len(removed), len(changed), unchanged, sign, formatProgressBytes(int64(delta)))
+	return nil
+}
+
+// diffSide names one of the compared models with its version
+func diffSide(diff map[string]interface{}, side string) string {
+	name, _ := diff[side].(string)
+	if version, _ := diff["version_"+side].(string); version != "" {
+		return name + " " + version
+	}
+	return name
+}
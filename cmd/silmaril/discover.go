@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/silmaril/silmaril/internal/api/client"
+	"github.com/silmaril/silmaril/pkg/types"
 )
 
 var discoverCmd = &cobra.Command{
@@ -18,6 +23,31 @@ You can optionally provide a search pattern to filter results:
   silmaril discover llama     # Show models containing "llama"
   silmaril discover meta-     # Show models starting with "meta-"
 
+Results can be narrowed down with filters:
+  silmaril discover --tag llama --max-size 20GB --license apache-2.0
+  silmaril discover llama --min-seeders 2
+
+The pattern matches model names, descriptions and tags. Results include models
+from the catalogs of publishers you subscribed to (see: silmaril subscribe) and
+of the catalog networks in the config, tagged with the networks listing them.
+Use --network to only show the models of one network (see: silmaril network list):
+  silmaril discover --network acme
+
+Use --check-health to probe the swarm of every matching model on the DHT and
+show seeders, leechers and estimated availability. Probing takes a few seconds;
+results are cached by the daemon for a few minutes. --min-seeders implies it.
+
+Use --watch to keep waiting for models matching the search, for example for a
+publisher to push a release. The daemon refreshes the catalog every minute
+while watching, and each new model or version is printed as it shows up:
+  silmaril discover meta-llama --watch
+
+Use --sort, --limit and --offset to browse a large catalog a page at a time,
+sorted by when models were added, their size, their seeders or their name.
+Pages come from the daemon's index of the catalog and don't wait for the DHT:
+  silmaril discover --sort size --limit 20
+  silmaril discover llama --sort name --limit 20 --offset 20
+
 This searches for models via DHT (Distributed Hash Table) on the BitTorrent network.`,
 	RunE: runDiscover,
 }
@@ -25,6 +55,17 @@ This searches for models via DHT (Distributed Hash Table) on the BitTorrent netw
 func init() {
 	rootCmd.AddCommand(discoverCmd)
 	discoverCmd.Flags().IntP("timeout", "t", 30, "Discovery timeout in seconds")
+	discoverCmd.Flags().StringSlice("tag", nil, "Only show models with this tag (repeatable)")
+	discoverCmd.Flags().String("max-size", "", "Maximum model size (e.g. 20GB, 500MB)")
+	discoverCmd.Flags().String("license", "", "Only show models with this license (e.g. apache-2.0)")
+	discoverCmd.Flags().Int("min-seeders", 0, "Only show models with at least this many seeders")
+	discoverCmd.Flags().Bool("check-health", false, "Probe the swarm for seeder and leecher counts")
+	discoverCmd.Flags().String("network", "", "Only show models of this catalog network")
+	discoverCmd.Flags().BoolP("watch", "w", false, "Keep watching the catalog and print new matching models")
+	discoverCmd.Flags().String("sort", "", "Browse the catalog sorted by added, size, seeders or name")
+	discoverCmd.Flags().String("order", "", "Sort order, asc or desc (default newest, largest, best seeded first and names A-Z)")
+	discoverCmd.Flags().Int("limit", 0, "Browse the catalog this many models at a time (default 50)")
+	discoverCmd.Flags().Int("offset", 0, "Skip this many models when browsing the catalog")
 }
 
 func runDiscover(cmd *cobra.Command, args []string) error {
@@ -46,17 +87,57 @@ func runDiscover(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	// Create API client
-	apiClient := client.NewClient(getDaemonURL())
+	apiClient := newDaemonClient()
 
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	maxSize, _ := cmd.Flags().GetString("max-size")
+	license, _ := cmd.Flags().GetString("license")
+	minSeeders, _ := cmd.Flags().GetInt("min-seeders")
+	checkHealth, _ := cmd.Flags().GetBool("check-health")
+	network, _ := cmd.Flags().GetString("network")
+	watch, _ := cmd.Flags().GetBool("watch")
+	
+	// Validate the size locally for a clearer error message
+	if maxSize != "" {
+		if _, err := types.ParseSize(maxSize); err != nil {
+			return fmt.Errorf("invalid --max-size: %w", err)
+		}
+	}
+	
 	// Discover models via API
-	models, err := apiClient.DiscoverModels(pattern)
+	opts := client.DiscoverOptions{
+		Pattern:     pattern,
+		Tags:        tags,
+		MaxSize:     maxSize,
+		License:     license,
+		MinSeeders:  minSeeders,
+		CheckHealth: checkHealth,
+		Network:     network,
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("sort") || flags.Changed("order") || flags.Changed("limit") || flags.Changed("offset") {
+		if watch || checkHealth || minSeeders > 0 {
+			return fmt.Errorf("--sort, --order, --limit and --offset can't be combined with --watch, --check-health or --min-seeders")
+		}
+		catalogOpts := client.CatalogOptions{DiscoverOptions: opts}
+		catalogOpts.Sort, _ = flags.GetString("sort")
+		catalogOpts.Order, _ = flags.GetString("order")
+		catalogOpts.Limit, _ = flags.GetInt("limit")
+		catalogOpts.Offset, _ = flags.GetInt("offset")
+		return browseCatalog(apiClient, catalogOpts)
+	}
+	models, err := apiClient.DiscoverModelsFiltered(opts)
 	if err != nil {
 		return fmt.Errorf("failed to discover models: %w", err)
 	}
 
 	if len(models) == 0 {
 		fmt.Println("No models found on the network.")
-		if pattern != "" {
+		if watch {
+			return watchDiscover(apiClient, opts, models)
+		}
+		if pattern != "" || len(tags) > 0 || maxSize != "" || license != "" || minSeeders > 0 || network != "" {
 			fmt.Println("\nTry a different search pattern or run without arguments to see all models.")
 		} else {
 			fmt.Println("\nModels shared by other users will appear here as they are announced to the DHT.")
@@ -94,9 +175,109 @@ func runDiscover(cmd *cobra.Command, args []string) error {
 
 	fmt.Println("To download a model, use: silmaril get <model-name>")
 
+	if watch {
+		return watchDiscover(apiClient, opts, models)
+	}
 	return nil
 }
 
+// browseCatalog prints a page of the catalog and how to get the next one
+func browseCatalog(apiClient *client.Client, opts client.CatalogOptions) error {
+	page, err := apiClient.BrowseCatalog(opts)
+	if err != nil {
+		return fmt.Errorf("failed to browse the catalog: %w", err)
+	}
+	if len(page.Models) == 0 {
+		fmt.Printf("No models on this page, the catalog has %d matching model(s).\n", page.Total)
+		return nil
+	}
+
+	fmt.Printf("Models %d-%d of %d, by %s (%s):\n\n", page.Offset+1, page.Offset+len(page.Models), page.Total, page.Sort, page.Order)
+	for _, model := range page.Models {
+		displayDiscoveredModel(model, false)
+	}
+	if next := page.Offset + len(page.Models); next < page.Total {
+		fmt.Printf("\nNext page: --offset %d\n", next)
+	}
+	return nil
+}
+
+// discoveredKey identifies a discovered model, a new version is a new model
+func discoveredKey(model map[string]interface{}) string {
+	name, _ := model["name"].(string)
+	infoHash, _ := model["info_hash"].(string)
+	return name + "@" + infoHash
+}
+
+// watchDiscover keeps the daemon watching the catalog and prints the models
+// matching opts that weren't in models, until interrupted. The daemon
+// publishes a catalog.added event for each new catalog entry; the search is
+// run again after one so the filters apply as usual.
+func watchDiscover(apiClient *client.Client, opts client.DiscoverOptions, models []map[string]interface{}) error {
+	seen := make(map[string]bool)
+	for _, model := range models {
+		seen[discoveredKey(model)] = true
+	}
+
+	_, lastSeq, err := apiClient.GetEvents(0)
+	if err != nil {
+		return fmt.Errorf("failed to get events: %w", err)
+	}
+	if _, err := apiClient.WatchDiscovery(); err != nil {
+		return fmt.Errorf("failed to watch the catalog: %w", err)
+	}
+	fmt.Println("\nWatching for new models, press Ctrl+C to stop...")
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	// The daemon stops watching a few minutes after the last renewal
+	renew := time.NewTicker(time.Minute)
+	defer renew.Stop()
+
+	for {
+		select {
+		case <-sigChan:
+			return nil
+		case <-renew.C:
+			if _, err := apiClient.WatchDiscovery(); err != nil {
+				return fmt.Errorf("lost connection to daemon: %w", err)
+			}
+		case <-ticker.C:
+			events, seq, err := apiClient.GetEvents(lastSeq)
+			if err != nil {
+				return fmt.Errorf("lost connection to daemon: %w", err)
+			}
+			lastSeq = seq
+			appeared := false
+			for _, event := range events {
+				if eventType, _ := event["type"].(string); eventType == "catalog.added" {
+					appeared = true
+				}
+			}
+			if !appeared {
+				continue
+			}
+
+			found, err := apiClient.DiscoverModelsFiltered(opts)
+			if err != nil {
+				fmt.Printf("Failed to search the catalog: %v\n", err)
+				continue
+			}
+			for _, model := range found {
+				key := discoveredKey(model)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				fmt.Printf("\n[%s] New model:\n", time.Now().Format("2006-01-02 15:04:05"))
+				displayDiscoveredModel(model, false)
+			}
+		}
+	}
+}
+
 func displayDiscoveredModel(model map[string]interface{}, indent bool) {
 	prefix := "  "
 	if indent {
@@ -120,5 +301,53 @@ func displayDiscoveredModel(model map[string]interface{}, indent bool) {
 		fmt.Printf(" - %.2f GB", sizeGB)
 	}
 	
+	if license, ok := model["license"].(string); ok && license != "" && license != "Unknown" {
+		fmt.Printf(" | License: %s", license)
+	}
+	
+	if params, ok := model["parameters"].(float64); ok && params > 0 {
+		fmt.Printf(" | Parameters: %.1fB", params/1e9)
+	}
+	
 	fmt.Println()
+	
+	detailPrefix := strings.Repeat(" ", len(prefix)+2)
+	if publisher, ok := model["publisher"].(string); ok && publisher != "" {
+		if name, ok := model["publisher_name"].(string); ok && name != "" {
+			publisher = fmt.Sprintf("%s (%s)", name, publisher)
+		}
+		fmt.Printf("%sPublisher: %s\n", detailPrefix, publisher)
+	}
+	if verified, _ := model["verified_publisher"].(bool); verified {
+		domain, _ := model["domain"].(string)
+		fmt.Printf("%sVerified publisher: %s\n", detailPrefix, domain)
+	}
+	if networks := getStringList(model["networks"]); len(networks) > 0 && !onlyPublicNetwork(networks) {
+		fmt.Printf("%sNetworks: %s\n", detailPrefix, strings.Join(networks, ", "))
+	}
+	if provenance, ok := model["provenance"].(map[string]interface{}); ok {
+		for _, line := range provenanceLines(provenance) {
+			fmt.Printf("%s%s\n", detailPrefix, line)
+		}
+	}
+	if availability, ok := model["availability"].(string); ok && availability != "" {
+		seeders, _ := model["seeders"].(float64)
+		leechers, _ := model["leechers"].(float64)
+		fmt.Printf("%sHealth: %s (seeders: %.0f, leechers: %.0f)\n", detailPrefix, availability, seeders, leechers)
+	}
+	if desc, ok := model["description"].(string); ok && desc != "" {
+		if len(desc) > 100 {
+			desc = desc[:97] + "..."
+		}
+		fmt.Printf("%s%s\n", detailPrefix, desc)
+	}
+	if tags := getStringList(model["tags"]); len(tags) > 0 {
+		fmt.Printf("%sTags: %s\n", detailPrefix, strings.Join(tags, ", "))
+	}
+}
+
+// onlyPublicNetwork reports whether a model is only listed in the public
+// catalog, which isn't worth mentioning
+func onlyPublicNetwork(networks []string) bool {
+	return len(networks) == 1 && networks[0] == "public"
 }
\ No newline at end of file
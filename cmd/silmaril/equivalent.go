@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var equivalentCmd = &cobra.Command{
+	Use:   "equivalent <model> <infohash>...",
+	Short: "Declare torrents holding the same files as a local model",
+	Long: `Declare that other torrents hold exactly the same files as a model you
+seed, such as the same revision published by someone else with another piece
+length. Each torrent's metadata is fetched and its pieces are checked against
+the local files, so only torrents of identical files are accepted.
+
+The torrents are recorded in the model's manifest and announced with it.
+Peers downloading the model then join the swarms of all of them at once,
+each swarm fetching part of the files, which merges duplicate swarms instead
+of leaving each with a few seeders.
+
+Examples:
+  silmaril equivalent meta-llama/Llama-3.1-8B 5f0c3b0b6e5a1d0c9e0a4b7a2d1e8f3c4b5a6d7e`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runEquivalent,
+}
+
+func init() {
+	rootCmd.AddCommand(equivalentCmd)
+}
+
+func runEquivalent(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	// Fetching metadata from peers and hashing the files takes a while
+	apiClient.SetTimeout(0)
+	result, err := apiClient.DeclareEquivalents(args[0], args[1:])
+	if err != nil {
+		return fmt.Errorf("failed to declare equivalent torrents: %w", err)
+	}
+
+	equivalents := getStringList(result["equivalents"])
+	fmt.Printf("%s has %d equivalent torrents:\n", args[0], len(equivalents))
+	for _, infoHash := range equivalents {
+		fmt.Printf("  %s\n", infoHash)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/silmaril/silmaril/internal/api/apierror"
+)
+
+// Exit codes of failed commands, so scripts can tell failures apart
+const (
+	exitFailure     = 1 // Any other failure
+	exitInvalid     = 2 // The request was invalid
+	exitNotFound    = 3 // The model, transfer, job or alias doesn't exist
+	exitConflict    = 4 // The model is busy or the request conflicts with the daemon's state
+	exitLicense     = 5 // The model's license must be accepted first
+	exitUnavailable = 6 // The daemon or a feature is not available right now, try again
+	exitDenied      = 7 // The API token is missing, invalid or lacks the scope
+)
+
+// exitCode returns the exit code for a command's error
+func exitCode(err error) int {
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) {
+		return exitFailure
+	}
+	switch apiErr.Code {
+	case apierror.CodeInvalidRequest:
+		return exitInvalid
+	case apierror.CodeUnauthorized, apierror.CodeForbidden, apierror.CodeModelBlocked:
+		return exitDenied
+	case apierror.CodeNotFound:
+		return exitNotFound
+	case apierror.CodeConflict, apierror.CodeModelInUse, apierror.CodeRelayMode:
+		return exitConflict
+	case apierror.CodeLicenseRequired:
+		return exitLicense
+	case apierror.CodeUnavailable:
+		return exitUnavailable
+	}
+	return exitFailure
+}
+
+// isAuthError reports whether the daemon refused a request for its API
+// token
+func isAuthError(err error) bool {
+	return errors.Is(err, &apierror.Error{Code: apierror.CodeUnauthorized}) ||
+		errors.Is(err, &apierror.Error{Code: apierror.CodeForbidden})
+}
+
+// errorHint suggests what to do about a command's error, empty if there is
+// nothing to suggest
+func errorHint(err error) string {
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+	switch apiErr.Code {
+	case apierror.CodeInvalidRequest:
+		return "Check the arguments of the command with --help."
+	case apierror.CodeNotFound:
+		return "List local models with 'silmaril list' and transfers with 'silmaril transfers'."
+	case apierror.CodeModelInUse:
+		return "Wait for the download to finish or cancel it with 'silmaril cancel'."
+	case apierror.CodeLicenseRequired:
+		return "Accept the license with 'silmaril get --accept-license'."
+	case apierror.CodeRelayMode:
+		return "Relays don't store models, use another daemon or set daemon.mode to full and restart it."
+	case apierror.CodeModelBlocked:
+		return "See the model blocklist with 'silmaril blocklist list'."
+	case apierror.CodeUnauthorized:
+		return "Set SILMARIL_API_TOKEN to an API token of the daemon, see 'silmaril token --help'."
+	case apierror.CodeForbidden:
+		return "Use a token with the required scope, see 'silmaril token list'."
+	case apierror.CodeInternal:
+		return "See what went wrong with 'silmaril daemon logs'."
+	}
+	if apiErr.Retryable {
+		return "This is likely temporary, try again in a moment."
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/api/apierror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{fmt.Errorf("plain failure"), exitFailure},
+		{apierror.New(http.StatusBadRequest, "bad"), exitInvalid},
+		{apierror.New(http.StatusNotFound, "missing"), exitNotFound},
+		{apierror.New(http.StatusConflict, "busy").WithCode(apierror.CodeModelInUse), exitConflict},
+		{apierror.New(http.StatusConflict, "relay").WithCode(apierror.CodeRelayMode), exitConflict},
+		{apierror.New(http.StatusForbidden, "license").WithCode(apierror.CodeLicenseRequired), exitLicense},
+		{apierror.New(http.StatusServiceUnavailable, "later"), exitUnavailable},
+		{apierror.New(http.StatusUnauthorized, "token required"), exitDenied},
+		{apierror.New(http.StatusForbidden, "needs admin"), exitDenied},
+		{apierror.New(http.StatusForbidden, "blocked").WithCode(apierror.CodeModelBlocked), exitDenied},
+		{apierror.New(http.StatusInternalServerError, "broken"), exitFailure},
+		// Errors wrapped by commands keep their code
+		{fmt.Errorf("failed to remove model: %w", apierror.New(http.StatusNotFound, "missing")), exitNotFound},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.code, exitCode(tt.err), tt.err.Error())
+	}
+}
+
+func TestErrorHint(t *testing.T) {
+	assert.Empty(t, errorHint(fmt.Errorf("plain failure")))
+	assert.Contains(t, errorHint(apierror.New(http.StatusConflict, "busy").WithCode(apierror.CodeModelInUse)), "silmaril cancel")
+	assert.Contains(t, errorHint(apierror.New(http.StatusServiceUnavailable, "later")), "try again")
+	assert.Contains(t, errorHint(apierror.New(http.StatusUnauthorized, "token required")), "SILMARIL_API_TOKEN")
+}
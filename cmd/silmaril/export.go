@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/oci"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var exportModelCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export local models to other distribution channels",
+}
+
+var exportOCICmd = &cobra.Command{
+	Use:   "oci <model-name> <registry/repository:tag>",
+	Short: "Push a model to an OCI registry",
+	Long: `Push a local model to a container registry as an OCI artifact, for
+environments where P2P traffic is blocked. The Silmaril manifest is the
+config blob and every model file is a layer named after its path, so the
+artifact can also be pulled with ORAS.
+
+Credentials are read from SILMARIL_OCI_USERNAME and SILMARIL_OCI_PASSWORD,
+or from 'docker login' (credential helpers are not supported). Files the
+registry already has are not uploaded again.
+
+Examples:
+  silmaril export oci meta-llama/Llama-3.1-8B ghcr.io/acme/llama-3.1-8b:v1
+  silmaril export oci meta-llama/Llama-3.1-8B localhost:5000/llama:v1 --plain-http
+  silmaril import oci ghcr.io/acme/llama-3.1-8b:v1`,
+	Args: cobra.ExactArgs(2),
+	RunE: runExportOCI,
+}
+
+func init() {
+	rootCmd.AddCommand(exportModelCmd)
+	exportModelCmd.AddCommand(exportOCICmd)
+	exportOCICmd.Flags().Bool("plain-http", false, "Talk to the registry over HTTP instead of HTTPS")
+}
+
+func runExportOCI(cmd *cobra.Command, args []string) error {
+	modelName, err := modelname.Canonical(args[0])
+	if err != nil {
+		return err
+	}
+	plainHTTP, _ := cmd.Flags().GetBool("plain-http")
+
+	ref, err := oci.ParseReference(args[1])
+	if err != nil {
+		return err
+	}
+
+	paths, err := storage.NewPaths()
+	if err != nil {
+		return fmt.Errorf("failed to initialize paths: %w", err)
+	}
+	modelPath, err := paths.ModelDir(modelName)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(modelPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("model %s not found locally, download it with 'silmaril get %s'", modelName, modelName)
+	}
+	if storage.IsPartial(modelPath) {
+		return fmt.Errorf("model %s is still downloading", modelName)
+	}
+
+	registry, err := models.NewRegistry(paths)
+	if err != nil {
+		return fmt.Errorf("failed to load registry: %w", err)
+	}
+	manifest, err := registry.GetManifest(modelName)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client := oci.NewClient()
+	client.PlainHTTP = plainHTTP
+
+	fmt.Printf("Pushing %s to %s\n", modelName, ref)
+	digest, err := client.PushModel(ctx, ref, modelPath, manifest, func(file string, size int64, skipped bool) {
+		if skipped {
+			fmt.Printf("  %s (already in registry)\n", file)
+			return
+		}
+		fmt.Printf("  %s (%.2f MB)\n", file, float64(size)/(1024*1024))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export model: %w", err)
+	}
+
+	fmt.Printf("✓ Exported %s to %s\n", modelName, ref)
+	fmt.Printf("  Digest: %s\n", digest)
+	return nil
+}
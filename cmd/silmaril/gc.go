@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove orphaned torrents, downloads and temporary files",
+	Long: `Remove what no model, transfer or subscription uses anymore:
+
+  - catalog torrents superseded by a newer catalog
+  - cached catalogs of publishers you unsubscribed from
+  - torrent files of removed models and transfers
+  - unfinished downloads no transfer resumes, untouched for a week
+  - update downloads no subscription waits for
+  - temporary files left by interrupted writes
+
+Files changed in the last day are left alone. The daemon also collects
+garbage every storage.gc_interval_hours.
+
+Examples:
+  silmaril gc --dry-run   # List what would be removed
+  silmaril gc             # Remove it`,
+	Args: cobra.NoArgs,
+	RunE: runGC,
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+	gcCmd.Flags().Bool("dry-run", false, "List what would be removed without removing it")
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	report, err := newDaemonClient().CollectGarbage(dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to collect garbage: %w", err)
+	}
+
+	items, _ := report["items"].([]interface{})
+	freed, _ := report["freed"].(float64)
+	if len(items) == 0 {
+		fmt.Println("Nothing to remove.")
+		return nil
+	}
+
+	for _, raw := range items {
+		item, _ := raw.(map[string]interface{})
+		kind, _ := item["kind"].(string)
+		path, _ := item["path"].(string)
+		size, _ := item["size"].(float64)
+		reason, _ := item["reason"].(string)
+		fmt.Printf("  [%s] %s (%.2f MB)\n", kind, path, size/(1024*1024))
+		fmt.Printf("    %s\n", reason)
+	}
+	fmt.Println()
+	if dryRun {
+		fmt.Printf("Would remove %d items, freeing %.2f MB. Run without --dry-run to remove them.\n", len(items), freed/(1024*1024))
+	} else {
+		fmt.Printf("✓ Removed %d items, freed %.2f MB\n", len(items), freed/(1024*1024))
+	}
+
+	if errs, _ := report["errors"].([]interface{}); len(errs) > 0 {
+		fmt.Println("\nFailed to remove:")
+		for _, e := range errs {
+			fmt.Printf("  %v\n", e)
+		}
+	}
+	return nil
+}
@@ -1,20 +1,60 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/silmaril/silmaril/internal/api/apierror"
+	"github.com/silmaril/silmaril/internal/api/client"
+	"github.com/silmaril/silmaril/internal/modelname"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"github.com/silmaril/silmaril/internal/api/client"
-	"github.com/schollz/progressbar/v3"
 )
 
 var getCmd = &cobra.Command{
 	Use:   "get [model-name]",
 	Short: "Download a model from the P2P network",
 	Long: `Downloads a model from the Silmaril P2P network.
-Shows progress with speed and ETA, verifies checksums after download.`,
+Shows progress with a bar per file, speed, peers and ETA, along with the
+daemon's events about the model, and verifies checksums after download.
+
+With --json the progress is printed as one JSON object per line instead,
+"progress" lines with the bytes of the download and each file and "event"
+lines with the daemon's events, for scripts. Other messages go to stderr.
+Models whose license must be accepted need --accept-license then.
+
+  silmaril get org/model --json --accept-license | jq -c 'select(.type == "progress") | .bytes'
+
+With --files only the matching files are downloaded and seeded, for example a
+single quantization of a repository holding many. Patterns without a slash
+match file names, others the path within the model.
+
+Examples:
+  silmaril get TheBloke/Llama-2-7B-GGUF --files "*Q4_K_M.gguf"
+  silmaril get org/model --files "*.json,*.safetensors"
+
+Encrypted models are decrypted once downloaded, with the key given by the
+publisher or fetched from the key endpoint of the model with --key-token.
+Without either, add the key later with 'silmaril decrypt'.
+
+  silmaril get org/gated-model --key <key>
+
+With --output the files are stored in another directory, such as a fast
+scratch disk, instead of the models directory. The directory is linked
+into the models directory, so the model is listed and seeded from there
+like any other; 'silmaril remove' only removes the link. The directory
+must be empty and below one of the storage.output_dirs of the daemon.
+
+  silmaril get org/model --output /mnt/nvme/org-model
+
+Models whose license must be accepted ask for acceptance before the first
+download. The acceptance is kept by the daemon; --accept-license accepts
+without asking, for scripts.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runGet,
 }
@@ -23,14 +63,26 @@ var (
 	outputDir   string
 	keepSeeding bool
 	noVerify    bool
+	getFiles    []string
+	getKey      string
+	getKeyToken string
+	acceptLicense bool
+	forceGet      bool
+	getJSON       bool
 )
 
 func init() {
 	rootCmd.AddCommand(getCmd)
 	
-	getCmd.Flags().StringVarP(&outputDir, "output", "o", "", "output directory (default: ~/.silmaril/models/)")
+	getCmd.Flags().StringVarP(&outputDir, "output", "o", "", "directory to store the model files in (default: the models directory)")
 	getCmd.Flags().BoolVar(&keepSeeding, "seed", true, "continue seeding after download")
 	getCmd.Flags().BoolVar(&noVerify, "no-verify", false, "skip checksum verification")
+	getCmd.Flags().StringSliceVar(&getFiles, "files", nil, "only download and seed files matching these glob patterns")
+	getCmd.Flags().StringVar(&getKey, "key", "", "key to decrypt an encrypted model")
+	getCmd.Flags().StringVar(&getKeyToken, "key-token", "", "token for the key endpoint of an encrypted model")
+	getCmd.Flags().BoolVar(&acceptLicense, "accept-license", false, "accept the model license without asking")
+	getCmd.Flags().BoolVar(&forceGet, "force", false, "download again even if the model is downloading or downloaded")
+	getCmd.Flags().BoolVar(&getJSON, "json", false, "print the progress as JSON lines, for scripts")
 	
 	viper.BindPFlag("output", getCmd.Flags().Lookup("output"))
 	viper.BindPFlag("seed", getCmd.Flags().Lookup("seed"))
@@ -38,7 +90,10 @@ func init() {
 }
 
 func runGet(cmd *cobra.Command, args []string) error {
-	modelName := args[0]
+	modelName, err := modelname.Canonical(args[0])
+	if err != nil {
+		return err
+	}
 	
 	// Ensure daemon is running
 	if err := ensureDaemonRunning(); err != nil {
@@ -46,13 +101,19 @@ func runGet(cmd *cobra.Command, args []string) error {
 	}
 	
 	// Create API client
-	apiClient := client.NewClient(getDaemonURL())
+	apiClient := newDaemonClient()
+	
+	// With --json only the progress lines go to stdout
+	var info io.Writer = os.Stdout
+	if getJSON {
+		info = os.Stderr
+	}
 	
 	// Check if model exists
 	model, err := apiClient.GetModel(modelName)
 	if err != nil {
 		// Model not found locally, try to discover it
-		fmt.Printf("Model not found locally, searching on P2P network...\n")
+		fmt.Fprintf(info, "Model not found locally, searching on P2P network...\n")
 		
 		models, err := apiClient.DiscoverModels(modelName)
 		if err != nil {
@@ -63,20 +124,32 @@ func runGet(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("model '%s' not found on the network", modelName)
 		}
 		
-		// Use the first matching model
-		model = models[0]
+		// The search also matches other names, descriptions and tags
+		model = findDiscoveredModel(models, modelName)
+		if model == nil {
+			fmt.Fprintf(info, "No model is named '%s', did you mean:\n", modelName)
+			for _, m := range models {
+				if name, ok := m["name"].(string); ok {
+					fmt.Fprintf(info, "  %s\n", name)
+				}
+			}
+			return fmt.Errorf("model '%s' not found on the network", modelName)
+		}
 	} else {
-		fmt.Printf("Model already exists locally. Use 'silmaril share %s' to seed it.\n", modelName)
+		fmt.Fprintf(info, "Model already exists locally. Use 'silmaril share %s' to seed it.\n", modelName)
 		return nil
 	}
 	
 	// Display model information
-	fmt.Printf("\nModel: %s\n", modelName)
+	fmt.Fprintf(info, "\nModel: %s\n", modelName)
 	if version, ok := model["version"].(string); ok && version != "" {
-		fmt.Printf("Version: %s\n", version)
+		fmt.Fprintf(info, "Version: %s\n", version)
 	}
 	if license, ok := model["license"].(string); ok && license != "" {
-		fmt.Printf("License: %s\n", license)
+		fmt.Fprintf(info, "License: %s\n", license)
+	}
+	if licenseURL, ok := model["license_url"].(string); ok && licenseURL != "" {
+		fmt.Fprintf(info, "License terms: %s\n", licenseURL)
 	}
 	
 	var totalSize float64
@@ -87,10 +160,10 @@ func runGet(cmd *cobra.Command, args []string) error {
 	}
 	
 	if totalSize > 0 {
-		fmt.Printf("Size: %.2f GB\n", totalSize/(1024*1024*1024))
+		fmt.Fprintf(info, "Size: %.2f GB\n", totalSize/(1024*1024*1024))
 	}
 	
-	fmt.Println("\nStarting download...")
+	fmt.Fprintln(info, "\nStarting download...")
 	
 	// Start the download via API
 	infoHash := ""
@@ -98,7 +171,34 @@ func runGet(cmd *cobra.Command, args []string) error {
 		infoHash = ih
 	}
 	
-	result, err := apiClient.DownloadModel(modelName, infoHash, keepSeeding)
+	// The daemon may run in another directory
+	if outputDir != "" {
+		abs, err := filepath.Abs(outputDir)
+		if err != nil {
+			return fmt.Errorf("invalid output directory: %w", err)
+		}
+		outputDir = abs
+	}
+	
+	if getKey != "" || getKeyToken != "" {
+		if _, err := apiClient.SetModelKey(modelName, getKey, getKeyToken); err != nil {
+			return fmt.Errorf("failed to set key: %w", err)
+		}
+	}
+	
+	// Events of the download are shown from here on
+	_, lastSeq, _ := apiClient.GetEvents(0)
+	
+	// Scripts must accept licenses with --accept-license, there is no one
+	// to ask
+	result, err := apiClient.DownloadModel(modelName, infoHash, keepSeeding, getFiles, acceptLicense, forceGet, outputDir)
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) && apiErr.Code == apierror.CodeLicenseRequired && !getJSON {
+		if !confirmLicense(modelName, apiErr.Details) {
+			return fmt.Errorf("license of %s not accepted, download cancelled", modelName)
+		}
+		result, err = apiClient.DownloadModel(modelName, infoHash, keepSeeding, getFiles, true, forceGet, outputDir)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to start download: %w", err)
 	}
@@ -112,66 +212,104 @@ func runGet(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no transfer ID returned from daemon")
 	}
 	
-	fmt.Printf("Download started (Transfer ID: %s)\n", transferID)
-	
-	// Create progress bar
-	bar := progressbar.NewOptions64(
-		int64(totalSize),
-		progressbar.OptionSetDescription("Downloading"),
-		progressbar.OptionShowBytes(true),
-		progressbar.OptionShowCount(),
-		progressbar.OptionSetWidth(40),
-		progressbar.OptionThrottle(100*time.Millisecond),
-		progressbar.OptionSetRenderBlankState(true),
-	)
+	if existing, _ := result["existing"].(bool); existing {
+		fmt.Fprintf(info, "Download already in progress (Transfer ID: %s), use --force to start over\n", transferID)
+	} else {
+		fmt.Fprintf(info, "Download started (Transfer ID: %s)\n", transferID)
+	}
+	if len(getFiles) > 0 {
+		fmt.Fprintf(info, "Files: %s\n", strings.Join(getFiles, ", "))
+	}
+	if outputDir != "" {
+		fmt.Fprintf(info, "Output: %s\n", outputDir)
+	}
+	if fetching, _ := result["fetching_metadata"].(bool); fetching {
+		fmt.Fprintln(info, "Fetching the torrent metadata from peers...")
+	}
 	
-	// Monitor progress
+	return watchDownload(apiClient, modelName, transferID, lastSeq, newProgressReporter(os.Stdout, getJSON), info)
+}
+
+// watchDownload shows the progress of a download and the daemon events about
+// its model, from the event after lastSeq on, until the download ends
+func watchDownload(apiClient *client.Client, modelName, transferID string, lastSeq int64, reporter progressReporter, info io.Writer) error {
 	for {
 		transfer, err := apiClient.GetTransfer(transferID)
 		if err != nil {
 			return fmt.Errorf("failed to get transfer status: %w", err)
 		}
 		
-		status := ""
-		if s, ok := transfer["status"].(string); ok {
-			status = s
+		// Events can't be had from daemons older than the events API, the
+		// progress is shown all the same
+		if events, seq, err := apiClient.GetEvents(lastSeq); err == nil {
+			for _, event := range events {
+				if model, _ := event["model"].(string); model == modelName {
+					reporter.Event(event)
+				}
+			}
+			lastSeq = seq
 		}
 		
-		if status == "completed" {
-			bar.Finish()
-			fmt.Println("\n✅ Download complete!")
-			
+		// Files are known once the metadata arrived
+		files, _ := apiClient.GetTransferFiles(transferID)
+		snapshot := snapshotFromAPI(transfer, files)
+		reporter.Progress(snapshot)
+		
+		switch snapshot.Status {
+		case "completed":
+			reporter.Done()
+			fmt.Fprintln(info, "\n✅ Download complete!")
 			if keepSeeding {
-				fmt.Println("Model is now seeding. Use 'silmaril share' to manage seeding.")
+				fmt.Fprintln(info, "Model is now seeding. Use 'silmaril share' to manage seeding.")
 			}
 			return nil
-		}
-		
-		if status == "failed" {
+		case "failed":
+			reporter.Done()
 			errorMsg := "unknown error"
-			if e, ok := transfer["error"].(string); ok {
-				errorMsg = e
+			if snapshot.Error != "" {
+				errorMsg = snapshot.Error
 			}
 			return fmt.Errorf("download failed: %s", errorMsg)
-		}
-		
-		if status == "cancelled" {
+		case "cancelled":
+			reporter.Done()
 			return fmt.Errorf("download was cancelled")
 		}
 		
-		// Update progress
-		if bytesTransferred, ok := transfer["bytes_transferred"].(float64); ok {
-			bar.Set64(int64(bytesTransferred))
-		}
-		
-		// Show peer info
-		if peers, ok := transfer["peers"].(float64); ok {
-			if seeders, ok := transfer["seeders"].(float64); ok {
-				bar.Describe(fmt.Sprintf("Downloading [%d peers, %d seeders]", int(peers), int(seeders)))
-			}
-		}
-		
 		// Wait before next poll
 		time.Sleep(1 * time.Second)
 	}
-}
\ No newline at end of file
+}
+
+// findDiscoveredModel returns the discovered model named name, the newest if
+// the catalogs list several versions
+func findDiscoveredModel(models []map[string]interface{}, name string) map[string]interface{} {
+	var found map[string]interface{}
+	var foundTime float64
+	for _, model := range models {
+		if n, _ := model["name"].(string); n != name {
+			continue
+		}
+		if ih, _ := model["info_hash"].(string); ih == "" {
+			continue
+		}
+		t, _ := model["time"].(float64)
+		if found == nil || t > foundTime {
+			found, foundTime = model, t
+		}
+	}
+	return found
+}
+
+// confirmLicense asks the user to accept the license of a model the daemon
+// refused to download without acceptance, described by the error details
+func confirmLicense(modelName string, details map[string]interface{}) bool {
+	license, _ := details["license"].(string)
+	fmt.Printf("\n%s requires accepting its license %s before downloading.\n", modelName, license)
+	if licenseURL, ok := details["license_url"].(string); ok && licenseURL != "" {
+		fmt.Printf("Read the terms at: %s\n", licenseURL)
+	}
+	fmt.Printf("Type 'yes' to accept the license: ")
+	var response string
+	fmt.Scanln(&response)
+	return response == "yes"
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/api/client"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the transfers that ended",
+	Long: `Show the downloads and seeds that completed, were cancelled or failed,
+with the bytes transferred and the most peers they had at once. The history
+outlives the transfers themselves and is kept for
+storage.history_retention_days.
+
+Examples:
+  silmaril history                     # Recent transfers
+  silmaril history --result failed     # Failed downloads and why
+  silmaril history --type seed --since 720h  # What was seeded in 30 days
+  silmaril history --model org/model   # Transfers of a model`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().String("model", "", "Only show transfers of this model")
+	historyCmd.Flags().String("type", "", "Only show transfers of this type (download, upload, seed)")
+	historyCmd.Flags().String("result", "", "Only show transfers that ended this way (completed, cancelled, failed)")
+	historyCmd.Flags().String("since", "", "Only show transfers that ended within a duration (e.g. 24h)")
+	historyCmd.Flags().Int("limit", 50, "Show at most this many transfers, 0 for all")
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	var opts client.HistoryOptions
+	opts.Model, _ = cmd.Flags().GetString("model")
+	opts.Type, _ = cmd.Flags().GetString("type")
+	opts.Result, _ = cmd.Flags().GetString("result")
+	opts.Since, _ = cmd.Flags().GetString("since")
+	opts.Limit, _ = cmd.Flags().GetInt("limit")
+
+	entries, err := newDaemonClient().GetHistory(opts)
+	if err != nil {
+		return fmt.Errorf("failed to get history: %w", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No transfers in the history.")
+		return nil
+	}
+
+	var uploaded, downloaded float64
+	for _, entry := range entries {
+		displayHistoryEntry(entry)
+		bytes, _ := entry["bytes_transferred"].(float64)
+		if entryType, _ := entry["type"].(string); entryType == "download" {
+			downloaded += bytes
+		} else {
+			uploaded += bytes
+		}
+	}
+	fmt.Printf("Total transfers: %d | Downloaded: %.2f GB | Uploaded: %.2f GB\n",
+		len(entries), downloaded/(1024*1024*1024), uploaded/(1024*1024*1024))
+	return nil
+}
+
+func displayHistoryEntry(entry map[string]interface{}) {
+	name, _ := entry["model_name"].(string)
+	entryType, _ := entry["type"].(string)
+	result, _ := entry["result"].(string)
+	fmt.Printf("  %s (%s, %s)\n", name, entryType, result)
+
+	started := parseHistoryTime(entry["started_at"])
+	ended := parseHistoryTime(entry["ended_at"])
+	if !ended.IsZero() {
+		fmt.Printf("    Ended: %s", ended.Local().Format("2006-01-02 15:04"))
+		if !started.IsZero() && ended.After(started) {
+			fmt.Printf(" | Took: %v", ended.Sub(started).Round(time.Second))
+		}
+		fmt.Println()
+	}
+
+	bytes, _ := entry["bytes_transferred"].(float64)
+	total, _ := entry["total_bytes"].(float64)
+	peers, _ := entry["peak_peers"].(float64)
+	direction := "Uploaded"
+	if entryType == "download" {
+		direction = "Downloaded"
+	}
+	fmt.Printf("    %s: %.2f GB", direction, bytes/(1024*1024*1024))
+	if total > 0 {
+		fmt.Printf(" of %.2f GB", total/(1024*1024*1024))
+	}
+	fmt.Printf(" | Peak peers: %.0f\n", peers)
+
+	if reason, ok := entry["stop_reason"].(string); ok && reason != "" {
+		fmt.Printf("    Seeding stopped: %s\n", reason)
+	}
+	if errMsg, ok := entry["error"].(string); ok && errMsg != "" {
+		fmt.Printf("    Error: %s\n", errMsg)
+	}
+	fmt.Println()
+}
+
+func parseHistoryTime(value interface{}) time.Time {
+	s, _ := value.(string)
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
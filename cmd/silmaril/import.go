@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/oci"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var importModelCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import models from other distribution channels",
+}
+
+var importOCICmd = &cobra.Command{
+	Use:   "oci <registry/repository[:tag|@digest]>",
+	Short: "Pull a model from an OCI registry",
+	Long: `Pull a model pushed with 'silmaril export oci' from a container registry
+into the models directory. The model keeps the name it was exported under
+unless --name is given. Share it with 'silmaril share <model-name>' to seed
+it to peers that can't reach the registry.
+
+An interrupted import can be run again; files already pulled are kept.
+
+Examples:
+  silmaril import oci ghcr.io/acme/llama-3.1-8b:v1
+  silmaril import oci localhost:5000/llama:v1 --plain-http --name acme/llama`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImportOCI,
+}
+
+func init() {
+	rootCmd.AddCommand(importModelCmd)
+	importModelCmd.AddCommand(importOCICmd)
+	importOCICmd.Flags().String("name", "", "Model name to import as (default the exported name)")
+	importOCICmd.Flags().Bool("plain-http", false, "Talk to the registry over HTTP instead of HTTPS")
+}
+
+func runImportOCI(cmd *cobra.Command, args []string) error {
+	name, _ := cmd.Flags().GetString("name")
+	plainHTTP, _ := cmd.Flags().GetBool("plain-http")
+
+	ref, err := oci.ParseReference(args[0])
+	if err != nil {
+		return err
+	}
+
+	paths, err := storage.NewPaths()
+	if err != nil {
+		return fmt.Errorf("failed to initialize paths: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client := oci.NewClient()
+	client.PlainHTTP = plainHTTP
+
+	// The model name is in the config blob, so look at it before pulling
+	if name == "" {
+		manifest, err := client.FetchModelManifest(ctx, ref)
+		if err != nil {
+			return err
+		}
+		name = manifest.Name
+	}
+	if name == "" {
+		return fmt.Errorf("%s has no model name, set one with --name", ref)
+	}
+	if name, err = modelname.Canonical(name); err != nil {
+		return err
+	}
+
+	modelPath, err := paths.ModelDir(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(modelPath); err == nil && !storage.IsPartial(modelPath) {
+		return fmt.Errorf("model %s already exists, remove it first with 'silmaril remove %s --purge'", name, name)
+	}
+	if err := storage.MarkPartial(modelPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("Pulling %s as %s\n", ref, name)
+	manifest, err := client.PullModel(ctx, ref, modelPath, func(file string, size int64, skipped bool) {
+		if skipped {
+			fmt.Printf("  %s (already pulled)\n", file)
+			return
+		}
+		fmt.Printf("  %s (%.2f MB)\n", file, float64(size)/(1024*1024))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import model: %w", err)
+	}
+
+	manifest.Name = name
+	registry, err := models.NewRegistry(paths)
+	if err != nil {
+		return fmt.Errorf("failed to load registry: %w", err)
+	}
+	if err := registry.SaveManifest(manifest); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+	if err := storage.ClearPartial(modelPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Imported %s from %s\n", name, ref)
+	fmt.Printf("\nShare it with peers with 'silmaril share %s'\n", name)
+	return nil
+}
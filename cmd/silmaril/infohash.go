@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/silmaril/silmaril/internal/api/client"
+	"github.com/spf13/cobra"
+)
+
+var infohashCmd = &cobra.Command{
+	Use:   "infohash <directory>",
+	Short: "Compute the infohash a directory would be published with",
+	Long: `Compute the infohash of the torrent publishing a directory would create,
+without writing anything. The files are hashed, so this takes as long as
+creating the torrent.
+
+Torrents only depend on the published files and the piece length: files are
+sorted by path, torrents have no creation date, and without --piece-length
+the piece length is the canonical one for the size of the model. Anyone
+publishing the same files, such as the same revision of a HuggingFace
+repository, gets the same infohash and joins the same swarm.
+
+--expect checks the directory against an infohash announced on the network,
+and fails if they differ.
+
+Examples:
+  silmaril infohash ./Llama-3.1-8B
+  silmaril infohash ./Llama-3.1-8B --expect 5f0c3b0b6e5a1d0c9e0a4b7a2d1e8f3c4b5a6d7e
+  silmaril infohash ./model --exclude "*.bin"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runInfohash,
+}
+
+func init() {
+	rootCmd.AddCommand(infohashCmd)
+	infohashCmd.Flags().Int64("piece-length", 0, "piece length of the torrent (default canonical for the model size)")
+	infohashCmd.Flags().String("expect", "", "fail unless the directory has this infohash")
+	infohashCmd.Flags().StringSlice("exclude", nil, "leave out files matching these .gitignore style patterns")
+	infohashCmd.Flags().StringSlice("include", nil, "keep files matching these patterns even if they are excluded")
+}
+
+func runInfohash(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	dir, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	pieceLength, _ := cmd.Flags().GetInt64("piece-length")
+	expect, _ := cmd.Flags().GetString("expect")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+	include, _ := cmd.Flags().GetStringSlice("include")
+
+	apiClient := newDaemonClient()
+	// The files are hashed before the daemon answers
+	apiClient.SetTimeout(0)
+	result, err := apiClient.ComputeInfoHash(client.InfoHashOptions{
+		Path:        dir,
+		PieceLength: pieceLength,
+		Exclude:     exclude,
+		Include:     include,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to compute infohash: %w", err)
+	}
+
+	infoHash, _ := result["info_hash"].(string)
+	pieces, _ := result["pieces"].(float64)
+	length, _ := result["piece_length"].(float64)
+	files, _ := result["files"].(float64)
+	size, _ := result["total_size"].(float64)
+
+	fmt.Println(infoHash)
+	fmt.Printf("  %.0f files | %s | %.0f pieces of %s\n", files, formatProgressBytes(int64(size)), pieces, formatProgressBytes(int64(length)))
+
+	if expect != "" {
+		if !strings.EqualFold(expect, infoHash) {
+			return fmt.Errorf("infohash %s does not match the expected %s", infoHash, expect)
+		}
+		fmt.Println("  Matches the expected infohash")
+	}
+	return nil
+}
@@ -28,7 +28,8 @@ This command will create:
   - Torrents directory for torrent files
   - Registry directory for model manifests
   - Database directory for metadata
-  - Configuration file (~/.config/silmaril/config.yaml)
+  - Configuration file (~/.config/silmaril/config.yaml, %APPDATA%\silmaril on
+    Windows, ~/Library/Application Support/silmaril on macOS)
 
 Use --path to initialize in a custom location instead of the default.
 Use --cleanup to remove all Silmaril directories and configuration.`,
@@ -85,11 +86,14 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create configuration file
-	configDir := filepath.Join(os.Getenv("HOME"), ".config", "silmaril")
+	configDir := config.UserConfigDir()
 	if initPath != "" {
 		// If custom path, put config in the same directory
 		configDir = filepath.Join(baseDir, "config")
 	}
+	if configDir == "" {
+		return fmt.Errorf("failed to determine the config directory, use --path")
+	}
 	
 	if err := createDirectory(configDir, "Configuration directory"); err != nil {
 		return err
@@ -175,6 +179,11 @@ storage:
   torrents_dir: %s
   registry_dir: %s
   db_dir: %s
+  scrub_interval_hours: 168 # re-verify seeded data weekly, 0 = never
+  auto_share_new_models: false # publish models copied into models_dir by hand
+  hash_rate_mb: 50 # MB/s read to checksum large model files, 0 = never
+  history_retention_days: 90 # keep finished transfers in the history, 0 = forever
+  gc_interval_hours: 24 # remove orphaned torrents, downloads and temp files, 0 = never
 
 # Network configuration
 network:
@@ -183,32 +192,53 @@ network:
     - "router.bittorrent.com:6881"
     - "dht.transmissionbt.com:6881"
     - "router.utorrent.com:6881"
-  dht_port: 0  # 0 = random port
-  listen_port: 0  # 0 = random port
-  max_connections: 50
+  dht_port: 0  # 0 = random port, kept across restarts
+  dht_announce_interval_minutes: 15  # announce seeded torrents by infohash, 0 = never
+  listen_port: 0  # 0 = random port, kept across restarts
+  listen_addrs: []  # e.g. ["0.0.0.0:42069", "[::]:42069"], empty = all interfaces
+  ip_preference: dual  # dual, prefer_ipv4, prefer_ipv6, ipv4_only or ipv6_only
+  max_connections: 50  # established peer connections of all torrents
+  max_connections_per_torrent: 50
+  max_half_open: 100  # connection attempts in progress
+  max_half_open_per_torrent: 25
   upload_rate_limit: 0    # bytes/sec, 0 = unlimited
   download_rate_limit: 0  # bytes/sec, 0 = unlimited
+  upload_fairness: true   # divide upload_rate_limit between models by seed priority
   disable_trackers: true
+  peer_blocklist: []  # addresses, CIDR prefixes or ranges to refuse
+  peer_allowlist: []  # only accept these peers, empty = all
+  peer_blocklist_url: ""  # P2P format blocklist URL or file, may be gzipped
+  peer_blocklist_refresh_hours: 24
+  share_stats: false  # opt-in, publish anonymous daily network stats
+
+# Outbound proxy, socks5://, socks5h:// or http://host:port
+proxy:
+  url: ""
+  no_proxy: ["localhost", "127.0.0.1", "::1"]
+  # Per component overrides, empty = use url, "direct" = no proxy
+  torrent: ""
+  dht: ""
+  git: ""
+  api: ""
 
 # Torrent configuration
 torrent:
   piece_length: 4194304   # 4MB pieces
   seed_ratio: 0           # 0 = unlimited
   seed_time: 0            # seconds, 0 = unlimited
+  stop_if_no_peers_for: 0 # seconds without peers, 0 = never
+  initial_seeding: true # focus uploads on fewer peers while we are the only seeder
+  swarm_coordinator: false # download rare pieces first, keep seeding pieces only we have
   download_timeout: 1800  # 30 minutes
-
-# UI configuration
-ui:
-  progress_bar: true
-  color: true
-  verbose: false
+  storage_backend: file   # file, mmap or cached
 
 # Security configuration
 security:
   sign_manifests: true
   verify_manifests: true
-  verify_checksums: true
   keys_dir: %s
+  model_blocklist_urls: []  # feeds of infohashes and model names to refuse
+  model_blocklist_refresh_hours: 24
 `,
 		baseDir,
 		filepath.Join(baseDir, "models"),
@@ -249,8 +279,7 @@ func InitializeEnvironment() error {
 	// Ensure config is loaded
 	if err := config.Initialize(); err != nil {
 		// Config might not exist, try to create it
-		homeDir, _ := os.UserHomeDir()
-		configPath := filepath.Join(homeDir, ".config", "silmaril", "config.yaml")
+		configPath := filepath.Join(config.UserConfigDir(), "config.yaml")
 		if _, err := os.Stat(configPath); os.IsNotExist(err) {
 			// Create minimal config
 			configDir := filepath.Dir(configPath)
@@ -276,7 +305,7 @@ func cleanupSilmaril(baseDir string) error {
 	fmt.Printf("  - Base directory: %s\n", baseDir)
 	
 	// Determine config directory
-	configDir := filepath.Join(os.Getenv("HOME"), ".config", "silmaril")
+	configDir := config.UserConfigDir()
 	if initPath != "" {
 		configDir = filepath.Join(baseDir, "config")
 	}
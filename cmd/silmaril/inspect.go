@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <model>",
+	Short: "Show the manifest, files, torrent and seeding of a local model",
+	Long: `Show everything the daemon knows about a local model: its manifest, the
+files it lists with their sizes and hashes checked against the files on
+disk, the pieces of its torrent, whether the manifest is signed, and how
+the model is seeded.
+
+Files are marked missing or with the wrong size when they don't match the
+manifest, and files on disk the manifest doesn't list are shown as extra.
+Signatures can only be verified for manifests signed with this node's key.
+
+Examples:
+  silmaril inspect meta-llama/Llama-3.1-8B
+  silmaril inspect llama --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runInspect,
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+	inspectCmd.Flags().Bool("json", false, "Print the inspection as JSON")
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	inspection, err := apiClient.InspectModel(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to inspect model: %w", err)
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		data, err := json.MarshalIndent(inspection, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode inspection: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	name, _ := inspection["name"].(string)
+	path, _ := inspection["path"].(string)
+	fmt.Printf("%s\n", name)
+	fmt.Printf("  Path: %s", path)
+	if linked, _ := inspection["linked"].(bool); linked {
+		fmt.Print(" (imported in place)")
+	}
+	fmt.Println()
+
+	if manifest, ok := inspection["manifest"].(map[string]interface{}); ok {
+		printInspectedManifest(manifest)
+	}
+	if signature, ok := inspection["signature"].(map[string]interface{}); ok {
+		status, _ := signature["status"].(string)
+		fmt.Printf("  Signature: %s", status)
+		if detail, _ := signature["detail"].(string); detail != "" {
+			fmt.Printf(" (%s)", detail)
+		}
+		fmt.Println()
+	}
+	if signers, ok := inspection["signers"].(map[string]interface{}); ok {
+		printInspectedSigners(signers)
+	}
+	if publisher, ok := inspection["publisher"].(map[string]interface{}); ok {
+		printInspectedPublisher(publisher)
+	}
+	if files, ok := inspection["files"].([]interface{}); ok {
+		printInspectedFiles(files)
+	}
+	if torrent, ok := inspection["torrent"].(map[string]interface{}); ok {
+		printInspectedTorrent(torrent)
+	} else {
+		fmt.Println("\n  Torrent: none")
+	}
+	if seeding, ok := inspection["seeding"].(map[string]interface{}); ok {
+		printInspectedSeeding(seeding)
+	}
+	return nil
+}
+
+// printInspectedSigners prints the publisher keys that signed the manifest
+// and its signature policy
+func printInspectedSigners(signers map[string]interface{}) {
+	status, _ := signers["status"].(string)
+	keys, _ := signers["signers"].([]interface{})
+	fmt.Printf("  Signers:   %s, %d signatures", status, len(keys))
+	if detail, _ := signers["detail"].(string); detail != "" {
+		fmt.Printf(" (%s)", detail)
+	}
+	fmt.Println()
+	if policyID, _ := signers["policy_id"].(string); policyID != "" {
+		threshold, _ := signers["threshold"].(float64)
+		total, _ := signers["keys"].(float64)
+		fmt.Printf("  Policy:    %d of %d keys, %s", int(threshold), int(total), policyID)
+		if org, _ := signers["organization"].(string); org != "" {
+			fmt.Printf(" (%s)", org)
+		}
+		fmt.Println()
+	}
+	for _, key := range keys {
+		fmt.Printf("    %v\n", key)
+	}
+}
+
+// printInspectedPublisher prints whether the catalog publisher of the
+// model proved it owns its namespace
+func printInspectedPublisher(publisher map[string]interface{}) {
+	namespace, _ := publisher["namespace"].(string)
+	domain, _ := publisher["domain"].(string)
+	if verified, _ := publisher["verified"].(bool); verified {
+		method, _ := publisher["method"].(string)
+		fmt.Printf("  Publisher: verified owner of %s/* at %s (%s proof)\n", namespace, domain, method)
+		return
+	}
+	fmt.Printf("  Publisher: unverified claim of %s/* at %s", namespace, domain)
+	if detail, _ := publisher["detail"].(string); detail != "" {
+		fmt.Printf(" (%s)", detail)
+	}
+	fmt.Println()
+}
+
+// printInspectedManifest prints the description of the model in its manifest
+func printInspectedManifest(manifest map[string]interface{}) {
+	for _, field := range []struct{ key, label string }{
+		{"version", "Version"},
+		{"description", "Description"},
+		{"license", "License"},
+		{"architecture", "Architecture"},
+		{"model_type", "Type"},
+		{"quantization", "Quantization"},
+	} {
+		if value, _ := manifest[field.key].(string); value != "" {
+			fmt.Printf("  %s: %s\n", field.label, value)
+		}
+	}
+	if parameters, _ := manifest["parameters"].(float64); parameters > 0 {
+		fmt.Printf("  Parameters: %.0f\n", parameters)
+	}
+	if size, _ := manifest["total_size"].(float64); size > 0 {
+		fmt.Printf("  Size: %s\n", formatProgressBytes(int64(size)))
+	}
+	if created, _ := manifest["created_at"].(string); created != "" {
+		if t, err := time.Parse(time.RFC3339Nano, created); err == nil && !t.IsZero() {
+			fmt.Printf("  Created: %s\n", t.Local().Format("2006-01-02 15:04"))
+		}
+	}
+	if encryption, ok := manifest["encryption"].(map[string]interface{}); ok && encryption != nil {
+		fmt.Println("  Encrypted: yes")
+	}
+	if provenance, ok := manifest["provenance"].(map[string]interface{}); ok {
+		for _, line := range provenanceLines(provenance) {
+			fmt.Printf("  %s\n", line)
+		}
+		if infoHash, _ := provenance["parent_info_hash"].(string); infoHash != "" {
+			fmt.Printf("  Parent InfoHash: %s\n", infoHash)
+		}
+	}
+}
+
+// provenanceLines describes where the weights of a model came from: the
+// model they were derived from and the repository they were mirrored from
+func provenanceLines(provenance map[string]interface{}) []string {
+	var lines []string
+	if parent, _ := provenance["parent_model"].(string); parent != "" {
+		line := "Derived from: " + parent
+		if version, _ := provenance["parent_version"].(string); version != "" && version != "main" {
+			line += " (v" + version + ")"
+		}
+		if transformation, _ := provenance["transformation"].(string); transformation != "" {
+			line += " via " + transformation
+		}
+		lines = append(lines, line)
+	}
+	if upstream, _ := provenance["upstream_url"].(string); upstream != "" {
+		line := "Upstream: " + upstream
+		if revision, _ := provenance["upstream_revision"].(string); revision != "" {
+			if len(revision) > 12 {
+				revision = revision[:12]
+			}
+			line += " @ " + revision
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// printInspectedFiles prints the files of the model and how they compare to
+// the files on disk
+func printInspectedFiles(files []interface{}) {
+	problems := 0
+	fmt.Printf("\n  Files (%d):\n", len(files))
+	for _, f := range files {
+		file, _ := f.(map[string]interface{})
+		path, _ := file["path"].(string)
+		size, _ := file["size"].(float64)
+		diskSize, _ := file["disk_size"].(float64)
+		hash, _ := file["sha256"].(string)
+		status, _ := file["status"].(string)
+
+		if hash == "" {
+			hash = "-"
+		} else if len(hash) > 16 {
+			hash = hash[:16]
+		}
+		note := ""
+		switch status {
+		case "missing":
+			note = "  MISSING"
+		case "size_mismatch":
+			note = fmt.Sprintf("  SIZE MISMATCH (%s on disk)", formatProgressBytes(int64(diskSize)))
+		case "extra":
+			size = diskSize
+			note = "  not in manifest"
+		}
+		if status != "ok" {
+			problems++
+		}
+		fmt.Printf("    %-16s %10s  %s%s\n", hash, formatProgressBytes(int64(size)), path, note)
+	}
+	if problems > 0 {
+		fmt.Printf("  %d files don't match the manifest\n", problems)
+	}
+}
+
+// printInspectedTorrent prints the piece stats of the model's torrent
+func printInspectedTorrent(torrent map[string]interface{}) {
+	infoHash, _ := torrent["info_hash"].(string)
+	pieceLength, _ := torrent["piece_length"].(float64)
+	pieces, _ := torrent["pieces"].(float64)
+	complete, _ := torrent["pieces_complete"].(float64)
+	files, _ := torrent["files"].(float64)
+	size, _ := torrent["size"].(float64)
+
+	fmt.Println("\n  Torrent:")
+	fmt.Printf("    InfoHash: %s\n", infoHash)
+	fmt.Printf("    Pieces: %.0f x %s | %.0f files | %s\n", pieces, formatProgressBytes(int64(pieceLength)), files, formatProgressBytes(int64(size)))
+	if running, _ := torrent["running"].(bool); running {
+		peers, _ := torrent["peers"].(float64)
+		percent := 0.0
+		if pieces > 0 {
+			percent = complete / pieces * 100
+		}
+		fmt.Printf("    Complete: %.0f of %.0f pieces (%.1f%%) | %.0f peers\n", complete, pieces, percent, peers)
+	} else {
+		fmt.Println("    Not running")
+	}
+	if private, _ := torrent["private"].(bool); private {
+		fmt.Println("    Private: yes")
+	}
+	if createdBy, _ := torrent["created_by"].(string); createdBy != "" {
+		fmt.Printf("    Created by: %s\n", createdBy)
+	}
+}
+
+// printInspectedSeeding prints the latest seed of the model and its policy
+func printInspectedSeeding(seeding map[string]interface{}) {
+	fmt.Println("\n  Seeding:")
+	if id, _ := seeding["transfer_id"].(string); id != "" {
+		status, _ := seeding["status"].(string)
+		ratio, _ := seeding["ratio"].(float64)
+		seconds, _ := seeding["seeding_seconds"].(float64)
+		fmt.Printf("    %s (%s) | ratio %.2f | %v\n", status, id, ratio, time.Duration(seconds)*time.Second)
+		if mode, _ := seeding["seed_mode"].(string); mode != "" {
+			fmt.Printf("    Mode: %s\n", mode)
+		}
+		if reason, _ := seeding["stop_reason"].(string); reason != "" {
+			fmt.Printf("    Stopped: %s\n", reason)
+		}
+	} else {
+		fmt.Println("    Not seeded")
+	}
+	if policy, ok := seeding["policy"].(map[string]interface{}); ok {
+		var limits []string
+		if ratio, ok := policy["seed_ratio"].(float64); ok {
+			limits = append(limits, fmt.Sprintf("ratio %.2f", ratio))
+		}
+		if seconds, ok := policy["seed_time"].(float64); ok {
+			limits = append(limits, fmt.Sprintf("%v", time.Duration(seconds)*time.Second))
+		}
+		if seconds, ok := policy["stop_if_no_peers_for"].(float64); ok {
+			limits = append(limits, fmt.Sprintf("%v without peers", time.Duration(seconds)*time.Second))
+		}
+		if len(limits) > 0 {
+			fmt.Printf("    Policy: stop after %s\n", strings.Join(limits, " or "))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs [id]",
+	Short: "Show the progress of publish jobs",
+	Long: `Show the jobs publishing models, such as cloning and sharing a repository.
+Each job goes through the stages clone, manifest, torrent, seed and announce,
+and a failed job shows the stage it failed at and why.
+
+Examples:
+  silmaril jobs                                       # All recent jobs
+  silmaril jobs 0b6f1d1e-5c1a-4f8e-9d43-2f3a8c7e9b10  # A single job`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runJobs,
+}
+
+func init() {
+	rootCmd.AddCommand(jobsCmd)
+}
+
+func runJobs(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	if len(args) == 1 {
+		job, err := apiClient.GetJob(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get job: %w", err)
+		}
+		displayJob(job)
+		return nil
+	}
+
+	jobs, err := apiClient.ListJobs()
+	if err != nil {
+		return fmt.Errorf("failed to list jobs: %w", err)
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No jobs.")
+		return nil
+	}
+	for _, job := range jobs {
+		displayJob(job)
+	}
+	fmt.Printf("Total jobs: %d\n", len(jobs))
+	return nil
+}
+
+func displayJob(job map[string]interface{}) {
+	id, _ := job["id"].(string)
+	model, _ := job["model"].(string)
+	status, _ := job["status"].(string)
+	stage, _ := job["stage"].(string)
+	fmt.Printf("  %s (%s, %s)\n", model, status, stage)
+	fmt.Printf("    ID: %s\n", id)
+	if repoURL, ok := job["repo_url"].(string); ok && repoURL != "" {
+		fmt.Printf("    Repository: %s\n", repoURL)
+	}
+	if infoHash, ok := job["info_hash"].(string); ok && infoHash != "" {
+		fmt.Printf("    InfoHash: %s\n", infoHash)
+	}
+	if clone, ok := job["clone"].(map[string]interface{}); ok && len(clone) > 0 {
+		var downloaded, size int64
+		for _, file := range clone {
+			if progress, ok := file.(map[string]interface{}); ok {
+				d, _ := progress["downloaded"].(float64)
+				s, _ := progress["size"].(float64)
+				downloaded += int64(d)
+				size += int64(s)
+			}
+		}
+		fmt.Printf("    LFS files: %s of %s in %d files\n", formatProgressBytes(downloaded), formatProgressBytes(size), len(clone))
+	}
+	if attempts, ok := job["announce_attempts"].(float64); ok && attempts > 1 {
+		fmt.Printf("    Announce attempts: %.0f\n", attempts)
+	}
+	if errMsg, ok := job["error"].(string); ok && errMsg != "" {
+		fmt.Printf("    Error: %s\n", errMsg)
+	}
+	fmt.Println()
+}
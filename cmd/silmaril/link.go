@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var linkCmd = &cobra.Command{
+	Use:   "link",
+	Short: "Expose local models to other tools",
+}
+
+var linkHFCmd = &cobra.Command{
+	Use:   "hf <model-name>",
+	Short: "Link a model into the HuggingFace cache",
+	Long: `Link a local model into the HuggingFace Hub cache, so transformers, vLLM
+and other tools using huggingface_hub load it without downloading or copying.
+
+The cache directory is taken from HF_HUB_CACHE or HF_HOME, and defaults to
+~/.cache/huggingface/hub. The cache only holds links to the files in the
+Silmaril store; removing the model from Silmaril breaks them, so unlink it
+with --remove first. Hardlinks (--hardlink) keep working after the model is
+removed, but need the cache on the same filesystem as the Silmaril store.
+
+Examples:
+  silmaril link hf meta-llama/Llama-3.1-8B
+  HF_HUB_OFFLINE=1 python -c "from transformers import AutoModel; AutoModel.from_pretrained('meta-llama/Llama-3.1-8B')"
+  silmaril link hf meta-llama/Llama-3.1-8B --remove`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLinkHF,
+}
+
+func init() {
+	rootCmd.AddCommand(linkCmd)
+	linkCmd.AddCommand(linkHFCmd)
+	linkHFCmd.Flags().String("cache-dir", "", "HuggingFace cache directory (default $HF_HUB_CACHE or ~/.cache/huggingface/hub)")
+	linkHFCmd.Flags().Bool("hardlink", false, "Hardlink the model files instead of symlinking them")
+	linkHFCmd.Flags().String("revision", "", "Commit hash to name the snapshot (default derived from the model files)")
+	linkHFCmd.Flags().Bool("remove", false, "Remove the model from the HuggingFace cache")
+}
+
+func runLinkHF(cmd *cobra.Command, args []string) error {
+	modelName, err := modelname.Canonical(args[0])
+	if err != nil {
+		return err
+	}
+	hardlink, _ := cmd.Flags().GetBool("hardlink")
+	revision, _ := cmd.Flags().GetString("revision")
+	remove, _ := cmd.Flags().GetBool("remove")
+
+	cacheDir, _ := cmd.Flags().GetString("cache-dir")
+	if cacheDir == "" {
+		dir, err := models.HFCacheDir()
+		if err != nil {
+			return err
+		}
+		cacheDir = dir
+	}
+
+	if remove {
+		if err := models.UnlinkFromHFCache(cacheDir, modelName); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Removed %s from %s\n", modelName, cacheDir)
+		return nil
+	}
+
+	paths, err := storage.NewPaths()
+	if err != nil {
+		return fmt.Errorf("failed to initialize paths: %w", err)
+	}
+	modelPath, err := paths.ModelDir(modelName)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(modelPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("model %s not found locally, download it with 'silmaril get %s'", modelName, modelName)
+	}
+	if storage.IsPartial(modelPath) {
+		return fmt.Errorf("model %s is still downloading", modelName)
+	}
+
+	result, err := models.LinkToHFCache(cacheDir, modelName, modelPath, models.HFLinkOptions{
+		Hardlink: hardlink,
+		Revision: revision,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to link model: %w", err)
+	}
+
+	fmt.Printf("✓ Linked %s into the HuggingFace cache\n", modelName)
+	fmt.Printf("  Snapshot: %s\n", result.SnapshotDir)
+	fmt.Printf("  Files: %d\n", result.Files)
+	fmt.Printf("\nLoad it with huggingface_hub or transformers as %s, set HF_HUB_OFFLINE=1 to skip the hub.\n", modelName)
+	return nil
+}
@@ -3,23 +3,37 @@ package main
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/silmaril/silmaril/internal/api/client"
 )
 
 var listCmd = &cobra.Command{
-	Use:   "list",
+	Use:   "list [pattern]",
 	Short: "List locally downloaded models",
 	Long: `Shows models that have been downloaded and are managed by Silmaril on this machine.
 
+An optional pattern filters models by name, description or model card tags.
+Use --tag to only show models carrying all of the given tags.
+
+The daemon notices models added to or removed from the models directory on
+its own after a moment; --refresh rescans the directory first.
+
 This command only shows models stored locally on your computer.
 Use 'silmaril discover' to search for models available on the P2P network.`,
+	Args:  cobra.MaximumNArgs(1),
 	RunE:  runList,
 }
 
+var (
+	listTags    []string
+	listRefresh bool
+)
+
 func init() {
 	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().StringSliceVar(&listTags, "tag", nil, "Only show models with this tag (repeatable)")
+	listCmd.Flags().BoolVar(&listRefresh, "refresh", false, "Rescan the models directory first")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
@@ -29,10 +43,21 @@ func runList(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create API client
-	apiClient := client.NewClient(getDaemonURL())
+	apiClient := newDaemonClient()
 
+	pattern := ""
+	if len(args) > 0 {
+		pattern = args[0]
+	}
+	
+	if listRefresh {
+		if _, err := apiClient.RefreshModels(); err != nil {
+			return fmt.Errorf("failed to rescan models: %w", err)
+		}
+	}
+	
 	// Get list of models from API
-	models, err := apiClient.ListModels()
+	models, err := apiClient.ListModelsFiltered(pattern, listTags)
 	if err != nil {
 		return fmt.Errorf("failed to list models: %w", err)
 	}
@@ -82,6 +107,10 @@ func displayModelFromAPI(model map[string]interface{}) {
 	}
 	fmt.Println()
 	
+	if aliases := getStringList(model["aliases"]); len(aliases) > 0 {
+		fmt.Printf("    Aliases: %s\n", strings.Join(aliases, ", "))
+	}
+	
 	// Size
 	var sizeGB float64
 	if size, ok := model["total_size"].(float64); ok {
@@ -137,10 +166,33 @@ func displayModelFromAPI(model map[string]interface{}) {
 		}
 	}
 	
+	// Model card tags
+	if tags := getStringList(model["tags"]); len(tags) > 0 {
+		fmt.Printf("    Tags: %s", strings.Join(tags, ", "))
+		if pipeline, ok := model["pipeline_tag"].(string); ok && pipeline != "" {
+			fmt.Printf(" | Pipeline: %s", pipeline)
+		}
+		fmt.Println()
+	} else if pipeline, ok := model["pipeline_tag"].(string); ok && pipeline != "" {
+		fmt.Printf("    Pipeline: %s\n", pipeline)
+	}
+	
+	// Checksums of large files are filled in by the daemon in the background
+	if complete, ok := model["hashes_complete"].(bool); ok && !complete {
+		hashed, _ := model["files_hashed"].(float64)
+		total, _ := model["file_count"].(float64)
+		fmt.Printf("    Checksums: %.0f of %.0f files hashed\n", hashed, total)
+	}
+	
 	// P2P status
 	if magnet, ok := model["magnet_uri"].(string); ok && magnet != "" {
 		fmt.Println("    ✓ Ready to share via P2P")
 	}
+	if seeding, ok := model["seeding"].(map[string]interface{}); ok {
+		if reason, ok := seeding["stop_reason"].(string); ok && reason != "" {
+			fmt.Printf("    Seeding stopped: %s\n", reason)
+		}
+	}
 	
 	fmt.Println()
 }
@@ -150,4 +202,19 @@ func getModelName(model map[string]interface{}) string {
 		return name
 	}
 	return "unknown"
+}
+
+// getStringList converts a decoded JSON array into a string slice
+func getStringList(value interface{}) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	var result []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
 }
\ No newline at end of file
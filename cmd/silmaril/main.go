@@ -6,12 +6,12 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"github.com/silmaril/silmaril/internal/api/client"
 	"github.com/silmaril/silmaril/internal/config"
 )
 
 var (
 	cfgFile string
+	profile string
 	rootCmd = &cobra.Command{
 		Use:   "silmaril",
 		Short: "P2P distribution system for AI models",
@@ -29,7 +29,8 @@ Key Commands:
 
 func init() {
 	cobra.OnInitialize(initConfig)
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/silmaril/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is config.yaml in the user config directory, e.g. $HOME/.config/silmaril)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "config profile to use (default is $SILMARIL_PROFILE)")
 	rootCmd.PersistentFlags().Bool("verbose", false, "enable verbose output")
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
 }
@@ -41,12 +42,6 @@ func initConfig() {
 		os.Exit(1)
 	}
 	
-	// Create all necessary directories
-	if err := config.CreateAllDirs(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating directories: %v\n", err)
-		os.Exit(1)
-	}
-	
 	// If user specified a config file, load it
 	if cfgFile != "" {
 		v := config.GetViper()
@@ -55,17 +50,45 @@ func initConfig() {
 			fmt.Fprintf(os.Stderr, "Error reading config file: %v\n", err)
 			os.Exit(1)
 		}
+		if err := config.Reload(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Switch to the selected profile before anything touches storage
+	if profile == "" {
+		profile = os.Getenv("SILMARIL_PROFILE")
+	}
+	if profile != "" {
+		if err := config.UseProfile(profile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error selecting profile: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// The daemon commands read the API port from the global viper instance
+	viper.Set("daemon.port", config.Get().Daemon.Port)
+	
+	// Create all necessary directories
+	if err := config.CreateAllDirs(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating directories: %v\n", err)
+		os.Exit(1)
 	}
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		if hint := errorHint(err); hint != "" {
+			fmt.Fprintln(os.Stderr, hint)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
-// ensureDaemonRunning checks if the daemon is running and starts it if not
+// ensureDaemonRunning checks that the daemon is running and that its version
+// works with the CLI
 func ensureDaemonRunning() error {
 	// Skip daemon check for daemon commands themselves
 	if len(os.Args) > 1 && os.Args[1] == "daemon" {
@@ -78,9 +101,9 @@ func ensureDaemonRunning() error {
 	}
 
 	// Check if daemon is running
-	apiClient := client.NewClient(getDaemonURL())
+	apiClient := newDaemonClient()
 	if err := apiClient.Health(); err == nil {
-		return nil // Already running
+		return checkDaemonVersion(apiClient)
 	}
 
 	// Daemon is not running - tell the user to start it
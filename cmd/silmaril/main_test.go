@@ -349,4 +349,36 @@ func TestCLIIntegration(t *testing.T) {
 			assert.Contains(t, string(output), tt.expected)
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestFindDiscoveredModel(t *testing.T) {
+	models := []map[string]interface{}{
+		{"name": "org/model-instruct", "info_hash": "aa", "time": float64(3)},
+		{"name": "org/model", "info_hash": "bb", "time": float64(1)},
+		{"name": "org/model", "info_hash": "cc", "time": float64(2)},
+		{"name": "org/model", "time": float64(4)},
+	}
+	
+	// The newest version named exactly like the model, with an infohash
+	model := findDiscoveredModel(models, "org/model")
+	require.NotNil(t, model)
+	assert.Equal(t, "cc", model["info_hash"])
+	
+	assert.Nil(t, findDiscoveredModel(models, "org"))
+}
+
+func TestProvenanceLines(t *testing.T) {
+	lines := provenanceLines(map[string]interface{}{
+		"parent_model":      "org/model",
+		"parent_version":    "2",
+		"transformation":    "gguf:Q4_K_M",
+		"upstream_url":      "https://huggingface.co/org/model",
+		"upstream_revision": "0123456789abcdef0123456789abcdef01234567",
+	})
+	assert.Equal(t, []string{
+		"Derived from: org/model (v2) via gguf:Q4_K_M",
+		"Upstream: https://huggingface.co/org/model @ 0123456789ab",
+	}, lines)
+	
+	assert.Empty(t, provenanceLines(map[string]interface{}{}))
+}
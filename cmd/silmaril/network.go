@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/spf13/cobra"
+)
+
+var networkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "Show the health of the Silmaril network",
+}
+
+var networkStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the anonymous stats published by the nodes of the network",
+	Long: `Show how many nodes published stats for a day, the models they seed and
+the bytes they uploaded in total. Days are in UTC.
+
+Nodes only publish stats if they opted in, so the numbers are a lower bound.
+To take part:
+
+  silmaril config set network.share_stats true
+
+Examples:
+  silmaril network stats                    # Today
+  silmaril network stats --day 2026-03-01   # An earlier day`,
+	Args: cobra.NoArgs,
+	RunE: runNetworkStats,
+}
+
+var networkListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the catalog networks the daemon joined",
+	Long: `List the public network and the catalog networks in the catalogs section
+of the config, with the key each catalog is published under and how many
+models it has. Networks with a seed are shared catalogs this node publishes
+its models to, networks with a public key are only read.
+
+Example config:
+
+  catalogs:
+    acme:
+      seed: acme-models-2026
+    research:
+      public_key: 3b6a27bcceb6a42d62a3a8d02a6f0d73653215771de243a63ac048a18b59da29`,
+	Args: cobra.NoArgs,
+	RunE: runNetworkList,
+}
+
+func init() {
+	rootCmd.AddCommand(networkCmd)
+	networkCmd.AddCommand(networkStatsCmd)
+	networkCmd.AddCommand(networkListCmd)
+	networkStatsCmd.Flags().String("day", "", "Day to show, YYYY-MM-DD in UTC (default today)")
+}
+
+func runNetworkStats(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	day, _ := cmd.Flags().GetString("day")
+	fmt.Println("Reading network stats from the DHT...")
+	stats, err := newDaemonClient().GetNetworkStats(day)
+	if err != nil {
+		return fmt.Errorf("failed to get network stats: %w", err)
+	}
+
+	statsDay, _ := stats["day"].(string)
+	nodes, _ := stats["nodes"].(float64)
+	models, _ := stats["models_seeded"].(float64)
+	uploadedMB, _ := stats["uploaded_mb"].(float64)
+	slotsRead, _ := stats["slots_read"].(float64)
+	fmt.Printf("Network stats for %s (UTC)\n", statsDay)
+	fmt.Printf("  Reporting nodes: %.0f\n", nodes)
+	fmt.Printf("  Models seeded:   %.0f\n", models)
+	fmt.Printf("  Uploaded:        %.2f GB\n", uploadedMB/1024)
+	if slotsRead < discovery.StatsSlots {
+		fmt.Printf("  (only %.0f of %d stats slots answered, the numbers may be incomplete)\n", slotsRead, discovery.StatsSlots)
+	}
+
+	fmt.Println()
+	if sharing, _ := stats["sharing"].(bool); sharing {
+		if last, _ := stats["last_published"].(string); last != "" {
+			fmt.Printf("This node shares its stats, last published for %s\n", last)
+		} else {
+			fmt.Println("This node shares its stats, not published yet")
+		}
+		if errMsg, _ := stats["publish_error"].(string); errMsg != "" {
+			fmt.Printf("  Last attempt failed: %s\n", errMsg)
+		}
+	} else {
+		fmt.Println("This node doesn't share its stats (network.share_stats is off)")
+	}
+	return nil
+}
+
+func runNetworkList(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	networks, err := newDaemonClient().ListNetworks()
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	fmt.Printf("%d catalog network(s):\n\n", len(networks))
+	for _, network := range networks {
+		access := "publish"
+		if network.ReadOnly {
+			access = "read-only"
+		}
+		models := "not loaded yet"
+		if network.Loaded {
+			models = fmt.Sprintf("%d models", network.Models)
+		}
+		fmt.Printf("  %s (%s, %s)\n", network.Name, access, models)
+		fmt.Printf("    Key: %s\n", network.PublicKey)
+	}
+	fmt.Println()
+	fmt.Println("Search a network with: silmaril discover --network <name>")
+	return nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/silmaril/silmaril/internal/api/client"
+	"github.com/spf13/cobra"
+)
+
+var peersCmd = &cobra.Command{
+	Use:   "peers <model-name|infohash>",
+	Short: "List the peers a model's torrent is connected to",
+	Long: `Show the peers connected to the torrent of a model, with their address,
+client, download rate, progress and flags, and how many more peers are known
+but not connected. Useful to find out why a download is slow or a seed gets
+no traffic.
+
+The sources table shows, for the DHT, peer exchange, trackers, peer hints and
+incoming connections, how many distinct peers each found this session, how
+many of those could be connected to, how many are connected now and the data
+they sent, so you can tell which source does the work.
+
+Flags: I incoming, D found on the DHT, T from a tracker, X from peer exchange,
+H holepunched, P from network.peer_hints, M added with "peers add", U uTP,
+E prefers encryption, S seed.
+
+Examples:
+  silmaril peers org/model
+  silmaril peers 0123456789abcdef0123456789abcdef01234567
+  silmaril peers add org/model 192.168.1.20`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPeers,
+}
+
+var peersAddCmd = &cobra.Command{
+	Use:   "add <model-name|infohash> <address>...",
+	Short: "Add peer addresses to a model's torrent",
+	Long: `Add the addresses of other daemons as peers of the torrent of a model. For
+two machines that know each other, this transfers a model without the DHT,
+trackers or peer hints. The daemon remembers the peers and adds them again
+after a restart, until they are removed or the model is.
+
+Addresses are a host name or IP address, with or without a port. Without a
+port, the port this daemon listens for peers on is used. Host names are
+resolved again every minute.
+
+The torrent has to be loaded: seed the model on one machine and start the
+download on the other, then add each machine to the other, or only the seed
+to the downloader.
+
+Examples:
+  silmaril peers add org/model 192.168.1.20
+  silmaril peers add org/model lab-b.local:42069 [fd00::20]:42069`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runPeersAdd,
+}
+
+var peersRemoveCmd = &cobra.Command{
+	Use:   "remove <model-name|infohash> <address>",
+	Short: "Stop adding a peer address to a model's torrent",
+	Long: `Forget a peer added with "silmaril peers add". The peer is no longer added
+after a restart; a connection to it is kept until it closes.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runPeersRemove,
+}
+
+var infoHashPattern = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+func init() {
+	peersCmd.AddCommand(peersAddCmd)
+	peersCmd.AddCommand(peersRemoveCmd)
+	rootCmd.AddCommand(peersCmd)
+}
+
+func runPeers(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	infoHash, err := resolveInfoHash(apiClient, args[0])
+	if err != nil {
+		return err
+	}
+
+	result, err := apiClient.GetTorrentPeers(infoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get peers: %w", err)
+	}
+
+	name, _ := result["name"].(string)
+	connected, _ := result["connected"].(float64)
+	known, _ := result["known"].(float64)
+	fmt.Printf("  %s (%s)\n", name, infoHash)
+	fmt.Printf("    Connected: %.0f | Known: %.0f\n", connected, known)
+	printPeerSources(result["sources"])
+	if manual, ok := result["manual"].([]interface{}); ok && len(manual) > 0 {
+		addrs := make([]string, 0, len(manual))
+		for _, addr := range manual {
+			if s, ok := addr.(string); ok {
+				addrs = append(addrs, s)
+			}
+		}
+		fmt.Printf("    Added by hand: %s\n\n", strings.Join(addrs, ", "))
+	}
+
+	peers, _ := result["peers"].([]interface{})
+	if len(peers) == 0 {
+		fmt.Println("    No connected peers.")
+		return nil
+	}
+	fmt.Printf("    %-40s %-6s %10s %5s  %s\n", "ADDRESS", "FLAGS", "DOWN", "HAVE", "CLIENT")
+	for _, p := range peers {
+		peer, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		addr, _ := peer["addr"].(string)
+		flags, _ := peer["flags"].(string)
+		rate, _ := peer["download_rate"].(float64)
+		progress, _ := peer["progress"].(float64)
+		peerClient, _ := peer["client"].(string)
+		fmt.Printf("    %-40s %-6s %5.2f MB/s %4.0f%%  %s\n", addr, flags, rate/(1024*1024), progress, peerClient)
+	}
+	return nil
+}
+
+func runPeersAdd(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	infoHash, err := resolveInfoHash(apiClient, args[0])
+	if err != nil {
+		return err
+	}
+
+	result, err := apiClient.AddTorrentPeers(infoHash, args[1:])
+	if err != nil {
+		return fmt.Errorf("failed to add peers: %w", err)
+	}
+
+	fmt.Printf("Added %d new peer addresses to %s\n", result.Added, result.Name)
+	fmt.Printf("Peers added by hand: %s\n", strings.Join(result.Peers, ", "))
+	if result.Error != "" {
+		fmt.Printf("Warning: %s\n", result.Error)
+	}
+	return nil
+}
+
+func runPeersRemove(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	infoHash, err := resolveInfoHash(apiClient, args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := apiClient.RemoveTorrentPeer(infoHash, args[1]); err != nil {
+		return fmt.Errorf("failed to remove peer: %w", err)
+	}
+	fmt.Printf("Removed %s from %s\n", args[1], args[0])
+	return nil
+}
+
+// resolveInfoHash returns the infohash of the torrent of a model, preferring
+// a running transfer of it. An infohash is returned as is.
+func resolveInfoHash(apiClient *client.Client, target string) (string, error) {
+	if infoHashPattern.MatchString(target) {
+		return target, nil
+	}
+
+	transfers, err := apiClient.ListTransfers("")
+	if err != nil {
+		return "", fmt.Errorf("failed to list transfers: %w", err)
+	}
+	var infoHash string
+	for _, t := range transfers {
+		name, _ := t["model_name"].(string)
+		status, _ := t["status"].(string)
+		hash, _ := t["info_hash"].(string)
+		if name == target && hash != "" && (infoHash == "" || status == "active") {
+			infoHash = hash
+		}
+	}
+	if infoHash == "" {
+		return "", fmt.Errorf("no torrent found for %s", target)
+	}
+	return infoHash, nil
+}
+
+// printPeerSources prints the peers a torrent found through each source
+func printPeerSources(value interface{}) {
+	sources, ok := value.([]interface{})
+	if !ok || len(sources) == 0 {
+		return
+	}
+	fmt.Printf("    %-10s %6s %10s %6s %7s %10s\n", "SOURCE", "FOUND", "CONNECTED", "RATE", "ACTIVE", "DOWNLOADED")
+	for _, s := range sources {
+		source, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := source["source"].(string)
+		found, _ := source["found"].(float64)
+		connected, _ := source["connected"].(float64)
+		rate, _ := source["success_rate"].(float64)
+		active, _ := source["active"].(float64)
+		downloaded, _ := source["bytes_downloaded"].(float64)
+		fmt.Printf("    %-10s %6.0f %10.0f %5.0f%% %7.0f %7.1f MB\n", name, found, connected, rate, active, downloaded/(1024*1024))
+	}
+	fmt.Println()
+}
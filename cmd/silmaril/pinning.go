@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var pinningCmd = &cobra.Command{
+	Use:   "pinning",
+	Short: "Show the catalog models this node seeds for the network",
+	Long: `Show the community pinning policy and the models pinned under it.
+
+With pinning enabled, the daemon probes the swarms of the catalog models
+every pinning.interval_minutes and downloads models with fewer than
+pinning.min_seeders seeders, up to pinning.max_disk_gb in total and
+pinning.max_downloads at a time. Pinned models are seeded without seeding
+limits. Removing a pinned model with 'silmaril remove' stops pinning it.
+
+  silmaril config set pinning.enabled true
+  silmaril config set pinning.max_disk_gb 200
+  silmaril config set pinning.models '["meta-llama/*", "mistralai/*"]'`,
+	Args: cobra.NoArgs,
+	RunE: runPinning,
+}
+
+func init() {
+	rootCmd.AddCommand(pinningCmd)
+}
+
+func runPinning(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	report, err := apiClient.GetPinning()
+	if err != nil {
+		return fmt.Errorf("failed to get pinning report: %w", err)
+	}
+
+	enabled, _ := report["enabled"].(bool)
+	policy, _ := report["policy"].(map[string]interface{})
+	used, _ := report["used_bytes"].(float64)
+	maxDisk, _ := policy["max_disk_bytes"].(float64)
+	minSeeders, _ := policy["min_seeders"].(float64)
+
+	if enabled {
+		fmt.Printf("Community pinning: enabled, pinning models with fewer than %.0f seeders\n", minSeeders)
+	} else {
+		fmt.Println("Community pinning: disabled (silmaril config set pinning.enabled true)")
+	}
+	fmt.Printf("Disk: %s of %s", formatProgressBytes(int64(used)), formatProgressBytes(int64(maxDisk)))
+	if maxModel, _ := policy["max_model_bytes"].(float64); maxModel > 0 {
+		fmt.Printf(" | Models up to %s", formatProgressBytes(int64(maxModel)))
+	}
+	if patterns, ok := policy["models"].([]interface{}); ok && len(patterns) > 0 {
+		names := make([]string, 0, len(patterns))
+		for _, p := range patterns {
+			if s, ok := p.(string); ok {
+				names = append(names, s)
+			}
+		}
+		fmt.Printf(" | Only %s", strings.Join(names, ", "))
+	}
+	fmt.Println()
+	if last, _ := report["last_check"].(string); last != "" {
+		if t, err := time.Parse(time.RFC3339Nano, last); err == nil {
+			fmt.Printf("Last check: %s\n", t.Local().Format("2006-01-02 15:04"))
+		}
+	}
+	if lastError, _ := report["last_error"].(string); lastError != "" {
+		fmt.Printf("Last error: %s\n", lastError)
+	}
+
+	pinned, _ := report["models"].([]interface{})
+	if len(pinned) == 0 {
+		fmt.Println("\nNo models pinned yet.")
+		return nil
+	}
+
+	fmt.Printf("\nPinned models (%d):\n", len(pinned))
+	for _, p := range pinned {
+		pin, _ := p.(map[string]interface{})
+		model, _ := pin["model"].(string)
+		version, _ := pin["version"].(string)
+		status, _ := pin["status"].(string)
+		size, _ := pin["size"].(float64)
+		seeders, _ := pin["seeders"].(float64)
+
+		fmt.Printf("  %s", model)
+		if version != "" {
+			fmt.Printf(" (%s)", version)
+		}
+		fmt.Println()
+		fmt.Printf("    %s | %s | %.0f seeders when pinned", status, formatProgressBytes(int64(size)), seeders)
+		switch status {
+		case "downloading":
+			progress, _ := pin["progress"].(float64)
+			fmt.Printf(" | %.1f%%", progress)
+		case "seeding":
+			uploaded, _ := pin["uploaded"].(float64)
+			peers, _ := pin["peers"].(float64)
+			fmt.Printf(" | %s uploaded | %.0f peers", formatProgressBytes(int64(uploaded)), peers)
+		}
+		fmt.Println()
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Files shown with a bar of their own, the others are summed up in a line
+const maxProgressFiles = 8
+
+// Lines printed at most this often when the output is not a terminal
+const plainProgressInterval = 10 * time.Second
+
+// downloadSnapshot is what the daemon reports of a download at one moment
+type downloadSnapshot struct {
+	Model    string         `json:"model"`
+	Transfer string         `json:"transfer_id"`
+	Status   string         `json:"status"`
+	Bytes    int64          `json:"bytes"`
+	Total    int64          `json:"total"` // 0 until the metadata arrived
+	Rate     int64          `json:"rate"`  // Bytes per second
+	Peers    int            `json:"peers"`
+	Seeders  int            `json:"seeders"`
+	ETA      float64        `json:"eta_seconds,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Files    []fileSnapshot `json:"files,omitempty"`
+}
+
+// fileSnapshot is how much of a file of a download is local
+type fileSnapshot struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Completed int64  `json:"completed"`
+}
+
+// snapshotFromAPI builds a snapshot from a transfer and its files as the
+// daemon API returns them
+func snapshotFromAPI(transfer map[string]interface{}, files []map[string]interface{}) downloadSnapshot {
+	s := downloadSnapshot{}
+	s.Model, _ = transfer["model_name"].(string)
+	s.Transfer, _ = transfer["id"].(string)
+	s.Status, _ = transfer["status"].(string)
+	s.Error, _ = transfer["error"].(string)
+	if v, ok := transfer["bytes_transferred"].(float64); ok {
+		s.Bytes = int64(v)
+	}
+	if v, ok := transfer["total_bytes"].(float64); ok {
+		s.Total = int64(v)
+	}
+	if v, ok := transfer["download_rate"].(float64); ok {
+		s.Rate = int64(v)
+	}
+	if v, ok := transfer["peers"].(float64); ok {
+		s.Peers = int(v)
+	}
+	if v, ok := transfer["seeders"].(float64); ok {
+		s.Seeders = int(v)
+	}
+	if v, ok := transfer["eta"].(float64); ok {
+		s.ETA = time.Duration(v).Seconds()
+	}
+
+	// The bytes of the selected files are more accurate than the bytes read
+	// from peers, which count pieces overlapping other files too
+	var completed int64
+	for _, f := range files {
+		file := fileSnapshot{}
+		file.Path, _ = f["path"].(string)
+		if v, ok := f["size"].(float64); ok {
+			file.Size = int64(v)
+		}
+		if v, ok := f["completed"].(float64); ok {
+			file.Completed = int64(v)
+		}
+		completed += file.Completed
+		s.Files = append(s.Files, file)
+	}
+	if len(s.Files) > 0 {
+		s.Bytes = completed
+	}
+	return s
+}
+
+// progressReporter shows the progress of a download and the daemon events
+// about its model
+type progressReporter interface {
+	Progress(s downloadSnapshot)
+	Event(event map[string]interface{})
+	Done()
+}
+
+// newProgressReporter returns the reporter for the output of 'silmaril get',
+// JSON lines with jsonOutput
+func newProgressReporter(out *os.File, jsonOutput bool) progressReporter {
+	if jsonOutput {
+		return &jsonProgress{enc: json.NewEncoder(out)}
+	}
+	info, err := out.Stat()
+	tty := err == nil && info.Mode()&os.ModeCharDevice != 0
+	return &progressDisplay{out: out, tty: tty}
+}
+
+// progressDisplay draws a bar for the whole download and for each of its
+// files, redrawn in place on a terminal. Elsewhere, as in logs, it prints a
+// line now and then instead.
+type progressDisplay struct {
+	out       io.Writer
+	tty       bool
+	lines     int // Lines drawn last, overwritten by the next drawing
+	lastPrint time.Time
+}
+
+func (p *progressDisplay) Progress(s downloadSnapshot) {
+	lines := renderProgress(s, maxProgressFiles)
+	if !p.tty {
+		if time.Since(p.lastPrint) < plainProgressInterval && s.Status == "active" {
+			return
+		}
+		p.lastPrint = time.Now()
+		fmt.Fprintln(p.out, lines[0])
+		return
+	}
+
+	p.clear()
+	for _, line := range lines {
+		fmt.Fprintf(p.out, "%s\033[K\n", line)
+	}
+	p.lines = len(lines)
+}
+
+// Event prints an event above the bars
+func (p *progressDisplay) Event(event map[string]interface{}) {
+	p.clear()
+	message, _ := event["message"].(string)
+	fmt.Fprintf(p.out, "• %s\033[K\n", message)
+}
+
+// Done leaves the last drawing on the terminal
+func (p *progressDisplay) Done() {
+	p.lines = 0
+}
+
+// clear moves the cursor back to the first line drawn, so the next drawing
+// overwrites the last
+func (p *progressDisplay) clear() {
+	if p.tty && p.lines > 0 {
+		fmt.Fprintf(p.out, "\033[%dA\r", p.lines)
+	}
+	p.lines = 0
+}
+
+// jsonProgress writes a JSON object per line for each snapshot and event,
+// for scripts
+type jsonProgress struct {
+	enc *json.Encoder
+}
+
+func (p *jsonProgress) Progress(s downloadSnapshot) {
+	p.enc.Encode(struct {
+		Type string `json:"type"`
+		downloadSnapshot
+	}{"progress", s})
+}
+
+func (p *jsonProgress) Event(event map[string]interface{}) {
+	line := map[string]interface{}{"type": "event"}
+	for _, key := range []string{"seq", "time", "model", "message"} {
+		if value, ok := event[key]; ok {
+			line[key] = value
+		}
+	}
+	line["event"] = event["type"]
+	p.enc.Encode(line)
+}
+
+func (p *jsonProgress) Done() {}
+
+// renderProgress returns the lines showing a download: its total progress,
+// speed, peers and ETA, then a bar per file. Files beyond maxFiles are
+// summed up in the last line, finished files first.
+func renderProgress(s downloadSnapshot, maxFiles int) []string {
+	var head string
+	switch {
+	case s.Total == 0:
+		head = fmt.Sprintf("%s  fetching metadata  %d peers", s.Model, s.Peers)
+	default:
+		fraction := float64(s.Bytes) / float64(s.Total)
+		head = fmt.Sprintf("%s  %s %5.1f%%  %s/%s  %s/s  %d peers (%d seeders)",
+			s.Model, progressBar(fraction, 30), fraction*100,
+			formatProgressBytes(s.Bytes), formatProgressBytes(s.Total),
+			formatProgressBytes(s.Rate), s.Peers, s.Seeders)
+		if s.ETA > 0 && s.Status == "active" {
+			head += "  ETA " + (time.Duration(s.ETA) * time.Second).String()
+		}
+	}
+	lines := []string{head}
+	if len(s.Files) <= 1 {
+		return lines
+	}
+
+	// Files being downloaded are the interesting ones, finished ones are
+	// only counted once there is no room left
+	shown := make([]fileSnapshot, 0, len(s.Files))
+	var done, hidden int
+	for _, f := range s.Files {
+		if f.Completed >= f.Size {
+			done++
+		}
+	}
+	for pass := 0; pass < 2; pass++ {
+		for _, f := range s.Files {
+			finished := f.Completed >= f.Size
+			if finished != (pass == 1) {
+				continue
+			}
+			if len(shown) < maxFiles {
+				shown = append(shown, f)
+			} else {
+				hidden++
+			}
+		}
+	}
+
+	width := 0
+	for _, f := range shown {
+		if len(f.Path) > width {
+			width = len(f.Path)
+		}
+	}
+	if width > 40 {
+		width = 40
+	}
+	for _, f := range shown {
+		fraction := 1.0
+		if f.Size > 0 {
+			fraction = float64(f.Completed) / float64(f.Size)
+		}
+		lines = append(lines, fmt.Sprintf("  %-*s %s %5.1f%%  %s",
+			width, shortenPath(f.Path, width), progressBar(fraction, 20), fraction*100, formatProgressBytes(f.Size)))
+	}
+	if hidden > 0 {
+		lines = append(lines, fmt.Sprintf("  + %d more files, %d of %d files complete", hidden, done, len(s.Files)))
+	}
+	return lines
+}
+
+// progressBar draws a bar width characters wide, filled by fraction
+func progressBar(fraction float64, width int) string {
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction * float64(width))
+	bar := strings.Repeat("=", filled)
+	if filled < width {
+		bar += ">" + strings.Repeat(" ", width-filled-1)
+	}
+	return "[" + bar + "]"
+}
+
+// shortenPath cuts the middle out of paths longer than width, keeping the
+// file name
+func shortenPath(p string, width int) string {
+	if len(p) <= width {
+		return p
+	}
+	name := path.Base(p)
+	if len(name)+4 > width {
+		return "…" + name[len(name)-width+1:]
+	}
+	return p[:width-len(name)-2] + "…/" + name
+}
+
+// formatProgressBytes formats a byte count with a unit of the right size
+func formatProgressBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		value /= unit
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+	return fmt.Sprintf("%.1f TB", value/unit)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotFromAPI(t *testing.T) {
+	transfer := map[string]interface{}{
+		"id":                "t1",
+		"model_name":        "org/model",
+		"status":            "active",
+		"bytes_transferred": float64(5000),
+		"total_bytes":       float64(3000),
+		"download_rate":     float64(1024),
+		"peers":             float64(4),
+		"seeders":           float64(2),
+		"eta":               float64(90 * time.Second),
+	}
+	files := []map[string]interface{}{
+		{"path": "a.bin", "size": float64(1000), "completed": float64(1000)},
+		{"path": "b.bin", "size": float64(2000), "completed": float64(500)},
+	}
+
+	s := snapshotFromAPI(transfer, files)
+	assert.Equal(t, "org/model", s.Model)
+	assert.Equal(t, "t1", s.Transfer)
+	assert.Equal(t, int64(1500), s.Bytes, "the bytes of the files count")
+	assert.Equal(t, int64(3000), s.Total)
+	assert.Equal(t, 4, s.Peers)
+	assert.Equal(t, 90.0, s.ETA)
+	require.Len(t, s.Files, 2)
+	assert.Equal(t, fileSnapshot{Path: "b.bin", Size: 2000, Completed: 500}, s.Files[1])
+
+	s = snapshotFromAPI(transfer, nil)
+	assert.Equal(t, int64(5000), s.Bytes)
+}
+
+func TestRenderProgress(t *testing.T) {
+	s := downloadSnapshot{Model: "org/model", Status: "active", Bytes: 1536, Total: 3072, Rate: 2048, Peers: 3, Seeders: 1, ETA: 60}
+	lines := renderProgress(s, 8)
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "50.0%")
+	assert.Contains(t, lines[0], "1.5 KB/3.0 KB")
+	assert.Contains(t, lines[0], "2.0 KB/s")
+	assert.Contains(t, lines[0], "3 peers (1 seeders)")
+	assert.Contains(t, lines[0], "ETA 1m0s")
+
+	// Files being downloaded come first, the rest is summed up
+	s.Files = []fileSnapshot{
+		{Path: "done.bin", Size: 10, Completed: 10},
+		{Path: "half.bin", Size: 10, Completed: 5},
+		{Path: "new.bin", Size: 10},
+	}
+	lines = renderProgress(s, 2)
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[1], "half.bin")
+	assert.Contains(t, lines[2], "new.bin")
+	assert.Contains(t, lines[3], "+ 1 more files, 1 of 3 files complete")
+
+	lines = renderProgress(downloadSnapshot{Model: "org/model", Status: "active", Peers: 2}, 8)
+	assert.Equal(t, []string{"org/model  fetching metadata  2 peers"}, lines)
+}
+
+func TestProgressBar(t *testing.T) {
+	assert.Equal(t, "[>    ]", progressBar(0, 5))
+	assert.Equal(t, "[==>  ]", progressBar(0.5, 5))
+	assert.Equal(t, "[=====]", progressBar(1, 5))
+	assert.Equal(t, "[=====]", progressBar(2, 5))
+}
+
+func TestShortenPath(t *testing.T) {
+	assert.Equal(t, "short.bin", shortenPath("short.bin", 20))
+	assert.Equal(t, "quant…/model.gguf", shortenPath("quantizations/q4/model.gguf", 17))
+	assert.Equal(t, "…odel.gguf", shortenPath("dir/model.gguf", 10))
+}
+
+func TestJSONProgress(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := &jsonProgress{enc: json.NewEncoder(&buf)}
+	reporter.Progress(downloadSnapshot{Model: "org/model", Transfer: "t1", Status: "active", Bytes: 10, Total: 20,
+		Files: []fileSnapshot{{Path: "a.bin", Size: 20, Completed: 10}}})
+	reporter.Event(map[string]interface{}{"seq": float64(7), "type": "download.completed", "model": "org/model", "message": "Downloaded org/model"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var progress map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &progress))
+	assert.Equal(t, "progress", progress["type"])
+	assert.Equal(t, "t1", progress["transfer_id"])
+	assert.Equal(t, float64(10), progress["bytes"])
+	assert.Len(t, progress["files"], 1)
+
+	var event map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, "event", event["type"])
+	assert.Equal(t, "download.completed", event["event"])
+	assert.Equal(t, "Downloaded org/model", event["message"])
+}
+
+func TestProgressDisplayRedraws(t *testing.T) {
+	var buf bytes.Buffer
+	display := &progressDisplay{out: &buf, tty: true}
+	s := downloadSnapshot{Model: "org/model", Status: "active", Bytes: 1, Total: 2,
+		Files: []fileSnapshot{{Path: "a.bin", Size: 1, Completed: 1}, {Path: "b.bin", Size: 1}}}
+	display.Progress(s)
+	display.Progress(s)
+	assert.Contains(t, buf.String(), "\033[3A", "the second drawing overwrites the first")
+
+	// Without a terminal a line is printed now and then
+	buf.Reset()
+	display = &progressDisplay{out: &buf}
+	display.Progress(s)
+	display.Progress(s)
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+	assert.NotContains(t, buf.String(), "\033")
+}
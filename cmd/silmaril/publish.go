@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/api/client"
+	"github.com/spf13/cobra"
+)
+
+var publishCmd = &cobra.Command{
+	Use:   "publish [directory]",
+	Short: "Publish every model in a directory tree",
+	Long: `Publish the models found in a directory, such as a whole model zoo. With
+--recursive the tree is searched for model directories, those with a
+config.json or GGUF file, and each is published on its own. Models are named
+after their place in the tree: zoo/org/model becomes org/model and
+zoo/model becomes zoo/model.
+
+Torrents are created by a pool of --workers workers, and the command waits
+until every model is published and summarizes what succeeded and failed.
+The license of each model comes from its model card, or --license for models
+whose card names none; models without a license are skipped.
+
+Examples:
+  silmaril publish --recursive /zoo
+  silmaril publish --recursive /zoo --license apache-2.0 --import inplace
+  silmaril publish /zoo/org/model --license mit     # A single model
+  silmaril publish --recursive /zoo --dry-run       # Show what would be published
+
+Files listed in a model's .silmarilignore, in .gitignore syntax, and hidden
+files are left out; --exclude and --include add patterns for this publish.
+
+With --dry-run the files, size, piece length, infohash, manifest and catalog
+announcement of each model are printed without writing anything.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPublish,
+}
+
+var (
+	publishRecursive bool
+	publishWorkers   int
+	publishNoWait    bool
+	publishDryRun    bool
+)
+
+func init() {
+	rootCmd.AddCommand(publishCmd)
+
+	publishCmd.Flags().BoolVarP(&publishRecursive, "recursive", "r", false, "search the directory tree for models")
+	publishCmd.Flags().IntVar(&publishWorkers, "workers", 2, "number of torrents created at the same time")
+	publishCmd.Flags().BoolVar(&publishNoWait, "no-wait", false, "return once publishing started instead of waiting for a summary")
+	publishCmd.Flags().String("license", "", "license of models whose model card names none")
+	publishCmd.Flags().String("version", "", "version of the published models")
+	publishCmd.Flags().String("import", "copy", "how to add the model directories: copy, link (reflink or hardlink) or inplace")
+	publishCmd.Flags().Int64("piece-length", 0, "piece length for torrents (default canonical for the model size)")
+	publishCmd.Flags().Bool("skip-dht", false, "skip DHT announcement")
+	publishCmd.Flags().BoolVar(&publishDryRun, "dry-run", false, "show what would be published without writing anything")
+	publishCmd.Flags().StringSlice("exclude", nil, "leave out files matching these .gitignore style patterns")
+	publishCmd.Flags().StringSlice("include", nil, "keep files matching these patterns even if they are excluded")
+}
+
+func runPublish(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	root, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	license, _ := cmd.Flags().GetString("license")
+	version, _ := cmd.Flags().GetString("version")
+	mode, _ := cmd.Flags().GetString("import")
+	pieces, _ := cmd.Flags().GetInt64("piece-length")
+	noDHT, _ := cmd.Flags().GetBool("skip-dht")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+	include, _ := cmd.Flags().GetStringSlice("include")
+
+	apiClient := newDaemonClient()
+	if publishDryRun {
+		// Every model is hashed before the daemon answers
+		apiClient.SetTimeout(0)
+	}
+	result, err := apiClient.PublishModels(client.PublishModelsOptions{
+		Path:        root,
+		Recursive:   publishRecursive,
+		License:     license,
+		Version:     version,
+		Import:      mode,
+		PieceLength: pieces,
+		SkipDHT:     noDHT,
+		Workers:     publishWorkers,
+		DryRun:      publishDryRun,
+		Exclude:     exclude,
+		Include:     include,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish: %w", err)
+	}
+
+	if publishDryRun {
+		return printPublishPlans(result)
+	}
+
+	jobs, _ := result["jobs"].([]interface{})
+	skipped, _ := result["skipped"].([]interface{})
+	fmt.Printf("Found %d models in %s\n", len(jobs)+len(skipped), root)
+	for _, s := range skipped {
+		model, _ := s.(map[string]interface{})
+		fmt.Printf("  ⏭  %v (%v): %v\n", model["name"], model["path"], model["error"])
+	}
+
+	var ids []string
+	for _, j := range jobs {
+		job, _ := j.(map[string]interface{})
+		id, _ := job["id"].(string)
+		ids = append(ids, id)
+		fmt.Printf("  •  %v\n", job["model"])
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("no models to publish")
+	}
+	if publishNoWait {
+		fmt.Println("\nModels are being published in the background.")
+		fmt.Println("Use 'silmaril jobs' to follow their progress.")
+		return nil
+	}
+
+	fmt.Printf("\nPublishing %d models with %d workers...\n", len(ids), publishWorkers)
+	finished, err := waitForJobs(apiClient, ids)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	fmt.Println("\nSummary:")
+	for _, job := range finished {
+		if job["status"] == "completed" {
+			fmt.Printf("  ✓ %v (%v)\n", job["model"], job["info_hash"])
+			continue
+		}
+		failed++
+		fmt.Printf("  ✗ %v: %v\n", job["model"], job["error"])
+	}
+	fmt.Printf("\nPublished %d, failed %d, skipped %d\n", len(finished)-failed, failed, len(skipped))
+	if failed > 0 {
+		return fmt.Errorf("%d models failed to publish", failed)
+	}
+	return nil
+}
+
+// waitForJobs polls jobs until all of them completed or failed, printing each
+// as it finishes, and returns them in the order of ids
+func waitForJobs(apiClient *client.Client, ids []string) ([]map[string]interface{}, error) {
+	finished := make([]map[string]interface{}, len(ids))
+	remaining := len(ids)
+	for remaining > 0 {
+		for i, id := range ids {
+			if finished[i] != nil {
+				continue
+			}
+			job, err := apiClient.GetJob(id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get job: %w", err)
+			}
+			if status := job["status"]; status != "completed" && status != "failed" {
+				continue
+			}
+			finished[i] = job
+			remaining--
+			fmt.Printf("  [%d/%d] %v %v\n", len(ids)-remaining, len(ids), job["model"], job["status"])
+		}
+		if remaining > 0 {
+			time.Sleep(2 * time.Second)
+		}
+	}
+	return finished, nil
+}
+
+// printPublishPlans prints the plans of a dry run of publish
+func printPublishPlans(result map[string]interface{}) error {
+	plans, _ := result["plans"].([]interface{})
+	skipped, _ := result["skipped"].([]interface{})
+	for _, s := range skipped {
+		model, _ := s.(map[string]interface{})
+		fmt.Printf("⏭  %v (%v): %v\n", model["name"], model["path"], model["error"])
+	}
+	for _, p := range plans {
+		plan, _ := p.(map[string]interface{})
+		fmt.Println()
+		printPlan(plan)
+	}
+
+	fmt.Printf("\nDry run: would publish %d models, skip %d. Nothing was written.\n", len(plans), len(skipped))
+	return nil
+}
+
+// printPlan prints what publishing a model would do
+func printPlan(plan map[string]interface{}) {
+	fmt.Printf("Model: %v\n", plan["model"])
+	fmt.Printf("Directory: %v\n", plan["path"])
+
+	files, _ := plan["files"].([]interface{})
+	fmt.Printf("Files (%d):\n", len(files))
+	for _, f := range files {
+		file, _ := f.(map[string]interface{})
+		size, _ := file["size"].(float64)
+		fmt.Printf("  %-50v %12.0f bytes\n", file["path"], size)
+	}
+	if excluded, _ := plan["excluded"].([]interface{}); len(excluded) > 0 {
+		fmt.Printf("Excluded (%d):\n", len(excluded))
+		for _, path := range excluded {
+			fmt.Printf("  %v\n", path)
+		}
+	}
+
+	totalSize, _ := plan["total_size"].(float64)
+	pieceLength, _ := plan["piece_length"].(float64)
+	fmt.Printf("Total size: %.0f bytes (%.2f GB)\n", totalSize, totalSize/(1024*1024*1024))
+	fmt.Printf("Piece length: %.0f bytes (%v pieces)\n", pieceLength, plan["pieces"])
+	fmt.Printf("Info hash: %v\n", plan["info_hash"])
+
+	if manifest, err := json.MarshalIndent(plan["manifest"], "", "  "); err == nil {
+		fmt.Printf("Manifest:\n%s\n", manifest)
+	}
+	if announcement, ok := plan["announcement"]; ok && announcement != nil {
+		if data, err := json.MarshalIndent(announcement, "", "  "); err == nil {
+			fmt.Printf("Catalog announcement:\n%s\n", data)
+		}
+	} else {
+		fmt.Println("Catalog announcement: skipped")
+	}
+}
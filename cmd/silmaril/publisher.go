@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/spf13/cobra"
+)
+
+var publisherCmd = &cobra.Command{
+	Use:   "publisher",
+	Short: "Manage your publisher key",
+}
+
+var publisherRotateCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Replace your publisher key with a new one",
+	Long: `Replace your publisher key with a new one, for example when the old key
+may have leaked or on a schedule.
+
+The new key is cross-signed with the old one: both keys sign a rotation
+record that is published in the catalog of the old key, in your new
+catalog and in the public catalog. Subscribers of the old key verify it and
+follow the new key, catalog entries of the old key are taken over by the
+new one, and models you share are published again under it. Nodes that
+were offline follow chains of rotations, as long as they come back before
+a rotation expires from the catalog.
+
+Your manifest signing key is replaced too, and local manifests signed with
+the old one are signed again. Old keys are kept in the retired directory of
+your keys directory.
+
+Examples:
+  silmaril publisher rotate-key
+  silmaril publisher rotate-key --yes`,
+	Args: cobra.NoArgs,
+	RunE: runPublisherRotate,
+}
+
+var publisherClaimCmd = &cobra.Command{
+	Use:   "claim <namespace> <domain>",
+	Short: "Print the proof that you own a namespace",
+	Long: `Print the DNS TXT record and the well-known document proving that your
+publisher key owns a namespace, the organization part of model names like
+mistralai/*, at a domain you control.
+
+Publish either one, then add the namespace to security.namespaces in your
+config. Models you share under the namespace name the domain in the
+catalog with your signature, and nodes that trust the domain for the
+namespace in security.trusted_namespaces and find your key in its proof
+show them as verified.
+After rotating your publisher key, publish the proof again with the new one.
+
+Examples:
+  silmaril publisher claim mistralai mistral.ai`,
+	Args: cobra.ExactArgs(2),
+	RunE: runPublisherClaim,
+}
+
+func init() {
+	rootCmd.AddCommand(publisherCmd)
+	publisherCmd.AddCommand(publisherRotateCmd)
+	publisherCmd.AddCommand(publisherClaimCmd)
+	publisherRotateCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+}
+
+func runPublisherRotate(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	yes, _ := cmd.Flags().GetBool("yes")
+	if !yes {
+		fmt.Print("This replaces your publisher key. Type 'yes' to continue: ")
+		var response string
+		fmt.Scanln(&response)
+		if response != "yes" {
+			fmt.Println("Key rotation cancelled.")
+			return nil
+		}
+	}
+
+	apiClient := newDaemonClient()
+	rotation, err := apiClient.RotatePublisherKey()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("✓ Rotated publisher key")
+	fmt.Printf("  Old key:     %s\n", rotation.OldKey)
+	fmt.Printf("  New key:     %s\n", rotation.NewKey)
+	fmt.Printf("  Republished: %d models\n", rotation.Republished)
+	fmt.Printf("  Re-signed:   %d manifests\n", rotation.Resigned)
+	fmt.Println("\nSubscribers of the old key follow the new one after their next catalog refresh.")
+	return nil
+}
+
+func runPublisherClaim(cmd *cobra.Command, args []string) error {
+	namespace, domain := strings.ToLower(args[0]), strings.ToLower(args[1])
+	if strings.Contains(namespace, "/") {
+		return fmt.Errorf("namespace %q must not contain a slash", namespace)
+	}
+
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	publisher, err := apiClient.GetPublisher()
+	if err != nil {
+		return err
+	}
+	key, _ := publisher["public_key"].(string)
+
+	wellKnown, err := json.MarshalIndent(map[string]interface{}{
+		"publishers": []map[string]interface{}{
+			{"key": key, "namespaces": []string{namespace}},
+		},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Prove that publisher %s owns %s/* with either:\n\n", key, namespace)
+	fmt.Printf("  A DNS TXT record at %s%s:\n\n", discovery.NamespaceTXTPrefix, domain)
+	fmt.Printf("    %s\n\n", discovery.NamespaceTXTRecord(key, namespace))
+	fmt.Printf("  Or this document at https://%s%s:\n\n", domain, discovery.NamespaceWellKnownPath)
+	for _, line := range strings.Split(string(wellKnown), "\n") {
+		fmt.Printf("    %s\n", line)
+	}
+	fmt.Println("\nThen add the namespace to your config and restart the daemon:")
+	fmt.Printf("\n  security:\n    namespaces:\n      %s: %s\n", namespace, domain)
+	return nil
+}
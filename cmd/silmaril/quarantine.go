@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var quarantineCmd = &cobra.Command{
+	Use:   "quarantine",
+	Short: "List downloads held in quarantine",
+	Long: `List the finished downloads held in quarantine.
+
+A finished download stays in quarantine, neither listed as a local model nor
+seeded, until its files match the checksums of its manifest. Downloads
+whose files don't match stay there until they are removed or released.
+torrent.verify_downloads: false lists downloads as soon as they finish.`,
+	Args: cobra.NoArgs,
+	RunE: runQuarantineList,
+}
+
+var quarantineReleaseCmd = &cobra.Command{
+	Use:   "release [model]",
+	Short: "Release a model from quarantine",
+	Long: `Release a model from quarantine without waiting for its verification,
+or despite it failing. The model is listed and seeded like any other;
+releasing needs an admin token.
+
+Examples:
+  silmaril quarantine release meta-llama/Llama-3.1-8B`,
+	Args: cobra.ExactArgs(1),
+	RunE: runQuarantineRelease,
+}
+
+func init() {
+	rootCmd.AddCommand(quarantineCmd)
+	quarantineCmd.AddCommand(quarantineReleaseCmd)
+}
+
+func runQuarantineList(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return err
+	}
+	models, err := newDaemonClient().ListQuarantine()
+	if err != nil {
+		return fmt.Errorf("failed to list quarantine: %w", err)
+	}
+	if len(models) == 0 {
+		fmt.Println("No downloads in quarantine.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tSTATUS\tSINCE\tREASON")
+	for _, q := range models {
+		reason := q.Reason
+		if len(q.Files) > 0 {
+			reason = fmt.Sprintf("%s: %s", reason, strings.Join(q.Files, ", "))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", q.Model, q.Status, q.Since.Local().Format("2006-01-02 15:04"), reason)
+	}
+	return w.Flush()
+}
+
+func runQuarantineRelease(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return err
+	}
+	if err := newDaemonClient().ReleaseQuarantine(args[0]); err != nil {
+		return fmt.Errorf("failed to release %s: %w", args[0], err)
+	}
+	fmt.Printf("✅ Released %s from quarantine\n", args[0])
+	return nil
+}
@@ -6,7 +6,6 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/silmaril/silmaril/internal/api/client"
 )
 
 var registryCmd = &cobra.Command{
@@ -68,7 +67,7 @@ func runImport(cmd *cobra.Command, args []string) error {
 	}
 	
 	// Create API client
-	apiClient := client.NewClient(getDaemonURL())
+	apiClient := newDaemonClient()
 	
 	// Import via API (using share endpoint for now)
 	// TODO: Add dedicated import endpoint
@@ -108,7 +107,7 @@ func runExport(cmd *cobra.Command, args []string) error {
 	}
 	
 	// Create API client
-	apiClient := client.NewClient(getDaemonURL())
+	apiClient := newDaemonClient()
 	
 	// Get model from daemon
 	model, err := apiClient.GetModel(modelName)
@@ -134,7 +133,7 @@ func runScan(cmd *cobra.Command, args []string) error {
 	}
 	
 	// Create API client
-	apiClient := client.NewClient(getDaemonURL())
+	apiClient := newDaemonClient()
 	
 	fmt.Println("Scanning for models...")
 	
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/spf13/cobra"
+)
+
+var removeCmd = &cobra.Command{
+	Use:   "remove <model-name>",
+	Short: "Stop sharing a local model and optionally delete it",
+	Long: `Stop seeding a local model and drop its torrents.
+
+Without --purge the model files stay on disk. With --purge the model
+directory and its torrent files are deleted. Models that are still being
+downloaded cannot be removed; cancel the download first with
+'silmaril cancel <model-name>'.
+
+The model stays discoverable by others until its catalog entry expires. Use
+'silmaril unpublish <model-name>' to withdraw it right away.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRemove,
+}
+
+func init() {
+	rootCmd.AddCommand(removeCmd)
+	removeCmd.Flags().Bool("purge", false, "Delete the model files from disk")
+	removeCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+}
+
+func runRemove(cmd *cobra.Command, args []string) error {
+	modelName, err := modelname.Canonical(args[0])
+	if err != nil {
+		return err
+	}
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	purge, _ := cmd.Flags().GetBool("purge")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	apiClient := newDaemonClient()
+	if !purge {
+		if err := apiClient.RemoveModel(modelName); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Stopped sharing %s, files were kept\n", modelName)
+		return nil
+	}
+
+	if !yes {
+		fmt.Printf("This deletes all files of %s. Type 'yes' to continue: ", modelName)
+		var response string
+		fmt.Scanln(&response)
+		if response != "yes" {
+			fmt.Println("Remove cancelled.")
+			return nil
+		}
+	}
+
+	freed, err := apiClient.PurgeModel(modelName)
+	if err != nil {
+		return fmt.Errorf("failed to remove model: %w", err)
+	}
+	fmt.Printf("✓ Deleted %s and freed %.2f GB\n", modelName, float64(freed)/(1024*1024*1024))
+	return nil
+}
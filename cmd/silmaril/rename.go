@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/spf13/cobra"
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <model-name> <new-name>",
+	Short: "Rename a local model",
+	Long: `Rename a local model. Its torrents stop while the files move and are seeded
+again under the new name. The manifest, transfers and aliases follow the
+model, and the old name stays an alias of the new one unless --no-alias is
+given.
+
+A model that is downloading or subscribed to updates can't be renamed.
+
+Examples:
+  silmaril rename meta-llama/Llama-3.1-8B llama/3.1-8b
+  silmaril rename llama/3.1-8b meta-llama/Llama-3.1-8B --no-alias`,
+	Args: cobra.ExactArgs(2),
+	RunE: runRename,
+}
+
+var renameNoAlias bool
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+	renameCmd.Flags().BoolVar(&renameNoAlias, "no-alias", false, "Don't keep the old name as an alias")
+}
+
+func runRename(cmd *cobra.Command, args []string) error {
+	for i, arg := range args {
+		name, err := modelname.Canonical(arg)
+		if err != nil {
+			return err
+		}
+		args[i] = name
+	}
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	response, err := newDaemonClient().RenameModel(args[0], args[1], !renameNoAlias)
+	if err != nil {
+		return fmt.Errorf("failed to rename model: %w", err)
+	}
+
+	result, _ := response["result"].(map[string]interface{})
+	from, _ := result["from"].(string)
+	fmt.Printf("✓ Renamed %s to %s\n", from, args[1])
+	if torrents, _ := result["torrents"].([]interface{}); len(torrents) > 0 {
+		fmt.Printf("  Seeding %d torrent(s) under the new name\n", len(torrents))
+	}
+	if alias, _ := result["alias"].(bool); alias {
+		fmt.Printf("  %s is now an alias of %s\n", from, args[1])
+	}
+	for _, warning := range getStringList(response["warnings"]) {
+		fmt.Printf("  ⚠ %s\n", warning)
+	}
+	return nil
+}
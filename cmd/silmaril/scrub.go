@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/spf13/cobra"
+)
+
+var scrubCmd = &cobra.Command{
+	Use:   "scrub [model-name]",
+	Short: "Re-verify seeded models against their piece hashes",
+	Long: `Re-verify the data of a seeded model against its piece hashes, or show when
+each seeded model was last verified.
+
+The daemon scrubs every seeded model every storage.scrub_interval_hours, one
+model at a time. Corrupt pieces are downloaded again from peers, so bit rot
+on disk isn't served to others.
+
+Examples:
+  silmaril scrub                           # Show the last scrub of each model
+  silmaril scrub meta-llama/Llama-3.1-8B   # Scrub a model now`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runScrub,
+}
+
+func init() {
+	rootCmd.AddCommand(scrubCmd)
+}
+
+func runScrub(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+
+	if len(args) == 1 {
+		modelName, err := modelname.Canonical(args[0])
+		if err != nil {
+			return err
+		}
+		if err := apiClient.ScrubModel(modelName); err != nil {
+			return fmt.Errorf("failed to scrub: %w", err)
+		}
+		fmt.Printf("✓ Scrubbing %s, follow it with: silmaril daemon logs -f\n", modelName)
+		return nil
+	}
+
+	scrubs, err := apiClient.ListScrubs()
+	if err != nil {
+		return fmt.Errorf("failed to list scrubs: %w", err)
+	}
+	if len(scrubs) == 0 {
+		fmt.Println("No seeded models.")
+		return nil
+	}
+
+	sort.Slice(scrubs, func(i, j int) bool {
+		nameI, _ := scrubs[i]["model"].(string)
+		nameJ, _ := scrubs[j]["model"].(string)
+		return nameI < nameJ
+	})
+
+	for _, scrub := range scrubs {
+		name, _ := scrub["model"].(string)
+		fmt.Printf("  %s\n", name)
+
+		if scrubbing, _ := scrub["scrubbing"].(bool); scrubbing {
+			fmt.Println("    Scrubbing now")
+		}
+		last, _ := scrub["last_scrubbed"].(string)
+		lastTime, err := time.Parse(time.RFC3339Nano, last)
+		if err != nil {
+			fmt.Println("    Never scrubbed")
+			continue
+		}
+		fmt.Printf("    Last scrubbed: %s", lastTime.Local().Format("2006-01-02 15:04"))
+		if corrupt, _ := scrub["corrupt_pieces"].(float64); corrupt > 0 {
+			fmt.Printf(" | %.0f corrupt pieces found and downloaded again from peers", corrupt)
+		}
+		fmt.Println()
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <term>",
+	Short: "Search HuggingFace for models and show which are on the P2P network",
+	Long: `Search the HuggingFace Hub for models matching a term, most downloaded
+first, and mark each result that can be downloaded from peers on the
+Silmaril network or is already stored locally.
+
+Models on the network can be fetched with 'silmaril get' without touching
+the hub. Others can be cloned from the hub and shared with 'silmaril share'.
+The daemon searches the hub set in hf_proxy.upstream through the git proxy,
+and sends the token of 'silmaril auth hf login', or HF_TOKEN, so gated and
+private models show up.
+
+Examples:
+  silmaril search llama
+  silmaril search "qwen coder" --limit 50
+  silmaril search mistral --p2p-only`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSearch,
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmd.Flags().Int("limit", 20, "Maximum number of hub results")
+	searchCmd.Flags().Bool("p2p-only", false, "Only show models available on the P2P network")
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	term := strings.Join(args, " ")
+	limit, _ := cmd.Flags().GetInt("limit")
+	p2pOnly, _ := cmd.Flags().GetBool("p2p-only")
+
+	apiClient := newDaemonClient()
+	results, err := apiClient.SearchHub(term, limit)
+	if err != nil {
+		return fmt.Errorf("failed to search: %w", err)
+	}
+
+	shown, onNetwork := 0, 0
+	for _, model := range results {
+		id, _ := model["id"].(string)
+		available, _ := model["on_network"].(bool)
+		local, _ := model["local"].(bool)
+		if available {
+			onNetwork++
+		} else if p2pOnly {
+			continue
+		}
+		shown++
+
+		var marks []string
+		if local {
+			marks = append(marks, "local")
+		}
+		if available {
+			marks = append(marks, "p2p")
+		} else {
+			marks = append(marks, "hub only")
+		}
+		if gated, _ := model["gated"].(bool); gated {
+			marks = append(marks, "gated")
+		}
+		fmt.Printf("  %s [%s]\n", id, strings.Join(marks, ", "))
+
+		downloads, _ := model["downloads"].(float64)
+		likes, _ := model["likes"].(float64)
+		fmt.Printf("    Downloads: %.0f | Likes: %.0f", downloads, likes)
+		if tag, _ := model["pipeline_tag"].(string); tag != "" {
+			fmt.Printf(" | %s", tag)
+		}
+		fmt.Println()
+
+		if available {
+			version, _ := model["version"].(string)
+			size, _ := model["size"].(float64)
+			fmt.Printf("    P2P: version %s", version)
+			if size > 0 {
+				fmt.Printf(" | %s", formatProgressBytes(int64(size)))
+			}
+			if seeders, _ := model["seeders"].(float64); seeders > 0 {
+				fmt.Printf(" | %.0f seeders", seeders)
+			}
+			fmt.Println()
+		}
+		if !local {
+			if available {
+				fmt.Printf("    Get: silmaril get %s\n", id)
+			} else {
+				fmt.Printf("    Share: silmaril share %s\n", id)
+			}
+		}
+	}
+
+	if shown == 0 {
+		if p2pOnly && len(results) > 0 {
+			fmt.Printf("None of the %d hub results for %q are on the P2P network.\n", len(results), term)
+		} else {
+			fmt.Printf("No models found on the hub for %q.\n", term)
+		}
+		return nil
+	}
+	fmt.Printf("\n%d hub results, %d available from peers\n", len(results), onNetwork)
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/daemon"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serviceAccount  string
+	servicePassword string
+)
+
+var daemonServiceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Run the daemon as a Windows service",
+	Long: `Register the daemon with the Windows service manager, so it starts with the
+system, runs without anyone logged in and is restarted when it fails.
+
+The service runs 'silmaril daemon start --foreground' with the config file
+in use, so it finds the same models directory and keys. It runs as
+LocalSystem unless --account is given; use your own account to keep the
+files readable by you. Run these commands from an elevated prompt.
+
+On Linux and macOS run 'silmaril daemon start --foreground' under systemd or
+launchd instead.
+
+Examples:
+  silmaril daemon service install
+  silmaril daemon service install --account .\me --password secret
+  silmaril daemon service uninstall`,
+}
+
+var daemonServiceInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install and start the daemon service",
+	Args:  cobra.NoArgs,
+	RunE:  runServiceInstall,
+}
+
+var daemonServiceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Stop and remove the daemon service",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := daemon.UninstallService(); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Removed service %s\n", daemon.ServiceName)
+		return nil
+	},
+}
+
+func init() {
+	daemonCmd.AddCommand(daemonServiceCmd)
+	daemonServiceCmd.AddCommand(daemonServiceInstallCmd, daemonServiceUninstallCmd)
+
+	daemonServiceInstallCmd.Flags().StringVar(&serviceAccount, "account", "", "Account the service runs as, e.g. .\\me (default: LocalSystem)")
+	daemonServiceInstallCmd.Flags().StringVar(&servicePassword, "password", "", "Password of --account")
+}
+
+func runServiceInstall(cmd *cobra.Command, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate silmaril executable: %w", err)
+	}
+
+	// The service account has its own profile, point it at our config
+	configFile := config.GetViper().ConfigFileUsed()
+	if configFile == "" {
+		return fmt.Errorf("no config file found, run 'silmaril init' first")
+	}
+	if configFile, err = filepath.Abs(configFile); err != nil {
+		return err
+	}
+	serviceArgs := []string{"daemon", "start", "--foreground", "--config", configFile}
+	if profile != "" {
+		serviceArgs = append(serviceArgs, "--profile", profile)
+	}
+
+	if err := daemon.InstallService(daemon.ServiceOptions{
+		Executable: executable,
+		Args:       serviceArgs,
+		Account:    serviceAccount,
+		Password:   servicePassword,
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Installed and started service %s\n", daemon.ServiceName)
+	fmt.Printf("  Config: %s\n", configFile)
+	return nil
+}
@@ -30,7 +30,89 @@ Examples:
   silmaril share meta-llama/Llama-3.1-8B        # Seed specific model from registry
   silmaril share https://huggingface.co/meta-llama/Llama-3.1-8B  # Clone and share from HF
   silmaril share mistralai/Mistral-7B-v0.1      # Clone and share using HF short format
-  silmaril share /path/to/model/dir --name org/model --license apache-2.0  # Publish local dir`,
+  silmaril share /path/to/model/dir --name org/model --license apache-2.0  # Publish local dir
+  silmaril share /path/to/model/dir --name org/model --license apache-2.0 --import inplace  # Seed without copying
+
+When publishing a directory, --import controls how it is added to the
+models directory: "copy" copies the files, "link" clones them with reflinks
+or hardlinks where the filesystem supports it, and "inplace" seeds the
+directory from where it is. Files linked with "link" or "inplace" must not
+be modified while they are shared.
+
+--revision clones a repository at a tag or commit instead of the head of
+--branch. The model is versioned by the tag, or by the full hash of the
+commit, and its provenance records the commit, so sharing the same
+revision again publishes the same files under the same infohash.
+
+  silmaril share mistralai/Mistral-7B-v0.1 --revision 26bca36bde8333b5d7f72e9ed20ccda6a618af24
+
+A clone interrupted while downloading the LFS files, by a network error or
+a daemon restart, keeps what it downloaded. Sharing the same repository
+again continues it where it stopped; 'silmaril jobs' shows its progress.
+
+Seeding stops once the model reached a seed ratio (uploaded / model size),
+has been seeded for a while, or had no peers for a while. Limits given with
+--seed-ratio, --seed-time and --stop-if-no-peers-for are stored with the
+model and override the torrent settings of the config, 0 means unlimited.
+Sharing a model again resumes seeding after a stop.
+
+  silmaril share org/model --seed-ratio 2 --seed-time 72h
+  silmaril share --all --stop-if-no-peers-for 24h
+
+--seed-priority weighs the model's share of network.upload_rate_limit
+against the other models: low, normal (the default) or high. See
+'silmaril uploads'.
+
+  silmaril share org/model --seed-priority high
+
+--storage sets how the daemon stores the model's torrent data, overriding
+torrent.storage_backend: "file" reads and writes the files, "mmap" maps
+them into memory for models uploaded to many peers, and "cached" records
+verified pieces in the daemon's database instead of the model directory.
+It applies the next time the model's torrent is added, for a model
+already seeding after the daemon restarts.
+
+  silmaril share org/model --storage mmap
+
+With --files a model that is already seeded only keeps seeding the matching
+files, so the others can be deleted to free disk space. Pieces are only
+advertised to peers once they are local.
+
+  silmaril share TheBloke/Llama-2-7B-GGUF --files "*Q4_K_M.gguf"
+
+With --encrypt a published directory is seeded encrypted with a new key,
+for models only licensees may use. The key is printed once and kept in the
+key store; hand it to licensees out of band, or serve it from an access
+controlled endpoint given with --key-url.
+
+  silmaril share /path/to/model --name org/model --license proprietary --encrypt
+
+The license terms of a published model can be recorded in its manifest with
+--license-url and --license-file. With --require-license-acceptance
+downloaders have to accept them before their first download.
+
+  silmaril share /path/to/model --name org/model --license llama3 \
+    --license-url https://example.com/LICENSE --require-license-acceptance
+
+--mirror records HTTP servers holding the same files in the manifest.
+Downloaders fetch byte ranges from them alongside the swarm. Models cloned
+from HuggingFace use the repository at the cloned commit as a mirror too.
+
+  silmaril share /path/to/model --name org/model --license mit \
+    --mirror https://example.com/models/org/model/
+
+Hidden files, such as .git, and the files listed in the .silmarilignore of
+the model directory, in .gitignore syntax, are not published. --exclude and
+--include add patterns for a single publish, and --include keeps files that
+would be left out.
+
+  silmaril share https://huggingface.co/org/model --exclude "*.log" --exclude "checkpoint-*/"
+
+With --dry-run publishing a directory only prints the files, size, piece
+length, infohash, manifest and catalog announcement it would produce, and
+nothing is written.
+
+  silmaril share /path/to/model --name org/model --license mit --dry-run`,
 	RunE: runShare,
 }
 
@@ -43,10 +125,35 @@ var (
 	skipDHT      bool
 	signManifest bool
 	noMonitor    bool
+	importMode   string
 	// Git/repo cloning options
 	gitBranch    string
+	gitRevision  string
 	gitDepth     int
 	skipLFS      bool
+	// Seeding policy
+	seedRatio        float64
+	seedTime         time.Duration
+	stopIfNoPeersFor time.Duration
+	seedPriority     string
+	shareStorage     string
+	shareFiles       []string
+	// Encrypted publishing
+	shareEncrypt bool
+	shareKeyURL  string
+	// License terms
+	licenseURL               string
+	licenseFile              string
+	requireLicenseAcceptance bool
+	// HTTP servers holding the files of a published model
+	shareMirrors []string
+	// Dry run of publishing a directory
+	shareDryRun bool
+	// Seed again models already seeding
+	shareForce bool
+	// Files left out of a published model
+	shareExclude []string
+	shareInclude []string
 )
 
 func init() {
@@ -58,15 +165,59 @@ func init() {
 	shareCmd.Flags().StringVar(&modelName, "name", "", "model name for publishing (e.g., org/model-name)")
 	shareCmd.Flags().StringVar(&modelVersion, "version", "main", "model version/revision")
 	shareCmd.Flags().StringVar(&modelLicense, "license", "", "model license")
-	shareCmd.Flags().Int64Var(&pieceLength, "piece-length", 4*1024*1024, "piece length for torrent (default 4MB)")
+	shareCmd.Flags().Int64Var(&pieceLength, "piece-length", 0, "piece length for torrent (default canonical for the model size)")
 	shareCmd.Flags().BoolVar(&skipDHT, "skip-dht", false, "skip DHT announcement")
 	shareCmd.Flags().BoolVar(&signManifest, "sign", true, "sign the manifest")
 	shareCmd.Flags().BoolVar(&noMonitor, "no-monitor", true, "don't monitor seeding progress after sharing")
+	shareCmd.Flags().StringVar(&importMode, "import", "copy", "how to add a published directory: copy, link (reflink or hardlink) or inplace")
 	
 	// Git/repo cloning flags
 	shareCmd.Flags().StringVar(&gitBranch, "branch", "main", "Git branch to clone (for repository URLs)")
+	shareCmd.Flags().StringVar(&gitRevision, "revision", "", "Git tag or commit to clone instead of the branch head (for repository URLs)")
 	shareCmd.Flags().IntVar(&gitDepth, "depth", 1, "Git clone depth, 0 for full history (for repository URLs)")
 	shareCmd.Flags().BoolVar(&skipLFS, "skip-lfs", false, "Skip downloading large files via Git LFS (for repository URLs)")
+
+	// Seeding policy flags
+	shareCmd.Flags().Float64Var(&seedRatio, "seed-ratio", 0, "stop seeding at this upload ratio, 0 for unlimited (default from config)")
+	shareCmd.Flags().DurationVar(&seedTime, "seed-time", 0, "stop seeding after this long, e.g. 72h, 0 for unlimited (default from config)")
+	shareCmd.Flags().DurationVar(&stopIfNoPeersFor, "stop-if-no-peers-for", 0, "stop seeding after this long without peers, 0 for never (default from config)")
+	shareCmd.Flags().StringVar(&seedPriority, "seed-priority", "", "share of the upload bandwidth: low, normal or high (default normal)")
+	shareCmd.Flags().StringVar(&shareStorage, "storage", "", "storage backend of the model: file, mmap or cached (default from config)")
+	shareCmd.Flags().StringSliceVar(&shareFiles, "files", nil, "only keep seeding files of a seeded model matching these glob patterns")
+
+	// Encrypted publishing
+	shareCmd.Flags().BoolVar(&shareEncrypt, "encrypt", false, "seed a published directory encrypted, for gated models")
+	shareCmd.Flags().StringVar(&shareKeyURL, "key-url", "", "endpoint serving the key of an encrypted model to licensees")
+
+	// License terms
+	shareCmd.Flags().StringVar(&licenseURL, "license-url", "", "URL of the full license terms of a published model")
+	shareCmd.Flags().StringVar(&licenseFile, "license-file", "", "file with the license text of a published model")
+	shareCmd.Flags().BoolVar(&requireLicenseAcceptance, "require-license-acceptance", false, "require downloaders to accept the license first")
+	shareCmd.Flags().StringSliceVar(&shareMirrors, "mirror", nil, "base URL of an HTTP server holding the files of a published model")
+
+	// Files of published models
+	shareCmd.Flags().StringSliceVar(&shareExclude, "exclude", nil, "leave out files of a published model matching these .gitignore style patterns")
+	shareCmd.Flags().StringSliceVar(&shareInclude, "include", nil, "keep files of a published model matching these patterns even if they are excluded")
+
+	// Dry run
+	shareCmd.Flags().BoolVar(&shareDryRun, "dry-run", false, "show what publishing a directory would do without writing anything")
+	shareCmd.Flags().BoolVar(&shareForce, "force", false, "seed again models that are already seeding")
+}
+
+// applySeedingFlags adds the seeding policy and storage flags given on the
+// command line
+func applySeedingFlags(cmd *cobra.Command, opts *client.ShareModelOptions) {
+	opts.Storage = shareStorage
+	opts.SeedPriority = seedPriority
+	if cmd.Flags().Changed("seed-ratio") {
+		opts.SeedRatio = &seedRatio
+	}
+	if cmd.Flags().Changed("seed-time") {
+		opts.SeedTime = &seedTime
+	}
+	if cmd.Flags().Changed("stop-if-no-peers-for") {
+		opts.StopIfNoPeersFor = &stopIfNoPeersFor
+	}
 }
 
 func runShare(cmd *cobra.Command, args []string) error {
@@ -75,8 +226,22 @@ func runShare(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to start daemon: %w", err)
 	}
 
+	// Only publishing a directory can be planned without side effects
+	if shareDryRun {
+		if shareAll || len(args) == 0 {
+			return fmt.Errorf("--dry-run is only supported when publishing a directory")
+		}
+		if info, err := os.Stat(args[0]); err != nil || !info.IsDir() {
+			return fmt.Errorf("--dry-run is only supported when publishing a directory")
+		}
+	}
+
 	// Create API client
-	apiClient := client.NewClient(getDaemonURL())
+	apiClient := newDaemonClient()
+	if shareDryRun {
+		// The model is hashed before the daemon answers
+		apiClient.SetTimeout(0)
+	}
 
 	var modelNameToShare string
 	var pathToShare string
@@ -86,18 +251,24 @@ func runShare(cmd *cobra.Command, args []string) error {
 		fmt.Println("Seeding all downloaded models...")
 
 		opts := client.ShareModelOptions{
-			All: true,
+			All:   true,
+			Force: shareForce,
 		}
+		applySeedingFlags(cmd, &opts)
 		result, err := apiClient.ShareModel(opts)
 		if err != nil {
 			return fmt.Errorf("failed to share models: %w", err)
 		}
 
 		modelsShared := 0
+		alreadyShared := 0
 		totalModels := 0
 		if ms, ok := result["models_shared"].(float64); ok {
 			modelsShared = int(ms)
 		}
+		if as, ok := result["already_shared"].(float64); ok {
+			alreadyShared = int(as)
+		}
 		if tm, ok := result["total_models"].(float64); ok {
 			totalModels = int(tm)
 		}
@@ -109,10 +280,28 @@ func runShare(cmd *cobra.Command, args []string) error {
 		}
 
 		fmt.Printf("✅ Started sharing %d out of %d models\n", modelsShared, totalModels)
+		if alreadyShared > 0 {
+			fmt.Printf("%d models were already seeding, use --force to seed them again\n", alreadyShared)
+		}
 
 	} else if len(args) > 0 {
 		input := args[0]
 
+		// Only the files seeded of a model we already seed change
+		if len(shareFiles) > 0 {
+			result, err := apiClient.ShareModel(client.ShareModelOptions{
+				ModelName: input,
+				Files:     shareFiles,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to select files: %w", err)
+			}
+			selected, _ := result["selected_files"].(float64)
+			fmt.Printf("✓ Seeding %.0f files of %s matching %s\n", selected, input, strings.Join(shareFiles, ", "))
+			fmt.Println("Files that are no longer seeded can be deleted to free disk space.")
+			return nil
+		}
+
 		// Check if it's a repository URL (git or HuggingFace)
 		if isRepositoryURL(input) {
 			// It's a git/HF repo - need to clone it first
@@ -121,19 +310,23 @@ func runShare(cmd *cobra.Command, args []string) error {
 			fmt.Printf("Cloning repository: %s\n", gitURL)
 			fmt.Printf("Model will be named: %s\n", repoModelName)
 			
-			// Check if it's a HuggingFace URL and HF_TOKEN is not set
-			if strings.Contains(gitURL, "huggingface.co") && os.Getenv("HF_TOKEN") == "" {
-				fmt.Println("Note: Some models require authentication. Set HF_TOKEN environment variable for gated models.")
+			// Check if it's a HuggingFace URL and there is no token
+			if strings.Contains(gitURL, "huggingface.co") && hfTokenStore().TokenFor(gitURL) == "" {
+				fmt.Println("Note: Some models require authentication. Run 'silmaril auth hf login' or set HF_TOKEN for gated models.")
 			}
 			
 			// Use the share API with repository options
 			opts := client.ShareModelOptions{
 				RepoURL: gitURL,
-				Branch:  gitBranch,
-				Depth:   gitDepth,
+				Branch:   gitBranch,
+				Revision: gitRevision,
+				Depth:    gitDepth,
 				SkipLFS: skipLFS,
 				SkipDHT: skipDHT,
+				Exclude: shareExclude,
+				Include: shareInclude,
 			}
+			applySeedingFlags(cmd, &opts)
 			
 			result, err := apiClient.ShareModel(opts)
 			if err != nil {
@@ -144,8 +337,16 @@ func runShare(cmd *cobra.Command, args []string) error {
 				fmt.Printf("✅ %s\n", msg)
 			}
 			
-			fmt.Println("\nRepository is being cloned and shared in the background.")
-			fmt.Println("Use 'silmaril list' to check when the model is available.")
+			if resumed, _ := result["resumed"].(bool); resumed {
+				fmt.Println("\nContinuing the interrupted clone of the repository in the background.")
+			} else {
+				fmt.Println("\nRepository is being cloned and shared in the background.")
+			}
+			if jobID, ok := result["job_id"].(string); ok {
+				fmt.Printf("Use 'silmaril jobs %s' to follow its progress.\n", jobID)
+			} else {
+				fmt.Println("Use 'silmaril list' to check when the model is available.")
+			}
 			return nil
 			
 		} else {
@@ -176,19 +377,23 @@ func runShare(cmd *cobra.Command, args []string) error {
 					gitURL := fmt.Sprintf("https://huggingface.co/%s", input)
 					fmt.Printf("Cloning HuggingFace model: %s\n", input)
 					
-					// Check if HF_TOKEN is not set
-					if os.Getenv("HF_TOKEN") == "" {
-						fmt.Println("Note: Some models require authentication. Set HF_TOKEN environment variable for gated models.")
+					// Check if there is no token
+					if hfTokenStore().TokenFor(gitURL) == "" {
+						fmt.Println("Note: Some models require authentication. Run 'silmaril auth hf login' or set HF_TOKEN for gated models.")
 					}
 					
 					// Use the share API with repository options
 					opts := client.ShareModelOptions{
 						RepoURL: gitURL,
-						Branch:  gitBranch,
-						Depth:   gitDepth,
+						Branch:   gitBranch,
+						Revision: gitRevision,
+						Depth:    gitDepth,
 						SkipLFS: skipLFS,
 						SkipDHT: skipDHT,
+						Exclude: shareExclude,
+						Include: shareInclude,
 					}
+					applySeedingFlags(cmd, &opts)
 					
 					result, err := apiClient.ShareModel(opts)
 					if err != nil {
@@ -200,7 +405,11 @@ func runShare(cmd *cobra.Command, args []string) error {
 					}
 					
 					fmt.Println("\nModel is being cloned and shared in the background.")
-					fmt.Println("Use 'silmaril list' to check when the model is available.")
+					if jobID, ok := result["job_id"].(string); ok {
+						fmt.Printf("Use 'silmaril jobs %s' to follow its progress.\n", jobID)
+					} else {
+						fmt.Println("Use 'silmaril list' to check when the model is available.")
+					}
 					return nil
 				} else {
 					// Assume it's a model name from registry
@@ -224,7 +433,28 @@ func runShare(cmd *cobra.Command, args []string) error {
 			PieceLength:  pieceLength,  // From --piece-length flag
 			SkipDHT:      skipDHT,      // From --skip-dht flag
 			SignManifest: signManifest, // From --sign flag
+			Import:       importMode,   // From --import flag
+			Encrypt:      shareEncrypt, // From --encrypt flag
+			KeyURL:       shareKeyURL,  // From --key-url flag
+		}
+		applySeedingFlags(cmd, &opts)
+		if shareEncrypt && pathToShare == "" {
+			return fmt.Errorf("--encrypt is only supported when publishing a directory")
+		}
+		if licenseFile != "" {
+			text, err := os.ReadFile(licenseFile)
+			if err != nil {
+				return fmt.Errorf("failed to read license file: %w", err)
+			}
+			opts.LicenseText = string(text)
 		}
+		opts.LicenseURL = licenseURL
+		opts.RequireLicenseAcceptance = requireLicenseAcceptance
+		opts.Mirrors = shareMirrors
+		opts.DryRun = shareDryRun
+		opts.Force = shareForce
+		opts.Exclude = shareExclude
+		opts.Include = shareInclude
 		
 
 		// Share the specific model or path
@@ -233,9 +463,10 @@ func runShare(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to share: %w", err)
 		}
 
-		// Check if the result contains an error
-		if errMsg, ok := result["error"].(string); ok {
-			return fmt.Errorf("API error: %s", errMsg)
+		if plan, ok := result["plan"].(map[string]interface{}); ok {
+			printPlan(plan)
+			fmt.Println("\nDry run: nothing was written.")
+			return nil
 		}
 
 		if msg, ok := result["message"].(string); ok {
@@ -246,6 +477,21 @@ func runShare(cmd *cobra.Command, args []string) error {
 			fmt.Printf("Transfer ID: %s\n", transferID)
 		}
 
+		if warning, ok := result["warning"].(string); ok {
+			fmt.Printf("⚠️  Warning: %s\n", warning)
+		}
+
+		if imported, ok := result["imported"].(map[string]interface{}); ok && importMode == "link" {
+			fmt.Printf("Files reflinked: %v, hardlinked: %v, copied: %v\n",
+				imported["reflinked"], imported["hardlinked"], imported["copied"])
+		}
+
+		if key, ok := result["key"].(string); ok {
+			fmt.Printf("\n✓ Seeding encrypted with key %v\n", result["key_id"])
+			fmt.Printf("Key: %s\n", key)
+			fmt.Printf("Licensees download it with: silmaril get %s --key <key>\n", modelName)
+		}
+
 	} else {
 		// No arguments and not --all
 		fmt.Println("Please specify a model name, repository URL, or use --all to seed all models")
@@ -374,7 +620,7 @@ func isHuggingFaceShortFormat(input string) bool {
 			// Check if model already exists in local registry
 			// If it does, it's probably a reference to an existing model
 			// If not, treat it as a HuggingFace identifier to clone
-			apiClient := client.NewClient(getDaemonURL())
+			apiClient := newDaemonClient()
 			if _, err := apiClient.GetModel(input); err != nil {
 				// Model doesn't exist locally, likely a HF identifier
 				return true
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/signing"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var signCmd = &cobra.Command{
+	Use:   "sign <model-name|manifest-file>",
+	Short: "Add your signature to the manifest of a model",
+	Long: `Sign the manifest of a local model, or a manifest file, with your
+publisher key, next to the signatures already on it.
+
+An organization publishes under a signature policy: its member keys and how
+many of them must sign, so no single member can publish under its name.
+Set it with --org, --threshold and --key before anyone signs, send the
+manifest file to the members to sign, and merge their copies back with
+--merge. Nodes that pin the organization in security.organizations refuse
+its manifests without enough signatures.
+
+Signatures cover the files and their checksums, so every file must be hashed.
+Changing the manifest afterwards drops the signatures made before.
+
+Examples:
+  silmaril sign acme/model --org acme --threshold 2 --key 3b6a... --key 9f01... --key c4d2...
+  silmaril sign ./model.silmaril.json
+  silmaril sign acme/model --merge alice.json --merge bob.json --no-sign`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSign,
+}
+
+func init() {
+	rootCmd.AddCommand(signCmd)
+
+	signCmd.Flags().String("org", "", "Organization the signature policy stands for")
+	signCmd.Flags().Int("threshold", 0, "Signatures of policy keys a manifest needs")
+	signCmd.Flags().StringArray("key", nil, "Publisher key of the policy, repeat for each member")
+	signCmd.Flags().StringArray("merge", nil, "Merge the signatures of another copy of the manifest")
+	signCmd.Flags().Bool("no-sign", false, "Only set the policy or merge signatures, don't sign")
+}
+
+func runSign(cmd *cobra.Command, args []string) error {
+	org, _ := cmd.Flags().GetString("org")
+	threshold, _ := cmd.Flags().GetInt("threshold")
+	keys, _ := cmd.Flags().GetStringArray("key")
+	merge, _ := cmd.Flags().GetStringArray("merge")
+	noSign, _ := cmd.Flags().GetBool("no-sign")
+
+	manifest, save, err := loadManifestToSign(args[0])
+	if err != nil {
+		return err
+	}
+	for _, file := range manifest.Files {
+		if file.SHA256 == "" {
+			return fmt.Errorf("%s has no checksum yet, wait until the daemon has hashed the model, see 'silmaril list'", file.Path)
+		}
+	}
+
+	if len(keys) > 0 || threshold > 0 || org != "" {
+		policy := &types.SignaturePolicy{Organization: org, Threshold: threshold, Keys: keys}
+		if err := signing.ValidatePolicy(policy); err != nil {
+			return err
+		}
+		manifest.SignaturePolicy = policy
+	}
+
+	for _, file := range merge {
+		other, err := readManifestFile(file)
+		if err != nil {
+			return err
+		}
+		added, err := signing.MergeSignatures(manifest, other)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Merged %d signatures from %s\n", added, file)
+	}
+
+	if !noSign {
+		privateKey, err := discovery.LoadOrCreatePublisherKey(filepath.Join(config.Get().Security.KeysDir, "publisher.key"))
+		if err != nil {
+			return err
+		}
+		if err := signing.AddSignature(manifest, privateKey); err != nil {
+			return err
+		}
+	}
+
+	if err := save(manifest); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+
+	signers, err := signing.VerifySignatures(manifest)
+	fmt.Printf("✓ Saved %s with %d signatures\n", args[0], len(manifest.Signatures))
+	if policy := manifest.SignaturePolicy; policy != nil {
+		if policy.Organization != "" {
+			fmt.Printf("  Organization: %s\n", policy.Organization)
+		}
+		fmt.Printf("  Policy:       %s\n", signing.PolicyID(policy))
+		fmt.Printf("  Signed by:    %d of %d required keys\n", len(signers), policy.Threshold)
+	}
+	if errors.Is(err, signing.ErrThresholdNotMet) {
+		fmt.Println("\nSend the manifest to more members to sign and merge their copies with --merge")
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// loadManifestToSign reads a manifest file, or the manifest of a local model,
+// and returns a function saving it back
+func loadManifestToSign(target string) (*types.ModelManifest, func(*types.ModelManifest) error, error) {
+	if info, err := os.Stat(target); err == nil && !info.IsDir() {
+		manifest, err := readManifestFile(target)
+		if err != nil {
+			return nil, nil, err
+		}
+		return manifest, func(m *types.ModelManifest) error {
+			data, err := json.MarshalIndent(m, "", "  ")
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(target, append(data, '\n'), 0644)
+		}, nil
+	}
+
+	paths, err := storage.NewPaths()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize paths: %w", err)
+	}
+	registry, err := models.NewRegistry(paths)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load registry: %w", err)
+	}
+	manifest, err := registry.GetManifest(target)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s is neither a manifest file nor a local model: %w", target, err)
+	}
+	// The registry hands out its own copy, sign another one
+	updated := *manifest
+	return &updated, registry.SaveManifest, nil
+}
+
+// readManifestFile reads a manifest from a file
+func readManifestFile(path string) (*types.ModelManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest types.ModelManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	return &manifest, nil
+}
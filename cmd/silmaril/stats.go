@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show how much this node uploaded and downloaded",
+	Long: `Show the bytes uploaded and downloaded across all daemon restarts, the
+share ratio, the models seeded the most, the number of distinct peers and the
+daily totals. The same counters are exported for Prometheus at /metrics.
+
+Examples:
+  silmaril stats              # Totals, top models and the last 14 days
+  silmaril stats --top 20     # The 20 most uploaded models
+  silmaril stats --days 90    # Daily totals for the last 90 days`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().Int("top", 10, "Show this many of the most uploaded models, 0 for all")
+	statsCmd.Flags().Int("days", 14, "Show daily totals for this many days, 0 for none")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	top, _ := cmd.Flags().GetInt("top")
+	days, _ := cmd.Flags().GetInt("days")
+	if days < 0 {
+		return fmt.Errorf("invalid days: %d", days)
+	}
+
+	// The API returns all kept days for 0, which here means none
+	requestDays := days
+	if requestDays == 0 {
+		requestDays = 1
+	}
+	stats, err := newDaemonClient().GetStats(requestDays)
+	if err != nil {
+		return fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	uploaded, _ := stats["uploaded"].(float64)
+	downloaded, _ := stats["downloaded"].(float64)
+	peers, _ := stats["unique_peers"].(float64)
+	fmt.Printf("Uploaded: %.2f GB | Downloaded: %.2f GB | Ratio: %s | Unique peers: %.0f\n",
+		uploaded/(1024*1024*1024), downloaded/(1024*1024*1024), formatRatio(stats["ratio"], downloaded), peers)
+
+	models, _ := stats["models"].([]interface{})
+	if top > 0 && len(models) > top {
+		models = models[:top]
+	}
+	if len(models) > 0 {
+		fmt.Println("\nTop models seeded:")
+		for _, m := range models {
+			model, ok := m.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := model["model"].(string)
+			up, _ := model["uploaded"].(float64)
+			down, _ := model["downloaded"].(float64)
+			modelPeers, _ := model["unique_peers"].(float64)
+			fmt.Printf("  %s\n", name)
+			fmt.Printf("    Uploaded: %.2f GB | Downloaded: %.2f GB | Ratio: %s | Peers: %.0f\n",
+				up/(1024*1024*1024), down/(1024*1024*1024), formatRatio(model["ratio"], down), modelPeers)
+		}
+	}
+
+	daily, _ := stats["daily"].([]interface{})
+	if days > 0 && len(daily) > 0 {
+		fmt.Println("\nContribution by day:")
+		for _, d := range daily {
+			day, ok := d.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			date, _ := day["date"].(string)
+			up, _ := day["uploaded"].(float64)
+			down, _ := day["downloaded"].(float64)
+			fmt.Printf("  %s  Uploaded: %.2f GB | Downloaded: %.2f GB\n",
+				date, up/(1024*1024*1024), down/(1024*1024*1024))
+		}
+	}
+	return nil
+}
+
+// formatRatio shows a share ratio, which is undefined until something was
+// downloaded
+func formatRatio(ratio interface{}, downloaded float64) string {
+	value, _ := ratio.(float64)
+	if downloaded <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.2f", value)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/daemon"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Manage where Silmaril keeps its data",
+}
+
+var storageMigrateCmd = &cobra.Command{
+	Use:   "migrate <path>",
+	Short: "Move the models, torrents and state to a new base directory",
+	Long: `Move everything in the base directory, models, torrents, the database and
+the daemon's state, to a new base directory, for example when the disk it is
+on fills up. The new directory must be empty.
+
+Files are moved one at a time: renamed if both directories are on the same
+filesystem, otherwise copied and removed once the copy is on disk. If the
+migration is interrupted, run the same command again to resume it, the
+daemon refuses to start until it has finished.
+
+Once the files are moved, the paths recorded in the daemon's state point to
+the new directory, and storage.base_dir (or the base_dir of the active
+profile) is set to it in the config file. Models imported in place stay
+where they are. The daemon must be stopped first.
+
+Examples:
+  silmaril daemon stop
+  silmaril storage migrate /mnt/big-disk/silmaril
+  silmaril daemon start`,
+	Args: cobra.ExactArgs(1),
+	RunE: runStorageMigrate,
+}
+
+var storageRebalanceCmd = &cobra.Command{
+	Use:   "rebalance",
+	Short: "Move models to the storage pool their size belongs in",
+	Long: `Move the local models to the storage pool configured for their size in
+storage.pools, for example after adding a pool or changing its limits.
+Models stay in their pool while its limits take them, others go to the pool
+with the most free space among those that take them, or back to the models
+directory if none does.
+
+Models are moved by the daemon, their torrents stop seeding while the files
+move. Models imported in place or downloaded with --output stay where they
+are.
+
+Examples:
+  silmaril storage rebalance --dry-run   # List the moves
+  silmaril storage rebalance             # Move the models`,
+	Args: cobra.NoArgs,
+	RunE: runStorageRebalance,
+}
+
+func init() {
+	rootCmd.AddCommand(storageCmd)
+	storageCmd.AddCommand(storageMigrateCmd)
+	storageCmd.AddCommand(storageRebalanceCmd)
+	storageRebalanceCmd.Flags().Bool("dry-run", false, "List the moves without moving any model")
+}
+
+func runStorageMigrate(cmd *cobra.Command, args []string) error {
+	target, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid path %s: %w", args[0], err)
+	}
+	from, err := filepath.Abs(storage.GetBaseDir())
+	if err != nil {
+		return fmt.Errorf("invalid base directory: %w", err)
+	}
+
+	// Moving the files of a running daemon would pull them from under it
+	if pid, _ := daemon.RunningPID(daemon.DefaultPIDFilePath()); pid > 0 {
+		return fmt.Errorf("the daemon is running (PID %d), stop it with 'silmaril daemon stop' first", pid)
+	}
+
+	// A migration interrupted after the config file was updated is resumed
+	// from the directory it started in
+	pending, err := storage.ReadMigration(target)
+	if err != nil {
+		return err
+	}
+	if pending != nil {
+		from = pending.From
+		fmt.Printf("Resuming the migration from %s to %s\n", from, target)
+	} else if from == target {
+		return fmt.Errorf("%s is already the base directory", target)
+	} else {
+		fmt.Printf("Migrating %s to %s\n", from, target)
+	}
+
+	err = storage.MigrateBaseDir(from, target, func(p storage.MigrateProgress) {
+		fmt.Printf("\r  %d/%d files, %.2f/%.2f GB", p.Files, p.TotalFiles,
+			float64(p.Bytes)/(1024*1024*1024), float64(p.TotalBytes)/(1024*1024*1024))
+	})
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("migration interrupted: %w\nRun 'silmaril storage migrate %s' again to resume it", err, target)
+	}
+
+	// Paths recorded while the data was in the old directory
+	dbDir, _ := storage.RelocatePath(config.Get().Storage.DBDir, from, target)
+	relocated, err := daemon.RelocateState(filepath.Join(target, "daemon"), dbDir, from, target)
+	if err != nil {
+		return fmt.Errorf("failed to update the daemon's state: %w", err)
+	}
+	configPath, err := config.MoveBaseDir(from, target)
+	if err != nil {
+		return fmt.Errorf("failed to update the config file: %w", err)
+	}
+
+	m, err := storage.ReadMigration(target)
+	if err != nil {
+		return err
+	}
+	if m != nil {
+		if err := storage.FinishMigration(m); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✓ Moved the data to %s\n", target)
+	fmt.Printf("  Updated %d paths in the daemon's state\n", relocated)
+	fmt.Printf("  Set the base directory in %s\n", configPath)
+	if config.HomeFromEnv() && os.Getenv("SILMARIL_HOME") != target {
+		fmt.Printf("\nSILMARIL_HOME takes precedence over the config file, set SILMARIL_HOME=%s\n", target)
+	}
+	return nil
+}
+
+func runStorageRebalance(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	report, err := newDaemonClient().RebalanceStorage(dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to rebalance storage: %w", err)
+	}
+
+	pools, _ := report["pools"].([]interface{})
+	fmt.Println("Storage pools:")
+	for _, raw := range pools {
+		pool, _ := raw.(map[string]interface{})
+		name, _ := pool["name"].(string)
+		path, _ := pool["path"].(string)
+		models, _ := pool["models"].(float64)
+		size, _ := pool["size"].(float64)
+		free, _ := pool["free"].(float64)
+		freeText := "unknown"
+		if free >= 0 {
+			freeText = fmt.Sprintf("%.2f GB", free/(1024*1024*1024))
+		}
+		fmt.Printf("  %-12s %s\n", name, path)
+		fmt.Printf("    %d models, %.2f GB, %s free\n", int(models), size/(1024*1024*1024), freeText)
+	}
+	fmt.Println()
+
+	moves, _ := report["moves"].([]interface{})
+	if len(moves) == 0 {
+		fmt.Println("Every model is in the pool it belongs in.")
+		return nil
+	}
+
+	failed := 0
+	for _, raw := range moves {
+		move, _ := raw.(map[string]interface{})
+		model, _ := move["model"].(string)
+		from, _ := move["from"].(string)
+		to, _ := move["to"].(string)
+		size, _ := move["size"].(float64)
+		fmt.Printf("  %s (%.2f GB): %s -> %s\n", model, size/(1024*1024*1024), from, to)
+		if msg, _ := move["error"].(string); msg != "" {
+			fmt.Printf("    failed: %s\n", msg)
+			failed++
+		}
+	}
+	fmt.Println()
+
+	moved, _ := report["moved"].(float64)
+	if dryRun {
+		fmt.Printf("Would move %d models. Run without --dry-run to move them.\n", len(moves))
+	} else {
+		fmt.Printf("✓ Moved %d models, %.2f GB\n", len(moves)-failed, moved/(1024*1024*1024))
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to move %d models", failed)
+	}
+	return nil
+}
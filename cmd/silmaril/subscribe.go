@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/spf13/cobra"
+)
+
+var subscribeCmd = &cobra.Command{
+	Use:   "subscribe <pubkey|model-name>",
+	Short: "Follow the model catalog of a publisher or keep a model up to date",
+	Long: `Subscribe to the catalog of a publisher identified by their public key,
+or to a model to keep it up to date.
+
+Every Silmaril node signs its own catalog with a publisher key and publishes
+it on the DHT. Models from subscribed publishers are included in discover
+results alongside the public catalog and show who published them.
+
+When subscribed to a model, the daemon checks the catalog for new versions
+every catalog refresh and downloads them. A model that is not local yet is
+downloaded right away. Updates download next to the current version, which
+stays usable until the update is complete and replaces it. The old version
+is kept as <model-name>@<version> unless --auto-remove is given.
+
+Examples:
+  silmaril subscribe meta-llama/Llama-3.1-8B
+  silmaril subscribe meta-llama/Llama-3.1-8B --auto-remove
+  silmaril subscribe <pubkey> --name acme
+
+Show your own publisher key and subscriptions with: silmaril subscriptions list`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSubscribe,
+}
+
+var unsubscribeCmd = &cobra.Command{
+	Use:   "unsubscribe <pubkey|model-name>",
+	Short: "Stop following a publisher or updating a model",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runUnsubscribe,
+}
+
+var subscriptionsCmd = &cobra.Command{
+	Use:   "subscriptions",
+	Short: "List subscribed publishers and models and your own publisher key",
+	RunE:  runSubscriptions,
+}
+
+var subscriptionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List subscribed publishers and models and your own publisher key",
+	RunE:  runSubscriptions,
+}
+
+func init() {
+	rootCmd.AddCommand(subscribeCmd, unsubscribeCmd, subscriptionsCmd)
+	subscriptionsCmd.AddCommand(subscriptionsListCmd)
+	subscribeCmd.Flags().String("name", "", "Friendly name for the publisher")
+	subscribeCmd.Flags().Bool("auto-remove", false, "Delete the old version of a model after an update")
+}
+
+// isPublisherKey tells publisher keys apart from model names
+func isPublisherKey(arg string) bool {
+	_, err := discovery.ParsePublisherKey(arg)
+	return err == nil
+}
+
+func runSubscribe(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+
+	if !isPublisherKey(args[0]) {
+		modelName, err := modelname.Canonical(args[0])
+		if err != nil {
+			return err
+		}
+		autoRemove, _ := cmd.Flags().GetBool("auto-remove")
+		if _, err := apiClient.SubscribeModel(modelName, autoRemove); err != nil {
+			return fmt.Errorf("failed to subscribe: %w", err)
+		}
+
+		fmt.Printf("✓ Subscribed to model %s\n", modelName)
+		if autoRemove {
+			fmt.Println("New versions will be downloaded and replace the current one.")
+		} else {
+			fmt.Println("New versions will be downloaded, old versions are kept as <model-name>@<version>.")
+		}
+		fmt.Println("Follow updates with 'silmaril subscriptions list'.")
+		return nil
+	}
+
+	name, _ := cmd.Flags().GetString("name")
+	if _, err := apiClient.Subscribe(args[0], name); err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	fmt.Printf("✓ Subscribed to publisher %s\n", args[0])
+	fmt.Println("Their models will appear in 'silmaril discover' once the catalog is fetched.")
+	return nil
+}
+
+func runUnsubscribe(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+
+	if !isPublisherKey(args[0]) {
+		if err := apiClient.UnsubscribeModel(args[0]); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Unsubscribed from model %s\n", args[0])
+		return nil
+	}
+
+	if err := apiClient.Unsubscribe(args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Unsubscribed from publisher %s\n", args[0])
+	return nil
+}
+
+func runSubscriptions(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+
+	if publisher, err := apiClient.GetPublisher(); err == nil {
+		fmt.Printf("Your publisher key: %s\n", publisher["public_key"])
+		if models, ok := publisher["models"].(float64); ok {
+			fmt.Printf("Models in your catalog: %.0f\n", models)
+		}
+		fmt.Println()
+	}
+
+	subscriptions, err := apiClient.ListSubscriptions()
+	if err != nil {
+		return fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+
+	modelSubscriptions, err := apiClient.ListModelSubscriptions()
+	if err != nil {
+		return fmt.Errorf("failed to list model subscriptions: %w", err)
+	}
+
+	if len(subscriptions) == 0 && len(modelSubscriptions) == 0 {
+		fmt.Println("No subscriptions.")
+		fmt.Println("\nTo follow a publisher, use: silmaril subscribe <pubkey>")
+		fmt.Println("To keep a model up to date, use: silmaril subscribe <model-name>")
+		return nil
+	}
+
+	if len(modelSubscriptions) > 0 {
+		printModelSubscriptions(modelSubscriptions)
+		if len(subscriptions) > 0 {
+			fmt.Println()
+		}
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	fmt.Printf("Subscribed to %d publisher(s):\n\n", len(subscriptions))
+	for _, sub := range subscriptions {
+		key, _ := sub["public_key"].(string)
+		fmt.Printf("  %s", key)
+		if name, ok := sub["name"].(string); ok && name != "" {
+			fmt.Printf(" (%s)", name)
+		}
+		fmt.Println()
+
+		if previous, ok := sub["previous_keys"].([]interface{}); ok && len(previous) > 0 {
+			fmt.Printf("    Rotated from: %v\n", previous[len(previous)-1])
+		}
+		if loaded, ok := sub["loaded"].(bool); ok && loaded {
+			models, _ := sub["models"].(float64)
+			fmt.Printf("    Models: %.0f\n", models)
+		} else {
+			fmt.Println("    Catalog not fetched yet")
+		}
+	}
+
+	return nil
+}
+
+func printModelSubscriptions(subscriptions []map[string]interface{}) {
+	fmt.Printf("Subscribed to %d model(s):\n\n", len(subscriptions))
+	for _, sub := range subscriptions {
+		model, _ := sub["model"].(string)
+		fmt.Printf("  %s", model)
+		if autoRemove, ok := sub["auto_remove"].(bool); ok && autoRemove {
+			fmt.Printf(" (auto-remove)")
+		}
+		fmt.Println()
+
+		version, _ := sub["version"].(string)
+		infoHash, _ := sub["info_hash"].(string)
+		switch {
+		case version != "":
+			fmt.Printf("    Version: %s\n", version)
+		case infoHash != "":
+			fmt.Printf("    Infohash: %s\n", infoHash)
+		}
+		if pending, ok := sub["pending_info_hash"].(string); ok && pending != "" {
+			pendingVersion, _ := sub["pending_version"].(string)
+			if pendingVersion == "" {
+				pendingVersion = pending
+			}
+			fmt.Printf("    Downloading update: %s\n", pendingVersion)
+		}
+		if checked := formatSubscriptionTime(sub["last_checked"]); checked != "" {
+			fmt.Printf("    Last checked: %s\n", checked)
+		} else {
+			fmt.Println("    Not checked yet")
+		}
+		if updated := formatSubscriptionTime(sub["last_updated"]); updated != "" {
+			fmt.Printf("    Last updated: %s\n", updated)
+		}
+		if lastError, ok := sub["last_error"].(string); ok && lastError != "" {
+			fmt.Printf("    Error: %s\n", lastError)
+		}
+	}
+}
+
+func formatSubscriptionTime(value interface{}) string {
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return ""
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return s
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var swarmCmd = &cobra.Command{
+	Use:   "swarm",
+	Short: "Show how well the pieces of local models are spread among peers",
+	Long: `Show the piece availability of every model the daemon downloads or seeds,
+measured among the peers it is connected to.
+
+Rare pieces are held by at most one peer. Unique pieces are held by this node
+and no connected peer, so the swarm loses them if we stop seeding. With
+torrent.swarm_coordinator enabled the daemon downloads rare pieces first and
+keeps seeding models holding unique pieces past their seeding limits:
+
+  silmaril config set torrent.swarm_coordinator true`,
+	Args: cobra.NoArgs,
+	RunE: runSwarm,
+}
+
+func init() {
+	rootCmd.AddCommand(swarmCmd)
+}
+
+func runSwarm(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	models, err := apiClient.GetSwarmAvailability()
+	if err != nil {
+		return fmt.Errorf("failed to get swarm availability: %w", err)
+	}
+
+	if len(models) == 0 {
+		fmt.Println("No swarms measured yet.")
+		return nil
+	}
+
+	for _, model := range models {
+		name, _ := model["model"].(string)
+		fmt.Printf("  %s\n", name)
+		printAvailability(model)
+	}
+	return nil
+}
+
+// printAvailability prints the piece availability of a swarm
+func printAvailability(availability map[string]interface{}) {
+	peers, _ := availability["peers"].(float64)
+	copies, _ := availability["distributed_copies"].(float64)
+	rare, _ := availability["rare_pieces"].(float64)
+	pieces, _ := availability["pieces"].(float64)
+
+	fmt.Printf("    Swarm: %.0f peers | %.2f copies | %.0f of %.0f pieces rare", peers, copies, rare, pieces)
+	if unique, _ := availability["unique_pieces"].(float64); unique > 0 {
+		fmt.Printf(" | %.0f only we have", unique)
+	}
+	if boosted, _ := availability["boosted_pieces"].(float64); boosted > 0 {
+		fmt.Printf(" | %.0f downloading first", boosted)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// File in the keys directory holding the API token the CLI sends
+const apiTokenFile = "api_token"
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens of the daemon",
+	Long: `Manage the tokens clients authenticate to the daemon API with.
+
+Until the first token is created the API is open to anyone who can reach
+it. Once a token exists every request needs one, with a scope allowing it:
+
+  read      list, discover, inspect and status
+  operator  also download, share, publish and manage transfers
+  admin     also shut down, change the config, remove models, collect
+            garbage and manage tokens
+
+The first token must be an admin token. The CLI sends SILMARIL_API_TOKEN, or
+the token saved with 'silmaril token create --save'.`,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create an API token",
+	Long: `Create a named API token with a scope. The token is shown once; the
+daemon only keeps its hash. With --save the CLI uses it from now on.
+
+Examples:
+  silmaril token create admin --scope admin --save
+  silmaril token create grafana --scope read
+  silmaril token create ci --scope operator`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTokenCreate,
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API tokens",
+	Args:  cobra.NoArgs,
+	RunE:  runTokenList,
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke [name]",
+	Short: "Revoke an API token",
+	Long: `Revoke an API token; requests with it fail from now on. The last admin
+token can only be revoked after all other tokens, which turns
+authentication off again.
+
+Examples:
+  silmaril token revoke grafana`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTokenRevoke,
+}
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+
+	tokenCreateCmd.Flags().String("scope", "", "Scope of the token: read, operator or admin (required)")
+	tokenCreateCmd.Flags().Bool("save", false, "Save the token for the CLI to use")
+	tokenCreateCmd.MarkFlagRequired("scope")
+}
+
+// apiTokenPath returns the file the CLI keeps its API token in
+func apiTokenPath() string {
+	return filepath.Join(config.Get().Security.KeysDir, apiTokenFile)
+}
+
+// apiToken returns the API token the CLI sends, SILMARIL_API_TOKEN or the
+// saved one, empty if there is none
+func apiToken() string {
+	if token := os.Getenv("SILMARIL_API_TOKEN"); token != "" {
+		return token
+	}
+	data, err := os.ReadFile(apiTokenPath())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// saveAPIToken saves the API token the CLI sends, readable only by the user
+func saveAPIToken(token string) error {
+	path := apiTokenPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create keys directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	return nil
+}
+
+func runTokenCreate(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return err
+	}
+	scope, _ := cmd.Flags().GetString("scope")
+	save, _ := cmd.Flags().GetBool("save")
+
+	token, err := newDaemonClient().CreateToken(args[0], scope)
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+
+	fmt.Printf("✅ Created %s token %s\n", scope, args[0])
+	fmt.Printf("  %s\n", token)
+	if save {
+		if err := saveAPIToken(token); err != nil {
+			return err
+		}
+		fmt.Printf("Saved to %s for the CLI to use.\n", apiTokenPath())
+	} else {
+		fmt.Println("Copy it now, it can't be shown again. Clients send it as SILMARIL_API_TOKEN")
+		fmt.Println("or in an 'Authorization: Bearer' header.")
+	}
+	return nil
+}
+
+func runTokenList(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return err
+	}
+	tokens, err := newDaemonClient().ListTokens()
+	if err != nil {
+		return fmt.Errorf("failed to list tokens: %w", err)
+	}
+	if len(tokens) == 0 {
+		fmt.Println("No API tokens, the API is open to anyone who can reach it.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCOPE\tCREATED\tLAST USED")
+	for _, token := range tokens {
+		lastUsed := "never"
+		if token.LastUsed != nil {
+			lastUsed = token.LastUsed.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", token.Name, token.Scope, token.CreatedAt.Local().Format(time.DateOnly), lastUsed)
+	}
+	return w.Flush()
+}
+
+func runTokenRevoke(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return err
+	}
+	if err := newDaemonClient().RevokeToken(args[0]); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	fmt.Printf("✅ Revoked API token %s\n", args[0])
+	return nil
+}
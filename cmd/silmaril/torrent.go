@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/spf13/cobra"
+)
+
+var torrentCmd = &cobra.Command{
+	Use:   "torrent",
+	Short: "Move models between networks with torrent files",
+}
+
+var torrentExportCmd = &cobra.Command{
+	Use:   "export <model-name>",
+	Short: "Write the torrent file of a local model",
+	Long: `Write the torrent file of a local model, so the model can be carried to a
+network that can't reach its peers. Copy the torrent file together with the
+model directory, then run 'silmaril torrent import' on the other side.
+
+Examples:
+  silmaril torrent export meta-llama/Llama-3.1-8B
+  silmaril torrent export meta-llama/Llama-3.1-8B -o /mnt/usb/llama.torrent`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTorrentExport,
+}
+
+var torrentImportCmd = &cobra.Command{
+	Use:   "import <torrent-file>",
+	Short: "Add a model from a torrent file",
+	Long: `Add a model from a torrent file written by 'silmaril torrent export'.
+
+If the model files were copied into the models directory under the model
+name, they are registered as a local model, and seeded right away with
+--seed. Missing files are downloaded from peers instead.
+
+Examples:
+  silmaril torrent import llama.torrent --name meta-llama/Llama-3.1-8B
+  silmaril torrent import llama.torrent --name meta-llama/Llama-3.1-8B --seed`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTorrentImport,
+}
+
+func init() {
+	rootCmd.AddCommand(torrentCmd)
+	torrentCmd.AddCommand(torrentExportCmd)
+	torrentCmd.AddCommand(torrentImportCmd)
+
+	torrentExportCmd.Flags().StringP("output", "o", "", "File to write (default <model>.torrent in the current directory)")
+	torrentImportCmd.Flags().String("name", "", "Model name (default the name in the torrent)")
+	torrentImportCmd.Flags().Bool("seed", false, "Seed the model right away")
+}
+
+func runTorrentExport(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	modelName := args[0]
+	output, _ := cmd.Flags().GetString("output")
+	if output == "" {
+		output = path.Base(modelName) + ".torrent"
+	}
+
+	data, err := newDaemonClient().ExportTorrent(modelName)
+	if err != nil {
+		return fmt.Errorf("failed to export torrent: %w", err)
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write torrent file: %w", err)
+	}
+
+	fmt.Printf("Wrote the torrent of %s to %s\n", modelName, output)
+	fmt.Println("Copy it with the model directory, then run 'silmaril torrent import' on the other side")
+	return nil
+}
+
+func runTorrentImport(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read torrent file: %w", err)
+	}
+	name, _ := cmd.Flags().GetString("name")
+	seed, _ := cmd.Flags().GetBool("seed")
+
+	result, err := newDaemonClient().ImportTorrent(data, name, seed)
+	if err != nil {
+		return fmt.Errorf("failed to import torrent: %w", err)
+	}
+
+	modelName, _ := result["name"].(string)
+	infoHash, _ := result["info_hash"].(string)
+	files, _ := result["files"].(float64)
+	size, _ := result["size"].(float64)
+	status, _ := result["status"].(string)
+	transferID, _ := result["transfer_id"].(string)
+
+	fmt.Printf("Imported %s\n", modelName)
+	fmt.Printf("  InfoHash: %s\n", infoHash)
+	fmt.Printf("  Files:    %.0f (%.2f GB)\n", files, size/(1024*1024*1024))
+	switch status {
+	case "seeding":
+		fmt.Printf("Seeding (transfer %s)\n", transferID)
+	case "downloading":
+		fmt.Printf("Model files are missing, downloading them from peers (transfer %s)\n", transferID)
+		fmt.Println("Follow it with 'silmaril transfers'")
+	default:
+		fmt.Printf("Registered as a local model, share it with 'silmaril share %s'\n", modelName)
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/api/client"
+	"github.com/spf13/cobra"
+)
+
+var transfersCmd = &cobra.Command{
+	Use:   "transfers [transfer-id|model-name]",
+	Short: "List downloads and seeded models",
+	Long: `Show the downloads and seeds of the daemon. Seeds show their upload ratio
+and seeding time against the seeding policy, and why seeding stopped once a
+limit was reached.
+
+Given a transfer or model, show its rates smoothed over the last seconds,
+the time left, the recent rate history and what each connected peer sends.
+Downloads also fetching from mirrors or IPFS gateways show the share of the
+data each source sent.
+
+Examples:
+  silmaril transfers                  # All transfers
+  silmaril transfers --status active  # Only running transfers
+  silmaril transfers org/model        # Rates and peers of a transfer`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTransfers,
+}
+
+func init() {
+	rootCmd.AddCommand(transfersCmd)
+	transfersCmd.Flags().String("status", "", "Only show transfers with this status (active, paused, completed, failed, cancelled)")
+}
+
+func runTransfers(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	status, _ := cmd.Flags().GetString("status")
+
+	apiClient := newDaemonClient()
+	if len(args) == 1 {
+		return showTransferStats(apiClient, args[0])
+	}
+
+	transfers, err := apiClient.ListTransfers(status)
+	if err != nil {
+		return fmt.Errorf("failed to list transfers: %w", err)
+	}
+
+	if len(transfers) == 0 {
+		fmt.Println("No transfers.")
+		return nil
+	}
+
+	sort.Slice(transfers, func(i, j int) bool {
+		nameI, _ := transfers[i]["model_name"].(string)
+		nameJ, _ := transfers[j]["model_name"].(string)
+		return nameI < nameJ
+	})
+
+	for _, transfer := range transfers {
+		displayTransfer(transfer)
+	}
+	fmt.Printf("Total transfers: %d\n", len(transfers))
+	return nil
+}
+
+func displayTransfer(transfer map[string]interface{}) {
+	name, _ := transfer["model_name"].(string)
+	transferType, _ := transfer["type"].(string)
+	status, _ := transfer["status"].(string)
+	fmt.Printf("  %s (%s, %s)\n", name, transferType, status)
+
+	progress, _ := transfer["progress"].(float64)
+	peers, _ := transfer["peers"].(float64)
+	fmt.Printf("    Progress: %.0f%% | Peers: %.0f", progress, peers)
+	if rate, ok := transfer["upload_rate"].(float64); ok && rate > 0 {
+		fmt.Printf(" | Up: %.2f MB/s", rate/(1024*1024))
+	}
+	if rate, ok := transfer["download_rate"].(float64); ok && rate > 0 {
+		fmt.Printf(" | Down: %.2f MB/s", rate/(1024*1024))
+	}
+	if eta, ok := transfer["eta"].(float64); ok && eta > 0 {
+		fmt.Printf(" | ETA: %v", time.Duration(eta).Round(time.Second))
+	}
+	fmt.Println()
+	if files, ok := transfer["files"].([]interface{}); ok && len(files) > 0 {
+		patterns := make([]string, 0, len(files))
+		for _, f := range files {
+			if pattern, ok := f.(string); ok {
+				patterns = append(patterns, pattern)
+			}
+		}
+		fmt.Printf("    Files: %s\n", strings.Join(patterns, ", "))
+	}
+
+	// Seeding progress against the seeding policy
+	if limits, ok := transfer["seeding_limits"].(map[string]interface{}); ok {
+		ratio, _ := transfer["ratio"].(float64)
+		seconds, _ := transfer["seeding_seconds"].(float64)
+		seedRatio, _ := limits["seed_ratio"].(float64)
+		seedTime, _ := limits["seed_time"].(float64)
+		idle, _ := limits["stop_if_no_peers_for"].(float64)
+
+		fmt.Printf("    Ratio: %.2f", ratio)
+		if seedRatio > 0 {
+			fmt.Printf(" / %.2f", seedRatio)
+		}
+		fmt.Printf(" | Seeded: %v", time.Duration(seconds)*time.Second)
+		if seedTime > 0 {
+			fmt.Printf(" / %v", time.Duration(seedTime)*time.Second)
+		}
+		if idle > 0 {
+			fmt.Printf(" | Stops after %v without peers", time.Duration(idle)*time.Second)
+		}
+		fmt.Println()
+	}
+
+	if availability, ok := transfer["availability"].(map[string]interface{}); ok {
+		printAvailability(availability)
+	}
+	if mode, ok := transfer["seed_mode"].(string); ok && mode == "initial" {
+		copies, _ := transfer["distributed_copies"].(float64)
+		fmt.Printf("    Initial seeding: peers hold %.2f copies between them\n", copies)
+	}
+	if reason, ok := transfer["stop_reason"].(string); ok && reason != "" {
+		fmt.Printf("    Seeding stopped: %s\n", reason)
+	}
+	if errMsg, ok := transfer["error"].(string); ok && errMsg != "" {
+		fmt.Printf("    Error: %s\n", errMsg)
+	}
+	fmt.Println()
+}
+
+// showTransferStats prints the live rates of the transfer matching a transfer
+// ID or model name, preferring a running transfer of the model
+func showTransferStats(apiClient *client.Client, target string) error {
+	transfers, err := apiClient.ListTransfers("")
+	if err != nil {
+		return fmt.Errorf("failed to list transfers: %w", err)
+	}
+
+	var transfer map[string]interface{}
+	for _, t := range transfers {
+		id, _ := t["id"].(string)
+		name, _ := t["model_name"].(string)
+		status, _ := t["status"].(string)
+		if id == target || (name == target && (transfer == nil || status == "active")) {
+			transfer = t
+		}
+	}
+	if transfer == nil {
+		return fmt.Errorf("no transfer found for %s", target)
+	}
+
+	id, _ := transfer["id"].(string)
+	stats, err := apiClient.GetTransferStats(id)
+	if err != nil {
+		return fmt.Errorf("failed to get transfer stats: %w", err)
+	}
+
+	name, _ := transfer["model_name"].(string)
+	transferType, _ := transfer["type"].(string)
+	status, _ := transfer["status"].(string)
+	fmt.Printf("  %s (%s, %s)\n", name, transferType, status)
+	fmt.Printf("    ID: %s\n", id)
+
+	down, _ := stats["download_rate"].(float64)
+	up, _ := stats["upload_rate"].(float64)
+	avgDown, _ := stats["average_download_rate"].(float64)
+	avgUp, _ := stats["average_upload_rate"].(float64)
+	fmt.Printf("    Down: %.2f MB/s (average %.2f MB/s) | Up: %.2f MB/s (average %.2f MB/s)\n",
+		down/(1024*1024), avgDown/(1024*1024), up/(1024*1024), avgUp/(1024*1024))
+	if remaining, _ := stats["bytes_remaining"].(float64); remaining > 0 {
+		fmt.Printf("    Remaining: %.2f GB", remaining/(1024*1024*1024))
+		if eta, ok := stats["eta"].(float64); ok && eta > 0 {
+			fmt.Printf(" | ETA: %v", time.Duration(eta).Round(time.Second))
+		}
+		fmt.Println()
+	}
+
+	// Rate history as a sparkline, downloads for downloads and uploads
+	// for seeds
+	key := "upload_rate"
+	if transferType == "download" {
+		key = "download_rate"
+	}
+	history, _ := stats["history"].([]interface{})
+	rates := make([]float64, 0, len(history))
+	for _, h := range history {
+		if sample, ok := h.(map[string]interface{}); ok {
+			rate, _ := sample[key].(float64)
+			rates = append(rates, rate)
+		}
+	}
+	if len(rates) > 0 {
+		interval, _ := stats["sample_interval"].(float64)
+		span := time.Duration(interval) * time.Duration(len(rates))
+		fmt.Printf("    Last %v: %s\n", span.Round(time.Second), sparkline(rates, 60))
+	}
+
+	// Downloads also fetching from mirrors or IPFS gateways
+	sources, _ := stats["sources"].([]interface{})
+	if len(sources) > 1 {
+		fmt.Println("    Sources:")
+		for _, s := range sources {
+			source, ok := s.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			kind, _ := source["kind"].(string)
+			url, _ := source["url"].(string)
+			received, _ := source["bytes_downloaded"].(float64)
+			share, _ := source["share"].(float64)
+			fmt.Printf("      %-7s %10s %4.0f%%  %s\n", kind, formatProgressBytes(int64(received)), share, url)
+		}
+	}
+
+	peers, _ := stats["peers"].([]interface{})
+	fmt.Printf("    Peers: %d\n", len(peers))
+	for _, p := range peers {
+		peer, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		addr, _ := peer["addr"].(string)
+		peerClient, _ := peer["client"].(string)
+		rate, _ := peer["download_rate"].(float64)
+		progress, _ := peer["progress"].(float64)
+		fmt.Printf("      %-40s %6.2f MB/s %4.0f%%", addr, rate/(1024*1024), progress)
+		if peerClient != "" {
+			fmt.Printf("  %s", peerClient)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// sparkline draws values as a line of block characters scaled to the
+// largest value, averaging neighbouring values to fit into width
+func sparkline(values []float64, width int) string {
+	if len(values) > width {
+		buckets := make([]float64, width)
+		for i := range buckets {
+			from, to := i*len(values)/width, (i+1)*len(values)/width
+			var sum float64
+			for _, v := range values[from:to] {
+				sum += v
+			}
+			buckets[i] = sum / float64(to-from)
+		}
+		values = buckets
+	}
+
+	var max float64
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	blocks := []rune("▁▂▃▄▅▆▇█")
+	line := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if max > 0 {
+			level = int(v / max * float64(len(blocks)-1))
+		}
+		line[i] = blocks[level]
+	}
+	return string(line)
+}
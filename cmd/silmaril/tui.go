@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Watch and control the daemon in a terminal UI",
+	Long: `Show the local models, transfers, the peers of a transfer and the DHT
+status of the daemon in the terminal, refreshed every few seconds.
+
+Keys:
+  tab, 1-4   switch between Models, Transfers, Peers and Discover
+  ↑↓, j k    select
+  p r c      pause, resume or cancel the selected transfer
+  enter      show the peers of the selected transfer
+  /          search the network (Discover)
+  enter, a   download the selected model, a accepts its license
+  q          quit
+
+Examples:
+  silmaril tui
+  silmaril tui --refresh 5s`,
+	Args: cobra.NoArgs,
+	RunE: runTUI,
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+	tuiCmd.Flags().Duration("refresh", 2*time.Second, "how often to refresh the screen")
+}
+
+func runTUI(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	refresh, _ := cmd.Flags().GetDuration("refresh")
+	if refresh <= 0 {
+		return fmt.Errorf("invalid refresh interval %v", refresh)
+	}
+	return tui.Run(newDaemonClient(), os.Stdin, os.Stdout, refresh)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var unpublishCmd = &cobra.Command{
+	Use:   "unpublish <model-name>",
+	Short: "Withdraw a model you published from discovery",
+	Long: `Withdraw a model you published from the P2P catalog.
+
+The daemon adds a tombstone signed with your publisher key to the catalog, so
+peers remove the entry even if they still hold an older copy of the catalog.
+Only the node that published a model can unpublish it. The local files are
+kept and the model keeps seeding to peers that already know its infohash.
+
+Catalog entries that are not re-announced by their publisher expire after a
+week, so models that are no longer shared disappear on their own.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runUnpublish,
+}
+
+func init() {
+	rootCmd.AddCommand(unpublishCmd)
+}
+
+func runUnpublish(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	apiClient := newDaemonClient()
+	if err := apiClient.UnpublishModel(args[0]); err != nil {
+		return fmt.Errorf("failed to unpublish model: %w", err)
+	}
+
+	fmt.Printf("✓ Unpublished %s\n", args[0])
+	return nil
+}
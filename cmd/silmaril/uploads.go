@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var uploadsCmd = &cobra.Command{
+	Use:   "uploads",
+	Short: "Show how the upload bandwidth is divided between models",
+	Long: `Show the upload rate of every model the daemon seeds or downloads and the
+share of the upload bandwidth the daemon gave it.
+
+With network.upload_rate_limit set, the limit is divided between the models
+with peers by their seed priority, so one busy swarm can't starve the others.
+A model uploading less than its share leaves the rest to the others. Models
+are seeded at normal priority, models pinned for the network at low:
+
+  silmaril share org/model --seed-priority high
+  silmaril config set network.upload_rate_limit 10485760
+
+network.upload_fairness: false leaves the division to the torrent client.`,
+	Args: cobra.NoArgs,
+	RunE: runUploads,
+}
+
+func init() {
+	rootCmd.AddCommand(uploadsCmd)
+}
+
+func runUploads(cmd *cobra.Command, args []string) error {
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	schedule, err := newDaemonClient().GetUploadSchedule()
+	if err != nil {
+		return fmt.Errorf("failed to get upload schedule: %w", err)
+	}
+
+	switch {
+	case schedule.Enabled:
+		fmt.Printf("Dividing %s/s of upload bandwidth by seed priority\n\n", formatProgressBytes(schedule.Total))
+	case schedule.Total > 0:
+		fmt.Printf("Uploading at most %s/s, not divided between models (network.upload_fairness is off)\n\n", formatProgressBytes(schedule.Total))
+	default:
+		fmt.Println("Upload bandwidth is unlimited, set network.upload_rate_limit to divide it between models")
+		fmt.Println()
+	}
+	if len(schedule.Shares) == 0 {
+		fmt.Println("No models seeding or downloading.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tPRIORITY\tPEERS\tUPLOAD\tSHARE")
+	for _, share := range schedule.Shares {
+		limit := "unlimited"
+		if share.Limit > 0 {
+			limit = formatProgressBytes(share.Limit) + "/s"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s/s\t%s\n", share.Model, share.Priority, share.Peers, formatProgressBytes(share.UploadRate), limit)
+	}
+	return w.Flush()
+}
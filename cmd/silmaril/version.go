@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/api/apierror"
+	"github.com/silmaril/silmaril/internal/api/apiversion"
+	"github.com/silmaril/silmaril/internal/api/client"
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/proxy"
+	"github.com/silmaril/silmaril/internal/version"
+	"github.com/spf13/cobra"
+)
+
+// How long looking up the latest release may take
+const releaseCheckTimeout = 15 * time.Second
+
+// Set once the daemon version was checked, commands reaching the daemon
+// more than once check it the first time only
+var daemonVersionChecked bool
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Show the version of the CLI and the daemon",
+	Long: `Show the version of the CLI and of the running daemon, and whether they
+work together.
+
+With --check the latest release is looked up on GitHub, through the git
+proxy if one is configured, and compared with the CLI.
+
+Examples:
+  silmaril version
+  silmaril version --check`,
+	Args: cobra.NoArgs,
+	RunE: runVersion,
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+	versionCmd.Flags().Bool("check", false, "Check whether a newer release exists")
+	rootCmd.PersistentFlags().Bool("skip-version-check", false, "Use the daemon even if its version is incompatible with the CLI")
+}
+
+func runVersion(cmd *cobra.Command, args []string) error {
+	cli := version.Get()
+	fmt.Printf("CLI:    %s, %s\n", cli, cli.GoVersion)
+
+	caps, err := newDaemonClient().Capabilities()
+	switch {
+	case err == nil:
+		fmt.Printf("Daemon: %s, API %s\n", daemonVersion(caps), strings.Join(caps.APIVersions, ", "))
+		if problem := daemonCompatibility(cli.Version, caps); problem != "" {
+			fmt.Printf("  %s\n", problem)
+		}
+	case errors.Is(err, &apierror.Error{Code: apierror.CodeNotFound}):
+		fmt.Println("Daemon: older than the CLI, from before version negotiation")
+	default:
+		fmt.Println("Daemon: not running")
+	}
+
+	if check, _ := cmd.Flags().GetBool("check"); check {
+		return checkLatestRelease(cli.Version)
+	}
+	return nil
+}
+
+// checkLatestRelease reports whether a newer release than current exists
+func checkLatestRelease(current string) error {
+	p, err := proxy.FromConfig(config.Get(), proxy.Git)
+	if err != nil {
+		return err
+	}
+	url := os.Getenv("SILMARIL_RELEASES_URL")
+	if url == "" {
+		url = version.ReleasesURL
+	}
+	release, err := version.LatestRelease(p.HTTPClient(releaseCheckTimeout), url)
+	if err != nil {
+		return err
+	}
+
+	_, semantic := version.Parse(current)
+	switch {
+	case release.Newer(current):
+		fmt.Printf("\nA newer release is available: %s (published %s)\n", release.Tag, release.PublishedAt.Format("2006-01-02"))
+		fmt.Printf("  %s\n", release.URL)
+	case !semantic:
+		fmt.Printf("\nLatest release: %s, this is a %s build\n", release.Tag, current)
+	default:
+		fmt.Printf("\nUp to date, the latest release is %s\n", release.Tag)
+	}
+	return nil
+}
+
+// daemonVersion formats the version of a daemon from its capabilities
+func daemonVersion(caps *apiversion.Capabilities) string {
+	return version.Info{Version: caps.Version, Commit: caps.Commit}.String()
+}
+
+// daemonCompatibility describes what keeps the CLI and a daemon from
+// working together, empty if nothing does
+func daemonCompatibility(cli string, caps *apiversion.Capabilities) string {
+	if !caps.SupportsAPI(apiversion.V1) {
+		return fmt.Sprintf("The daemon no longer serves API %s, which this CLI uses. Upgrade the CLI.", apiversion.V1)
+	}
+	// Only the older side can be brought up to date
+	advice := "Restart the daemon with 'silmaril daemon restart' to run this version."
+	a, _ := version.Parse(cli)
+	b, _ := version.Parse(caps.Version)
+	if a.Compare(b) < 0 {
+		advice = "Upgrade the CLI, see 'silmaril version --check'."
+	}
+	switch version.Compatibility(cli, caps.Version) {
+	case version.Incompatible:
+		return fmt.Sprintf("The CLI (%s) and the daemon (%s) are incompatible. %s", cli, caps.Version, advice)
+	case version.MinorSkew:
+		return fmt.Sprintf("The CLI (%s) and the daemon (%s) differ, features of the newer one may be missing. %s", cli, caps.Version, advice)
+	}
+	return ""
+}
+
+// checkDaemonVersion compares the versions of the CLI and the daemon before
+// a command uses it. Versions that differ are warned about; a daemon of
+// another major version, or one no longer serving the API the CLI speaks,
+// is refused unless --skip-version-check is given.
+func checkDaemonVersion(c *client.Client) error {
+	if daemonVersionChecked {
+		return nil
+	}
+	daemonVersionChecked = true
+	if skip, _ := rootCmd.PersistentFlags().GetBool("skip-version-check"); skip {
+		return nil
+	}
+
+	caps, err := c.Capabilities()
+	if errors.Is(err, &apierror.Error{Code: apierror.CodeNotFound}) {
+		fmt.Fprintln(os.Stderr, "Warning: the daemon is older than the CLI. Restart it with 'silmaril daemon restart' to run this version.")
+		return nil
+	}
+	if err != nil {
+		return nil // The command reports a daemon that can't be reached
+	}
+
+	cli := version.Get().Version
+	problem := daemonCompatibility(cli, caps)
+	if problem == "" {
+		return nil
+	}
+	if !caps.SupportsAPI(apiversion.V1) || version.Compatibility(cli, caps.Version) == version.Incompatible {
+		return fmt.Errorf("%s\nUse --skip-version-check to try anyway", problem)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s\n", problem)
+	return nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/api/apiversion"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaemonCompatibility(t *testing.T) {
+	caps := func(v string) *apiversion.Capabilities {
+		return &apiversion.Capabilities{Version: v, APIVersions: []string{"v1", "v2"}}
+	}
+
+	assert.Empty(t, daemonCompatibility("v0.3.0", caps("v0.3.4")))
+	assert.Empty(t, daemonCompatibility("dev", caps("v0.3.4")))
+
+	// The older side is the one to update
+	assert.Contains(t, daemonCompatibility("v0.4.0", caps("v0.3.4")), "silmaril daemon restart")
+	assert.Contains(t, daemonCompatibility("v0.3.0", caps("v0.4.0")), "Upgrade the CLI")
+	assert.Contains(t, daemonCompatibility("v2.0.0", caps("v1.0.0")), "incompatible")
+
+	assert.Contains(t, daemonCompatibility("v1.0.0", &apiversion.Capabilities{Version: "v1.0.0", APIVersions: []string{"v3"}}), "no longer serves API v1")
+}
@@ -0,0 +1,139 @@
+// Package apierror defines the error envelope of the daemon API, shared by
+// the handlers writing it and the client reading it
+package apierror
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Code identifies the kind of an API error, for clients to act on without
+// parsing messages
+type Code string
+
+const (
+	CodeInvalidRequest  Code = "invalid_request"  // The request is malformed or its values invalid
+	CodeNotFound        Code = "not_found"        // The model, transfer, job or alias doesn't exist
+	CodeConflict        Code = "conflict"         // The request conflicts with the current state
+	CodeModelInUse      Code = "model_in_use"     // The model is downloading or otherwise busy
+	CodeLicenseRequired Code = "license_required" // The model's license must be accepted first
+	CodeRelayMode       Code = "relay_mode"       // The daemon is a relay and doesn't store models
+	CodeModelBlocked    Code = "model_blocked"    // The model is on the model blocklist
+	CodeUnauthorized    Code = "unauthorized"     // No valid API token was given
+	CodeForbidden       Code = "forbidden"        // The request is not allowed
+	CodeUnavailable     Code = "unavailable"      // A feature or dependency is not available right now
+	CodeInternal        Code = "internal"         // The daemon failed to handle the request
+)
+
+// Error is the body of every error response of the API:
+//
+//	{"error": {"code": "not_found", "message": "...", "details": {...}, "retryable": false}}
+type Error struct {
+	Code      Code                   `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Retryable bool                   `json:"retryable"` // Trying again later may succeed
+	Status    int                    `json:"-"`         // HTTP status of the response
+}
+
+// Envelope wraps an Error in responses
+type Envelope struct {
+	Error *Error `json:"error"`
+}
+
+// New returns an error with the code, message and retryability matching an
+// HTTP status
+func New(status int, message string) *Error {
+	return &Error{
+		Code:      CodeForStatus(status),
+		Message:   message,
+		Retryable: RetryableStatus(status),
+		Status:    status,
+	}
+}
+
+// Newf is New with a formatted message
+func Newf(status int, format string, args ...interface{}) *Error {
+	return New(status, fmt.Sprintf(format, args...))
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is matches errors by code, so errors.Is(err, &Error{Code: CodeNotFound})
+// tells what went wrong without comparing messages
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithCode sets a more specific code than the one derived from the status
+func (e *Error) WithCode(code Code) *Error {
+	e.Code = code
+	return e
+}
+
+// WithDetail adds structured information about the error
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+// CodeForStatus returns the generic code of an HTTP status
+func CodeForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusRequestEntityTooLarge:
+		return CodeInvalidRequest
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusTooManyRequests:
+		return CodeUnavailable
+	}
+	return CodeInternal
+}
+
+// RetryableStatus reports whether a request failing with status may succeed
+// when sent again unchanged
+func RetryableStatus(status int) bool {
+	switch status {
+	case http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+// Decode reads the error of a failed response. Bodies without an envelope,
+// such as those of older daemons using {"error": "message"}, still give an
+// error with the code of the status.
+func Decode(status int, body []byte) *Error {
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && len(envelope.Error) > 0 {
+		var e Error
+		if err := json.Unmarshal(envelope.Error, &e); err == nil && e.Message != "" {
+			if e.Code == "" {
+				e.Code = CodeForStatus(status)
+			}
+			e.Status = status
+			return &e
+		}
+		var message string
+		if err := json.Unmarshal(envelope.Error, &message); err == nil && message != "" {
+			return New(status, message)
+		}
+	}
+	return Newf(status, "request failed: %s (%d)", strings.ToLower(http.StatusText(status)), status)
+}
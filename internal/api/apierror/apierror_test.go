@@ -0,0 +1,60 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	e := New(http.StatusServiceUnavailable, "log capture is not enabled")
+	assert.Equal(t, CodeUnavailable, e.Code)
+	assert.True(t, e.Retryable)
+	assert.Equal(t, http.StatusServiceUnavailable, e.Status)
+
+	e = Newf(http.StatusBadRequest, "invalid tail: %s", "x")
+	assert.Equal(t, CodeInvalidRequest, e.Code)
+	assert.False(t, e.Retryable)
+	assert.Equal(t, "invalid tail: x", e.Error())
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	sent := New(http.StatusForbidden, "model org/model requires accepting its license").
+		WithCode(CodeLicenseRequired).
+		WithDetail("license", "llama3")
+
+	body, err := json.Marshal(Envelope{Error: sent})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"error": {
+		"code": "license_required",
+		"message": "model org/model requires accepting its license",
+		"details": {"license": "llama3"},
+		"retryable": false
+	}}`, string(body))
+
+	got := Decode(http.StatusForbidden, body)
+	assert.Equal(t, sent, got)
+}
+
+func TestDecodeFallbacks(t *testing.T) {
+	// Older daemons sent the message as a string
+	e := Decode(http.StatusNotFound, []byte(`{"error": "model org/model not found"}`))
+	assert.Equal(t, CodeNotFound, e.Code)
+	assert.Equal(t, "model org/model not found", e.Message)
+
+	e = Decode(http.StatusBadGateway, []byte("<html>bad gateway</html>"))
+	assert.Equal(t, CodeUnavailable, e.Code)
+	assert.True(t, e.Retryable)
+	assert.Equal(t, "request failed: bad gateway (502)", e.Message)
+}
+
+func TestIsMatchesCode(t *testing.T) {
+	err := fmt.Errorf("failed to remove model: %w", New(http.StatusConflict, "busy").WithCode(CodeModelInUse))
+	assert.True(t, errors.Is(err, &Error{Code: CodeModelInUse}))
+	assert.False(t, errors.Is(err, &Error{Code: CodeConflict}))
+}
@@ -0,0 +1,146 @@
+// Package apiversion describes the versions of the daemon API, the features
+// a daemon supports and the endpoints being retired, shared by the routes
+// serving them and the client negotiating with daemons of other versions
+package apiversion
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Versions of the API. Routes that didn't change are served under every
+// version; /api/v1 keeps the old form of those that did.
+const (
+	V1      = "v1"
+	V2      = "v2"
+	Current = V2
+)
+
+// Supported lists the versions the daemon serves, oldest first
+var Supported = []string{V1, V2}
+
+// Features a daemon may support, for clients to check before relying on them
+const (
+	FeatureCapabilities     = "capabilities"      // This endpoint
+	FeatureComponentStatus  = "component_status"  // Per-component health and build in the status
+	FeatureCatalogBrowse    = "catalog_browse"    // Paginated, sorted catalog at /catalog
+	FeatureRuntimeConfig    = "runtime_config"    // Reading and changing the config at /config
+	FeatureEvents           = "events"            // Daemon events at /events
+	FeatureJobs             = "jobs"              // Background publish jobs at /jobs
+	FeatureRepoRevision     = "repo_revision"     // Sharing a repository pinned to a tag or commit
+	FeatureCloneResume      = "clone_resume"      // Resuming interrupted repository clones
+	FeatureHubSearch        = "hub_search"        // Searching the HuggingFace Hub at /search
+	FeatureStoragePools     = "storage_pools"     // Rebalancing models between storage pools
+	FeatureAPITokens        = "api_tokens"        // Scoped API tokens at /tokens
+	FeatureQuarantine       = "quarantine"        // Verifying downloads in quarantine at /quarantine
+	FeatureKeyRotation      = "key_rotation"      // Rotating the publisher key at /publisher/rotate
+	FeatureCatalogSnapshot  = "catalog_snapshot"  // Exporting and importing the catalog at /catalog/export and /catalog/import
+	FeatureCatalogNetworks  = "catalog_networks"  // Catalog networks at /networks and the network filter of discover
+	FeatureModelBlocklist   = "model_blocklist"   // Models refused for download, seeding and discovery at /blocklist
+	FeatureUploadFairness   = "upload_fairness"   // Upload bandwidth shares of torrents at /uploads and seed priorities
+	FeatureConnectionLimits = "connection_limits" // Peer connections of torrents against the limits at /connections
+	FeatureDHTAnnounces     = "dht_announces"     // Announces of the model torrents by infohash at /dht/announces
+	FeaturePeerSources      = "peer_sources"      // Peers found and connected to by source in the peers of a torrent
+	FeatureManualPeers      = "manual_peers"      // Peers added to a torrent by hand at /torrents/:infohash/peers
+)
+
+// Features lists the features of this daemon
+var Features = []string{
+	FeatureCapabilities,
+	FeatureComponentStatus,
+	FeatureCatalogBrowse,
+	FeatureRuntimeConfig,
+	FeatureEvents,
+	FeatureJobs,
+	FeatureRepoRevision,
+	FeatureCloneResume,
+	FeatureHubSearch,
+	FeatureStoragePools,
+	FeatureAPITokens,
+	FeatureQuarantine,
+	FeatureKeyRotation,
+	FeatureCatalogSnapshot,
+	FeatureCatalogNetworks,
+	FeatureModelBlocklist,
+	FeatureUploadFairness,
+	FeatureConnectionLimits,
+	FeatureDHTAnnounces,
+	FeaturePeerSources,
+	FeatureManualPeers,
+}
+
+// Deprecation is an endpoint that is going away. Responses of the endpoint
+// carry its Deprecation and Sunset dates and its successor in headers.
+type Deprecation struct {
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Successor  string    `json:"successor,omitempty"` // Path replacing it, if any
+	Deprecated time.Time `json:"deprecated"`
+	Sunset     time.Time `json:"sunset"` // Removed from this date on
+}
+
+// Deprecations lists the endpoints being retired
+var Deprecations = []Deprecation{
+	{
+		Method:     http.MethodGet,
+		Path:       "/api/v1/status",
+		Successor:  "/api/v2/status",
+		Deprecated: time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC),
+		Sunset:     time.Date(2027, 7, 1, 0, 0, 0, 0, time.UTC),
+	},
+	{
+		Method:     http.MethodPost,
+		Path:       "/api/v1/test",
+		Deprecated: time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC),
+		Sunset:     time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+	},
+	{
+		Method:     http.MethodPost,
+		Path:       "/api/v1/models/test",
+		Deprecated: time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC),
+		Sunset:     time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+	},
+}
+
+// Find returns the deprecation of an endpoint, if it is being retired
+func Find(method, path string) (Deprecation, bool) {
+	for _, d := range Deprecations {
+		if d.Method == method && d.Path == path {
+			return d, true
+		}
+	}
+	return Deprecation{}, false
+}
+
+// Headers sets the Deprecation (RFC 9745), Sunset (RFC 8594) and successor
+// Link headers of a deprecated endpoint
+func (d Deprecation) Headers(h http.Header) {
+	h.Set("Deprecation", "@"+strconv.FormatInt(d.Deprecated.Unix(), 10))
+	h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	if d.Successor != "" {
+		h.Set("Link", "<"+d.Successor+`>; rel="successor-version"`)
+	}
+}
+
+// Capabilities is what a daemon supports, served at /api/v1/capabilities
+// so clients of any version find it
+type Capabilities struct {
+	Version      string        `json:"version"` // Build of the daemon
+	Commit       string        `json:"commit,omitempty"`
+	APIVersions  []string      `json:"api_versions"`
+	APIVersion   string        `json:"api_version"` // Current version
+	Features     []string      `json:"features"`
+	Deprecations []Deprecation `json:"deprecations"`
+}
+
+// Has reports whether the daemon supports a feature
+func (c *Capabilities) Has(feature string) bool {
+	return slices.Contains(c.Features, feature)
+}
+
+// SupportsAPI reports whether the daemon serves an API version
+func (c *Capabilities) SupportsAPI(version string) bool {
+	return slices.Contains(c.APIVersions, version)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/api/apierror"
+	"github.com/silmaril/silmaril/internal/daemon"
+)
+
+// Endpoints anyone may use, so clients can find a daemon and negotiate
+// with it before they authenticate
+var publicRoutes = map[string]bool{
+	"GET /healthz":      true,
+	"GET /readyz":       true,
+	"GET /health":       true,
+	"GET /capabilities": true,
+}
+
+// Endpoints that need an admin token: shutting down, configuration,
+// removing models and data, releasing quarantined downloads, rotating the
+// publisher key, changing the model blocklist and managing tokens
+var adminRoutes = map[string]bool{
+	"POST /admin/shutdown":       true,
+	"GET /config":                true,
+	"PATCH /config":              true,
+	"DELETE /models/*name":       true,
+	"POST /gc":                   true,
+	"POST /storage/rebalance":    true,
+	"POST /peer-filter/reload":   true,
+	"POST /quarantine/release":   true,
+	"POST /publisher/rotate":     true,
+	"POST /blocklist":            true,
+	"POST /blocklist/reload":     true,
+	"DELETE /blocklist/*pattern": true,
+	"GET /tokens":                true,
+	"POST /tokens":               true,
+	"DELETE /tokens/:name":       true,
+}
+
+// Endpoints that only read although they aren't GET requests
+var readRoutes = map[string]bool{
+	"POST /discover/watch": true,
+}
+
+// requiredScope returns the scope of API tokens an endpoint needs, empty
+// for public endpoints. Reading needs read, changing needs operator unless
+// the endpoint is listed as admin.
+func requiredScope(method, route string) string {
+	route = apiRoute(route)
+	key := method + " " + route
+	switch {
+	case route == "" || publicRoutes[key]:
+		return "" // Unknown routes answer 404 either way
+	case adminRoutes[key]:
+		return daemon.ScopeAdmin
+	case method == http.MethodGet || method == http.MethodHead || readRoutes[key]:
+		return daemon.ScopeRead
+	}
+	return daemon.ScopeOperator
+}
+
+// apiRoute returns a route without its API version prefix, so the routes
+// of every version are listed once
+func apiRoute(route string) string {
+	for _, version := range []string{"/api/v1", "/api/v2"} {
+		if strings.HasPrefix(route, version+"/") {
+			return strings.TrimPrefix(route, version)
+		}
+	}
+	return route
+}
+
+// authMiddleware requires a bearer token with the scope of the endpoint
+// once API tokens exist. Without tokens the API stays open, as it was
+// before tokens, to whoever can reach its address. Requests on the local
+// socket need no token, its file permissions already tell who may connect.
+func authMiddleware(d *daemon.Daemon) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		required := requiredScope(c.Request.Method, c.FullPath())
+		if required == "" || c.Request.Method == http.MethodOptions || !d.APIAuthEnabled() {
+			c.Next()
+			return
+		}
+
+		// Only the daemon's user can reach the local socket
+		if daemon.ViaSocket(c.Request.Context()) {
+			c.Set("api_token", "socket")
+			c.Next()
+			return
+		}
+
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="silmaril"`)
+			abortError(c, apierror.New(http.StatusUnauthorized, "an API token is required, set SILMARIL_API_TOKEN"))
+			return
+		}
+		record, ok := d.AuthenticateAPIToken(strings.TrimSpace(token))
+		if !ok {
+			c.Header("WWW-Authenticate", `Bearer realm="silmaril", error="invalid_token"`)
+			abortError(c, apierror.New(http.StatusUnauthorized, "the API token is invalid or was revoked"))
+			return
+		}
+		if !daemon.ScopeAllows(record.Scope, required) {
+			abortError(c, apierror.Newf(http.StatusForbidden, "token %s has the %s scope, this needs %s", record.Name, record.Scope, required).
+				WithDetail("scope", record.Scope).
+				WithDetail("required_scope", required))
+			return
+		}
+		c.Set("api_token", record.Name)
+		c.Next()
+	}
+}
+
+// abortError answers a request with an error before its handler runs
+func abortError(c *gin.Context, err *apierror.Error) {
+	c.AbortWithStatusJSON(err.Status, apierror.Envelope{Error: err})
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/api/apierror"
+	"github.com/silmaril/silmaril/internal/daemon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveWithToken(router http.Handler, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method, route, scope string
+	}{
+		{http.MethodGet, "/api/v1/health", ""},
+		{http.MethodGet, "/api/v2/capabilities", ""},
+		{http.MethodGet, "/healthz", ""},
+		{http.MethodGet, "", ""},
+		{http.MethodGet, "/api/v1/models", daemon.ScopeRead},
+		{http.MethodGet, "/metrics", daemon.ScopeRead},
+		{http.MethodPost, "/api/v1/discover/watch", daemon.ScopeRead},
+		{http.MethodPost, "/api/v1/models/download", daemon.ScopeOperator},
+		{http.MethodPost, "/api/v2/models/share", daemon.ScopeOperator},
+		{http.MethodDelete, "/api/v1/models/*name", daemon.ScopeAdmin},
+		{http.MethodPost, "/api/v1/admin/shutdown", daemon.ScopeAdmin},
+		{http.MethodGet, "/api/v2/tokens", daemon.ScopeAdmin},
+		{http.MethodPatch, "/api/v1/config", daemon.ScopeAdmin},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.scope, requiredScope(tt.method, tt.route), "%s %s", tt.method, tt.route)
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	d := newTestDaemon(t)
+	router := SetupRoutes(d)
+
+	// Without tokens the API stays open
+	assert.Equal(t, http.StatusOK, serve(router, http.MethodGet, "/api/v1/models").Code)
+
+	admin, _, err := d.CreateAPIToken("admin", daemon.ScopeAdmin)
+	require.NoError(t, err)
+	read, _, err := d.CreateAPIToken("dashboard", daemon.ScopeRead)
+	require.NoError(t, err)
+
+	w := serve(router, http.MethodGet, "/api/v1/models")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+	var envelope apierror.Envelope
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &envelope))
+	assert.Equal(t, apierror.CodeUnauthorized, envelope.Error.Code)
+
+	w = serveWithToken(router, http.MethodGet, "/api/v1/models", "slm_wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "invalid_token")
+
+	assert.Equal(t, http.StatusOK, serveWithToken(router, http.MethodGet, "/api/v1/models", read).Code)
+	assert.Equal(t, http.StatusOK, serveWithToken(router, http.MethodGet, "/api/v2/models", admin).Code)
+
+	// A read token can't manage tokens
+	w = serveWithToken(router, http.MethodGet, "/api/v1/tokens", read)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	envelope = apierror.Envelope{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &envelope))
+	assert.Equal(t, apierror.CodeForbidden, envelope.Error.Code)
+	assert.Equal(t, daemon.ScopeAdmin, envelope.Error.Details["required_scope"])
+	assert.Equal(t, http.StatusOK, serveWithToken(router, http.MethodGet, "/api/v1/tokens", admin).Code)
+
+	// Clients find and negotiate with the daemon before authenticating
+	assert.Equal(t, http.StatusOK, serve(router, http.MethodGet, "/api/v1/health").Code)
+	assert.Equal(t, http.StatusOK, serve(router, http.MethodGet, "/api/v2/capabilities").Code)
+}
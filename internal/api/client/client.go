@@ -2,27 +2,84 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
+
+	"github.com/silmaril/silmaril/internal/api/apierror"
+	"github.com/silmaril/silmaril/internal/api/apiversion"
 )
 
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	auth       *authTransport
 }
 
 func NewClient(baseURL string) *Client {
+	auth := &authTransport{}
 	return &Client{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
+			Timeout:   60 * time.Second,
+			Transport: auth,
+		},
+		auth: auth,
+	}
+}
+
+// authTransport sends the API token with every request
+type authTransport struct {
+	base  http.RoundTripper // http.DefaultTransport if nil
+	token string
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.token != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// SetTransport sets the HTTP transport used to reach the daemon, for example
+// to go through a proxy
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.auth.base = transport
+}
+
+// SetSocket reaches the daemon through its local socket, a Unix domain
+// socket or a named pipe on Windows, see daemon.socket, instead of its port
+func (c *Client) SetSocket(path string) {
+	c.auth.base = &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialSocket(ctx, path)
 		},
 	}
 }
 
+// SetToken sets the API token sent with every request, needed once the
+// daemon has API tokens
+func (c *Client) SetToken(token string) {
+	c.auth.token = token
+}
+
+// SetTimeout sets how long requests to the daemon may take, zero waits for
+// as long as the daemon needs, for example to hash a model
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.httpClient.Timeout = timeout
+}
+
 // Health checks if the daemon is healthy
 func (c *Client) Health() error {
 	resp, err := c.get("/api/v1/health")
@@ -46,6 +103,10 @@ func (c *Client) GetStatus() (map[string]interface{}, error) {
 	}
 	defer resp.Body.Close()
 	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
 	var status map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
 		return nil, err
@@ -54,6 +115,173 @@ func (c *Client) GetStatus() (map[string]interface{}, error) {
 	return status, nil
 }
 
+// Capabilities returns the API versions and features of the daemon. Daemons
+// from before capabilities were added respond with a not_found API error.
+func (c *Client) Capabilities() (*apiversion.Capabilities, error) {
+	resp, err := c.get("/api/v1/capabilities")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var caps apiversion.Capabilities
+	if err := json.NewDecoder(resp.Body).Decode(&caps); err != nil {
+		return nil, fmt.Errorf("failed to decode capabilities: %w", err)
+	}
+	return &caps, nil
+}
+
+// APIToken is a named API token of the daemon
+type APIToken struct {
+	Name      string     `json:"name"`
+	Scope     string     `json:"scope"`
+	CreatedAt time.Time  `json:"created_at"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+}
+
+// ListTokens returns the API tokens of the daemon
+func (c *Client) ListTokens() ([]APIToken, error) {
+	resp, err := c.get("/api/v1/tokens")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Tokens []APIToken `json:"tokens"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Tokens, nil
+}
+
+// CreateToken creates an API token of a scope and returns it. The daemon
+// doesn't show it again.
+func (c *Client) CreateToken(name, scope string) (string, error) {
+	resp, err := c.post("/api/v1/tokens", map[string]string{"name": name, "scope": scope})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusCreated); err != nil {
+		return "", err
+	}
+	
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Token, nil
+}
+
+// RevokeToken removes an API token
+func (c *Client) RevokeToken(name string) error {
+	resp, err := c.delete("/api/v1/tokens/" + url.PathEscape(name))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	return checkResponse(resp, http.StatusOK)
+}
+
+// QuarantinedModel is a finished download the daemon holds back until its
+// files are verified
+type QuarantinedModel struct {
+	Model    string    `json:"model"`
+	InfoHash string    `json:"info_hash"`
+	Status   string    `json:"status"`
+	Reason   string    `json:"reason,omitempty"`
+	Files    []string  `json:"files,omitempty"`
+	Since    time.Time `json:"since"`
+	Path     string    `json:"path,omitempty"`
+}
+
+// ListQuarantine returns the downloads held in quarantine
+func (c *Client) ListQuarantine() ([]QuarantinedModel, error) {
+	resp, err := c.get("/api/v1/quarantine")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Models []QuarantinedModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Models, nil
+}
+
+// ReleaseQuarantine lists a quarantined model as a local model whether or
+// not its files were verified
+func (c *Client) ReleaseQuarantine(name string) error {
+	resp, err := c.post("/api/v1/quarantine/release", map[string]string{"name": name})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	return checkResponse(resp, http.StatusOK)
+}
+
+// GetLogs returns daemon log lines. since is the sequence number of the last
+// line already seen or a duration like "10m"; tail limits the number of lines.
+// It also returns the sequence number of the most recent line.
+func (c *Client) GetLogs(since string, tail int) ([]map[string]interface{}, int64, error) {
+	path := "/api/v1/logs"
+	query := url.Values{}
+	if since != "" {
+		query.Set("since", since)
+	}
+	if tail > 0 {
+		query.Set("tail", strconv.Itoa(tail))
+	}
+	if len(query) > 0 {
+		path = fmt.Sprintf("%s?%s", path, query.Encode())
+	}
+	
+	resp, err := c.get(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, 0, err
+	}
+	
+	var result struct {
+		Lines   []map[string]interface{} `json:"lines"`
+		Count   int                      `json:"count"`
+		LastSeq int64                    `json:"last_seq"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, 0, err
+	}
+	
+	
+	return result.Lines, result.LastSeq, nil
+}
+
 // Shutdown requests daemon shutdown
 func (c *Client) Shutdown() error {
 	resp, err := c.post("/api/v1/admin/shutdown", nil)
@@ -62,8 +290,8 @@ func (c *Client) Shutdown() error {
 	}
 	defer resp.Body.Close()
 	
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("shutdown failed: status %d", resp.StatusCode)
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return err
 	}
 	
 	return nil
@@ -71,12 +299,55 @@ func (c *Client) Shutdown() error {
 
 // ListModels returns all local models
 func (c *Client) ListModels() ([]map[string]interface{}, error) {
-	resp, err := c.get("/api/v1/models")
+	return c.ListModelsFiltered("", nil)
+}
+
+// RefreshModels makes the daemon scan its models directory again and
+// returns the number of models found
+func (c *Client) RefreshModels() (int, error) {
+	resp, err := c.post("/api/v1/models/refresh", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return 0, err
+	}
+	
+	var result struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Count, nil
+}
+
+// ListModelsFiltered returns local models matching a search pattern and all given tags
+func (c *Client) ListModelsFiltered(pattern string, tags []string) ([]map[string]interface{}, error) {
+	path := "/api/v1/models"
+	query := url.Values{}
+	if pattern != "" {
+		query.Set("pattern", pattern)
+	}
+	for _, tag := range tags {
+		query.Add("tag", tag)
+	}
+	if len(query) > 0 {
+		path = fmt.Sprintf("%s?%s", path, query.Encode())
+	}
+	
+	resp, err := c.get(path)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
 	var result struct {
 		Models []map[string]interface{} `json:"models"`
 		Count  int                      `json:"count"`
@@ -97,8 +368,8 @@ func (c *Client) GetModel(name string) (map[string]interface{}, error) {
 	}
 	defer resp.Body.Close()
 	
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("model not found: %s", name)
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
 	}
 	
 	var model map[string]interface{}
@@ -109,13 +380,27 @@ func (c *Client) GetModel(name string) (map[string]interface{}, error) {
 	return model, nil
 }
 
-// DownloadModel starts downloading a model
-func (c *Client) DownloadModel(modelName, infoHash string, seed bool) (map[string]interface{}, error) {
+// DownloadModel starts downloading a model. acceptLicense accepts the
+// license of models that require it. A non-empty output stores the files in
+// that directory instead of the models directory.
+func (c *Client) DownloadModel(modelName, infoHash string, seed bool, files []string, acceptLicense, force bool, output string) (map[string]interface{}, error) {
 	payload := map[string]interface{}{
 		"model_name": modelName,
 		"info_hash":  infoHash,
 		"seed":       seed,
 	}
+	if len(files) > 0 {
+		payload["files"] = files
+	}
+	if acceptLicense {
+		payload["accept_license"] = true
+	}
+	if force {
+		payload["force"] = true
+	}
+	if output != "" {
+		payload["output"] = output
+	}
 	
 	resp, err := c.post("/api/v1/models/download", payload)
 	if err != nil {
@@ -123,6 +408,10 @@ func (c *Client) DownloadModel(modelName, infoHash string, seed bool) (map[strin
 	}
 	defer resp.Body.Close()
 	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
 	var result map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, err
@@ -142,11 +431,38 @@ type ShareModelOptions struct {
 	PieceLength  int64
 	SkipDHT      bool
 	SignManifest bool
+	Import       string // copy, link or inplace when publishing a directory
+	// Patterns of files left out of, or kept in, a published model
+	Exclude []string
+	Include []string
 	// Repository cloning options
 	RepoURL      string
 	Branch       string
+	Revision     string // Tag or commit to clone instead of the branch head
 	Depth        int
 	SkipLFS      bool
+	// Seeding policy of the model, nil limits use the daemon settings
+	SeedRatio        *float64
+	SeedTime         *time.Duration
+	StopIfNoPeersFor *time.Duration
+	SeedPriority     string // low, normal or high, empty keeps the model's
+	// Storage backend of the model, empty uses the daemon's
+	Storage string
+	// Glob patterns of the files of a seeded model to keep seeding
+	Files []string
+	// Seed the model encrypted, optionally with an endpoint serving its key
+	Encrypt bool
+	KeyURL  string
+	// License terms of a published model, which may have to be accepted
+	LicenseURL               string
+	LicenseText              string
+	RequireLicenseAcceptance bool
+	// Base URLs of HTTP servers holding the files of a published model
+	Mirrors []string
+	// Report what publishing a directory would do without writing anything
+	DryRun bool
+	// Seed again even if the model is already seeding
+	Force bool
 }
 
 // ShareModel starts sharing a model
@@ -161,12 +477,60 @@ func (c *Client) ShareModel(opts ShareModelOptions) (map[string]interface{}, err
 		"piece_length":  opts.PieceLength,
 		"skip_dht":      opts.SkipDHT,
 		"sign_manifest": opts.SignManifest,
+		"import":        opts.Import,
 		// Repository cloning fields
 		"repo_url":      opts.RepoURL,
 		"branch":        opts.Branch,
+		"revision":      opts.Revision,
 		"depth":         opts.Depth,
 		"skip_lfs":      opts.SkipLFS,
 	}
+	if opts.SeedRatio != nil {
+		payload["seed_ratio"] = *opts.SeedRatio
+	}
+	if opts.SeedTime != nil {
+		payload["seed_time"] = int(opts.SeedTime.Seconds())
+	}
+	if opts.StopIfNoPeersFor != nil {
+		payload["stop_if_no_peers_for"] = int(opts.StopIfNoPeersFor.Seconds())
+	}
+	if opts.SeedPriority != "" {
+		payload["seed_priority"] = opts.SeedPriority
+	}
+	if opts.Storage != "" {
+		payload["storage"] = opts.Storage
+	}
+	if len(opts.Files) > 0 {
+		payload["files"] = opts.Files
+	}
+	if opts.Encrypt {
+		payload["encrypt"] = true
+		payload["key_url"] = opts.KeyURL
+	}
+	if opts.LicenseURL != "" {
+		payload["license_url"] = opts.LicenseURL
+	}
+	if opts.LicenseText != "" {
+		payload["license_text"] = opts.LicenseText
+	}
+	if opts.RequireLicenseAcceptance {
+		payload["require_license_acceptance"] = true
+	}
+	if len(opts.Mirrors) > 0 {
+		payload["mirrors"] = opts.Mirrors
+	}
+	if len(opts.Exclude) > 0 {
+		payload["exclude"] = opts.Exclude
+	}
+	if len(opts.Include) > 0 {
+		payload["include"] = opts.Include
+	}
+	if opts.DryRun {
+		payload["dry_run"] = true
+	}
+	if opts.Force {
+		payload["force"] = true
+	}
 	
 	resp, err := c.post("/api/v1/models/share", payload)
 	if err != nil {
@@ -174,151 +538,1671 @@ func (c *Client) ShareModel(opts ShareModelOptions) (map[string]interface{}, err
 	}
 	defer resp.Body.Close()
 	
+	if err := checkResponse(resp, http.StatusOK, http.StatusAccepted); err != nil {
+		return nil, err
+	}
+	
 	var result map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, err
 	}
 	
-	// Check if response contains an error
-	// Accept both 200 (OK) and 202 (Accepted) as success
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		if _, ok := result["error"].(string); ok {
-			return result, nil // Return the result so caller can check the error
-		}
-		return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)
-	}
 	
 	return result, nil
 }
 
-// RemoveModel removes a model
-func (c *Client) RemoveModel(name string) error {
-	resp, err := c.delete(fmt.Sprintf("/api/v1/models/%s", name))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to remove model: status %d", resp.StatusCode)
-	}
-	
-	return nil
+// PublishModelsOptions contains options for publishing a directory tree of
+// models
+type PublishModelsOptions struct {
+	Path        string
+	Recursive   bool
+	License     string // For models whose model card names none
+	Version     string
+	Import      string
+	PieceLength int64
+	SkipDHT     bool
+	Workers     int
+	DryRun      bool // Plan the models without publishing them
+	Exclude     []string
+	Include     []string
 }
 
-// DiscoverModels searches for models on the P2P network
-func (c *Client) DiscoverModels(pattern string) ([]map[string]interface{}, error) {
-	url := "/api/v1/discover"
-	if pattern != "" {
-		url = fmt.Sprintf("%s?pattern=%s", url, pattern)
-	}
-	
-	resp, err := c.get(url)
+// PublishModels starts publishing the models found in a directory tree. The
+// result holds a job for each model and the models skipped, or with DryRun a
+// plan for each model.
+func (c *Client) PublishModels(opts PublishModelsOptions) (map[string]interface{}, error) {
+	resp, err := c.post("/api/v1/models/publish", map[string]interface{}{
+		"path":         opts.Path,
+		"recursive":    opts.Recursive,
+		"license":      opts.License,
+		"version":      opts.Version,
+		"import":       opts.Import,
+		"piece_length": opts.PieceLength,
+		"skip_dht":     opts.SkipDHT,
+		"workers":      opts.Workers,
+		"dry_run":      opts.DryRun,
+		"exclude":      opts.Exclude,
+		"include":      opts.Include,
+	})
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	
-	var result struct {
-		Models []map[string]interface{} `json:"models"`
-		Count  int                      `json:"count"`
+	if err := checkResponse(resp, http.StatusOK, http.StatusAccepted); err != nil {
+		return nil, err
 	}
 	
+	var result map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 	
-	return result.Models, nil
+	return result, nil
 }
 
-// GetTransfer returns details about a specific transfer
-func (c *Client) GetTransfer(id string) (map[string]interface{}, error) {
-	resp, err := c.get(fmt.Sprintf("/api/v1/transfers/%s", id))
+// InfoHashOptions selects the files and piece length of the torrent an
+// infohash is computed for
+type InfoHashOptions struct {
+	Path        string
+	PieceLength int64
+	Exclude     []string
+	Include     []string
+}
+
+// ComputeInfoHash returns the infohash a directory would be published with
+func (c *Client) ComputeInfoHash(opts InfoHashOptions) (map[string]interface{}, error) {
+	resp, err := c.post("/api/v1/models/infohash", map[string]interface{}{
+		"path":         opts.Path,
+		"piece_length": opts.PieceLength,
+		"exclude":      opts.Exclude,
+		"include":      opts.Include,
+	})
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("transfer not found: %s", id)
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
 	}
 	
-	var transfer map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&transfer); err != nil {
-		return nil, err
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 	
-	return transfer, nil
+	return result, nil
 }
 
-// ListTransfers returns all transfers
-func (c *Client) ListTransfers(status string) ([]map[string]interface{}, error) {
-	url := "/api/v1/transfers"
-	if status != "" {
-		url = fmt.Sprintf("%s?status=%s", url, status)
-	}
-	
-	resp, err := c.get(url)
+// DeclareEquivalents declares torrents holding the same files as a local
+// model, so downloads of the model use their swarms too
+func (c *Client) DeclareEquivalents(model string, infoHashes []string) (map[string]interface{}, error) {
+	resp, err := c.post("/api/v1/models/equivalents", map[string]interface{}{
+		"model":       model,
+		"info_hashes": infoHashes,
+	})
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	
-	var result struct {
-		Transfers []map[string]interface{} `json:"transfers"`
-		Count     int                      `json:"count"`
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
 	}
 	
+	var result map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 	
-	return result.Transfers, nil
+	return result, nil
 }
 
-// PauseTransfer pauses a transfer
-func (c *Client) PauseTransfer(id string) error {
-	resp, err := c.put(fmt.Sprintf("/api/v1/transfers/%s/pause", id), nil)
+// RemoveModel removes a model
+func (c *Client) RemoveModel(name string) error {
+	resp, err := c.delete(fmt.Sprintf("/api/v1/models/%s", name))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 	
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to pause transfer: status %d", resp.StatusCode)
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return err
 	}
 	
 	return nil
 }
 
-// ResumeTransfer resumes a transfer
-func (c *Client) ResumeTransfer(id string) error {
-	resp, err := c.put(fmt.Sprintf("/api/v1/transfers/%s/resume", id), nil)
+// PurgeModel stops sharing a model and deletes its files, returning the
+// number of bytes freed
+func (c *Client) PurgeModel(name string) (int64, error) {
+	resp, err := c.delete(fmt.Sprintf("/api/v1/models/%s?purge=true", name))
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer resp.Body.Close()
 	
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to resume transfer: status %d", resp.StatusCode)
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return 0, err
 	}
 	
-	return nil
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+	
+	
+	freed, _ := result["freed_bytes"].(float64)
+	return int64(freed), nil
 }
 
-// CancelTransfer cancels a transfer
-func (c *Client) CancelTransfer(id string) error {
-	resp, err := c.delete(fmt.Sprintf("/api/v1/transfers/%s", id))
+// DiscoverOptions are the search filters for DiscoverModelsFiltered
+type DiscoverOptions struct {
+	Pattern     string
+	Tags        []string
+	MaxSize     string // Human readable size, e.g. "20GB"
+	License     string
+	MinSeeders  int
+	CheckHealth bool   // Annotate results with seeder statistics
+	Network     string // Only models of this catalog network
+}
+
+// DiscoverModels searches for models on the P2P network
+func (c *Client) DiscoverModels(pattern string) ([]map[string]interface{}, error) {
+	return c.DiscoverModelsFiltered(DiscoverOptions{Pattern: pattern})
+}
+
+// SearchHub searches the HuggingFace Hub for models matching term, marking
+// the ones available from peers or stored locally
+func (c *Client) SearchHub(term string, limit int) ([]map[string]interface{}, error) {
+	query := url.Values{}
+	query.Set("q", term)
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	resp, err := c.get("/api/v1/search?" + query.Encode())
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to cancel transfer: status %d", resp.StatusCode)
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
 	}
 	
-	return nil
+	var result struct {
+		Models []map[string]interface{} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Models, nil
+}
+
+// DiscoverModelsFiltered searches for models on the P2P network using filters
+func (c *Client) DiscoverModelsFiltered(opts DiscoverOptions) ([]map[string]interface{}, error) {
+	path := "/api/v1/discover"
+	query := url.Values{}
+	if opts.Pattern != "" {
+		query.Set("pattern", opts.Pattern)
+	}
+	for _, tag := range opts.Tags {
+		query.Add("tag", tag)
+	}
+	if opts.MaxSize != "" {
+		query.Set("max_size", opts.MaxSize)
+	}
+	if opts.License != "" {
+		query.Set("license", opts.License)
+	}
+	if opts.MinSeeders > 0 {
+		query.Set("min_seeders", strconv.Itoa(opts.MinSeeders))
+	}
+	if opts.CheckHealth {
+		query.Set("check_health", "true")
+	}
+	if opts.Network != "" {
+		query.Set("network", opts.Network)
+	}
+	if len(query) > 0 {
+		path = fmt.Sprintf("%s?%s", path, query.Encode())
+	}
+	
+	resp, err := c.get(path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Models []map[string]interface{} `json:"models"`
+		Count  int                      `json:"count"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	
+	return result.Models, nil
+}
+
+// CatalogOptions selects a page of the catalog
+type CatalogOptions struct {
+	DiscoverOptions        // Pattern, tag, size and license filters
+	Sort            string // added, size, seeders or name
+	Order           string // asc or desc, empty for the default of Sort
+	Limit           int
+	Offset          int
+	Cursor          string // next_cursor of the previous page
+}
+
+// CatalogPage is a page of the catalog
+type CatalogPage struct {
+	Models     []map[string]interface{} `json:"models"`
+	Total      int                      `json:"total"`
+	Offset     int                      `json:"offset"`
+	Limit      int                      `json:"limit"`
+	NextCursor string                   `json:"next_cursor"`
+	Sort       string                   `json:"sort"`
+	Order      string                   `json:"order"`
+}
+
+// BrowseCatalog returns a page of the catalog in the order of opts, without
+// waiting for the DHT like DiscoverModelsFiltered
+func (c *Client) BrowseCatalog(opts CatalogOptions) (*CatalogPage, error) {
+	query := url.Values{}
+	if opts.Pattern != "" {
+		query.Set("pattern", opts.Pattern)
+	}
+	for _, tag := range opts.Tags {
+		query.Add("tag", tag)
+	}
+	if opts.MaxSize != "" {
+		query.Set("max_size", opts.MaxSize)
+	}
+	if opts.License != "" {
+		query.Set("license", opts.License)
+	}
+	if opts.Network != "" {
+		query.Set("network", opts.Network)
+	}
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+	if opts.Order != "" {
+		query.Set("order", opts.Order)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+
+	resp, err := c.get("/api/v1/catalog?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	var page CatalogPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &page, nil
+}
+
+// WatchDiscovery makes the daemon refresh the catalog frequently for a few
+// minutes and publish a catalog.added event for each model that appears. It
+// returns when the watch ends unless it is renewed.
+func (c *Client) WatchDiscovery() (time.Time, error) {
+	resp, err := c.post("/api/v1/discover/watch", nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return time.Time{}, err
+	}
+	
+	var result struct {
+		WatchingUntil time.Time `json:"watching_until"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return time.Time{}, err
+	}
+	
+	return result.WatchingUntil, nil
+}
+
+// UnpublishModel withdraws a model we published from discovery
+func (c *Client) UnpublishModel(name string) error {
+	payload := map[string]interface{}{
+		"model_name": name,
+	}
+	
+	resp, err := c.post("/api/v1/unpublish", payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return err
+	}
+	
+	return nil
+}
+
+// GetPublisher returns our own publisher key
+func (c *Client) GetPublisher() (map[string]interface{}, error) {
+	resp, err := c.get("/api/v1/publisher")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	
+	return result, nil
+}
+
+// KeyRotation is the outcome of rotating the publisher key of the daemon
+type KeyRotation struct {
+	OldKey      string `json:"old_key"`
+	NewKey      string `json:"new_key"`
+	Resigned    int    `json:"resigned"`
+	Republished int    `json:"republished"`
+}
+
+// RotatePublisherKey replaces the publisher key of the daemon with a new one
+func (c *Client) RotatePublisherKey() (*KeyRotation, error) {
+	resp, err := c.post("/api/v1/publisher/rotate", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Rotation KeyRotation `json:"rotation"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result.Rotation, nil
+}
+
+// CatalogNetwork is a catalog network the daemon joined
+type CatalogNetwork struct {
+	Name      string `json:"name"`
+	PublicKey string `json:"public_key"`
+	ReadOnly  bool   `json:"read_only"`
+	Loaded    bool   `json:"loaded"`
+	Models    int    `json:"models"`
+}
+
+// ListNetworks returns the public network and the catalog networks the
+// daemon joined
+func (c *Client) ListNetworks() ([]CatalogNetwork, error) {
+	resp, err := c.get("/api/v1/networks")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Networks []CatalogNetwork `json:"networks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Networks, nil
+}
+
+// BlockedModel is an entry of the local model blocklist
+type BlockedModel struct {
+	Pattern string    `json:"pattern"`
+	Reason  string    `json:"reason,omitempty"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// BlocklistFeed is a remote list of models the daemon refuses
+type BlocklistFeed struct {
+	URL          string     `json:"url"`
+	Entries      int        `json:"entries"`
+	Updated      *time.Time `json:"updated,omitempty"`
+	SkippedLines int        `json:"skipped_lines"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// ModelBlocklist is the local model blocklist and the feeds the daemon
+// follows
+type ModelBlocklist struct {
+	Entries []BlockedModel  `json:"entries"`
+	Feeds   []BlocklistFeed `json:"feeds"`
+	Total   int             `json:"total"`
+}
+
+// GetBlocklist returns the model blocklist
+func (c *Client) GetBlocklist() (*ModelBlocklist, error) {
+	resp, err := c.get("/api/v1/blocklist")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	return decodeBlocklist(resp)
+}
+
+// ReloadBlocklist downloads the model blocklist feeds again
+func (c *Client) ReloadBlocklist() (*ModelBlocklist, error) {
+	resp, err := c.post("/api/v1/blocklist/reload", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	return decodeBlocklist(resp)
+}
+
+// decodeBlocklist reads the model blocklist from a response
+func decodeBlocklist(resp *http.Response) (*ModelBlocklist, error) {
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result ModelBlocklist
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// BlockModel adds an infohash or a pattern of model names to the model
+// blocklist
+func (c *Client) BlockModel(pattern, reason string) (*BlockedModel, error) {
+	resp, err := c.post("/api/v1/blocklist", map[string]interface{}{
+		"pattern": pattern,
+		"reason":  reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Entry BlockedModel `json:"entry"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result.Entry, nil
+}
+
+// UnblockModel removes an entry from the model blocklist
+func (c *Client) UnblockModel(pattern string) error {
+	resp, err := c.delete(fmt.Sprintf("/api/v1/blocklist/%s", pattern))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	return checkResponse(resp, http.StatusOK)
+}
+
+// ListSubscriptions returns all subscribed publishers
+func (c *Client) ListSubscriptions() ([]map[string]interface{}, error) {
+	resp, err := c.get("/api/v1/subscriptions")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Subscriptions []map[string]interface{} `json:"subscriptions"`
+		Count         int                      `json:"count"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	return result.Subscriptions, nil
+}
+
+// Subscribe follows a publisher's catalog
+func (c *Client) Subscribe(publicKey, name string) (map[string]interface{}, error) {
+	payload := map[string]interface{}{
+		"public_key": publicKey,
+		"name":       name,
+	}
+	
+	resp, err := c.post("/api/v1/subscriptions", payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	
+	return result, nil
+}
+
+// Unsubscribe stops following a publisher's catalog
+func (c *Client) Unsubscribe(publicKey string) error {
+	resp, err := c.delete(fmt.Sprintf("/api/v1/subscriptions/%s", publicKey))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return err
+	}
+	
+	return nil
+}
+
+// ListModelSubscriptions returns all subscribed models
+func (c *Client) ListModelSubscriptions() ([]map[string]interface{}, error) {
+	resp, err := c.get("/api/v1/model-subscriptions")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Subscriptions []map[string]interface{} `json:"subscriptions"`
+		Count         int                      `json:"count"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	return result.Subscriptions, nil
+}
+
+// SubscribeModel keeps a model up to date with the catalog. With autoRemove
+// the old version is deleted after an update.
+func (c *Client) SubscribeModel(model string, autoRemove bool) (map[string]interface{}, error) {
+	payload := map[string]interface{}{
+		"model":       model,
+		"auto_remove": autoRemove,
+	}
+	
+	resp, err := c.post("/api/v1/model-subscriptions", payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	
+	return result, nil
+}
+
+// UnsubscribeModel stops updating a model
+func (c *Client) UnsubscribeModel(model string) error {
+	resp, err := c.delete(fmt.Sprintf("/api/v1/model-subscriptions/%s", model))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return err
+	}
+	
+	return nil
+}
+
+// GetEvents returns daemon events after the sequence number since, and the
+// sequence number of the most recent event
+func (c *Client) GetEvents(since int64) ([]map[string]interface{}, int64, error) {
+	resp, err := c.get(fmt.Sprintf("/api/v1/events?since=%d", since))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, 0, err
+	}
+	
+	var result struct {
+		Events  []map[string]interface{} `json:"events"`
+		Count   int                      `json:"count"`
+		LastSeq int64                    `json:"last_seq"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, 0, err
+	}
+	
+	
+	return result.Events, result.LastSeq, nil
+}
+
+// GetSwarmAvailability returns how well the pieces of each model are spread
+// among the daemon's peers
+func (c *Client) GetSwarmAvailability() ([]map[string]interface{}, error) {
+	resp, err := c.get("/api/v1/swarm")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Models []map[string]interface{} `json:"models"`
+		Count  int                      `json:"count"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	return result.Models, nil
+}
+
+// UploadShare is the part of the upload bandwidth a torrent was given
+type UploadShare struct {
+	Model      string  `json:"model"`
+	InfoHash   string  `json:"info_hash"`
+	Priority   string  `json:"priority"`
+	Weight     float64 `json:"weight"`
+	Peers      int     `json:"peers"`
+	UploadRate int64   `json:"upload_rate"`
+	Limit      int64   `json:"limit"` // 0 = unlimited
+}
+
+// UploadSchedule is how the daemon divides its upload bandwidth
+type UploadSchedule struct {
+	Enabled   bool          `json:"enabled"`
+	Total     int64         `json:"total"`
+	Shares    []UploadShare `json:"shares"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// GetUploadSchedule returns how the upload bandwidth is divided between the
+// torrents
+func (c *Client) GetUploadSchedule() (*UploadSchedule, error) {
+	resp, err := c.get("/api/v1/uploads")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result UploadSchedule
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// ConnectionLimits are the peer connection limits of the daemon
+type ConnectionLimits struct {
+	MaxConnections     int `json:"max_connections"`
+	PerTorrent         int `json:"max_connections_per_torrent"`
+	HalfOpen           int `json:"max_half_open"`
+	HalfOpenPerTorrent int `json:"max_half_open_per_torrent"`
+}
+
+// TorrentConnections are the peer connections of a torrent
+type TorrentConnections struct {
+	Model     string `json:"model"`
+	InfoHash  string `json:"info_hash"`
+	Connected int    `json:"connected"`
+	HalfOpen  int    `json:"half_open"`
+	Known     int    `json:"known"`
+	Limit     int    `json:"limit"` // Its share of max_connections
+}
+
+// ConnectionStats are the peer connections of the daemon against its limits
+type ConnectionStats struct {
+	Limits    ConnectionLimits     `json:"limits"`
+	Connected int                  `json:"connected"`
+	HalfOpen  int                  `json:"half_open"`
+	Torrents  []TorrentConnections `json:"torrents"`
+}
+
+// GetConnectionStats returns the peer connections of each torrent against
+// the connection limits
+func (c *Client) GetConnectionStats() (*ConnectionStats, error) {
+	resp, err := c.get("/api/v1/connections")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result ConnectionStats
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// InfohashAnnounce is the last announce of a model's infohash on the DHT
+type InfohashAnnounce struct {
+	Model       string     `json:"model"`
+	InfoHash    string     `json:"info_hash"`
+	LastAttempt time.Time  `json:"last_attempt"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Nodes       int        `json:"nodes"` // Nodes that stored our peer
+	Peers       int        `json:"peers"` // Other peers the nodes returned
+	Failures    int        `json:"failures"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// GetDHTAnnounces returns the last announce of each model's infohash on the
+// DHT
+func (c *Client) GetDHTAnnounces() ([]InfohashAnnounce, error) {
+	resp, err := c.get("/api/v1/dht/announces")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Announces []InfohashAnnounce `json:"announces"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Announces, nil
+}
+
+// ListScrubs returns when the data of each seeded model was last verified
+func (c *Client) ListScrubs() ([]map[string]interface{}, error) {
+	resp, err := c.get("/api/v1/scrub")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Scrubs []map[string]interface{} `json:"scrubs"`
+		Count  int                      `json:"count"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	return result.Scrubs, nil
+}
+
+// ScrubModel starts re-verifying the data of a seeded model
+func (c *Client) ScrubModel(model string) error {
+	resp, err := c.post("/api/v1/scrub", map[string]interface{}{"model": model})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusAccepted); err != nil {
+		return err
+	}
+	
+	return nil
+}
+
+// CollectGarbage removes orphaned torrents, downloads and temporary files,
+// or only lists them if dryRun is set
+func (c *Client) CollectGarbage(dryRun bool) (map[string]interface{}, error) {
+	path := "/api/v1/gc"
+	if dryRun {
+		path += "?dry_run=true"
+	}
+	resp, err := c.post(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var report map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, err
+	}
+	
+	return report, nil
+}
+
+// RebalanceStorage moves the local models to the storage pool their size
+// belongs in, or only lists the moves if dryRun is set
+func (c *Client) RebalanceStorage(dryRun bool) (map[string]interface{}, error) {
+	path := "/api/v1/storage/rebalance"
+	if dryRun {
+		path += "?dry_run=true"
+	}
+	resp, err := c.post(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var report map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, err
+	}
+	
+	return report, nil
+}
+
+// ListAliases returns the aliases of the local models
+func (c *Client) ListAliases() ([]map[string]interface{}, error) {
+	resp, err := c.get("/api/v1/aliases")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Aliases []map[string]interface{} `json:"aliases"`
+		Count   int                      `json:"count"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	
+	return result.Aliases, nil
+}
+
+// SetAlias lets a local model be referred to as alias. It returns the name
+// of the model the alias points to.
+func (c *Client) SetAlias(alias, model string) (string, error) {
+	resp, err := c.post("/api/v1/aliases", map[string]interface{}{
+		"alias": alias,
+		"model": model,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return "", err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	
+	
+	target, _ := result["model"].(string)
+	return target, nil
+}
+
+// RemoveAlias deletes an alias, the model it points to is kept
+func (c *Client) RemoveAlias(alias string) error {
+	resp, err := c.delete(fmt.Sprintf("/api/v1/aliases/%s", alias))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return err
+	}
+	
+	return nil
+}
+
+// RenameModel renames a local model. With keepAlias the old name stays an
+// alias of the new one.
+func (c *Client) RenameModel(from, to string, keepAlias bool) (map[string]interface{}, error) {
+	resp, err := c.post("/api/v1/models/rename", map[string]interface{}{
+		"from":       from,
+		"to":         to,
+		"keep_alias": keepAlias,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	
+	
+	return result, nil
+}
+
+// SetModelKey gives the daemon the key of an encrypted model, or the token
+// for its key endpoint. It reports whether the model is being decrypted.
+func (c *Client) SetModelKey(modelName, key, keyToken string) (bool, error) {
+	resp, err := c.post("/api/v1/models/key", map[string]interface{}{
+		"model_name": modelName,
+		"key":        key,
+		"key_token":  keyToken,
+	})
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return false, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+	
+	decrypting, _ := result["decrypting"].(bool)
+	return decrypting, nil
+}
+
+// GetTransfer returns details about a specific transfer
+func (c *Client) GetTransfer(id string) (map[string]interface{}, error) {
+	resp, err := c.get(fmt.Sprintf("/api/v1/transfers/%s", id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var transfer map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&transfer); err != nil {
+		return nil, err
+	}
+	
+	return transfer, nil
+}
+
+// GetTransferStats returns the smoothed rates, ETA, peers and recent rate
+// history of a transfer
+func (c *Client) GetTransferStats(id string) (map[string]interface{}, error) {
+	resp, err := c.get(fmt.Sprintf("/api/v1/transfers/%s/stats", id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	
+	
+	return result, nil
+}
+
+// GetTransferFiles returns how much of each file of a transfer is local
+func (c *Client) GetTransferFiles(id string) ([]map[string]interface{}, error) {
+	resp, err := c.get(fmt.Sprintf("/api/v1/transfers/%s/files", id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Files []map[string]interface{} `json:"files"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	
+	return result.Files, nil
+}
+
+// GetTorrentPeers returns the peers a torrent is connected to
+func (c *Client) GetTorrentPeers(infoHash string) (map[string]interface{}, error) {
+	resp, err := c.get(fmt.Sprintf("/api/v1/torrents/%s/peers", infoHash))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	
+	return result, nil
+}
+
+// ManualPeers are the peers added by hand to the torrent of a model
+type ManualPeers struct {
+	InfoHash string   `json:"info_hash"`
+	Name     string   `json:"name"`
+	Peers    []string `json:"peers"` // All peers added by hand
+	Added    int      `json:"added"` // Addresses new to the torrent
+	Error    string   `json:"error,omitempty"`
+}
+
+// AddTorrentPeers adds peer addresses to a torrent. The daemon adds them
+// again after a restart.
+func (c *Client) AddTorrentPeers(infoHash string, peers []string) (*ManualPeers, error) {
+	resp, err := c.post(fmt.Sprintf("/api/v1/torrents/%s/peers", infoHash), map[string]interface{}{
+		"peers": peers,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result ManualPeers
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// RemoveTorrentPeer forgets a peer added to a torrent
+func (c *Client) RemoveTorrentPeer(infoHash, peer string) error {
+	resp, err := c.delete(fmt.Sprintf("/api/v1/torrents/%s/peers/%s", infoHash, url.PathEscape(peer)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	return checkResponse(resp, http.StatusOK)
+}
+
+// ListTransfers returns all transfers
+func (c *Client) ListTransfers(status string) ([]map[string]interface{}, error) {
+	url := "/api/v1/transfers"
+	if status != "" {
+		url = fmt.Sprintf("%s?status=%s", url, status)
+	}
+	
+	resp, err := c.get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Transfers []map[string]interface{} `json:"transfers"`
+		Count     int                      `json:"count"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	return result.Transfers, nil
+}
+
+// PauseTransfer pauses a transfer
+func (c *Client) PauseTransfer(id string) error {
+	resp, err := c.put(fmt.Sprintf("/api/v1/transfers/%s/pause", id), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return err
+	}
+	
+	return nil
+}
+
+// ResumeTransfer resumes a transfer
+func (c *Client) ResumeTransfer(id string) error {
+	resp, err := c.put(fmt.Sprintf("/api/v1/transfers/%s/resume", id), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return err
+	}
+	
+	return nil
+}
+
+// CancelTransfer cancels a transfer
+func (c *Client) CancelTransfer(id string) error {
+	resp, err := c.delete(fmt.Sprintf("/api/v1/transfers/%s", id))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return err
+	}
+	
+	return nil
+}
+
+// PurgeTransfer cancels a download and deletes its partial files, returning
+// the number of bytes freed
+func (c *Client) PurgeTransfer(id string) (int64, error) {
+	resp, err := c.delete(fmt.Sprintf("/api/v1/transfers/%s?purge=true", id))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return 0, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+	
+	
+	freed, _ := result["freed_bytes"].(float64)
+	return int64(freed), nil
+}
+
+// ListJobs returns the publish jobs, the most recent first
+func (c *Client) ListJobs() ([]map[string]interface{}, error) {
+	resp, err := c.get("/api/v1/jobs")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Jobs  []map[string]interface{} `json:"jobs"`
+		Count int                      `json:"count"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	return result.Jobs, nil
+}
+
+// HistoryOptions selects the entries of the transfer history, empty fields
+// select all
+type HistoryOptions struct {
+	Model  string
+	Type   string // download, upload or seed
+	Result string // completed, cancelled or failed
+	Since  string // A duration such as 24h, or an RFC 3339 time
+	Limit  int
+}
+
+// GetHistory returns the transfers that ended, the most recent first
+func (c *Client) GetHistory(opts HistoryOptions) ([]map[string]interface{}, error) {
+	path := "/api/v1/history"
+	query := url.Values{}
+	for key, value := range map[string]string{"model": opts.Model, "type": opts.Type, "result": opts.Result, "since": opts.Since} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if len(query) > 0 {
+		path = fmt.Sprintf("%s?%s", path, query.Encode())
+	}
+	
+	resp, err := c.get(path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		History []map[string]interface{} `json:"history"`
+		Count   int                      `json:"count"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	return result.History, nil
+}
+
+// GetStats returns the bytes uploaded and downloaded in total, per model and
+// for the last days, all kept days if days is 0
+func (c *Client) GetStats(days int) (map[string]interface{}, error) {
+	resp, err := c.get(fmt.Sprintf("/api/v1/stats?days=%d", days))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var stats map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+	
+	return stats, nil
+}
+
+// GetNetworkStats returns the anonymous stats published by the nodes of the
+// network for a day, 2006-01-02 in UTC, today if day is empty
+func (c *Client) GetNetworkStats(day string) (map[string]interface{}, error) {
+	path := "/api/v1/network/stats"
+	if day != "" {
+		path += "?day=" + url.QueryEscape(day)
+	}
+	resp, err := c.get(path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var stats map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+	
+	return stats, nil
+}
+
+// ExportTorrent returns the torrent file of a local model
+func (c *Client) ExportTorrent(model string) ([]byte, error) {
+	resp, err := c.get("/api/v1/torrents/export?model=" + url.QueryEscape(model))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	return io.ReadAll(resp.Body)
+}
+
+// CatalogImport is the outcome of importing a catalog snapshot
+type CatalogImport struct {
+	Models  int  `json:"models"`
+	Changed bool `json:"changed"`
+	Total   int  `json:"total"`
+}
+
+// ExportCatalog returns a snapshot of the public catalog of the daemon
+func (c *Client) ExportCatalog() ([]byte, error) {
+	resp, err := c.get("/api/v1/catalog/export")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	return io.ReadAll(resp.Body)
+}
+
+// ImportCatalog merges a catalog snapshot into the public catalog of the
+// daemon
+func (c *Client) ImportCatalog(data []byte) (*CatalogImport, error) {
+	req, err := http.NewRequest("POST", c.baseURL+"/api/v1/catalog/import", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result CatalogImport
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// GetPinning returns the community pinning policy and the pinned models
+func (c *Client) GetPinning() (map[string]interface{}, error) {
+	resp, err := c.get("/api/v1/pinning")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result, nil
+}
+
+// ListConversions returns the recent conversions of local models, the most
+// recent first
+func (c *Client) ListConversions() ([]map[string]interface{}, error) {
+	resp, err := c.get("/api/v1/convert")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Conversions []map[string]interface{} `json:"conversions"`
+		Count       int                      `json:"count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Conversions, nil
+}
+
+// ConvertModel queues conversions of a local model into variants, the
+// daemon's configured ones if none are given
+func (c *Client) ConvertModel(model string, variants []string, publish bool) ([]map[string]interface{}, error) {
+	resp, err := c.post("/api/v1/convert", map[string]interface{}{
+		"model":    model,
+		"variants": variants,
+		"publish":  publish,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusAccepted); err != nil {
+		return nil, err
+	}
+	
+	var result struct {
+		Conversions []map[string]interface{} `json:"conversions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Conversions, nil
+}
+
+// InspectModel describes a local model, its files, torrent, signature and
+// seeding
+func (c *Client) InspectModel(model string) (map[string]interface{}, error) {
+	resp, err := c.get("/api/v1/inspect?model=" + url.QueryEscape(model))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result, nil
+}
+
+// DiffModels compares the files of the local models a and b
+func (c *Client) DiffModels(a, b string) (map[string]interface{}, error) {
+	params := url.Values{}
+	params.Set("a", a)
+	params.Set("b", b)
+	resp, err := c.get("/api/v1/diff?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result, nil
+}
+
+// ImportTorrent imports a torrent file as the model name, the torrent's own
+// name if empty, and seeds it right away if seed is set
+func (c *Client) ImportTorrent(data []byte, name string, seed bool) (map[string]interface{}, error) {
+	params := url.Values{}
+	if name != "" {
+		params.Set("name", name)
+	}
+	if seed {
+		params.Set("seed", "true")
+	}
+	path := "/api/v1/torrents/import"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	
+	req, err := http.NewRequest("POST", c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-bittorrent")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	return result, nil
+}
+
+// GetJob returns a publish job
+func (c *Client) GetJob(id string) (map[string]interface{}, error) {
+	resp, err := c.get(fmt.Sprintf("/api/v1/jobs/%s", id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var job map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return nil, err
+	}
+	
+	return job, nil
+}
+
+// GetConfig returns the running daemon configuration
+func (c *Client) GetConfig() (map[string]interface{}, error) {
+	resp, err := c.get("/api/v1/config")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	
+	return result, nil
+}
+
+// UpdateConfig changes settings of the running daemon
+func (c *Client) UpdateConfig(changes map[string]interface{}) (map[string]interface{}, error) {
+	resp, err := c.patch("/api/v1/config", changes)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	
+	
+	return result, nil
 }
 
 // AutoStartDaemon attempts to start the daemon if it's not running
@@ -336,6 +2220,18 @@ func (c *Client) AutoStartDaemon() error {
 
 // HTTP helper methods
 
+// checkResponse returns the API error of a response whose status is none of
+// the expected ones, as an *apierror.Error
+func checkResponse(resp *http.Response, expected ...int) error {
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return apierror.Decode(resp.StatusCode, body)
+}
+
 func (c *Client) get(path string) (*http.Response, error) {
 	return c.httpClient.Get(c.baseURL + path)
 }
@@ -378,6 +2274,21 @@ func (c *Client) put(path string, body interface{}) (*http.Response, error) {
 	return c.httpClient.Do(req)
 }
 
+func (c *Client) patch(path string, body interface{}) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	
+	req, err := http.NewRequest("PATCH", c.baseURL+path, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	
+	req.Header.Set("Content-Type", "application/json")
+	return c.httpClient.Do(req)
+}
+
 func (c *Client) delete(path string) (*http.Response, error) {
 	req, err := http.NewRequest("DELETE", c.baseURL+path, nil)
 	if err != nil {
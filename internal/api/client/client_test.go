@@ -2,10 +2,17 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
+	"github.com/silmaril/silmaril/internal/api/apierror"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -48,6 +55,58 @@ func TestClientHealthError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestClientCapabilities(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/capabilities", r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"version":      "v0.3.0",
+			"api_versions": []string{"v1", "v2"},
+			"api_version":  "v2",
+			"features":     []string{"catalog_browse"},
+		})
+	}))
+	defer server.Close()
+	
+	caps, err := NewClient(server.URL).Capabilities()
+	require.NoError(t, err)
+	assert.Equal(t, "v0.3.0", caps.Version)
+	assert.True(t, caps.SupportsAPI("v2"))
+	assert.True(t, caps.Has("catalog_browse"))
+	assert.False(t, caps.Has("jobs"))
+}
+
+func TestClientToken(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		switch r.Method {
+		case http.MethodPost:
+			assert.Equal(t, "/api/v1/tokens", r.URL.Path)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{"token": "slm_new", "name": "ci", "scope": "read"})
+		case http.MethodDelete:
+			assert.Equal(t, "/api/v1/tokens/ci", r.URL.Path)
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"code": "forbidden", "message": "needs admin"}})
+		}
+	}))
+	defer server.Close()
+	
+	c := NewClient(server.URL)
+	_, err := c.CreateToken("ci", "read")
+	require.NoError(t, err)
+	assert.Empty(t, auth)
+	
+	c.SetToken("slm_secret")
+	token, err := c.CreateToken("ci", "read")
+	require.NoError(t, err)
+	assert.Equal(t, "slm_new", token)
+	assert.Equal(t, "Bearer slm_secret", auth)
+	
+	err = c.RevokeToken("ci")
+	assert.True(t, errors.Is(err, &apierror.Error{Code: apierror.CodeForbidden}))
+}
+
 func TestClientGetStatus(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/api/v1/status", r.URL.Path)
@@ -143,7 +202,60 @@ func TestClientDownloadModel(t *testing.T) {
 	defer server.Close()
 	
 	client := NewClient(server.URL)
-	result, err := client.DownloadModel("test-model", "hash123", true)
+	result, err := client.DownloadModel("test-model", "hash123", true, nil, false, false, "")
+	require.NoError(t, err)
+	assert.Equal(t, "transfer-123", result["transfer_id"])
+}
+
+func TestClientDownloadModelFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		assert.Equal(t, []interface{}{"*Q4_K_M.gguf"}, req["files"])
+		
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"transfer_id": "transfer-123",
+			"files":       req["files"],
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	result, err := client.DownloadModel("org/model", "hash123", true, []string{"*Q4_K_M.gguf"}, false, false, "")
+	require.NoError(t, err)
+	assert.Equal(t, "transfer-123", result["transfer_id"])
+}
+
+func TestClientDownloadModelLicense(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		
+		if req["accept_license"] != true {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(apierror.Envelope{
+				Error: apierror.New(http.StatusForbidden, "model org/model requires accepting its license").
+					WithCode(apierror.CodeLicenseRequired).
+					WithDetail("license", "llama3"),
+			})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"transfer_id": "transfer-123",
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	_, err := client.DownloadModel("org/model", "hash123", true, nil, false, false, "")
+	var apiErr *apierror.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.CodeLicenseRequired, apiErr.Code)
+	assert.Equal(t, "llama3", apiErr.Details["license"])
+	
+	result, err := client.DownloadModel("org/model", "hash123", true, nil, true, false, "")
 	require.NoError(t, err)
 	assert.Equal(t, "transfer-123", result["transfer_id"])
 }
@@ -215,6 +327,51 @@ func TestClientDiscoverModels(t *testing.T) {
 	assert.Equal(t, "discovered-model", models[0]["name"])
 }
 
+func TestClientDiscoverModelsFiltered(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/discover", r.URL.Path)
+		query := r.URL.Query()
+		assert.Equal(t, []string{"llama", "chat"}, query["tag"])
+		assert.Equal(t, "20GB", query.Get("max_size"))
+		assert.Equal(t, "apache-2.0", query.Get("license"))
+		assert.Equal(t, "2", query.Get("min_seeders"))
+		
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"models": []map[string]interface{}{
+				{"name": "discovered-model", "license": "apache-2.0"},
+			},
+			"count": 1,
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	models, err := client.DiscoverModelsFiltered(DiscoverOptions{
+		Tags:       []string{"llama", "chat"},
+		MaxSize:    "20GB",
+		License:    "apache-2.0",
+		MinSeeders: 2,
+	})
+	require.NoError(t, err)
+	assert.Len(t, models, 1)
+}
+
+func TestClientDiscoverModelsBadRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "invalid max_size",
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	_, err := client.DiscoverModelsFiltered(DiscoverOptions{MaxSize: "big"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid max_size")
+}
+
 func TestClientGetTransfer(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/api/v1/transfers/transfer-123", r.URL.Path)
@@ -316,4 +473,603 @@ func TestClientShutdown(t *testing.T) {
 	client := NewClient(server.URL)
 	err := client.Shutdown()
 	assert.NoError(t, err)
-}
\ No newline at end of file
+}
+func TestClientSubscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/subscriptions", r.URL.Path)
+		assert.Equal(t, "POST", r.Method)
+		
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		assert.Equal(t, "abcd", req["public_key"])
+		assert.Equal(t, "acme", req["name"])
+		
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "subscribed to publisher",
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	result, err := client.Subscribe("abcd", "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "subscribed to publisher", result["message"])
+}
+
+func TestClientSubscribeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "failed to subscribe: invalid publisher key",
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	_, err := client.Subscribe("bad", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid publisher key")
+}
+
+func TestClientListSubscriptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/subscriptions", r.URL.Path)
+		assert.Equal(t, "GET", r.Method)
+		
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"subscriptions": []map[string]interface{}{
+				{"public_key": "abcd", "name": "acme", "models": 3},
+			},
+			"count": 1,
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	subscriptions, err := client.ListSubscriptions()
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	assert.Equal(t, "abcd", subscriptions[0]["public_key"])
+}
+
+func TestClientListNetworks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/networks", r.URL.Path)
+		assert.Equal(t, "GET", r.Method)
+		
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"networks": []map[string]interface{}{
+				{"name": "public", "public_key": "abcd", "loaded": true, "models": 12},
+				{"name": "acme", "public_key": "ef01", "read_only": true},
+			},
+			"count": 2,
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	networks, err := client.ListNetworks()
+	require.NoError(t, err)
+	require.Len(t, networks, 2)
+	assert.Equal(t, "public", networks[0].Name)
+	assert.Equal(t, 12, networks[0].Models)
+	assert.True(t, networks[1].ReadOnly)
+	assert.False(t, networks[1].Loaded)
+}
+
+func TestClientBlocklist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/blocklist":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"entries": []map[string]interface{}{{"pattern": "badorg/*", "reason": "malware"}},
+				"feeds":   []map[string]interface{}{{"url": "https://example.com/models.txt", "entries": 3}},
+				"total":   4,
+			})
+		case "POST /api/v1/blocklist":
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "org/model", body["pattern"])
+			assert.Equal(t, "leaked", body["reason"])
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"entry": map[string]interface{}{"pattern": "org/model", "reason": "leaked"},
+			})
+		case "DELETE /api/v1/blocklist/badorg/*":
+			json.NewEncoder(w).Encode(map[string]interface{}{"pattern": "badorg/*"})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	list, err := client.GetBlocklist()
+	require.NoError(t, err)
+	require.Len(t, list.Entries, 1)
+	assert.Equal(t, "malware", list.Entries[0].Reason)
+	require.Len(t, list.Feeds, 1)
+	assert.Equal(t, 3, list.Feeds[0].Entries)
+	assert.Equal(t, 4, list.Total)
+	
+	entry, err := client.BlockModel("org/model", "leaked")
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", entry.Pattern)
+	
+	require.NoError(t, client.UnblockModel("badorg/*"))
+}
+
+func TestClientUploadSchedule(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/uploads", r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"enabled": true,
+			"total":   1048576,
+			"shares": []map[string]interface{}{
+				{"model": "org/hot", "priority": "normal", "weight": 2, "peers": 12, "upload_rate": 700000, "limit": 699050},
+				{"model": "org/quiet", "priority": "high", "weight": 4, "peers": 1, "upload_rate": 1000, "limit": 65536},
+			},
+		})
+	}))
+	defer server.Close()
+	
+	schedule, err := NewClient(server.URL).GetUploadSchedule()
+	require.NoError(t, err)
+	assert.True(t, schedule.Enabled)
+	assert.Equal(t, int64(1048576), schedule.Total)
+	require.Len(t, schedule.Shares, 2)
+	assert.Equal(t, "high", schedule.Shares[1].Priority)
+	assert.Equal(t, int64(699050), schedule.Shares[0].Limit)
+}
+
+func TestClientConnectionStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/connections", r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"limits":    map[string]interface{}{"max_connections": 100, "max_connections_per_torrent": 50, "max_half_open": 100, "max_half_open_per_torrent": 25},
+			"connected": 42,
+			"half_open": 3,
+			"torrents": []map[string]interface{}{
+				{"model": "org/hot", "connected": 38, "half_open": 3, "known": 120, "limit": 50},
+				{"model": "org/quiet", "connected": 4, "half_open": 0, "known": 4, "limit": 4},
+			},
+		})
+	}))
+	defer server.Close()
+	
+	stats, err := NewClient(server.URL).GetConnectionStats()
+	require.NoError(t, err)
+	assert.Equal(t, 100, stats.Limits.MaxConnections)
+	assert.Equal(t, 25, stats.Limits.HalfOpenPerTorrent)
+	assert.Equal(t, 42, stats.Connected)
+	require.Len(t, stats.Torrents, 2)
+	assert.Equal(t, 50, stats.Torrents[0].Limit)
+	assert.Equal(t, 120, stats.Torrents[0].Known)
+}
+
+func TestClientDHTAnnounces(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/dht/announces", r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"announces": []map[string]interface{}{
+				{"model": "org/model", "info_hash": "abc", "last_attempt": "2026-10-15T09:00:00Z", "last_success": "2026-10-15T09:00:00Z", "nodes": 7, "peers": 12},
+				{"model": "org/lonely", "info_hash": "def", "last_attempt": "2026-10-15T09:00:00Z", "failures": 2, "error": "no DHT node answered"},
+			},
+			"count": 2,
+		})
+	}))
+	defer server.Close()
+	
+	announces, err := NewClient(server.URL).GetDHTAnnounces()
+	require.NoError(t, err)
+	require.Len(t, announces, 2)
+	assert.Equal(t, 7, announces[0].Nodes)
+	require.NotNil(t, announces[0].LastSuccess)
+	assert.Nil(t, announces[1].LastSuccess)
+	assert.Equal(t, 2, announces[1].Failures)
+}
+
+func TestClientManualPeers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			assert.Equal(t, "/api/v1/torrents/abc/peers", r.URL.Path)
+			var req struct {
+				Peers []string `json:"peers"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, []string{"10.0.0.5", "lab-b:6881"}, req.Peers)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"info_hash": "abc",
+				"name":      "org/model",
+				"peers":     []string{"10.0.0.5:42069", "lab-b:6881"},
+				"added":     2,
+			})
+		case http.MethodDelete:
+			assert.Equal(t, "/api/v1/torrents/abc/peers/[fd00::2]:7000", r.URL.Path)
+			json.NewEncoder(w).Encode(map[string]string{"message": "peer removed"})
+		}
+	}))
+	defer server.Close()
+	
+	c := NewClient(server.URL)
+	result, err := c.AddTorrentPeers("abc", []string{"10.0.0.5", "lab-b:6881"})
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", result.Name)
+	assert.Equal(t, 2, result.Added)
+	assert.Equal(t, []string{"10.0.0.5:42069", "lab-b:6881"}, result.Peers)
+	
+	require.NoError(t, c.RemoveTorrentPeer("abc", "[fd00::2]:7000"))
+}
+
+func TestClientSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the daemon socket is a named pipe on Windows")
+	}
+	dir, err := os.MkdirTemp("", "slm")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "daemon.sock")
+	
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/health", r.URL.Path)
+		assert.Equal(t, "Bearer slm_token", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+	
+	// The port in the URL is never dialed
+	client := NewClient("http://127.0.0.1:1")
+	client.SetToken("slm_token")
+	client.SetSocket(path)
+	assert.NoError(t, client.Health())
+}
+
+func TestClientUnsubscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/subscriptions/abcd", r.URL.Path)
+		assert.Equal(t, "DELETE", r.Method)
+		
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	err := client.Unsubscribe("abcd")
+	assert.NoError(t, err)
+}
+
+func TestClientGetLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/logs", r.URL.Path)
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "42", r.URL.Query().Get("since"))
+		
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lines": []map[string]interface{}{
+				{"seq": 43, "text": "[DHT] Bootstrap complete"},
+			},
+			"count":    1,
+			"last_seq": 43,
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	lines, lastSeq, err := client.GetLogs("42", 0)
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "[DHT] Bootstrap complete", lines[0]["text"])
+	assert.Equal(t, int64(43), lastSeq)
+}
+
+func TestClientUpdateConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/config", r.URL.Path)
+		assert.Equal(t, "PATCH", r.Method)
+		
+		var changes map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&changes)
+		
+		if _, ok := changes["network.unknown"]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": "failed to update config: unknown config key \"network.unknown\"",
+			})
+			return
+		}
+		
+		assert.Equal(t, float64(1024), changes["network.upload_rate_limit"])
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"applied":          []string{"network.upload_rate_limit"},
+			"restart_required": []string{},
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	result, err := client.UpdateConfig(map[string]interface{}{"network.upload_rate_limit": 1024})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"network.upload_rate_limit"}, result["applied"])
+	
+	_, err = client.UpdateConfig(map[string]interface{}{"network.unknown": 1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown config key")
+}
+
+func TestClientSubscribeModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/model-subscriptions", r.URL.Path)
+		assert.Equal(t, "POST", r.Method)
+		
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		assert.Equal(t, "org/model", req["model"])
+		assert.Equal(t, true, req["auto_remove"])
+		
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":      "subscribed to model",
+			"subscription": map[string]interface{}{"model": "org/model", "auto_remove": true},
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	result, err := client.SubscribeModel("org/model", true)
+	require.NoError(t, err)
+	assert.Equal(t, "subscribed to model", result["message"])
+}
+
+func TestClientUnsubscribeModelNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/model-subscriptions/org/model", r.URL.Path)
+		assert.Equal(t, "DELETE", r.Method)
+		
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "failed to unsubscribe: not subscribed to model org/model",
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	err := client.UnsubscribeModel("org/model")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not subscribed")
+}
+
+func TestClientScrubModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/scrub", r.URL.Path)
+		assert.Equal(t, "POST", r.Method)
+		
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["model"] == "org/busy" {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": "failed to scrub: a scrub is already running",
+			})
+			return
+		}
+		
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "scrub started",
+			"model":   req["model"],
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	require.NoError(t, client.ScrubModel("org/model"))
+	
+	err := client.ScrubModel("org/busy")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already running")
+}
+
+func TestClientGetEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/events", r.URL.Path)
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "7", r.URL.Query().Get("since"))
+		
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"events": []map[string]interface{}{
+				{"seq": 8, "type": "update.completed", "model": "org/model", "message": "updated to version v2"},
+			},
+			"count":    1,
+			"last_seq": 8,
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	events, lastSeq, err := client.GetEvents(7)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "update.completed", events[0]["type"])
+	assert.Equal(t, int64(8), lastSeq)
+}
+
+func TestClientSetModelKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/models/key", r.URL.Path)
+		assert.Equal(t, "POST", r.Method)
+		
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["key"] == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": "failed to set key: invalid key",
+			})
+			return
+		}
+		
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model_name": req["model_name"],
+			"decrypting": req["key"] != "",
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	decrypting, err := client.SetModelKey("org/model", "00ff", "")
+	require.NoError(t, err)
+	assert.True(t, decrypting)
+	
+	decrypting, err = client.SetModelKey("org/model", "", "token")
+	require.NoError(t, err)
+	assert.False(t, decrypting)
+	
+	_, err = client.SetModelKey("org/model", "bad", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid key")
+}
+
+func TestClientQuarantine(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/quarantine":
+			assert.Equal(t, "GET", r.Method)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"models": []map[string]interface{}{
+					{"model": "org/model", "info_hash": "abc", "status": "failed", "files": []string{"model.safetensors"}},
+				},
+				"count": 1,
+			})
+		case "/api/v1/quarantine/release":
+			assert.Equal(t, "POST", r.Method)
+			var req map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["name"] != "org/model" {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"error": "org/other is not in quarantine",
+				})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"message": "org/model released from quarantine"})
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	models, err := client.ListQuarantine()
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	assert.Equal(t, "failed", models[0].Status)
+	assert.Equal(t, []string{"model.safetensors"}, models[0].Files)
+	
+	require.NoError(t, client.ReleaseQuarantine("org/model"))
+	err = client.ReleaseQuarantine("org/other")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not in quarantine")
+}
+
+func TestClientRotatePublisherKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/publisher/rotate", r.URL.Path)
+		assert.Equal(t, "POST", r.Method)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "publisher key rotated",
+			"rotation": map[string]interface{}{
+				"old_key":     "aa",
+				"new_key":     "bb",
+				"resigned":    2,
+				"republished": 3,
+			},
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	rotation, err := client.RotatePublisherKey()
+	require.NoError(t, err)
+	assert.Equal(t, "aa", rotation.OldKey)
+	assert.Equal(t, "bb", rotation.NewKey)
+	assert.Equal(t, 2, rotation.Resigned)
+	assert.Equal(t, 3, rotation.Republished)
+}
+
+func TestClientCatalogSnapshot(t *testing.T) {
+	snapshot := `{"format":"silmaril-catalog-snapshot/v1","catalog":{"v":1,"seq":4,"m":{}}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/catalog/export":
+			assert.Equal(t, "GET", r.Method)
+			w.Write([]byte(snapshot))
+		case "/api/v1/catalog/import":
+			assert.Equal(t, "POST", r.Method)
+			body, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, snapshot, string(body))
+			json.NewEncoder(w).Encode(map[string]interface{}{"models": 12, "changed": true, "total": 40})
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	data, err := client.ExportCatalog()
+	require.NoError(t, err)
+	assert.JSONEq(t, snapshot, string(data))
+	
+	result, err := client.ImportCatalog(data)
+	require.NoError(t, err)
+	assert.Equal(t, 12, result.Models)
+	assert.True(t, result.Changed)
+	assert.Equal(t, 40, result.Total)
+}
+
+func TestClientTypedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(apierror.Envelope{
+			Error: apierror.New(http.StatusConflict, "failed to remove model: model is in use").
+				WithCode(apierror.CodeModelInUse).
+				WithDetail("model", "org/model"),
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	_, err := client.PurgeModel("org/model")
+	require.Error(t, err)
+	
+	var apiErr *apierror.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.CodeModelInUse, apiErr.Code)
+	assert.Equal(t, http.StatusConflict, apiErr.Status)
+	assert.Equal(t, "org/model", apiErr.Details["model"])
+	assert.ErrorIs(t, err, &apierror.Error{Code: apierror.CodeModelInUse})
+	assert.Equal(t, "failed to remove model: model is in use", err.Error())
+}
+
+func TestClientErrorsWithoutEnvelope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL)
+	_, err := client.GetTransfer("missing")
+	
+	var apiErr *apierror.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.CodeNotFound, apiErr.Code)
+}
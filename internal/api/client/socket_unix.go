@@ -0,0 +1,14 @@
+//go:build !windows
+
+package client
+
+import (
+	"context"
+	"net"
+)
+
+// dialSocket connects to the Unix domain socket at path
+func dialSocket(ctx context.Context, path string) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "unix", path)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListAliases returns the aliases of the local models
+func (h *Handlers) ListAliases(c *gin.Context) {
+	aliases, err := h.daemon.ListAliases()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to list aliases: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"aliases": aliases,
+		"count":   len(aliases),
+	})
+}
+
+// SetAlias lets a local model be referred to by another name
+func (h *Handlers) SetAlias(c *gin.Context) {
+	var req struct {
+		Alias string `json:"alias" binding:"required"`
+		Model string `json:"model" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	var ok bool
+	if req.Alias, ok = canonicalModelName(c, req.Alias); !ok {
+		return
+	}
+	if req.Model, ok = canonicalModelName(c, req.Model); !ok {
+		return
+	}
+
+	model, err := h.daemon.SetAlias(req.Alias, req.Model)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to set alias: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "alias set",
+		"alias":   req.Alias,
+		"model":   model,
+	})
+}
+
+// RemoveAlias deletes an alias, the model it points to is kept
+func (h *Handlers) RemoveAlias(c *gin.Context) {
+	// Aliases may contain a slash, so the route uses a wildcard parameter
+	alias := strings.TrimPrefix(c.Param("alias"), "/")
+
+	if err := h.daemon.RemoveAlias(alias); err != nil {
+		respondAPIError(c, daemonError(http.StatusInternalServerError, "failed to remove alias", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "alias removed",
+		"alias":   alias,
+	})
+}
+
+// RenameModel renames a local model, keeping the old name as an alias
+// unless keep_alias is false
+func (h *Handlers) RenameModel(c *gin.Context) {
+	var req struct {
+		From      string `json:"from" binding:"required"`
+		To        string `json:"to" binding:"required"`
+		KeepAlias *bool  `json:"keep_alias"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	var ok bool
+	if req.From, ok = canonicalModelName(c, req.From); !ok {
+		return
+	}
+	if req.To, ok = canonicalModelName(c, req.To); !ok {
+		return
+	}
+
+	keepAlias := req.KeepAlias == nil || *req.KeepAlias
+	result, err := h.daemon.RenameModel(req.From, req.To, keepAlias)
+	if err != nil && result == nil {
+		respondAPIError(c, daemonError(http.StatusBadRequest, "failed to rename model", err))
+		return
+	}
+
+	response := gin.H{
+		"message": "model renamed",
+		"result":  result,
+	}
+	if err != nil {
+		// The model moved but not every torrent is seeded again
+		response["warnings"] = []string{err.Error()}
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListBlocklist returns the local entries of the model blocklist and the
+// feeds it follows
+func (h *Handlers) ListBlocklist(c *gin.Context) {
+	c.JSON(http.StatusOK, h.daemon.ModelBlocklist())
+}
+
+// BlockModel adds an infohash or a pattern of model names to the model
+// blocklist, stopping the torrents of the models it blocks
+func (h *Handlers) BlockModel(c *gin.Context) {
+	var req struct {
+		Pattern string `json:"pattern" binding:"required"`
+		Reason  string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	blocked, err := h.daemon.BlockModel(req.Pattern, req.Reason)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to block model: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "model blocked",
+		"entry":   blocked,
+	})
+}
+
+// UnblockModel removes an entry from the model blocklist
+func (h *Handlers) UnblockModel(c *gin.Context) {
+	// Patterns may contain a slash, so the route uses a wildcard parameter
+	pattern := strings.TrimPrefix(c.Param("pattern"), "/")
+
+	if err := h.daemon.UnblockModel(pattern); err != nil {
+		respondAPIError(c, daemonError(http.StatusBadRequest, "failed to unblock model", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "model unblocked",
+		"pattern": pattern,
+	})
+}
+
+// ReloadBlocklist downloads the model blocklist feeds again
+func (h *Handlers) ReloadBlocklist(c *gin.Context) {
+	c.JSON(http.StatusOK, h.daemon.ReloadModelBlocklist(true))
+}
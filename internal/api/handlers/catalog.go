@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/discovery"
+)
+
+// Largest request body accepted as a catalog snapshot
+const maxCatalogSnapshot = 32 << 20
+
+// ExportCatalog returns a snapshot of the public catalog, for communities
+// to serve to nodes that haven't reached the DHT yet
+func (h *Handlers) ExportCatalog(c *gin.Context) {
+	snapshot, err := h.daemon.ExportCatalog()
+	if err != nil {
+		respondAPIError(c, daemonError(http.StatusInternalServerError, "failed to export catalog", err))
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="catalog.json"`)
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// ImportCatalog merges the catalog snapshot in the request body into the
+// public catalog
+func (h *Handlers) ImportCatalog(c *gin.Context) {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCatalogSnapshot+1))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to read catalog snapshot: %v", err))
+		return
+	}
+	if len(data) == 0 {
+		respondError(c, http.StatusBadRequest, "catalog snapshot is required")
+		return
+	}
+	if len(data) > maxCatalogSnapshot {
+		respondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("catalog snapshot is larger than %d MB", maxCatalogSnapshot>>20))
+		return
+	}
+
+	snapshot, err := discovery.ParseSnapshot(data)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := h.daemon.ImportCatalog(snapshot)
+	if err != nil {
+		respondAPIError(c, daemonError(http.StatusInternalServerError, "failed to import catalog", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/config"
+)
+
+// GetConfig returns the running configuration, where each value comes from
+// and which keys can be changed without a restart
+func (h *Handlers) GetConfig(c *gin.Context) {
+	settings, err := config.Settings()
+	if err != nil {
+		respondError(c, http.StatusServiceUnavailable, fmt.Sprintf("failed to get config: %v", err))
+		return
+	}
+	sources, err := config.Sources()
+	if err != nil {
+		respondError(c, http.StatusServiceUnavailable, fmt.Sprintf("failed to get config: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"settings":        settings,
+		"sources":         sources,
+		"live_keys":       config.LiveKeys(),
+		"restart_keys":    config.RestartKeys(),
+		"pending_restart": config.PendingRestart(),
+	})
+}
+
+// UpdateConfig changes settings of the running daemon. The body maps config
+// keys like "network.upload_rate_limit" to their new values.
+func (h *Handlers) UpdateConfig(c *gin.Context) {
+	var changes map[string]interface{}
+	if err := c.ShouldBindJSON(&changes); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	if len(changes) == 0 {
+		respondError(c, http.StatusBadRequest, "no settings to change")
+		return
+	}
+
+	change, err := h.daemon.UpdateConfig(changes)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to update config: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"applied":          change.Applied,
+		"restart_required": change.RestartRequired,
+	})
+}
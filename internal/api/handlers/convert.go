@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListConversions returns the recent conversions of local models
+func (h *Handlers) ListConversions(c *gin.Context) {
+	conversions := h.daemon.Conversions()
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversions": conversions,
+		"count":       len(conversions),
+	})
+}
+
+// ConvertModel queues conversions of a local model into derived variants
+func (h *Handlers) ConvertModel(c *gin.Context) {
+	var req struct {
+		Model    string   `json:"model" binding:"required"`
+		Variants []string `json:"variants"`
+		Publish  bool     `json:"publish"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	var ok bool
+	if req.Model, ok = canonicalModelName(c, req.Model); !ok {
+		return
+	}
+
+	conversions, err := h.daemon.ConvertModel(req.Model, req.Variants, req.Publish)
+	if err != nil {
+		respondAPIError(c, daemonError(http.StatusBadRequest, "failed to convert", err))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":     "conversion queued",
+		"conversions": conversions,
+	})
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/daemon"
+	"github.com/silmaril/silmaril/pkg/types"
 )
 
 // DiscoverModels searches for models on the P2P network
@@ -14,12 +18,40 @@ func (h *Handlers) DiscoverModels(c *gin.Context) {
 		pattern = "*" // Search for all models
 	}
 	
+	filter, err := searchFilterFromQuery(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Pattern = pattern
+	filter.Network = c.Query("network")
+	
+	if checkHealth := c.Query("check_health"); checkHealth != "" {
+		check, err := strconv.ParseBool(checkHealth)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid check_health: %s", checkHealth))
+			return
+		}
+		filter.CheckHealth = check
+	}
+	
+	if minSeeders := c.Query("min_seeders"); minSeeders != "" {
+		seeders, err := strconv.Atoi(minSeeders)
+		if err != nil || seeders < 0 {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid min_seeders: %s", minSeeders))
+			return
+		}
+		filter.MinSeeders = seeders
+	}
+	
 	// Search via DHT
-	results, err := h.daemon.GetDHTManager().DiscoverModels(pattern)
+	results, err := h.daemon.GetDHTManager().SearchModels(filter)
+	if errors.Is(err, daemon.ErrUnknownNetwork) {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to discover models: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to discover models: %v", err))
 		return
 	}
 	
@@ -28,4 +60,140 @@ func (h *Handlers) DiscoverModels(c *gin.Context) {
 		"count":   len(results),
 		"pattern": pattern,
 	})
-}
\ No newline at end of file
+}
+
+// searchFilterFromQuery reads the pattern, tag, license and max_size
+// filters of a catalog search
+func searchFilterFromQuery(c *gin.Context) (types.SearchFilter, error) {
+	filter := types.SearchFilter{
+		Pattern: c.Query("pattern"),
+		Tags:    c.QueryArray("tag"),
+		License: c.Query("license"),
+	}
+	if maxSize := c.Query("max_size"); maxSize != "" {
+		size, err := types.ParseSize(maxSize)
+		if err != nil {
+			return filter, fmt.Errorf("invalid max_size: %v", err)
+		}
+		filter.MaxSize = size
+	}
+	return filter, nil
+}
+
+// BrowseCatalog returns a page of the catalog, sorted by the sort query
+// parameter (added, size, seeders or name) in the direction of order (asc
+// or desc). Pages continue from cursor, the next_cursor of the previous
+// page, or start at offset. Unlike discover it doesn't wait for the DHT.
+func (h *Handlers) BrowseCatalog(c *gin.Context) {
+	filter, err := searchFilterFromQuery(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	sortBy, desc, err := daemon.ParseCatalogSort(c.Query("sort"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	switch order := c.Query("order"); order {
+	case "":
+	case "asc", "desc":
+		desc = order == "desc"
+	default:
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid order: %s, use asc or desc", order))
+		return
+	}
+	
+	filter.Network = c.Query("network")
+	query := daemon.CatalogQuery{Filter: filter, Sort: sortBy, Desc: desc, Cursor: c.Query("cursor")}
+	for name, value := range map[string]*int{"limit": &query.Limit, "offset": &query.Offset} {
+		if s := c.Query(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid %s: %s", name, s))
+				return
+			}
+			*value = n
+		}
+	}
+	
+	dm := h.daemon.GetDHTManager()
+	if dm == nil {
+		respondError(c, http.StatusServiceUnavailable, "catalog not available")
+		return
+	}
+	page, err := dm.BrowseCatalog(query)
+	if errors.Is(err, daemon.ErrInvalidCursor) || errors.Is(err, daemon.ErrUnknownNetwork) {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// SearchHub searches the HuggingFace Hub for the q query parameter and marks
+// the models that can be downloaded from peers or are stored locally
+func (h *Handlers) SearchHub(c *gin.Context) {
+	term := c.Query("q")
+	if term == "" {
+		respondError(c, http.StatusBadRequest, "q is required")
+		return
+	}
+	
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid limit: %s", value))
+			return
+		}
+	}
+	
+	results, err := h.daemon.SearchHub(term, limit)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, err.Error())
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"models": results,
+		"count":  len(results),
+		"query":  term,
+	})
+}
+
+// UnpublishModel withdraws a model we published from discovery
+func (h *Handlers) UnpublishModel(c *gin.Context) {
+	var req struct {
+		ModelName string `json:"model_name" binding:"required"`
+	}
+	
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	
+	if err := h.daemon.GetDHTManager().UnpublishModel(req.ModelName); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to unpublish model: %v", err))
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "model unpublished",
+		"model_name": req.ModelName,
+	})
+}
+// WatchDiscovery makes the daemon refresh the catalog every minute for a few
+// minutes and publish a catalog.added event for each model that appears.
+// Clients call it again to keep watching.
+func (h *Handlers) WatchDiscovery(c *gin.Context) {
+	until := h.daemon.WatchCatalog()
+	c.JSON(http.StatusOK, gin.H{
+		"watching_until": until,
+	})
+}
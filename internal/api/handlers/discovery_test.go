@@ -185,4 +185,31 @@ func TestDiscoverModelsConcurrent(t *testing.T) {
 	for i := 0; i < 3; i++ {
 		<-done
 	}
-}
\ No newline at end of file
+}
+func TestBrowseCatalogValidation(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	
+	router := gin.New()
+	router.GET("/catalog", h.BrowseCatalog)
+	
+	for _, query := range []string{"sort=popularity", "order=up", "limit=-1", "offset=x", "max_size=big"} {
+		req, _ := http.NewRequest("GET", "/catalog?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+	
+	req, _ := http.NewRequest("GET", "/catalog?sort=size&limit=10", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code == http.StatusOK {
+		var page map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, "size", page["sort"])
+		assert.Equal(t, "desc", page["order"])
+		assert.Equal(t, float64(10), page["limit"])
+	} else {
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetModelKey stores the key of an encrypted model, or the token for its key
+// endpoint, and decrypts the model if it is already downloaded
+func (h *Handlers) SetModelKey(c *gin.Context) {
+	var req struct {
+		ModelName string `json:"model_name" binding:"required"`
+		Key       string `json:"key"`
+		KeyToken  string `json:"key_token"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	var ok bool
+	if req.ModelName, ok = canonicalModelName(c, req.ModelName); !ok {
+		return
+	}
+
+	decrypting, err := h.daemon.SetModelKey(h.daemon.ResolveModel(req.ModelName), req.Key, req.KeyToken)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to set key: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"model_name": req.ModelName,
+		"decrypting": decrypting,
+	})
+}
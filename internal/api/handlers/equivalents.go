@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeclareEquivalents records torrents holding the same files as a local
+// model, checked against its files, and announces them with the model
+func (h *Handlers) DeclareEquivalents(c *gin.Context) {
+	var req struct {
+		Model      string   `json:"model" binding:"required"`
+		InfoHashes []string `json:"info_hashes" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	var ok bool
+	if req.Model, ok = canonicalModelName(c, req.Model); !ok {
+		return
+	}
+
+	manifest, err := h.daemon.DeclareEquivalents(req.Model, req.InfoHashes)
+	if err != nil {
+		respondAPIError(c, daemonError(http.StatusBadRequest, "failed to declare equivalent torrents", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "equivalent torrents declared",
+		"model":       manifest.Name,
+		"equivalents": manifest.Equivalents,
+	})
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/api/apierror"
+	"github.com/silmaril/silmaril/internal/daemon"
+	"github.com/silmaril/silmaril/internal/modelname"
+)
+
+// respondError writes an error response with the code matching status
+func respondError(c *gin.Context, status int, message string) {
+	respondAPIError(c, apierror.New(status, message))
+}
+
+// respondAPIError writes an error response in the API error envelope
+func respondAPIError(c *gin.Context, e *apierror.Error) {
+	c.JSON(e.Status, apierror.Envelope{Error: e})
+}
+
+// daemonError describes a failed daemon call. Errors whose cause the daemon
+// knows get the status and code of that cause instead of status.
+func daemonError(status int, message string, err error) *apierror.Error {
+	message = fmt.Sprintf("%s: %v", message, err)
+
+	var licenseErr *daemon.LicenseError
+	switch {
+	case errors.As(err, &licenseErr):
+		return apierror.New(http.StatusForbidden, err.Error()).
+			WithCode(apierror.CodeLicenseRequired).
+			WithDetail("model", licenseErr.Model).
+			WithDetail("license", licenseErr.License).
+			WithDetail("license_url", licenseErr.LicenseURL)
+	case errors.Is(err, daemon.ErrModelBlocked):
+		return apierror.New(http.StatusForbidden, message).WithCode(apierror.CodeModelBlocked)
+	case errors.Is(err, daemon.ErrModelInUse):
+		return apierror.New(http.StatusConflict, message).WithCode(apierror.CodeModelInUse)
+	case errors.Is(err, daemon.ErrAliasNotFound), errors.Is(err, daemon.ErrTorrentNotFound),
+		errors.Is(err, daemon.ErrModelNotFound), errors.Is(err, daemon.ErrNotBlocked),
+		errors.Is(err, daemon.ErrManualPeerNotFound):
+		return apierror.New(http.StatusNotFound, message)
+	case errors.Is(err, daemon.ErrInvalidPeer), errors.Is(err, modelname.ErrInvalid):
+		return apierror.New(http.StatusBadRequest, message)
+	case errors.Is(err, daemon.ErrNoTransferStats), errors.Is(err, daemon.ErrTorrentLoaded):
+		return apierror.New(http.StatusConflict, message)
+	case errors.Is(err, daemon.ErrCatalogUnavailable):
+		return apierror.New(http.StatusServiceUnavailable, message)
+	}
+	return apierror.New(status, message)
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CollectGarbage removes orphaned torrents, downloads and temporary files,
+// or only lists them with dry_run=true
+func (h *Handlers) CollectGarbage(c *gin.Context) {
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid dry_run: %s", value))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, h.daemon.CollectGarbage(dryRun))
+}
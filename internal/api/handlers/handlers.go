@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/api/apiversion"
 	"github.com/silmaril/silmaril/internal/daemon"
 )
 
@@ -26,12 +29,58 @@ func (h *Handlers) Health(c *gin.Context) {
 	})
 }
 
+// Healthz reports whether the daemon is live: its torrent client runs and
+// its storage is writable. Responds 503 otherwise, so the container can be
+// restarted.
+func (h *Handlers) Healthz(c *gin.Context) {
+	report := h.daemon.Health()
+	status := http.StatusOK
+	if !report.Live {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// Readyz reports whether the daemon is ready to serve: it is live, the DHT
+// is bootstrapped and it isn't shutting down. Responds 503 otherwise.
+func (h *Handlers) Readyz(c *gin.Context) {
+	report := h.daemon.Health()
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
 // Status returns daemon status information
 func (h *Handlers) Status(c *gin.Context) {
 	status := h.daemon.GetStatus()
 	c.JSON(http.StatusOK, status)
 }
 
+// StatusV2 returns daemon status information with the uptime as an object
+// instead of a duration string
+func (h *Handlers) StatusV2(c *gin.Context) {
+	status := h.daemon.GetStatus()
+	status["uptime"] = status["uptime_info"]
+	delete(status, "uptime_info")
+	c.JSON(http.StatusOK, status)
+}
+
+// Capabilities returns the API versions and features of the daemon and the
+// endpoints being retired, for clients of other versions to negotiate with
+func (h *Handlers) Capabilities(c *gin.Context) {
+	build := h.daemon.BuildInfo()
+	c.JSON(http.StatusOK, apiversion.Capabilities{
+		Version:      build.Version,
+		Commit:       build.Commit,
+		APIVersions:  apiversion.Supported,
+		APIVersion:   apiversion.Current,
+		Features:     apiversion.Features,
+		Deprecations: apiversion.Deprecations,
+	})
+}
+
 // Shutdown gracefully shuts down the daemon
 func (h *Handlers) Shutdown(c *gin.Context) {
 	// Schedule shutdown after response
@@ -43,4 +92,45 @@ func (h *Handlers) Shutdown(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "daemon shutting down",
 	})
+}
+
+// Logs returns recent daemon output. since is either the sequence number of
+// the last line the caller has seen or a duration like 10m, tail limits the
+// result to the last n lines.
+func (h *Handlers) Logs(c *gin.Context) {
+	logs := h.daemon.GetLogBuffer()
+	if logs == nil {
+		respondError(c, http.StatusServiceUnavailable, "log capture is not enabled for this daemon")
+		return
+	}
+	
+	var lines []daemon.LogLine
+	since := c.Query("since")
+	if since == "" {
+		lines = logs.Since(0)
+	} else if seq, err := strconv.ParseInt(since, 10, 64); err == nil {
+		lines = logs.Since(seq)
+	} else if duration, err := time.ParseDuration(since); err == nil {
+		lines = logs.SinceTime(time.Now().Add(-duration))
+	} else {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid since: %s", since))
+		return
+	}
+	
+	if tail := c.Query("tail"); tail != "" {
+		n, err := strconv.Atoi(tail)
+		if err != nil || n < 0 {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid tail: %s", tail))
+			return
+		}
+		if len(lines) > n {
+			lines = lines[len(lines)-n:]
+		}
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"lines":    lines,
+		"count":    len(lines),
+		"last_seq": logs.LastSeq(),
+	})
 }
\ No newline at end of file
@@ -14,6 +14,13 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// apiError returns the error envelope of a response
+func apiError(t *testing.T, response map[string]interface{}) map[string]interface{} {
+	e, ok := response["error"].(map[string]interface{})
+	require.True(t, ok, "response has no error envelope: %v", response)
+	return e
+}
+
 func setupTestHandlers(t *testing.T) (*Handlers, *daemon.Daemon) {
 	// Set test mode for Gin
 	gin.SetMode(gin.TestMode)
@@ -125,4 +132,34 @@ func TestShutdownEndpoint(t *testing.T) {
 	require.NoError(t, err)
 
 	assert.Equal(t, "daemon shutting down", response["message"])
-}
\ No newline at end of file
+}
+func TestHealthzAndReadyz(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+
+	router := gin.New()
+	router.GET("/healthz", h.Healthz)
+	router.GET("/readyz", h.Readyz)
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, "%s: %s", path, w.Body.String())
+
+		var report daemon.HealthReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, daemon.HealthOK, report.Checks["torrent_client"].Status)
+		assert.Equal(t, daemon.HealthOK, report.Checks["storage"].Status)
+		assert.Equal(t, daemon.HealthDisabled, report.Checks["dht"].Status)
+	}
+
+	// A daemon that has shut down is neither live nor ready
+	d.Shutdown()
+	for _, path := range []string{"/healthz", "/readyz"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, path)
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/proxy"
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// hfRepo is a model repository requested through the HuggingFace Hub API
+type hfRepo struct {
+	ID       string // org/name, or name for repositories without organization
+	Revision string
+}
+
+// hfSnapshot is a local model the proxy can serve without the upstream hub
+type hfSnapshot struct {
+	Commit string
+	Files  []string
+	Dir    string
+}
+
+// HFProxy serves the HuggingFace Hub API to HF clients that point
+// HF_ENDPOINT at the proxy. Model files and model info come from the local
+// store, after models missing there are downloaded from peers, and from the
+// upstream hub only if Silmaril has no copy. All other requests are passed
+// to the upstream hub.
+func (h *Handlers) HFProxy(c *gin.Context) {
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		if repo, ok := parseHFModelInfoPath(c.Request.URL.Path); ok {
+			h.hfModelInfo(c, repo)
+			return
+		}
+		if repo, file, ok := parseHFResolvePath(c.Request.URL.Path); ok {
+			h.hfResolve(c, repo, file)
+			return
+		}
+	}
+	h.hfUpstream(c)
+}
+
+// hfModelInfo answers /api/models/<repo>[/revision/<revision>]
+func (h *Handlers) hfModelInfo(c *gin.Context, repo hfRepo) {
+	snapshot := h.hfFindSnapshot(c.Request.Context(), repo)
+	if snapshot == nil {
+		h.hfUpstream(c)
+		return
+	}
+
+	siblings := make([]gin.H, 0, len(snapshot.Files))
+	for _, file := range snapshot.Files {
+		siblings = append(siblings, gin.H{"rfilename": file})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"_id":          repo.ID,
+		"id":           repo.ID,
+		"modelId":      repo.ID,
+		"sha":          snapshot.Commit,
+		"private":      false,
+		"disabled":     false,
+		"gated":        false,
+		"lastModified": time.Now().UTC().Format(time.RFC3339),
+		"tags":         []string{"silmaril"},
+		"siblings":     siblings,
+	})
+}
+
+// hfResolve answers /<repo>/resolve/<revision>/<file>
+func (h *Handlers) hfResolve(c *gin.Context, repo hfRepo, file string) {
+	snapshot := h.hfFindSnapshot(c.Request.Context(), repo)
+	if snapshot == nil {
+		h.hfUpstream(c)
+		return
+	}
+
+	c.Header("X-Repo-Commit", snapshot.Commit)
+	c.Header("ETag", fmt.Sprintf("%q", models.HFBlobID(repo.ID, file)))
+
+	path := filepath.Join(snapshot.Dir, filepath.FromSlash(file))
+	rel, err := filepath.Rel(snapshot.Dir, path)
+	info, statErr := os.Stat(path)
+	if err != nil || strings.HasPrefix(rel, "..") || statErr != nil || info.IsDir() {
+		hfEntryNotFound(c, repo, file)
+		return
+	}
+	fmt.Printf("[HFProxy] Serving %s/%s from local store\n", repo.ID, file)
+	http.ServeFile(c.Writer, c.Request, path)
+}
+
+// hfFindSnapshot returns the local copy of repo, downloading it from peers
+// if it is in the catalog, or nil if the request has to go to the upstream
+// hub
+func (h *Handlers) hfFindSnapshot(ctx context.Context, repo hfRepo) *hfSnapshot {
+	// A specific commit can only be served if it is the one we have
+	wants := func(commit string) bool {
+		return !models.IsHFCommitHash(repo.Revision) || repo.Revision == commit
+	}
+
+	modelDir, err := h.daemon.GetRegistry().Paths().ModelDir(repo.ID)
+	if err != nil {
+		return nil
+	}
+	if snapshot := hfLocalSnapshot(repo.ID, modelDir, wants); snapshot != nil {
+		return snapshot
+	}
+
+	model := h.daemon.FindCatalogModel(repo.ID)
+	if model == nil || !wants(strings.ToLower(model.InfoHash)) {
+		return nil
+	}
+	// The hub gates the model itself
+	if err := h.daemon.CheckLicense(model, false); err != nil {
+		fmt.Printf("[HFProxy] Not fetching %s from peers: %v\n", repo.ID, err)
+		return nil
+	}
+
+	if _, err := h.daemon.FetchModel(repo.ID, model.InfoHash); err != nil {
+		fmt.Printf("[HFProxy] Failed to fetch %s from peers: %v\n", repo.ID, err)
+		return nil
+	}
+
+	// Files are only served once the download is verified and released
+	// from quarantine, until then they could be anything peers sent. If it
+	// takes longer fall back to the hub and let the download go on.
+	timeout := time.NewTimer(h.daemon.HFProxyP2PTimeout())
+	defer timeout.Stop()
+	poll := time.NewTicker(time.Second)
+	defer poll.Stop()
+	for {
+		select {
+		case <-poll.C:
+			if snapshot := hfLocalSnapshot(repo.ID, modelDir, wants); snapshot != nil {
+				return snapshot
+			}
+		case <-timeout.C:
+			fmt.Printf("[HFProxy] %s is not downloaded yet, falling back to %s\n", repo.ID, h.daemon.HFProxyUpstream())
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// hfLocalSnapshot returns the copy of the model id in dir, if it is a
+// complete local model of a commit wanted
+func hfLocalSnapshot(id, dir string, wants func(commit string) bool) *hfSnapshot {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() || storage.IsPartial(dir) || storage.IsQuarantined(dir) {
+		return nil
+	}
+	commit, files, err := models.HFSnapshot(id, dir)
+	if err != nil || !wants(commit) {
+		return nil
+	}
+	return &hfSnapshot{Commit: commit, Files: files, Dir: dir}
+}
+
+// hfUpstream passes the request to the upstream hub
+func (h *Handlers) hfUpstream(c *gin.Context) {
+	upstream, err := url.Parse(h.daemon.HFProxyUpstream())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": fmt.Sprintf("invalid upstream %q: %v", h.daemon.HFProxyUpstream(), err),
+		})
+		return
+	}
+
+	hubProxy, err := h.daemon.Proxy(proxy.Git)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(upstream)
+	proxy.Transport = hubProxy.Transport()
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Host = upstream.Host
+	}
+	proxy.FlushInterval = -1
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		fmt.Printf("[HFProxy] Upstream request %s failed: %v\n", r.URL.Path, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": fmt.Sprintf("upstream hub unreachable: %v", err),
+		})
+	}
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// hfEntryNotFound answers like the hub does for missing files, which HF
+// clients turn into EntryNotFoundError
+func hfEntryNotFound(c *gin.Context, repo hfRepo, file string) {
+	c.Header("X-Error-Code", "EntryNotFound")
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": fmt.Sprintf("%s does not exist in %s", file, repo.ID),
+	})
+}
+
+// parseHFModelInfoPath parses /api/models/<repo>[/revision/<revision>]
+func parseHFModelInfoPath(path string) (hfRepo, bool) {
+	rest, ok := strings.CutPrefix(path, "/api/models/")
+	if !ok {
+		return hfRepo{}, false
+	}
+
+	repo := hfRepo{Revision: "main"}
+	if id, revision, found := strings.Cut(rest, "/revision/"); found {
+		rest, repo.Revision = id, revision
+	}
+	if !validHFRepoID(rest) {
+		return hfRepo{}, false
+	}
+	repo.ID = rest
+	return repo, true
+}
+
+// parseHFResolvePath parses /<repo>/resolve/<revision>/<file>
+func parseHFResolvePath(path string) (hfRepo, string, bool) {
+	id, rest, found := strings.Cut(strings.TrimPrefix(path, "/"), "/resolve/")
+	if !found || !validHFRepoID(id) {
+		return hfRepo{}, "", false
+	}
+	revision, file, found := strings.Cut(rest, "/")
+	if !found || revision == "" || file == "" {
+		return hfRepo{}, "", false
+	}
+	return hfRepo{ID: id, Revision: revision}, file, true
+}
+
+// validHFRepoID accepts model repository IDs that are model names without a
+// version, but not datasets, spaces or other hub paths
+func validHFRepoID(id string) bool {
+	if modelname.Validate(id) != nil || strings.Contains(id, "@") {
+		return false
+	}
+	first, _, _ := strings.Cut(id, "/")
+	switch first {
+	case "api", "datasets", "spaces", "models":
+		return false
+	}
+	return true
+}
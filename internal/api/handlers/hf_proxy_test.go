@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/daemon"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupHFProxy(t *testing.T, upstream string) (*gin.Engine, *daemon.Daemon) {
+	gin.SetMode(gin.TestMode)
+
+	tmpDir := t.TempDir()
+	t.Setenv("SILMARIL_HOME", tmpDir)
+
+	cfg := &config.Config{
+		Storage: config.StorageConfig{
+			BaseDir: tmpDir,
+		},
+		Network: config.NetworkConfig{
+			DHTEnabled: false,
+			ListenPort: 0,
+		},
+		HFProxy: config.HFProxyConfig{
+			Upstream:          upstream,
+			P2PTimeoutSeconds: 1,
+		},
+	}
+
+	d, err := daemon.New(cfg)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.NoRoute(NewHandlers(d).HFProxy)
+	return router, d
+}
+
+func TestParseHFPaths(t *testing.T) {
+	repo, ok := parseHFModelInfoPath("/api/models/org/model")
+	assert.True(t, ok)
+	assert.Equal(t, hfRepo{ID: "org/model", Revision: "main"}, repo)
+
+	repo, ok = parseHFModelInfoPath("/api/models/gpt2/revision/v1.0")
+	assert.True(t, ok)
+	assert.Equal(t, hfRepo{ID: "gpt2", Revision: "v1.0"}, repo)
+
+	_, ok = parseHFModelInfoPath("/api/models/org/model/tree/main")
+	assert.False(t, ok)
+
+	repo, file, ok := parseHFResolvePath("/org/model/resolve/main/onnx/model.onnx")
+	assert.True(t, ok)
+	assert.Equal(t, hfRepo{ID: "org/model", Revision: "main"}, repo)
+	assert.Equal(t, "onnx/model.onnx", file)
+
+	for _, path := range []string{
+		"/datasets/org/data/resolve/main/train.csv",
+		"/org/model/resolve/main",
+		"/org/../resolve/main/config.json",
+		"/org/model@v1/resolve/main/config.json",
+		"/org/.hidden/resolve/main/config.json",
+		"/org/model/blob/main/config.json",
+	} {
+		_, _, ok = parseHFResolvePath(path)
+		assert.False(t, ok, path)
+	}
+}
+
+func TestHFProxyLocalModel(t *testing.T) {
+	router, d := setupHFProxy(t, "http://127.0.0.1:1")
+	defer d.Shutdown()
+
+	modelDir := filepath.Join(storage.GetModelsDir(), "org", "model")
+	require.NoError(t, os.MkdirAll(modelDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "config.json"), []byte(`{"model_type": "llama"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "model.safetensors"), []byte("0123456789"), 0644))
+
+	// Model info lists the files and the commit they are served with
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/models/org/model", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var info struct {
+		ID       string              `json:"id"`
+		SHA      string              `json:"sha"`
+		Siblings []map[string]string `json:"siblings"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, "org/model", info.ID)
+	assert.Len(t, info.SHA, 40)
+	assert.Equal(t, []map[string]string{{"rfilename": "config.json"}, {"rfilename": "model.safetensors"}}, info.Siblings)
+
+	// HF clients probe files with HEAD before downloading them
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/org/model/resolve/main/model.safetensors", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, info.SHA, w.Header().Get("X-Repo-Commit"))
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+	assert.Equal(t, "10", w.Header().Get("Content-Length"))
+
+	req := httptest.NewRequest("GET", "/org/model/resolve/"+info.SHA+"/model.safetensors", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "2345", w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/org/model/resolve/main/missing.json", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "EntryNotFound", w.Header().Get("X-Error-Code"))
+}
+
+func TestHFProxyUpstreamFallback(t *testing.T) {
+	var requested []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		io.WriteString(w, "upstream")
+	}))
+	defer upstream.Close()
+
+	router, d := setupHFProxy(t, upstream.URL)
+	defer d.Shutdown()
+
+	// A local model at a commit we don't have, a model Silmaril doesn't know
+	// and other hub endpoints all go upstream
+	modelDir := filepath.Join(storage.GetModelsDir(), "org", "model")
+	require.NoError(t, os.MkdirAll(modelDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "config.json"), []byte(`{}`), 0644))
+
+	// The reverse proxy needs a real connection, a recorder can't be proxied
+	proxy := httptest.NewServer(router)
+	defer proxy.Close()
+
+	for _, path := range []string{
+		"/org/model/resolve/0123456789abcdef0123456789abcdef01234567/config.json",
+		"/org/unknown/resolve/main/config.json",
+		"/api/whoami-v2",
+	} {
+		resp, err := http.Get(proxy.URL + path)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+		assert.Equal(t, "upstream", string(body), path)
+	}
+	assert.Equal(t, []string{
+		"/org/model/resolve/0123456789abcdef0123456789abcdef01234567/config.json",
+		"/org/unknown/resolve/main/config.json",
+		"/api/whoami-v2",
+	}, requested)
+
+	// Downloads are only served once they are released from quarantine
+	require.NoError(t, storage.MarkQuarantined(modelDir, []byte("{}")))
+	resp, err := http.Get(proxy.URL + "/org/model/resolve/main/config.json")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "upstream", string(body))
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/daemon"
+)
+
+// History returns the transfers that ended, the most recent first. They can
+// be filtered by model, type, result and age.
+func (h *Handlers) History(c *gin.Context) {
+	filter := daemon.HistoryFilter{
+		Model:  c.Query("model"),
+		Type:   daemon.TransferType(c.Query("type")),
+		Result: daemon.TransferStatus(c.Query("result")),
+	}
+	switch filter.Type {
+	case "", daemon.TransferTypeDownload, daemon.TransferTypeUpload, daemon.TransferTypeSeed:
+	default:
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid type: %s", filter.Type))
+		return
+	}
+	switch filter.Result {
+	case "", daemon.TransferStatusCompleted, daemon.TransferStatusCancelled, daemon.TransferStatusFailed:
+	default:
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid result: %s", filter.Result))
+		return
+	}
+	if since := c.Query("since"); since != "" {
+		if duration, err := time.ParseDuration(since); err == nil {
+			filter.Since = time.Now().Add(-duration)
+		} else if at, err := time.Parse(time.RFC3339, since); err == nil {
+			filter.Since = at
+		} else {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid since: %s", since))
+			return
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid limit: %s", limit))
+			return
+		}
+		filter.Limit = n
+	}
+
+	entries, err := h.daemon.GetHistory(filter)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to read history: %v", err))
+		return
+	}
+	if entries == nil {
+		entries = []daemon.HistoryEntry{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history": entries,
+		"count":   len(entries),
+	})
+}
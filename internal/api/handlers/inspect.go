@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InspectModel describes the local model given by the model query
+// parameter: its manifest, files on disk, torrent, signature and seeding
+func (h *Handlers) InspectModel(c *gin.Context) {
+	model := c.Query("model")
+	if model == "" {
+		respondError(c, http.StatusBadRequest, "model is required")
+		return
+	}
+
+	inspection, err := h.daemon.InspectModel(model)
+	if err != nil {
+		respondAPIError(c, daemonError(http.StatusInternalServerError, "failed to inspect model", err))
+		return
+	}
+	c.JSON(http.StatusOK, inspection)
+}
+
+// DiffModels compares the files of the local models given by the a and b
+// query parameters
+func (h *Handlers) DiffModels(c *gin.Context) {
+	a, b := c.Query("a"), c.Query("b")
+	if a == "" || b == "" {
+		respondError(c, http.StatusBadRequest, "a and b are required")
+		return
+	}
+
+	diff, err := h.daemon.DiffModels(a, b)
+	if err != nil {
+		respondAPIError(c, daemonError(http.StatusInternalServerError, "failed to compare models", err))
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
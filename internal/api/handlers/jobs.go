@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListJobs returns the publish jobs, the most recent first
+func (h *Handlers) ListJobs(c *gin.Context) {
+	jobs := h.daemon.PublishJobs()
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":  jobs,
+		"count": len(jobs),
+	})
+}
+
+// GetJob returns a publish job, with the stage and error it failed at
+func (h *Handlers) GetJob(c *gin.Context) {
+	id := c.Param("id")
+
+	job, ok := h.daemon.PublishJob(id)
+	if !ok {
+		respondError(c, http.StatusNotFound, fmt.Sprintf("job %s not found", id))
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListModelSubscriptions returns all subscribed models and their update state
+func (h *Handlers) ListModelSubscriptions(c *gin.Context) {
+	subscriptions := h.daemon.ModelSubscriptions()
+
+	c.JSON(http.StatusOK, gin.H{
+		"subscriptions": subscriptions,
+		"count":         len(subscriptions),
+	})
+}
+
+// SubscribeModel keeps a model up to date with the catalog
+func (h *Handlers) SubscribeModel(c *gin.Context) {
+	var req struct {
+		Model      string `json:"model" binding:"required"`
+		AutoRemove bool   `json:"auto_remove"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	model, ok := canonicalModelName(c, req.Model)
+	if !ok {
+		return
+	}
+
+	sub, err := h.daemon.SubscribeModel(model, req.AutoRemove)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to subscribe: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "subscribed to model",
+		"subscription": sub,
+	})
+}
+
+// UnsubscribeModel stops updating a model
+func (h *Handlers) UnsubscribeModel(c *gin.Context) {
+	// Model names contain a slash, so the route uses a wildcard parameter
+	modelName := strings.TrimPrefix(c.Param("name"), "/")
+
+	if err := h.daemon.UnsubscribeModel(modelName); err != nil {
+		respondError(c, http.StatusNotFound, fmt.Sprintf("failed to unsubscribe: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "unsubscribed from model",
+		"model":   modelName,
+	})
+}
+
+// Events returns daemon events after the sequence number since
+func (h *Handlers) Events(c *gin.Context) {
+	var since int64
+	if value := c.Query("since"); value != "" {
+		seq, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid since: %s", value))
+			return
+		}
+		since = seq
+	}
+
+	events := h.daemon.Events()
+	list := events.Since(since)
+	c.JSON(http.StatusOK, gin.H{
+		"events":   list,
+		"count":    len(list),
+		"last_seq": events.LastSeq(),
+	})
+}
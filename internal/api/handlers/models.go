@@ -1,65 +1,78 @@
 package handlers
 
 import (
-	"bufio"
-	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/transport"
-	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/silmaril/silmaril/internal/api/apierror"
+	"github.com/silmaril/silmaril/internal/daemon"
+	"github.com/silmaril/silmaril/internal/modelname"
 	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/proxy"
+	"github.com/silmaril/silmaril/internal/publish"
 	"github.com/silmaril/silmaril/internal/storage"
-	"github.com/silmaril/silmaril/internal/torrent"
 	"github.com/silmaril/silmaril/pkg/types"
 )
 
-// ListModels returns all local models
-func (h *Handlers) ListModels(c *gin.Context) {
-	paths, err := storage.NewPaths()
+// findManifest returns the manifest of a local model by its name or an
+// alias. Models the registry hasn't picked up yet, such as one that was just
+// copied into the models directory, are read from disk.
+func (h *Handlers) findManifest(name string) (*types.ModelManifest, error) {
+	registry := h.daemon.GetRegistry()
+	if manifest, err := registry.GetManifest(name); err == nil {
+		return manifest, nil
+	}
+	return registry.LoadModel(registry.Resolve(name))
+}
+
+// canonicalModelName checks a model name from a request and returns its
+// canonical form, responding with an error if it isn't valid
+func canonicalModelName(c *gin.Context, name string) (string, bool) {
+	canonical, err := modelname.Canonical(name)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to initialize paths: %v", err),
-		})
-		return
+		respondAPIError(c, apierror.New(http.StatusBadRequest, err.Error()).WithDetail("model", name))
+		return "", false
 	}
-	
-	registry, err := models.NewRegistry(paths)
+	return canonical, true
+}
+
+// modelDir returns the directory of the model called name below paths,
+// responding with an error if the name isn't valid
+func modelDir(c *gin.Context, paths *storage.Paths, name string) (string, bool) {
+	dir, err := paths.ModelDir(name)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to create registry: %v", err),
-		})
-		return
+		respondAPIError(c, apierror.New(http.StatusBadRequest, err.Error()).WithDetail("model", name))
+		return "", false
 	}
-	
-	if err := registry.ScanModels(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to scan models: %v", err),
-		})
-		return
+	return dir, true
+}
+
+// validInfoHash reports whether s is an infohash, 40 hex characters
+func validInfoHash(s string) bool {
+	if len(s) != 40 {
+		return false
 	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// ListModels returns all local models
+func (h *Handlers) ListModels(c *gin.Context) {
+	registry := h.daemon.GetRegistry()
 	
-	// Get model names
-	modelNames := registry.ListModels()
+	// Filter by search pattern and model card tags if provided
+	pattern := c.Query("pattern")
+	tags := c.QueryArray("tag")
 	
 	// Convert to model details
 	var modelDetails []map[string]interface{}
-	for _, name := range modelNames {
-		manifest, err := registry.GetManifest(name)
-		if err != nil {
-			// Skip models we can't load
-			continue
-		}
-		
+	for _, manifest := range registry.SearchModels(pattern, tags) {
 		// Convert manifest to map for API response
 		modelMap := map[string]interface{}{
 			"name":        manifest.Name,
@@ -82,8 +95,24 @@ func (h *Handlers) ListModels(c *gin.Context) {
 		if manifest.MagnetURI != "" {
 			modelMap["magnet_uri"] = manifest.MagnetURI
 		}
+		if len(manifest.Tags) > 0 {
+			modelMap["tags"] = manifest.Tags
+		}
+		if len(manifest.Languages) > 0 {
+			modelMap["languages"] = manifest.Languages
+		}
+		if manifest.PipelineTag != "" {
+			modelMap["pipeline_tag"] = manifest.PipelineTag
+		}
+		if aliases := registry.Aliases(manifest.Name); len(aliases) > 0 {
+			modelMap["aliases"] = aliases
+		}
 		// InferenceHints is a struct, not a pointer, so just add it directly
 		modelMap["inference_hints"] = manifest.InferenceHints
+		// Large files are hashed in the background after the model is found
+		modelMap["hashes_complete"] = manifest.HashesComplete()
+		modelMap["files_hashed"] = manifest.HashedFiles()
+		modelMap["file_count"] = len(manifest.Files)
 		
 		modelDetails = append(modelDetails, modelMap)
 	}
@@ -94,42 +123,56 @@ func (h *Handlers) ListModels(c *gin.Context) {
 	})
 }
 
-// GetModel returns details about a specific model
-func (h *Handlers) GetModel(c *gin.Context) {
-	modelName := c.Param("name")
-	
-	paths, err := storage.NewPaths()
+// RefreshModels scans the models directory again. The registry follows
+// changes on its own, this picks them up at once.
+func (h *Handlers) RefreshModels(c *gin.Context) {
+	count, err := h.daemon.RefreshRegistry()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to initialize paths: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 	
-	registry, err := models.NewRegistry(paths)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to create registry: %v", err),
-		})
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "models rescanned",
+		"count":      count,
+		"scanned_at": h.daemon.GetRegistry().ScannedAt(),
+	})
+}
+
+// GetModel returns details about a specific model
+func (h *Handlers) GetModel(c *gin.Context) {
+	modelName, ok := canonicalModelName(c, c.Param("name"))
+	if !ok {
 		return
 	}
 	
-	if err := registry.ScanModels(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to scan models: %v", err),
-		})
+	manifest, err := h.findManifest(modelName)
+	if err != nil {
+		respondError(c, http.StatusNotFound, fmt.Sprintf("model %s not found", modelName))
 		return
 	}
 	
-	manifest, err := registry.GetManifest(modelName)
+	// Only kept with a database, the model is still found without
+	versions, err := h.daemon.GetRegistry().Versions(manifest.Name)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": fmt.Sprintf("model %s not found", modelName),
-		})
-		return
+		fmt.Printf("[API] Failed to read versions of %s: %v\n", manifest.Name, err)
 	}
 	
-	c.JSON(http.StatusOK, manifest)
+	c.JSON(http.StatusOK, modelResponse{
+		ModelManifest:  manifest,
+		HashesComplete: manifest.HashesComplete(),
+		FilesHashed:    manifest.HashedFiles(),
+		Versions:       versions,
+	})
+}
+
+// modelResponse is a manifest with the progress of its background hashing
+// and the versions the model had
+type modelResponse struct {
+	*types.ModelManifest
+	HashesComplete bool                  `json:"hashes_complete"`
+	FilesHashed    int                   `json:"files_hashed"`
+	Versions       []models.ModelVersion `json:"versions,omitempty"`
 }
 
 // DownloadModelRequest represents a download request
@@ -137,46 +180,203 @@ type DownloadModelRequest struct {
 	ModelName string `json:"model_name" binding:"required"`
 	InfoHash  string `json:"info_hash"`
 	Seed      bool   `json:"seed"`
+	// Glob patterns of the files to download and seed, all files if empty
+	Files     []string `json:"files,omitempty"`
+	// Accept the license of a model that requires it
+	AcceptLicense bool `json:"accept_license,omitempty"`
+	// Download again even if the torrent is downloading or downloaded
+	Force bool `json:"force,omitempty"`
+	// Absolute path of a directory outside the models directory to store
+	// the files in, linked into the models directory
+	Output string `json:"output,omitempty"`
 }
 
 // DownloadModel starts downloading a model
 func (h *Handlers) DownloadModel(c *gin.Context) {
 	var req DownloadModelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("invalid request: %v", err),
-		})
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	modelName, ok := canonicalModelName(c, req.ModelName)
+	if !ok {
+		return
+	}
+	req.ModelName = modelName
+	if req.InfoHash != "" && !validInfoHash(req.InfoHash) {
+		respondAPIError(c, apierror.New(http.StatusBadRequest, "invalid infohash, want 40 hex characters").WithDetail("info_hash", req.InfoHash))
 		return
 	}
 	
-	// Create transfer
+	// Models on the blocklist are refused, whatever their infohash
+	if err := h.daemon.CheckModelBlocked(req.ModelName, req.InfoHash); err != nil {
+		respondAPIError(c, daemonError(http.StatusForbidden, "failed to start download", err))
+		return
+	}
+	
+	// Gated models are only downloaded once their license is accepted, also
+	// when asked for by infohash under another name. Downloads whose manifest
+	// asks for acceptance are held in quarantine until it is accepted.
+	catalogModel := h.daemon.FindCatalogModel(req.ModelName)
+	for _, model := range []*types.ModelAnnouncement{catalogModel, h.daemon.FindCatalogInfoHash(req.ModelName, req.InfoHash)} {
+		if err := h.daemon.CheckLicense(model, req.AcceptLicense); err != nil {
+			respondAPIError(c, daemonError(http.StatusInternalServerError, "failed to check license", err))
+			return
+		}
+	}
+	if req.AcceptLicense {
+		h.daemon.AcceptHeldLicense(req.ModelName)
+	}
+	
+	// Without an infohash the model is looked up in the catalog by name
+	if req.InfoHash == "" {
+		if catalogModel == nil {
+			respondError(c, http.StatusNotFound, fmt.Sprintf("model %s not found in the catalog", req.ModelName))
+			return
+		}
+		req.InfoHash = catalogModel.InfoHash
+	}
+	req.InfoHash = strings.ToLower(req.InfoHash)
+	
+	// Without an output directory, models of a known size go to the storage
+	// pool their size belongs in
+	output := req.Output
+	if output == "" && catalogModel != nil && strings.EqualFold(catalogModel.InfoHash, req.InfoHash) {
+		output = h.daemon.PoolPathFor(req.ModelName, catalogModel.Size)
+	}
+	
+	// Files stored elsewhere are linked into the models directory, so the
+	// model is registered and seeded like any other
+	downloadPath, ok := modelDir(c, h.daemon.GetRegistry().Paths(), req.ModelName)
+	if !ok {
+		return
+	}
+	linked := false
+	if output != "" {
+		if !filepath.IsAbs(output) {
+			respondError(c, http.StatusBadRequest, "output must be an absolute path")
+			return
+		}
+		if rel, err := filepath.Rel(storage.GetModelsDir(), output); err == nil && !strings.HasPrefix(rel, "..") {
+			respondError(c, http.StatusBadRequest, "output must be outside the models directory")
+			return
+		}
+		if req.Output != "" {
+			if err := h.daemon.CheckOutputDir(output); err != nil {
+				respondError(c, http.StatusForbidden, err.Error())
+				return
+			}
+		}
+		var err error
+		if linked, err = storage.LinkOutputDir(output, downloadPath); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, storage.ErrModelExists) || errors.Is(err, storage.ErrOutputNotEmpty) {
+				status = http.StatusConflict
+			}
+			respondError(c, status, fmt.Sprintf("failed to use output directory: %v", err))
+			return
+		}
+	}
+	
+	// Asking again for a torrent being downloaded returns its transfer
 	tm := h.daemon.GetTransferManager()
-	transfer := tm.CreateDownload(req.ModelName, req.InfoHash, 0)
+	var transfer *daemon.Transfer
+	if req.Force {
+		if existing, ok := tm.FindTransfer(req.InfoHash, daemon.TransferTypeDownload); ok && existing.IsLive() {
+			if err := tm.CancelTransfer(existing.ID); err != nil {
+				respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to cancel transfer %s: %v", existing.ID, err))
+				return
+			}
+		}
+		transfer = tm.CreateDownload(req.ModelName, req.InfoHash, 0)
+	} else {
+		var created bool
+		if transfer, created = tm.CreateDownloadOnce(req.ModelName, req.InfoHash); !created {
+			if linked {
+				os.Remove(downloadPath)
+			}
+			respondExistingDownload(c, transfer, req.ModelName)
+			return
+		}
+	}
 	
-	// Start download
-	torrentPath := filepath.Join(storage.GetTorrentsDir(), req.InfoHash+".torrent")
-	downloadPath := filepath.Join(storage.GetModelsDir(), req.ModelName)
-	mt, err := h.daemon.GetTorrentManager().AddTorrentForDownload(torrentPath, req.ModelName, downloadPath)
+	// Start download, fetching the metadata from peers without a local
+	// torrent file. A manifest received with it is checked against the
+	// torrent's files before it is kept.
+	mt, fetchingMetadata, err := h.daemon.GetTorrentManager().AddForDownload(req.InfoHash, req.ModelName, downloadPath)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to start download: %v", err),
-		})
+		if linked {
+			os.Remove(downloadPath)
+		}
+		tm.FailTransfer(transfer.ID, err)
+		respondAPIError(c, daemonError(http.StatusInternalServerError, "failed to start download", err))
 		return
 	}
 	
+	// Only download the selected files
+	if len(req.Files) > 0 {
+		if _, err := h.daemon.GetTorrentManager().SetFileSelection(mt.InfoHash, req.Files); err != nil {
+			transfer.InfoHash = mt.InfoHash
+			tm.CancelTransfer(transfer.ID)
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to select files: %v", err))
+			return
+		}
+	}
+	
+	// Torrents of the same files declared by the publisher are downloaded
+	// from too
+	if len(req.Files) == 0 {
+		h.daemon.JoinEquivalentSwarms(mt.InfoHash, catalogModel)
+	}
+	
 	// Update transfer with torrent info
 	transfer.InfoHash = mt.InfoHash
 	transfer.TotalBytes = mt.Torrent.Length()
+	if _, total := mt.WantedBytes(); total > 0 {
+		transfer.TotalBytes = total
+	}
+	transfer.Files = req.Files
 	transfer.Status = "active"
 	
 	c.JSON(http.StatusOK, gin.H{
 		"transfer_id": transfer.ID,
 		"model_name":  req.ModelName,
 		"info_hash":   mt.InfoHash,
+		"files":       req.Files,
+		"path":        storage.ResolveModelDir(downloadPath),
+		"fetching_metadata": fetchingMetadata,
 		"message":     "download started",
 	})
 }
 
+// respondExistingDownload answers a download of a torrent that already has a
+// transfer: the same download still running is returned as is, a finished one
+// or one under another name is a conflict
+func respondExistingDownload(c *gin.Context, transfer *daemon.Transfer, modelName string) {
+	var apiErr *apierror.Error
+	switch {
+	case transfer.ModelName != modelName:
+		apiErr = apierror.Newf(http.StatusConflict, "torrent %s is already downloaded as %s", transfer.InfoHash, transfer.ModelName)
+	case transfer.Status == daemon.TransferStatusCompleted:
+		apiErr = apierror.Newf(http.StatusConflict, "model %s is already downloaded", modelName)
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"transfer_id": transfer.ID,
+			"model_name":  transfer.ModelName,
+			"info_hash":   transfer.InfoHash,
+			"files":       transfer.Files,
+			"status":      transfer.Status,
+			"existing":    true,
+			"message":     "download already in progress",
+		})
+		return
+	}
+	respondAPIError(c, apiErr.
+		WithDetail("transfer_id", transfer.ID).
+		WithDetail("model_name", transfer.ModelName).
+		WithDetail("status", transfer.Status))
+}
+
 // ShareModelRequest represents a share request
 type ShareModelRequest struct {
 	ModelName    string `json:"model_name"`
@@ -189,22 +389,132 @@ type ShareModelRequest struct {
 	PieceLength  int64  `json:"piece_length"` // Piece length for torrent
 	SkipDHT      bool   `json:"skip_dht"`      // Skip DHT announcement
 	SignManifest bool   `json:"sign_manifest"` // Sign the manifest
+	Import       string `json:"import"`        // copy, link or inplace
 	// Repository cloning parameters
 	RepoURL      string `json:"repo_url"`      // Git/HF repository URL
 	Branch       string `json:"branch"`        // Git branch
+	Revision     string `json:"revision"`      // Git tag or commit, pins the clone
 	Depth        int    `json:"depth"`         // Git clone depth
 	SkipLFS      bool   `json:"skip_lfs"`      // Skip Git LFS files
+	// Seeding policy of the model, unset limits use the torrent settings
+	SeedRatio        *float64 `json:"seed_ratio,omitempty"`
+	SeedTime         *int     `json:"seed_time,omitempty"`            // Seconds
+	StopIfNoPeersFor *int     `json:"stop_if_no_peers_for,omitempty"` // Seconds
+	SeedPriority     string   `json:"seed_priority,omitempty"`        // low, normal or high
+	// Storage backend of the model, empty uses torrent.storage_backend
+	Storage string `json:"storage,omitempty"`
+	// Glob patterns of the files of a seeded model to keep seeding
+	Files []string `json:"files,omitempty"`
+	// Seed the model encrypted, its key is handed out by the publisher
+	Encrypt bool   `json:"encrypt,omitempty"`
+	KeyURL  string `json:"key_url,omitempty"` // Endpoint serving the key to licensees
+	// License terms of new models, which downloaders may have to accept
+	LicenseURL               string `json:"license_url,omitempty"`
+	LicenseText              string `json:"license_text,omitempty"`
+	RequireLicenseAcceptance bool   `json:"require_license_acceptance,omitempty"`
+	// Base URLs of HTTP servers holding the files of new models
+	Mirrors []string `json:"mirrors,omitempty"`
+	// Patterns of files left out of, or kept in, a published model, on top
+	// of its .silmarilignore
+	Exclude []string `json:"exclude,omitempty"`
+	Include []string `json:"include,omitempty"`
+	// Report what publishing a directory would do without writing anything
+	DryRun bool `json:"dry_run,omitempty"`
+	// Seed again even if the model is already seeding
+	Force bool `json:"force,omitempty"`
+}
+
+// applyMetadata stores the license terms, version and seeding policy of a
+// publish request in the manifest
+func (req *ShareModelRequest) applyMetadata(manifest *types.ModelManifest) {
+	manifest.License = req.License
+	manifest.LicenseURL = req.LicenseURL
+	manifest.LicenseText = req.LicenseText
+	manifest.RequireLicenseAcceptance = req.RequireLicenseAcceptance
+	if len(req.Mirrors) > 0 {
+		manifest.Mirrors = req.Mirrors
+	}
+	if req.Version != "" {
+		manifest.Version = req.Version
+	}
+	req.applySeedingPolicy(manifest)
+}
+
+// applySeedingPolicy stores the seeding limits and storage backend of a share
+// request in the manifest and clears a previous stop, since sharing again
+// resumes seeding. It reports whether the manifest changed.
+func (req *ShareModelRequest) applySeedingPolicy(manifest *types.ModelManifest) bool {
+	storageChanged := req.Storage != "" && req.Storage != manifest.Storage
+	if storageChanged {
+		manifest.Storage = req.Storage
+	}
+	hasLimits := req.SeedRatio != nil || req.SeedTime != nil || req.StopIfNoPeersFor != nil || req.SeedPriority != ""
+	if manifest.Seeding == nil {
+		if !hasLimits {
+			return storageChanged
+		}
+		manifest.Seeding = &types.SeedingPolicy{}
+	}
+	if req.SeedRatio != nil {
+		manifest.Seeding.SeedRatio = req.SeedRatio
+	}
+	if req.SeedTime != nil {
+		manifest.Seeding.SeedTime = req.SeedTime
+	}
+	if req.StopIfNoPeersFor != nil {
+		manifest.Seeding.StopIfNoPeersFor = req.StopIfNoPeersFor
+	}
+	if req.SeedPriority != "" {
+		manifest.Seeding.Priority = req.SeedPriority
+	}
+	manifest.Seeding.StoppedAt = nil
+	manifest.Seeding.StopReason = ""
+	return true
 }
 
 // ShareModel starts sharing a model
 func (h *Handlers) ShareModel(c *gin.Context) {
 	var req ShareModelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("invalid request: %v", err),
-		})
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	
+	// Only publishing a directory can be planned without side effects
+	if req.DryRun && (req.Path == "" || req.RepoURL != "" || req.All) {
+		respondError(c, http.StatusBadRequest, "a dry run only applies to publishing a directory")
+		return
+	}
+	if req.Storage != "" && !daemon.ValidStorageBackend(req.Storage) {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("unknown storage backend %q, expected one of %v", req.Storage, daemon.StorageBackends))
+		return
+	}
+	if req.SeedPriority != "" && !daemon.ValidSeedPriority(req.SeedPriority) {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("unknown seed priority %q, expected one of %v", req.SeedPriority, daemon.SeedPriorities))
+		return
+	}
+	if req.DryRun && req.Encrypt {
+		respondError(c, http.StatusBadRequest, "a dry run can't predict the torrent of an encrypted model")
 		return
 	}
+	if req.KeyURL != "" {
+		if err := daemon.CheckKeyURL(req.KeyURL); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	
+	// Names become paths below the models directory
+	for _, name := range []*string{&req.ModelName, &req.Name} {
+		if *name == "" {
+			continue
+		}
+		canonical, ok := canonicalModelName(c, *name)
+		if !ok {
+			return
+		}
+		*name = canonical
+	}
 	
 	// Handle repository URL first (clone and share)
 	if req.RepoURL != "" {
@@ -217,237 +527,89 @@ func (h *Handlers) ShareModel(c *gin.Context) {
 		}
 		
 		// Parse repository URL to get model name
-		modelName := parseRepoURL(req.RepoURL)
+		modelName := publish.RepoModelName(req.RepoURL)
 		if modelName == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "invalid repository URL",
-			})
+			respondError(c, http.StatusBadRequest, "invalid repository URL")
 			return
 		}
-		
-		// Get storage paths
-		paths, err := storage.NewPaths()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to initialize paths: %v", err),
-			})
+		modelName, ok := canonicalModelName(c, modelName)
+		if !ok {
 			return
 		}
 		
 		// Determine clone destination
-		modelPath := paths.ModelPath(modelName)
+		paths := h.daemon.GetRegistry().Paths()
+		modelPath, ok := modelDir(c, paths, modelName)
+		if !ok {
+			return
+		}
 		
-		// Check if model already exists
+		// Check if model already exists, unless an interrupted clone of the
+		// repository is there to continue
+		var resume publish.CloneProgress
 		if _, err := os.Stat(modelPath); err == nil {
-			c.JSON(http.StatusConflict, gin.H{
-				"error": fmt.Sprintf("model %s already exists", modelName),
-			})
-			return
+			var ok bool
+			if resume, ok = h.daemon.ResumableClone(modelName, req.RepoURL, modelPath); !ok {
+				respondError(c, http.StatusConflict, fmt.Sprintf("model %s already exists", modelName))
+				return
+			}
 		}
 		
-		// Create parent directory
-		if err := os.MkdirAll(filepath.Dir(modelPath), 0755); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to create model directory: %v", err),
-			})
+		gitProxy, err := h.daemon.Proxy(proxy.Git)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err.Error())
 			return
 		}
 		
-		// Execute git clone in background
-		go func() {
-			fmt.Printf("[ShareModel] Cloning repository: %s to %s\n", req.RepoURL, modelPath)
-			
-			// Prepare clone options
-			cloneOptions := &git.CloneOptions{
+		// The model card and license files complete the manifest once cloned
+		manifest := &types.ModelManifest{
+			Name:    modelName,
+			Version: req.Branch,
+			License: "Unknown",
+			Mirrors: req.Mirrors,
+		}
+		req.applySeedingPolicy(manifest)
+		
+		// Clone, describe, seed and announce in the background
+		job := h.daemon.StartPublish(publish.Request{
+			Name:        modelName,
+			Path:        modelPath,
+			TorrentPath: paths.TorrentPath(modelName),
+			Repo: &publish.Repo{
 				URL:      req.RepoURL,
-				Progress: os.Stdout,
-			}
-			
-			// Set branch if not main/master
-			if req.Branch != "" && req.Branch != "main" && req.Branch != "master" {
-				cloneOptions.ReferenceName = plumbing.NewBranchReferenceName(req.Branch)
-			}
-			
-			// Set depth for shallow clone
-			if req.Depth > 0 {
-				cloneOptions.Depth = req.Depth
-			}
-			
-			// Handle authentication for private repos (optional)
-			// For HuggingFace, we might need token authentication
-			if strings.Contains(req.RepoURL, "huggingface.co") {
-				// Check for HF token in environment
-				if token := os.Getenv("HF_TOKEN"); token != "" {
-					cloneOptions.Auth = &githttp.BasicAuth{
-						Username: "hf",
-						Password: token,
-					}
-				}
-			}
-			
-			// Clone the repository
-			repo, err := git.PlainClone(modelPath, false, cloneOptions)
-			if err != nil {
-				// Handle specific errors
-				if err == transport.ErrAuthenticationRequired {
-					fmt.Printf("[ShareModel] Authentication required for repository: %v\n", err)
-				} else if err == transport.ErrRepositoryNotFound {
-					fmt.Printf("[ShareModel] Repository not found: %v\n", err)
-				} else {
-					fmt.Printf("[ShareModel] Failed to clone repository: %v\n", err)
-				}
-				// Clean up partial clone
-				os.RemoveAll(modelPath)
-				return
-			}
-			
-			fmt.Printf("[ShareModel] Repository cloned successfully to %s\n", modelPath)
-			
-			// Download LFS files if present
-			if !req.SkipLFS {
-				fmt.Printf("[ShareModel] Checking for LFS files...\n")
-				if err := downloadLFSFiles(repo, modelPath, cloneOptions.Auth); err != nil {
-					fmt.Printf("[ShareModel] Warning: Failed to download LFS files: %v\n", err)
-					// Continue anyway - some files might not need LFS
-				} else {
-					fmt.Printf("[ShareModel] LFS files downloaded successfully\n")
-				}
-			}
-			
-			// Remove .git directory to save space
-			gitDir := filepath.Join(modelPath, ".git")
-			if err := os.RemoveAll(gitDir); err != nil {
-				fmt.Printf("[ShareModel] Warning: failed to remove .git directory: %v\n", err)
-			}
-			
-			// Create registry to generate manifest
-			registry, err := models.NewRegistry(paths)
-			if err != nil {
-				fmt.Printf("[ShareModel] Failed to create registry: %v\n", err)
-				return
-			}
-			
-			// Generate manifest for the cloned model
-			manifest := &types.ModelManifest{
-				Name:    modelName,
-				Version: req.Branch,
-				License: "Unknown", // Will be detected from repo if possible
-			}
-			
-			// Try to detect license from common files
-			licenseFiles := []string{"LICENSE", "LICENSE.txt", "LICENSE.md", "LICENCE", "LICENCE.txt", "LICENCE.md"}
-			for _, lf := range licenseFiles {
-				if _, err := os.Stat(filepath.Join(modelPath, lf)); err == nil {
-					// License file exists, could parse it to detect type
-					manifest.License = "See LICENSE file"
-					break
-				}
-			}
-			
-			// Calculate model size
-			var totalSize int64
-			filepath.Walk(modelPath, func(path string, info os.FileInfo, err error) error {
-				if err == nil && !info.IsDir() {
-					totalSize += info.Size()
-				}
-				return nil
-			})
-			manifest.TotalSize = totalSize
-			
-			// Save manifest
-			if err := registry.SaveManifest(manifest); err != nil {
-				fmt.Printf("[ShareModel] Failed to save manifest: %v\n", err)
-				return
-			}
-			
-			// Create torrent
-			torrentPath := filepath.Join(paths.TorrentsDir(), modelName+".torrent")
-			
-			// Ensure torrents directory exists (including parent directories for nested model names)
-			torrentDir := filepath.Dir(torrentPath)
-			if err := os.MkdirAll(torrentDir, 0755); err != nil {
-				fmt.Printf("[ShareModel] Failed to create torrents directory: %v\n", err)
-				return
-			}
-			
-			// Create the torrent file
-			pieceLength := int64(4 * 1024 * 1024) // 4MB pieces
-			if req.PieceLength > 0 {
-				pieceLength = req.PieceLength
-			}
-			
-			infoHash, err := torrent.CreateTorrentFromDirectory(modelPath, torrentPath, pieceLength)
-			if err != nil {
-				fmt.Printf("[ShareModel] Failed to create torrent: %v\n", err)
-				return
-			}
-			
-			fmt.Printf("[ShareModel] Torrent created: %s (InfoHash: %s)\n", torrentPath, infoHash)
-			
-			// Start sharing the model
-			torrentManager := h.daemon.GetTorrentManager()
-			managedTorrent, err := torrentManager.AddTorrentForSeeding(torrentPath, modelName, modelPath)
-			if err != nil {
-				fmt.Printf("[ShareModel] Failed to add torrent: %v\n", err)
-				return
-			}
-			
-			// Start seeding
-			if err := torrentManager.StartSeeding(managedTorrent.InfoHash); err != nil {
-				fmt.Printf("[ShareModel] Failed to start seeding: %v\n", err)
-				return
-			}
-			
-			fmt.Printf("[ShareModel] Started sharing model: %s\n", modelName)
-			
-			// Announce on DHT unless disabled
-			if !req.SkipDHT {
-				announcement := types.ModelAnnouncement{
-					Name:     modelName,
-					InfoHash: managedTorrent.InfoHash,
-					Size:     totalSize,
-				}
-				h.daemon.GetDHTManager().AnnounceModel(&announcement)
-				fmt.Printf("[ShareModel] Announced model on DHT: %s\n", modelName)
-			}
-		}()
+				Branch:   req.Branch,
+				Revision: req.Revision,
+				Depth:    req.Depth,
+				SkipLFS:  req.SkipLFS,
+				Proxy:    gitProxy,
+				Resume:   resume,
+			},
+			Manifest:     manifest,
+			Exclude:      req.Exclude,
+			Include:      req.Include,
+			PieceLength:  req.PieceLength,
+			SkipAnnounce: req.SkipDHT,
+		})
 		
 		c.JSON(http.StatusAccepted, gin.H{
 			"message": "share operation started",
 			"model_name": modelName,
 			"repo_url": req.RepoURL,
 			"status": "cloning",
+			"job_id": job.ID,
+			"resumed": resume != nil,
 		})
 		return
 	}
 	
 	if req.All {
 		// Share all models
-		paths, err := storage.NewPaths()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to initialize paths: %v", err),
-			})
-			return
-		}
-		
-		registry, err := models.NewRegistry(paths)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to create registry: %v", err),
-			})
-			return
-		}
-		
-		if err := registry.ScanModels(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to scan models: %v", err),
-			})
-			return
-		}
+		registry := h.daemon.GetRegistry()
+		paths := registry.Paths()
 		
 		modelsList := registry.GetAllManifests()
 		shared := 0
+		alreadyShared := 0
 		var errors []string
 		
 		for _, manifest := range modelsList {
@@ -462,9 +624,24 @@ func (h *Handlers) ShareModel(c *gin.Context) {
 				}
 			}
 			
+			// Sharing again resumes seeding stopped by the seeding policy
+			if req.applySeedingPolicy(manifest) {
+				if err := registry.SaveManifest(manifest); err != nil {
+					errors = append(errors, fmt.Sprintf("%s: failed to save manifest: %v", manifest.Name, err))
+					continue
+				}
+			}
+			
 			// Add torrent to torrent manager
 			torrentManager := h.daemon.GetTorrentManager()
-			modelPath := filepath.Join(paths.ModelsDir(), manifest.Name)
+			modelPath, err := paths.ModelDir(manifest.Name)
+			if manifest.Encryption != nil {
+				modelPath, err = modelname.Join(storage.GetEncryptedDir(), manifest.Name)
+			}
+			if err != nil {
+				errors = append(errors, fmt.Sprintf("%s: %v", manifest.Name, err))
+				continue
+			}
 			managedTorrent, err := torrentManager.AddTorrentForSeeding(torrentPath, manifest.Name, modelPath)
 			if err != nil {
 				errors = append(errors, fmt.Sprintf("%s: %v", manifest.Name, err))
@@ -474,17 +651,31 @@ func (h *Handlers) ShareModel(c *gin.Context) {
 			// Mark as seeding
 			managedTorrent.Seeding = true
 			
-			// Create seed transfer
+			// Models already seeding keep their transfer
 			tm := h.daemon.GetTransferManager()
+			if existing, ok := tm.FindTransfer(managedTorrent.InfoHash, daemon.TransferTypeSeed); ok && existing.IsLive() && !req.Force {
+				alreadyShared++
+				continue
+			}
+			
+			// Create seed transfer
 			transfer := tm.CreateSeed(manifest.Name, managedTorrent.InfoHash)
 			transfer.Status = "active"
 			
 			// Announce to DHT if not skipping
 			if !req.SkipDHT {
 				announcement := &types.ModelAnnouncement{
-					Name:     manifest.Name,
-					InfoHash: managedTorrent.InfoHash,
-					Size:     manifest.TotalSize,
+					Name:        manifest.Name,
+					InfoHash:    managedTorrent.InfoHash,
+					Size:        manifest.TotalSize,
+					Description: manifest.Description,
+					Tags:        manifest.Keywords(),
+					License:     manifest.License,
+					Parameters:  manifest.Parameters,
+					LicenseURL:  manifest.LicenseURL,
+					RequireLicenseAcceptance: manifest.RequireLicenseAcceptance,
+					Provenance:  manifest.Provenance,
+					Equivalents: manifest.Equivalents,
 				}
 				h.daemon.GetDHTManager().AnnounceModel(announcement)
 			}
@@ -495,6 +686,7 @@ func (h *Handlers) ShareModel(c *gin.Context) {
 		response := gin.H{
 			"message":      "started sharing models",
 			"models_shared": shared,
+			"already_shared": alreadyShared,
 			"total_models": len(modelsList),
 		}
 		
@@ -506,49 +698,63 @@ func (h *Handlers) ShareModel(c *gin.Context) {
 		return
 	}
 	
-	// Share specific model
 	if req.ModelName != "" {
-		paths, err := storage.NewPaths()
+		req.ModelName = h.daemon.ResolveModel(req.ModelName)
+	}
+	
+	// Seed only some files of a model we already seed
+	if req.ModelName != "" && len(req.Files) > 0 {
+		selected, err := h.daemon.SelectModelFiles(req.ModelName, req.Files)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to initialize paths: %v", err),
-			})
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to select files: %v", err))
 			return
 		}
 		
-		registry, err := models.NewRegistry(paths)
+		c.JSON(http.StatusOK, gin.H{
+			"message":        "seeding selected files",
+			"model":          req.ModelName,
+			"files":          req.Files,
+			"selected_files": selected,
+		})
+		return
+	}
+	
+	// Share specific model
+	if req.ModelName != "" {
+		registry := h.daemon.GetRegistry()
+		manifest, err := h.findManifest(req.ModelName)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to create registry: %v", err),
-			})
+			respondError(c, http.StatusNotFound, fmt.Sprintf("model %s not found", req.ModelName))
 			return
 		}
 		
-		if err := registry.ScanModels(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to scan models: %v", err),
-			})
-			return
+		if req.applySeedingPolicy(manifest) {
+			if err := registry.SaveManifest(manifest); err != nil {
+				respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to save manifest: %v", err))
+				return
+			}
 		}
 		
-		manifest, err := registry.GetManifest(req.ModelName)
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": fmt.Sprintf("model %s not found", req.ModelName),
+		// Sharing a model already seeding returns its transfer
+		tm := h.daemon.GetTransferManager()
+		infoHash := manifest.Name // Use model name as identifier for now
+		if existing, ok := tm.FindTransfer(infoHash, daemon.TransferTypeSeed); ok && existing.IsLive() && !req.Force {
+			c.JSON(http.StatusOK, gin.H{
+				"message":     "model is already shared",
+				"model_name":  manifest.Name,
+				"info_hash":   existing.InfoHash,
+				"transfer_id": existing.ID,
+				"existing":    true,
 			})
 			return
 		}
 		
 		// Create seed transfer
-		tm := h.daemon.GetTransferManager()
-		infoHash := manifest.Name // Use model name as identifier for now
 		transfer := tm.CreateSeed(manifest.Name, infoHash)
 		
 		// Start seeding
 		if err := h.daemon.GetTorrentManager().StartSeeding(infoHash); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to start sharing: %v", err),
-			})
+			respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to start sharing: %v", err))
 			return
 		}
 		
@@ -556,9 +762,17 @@ func (h *Handlers) ShareModel(c *gin.Context) {
 		
 		// Announce to DHT
 		announcement := &types.ModelAnnouncement{
-			Name:     manifest.Name,
-			InfoHash: infoHash,
-			Size:     manifest.TotalSize,
+			Name:        manifest.Name,
+			InfoHash:    infoHash,
+			Size:        manifest.TotalSize,
+			Description: manifest.Description,
+			Tags:        manifest.Keywords(),
+			License:     manifest.License,
+			Parameters:  manifest.Parameters,
+			LicenseURL:  manifest.LicenseURL,
+			RequireLicenseAcceptance: manifest.RequireLicenseAcceptance,
+			Provenance:  manifest.Provenance,
+			Equivalents: manifest.Equivalents,
 		}
 		h.daemon.GetDHTManager().AnnounceModel(announcement)
 		
@@ -577,9 +791,11 @@ func (h *Handlers) ShareModel(c *gin.Context) {
 		
 		// For publishing new models, Name and License are required
 		if req.Name == "" || req.License == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "name and license are required when publishing from directory",
-			})
+			respondError(c, http.StatusBadRequest, "name and license are required when publishing from directory")
+			return
+		}
+		if req.RequireLicenseAcceptance && req.LicenseURL == "" && req.LicenseText == "" {
+			respondError(c, http.StatusBadRequest, "a license URL or text is required for licenses that must be accepted")
 			return
 		}
 		fmt.Printf("[ShareModel] Model name: %s, License: %s, Version: %s\n", req.Name, req.License, req.Version)
@@ -587,531 +803,212 @@ func (h *Handlers) ShareModel(c *gin.Context) {
 		// Verify path exists and is a directory
 		info, err := os.Stat(req.Path)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": fmt.Sprintf("path not found: %v", err),
-			})
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("path not found: %v", err))
 			return
 		}
 		if !info.IsDir() {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "path must be a directory",
-			})
+			respondError(c, http.StatusBadRequest, "path must be a directory")
 			return
 		}
 
-		// Get storage paths
-		paths, err := storage.NewPaths()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to initialize paths: %v", err),
-			})
+		registry := h.daemon.GetRegistry()
+		paths := registry.Paths()
+
+		if req.Import != "" && !storage.ValidImportMode(req.Import) {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid import mode %q, must be copy, link or inplace", req.Import))
 			return
 		}
-
-		// Create registry to generate manifest
-		registry, err := models.NewRegistry(paths)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to create registry: %v", err),
-			})
+		
+		modelPath, ok := modelDir(c, paths, req.Name)
+		if !ok {
 			return
 		}
-
-		// Copy model to models directory if not already there
-		modelPath := paths.ModelPath(req.Name)
-		if req.Path != modelPath {
-			// Create parent directory
-			if err := os.MkdirAll(filepath.Dir(modelPath), 0755); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": fmt.Sprintf("failed to create model directory: %v", err),
-				})
-				return
+		if req.DryRun {
+			publishReq := publish.Request{
+				Name:         req.Name,
+				Path:         modelPath,
+				Edit:         req.applyMetadata,
+				Exclude:      req.Exclude,
+				Include:      req.Include,
+				Version:      req.Version,
+				PieceLength:  req.PieceLength,
+				SkipAnnounce: req.SkipDHT,
 			}
-
-			// Copy directory contents
-			if err := copyDir(req.Path, modelPath); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": fmt.Sprintf("failed to copy model: %v", err),
-				})
+			if req.Path != modelPath {
+				publishReq.Import = &publish.Import{From: req.Path, Mode: req.Import}
+			} else if manifest, err := models.ReadManifest(modelPath); err == nil {
+				publishReq.Manifest = manifest
+			}
+			
+			plan, err := publish.PlanRequest(publishReq)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to plan publishing: %v", err))
 				return
 			}
-		}
-
-		// Scan to pick up the new model
-		if err := registry.ScanModels(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to scan models: %v", err),
+			c.JSON(http.StatusOK, gin.H{
+				"message": "dry run, nothing was written",
+				"dry_run": true,
+				"plan":    plan,
 			})
 			return
 		}
 		
-		// Get or generate manifest for the model
-		manifest, err := registry.GetManifest(req.Name)
+		// Place the model in the models directory if not already there
+		var imported storage.ImportStats
+		if req.Path != modelPath {
+			if target, ok := storage.IsLinkedModel(modelPath); ok {
+				// Only publishing the same directory in place again is allowed,
+				// anything else would write into the linked directory
+				if !sameDir(target, req.Path) || req.Import != storage.ImportInPlace {
+					respondError(c, http.StatusConflict, fmt.Sprintf("model %s is already published in place from %s", req.Name, target))
+					return
+				}
+			} else {
+				if _, err := os.Stat(modelPath); err == nil && req.Import == storage.ImportInPlace {
+					respondError(c, http.StatusConflict, fmt.Sprintf("model %s already exists", req.Name))
+					return
+				}
+				
+				fmt.Printf("[ShareModel] Importing %s to %s (mode: %s)\n", req.Path, modelPath, req.Import)
+				imported, err = storage.ImportDir(req.Path, modelPath, req.Import)
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to import model: %v", err))
+					return
+				}
+			}
+		}
+		
+		// Read the new model, or the manifest it already has
+		manifest, err := registry.LoadModel(req.Name)
 		if err != nil {
 			// Model not found, need to refresh
 			if err := registry.RefreshModel(req.Name); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": fmt.Sprintf("failed to generate manifest: %v", err),
-				})
+				respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to generate manifest: %v", err))
 				return
 			}
 			manifest, err = registry.GetManifest(req.Name)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": fmt.Sprintf("failed to get manifest: %v", err),
-				})
+				respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to get manifest: %v", err))
 				return
 			}
 		}
 		
 		// Update manifest with provided metadata
-		manifest.License = req.License
-		if req.Version != "" {
-			manifest.Version = req.Version
-		}
-
-		// Create torrent file
-		torrentPath := paths.TorrentPath(req.Name)
-		fmt.Printf("[ShareModel] Creating torrent at: %s\n", torrentPath)
-		if err := os.MkdirAll(filepath.Dir(torrentPath), 0755); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to create torrent directory: %v", err),
-			})
-			return
-		}
-
-		fmt.Printf("[ShareModel] Generating torrent from directory: %s\n", modelPath)
-		infoHash, err := torrent.CreateTorrentFromDirectory(modelPath, torrentPath, req.PieceLength)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to create torrent: %v", err),
-			})
-			return
-		}
-		fmt.Printf("[ShareModel] Torrent created with InfoHash: %s\n", infoHash)
-
-		// Save manifest to disk
-		if err := registry.SaveManifest(manifest); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to save manifest: %v", err),
-			})
-			return
-		}
-
-		// Add torrent to torrent manager for seeding
-		tm := h.daemon.GetTorrentManager()
-		fmt.Printf("[ShareModel] Adding torrent to torrent manager\n")
-		managedTorrent, err := tm.AddTorrentForSeeding(torrentPath, req.Name, modelPath)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to add torrent: %v", err),
-			})
-			return
-		}
-		fmt.Printf("[ShareModel] Torrent added to manager with InfoHash: %s\n", managedTorrent.InfoHash)
-		
-		// Start seeding
-		fmt.Printf("[ShareModel] Starting seeding for model: %s\n", req.Name)
-		if err := tm.StartSeeding(managedTorrent.InfoHash); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to start seeding: %v", err),
-			})
-			return
-		}
-		fmt.Printf("[ShareModel] Seeding started successfully\n")
-
-		// Announce to DHT (both regular DHT and BEP44)
-		fmt.Printf("[ShareModel] Announcing model to DHT\n")
-		dhtManager := h.daemon.GetDHTManager()
-		if !req.SkipDHT {
-			// Create announcement for BEP44 discovery
-			announcement := &types.ModelAnnouncement{
-				Name:     req.Name,
-				InfoHash: managedTorrent.InfoHash,
-				Size:     manifest.TotalSize,
-				Version:  req.Version,
+		req.applyMetadata(manifest)
+
+		// Encrypted models seed their encrypted files instead of the model
+		seedPath := modelPath
+		manifest.Encryption = nil
+		var key string
+		if req.Encrypt {
+			fmt.Printf("[ShareModel] Encrypting model: %s\n", req.Name)
+			ignore, err := storage.LoadIgnore(storage.ResolveModelDir(modelPath), req.Exclude, req.Include)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, err.Error())
+				return
 			}
-			fmt.Printf("[ShareModel] Creating BEP44 announcement for model: %s\n", req.Name)
-			if err := dhtManager.AnnounceModel(announcement); err != nil {
-				fmt.Printf("[ShareModel] Warning: BEP44 announcement failed: %v\n", err)
-			} else {
-				fmt.Printf("[ShareModel] BEP44 announcement successful\n")
+			manifest.Encryption, key, seedPath, err = h.daemon.EncryptModel(req.Name, modelPath, req.KeyURL, ignore)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to encrypt model: %v", err))
+				return
 			}
-			
-			// Regular DHT announcement happens automatically via BitTorrent client
-			fmt.Printf("[ShareModel] Regular DHT announcement will be handled by BitTorrent client\n")
-		} else {
-			fmt.Printf("[ShareModel] Skipping DHT announcement (--skip-dht flag)\n")
 		}
 
-		// Create transfer entry
-		transferManager := h.daemon.GetTransferManager()
-		transfer := transferManager.CreateSeed(req.Name, managedTorrent.InfoHash)
-		transfer.Status = "active"
-
-		c.JSON(http.StatusOK, gin.H{
-			"message":     "model published and seeding started",
-			"model_name":  req.Name,
-			"info_hash":   infoHash,
-			"transfer_id": transfer.ID,
+		// Save the manifest, create the torrent, seed and announce it
+		job, err := h.daemon.Publish(publish.Request{
+			Name:         req.Name,
+			Path:         modelPath,
+			SeedPath:     seedPath,
+			TorrentPath:  paths.TorrentPath(req.Name),
+			Manifest:     manifest,
+			Exclude:      req.Exclude,
+			Include:      req.Include,
+			Version:      req.Version,
+			PieceLength:  req.PieceLength,
+			SkipAnnounce: req.SkipDHT,
 		})
-		return
-	}
-	
-	c.JSON(http.StatusBadRequest, gin.H{
-		"error": "must specify model_name, path, or all=true",
-	})
-}
-
-
-// parseRepoURL extracts model name from repository URL
-func parseRepoURL(repoURL string) string {
-	// Handle HuggingFace URLs
-	if strings.Contains(repoURL, "huggingface.co") {
-		parts := strings.Split(repoURL, "/")
-		if len(parts) >= 5 {
-			// Format: https://huggingface.co/owner/model
-			return parts[3] + "/" + parts[4]
-		}
-	}
-	
-	// Handle GitHub URLs
-	if strings.Contains(repoURL, "github.com") {
-		parts := strings.Split(repoURL, "/")
-		if len(parts) >= 5 {
-			// Format: https://github.com/owner/repo
-			owner := parts[3]
-			repo := strings.TrimSuffix(parts[4], ".git")
-			return owner + "/" + repo
-		}
-	}
-	
-	// For other git URLs, try to extract a reasonable name
-	parts := strings.Split(repoURL, "/")
-	if len(parts) >= 2 {
-		modelName := strings.TrimSuffix(parts[len(parts)-1], ".git")
-		if len(parts) >= 3 {
-			return parts[len(parts)-2] + "/" + modelName
+		// The model is seeded even if announcing it failed
+		if err != nil && publish.FailedStage(err) != publish.StageAnnounce {
+			respondAPIError(c, apierror.Newf(http.StatusInternalServerError, "failed to publish model: %v", err).
+				WithDetail("job_id", job.ID))
+			return
 		}
-		return "unknown/" + modelName
-	}
-	
-	return ""
-}
 
-// downloadLFSFiles downloads Git LFS files in the repository
-func downloadLFSFiles(repo *git.Repository, repoPath string, auth transport.AuthMethod) error {
-	// First, check if git-lfs is installed
-	if _, err := exec.LookPath("git-lfs"); err != nil {
-		fmt.Printf("[LFS] git-lfs not found in PATH, trying to download LFS files manually\n")
-		return downloadLFSFilesManually(repo, repoPath, auth)
-	}
-	
-	// Use git-lfs to pull files
-	fmt.Printf("[LFS] Using git-lfs to pull LFS files\n")
-	cmd := exec.Command("git", "lfs", "pull")
-	cmd.Dir = repoPath
-	
-	// Set up authentication if provided
-	if auth != nil {
-		if basicAuth, ok := auth.(*githttp.BasicAuth); ok {
-			cmd.Env = append(os.Environ(),
-				fmt.Sprintf("GIT_ASKPASS=echo"),
-				fmt.Sprintf("GIT_USERNAME=%s", basicAuth.Username),
-				fmt.Sprintf("GIT_PASSWORD=%s", basicAuth.Password),
-			)
+		response := gin.H{
+			"message":     "model published and seeding started",
+			"model_name":  req.Name,
+			"info_hash":   job.InfoHash,
+			"transfer_id": job.TransferID,
+			"job_id":      job.ID,
+			"imported":    imported,
 		}
-	}
-	
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git lfs pull failed: %v, output: %s", err, output)
-	}
-	
-	fmt.Printf("[LFS] git-lfs pull output: %s\n", output)
-	return nil
-}
-
-// downloadLFSFilesManually downloads LFS files without git-lfs command
-func downloadLFSFilesManually(repo *git.Repository, repoPath string, auth transport.AuthMethod) error {
-	// Find all LFS pointer files
-	lfsFiles := make(map[string]*LFSPointer)
-	
-	err := filepath.Walk(repoPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			return err
-		}
-		
-		// Skip directories and .git folder
-		if info.IsDir() || strings.Contains(path, ".git") {
-			return nil
-		}
-		
-		// Check if file is an LFS pointer
-		if pointer := parseLFSPointer(path); pointer != nil {
-			lfsFiles[path] = pointer
-		}
-		
-		return nil
-	})
-	
-	if err != nil {
-		return fmt.Errorf("failed to walk repository: %v", err)
-	}
-	
-	if len(lfsFiles) == 0 {
-		fmt.Printf("[LFS] No LFS files found\n")
-		return nil
-	}
-	
-	fmt.Printf("[LFS] Found %d LFS pointer files\n", len(lfsFiles))
-	
-	// Get the remote URL
-	remotes, err := repo.Remotes()
-	if err != nil || len(remotes) == 0 {
-		return fmt.Errorf("no remotes configured")
-	}
-	
-	remoteURL := remotes[0].Config().URLs[0]
-	lfsURL := getLFSEndpoint(remoteURL)
-	
-	// Download each LFS file
-	for filePath, pointer := range lfsFiles {
-		fmt.Printf("[LFS] Downloading %s (%.2f MB)\n", filePath, float64(pointer.Size)/(1024*1024))
-		
-		if err := downloadLFSObject(lfsURL, pointer, filePath, auth); err != nil {
-			fmt.Printf("[LFS] Failed to download %s: %v\n", filePath, err)
-			// Continue with other files
+			response["warning"] = err.Error()
 		}
-	}
-	
-	return nil
-}
-
-// LFSPointer represents a Git LFS pointer file
-type LFSPointer struct {
-	OID  string
-	Size int64
-}
-
-// parseLFSPointer checks if a file is an LFS pointer and parses it
-func parseLFSPointer(filePath string) *LFSPointer {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil
-	}
-	defer file.Close()
-	
-	// LFS pointer files are small (< 1KB)
-	info, err := file.Stat()
-	if err != nil || info.Size() > 1024 {
-		return nil
-	}
-	
-	scanner := bufio.NewScanner(file)
-	var pointer LFSPointer
-	hasVersion := false
-	
-	for scanner.Scan() {
-		line := scanner.Text()
-		
-		if line == "version https://git-lfs.github.com/spec/v1" {
-			hasVersion = true
-		} else if strings.HasPrefix(line, "oid sha256:") {
-			pointer.OID = strings.TrimPrefix(line, "oid sha256:")
-		} else if strings.HasPrefix(line, "size ") {
-			fmt.Sscanf(line, "size %d", &pointer.Size)
+		if manifest.Encryption != nil {
+			response["key"] = key
+			response["key_id"] = manifest.Encryption.KeyID
 		}
+		c.JSON(http.StatusOK, response)
+		return
 	}
 	
-	if hasVersion && pointer.OID != "" && pointer.Size > 0 {
-		return &pointer
-	}
-	
-	return nil
+	respondError(c, http.StatusBadRequest, "must specify model_name, path, or all=true")
 }
 
-// getLFSEndpoint converts a git remote URL to an LFS endpoint
-func getLFSEndpoint(remoteURL string) string {
-	// For GitHub
-	if strings.Contains(remoteURL, "github.com") {
-		// Convert git@github.com:user/repo.git to https://github.com/user/repo.git
-		if strings.HasPrefix(remoteURL, "git@") {
-			remoteURL = strings.Replace(remoteURL, "git@github.com:", "https://github.com/", 1)
-		}
-		remoteURL = strings.TrimSuffix(remoteURL, ".git")
-		return remoteURL + ".git/info/lfs"
-	}
-	
-	// For HuggingFace
-	if strings.Contains(remoteURL, "huggingface.co") {
-		remoteURL = strings.TrimSuffix(remoteURL, ".git")
-		return remoteURL + ".git/info/lfs"
-	}
-	
-	// Generic Git LFS endpoint
-	return strings.TrimSuffix(remoteURL, "/") + "/info/lfs"
-}
 
-// downloadLFSObject downloads a single LFS object
-func downloadLFSObject(lfsEndpoint string, pointer *LFSPointer, filePath string, auth transport.AuthMethod) error {
-	// Construct download URL
-	// For simplicity, try direct download first (works for public repos)
-	downloadURL := fmt.Sprintf("%s/objects/%s", lfsEndpoint, pointer.OID)
-	
-	req, err := http.NewRequest("GET", downloadURL, nil)
-	if err != nil {
-		return err
-	}
-	
-	// Add authentication if provided
-	if auth != nil {
-		if basicAuth, ok := auth.(*githttp.BasicAuth); ok {
-			req.SetBasicAuth(basicAuth.Username, basicAuth.Password)
-		}
-	}
-	
-	req.Header.Set("Accept", "application/vnd.git-lfs+json")
-	
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// sameDir reports whether a and b are the same directory
+func sameDir(a, b string) bool {
+	infoA, err := os.Stat(a)
 	if err != nil {
-		return err
+		return false
 	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		// Try batch API for authenticated downloads
-		return downloadLFSObjectBatch(lfsEndpoint, pointer, filePath, auth)
-	}
-	
-	// Download to temporary file first
-	tmpFile := filePath + ".tmp"
-	out, err := os.Create(tmpFile)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-	
-	// Download and verify SHA256
-	hasher := sha256.New()
-	writer := io.MultiWriter(out, hasher)
-	
-	_, err = io.Copy(writer, resp.Body)
+	infoB, err := os.Stat(b)
 	if err != nil {
-		os.Remove(tmpFile)
-		return err
+		return false
 	}
-	
-	// Verify OID
-	computedOID := hex.EncodeToString(hasher.Sum(nil))
-	if computedOID != pointer.OID {
-		os.Remove(tmpFile)
-		return fmt.Errorf("OID mismatch: expected %s, got %s", pointer.OID, computedOID)
-	}
-	
-	// Replace pointer file with actual content
-	return os.Rename(tmpFile, filePath)
-}
-
-// downloadLFSObjectBatch uses the batch API for authenticated downloads
-func downloadLFSObjectBatch(lfsEndpoint string, pointer *LFSPointer, filePath string, auth transport.AuthMethod) error {
-	// This is a simplified version - full implementation would use the batch API
-	// For now, return an error suggesting to use git-lfs
-	return fmt.Errorf("authenticated LFS download requires git-lfs command")
-}
-
-// copyDir recursively copies a directory
-func copyDir(src, dst string) error {
-	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Get relative path
-		relPath, err := filepath.Rel(src, path)
-		if err != nil {
-			return err
-		}
-
-		dstPath := filepath.Join(dst, relPath)
-
-		if info.IsDir() {
-			return os.MkdirAll(dstPath, info.Mode())
-		}
-
-		// Copy file
-		srcFile, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-		defer srcFile.Close()
-
-		dstFile, err := os.Create(dstPath)
-		if err != nil {
-			return err
-		}
-		defer dstFile.Close()
-
-		_, err = io.Copy(dstFile, srcFile)
-		return err
-	})
+	return os.SameFile(infoA, infoB)
 }
 
 // RemoveModel removes a model from local storage
 func (h *Handlers) RemoveModel(c *gin.Context) {
-	modelName := c.Param("name")
-	
-	// Clean up model name
-	modelName = strings.ReplaceAll(modelName, "/", "_")
-	
-	paths, err := storage.NewPaths()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to initialize paths: %v", err),
-		})
+	// Model names contain a slash, so the route uses a wildcard parameter
+	modelName, ok := canonicalModelName(c, strings.TrimPrefix(c.Param("name"), "/"))
+	if !ok {
 		return
 	}
+	modelName = h.daemon.ResolveModel(modelName)
+	purge := c.Query("purge") == "true"
 	
-	registry, err := models.NewRegistry(paths)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to create registry: %v", err),
-		})
+	modelPath, ok := modelDir(c, h.daemon.GetRegistry().Paths(), modelName)
+	if !ok {
 		return
 	}
 	
-	if err := registry.ScanModels(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to scan models: %v", err),
-		})
+	// Check if model exists. Cancelled downloads are not listed but can
+	// still be purged.
+	_, err := h.findManifest(modelName)
+	if err != nil && !(purge && storage.IsPartial(modelPath)) {
+		respondError(c, http.StatusNotFound, fmt.Sprintf("model %s not found: %v", modelName, err))
 		return
 	}
 	
-	// Check if model exists
-	_, err = registry.GetManifest(modelName)
+	result, err := h.daemon.RemoveModel(modelName, purge)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": fmt.Sprintf("model %s not found: %v", modelName, err),
-		})
+		respondAPIError(c, daemonError(http.StatusInternalServerError, "failed to remove model", err))
 		return
 	}
 	
-	// Get the info hash from the manifest (we need to extract it from magnet URI)
-	// For now, just use the model name as identifier
-	infoHash := modelName
-	
-	// Stop seeding if active
-	h.daemon.GetTorrentManager().RemoveTorrent(infoHash)
-	
-	// Remove from DHT
-	h.daemon.GetDHTManager().RemoveTorrentFromDHT(infoHash)
-	
-	// Note: We don't actually delete the files here - that would be done separately
-	// This just removes it from active management
+	message := "model removed from active management"
+	if result.Purged {
+		message = "model deleted"
+	}
 	
 	c.JSON(http.StatusOK, gin.H{
-		"message":    "model removed from active management",
-		"model_name": modelName,
+		"message":          message,
+		"model_name":       modelName,
+		"stopped_torrents": result.StoppedTorrents,
+		"purged":           result.Purged,
+		"freed_bytes":      result.FreedBytes,
 	})
 }
\ No newline at end of file
@@ -5,9 +5,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/daemon"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -65,7 +72,7 @@ func TestGetModel(t *testing.T) {
 	var response map[string]interface{}
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err)
-	assert.Contains(t, response["error"], "not found")
+	assert.Contains(t, apiError(t, response)["message"], "not found")
 }
 
 func TestDownloadModel(t *testing.T) {
@@ -104,6 +111,42 @@ func TestDownloadModel(t *testing.T) {
 	}
 }
 
+func TestDownloadModelExisting(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	
+	router := gin.New()
+	router.POST("/models/download", h.DownloadModel)
+	
+	const infoHash = "abcd000000000000000000000000000000000000"
+	existing := d.GetTransferManager().CreateDownload("org/model", infoHash, 0)
+	download := func(modelName string) (int, map[string]interface{}) {
+		body, _ := json.Marshal(DownloadModelRequest{ModelName: modelName, InfoHash: infoHash})
+		req, _ := http.NewRequest("POST", "/models/download", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+	
+	// Asking again returns the running download
+	code, response := download("org/model")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, existing.ID, response["transfer_id"])
+	assert.Equal(t, true, response["existing"])
+	assert.Len(t, d.GetTransferManager().GetAllTransfers(), 1)
+	
+	// The same torrent under another name conflicts
+	code, response = download("org/other")
+	assert.Equal(t, http.StatusConflict, code)
+	apiErr := apiError(t, response)
+	assert.Equal(t, "conflict", apiErr["code"])
+	assert.Equal(t, existing.ID, apiErr["details"].(map[string]interface{})["transfer_id"])
+}
+
 func TestShareModel(t *testing.T) {
 	h, d := setupTestHandlers(t)
 	defer d.Shutdown()
@@ -162,7 +205,7 @@ func TestShareModelSpecific(t *testing.T) {
 		var response map[string]interface{}
 		err := json.Unmarshal(w.Body.Bytes(), &response)
 		require.NoError(t, err)
-		assert.Contains(t, response["error"], "not found")
+		assert.Contains(t, apiError(t, response)["message"], "not found")
 	}
 }
 
@@ -188,7 +231,7 @@ func TestRemoveModel(t *testing.T) {
 	var response map[string]interface{}
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err)
-	assert.Contains(t, response["error"], "not found")
+	assert.Contains(t, apiError(t, response)["message"], "not found")
 }
 
 func TestDownloadModelInvalidRequest(t *testing.T) {
@@ -214,6 +257,57 @@ func TestDownloadModelInvalidRequest(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestDownloadModelInvalidInfoHash(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	
+	router := gin.New()
+	router.POST("/models/download", h.DownloadModel)
+	
+	for _, infoHash := range []string{"../../../tmp/evil", "0123456789abcdef", "0123456789abcdef0123456789abcdef0123456z"} {
+		body := `{"model_name": "org/model", "info_hash": "` + infoHash + `"}`
+		req, _ := http.NewRequest("POST", "/models/download", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Contains(t, apiError(t, response)["message"], "invalid infohash")
+	}
+}
+
+func TestInvalidModelNames(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	
+	router := gin.New()
+	router.POST("/models/download", h.DownloadModel)
+	router.POST("/models/rename", h.RenameModel)
+	router.DELETE("/models/*name", h.RemoveModel)
+	
+	requests := []struct {
+		method, path, body string
+	}{
+		{"POST", "/models/download", `{"model_name": "../../etc", "info_hash": "0123456789abcdef0123456789abcdef01234567"}`},
+		{"POST", "/models/download", `{"model_name": "org/model/extra"}`},
+		{"POST", "/models/rename", `{"from": "org/model", "to": "org/../../outside"}`},
+		{"DELETE", "/models/org/model%20name", ""},
+	}
+	for _, r := range requests {
+		req, _ := http.NewRequest(r.method, r.path, strings.NewReader(r.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		
+		require.Equal(t, http.StatusBadRequest, w.Code, r.body)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Contains(t, apiError(t, response)["message"], "invalid model name")
+	}
+}
+
 func TestShareModelInvalidRequest(t *testing.T) {
 	h, d := setupTestHandlers(t)
 	defer d.Shutdown()
@@ -240,5 +334,119 @@ func TestShareModelInvalidRequest(t *testing.T) {
 	var response map[string]interface{}
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err)
-	assert.Contains(t, response["error"], "must specify")
-}
\ No newline at end of file
+	assert.Contains(t, apiError(t, response)["message"], "must specify")
+}
+func TestShareRequestSeedingPolicy(t *testing.T) {
+	manifest := &types.ModelManifest{Name: "org/model"}
+
+	// Without limits the manifest is left alone
+	req := &ShareModelRequest{}
+	assert.False(t, req.applySeedingPolicy(manifest))
+	assert.Nil(t, manifest.Seeding)
+
+	ratio := 2.0
+	req = &ShareModelRequest{SeedRatio: &ratio}
+	assert.True(t, req.applySeedingPolicy(manifest))
+	require.NotNil(t, manifest.Seeding)
+	assert.Equal(t, 2.0, *manifest.Seeding.SeedRatio)
+	assert.Nil(t, manifest.Seeding.SeedTime)
+
+	// Sharing again keeps the limits and clears a stop
+	manifest.Seeding.StopReason = "seed ratio 2.00 reached"
+	req = &ShareModelRequest{}
+	assert.True(t, req.applySeedingPolicy(manifest))
+	assert.Empty(t, manifest.Seeding.StopReason)
+	assert.Equal(t, 2.0, *manifest.Seeding.SeedRatio)
+
+	req = &ShareModelRequest{SeedPriority: "high"}
+	assert.True(t, req.applySeedingPolicy(manifest))
+	assert.Equal(t, "high", manifest.Seeding.Priority)
+	assert.Equal(t, 2.0, *manifest.Seeding.SeedRatio)
+}
+
+func TestShareRequestStorage(t *testing.T) {
+	manifest := &types.ModelManifest{Name: "org/model"}
+
+	req := &ShareModelRequest{Storage: "mmap"}
+	assert.True(t, req.applySeedingPolicy(manifest))
+	assert.Equal(t, "mmap", manifest.Storage)
+	assert.Nil(t, manifest.Seeding)
+
+	// Sharing again without a backend keeps the model's
+	req = &ShareModelRequest{}
+	assert.False(t, req.applySeedingPolicy(manifest))
+	assert.Equal(t, "mmap", manifest.Storage)
+
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	router := gin.New()
+	router.POST("/models/share", h.ShareModel)
+
+	body, _ := json.Marshal(ShareModelRequest{ModelName: "org/model", Storage: "tape"})
+	httpReq, _ := http.NewRequest("POST", "/models/share", bytes.NewBuffer(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httpReq)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDownloadModelOutput(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tmpDir := t.TempDir()
+	t.Setenv("SILMARIL_HOME", tmpDir)
+	outputDir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	d, err := daemon.New(&config.Config{
+		Storage: config.StorageConfig{BaseDir: tmpDir, OutputDirs: []string{outputDir}},
+	})
+	require.NoError(t, err)
+	defer d.Shutdown()
+	h := NewHandlers(d)
+	
+	router := gin.New()
+	router.POST("/models/download", h.DownloadModel)
+	download := func(modelName, output string) (int, map[string]interface{}) {
+		body, _ := json.Marshal(DownloadModelRequest{ModelName: modelName, InfoHash: strings.Repeat("ab", 20), Output: output})
+		req, _ := http.NewRequest("POST", "/models/download", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+	
+	code, _ := download("org/model", "relative/dir")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = download("org/model", filepath.Join(storage.GetModelsDir(), "elsewhere"))
+	assert.Equal(t, http.StatusBadRequest, code)
+	
+	// Only directories below storage.output_dirs can be written to
+	code, _ = download("org/model", t.TempDir())
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = download("org/model", outputDir)
+	assert.Equal(t, http.StatusForbidden, code)
+	
+	// A model directory already taken is not replaced
+	require.NoError(t, os.MkdirAll(filepath.Join(storage.GetModelsDir(), "org", "taken"), 0755))
+	code, _ = download("org/taken", filepath.Join(outputDir, "taken"))
+	assert.Equal(t, http.StatusConflict, code)
+	
+	// Nor are the files of a directory in use
+	used := filepath.Join(outputDir, "used")
+	require.NoError(t, os.MkdirAll(used, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(used, "notes.txt"), []byte("keep"), 0644))
+	code, _ = download("org/model", used)
+	assert.Equal(t, http.StatusConflict, code)
+	
+	output := filepath.Join(outputDir, "model")
+	code, response := download("org/model", output)
+	require.Equal(t, http.StatusOK, code, "%v", response)
+	assert.Equal(t, output, response["path"])
+	
+	target, ok := storage.IsLinkedModel(filepath.Join(storage.GetModelsDir(), "org", "model"))
+	require.True(t, ok)
+	assert.Equal(t, output, target)
+	assert.True(t, storage.IsPartial(output))
+}
@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pinning reports the community pinning policy and the models pinned for
+// the network
+func (h *Handlers) Pinning(c *gin.Context) {
+	c.JSON(http.StatusOK, h.daemon.PinningReport())
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/publish"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/torrent"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// Torrents created at the same time when publishing a directory tree
+const defaultPublishWorkers = 2
+
+// PublishModelsRequest publishes the models found in a directory tree
+type PublishModelsRequest struct {
+	Path        string `json:"path" binding:"required"`
+	Recursive   bool   `json:"recursive"` // Search the tree for models
+	License     string `json:"license"`   // For models whose model card names none
+	Version     string `json:"version"`
+	Import      string `json:"import"` // copy, link or inplace
+	PieceLength int64  `json:"piece_length"`
+	SkipDHT     bool   `json:"skip_dht"`
+	Workers     int    `json:"workers"` // Models published at the same time
+	DryRun      bool   `json:"dry_run"` // Report what would be published
+	// Patterns of files left out of, or kept in, every model
+	Exclude []string `json:"exclude"`
+	Include []string `json:"include"`
+}
+
+// SkippedModel is a model found in a directory tree that is not published
+type SkippedModel struct {
+	publish.Found
+	Error string `json:"error"`
+}
+
+// PublishModels publishes every model found under a directory in the
+// background. Models are named after their place in the tree, and their
+// progress is followed with the jobs API.
+func (h *Handlers) PublishModels(c *gin.Context) {
+	var req PublishModelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	if req.Import != "" && !storage.ValidImportMode(req.Import) {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid import mode %q, must be copy, link or inplace", req.Import))
+		return
+	}
+	if info, err := os.Stat(req.Path); err != nil || !info.IsDir() {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("%s is not a directory", req.Path))
+		return
+	}
+
+	found, err := publish.FindModels(req.Path, req.Recursive)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(found) == 0 {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("no models found in %s, models need a config.json or GGUF file", req.Path))
+		return
+	}
+
+	paths := h.daemon.GetRegistry().Paths()
+
+	var reqs []publish.Request
+	skipped := []SkippedModel{}
+	names := make(map[string]string)
+	for _, model := range found {
+		modelPath, err := checkPublishable(paths, model, req.License, names)
+		if err != nil {
+			skipped = append(skipped, SkippedModel{Found: model, Error: err.Error()})
+			continue
+		}
+		names[model.Name] = model.Path
+
+		reqs = append(reqs, publish.Request{
+			Name:        model.Name,
+			Path:        modelPath,
+			TorrentPath: paths.TorrentPath(model.Name),
+			Import:      &publish.Import{From: model.Path, Mode: req.Import},
+			Edit: func(manifest *types.ModelManifest) {
+				if req.License != "" && (manifest.License == "" || manifest.License == "Unknown") {
+					manifest.License = req.License
+				}
+				if req.Version != "" {
+					manifest.Version = req.Version
+				}
+			},
+			Exclude:      req.Exclude,
+			Include:      req.Include,
+			Version:      req.Version,
+			PieceLength:  req.PieceLength,
+			SkipAnnounce: req.SkipDHT,
+		})
+	}
+
+	if req.DryRun {
+		plans := []*publish.Plan{}
+		for _, publishReq := range reqs {
+			plan, err := publish.PlanRequest(publishReq)
+			if err != nil {
+				skipped = append(skipped, SkippedModel{
+					Found: publish.Found{Name: publishReq.Name, Path: publishReq.Import.From},
+					Error: err.Error(),
+				})
+				continue
+			}
+			plans = append(plans, plan)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": fmt.Sprintf("dry run, would publish %d of %d models found", len(plans), len(found)),
+			"dry_run": true,
+			"plans":   plans,
+			"skipped": skipped,
+			"count":   len(plans),
+		})
+		return
+	}
+
+	workers := req.Workers
+	if workers <= 0 {
+		workers = defaultPublishWorkers
+	}
+	jobs := h.daemon.StartPublishBatch(reqs, workers)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": fmt.Sprintf("publishing %d of %d models found", len(jobs), len(found)),
+		"jobs":    jobs,
+		"skipped": skipped,
+		"count":   len(jobs),
+	})
+}
+
+// InfoHashRequest computes the infohash a directory would be published with
+type InfoHashRequest struct {
+	Path        string   `json:"path" binding:"required"`
+	PieceLength int64    `json:"piece_length"`
+	Exclude     []string `json:"exclude"`
+	Include     []string `json:"include"`
+}
+
+// ComputeInfoHash hashes the files of a directory into the torrent
+// publishing it would create, without writing anything. Publishers of the
+// same files get the same infohash.
+func (h *Handlers) ComputeInfoHash(c *gin.Context) {
+	var req InfoHashRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	if info, err := os.Stat(req.Path); err != nil || !info.IsDir() {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("%s is not a directory", req.Path))
+		return
+	}
+
+	ignore, err := storage.LoadIgnore(storage.ResolveModelDir(req.Path), req.Exclude, req.Include)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	info, err := torrent.BuildInfo(req.Path, req.PieceLength, ignore)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	infoHash, err := torrent.InfoHash(info)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"path":         req.Path,
+		"info_hash":    infoHash,
+		"piece_length": info.PieceLength,
+		"pieces":       info.NumPieces(),
+		"files":        len(info.Files),
+		"total_size":   info.TotalLength(),
+	})
+}
+
+// checkPublishable returns the directory a model found in a tree is
+// published to, or why it can't be published: its name, taken from its
+// place in the tree, is invalid or taken, or it has no license
+func checkPublishable(paths *storage.Paths, model publish.Found, license string, names map[string]string) (string, error) {
+	modelPath, err := paths.ModelDir(model.Name)
+	if err != nil {
+		return "", err
+	}
+	if other, ok := names[model.Name]; ok {
+		return "", fmt.Errorf("name %s is already used by %s", model.Name, other)
+	}
+	if _, err := os.Stat(modelPath); err == nil {
+		return "", fmt.Errorf("model %s already exists", model.Name)
+	}
+	if license == "" {
+		card, err := models.LoadModelCard(model.Path)
+		if err != nil || card.License == "" {
+			return "", fmt.Errorf("no license, set one in the model card or with --license")
+		}
+	}
+	return modelPath, nil
+}
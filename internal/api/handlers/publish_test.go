@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishModelsValidation(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+
+	router := gin.New()
+	router.POST("/models/publish", h.PublishModels)
+
+	post := func(req PublishModelsRequest) (int, map[string]interface{}) {
+		body, _ := json.Marshal(req)
+		httpReq, _ := http.NewRequest("POST", "/models/publish", bytes.NewBuffer(body))
+		httpReq.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httpReq)
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+
+	zoo := t.TempDir()
+	code, response := post(PublishModelsRequest{Path: zoo, Recursive: true})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, apiError(t, response)["message"], "no models found")
+
+	code, _ = post(PublishModelsRequest{Path: zoo, Recursive: true, Import: "move"})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// Models without a license are skipped
+	model := filepath.Join(zoo, "org", "model")
+	require.NoError(t, os.MkdirAll(model, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(model, "config.json"), []byte("{}"), 0644))
+
+	code, response = post(PublishModelsRequest{Path: zoo, Recursive: true})
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, float64(0), response["count"])
+	skipped, ok := response["skipped"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, skipped, 1)
+	assert.Equal(t, "org/model", skipped[0].(map[string]interface{})["name"])
+	assert.Contains(t, skipped[0].(map[string]interface{})["error"], "no license")
+}
+
+func TestPublishModelsDryRun(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+
+	router := gin.New()
+	router.POST("/models/publish", h.PublishModels)
+
+	zoo := t.TempDir()
+	model := filepath.Join(zoo, "org", "model")
+	require.NoError(t, os.MkdirAll(model, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(model, "config.json"), []byte("{}"), 0644))
+
+	body, _ := json.Marshal(PublishModelsRequest{Path: zoo, Recursive: true, License: "mit", DryRun: true})
+	httpReq, _ := http.NewRequest("POST", "/models/publish", bytes.NewBuffer(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httpReq)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, true, response["dry_run"])
+	plans, ok := response["plans"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, plans, 1)
+	plan := plans[0].(map[string]interface{})
+	assert.Equal(t, "org/model", plan["model"])
+	assert.Len(t, plan["info_hash"], 40)
+	assert.Equal(t, "mit", plan["manifest"].(map[string]interface{})["license"])
+
+	// Nothing is imported or seeded
+	assert.Empty(t, h.daemon.PublishJobs())
+	assert.NoFileExists(t, filepath.Join(model, ".silmaril.json"))
+}
+
+func TestPublishModelsInvalidName(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+
+	router := gin.New()
+	router.POST("/models/publish", h.PublishModels)
+
+	// Models are named after their directories, which need not be valid names
+	zoo := t.TempDir()
+	for _, dir := range []string{"org/model", "org/bad model"} {
+		model := filepath.Join(zoo, filepath.FromSlash(dir))
+		require.NoError(t, os.MkdirAll(model, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(model, "config.json"), []byte("{}"), 0644))
+	}
+
+	body, _ := json.Marshal(PublishModelsRequest{Path: zoo, Recursive: true, License: "mit", DryRun: true})
+	httpReq, _ := http.NewRequest("POST", "/models/publish", bytes.NewBuffer(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httpReq)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response["plans"], 1)
+	skipped, ok := response["skipped"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, skipped, 1)
+	assert.Equal(t, "org/bad model", skipped[0].(map[string]interface{})["name"])
+	assert.Contains(t, skipped[0].(map[string]interface{})["error"], "invalid model name")
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPublisher returns our own publisher key
+func (h *Handlers) GetPublisher(c *gin.Context) {
+	dm := h.daemon.GetDHTManager()
+
+	publicKey := dm.PublisherKey()
+	if publicKey == "" {
+		respondError(c, http.StatusServiceUnavailable, "publisher key not available")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"public_key": publicKey,
+		"models":     dm.PublisherModelCount(),
+	})
+}
+
+// RotatePublisherKey replaces our publisher key with a new one that
+// subscribers of the old key follow
+func (h *Handlers) RotatePublisherKey(c *gin.Context) {
+	result, err := h.daemon.RotatePublisherKey()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to rotate publisher key: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "publisher key rotated",
+		"rotation": result,
+	})
+}
+
+// ListSubscriptions returns all subscribed publishers
+func (h *Handlers) ListSubscriptions(c *gin.Context) {
+	subscriptions := h.daemon.GetDHTManager().ListSubscriptions()
+
+	c.JSON(http.StatusOK, gin.H{
+		"subscriptions": subscriptions,
+		"count":         len(subscriptions),
+	})
+}
+
+// ListNetworks returns the public network and the catalog networks joined
+func (h *Handlers) ListNetworks(c *gin.Context) {
+	networks := h.daemon.GetDHTManager().ListNetworks()
+
+	c.JSON(http.StatusOK, gin.H{
+		"networks": networks,
+		"count":    len(networks),
+	})
+}
+
+// Subscribe follows a publisher's catalog
+func (h *Handlers) Subscribe(c *gin.Context) {
+	var req struct {
+		PublicKey string `json:"public_key" binding:"required"`
+		Name      string `json:"name"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	sub, err := h.daemon.GetDHTManager().Subscribe(req.PublicKey, req.Name)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to subscribe: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "subscribed to publisher",
+		"subscription": sub,
+	})
+}
+
+// Unsubscribe stops following a publisher's catalog
+func (h *Handlers) Unsubscribe(c *gin.Context) {
+	publicKey := c.Param("key")
+
+	if err := h.daemon.GetDHTManager().Unsubscribe(publicKey); err != nil {
+		respondError(c, http.StatusNotFound, fmt.Sprintf("failed to unsubscribe: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "unsubscribed from publisher",
+		"public_key": publicKey,
+	})
+}
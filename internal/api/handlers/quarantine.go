@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/daemon"
+)
+
+// ListQuarantine returns the finished downloads held in quarantine until
+// their files are verified
+func (h *Handlers) ListQuarantine(c *gin.Context) {
+	models := h.daemon.ListQuarantine()
+	c.JSON(http.StatusOK, gin.H{
+		"models": models,
+		"count":  len(models),
+	})
+}
+
+// ReleaseQuarantine lists a quarantined model as a local model without
+// waiting for, or despite, its verification
+func (h *Handlers) ReleaseQuarantine(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	var ok bool
+	if req.Name, ok = canonicalModelName(c, req.Name); !ok {
+		return
+	}
+
+	q, err := h.daemon.ReleaseQuarantine(req.Name)
+	switch {
+	case errors.Is(err, daemon.ErrNotQuarantined):
+		respondError(c, http.StatusNotFound, fmt.Sprintf("%s is not in quarantine", req.Name))
+		return
+	case err != nil:
+		respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to release %s: %v", req.Name, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("%s released from quarantine", req.Name),
+		"model":   q,
+	})
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListScrubs returns when the data of each seeded model was last verified
+func (h *Handlers) ListScrubs(c *gin.Context) {
+	scrubs := h.daemon.ScrubStatuses()
+
+	c.JSON(http.StatusOK, gin.H{
+		"scrubs": scrubs,
+		"count":  len(scrubs),
+	})
+}
+
+// ScrubModel starts re-verifying the data of a seeded model
+func (h *Handlers) ScrubModel(c *gin.Context) {
+	var req struct {
+		Model string `json:"model" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	var ok bool
+	if req.Model, ok = canonicalModelName(c, req.Model); !ok {
+		return
+	}
+
+	req.Model = h.daemon.ResolveModel(req.Model)
+	if err := h.daemon.ScrubModel(req.Model); err != nil {
+		respondError(c, http.StatusConflict, fmt.Sprintf("failed to scrub: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "scrub started",
+		"model":   req.Model,
+	})
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/daemon"
+	"github.com/silmaril/silmaril/internal/discovery"
+)
+
+// Days of daily contribution returned unless the caller asks for more
+const defaultStatsDays = 30
+
+// Stats returns the bytes uploaded and downloaded in total, per model and
+// per day. days limits the daily totals, 0 returns all kept days.
+func (h *Handlers) Stats(c *gin.Context) {
+	days := defaultStatsDays
+	if value := c.Query("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid days: %s", value))
+			return
+		}
+		days = n
+	}
+
+	c.JSON(http.StatusOK, h.daemon.GetContributionStats(days))
+}
+
+// NetworkStats returns the anonymous stats published by the nodes of the
+// network for a day, 2006-01-02 in UTC, today by default
+func (h *Handlers) NetworkStats(c *gin.Context) {
+	day := c.DefaultQuery("day", discovery.StatsDay(time.Now()))
+	if _, err := time.Parse("2006-01-02", day); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid day: %s", day))
+		return
+	}
+
+	stats, err := h.daemon.GetNetworkStats(c.Request.Context(), day)
+	if err != nil {
+		respondError(c, http.StatusServiceUnavailable, fmt.Sprintf("failed to read network stats: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// Metrics exposes the contribution counters in the Prometheus text format
+func (h *Handlers) Metrics(c *gin.Context) {
+	stats := h.daemon.GetContributionStats(1)
+
+	var b strings.Builder
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+	metric("silmaril_uploaded_bytes_total", "counter", "Bytes uploaded to peers across all sessions.")
+	fmt.Fprintf(&b, "silmaril_uploaded_bytes_total %d\n", stats.Uploaded)
+	metric("silmaril_downloaded_bytes_total", "counter", "Bytes downloaded from peers across all sessions.")
+	fmt.Fprintf(&b, "silmaril_downloaded_bytes_total %d\n", stats.Downloaded)
+	metric("silmaril_unique_peers", "gauge", "Distinct peers exchanged data with.")
+	fmt.Fprintf(&b, "silmaril_unique_peers %d\n", stats.UniquePeers)
+
+	metric("silmaril_model_uploaded_bytes_total", "counter", "Bytes of a model uploaded to peers across all sessions.")
+	for _, m := range stats.Models {
+		fmt.Fprintf(&b, "silmaril_model_uploaded_bytes_total{model=\"%s\"} %d\n", escapeLabel(m.Model), m.Uploaded)
+	}
+	metric("silmaril_model_downloaded_bytes_total", "counter", "Bytes of a model downloaded from peers across all sessions.")
+	for _, m := range stats.Models {
+		fmt.Fprintf(&b, "silmaril_model_downloaded_bytes_total{model=\"%s\"} %d\n", escapeLabel(m.Model), m.Downloaded)
+	}
+	metric("silmaril_model_unique_peers", "gauge", "Distinct peers a model was exchanged with.")
+	for _, m := range stats.Models {
+		fmt.Fprintf(&b, "silmaril_model_unique_peers{model=\"%s\"} %d\n", escapeLabel(m.Model), m.UniquePeers)
+	}
+
+	// Which sources find the peers that do the work
+	if tm := h.daemon.GetTorrentManager(); tm != nil {
+		sources := tm.PeerSourceStats()
+		metric("silmaril_peers_found_total", "counter", "Distinct peers the torrents found this session, by source.")
+		for _, s := range sources {
+			fmt.Fprintf(&b, "silmaril_peers_found_total{source=\"%s\"} %d\n", escapeLabel(s.Source), s.Found)
+		}
+		metric("silmaril_peers_connected_total", "counter", "Found peers a connection was established with, by source.")
+		for _, s := range sources {
+			fmt.Fprintf(&b, "silmaril_peers_connected_total{source=\"%s\"} %d\n", escapeLabel(s.Source), s.Connected)
+		}
+		metric("silmaril_peers_active", "gauge", "Peers connected right now, by source.")
+		for _, s := range sources {
+			fmt.Fprintf(&b, "silmaril_peers_active{source=\"%s\"} %d\n", escapeLabel(s.Source), s.Active)
+		}
+		metric("silmaril_peer_downloaded_bytes_total", "counter", "Useful data received from peers this session, by source.")
+		for _, s := range sources {
+			fmt.Fprintf(&b, "silmaril_peer_downloaded_bytes_total{source=\"%s\"} %d\n", escapeLabel(s.Source), s.BytesDownloaded)
+		}
+	}
+
+	limits := h.daemon.APILimitStats()
+	metric("silmaril_api_requests_in_flight", "gauge", "API requests being handled.")
+	fmt.Fprintf(&b, "silmaril_api_requests_in_flight %d\n", limits.InFlight)
+	metric("silmaril_api_requests_limited_total", "counter", "API requests refused for the rate, concurrency or body size limits.")
+	for _, reason := range []string{daemon.APILimitRate, daemon.APILimitConcurrency, daemon.APILimitBodySize} {
+		fmt.Fprintf(&b, "silmaril_api_requests_limited_total{reason=\"%s\"} %d\n", reason, limits.Limited[reason])
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// escapeLabel escapes a Prometheus label value
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
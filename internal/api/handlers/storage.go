@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RebalanceStorage moves the local models to the storage pool their size
+// belongs in, or only lists the moves with dry_run=true
+func (h *Handlers) RebalanceStorage(c *gin.Context) {
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid dry_run: %s", value))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, h.daemon.RebalanceStorage(dryRun))
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SwarmAvailability returns how well the pieces of each model are spread
+// among the peers the daemon is connected to
+func (h *Handlers) SwarmAvailability(c *gin.Context) {
+	models := h.daemon.SwarmAvailability()
+
+	c.JSON(http.StatusOK, gin.H{
+		"models": models,
+		"count":  len(models),
+	})
+}
+
+// UploadSchedule returns how the upload bandwidth is divided between the
+// torrents, with the priority, rate and limit of each
+func (h *Handlers) UploadSchedule(c *gin.Context) {
+	c.JSON(http.StatusOK, h.daemon.UploadSchedule())
+}
+
+// ConnectionStats returns the peer connections of each torrent against the
+// global and per torrent connection limits
+func (h *Handlers) ConnectionStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.daemon.ConnectionStats())
+}
+
+// DHTAnnounces returns the last announce of each model's infohash on the
+// DHT, with how many nodes accepted it
+func (h *Handlers) DHTAnnounces(c *gin.Context) {
+	dm := h.daemon.GetDHTManager()
+	if dm == nil {
+		respondError(c, http.StatusServiceUnavailable, "DHT is not running")
+		return
+	}
+	announces := dm.InfohashAnnounces()
+
+	c.JSON(http.StatusOK, gin.H{
+		"announces": announces,
+		"count":     len(announces),
+	})
+}
+
+// TorrentPeers returns the peers a torrent is connected to, with their
+// client, rate, progress and how they were found
+func (h *Handlers) TorrentPeers(c *gin.Context) {
+	tm := h.daemon.GetTorrentManager()
+	if tm == nil {
+		respondError(c, http.StatusServiceUnavailable, "torrent client is not running")
+		return
+	}
+
+	peers, err := tm.GetPeers(c.Param("infohash"))
+	if err != nil {
+		respondAPIError(c, daemonError(http.StatusNotFound, "failed to get peers", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, peers)
+}
+
+// AddTorrentPeers adds peer addresses to a torrent by hand. They are
+// remembered and added again after a restart.
+func (h *Handlers) AddTorrentPeers(c *gin.Context) {
+	var req struct {
+		Peers []string `json:"peers" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	if h.daemon.GetTorrentManager() == nil {
+		respondError(c, http.StatusServiceUnavailable, "torrent client is not running")
+		return
+	}
+
+	result, err := h.daemon.AddManualPeers(c.Param("infohash"), req.Peers)
+	if err != nil {
+		respondAPIError(c, daemonError(http.StatusNotFound, "failed to add peers", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RemoveTorrentPeer forgets a peer added to a torrent by hand
+func (h *Handlers) RemoveTorrentPeer(c *gin.Context) {
+	if err := h.daemon.RemoveManualPeer(c.Param("infohash"), c.Param("addr")); err != nil {
+		respondAPIError(c, daemonError(http.StatusBadRequest, "failed to remove peer", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "peer removed"})
+}
+
+// PeerFilter returns the number of blocked and allowed ranges and how many
+// peer addresses were rejected
+func (h *Handlers) PeerFilter(c *gin.Context) {
+	c.JSON(http.StatusOK, h.daemon.PeerFilterStatus())
+}
+
+// ReloadPeerFilter reads the configured peer lists again and downloads the
+// blocklist
+func (h *Handlers) ReloadPeerFilter(c *gin.Context) {
+	status, err := h.daemon.ReloadPeerFilter(true)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to reload peer filter: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/daemon"
+)
+
+// ListTokens returns the API tokens, without the tokens themselves
+func (h *Handlers) ListTokens(c *gin.Context) {
+	tokens := h.daemon.ListAPITokens()
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+		"count":  len(tokens),
+	})
+}
+
+// CreateToken creates a named API token of a scope. The token is only
+// returned by this request.
+func (h *Handlers) CreateToken(c *gin.Context) {
+	var req struct {
+		Name  string `json:"name" binding:"required"`
+		Scope string `json:"scope" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	token, record, err := h.daemon.CreateAPIToken(req.Name, req.Scope)
+	switch {
+	case errors.Is(err, daemon.ErrAPITokenExists):
+		respondError(c, http.StatusConflict, fmt.Sprintf("API token %s exists, revoke it first", req.Name))
+		return
+	case err != nil:
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":   token,
+		"name":    record.Name,
+		"scope":   record.Scope,
+		"created": record.CreatedAt,
+	})
+}
+
+// RevokeToken removes an API token
+func (h *Handlers) RevokeToken(c *gin.Context) {
+	name := c.Param("name")
+	err := h.daemon.RevokeAPIToken(name)
+	switch {
+	case errors.Is(err, daemon.ErrAPITokenNotFound):
+		respondError(c, http.StatusNotFound, fmt.Sprintf("API token %s not found", name))
+		return
+	case errors.Is(err, daemon.ErrLastAdminAPIToken):
+		respondError(c, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to revoke API token: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("API token %s revoked", name),
+		"auth":    h.daemon.APIAuthEnabled(),
+	})
+}
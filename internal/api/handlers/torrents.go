@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Largest request body accepted as a torrent file
+const maxTorrentUpload = 64 << 20
+
+// ExportTorrent returns the torrent file of a local model, for moving it to
+// a machine that can't reach its peers
+func (h *Handlers) ExportTorrent(c *gin.Context) {
+	model := c.Query("model")
+	if model == "" {
+		respondError(c, http.StatusBadRequest, "model is required")
+		return
+	}
+
+	data, infoHash, err := h.daemon.ExportTorrent(model)
+	if err != nil {
+		respondAPIError(c, daemonError(http.StatusInternalServerError, "failed to export torrent", err))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(model)+".torrent"))
+	c.Header("X-Info-Hash", infoHash)
+	c.Data(http.StatusOK, "application/x-bittorrent", data)
+}
+
+// ImportTorrent adds the torrent file in the request body as a local model,
+// named by the name query parameter or else the torrent. Files already in
+// the models directory are registered and seeded with seed=true, missing
+// ones are downloaded.
+func (h *Handlers) ImportTorrent(c *gin.Context) {
+	seed := false
+	if value := c.Query("seed"); value != "" {
+		var err error
+		if seed, err = strconv.ParseBool(value); err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid seed: %s", value))
+			return
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxTorrentUpload+1))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to read torrent file: %v", err))
+		return
+	}
+	if len(data) == 0 {
+		respondError(c, http.StatusBadRequest, "torrent file is required")
+		return
+	}
+
+	name := c.Query("name")
+	if name != "" {
+		var ok bool
+		if name, ok = canonicalModelName(c, name); !ok {
+			return
+		}
+	}
+
+	result, err := h.daemon.ImportTorrent(data, name, seed)
+	if err != nil {
+		respondAPIError(c, daemonError(http.StatusBadRequest, "failed to import torrent", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
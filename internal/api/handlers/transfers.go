@@ -35,9 +35,7 @@ func (h *Handlers) GetTransfer(c *gin.Context) {
 	tm := h.daemon.GetTransferManager()
 	transfer, exists := tm.GetTransfer(transferID)
 	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": fmt.Sprintf("transfer %s not found", transferID),
-		})
+		respondError(c, http.StatusNotFound, fmt.Sprintf("transfer %s not found", transferID))
 		return
 	}
 	
@@ -47,15 +45,44 @@ func (h *Handlers) GetTransfer(c *gin.Context) {
 	c.JSON(http.StatusOK, transfer)
 }
 
+// GetTransferStats returns the smoothed rates, ETA, peers and recent rate
+// history of a transfer, for drawing speed graphs
+func (h *Handlers) GetTransferStats(c *gin.Context) {
+	transferID := c.Param("id")
+	
+	stats, err := h.daemon.GetTransferManager().GetTransferStats(transferID)
+	if err != nil {
+		respondAPIError(c, daemonError(http.StatusNotFound, "failed to get transfer stats", err))
+		return
+	}
+	
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetTransferFiles returns how much of each file of a transfer is local, for
+// drawing a progress bar per file
+func (h *Handlers) GetTransferFiles(c *gin.Context) {
+	transferID := c.Param("id")
+	
+	files, err := h.daemon.GetTransferManager().GetTransferFiles(transferID)
+	if err != nil {
+		respondAPIError(c, daemonError(http.StatusNotFound, "failed to get transfer files", err))
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"transfer_id": transferID,
+		"files":       files,
+	})
+}
+
 // PauseTransfer pauses an active transfer
 func (h *Handlers) PauseTransfer(c *gin.Context) {
 	transferID := c.Param("id")
 	
 	tm := h.daemon.GetTransferManager()
 	if err := tm.PauseTransfer(transferID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("failed to pause transfer: %v", err),
-		})
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to pause transfer: %v", err))
 		return
 	}
 	
@@ -71,9 +98,7 @@ func (h *Handlers) ResumeTransfer(c *gin.Context) {
 	
 	tm := h.daemon.GetTransferManager()
 	if err := tm.ResumeTransfer(transferID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("failed to resume transfer: %v", err),
-		})
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to resume transfer: %v", err))
 		return
 	}
 	
@@ -83,20 +108,36 @@ func (h *Handlers) ResumeTransfer(c *gin.Context) {
 	})
 }
 
-// CancelTransfer cancels and removes a transfer
+// CancelTransfer cancels a transfer and drops its torrent. With purge=true
+// the partially downloaded files are deleted as well.
 func (h *Handlers) CancelTransfer(c *gin.Context) {
 	transferID := c.Param("id")
+	purge := c.Query("purge") == "true"
 	
 	tm := h.daemon.GetTransferManager()
-	if err := tm.CancelTransfer(transferID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("failed to cancel transfer: %v", err),
+	if !purge {
+		if err := tm.CancelTransfer(transferID); err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to cancel transfer: %v", err))
+			return
+		}
+		
+		c.JSON(http.StatusOK, gin.H{
+			"message":     "transfer cancelled",
+			"transfer_id": transferID,
 		})
 		return
 	}
 	
+	freed, err := tm.PurgeTransfer(transferID)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to cancel transfer: %v", err))
+		return
+	}
+	
 	c.JSON(http.StatusOK, gin.H{
 		"message":     "transfer cancelled",
 		"transfer_id": transferID,
+		"purged":      true,
+		"freed_bytes": freed,
 	})
-}
\ No newline at end of file
+}
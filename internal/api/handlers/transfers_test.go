@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -124,7 +126,7 @@ func TestGetTransferNotFound(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err)
 	
-	assert.Contains(t, response["error"], "not found")
+	assert.Contains(t, apiError(t, response)["message"], "not found")
 }
 
 func TestPauseTransfer(t *testing.T) {
@@ -185,7 +187,7 @@ func TestPauseTransferInvalid(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err)
 	
-	assert.Contains(t, response["error"], "not active")
+	assert.Contains(t, apiError(t, response)["message"], "not active")
 }
 
 func TestResumeTransfer(t *testing.T) {
@@ -246,7 +248,7 @@ func TestResumeTransferInvalid(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err)
 	
-	assert.Contains(t, response["error"], "not paused")
+	assert.Contains(t, apiError(t, response)["message"], "not paused")
 }
 
 func TestCancelTransfer(t *testing.T) {
@@ -307,5 +309,185 @@ func TestCancelTransferNotFound(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err)
 	
-	assert.Contains(t, response["error"], "not found")
-}
\ No newline at end of file
+	assert.Contains(t, apiError(t, response)["message"], "not found")
+}
+func TestGetTransferStatsNotFound(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	
+	router := gin.New()
+	router.GET("/transfers/:id/stats", h.GetTransferStats)
+	
+	req, _ := http.NewRequest("GET", "/transfers/"+uuid.New().String()+"/stats", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHistory(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	
+	router := gin.New()
+	router.GET("/history", h.History)
+	
+	tm := d.GetTransferManager()
+	transfer := tm.CreateDownload("org/model", "abcd", 0)
+	require.NoError(t, tm.CancelTransfer(transfer.ID))
+	
+	req, _ := http.NewRequest("GET", "/history?model=org/model&result=cancelled", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	
+	var response struct {
+		History []daemon.HistoryEntry `json:"history"`
+		Count   int                   `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, 1, response.Count)
+	assert.Equal(t, transfer.ID, response.History[0].TransferID)
+	
+	for _, query := range []string{"type=torrent", "result=active", "since=yesterday", "limit=-1"} {
+		req, _ := http.NewRequest("GET", "/history?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestStats(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	
+	router := gin.New()
+	router.GET("/stats", h.Stats)
+	router.GET("/metrics", h.Metrics)
+	
+	d.GetState().AddContribution(daemon.ContributionSample{Model: `org/"model"`, Uploaded: 300, Downloaded: 100}, time.Now())
+	
+	req, _ := http.NewRequest("GET", "/stats", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	
+	var stats daemon.ContributionStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, int64(300), stats.Uploaded)
+	assert.InDelta(t, 3.0, stats.Ratio, 0.001)
+	require.Len(t, stats.Models, 1)
+	assert.Len(t, stats.Daily, 1)
+	
+	req, _ = http.NewRequest("GET", "/stats?days=-1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "silmaril_uploaded_bytes_total 300\n")
+	assert.Contains(t, w.Body.String(), `silmaril_model_downloaded_bytes_total{model="org/\"model\""} 100`)
+	assert.Contains(t, w.Body.String(), "# TYPE silmaril_peers_found_total counter\n")
+}
+
+func TestTorrentPeersNotFound(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	
+	router := gin.New()
+	router.GET("/torrents/:infohash/peers", h.TorrentPeers)
+	
+	req, _ := http.NewRequest("GET", "/torrents/0123456789abcdef0123456789abcdef01234567/peers", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTorrentManualPeers(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	
+	router := gin.New()
+	router.POST("/torrents/:infohash/peers", h.AddTorrentPeers)
+	router.DELETE("/torrents/:infohash/peers/:addr", h.RemoveTorrentPeer)
+	
+	path := "/torrents/0123456789abcdef0123456789abcdef01234567/peers"
+	req, _ := http.NewRequest("POST", path, strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	
+	req, _ = http.NewRequest("POST", path, strings.NewReader(`{"peers": ["10.0.0.5:6881"]}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	
+	req, _ = http.NewRequest("DELETE", path+"/10.0.0.5:6881", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestNetworkStatsInvalidDay(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	
+	router := gin.New()
+	router.GET("/network/stats", h.NetworkStats)
+	
+	req, _ := http.NewRequest("GET", "/network/stats?day=yesterday", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExportTorrentNotFound(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	
+	router := gin.New()
+	router.GET("/torrents/export", h.ExportTorrent)
+	
+	req, _ := http.NewRequest("GET", "/torrents/export?model=org/missing", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestImportTorrentInvalid(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	
+	router := gin.New()
+	router.POST("/torrents/import", h.ImportTorrent)
+	
+	req, _ := http.NewRequest("POST", "/torrents/import?name=org/model", strings.NewReader("not a torrent"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCollectGarbageDryRun(t *testing.T) {
+	h, d := setupTestHandlers(t)
+	defer d.Shutdown()
+	
+	router := gin.New()
+	router.POST("/gc", h.CollectGarbage)
+	
+	req, _ := http.NewRequest("POST", "/gc?dry_run=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	
+	var report daemon.GCReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.True(t, report.DryRun)
+	
+	req, _ = http.NewRequest("POST", "/gc?dry_run=maybe", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/api/apierror"
+	"github.com/silmaril/silmaril/internal/daemon"
+)
+
+// Endpoints whose JSON bodies are limited to daemon.api_max_body_kb. Their
+// bodies list models and files, and are decoded whole before anything is
+// checked.
+var bodyLimitedRoutes = map[string]bool{
+	"POST /models/share":   true,
+	"POST /models/publish": true,
+	"POST /unpublish":      true,
+}
+
+// Endpoints clients poll for as long as they follow the daemon, as with
+// 'silmaril daemon logs -f', 'silmaril daemon events -f' and 'silmaril
+// discover --watch'. They only read what the daemon keeps in memory, so they
+// don't count against daemon.api_max_concurrent and followers keep going
+// while the daemon is busy.
+var followedRoutes = map[string]bool{
+	"GET /events":          true,
+	"GET /logs":            true,
+	"POST /discover/watch": true,
+}
+
+// limitMiddleware protects the daemon from misbehaving clients. It refuses
+// requests of clients sending too many with 429, requests beyond the number
+// handled at the same time with 503, both with a Retry-After header, and
+// too large publish and share bodies with 413. Clients are told apart by
+// their API token, or by their address without one. Probes and endpoints
+// clients negotiate with aren't limited, followed endpoints only by rate.
+func limitMiddleware(d *daemon.Daemon) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := apiRoute(c.FullPath())
+		if requiredScope(c.Request.Method, c.FullPath()) == "" || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+		limits := d.APILimits()
+
+		client := "ip:" + c.ClientIP()
+		if token := c.GetString("api_token"); token != "" {
+			client = "token:" + token
+		}
+		if ok, wait := d.AllowAPIRequest(client, limits); !ok {
+			c.Header("Retry-After", strconv.Itoa(retryAfterSeconds(wait)))
+			abortError(c, apierror.New(http.StatusTooManyRequests, "too many requests, slow down").
+				WithDetail("limit", limits.Rate))
+			return
+		}
+
+		if !followedRoutes[c.Request.Method+" "+route] {
+			if !d.BeginAPIRequest(limits) {
+				c.Header("Retry-After", "1")
+				abortError(c, apierror.New(http.StatusServiceUnavailable, "the daemon is handling too many requests, try again").
+					WithDetail("max_concurrent", limits.MaxConcurrent))
+				return
+			}
+			defer d.EndAPIRequest()
+		}
+
+		if limits.MaxBodyBytes > 0 && bodyLimitedRoutes[c.Request.Method+" "+route] {
+			if c.Request.ContentLength > limits.MaxBodyBytes {
+				d.RecordAPILimited(daemon.APILimitBodySize)
+				abortError(c, apierror.Newf(http.StatusRequestEntityTooLarge, "request body is larger than %d KB", limits.MaxBodyBytes/1024).
+					WithDetail("max_bytes", limits.MaxBodyBytes))
+				return
+			}
+			// Bodies of unknown length fail to decode once they pass the limit
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBodyBytes)
+		}
+		c.Next()
+	}
+}
+
+// retryAfterSeconds rounds a wait up to the whole seconds of a Retry-After
+// header
+func retryAfterSeconds(wait time.Duration) int {
+	return int(math.Max(1, math.Ceil(wait.Seconds())))
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/api/apierror"
+	"github.com/silmaril/silmaril/internal/daemon"
+)
+
+// Endpoints that download, publish or change local models, which a relay
+// daemon doesn't store
+var modelDataRoutes = map[string]bool{
+	"POST /models/download":     true,
+	"POST /models/share":        true,
+	"POST /models/publish":      true,
+	"POST /models/infohash":     true,
+	"POST /models/equivalents":  true,
+	"POST /models/key":          true,
+	"POST /models/rename":       true,
+	"POST /model-subscriptions": true,
+	"POST /torrents/import":     true,
+	"POST /scrub":               true,
+	"POST /convert":             true,
+	"POST /storage/rebalance":   true,
+	"POST /quarantine/release":  true,
+}
+
+// relayMiddleware refuses the endpoints handling model data with 409 when
+// the daemon runs as a relay
+func relayMiddleware(d *daemon.Daemon) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d.Relay() && modelDataRoutes[c.Request.Method+" "+apiRoute(c.FullPath())] {
+			abortError(c, apierror.New(http.StatusConflict, daemon.ErrRelayMode.Error()+", set daemon.mode: full to use this").
+				WithCode(apierror.CodeRelayMode).
+				WithDetail("mode", d.Mode()))
+			return
+		}
+		c.Next()
+	}
+}
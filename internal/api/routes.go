@@ -1,9 +1,13 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"os"
 
 	"github.com/gin-gonic/gin"
+	"github.com/silmaril/silmaril/internal/api/apierror"
+	"github.com/silmaril/silmaril/internal/api/apiversion"
 	"github.com/silmaril/silmaril/internal/api/handlers"
 	"github.com/silmaril/silmaril/internal/daemon"
 )
@@ -15,76 +19,234 @@ func SetupRoutes(d *daemon.Daemon) *gin.Engine {
 	router := gin.New()
 	
 	// Add middleware
-	router.Use(gin.Logger())
+	// Log to the current stdout, which the daemon may have redirected into its log buffer
+	router.Use(gin.LoggerWithWriter(os.Stdout))
 	router.Use(gin.Recovery())
 	router.Use(corsMiddleware())
+	router.Use(authMiddleware(d))
+	router.Use(limitMiddleware(d))
+	router.Use(relayMiddleware(d))
 	
 	// Create handlers
 	h := handlers.NewHandlers(d)
 	
-	// API v1 routes
+	// Liveness and readiness probes for container runtimes
+	router.GET("/healthz", h.Healthz)
+	router.GET("/readyz", h.Readyz)
+	
+	// API routes. Routes that didn't change are served under every version,
+	// the old forms of those that did stay in /api/v1 until their sunset.
 	v1 := router.Group("/api/v1")
-	{
-		// Health and status endpoints
-		v1.GET("/health", h.Health)
-		v1.GET("/status", h.Status)
-		
-		// Debug test
-		v1.POST("/test", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{"message": "v1 POST test works"})
-		})
-		
-		// Model endpoints
-		models := v1.Group("/models")
-		{
-			models.GET("", h.ListModels)
-			models.GET("/:name", h.GetModel)
-			models.POST("/download", h.DownloadModel)
-			models.POST("/share", h.ShareModel)
-			models.DELETE("/:name", h.RemoveModel)
-			
-			// Debug endpoint
-			models.POST("/test", func(c *gin.Context) {
-				c.JSON(http.StatusOK, gin.H{"message": "POST test works"})
-			})
-		}
-		
-		// Discovery endpoints
-		v1.GET("/discover", h.DiscoverModels)
-		
-		// Transfer endpoints
-		transfers := v1.Group("/transfers")
-		{
-			transfers.GET("", h.ListTransfers)
-			transfers.GET("/:id", h.GetTransfer)
-			transfers.PUT("/:id/pause", h.PauseTransfer)
-			transfers.PUT("/:id/resume", h.ResumeTransfer)
-			transfers.DELETE("/:id", h.CancelTransfer)
-		}
-		
-		// Admin endpoints
-		admin := v1.Group("/admin")
-		{
-			admin.POST("/shutdown", h.Shutdown)
-		}
-	}
+	registerRoutes(v1, h)
+	v1.GET("/status", deprecated(http.MethodGet, "/api/v1/status"), h.Status)
+	v1.POST("/test", deprecated(http.MethodPost, "/api/v1/test"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "v1 POST test works"})
+	})
+	v1.POST("/models/test", deprecated(http.MethodPost, "/api/v1/models/test"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "POST test works"})
+	})
+	
+	v2 := router.Group("/api/v2")
+	registerRoutes(v2, h)
+	v2.GET("/status", h.StatusV2)
+	
+	// Prometheus scrape endpoint
+	router.GET("/metrics", h.Metrics)
 	
 	// Catch-all for undefined routes
 	router.NoRoute(func(c *gin.Context) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "endpoint not found",
-			"path":  c.Request.URL.Path,
+		c.JSON(http.StatusNotFound, apierror.Envelope{
+			Error: apierror.New(http.StatusNotFound, "endpoint not found").WithDetail("path", c.Request.URL.Path),
 		})
 	})
 	
 	return router
 }
 
+// registerRoutes registers the routes every API version serves
+func registerRoutes(g *gin.RouterGroup, h *handlers.Handlers) {
+	// Health and status endpoints
+	g.GET("/health", h.Health)
+	g.GET("/capabilities", h.Capabilities)
+	g.GET("/logs", h.Logs)
+	g.GET("/events", h.Events)
+	
+	// Runtime configuration
+	g.GET("/config", h.GetConfig)
+	g.PATCH("/config", h.UpdateConfig)
+	
+	// Model endpoints
+	models := g.Group("/models")
+	{
+		models.GET("", h.ListModels)
+		models.GET("/:name", h.GetModel)
+		models.POST("/download", h.DownloadModel)
+		models.POST("/share", h.ShareModel)
+		models.POST("/publish", h.PublishModels)
+		models.POST("/infohash", h.ComputeInfoHash)
+		models.POST("/equivalents", h.DeclareEquivalents)
+		models.POST("/key", h.SetModelKey)
+		models.POST("/rename", h.RenameModel)
+		models.POST("/refresh", h.RefreshModels)
+		models.DELETE("/*name", h.RemoveModel)
+	}
+	
+	// Alternative names of local models
+	aliases := g.Group("/aliases")
+	{
+		aliases.GET("", h.ListAliases)
+		aliases.POST("", h.SetAlias)
+		aliases.DELETE("/*alias", h.RemoveAlias)
+	}
+	
+	// Discovery endpoints
+	g.GET("/discover", h.DiscoverModels)
+	g.GET("/catalog", h.BrowseCatalog)
+	g.GET("/catalog/export", h.ExportCatalog)
+	g.POST("/catalog/import", h.ImportCatalog)
+	g.POST("/discover/watch", h.WatchDiscovery)
+	g.GET("/search", h.SearchHub)
+	g.POST("/unpublish", h.UnpublishModel)
+	
+	// Publisher catalog endpoints
+	g.GET("/publisher", h.GetPublisher)
+	g.POST("/publisher/rotate", h.RotatePublisherKey)
+	subscriptions := g.Group("/subscriptions")
+	{
+		subscriptions.GET("", h.ListSubscriptions)
+		subscriptions.POST("", h.Subscribe)
+		subscriptions.DELETE("/:key", h.Unsubscribe)
+	}
+	modelSubscriptions := g.Group("/model-subscriptions")
+	{
+		modelSubscriptions.GET("", h.ListModelSubscriptions)
+		modelSubscriptions.POST("", h.SubscribeModel)
+		modelSubscriptions.DELETE("/*name", h.UnsubscribeModel)
+	}
+	
+	// Piece availability and connected peers in the swarms of local models
+	g.GET("/swarm", h.SwarmAvailability)
+	g.GET("/torrents/:infohash/peers", h.TorrentPeers)
+	g.POST("/torrents/:infohash/peers", h.AddTorrentPeers)
+	g.DELETE("/torrents/:infohash/peers/:addr", h.RemoveTorrentPeer)
+
+	// Upload bandwidth shares of the torrents
+	g.GET("/uploads", h.UploadSchedule)
+
+	// Peer connections of the torrents against the connection limits
+	g.GET("/connections", h.ConnectionStats)
+
+	// Announces of the model torrents on the DHT by infohash
+	g.GET("/dht/announces", h.DHTAnnounces)
+	
+	// Torrent files of local models, for moving models between networks
+	g.GET("/torrents/export", h.ExportTorrent)
+	g.POST("/torrents/import", h.ImportTorrent)
+	
+	// Details of a local model and the differences between two
+	g.GET("/inspect", h.InspectModel)
+	g.GET("/diff", h.DiffModels)
+	
+	// Peer block and allow lists
+	g.GET("/peer-filter", h.PeerFilter)
+	g.POST("/peer-filter/reload", h.ReloadPeerFilter)
+	
+	// Models refused for download, seeding and discovery
+	blocklist := g.Group("/blocklist")
+	{
+		blocklist.GET("", h.ListBlocklist)
+		blocklist.POST("", h.BlockModel)
+		blocklist.POST("/reload", h.ReloadBlocklist)
+		blocklist.DELETE("/*pattern", h.UnblockModel)
+	}
+	
+	// Seeded data verification
+	g.GET("/scrub", h.ListScrubs)
+	g.POST("/scrub", h.ScrubModel)
+	
+	// Orphaned torrents, downloads and temporary files
+	g.POST("/gc", h.CollectGarbage)
+	
+	// Catalog models seeded for the network
+	g.GET("/pinning", h.Pinning)
+	
+	// Derived models made by the converter
+	g.GET("/convert", h.ListConversions)
+	g.POST("/convert", h.ConvertModel)
+	
+	// Placement of models in storage pools
+	g.POST("/storage/rebalance", h.RebalanceStorage)
+	
+	// Transfer endpoints
+	transfers := g.Group("/transfers")
+	{
+		transfers.GET("", h.ListTransfers)
+		transfers.GET("/:id", h.GetTransfer)
+		transfers.GET("/:id/stats", h.GetTransferStats)
+		transfers.GET("/:id/files", h.GetTransferFiles)
+		transfers.PUT("/:id/pause", h.PauseTransfer)
+		transfers.PUT("/:id/resume", h.ResumeTransfer)
+		transfers.DELETE("/:id", h.CancelTransfer)
+	}
+	g.GET("/history", h.History)
+	g.GET("/stats", h.Stats)
+	g.GET("/network/stats", h.NetworkStats)
+	g.GET("/networks", h.ListNetworks)
+	
+	// Publish jobs, such as cloning and sharing a repository
+	jobs := g.Group("/jobs")
+	{
+		jobs.GET("", h.ListJobs)
+		jobs.GET("/:id", h.GetJob)
+	}
+	
+	// Finished downloads waiting for verification
+	g.GET("/quarantine", h.ListQuarantine)
+	g.POST("/quarantine/release", h.ReleaseQuarantine)
+	
+	// API tokens and their scopes
+	g.GET("/tokens", h.ListTokens)
+	g.POST("/tokens", h.CreateToken)
+	g.DELETE("/tokens/:name", h.RevokeToken)
+	
+	// Admin endpoints
+	admin := g.Group("/admin")
+	{
+		admin.POST("/shutdown", h.Shutdown)
+	}
+}
+
+// deprecated sets the deprecation headers of an endpoint listed in
+// apiversion.Deprecations on its responses
+func deprecated(method, path string) gin.HandlerFunc {
+	d, ok := apiversion.Find(method, path)
+	if !ok {
+		panic(fmt.Sprintf("%s %s is not listed as deprecated", method, path))
+	}
+	return func(c *gin.Context) {
+		d.Headers(c.Writer.Header())
+		c.Next()
+	}
+}
+
+// SetupHFProxyRoutes returns the handler of the HuggingFace Hub proxy. Every
+// path belongs to the hub API, so a single handler dispatches all requests.
+func SetupHFProxyRoutes(d *daemon.Daemon) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.LoggerWithWriter(os.Stdout))
+	router.Use(gin.Recovery())
+	
+	h := handlers.NewHandlers(d)
+	router.NoRoute(h.HFProxy)
+	
+	return router
+}
+
 // corsMiddleware adds CORS headers for local development
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "http://localhost:*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 		
 		if c.Request.Method == "OPTIONS" {
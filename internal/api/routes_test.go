@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/api/apierror"
+	"github.com/silmaril/silmaril/internal/api/apiversion"
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/daemon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestRouter(t *testing.T) http.Handler {
+	return SetupRoutes(newTestDaemon(t))
+}
+
+func newTestDaemon(t *testing.T) *daemon.Daemon {
+	tmpDir := t.TempDir()
+	t.Setenv("SILMARIL_HOME", tmpDir)
+
+	d, err := daemon.New(&config.Config{
+		Storage: config.StorageConfig{BaseDir: tmpDir},
+		Network: config.NetworkConfig{DHTEnabled: false},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { d.Shutdown() })
+	return d
+}
+
+func serve(router http.Handler, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestVersionedStatus(t *testing.T) {
+	router := setupTestRouter(t)
+
+	// v1 keeps the uptime string and announces its successor
+	w := serve(router, http.MethodGet, "/api/v1/status")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "@1792022400", w.Header().Get("Deprecation")) // 2026-10-15
+	assert.Equal(t, "Thu, 01 Jul 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2/status>; rel="successor-version"`, w.Header().Get("Link"))
+	var status map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.IsType(t, "", status["uptime"])
+
+	w = serve(router, http.MethodGet, "/api/v2/status")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	status = nil
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	uptime, ok := status["uptime"].(map[string]interface{})
+	require.True(t, ok, "uptime is an object in v2: %v", status["uptime"])
+	assert.Contains(t, uptime, "started_at")
+	assert.NotContains(t, status, "uptime_info")
+
+	// Unchanged routes are served by both versions without deprecation
+	for _, path := range []string{"/api/v1/models", "/api/v2/models"} {
+		w = serve(router, http.MethodGet, path)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.Empty(t, w.Header().Get("Deprecation"), path)
+	}
+
+	// Debug endpoints are not carried over
+	assert.NotEmpty(t, serve(router, http.MethodPost, "/api/v1/test").Header().Get("Deprecation"))
+	assert.Equal(t, http.StatusNotFound, serve(router, http.MethodPost, "/api/v2/test").Code)
+}
+
+func TestCapabilities(t *testing.T) {
+	router := setupTestRouter(t)
+
+	for _, path := range []string{"/api/v1/capabilities", "/api/v2/capabilities"} {
+		w := serve(router, http.MethodGet, path)
+		require.Equal(t, http.StatusOK, w.Code, path)
+
+		var caps apiversion.Capabilities
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &caps))
+		assert.NotEmpty(t, caps.Version)
+		assert.Equal(t, apiversion.Current, caps.APIVersion)
+		assert.True(t, caps.SupportsAPI(apiversion.V1))
+		assert.True(t, caps.Has(apiversion.FeatureCatalogBrowse))
+		assert.False(t, caps.Has("time_travel"))
+		require.NotEmpty(t, caps.Deprecations)
+		assert.Equal(t, "/api/v1/status", caps.Deprecations[0].Path)
+	}
+}
+
+func TestRelayMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("SILMARIL_HOME", tmpDir)
+	d, err := daemon.New(&config.Config{
+		Storage: config.StorageConfig{BaseDir: tmpDir},
+		Network: config.NetworkConfig{DHTEnabled: false},
+		Daemon:  config.DaemonConfig{Mode: daemon.ModeRelay},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { d.Shutdown() })
+	router := SetupRoutes(d)
+
+	// Relays refuse to download or publish models
+	for _, path := range []string{"/api/v1/models/download", "/api/v2/models/share"} {
+		w := serve(router, http.MethodPost, path)
+		require.Equal(t, http.StatusConflict, w.Code, path)
+		var body apierror.Envelope
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, apierror.CodeRelayMode, body.Error.Code)
+	}
+
+	// but still answer reads and report their mode
+	assert.Equal(t, http.StatusOK, serve(router, http.MethodGet, "/api/v1/models").Code)
+	w := serve(router, http.MethodGet, "/api/v2/status")
+	require.Equal(t, http.StatusOK, w.Code)
+	var status map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, daemon.ModeRelay, status["mode"])
+
+	// Full daemons accept them, failing on the missing body instead
+	assert.Equal(t, http.StatusBadRequest, serve(setupTestRouter(t), http.MethodPost, "/api/v1/models/download").Code)
+}
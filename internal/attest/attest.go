@@ -0,0 +1,243 @@
+// Package attest writes and checks signed attestations of the contents of a
+// model. An attestation is an in-toto Statement naming every file of the
+// model with its SHA256 as a subject, and the model, its torrent and its
+// provenance as the predicate. It is signed with an ed25519 publisher key in
+// a DSSE envelope, which compliance tooling built for in-toto and SLSA
+// attestations reads as is.
+package attest
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// Types of the statement, its predicate and the envelope payload
+const (
+	StatementType = "https://in-toto.io/Statement/v1"
+	PredicateType = "https://silmaril.dev/attestation/model/v1"
+	PayloadType   = "application/vnd.in-toto+json"
+
+	legacyStatementType = "https://in-toto.io/Statement/v0.1" // Still written by some tools
+)
+
+// ErrUnsigned is returned for envelopes without a valid signature
+var ErrUnsigned = errors.New("attestation has no valid signature")
+
+// Statement is an in-toto Statement about the files of a model
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Subject is a file of the model, named by its path in the model directory
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate describes the model the subjects are the files of
+type Predicate struct {
+	Model      string            `json:"model"`
+	Version    string            `json:"version,omitempty"`
+	License    string            `json:"license,omitempty"`
+	InfoHash   string            `json:"info_hash,omitempty"`
+	TotalSize  int64             `json:"total_size"`
+	Files      []File            `json:"files"`
+	Publisher  string            `json:"publisher"` // Hex encoded ed25519 public key
+	Provenance *types.Provenance `json:"provenance,omitempty"`
+	Created    time.Time         `json:"created"` // When the model was published
+	Attested   time.Time         `json:"attested"`
+	Builder    string            `json:"builder"` // The Silmaril version writing the attestation
+}
+
+// File is a file of the model with its size
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Envelope is a DSSE envelope holding a signed statement
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"` // Base64 encoded statement
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a signature of the envelope payload. KeyID is the hex
+// encoded ed25519 public key it was made with.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"` // Base64 encoded
+}
+
+// Build describes the model in modelDir. Checksums come from the manifest,
+// files it has none for, or whose size differs, are hashed.
+func Build(ctx context.Context, modelDir string, manifest *types.ModelManifest, infoHash, publisher, builder string) (*Statement, error) {
+	predicate := Predicate{
+		Model:      manifest.Name,
+		Version:    manifest.Version,
+		License:    manifest.License,
+		InfoHash:   strings.ToLower(infoHash),
+		Publisher:  publisher,
+		Provenance: manifest.Provenance,
+		Created:    manifest.CreatedAt.UTC(),
+		Attested:   time.Now().UTC().Truncate(time.Second),
+		Builder:    builder,
+	}
+	for _, f := range manifest.Files {
+		path := filepath.Join(modelDir, filepath.FromSlash(f.Path))
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Path, err)
+		}
+		file := File{Path: f.Path, Size: stat.Size(), SHA256: f.SHA256}
+		if file.SHA256 == "" || file.Size != f.Size {
+			if file.SHA256, err = models.HashFileRate(ctx, path, 0); err != nil {
+				return nil, fmt.Errorf("failed to hash %s: %w", f.Path, err)
+			}
+		}
+		predicate.Files = append(predicate.Files, file)
+		predicate.TotalSize += file.Size
+	}
+	if len(predicate.Files) == 0 {
+		return nil, fmt.Errorf("manifest of %s lists no files", manifest.Name)
+	}
+	sort.Slice(predicate.Files, func(i, j int) bool { return predicate.Files[i].Path < predicate.Files[j].Path })
+
+	statement := &Statement{
+		Type:          StatementType,
+		PredicateType: PredicateType,
+		Predicate:     predicate,
+	}
+	for _, f := range predicate.Files {
+		statement.Subject = append(statement.Subject, Subject{Name: f.Path, Digest: map[string]string{"sha256": f.SHA256}})
+	}
+	return statement, nil
+}
+
+// Sign signs the statement with privateKey
+func Sign(statement *Statement, privateKey ed25519.PrivateKey) (*Envelope, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal statement: %w", err)
+	}
+	return &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []Signature{{
+			KeyID: hex.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+			Sig:   base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, pae(PayloadType, payload))),
+		}},
+	}, nil
+}
+
+// Open parses an envelope and checks its signatures. It returns the
+// statement and the keys that signed it; whether they are trusted is up to
+// the caller. Signatures made with other kinds of keys are skipped.
+func Open(data []byte) (*Statement, []string, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, nil, fmt.Errorf("invalid attestation: %w", err)
+	}
+	if envelope.PayloadType != PayloadType {
+		return nil, nil, fmt.Errorf("unsupported attestation payload type %q", envelope.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid attestation payload: %w", err)
+	}
+
+	var signers []string
+	for _, s := range envelope.Signatures {
+		publicKey, err := hex.DecodeString(s.KeyID)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err != nil {
+			continue
+		}
+		if ed25519.Verify(publicKey, pae(envelope.PayloadType, payload), sig) {
+			signers = append(signers, strings.ToLower(s.KeyID))
+		}
+	}
+	if len(signers) == 0 {
+		return nil, nil, ErrUnsigned
+	}
+
+	// Statements of other tools have predicates of their own, only their
+	// subjects are read
+	var raw struct {
+		Type          string          `json:"_type"`
+		Subject       []Subject       `json:"subject"`
+		PredicateType string          `json:"predicateType"`
+		Predicate     json.RawMessage `json:"predicate"`
+	}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, nil, fmt.Errorf("invalid attestation statement: %w", err)
+	}
+	if raw.Type != StatementType && raw.Type != legacyStatementType {
+		return nil, nil, fmt.Errorf("unsupported statement type %q", raw.Type)
+	}
+	statement := &Statement{Type: raw.Type, Subject: raw.Subject, PredicateType: raw.PredicateType}
+	if raw.PredicateType == PredicateType {
+		if err := json.Unmarshal(raw.Predicate, &statement.Predicate); err != nil {
+			return nil, nil, fmt.Errorf("invalid attestation predicate: %w", err)
+		}
+	}
+	return statement, signers, nil
+}
+
+// Check hashes the files of modelDir and returns the subjects they don't
+// match, missing files included. Files the statement doesn't name are
+// ignored, and so are subjects without a SHA256 digest, which can't be
+// checked.
+func (s *Statement) Check(ctx context.Context, modelDir string, progress func(file string)) ([]string, error) {
+	var mismatched []string
+	for _, subject := range s.Subject {
+		if subject.Digest["sha256"] == "" {
+			continue
+		}
+		if progress != nil {
+			progress(subject.Name)
+		}
+		if !filepath.IsLocal(filepath.FromSlash(subject.Name)) {
+			return nil, fmt.Errorf("invalid subject %q", subject.Name)
+		}
+		path := filepath.Join(modelDir, filepath.FromSlash(subject.Name))
+		hash, err := models.HashFileRate(ctx, path, 0)
+		if errors.Is(err, os.ErrNotExist) {
+			mismatched = append(mismatched, subject.Name)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", subject.Name, err)
+		}
+		if !strings.EqualFold(hash, subject.Digest["sha256"]) {
+			mismatched = append(mismatched, subject.Name)
+		}
+	}
+	return mismatched, nil
+}
+
+// pae is the DSSE pre-authentication encoding of a payload, the message
+// that is signed
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
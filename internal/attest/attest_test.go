@@ -0,0 +1,98 @@
+package attest
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttestation(t *testing.T) {
+	modelDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "config.json"), []byte(`{"model_type":"llama"}`), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(modelDir, "weights"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "weights", "model.safetensors"), make([]byte, 4096), 0644))
+
+	// Files without a checksum are hashed
+	manifest := &types.ModelManifest{
+		Name:    "org/model",
+		License: "apache-2.0",
+		Files: []types.ModelFile{
+			{Path: "weights/model.safetensors", Size: 4096},
+			{Path: "config.json", Size: 22},
+		},
+		Provenance: &types.Provenance{UpstreamURL: "https://huggingface.co/org/model", UpstreamRevision: "abc"},
+	}
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	publisher := hex.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+
+	statement, err := Build(context.Background(), modelDir, manifest, "ABC123", publisher, "silmaril/dev")
+	require.NoError(t, err)
+	require.Len(t, statement.Subject, 2)
+	assert.Equal(t, "config.json", statement.Subject[0].Name)
+	assert.Len(t, statement.Subject[1].Digest["sha256"], 64)
+	assert.Equal(t, int64(4118), statement.Predicate.TotalSize)
+	assert.Equal(t, "abc123", statement.Predicate.InfoHash)
+
+	envelope, err := Sign(statement, privateKey)
+	require.NoError(t, err)
+	data, err := json.Marshal(envelope)
+	require.NoError(t, err)
+
+	opened, signers, err := Open(data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{publisher}, signers)
+	assert.Equal(t, "org/model", opened.Predicate.Model)
+	assert.Equal(t, "abc", opened.Predicate.Provenance.UpstreamRevision)
+
+	mismatched, err := opened.Check(context.Background(), modelDir, nil)
+	require.NoError(t, err)
+	assert.Empty(t, mismatched)
+
+	// Changed and missing files don't match
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "config.json"), []byte(`{"model_type":"other"}`), 0644))
+	require.NoError(t, os.Remove(filepath.Join(modelDir, "weights", "model.safetensors")))
+	mismatched, err = opened.Check(context.Background(), modelDir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"config.json", "weights/model.safetensors"}, mismatched)
+
+	// A changed statement no longer matches its signature
+	envelope.Payload = base64.StdEncoding.EncodeToString([]byte(`{"_type":"https://in-toto.io/Statement/v1"}`))
+	data, err = json.Marshal(envelope)
+	require.NoError(t, err)
+	_, _, err = Open(data)
+	assert.ErrorIs(t, err, ErrUnsigned)
+}
+
+func TestOpenThirdPartyStatement(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	payload := []byte(`{"_type":"https://in-toto.io/Statement/v0.1","subject":[{"name":"model.gguf","digest":{"sha256":"00ff"}}],"predicateType":"https://slsa.dev/provenance/v1","predicate":{"buildDefinition":{}}}`)
+	envelope := Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []Signature{
+			{KeyID: "sigstore", Sig: "AAAA"}, // Not an ed25519 key, skipped
+			{KeyID: hex.EncodeToString(privateKey.Public().(ed25519.PublicKey)), Sig: base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, pae(PayloadType, payload)))},
+		},
+	}
+	data, err := json.Marshal(envelope)
+	require.NoError(t, err)
+
+	statement, signers, err := Open(data)
+	require.NoError(t, err)
+	assert.Len(t, signers, 1)
+	assert.Equal(t, "https://slsa.dev/provenance/v1", statement.PredicateType)
+	require.Len(t, statement.Subject, 1)
+	assert.Empty(t, statement.Predicate.Model)
+}
@@ -0,0 +1,154 @@
+// Package blocklist decides which models the daemon refuses to download,
+// seed or show in discovery, from entries naming their infohash, their name
+// or a pattern of names.
+package blocklist
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Longest line read from a feed
+const maxLineLength = 64 << 10
+
+// Entry blocks the model with an infohash, or the models whose name matches
+// a pattern such as org/model or org/*
+type Entry struct {
+	Pattern string `json:"pattern"`
+	Reason  string `json:"reason,omitempty"`
+	Source  string `json:"source,omitempty"` // Feed the entry came from, empty for local entries
+}
+
+// ParseEntry checks an infohash or a name pattern and returns it in the
+// form it is matched in, lowercase
+func ParseEntry(pattern string) (string, error) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return "", fmt.Errorf("empty blocklist entry")
+	}
+	if isInfoHash(pattern) {
+		return pattern, nil
+	}
+	if strings.ContainsAny(pattern, " \t") {
+		return "", fmt.Errorf("invalid blocklist entry %q: contains whitespace", pattern)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", fmt.Errorf("invalid blocklist entry %q: %w", pattern, err)
+	}
+	return pattern, nil
+}
+
+// isInfoHash reports whether s is a hex encoded infohash
+func isInfoHash(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// Parse reads a feed: one entry per line, an infohash or a name pattern,
+// optionally followed by the reason. Empty lines and lines starting with #
+// are ignored. It returns the number of lines that didn't parse.
+func Parse(r io.Reader, source string) ([]Entry, int, error) {
+	var entries []Entry
+	skipped := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), maxLineLength)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		pattern, err := ParseEntry(fields[0])
+		if err != nil {
+			skipped++
+			continue
+		}
+		entries = append(entries, Entry{Pattern: pattern, Reason: strings.Join(fields[1:], " "), Source: source})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, skipped, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+	return entries, skipped, nil
+}
+
+// Filter matches models against the entries of every list. Its zero value
+// and nil block nothing.
+type Filter struct {
+	mu       sync.RWMutex
+	exact    map[string]Entry // By infohash or name
+	patterns []Entry          // Names with wildcards
+}
+
+// New creates a filter blocking nothing
+func New() *Filter {
+	return &Filter{}
+}
+
+// SetEntries replaces the entries of the filter. The first entry of a
+// pattern wins, so local entries should come first.
+func (f *Filter) SetEntries(entries []Entry) {
+	exact := make(map[string]Entry, len(entries))
+	var patterns []Entry
+	for _, entry := range entries {
+		if strings.ContainsAny(entry.Pattern, "*?[") {
+			patterns = append(patterns, entry)
+			continue
+		}
+		if _, exists := exact[entry.Pattern]; !exists {
+			exact[entry.Pattern] = entry
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.exact = exact
+	f.patterns = patterns
+}
+
+// Match returns the entry blocking the model with name or infoHash, either
+// may be empty
+func (f *Filter) Match(name, infoHash string) (Entry, bool) {
+	if f == nil {
+		return Entry{}, false
+	}
+	name, infoHash = strings.ToLower(name), strings.ToLower(infoHash)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if infoHash != "" {
+		if entry, exists := f.exact[infoHash]; exists {
+			return entry, true
+		}
+	}
+	if name == "" {
+		return Entry{}, false
+	}
+	if entry, exists := f.exact[name]; exists {
+		return entry, true
+	}
+	for _, entry := range f.patterns {
+		if matched, _ := path.Match(entry.Pattern, name); matched {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Len returns the number of entries
+func (f *Filter) Len() int {
+	if f == nil {
+		return 0
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.exact) + len(f.patterns)
+}
@@ -0,0 +1,74 @@
+package blocklist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEntry(t *testing.T) {
+	hash := strings.Repeat("AB", 20)
+	entry, err := ParseEntry(hash)
+	require.NoError(t, err)
+	assert.Equal(t, strings.ToLower(hash), entry)
+
+	entry, err = ParseEntry(" BadOrg/* ")
+	require.NoError(t, err)
+	assert.Equal(t, "badorg/*", entry)
+
+	for _, invalid := range []string{"", "bad org/model", "org/[model"} {
+		_, err := ParseEntry(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParse(t *testing.T) {
+	hash := strings.Repeat("ab", 20)
+	entries, skipped, err := Parse(strings.NewReader(`# Models reported to the feed
+`+hash+` leaked weights
+
+badorg/*	malware in pickle files
+org/[broken
+`), "https://example.com/models.txt")
+	require.NoError(t, err)
+	assert.Equal(t, 1, skipped)
+	require.Len(t, entries, 2)
+	assert.Equal(t, Entry{Pattern: hash, Reason: "leaked weights", Source: "https://example.com/models.txt"}, entries[0])
+	assert.Equal(t, "badorg/*", entries[1].Pattern)
+	assert.Equal(t, "malware in pickle files", entries[1].Reason)
+}
+
+func TestFilter(t *testing.T) {
+	var none *Filter
+	_, blocked := none.Match("org/model", "")
+	assert.False(t, blocked)
+
+	hash := strings.Repeat("ab", 20)
+	f := New()
+	f.SetEntries([]Entry{
+		{Pattern: "org/model", Reason: "local"},
+		{Pattern: "org/model", Reason: "feed"},
+		{Pattern: hash},
+		{Pattern: "badorg/*"},
+	})
+	assert.Equal(t, 3, f.Len())
+
+	entry, blocked := f.Match("Org/Model", "")
+	assert.True(t, blocked)
+	assert.Equal(t, "local", entry.Reason)
+
+	_, blocked = f.Match("other/model", strings.ToUpper(hash))
+	assert.True(t, blocked)
+
+	_, blocked = f.Match("BadOrg/anything", "")
+	assert.True(t, blocked)
+
+	_, blocked = f.Match("org/model-2", strings.Repeat("cd", 20))
+	assert.False(t, blocked)
+
+	f.SetEntries(nil)
+	_, blocked = f.Match("org/model", hash)
+	assert.False(t, blocked)
+}
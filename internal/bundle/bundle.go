@@ -0,0 +1,360 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// Version of the bundle format
+const Version = 1
+
+// Entries of a bundle, a tar archive. The metadata entries come first in
+// this order, so a bundle is verified before any model file is extracted.
+const (
+	IndexEntry    = "bundle.json"
+	ManifestEntry = "manifest.json"
+	TorrentEntry  = "model.torrent"
+	FilesDir      = "files/"
+)
+
+// Largest metadata entry read into memory
+const maxMetadataSize = 64 << 20
+
+// Index describes a bundle and signs it. The manifest holds the SHA256 of
+// every model file, so the signature covers the files as well.
+type Index struct {
+	Version        int       `json:"version"`
+	Name           string    `json:"name"`
+	InfoHash       string    `json:"info_hash"`
+	Created        time.Time `json:"created"`
+	ManifestSHA256 string    `json:"manifest_sha256"`
+	TorrentSHA256  string    `json:"torrent_sha256"`
+	Publisher      string    `json:"publisher"` // Hex encoded ed25519 public key
+	Signature      string    `json:"signature"`
+}
+
+// Verify checks the signature of the index
+func (idx *Index) Verify() error {
+	publicKey, err := hex.DecodeString(idx.Publisher)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid bundle publisher %q", idx.Publisher)
+	}
+	signature, err := hex.DecodeString(idx.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid bundle signature: %w", err)
+	}
+	if !ed25519.Verify(publicKey, idx.message(), signature) {
+		return fmt.Errorf("bundle signature mismatch")
+	}
+	return nil
+}
+
+func (idx *Index) message() []byte {
+	return []byte(fmt.Sprintf("silmaril-bundle\x00%d\x00%s\x00%s\x00%d\x00%s\x00%s",
+		idx.Version, idx.Name, idx.InfoHash, idx.Created.Unix(), idx.ManifestSHA256, idx.TorrentSHA256))
+}
+
+// Progress reports each model file written or extracted
+type Progress func(file string, size int64)
+
+// Write writes a bundle of the model in modelDir to w, signed with
+// privateKey. The bundle holds the files of the torrent, with a copy of the
+// manifest listing them; files the manifest has no checksum for are hashed
+// first.
+func Write(ctx context.Context, w io.Writer, modelDir string, manifest *types.ModelManifest, torrentData []byte, privateKey ed25519.PrivateKey, progress Progress) (*Index, error) {
+	mi, err := metainfo.Load(bytes.NewReader(torrentData))
+	if err != nil {
+		return nil, fmt.Errorf("invalid torrent file: %w", err)
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return nil, fmt.Errorf("invalid torrent file: %w", err)
+	}
+
+	known := make(map[string]types.ModelFile, len(manifest.Files))
+	for _, f := range manifest.Files {
+		known[f.Path] = f
+	}
+	bundled := *manifest
+	bundled.Files = nil
+	bundled.TotalSize = info.TotalLength()
+	bundled.Seeding = nil
+	bundled.Storage = ""
+	bundled.Signature = "" // Replaced by the signature of the bundle
+	for _, f := range info.UpvertedFiles() {
+		file := types.ModelFile{Path: f.DisplayPath(&info), Size: f.Length}
+		if m, ok := known[file.Path]; ok && m.Size == file.Size && m.SHA256 != "" {
+			file.SHA256 = m.SHA256
+		} else {
+			hash, err := models.HashFileRate(ctx, filepath.Join(modelDir, filepath.FromSlash(file.Path)), 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to hash %s: %w", file.Path, err)
+			}
+			file.SHA256 = hash
+		}
+		bundled.Files = append(bundled.Files, file)
+	}
+
+	manifestData, err := json.MarshalIndent(&bundled, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	index := &Index{
+		Version:        Version,
+		Name:           manifest.Name,
+		InfoHash:       mi.HashInfoBytes().HexString(),
+		Created:        time.Now().UTC().Truncate(time.Second),
+		ManifestSHA256: sha256Hex(manifestData),
+		TorrentSHA256:  sha256Hex(torrentData),
+		Publisher:      hex.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+	}
+	index.Signature = hex.EncodeToString(ed25519.Sign(privateKey, index.message()))
+	indexData, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle index: %w", err)
+	}
+
+	tw := tar.NewWriter(w)
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{IndexEntry, indexData},
+		{ManifestEntry, manifestData},
+		{TorrentEntry, torrentData},
+	} {
+		if err := writeEntry(tw, entry.name, entry.data, index.Created); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range bundled.Files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := writeFile(tw, filepath.Join(modelDir, filepath.FromSlash(file.Path)), file); err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(file.Path, file.Size)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return index, nil
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+func writeFile(tw *tar.Writer, path string, file types.ModelFile) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.Size() != file.Size {
+		return fmt.Errorf("%s is %d bytes, the torrent says %d", file.Path, stat.Size(), file.Size)
+	}
+	header := &tar.Header{Name: FilesDir + file.Path, Mode: 0644, Size: file.Size, ModTime: stat.ModTime()}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("failed to write %s to bundle: %w", file.Path, err)
+	}
+	return nil
+}
+
+// Reader reads a bundle. Open reads and verifies its metadata, Extract its
+// model files.
+type Reader struct {
+	Index    Index
+	Manifest *types.ModelManifest
+	Torrent  []byte
+
+	tr *tar.Reader
+}
+
+// Open reads the metadata of the bundle in r and checks its signature and
+// that the manifest and torrent match it. Whether the publisher is trusted
+// is up to the caller.
+func Open(r io.Reader) (*Reader, error) {
+	br := &Reader{tr: tar.NewReader(r)}
+
+	var entries [3][]byte
+	for i, name := range []string{IndexEntry, ManifestEntry, TorrentEntry} {
+		header, err := br.tr.Next()
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+		if header.Name != name {
+			return nil, fmt.Errorf("invalid bundle: expected %s, found %s", name, header.Name)
+		}
+		if header.Size > maxMetadataSize {
+			return nil, fmt.Errorf("invalid bundle: %s is too large", name)
+		}
+		if entries[i], err = io.ReadAll(br.tr); err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+	}
+	indexData, manifestData, torrentData := entries[0], entries[1], entries[2]
+
+	if err := json.Unmarshal(indexData, &br.Index); err != nil {
+		return nil, fmt.Errorf("invalid bundle index: %w", err)
+	}
+	if br.Index.Version != Version {
+		return nil, fmt.Errorf("unsupported bundle version %d", br.Index.Version)
+	}
+	if err := br.Index.Verify(); err != nil {
+		return nil, err
+	}
+	if sha256Hex(manifestData) != br.Index.ManifestSHA256 {
+		return nil, fmt.Errorf("bundle manifest doesn't match its signature")
+	}
+	if sha256Hex(torrentData) != br.Index.TorrentSHA256 {
+		return nil, fmt.Errorf("bundle torrent doesn't match its signature")
+	}
+
+	if err := json.Unmarshal(manifestData, &br.Manifest); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if br.Manifest.Name != br.Index.Name {
+		return nil, fmt.Errorf("bundle manifest is for %s, not %s", br.Manifest.Name, br.Index.Name)
+	}
+	mi, err := metainfo.Load(bytes.NewReader(torrentData))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle torrent: %w", err)
+	}
+	if infoHash := mi.HashInfoBytes().HexString(); infoHash != br.Index.InfoHash {
+		return nil, fmt.Errorf("bundle torrent has infohash %s, not %s", infoHash, br.Index.InfoHash)
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle torrent: %w", err)
+	}
+
+	// Every file of the torrent needs a checksum to be verified against
+	files := make(map[string]types.ModelFile, len(br.Manifest.Files))
+	for _, f := range br.Manifest.Files {
+		files[f.Path] = f
+	}
+	for _, f := range info.UpvertedFiles() {
+		path := f.DisplayPath(&info)
+		if m, ok := files[path]; !ok || m.Size != f.Length || m.SHA256 == "" {
+			return nil, fmt.Errorf("bundle manifest doesn't describe %s", path)
+		}
+	}
+	if len(files) != len(info.UpvertedFiles()) {
+		return nil, fmt.Errorf("bundle manifest and torrent list different files")
+	}
+
+	br.Torrent = torrentData
+	return br, nil
+}
+
+// Extract writes the model files of the bundle to modelDir, checking each
+// against its checksum in the manifest. A file that doesn't match is
+// removed and fails the extraction.
+func (br *Reader) Extract(ctx context.Context, modelDir string, progress Progress) error {
+	want := make(map[string]types.ModelFile, len(br.Manifest.Files))
+	for _, f := range br.Manifest.Files {
+		want[f.Path] = f
+	}
+	root := filepath.Clean(modelDir) + string(filepath.Separator)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := br.tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		path := strings.TrimPrefix(header.Name, FilesDir)
+		file, ok := want[path]
+		if !ok || path == header.Name || header.Typeflag != tar.TypeReg {
+			return fmt.Errorf("unexpected bundle entry %s", header.Name)
+		}
+		delete(want, path)
+		if header.Size != file.Size {
+			return fmt.Errorf("%s is %d bytes, the manifest says %d", path, header.Size, file.Size)
+		}
+
+		target := filepath.Join(modelDir, filepath.FromSlash(path))
+		if !strings.HasPrefix(target, root) {
+			return fmt.Errorf("bundle entry %s is outside the model directory", header.Name)
+		}
+		if err := extractFile(br.tr, target, file); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(path, file.Size)
+		}
+	}
+
+	for path := range want {
+		return fmt.Errorf("bundle is missing %s", path)
+	}
+	return nil
+}
+
+func extractFile(r io.Reader, target string, file types.ModelFile) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, hasher), r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && hex.EncodeToString(hasher.Sum(nil)) != file.SHA256 {
+		err = fmt.Errorf("checksum mismatch")
+	}
+	if err != nil {
+		os.Remove(target)
+		return fmt.Errorf("failed to extract %s: %w", file.Path, err)
+	}
+	return nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,96 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/silmaril/silmaril/internal/torrent"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestBundle(t *testing.T) []byte {
+	t.Helper()
+	modelDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "config.json"), []byte(`{"model_type":"llama"}`), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(modelDir, "weights"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "weights", "model.safetensors"), bytes.Repeat([]byte{7}, 4096), 0644))
+
+	info, err := torrent.BuildInfo(modelDir, 0, nil)
+	require.NoError(t, err)
+	infoBytes, err := bencode.Marshal(info)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, (&metainfo.MetaInfo{InfoBytes: infoBytes}).Write(&buf))
+
+	// Files without a checksum are hashed when writing
+	manifest := &types.ModelManifest{
+		Name:  "org/model",
+		Files: []types.ModelFile{{Path: "config.json", Size: 22}},
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	var out bytes.Buffer
+	var written []string
+	index, err := Write(context.Background(), &out, modelDir, manifest, buf.Bytes(), privateKey, func(file string, size int64) {
+		written = append(written, file)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", index.Name)
+	assert.ElementsMatch(t, []string{"config.json", "weights/model.safetensors"}, written)
+	return out.Bytes()
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	data := writeTestBundle(t)
+
+	br, err := Open(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", br.Manifest.Name)
+	assert.Len(t, br.Manifest.Files, 2)
+	for _, f := range br.Manifest.Files {
+		assert.Len(t, f.SHA256, 64, f.Path)
+	}
+
+	target := filepath.Join(t.TempDir(), "org", "model")
+	require.NoError(t, br.Extract(context.Background(), target, nil))
+	weights, err := os.ReadFile(filepath.Join(target, "weights", "model.safetensors"))
+	require.NoError(t, err)
+	assert.Equal(t, bytes.Repeat([]byte{7}, 4096), weights)
+}
+
+func TestBundleTampered(t *testing.T) {
+	data := writeTestBundle(t)
+
+	// A changed model file fails its checksum and isn't kept
+	tampered := bytes.Replace(data, bytes.Repeat([]byte{7}, 4096), bytes.Repeat([]byte{8}, 4096), 1)
+	br, err := Open(bytes.NewReader(tampered))
+	require.NoError(t, err)
+	target := t.TempDir()
+	err = br.Extract(context.Background(), target, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+	assert.NoFileExists(t, filepath.Join(target, "weights", "model.safetensors"))
+
+	// A changed index fails its signature
+	tampered = bytes.Replace(data, []byte(`"name": "org/model"`), []byte(`"name": "org/evil1"`), 1)
+	_, err = Open(bytes.NewReader(tampered))
+	assert.ErrorContains(t, err, "signature mismatch")
+
+	// A changed manifest no longer matches the signed index
+	tampered = bytes.Replace(data, []byte(`"config.json"`), []byte(`"config.jsox"`), 1)
+	_, err = Open(bytes.NewReader(tampered))
+	assert.ErrorContains(t, err, "manifest doesn't match")
+
+	_, err = Open(bytes.NewReader([]byte("not a bundle")))
+	assert.Error(t, err)
+}
@@ -5,10 +5,14 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
+// DefaultDaemonPort is the port the daemon serves its API on by default
+const DefaultDaemonPort = 8737
+
 // Config represents the Silmaril configuration
 type Config struct {
 	// Storage paths
@@ -17,6 +21,9 @@ type Config struct {
 	// Network settings
 	Network NetworkConfig `mapstructure:"network"`
 
+	// Catalog networks joined besides the public one, by name
+	Catalogs map[string]CatalogConfig `mapstructure:"catalogs"`
+
 	// Daemon settings
 	Daemon DaemonConfig `mapstructure:"daemon"`
 
@@ -25,6 +32,30 @@ type Config struct {
 
 	// Security settings
 	Security SecurityConfig `mapstructure:"security"`
+
+	// HuggingFace Hub proxy settings
+	HFProxy HFProxyConfig `mapstructure:"hf_proxy"`
+
+	// Proxy for outbound traffic
+	Proxy ProxyConfig `mapstructure:"proxy"`
+
+	// Seeding catalog models few peers seed
+	Pinning PinningConfig `mapstructure:"pinning"`
+
+	// Endpoints the daemon posts events to, by name
+	Webhooks map[string]WebhookConfig `mapstructure:"webhooks"`
+
+	// Scripts run when models are ready
+	Hooks HooksConfig `mapstructure:"hooks"`
+
+	// Conversion of downloaded models, such as to quantized GGUF
+	Convert ConvertConfig `mapstructure:"convert"`
+
+	// Named profiles with separate storage and daemon
+	Profiles map[string]ProfileConfig `mapstructure:"profiles"`
+
+	// Name of the active profile, empty when no profile is selected
+	Profile string `mapstructure:"-"`
 }
 
 type StorageConfig struct {
@@ -33,6 +64,38 @@ type StorageConfig struct {
 	TorrentsDir string `mapstructure:"torrents_dir"`
 	RegistryDir string `mapstructure:"registry_dir"`
 	DBDir       string `mapstructure:"db_dir"`
+
+	// Hours between re-verifications of seeded data, 0 disables scrubbing
+	ScrubIntervalHours int `mapstructure:"scrub_interval_hours"`
+
+	// Publish models put into the models directory by hand
+	AutoShareNewModels bool `mapstructure:"auto_share_new_models"`
+
+	// MB per second read to fill in missing file checksums, 0 disables it
+	HashRateMB int `mapstructure:"hash_rate_mb"`
+
+	// Days finished transfers are kept in the history, 0 keeps them forever
+	HistoryRetentionDays int `mapstructure:"history_retention_days"`
+
+	// Hours between removals of orphaned torrents, downloads and temp files,
+	// 0 disables them
+	GCIntervalHours int `mapstructure:"gc_interval_hours"`
+
+	// Directories models are placed in by size, besides the models directory
+	Pools map[string]PoolConfig `mapstructure:"pools"`
+
+	// Directories API clients may download models below with an output
+	// directory, none if empty
+	OutputDirs []string `mapstructure:"output_dirs"`
+}
+
+// PoolConfig is a directory models are kept in, for example on a fast or a
+// big disk. Downloads go to the pool whose size limits take the model, and
+// are linked into the models directory. Limits of 0 don't limit.
+type PoolConfig struct {
+	Path           string `mapstructure:"path"`
+	MinModelSizeGB int    `mapstructure:"min_model_size_gb"`
+	MaxModelSizeGB int    `mapstructure:"max_model_size_gb"`
 }
 
 type NetworkConfig struct {
@@ -40,22 +103,63 @@ type NetworkConfig struct {
 	DHTEnabled        bool     `mapstructure:"dht_enabled"`
 	DHTBootstrapNodes []string `mapstructure:"dht_bootstrap_nodes"`
 	DHTPort           int      `mapstructure:"dht_port"`
+	// How often seeded models are announced by infohash, 0 = never
+	DHTAnnounceIntervalMinutes int `mapstructure:"dht_announce_interval_minutes"`
 
 	// Torrent network settings
 	ListenPort        int   `mapstructure:"listen_port"`
+	// Addresses to listen on, e.g. "0.0.0.0:42069" and "[::]:42069". A family
+	// without an address is disabled, all share one port.
+	ListenAddrs       []string `mapstructure:"listen_addrs"`
+	// dual, prefer_ipv4, prefer_ipv6, ipv4_only or ipv6_only
+	IPPreference      string   `mapstructure:"ip_preference"`
 	MaxConnections    int   `mapstructure:"max_connections"`
+	// Established connections of each torrent, and connection attempts in
+	// progress in total and per torrent
+	MaxConnectionsPerTorrent int `mapstructure:"max_connections_per_torrent"`
+	MaxHalfOpen              int `mapstructure:"max_half_open"`
+	MaxHalfOpenPerTorrent    int `mapstructure:"max_half_open_per_torrent"`
 	UploadRateLimit   int64 `mapstructure:"upload_rate_limit"`
 	DownloadRateLimit int64 `mapstructure:"download_rate_limit"`
+	// Divide the upload rate limit between torrents by their seed priority
+	UploadFairness    bool  `mapstructure:"upload_fairness"`
 
 	// Tracker/peer settings
 	DisableTrackers   bool `mapstructure:"disable_trackers"`
 	DisableWebTorrent bool `mapstructure:"disable_webtorrent"`
 	DisablePEX        bool `mapstructure:"disable_pex"`
+
+	// Peer addresses, CIDR prefixes or ranges to refuse, and if an
+	// allowlist is set the only ones to accept
+	PeerBlocklist []string `mapstructure:"peer_blocklist"`
+	PeerAllowlist []string `mapstructure:"peer_allowlist"`
+	// URL or path of a blocklist in the P2P format, optionally gzipped,
+	// fetched again after PeerBlocklistRefreshHours
+	PeerBlocklistURL          string `mapstructure:"peer_blocklist_url"`
+	PeerBlocklistRefreshHours int    `mapstructure:"peer_blocklist_refresh_hours"`
+
+	// Addresses or DNS names of other daemons, such as a Kubernetes headless
+	// service, added as peers of every torrent. "host" uses our listen port,
+	// "host:port" another one.
+	PeerHints []string `mapstructure:"peer_hints"`
+	
+	// Publish the number of models seeded and the bytes uploaded to the
+	// anonymous daily network stats
+	ShareStats bool `mapstructure:"share_stats"`
 	
 	// Catalog refresh interval in minutes
 	CatalogRefreshIntervalMinutes int `mapstructure:"catalog_refresh_interval_minutes"`
 }
 
+// CatalogConfig is a catalog network, with models apart from the public
+// catalog. A network has a seed or a public key: every node knowing the seed
+// writes to the network's catalog like to the public one and publishes its
+// models there, a catalog under a public key is only read.
+type CatalogConfig struct {
+	Seed      string `mapstructure:"seed"`
+	PublicKey string `mapstructure:"public_key"`
+}
+
 type DaemonConfig struct {
 	// REST API bind address
 	BindAddress string `mapstructure:"bind_address"`
@@ -65,24 +169,232 @@ type DaemonConfig struct {
 	
 	// Auto-start daemon if not running
 	AutoStart bool `mapstructure:"auto_start"`
+
+	// "full" stores, downloads and seeds models, "relay" only seeds catalogs
+	// and republishes DHT references to keep discovery alive
+	Mode string `mapstructure:"mode"`
+
+	// Log level of daemon output, "debug" or "info"
+	LogLevel string `mapstructure:"log_level"`
+
+	// Seconds a shutdown may take to leave swarms and save state before
+	// the daemon exits anyway
+	ShutdownGraceSeconds int `mapstructure:"shutdown_grace_seconds"`
+
+	// Requests per second each API client, a token or an address, may send
+	// and how many at once, 0 for no limit
+	APIRateLimit int `mapstructure:"api_rate_limit"`
+	APIRateBurst int `mapstructure:"api_rate_burst"`
+
+	// Requests the API handles at the same time, 0 for no limit
+	APIMaxConcurrent int `mapstructure:"api_max_concurrent"`
+
+	// Size of the bodies of publish and share requests in KB, 0 for no limit
+	APIMaxBodyKB int `mapstructure:"api_max_body_kb"`
+
+	// Local endpoint the API is served on besides the port: a Unix domain
+	// socket such as ~/.silmaril/daemon.sock, or a named pipe such as
+	// \\.\pipe\silmaril on Windows. The CLI talks to the daemon through it
+	// when it is there.
+	Socket string `mapstructure:"socket"`
+
+	// Serve the API only on the socket, not on the port
+	SocketOnly bool `mapstructure:"socket_only"`
+}
+
+// HFProxyConfig configures the HuggingFace Hub pull-through proxy. HF
+// clients pointed at it with HF_ENDPOINT get files from the local store or
+// peers, and from the upstream hub only when Silmaril has no copy.
+type HFProxyConfig struct {
+	// Serve the proxy while the daemon runs
+	Enabled bool `mapstructure:"enabled"`
+
+	// Address the proxy listens on. It is the loopback interface by
+	// default, the proxy has no API tokens and makes the daemon download
+	// models for whoever asks.
+	BindAddress string `mapstructure:"bind_address"`
+
+	// Port of the proxy
+	Port int `mapstructure:"port"`
+
+	// Hub to fall back to
+	Upstream string `mapstructure:"upstream"`
+
+	// Seconds to wait for peers before falling back to the upstream hub
+	P2PTimeoutSeconds int `mapstructure:"p2p_timeout_seconds"`
+}
+
+// PinningConfig lets the daemon download and seed catalog models that too
+// few peers seed, so they stay available to the network ("community
+// pinning"). Pinned models are seeded without seeding limits.
+type PinningConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// Models with fewer seeders are pinned
+	MinSeeders int `mapstructure:"min_seeders"`
+
+	// Disk space all pinned models may take together, and the largest model
+	// pinned, in GB. A max model size of 0 doesn't limit.
+	MaxDiskGB      int `mapstructure:"max_disk_gb"`
+	MaxModelSizeGB int `mapstructure:"max_model_size_gb"`
+
+	// Pinned models downloaded at the same time
+	MaxDownloads int `mapstructure:"max_downloads"`
+
+	// Patterns of the model names to pin, all models if empty
+	Models []string `mapstructure:"models"`
+
+	// Minutes between checks of the catalog
+	IntervalMinutes int `mapstructure:"interval_minutes"`
+}
+
+// WebhookConfig is an endpoint the daemon POSTs events to as JSON, such as
+// a Slack incoming webhook or a CI trigger. Requests go through proxy.url.
+type WebhookConfig struct {
+	URL string `mapstructure:"url"`
+
+	// Headers sent with every request, values may reference environment
+	// variables like ${CI_TOKEN}
+	Headers map[string]string `mapstructure:"headers"`
+
+	// Patterns of the event types to send, like "publish.*". Downloads
+	// completed, failed verifications, publishes and catalog updates if empty.
+	Events []string `mapstructure:"events"`
+}
+
+// HooksConfig names scripts the daemon runs when a model is ready, for
+// example to convert or quantize it or to load it into an inference server.
+// Scripts get the model in SILMARIL_* environment variables and only the
+// variables of the daemon listed in Env besides PATH, HOME and the locale.
+type HooksConfig struct {
+	// Run when a downloaded model is in the models directory, after it was
+	// decrypted or an update was installed
+	OnDownloadComplete string `mapstructure:"on_download_complete"`
+
+	// Seconds a hook may run before it is killed
+	TimeoutSeconds int `mapstructure:"timeout_seconds"`
+
+	// Environment variables of the daemon passed on to hooks
+	Env []string `mapstructure:"env"`
+}
+
+// ConvertConfig runs a converter on downloaded safetensors models to make
+// derived models, such as GGUF quantizations with llama.cpp. The converter
+// is run once per variant with the model directory, an output directory and
+// the variant, in the environment hooks get.
+type ConvertConfig struct {
+	// Convert models when their download is ready, conversions can always
+	// be started with 'silmaril convert'
+	Enabled bool `mapstructure:"enabled"`
+
+	// Path of the converter
+	Command string `mapstructure:"command"`
+
+	// Variants made of each model, like quantization types
+	Variants []string `mapstructure:"variants"`
+
+	// Patterns of the model names to convert, all models if empty
+	Models []string `mapstructure:"models"`
+
+	// Publish derived models to the network
+	Publish bool `mapstructure:"publish"`
+
+	// Minutes a conversion may run before it is killed
+	TimeoutMinutes int `mapstructure:"timeout_minutes"`
+}
+
+// ProxyConfig routes outbound traffic through a SOCKS5 or HTTP proxy.
+// Components use URL unless they set their own proxy, "direct" connects a
+// component without a proxy.
+type ProxyConfig struct {
+	URL string `mapstructure:"url"`
+
+	// Hosts, domains and CIDR ranges that are reached directly
+	NoProxy []string `mapstructure:"no_proxy"`
+
+	Torrent string `mapstructure:"torrent"` // Trackers, web seeds and outgoing peer connections
+	DHT     string `mapstructure:"dht"`     // Resolution of DHT bootstrap nodes
+	Git     string `mapstructure:"git"`     // Git and HuggingFace mirroring
+	API     string `mapstructure:"api"`     // CLI requests to the daemon
+}
+
+// ProfileConfig overrides storage and daemon settings for a named profile.
+// Each profile has its own base directory, so its models, catalog and daemon
+// state are kept apart from other profiles.
+type ProfileConfig struct {
+	BaseDir string `mapstructure:"base_dir"`
+
+	// REST API port of the profile's daemon, profiles that run side by side
+	// need different ports
+	DaemonPort int `mapstructure:"daemon_port"`
 }
 
 type TorrentConfig struct {
 	PieceLength     int64   `mapstructure:"piece_length"`
-	SeedRatio       float64 `mapstructure:"seed_ratio"`
-	SeedTime        int     `mapstructure:"seed_time"`
 	DownloadTimeout int     `mapstructure:"download_timeout"`
+
+	// Seeding policy, per-model policies in the manifest take precedence.
+	// Seeding of a model stops once any limit is reached, 0 means unlimited.
+	SeedRatio        float64 `mapstructure:"seed_ratio"`
+	SeedTime         int     `mapstructure:"seed_time"`            // Seconds
+	StopIfNoPeersFor int     `mapstructure:"stop_if_no_peers_for"` // Seconds
+
+	// Focus uploads on fewer peers while we are the only seeder of a model
+	InitialSeeding bool `mapstructure:"initial_seeding"`
+
+	// Download pieces that are rare in a swarm first and keep seeding models
+	// holding pieces no peer has
+	SwarmCoordinator bool `mapstructure:"swarm_coordinator"`
+
+	// Backend storing the data of torrents: file, mmap or cached. A model
+	// can set its own in its manifest.
+	StorageBackend string `mapstructure:"storage_backend"`
+
+	// Hash every piece of the restored torrents again on start instead of
+	// trusting the pieces verified before, see 'daemon start --recheck'
+	RecheckOnStart bool `mapstructure:"recheck_on_start"`
+
+	// Fetch downloads from the HTTP mirrors in their manifest too, and from
+	// these IPFS gateways when the manifest has the CID of every file
+	WebSeeds     bool     `mapstructure:"web_seeds"`
+	IPFSGateways []string `mapstructure:"ipfs_gateways"`
+
+	// Check downloaded files against the checksums in their manifest before
+	// hooks run
+	VerifyDownloads bool `mapstructure:"verify_downloads"`
 }
 
 type SecurityConfig struct {
 	SignManifests   bool   `mapstructure:"sign_manifests"`
 	VerifyManifests bool   `mapstructure:"verify_manifests"`
 	KeysDir         string `mapstructure:"keys_dir"`
+
+	// Policy ID of each organization. Manifests of the organization must be
+	// signed under that policy.
+	Organizations map[string]string `mapstructure:"organizations"`
+
+	// Domain proving we own each namespace we publish models in, the
+	// organization part of model names. The domain lists our publisher key
+	// in a DNS TXT record or at /.well-known/silmaril.
+	Namespaces map[string]string `mapstructure:"namespaces"`
+
+	// Domain trusted to name the publishers of each namespace of others.
+	// Catalog entries naming another domain for a namespace are never
+	// verified, whatever that domain lists.
+	TrustedNamespaces map[string]string `mapstructure:"trusted_namespaces"`
+
+	// URLs or paths of feeds listing models to refuse, fetched again after
+	// ModelBlocklistRefreshHours
+	ModelBlocklistURLs         []string `mapstructure:"model_blocklist_urls"`
+	ModelBlocklistRefreshHours int      `mapstructure:"model_blocklist_refresh_hours"`
 }
 
 var (
 	cfg *Config
 	v   *viper.Viper
+
+	// Set once SILMARIL_HOME was exported from the config file
+	homeFromFile bool
 )
 
 // Helper methods for accessing config values
@@ -90,6 +402,8 @@ var (
 // GetInt returns an integer value from the config
 func (c *Config) GetInt(key string) int {
 	if v != nil {
+		runtimeMu.RLock()
+		defer runtimeMu.RUnlock()
 		return v.GetInt(key)
 	}
 	return 0
@@ -98,14 +412,28 @@ func (c *Config) GetInt(key string) int {
 // GetBool returns a boolean value from the config
 func (c *Config) GetBool(key string) bool {
 	if v != nil {
+		runtimeMu.RLock()
+		defer runtimeMu.RUnlock()
 		return v.GetBool(key)
 	}
 	return false
 }
 
+// GetFloat64 returns a float value from the config
+func (c *Config) GetFloat64(key string) float64 {
+	if v != nil {
+		runtimeMu.RLock()
+		defer runtimeMu.RUnlock()
+		return v.GetFloat64(key)
+	}
+	return 0
+}
+
 // GetString returns a string value from the config
 func (c *Config) GetString(key string) string {
 	if v != nil {
+		runtimeMu.RLock()
+		defer runtimeMu.RUnlock()
 		return v.GetString(key)
 	}
 	return ""
@@ -114,6 +442,8 @@ func (c *Config) GetString(key string) string {
 // GetStringSlice returns a string slice from the config
 func (c *Config) GetStringSlice(key string) []string {
 	if v != nil {
+		runtimeMu.RLock()
+		defer runtimeMu.RUnlock()
 		return v.GetStringSlice(key)
 	}
 	return nil
@@ -156,15 +486,101 @@ func Initialize() error {
 		// Config file not found is ok, we'll use defaults
 	}
 
-	// Unmarshal into struct
-	cfg = &Config{}
-	if err := v.Unmarshal(cfg); err != nil {
+	if err := load(); err != nil {
+		return err
+	}
+	return exportBaseDir()
+}
+
+// Reload re-reads the configuration from the viper instance, for example
+// after a different config file has been read into it
+func Reload() error {
+	if v == nil {
+		return fmt.Errorf("config not initialized")
+	}
+	if err := load(); err != nil {
+		return err
+	}
+	return exportBaseDir()
+}
+
+// exportBaseDir exports the base directory set in the config file as
+// SILMARIL_HOME, so every part of Silmaril keeps its data there. A
+// SILMARIL_HOME set by the user takes precedence.
+func exportBaseDir() error {
+	if os.Getenv("SILMARIL_HOME") != "" && !homeFromFile {
+		return nil
+	}
+	if !v.InConfig("storage.base_dir") || cfg.Storage.BaseDir == "" {
+		return nil
+	}
+	if err := os.Setenv("SILMARIL_HOME", cfg.Storage.BaseDir); err != nil {
+		return fmt.Errorf("failed to set SILMARIL_HOME: %w", err)
+	}
+	homeFromFile = true
+	return nil
+}
+
+// HomeFromEnv reports whether the base directory comes from a SILMARIL_HOME
+// set by the user rather than from the config file or a profile
+func HomeFromEnv() bool {
+	return os.Getenv("SILMARIL_HOME") != "" && !homeFromFile && (cfg == nil || cfg.Profile == "")
+}
+
+// load unmarshals the viper settings into cfg
+func load() error {
+	c := &Config{}
+	if err := v.Unmarshal(c); err != nil {
 		return fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
 	// Expand paths
-	expandPaths(cfg)
+	expandPaths(c)
+
+	// Keep the active profile across reloads
+	if cfg != nil {
+		c.Profile = cfg.Profile
+	}
+	cfg = c
+	return nil
+}
+
+// UseProfile switches the configuration to the named profile. The profile's
+// base directory is also exported as SILMARIL_HOME, so every part of Silmaril
+// and any process started from this one uses the profile's storage.
+func UseProfile(name string) error {
+	if v == nil {
+		return fmt.Errorf("config not initialized")
+	}
+
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	if profile.BaseDir == "" {
+		return fmt.Errorf("profile %q has no base_dir", name)
+	}
+
+	// Derive all storage directories from the profile's base directory
+	baseDir := ExpandPath(profile.BaseDir)
+	v.Set("storage.base_dir", baseDir)
+	overrides["storage.base_dir"] = SourceProfile
+	for _, key := range []string{"storage.models_dir", "storage.torrents_dir", "storage.registry_dir", "storage.db_dir", "security.keys_dir"} {
+		v.Set(key, "")
+		overrides[key] = SourceProfile
+	}
+	if profile.DaemonPort != 0 {
+		v.Set("daemon.port", profile.DaemonPort)
+		overrides["daemon.port"] = SourceProfile
+	}
+	if err := load(); err != nil {
+		return err
+	}
+	cfg.Profile = name
 
+	if err := os.Setenv("SILMARIL_HOME", baseDir); err != nil {
+		return fmt.Errorf("failed to set SILMARIL_HOME: %w", err)
+	}
 	return nil
 }
 
@@ -176,6 +592,11 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("storage.torrents_dir", "") // Will be set to base_dir/torrents
 	v.SetDefault("storage.registry_dir", "") // Will be set to base_dir/registry
 	v.SetDefault("storage.db_dir", "")       // Will be set to base_dir/db
+	v.SetDefault("storage.scrub_interval_hours", 168) // Weekly
+	v.SetDefault("storage.auto_share_new_models", false)
+	v.SetDefault("storage.hash_rate_mb", 50)
+	v.SetDefault("storage.history_retention_days", 90)
+	v.SetDefault("storage.gc_interval_hours", 24)
 
 	// Network defaults
 	v.SetDefault("network.dht_enabled", true)
@@ -186,29 +607,98 @@ func setDefaults(v *viper.Viper) {
 	})
 	v.SetDefault("network.dht_port", 0)    // Random port
 	v.SetDefault("network.listen_port", 0) // Random port
+	v.SetDefault("network.dht_announce_interval_minutes", 15)
+	v.SetDefault("network.listen_addrs", []string{}) // All interfaces of both families
+	v.SetDefault("network.ip_preference", "dual")
 	v.SetDefault("network.max_connections", 100)
+	v.SetDefault("network.max_connections_per_torrent", 50)
+	v.SetDefault("network.max_half_open", 100)
+	v.SetDefault("network.max_half_open_per_torrent", 25)
 	v.SetDefault("network.upload_rate_limit", 0)   // Unlimited
 	v.SetDefault("network.download_rate_limit", 0) // Unlimited
+	v.SetDefault("network.upload_fairness", true)
 	v.SetDefault("network.disable_trackers", true)
 	v.SetDefault("network.disable_webtorrent", true)
 	v.SetDefault("network.disable_pex", false)
+	v.SetDefault("network.peer_blocklist", []string{})
+	v.SetDefault("network.peer_allowlist", []string{}) // Allow all peers
+	v.SetDefault("network.peer_blocklist_url", "")
+	v.SetDefault("network.peer_blocklist_refresh_hours", 24)
+	v.SetDefault("network.peer_hints", []string{})
+	v.SetDefault("network.share_stats", false)
 	v.SetDefault("network.catalog_refresh_interval_minutes", 30)
 	
 	// Daemon defaults
 	v.SetDefault("daemon.bind_address", "0.0.0.0")
-	v.SetDefault("daemon.port", 8737)
+	v.SetDefault("daemon.port", DefaultDaemonPort)
 	v.SetDefault("daemon.auto_start", true)
+	v.SetDefault("daemon.mode", "full")
+	v.SetDefault("daemon.log_level", "debug")
+	v.SetDefault("daemon.shutdown_grace_seconds", 30)
+	v.SetDefault("daemon.api_rate_limit", 20)
+	v.SetDefault("daemon.api_rate_burst", 50)
+	v.SetDefault("daemon.api_max_concurrent", 64)
+	v.SetDefault("daemon.api_max_body_kb", 1024)
+	v.SetDefault("daemon.socket", "")
+	v.SetDefault("daemon.socket_only", false)
+
+	// Hook defaults
+	v.SetDefault("hooks.on_download_complete", "")
+	v.SetDefault("hooks.timeout_seconds", 1800)
+	v.SetDefault("hooks.env", []string{})
+
+	// Conversion defaults
+	v.SetDefault("convert.enabled", false)
+	v.SetDefault("convert.command", "")
+	v.SetDefault("convert.variants", []string{"Q4_K_M"})
+	v.SetDefault("convert.models", []string{})
+	v.SetDefault("convert.publish", false)
+	v.SetDefault("convert.timeout_minutes", 120)
+
+	// HuggingFace proxy defaults
+	v.SetDefault("hf_proxy.enabled", false)
+	v.SetDefault("hf_proxy.bind_address", "127.0.0.1")
+	v.SetDefault("hf_proxy.port", 8747)
+	v.SetDefault("hf_proxy.upstream", "https://huggingface.co")
+	v.SetDefault("hf_proxy.p2p_timeout_seconds", 30)
+
+	// Community pinning defaults
+	v.SetDefault("pinning.enabled", false)
+	v.SetDefault("pinning.min_seeders", 3)
+	v.SetDefault("pinning.max_disk_gb", 100)
+	v.SetDefault("pinning.max_model_size_gb", 0)
+	v.SetDefault("pinning.max_downloads", 1)
+	v.SetDefault("pinning.models", []string{})
+	v.SetDefault("pinning.interval_minutes", 60)
+
+	// Outbound proxy defaults
+	v.SetDefault("proxy.url", "")
+	v.SetDefault("proxy.no_proxy", []string{"localhost", "127.0.0.1", "::1"})
+	v.SetDefault("proxy.torrent", "")
+	v.SetDefault("proxy.dht", "")
+	v.SetDefault("proxy.git", "")
+	v.SetDefault("proxy.api", "")
 
 	// Torrent defaults
 	v.SetDefault("torrent.piece_length", 4*1024*1024) // 4MB
 	v.SetDefault("torrent.seed_ratio", 0)             // Unlimited
 	v.SetDefault("torrent.seed_time", 0)              // Unlimited
+	v.SetDefault("torrent.stop_if_no_peers_for", 0)   // Unlimited
+	v.SetDefault("torrent.initial_seeding", true)
+	v.SetDefault("torrent.swarm_coordinator", false)
 	v.SetDefault("torrent.download_timeout", 0)       // Unlimited
+	v.SetDefault("torrent.storage_backend", "file")
+	v.SetDefault("torrent.recheck_on_start", false)
+	v.SetDefault("torrent.web_seeds", true)
+	v.SetDefault("torrent.ipfs_gateways", []string{})
+	v.SetDefault("torrent.verify_downloads", true)
 
 	// Security defaults
 	v.SetDefault("security.sign_manifests", true)
 	v.SetDefault("security.verify_manifests", true)
 	v.SetDefault("security.keys_dir", "") // Will be set to base_dir/keys
+	v.SetDefault("security.model_blocklist_urls", []string{})
+	v.SetDefault("security.model_blocklist_refresh_hours", 24)
 }
 
 // getDefaultBaseDir returns the default base directory
@@ -225,6 +715,13 @@ func getDefaultBaseDir() string {
 	return filepath.Join(home, ".silmaril")
 }
 
+// UserConfigDir returns the directory the user's config file is looked up in:
+// $XDG_CONFIG_HOME/silmaril if set, otherwise ~/Library/Application Support
+// on macOS, %APPDATA% on Windows and ~/.config elsewhere
+func UserConfigDir() string {
+	return getUserConfigDir()
+}
+
 // getUserConfigDir returns the user's config directory
 func getUserConfigDir() string {
 	// Use XDG_CONFIG_HOME if set
@@ -254,43 +751,53 @@ func getUserConfigDir() string {
 func expandPaths(cfg *Config) {
 	// Expand base dir
 	if cfg.Storage.BaseDir != "" {
-		cfg.Storage.BaseDir = expandPath(cfg.Storage.BaseDir)
+		cfg.Storage.BaseDir = ExpandPath(cfg.Storage.BaseDir)
 	}
 
 	// Set subdirectories if not specified
 	if cfg.Storage.ModelsDir == "" {
 		cfg.Storage.ModelsDir = filepath.Join(cfg.Storage.BaseDir, "models")
 	} else {
-		cfg.Storage.ModelsDir = expandPath(cfg.Storage.ModelsDir)
+		cfg.Storage.ModelsDir = ExpandPath(cfg.Storage.ModelsDir)
 	}
 
 	if cfg.Storage.TorrentsDir == "" {
 		cfg.Storage.TorrentsDir = filepath.Join(cfg.Storage.BaseDir, "torrents")
 	} else {
-		cfg.Storage.TorrentsDir = expandPath(cfg.Storage.TorrentsDir)
+		cfg.Storage.TorrentsDir = ExpandPath(cfg.Storage.TorrentsDir)
 	}
 
 	if cfg.Storage.RegistryDir == "" {
 		cfg.Storage.RegistryDir = filepath.Join(cfg.Storage.BaseDir, "registry")
 	} else {
-		cfg.Storage.RegistryDir = expandPath(cfg.Storage.RegistryDir)
+		cfg.Storage.RegistryDir = ExpandPath(cfg.Storage.RegistryDir)
 	}
 
 	if cfg.Storage.DBDir == "" {
 		cfg.Storage.DBDir = filepath.Join(cfg.Storage.BaseDir, "db")
 	} else {
-		cfg.Storage.DBDir = expandPath(cfg.Storage.DBDir)
+		cfg.Storage.DBDir = ExpandPath(cfg.Storage.DBDir)
 	}
 
 	if cfg.Security.KeysDir == "" {
 		cfg.Security.KeysDir = filepath.Join(cfg.Storage.BaseDir, "keys")
 	} else {
-		cfg.Security.KeysDir = expandPath(cfg.Security.KeysDir)
+		cfg.Security.KeysDir = ExpandPath(cfg.Security.KeysDir)
+	}
+
+	for name, pool := range cfg.Storage.Pools {
+		pool.Path = ExpandPath(pool.Path)
+		cfg.Storage.Pools[name] = pool
+	}
+	for i, dir := range cfg.Storage.OutputDirs {
+		cfg.Storage.OutputDirs[i] = ExpandPath(dir)
 	}
+
+	cfg.Daemon.Socket = ExpandPath(cfg.Daemon.Socket)
 }
 
-// expandPath expands ~ and environment variables
-func expandPath(path string) string {
+// ExpandPath expands ~ and environment variables
+func ExpandPath(path string) string {
 	if path == "" {
 		return path
 	}
@@ -303,8 +810,40 @@ func expandPath(path string) string {
 		}
 	}
 
-	// Expand environment variables
-	return os.ExpandEnv(path)
+	// Expand environment variables, and %VAR% on Windows
+	path = os.ExpandEnv(path)
+	if runtime.GOOS == "windows" {
+		path = expandWindowsEnv(path)
+	}
+	return path
+}
+
+// expandWindowsEnv expands %VAR% references such as %APPDATA%, leaving
+// unset variables as they are
+func expandWindowsEnv(path string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(path, '%')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(path[start+1:], '%')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+		if value, ok := os.LookupEnv(path[start+1 : end]); ok && end > start+1 {
+			b.WriteString(path[:start])
+			b.WriteString(value)
+			path = path[end+1:]
+			continue
+		}
+		// Not a variable, its closing % may open the next one
+		b.WriteString(path[:end])
+		path = path[end:]
+	}
+	b.WriteString(path)
+	return b.String()
 }
 
 // Get returns the current configuration
@@ -81,12 +81,21 @@ func TestExpandPath(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := expandPath(tt.input)
+			result := ExpandPath(tt.input)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
 
+func TestExpandWindowsEnv(t *testing.T) {
+	t.Setenv("SILMARIL_TEST_DIR", `C:\Users\me\AppData\Roaming`)
+
+	assert.Equal(t, `C:\Users\me\AppData\Roaming\silmaril`, expandWindowsEnv(`%SILMARIL_TEST_DIR%\silmaril`))
+	assert.Equal(t, `%SILMARIL_UNSET%\silmaril`, expandWindowsEnv(`%SILMARIL_UNSET%\silmaril`))
+	assert.Equal(t, `100% C:\Users\me\AppData\Roaming`, expandWindowsEnv(`100% %SILMARIL_TEST_DIR%`))
+	assert.Equal(t, `50%`, expandWindowsEnv(`50%`))
+}
+
 func TestSetDefaults(t *testing.T) {
 	v := viper.New()
 	setDefaults(v)
@@ -106,6 +115,8 @@ func TestSetDefaults(t *testing.T) {
 	assert.Equal(t, int64(4*1024*1024), v.GetInt64("torrent.piece_length"))
 	assert.Equal(t, 0.0, v.GetFloat64("torrent.seed_ratio"))
 	assert.Equal(t, 0, v.GetInt("torrent.download_timeout"))
+	assert.True(t, v.GetBool("torrent.initial_seeding"))
+	assert.False(t, v.GetBool("torrent.swarm_coordinator"))
 
 	// Test daemon defaults
 	assert.Equal(t, "0.0.0.0", v.GetString("daemon.bind_address"))
@@ -280,4 +291,112 @@ daemon:
 	// Check that defaults are still set for non-overridden values
 	assert.True(t, v.GetBool("daemon.auto_start"))
 	assert.True(t, v.GetBool("security.sign_manifests"))
-}
\ No newline at end of file
+}
+func TestUseProfile(t *testing.T) {
+	originalCfg := cfg
+	originalV := v
+	defer func() {
+		cfg = originalCfg
+		v = originalV
+	}()
+	t.Setenv("SILMARIL_HOME", "")
+
+	workDir := filepath.Join(t.TempDir(), "work")
+	v = viper.New()
+	setDefaults(v)
+	v.Set("profiles", map[string]interface{}{
+		"work":   map[string]interface{}{"base_dir": workDir, "daemon_port": 8747},
+		"broken": map[string]interface{}{"daemon_port": 8757},
+	})
+	require.NoError(t, load())
+
+	require.NoError(t, UseProfile("work"))
+	assert.Equal(t, "work", cfg.Profile)
+	assert.Equal(t, workDir, cfg.Storage.BaseDir)
+	assert.Equal(t, filepath.Join(workDir, "models"), cfg.Storage.ModelsDir)
+	assert.Equal(t, filepath.Join(workDir, "keys"), cfg.Security.KeysDir)
+	assert.Equal(t, 8747, cfg.Daemon.Port)
+	assert.Equal(t, workDir, os.Getenv("SILMARIL_HOME"))
+
+	assert.Error(t, UseProfile("missing"))
+	assert.Error(t, UseProfile("broken"))
+}
+
+func TestConfigFileBaseDirExported(t *testing.T) {
+	originalCfg := cfg
+	originalV := v
+	defer func() {
+		cfg = originalCfg
+		v = originalV
+		homeFromFile = false
+	}()
+	t.Setenv("SILMARIL_HOME", "")
+
+	baseDir := filepath.Join(t.TempDir(), "data")
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("storage:\n  base_dir: "+baseDir+"\n"), 0644))
+
+	v = viper.New()
+	setDefaults(v)
+	v.SetConfigFile(configFile)
+	require.NoError(t, v.ReadInConfig())
+	require.NoError(t, Reload())
+	assert.Equal(t, baseDir, os.Getenv("SILMARIL_HOME"))
+	assert.False(t, HomeFromEnv())
+
+	// A SILMARIL_HOME set by the user wins over the config file
+	homeFromFile = false
+	t.Setenv("SILMARIL_HOME", "/from/env")
+	require.NoError(t, Reload())
+	assert.Equal(t, "/from/env", os.Getenv("SILMARIL_HOME"))
+	assert.True(t, HomeFromEnv())
+}
+
+func TestMoveBaseDir(t *testing.T) {
+	originalCfg := cfg
+	originalV := v
+	defer func() {
+		cfg = originalCfg
+		v = originalV
+	}()
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `# Where models are kept
+storage:
+  base_dir: /old/base
+  models_dir: /old/base/models # Models
+  db_dir: /fast/db
+daemon:
+  port: 9999
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(content), 0644))
+
+	v = viper.New()
+	setDefaults(v)
+	v.SetConfigFile(configFile)
+	require.NoError(t, v.ReadInConfig())
+	require.NoError(t, load())
+
+	path, err := MoveBaseDir("/old/base", "/new/base")
+	require.NoError(t, err)
+	assert.Equal(t, configFile, path)
+
+	data, err := os.ReadFile(configFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# Where models are kept")
+	assert.Contains(t, string(data), "base_dir: /new/base")
+	assert.Contains(t, string(data), "models_dir: /new/base/models # Models")
+	assert.Contains(t, string(data), "db_dir: /fast/db")
+	assert.Contains(t, string(data), "port: 9999")
+
+	_, err = ValidateFile(configFile)
+	assert.NoError(t, err)
+
+	// The base directory of the active profile is the one moved
+	cfg.Profile = "work"
+	_, err = MoveBaseDir("/work/old", "/work/new")
+	require.NoError(t, err)
+	data, err = os.ReadFile(configFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "profiles:\n  work:\n    base_dir: /work/new")
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/silmaril/silmaril/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// storageDirKeys are the directories that default to a directory in the base
+// directory
+var storageDirKeys = []string{"storage.models_dir", "storage.torrents_dir", "storage.registry_dir", "storage.db_dir", "security.keys_dir"}
+
+// MoveBaseDir records in the config file that the base directory moved from
+// from to to. storage.base_dir, or the base_dir of the active profile, is set
+// to to, and directories configured inside from are moved along. The config
+// file in use is edited in place, keeping its comments, or the user's config
+// file is created if there is none. It returns the file written.
+func MoveBaseDir(from, to string) (string, error) {
+	if v == nil {
+		return "", fmt.Errorf("config not initialized")
+	}
+
+	path := v.ConfigFileUsed()
+	if path == "" {
+		configDir := getUserConfigDir()
+		if configDir == "" {
+			return "", fmt.Errorf("no config file to record the base directory in")
+		}
+		path = filepath.Join(configDir, "config.yaml")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("%s: config must be a mapping of sections", path)
+	}
+
+	baseKey := "storage.base_dir"
+	if cfg != nil && cfg.Profile != "" {
+		baseKey = "profiles." + cfg.Profile + ".base_dir"
+	}
+	if err := setNode(root, strings.Split(baseKey, "."), to); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	for _, key := range storageDirKeys {
+		node := findNode(root, strings.Split(key, "."))
+		if node == nil || node.Kind != yaml.ScalarNode {
+			continue
+		}
+		if relocated, ok := storage.RelocatePath(ExpandPath(node.Value), from, to); ok {
+			node.Value = relocated
+		}
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return "", fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := storage.WriteFileAtomic(path, out.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write config file: %w", err)
+	}
+	return path, nil
+}
+
+// findNode returns the value of the key at keys below the mapping node, nil
+// if it isn't set
+func findNode(node *yaml.Node, keys []string) *yaml.Node {
+	for _, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// setNode sets the key at keys below the mapping node to value, adding the
+// sections missing on the way
+func setNode(node *yaml.Node, keys []string, value string) error {
+	for i, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s must be a section", strings.Join(keys[:i], "."))
+		}
+		next := findNode(node, []string{key})
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode}
+			if i == len(keys)-1 {
+				next = &yaml.Node{Kind: yaml.ScalarNode}
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, next)
+		}
+		node = next
+	}
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("%s must be a value", strings.Join(keys, "."))
+	}
+	node.Value = value
+	node.Tag = "!!str"
+	node.Style = 0
+	return nil
+}
@@ -0,0 +1,482 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// settingKind is the type of value a setting accepts
+type settingKind int
+
+const (
+	intSetting settingKind = iota
+	floatSetting
+	boolSetting
+	stringSetting
+	listSetting
+)
+
+// setting describes a configuration key that can be changed at runtime
+type setting struct {
+	kind settingKind
+
+	// Live settings are applied by a running daemon, all others need a restart
+	live bool
+
+	// File-only settings name commands the daemon runs or what they see, so
+	// they can only be set in the config file and never through the API
+	fileOnly bool
+
+	// Bounds for int settings, no upper bound if max is 0
+	min int64
+	max int64
+
+	// Allowed values for string settings, any value if empty
+	values []string
+}
+
+// settings lists every key that can be changed through the config API or by
+// editing the config file while the daemon is running
+var settings = map[string]setting{
+	"storage.base_dir":               {kind: stringSetting},
+	"storage.models_dir":             {kind: stringSetting},
+	"storage.torrents_dir":           {kind: stringSetting},
+	"storage.registry_dir":           {kind: stringSetting},
+	"storage.db_dir":                 {kind: stringSetting},
+	"storage.scrub_interval_hours":   {kind: intSetting, live: true},
+	"storage.auto_share_new_models":  {kind: boolSetting, live: true},
+	"storage.hash_rate_mb":           {kind: intSetting, live: true},
+	"storage.history_retention_days": {kind: intSetting, live: true},
+	"storage.gc_interval_hours":      {kind: intSetting, live: true},
+	"storage.output_dirs":            {kind: listSetting},
+
+	"network.dht_enabled":                      {kind: boolSetting},
+	"network.dht_bootstrap_nodes":              {kind: listSetting},
+	"network.dht_port":                         {kind: intSetting, max: 65535},
+	"network.listen_port":                      {kind: intSetting, max: 65535},
+	"network.dht_announce_interval_minutes":    {kind: intSetting, live: true},
+	"network.listen_addrs":                     {kind: listSetting},
+	"network.ip_preference":                    {kind: stringSetting, values: []string{"dual", "prefer_ipv4", "prefer_ipv6", "ipv4_only", "ipv6_only"}},
+	"network.max_connections":                  {kind: intSetting, min: 1, live: true},
+	"network.max_connections_per_torrent":      {kind: intSetting, min: 1, live: true},
+	"network.max_half_open":                    {kind: intSetting, min: 1},
+	"network.max_half_open_per_torrent":        {kind: intSetting, min: 1},
+	"network.upload_rate_limit":                {kind: intSetting, live: true},
+	"network.download_rate_limit":              {kind: intSetting, live: true},
+	"network.upload_fairness":                  {kind: boolSetting, live: true},
+	"network.disable_trackers":                 {kind: boolSetting},
+	"network.disable_webtorrent":               {kind: boolSetting},
+	"network.disable_pex":                      {kind: boolSetting},
+	"network.peer_blocklist":                   {kind: listSetting, live: true},
+	"network.peer_allowlist":                   {kind: listSetting, live: true},
+	"network.peer_blocklist_url":               {kind: stringSetting, live: true},
+	"network.peer_blocklist_refresh_hours":     {kind: intSetting, live: true, min: 1},
+	"network.peer_hints":                       {kind: listSetting, live: true},
+	"network.share_stats":                      {kind: boolSetting, live: true},
+	"network.catalog_refresh_interval_minutes": {kind: intSetting, live: true, min: 1},
+
+	"daemon.bind_address":           {kind: stringSetting},
+	"daemon.port":                   {kind: intSetting, min: 1, max: 65535},
+	"daemon.auto_start":             {kind: boolSetting, live: true},
+	"daemon.mode":                   {kind: stringSetting, values: []string{"full", "relay"}},
+	"daemon.log_level":              {kind: stringSetting, live: true, values: []string{"debug", "info"}},
+	"daemon.shutdown_grace_seconds": {kind: intSetting, live: true, min: 1},
+	"daemon.api_rate_limit":         {kind: intSetting, live: true},
+	"daemon.api_rate_burst":         {kind: intSetting, live: true},
+	"daemon.api_max_concurrent":     {kind: intSetting, live: true},
+	"daemon.api_max_body_kb":        {kind: intSetting, live: true},
+	"daemon.socket":                 {kind: stringSetting},
+	"daemon.socket_only":            {kind: boolSetting},
+
+	"torrent.piece_length":         {kind: intSetting, min: 1},
+	"torrent.seed_ratio":           {kind: floatSetting, live: true},
+	"torrent.seed_time":            {kind: intSetting, live: true},
+	"torrent.stop_if_no_peers_for": {kind: intSetting, live: true},
+	"torrent.initial_seeding":      {kind: boolSetting, live: true},
+	"torrent.swarm_coordinator":    {kind: boolSetting, live: true},
+	"torrent.download_timeout":     {kind: intSetting},
+	"torrent.storage_backend":      {kind: stringSetting, values: []string{"file", "mmap", "cached"}},
+	"torrent.recheck_on_start":     {kind: boolSetting},
+	"torrent.web_seeds":            {kind: boolSetting, live: true},
+	"torrent.ipfs_gateways":        {kind: listSetting, live: true},
+	"torrent.verify_downloads":     {kind: boolSetting, live: true},
+
+	"hf_proxy.enabled":             {kind: boolSetting},
+	"hf_proxy.bind_address":        {kind: stringSetting},
+	"hf_proxy.port":                {kind: intSetting, min: 1, max: 65535},
+	"hf_proxy.upstream":            {kind: stringSetting, live: true},
+	"hf_proxy.p2p_timeout_seconds": {kind: intSetting, live: true},
+
+	"pinning.enabled":           {kind: boolSetting, live: true},
+	"pinning.min_seeders":       {kind: intSetting, live: true, min: 1},
+	"pinning.max_disk_gb":       {kind: intSetting, live: true},
+	"pinning.max_model_size_gb": {kind: intSetting, live: true},
+	"pinning.max_downloads":     {kind: intSetting, live: true, min: 1},
+	"pinning.models":            {kind: listSetting, live: true},
+	"pinning.interval_minutes":  {kind: intSetting, live: true, min: 1},
+
+	"hooks.on_download_complete": {kind: stringSetting, fileOnly: true},
+	"hooks.timeout_seconds":      {kind: intSetting, fileOnly: true, min: 1},
+	"hooks.env":                  {kind: listSetting, fileOnly: true},
+
+	"convert.enabled":         {kind: boolSetting, live: true},
+	"convert.command":         {kind: stringSetting, fileOnly: true},
+	"convert.variants":        {kind: listSetting, live: true},
+	"convert.models":          {kind: listSetting, live: true},
+	"convert.publish":         {kind: boolSetting, live: true},
+	"convert.timeout_minutes": {kind: intSetting, live: true, min: 1},
+
+	"proxy.url":      {kind: stringSetting},
+	"proxy.no_proxy": {kind: listSetting},
+	"proxy.torrent":  {kind: stringSetting},
+	"proxy.dht":      {kind: stringSetting},
+	"proxy.git":      {kind: stringSetting},
+	"proxy.api":      {kind: stringSetting, live: true},
+
+	"security.sign_manifests":                {kind: boolSetting},
+	"security.verify_manifests":              {kind: boolSetting},
+	"security.keys_dir":                      {kind: stringSetting},
+	"security.model_blocklist_urls":          {kind: listSetting, live: true},
+	"security.model_blocklist_refresh_hours": {kind: intSetting, live: true, min: 1},
+}
+
+// ConfigChange reports the keys changed by an update or a config file reload
+type ConfigChange struct {
+	// Keys applied by the running daemon
+	Applied []string `json:"applied"`
+
+	// Keys that only take effect after a daemon restart
+	RestartRequired []string `json:"restart_required"`
+}
+
+// Empty reports whether the change did not touch any known key
+func (c ConfigChange) Empty() bool {
+	return len(c.Applied) == 0 && len(c.RestartRequired) == 0
+}
+
+// Time to wait for a config file write to settle before reloading it
+const reloadDelay = 100 * time.Millisecond
+
+var (
+	// runtimeMu guards the viper instance against runtime updates and reloads
+	runtimeMu sync.RWMutex
+
+	// Keys changed since startup that still need a restart
+	pendingRestart = make(map[string]bool)
+)
+
+// LiveKeys returns the keys a running daemon applies without restart
+func LiveKeys() []string {
+	var keys []string
+	for key, s := range settings {
+		if s.live {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RestartKeys returns the keys that only take effect after a daemon restart
+func RestartKeys() []string {
+	var keys []string
+	for key, s := range settings {
+		if !s.live {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// PendingRestart returns the keys changed since startup that need a restart
+func PendingRestart() []string {
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+
+	var keys []string
+	for key := range pendingRestart {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Settings returns the current value of every known key
+func Settings() (map[string]interface{}, error) {
+	if v == nil {
+		return nil, fmt.Errorf("config not initialized")
+	}
+
+	runtimeMu.RLock()
+	defer runtimeMu.RUnlock()
+	return snapshot(), nil
+}
+
+// Update validates and applies changes to the running configuration. Either
+// all changes are applied or, if any of them is invalid, none. Changes are not
+// written back to the config file. File-only settings are refused.
+func Update(changes map[string]interface{}) (ConfigChange, error) {
+	if v == nil {
+		return ConfigChange{}, fmt.Errorf("config not initialized")
+	}
+
+	values := make(map[string]interface{}, len(changes))
+	for key, value := range changes {
+		if settings[key].fileOnly {
+			return ConfigChange{}, fmt.Errorf("%s can only be set in the config file", key)
+		}
+		normalized, err := validate(key, value)
+		if err != nil {
+			return ConfigChange{}, err
+		}
+		values[key] = normalized
+	}
+
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+
+	before := snapshot()
+	for key, value := range values {
+		v.Set(key, value)
+		overrides[key] = SourceRuntime
+	}
+	if err := load(); err != nil {
+		return ConfigChange{}, err
+	}
+
+	return recordChanges(before), nil
+}
+
+// Watch reloads the config file whenever it changes and calls onChange with
+// the keys that changed, until ctx is done. It returns an error if no config
+// file is in use.
+func Watch(ctx context.Context, onChange func(ConfigChange)) error {
+	if v == nil {
+		return fmt.Errorf("config not initialized")
+	}
+	path := v.ConfigFileUsed()
+	if path == "" {
+		return fmt.Errorf("no config file in use")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+
+	// Watch the directory, editors often replace the file instead of writing it
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch config directory: %w", err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		// Editors and tools often write a file in several steps, so reload
+		// once the events have settled
+		var reload <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != filepath.Clean(path) {
+					continue
+				}
+				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
+					reload = time.After(reloadDelay)
+				}
+			case <-reload:
+				reload = nil
+				change, err := reloadFile()
+				if err != nil {
+					fmt.Printf("[Config] Failed to reload %s: %v\n", path, err)
+					continue
+				}
+				if !change.Empty() {
+					onChange(change)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fmt.Printf("[Config] Watcher error: %v\n", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// reloadFile re-reads the config file and returns the keys that changed
+func reloadFile() (ConfigChange, error) {
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+
+	// Validate the new file before it replaces the running configuration
+	if _, err := ValidateFile(v.ConfigFileUsed()); err != nil {
+		return ConfigChange{}, err
+	}
+
+	before := snapshot()
+	if err := v.ReadInConfig(); err != nil {
+		return ConfigChange{}, err
+	}
+	if err := load(); err != nil {
+		return ConfigChange{}, err
+	}
+
+	return recordChanges(before), nil
+}
+
+// recordChanges diffs the current settings against before. Callers must hold
+// runtimeMu.
+func recordChanges(before map[string]interface{}) ConfigChange {
+	var change ConfigChange
+	for key, value := range snapshot() {
+		if reflect.DeepEqual(before[key], value) {
+			continue
+		}
+		if settings[key].live {
+			change.Applied = append(change.Applied, key)
+		} else {
+			change.RestartRequired = append(change.RestartRequired, key)
+			pendingRestart[key] = true
+		}
+	}
+	sort.Strings(change.Applied)
+	sort.Strings(change.RestartRequired)
+	return change
+}
+
+// snapshot returns the current value of every known key
+func snapshot() map[string]interface{} {
+	values := make(map[string]interface{}, len(settings))
+	for key, s := range settings {
+		switch s.kind {
+		case intSetting:
+			values[key] = v.GetInt64(key)
+		case floatSetting:
+			values[key] = v.GetFloat64(key)
+		case boolSetting:
+			values[key] = v.GetBool(key)
+		case stringSetting:
+			values[key] = v.GetString(key)
+		case listSetting:
+			values[key] = v.GetStringSlice(key)
+		}
+	}
+	return values
+}
+
+// validate checks a value for key and converts it to the setting's type
+func validate(key string, value interface{}) (interface{}, error) {
+	s, ok := settings[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown config key %q", key)
+	}
+	return validateSetting(key, s, value)
+}
+
+// validateSetting checks a value against the schema of a setting and converts
+// it to the setting's type
+func validateSetting(key string, s setting, value interface{}) (interface{}, error) {
+	switch s.kind {
+	case intSetting:
+		var n int64
+		switch val := value.(type) {
+		case int:
+			n = int64(val)
+		case int64:
+			n = val
+		case float64:
+			if val != float64(int64(val)) {
+				return nil, fmt.Errorf("%s must be an integer", key)
+			}
+			n = int64(val)
+		default:
+			return nil, fmt.Errorf("%s must be an integer", key)
+		}
+		if n < s.min {
+			return nil, fmt.Errorf("%s must be at least %d", key, s.min)
+		}
+		if s.max != 0 && n > s.max {
+			return nil, fmt.Errorf("%s must be at most %d", key, s.max)
+		}
+		return n, nil
+
+	case floatSetting:
+		var f float64
+		switch val := value.(type) {
+		case int:
+			f = float64(val)
+		case int64:
+			f = float64(val)
+		case float64:
+			f = val
+		default:
+			return nil, fmt.Errorf("%s must be a number", key)
+		}
+		if f < float64(s.min) {
+			return nil, fmt.Errorf("%s must be at least %d", key, s.min)
+		}
+		return f, nil
+
+	case boolSetting:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s must be true or false", key)
+		}
+		return b, nil
+
+	case stringSetting:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", key)
+		}
+		if len(s.values) > 0 {
+			valid := false
+			for _, allowed := range s.values {
+				if str == allowed {
+					valid = true
+				}
+			}
+			if !valid {
+				return nil, fmt.Errorf("%s must be one of: %s", key, strings.Join(s.values, ", "))
+			}
+		}
+		return str, nil
+
+	case listSetting:
+		switch val := value.(type) {
+		case []string:
+			return val, nil
+		case []interface{}:
+			list := make([]string, 0, len(val))
+			for _, item := range val {
+				str, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s must be a list of strings", key)
+				}
+				list = append(list, str)
+			}
+			return list, nil
+		}
+		return nil, fmt.Errorf("%s must be a list of strings", key)
+	}
+
+	return nil, fmt.Errorf("unknown config key %q", key)
+}
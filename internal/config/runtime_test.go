@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRuntimeConfig installs a fresh viper instance for the test
+func setupRuntimeConfig(t *testing.T) {
+	originalCfg := cfg
+	originalV := v
+	t.Cleanup(func() {
+		cfg = originalCfg
+		v = originalV
+		pendingRestart = make(map[string]bool)
+		overrides = make(map[string]string)
+	})
+
+	v = viper.New()
+	setDefaults(v)
+	require.NoError(t, load())
+}
+
+func TestUpdate(t *testing.T) {
+	setupRuntimeConfig(t)
+
+	change, err := Update(map[string]interface{}{
+		"network.upload_rate_limit": float64(1024),
+		"network.listen_port":       float64(6881),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"network.upload_rate_limit"}, change.Applied)
+	assert.Equal(t, []string{"network.listen_port"}, change.RestartRequired)
+	assert.Equal(t, int64(1024), cfg.Network.UploadRateLimit)
+	assert.Equal(t, []string{"network.listen_port"}, PendingRestart())
+
+	// Setting the same value again is not a change
+	change, err = Update(map[string]interface{}{"network.upload_rate_limit": 1024})
+	require.NoError(t, err)
+	assert.True(t, change.Empty())
+}
+
+func TestUpdateValidation(t *testing.T) {
+	setupRuntimeConfig(t)
+
+	invalid := []map[string]interface{}{
+		{"network.unknown": 1},
+		{"network.upload_rate_limit": "fast"},
+		{"network.upload_rate_limit": -1},
+		{"network.upload_rate_limit": 1.5},
+		{"network.catalog_refresh_interval_minutes": 0},
+		{"daemon.log_level": "verbose"},
+		{"network.dht_enabled": "yes"},
+		// Hooks run commands, so they are only read from the config file
+		{"hooks.on_download_complete": "/bin/sh"},
+		{"hooks.env": []interface{}{"HF_TOKEN"}},
+		{"convert.command": "/bin/sh"},
+		// One invalid key rejects the whole update
+		{"network.download_rate_limit": 2048, "daemon.port": 0},
+	}
+	for _, changes := range invalid {
+		_, err := Update(changes)
+		assert.Error(t, err, "%v", changes)
+	}
+
+	assert.Equal(t, int64(0), cfg.Network.DownloadRateLimit)
+	assert.Empty(t, cfg.Hooks.OnDownloadComplete)
+	assert.Equal(t, 8737, cfg.Daemon.Port)
+}
+
+func TestWatch(t *testing.T) {
+	setupRuntimeConfig(t)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("network:\n  download_rate_limit: 0\n"), 0644))
+	v.SetConfigFile(path)
+	require.NoError(t, v.ReadInConfig())
+	require.NoError(t, load())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan ConfigChange, 10)
+	require.NoError(t, Watch(ctx, func(change ConfigChange) {
+		changes <- change
+	}))
+
+	require.NoError(t, os.WriteFile(path, []byte("network:\n  download_rate_limit: 4096\n  dht_port: 6882\n"), 0644))
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, []string{"network.download_rate_limit"}, change.Applied)
+		assert.Equal(t, []string{"network.dht_port"}, change.RestartRequired)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change was not detected")
+	}
+
+	settings, err := Settings()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4096), settings["network.download_rate_limit"])
+
+	// An invalid file is not applied
+	require.NoError(t, os.WriteFile(path, []byte("daemon:\n  log_level: loud\n"), 0644))
+	time.Sleep(500 * time.Millisecond)
+	settings, err = Settings()
+	require.NoError(t, err)
+	assert.Equal(t, "debug", settings["daemon.log_level"])
+	assert.Equal(t, int64(4096), settings["network.download_rate_limit"])
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Sources a setting can get its value from, a source overrides the ones
+// listed before it
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+	SourceProfile = "profile"
+	SourceRuntime = "runtime" // Changed through the config API
+)
+
+// Keys set on top of the config file and environment, with their source.
+// Guarded by runtimeMu.
+var overrides = make(map[string]string)
+
+// SetFlag sets a key from a command line flag, overriding the config file and
+// environment
+func SetFlag(key string, value interface{}) error {
+	if v == nil {
+		return fmt.Errorf("config not initialized")
+	}
+	normalized, err := validate(key, value)
+	if err != nil {
+		return err
+	}
+
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+
+	v.Set(key, normalized)
+	overrides[key] = SourceFlag
+	return load()
+}
+
+// Sources returns where the current value of every known key comes from
+func Sources() (map[string]string, error) {
+	if v == nil {
+		return nil, fmt.Errorf("config not initialized")
+	}
+
+	runtimeMu.RLock()
+	defer runtimeMu.RUnlock()
+
+	sources := make(map[string]string, len(settings))
+	for key := range settings {
+		sources[key] = source(key)
+	}
+	return sources, nil
+}
+
+// source returns where the value of key comes from, following viper's order
+// of precedence. Callers must hold runtimeMu.
+func source(key string) string {
+	if src, ok := overrides[key]; ok {
+		return src
+	}
+	if _, ok := os.LookupEnv(envVar(key)); ok {
+		return SourceEnv
+	}
+	if v.InConfig(key) {
+		return SourceFile
+	}
+	return SourceDefault
+}
+
+// envVar returns the environment variable viper reads key from
+func envVar(key string) string {
+	return strings.ToUpper("SILMARIL_" + key)
+}
@@ -0,0 +1,453 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// retiredKeys were written by earlier versions of 'silmaril init' but are not
+// read by Silmaril. They are reported as warnings instead of errors so
+// existing config files keep working.
+var retiredKeys = map[string]bool{
+	"ui.progress_bar":           true,
+	"ui.color":                  true,
+	"ui.verbose":                true,
+	"security.verify_checksums": true,
+}
+
+// profileSettings is the schema of each entry under profiles
+var profileSettings = map[string]setting{
+	"base_dir":    {kind: stringSetting},
+	"daemon_port": {kind: intSetting, min: 1, max: 65535},
+}
+
+// poolSettings is the schema of each entry under storage.pools
+var poolSettings = map[string]setting{
+	"path":              {kind: stringSetting},
+	"min_model_size_gb": {kind: intSetting, min: 0},
+	"max_model_size_gb": {kind: intSetting, min: 0},
+}
+
+// catalogSettings is the schema of each entry under catalogs
+var catalogSettings = map[string]setting{
+	"seed":       {kind: stringSetting},
+	"public_key": {kind: stringSetting},
+}
+
+// webhookSettings is the schema of each entry under webhooks, headers are
+// checked on their own
+var webhookSettings = map[string]setting{
+	"url":    {kind: stringSetting},
+	"events": {kind: listSetting},
+}
+
+// FieldError is a problem with one key of a config file
+type FieldError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// ValidationError lists every problem found in a config file
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		lines[i] = fieldErr.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks the config file in use against the schema. It returns no
+// error if no config file is in use.
+func Validate() ([]FieldError, error) {
+	if v == nil {
+		return nil, fmt.Errorf("config not initialized")
+	}
+	path := v.ConfigFileUsed()
+	if path == "" {
+		return nil, nil
+	}
+	return ValidateFile(path)
+}
+
+// ValidateFile checks a config file against the schema: every key must be
+// known and every value must have the right type and be in range. Problems
+// are returned as a *ValidationError with the line and column of each.
+// Retired keys don't fail validation and are returned as warnings.
+func ValidateFile(path string) ([]FieldError, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Viper reads every config file as YAML, whatever its extension
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	check := &schemaCheck{file: path}
+	if len(doc.Content) > 0 {
+		check.mapping("", doc.Content[0])
+	}
+	if len(check.errors) > 0 {
+		return check.warnings, &ValidationError{Errors: check.errors}
+	}
+	return check.warnings, nil
+}
+
+// schemaCheck collects the problems found while walking a config file
+type schemaCheck struct {
+	file     string
+	errors   []FieldError
+	warnings []FieldError
+}
+
+func (c *schemaCheck) fail(node *yaml.Node, key, format string, args ...interface{}) {
+	c.errors = append(c.errors, c.fieldError(node, key, fmt.Sprintf(format, args...)))
+}
+
+func (c *schemaCheck) warn(node *yaml.Node, key, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, c.fieldError(node, key, fmt.Sprintf(format, args...)))
+}
+
+func (c *schemaCheck) fieldError(node *yaml.Node, key, message string) FieldError {
+	return FieldError{File: c.file, Line: node.Line, Column: node.Column, Key: key, Message: message}
+}
+
+// mapping checks the keys of a section, prefix is empty for the top level
+func (c *schemaCheck) mapping(prefix string, node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		if prefix == "" {
+			c.fail(node, "", "config must be a mapping of sections")
+		} else {
+			c.fail(node, prefix, "%s must be a section", prefix)
+		}
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		key := strings.ToLower(keyNode.Value)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if s, ok := settings[key]; ok {
+			c.value(key, s, valueNode)
+			continue
+		}
+		switch {
+		case key == "profiles":
+			c.profiles(valueNode)
+		case key == "storage.pools":
+			c.pools(valueNode)
+		case key == "webhooks":
+			c.webhooks(valueNode)
+		case key == "catalogs":
+			c.catalogs(valueNode)
+		case key == "security.organizations":
+			c.organizations(valueNode)
+		case key == "security.namespaces", key == "security.trusted_namespaces":
+			c.namespaces(key, valueNode)
+		case retiredKeys[key]:
+			c.warn(keyNode, key, "%s is no longer used and can be removed", key)
+		case isSection(key):
+			c.mapping(key, valueNode)
+		default:
+			c.fail(keyNode, key, "unknown config key %q", key)
+		}
+	}
+}
+
+// value checks the value of a setting, an empty value leaves the default
+func (c *schemaCheck) value(key string, s setting, node *yaml.Node) {
+	if node.Tag == "!!null" {
+		return
+	}
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		c.fail(node, key, "%s: %v", key, err)
+		return
+	}
+	if _, err := validateSetting(key, s, value); err != nil {
+		c.fail(node, key, "%v", err)
+	}
+}
+
+// profiles checks the named profiles, each with its own base directory
+func (c *schemaCheck) profiles(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		c.fail(node, "profiles", "profiles must be a section")
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		nameNode, profileNode := node.Content[i], node.Content[i+1]
+		prefix := "profiles." + nameNode.Value
+		if profileNode.Kind != yaml.MappingNode {
+			c.fail(profileNode, prefix, "%s must be a section", prefix)
+			continue
+		}
+
+		hasBaseDir := false
+		for j := 0; j+1 < len(profileNode.Content); j += 2 {
+			keyNode, valueNode := profileNode.Content[j], profileNode.Content[j+1]
+			field := strings.ToLower(keyNode.Value)
+			key := prefix + "." + field
+			s, ok := profileSettings[field]
+			if !ok {
+				c.fail(keyNode, key, "unknown config key %q", key)
+				continue
+			}
+			if field == "base_dir" && valueNode.Value != "" {
+				hasBaseDir = true
+			}
+			c.value(key, s, valueNode)
+		}
+		if !hasBaseDir {
+			c.fail(nameNode, prefix, "profile %q has no base_dir", nameNode.Value)
+		}
+	}
+}
+
+// pools checks the storage pools, each with its own directory
+func (c *schemaCheck) pools(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		c.fail(node, "storage.pools", "storage.pools must be a section")
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		nameNode, poolNode := node.Content[i], node.Content[i+1]
+		prefix := "storage.pools." + nameNode.Value
+		if poolNode.Kind != yaml.MappingNode {
+			c.fail(poolNode, prefix, "%s must be a section", prefix)
+			continue
+		}
+		if nameNode.Value == "default" {
+			c.fail(nameNode, prefix, "pool name \"default\" is taken by the models directory")
+		}
+
+		hasPath := false
+		for j := 0; j+1 < len(poolNode.Content); j += 2 {
+			keyNode, valueNode := poolNode.Content[j], poolNode.Content[j+1]
+			field := strings.ToLower(keyNode.Value)
+			key := prefix + "." + field
+			s, ok := poolSettings[field]
+			if !ok {
+				c.fail(keyNode, key, "unknown config key %q", key)
+				continue
+			}
+			if field == "path" && valueNode.Value != "" {
+				hasPath = true
+			}
+			c.value(key, s, valueNode)
+		}
+		if !hasPath {
+			c.fail(nameNode, prefix, "pool %q has no path", nameNode.Value)
+		}
+	}
+}
+
+// webhooks checks the webhooks, each with its own URL
+func (c *schemaCheck) webhooks(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		c.fail(node, "webhooks", "webhooks must be a section")
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		nameNode, hookNode := node.Content[i], node.Content[i+1]
+		prefix := "webhooks." + nameNode.Value
+		if hookNode.Kind != yaml.MappingNode {
+			c.fail(hookNode, prefix, "%s must be a section", prefix)
+			continue
+		}
+
+		hasURL := false
+		for j := 0; j+1 < len(hookNode.Content); j += 2 {
+			keyNode, valueNode := hookNode.Content[j], hookNode.Content[j+1]
+			field := strings.ToLower(keyNode.Value)
+			key := prefix + "." + field
+			if field == "headers" {
+				c.headers(key, valueNode)
+				continue
+			}
+			s, ok := webhookSettings[field]
+			if !ok {
+				c.fail(keyNode, key, "unknown config key %q", key)
+				continue
+			}
+			if field == "url" && valueNode.Value != "" {
+				hasURL = true
+				if !strings.HasPrefix(valueNode.Value, "http://") && !strings.HasPrefix(valueNode.Value, "https://") {
+					c.fail(valueNode, key, "%s must be an http:// or https:// URL", key)
+				}
+			}
+			c.value(key, s, valueNode)
+		}
+		if !hasURL {
+			c.fail(nameNode, prefix, "webhook %q has no url", nameNode.Value)
+		}
+	}
+}
+
+// catalogs checks the catalog networks, each with a seed or a public key
+func (c *schemaCheck) catalogs(node *yaml.Node) {
+	if node.Tag == "!!null" {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		c.fail(node, "catalogs", "catalogs must be a section")
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		nameNode, catalogNode := node.Content[i], node.Content[i+1]
+		prefix := "catalogs." + nameNode.Value
+		if catalogNode.Kind != yaml.MappingNode {
+			c.fail(catalogNode, prefix, "%s must be a section", prefix)
+			continue
+		}
+		if nameNode.Value == "public" {
+			c.fail(nameNode, prefix, "catalog name \"public\" is taken by the public catalog")
+		}
+
+		identities := 0
+		for j := 0; j+1 < len(catalogNode.Content); j += 2 {
+			keyNode, valueNode := catalogNode.Content[j], catalogNode.Content[j+1]
+			field := strings.ToLower(keyNode.Value)
+			key := prefix + "." + field
+			s, ok := catalogSettings[field]
+			if !ok {
+				c.fail(keyNode, key, "unknown config key %q", key)
+				continue
+			}
+			if valueNode.Value == "" {
+				continue
+			}
+			identities++
+			if field == "public_key" && !isHex256(valueNode.Value) {
+				c.fail(valueNode, key, "%s must be a publisher key, 64 hex characters", key)
+			}
+			c.value(key, s, valueNode)
+		}
+		if identities != 1 {
+			c.fail(nameNode, prefix, "catalog %q needs either a seed or a public_key", nameNode.Value)
+		}
+	}
+}
+
+// headers checks the HTTP headers of a webhook, a mapping of names to values
+func (c *schemaCheck) headers(key string, node *yaml.Node) {
+	if node.Tag == "!!null" {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		c.fail(node, key, "%s must be a section", key)
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if value := node.Content[i+1]; value.Kind != yaml.ScalarNode {
+			c.fail(value, key+"."+node.Content[i].Value, "%s.%s must be a string", key, node.Content[i].Value)
+		}
+	}
+}
+
+// organizations checks the organizations, each with the ID of its
+// signature policy
+func (c *schemaCheck) organizations(node *yaml.Node) {
+	if node.Tag == "!!null" {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		c.fail(node, "security.organizations", "security.organizations must be a section")
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := "security.organizations." + node.Content[i].Value
+		value := node.Content[i+1]
+		if value.Kind != yaml.ScalarNode || !isHex256(value.Value) {
+			c.fail(value, key, "%s must be a signature policy ID, 64 hex characters", key)
+		}
+	}
+}
+
+// namespaces checks a section of namespaces, each with the domain owning it
+func (c *schemaCheck) namespaces(section string, node *yaml.Node) {
+	if node.Tag == "!!null" {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		c.fail(node, section, "%s must be a section", section)
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := section + "." + node.Content[i].Value
+		value := node.Content[i+1]
+		if value.Kind != yaml.ScalarNode || !isDomain(value.Value) {
+			c.fail(value, key, "%s must be a domain name, like example.com", key)
+		}
+	}
+}
+
+// isDomain reports whether s looks like a domain name
+func isDomain(s string) bool {
+	if len(s) > 253 || !strings.Contains(s, ".") || strings.HasPrefix(s, ".") || strings.HasSuffix(s, ".") {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isHex256 reports whether s is 32 bytes in hex, like the ID of a signature
+// policy or a public key
+func isHex256(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSection reports whether key holds other keys of the schema
+func isSection(key string) bool {
+	for known := range settings {
+		if strings.HasPrefix(known, key+".") {
+			return true
+		}
+	}
+	for known := range retiredKeys {
+		if strings.HasPrefix(known, key+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestValidateFile(t *testing.T) {
+	path := writeConfigFile(t, `storage:
+  models_dir: ~/models
+  hash_rate_mb:
+network:
+  dht_bootstrap_nodes: ["router.bittorrent.com:6881"]
+  max_connections: 50
+torrent:
+  seed_ratio: 1.5
+profiles:
+  work:
+    base_dir: ~/.silmaril-work
+    daemon_port: 8738
+`)
+	warnings, err := ValidateFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
+
+func TestValidateFileErrors(t *testing.T) {
+	path := writeConfigFile(t, `storage:
+  hash_rate_mb: fast
+network:
+  dht_port: 70000
+  max_conections: 50
+  ip_preference: ipv5
+torrent:
+  seed_ratio: -1
+daemon: 8737
+colour: true
+profiles:
+  work:
+    daemon_port: 8738
+`)
+	_, err := ValidateFile(path)
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "%v", err)
+
+	type location struct {
+		line, column int
+		key          string
+	}
+	var got []location
+	for _, fieldErr := range validationErr.Errors {
+		assert.Equal(t, path, fieldErr.File)
+		got = append(got, location{fieldErr.Line, fieldErr.Column, fieldErr.Key})
+	}
+	assert.Equal(t, []location{
+		{2, 17, "storage.hash_rate_mb"},
+		{4, 13, "network.dht_port"},
+		{5, 3, "network.max_conections"},
+		{6, 18, "network.ip_preference"},
+		{8, 15, "torrent.seed_ratio"},
+		{9, 9, "daemon"},
+		{10, 1, "colour"},
+		{12, 3, "profiles.work"},
+	}, got)
+	assert.Contains(t, err.Error(), path+":4:13: network.dht_port must be at most 65535")
+}
+
+func TestValidateFileRetiredKeys(t *testing.T) {
+	path := writeConfigFile(t, `ui:
+  color: true
+security:
+  verify_checksums: true
+`)
+	warnings, err := ValidateFile(path)
+	require.NoError(t, err)
+	require.Len(t, warnings, 2)
+	assert.Equal(t, "ui.color", warnings[0].Key)
+	assert.Equal(t, 2, warnings[0].Line)
+	assert.Equal(t, "security.verify_checksums", warnings[1].Key)
+}
+
+func TestValidateFilePools(t *testing.T) {
+	path := writeConfigFile(t, `storage:
+  pools:
+    nvme:
+      path: /mnt/nvme/models
+      max_model_size_gb: 20
+    hdd:
+      path: /mnt/hdd/models
+      min_model_size_gb: 20
+`)
+	warnings, err := ValidateFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	path = writeConfigFile(t, `storage:
+  pools:
+    default:
+      path: /mnt/nvme/models
+    hdd:
+      min_model_size_gb: -1
+      speed: slow
+`)
+	_, err = ValidateFile(path)
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "%v", err)
+	var keys []string
+	for _, fieldErr := range validationErr.Errors {
+		keys = append(keys, fieldErr.Key)
+	}
+	assert.Equal(t, []string{
+		"storage.pools.default",
+		"storage.pools.hdd.min_model_size_gb",
+		"storage.pools.hdd.speed",
+		"storage.pools.hdd",
+	}, keys)
+}
+
+func TestValidateFileOrganizations(t *testing.T) {
+	path := writeConfigFile(t, `security:
+  organizations:
+    acme: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+`)
+	warnings, err := ValidateFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	path = writeConfigFile(t, `security:
+  organizations:
+    acme: 9f86d081
+`)
+	_, err = ValidateFile(path)
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "%v", err)
+	require.Len(t, validationErr.Errors, 1)
+	assert.Equal(t, "security.organizations.acme", validationErr.Errors[0].Key)
+}
+
+func TestValidateFileNamespaces(t *testing.T) {
+	path := writeConfigFile(t, `security:
+  namespaces:
+    mistralai: mistral.ai
+  trusted_namespaces:
+    meta-llama: meta.com
+`)
+	warnings, err := ValidateFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	path = writeConfigFile(t, `security:
+  namespaces:
+    acme: https://acme.example.com
+    local: localhost
+  trusted_namespaces:
+    other: not a domain
+`)
+	_, err = ValidateFile(path)
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "%v", err)
+	require.Len(t, validationErr.Errors, 3)
+	assert.Equal(t, "security.namespaces.acme", validationErr.Errors[0].Key)
+	assert.Equal(t, "security.namespaces.local", validationErr.Errors[1].Key)
+	assert.Equal(t, "security.trusted_namespaces.other", validationErr.Errors[2].Key)
+}
+
+func TestValidateFileCatalogs(t *testing.T) {
+	path := writeConfigFile(t, `catalogs:
+  acme:
+    seed: acme-models-2026
+  research:
+    public_key: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+`)
+	warnings, err := ValidateFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	path = writeConfigFile(t, `catalogs:
+  public:
+    seed: other
+  both:
+    seed: acme-models-2026
+    public_key: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+  short:
+    public_key: 9f86d081
+`)
+	_, err = ValidateFile(path)
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "%v", err)
+	var keys []string
+	for _, fieldErr := range validationErr.Errors {
+		keys = append(keys, fieldErr.Key)
+	}
+	assert.Equal(t, []string{
+		"catalogs.public",
+		"catalogs.both",
+		"catalogs.short.public_key",
+	}, keys)
+}
+
+func TestValidateFileWebhooks(t *testing.T) {
+	path := writeConfigFile(t, `webhooks:
+  slack:
+    url: https://hooks.slack.com/services/x
+  ci:
+    url: https://ci.example.com/hook
+    headers:
+      Authorization: Bearer ${CI_TOKEN}
+    events: ["publish.*"]
+`)
+	warnings, err := ValidateFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	path = writeConfigFile(t, `webhooks:
+  slack:
+    url: hooks.slack.com
+  ci:
+    headers:
+      X-Tags: [a, b]
+    method: PUT
+`)
+	_, err = ValidateFile(path)
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "%v", err)
+	var keys []string
+	for _, fieldErr := range validationErr.Errors {
+		keys = append(keys, fieldErr.Key)
+	}
+	assert.Equal(t, []string{
+		"webhooks.slack.url",
+		"webhooks.ci.headers.X-Tags",
+		"webhooks.ci.method",
+		"webhooks.ci",
+	}, keys)
+}
+
+func TestValidateFileSyntax(t *testing.T) {
+	_, err := ValidateFile(writeConfigFile(t, "storage:\n  models_dir: [unclosed\n"))
+	assert.Error(t, err)
+
+	_, err = ValidateFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestSources(t *testing.T) {
+	setupRuntimeConfig(t)
+
+	path := writeConfigFile(t, "network:\n  max_connections: 20\n")
+	v.SetConfigFile(path)
+	require.NoError(t, v.ReadInConfig())
+	t.Setenv("SILMARIL_DAEMON.LOG_LEVEL", "info")
+	v.SetEnvPrefix("SILMARIL")
+	v.AutomaticEnv()
+
+	require.NoError(t, SetFlag("hf_proxy.enabled", true))
+	_, err := Update(map[string]interface{}{"network.upload_rate_limit": 1024})
+	require.NoError(t, err)
+	assert.Error(t, SetFlag("daemon.port", 0))
+
+	sources, err := Sources()
+	require.NoError(t, err)
+	assert.Equal(t, SourceFile, sources["network.max_connections"])
+	assert.Equal(t, SourceEnv, sources["daemon.log_level"])
+	assert.Equal(t, SourceFlag, sources["hf_proxy.enabled"])
+	assert.Equal(t, SourceRuntime, sources["network.upload_rate_limit"])
+	assert.Equal(t, SourceDefault, sources["daemon.port"])
+	assert.True(t, cfg.HFProxy.Enabled)
+	assert.Equal(t, "info", cfg.Daemon.LogLevel)
+}
+
+// The example config shipped with Silmaril must pass validation
+func TestValidateExampleConfig(t *testing.T) {
+	warnings, err := ValidateFile(filepath.Join("..", "..", "config.yaml.example"))
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
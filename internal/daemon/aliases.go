@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// ErrAliasNotFound is returned when removing an alias that doesn't exist
+var ErrAliasNotFound = errors.New("alias not found")
+
+// loadAliases reads the aliases of the local models
+func loadAliases() (*models.Aliases, error) {
+	return models.LoadAliases(storage.GetRegistryDir())
+}
+
+// ResolveModel returns the name of the local model name refers to: name
+// itself if a model has that name, otherwise the model it is an alias of
+func (d *Daemon) ResolveModel(name string) string {
+	if modelPath, err := modelPathFor(name); err == nil {
+		if _, err := os.Lstat(modelPath); err == nil {
+			return name
+		}
+	}
+	aliases, err := loadAliases()
+	if err != nil {
+		return name
+	}
+	return aliases.Resolve(name)
+}
+
+// SetAlias lets the local model be referred to as alias. The model may be
+// given by another alias, aliases always point to the model itself.
+func (d *Daemon) SetAlias(alias, model string) (string, error) {
+	d.aliasMu.Lock()
+	defer d.aliasMu.Unlock()
+
+	aliasPath, err := modelPathFor(alias)
+	if err != nil {
+		return "", fmt.Errorf("invalid alias: %s", alias)
+	}
+	if _, err := os.Lstat(aliasPath); err == nil {
+		return "", fmt.Errorf("a model named %s already exists", alias)
+	}
+
+	model = d.ResolveModel(model)
+	modelPath, err := modelPathFor(model)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(modelPath); err != nil {
+		return "", fmt.Errorf("model %s not found", model)
+	}
+
+	aliases, err := loadAliases()
+	if err != nil {
+		return "", err
+	}
+	if err := aliases.Set(alias, model); err != nil {
+		return "", err
+	}
+	if err := aliases.Save(); err != nil {
+		return "", err
+	}
+	d.reloadRegistryModels()
+	fmt.Printf("[Daemon] Alias %s now points to %s\n", alias, model)
+	return model, nil
+}
+
+// RemoveAlias deletes an alias, the model it points to is kept
+func (d *Daemon) RemoveAlias(alias string) error {
+	d.aliasMu.Lock()
+	defer d.aliasMu.Unlock()
+
+	aliases, err := loadAliases()
+	if err != nil {
+		return err
+	}
+	if !aliases.Remove(alias) {
+		return fmt.Errorf("%w: %s", ErrAliasNotFound, alias)
+	}
+	if err := aliases.Save(); err != nil {
+		return err
+	}
+	d.reloadRegistryModels()
+	return nil
+}
+
+// ListAliases returns the aliases of the local models
+func (d *Daemon) ListAliases() ([]models.Alias, error) {
+	aliases, err := loadAliases()
+	if err != nil {
+		return nil, err
+	}
+	return aliases.List(), nil
+}
+
+// removeModelAliases deletes the aliases of a model that is gone
+func (d *Daemon) removeModelAliases(name string) {
+	d.aliasMu.Lock()
+	defer d.aliasMu.Unlock()
+
+	aliases, err := loadAliases()
+	if err != nil {
+		fmt.Printf("[Daemon] Failed to load aliases: %v\n", err)
+		return
+	}
+	if removed := aliases.RemoveModel(name); len(removed) > 0 {
+		if err := aliases.Save(); err != nil {
+			fmt.Printf("[Daemon] Failed to remove aliases of %s: %v\n", name, err)
+		}
+	}
+}
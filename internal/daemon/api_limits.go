@@ -0,0 +1,159 @@
+package daemon
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Reasons API requests are refused for
+const (
+	APILimitRate        = "rate"        // The client sent too many requests
+	APILimitConcurrency = "concurrency" // Too many requests were being handled
+	APILimitBodySize    = "body_size"   // The request body was too large
+)
+
+// Clients that sent no request for this long are forgotten
+const apiClientIdleTimeout = 10 * time.Minute
+
+// APILimits are the limits on requests of API clients. Zero values are
+// unlimited.
+type APILimits struct {
+	Rate          int   // Requests per second of each client
+	Burst         int   // Requests a client may send at once
+	MaxConcurrent int   // Requests handled at the same time
+	MaxBodyBytes  int64 // Size of the bodies of publish and share requests
+}
+
+// APILimitStats counts requests refused for the API limits
+type APILimitStats struct {
+	InFlight int64             `json:"in_flight"`
+	Limited  map[string]uint64 `json:"limited"` // By reason
+}
+
+// apiLimiter keeps the request rate of each API client and the number of
+// requests being handled
+type apiLimiter struct {
+	mu        sync.Mutex
+	clients   map[string]*apiClient
+	lastPrune time.Time
+	limited   map[string]uint64
+	inFlight  atomic.Int64
+}
+
+// apiClient is the request rate of a token or address
+type apiClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// APILimits returns the configured limits on API requests
+func (d *Daemon) APILimits() APILimits {
+	if d.config == nil {
+		return APILimits{}
+	}
+	return APILimits{
+		Rate:          d.config.GetInt("daemon.api_rate_limit"),
+		Burst:         d.config.GetInt("daemon.api_rate_burst"),
+		MaxConcurrent: d.config.GetInt("daemon.api_max_concurrent"),
+		MaxBodyBytes:  int64(d.config.GetInt("daemon.api_max_body_kb")) * 1024,
+	}
+}
+
+// AllowAPIRequest reports whether client, a token or an address, may send
+// a request now under limits. If not it returns how long the client should
+// wait before trying again.
+func (d *Daemon) AllowAPIRequest(client string, limits APILimits) (bool, time.Duration) {
+	if limits.Rate <= 0 {
+		return true, 0
+	}
+	burst := limits.Burst
+	if burst < 1 {
+		burst = limits.Rate
+	}
+
+	l := &d.apiLimits
+	now := time.Now()
+	l.mu.Lock()
+	if l.clients == nil {
+		l.clients = make(map[string]*apiClient)
+	}
+	if now.Sub(l.lastPrune) >= apiClientIdleTimeout {
+		for key, c := range l.clients {
+			if now.Sub(c.lastSeen) >= apiClientIdleTimeout {
+				delete(l.clients, key)
+			}
+		}
+		l.lastPrune = now
+	}
+	c, exists := l.clients[client]
+	if !exists {
+		c = &apiClient{limiter: rate.NewLimiter(rate.Limit(limits.Rate), burst)}
+		l.clients[client] = c
+	}
+	c.lastSeen = now
+	l.mu.Unlock()
+
+	// Changed limits apply to known clients too
+	if c.limiter.Limit() != rate.Limit(limits.Rate) {
+		c.limiter.SetLimitAt(now, rate.Limit(limits.Rate))
+	}
+	if c.limiter.Burst() != burst {
+		c.limiter.SetBurstAt(now, burst)
+	}
+
+	reservation := c.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		d.RecordAPILimited(APILimitRate)
+		return false, delay
+	}
+	return true, 0
+}
+
+// BeginAPIRequest counts a request being handled, false if limits allow no
+// more at the same time. Requests that began must end with EndAPIRequest.
+func (d *Daemon) BeginAPIRequest(limits APILimits) bool {
+	inFlight := d.apiLimits.inFlight.Add(1)
+	if limits.MaxConcurrent > 0 && inFlight > int64(limits.MaxConcurrent) {
+		d.apiLimits.inFlight.Add(-1)
+		d.RecordAPILimited(APILimitConcurrency)
+		return false
+	}
+	return true
+}
+
+// EndAPIRequest counts a request that began as handled
+func (d *Daemon) EndAPIRequest() {
+	d.apiLimits.inFlight.Add(-1)
+}
+
+// RecordAPILimited counts a request refused for one of the API limits
+func (d *Daemon) RecordAPILimited(reason string) {
+	l := &d.apiLimits
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limited == nil {
+		l.limited = make(map[string]uint64)
+	}
+	l.limited[reason]++
+}
+
+// APILimitStats returns the requests being handled and those refused for
+// the API limits
+func (d *Daemon) APILimitStats() APILimitStats {
+	l := &d.apiLimits
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := APILimitStats{
+		InFlight: l.inFlight.Load(),
+		Limited:  map[string]uint64{APILimitRate: 0, APILimitConcurrency: 0, APILimitBodySize: 0},
+	}
+	for reason, count := range l.limited {
+		stats.Limited[reason] = count
+	}
+	return stats
+}
@@ -0,0 +1,54 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowAPIRequest(t *testing.T) {
+	d := &Daemon{}
+	limits := APILimits{Rate: 1, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		ok, _ := d.AllowAPIRequest("token:ci", limits)
+		require.True(t, ok)
+	}
+	ok, wait := d.AllowAPIRequest("token:ci", limits)
+	assert.False(t, ok)
+	assert.Greater(t, wait, time.Duration(0))
+	assert.LessOrEqual(t, wait, time.Second)
+
+	// Other clients have their own rate
+	ok, _ = d.AllowAPIRequest("ip:10.0.0.2", limits)
+	assert.True(t, ok)
+
+	// Without a rate every request is allowed
+	for i := 0; i < 10; i++ {
+		ok, _ = d.AllowAPIRequest("token:ci", APILimits{})
+		require.True(t, ok)
+	}
+
+	assert.Equal(t, uint64(1), d.APILimitStats().Limited[APILimitRate])
+}
+
+func TestBeginAPIRequest(t *testing.T) {
+	d := &Daemon{}
+	limits := APILimits{MaxConcurrent: 2}
+
+	require.True(t, d.BeginAPIRequest(limits))
+	require.True(t, d.BeginAPIRequest(limits))
+	assert.False(t, d.BeginAPIRequest(limits))
+	assert.Equal(t, int64(2), d.APILimitStats().InFlight)
+
+	d.EndAPIRequest()
+	assert.True(t, d.BeginAPIRequest(limits))
+	assert.True(t, d.BeginAPIRequest(APILimits{}))
+
+	stats := d.APILimitStats()
+	assert.Equal(t, int64(3), stats.InFlight)
+	assert.Equal(t, uint64(1), stats.Limited[APILimitConcurrency])
+	assert.Equal(t, uint64(0), stats.Limited[APILimitBodySize])
+}
@@ -0,0 +1,171 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Scopes of API tokens, each allowing what the ones before it allow
+const (
+	ScopeRead     = "read"     // Listing, discovering and status
+	ScopeOperator = "operator" // Downloading, sharing and managing transfers
+	ScopeAdmin    = "admin"    // Shutdown, configuration, removing models and tokens
+)
+
+var scopeRanks = map[string]int{ScopeRead: 1, ScopeOperator: 2, ScopeAdmin: 3}
+
+const (
+	// Prefix of API tokens, telling them apart from other secrets
+	apiTokenPrefix = "slm_"
+
+	// How often the last use of a token is recorded
+	apiTokenUseInterval = time.Minute
+)
+
+var (
+	ErrAPITokenExists    = errors.New("an API token of this name exists")
+	ErrAPITokenNotFound  = errors.New("API token not found")
+	ErrFirstTokenAdmin   = errors.New("the first API token must have the admin scope, or no one could manage tokens")
+	ErrLastAdminAPIToken = errors.New("the last admin token can't be revoked while other tokens exist, revoke them first")
+
+	apiTokenName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+)
+
+// APIToken is a named token for the daemon API. Only the hash of the token
+// is kept, the token itself is shown once when it is created.
+type APIToken struct {
+	Name      string     `json:"name"`
+	Scope     string     `json:"scope"`
+	Hash      string     `json:"hash,omitempty"` // SHA-256 of the token
+	CreatedAt time.Time  `json:"created_at"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+}
+
+// ValidScope reports whether scope is a scope of API tokens
+func ValidScope(scope string) bool {
+	_, ok := scopeRanks[scope]
+	return ok
+}
+
+// ScopeAllows reports whether a token of scope may use an endpoint that
+// needs required
+func ScopeAllows(scope, required string) bool {
+	return scopeRanks[scope] >= scopeRanks[required] && ValidScope(scope)
+}
+
+// hashAPIToken returns the hash an API token is kept as
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIAuthEnabled reports whether API requests need a token, which they do
+// once the first token is created
+func (d *Daemon) APIAuthEnabled() bool {
+	return d.state.HasAPITokens()
+}
+
+// CreateAPIToken creates an API token and returns it with its record. The
+// token can't be shown again.
+func (d *Daemon) CreateAPIToken(name, scope string) (string, APIToken, error) {
+	if !apiTokenName.MatchString(name) {
+		return "", APIToken{}, fmt.Errorf("invalid token name %q, use up to 64 letters, digits, dots, dashes and underscores", name)
+	}
+	if !ValidScope(scope) {
+		return "", APIToken{}, fmt.Errorf("unknown scope %q, use read, operator or admin", scope)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", APIToken{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	record := APIToken{Name: name, Scope: scope, Hash: hashAPIToken(token), CreatedAt: time.Now()}
+	err := d.state.UpdateAPITokens(func(tokens map[string]*APIToken) error {
+		if _, exists := tokens[name]; exists {
+			return ErrAPITokenExists
+		}
+		if len(tokens) == 0 && scope != ScopeAdmin {
+			return ErrFirstTokenAdmin
+		}
+		created := record
+		tokens[name] = &created
+		return nil
+	})
+	if err != nil {
+		return "", APIToken{}, err
+	}
+	// Tokens must work, and revoked ones stop working, across restarts
+	if err := d.state.Save(); err != nil {
+		fmt.Printf("[API] Warning: failed to save API tokens: %v\n", err)
+	}
+	record.Hash = ""
+	return token, record, nil
+}
+
+// RevokeAPIToken removes an API token. Revoking every token turns
+// authentication off again.
+func (d *Daemon) RevokeAPIToken(name string) error {
+	err := d.state.UpdateAPITokens(func(tokens map[string]*APIToken) error {
+		token, exists := tokens[name]
+		if !exists {
+			return ErrAPITokenNotFound
+		}
+		if token.Scope == ScopeAdmin && len(tokens) > 1 {
+			admins := 0
+			for _, t := range tokens {
+				if t.Scope == ScopeAdmin {
+					admins++
+				}
+			}
+			if admins == 1 {
+				return ErrLastAdminAPIToken
+			}
+		}
+		delete(tokens, name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := d.state.Save(); err != nil {
+		fmt.Printf("[API] Warning: failed to save API tokens: %v\n", err)
+	}
+	return nil
+}
+
+// ListAPITokens returns the API tokens without their hashes
+func (d *Daemon) ListAPITokens() []APIToken {
+	tokens := d.state.ListAPITokens()
+	for i := range tokens {
+		tokens[i].Hash = ""
+	}
+	return tokens
+}
+
+// AuthenticateAPIToken returns the record of an API token, false if it
+// doesn't exist or was revoked
+func (d *Daemon) AuthenticateAPIToken(token string) (APIToken, bool) {
+	record, ok := d.state.FindAPIToken(hashAPIToken(token))
+	if !ok {
+		return APIToken{}, false
+	}
+
+	now := time.Now()
+	if record.LastUsed == nil || now.Sub(*record.LastUsed) >= apiTokenUseInterval {
+		d.state.UpdateAPITokens(func(tokens map[string]*APIToken) error {
+			if t, exists := tokens[record.Name]; exists {
+				t.LastUsed = &now
+			}
+			return nil
+		})
+	}
+	record.Hash = ""
+	return record, true
+}
@@ -0,0 +1,120 @@
+package daemon
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopeAllows(t *testing.T) {
+	assert.True(t, ScopeAllows(ScopeAdmin, ScopeOperator))
+	assert.True(t, ScopeAllows(ScopeOperator, ScopeOperator))
+	assert.True(t, ScopeAllows(ScopeOperator, ScopeRead))
+	assert.False(t, ScopeAllows(ScopeRead, ScopeOperator))
+	assert.False(t, ScopeAllows(ScopeOperator, ScopeAdmin))
+	assert.False(t, ScopeAllows("root", ScopeRead))
+}
+
+func TestCreateAPIToken(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	d := &Daemon{state: NewState(filepath.Join(t.TempDir(), "state.json"))}
+	assert.False(t, d.APIAuthEnabled())
+
+	// The first token manages the others
+	_, _, err := d.CreateAPIToken("ci", ScopeRead)
+	assert.ErrorIs(t, err, ErrFirstTokenAdmin)
+	assert.False(t, d.APIAuthEnabled())
+
+	admin, record, err := d.CreateAPIToken("admin", ScopeAdmin)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(admin, apiTokenPrefix))
+	assert.Equal(t, ScopeAdmin, record.Scope)
+	assert.Empty(t, record.Hash)
+	assert.True(t, d.APIAuthEnabled())
+
+	_, _, err = d.CreateAPIToken("admin", ScopeRead)
+	assert.ErrorIs(t, err, ErrAPITokenExists)
+	_, _, err = d.CreateAPIToken("bad name", ScopeRead)
+	assert.Error(t, err)
+	_, _, err = d.CreateAPIToken("ci", "root")
+	assert.Error(t, err)
+
+	read, _, err := d.CreateAPIToken("ci", ScopeRead)
+	require.NoError(t, err)
+	assert.NotEqual(t, admin, read)
+
+	tokens := d.ListAPITokens()
+	require.Len(t, tokens, 2)
+	assert.Equal(t, "admin", tokens[0].Name)
+	assert.Equal(t, "ci", tokens[1].Name)
+	for _, token := range tokens {
+		assert.Empty(t, token.Hash)
+	}
+}
+
+func TestAuthenticateAPIToken(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	d := &Daemon{state: NewState(filepath.Join(t.TempDir(), "state.json"))}
+
+	token, _, err := d.CreateAPIToken("admin", ScopeAdmin)
+	require.NoError(t, err)
+
+	record, ok := d.AuthenticateAPIToken(token)
+	require.True(t, ok)
+	assert.Equal(t, "admin", record.Name)
+	assert.Equal(t, ScopeAdmin, record.Scope)
+	assert.Empty(t, record.Hash)
+	require.NotNil(t, d.ListAPITokens()[0].LastUsed)
+
+	_, ok = d.AuthenticateAPIToken(token + "x")
+	assert.False(t, ok)
+	_, ok = d.AuthenticateAPIToken("")
+	assert.False(t, ok)
+
+	require.NoError(t, d.RevokeAPIToken("admin"))
+	_, ok = d.AuthenticateAPIToken(token)
+	assert.False(t, ok)
+	assert.False(t, d.APIAuthEnabled())
+}
+
+func TestRevokeAPIToken(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	d := &Daemon{state: NewState(filepath.Join(t.TempDir(), "state.json"))}
+
+	_, _, err := d.CreateAPIToken("admin", ScopeAdmin)
+	require.NoError(t, err)
+	_, _, err = d.CreateAPIToken("ops", ScopeOperator)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, d.RevokeAPIToken("missing"), ErrAPITokenNotFound)
+
+	// No one could manage the remaining tokens without an admin
+	assert.ErrorIs(t, d.RevokeAPIToken("admin"), ErrLastAdminAPIToken)
+
+	_, _, err = d.CreateAPIToken("admin2", ScopeAdmin)
+	require.NoError(t, err)
+	require.NoError(t, d.RevokeAPIToken("admin"))
+
+	require.NoError(t, d.RevokeAPIToken("ops"))
+	require.NoError(t, d.RevokeAPIToken("admin2"))
+	assert.Empty(t, d.ListAPITokens())
+}
+
+func TestAPITokensPersist(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	d := &Daemon{state: NewState(stateFile)}
+
+	token, _, err := d.CreateAPIToken("admin", ScopeAdmin)
+	require.NoError(t, err)
+
+	restarted := &Daemon{state: NewState(stateFile)}
+	require.NoError(t, restarted.state.Load())
+	assert.True(t, restarted.APIAuthEnabled())
+	record, ok := restarted.AuthenticateAPIToken(token)
+	require.True(t, ok)
+	assert.Equal(t, "admin", record.Name)
+}
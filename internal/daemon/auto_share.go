@@ -0,0 +1,68 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/silmaril/silmaril/internal/publish"
+)
+
+// autoShareModel publishes a model that was put into the models directory
+// by hand. Models that came from a download or a publish, or that are
+// seeded already, are left alone, as are models without a license.
+func (d *Daemon) autoShareModel(name string) error {
+	manifest, err := d.registry.GetManifest(name)
+	if err != nil {
+		return err
+	}
+	if manifest.Provenance.Derived() {
+		return fmt.Errorf("it was converted from %s, set convert.publish to share conversions", manifest.Provenance.ParentModel)
+	}
+	if manifest.License == "" || strings.EqualFold(manifest.License, "unknown") {
+		return fmt.Errorf("no license, set one in its model card or share it with 'silmaril share'")
+	}
+
+	paths := d.registry.Paths()
+	modelPath, err := paths.ModelDir(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(paths.TorrentPath(name)); err == nil {
+		return fmt.Errorf("it already has a torrent")
+	}
+	if d.torrentManager != nil {
+		for _, mt := range d.torrentManager.GetAllTorrents() {
+			if mt.Name == name || filepath.Clean(mt.StoragePath) == modelPath {
+				return fmt.Errorf("it is already served by torrent %s", mt.InfoHash)
+			}
+		}
+	}
+	if d.transferManager != nil {
+		for _, t := range d.transferManager.GetAllTransfers() {
+			if t.ModelName == name && t.Type == TransferTypeDownload {
+				return fmt.Errorf("it was downloaded by transfer %s", t.ID)
+			}
+		}
+	}
+	if d.publisher == nil {
+		return fmt.Errorf("publishing is not available")
+	}
+	for _, job := range d.publisher.Jobs() {
+		if job.Model == name && (job.Status == publish.StatusQueued || job.Status == publish.StatusRunning) {
+			return fmt.Errorf("it is being published by job %s", job.ID)
+		}
+	}
+
+	// The publisher completes the manifest, leave the registry's alone
+	m := *manifest
+	job := d.StartPublish(publish.Request{
+		Name:        name,
+		Path:        modelPath,
+		TorrentPath: paths.TorrentPath(name),
+		Manifest:    &m,
+	})
+	fmt.Printf("[Registry] Sharing new model %s (job %s)\n", name, job.ID)
+	return nil
+}
@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoShareModelSkips(t *testing.T) {
+	d := newRenameTestDaemon(t)
+
+	unlicensed := writeTestModel(t, "org/unlicensed")
+	for _, name := range []string{"org/torrent", "org/downloaded"} {
+		modelDir := writeTestModel(t, name)
+		require.NoError(t, models.WriteManifest(modelDir, &types.ModelManifest{Name: name, License: "mit"}))
+	}
+	torrentPath := filepath.Join(storage.GetTorrentsDir(), "org", "torrent.torrent")
+	require.NoError(t, os.MkdirAll(filepath.Dir(torrentPath), 0755))
+	require.NoError(t, os.WriteFile(torrentPath, []byte("d4:infoe"), 0644))
+	download := d.transferManager.CreateDownload("org/downloaded", "abcd", 0)
+
+	var err error
+	d.registry, err = newRegistry(nil)
+	require.NoError(t, err)
+
+	err = d.autoShareModel("org/unlicensed")
+	assert.ErrorContains(t, err, "no license")
+	assert.DirExists(t, unlicensed)
+
+	err = d.autoShareModel("org/torrent")
+	assert.ErrorContains(t, err, "already has a torrent")
+
+	err = d.autoShareModel("org/downloaded")
+	assert.ErrorContains(t, err, download.ID)
+
+	err = d.autoShareModel("org/missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/silmaril/silmaril/internal/discovery"
+)
+
+// liveInfoHashes returns the infohashes of all models we still share. Their
+// catalog entries are renewed by the catalog refresh worker; entries nobody
+// renews expire after discovery.CatalogEntryTTL.
+func (dm *DHTManager) liveInfoHashes() map[string]bool {
+	live := make(map[string]bool)
+
+	dm.mu.RLock()
+	for infoHash := range dm.announcements {
+		live[infoHash] = true
+	}
+	dm.mu.RUnlock()
+
+	if dm.torrentManager != nil {
+		for _, mt := range dm.torrentManager.GetSeedingModels() {
+			live[mt.InfoHash] = true
+		}
+	}
+	return live
+}
+
+// UnpublishModel withdraws a model we published from the public catalog, our
+// publisher catalog and the catalogs of the networks we publish to with a
+// tombstone signed by our publisher key. The model keeps seeding, it is only
+// no longer discoverable.
+func (dm *DHTManager) UnpublishModel(name string) error {
+	dm.mu.RLock()
+	catalogRef := dm.catalogRef
+	publisherRef := dm.publisherRef
+	privateKey := dm.publisherKey
+	publisherID := dm.publisherID
+	dm.mu.RUnlock()
+
+	if catalogRef == nil {
+		return fmt.Errorf("catalog not available")
+	}
+	if privateKey == nil {
+		return fmt.Errorf("publisher key not available")
+	}
+
+	entry, exists := catalogRef.FindModel(name)
+	if !exists {
+		return fmt.Errorf("model %s not found in catalog", name)
+	}
+	// Entries published before a key rotation are withdrawn with the new key
+	if !catalogRef.PublishedBy(entry, publisherID) {
+		return fmt.Errorf("model %s was not published by this node", name)
+	}
+
+	tombstone := discovery.NewTombstone(name, entry.InfoHash, privateKey)
+	if err := catalogRef.Unpublish(name, tombstone); err != nil {
+		return err
+	}
+
+	if publisherRef != nil {
+		if err := publisherRef.Unpublish(name, tombstone); err != nil {
+			fmt.Printf("[DHT] Failed to unpublish %s from publisher catalog: %v\n", name, err)
+		}
+	}
+	dm.unpublishFromNetworks(name, tombstone)
+
+	// Stop re-announcing the model
+	dm.mu.Lock()
+	delete(dm.announcements, entry.InfoHash)
+	delete(dm.lastAnnounce, entry.InfoHash)
+	dm.mu.Unlock()
+
+	// Spread the tombstone to peers holding the old entry
+	if dm.torrentManager != nil && dm.torrentManager.gossip != nil {
+		go dm.torrentManager.gossip.Broadcast()
+	}
+
+	fmt.Printf("[DHT] Unpublished model %s\n", name)
+	return nil
+}
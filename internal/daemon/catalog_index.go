@@ -0,0 +1,288 @@
+package daemon
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+const (
+	// How long the catalog index is used before it is built again. The
+	// catalog itself is refreshed from the DHT by the catalog refresh worker.
+	catalogIndexTTL = 30 * time.Second
+
+	// Page sizes of catalog browsing
+	defaultCatalogPageSize = 50
+	maxCatalogPageSize     = 500
+)
+
+// ErrInvalidCursor is returned for catalog cursors that weren't returned
+// with a page
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Orders the catalog can be browsed in
+const (
+	CatalogSortAdded   = "added"
+	CatalogSortSize    = "size"
+	CatalogSortSeeders = "seeders" // Swarms probed in the last few minutes, 0 for others
+	CatalogSortName    = "name"
+)
+
+// CatalogQuery selects a page of the catalog
+type CatalogQuery struct {
+	Filter types.SearchFilter // Pattern, tag, size and license filters
+	Sort   string
+	Desc   bool
+	Limit  int
+	Offset int
+	Cursor string // Continues after the last model of a previous page, overrides Offset
+}
+
+// CatalogPage is a page of the catalog
+type CatalogPage struct {
+	Models     []*types.ModelAnnouncement `json:"models"`
+	Total      int                        `json:"total"` // Models matching the filters
+	Offset     int                        `json:"offset"`
+	Limit      int                        `json:"limit"`
+	NextCursor string                     `json:"next_cursor,omitempty"`
+	Sort       string                     `json:"sort"`
+	Order      string                     `json:"order"`
+	IndexedAt  time.Time                  `json:"indexed_at"`
+}
+
+// catalogCursor is the position after the last model of a page. Cursors
+// hold the sort key rather than an offset, so pages don't skip or repeat
+// models when the catalog changes between them.
+type catalogCursor struct {
+	Sort     string `json:"s"`
+	Desc     bool   `json:"d"`
+	Key      int64  `json:"k,omitempty"`
+	Name     string `json:"n"`
+	InfoHash string `json:"h"`
+}
+
+// ParseCatalogSort validates a sort order, empty for the default, and
+// returns it with the direction it is browsed in by default: newest,
+// largest and best seeded first, names alphabetically
+func ParseCatalogSort(s string) (string, bool, error) {
+	switch s {
+	case "", CatalogSortAdded:
+		return CatalogSortAdded, true, nil
+	case CatalogSortSize, CatalogSortSeeders:
+		return s, true, nil
+	case CatalogSortName:
+		return s, false, nil
+	}
+	return "", false, fmt.Errorf("unknown sort %q, use added, size, seeders or name", s)
+}
+
+// catalogIndex holds the models of the catalog and the subscribed publisher
+// catalogs, sorted in each order as it is first browsed
+type catalogIndex struct {
+	mu      sync.Mutex
+	models  []*types.ModelAnnouncement
+	sorted  map[string][]*types.ModelAnnouncement // By sort and direction
+	builtAt time.Time
+}
+
+func newCatalogIndex(models []*types.ModelAnnouncement, builtAt time.Time) *catalogIndex {
+	return &catalogIndex{
+		models:  models,
+		sorted:  make(map[string][]*types.ModelAnnouncement),
+		builtAt: builtAt,
+	}
+}
+
+// catalogKey returns the sort key of a model, names are compared apart
+func catalogKey(model *types.ModelAnnouncement, sortBy string) int64 {
+	switch sortBy {
+	case CatalogSortAdded:
+		return model.Time
+	case CatalogSortSize:
+		return model.Size
+	case CatalogSortSeeders:
+		return int64(model.Seeders)
+	}
+	return 0
+}
+
+// catalogBefore reports whether a model at (key, name, infoHash) comes
+// before another in a sort order. Name and infohash break ties, so the
+// order is total and cursors are unambiguous.
+func catalogBefore(key int64, name, infoHash string, otherKey int64, otherName, otherInfoHash string, desc bool) bool {
+	if key != otherKey {
+		return (key < otherKey) != desc
+	}
+	if name != otherName {
+		return (name < otherName) != desc
+	}
+	return infoHash != otherInfoHash && (infoHash < otherInfoHash) != desc
+}
+
+// order returns the models in a sort order
+func (idx *catalogIndex) order(sortBy string, desc bool) []*types.ModelAnnouncement {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	key := fmt.Sprintf("%s:%t", sortBy, desc)
+	if sorted, ok := idx.sorted[key]; ok {
+		return sorted
+	}
+	sorted := make([]*types.ModelAnnouncement, len(idx.models))
+	copy(sorted, idx.models)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		return catalogBefore(catalogKey(a, sortBy), a.Name, a.InfoHash, catalogKey(b, sortBy), b.Name, b.InfoHash, desc)
+	})
+	idx.sorted[key] = sorted
+	return sorted
+}
+
+// page returns the models matching the query filters in its order, from
+// its cursor or offset
+func (idx *catalogIndex) page(q CatalogQuery) (*CatalogPage, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultCatalogPageSize
+	}
+	q.Limit = min(q.Limit, maxCatalogPageSize)
+	if q.Offset < 0 {
+		return nil, errors.New("offset must not be negative")
+	}
+
+	var after *catalogCursor
+	if q.Cursor != "" {
+		cursor, err := decodeCatalogCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		// The cursor decides the order it continues in
+		q.Sort, q.Desc = cursor.Sort, cursor.Desc
+		after = cursor
+	}
+
+	page := &CatalogPage{
+		Models:    []*types.ModelAnnouncement{},
+		Limit:     q.Limit,
+		Sort:      q.Sort,
+		Order:     "asc",
+		IndexedAt: idx.builtAt,
+	}
+	if q.Desc {
+		page.Order = "desc"
+	}
+
+	position := 0
+	for _, model := range idx.order(q.Sort, q.Desc) {
+		if !q.Filter.Matches(model) || (q.Filter.Network != "" && !model.InNetwork(q.Filter.Network)) {
+			continue
+		}
+		page.Total++
+		if after != nil {
+			if !catalogBefore(after.Key, after.Name, after.InfoHash, catalogKey(model, q.Sort), model.Name, model.InfoHash, q.Desc) {
+				position++
+				continue
+			}
+		} else if position < q.Offset {
+			position++
+			continue
+		}
+		if len(page.Models) < q.Limit {
+			page.Models = append(page.Models, model)
+		}
+	}
+	page.Offset = position
+
+	if len(page.Models) > 0 && page.Offset+len(page.Models) < page.Total {
+		last := page.Models[len(page.Models)-1]
+		page.NextCursor = encodeCatalogCursor(catalogCursor{
+			Sort:     q.Sort,
+			Desc:     q.Desc,
+			Key:      catalogKey(last, q.Sort),
+			Name:     last.Name,
+			InfoHash: last.InfoHash,
+		})
+	}
+	return page, nil
+}
+
+func encodeCatalogCursor(cursor catalogCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCatalogCursor(s string) (*catalogCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor catalogCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if _, _, err := ParseCatalogSort(cursor.Sort); err != nil || cursor.Sort == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// BrowseCatalog returns a page of the models in the catalog, the catalogs
+// of the other networks and the subscribed publisher catalogs. Pages come from an index of the local
+// catalogs that is kept for catalogIndexTTL, so browsing doesn't wait for
+// the DHT; seeder counts are those of swarms probed recently.
+func (dm *DHTManager) BrowseCatalog(q CatalogQuery) (*CatalogPage, error) {
+	if q.Filter.Network != "" {
+		if err := dm.checkNetwork(q.Filter.Network); err != nil {
+			return nil, err
+		}
+	}
+	idx, err := dm.currentCatalogIndex()
+	if err != nil {
+		return nil, err
+	}
+	return idx.page(q)
+}
+
+// resetCatalogIndex makes the next page build the catalog index again
+func (dm *DHTManager) resetCatalogIndex() {
+	dm.indexMu.Lock()
+	defer dm.indexMu.Unlock()
+	dm.catalogIndex = nil
+}
+
+// currentCatalogIndex returns the catalog index, building it if it is
+// missing or stale
+func (dm *DHTManager) currentCatalogIndex() (*catalogIndex, error) {
+	dm.indexMu.Lock()
+	defer dm.indexMu.Unlock()
+
+	if dm.catalogIndex != nil && time.Since(dm.catalogIndex.builtAt) < catalogIndexTTL {
+		return dm.catalogIndex, nil
+	}
+	if dm.catalogRef == nil {
+		return nil, fmt.Errorf("catalog not available")
+	}
+	models, err := dm.catalogRef.SearchLocalModels(types.SearchFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+	models = dm.mergeNetworkResults(models, types.SearchFilter{})
+	models = dm.mergeSubscribedResults(models, types.SearchFilter{})
+	models = filterBlockedModels(models, dm.blockedModels())
+	dm.annotateCachedVerified(models)
+	for _, model := range models {
+		if health, ok := dm.healthCache.Get(model.InfoHash); ok {
+			model.Seeders = health.Seeders
+			model.Leechers = health.Leechers
+			model.Availability = health.Availability
+		}
+	}
+
+	dm.catalogIndex = newCatalogIndex(models, time.Now())
+	return dm.catalogIndex, nil
+}
@@ -0,0 +1,92 @@
+package daemon
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCatalogIndex() *catalogIndex {
+	var models []*types.ModelAnnouncement
+	for i := 0; i < 7; i++ {
+		models = append(models, &types.ModelAnnouncement{
+			Name:     fmt.Sprintf("org/model-%d", i),
+			InfoHash: fmt.Sprintf("%040d", i),
+			Size:     int64(i%3) << 30, // Sizes repeat, the name breaks ties
+			Time:     int64(1000 + i),
+			Tags:     []string{map[bool]string{true: "even", false: "odd"}[i%2 == 0]},
+		})
+	}
+	return newCatalogIndex(models, time.Now())
+}
+
+func pageNames(page *CatalogPage) []string {
+	names := make([]string, len(page.Models))
+	for i, model := range page.Models {
+		names[i] = model.Name
+	}
+	return names
+}
+
+func TestCatalogIndexPage(t *testing.T) {
+	idx := newTestCatalogIndex()
+
+	// Newest first by default
+	sortBy, desc, err := ParseCatalogSort("")
+	require.NoError(t, err)
+	page, err := idx.page(CatalogQuery{Sort: sortBy, Desc: desc, Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"org/model-6", "org/model-5", "org/model-4"}, pageNames(page))
+	assert.Equal(t, 7, page.Total)
+	assert.Equal(t, "desc", page.Order)
+
+	page, err = idx.page(CatalogQuery{Sort: CatalogSortName, Limit: 3, Offset: 5})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"org/model-5", "org/model-6"}, pageNames(page))
+	assert.Equal(t, 5, page.Offset)
+	assert.Empty(t, page.NextCursor, "last page")
+
+	// Filters apply before paging
+	page, err = idx.page(CatalogQuery{Sort: CatalogSortName, Filter: types.SearchFilter{Tags: []string{"odd"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"org/model-1", "org/model-3", "org/model-5"}, pageNames(page))
+	assert.Equal(t, 3, page.Total)
+
+	_, _, err = ParseCatalogSort("popularity")
+	assert.Error(t, err)
+}
+
+func TestCatalogIndexCursor(t *testing.T) {
+	idx := newTestCatalogIndex()
+
+	// Walking the pages of the size order visits every model once
+	var names []string
+	query := CatalogQuery{Sort: CatalogSortSize, Desc: true, Limit: 2}
+	for {
+		page, err := idx.page(query)
+		require.NoError(t, err)
+		assert.Equal(t, len(names), page.Offset)
+		names = append(names, pageNames(page)...)
+		if page.NextCursor == "" {
+			break
+		}
+		query = CatalogQuery{Cursor: page.NextCursor, Limit: 2}
+	}
+	assert.Equal(t, []string{"org/model-5", "org/model-2", "org/model-4", "org/model-1", "org/model-6", "org/model-3", "org/model-0"}, names)
+
+	// A model added between pages doesn't shift the next page
+	page, err := idx.page(CatalogQuery{Sort: CatalogSortName, Limit: 2})
+	require.NoError(t, err)
+	idx = newCatalogIndex(append(idx.models, &types.ModelAnnouncement{Name: "org/a-new-model", InfoHash: "new"}), time.Now())
+	page, err = idx.page(CatalogQuery{Cursor: page.NextCursor, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"org/model-2", "org/model-3"}, pageNames(page))
+	assert.Equal(t, 3, page.Offset)
+
+	_, err = idx.page(CatalogQuery{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
@@ -0,0 +1,280 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// PublicNetwork is the name of the public catalog network
+const PublicNetwork = "public"
+
+// ErrUnknownNetwork is returned when searching a catalog network the daemon
+// didn't join
+var ErrUnknownNetwork = errors.New("unknown catalog network")
+
+// networkNamePattern matches the names of catalog networks, which name the
+// directories their catalogs are cached in
+var networkNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// CatalogNetwork describes a catalog network the daemon joined
+type CatalogNetwork struct {
+	Name      string `json:"name"`
+	PublicKey string `json:"public_key"` // Key the catalog is published under
+	ReadOnly  bool   `json:"read_only"`  // Followed by its public key, we don't publish to it
+	Loaded    bool   `json:"loaded"`
+	Models    int    `json:"models"`
+}
+
+// networkCatalogDir returns where the catalog of a network is cached
+func networkCatalogDir(name string) string {
+	return filepath.Join(storage.GetBaseDir(), "networks", name)
+}
+
+// initNetworkCatalogs joins the catalog networks in the config and adds the
+// models we share to the ones we publish to. It must run after DHT
+// bootstrap, networks only change on a restart.
+func (dm *DHTManager) initNetworkCatalogs() {
+	if dm.config == nil || len(dm.config.Catalogs) == 0 || dm.torrentClient == nil {
+		return
+	}
+
+	names := make([]string, 0, len(dm.config.Catalogs))
+	for name := range dm.config.Catalogs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		dm.joinNetwork(name, dm.config.Catalogs[name])
+	}
+
+	dm.mu.RLock()
+	announcements := make([]*types.ModelAnnouncement, 0, len(dm.announcements))
+	for _, ann := range dm.announcements {
+		announcements = append(announcements, ann)
+	}
+	dm.mu.RUnlock()
+
+	for _, ann := range announcements {
+		dm.addToNetworkCatalogs(ann)
+	}
+}
+
+// joinNetwork loads the catalog of a network, by its seed or its public key
+func (dm *DHTManager) joinNetwork(name string, cfg config.CatalogConfig) {
+	if name == PublicNetwork || !networkNamePattern.MatchString(name) {
+		fmt.Printf("[DHT] Skipping catalog network %q: invalid name\n", name)
+		return
+	}
+
+	var ref *discovery.BEP44CatalogRef
+	var err error
+	switch {
+	case cfg.Seed != "" && cfg.PublicKey != "":
+		err = fmt.Errorf("set either a seed or a public_key")
+	case cfg.Seed != "":
+		fmt.Printf("[DHT] Joining catalog network %s\n", name)
+		ref, err = discovery.NewNetworkCatalogRef(dm.dhtServer, dm.torrentClient, cfg.Seed, networkCatalogDir(name))
+	case cfg.PublicKey != "":
+		var key [32]byte
+		if key, err = discovery.ParsePublisherKey(cfg.PublicKey); err == nil {
+			fmt.Printf("[DHT] Following catalog network %s\n", name)
+			ref, err = discovery.NewSubscribedCatalogRef(dm.dhtServer, dm.torrentClient, key, networkCatalogDir(name))
+		}
+	default:
+		err = fmt.Errorf("no seed or public_key")
+	}
+	if err != nil {
+		fmt.Printf("[DHT] Failed to join catalog network %s: %v\n", name, err)
+		return
+	}
+
+	dm.mu.Lock()
+	dm.networks[name] = ref
+	dm.mu.Unlock()
+}
+
+// networkRefs returns the catalogs of the networks joined, by name
+func (dm *DHTManager) networkRefs() map[string]*discovery.BEP44CatalogRef {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	refs := make(map[string]*discovery.BEP44CatalogRef, len(dm.networks))
+	for name, ref := range dm.networks {
+		refs[name] = ref
+	}
+	return refs
+}
+
+// addToNetworkCatalogs adds a shared model to the catalogs of the networks
+// we publish to
+func (dm *DHTManager) addToNetworkCatalogs(ann *types.ModelAnnouncement) {
+	dm.mu.RLock()
+	publisherKey := dm.publisherKey
+	dm.mu.RUnlock()
+
+	for name, ref := range dm.networkRefs() {
+		if ref.ReadOnly() || ref.IsWithdrawn(ann.Name, ann.InfoHash) {
+			continue
+		}
+		if err := ref.AddModelWithMetadata(ann.Name, ann.InfoHash, ann.Size, announcementMetadata(ann, publisherKey)); err != nil {
+			fmt.Printf("[DHT] Failed to add %s to catalog network %s: %v\n", ann.Name, name, err)
+		}
+	}
+}
+
+// unpublishFromNetworks withdraws a model from the catalogs of the networks
+// we publish to
+func (dm *DHTManager) unpublishFromNetworks(name string, tombstone discovery.Tombstone) {
+	for network, ref := range dm.networkRefs() {
+		if ref.ReadOnly() {
+			continue
+		}
+		if _, exists := ref.FindModel(name); !exists {
+			continue
+		}
+		if err := ref.Unpublish(name, tombstone); err != nil {
+			fmt.Printf("[DHT] Failed to unpublish %s from catalog network %s: %v\n", name, network, err)
+		}
+	}
+}
+
+// refreshNetworkCatalogs keeps the catalogs of the networks we publish to
+// alive like the public catalog, and fetches updates of the others
+func (dm *DHTManager) refreshNetworkCatalogs() {
+	for name, ref := range dm.networkRefs() {
+		if err := ref.RefreshCatalog(); err != nil {
+			fmt.Printf("[DHT] Failed to refresh catalog network %s: %v\n", name, err)
+		}
+		if ref.ReadOnly() || ref.ModelCount() == 0 {
+			continue
+		}
+
+		published, err := ref.Cleanup(dm.liveInfoHashes())
+		if err != nil {
+			fmt.Printf("[DHT] Catalog network %s cleanup failed: %v\n", name, err)
+		}
+		if !published {
+			if err := ref.RepublishCatalog(); err != nil {
+				fmt.Printf("[DHT] Failed to republish catalog network %s: %v\n", name, err)
+			}
+		}
+	}
+}
+
+// ListNetworks returns the public network and the catalog networks in the
+// config, including the ones not loaded yet
+func (dm *DHTManager) ListNetworks() []CatalogNetwork {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	public := CatalogNetwork{Name: PublicNetwork, PublicKey: discovery.SeedCatalogKey(discovery.WellKnownSeed)}
+	if dm.catalogRef != nil {
+		public.Loaded = true
+		public.Models = dm.catalogRef.ModelCount()
+	}
+	networks := []CatalogNetwork{public}
+
+	var others []CatalogNetwork
+	if dm.config != nil {
+		for name, cfg := range dm.config.Catalogs {
+			network := CatalogNetwork{Name: name, PublicKey: cfg.PublicKey, ReadOnly: cfg.Seed == ""}
+			if cfg.Seed != "" {
+				network.PublicKey = discovery.SeedCatalogKey(cfg.Seed)
+			}
+			if ref, exists := dm.networks[name]; exists {
+				network.Loaded = true
+				network.Models = ref.ModelCount()
+			}
+			others = append(others, network)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool {
+		return others[i].Name < others[j].Name
+	})
+	return append(networks, others...)
+}
+
+// mergeNetworkResults tags the public results with the public network and
+// adds matching models from the catalogs of the other networks, tagged with
+// every network listing them
+func (dm *DHTManager) mergeNetworkResults(results []*types.ModelAnnouncement, filter types.SearchFilter) []*types.ModelAnnouncement {
+	byHash := make(map[string]*types.ModelAnnouncement, len(results))
+	for _, ann := range results {
+		ann.Networks = append(ann.Networks, PublicNetwork)
+		byHash[ann.InfoHash] = ann
+	}
+
+	refs := dm.networkRefs()
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		models, err := refs[name].SearchLocalModels(filter)
+		if err != nil {
+			fmt.Printf("[DHT] Failed to search catalog network %s: %v\n", name, err)
+			continue
+		}
+		for _, ann := range models {
+			if existing, exists := byHash[ann.InfoHash]; exists {
+				ann = existing
+			} else {
+				byHash[ann.InfoHash] = ann
+				results = append(results, ann)
+			}
+			ann.Networks = append(ann.Networks, name)
+		}
+	}
+
+	return results
+}
+
+// checkNetwork returns ErrUnknownNetwork unless network is the public
+// network or one in the config
+func (dm *DHTManager) checkNetwork(network string) error {
+	if network == PublicNetwork {
+		return nil
+	}
+	if dm.config != nil {
+		if _, exists := dm.config.Catalogs[network]; exists {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownNetwork, network)
+}
+
+// filterByNetwork keeps the models listed in a network
+func (dm *DHTManager) filterByNetwork(models []*types.ModelAnnouncement, network string) ([]*types.ModelAnnouncement, error) {
+	if err := dm.checkNetwork(network); err != nil {
+		return nil, err
+	}
+
+	var results []*types.ModelAnnouncement
+	for _, model := range models {
+		if model.InNetwork(network) {
+			results = append(results, model)
+		}
+	}
+	return results, nil
+}
+
+// closeNetworkCatalogs stops the catalog references of all networks
+func (dm *DHTManager) closeNetworkCatalogs() {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	for _, ref := range dm.networks {
+		ref.Close()
+	}
+}
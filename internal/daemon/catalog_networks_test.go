@@ -0,0 +1,57 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogNetworks(t *testing.T) {
+	followed := discovery.SeedCatalogKey("research")
+	dm := &DHTManager{
+		config: &config.Config{Catalogs: map[string]config.CatalogConfig{
+			"research": {PublicKey: followed},
+			"acme":     {Seed: "acme-models-2026"},
+		}},
+		networks: make(map[string]*discovery.BEP44CatalogRef),
+	}
+
+	networks := dm.ListNetworks()
+	require.Len(t, networks, 3)
+	assert.Equal(t, PublicNetwork, networks[0].Name)
+	assert.Equal(t, discovery.SeedCatalogKey(discovery.WellKnownSeed), networks[0].PublicKey)
+	assert.Equal(t, "acme", networks[1].Name)
+	assert.Equal(t, discovery.SeedCatalogKey("acme-models-2026"), networks[1].PublicKey)
+	assert.False(t, networks[1].ReadOnly)
+	assert.Equal(t, "research", networks[2].Name)
+	assert.Equal(t, followed, networks[2].PublicKey)
+	assert.True(t, networks[2].ReadOnly)
+	for _, network := range networks {
+		assert.False(t, network.Loaded)
+	}
+
+	// Public results are tagged with the public network
+	results := dm.mergeNetworkResults([]*types.ModelAnnouncement{
+		{Name: "org/model", InfoHash: "aaaa"},
+	}, types.SearchFilter{})
+	require.Len(t, results, 1)
+	assert.Equal(t, []string{PublicNetwork}, results[0].Networks)
+
+	results = append(results, &types.ModelAnnouncement{Name: "acme/internal", InfoHash: "bbbb", Networks: []string{"acme"}})
+	filtered, err := dm.filterByNetwork(results, "acme")
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "acme/internal", filtered[0].Name)
+
+	filtered, err = dm.filterByNetwork(results, PublicNetwork)
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "org/model", filtered[0].Name)
+
+	_, err = dm.filterByNetwork(results, "unknown")
+	assert.ErrorIs(t, err, ErrUnknownNetwork)
+}
@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/silmaril/silmaril/internal/discovery"
+)
+
+// ErrCatalogUnavailable is returned while the catalog isn't set up yet
+var ErrCatalogUnavailable = errors.New("catalog not available yet, the DHT is starting")
+
+// CatalogImport is the outcome of importing a catalog snapshot
+type CatalogImport struct {
+	Models  int  `json:"models"`  // Entries in the snapshot
+	Changed bool `json:"changed"` // Whether the snapshot added to our catalog
+	Total   int  `json:"total"`   // Entries in our catalog after the import
+}
+
+// ExportCatalog returns a snapshot of the public catalog
+func (d *Daemon) ExportCatalog() (*discovery.CatalogSnapshot, error) {
+	ref := d.publicCatalog()
+	if ref == nil {
+		return nil, ErrCatalogUnavailable
+	}
+	return ref.ExportSnapshot(), nil
+}
+
+// ImportCatalog merges a catalog snapshot into the public catalog, as if a
+// peer had gossiped it, and publishes the merged catalog
+func (d *Daemon) ImportCatalog(snapshot *discovery.CatalogSnapshot) (*CatalogImport, error) {
+	ref := d.publicCatalog()
+	if ref == nil {
+		return nil, ErrCatalogUnavailable
+	}
+	changed, err := ref.ImportSnapshot(snapshot)
+	if err != nil && !changed {
+		return nil, fmt.Errorf("failed to import catalog snapshot: %w", err)
+	}
+	if err != nil {
+		// Merged locally, the next refresh publishes it
+		fmt.Printf("[Catalog] Failed to publish the imported catalog: %v\n", err)
+	}
+	fmt.Printf("[Catalog] Imported a snapshot of %d models exported %s\n", len(snapshot.Catalog.Models), snapshot.Exported.Format("2006-01-02 15:04"))
+
+	if changed {
+		d.checkCatalogUpdates()
+	}
+	return &CatalogImport{
+		Models:  len(snapshot.Catalog.Models),
+		Changed: changed,
+		Total:   ref.ModelCount(),
+	}, nil
+}
+
+// publicCatalog returns the public catalog, nil until the DHT manager has
+// set it up
+func (d *Daemon) publicCatalog() *discovery.BEP44CatalogRef {
+	if d.dhtManager == nil {
+		return nil
+	}
+	return d.dhtManager.GetCatalogRef()
+}
@@ -0,0 +1,153 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// How often the catalog is refreshed while a client watches it
+const catalogWatchInterval = time.Minute
+
+// How long a watch lasts unless the client renews it
+const catalogWatchLease = 3 * time.Minute
+
+// catalogWatch refreshes the catalog often while clients wait for models to
+// appear, and publishes an event for every new entry
+type catalogWatch struct {
+	mu      sync.Mutex
+	until   time.Time
+	known   map[string]bool // Entries seen, nil until the first refresh of a watch
+	checkMu sync.Mutex      // Serializes refreshes
+
+	// Entries seen by the periodic catalog refreshes, nil until the first
+	refreshed map[string]bool
+}
+
+// catalogEntryKey identifies a catalog entry, a new version of a model is a
+// new entry
+func catalogEntryKey(model *types.ModelAnnouncement) string {
+	return model.Name + "@" + model.InfoHash
+}
+
+// newCatalogEntries returns the models missing from known and adds them. A
+// nil known is filled without returning anything, the first refresh only
+// learns what is already there.
+func newCatalogEntries(known map[string]bool, models []*types.ModelAnnouncement) (map[string]bool, []*types.ModelAnnouncement) {
+	if known == nil {
+		known = make(map[string]bool, len(models))
+		for _, model := range models {
+			known[catalogEntryKey(model)] = true
+		}
+		return known, nil
+	}
+	var added []*types.ModelAnnouncement
+	for _, model := range models {
+		key := catalogEntryKey(model)
+		if !known[key] {
+			known[key] = true
+			added = append(added, model)
+		}
+	}
+	return known, added
+}
+
+// WatchCatalog refreshes the catalog every catalogWatchInterval until the
+// returned time and publishes a catalog.added event for each model that
+// appears. Clients renew the watch by calling it again.
+func (d *Daemon) WatchCatalog() time.Time {
+	d.catalogWatch.mu.Lock()
+	now := time.Now()
+	starting := !now.Before(d.catalogWatch.until)
+	if starting {
+		// Forget the entries of an earlier watch, models that appeared
+		// since then aren't new to this one
+		d.catalogWatch.known = nil
+	}
+	d.catalogWatch.until = now.Add(catalogWatchLease)
+	until := d.catalogWatch.until
+	d.catalogWatch.mu.Unlock()
+
+	if starting {
+		fmt.Println("[CatalogWatch] Watching the catalog for new models")
+		go d.checkCatalog()
+	}
+	return until
+}
+
+// checkCatalog refreshes the catalog and publishes the new entries
+func (d *Daemon) checkCatalog() {
+	if d.dhtManager == nil {
+		return
+	}
+	d.catalogWatch.checkMu.Lock()
+	defer d.catalogWatch.checkMu.Unlock()
+
+	models, err := d.dhtManager.SearchModels(types.SearchFilter{Pattern: "*"})
+	if err != nil {
+		fmt.Printf("[CatalogWatch] Failed to refresh the catalog: %v\n", err)
+		return
+	}
+
+	d.catalogWatch.mu.Lock()
+	var added []*types.ModelAnnouncement
+	d.catalogWatch.known, added = newCatalogEntries(d.catalogWatch.known, models)
+	d.catalogWatch.mu.Unlock()
+
+	for _, model := range added {
+		d.events.Publish(EventCatalogAdded, model.Name, "version %s appeared in the catalog (infohash %s, %.2f GB)",
+			displayVersion(model.Version), model.InfoHash, float64(model.Size)/(1024*1024*1024))
+	}
+}
+
+// checkCatalogUpdates publishes a catalog.updated event listing the entries
+// that appeared in the catalog since the last periodic refresh
+func (d *Daemon) checkCatalogUpdates() {
+	if d.dhtManager == nil {
+		return
+	}
+	models, err := d.dhtManager.SearchModels(types.SearchFilter{Pattern: "*"})
+	if err != nil {
+		fmt.Printf("[Daemon] Failed to read the catalog: %v\n", err)
+		return
+	}
+
+	d.catalogWatch.mu.Lock()
+	var added []*types.ModelAnnouncement
+	d.catalogWatch.refreshed, added = newCatalogEntries(d.catalogWatch.refreshed, models)
+	d.catalogWatch.mu.Unlock()
+
+	if len(added) == 0 {
+		return
+	}
+	entries := make([]string, len(added))
+	for i, model := range added {
+		entries[i] = model.Name + " " + displayVersion(model.Version)
+	}
+	d.events.Publish(EventCatalogUpdated, "", "%d new catalog entries: %s", len(added), strings.Join(entries, ", "))
+}
+
+// catalogWatchWorker refreshes the catalog while a client watches it
+func (d *Daemon) catalogWatchWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(catalogWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.catalogWatch.mu.Lock()
+			watching := time.Now().Before(d.catalogWatch.until)
+			d.catalogWatch.mu.Unlock()
+			if watching {
+				d.checkCatalog()
+			}
+		}
+	}
+}
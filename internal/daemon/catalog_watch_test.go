@@ -0,0 +1,28 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCatalogEntries(t *testing.T) {
+	llama := &types.ModelAnnouncement{Name: "meta/llama", InfoHash: "aa"}
+	mistral := &types.ModelAnnouncement{Name: "mistral/7b", InfoHash: "bb"}
+
+	// The first refresh only learns the catalog
+	known, added := newCatalogEntries(nil, []*types.ModelAnnouncement{llama})
+	assert.Empty(t, added)
+
+	known, added = newCatalogEntries(known, []*types.ModelAnnouncement{llama, mistral})
+	assert.Equal(t, []*types.ModelAnnouncement{mistral}, added)
+
+	// A new version of a known model is new
+	release := &types.ModelAnnouncement{Name: "meta/llama", InfoHash: "cc", Version: "2"}
+	known, added = newCatalogEntries(known, []*types.ModelAnnouncement{llama, mistral, release})
+	assert.Equal(t, []*types.ModelAnnouncement{release}, added)
+
+	_, added = newCatalogEntries(known, []*types.ModelAnnouncement{llama, mistral, release})
+	assert.Empty(t, added)
+}
@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/config"
+)
+
+// watchConfig applies changes to the config file while the daemon runs
+func (d *Daemon) watchConfig() {
+	err := config.Watch(d.ctx, func(change config.ConfigChange) {
+		fmt.Println("[Config] Config file changed")
+		d.applyConfig(change)
+	})
+	if err != nil {
+		fmt.Printf("[Config] Not watching config file: %v\n", err)
+	}
+}
+
+// UpdateConfig validates and applies runtime config changes. Keys that need a
+// restart are accepted but only reported.
+func (d *Daemon) UpdateConfig(changes map[string]interface{}) (config.ConfigChange, error) {
+	change, err := config.Update(changes)
+	if err != nil {
+		return change, err
+	}
+	d.applyConfig(change)
+	return change, nil
+}
+
+// applyConfig pushes live settings to the running components
+func (d *Daemon) applyConfig(change config.ConfigChange) {
+	for _, key := range change.Applied {
+		switch key {
+		case "network.upload_rate_limit", "network.download_rate_limit":
+			if d.torrentManager != nil {
+				d.torrentManager.SetRateLimits(
+					int64(d.config.GetInt("network.upload_rate_limit")),
+					int64(d.config.GetInt("network.download_rate_limit")))
+			}
+		case "network.max_connections", "network.max_connections_per_torrent":
+			go d.balanceConnections()
+		case "network.catalog_refresh_interval_minutes":
+			// Wake the refresh worker so it picks up the new interval
+			select {
+			case d.configChanged <- struct{}{}:
+			default:
+			}
+		case "network.peer_blocklist", "network.peer_allowlist":
+			d.ReloadPeerFilter(false)
+		case "network.peer_blocklist_url":
+			// Downloading may take a while
+			go d.ReloadPeerFilter(true)
+		case "security.model_blocklist_urls":
+			go d.ReloadModelBlocklist(true)
+		case "network.peer_hints":
+			go d.addHintedPeers()
+		case "network.share_stats":
+			// Publish today's report right away rather than within the hour
+			go d.publishNetworkStats()
+		case "daemon.log_level":
+			SetLogLevel(d.config.GetString("daemon.log_level"))
+		}
+	}
+
+	if len(change.Applied) > 0 {
+		fmt.Printf("[Config] Applied: %s\n", strings.Join(change.Applied, ", "))
+	}
+	if len(change.RestartRequired) > 0 {
+		fmt.Printf("[Config] Restart required for: %s\n", strings.Join(change.RestartRequired, ", "))
+	}
+}
+
+// catalogRefreshInterval returns the configured catalog refresh interval
+func (d *Daemon) catalogRefreshInterval() time.Duration {
+	if d.config != nil {
+		if minutes := d.config.GetInt("network.catalog_refresh_interval_minutes"); minutes > 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+	}
+	return 30 * time.Minute
+}
@@ -0,0 +1,201 @@
+package daemon
+
+import (
+	"sort"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/silmaril/silmaril/internal/config"
+)
+
+// How often network.max_connections is divided between torrents again
+const connectionBalanceInterval = 30 * time.Second
+
+// Connections every torrent may keep while the global cap allows it, so a
+// torrent without known peers can still accept the ones that find it
+const minTorrentConns = 4
+
+// ConnectionLimits are the peer connection limits of the torrent client. The
+// client unchokes every interested peer it is connected to, so the
+// connections of a torrent are its upload slots.
+type ConnectionLimits struct {
+	MaxConnections     int `json:"max_connections"`             // Established, all torrents together
+	PerTorrent         int `json:"max_connections_per_torrent"` // Established, each torrent
+	HalfOpen           int `json:"max_half_open"`               // Connection attempts, all torrents together
+	HalfOpenPerTorrent int `json:"max_half_open_per_torrent"`   // Connection attempts, each torrent
+}
+
+// TorrentConnections are the peer connections of a torrent
+type TorrentConnections struct {
+	Model     string `json:"model"`
+	InfoHash  string `json:"info_hash"`
+	Connected int    `json:"connected"`
+	HalfOpen  int    `json:"half_open"`
+	Known     int    `json:"known"` // Peers known, connected or not
+	Limit     int    `json:"limit"` // Its share of max_connections
+}
+
+// ConnectionStats are the peer connections of all torrents against the limits
+type ConnectionStats struct {
+	Limits    ConnectionLimits     `json:"limits"`
+	Connected int                  `json:"connected"`
+	HalfOpen  int                  `json:"half_open"`
+	Torrents  []TorrentConnections `json:"torrents"`
+}
+
+// connectionLimits reads the connection limits from the network settings
+func connectionLimits(cfg *config.Config) ConnectionLimits {
+	if cfg == nil {
+		defaults := torrent.NewDefaultClientConfig()
+		return ConnectionLimits{
+			MaxConnections:     100,
+			PerTorrent:         defaults.EstablishedConnsPerTorrent,
+			HalfOpen:           defaults.TotalHalfOpenConns,
+			HalfOpenPerTorrent: defaults.HalfOpenConnsPerTorrent,
+		}
+	}
+	return ConnectionLimits{
+		MaxConnections:     cfg.GetInt("network.max_connections"),
+		PerTorrent:         cfg.GetInt("network.max_connections_per_torrent"),
+		HalfOpen:           cfg.GetInt("network.max_half_open"),
+		HalfOpenPerTorrent: cfg.GetInt("network.max_half_open_per_torrent"),
+	}
+}
+
+// applyConnectionLimits sets the limits of the torrent client that only
+// apply when it starts. Established connections are divided between the
+// torrents by balanceConnections.
+func applyConnectionLimits(clientCfg *torrent.ClientConfig, limits ConnectionLimits) {
+	if limits.PerTorrent > 0 {
+		clientCfg.EstablishedConnsPerTorrent = min(limits.PerTorrent, max(limits.MaxConnections, 1))
+	}
+	if limits.HalfOpen > 0 {
+		clientCfg.TotalHalfOpenConns = limits.HalfOpen
+	}
+	if limits.HalfOpenPerTorrent > 0 {
+		clientCfg.HalfOpenConnsPerTorrent = limits.HalfOpenPerTorrent
+	}
+}
+
+// divideConnections gives each torrent a share of the global cap: torrents
+// knowing fewer peers than their equal share get what they can use, the
+// others split the rest. known holds the peers each torrent knows. Every
+// torrent keeps a few connections, at least one, and none gets more than
+// perTorrent.
+func divideConnections(limits ConnectionLimits, known []int) []int {
+	result := make([]int, len(known))
+	if len(known) == 0 {
+		return result
+	}
+
+	floor := max(1, min(minTorrentConns, limits.MaxConnections/len(known)))
+	demands := make([]shareDemand, len(known))
+	for i, peers := range known {
+		demands[i] = shareDemand{weight: 1, demand: int64(max(floor, min(peers, limits.PerTorrent)))}
+	}
+	for i, share := range allocateFairShares(int64(limits.MaxConnections), demands) {
+		result[i] = min(max(int(share), floor), limits.PerTorrent)
+	}
+	return result
+}
+
+// balanceConnections divides network.max_connections between the torrents
+// by the peers they know, within network.max_connections_per_torrent
+func (d *Daemon) balanceConnections() {
+	if d.torrentManager == nil {
+		return
+	}
+	limits := connectionLimits(d.config)
+	if limits.MaxConnections <= 0 || limits.PerTorrent <= 0 {
+		return
+	}
+
+	torrents := d.torrentManager.GetAllTorrents()
+	known := make([]int, len(torrents))
+	for i, mt := range torrents {
+		stats := mt.Torrent.Stats()
+		known[i] = max(stats.TotalPeers, stats.ActivePeers)
+	}
+	for i, limit := range divideConnections(limits, known) {
+		d.torrentManager.SetConnectionLimit(torrents[i].InfoHash, limit)
+	}
+}
+
+// SetConnectionLimit limits the established connections of a torrent. In
+// initial seed mode the torrent keeps its lower limit until it leaves.
+func (tm *TorrentManager) SetConnectionLimit(infoHash string, limit int) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	mt, exists := tm.torrents[infoHash]
+	if !exists || mt.maxConns == limit {
+		return
+	}
+	mt.maxConns = limit
+	if mt.SeedMode == SeedModeInitial {
+		mt.normalMaxConns = limit
+		limit = min(limit, initialSeedMaxConns)
+	}
+	mt.Torrent.SetMaxEstablishedConns(limit)
+}
+
+// ConnectionStats returns the peer connections of each torrent against the
+// connection limits, the most connected first
+func (d *Daemon) ConnectionStats() ConnectionStats {
+	stats := ConnectionStats{Limits: connectionLimits(d.config), Torrents: []TorrentConnections{}}
+	if d.torrentManager == nil {
+		return stats
+	}
+
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		torrentStats := mt.Torrent.Stats()
+		conns := TorrentConnections{
+			Model:     mt.Name,
+			InfoHash:  mt.InfoHash,
+			Connected: torrentStats.ActivePeers,
+			HalfOpen:  torrentStats.HalfOpenPeers,
+			Known:     torrentStats.TotalPeers,
+			Limit:     d.torrentManager.ConnectionLimit(mt.InfoHash),
+		}
+		stats.Connected += conns.Connected
+		stats.HalfOpen += conns.HalfOpen
+		stats.Torrents = append(stats.Torrents, conns)
+	}
+	sort.Slice(stats.Torrents, func(i, j int) bool {
+		if stats.Torrents[i].Connected != stats.Torrents[j].Connected {
+			return stats.Torrents[i].Connected > stats.Torrents[j].Connected
+		}
+		return stats.Torrents[i].Model < stats.Torrents[j].Model
+	})
+	return stats
+}
+
+// ConnectionLimit returns the share of the global connection cap a torrent
+// was given, or the per torrent limit before the first balance
+func (tm *TorrentManager) ConnectionLimit(infoHash string) int {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	if mt, exists := tm.torrents[infoHash]; exists && mt.maxConns > 0 {
+		return mt.maxConns
+	}
+	return connectionLimits(tm.config).PerTorrent
+}
+
+// connectionBalanceWorker divides the connection cap again as torrents find
+// peers or are added
+func (d *Daemon) connectionBalanceWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(connectionBalanceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.balanceConnections()
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/anacrolix/torrent"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDivideConnections(t *testing.T) {
+	limits := ConnectionLimits{MaxConnections: 100, PerTorrent: 50}
+
+	// A busy swarm takes what the quiet ones leave, within the per torrent limit
+	assert.Equal(t, []int{50, 10, 4}, divideConnections(limits, []int{200, 10, 0}))
+
+	// Busy swarms split the cap evenly
+	assert.Equal(t, []int{33, 33, 33}, divideConnections(limits, []int{80, 80, 80}))
+
+	// With more torrents than connections each still keeps one
+	assert.Equal(t, []int{1, 1, 1}, divideConnections(ConnectionLimits{MaxConnections: 2, PerTorrent: 50}, []int{5, 5, 5}))
+
+	assert.Empty(t, divideConnections(limits, nil))
+}
+
+func TestApplyConnectionLimits(t *testing.T) {
+	defaults := connectionLimits(nil)
+	assert.Equal(t, 100, defaults.MaxConnections)
+	assert.Positive(t, defaults.PerTorrent)
+
+	clientCfg := torrent.NewDefaultClientConfig()
+	applyConnectionLimits(clientCfg, ConnectionLimits{MaxConnections: 20, PerTorrent: 50, HalfOpen: 30, HalfOpenPerTorrent: 10})
+	assert.Equal(t, 20, clientCfg.EstablishedConnsPerTorrent, "no torrent may exceed the global cap")
+	assert.Equal(t, 30, clientCfg.TotalHalfOpenConns)
+	assert.Equal(t, 10, clientCfg.HalfOpenConnsPerTorrent)
+
+	// Unset limits keep the client defaults
+	clientCfg = torrent.NewDefaultClientConfig()
+	applyConnectionLimits(clientCfg, ConnectionLimits{})
+	assert.Equal(t, torrent.NewDefaultClientConfig().TotalHalfOpenConns, clientCfg.TotalHalfOpenConns)
+}
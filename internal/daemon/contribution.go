@@ -0,0 +1,216 @@
+package daemon
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"slices"
+	"sort"
+	"time"
+)
+
+// How often the bytes transferred by torrents are added to the contribution
+const contributionInterval = 30 * time.Second
+
+// Days of contribution kept for the stats over time
+const contributionDays = 365
+
+// Peers remembered per model to count unique peers. Past this the count is
+// a lower bound.
+const maxContributionPeers = 4096
+
+// ModelContribution is what was transferred for a model over all sessions,
+// including versions and torrents that are gone
+type ModelContribution struct {
+	Model      string    `json:"model"`
+	Uploaded   int64     `json:"uploaded"`
+	Downloaded int64     `json:"downloaded"`
+	Peers      peerSet   `json:"peers,omitempty"`
+	LastActive time.Time `json:"last_active"`
+}
+
+// DailyContribution is what was transferred on one day
+type DailyContribution struct {
+	Date       string `json:"date"` // 2006-01-02 in local time
+	Uploaded   int64  `json:"uploaded"`
+	Downloaded int64  `json:"downloaded"`
+}
+
+// Contribution accumulates the bytes transferred per model and per day
+type Contribution struct {
+	Uploaded   int64                         `json:"uploaded"`
+	Downloaded int64                         `json:"downloaded"`
+	Peers      peerSet                       `json:"peers,omitempty"`
+	Models     map[string]*ModelContribution `json:"models,omitempty"`
+	Daily      []DailyContribution           `json:"daily,omitempty"` // Oldest first
+}
+
+// ContributionSample is what a torrent transferred since it was last sampled
+type ContributionSample struct {
+	Model      string
+	Uploaded   int64
+	Downloaded int64
+	Peers      []uint64 // Hashes of the connected peers, see peerHash
+}
+
+// peerSet is a sorted set of peer hashes holding at most maxContributionPeers
+type peerSet []uint64
+
+func (p *peerSet) add(peer uint64) {
+	i, found := slices.BinarySearch(*p, peer)
+	if found || len(*p) >= maxContributionPeers {
+		return
+	}
+	*p = slices.Insert(*p, i, peer)
+}
+
+// peerHash identifies a peer by its IP address, which unlike its peer ID
+// usually survives a restart of the peer's client. Only the hash is kept.
+func peerHash(addr fmt.Stringer) (uint64, bool) {
+	if addr == nil {
+		return 0, false
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+	h := fnv.New64a()
+	h.Write([]byte(host))
+	return h.Sum64(), true
+}
+
+// add counts a sample transferred at now
+func (c *Contribution) add(sample ContributionSample, now time.Time) {
+	if sample.Uploaded == 0 && sample.Downloaded == 0 && len(sample.Peers) == 0 {
+		return
+	}
+	if c.Models == nil {
+		c.Models = make(map[string]*ModelContribution)
+	}
+	m, exists := c.Models[sample.Model]
+	if !exists {
+		m = &ModelContribution{Model: sample.Model}
+		c.Models[sample.Model] = m
+	}
+	for _, peer := range sample.Peers {
+		m.Peers.add(peer)
+		c.Peers.add(peer)
+	}
+	if sample.Uploaded == 0 && sample.Downloaded == 0 {
+		return
+	}
+
+	m.Uploaded += sample.Uploaded
+	m.Downloaded += sample.Downloaded
+	m.LastActive = now
+	c.Uploaded += sample.Uploaded
+	c.Downloaded += sample.Downloaded
+
+	date := now.Format("2006-01-02")
+	if n := len(c.Daily); n > 0 && c.Daily[n-1].Date == date {
+		c.Daily[n-1].Uploaded += sample.Uploaded
+		c.Daily[n-1].Downloaded += sample.Downloaded
+		return
+	}
+	c.Daily = append(c.Daily, DailyContribution{Date: date, Uploaded: sample.Uploaded, Downloaded: sample.Downloaded})
+	if len(c.Daily) > contributionDays {
+		c.Daily = slices.Clone(c.Daily[len(c.Daily)-contributionDays:])
+	}
+}
+
+// ContributionStats summarizes what the node gave to and took from the network
+type ContributionStats struct {
+	Uploaded    int64                    `json:"uploaded"`
+	Downloaded  int64                    `json:"downloaded"`
+	Ratio       float64                  `json:"ratio"` // 0 if nothing was downloaded
+	UniquePeers int                      `json:"unique_peers"`
+	Models      []ModelContributionStats `json:"models"` // Most uploaded first
+	Daily       []DailyContribution      `json:"daily"`  // Oldest first
+}
+
+// ModelContributionStats summarizes the contribution for a model
+type ModelContributionStats struct {
+	Model       string    `json:"model"`
+	Uploaded    int64     `json:"uploaded"`
+	Downloaded  int64     `json:"downloaded"`
+	Ratio       float64   `json:"ratio"`
+	UniquePeers int       `json:"unique_peers"`
+	LastActive  time.Time `json:"last_active"`
+}
+
+func shareRatio(uploaded, downloaded int64) float64 {
+	if downloaded <= 0 {
+		return 0
+	}
+	return float64(uploaded) / float64(downloaded)
+}
+
+// stats summarizes the contribution with the last days of daily totals, all
+// of them if days is 0
+func (c *Contribution) stats(days int) ContributionStats {
+	stats := ContributionStats{
+		Uploaded:    c.Uploaded,
+		Downloaded:  c.Downloaded,
+		Ratio:       shareRatio(c.Uploaded, c.Downloaded),
+		UniquePeers: len(c.Peers),
+		Models:      make([]ModelContributionStats, 0, len(c.Models)),
+		Daily:       slices.Clone(c.Daily),
+	}
+	if stats.Daily == nil {
+		stats.Daily = []DailyContribution{}
+	}
+	if days > 0 && len(stats.Daily) > days {
+		stats.Daily = stats.Daily[len(stats.Daily)-days:]
+	}
+	for _, m := range c.Models {
+		stats.Models = append(stats.Models, ModelContributionStats{
+			Model:       m.Model,
+			Uploaded:    m.Uploaded,
+			Downloaded:  m.Downloaded,
+			Ratio:       shareRatio(m.Uploaded, m.Downloaded),
+			UniquePeers: len(m.Peers),
+			LastActive:  m.LastActive,
+		})
+	}
+	sort.Slice(stats.Models, func(i, j int) bool {
+		if stats.Models[i].Uploaded != stats.Models[j].Uploaded {
+			return stats.Models[i].Uploaded > stats.Models[j].Uploaded
+		}
+		return stats.Models[i].Model < stats.Models[j].Model
+	})
+	return stats
+}
+
+// recordContribution adds what the torrents transferred since the last call
+// to the contribution stats
+func (d *Daemon) recordContribution() {
+	if d.torrentManager == nil {
+		return
+	}
+	now := time.Now()
+	for _, sample := range d.torrentManager.SampleContribution() {
+		d.state.AddContribution(sample, now)
+	}
+}
+
+// GetContributionStats returns the upload and download accounting with the
+// last days of daily totals, all kept days if days is 0
+func (d *Daemon) GetContributionStats(days int) ContributionStats {
+	return d.state.GetContribution(days)
+}
+
+func (d *Daemon) contributionWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(contributionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.recordContribution()
+		}
+	}
+}
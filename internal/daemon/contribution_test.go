@@ -0,0 +1,92 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContribution(t *testing.T) {
+	var c Contribution
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+
+	c.add(ContributionSample{Model: "org/a", Uploaded: 300, Downloaded: 100, Peers: []uint64{1, 2}}, day)
+	c.add(ContributionSample{Model: "org/b", Uploaded: 50, Peers: []uint64{2, 3}}, day)
+	c.add(ContributionSample{Model: "org/a", Uploaded: 200, Peers: []uint64{1}}, day.Add(24*time.Hour))
+
+	// Idle torrents count their peers without touching the daily totals
+	c.add(ContributionSample{Model: "org/c", Peers: []uint64{4}}, day.Add(48*time.Hour))
+
+	stats := c.stats(0)
+	assert.Equal(t, int64(550), stats.Uploaded)
+	assert.Equal(t, int64(100), stats.Downloaded)
+	assert.InDelta(t, 5.5, stats.Ratio, 0.001)
+	assert.Equal(t, 4, stats.UniquePeers)
+
+	require.Len(t, stats.Models, 3)
+	assert.Equal(t, "org/a", stats.Models[0].Model, "most uploaded first")
+	assert.Equal(t, int64(500), stats.Models[0].Uploaded)
+	assert.Equal(t, 2, stats.Models[0].UniquePeers)
+	assert.Equal(t, "org/b", stats.Models[1].Model)
+	assert.Zero(t, stats.Models[1].Ratio, "nothing downloaded")
+	assert.True(t, stats.Models[2].LastActive.IsZero())
+
+	require.Len(t, stats.Daily, 2)
+	assert.Equal(t, DailyContribution{Date: "2026-03-01", Uploaded: 350, Downloaded: 100}, stats.Daily[0])
+	assert.Equal(t, DailyContribution{Date: "2026-03-02", Uploaded: 200}, stats.Daily[1])
+	assert.Equal(t, stats.Daily[1:], c.stats(1).Daily)
+}
+
+func TestContributionLimits(t *testing.T) {
+	var c Contribution
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local)
+	for i := 0; i < contributionDays+10; i++ {
+		c.add(ContributionSample{Model: "org/a", Uploaded: 1}, start.AddDate(0, 0, i))
+	}
+	require.Len(t, c.Daily, contributionDays)
+	assert.Equal(t, start.AddDate(0, 0, 10).Format("2006-01-02"), c.Daily[0].Date)
+	assert.Equal(t, int64(contributionDays+10), c.Uploaded, "totals outlive the daily history")
+
+	peers := make([]uint64, maxContributionPeers+100)
+	for i := range peers {
+		peers[i] = uint64(len(peers) - i)
+	}
+	c.add(ContributionSample{Model: "org/a", Peers: peers}, start)
+	assert.Len(t, c.Peers, maxContributionPeers)
+}
+
+func TestContributionPersists(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+
+	s := NewState(filepath.Join(tmpDir, "state.json"))
+	s.AddContribution(ContributionSample{Model: "org/a", Uploaded: 300, Downloaded: 100, Peers: []uint64{7}}, now)
+	require.NoError(t, s.Save())
+
+	s2 := NewState(filepath.Join(tmpDir, "state.json"))
+	require.NoError(t, s2.Load())
+	s2.AddContribution(ContributionSample{Model: "org/a", Uploaded: 200, Peers: []uint64{7}}, now)
+	stats := s2.GetContribution(0)
+	assert.Equal(t, int64(500), stats.Uploaded)
+	require.Len(t, stats.Models, 1)
+	assert.Equal(t, 1, stats.Models[0].UniquePeers, "a peer seen again is not counted twice")
+
+	st, err := store.Open(filepath.Join(tmpDir, "db"))
+	require.NoError(t, err)
+	defer st.Close()
+	s3 := NewState(filepath.Join(tmpDir, "state.json"))
+	s3.UseStore(st)
+	require.NoError(t, s3.Load())
+	assert.Equal(t, int64(300), s3.GetContribution(0).Uploaded, "imported from the state file")
+	s3.AddContribution(ContributionSample{Model: "org/a", Uploaded: 10}, now)
+	require.NoError(t, s3.Save())
+
+	s4 := NewState(filepath.Join(tmpDir, "state.json"))
+	s4.UseStore(st)
+	require.NoError(t, s4.Load())
+	assert.Equal(t, int64(310), s4.GetContribution(0).Uploaded)
+}
@@ -0,0 +1,403 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/publish"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+const (
+	defaultConvertTimeout = 2 * time.Hour
+
+	// Finished conversions kept for clients to look up
+	maxConversions = 100
+)
+
+// Status of a conversion
+const (
+	ConversionQueued    = "queued"
+	ConversionRunning   = "running"
+	ConversionCompleted = "completed"
+	ConversionFailed    = "failed"
+)
+
+// Variants become part of model names
+var variantPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Conversion is a variant of a model made by the converter
+type Conversion struct {
+	ID          string     `json:"id"`
+	Model       string     `json:"model"`
+	Variant     string     `json:"variant"`
+	Derived     string     `json:"derived"` // Name of the derived model
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	Publish     bool       `json:"publish"`
+	PublishJob  string     `json:"publish_job,omitempty"`
+	QueuedAt    time.Time  `json:"queued_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	parentInfoHash string
+}
+
+// converter runs one conversion at a time, converters take all the CPU and
+// memory they get, and keeps the recent conversions
+type converter struct {
+	runMu       sync.Mutex
+	mu          sync.Mutex
+	conversions []*Conversion // Oldest first
+}
+
+// convertSettings is the converter config in effect
+type convertSettings struct {
+	Command  string
+	Variants []string
+	Models   []string
+	Publish  bool
+	Timeout  time.Duration
+	Env      []string // Daemon variables passed on, from hooks.env
+}
+
+// convertConfig returns the converter config and whether models are
+// converted when their download is ready
+func convertConfig(cfg *config.Config, commands hookCommands) (convertSettings, bool) {
+	settings := convertSettings{Command: commands.Converter, Timeout: defaultConvertTimeout, Env: commands.Env}
+	if cfg == nil {
+		return settings, false
+	}
+	settings.Variants = cfg.GetStringSlice("convert.variants")
+	settings.Models = cfg.GetStringSlice("convert.models")
+	settings.Publish = cfg.GetBool("convert.publish")
+	if minutes := cfg.GetInt("convert.timeout_minutes"); minutes > 0 {
+		settings.Timeout = time.Duration(minutes) * time.Minute
+	}
+	return settings, cfg.GetBool("convert.enabled")
+}
+
+// derivedModelName returns the name of the variant of a model
+func derivedModelName(model, variant string) string {
+	return model + "-" + variant + "-GGUF"
+}
+
+// hasSafetensors reports whether the model in dir has safetensors weights
+func hasSafetensors(dir string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.safetensors"))
+	return len(matches) > 0
+}
+
+// ConvertModel queues conversions of a local model into variants, the
+// configured ones if none are given, and publishes the derived models if
+// publish is set
+func (d *Daemon) ConvertModel(name string, variants []string, publishDerived bool) ([]Conversion, error) {
+	settings, _ := convertConfig(d.config, d.commands)
+	if settings.Command == "" {
+		return nil, fmt.Errorf("no converter configured, set convert.command")
+	}
+	name, modelPath, manifest, err := d.localManifest(name)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Provenance.Derived() {
+		return nil, fmt.Errorf("%s is already derived from %s", name, manifest.Provenance.ParentModel)
+	}
+	if !hasSafetensors(modelPath) {
+		return nil, fmt.Errorf("%s has no safetensors weights to convert", name)
+	}
+	if len(variants) == 0 {
+		variants = settings.Variants
+	}
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("no variants given and convert.variants is empty")
+	}
+	for _, variant := range variants {
+		if !variantPattern.MatchString(variant) {
+			return nil, fmt.Errorf("invalid variant %q", variant)
+		}
+	}
+
+	queued := d.queueConversions(name, d.modelInfoHash(name), variants, publishDerived, settings)
+	if len(queued) == 0 {
+		return nil, fmt.Errorf("%s is already being converted into these variants", name)
+	}
+	return queued, nil
+}
+
+// autoConvert converts a downloaded model into the configured variants if
+// conversion is enabled and the model is one to convert
+func (d *Daemon) autoConvert(name, infoHash string) {
+	settings, enabled := convertConfig(d.config, d.commands)
+	if !enabled || settings.Command == "" || len(settings.Variants) == 0 || !matchPinPatterns(settings.Models, name) {
+		return
+	}
+	modelPath, err := modelPathFor(name)
+	if err != nil || !hasSafetensors(modelPath) {
+		return
+	}
+	if manifest, err := models.ReadManifest(modelPath); err == nil && manifest.Provenance.Derived() {
+		return
+	}
+	d.queueConversions(name, infoHash, settings.Variants, settings.Publish, settings)
+}
+
+// queueConversions queues the variants of a model that aren't queued or
+// running already and converts them in the background
+func (d *Daemon) queueConversions(name, infoHash string, variants []string, publishDerived bool, settings convertSettings) []Conversion {
+	d.conversions.mu.Lock()
+	var queued []*Conversion
+	var snapshots []Conversion
+	for _, variant := range variants {
+		if d.conversionPendingLocked(name, variant) {
+			continue
+		}
+		conv := &Conversion{
+			ID:             uuid.New().String(),
+			Model:          name,
+			Variant:        variant,
+			Derived:        derivedModelName(name, variant),
+			Status:         ConversionQueued,
+			Publish:        publishDerived,
+			QueuedAt:       time.Now(),
+			parentInfoHash: infoHash,
+		}
+		d.conversions.conversions = append(d.conversions.conversions, conv)
+		queued = append(queued, conv)
+		snapshots = append(snapshots, *conv)
+	}
+	d.pruneConversionsLocked()
+	d.conversions.mu.Unlock()
+
+	if len(queued) == 0 {
+		return nil
+	}
+	d.workers.Add(1)
+	go func() {
+		defer d.workers.Done()
+		for _, conv := range queued {
+			d.runConversion(conv, settings)
+		}
+	}()
+	return snapshots
+}
+
+// conversionPendingLocked reports whether a variant of a model is queued or
+// being converted. Callers must hold d.conversions.mu.
+func (d *Daemon) conversionPendingLocked(name, variant string) bool {
+	for _, conv := range d.conversions.conversions {
+		if conv.Model == name && conv.Variant == variant && (conv.Status == ConversionQueued || conv.Status == ConversionRunning) {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneConversionsLocked drops the oldest finished conversions beyond
+// maxConversions. Callers must hold d.conversions.mu.
+func (d *Daemon) pruneConversionsLocked() {
+	excess := len(d.conversions.conversions) - maxConversions
+	if excess <= 0 {
+		return
+	}
+	kept := d.conversions.conversions[:0]
+	for _, conv := range d.conversions.conversions {
+		if excess > 0 && (conv.Status == ConversionCompleted || conv.Status == ConversionFailed) {
+			excess--
+			continue
+		}
+		kept = append(kept, conv)
+	}
+	d.conversions.conversions = kept
+}
+
+// updateConversion changes a conversion under the lock
+func (d *Daemon) updateConversion(conv *Conversion, change func(conv *Conversion)) {
+	d.conversions.mu.Lock()
+	defer d.conversions.mu.Unlock()
+	change(conv)
+}
+
+// Conversions returns the recent conversions, the most recent first
+func (d *Daemon) Conversions() []Conversion {
+	d.conversions.mu.Lock()
+	defer d.conversions.mu.Unlock()
+
+	conversions := make([]Conversion, 0, len(d.conversions.conversions))
+	for i := len(d.conversions.conversions) - 1; i >= 0; i-- {
+		conversions = append(conversions, *d.conversions.conversions[i])
+	}
+	return conversions
+}
+
+// runConversion makes a derived model and publishes how it went
+func (d *Daemon) runConversion(conv *Conversion, settings convertSettings) {
+	d.conversions.runMu.Lock()
+	defer d.conversions.runMu.Unlock()
+
+	now := time.Now()
+	d.updateConversion(conv, func(conv *Conversion) {
+		conv.Status = ConversionRunning
+		conv.StartedAt = &now
+	})
+
+	jobID, err := d.convert(conv, settings)
+	finished := time.Now()
+	d.updateConversion(conv, func(conv *Conversion) {
+		conv.CompletedAt = &finished
+		conv.PublishJob = jobID
+		if err != nil {
+			conv.Status = ConversionFailed
+			conv.Error = err.Error()
+		} else {
+			conv.Status = ConversionCompleted
+		}
+	})
+	if err != nil {
+		d.events.Publish(EventConvertFailed, conv.Model, "conversion to %s failed: %v", conv.Variant, err)
+		return
+	}
+	d.events.Publish(EventModelConverted, conv.Model, "converted to %s in %v", conv.Derived, finished.Sub(now).Round(time.Second))
+}
+
+// convert runs the converter for a conversion in a directory of its own,
+// moves the result into the models directory and describes it with a
+// manifest linking it to its parent. It returns the ID of the publish job
+// if the derived model is published.
+func (d *Daemon) convert(conv *Conversion, settings convertSettings) (string, error) {
+	modelPath, err := modelPathFor(conv.Model)
+	if err != nil {
+		return "", err
+	}
+	derivedPath, err := modelPathFor(conv.Derived)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(derivedPath); err == nil {
+		return "", fmt.Errorf("%s already exists", conv.Derived)
+	}
+	parent, err := models.ReadManifest(modelPath)
+	if err != nil {
+		return "", fmt.Errorf("no manifest for model %s: %w", conv.Model, err)
+	}
+
+	workRoot := filepath.Join(storage.GetBaseDir(), "convert")
+	if err := os.MkdirAll(workRoot, 0755); err != nil {
+		return "", fmt.Errorf("failed to create conversion directory: %w", err)
+	}
+	outputDir, err := os.MkdirTemp(workRoot, "convert-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create conversion directory: %w", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	fmt.Printf("[Convert] Converting %s to %s\n", conv.Model, conv.Variant)
+	env := sandboxEnv(settings.Env,
+		"SILMARIL_MODEL_NAME="+conv.Model,
+		"SILMARIL_MODEL_PATH="+modelPath,
+		"SILMARIL_MANIFEST="+filepath.Join(modelPath, models.ManifestFileName),
+		"SILMARIL_OUTPUT_DIR="+outputDir,
+		"SILMARIL_VARIANT="+conv.Variant,
+		"SILMARIL_DERIVED_NAME="+conv.Derived,
+	)
+	output, err := runSandboxed(d.ctx, settings.Command, []string{modelPath, outputDir, conv.Variant}, outputDir, env, settings.Timeout)
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" {
+			fmt.Printf("[Convert] %s: %s\n", conv.Derived, line)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("converter failed: %w", err)
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) == 0 {
+		return "", fmt.Errorf("converter wrote no files")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(derivedPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create model directory: %w", err)
+	}
+	if err := os.Rename(outputDir, derivedPath); err != nil {
+		return "", fmt.Errorf("failed to move %s into the models directory: %w", conv.Derived, err)
+	}
+	manifest, err := derivedManifest(parent, conv, derivedPath)
+	if err != nil {
+		return "", err
+	}
+	if err := models.WriteManifest(derivedPath, manifest); err != nil {
+		return "", fmt.Errorf("failed to save manifest: %w", err)
+	}
+	d.reloadRegistryModels(conv.Derived)
+
+	if !conv.Publish {
+		return "", nil
+	}
+	if d.publisher == nil || d.registry == nil {
+		return "", fmt.Errorf("converted, but publishing is not available")
+	}
+	job := d.StartPublish(publish.Request{
+		Name:        conv.Derived,
+		Path:        derivedPath,
+		TorrentPath: d.registry.Paths().TorrentPath(conv.Derived),
+		Manifest:    manifest,
+		Version:     manifest.Version,
+	})
+	return job.ID, nil
+}
+
+// derivedManifest describes the files a converter made for a variant of
+// parent, with the license and model card of the parent and a link to it
+func derivedManifest(parent *types.ModelManifest, conv *Conversion, dir string) (*types.ModelManifest, error) {
+	manifest, err := models.GenerateManifest(dir, conv.Derived, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate manifest: %w", err)
+	}
+	manifest.Version = parent.Version
+	manifest.Description = fmt.Sprintf("%s conversion of %s", conv.Variant, conv.Model)
+	manifest.License = parent.License
+	manifest.LicenseURL = parent.LicenseURL
+	manifest.LicenseText = parent.LicenseText
+	manifest.RequireLicenseAcceptance = parent.RequireLicenseAcceptance
+	manifest.Languages = parent.Languages
+	manifest.PipelineTag = parent.PipelineTag
+	manifest.Architecture = parent.Architecture
+	manifest.ModelType = parent.ModelType
+	manifest.Parameters = parent.Parameters
+	manifest.InferenceHints.ContextLength = parent.InferenceHints.ContextLength
+	manifest.Quantization = conv.Variant
+	manifest.Tags = append(append([]string(nil), parent.Tags...), "gguf", conv.Variant)
+	manifest.Provenance = &types.Provenance{
+		ParentModel:    conv.Model,
+		ParentVersion:  parent.Version,
+		ParentInfoHash: conv.parentInfoHash,
+		Transformation: "gguf:" + conv.Variant,
+	}
+	// Derived weights still trace back to the repository the parent came from
+	if parent.Provenance != nil {
+		manifest.Provenance.UpstreamURL = parent.Provenance.UpstreamURL
+		manifest.Provenance.UpstreamRevision = parent.Provenance.UpstreamRevision
+	}
+	return manifest, nil
+}
+
+// modelInfoHash returns the infohash of the torrent of a local model, empty
+// if it has none
+func (d *Daemon) modelInfoHash(name string) string {
+	if d.torrentManager == nil {
+		return ""
+	}
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		if mt.Name == name && !isUpdateDownload(mt) {
+			return strings.ToLower(mt.InfoHash)
+		}
+	}
+	return ""
+}
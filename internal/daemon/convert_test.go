@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueConversions(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	d.ctx = context.Background()
+	modelDir := writeTestModel(t, "org/model")
+	require.NoError(t, models.WriteManifest(modelDir, &types.ModelManifest{
+		Name:    "org/model",
+		Version: "1.0",
+		License: "apache-2.0",
+		Tags:    []string{"llama"},
+		Provenance: &types.Provenance{
+			UpstreamURL:      "https://huggingface.co/org/model",
+			UpstreamRevision: "0123456789abcdef",
+		},
+	}))
+
+	settings := convertSettings{
+		Command: writeHookScript(t, `test -f "$1/model.safetensors" || exit 1
+test "$SILMARIL_VARIANT" = "$3" || exit 1
+echo "quantizing to $3"
+echo gguf > "$2/model-$3.gguf"
+`),
+		Timeout: time.Minute,
+	}
+	queued := d.queueConversions("org/model", "abcd", []string{"Q4_K_M"}, false, settings)
+	require.Len(t, queued, 1)
+	assert.Equal(t, "org/model-Q4_K_M-GGUF", queued[0].Derived)
+	d.workers.Wait()
+
+	conversions := d.Conversions()
+	require.Len(t, conversions, 1)
+	assert.Equal(t, ConversionCompleted, conversions[0].Status, conversions[0].Error)
+	assert.NotNil(t, conversions[0].CompletedAt)
+
+	derivedDir := filepath.Join(storage.GetModelsDir(), "org/model-Q4_K_M-GGUF")
+	assert.FileExists(t, filepath.Join(derivedDir, "model-Q4_K_M.gguf"))
+	manifest, err := models.ReadManifest(derivedDir)
+	require.NoError(t, err)
+	assert.Equal(t, "1.0", manifest.Version)
+	assert.Equal(t, "apache-2.0", manifest.License)
+	assert.Equal(t, "Q4_K_M", manifest.Quantization)
+	assert.Equal(t, []string{"llama", "gguf", "Q4_K_M"}, manifest.Tags)
+	assert.Equal(t, &types.Provenance{
+		UpstreamURL:      "https://huggingface.co/org/model",
+		UpstreamRevision: "0123456789abcdef",
+		ParentModel:      "org/model",
+		ParentVersion:    "1.0",
+		ParentInfoHash:   "abcd",
+		Transformation:   "gguf:Q4_K_M",
+	}, manifest.Provenance)
+
+	entries, err := os.ReadDir(filepath.Join(storage.GetBaseDir(), "convert"))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the work directory is moved or removed")
+
+	events := d.events.Since(0)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventModelConverted, events[0].Type)
+
+	// A variant that exists already isn't made again
+	d.queueConversions("org/model", "abcd", []string{"Q4_K_M"}, false, settings)
+	d.workers.Wait()
+	assert.Equal(t, ConversionFailed, d.Conversions()[0].Status)
+	assert.Equal(t, "org/model-Q4_K_M-GGUF already exists", d.Conversions()[0].Error)
+}
+
+func TestQueueConversionsFailed(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	d.ctx = context.Background()
+	writeTestModel(t, "org/model")
+
+	settings := convertSettings{Command: writeHookScript(t, "echo 'nothing to do'\n"), Timeout: time.Minute}
+	d.queueConversions("org/model", "", []string{"Q8_0"}, false, settings)
+	d.workers.Wait()
+
+	conversion := d.Conversions()[0]
+	assert.Equal(t, ConversionFailed, conversion.Status)
+	assert.Equal(t, "converter wrote no files", conversion.Error)
+	assert.NoDirExists(t, filepath.Join(storage.GetModelsDir(), "org/model-Q8_0-GGUF"))
+	assert.Equal(t, EventConvertFailed, d.events.Since(0)[0].Type)
+}
+
+func TestConvertModelErrors(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	_, err := d.ConvertModel("org/model", nil, false)
+	assert.EqualError(t, err, "no converter configured, set convert.command")
+
+	assert.True(t, variantPattern.MatchString("Q4_K_M"))
+	assert.False(t, variantPattern.MatchString("../Q4"))
+	assert.False(t, variantPattern.MatchString("Q4/K"))
+}
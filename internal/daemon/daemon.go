@@ -4,16 +4,23 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/silmaril/silmaril/internal/blocklist"
 	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/publish"
 	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/store"
 )
 
 type Daemon struct {
@@ -21,13 +28,45 @@ type Daemon struct {
 	ctx             context.Context
 	cancel          context.CancelFunc
 	config          *config.Config
+	mode            string // ModeFull or ModeRelay, from the config at startup
 	torrentManager  *TorrentManager
 	dhtManager      *DHTManager
 	transferManager *TransferManager
+	history         *History           // Transfers that ended
+	publisher       *publish.Publisher // Publish jobs of shared models
+	registry        *models.Registry   // Local models, shared by the API handlers
+	registryWatch   registryWatch      // Directories of the registry being watched
 	state           *State
+	store           *store.Store  // Database of the daemon, nil if it couldn't be opened
 	server          *http.Server
 	apiHandler      http.Handler  // Store the API handler
+	hfProxyServer   *http.Server
+	apiPort         int // Port the API listens on, 0 before it does
+	hfProxyHandler  http.Handler  // HuggingFace Hub proxy, served if enabled
+	logs            *LogBuffer    // Captured output, nil unless capture is enabled
+	events          *EventLog     // Notifications for clients, such as model updates
+	updateMu        sync.Mutex    // Serializes model update checks
+	aliasMu         sync.Mutex    // Serializes alias changes and renames
+	scrubs          scrubber      // Re-verification of seeded data
+	decryptions     decryptions   // Decryption of downloaded encrypted models
+	peerFilter      peerFilterLoader // Block and allow lists of peer addresses
+	modelFilter     *blocklist.Filter    // Models refused for download, seeding and discovery
+	modelBlocklist  modelBlocklistLoader // Local entries and feeds of the model filter
+	networkStats    networkStatsReporter // Our opt-in report to the network stats
+	catalogWatch    catalogWatch         // Frequent catalog refreshes for discover --watch
+	gc              garbageCollector     // Removal of orphaned torrents, downloads and temp files
+	peerHints       peerHints            // Daemons of the same cluster found through DNS
+	pinning         pinner               // Seeding of catalog models few peers seed
+	hooks           hookRunner           // Scripts run when models are ready
+	commands        hookCommands         // Hooks set in the config file at startup
+	conversions     converter            // Derived models made by the converter
+	apiLimits       apiLimiter           // Request rates and concurrency of API clients
+	uploadSchedule  uploadScheduler      // Division of the upload bandwidth between torrents
 	workers         sync.WaitGroup
+	shutdownOnce    sync.Once
+	draining        atomic.Bool   // Set once shutdown has started
+	done            chan struct{} // Closed once shutdown has completed
+	configChanged   chan struct{} // Signals workers that live settings changed
 }
 
 func New(cfg *config.Config) (*Daemon, error) {
@@ -46,10 +85,26 @@ func New(cfg *config.Config) (*Daemon, error) {
 		ctx:      ctx,
 		cancel:   cancel,
 		config:   cfg,
+		mode:     configMode(cfg),
+		done:     make(chan struct{}),
+		events:   NewEventLog(DefaultEventBufferSize),
+		modelFilter: blocklist.New(),
+		commands:    hookCommandsFor(cfg),
+
+		configChanged: make(chan struct{}, 1),
+	}
+
+	// Open the database, the state and catalogs are kept in it
+	d.store = openStore(cfg)
+	if d.store != nil {
+		discovery.SetCatalogCache(catalogCache{store: d.store})
 	}
 
 	// Initialize state
 	d.state = NewState(filepath.Join(daemonDir, "state.json"))
+	if d.store != nil {
+		d.state.UseStore(d.store)
+	}
 	if err := d.state.Load(); err != nil {
 		// Non-fatal: just log and continue with empty state
 		fmt.Printf("Warning: could not load previous state: %v\n", err)
@@ -63,6 +118,11 @@ func New(cfg *config.Config) (*Daemon, error) {
 		cancel()
 		return nil, fmt.Errorf("failed to initialize torrent manager: %w", err)
 	}
+	d.torrentManager.GetManifestExchange().SetStore(manifestStore{d: d})
+	d.torrentManager.SetQuarantine(d.quarantineDownload)
+	d.torrentManager.SetRejected(d.rejectDownload)
+	d.torrentManager.SetModelFilter(d.modelFilter)
+	d.ReloadPeerFilter(false)
 	fmt.Println("[DEBUG] Torrent manager initialized")
 
 	fmt.Println("[DEBUG] Initializing DHT manager...")
@@ -72,8 +132,27 @@ func New(cfg *config.Config) (*Daemon, error) {
 		return nil, fmt.Errorf("failed to initialize DHT manager: %w", err)
 	}
 	fmt.Printf("[DEBUG] DHT manager initialized with %d nodes\n", d.dhtManager.GetNodeCount())
+	d.dhtManager.SetModelFilter(d.modelFilter)
 
 	d.transferManager = NewTransferManager(d.torrentManager, d.state)
+	d.history = NewHistory(filepath.Join(daemonDir, "history.jsonl"), d.store)
+	d.transferManager.OnFinish = d.transferFinished
+
+	// Stop restored torrents of models on the blocklist
+	d.ReloadModelBlocklist(false)
+	d.publisher = d.newPublisher()
+	// Relays resume publish jobs once they store models again
+	if !d.Relay() {
+		if err := d.restoreJobs(); err != nil {
+			fmt.Printf("Warning: could not restore publish jobs: %v\n", err)
+		}
+	}
+
+	d.registry, err = newRegistry(d.store)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to initialize model registry: %w", err)
+	}
 
 	// Initialize catalog from existing shared models
 	fmt.Println("[DEBUG] Initializing catalog from shared models...")
@@ -102,10 +181,21 @@ func (d *Daemon) Start(apiPort int) error {
 		return fmt.Errorf("failed to start API server: %w", err)
 	}
 
+	// Start the HuggingFace Hub proxy if enabled, relays have no models to serve
+	if d.Relay() {
+		fmt.Println("Running as a relay: seeding catalogs and republishing DHT references, not storing models")
+	} else {
+		d.startHFProxyServer()
+	}
+
 	// Setup signal handlers
 	fmt.Println("[DEBUG] Setting up signal handlers...")
 	d.setupSignalHandlers()
 
+	// Apply config file changes without restart
+	SetLogLevel(d.config.GetString("daemon.log_level"))
+	d.watchConfig()
+
 	fmt.Printf("Daemon started on %s:%d (PID: %d)\n", bindAddress, apiPort, os.Getpid())
 	fmt.Printf("[DEBUG] Initial DHT nodes: %d\n", d.dhtManager.GetNodeCount())
 	
@@ -121,6 +211,10 @@ func (d *Daemon) startWorkers() {
 	d.workers.Add(1)
 	go d.catalogRefreshWorker()
 
+	// Announces of the model torrents by infohash
+	d.workers.Add(1)
+	go d.infohashAnnounceWorker()
+
 	// State persistence worker
 	d.workers.Add(1)
 	go d.statePersistenceWorker()
@@ -136,6 +230,100 @@ func (d *Daemon) startWorkers() {
 	// Stats collection worker
 	d.workers.Add(1)
 	go d.statsWorker()
+
+	// Relays don't store models, so they have no model data to handle
+	if !d.Relay() {
+		d.startModelWorkers()
+	}
+
+	// Smoothed transfer rates
+	d.workers.Add(1)
+	go d.throughputWorker()
+
+	// Upload and download accounting
+	d.workers.Add(1)
+	go d.contributionWorker()
+
+	// Downloaded peer blocklist updates
+	d.workers.Add(1)
+	go d.peerFilterWorker()
+
+	// Downloaded model blocklist feeds
+	d.workers.Add(1)
+	go d.modelBlocklistWorker()
+
+	d.workers.Add(1)
+	go d.networkStatsWorker()
+
+	// Catalog refreshes while clients wait for new models
+	d.workers.Add(1)
+	go d.catalogWatchWorker()
+
+	// Orphaned torrents, downloads and temporary files
+	d.workers.Add(1)
+	go d.gcWorker()
+
+	// Peers of the same cluster named by network.peer_hints
+	d.workers.Add(1)
+	go d.peerHintWorker()
+
+	// Events posted to the configured webhooks
+	d.startWebhooks()
+}
+
+// startModelWorkers starts the workers that download, verify and seed the
+// data of local models
+func (d *Daemon) startModelWorkers() {
+	// Subscribed model updates
+	d.workers.Add(1)
+	go d.modelUpdateWorker()
+
+	// Seeding policy enforcement
+	d.workers.Add(1)
+	go d.seedingPolicyWorker()
+
+	// Upload bandwidth shares of busy and quiet swarms
+	d.workers.Add(1)
+	go d.uploadSchedulerWorker()
+
+	// Shares of the peer connection cap
+	d.workers.Add(1)
+	go d.connectionBalanceWorker()
+
+	// Seeded data re-verification
+	d.workers.Add(1)
+	go d.scrubWorker()
+
+	// Checksums of large model files
+	d.workers.Add(1)
+	go d.hashWorker()
+
+	// Piece availability and rare piece rebalancing
+	d.workers.Add(1)
+	go d.swarmCoordinatorWorker()
+
+	// Downloads sharing files with equivalent swarms
+	d.workers.Add(1)
+	go d.equivalentSwarmWorker()
+
+	// Mirrors and IPFS gateways of downloads
+	d.workers.Add(1)
+	go d.downloadSourceWorker()
+
+	// Local model registry following the models directory
+	d.workers.Add(1)
+	go d.registryWorker()
+
+	// Decryption of downloaded encrypted models
+	d.workers.Add(1)
+	go d.decryptionWorker()
+
+	// Downloads whose verification a restart interrupted
+	d.resumeQuarantine()
+
+	// Catalog models pinned for the network
+	d.workers.Add(1)
+	go d.pinningWorker()
 }
 
 func (d *Daemon) dhtAnnouncementWorker() {
@@ -159,15 +347,7 @@ func (d *Daemon) catalogRefreshWorker() {
 	defer d.workers.Done()
 	
 	// Make interval configurable, default to 30 minutes
-	interval := 30 * time.Minute
-	if d.config != nil {
-		if configInterval := d.config.GetInt("network.catalog_refresh_interval_minutes"); configInterval > 0 {
-			interval = time.Duration(configInterval) * time.Minute
-			fmt.Printf("[Daemon] Using configured catalog refresh interval: %v\n", interval)
-		} else {
-			fmt.Printf("[Daemon] Using default catalog refresh interval: %v\n", interval)
-		}
-	}
+	interval := d.catalogRefreshInterval()
 	
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
@@ -179,24 +359,49 @@ func (d *Daemon) catalogRefreshWorker() {
 		select {
 		case <-d.ctx.Done():
 			return
+		case <-d.configChanged:
+			if newInterval := d.catalogRefreshInterval(); newInterval != interval {
+				interval = newInterval
+				ticker.Reset(interval)
+				fmt.Printf("[Daemon] Catalog refresh interval changed to %v\n", interval)
+			}
 		case <-ticker.C:
 			fmt.Println("[Daemon] Running periodic catalog refresh...")
 			if err := d.dhtManager.RefreshSeedingModels(); err != nil {
 				fmt.Printf("[Daemon] Error refreshing seeded models catalog: %v\n", err)
 			}
+			d.checkCatalogUpdates()
 		}
 	}
 }
 
+// How long changes to the state are collected before they are saved
+const stateSaveDelay = 2 * time.Second
+
+// How often the state is saved for the counters that don't signal changes
+const stateSaveInterval = 5 * time.Minute
+
+// statePersistenceWorker saves the state shortly after it changes, at most
+// once per stateSaveDelay, and periodically for transfer counters
 func (d *Daemon) statePersistenceWorker() {
 	defer d.workers.Done()
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := time.NewTicker(stateSaveInterval)
 	defer ticker.Stop()
 
+	var save <-chan time.Time
 	for {
 		select {
 		case <-d.ctx.Done():
 			return
+		case <-d.state.Changed():
+			if save == nil {
+				save = time.After(stateSaveDelay)
+			}
+		case <-save:
+			save = nil
+			if err := d.state.Save(); err != nil {
+				fmt.Printf("Error saving state: %v\n", err)
+			}
 		case <-ticker.C:
 			if err := d.state.Save(); err != nil {
 				fmt.Printf("Error saving state: %v\n", err)
@@ -210,12 +415,17 @@ func (d *Daemon) cleanupWorker() {
 	ticker := time.NewTicker(1 * time.Hour)
 	defer ticker.Stop()
 
+	// Finish model removals interrupted by a previous shutdown
+	d.emptyTrash()
+	d.pruneHistory()
+
 	for {
 		select {
 		case <-d.ctx.Done():
 			return
 		case <-ticker.C:
 			d.cleanupIncompleteDownloads()
+			d.pruneHistory()
 		}
 	}
 }
@@ -231,6 +441,7 @@ func (d *Daemon) statsWorker() {
 			return
 		case <-ticker.C:
 			d.transferManager.UpdateStats()
+			d.torrentManager.SamplePeerSources()
 		}
 	}
 }
@@ -291,6 +502,14 @@ func (d *Daemon) reportSeedingStatus() {
 				name,
 				infoHash[:8],
 				stats.TotalPeers)
+		} else if mt := d.torrentManager.GetManagedTorrent(infoHash); mt != nil && len(mt.Files()) > 0 && mt.Complete() {
+			// Seeding only the selected files
+			seedingCount++
+			fmt.Printf("[Seeding Status] • %s: %s (SEEDING SELECTED FILES, peers: %d, uploaded: %s)\n",
+				name,
+				infoHash[:8],
+				stats.ActivePeers,
+				formatBytes(stats.BytesWrittenData.Int64()))
 		} else if t.BytesCompleted() < t.Length() {
 			// Check if this is a managed torrent that we're supposed to be seeding
 			managedTorrent := d.torrentManager.GetManagedTorrent(infoHash)
@@ -369,26 +588,80 @@ func (d *Daemon) setupSignalHandlers() {
 	}()
 }
 
+// Shutdown stops the daemon. It is safe to call more than once, for example
+// from a signal handler and the shutdown API at the same time.
 func (d *Daemon) Shutdown() error {
-	fmt.Println("Shutting down daemon...")
+	d.shutdownOnce.Do(func() {
+		d.shutdown()
+		close(d.done)
+	})
+	return nil
+}
+
+// Done returns a channel that is closed once the daemon has shut down
+func (d *Daemon) Done() <-chan struct{} {
+	return d.done
+}
+
+// shutdown drains the daemon within the shutdown grace period. If draining
+// takes longer it gives up, so a container stop never hangs on a slow peer
+// or tracker.
+func (d *Daemon) shutdown() {
+	grace := d.shutdownGracePeriod()
+	fmt.Printf("Shutting down daemon, draining for up to %v...\n", grace)
+	d.draining.Store(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		d.drain(ctx)
+	}()
+
+	select {
+	case <-drained:
+		fmt.Println("Daemon shutdown complete")
+	case <-ctx.Done():
+		fmt.Printf("[Daemon] Drain did not finish within %v, exiting\n", grace)
+	}
+}
+
+// drain stops the daemon in the order that loses the least: state is saved
+// before swarms are left, and swarms are left before the DHT goes away
+func (d *Daemon) drain(ctx context.Context) {
+	// Stop background workers
+	d.cancel()
+
+	// Save state first, so it survives a drain that runs out of time
+	d.recordContribution()
+	if err := d.state.Save(); err != nil {
+		fmt.Printf("Error saving final state: %v\n", err)
+	}
 
-	// Stop accepting new requests
+	// Stop accepting new requests, waiting for the ones in flight
 	if d.server != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
 		if err := d.server.Shutdown(ctx); err != nil {
 			fmt.Printf("Error shutting down API server: %v\n", err)
 		}
 	}
-
-	// Save state
-	if err := d.state.Save(); err != nil {
-		fmt.Printf("Error saving final state: %v\n", err)
+	if d.hfProxyServer != nil {
+		if err := d.hfProxyServer.Close(); err != nil {
+			fmt.Printf("Error shutting down HuggingFace proxy: %v\n", err)
+		}
 	}
 
-	// Stop torrent client
+	// Leave the swarms: trackers are told we stopped and peer connections
+	// are closed
 	if d.torrentManager != nil {
+		fmt.Println("[Daemon] Leaving swarms...")
 		d.torrentManager.Stop()
+
+		// Keep the transfer stats collected while leaving
+		if err := d.state.Save(); err != nil {
+			fmt.Printf("Error saving final state: %v\n", err)
+		}
 	}
 
 	// Stop DHT
@@ -399,8 +672,12 @@ func (d *Daemon) Shutdown() error {
 	// Wait for workers to finish
 	d.workers.Wait()
 
-	fmt.Println("Daemon shutdown complete")
-	return nil
+	if d.store != nil {
+		discovery.SetCatalogCache(nil)
+		if err := d.store.Close(); err != nil {
+			fmt.Printf("Error closing database: %v\n", err)
+		}
+	}
 }
 
 func (d *Daemon) startAPIServer(port int) error {
@@ -429,10 +706,42 @@ func (d *Daemon) startAPIServer(port int) error {
 		Handler:      handler,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
+		ConnContext:  socketConnContext,
+	}
+
+	// Serve the same API on the local socket, closed with the server
+	socket := ""
+	if d.config != nil {
+		socket = d.config.Daemon.Socket
+	}
+	if socket != "" {
+		listener, err := listenSocket(socket)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("[Daemon] API listening on %s\n", socket)
+		go func() {
+			if err := d.server.Serve(socketListener{listener}); err != nil && err != http.ErrServerClosed {
+				fmt.Printf("API server error on %s: %v\n", socket, err)
+			}
+		}()
+		if d.config.Daemon.SocketOnly {
+			return nil
+		}
 	}
 	
+	// Listen before returning, so a taken port fails the start instead of
+	// leaving a daemon nobody can reach
+	listener, err := net.Listen("tcp", d.server.Addr)
+	if err != nil {
+		if isAddrInUse(err) {
+			return fmt.Errorf("daemon.port %d is in use by another program, possibly another silmaril daemon: start with --port, set daemon.port or serve the API on daemon.socket: %w", port, err)
+		}
+		return err
+	}
+	d.apiPort = listener.Addr().(*net.TCPAddr).Port
 	go func() {
-		if err := d.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := d.server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			fmt.Printf("API server error: %v\n", err)
 		}
 	}()
@@ -494,20 +803,48 @@ func (d *Daemon) SetAPIHandler(handler http.Handler) {
 	}
 }
 
-// GetStatus returns the current daemon status
+// GetStatus returns the current daemon status, with the health of each of
+// its components and the build it runs
 func (d *Daemon) GetStatus() map[string]interface{} {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
+	uptime := time.Since(d.state.StartTime)
 	return map[string]interface{}{
-		"pid":              os.Getpid(),
-		"uptime":           time.Since(d.state.StartTime).String(),
-		"active_transfers": d.transferManager.GetActiveCount(),
-		"total_peers":      d.torrentManager.GetTotalPeers(),
-		"dht_nodes":        d.dhtManager.GetNodeCount(),
+		"pid":                 os.Getpid(),
+		"uptime":              uptime.String(),
+		"uptime_info":         Uptime{StartedAt: d.state.StartTime, Seconds: int64(uptime.Seconds())},
+		"build":               d.BuildInfo(),
+		"components":          d.Components(),
+		"active_transfers":    d.transferManager.GetActiveCount(),
+		"total_peers":         d.torrentManager.GetTotalPeers(),
+		"dht_nodes":           d.dhtManager.GetNodeCount(),
+		"listen_addrs":        d.torrentManager.GetListenAddrs(),
+		"ports":               d.Ports(),
+		"peers_by_family":     d.torrentManager.GetPeersByFamily(),
+		"dht_nodes_by_family": d.dhtManager.GetNodesByFamily(),
+		"dht_bootstrap":       d.dhtManager.GetBootstrapState(),
+		"peer_hints":          d.PeerHintsStatus(),
+		"mode":                d.Mode(),
 	}
 }
 
+// SetLogBuffer sets the buffer of captured daemon output served by the API
+func (d *Daemon) SetLogBuffer(logs *LogBuffer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	d.logs = logs
+}
+
+// GetLogBuffer returns the buffer of captured daemon output, or nil
+func (d *Daemon) GetLogBuffer() *LogBuffer {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	
+	return d.logs
+}
+
 // GetTorrentManager returns the torrent manager
 func (d *Daemon) GetTorrentManager() *TorrentManager {
 	return d.torrentManager
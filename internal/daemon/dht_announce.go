@@ -0,0 +1,341 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/dht/v2"
+	"github.com/anacrolix/dht/v2/krpc"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+const (
+	// How often the announce worker looks for models due to be announced
+	infohashAnnounceCheckInterval = time.Minute
+
+	// How soon an announce no node accepted is tried again
+	infohashAnnounceRetry = 5 * time.Minute
+
+	// How long an announce walks the DHT towards the nodes of an infohash
+	infohashAnnounceTimeout = 30 * time.Second
+
+	// Nodes closest to an infohash asked to store our peer, as in BEP 5
+	infohashAnnounceNodes = 8
+
+	// Maximum number of models announced at the same time
+	maxConcurrentAnnounces = 4
+)
+
+// InfohashAnnounce is the last announce of a model's infohash on the DHT,
+// which lets get_peers find this node even when the catalog is stale
+type InfohashAnnounce struct {
+	Model       string     `json:"model"`
+	InfoHash    string     `json:"info_hash"`
+	LastAttempt time.Time  `json:"last_attempt"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Nodes       int        `json:"nodes"`    // Nodes that stored our peer in the last announce
+	Peers       int        `json:"peers"`    // Other peers the nodes returned
+	Failures    int        `json:"failures"` // Announces in a row no node accepted
+	Error       string     `json:"error,omitempty"`
+}
+
+// infohashAnnouncer keeps the announces of each infohash
+type infohashAnnouncer struct {
+	mu        sync.Mutex
+	announces map[string]*InfohashAnnounce
+	running   map[string]bool
+}
+
+func newInfohashAnnouncer() *infohashAnnouncer {
+	return &infohashAnnouncer{
+		announces: make(map[string]*InfohashAnnounce),
+		running:   make(map[string]bool),
+	}
+}
+
+// due reports whether an infohash should be announced again, and marks it
+// running if so
+func (a *infohashAnnouncer) due(infoHash string, interval time.Duration, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.running[infoHash] {
+		return false
+	}
+	if last, ok := a.announces[infoHash]; ok {
+		wait := interval
+		if last.Failures > 0 {
+			wait = min(interval, infohashAnnounceRetry)
+		}
+		if now.Sub(last.LastAttempt) < wait {
+			return false
+		}
+	}
+	a.running[infoHash] = true
+	return true
+}
+
+// record stores the result of an announce
+func (a *infohashAnnouncer) record(model, infoHash string, nodes, peers int, err error, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.running, infoHash)
+
+	announce, ok := a.announces[infoHash]
+	if !ok {
+		announce = &InfohashAnnounce{InfoHash: infoHash}
+		a.announces[infoHash] = announce
+	}
+	announce.Model = model
+	announce.LastAttempt = at
+	announce.Nodes = nodes
+	announce.Peers = peers
+	announce.Error = ""
+	if err == nil && nodes > 0 {
+		announce.LastSuccess = &at
+		announce.Failures = 0
+		return
+	}
+	announce.Failures++
+	if err != nil {
+		announce.Error = err.Error()
+	} else {
+		announce.Error = "no node accepted the announce"
+	}
+}
+
+// forget drops the announces of a model no longer seeded
+func (a *infohashAnnouncer) forget(infoHash string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.announces, infoHash)
+}
+
+// list returns the announces by model name
+func (a *infohashAnnouncer) list() []InfohashAnnounce {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	result := make([]InfohashAnnounce, 0, len(a.announces))
+	for _, announce := range a.announces {
+		result = append(result, *announce)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Model != result[j].Model {
+			return result[i].Model < result[j].Model
+		}
+		return result[i].InfoHash < result[j].InfoHash
+	})
+	return result
+}
+
+// announceNode is a node that answered get_peers with a token we may
+// announce with
+type announceNode struct {
+	addr  krpc.NodeAddr
+	id    krpc.ID
+	token string
+}
+
+// closestNodes returns up to n nodes by their XOR distance to target
+func closestNodes(target [20]byte, nodes []announceNode, n int) []announceNode {
+	distance := func(id krpc.ID) []byte {
+		d := make([]byte, len(target))
+		for i := range target {
+			d[i] = target[i] ^ id[i]
+		}
+		return d
+	}
+	sorted := append([]announceNode{}, nodes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(distance(sorted[i].id), distance(sorted[j].id)) < 0
+	})
+	return sorted[:min(n, len(sorted))]
+}
+
+// announcePeer walks a DHT server towards the nodes closest to an infohash,
+// then asks them to store port as a peer of it. It returns how many nodes
+// accepted and how many other peers they knew.
+func announcePeer(ctx context.Context, srv *dht.Server, infoHash metainfo.Hash, port int) (int, int, error) {
+	traversal, err := srv.AnnounceTraversal(infoHash)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer traversal.Close()
+
+	timer := time.NewTimer(infohashAnnounceTimeout)
+	defer timer.Stop()
+
+	nodes := make(map[string]announceNode)
+	peers := make(map[string]bool)
+walk:
+	for {
+		select {
+		case pv, ok := <-traversal.Peers:
+			if !ok {
+				break walk
+			}
+			for _, peer := range pv.Peers {
+				peers[peer.String()] = true
+			}
+			if pv.Return.Token != nil {
+				nodes[pv.NodeInfo.Addr.String()] = announceNode{addr: pv.NodeInfo.Addr, id: pv.NodeInfo.ID, token: *pv.Return.Token}
+			}
+		case <-timer.C:
+			break walk
+		case <-ctx.Done():
+			return 0, len(peers), ctx.Err()
+		}
+	}
+	if len(nodes) == 0 {
+		return 0, len(peers), errors.New("no DHT node answered")
+	}
+
+	candidates := make([]announceNode, 0, len(nodes))
+	for _, node := range nodes {
+		candidates = append(candidates, node)
+	}
+	var accepted int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, node := range closestNodes(infoHash, candidates, infohashAnnounceNodes) {
+		wg.Add(1)
+		go func(node announceNode) {
+			defer wg.Done()
+			result := srv.Query(ctx, dht.NewAddr(node.addr.UDP()), "announce_peer", dht.QueryInput{
+				MsgArgs: krpc.MsgArgs{
+					InfoHash: krpc.ID(infoHash),
+					Port:     &port,
+					Token:    node.token,
+				},
+			})
+			if result.ToError() == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}(node)
+	}
+	wg.Wait()
+	return accepted, len(peers), nil
+}
+
+// announceInfohash announces a model's torrent on every DHT server, so peers
+// looking it up by infohash find this node
+func (dm *DHTManager) announceInfohash(model, infoHash string) {
+	var nodes, peers int
+	err := func() error {
+		var ih metainfo.Hash
+		if err := ih.FromHexString(infoHash); err != nil {
+			return fmt.Errorf("invalid infohash: %w", err)
+		}
+		if dm.torrentClient == nil || dm.torrentClient.LocalPort() == 0 {
+			return errors.New("the torrent client isn't listening for peers")
+		}
+		port := dm.torrentClient.LocalPort()
+
+		var errs []error
+		for _, srv := range dm.dhtServers {
+			accepted, found, err := announcePeer(dm.ctx, srv, ih, port)
+			nodes += accepted
+			peers = max(peers, found)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if nodes > 0 {
+			return nil
+		}
+		return errors.Join(errs...)
+	}()
+	dm.infohashAnnounces.record(model, infoHash, nodes, peers, err, time.Now())
+	if err != nil {
+		fmt.Printf("[DHT] Failed to announce %s (%s): %v\n", model, infoHash, err)
+	}
+}
+
+// AnnounceInfohashes announces the torrents of seeded and published models
+// whose last announce is older than interval, or failed
+func (dm *DHTManager) AnnounceInfohashes(interval time.Duration) {
+	if len(dm.dhtServers) == 0 {
+		return
+	}
+
+	models := make(map[string]string)
+	if dm.torrentManager != nil {
+		for _, mt := range dm.torrentManager.GetSeedingModels() {
+			models[mt.InfoHash] = mt.Name
+		}
+	}
+	dm.mu.RLock()
+	for infoHash, ann := range dm.announcements {
+		models[infoHash] = ann.Name
+	}
+	dm.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentAnnounces)
+	now := time.Now()
+	for infoHash, model := range models {
+		if !dm.infohashAnnounces.due(infoHash, interval, now) {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(model, infoHash string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			dm.announceInfohash(model, infoHash)
+		}(model, infoHash)
+	}
+	wg.Wait()
+}
+
+// InfohashAnnounces returns the last announce of each model's infohash
+func (dm *DHTManager) InfohashAnnounces() []InfohashAnnounce {
+	return dm.infohashAnnounces.list()
+}
+
+// announcedInfohashes returns how many infohashes some node accepted the
+// last announce of
+func (dm *DHTManager) announcedInfohashes() int {
+	announced := 0
+	for _, announce := range dm.InfohashAnnounces() {
+		if announce.Failures == 0 && announce.Nodes > 0 {
+			announced++
+		}
+	}
+	return announced
+}
+
+// infohashAnnounceInterval returns how often seeded models are announced,
+// 0 if never
+func (d *Daemon) infohashAnnounceInterval() time.Duration {
+	if d.config == nil {
+		return 15 * time.Minute
+	}
+	return time.Duration(d.config.GetInt("network.dht_announce_interval_minutes")) * time.Minute
+}
+
+// infohashAnnounceWorker announces the torrents of seeded models on the DHT
+// and announces them again before nodes forget this node
+func (d *Daemon) infohashAnnounceWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(infohashAnnounceCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			if interval := d.infohashAnnounceInterval(); interval > 0 && d.dhtManager != nil {
+				d.dhtManager.AnnounceInfohashes(interval)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/krpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfohashAnnouncer(t *testing.T) {
+	a := newInfohashAnnouncer()
+	now := time.Now()
+	interval := 15 * time.Minute
+
+	// A new infohash is due once, until its announce finishes
+	assert.True(t, a.due("abc", interval, now))
+	assert.False(t, a.due("abc", interval, now))
+	a.record("org/model", "abc", 6, 10, nil, now)
+	assert.False(t, a.due("abc", interval, now.Add(time.Minute)))
+	assert.True(t, a.due("abc", interval, now.Add(interval)))
+	a.record("org/model", "abc", 6, 10, nil, now.Add(interval))
+
+	// Failed announces are retried sooner and counted
+	assert.True(t, a.due("def", interval, now))
+	a.record("org/lonely", "def", 0, 0, errors.New("no DHT node answered"), now)
+	assert.False(t, a.due("def", interval, now.Add(time.Minute)))
+	assert.True(t, a.due("def", interval, now.Add(infohashAnnounceRetry)))
+	a.record("org/lonely", "def", 0, 0, nil, now.Add(infohashAnnounceRetry))
+
+	announces := a.list()
+	require.Len(t, announces, 2)
+	assert.Equal(t, "org/lonely", announces[0].Model)
+	assert.Equal(t, 2, announces[0].Failures)
+	assert.Equal(t, "no node accepted the announce", announces[0].Error)
+	assert.Nil(t, announces[0].LastSuccess)
+	assert.Equal(t, 6, announces[1].Nodes)
+	assert.Zero(t, announces[1].Failures)
+	require.NotNil(t, announces[1].LastSuccess)
+
+	a.forget("def")
+	assert.Len(t, a.list(), 1)
+}
+
+func TestClosestNodes(t *testing.T) {
+	var target [20]byte
+	target[0] = 0x80
+
+	node := func(first byte) announceNode {
+		var id krpc.ID
+		id[0] = first
+		return announceNode{id: id, token: string(first)}
+	}
+	nodes := []announceNode{node(0x00), node(0x81), node(0xff), node(0x80)}
+
+	closest := closestNodes(target, nodes, 2)
+	require.Len(t, closest, 2)
+	assert.Equal(t, byte(0x80), closest[0].id[0])
+	assert.Equal(t, byte(0x81), closest[1].id[0])
+	assert.Len(t, closestNodes(target, nodes, 10), 4)
+}
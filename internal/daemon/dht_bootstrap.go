@@ -0,0 +1,445 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anacrolix/dht/v2"
+	"github.com/anacrolix/dht/v2/krpc"
+	"golang.org/x/time/rate"
+)
+
+// DHT bootstrap statuses reported in the daemon status
+const (
+	BootstrapPending  = "pending"       // No attempt made yet
+	BootstrapRunning  = "bootstrapping" // An attempt is in progress
+	BootstrapReady    = "ready"         // The last attempt reached the DHT
+	BootstrapRetrying = "retrying"      // The last attempt failed, waiting to retry
+)
+
+const (
+	// Time allowed for one bootstrap attempt
+	bootstrapTimeout = 30 * time.Second
+
+	// Delay before retrying a failed attempt, doubled after every failure
+	bootstrapMinBackoff = 5 * time.Second
+	bootstrapMaxBackoff = 10 * time.Minute
+
+	// Interval between bootstraps once the DHT is ready
+	bootstrapRefreshInterval = 15 * time.Minute
+
+	// Pings sent to bootstrap nodes per second, and per source and server
+	bootstrapPingRate = 20
+	bootstrapMaxPings = 32
+
+	// Name of the source used when the routing table alone reaches the DHT
+	routingTableSource = "routing_table"
+
+	// Saved routing tables older than this are only used as a bootstrap
+	// source, not restored into the routing table
+	nodeCacheMaxAge = 7 * 24 * time.Hour
+)
+
+// BootstrapSource provides nodes to bootstrap the DHT from
+type BootstrapSource interface {
+	// Name identifies the source in the bootstrap state
+	Name() string
+	// Nodes returns the nodes of the source for a UDP network such as "udp4"
+	Nodes(ctx context.Context, network string) ([]dht.Addr, error)
+}
+
+// BootstrapState is the progress of DHT bootstrapping
+type BootstrapState struct {
+	Status      string                 `json:"status"`
+	Attempts    int                    `json:"attempts"`
+	Failures    int                    `json:"consecutive_failures"`
+	Source      string                 `json:"source,omitempty"` // Source that reached the DHT last
+	Responses   int                    `json:"responses"`        // Nodes that answered the last attempt
+	Restored    int                    `json:"restored_nodes"`   // Nodes restored from the saved routing table
+	LastAttempt *time.Time             `json:"last_attempt,omitempty"`
+	LastSuccess *time.Time             `json:"last_success,omitempty"`
+	NextAttempt *time.Time             `json:"next_attempt,omitempty"`
+	LastError   string                 `json:"last_error,omitempty"`
+	Sources     []BootstrapSourceState `json:"sources"`
+}
+
+// BootstrapSourceState is the result of a bootstrap source in the last attempt
+type BootstrapSourceState struct {
+	Name      string `json:"name"`
+	Nodes     int    `json:"nodes"`
+	Responses int    `json:"responses"`
+	Error     string `json:"error,omitempty"`
+}
+
+// dhtBootstrapper bootstraps the DHT servers from a list of sources, trying
+// each source in turn until nodes answer. Failed attempts are retried with
+// exponential backoff, and pings to bootstrap nodes are rate limited.
+type dhtBootstrapper struct {
+	mu       sync.Mutex
+	servers  []*dht.Server
+	networks []string // UDP network of each server
+	sources  []BootstrapSource
+	pings    *rate.Limiter
+	state    BootstrapState
+
+	// Called after every successful attempt
+	onSuccess func()
+}
+
+func newDHTBootstrapper(sources []BootstrapSource) *dhtBootstrapper {
+	return &dhtBootstrapper{
+		sources: sources,
+		pings:   rate.NewLimiter(bootstrapPingRate, bootstrapPingRate),
+		state:   BootstrapState{Status: BootstrapPending},
+	}
+}
+
+// addServer adds a DHT server listening on a UDP network
+func (b *dhtBootstrapper) addServer(srv *dht.Server, network string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.servers = append(b.servers, srv)
+	b.networks = append(b.networks, network)
+}
+
+// setRestored records the number of nodes restored into the routing tables
+func (b *dhtBootstrapper) setRestored(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state.Restored = n
+}
+
+// State returns a copy of the bootstrap state
+func (b *dhtBootstrapper) State() BootstrapState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := b.state
+	state.Sources = append([]BootstrapSourceState(nil), b.state.Sources...)
+	return state
+}
+
+// startingNodes returns the nodes of the first source that has any, for DHT
+// servers that need to bootstrap on their own
+func (b *dhtBootstrapper) startingNodes(network string) ([]dht.Addr, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), bootstrapTimeout)
+	defer cancel()
+	for _, src := range b.sources {
+		if nodes, err := src.Nodes(ctx, network); err == nil && len(nodes) > 0 {
+			return nodes, nil
+		}
+	}
+	return nil, errors.New("no bootstrap source has nodes")
+}
+
+// run retries failed bootstraps with backoff and bootstraps again
+// periodically once the DHT is ready, until ctx is done. With attemptNow the
+// first attempt is made right away.
+func (b *dhtBootstrapper) run(ctx context.Context, attemptNow bool) {
+	if attemptNow {
+		b.attempt(ctx)
+	}
+	for {
+		b.mu.Lock()
+		delay := bootstrapBackoff(b.state.Failures)
+		next := time.Now().Add(delay)
+		b.state.NextAttempt = &next
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		b.attempt(ctx)
+	}
+}
+
+// bootstrapBackoff returns the delay before the next attempt after a number
+// of consecutive failures
+func bootstrapBackoff(failures int) time.Duration {
+	if failures == 0 {
+		return bootstrapRefreshInterval
+	}
+	delay := bootstrapMaxBackoff
+	if failures < 20 {
+		delay = min(bootstrapMinBackoff<<(failures-1), bootstrapMaxBackoff)
+	}
+	// Up to a quarter of jitter spreads out the retries of daemons that
+	// lost the network together
+	return delay - time.Duration(rand.Int63n(int64(delay/4)+1))
+}
+
+// attempt bootstraps all servers once and reports whether any node answered.
+// A routing table with nodes is tried first, then the sources in order.
+func (b *dhtBootstrapper) attempt(ctx context.Context) bool {
+	b.mu.Lock()
+	servers := append([]*dht.Server(nil), b.servers...)
+	networks := append([]string(nil), b.networks...)
+	now := time.Now()
+	b.state.Status = BootstrapRunning
+	b.state.Attempts++
+	b.state.LastAttempt = &now
+	b.state.NextAttempt = nil
+	b.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, bootstrapTimeout)
+	defer cancel()
+
+	var source string
+	var responses int
+	var sources []BootstrapSourceState
+	if hasNodes(servers) {
+		if responses = b.traverse(ctx, servers); responses > 0 {
+			source = routingTableSource
+		}
+	}
+	if source == "" {
+		for _, src := range b.sources {
+			st := b.pingSource(ctx, src, servers, networks)
+			sources = append(sources, st)
+			if st.Responses > 0 {
+				source = src.Name()
+				responses = st.Responses + b.traverse(ctx, servers)
+				break
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+	}
+
+	b.mu.Lock()
+	b.state.Responses = responses
+	if sources != nil {
+		b.state.Sources = sources
+	}
+	if source != "" {
+		done := time.Now()
+		b.state.Status = BootstrapReady
+		b.state.Failures = 0
+		b.state.Source = source
+		b.state.LastSuccess = &done
+		b.state.LastError = ""
+	} else {
+		b.state.Status = BootstrapRetrying
+		b.state.Failures++
+		b.state.LastError = bootstrapError(ctx, sources)
+	}
+	state := b.state
+	onSuccess := b.onSuccess
+	b.mu.Unlock()
+
+	if source == "" {
+		fmt.Printf("[DHT Bootstrap] Attempt %d failed: %s\n", state.Attempts, state.LastError)
+		return false
+	}
+	fmt.Printf("[DHT Bootstrap] Attempt %d reached the DHT through %s, %d nodes answered\n", state.Attempts, source, responses)
+	if onSuccess != nil {
+		onSuccess()
+	}
+	return true
+}
+
+// bootstrapError describes why no source reached the DHT
+func bootstrapError(ctx context.Context, sources []BootstrapSourceState) string {
+	if ctx.Err() != nil {
+		return "timed out"
+	}
+	var reasons []string
+	for _, st := range sources {
+		switch {
+		case st.Error != "":
+			reasons = append(reasons, st.Name+": "+st.Error)
+		case st.Nodes == 0:
+			reasons = append(reasons, st.Name+": no nodes")
+		default:
+			reasons = append(reasons, fmt.Sprintf("%s: none of %d nodes answered", st.Name, st.Nodes))
+		}
+	}
+	if len(reasons) == 0 {
+		return "no bootstrap sources"
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// pingSource pings the nodes of a source from every server. Nodes that
+// answer are added to the routing tables.
+func (b *dhtBootstrapper) pingSource(ctx context.Context, src BootstrapSource, servers []*dht.Server, networks []string) BootstrapSourceState {
+	st := BootstrapSourceState{Name: src.Name()}
+	for i, srv := range servers {
+		nodes, err := src.Nodes(ctx, networks[i])
+		if err != nil {
+			st.Error = err.Error()
+			continue
+		}
+		if len(nodes) > bootstrapMaxPings {
+			nodes = nodes[:bootstrapMaxPings]
+		}
+		st.Nodes += len(nodes)
+		st.Responses += b.ping(ctx, srv, nodes)
+	}
+	return st
+}
+
+// ping pings nodes from srv and returns how many answered
+func (b *dhtBootstrapper) ping(ctx context.Context, srv *dht.Server, nodes []dht.Addr) int {
+	var answered atomic.Int32
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		if err := b.pings.Wait(ctx); err != nil {
+			break
+		}
+		wg.Add(1)
+		go func(addr *net.UDPAddr) {
+			defer wg.Done()
+			if res := srv.Ping(addr); res.Err == nil {
+				answered.Add(1)
+			}
+		}(node.KRPC().UDP())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	return int(answered.Load())
+}
+
+// traverse bootstraps every server from its routing table and returns the
+// number of nodes that answered
+func (b *dhtBootstrapper) traverse(ctx context.Context, servers []*dht.Server) int {
+	responses := 0
+	for _, srv := range servers {
+		stats, err := srv.BootstrapContext(ctx)
+		if err != nil {
+			continue
+		}
+		responses += int(stats.NumResponses)
+	}
+	return responses
+}
+
+// hasNodes reports whether any server has nodes in its routing table
+func hasNodes(servers []*dht.Server) bool {
+	for _, srv := range servers {
+		if srv.NumNodes() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hostNodesSource resolves host:port bootstrap nodes, through the proxy if
+// one is configured. Hosts that fail to resolve are skipped.
+type hostNodesSource struct {
+	name     string
+	hosts    []string
+	resolver *net.Resolver
+}
+
+func (s hostNodesSource) Name() string {
+	return s.name
+}
+
+func (s hostNodesSource) Nodes(ctx context.Context, network string) ([]dht.Addr, error) {
+	var addrs []dht.Addr
+	var lastErr error
+	for _, host := range s.hosts {
+		udpAddrs, err := resolveUDPAddrs(ctx, s.resolver, network, host)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, addr := range udpAddrs {
+			addrs = append(addrs, dht.NewAddr(addr))
+		}
+	}
+	if len(addrs) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return addrs, nil
+}
+
+// resolveUDPAddrs resolves a host:port with resolver to the addresses of a
+// UDP network such as "udp4"
+func resolveUDPAddrs(ctx context.Context, resolver *net.Resolver, network, hostport string) ([]*net.UDPAddr, error) {
+	host, portStr, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return nil, err
+	}
+	port, err := resolver.LookupPort(ctx, "udp", portStr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := resolver.LookupIP(ctx, "ip"+strings.TrimPrefix(network, "udp"), host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]*net.UDPAddr, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, &net.UDPAddr{IP: ip, Port: port})
+	}
+	return addrs, nil
+}
+
+// cachedNodesSource returns the nodes saved from the routing table after a
+// successful bootstrap, usually of a previous run
+type cachedNodesSource struct {
+	path string
+}
+
+func (s cachedNodesSource) Name() string {
+	return "cache"
+}
+
+func (s cachedNodesSource) Nodes(ctx context.Context, network string) ([]dht.Addr, error) {
+	nodes, err := dht.ReadNodesFromFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node cache: %w", err)
+	}
+
+	var addrs []dht.Addr
+	for _, node := range nodesForNetwork(nodes, network) {
+		addrs = append(addrs, dht.NewAddr(node.Addr.UDP()))
+	}
+	return addrs, nil
+}
+
+// nodesForNetwork returns the nodes reachable on a UDP network such as "udp4"
+func nodesForNetwork(nodes []krpc.NodeInfo, network string) []krpc.NodeInfo {
+	family := ""
+	switch network {
+	case "udp4":
+		family = familyIPv4
+	case "udp6":
+		family = familyIPv6
+	}
+	if family == "" {
+		return nodes
+	}
+	var matching []krpc.NodeInfo
+	for _, node := range nodes {
+		if ipFamily(node.Addr.IP) == family {
+			matching = append(matching, node)
+		}
+	}
+	return matching
+}
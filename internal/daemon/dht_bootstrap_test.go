@@ -0,0 +1,160 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2"
+	"github.com/anacrolix/dht/v2/krpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticSource is a bootstrap source with fixed nodes
+type staticSource struct {
+	name  string
+	nodes []dht.Addr
+	err   error
+}
+
+func (s staticSource) Name() string {
+	return s.name
+}
+
+func (s staticSource) Nodes(ctx context.Context, network string) ([]dht.Addr, error) {
+	return s.nodes, s.err
+}
+
+// newTestDHTServer starts a DHT server on a loopback address
+func newTestDHTServer(t *testing.T) *dht.Server {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	cfg := dht.NewDefaultServerConfig()
+	cfg.Conn = conn
+	cfg.NoSecurity = true
+	cfg.StartingNodes = nil
+	srv, err := dht.NewServer(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		srv.Close()
+		conn.Close()
+	})
+	return srv
+}
+
+func TestBootstrapBackoff(t *testing.T) {
+	assert.Equal(t, bootstrapRefreshInterval, bootstrapBackoff(0))
+
+	for failures, want := range map[int]time.Duration{
+		1:   bootstrapMinBackoff,
+		3:   4 * bootstrapMinBackoff,
+		10:  bootstrapMaxBackoff,
+		100: bootstrapMaxBackoff,
+	} {
+		delay := bootstrapBackoff(failures)
+		assert.LessOrEqual(t, delay, want, failures)
+		assert.GreaterOrEqual(t, delay, want*3/4, failures)
+	}
+}
+
+func TestBootstrapperAttempt(t *testing.T) {
+	seed := newTestDHTServer(t)
+	srv := newTestDHTServer(t)
+	seedAddr := dht.NewAddr(seed.Addr())
+
+	b := newDHTBootstrapper([]BootstrapSource{
+		staticSource{name: "broken", err: errors.New("lookup failed")},
+		staticSource{name: "empty"},
+		staticSource{name: "seed", nodes: []dht.Addr{seedAddr}},
+	})
+	b.addServer(srv, "udp4")
+	saved := 0
+	b.onSuccess = func() { saved++ }
+
+	assert.Equal(t, BootstrapPending, b.State().Status)
+	require.True(t, b.attempt(context.Background()))
+
+	state := b.State()
+	assert.Equal(t, BootstrapReady, state.Status)
+	assert.Equal(t, "seed", state.Source)
+	assert.Equal(t, 1, state.Attempts)
+	assert.Zero(t, state.Failures)
+	assert.NotNil(t, state.LastSuccess)
+	require.Len(t, state.Sources, 3)
+	assert.Equal(t, "lookup failed", state.Sources[0].Error)
+	assert.Equal(t, BootstrapSourceState{Name: "seed", Nodes: 1, Responses: 1}, state.Sources[2])
+	assert.Equal(t, 1, saved)
+	assert.Equal(t, 1, srv.NumNodes())
+
+	// With nodes in the routing table the sources are not needed
+	require.True(t, b.attempt(context.Background()))
+	assert.Equal(t, routingTableSource, b.State().Source)
+}
+
+func TestBootstrapperAttemptFailure(t *testing.T) {
+	b := newDHTBootstrapper([]BootstrapSource{
+		staticSource{name: "config", err: errors.New("lookup failed")},
+		staticSource{name: "dns"},
+	})
+	b.addServer(newTestDHTServer(t), "udp4")
+	b.onSuccess = func() { t.Error("unexpected success") }
+
+	assert.False(t, b.attempt(context.Background()))
+	assert.False(t, b.attempt(context.Background()))
+
+	state := b.State()
+	assert.Equal(t, BootstrapRetrying, state.Status)
+	assert.Equal(t, 2, state.Attempts)
+	assert.Equal(t, 2, state.Failures)
+	assert.Nil(t, state.LastSuccess)
+	assert.Equal(t, "config: lookup failed; dns: no nodes", state.LastError)
+}
+
+func TestCachedNodesSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dht_nodes.dat")
+	source := cachedNodesSource{path: path}
+
+	nodes, err := source.Nodes(context.Background(), "udp")
+	require.NoError(t, err)
+	assert.Empty(t, nodes)
+
+	require.NoError(t, dht.WriteNodesToFile([]krpc.NodeInfo{
+		{ID: krpc.ID{1}, Addr: krpc.NodeAddr{IP: net.ParseIP("192.0.2.1"), Port: 6881}},
+		{ID: krpc.ID{2}, Addr: krpc.NodeAddr{IP: net.ParseIP("2001:db8::1"), Port: 6881}},
+	}, path))
+
+	nodes, err = source.Nodes(context.Background(), "udp")
+	require.NoError(t, err)
+	assert.Len(t, nodes, 2)
+
+	nodes, err = source.Nodes(context.Background(), "udp4")
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "192.0.2.1:6881", nodes[0].String())
+
+	nodes, err = source.Nodes(context.Background(), "udp6")
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "[2001:db8::1]:6881", nodes[0].String())
+}
+
+func TestHostNodesSource(t *testing.T) {
+	source := hostNodesSource{name: "config", hosts: []string{"127.0.0.1:6881", "[::1]:6882", "no-port"}, resolver: net.DefaultResolver}
+
+	nodes, err := source.Nodes(context.Background(), "udp4")
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "127.0.0.1:6881", nodes[0].String())
+
+	nodes, err = source.Nodes(context.Background(), "udp6")
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "[::1]:6882", nodes[0].String())
+
+	_, err = hostNodesSource{name: "config", hosts: []string{"no-port"}, resolver: net.DefaultResolver}.Nodes(context.Background(), "udp")
+	assert.Error(t, err)
+}
@@ -2,15 +2,23 @@ package daemon
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/hex"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/anacrolix/dht/v2"
+	"github.com/anacrolix/dht/v2/krpc"
 	"github.com/anacrolix/torrent"
+	"github.com/silmaril/silmaril/internal/blocklist"
 	"github.com/silmaril/silmaril/internal/config"
 	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/internal/proxy"
 	"github.com/silmaril/silmaril/pkg/types"
 )
 
@@ -19,11 +27,38 @@ type DHTManager struct {
 	config          *config.Config
 	torrentManager  *TorrentManager
 	torrentClient   *torrent.Client
-	dhtServer       *dht.Server
-	dhtConn         net.PacketConn
+	dhtServer       *dht.Server // Server of the preferred address family
+	dhtServers      []*dht.Server
+	dhtConns        []net.PacketConn
+	bootstrapProxy  *proxy.Proxy // Resolves bootstrap nodes, nil without a proxy
+	bootstrapper    *dhtBootstrapper
 	announcements   map[string]*types.ModelAnnouncement
 	lastAnnounce    map[string]time.Time
 	catalogRef      *discovery.BEP44CatalogRef
+	healthCache     *SwarmHealthCache
+	infohashAnnounces *infohashAnnouncer // Announces of the model torrents
+	statsCatalog    *discovery.StatsCatalog // Created on first use
+	
+	// Sorted catalog for browsing, built again once stale
+	indexMu         sync.Mutex
+	catalogIndex    *catalogIndex
+	
+	// Publisher catalogs signed with our own key and followed publishers
+	publisherKey    ed25519.PrivateKey
+	publisherID     string
+	publisherRef    *discovery.BEP44CatalogRef
+	retiredRefs     map[string]*discovery.BEP44CatalogRef // Catalogs of keys we rotated away from
+	subscriptions   map[string]*discovery.BEP44CatalogRef
+	
+	// Catalogs of the networks joined besides the public one, by name
+	networks        map[string]*discovery.BEP44CatalogRef
+	
+	// Namespace proofs of the domains publishers claim, by domain
+	namespaces      *namespaceVerifier
+	
+	// Models hidden from discovery, see SetModelFilter
+	modelFilter     *blocklist.Filter
+	
 	ctx             context.Context
 	cancel          context.CancelFunc
 }
@@ -37,73 +72,119 @@ func NewDHTManager(cfg *config.Config, tm *TorrentManager) (*DHTManager, error)
 		torrentManager: tm,
 		announcements:  make(map[string]*types.ModelAnnouncement),
 		lastAnnounce:   make(map[string]time.Time),
+		healthCache:    NewSwarmHealthCache(swarmHealthTTL),
+		infohashAnnounces: newInfohashAnnouncer(),
+		retiredRefs:    make(map[string]*discovery.BEP44CatalogRef),
+		subscriptions:  make(map[string]*discovery.BEP44CatalogRef),
+		networks:       make(map[string]*discovery.BEP44CatalogRef),
 		ctx:            ctx,
 		cancel:         cancel,
 	}
-
-	// Initialize DHT server with bootstrap nodes
-	fmt.Println("[DHT] Creating DHT server configuration...")
-	dhtCfg := dht.NewDefaultServerConfig()
 	
-	// Use custom bootstrap nodes if configured, otherwise use defaults
-	if cfg != nil && len(cfg.Network.DHTBootstrapNodes) > 0 {
-		fmt.Printf("[DHT] Using custom bootstrap nodes: %v\n", cfg.Network.DHTBootstrapNodes)
-		bootstrapNodes := cfg.Network.DHTBootstrapNodes
-		dhtCfg.StartingNodes = func() ([]dht.Addr, error) {
-			addrs := make([]dht.Addr, 0, len(bootstrapNodes))
-			for _, node := range bootstrapNodes {
-				fmt.Printf("[DHT] Resolving bootstrap node: %s\n", node)
-				udpAddr, err := net.ResolveUDPAddr("udp", node)
-				if err != nil {
-					fmt.Printf("[DHT] Warning: failed to resolve bootstrap node %s: %v\n", node, err)
-					continue
-				}
-				fmt.Printf("[DHT] Resolved %s to %s\n", node, udpAddr.String())
-				addrs = append(addrs, dht.NewAddr(udpAddr))
-			}
-			if len(addrs) == 0 {
-				fmt.Println("[DHT] All custom nodes failed, falling back to default bootstrap nodes")
-				// Fall back to defaults if all custom nodes failed
-				return dht.GlobalBootstrapAddrs("udp")
-			}
-			fmt.Printf("[DHT] Using %d bootstrap nodes\n", len(addrs))
-			return addrs, nil
-		}
+	// Load or create the key our publisher catalog is signed with
+	publisherKey, err := discovery.LoadOrCreatePublisherKey(dm.publisherKeyPath())
+	if err != nil {
+		fmt.Printf("[DHT] Warning: publisher catalog disabled: %v\n", err)
 	} else {
-		fmt.Println("[DHT] Using default DHT bootstrap nodes")
+		dm.publisherKey = publisherKey
+		dm.publisherID = hex.EncodeToString(publisherKey.Public().(ed25519.PublicKey))
 	}
-	// Otherwise dhtCfg.StartingNodes already points to GlobalBootstrapAddrs
-	
-	// Create UDP connection for DHT
-	fmt.Println("[DHT] Creating UDP listener...")
-	// Try standard DHT port first, fall back to random if unavailable
-	dhtPort := ":6881"
-	if cfg != nil && cfg.Network.DHTPort > 0 {
-		dhtPort = fmt.Sprintf(":%d", cfg.Network.DHTPort)
+
+	// Listen on the configured address of each address family
+	var listen listenConfig
+	networks := []string{"udp"}
+	if cfg != nil {
+		listen, err = parseListenConfig(cfg.Network.ListenAddrs, cfg.Network.ListenPort, cfg.Network.IPPreference)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		// Without explicit addresses one dual-stack socket serves both families
+		if len(cfg.Network.ListenAddrs) > 0 || (cfg.Network.IPPreference != "" && cfg.Network.IPPreference != IPPreferDual) {
+			networks = listen.UDPNetworks()
+		}
+
+		// DHT traffic is UDP, only bootstrap names are resolved through the proxy
+		dm.bootstrapProxy, err = proxy.FromConfig(cfg, proxy.DHT)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
 	}
-	conn, err := net.ListenPacket("udp", dhtPort)
-	if err != nil {
-		fmt.Printf("[DHT] Failed to bind to port %s, trying random port: %v\n", dhtPort, err)
-		conn, err = net.ListenPacket("udp", ":0") // Fall back to random port
+	dm.namespaces = newNamespaceVerifier(dm.bootstrapProxy)
+	
+	// The configured port, or else the one used last time, the standard
+	// port and a random one
+	configuredPort, savedPort := 0, 0
+	if cfg != nil {
+		configuredPort = cfg.Network.DHTPort
 	}
-	if err != nil {
-		cancel()
-		return nil, fmt.Errorf("failed to create UDP listener: %w", err)
+	if tm != nil && tm.state != nil {
+		savedPort = tm.state.SavedPorts().DHT
 	}
-	fmt.Printf("[DHT] UDP listener created on %s\n", conn.LocalAddr())
-	dhtCfg.Conn = conn
+	ports := dhtPorts(configuredPort, savedPort)
 	
-	fmt.Println("[DHT] Creating DHT server...")
-	srv, err := dht.NewServer(dhtCfg)
-	if err != nil {
-		conn.Close()
-		cancel()
-		return nil, fmt.Errorf("failed to create DHT server: %w", err)
-	}
-	dm.dhtServer = srv
-	dm.dhtConn = conn
+	dm.bootstrapper = newDHTBootstrapper(dm.bootstrapSources())
+	dm.bootstrapper.onSuccess = dm.saveNodeCache
+	
+	// Restore the routing table saved by the last run, so lookups and
+	// announcements work before bootstrapping finishes
+	cachedNodes := dm.loadNodeCache()
+	restored := 0
 	
-	fmt.Printf("[DHT] DHT server created and listening on %s\n", conn.LocalAddr())
+	// One DHT server per family, the preferred one first. It serves catalog
+	// lookups and announcements, the others keep the routing tables of their
+	// family.
+	for _, network := range networks {
+		fmt.Printf("[DHT] Creating %s listener...\n", network)
+		conn, err := listenDHT(network, listen.Host(network), ports)
+		if err != nil {
+			dm.closeDHTServers()
+			cancel()
+			if configuredPort > 0 && isAddrInUse(err) {
+				return nil, portConflictError("network.dht_port", configuredPort, err)
+			}
+			return nil, fmt.Errorf("failed to create UDP listener: %w", err)
+		}
+		// The other families listen on the same port, so peers and port
+		// mappings need only one
+		if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+			ports = []int{addr.Port}
+			if configuredPort == 0 {
+				ports = append(ports, 0)
+			}
+		}
+		fmt.Printf("[DHT] UDP listener created on %s\n", conn.LocalAddr())
+		
+		dhtCfg := dm.dhtServerConfig(network)
+		dhtCfg.Conn = conn
+		srv, err := dht.NewServer(dhtCfg)
+		if err != nil {
+			conn.Close()
+			dm.closeDHTServers()
+			cancel()
+			return nil, fmt.Errorf("failed to create DHT server: %w", err)
+		}
+		dm.dhtServers = append(dm.dhtServers, srv)
+		dm.dhtConns = append(dm.dhtConns, conn)
+		dm.bootstrapper.addServer(srv, network)
+		for _, node := range nodesForNetwork(cachedNodes, network) {
+			if srv.AddNode(node) == nil {
+				restored++
+			}
+		}
+		fmt.Printf("[DHT] DHT server created and listening on %s\n", conn.LocalAddr())
+	}
+	dm.dhtServer = dm.dhtServers[0]
+	if tm != nil && tm.state != nil && configuredPort == 0 {
+		if dhtPorts := dm.Ports(); len(dhtPorts) > 0 {
+			tm.state.SaveDHTPort(dhtPorts[0])
+		}
+	}
+	if restored > 0 {
+		fmt.Printf("[DHT] Restored %d nodes from the saved routing table\n", restored)
+		dm.bootstrapper.setRestored(restored)
+	}
 
 	// Get torrent client from torrent manager
 	if tm != nil && tm.client != nil {
@@ -117,64 +198,159 @@ func NewDHTManager(cfg *config.Config, tm *TorrentManager) (*DHTManager, error)
 	return dm, nil
 }
 
+// dhtServerConfig returns the DHT server config for a UDP network
+func (dm *DHTManager) dhtServerConfig(network string) *dht.ServerConfig {
+	dhtCfg := dht.NewDefaultServerConfig()
+	dhtCfg.StartingNodes = func() ([]dht.Addr, error) {
+		return dm.bootstrapper.startingNodes(network)
+	}
+	return dhtCfg
+}
+
+// bootstrapSources returns where bootstrap nodes come from, in the order they
+// are tried: nodes cached from a previous run, the configured nodes and the
+// well-known DNS seeds
+func (dm *DHTManager) bootstrapSources() []BootstrapSource {
+	resolver := dm.bootstrapProxy.Resolver()
+	sources := []BootstrapSource{cachedNodesSource{path: dm.nodeCachePath()}}
+	if dm.config != nil && len(dm.config.Network.DHTBootstrapNodes) > 0 {
+		fmt.Printf("[DHT] Using custom bootstrap nodes: %v\n", dm.config.Network.DHTBootstrapNodes)
+		sources = append(sources, hostNodesSource{name: "config", hosts: dm.config.Network.DHTBootstrapNodes, resolver: resolver})
+	}
+	return append(sources, hostNodesSource{name: "dns", hosts: dht.DefaultGlobalBootstrapHostPorts, resolver: resolver})
+}
+
+// nodeCachePath returns where routing table nodes are saved between runs
+func (dm *DHTManager) nodeCachePath() string {
+	return filepath.Join(dbDir(dm.config), "dht_nodes.dat")
+}
+
+// loadNodeCache returns the routing table nodes saved by a previous run, none
+// if they are too old to still be useful
+func (dm *DHTManager) loadNodeCache() []krpc.NodeInfo {
+	path := dm.nodeCachePath()
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > nodeCacheMaxAge {
+		return nil
+	}
+	nodes, err := dht.ReadNodesFromFile(path)
+	if err != nil {
+		fmt.Printf("[DHT] Warning: failed to read node cache: %v\n", err)
+		return nil
+	}
+	return nodes
+}
+
+// saveNodeCache saves the nodes of the routing tables, to restore and
+// bootstrap from after a restart
+func (dm *DHTManager) saveNodeCache() {
+	var nodes []krpc.NodeInfo
+	for _, srv := range dm.dhtServers {
+		nodes = append(nodes, srv.Nodes()...)
+	}
+	if len(nodes) == 0 {
+		return
+	}
+	path := dm.nodeCachePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Printf("[DHT] Warning: failed to save node cache: %v\n", err)
+		return
+	}
+	if err := dht.WriteNodesToFile(nodes, path); err != nil {
+		fmt.Printf("[DHT] Warning: failed to save node cache: %v\n", err)
+	}
+}
+
+// GetBootstrapState returns the progress of DHT bootstrapping
+func (dm *DHTManager) GetBootstrapState() BootstrapState {
+	if dm.bootstrapper == nil {
+		return BootstrapState{Status: BootstrapPending}
+	}
+	return dm.bootstrapper.State()
+}
+
+// listenDHT opens the UDP socket of a DHT server on host, trying ports in
+// order. Port 0 picks a random one.
+func listenDHT(network, host string, ports []int) (net.PacketConn, error) {
+	var err error
+	for _, port := range ports {
+		addr := net.JoinHostPort(host, strconv.Itoa(port))
+		var conn net.PacketConn
+		conn, err = net.ListenPacket(network, addr)
+		if err == nil {
+			return conn, nil
+		}
+		if len(ports) > 1 {
+			fmt.Printf("[DHT] Failed to bind to %s: %v\n", addr, err)
+		}
+	}
+	return nil, err
+}
+
+// Ports returns the UDP ports the DHT servers listen on
+func (dm *DHTManager) Ports() []int {
+	var ports []int
+	for _, conn := range dm.dhtConns {
+		if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+			ports = append(ports, addr.Port)
+		}
+	}
+	return ports
+}
+
+// closeDHTServers closes the DHT servers and their sockets
+func (dm *DHTManager) closeDHTServers() {
+	for _, srv := range dm.dhtServers {
+		srv.Close()
+	}
+	for _, conn := range dm.dhtConns {
+		conn.Close()
+	}
+}
+
+// bootstrapAndInitCatalog bootstraps the DHT in the background, then sets
+// up the catalogs. With a restored routing table the catalogs are set up
+// right away. Failed bootstraps are retried with backoff meanwhile.
 func (dm *DHTManager) bootstrapAndInitCatalog() {
-	// Run bootstrap in background
 	go func() {
-		fmt.Println("[DHT Bootstrap] Starting DHT network bootstrap...")
-		
-		// Use the DHT server's built-in bootstrap method
-		fmt.Println("[DHT Bootstrap] Creating context with 30s timeout...")
-		ctx, cancel := context.WithTimeout(dm.ctx, 30*time.Second)
-		defer cancel()
-		
-		fmt.Println("[DHT Bootstrap] Calling BootstrapContext...")
-		stats, err := dm.dhtServer.BootstrapContext(ctx)
-		if err != nil {
-			fmt.Printf("[DHT Bootstrap] Bootstrap error: %v\n", err)
-			// Continue anyway, might still work
+		restored := dm.GetBootstrapState().Restored > 0
+		if restored {
+			fmt.Println("[DHT Bootstrap] Using the restored routing table, bootstrapping in the background")
+			go dm.bootstrapper.run(dm.ctx, true)
 		} else {
-			fmt.Printf("[DHT Bootstrap] Bootstrap completed successfully\n")
-			fmt.Printf("[DHT Bootstrap] Stats: %+v\n", stats)
-			if stats.NumResponses == 0 {
-				fmt.Println("[DHT Bootstrap] WARNING: No responses from bootstrap nodes!")
-				fmt.Println("[DHT Bootstrap] Possible causes:")
-				fmt.Println("[DHT Bootstrap]   - Firewall blocking UDP port (try: sudo pfctl -d to disable macOS firewall temporarily)")
-				fmt.Println("[DHT Bootstrap]   - Network connectivity issues")
-				fmt.Println("[DHT Bootstrap]   - Bootstrap nodes may be down")
+			fmt.Println("[DHT Bootstrap] Starting DHT network bootstrap...")
+			if !dm.bootstrapper.attempt(dm.ctx) {
+				// The catalogs work locally and with gossip until the DHT is reached
+				fmt.Println("[DHT Bootstrap] WARNING: DHT not reached, retrying in the background. Check that UDP traffic is not blocked by a firewall.")
 			}
 		}
-		
-		// Give it a moment to stabilize
-		fmt.Println("[DHT Bootstrap] Waiting 2 seconds for stabilization...")
-		time.Sleep(2 * time.Second)
+		if dm.ctx.Err() != nil {
+			return
+		}
 		
 		// Do some random announces to populate the routing table
-		fmt.Println("[DHT Bootstrap] Performing random announces to populate routing table...")
 		for i := 0; i < 3; i++ {
 			var randomHash [20]byte
 			for j := range randomHash {
 				randomHash[j] = byte(i * 20 + j)
 			}
-			fmt.Printf("[DHT Bootstrap] Announcing random hash %d\n", i+1)
 			dm.dhtServer.Announce(randomHash, 0, true)
 		}
-		
-		// Report final stats
-		nodeCount := dm.GetNodeCount()
-		fmt.Printf("[DHT Bootstrap] DHT initialized with %d nodes\n", nodeCount)
-		if nodeCount == 0 {
-			fmt.Println("[DHT Bootstrap] WARNING: No DHT nodes found after bootstrap!")
-			fmt.Println("[DHT Bootstrap] This may indicate:")
-			fmt.Println("[DHT Bootstrap]   - Network connectivity issues")
-			fmt.Println("[DHT Bootstrap]   - Firewall blocking UDP traffic")
-			fmt.Println("[DHT Bootstrap]   - Bootstrap nodes are unreachable")
-		}
+		fmt.Printf("[DHT Bootstrap] DHT initialized with %d nodes\n", dm.GetNodeCount())
 		
 		// Now that DHT is ready, create the catalog reference
 		dm.initCatalogAfterBootstrap()
 		
-		// Continue to periodically refresh
-		go dm.periodicBootstrap()
+		// Then our own publisher catalog and subscribed publisher catalogs
+		dm.initPublisherCatalogs()
+		
+		// And the catalogs of the other networks we joined
+		dm.initNetworkCatalogs()
+		
+		// Keep retrying failed bootstraps and refresh the routing tables
+		if !restored {
+			go dm.bootstrapper.run(dm.ctx, false)
+		}
 	}()
 }
 
@@ -193,11 +369,16 @@ func (dm *DHTManager) initCatalogAfterBootstrap() {
 		}
 		fmt.Println("[DHT] BEP44 catalog reference created with well-known key")
 		
+		// Let connected peers gossip with and merge into our catalog
+		if dm.torrentManager != nil && dm.torrentManager.gossip != nil {
+			dm.torrentManager.gossip.SetCatalogRef(dm.catalogRef)
+		}
+		
 		// Add any pending announcements to the catalog
 		if len(dm.announcements) > 0 {
 			fmt.Printf("[DHT] Adding %d pending models to catalog...\n", len(dm.announcements))
 			for _, ann := range dm.announcements {
-				if err := dm.catalogRef.AddModel(ann.Name, ann.InfoHash, ann.Size); err != nil {
+				if err := dm.catalogRef.AddModelWithMetadata(ann.Name, ann.InfoHash, ann.Size, announcementMetadata(ann, dm.publisherKey)); err != nil {
 					fmt.Printf("[DHT] Failed to add pending model %s to catalog: %v\n", ann.Name, err)
 				} else {
 					fmt.Printf("[DHT] Added pending model %s to catalog\n", ann.Name)
@@ -210,9 +391,9 @@ func (dm *DHTManager) initCatalogAfterBootstrap() {
 		if len(seedingModels) > 0 {
 			fmt.Printf("[DHT] Found %d seeding models, adding to catalog...\n", len(seedingModels))
 			for _, model := range seedingModels {
-				// Check if not already announced
-				if _, exists := dm.announcements[model.InfoHash]; !exists {
-					if err := dm.catalogRef.AddModel(model.Name, model.InfoHash, 0); err != nil {
+				// Check if not already announced or withdrawn by us
+				if _, exists := dm.announcements[model.InfoHash]; !exists && !dm.catalogRef.IsWithdrawn(model.Name, model.InfoHash) {
+					if err := dm.catalogRef.AddModelWithMetadata(model.Name, model.InfoHash, 0, dm.seededMetadata(model, dm.publisherKey)); err != nil {
 						fmt.Printf("[DHT] Failed to add seeding model %s to catalog: %v\n", model.Name, err)
 					} else {
 						fmt.Printf("[DHT] Added seeding model %s to catalog\n", model.Name)
@@ -228,25 +409,6 @@ func (dm *DHTManager) initCatalogAfterBootstrap() {
 	}
 }
 
-func (dm *DHTManager) periodicBootstrap() {
-	ticker := time.NewTicker(15 * time.Minute)
-	defer ticker.Stop()
-	
-	for {
-		select {
-		case <-dm.ctx.Done():
-			return
-		case <-ticker.C:
-			ctx, cancel := context.WithTimeout(dm.ctx, 30*time.Second)
-			_, err := dm.dhtServer.BootstrapContext(ctx)
-			if err != nil {
-				fmt.Printf("Periodic DHT bootstrap error: %v\n", err)
-			}
-			cancel()
-		}
-	}
-}
-
 func (dm *DHTManager) periodicCatalogRefresh() {
 	// Check for catalog updates and republish every 30 minutes to keep it alive
 	// BEP44 values expire from DHT after ~2 hours, so 30 minutes is safe
@@ -270,14 +432,25 @@ func (dm *DHTManager) periodicCatalogRefresh() {
 					fmt.Printf("[DHT] Failed to refresh catalog: %v\n", err)
 				}
 				
+				// Renew the models we share and drop expired entries
+				published, err := catalogRef.Cleanup(dm.liveInfoHashes())
+				if err != nil {
+					fmt.Printf("[DHT] Catalog cleanup failed: %v\n", err)
+				}
+				
 				// Then republish our catalog reference to keep it alive in DHT
 				// This is critical - without this, the reference expires!
-				if err := catalogRef.RepublishCatalog(); err != nil {
+				if published {
+					fmt.Println("[DHT] Published cleaned up catalog reference")
+				} else if err := catalogRef.RepublishCatalog(); err != nil {
 					fmt.Printf("[DHT] Failed to republish catalog reference: %v\n", err)
 				} else {
 					fmt.Println("[DHT] Successfully republished catalog reference to keep it alive")
 				}
 			}
+			
+			dm.refreshPublisherCatalogs()
+			dm.refreshNetworkCatalogs()
 		}
 	}
 }
@@ -288,19 +461,41 @@ func (dm *DHTManager) AnnounceModel(announcement *types.ModelAnnouncement) error
 	dm.mu.Lock()
 	defer dm.mu.Unlock()
 
+	// Name the domain proving we own the namespace of the model
+	if announcement.Domain == "" {
+		announcement.Domain = dm.namespaceDomain(announcement.Name)
+	}
+
 	// Store announcement for refresh
 	dm.announcements[announcement.InfoHash] = announcement
 	dm.lastAnnounce[announcement.InfoHash] = time.Now()
 	fmt.Printf("[DHTManager] Stored announcement for periodic refresh\n")
 
+	// Announce the torrent itself, so lookups by infohash find us even
+	// while the catalog is stale
+	if len(dm.dhtServers) > 0 && dm.infohashAnnounces.due(announcement.InfoHash, 0, time.Now()) {
+		go dm.announceInfohash(announcement.Name, announcement.InfoHash)
+	}
+
 	// Add to catalog if available
 	if dm.catalogRef != nil {
 		fmt.Printf("[DHTManager] Adding model to catalog torrent...\n")
-		if err := dm.catalogRef.AddModel(announcement.Name, announcement.InfoHash, announcement.Size); err != nil {
+		if err := dm.catalogRef.AddModelWithMetadata(announcement.Name, announcement.InfoHash, announcement.Size, announcementMetadata(announcement, dm.publisherKey)); err != nil {
 			fmt.Printf("[DHTManager] Catalog update failed: %v\n", err)
 			return fmt.Errorf("failed to add model to catalog: %w", err)
 		}
 		fmt.Printf("[DHTManager] Successfully added model %s to catalog\n", announcement.Name)
+		
+		// Offer the updated catalog to gossip peers right away
+		if dm.torrentManager != nil && dm.torrentManager.gossip != nil {
+			go dm.torrentManager.gossip.Broadcast()
+		}
+		
+		// Also publish it in our own publisher catalog
+		go dm.addToPublisherCatalog(announcement)
+		
+		// And in the catalogs of the networks we publish to
+		go dm.addToNetworkCatalogs(announcement)
 	} else {
 		fmt.Printf("[DHTManager] WARNING: Catalog reference not yet initialized, model will be added when catalog is ready\n")
 		// The model is stored in announcements and will be added to catalog when it's initialized
@@ -308,6 +503,34 @@ func (dm *DHTManager) AnnounceModel(announcement *types.ModelAnnouncement) error
 	return nil
 }
 
+// seededMetadata is the catalog metadata of a model we seed without having
+// announced it, published under key
+func (dm *DHTManager) seededMetadata(mt *ManagedTorrent, key ed25519.PrivateKey) discovery.ModelMetadata {
+	return announcementMetadata(&types.ModelAnnouncement{Name: mt.Name, InfoHash: mt.InfoHash, Domain: dm.namespaceDomain(mt.Name)}, key)
+}
+
+// announcementMetadata extracts the searchable catalog metadata from an
+// announcement published under key, which signs the domain it names
+func announcementMetadata(ann *types.ModelAnnouncement, key ed25519.PrivateKey) discovery.ModelMetadata {
+	var publisher string
+	if key != nil {
+		publisher = hex.EncodeToString(key.Public().(ed25519.PublicKey))
+	}
+	return discovery.ModelMetadata{
+		Description: ann.Description,
+		Tags:        ann.Tags,
+		License:     ann.License,
+		Parameters:  ann.Parameters,
+		Publisher:   publisher,
+		LicenseURL:  ann.LicenseURL,
+		RequireLicenseAcceptance: ann.RequireLicenseAcceptance,
+		Provenance:  ann.Provenance,
+		Equivalents: ann.Equivalents,
+		Domain:      ann.Domain,
+		DomainSignature: discovery.SignNamespaceClaim(key, ann.Name, ann.InfoHash, ann.Domain),
+	}
+}
+
 func (dm *DHTManager) RefreshAnnouncements() error {
 	dm.mu.RLock()
 	announcements := make([]*types.ModelAnnouncement, 0, len(dm.announcements))
@@ -317,11 +540,12 @@ func (dm *DHTManager) RefreshAnnouncements() error {
 			announcements = append(announcements, ann)
 		}
 	}
+	publisherKey := dm.publisherKey
 	dm.mu.RUnlock()
 
 	for _, ann := range announcements {
 		if dm.catalogRef != nil {
-			if err := dm.catalogRef.AddModel(ann.Name, ann.InfoHash, ann.Size); err != nil {
+			if err := dm.catalogRef.AddModelWithMetadata(ann.Name, ann.InfoHash, ann.Size, announcementMetadata(ann, publisherKey)); err != nil {
 				fmt.Printf("Failed to refresh announcement for %s: %v\n", ann.Name, err)
 				continue
 			}
@@ -351,12 +575,20 @@ func (dm *DHTManager) RefreshSeedingModels() error {
 	fmt.Printf("[DHT] Found %d seeding models to refresh in catalog\n", len(seedingTorrents))
 	
 	// Refresh each seeded model in the catalog
+	dm.mu.RLock()
+	publisherKey := dm.publisherKey
+	dm.mu.RUnlock()
 	refreshed := 0
 	for _, mt := range seedingTorrents {
+		// Models we unpublished keep seeding but stay out of the catalog
+		if dm.catalogRef.IsWithdrawn(mt.Name, mt.InfoHash) {
+			continue
+		}
+		
 		fmt.Printf("[DHT] Refreshing catalog entry for seeded model: %s\n", mt.Name)
 		
 		// Re-add model to keep the catalog entry alive
-		if err := dm.catalogRef.AddModel(mt.Name, mt.InfoHash, 0); err != nil {
+		if err := dm.catalogRef.AddModelWithMetadata(mt.Name, mt.InfoHash, 0, dm.seededMetadata(mt, publisherKey)); err != nil {
 			fmt.Printf("[DHT] Failed to refresh catalog entry for %s: %v\n", mt.Name, err)
 			continue
 		}
@@ -369,7 +601,27 @@ func (dm *DHTManager) RefreshSeedingModels() error {
 	return nil
 }
 
+// SetModelFilter sets the filter of models hidden from discovery
+func (dm *DHTManager) SetModelFilter(filter *blocklist.Filter) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.modelFilter = filter
+}
+
+// blockedModels returns the filter of models hidden from discovery
+func (dm *DHTManager) blockedModels() *blocklist.Filter {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.modelFilter
+}
+
 func (dm *DHTManager) DiscoverModels(pattern string) ([]*types.ModelAnnouncement, error) {
+	return dm.SearchModels(types.SearchFilter{Pattern: pattern})
+}
+
+// SearchModels searches the catalog with the given filters. When CheckHealth or
+// MinSeeders is set, the swarm of every matching model is probed on the DHT.
+func (dm *DHTManager) SearchModels(filter types.SearchFilter) ([]*types.ModelAnnouncement, error) {
 	if dm.catalogRef == nil {
 		return nil, fmt.Errorf("catalog not available")
 	}
@@ -382,23 +634,77 @@ func (dm *DHTManager) DiscoverModels(pattern string) ([]*types.ModelAnnouncement
 	}
 	
 	// Use catalog for discovery
-	results, err := dm.catalogRef.GetModels(pattern)
+	results, err := dm.catalogRef.SearchModels(filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to discover models: %w", err)
 	}
+	
+	// Include models from the other networks and subscribed publisher catalogs
+	results = dm.mergeNetworkResults(results, filter)
+	results = dm.mergeSubscribedResults(results, filter)
+	
+	if filter.Network != "" {
+		if results, err = dm.filterByNetwork(results, filter.Network); err != nil {
+			return nil, err
+		}
+	}
+	
+	if filter.CheckHealth || filter.MinSeeders > 0 {
+		dm.annotateHealth(results)
+	}
+	
+	if filter.MinSeeders > 0 {
+		results = filterBySeeders(results, filter.MinSeeders)
+	}
+	
+	// Hide models on the blocklist
+	results = filterBlockedModels(results, dm.blockedModels())
+	
+	// Mark the models whose publisher proved it owns their namespace
+	dm.annotateVerified(dm.ctx, results)
 
 	return results, nil
 }
 
+// filterBySeeders keeps the models with at least minSeeders seeders
+func filterBySeeders(models []*types.ModelAnnouncement, minSeeders int) []*types.ModelAnnouncement {
+	var results []*types.ModelAnnouncement
+	for _, model := range models {
+		if model.Seeders >= minSeeders {
+			results = append(results, model)
+		}
+	}
+	return results
+}
+
 func (dm *DHTManager) GetNodeCount() int {
 	if dm.dhtServer == nil {
 		fmt.Println("[DHT] GetNodeCount: DHT server is nil")
 		return 0
 	}
 	
-	stats := dm.dhtServer.Stats()
-	fmt.Printf("[DHT] GetNodeCount: Nodes=%d, GoodNodes=%d\n", stats.Nodes, stats.GoodNodes)
-	return stats.Nodes
+	var nodes, goodNodes int
+	for _, srv := range dm.dhtServers {
+		stats := srv.Stats()
+		nodes += stats.Nodes
+		goodNodes += stats.GoodNodes
+	}
+	fmt.Printf("[DHT] GetNodeCount: Nodes=%d, GoodNodes=%d\n", nodes, goodNodes)
+	return nodes
+}
+
+// GetNodesByFamily returns the number of DHT routing table nodes per address
+// family
+func (dm *DHTManager) GetNodesByFamily() map[string]int {
+	counts := map[string]int{familyIPv4: 0, familyIPv6: 0}
+	for _, srv := range dm.dhtServers {
+		for _, node := range srv.Nodes() {
+			if family := ipFamily(node.Addr.IP); family != "" {
+				counts[family]++
+			}
+		}
+	}
+	return counts
 }
 
 // GetCatalogRef returns the BEP44 catalog reference manager
@@ -413,15 +719,32 @@ func (dm *DHTManager) GetStats() map[string]interface{} {
 	stats := make(map[string]interface{})
 	
 	if dm.dhtServer != nil {
-		dhtStats := dm.dhtServer.Stats()
-		stats["nodes"] = dhtStats.Nodes
-		stats["good_nodes"] = dhtStats.GoodNodes
+		var nodes, goodNodes int
+		for _, srv := range dm.dhtServers {
+			dhtStats := srv.Stats()
+			nodes += dhtStats.Nodes
+			goodNodes += dhtStats.GoodNodes
+		}
+		stats["nodes"] = nodes
+		stats["good_nodes"] = goodNodes
+		stats["nodes_by_family"] = dm.GetNodesByFamily()
+		stats["bootstrap"] = dm.GetBootstrapState()
 		// These fields may not exist in the DHT stats
 		stats["torrents"] = 0
 		stats["peers"] = 0
 	}
 	
 	stats["announcements"] = len(dm.announcements)
+	stats["infohashes_announced"] = dm.announcedInfohashes()
+	stats["swarm_health_cached"] = dm.healthCache.Len()
+	stats["subscriptions"] = len(dm.subscriptions)
+	stats["networks"] = len(dm.networks) + 1
+	if dm.catalogRef != nil {
+		stats["catalog_conflicts"] = dm.catalogRef.Conflicts()
+	}
+	if dm.torrentManager != nil && dm.torrentManager.gossip != nil {
+		stats["gossip_peers"] = dm.torrentManager.gossip.PeerCount()
+	}
 	stats["last_refresh"] = dm.getLastRefreshTime()
 	
 	return stats
@@ -441,16 +764,14 @@ func (dm *DHTManager) Stop() {
 	// Don't try to update catalog during shutdown - context is being cancelled
 	// Just cleanly shut down
 	dm.cancel()
+	dm.closePublisherCatalogs()
+	dm.closeNetworkCatalogs()
 	
-	// Close the DHT server first
-	if dm.dhtServer != nil {
-		dm.dhtServer.Close()
-	}
+	// Keep the routing table for the next start
+	dm.saveNodeCache()
 	
-	// Then close the DHT connection
-	if dm.dhtConn != nil {
-		dm.dhtConn.Close()
-	}
+	// Close the DHT servers, then their connections
+	dm.closeDHTServers()
 }
 
 // AddTorrentToDHT adds a torrent to DHT for peer discovery
@@ -471,4 +792,5 @@ func (dm *DHTManager) RemoveTorrentFromDHT(infoHash string) {
 	
 	delete(dm.announcements, infoHash)
 	delete(dm.lastAnnounce, infoHash)
+	dm.infohashAnnounces.forget(infoHash)
 }
\ No newline at end of file
@@ -1,10 +1,14 @@
 package daemon
 
 import (
+	"net"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/anacrolix/dht/v2"
+	"github.com/anacrolix/dht/v2/krpc"
 	"github.com/silmaril/silmaril/internal/config"
 	"github.com/silmaril/silmaril/pkg/types"
 	"github.com/stretchr/testify/assert"
@@ -264,4 +268,45 @@ func TestDHTManagerDisabled(t *testing.T) {
 			assert.False(t, b)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestDHTManagerRestoresRoutingTable(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Storage: config.StorageConfig{BaseDir: tmpDir},
+		Network: config.NetworkConfig{DHTEnabled: true},
+	}
+	cachePath := filepath.Join(tmpDir, "db", "dht_nodes.dat")
+	require.NoError(t, os.MkdirAll(filepath.Dir(cachePath), 0755))
+	var nodes []krpc.NodeInfo
+	for i := 1; i <= 3; i++ {
+		nodes = append(nodes, krpc.NodeInfo{
+			ID:   krpc.ID{byte(i)},
+			Addr: krpc.NodeAddr{IP: net.IPv4(192, 0, 2, byte(i)), Port: 6881},
+		})
+	}
+	require.NoError(t, dht.WriteNodesToFile(nodes, cachePath))
+
+	tm, err := NewTorrentManager(cfg, NewState(filepath.Join(tmpDir, "state.json")))
+	require.NoError(t, err)
+	defer tm.Stop()
+
+	dm, err := NewDHTManager(cfg, tm)
+	require.NoError(t, err)
+	assert.Equal(t, 3, dm.GetBootstrapState().Restored)
+	assert.GreaterOrEqual(t, dm.GetNodeCount(), 3)
+
+	// Stopping saves the routing table again
+	require.NoError(t, os.Remove(cachePath))
+	dm.Stop()
+	saved, err := dht.ReadNodesFromFile(cachePath)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(saved), 3)
+
+	// Old routing tables are not restored
+	old := time.Now().Add(-nodeCacheMaxAge - time.Hour)
+	require.NoError(t, os.Chtimes(cachePath, old, old))
+	dm, err = NewDHTManager(cfg, tm)
+	require.NoError(t, err)
+	defer dm.Stop()
+	assert.Zero(t, dm.GetBootstrapState().Restored)
+}
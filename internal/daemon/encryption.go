@@ -0,0 +1,517 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/encryption"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/proxy"
+	"github.com/silmaril/silmaril/internal/signing"
+	"github.com/silmaril/silmaril/internal/storage"
+	torrentclient "github.com/silmaril/silmaril/internal/torrent"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// How often completed downloads of encrypted models are decrypted
+const decryptionInterval = 15 * time.Second
+
+// How long fetching a key from a key endpoint may take
+const keyFetchTimeout = 30 * time.Second
+
+// decryptions tracks the decryption of downloaded encrypted models
+type decryptions struct {
+	mu      sync.Mutex
+	running map[string]bool   // Models being decrypted
+	tokens  map[string]string // Key endpoint tokens by model
+	waiting map[string]bool   // Models whose missing key was reported
+	failed  map[string]bool   // Models that failed to decrypt, not retried
+}
+
+// start marks a model as being decrypted, it returns false if it already is
+func (s *decryptions) start(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running == nil {
+		s.running = make(map[string]bool)
+	}
+	if s.running[name] {
+		return false
+	}
+	s.running[name] = true
+	return true
+}
+
+func (s *decryptions) finish(name string) {
+	s.mu.Lock()
+	delete(s.running, name)
+	s.mu.Unlock()
+}
+
+func (s *decryptions) setToken(name, token string) {
+	s.mu.Lock()
+	if s.tokens == nil {
+		s.tokens = make(map[string]string)
+	}
+	s.tokens[name] = token
+	s.mu.Unlock()
+}
+
+func (s *decryptions) token(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[name]
+}
+
+// keyMissing records that a model waits for its key and reports whether
+// this is news
+func (s *decryptions) keyMissing(name string, missing bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.waiting == nil {
+		s.waiting = make(map[string]bool)
+	}
+	news := missing && !s.waiting[name]
+	if missing {
+		s.waiting[name] = true
+	} else {
+		delete(s.waiting, name)
+	}
+	return news
+}
+
+// setFailed records whether a model failed to decrypt with its key.
+// Decrypting again would fail the same way until another key is set.
+func (s *decryptions) setFailed(name string, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failed == nil {
+		s.failed = make(map[string]bool)
+	}
+	if failed {
+		s.failed[name] = true
+	} else {
+		delete(s.failed, name)
+	}
+}
+
+func (s *decryptions) hasFailed(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failed[name]
+}
+
+// modelKeysDir returns where the keys of encrypted models are stored
+func (d *Daemon) modelKeysDir() string {
+	if d.config != nil && d.config.Security.KeysDir != "" {
+		return filepath.Join(d.config.Security.KeysDir, "models")
+	}
+	return filepath.Join(storage.GetBaseDir(), "keys", "models")
+}
+
+// encryptedModelPath returns where the encrypted files of a model are seeded from
+func encryptedModelPath(name string) string {
+	return filepath.Join(storage.GetEncryptedDir(), name)
+}
+
+// EncryptModel encrypts the files of a model with a new key for publishing.
+// The encrypted files are written to their own directory, which is seeded
+// instead of the model. It returns the encryption info for the manifest,
+// the hex encoded key to hand to licensees and the encrypted directory.
+// Files left out by ignore are not encrypted, nor published.
+func (d *Daemon) EncryptModel(name, modelPath, keyURL string, ignore *storage.Ignore) (*types.EncryptionInfo, string, string, error) {
+	if keyURL != "" {
+		if err := CheckKeyURL(keyURL); err != nil {
+			return nil, "", "", err
+		}
+	}
+	key, err := encryption.GenerateKey()
+	if err != nil {
+		return nil, "", "", err
+	}
+	keyID, err := encryption.SaveKey(d.modelKeysDir(), key)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	encryptedPath := encryptedModelPath(name)
+	if err := os.RemoveAll(encryptedPath); err != nil {
+		return nil, "", "", fmt.Errorf("failed to clear encrypted files: %w", err)
+	}
+
+	// Encrypt the files a torrent of the model would hold
+	root := storage.ResolveModelDir(modelPath)
+	files, _, err := torrentclient.DirectoryFiles(root, ignore)
+	if err == nil {
+		for _, file := range files {
+			relPath := filepath.FromSlash(file.Path[0])
+			if err = encryption.EncryptFile(filepath.Join(root, relPath), filepath.Join(encryptedPath, relPath), key); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		os.RemoveAll(encryptedPath)
+		return nil, "", "", fmt.Errorf("failed to encrypt model: %w", err)
+	}
+
+	fmt.Printf("[Encryption] Encrypted %s with key %s\n", name, keyID)
+	info := &types.EncryptionInfo{
+		Cipher: encryption.Cipher,
+		KeyID:  keyID,
+		KeyURL: keyURL,
+	}
+	return info, hex.EncodeToString(key), encryptedPath, nil
+}
+
+// SetModelKey stores the key of an encrypted model, or the token for its
+// key endpoint, and decrypts the model if it is already downloaded. It
+// reports whether a decryption was started.
+func (d *Daemon) SetModelKey(name, key, token string) (bool, error) {
+	if key == "" && token == "" {
+		return false, fmt.Errorf("a key or key token is required")
+	}
+	if key != "" {
+		parsed, err := encryption.ParseKey(key)
+		if err != nil {
+			return false, err
+		}
+		if _, err := encryption.SaveKey(d.modelKeysDir(), parsed); err != nil {
+			return false, err
+		}
+	}
+	if token != "" {
+		d.decryptions.setToken(name, token)
+	}
+	d.decryptions.setFailed(name, false)
+
+	if d.torrentManager == nil {
+		return false, nil
+	}
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		if mt.Name != name {
+			continue
+		}
+		manifest, ok := pendingDecryption(mt)
+		if !ok {
+			return false, nil
+		}
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			d.decryptModel(mt, manifest)
+		}()
+		return true, nil
+	}
+	return false, nil
+}
+
+// decryptionWorker decrypts encrypted models once their download completes
+func (d *Daemon) decryptionWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(decryptionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.decryptCompletedDownloads()
+		}
+	}
+}
+
+// decryptCompletedDownloads decrypts every downloaded encrypted model whose
+// key is available
+func (d *Daemon) decryptCompletedDownloads() {
+	if d.torrentManager == nil {
+		return
+	}
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		if d.decryptions.hasFailed(mt.Name) {
+			continue
+		}
+		if manifest, ok := pendingDecryption(mt); ok {
+			d.decryptModel(mt, manifest)
+		}
+	}
+}
+
+// pendingDecryption returns the manifest of a downloaded encrypted model
+// that was not decrypted yet. Published models are seeded from their
+// encrypted directory and have their plaintext in the models directory.
+func pendingDecryption(mt *ManagedTorrent) (*types.ModelManifest, bool) {
+	if !mt.Complete() || isUpdateDownload(mt) {
+		return nil, false
+	}
+	manifest, err := models.ReadManifest(mt.StoragePath)
+	if err != nil || manifest.Encryption == nil {
+		return nil, false
+	}
+
+	modelPath, err := modelPathFor(mt.Name)
+	if err != nil {
+		return nil, false
+	}
+	if filepath.Clean(mt.StoragePath) == modelPath || storage.IsPartial(modelPath) {
+		return manifest, true
+	}
+	_, err = os.Stat(filepath.Join(modelPath, models.ManifestFileName))
+	return manifest, os.IsNotExist(err)
+}
+
+// decryptModel decrypts a downloaded encrypted model into the models
+// directory. The encrypted files keep being seeded from their own directory.
+func (d *Daemon) decryptModel(mt *ManagedTorrent, manifest *types.ModelManifest) {
+	if !d.decryptions.start(mt.Name) {
+		return
+	}
+	defer d.decryptions.finish(mt.Name)
+
+	key, err := d.modelKey(mt.Name, manifest)
+	if err != nil {
+		if d.decryptions.keyMissing(mt.Name, true) {
+			d.events.Publish(EventKeyMissing, mt.Name, "downloaded but encrypted: %v", err)
+		}
+		return
+	}
+	d.decryptions.keyMissing(mt.Name, false)
+
+	if err := d.decryptTorrent(mt, manifest, key); err != nil {
+		d.decryptions.setFailed(mt.Name, true)
+		d.events.Publish(EventDecryptionFailed, mt.Name, "failed to decrypt: %v", err)
+		return
+	}
+	d.events.Publish(EventModelDecrypted, mt.Name, "decrypted and verified")
+	d.modelReady(mt.Name, strings.ToLower(mt.InfoHash))
+}
+
+func (d *Daemon) decryptTorrent(mt *ManagedTorrent, manifest *types.ModelManifest, key []byte) error {
+	modelPath, err := modelPathFor(mt.Name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[Encryption] Decrypting %s\n", mt.Name)
+
+	// Only complete files are decrypted, the selection may leave out some
+	var files []string
+	for _, f := range mt.Torrent.Files() {
+		if f.BytesCompleted() == f.Length() {
+			files = append(files, f.DisplayPath())
+		}
+	}
+
+	// Models downloaded into the models directory move their encrypted files
+	// out of the way of the plaintext
+	encryptedPath := mt.StoragePath
+	if filepath.Clean(mt.StoragePath) == modelPath {
+		var err error
+		if encryptedPath, err = d.relocateEncrypted(mt); err != nil {
+			return err
+		}
+	}
+
+	// Hide the model until all files are decrypted
+	if err := storage.MarkPartial(modelPath); err != nil {
+		return err
+	}
+
+	hashes := make(map[string]string)
+	for _, f := range manifest.Files {
+		hashes[f.Path] = f.SHA256
+	}
+	for _, file := range files {
+		h := sha256.New()
+		dst := filepath.Join(modelPath, filepath.FromSlash(file))
+		if err := encryption.DecryptFile(filepath.Join(encryptedPath, filepath.FromSlash(file)), dst, key, h); err != nil {
+			return err
+		}
+		if want := hashes[file]; want != "" && want != hex.EncodeToString(h.Sum(nil)) {
+			os.Remove(dst)
+			return fmt.Errorf("%s does not match its manifest hash", file)
+		}
+	}
+
+	if err := models.WriteManifest(modelPath, manifest); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+	return storage.ClearPartial(modelPath)
+}
+
+// relocateEncrypted moves the encrypted files of a download from the models
+// directory to the encrypted directory and seeds them from there. Their
+// pieces are verified again when the torrent is added back.
+func (d *Daemon) relocateEncrypted(mt *ManagedTorrent) (string, error) {
+	torrentPath := filepath.Join(storage.GetTorrentsDir(), mt.InfoHash+".torrent")
+	if _, err := os.Stat(torrentPath); err != nil {
+		if err := saveMetainfo(mt.Torrent.Metainfo(), torrentPath); err != nil {
+			return "", err
+		}
+	}
+	files := mt.Files()
+
+	if err := d.torrentManager.RemoveTorrent(mt.InfoHash); err != nil {
+		return "", err
+	}
+
+	encryptedPath := encryptedModelPath(mt.Name)
+	if err := os.MkdirAll(filepath.Dir(encryptedPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create encrypted directory: %w", err)
+	}
+	if err := os.RemoveAll(encryptedPath); err != nil {
+		return "", fmt.Errorf("failed to clear encrypted files: %w", err)
+	}
+	if err := os.Rename(mt.StoragePath, encryptedPath); err != nil {
+		return "", fmt.Errorf("failed to move encrypted files: %w", err)
+	}
+	if err := storage.ClearPartial(encryptedPath); err != nil {
+		fmt.Printf("[Encryption] Warning: %v\n", err)
+	}
+
+	seeded, err := d.torrentManager.AddTorrentForSeeding(torrentPath, mt.Name, encryptedPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to seed encrypted files: %w", err)
+	}
+	if len(files) > 0 {
+		if _, err := d.torrentManager.SetFileSelection(seeded.InfoHash, files); err != nil {
+			fmt.Printf("[Encryption] Warning: %v\n", err)
+		}
+	}
+	if err := d.state.Save(); err != nil {
+		fmt.Printf("[Encryption] Warning: failed to save state: %v\n", err)
+	}
+	return encryptedPath, nil
+}
+
+// modelKey returns the key of an encrypted model from the key store, or
+// fetches it from the key endpoint of the model
+func (d *Daemon) modelKey(name string, manifest *types.ModelManifest) ([]byte, error) {
+	info := manifest.Encryption
+	if key, err := encryption.LoadKey(d.modelKeysDir(), info.KeyID); err == nil {
+		return key, nil
+	}
+	if info.KeyURL == "" {
+		return nil, fmt.Errorf("no key %s, add it with: silmaril decrypt %s --key <key>", info.KeyID, name)
+	}
+	if err := d.trustKeyURL(name, manifest); err != nil {
+		return nil, fmt.Errorf("not fetching key %s from %s: %w", info.KeyID, info.KeyURL, err)
+	}
+
+	keyProxy, err := d.Proxy(proxy.Git)
+	if err != nil {
+		return nil, err
+	}
+	key, err := fetchKey(keyProxy.HTTPClient(keyFetchTimeout), info.KeyURL, d.decryptions.token(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key from %s: %w", info.KeyURL, err)
+	}
+	if encryption.KeyID(key) != info.KeyID {
+		return nil, fmt.Errorf("key from %s is not key %s", info.KeyURL, info.KeyID)
+	}
+	if _, err := encryption.SaveKey(d.modelKeysDir(), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// trustKeyURL checks that the key endpoint of a manifest was set by someone
+// we trust with the decrypt token of the model: the manifest must be signed
+// with our publisher key, the key of a followed publisher, or under the
+// policy of a configured organization. Any peer can send unsigned manifests.
+func (d *Daemon) trustKeyURL(name string, manifest *types.ModelManifest) error {
+	if err := CheckKeyURL(manifest.Encryption.KeyURL); err != nil {
+		return err
+	}
+	named := *manifest
+	named.Name = name
+	signers, err := signing.VerifySignatures(&named)
+	if err != nil {
+		return err
+	}
+	if err := checkManifestSignatures(&named, d.organizations()); err != nil {
+		return err
+	}
+
+	if named.SignaturePolicy != nil && d.verifyManifests() {
+		for _, org := range manifestOrganizations(&named) {
+			if _, ok := d.organizations()[strings.ToLower(org)]; ok {
+				return nil
+			}
+		}
+	}
+	trusted := d.trustedPublishers()
+	for _, signer := range signers {
+		if trusted[signer] {
+			return nil
+		}
+	}
+	return fmt.Errorf("manifest is not signed by a followed publisher or organization")
+}
+
+// trustedPublishers returns our own publisher key and the keys of followed
+// publishers
+func (d *Daemon) trustedPublishers() map[string]bool {
+	trusted := make(map[string]bool)
+	if d.dhtManager != nil {
+		if key := d.dhtManager.PublisherKey(); key != "" {
+			trusted[strings.ToLower(key)] = true
+		}
+	}
+	if d.state != nil {
+		for _, sub := range d.state.GetSubscriptions() {
+			trusted[strings.ToLower(sub.PublicKey)] = true
+		}
+	}
+	return trusted
+}
+
+// CheckKeyURL checks that a key endpoint is an https URL. Licensees send
+// their token to it, plain http would give it away.
+func CheckKeyURL(keyURL string) error {
+	u, err := url.Parse(keyURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("key endpoint %s is not an https URL", keyURL)
+	}
+	return nil
+}
+
+// fetchKey gets a hex encoded key from a key endpoint, authenticating with
+// token if set
+func fetchKey(client *http.Client, keyURL, token string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, keyURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("key endpoint returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return nil, err
+	}
+	return encryption.ParseKey(string(body))
+}
@@ -0,0 +1,137 @@
+package daemon
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/encryption"
+	"github.com/silmaril/silmaril/internal/signing"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecryptions(t *testing.T) {
+	var s decryptions
+
+	assert.True(t, s.start("org/model"))
+	assert.False(t, s.start("org/model"))
+	s.finish("org/model")
+	assert.True(t, s.start("org/model"))
+
+	// A missing key is only reported once
+	assert.True(t, s.keyMissing("org/model", true))
+	assert.False(t, s.keyMissing("org/model", true))
+	assert.False(t, s.keyMissing("org/model", false))
+	assert.True(t, s.keyMissing("org/model", true))
+
+	assert.False(t, s.hasFailed("org/model"))
+	s.setFailed("org/model", true)
+	assert.True(t, s.hasFailed("org/model"))
+	s.setFailed("org/model", false)
+	assert.False(t, s.hasFailed("org/model"))
+
+	s.setToken("org/model", "secret")
+	assert.Equal(t, "secret", s.token("org/model"))
+	assert.Empty(t, s.token("org/other"))
+}
+
+func TestSetModelKey(t *testing.T) {
+	cfg := &config.Config{Security: config.SecurityConfig{KeysDir: t.TempDir()}}
+	d := &Daemon{config: cfg}
+
+	_, err := d.SetModelKey("org/model", "", "")
+	assert.Error(t, err)
+	_, err = d.SetModelKey("org/model", "not-a-key", "")
+	assert.Error(t, err)
+
+	key, err := encryption.GenerateKey()
+	require.NoError(t, err)
+	d.decryptions.setFailed("org/model", true)
+
+	decrypting, err := d.SetModelKey("org/model", hex.EncodeToString(key), "")
+	require.NoError(t, err)
+	assert.False(t, decrypting)
+	assert.False(t, d.decryptions.hasFailed("org/model"))
+
+	// The key is found by the ID in the manifest
+	loaded, err := d.modelKey("org/model", &types.ModelManifest{Encryption: &types.EncryptionInfo{KeyID: encryption.KeyID(key)}})
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	_, err = d.modelKey("org/model", &types.ModelManifest{Encryption: &types.EncryptionInfo{KeyID: "0123456789abcdef"}})
+	assert.Error(t, err)
+}
+
+func TestModelKeyFromEndpoint(t *testing.T) {
+	key, err := encryption.GenerateKey()
+	require.NoError(t, err)
+
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(hex.EncodeToString(key) + "\n"))
+	}))
+	defer server.Close()
+
+	// Keys are fetched through the proxy transport, which has to trust the
+	// test server
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = server.Client().Transport
+	t.Cleanup(func() { http.DefaultTransport = defaultTransport })
+
+	publicKey, publisherKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	cfg := &config.Config{Security: config.SecurityConfig{KeysDir: t.TempDir()}}
+	d := &Daemon{config: cfg, state: NewState(filepath.Join(t.TempDir(), "state.json"))}
+	manifest := &types.ModelManifest{
+		Name:       "org/model",
+		Encryption: &types.EncryptionInfo{Cipher: encryption.Cipher, KeyID: encryption.KeyID(key), KeyURL: server.URL},
+	}
+	require.NoError(t, signing.AddSignature(manifest, publisherKey))
+	d.decryptions.setToken("org/model", "secret")
+
+	// The token is only sent to endpoints set by a followed publisher
+	_, err = d.modelKey("org/model", manifest)
+	assert.ErrorContains(t, err, "not signed by a followed publisher")
+	assert.Zero(t, requests)
+
+	d.state.AddSubscription(&Subscription{PublicKey: hex.EncodeToString(publicKey), AddedAt: time.Now()})
+	fetched, err := d.modelKey("org/model", manifest)
+	require.NoError(t, err)
+	assert.Equal(t, key, fetched)
+
+	// Fetched keys are kept in the key store
+	stored, err := encryption.LoadKey(d.modelKeysDir(), manifest.Encryption.KeyID)
+	require.NoError(t, err)
+	assert.Equal(t, key, stored)
+
+	// The endpoint refuses requests without the token
+	wrong := *manifest.Encryption
+	wrong.KeyID = "0123456789abcdef"
+	other := &types.ModelManifest{Name: "org/other", Encryption: &wrong}
+	require.NoError(t, signing.AddSignature(other, publisherKey))
+	_, err = d.modelKey("org/other", other)
+	assert.ErrorContains(t, err, "status 403")
+
+	// A key that doesn't match the manifest is rejected
+	_, err = d.modelKey("org/model", other)
+	assert.ErrorContains(t, err, "is not key")
+
+	// Tokens are never sent over plain http
+	plain := &types.ModelManifest{Name: "org/model", Encryption: &types.EncryptionInfo{KeyID: "0123456789abcdef", KeyURL: "http://example.com/key"}}
+	require.NoError(t, signing.AddSignature(plain, publisherKey))
+	_, err = d.modelKey("org/model", plain)
+	assert.ErrorContains(t, err, "not an https URL")
+	assert.Equal(t, 3, requests)
+}
@@ -0,0 +1,454 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	torrentStorage "github.com/anacrolix/torrent/storage"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/storage"
+	torrentclient "github.com/silmaril/silmaril/internal/torrent"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+const (
+	// How long fetching the metadata of a torrent declared equivalent may take
+	equivalentMetadataTimeout = 2 * time.Minute
+	// How often files are handed between a download and its equivalent swarms
+	equivalentSwarmInterval = 10 * time.Second
+)
+
+// equivalentSwarms are torrents of the same files as a download, joined so
+// its files are fetched from their swarms too. Each swarm is assigned some of
+// the files, and once it has them the download verifies their pieces from
+// disk instead of fetching them.
+type equivalentSwarms struct {
+	swarms   []*equivalentSwarm
+	own      map[string]bool // Files the download fetches itself
+	takeover bool            // The download fetches all remaining files
+}
+
+// equivalentSwarm is a torrent of the same files as a download, storing them
+// in the same directory
+type equivalentSwarm struct {
+	torrent   *torrent.Torrent
+	verified  bool            // Its files are the ones of the download
+	files     map[string]bool // Files assigned to it
+	handedOff map[string]bool // Files the download verified
+}
+
+// AddEquivalents joins the swarms of torrents declared to hold the same files
+// as a download. They download into its directory and are left once it is
+// complete. Downloads of some of the files of a torrent don't use them. It
+// returns the number of swarms joined.
+func (tm *TorrentManager) AddEquivalents(infoHash string, equivalents []string) int {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	mt, exists := tm.torrents[infoHash]
+	if !exists || len(mt.Files()) > 0 {
+		return 0
+	}
+	if tm.equivalents == nil {
+		tm.equivalents = make(map[string]*equivalentSwarms)
+	}
+	group := tm.equivalents[infoHash]
+	if group == nil {
+		group = &equivalentSwarms{}
+	}
+
+	added := 0
+	for _, equivalent := range equivalents {
+		var hash metainfo.Hash
+		if err := hash.FromHexString(equivalent); err != nil {
+			continue
+		}
+		// Torrents of local models are left alone
+		if _, exists := tm.torrents[hash.HexString()]; exists || group.has(hash) {
+			continue
+		}
+
+		opts := torrent.AddTorrentOpts{
+			InfoHash: hash,
+			Storage:  tm.storageFor(mt.StoragePath),
+		}
+		if mi, err := metainfo.LoadFromFile(filepath.Join(storage.GetTorrentsDir(), hash.HexString()+".torrent")); err == nil {
+			opts.InfoBytes = mi.InfoBytes
+		}
+		t, isNew := tm.client.AddTorrentOpt(opts)
+		if t == nil || !isNew {
+			continue
+		}
+		// Nothing is downloaded until the swarm is assigned files
+		group.swarms = append(group.swarms, &equivalentSwarm{
+			torrent:   t,
+			files:     make(map[string]bool),
+			handedOff: make(map[string]bool),
+		})
+		added++
+	}
+	if len(group.swarms) > 0 {
+		tm.equivalents[infoHash] = group
+		fmt.Printf("[TorrentManager] Downloading %s from %d equivalent swarms too\n", mt.Name, len(group.swarms))
+	}
+	return added
+}
+
+// has reports whether the torrent with hash is one of the swarms
+func (g *equivalentSwarms) has(hash metainfo.Hash) bool {
+	for _, swarm := range g.swarms {
+		if swarm.torrent.InfoHash() == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// dropEquivalentsLocked leaves the equivalent swarms of a download
+func (tm *TorrentManager) dropEquivalentsLocked(infoHash string) {
+	group, ok := tm.equivalents[infoHash]
+	if !ok {
+		return
+	}
+	for _, swarm := range group.swarms {
+		swarm.torrent.Drop()
+	}
+	delete(tm.equivalents, infoHash)
+}
+
+// balanceEquivalents checks the equivalent swarms that got their metadata
+// against their download, splits the remaining files between the download
+// and its swarms, and hands the files a swarm completed to the download.
+// Downloads that are complete, or gone, leave their swarms.
+func (tm *TorrentManager) balanceEquivalents() {
+	tm.mu.Lock()
+	type download struct {
+		mt    *ManagedTorrent
+		group *equivalentSwarms
+	}
+	var downloads []download
+	for infoHash, group := range tm.equivalents {
+		mt, exists := tm.torrents[infoHash]
+		if !exists || mt.Complete() {
+			if exists {
+				fmt.Printf("[TorrentManager] %s is complete, leaving its equivalent swarms\n", mt.Name)
+			}
+			tm.dropEquivalentsLocked(infoHash)
+			continue
+		}
+		if mt.Torrent.Info() != nil {
+			downloads = append(downloads, download{mt, group})
+		}
+	}
+	tm.mu.Unlock()
+
+	// Pieces are hashed without holding the lock
+	for _, dl := range downloads {
+		tm.balanceDownload(dl.mt, dl.group)
+	}
+}
+
+func (tm *TorrentManager) balanceDownload(mt *ManagedTorrent, group *equivalentSwarms) {
+	t := mt.Torrent
+	tm.mu.RLock()
+	swarms := slices.Clone(group.swarms)
+	tm.mu.RUnlock()
+
+	joined := false
+	for _, swarm := range swarms {
+		if swarm.verified || swarm.torrent.Info() == nil {
+			continue
+		}
+		if !torrentclient.SameFiles(t.Info(), swarm.torrent.Info()) {
+			fmt.Printf("[TorrentManager] %s has other files than %s, leaving its swarm\n", swarm.torrent.InfoHash().HexString(), mt.Name)
+			swarm.torrent.Drop()
+			continue
+		}
+		swarm.verified = true
+		joined = true
+	}
+	tm.mu.Lock()
+	group.swarms = slices.DeleteFunc(group.swarms, func(swarm *equivalentSwarm) bool {
+		return swarm.torrent.Info() != nil && !swarm.verified
+	})
+	tm.mu.Unlock()
+	swarms = slices.DeleteFunc(swarms, func(swarm *equivalentSwarm) bool {
+		return !swarm.verified
+	})
+	if joined && !group.takeover {
+		assignEquivalentFiles(t, group, swarms)
+	}
+
+	// Files a swarm completed are verified from disk by the download
+	files := make(map[string]*torrent.File, len(t.Files()))
+	for _, f := range t.Files() {
+		files[f.DisplayPath()] = f
+	}
+	for _, swarm := range swarms {
+		for _, sf := range swarm.torrent.Files() {
+			path := sf.DisplayPath()
+			f, ok := files[path]
+			if !ok || swarm.handedOff[path] || sf.BytesCompleted() < sf.Length() {
+				continue
+			}
+			for i := f.BeginPieceIndex(); i < f.EndPieceIndex(); i++ {
+				// Removing the download closes it
+				select {
+				case <-t.Closed():
+					return
+				default:
+				}
+				if !t.PieceState(i).Complete {
+					t.Piece(i).VerifyData()
+				}
+			}
+			swarm.handedOff[path] = true
+		}
+	}
+
+	// Once its own files are in, the download fetches whatever the swarms
+	// haven't delivered, so a slow or empty swarm doesn't hold it up
+	if !group.takeover && len(group.own) > 0 && ownFilesComplete(t, group.own) {
+		group.takeover = true
+		t.DownloadAll()
+	}
+}
+
+// ownFilesComplete reports whether the download has all files it fetches
+// itself
+func ownFilesComplete(t *torrent.Torrent, own map[string]bool) bool {
+	for _, f := range t.Files() {
+		if own[f.DisplayPath()] && f.BytesCompleted() < f.Length() {
+			return false
+		}
+	}
+	return true
+}
+
+// assignEquivalentFiles splits the files the download is missing between it
+// and its verified swarms
+func assignEquivalentFiles(t *torrent.Torrent, group *equivalentSwarms, verified []*equivalentSwarm) {
+	var missing []*torrent.File
+	for _, f := range t.Files() {
+		if f.BytesCompleted() < f.Length() {
+			missing = append(missing, f)
+		}
+	}
+	sizes := make([]int64, len(missing))
+	for i, f := range missing {
+		sizes[i] = f.Length()
+	}
+	owners := splitFiles(sizes, len(verified)+1)
+
+	group.own = make(map[string]bool)
+	for _, swarm := range verified {
+		swarm.files = make(map[string]bool)
+	}
+	for i, f := range missing {
+		if owners[i] == 0 {
+			group.own[f.DisplayPath()] = true
+		} else {
+			verified[owners[i]-1].files[f.DisplayPath()] = true
+		}
+	}
+
+	applyOwnedFiles(t, group.own)
+	for _, swarm := range verified {
+		applyOwnedFiles(swarm.torrent, swarm.files)
+	}
+}
+
+// applyOwnedFiles makes t download only the files in owned
+func applyOwnedFiles(t *torrent.Torrent, owned map[string]bool) {
+	t.CancelPieces(0, t.NumPieces())
+	for _, f := range t.Files() {
+		if owned[f.DisplayPath()] {
+			f.Download()
+		} else {
+			f.SetPriority(torrent.PiecePriorityNone)
+		}
+	}
+}
+
+// splitFiles assigns files of the given sizes to n sources, largest first to
+// the source with the fewest bytes so far. Ties go to the lowest source, the
+// download itself. It returns the source of each file.
+func splitFiles(sizes []int64, n int) []int {
+	order := make([]int, len(sizes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return sizes[order[i]] > sizes[order[j]]
+	})
+
+	owners := make([]int, len(sizes))
+	loads := make([]int64, n)
+	for _, i := range order {
+		least := 0
+		for source := 1; source < n; source++ {
+			if loads[source] < loads[least] {
+				least = source
+			}
+		}
+		owners[i] = least
+		loads[least] += sizes[i]
+	}
+	return owners
+}
+
+// fetchInfo returns the info of a torrent: the one of a local torrent, from
+// its torrent file, or else from peers
+func (tm *TorrentManager) fetchInfo(ctx context.Context, infoHash string) (*metainfo.Info, error) {
+	var hash metainfo.Hash
+	if err := hash.FromHexString(infoHash); err != nil {
+		return nil, fmt.Errorf("invalid infohash %s: %w", infoHash, err)
+	}
+	if mt, ok := tm.GetTorrent(hash.HexString()); ok && mt.Torrent.Info() != nil {
+		return mt.Torrent.Info(), nil
+	}
+	if mi, err := metainfo.LoadFromFile(filepath.Join(storage.GetTorrentsDir(), hash.HexString()+".torrent")); err == nil {
+		info, err := mi.UnmarshalInfo()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read torrent file: %w", err)
+		}
+		return &info, nil
+	}
+
+	// Nothing is downloaded, the storage only has to exist
+	dir, err := os.MkdirTemp("", "silmaril-metadata-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	t, isNew := tm.client.AddTorrentOpt(torrent.AddTorrentOpts{
+		InfoHash: hash,
+		Storage:  torrentStorage.NewFile(dir),
+	})
+	if t == nil {
+		return nil, fmt.Errorf("failed to add torrent to client")
+	}
+	if isNew {
+		defer t.Drop()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, equivalentMetadataTimeout)
+	defer cancel()
+	select {
+	case <-t.GotInfo():
+		return t.Info(), nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no peer sent the metadata within %v", equivalentMetadataTimeout)
+	}
+}
+
+// DeclareEquivalents records in the manifest of a local model the torrents
+// holding the same files, and announces them with the model so downloads use
+// their swarms too. Each torrent is checked by hashing the local files into
+// its pieces.
+func (d *Daemon) DeclareEquivalents(name string, infoHashes []string) (*types.ModelManifest, error) {
+	if d.torrentManager == nil {
+		return nil, fmt.Errorf("torrent manager not available")
+	}
+	name, modelPath, manifest, err := d.localManifest(name)
+	if err != nil {
+		return nil, err
+	}
+	own := d.modelInfoHash(name)
+	mt, ok := d.torrentManager.GetTorrent(own)
+	if !ok || mt.Torrent.Info() == nil || !mt.Complete() {
+		return nil, fmt.Errorf("%s has no complete torrent", name)
+	}
+
+	equivalents := append([]string(nil), manifest.Equivalents...)
+	for _, infoHash := range infoHashes {
+		infoHash = strings.ToLower(infoHash)
+		if infoHash == own {
+			return nil, fmt.Errorf("%s is the torrent of %s", infoHash, name)
+		}
+		if slices.Contains(equivalents, infoHash) {
+			continue
+		}
+		info, err := d.torrentManager.fetchInfo(d.ctx, infoHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the metadata of %s: %w", infoHash, err)
+		}
+		if err := torrentclient.VerifyEquivalent(mt.StoragePath, mt.Torrent.Info(), info); err != nil {
+			return nil, fmt.Errorf("%s does not hold the files of %s: %w", infoHash, name, err)
+		}
+		equivalents = append(equivalents, infoHash)
+	}
+	sort.Strings(equivalents)
+	if slices.Equal(equivalents, manifest.Equivalents) {
+		return manifest, nil
+	}
+
+	// The manifest changed, so earlier signatures no longer hold. The
+	// signature policy stays for the keys to sign again.
+	manifest.Equivalents = equivalents
+	manifest.Signature = ""
+	manifest.Signatures = nil
+	if err := models.WriteManifest(modelPath, manifest); err != nil {
+		return nil, fmt.Errorf("failed to save manifest: %w", err)
+	}
+	if storageDir := storage.ResolveModelDir(mt.StoragePath); storageDir != storage.ResolveModelDir(modelPath) {
+		if err := models.WriteManifest(storageDir, manifest); err != nil {
+			return nil, fmt.Errorf("failed to save manifest: %w", err)
+		}
+	}
+	d.reloadRegistryModels(name)
+
+	if d.dhtManager != nil {
+		d.dhtManager.AnnounceModel(&types.ModelAnnouncement{
+			Name:                     name,
+			Version:                  manifest.Version,
+			InfoHash:                 own,
+			Size:                     manifest.TotalSize,
+			Description:              manifest.Description,
+			Tags:                     manifest.Keywords(),
+			License:                  manifest.License,
+			Parameters:               manifest.Parameters,
+			LicenseURL:               manifest.LicenseURL,
+			RequireLicenseAcceptance: manifest.RequireLicenseAcceptance,
+			Provenance:               manifest.Provenance,
+			Equivalents:              manifest.Equivalents,
+		})
+	}
+	fmt.Printf("[Daemon] %s has %d equivalent torrents\n", name, len(equivalents))
+	return manifest, nil
+}
+
+// JoinEquivalentSwarms downloads a model from the swarms of the torrents
+// its catalog entry declares equivalent too
+func (d *Daemon) JoinEquivalentSwarms(infoHash string, model *types.ModelAnnouncement) int {
+	if d.torrentManager == nil || model == nil || !strings.EqualFold(model.InfoHash, infoHash) || len(model.Equivalents) == 0 {
+		return 0
+	}
+	return d.torrentManager.AddEquivalents(infoHash, model.Equivalents)
+}
+
+func (d *Daemon) equivalentSwarmWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(equivalentSwarmInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			if d.torrentManager != nil {
+				d.torrentManager.balanceEquivalents()
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/storage"
+	torrentclient "github.com/silmaril/silmaril/internal/torrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitFiles(t *testing.T) {
+	// Largest first, each to the source with the fewest bytes
+	assert.Equal(t, []int{1, 0, 1, 1}, splitFiles([]int64{50, 100, 30, 10}, 2))
+	assert.Equal(t, []int{0, 0}, splitFiles([]int64{5, 5}, 1))
+	assert.Empty(t, splitFiles(nil, 3))
+}
+
+func TestEquivalentSwarms(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	tm, _, tmpDir := setupTestTorrentManager(t)
+	defer tm.Stop()
+
+	sourceDir := filepath.Join(tmpDir, "source")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	a := bytes.Repeat([]byte("a"), 4*16384)
+	b := bytes.Repeat([]byte("b"), 70000)
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "a.gguf"), a, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "b.gguf"), b, 0644))
+
+	// The same files published with two piece lengths
+	torrentPath := filepath.Join(tmpDir, "model.torrent")
+	_, err := torrentclient.CreateTorrentFromDirectory(sourceDir, torrentPath, 16384, nil)
+	require.NoError(t, err)
+	otherPath := filepath.Join(tmpDir, "other.torrent")
+	other, err := torrentclient.CreateTorrentFromDirectory(sourceDir, otherPath, 32768, nil)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(storage.GetTorrentsDir(), 0755))
+	require.NoError(t, os.Rename(otherPath, filepath.Join(storage.GetTorrentsDir(), other+".torrent")))
+
+	downloadDir := filepath.Join(tmpDir, "download")
+	mt, err := tm.AddTorrentForDownload(torrentPath, "org/model", downloadDir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, tm.AddEquivalents(mt.InfoHash, []string{other, "not-a-hash", mt.InfoHash}))
+	assert.Equal(t, 0, tm.AddEquivalents(mt.InfoHash, []string{other}), "a swarm is joined once")
+
+	// The larger file is fetched by the download, the other by the swarm
+	tm.balanceEquivalents()
+	group := tm.equivalents[mt.InfoHash]
+	require.NotNil(t, group)
+	require.Len(t, group.swarms, 1)
+	swarm := group.swarms[0]
+	assert.True(t, swarm.verified)
+	assert.Equal(t, map[string]bool{"b.gguf": true}, group.own)
+	assert.Equal(t, map[string]bool{"a.gguf": true}, swarm.files)
+
+	// Once the swarm has its file, the download verifies it from disk
+	require.NoError(t, os.WriteFile(filepath.Join(downloadDir, "a.gguf"), a, 0644))
+	swarm.torrent.VerifyData()
+	tm.balanceEquivalents()
+	assert.True(t, swarm.handedOff["a.gguf"])
+	completed, _ := mt.WantedBytes()
+	assert.Equal(t, int64(len(a)), completed)
+	assert.False(t, group.takeover)
+
+	// With its own file in, the download is complete and leaves the swarm
+	require.NoError(t, os.WriteFile(filepath.Join(downloadDir, "b.gguf"), b, 0644))
+	mt.Torrent.VerifyData()
+	require.True(t, mt.Complete())
+	tm.balanceEquivalents()
+	assert.Empty(t, tm.equivalents)
+	select {
+	case <-swarm.torrent.Closed():
+	default:
+		t.Fatal("the swarm of a complete download is left")
+	}
+}
@@ -0,0 +1,119 @@
+package daemon
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Number of events kept in memory
+const DefaultEventBufferSize = 1000
+
+// Event types
+const (
+	EventUpdateAvailable    = "update.available"
+	EventUpdateStarted      = "update.started"
+	EventUpdateCompleted    = "update.completed"
+	EventUpdateFailed       = "update.failed"
+	EventSeedingStopped     = "seeding.stopped"
+	EventScrubCorrupt       = "scrub.corrupt"
+	EventManifestReceived   = "manifest.received"
+	EventManifestRejected   = "manifest.rejected"
+	EventKeyMissing         = "encryption.key_missing"
+	EventModelDecrypted     = "encryption.decrypted"
+	EventDecryptionFailed   = "encryption.failed"
+	EventLicenseAccepted    = "license.accepted"
+	EventPublishCompleted   = "publish.completed"
+	EventPublishFailed      = "publish.failed"
+	EventModelRenamed       = "model.renamed"
+	EventModelAdded         = "model.added"
+	EventModelRemoved       = "model.removed"
+	EventModelBlocked       = "model.blocked"
+	EventCatalogAdded       = "catalog.added"
+	EventCatalogUpdated     = "catalog.updated"
+	EventDownloadCompleted  = "download.completed"
+	EventDownloadFailed     = "download.failed"
+	EventDownloadCancelled  = "download.cancelled"
+	EventDownloadVerified   = "download.verified"
+	EventDownloadCorrupt    = "download.corrupt"
+	EventQuarantineReleased = "quarantine.released"
+	EventKeyRotated         = "publisher.key_rotated"
+	EventModelPinned        = "pinning.pinned"
+	EventHookCompleted      = "hook.completed"
+	EventHookFailed         = "hook.failed"
+	EventModelConverted     = "convert.completed"
+	EventConvertFailed      = "convert.failed"
+)
+
+// Event is a notable thing that happened in the daemon, such as a model
+// update. Clients poll the events API to be notified.
+type Event struct {
+	Seq     int64     `json:"seq"`
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Model   string    `json:"model,omitempty"`
+	Message string    `json:"message"`
+}
+
+// EventLog keeps the most recent events so clients can follow them by
+// sequence number, like the log buffer does for output lines
+type EventLog struct {
+	mu      sync.RWMutex
+	events  []Event
+	size    int
+	nextSeq int64
+}
+
+// NewEventLog creates an event log holding up to size events
+func NewEventLog(size int) *EventLog {
+	if size <= 0 {
+		size = DefaultEventBufferSize
+	}
+	return &EventLog{
+		size:    size,
+		nextSeq: 1,
+	}
+}
+
+// Publish records an event and writes it to the daemon output
+func (l *EventLog) Publish(eventType, model, format string, args ...interface{}) Event {
+	l.mu.Lock()
+	event := Event{
+		Seq:     l.nextSeq,
+		Time:    time.Now(),
+		Type:    eventType,
+		Model:   model,
+		Message: fmt.Sprintf(format, args...),
+	}
+	l.nextSeq++
+	l.events = append(l.events, event)
+	if len(l.events) > l.size {
+		l.events = append([]Event(nil), l.events[len(l.events)-l.size:]...)
+	}
+	l.mu.Unlock()
+
+	fmt.Printf("[Events] %s %s: %s\n", event.Type, event.Model, event.Message)
+	return event
+}
+
+// Since returns the events after sequence number seq, oldest first
+func (l *EventLog) Since(seq int64) []Event {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	events := make([]Event, 0)
+	for _, event := range l.events {
+		if event.Seq > seq {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// LastSeq returns the sequence number of the newest event, 0 if there is none
+func (l *EventLog) LastSeq() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.nextSeq - 1
+}
@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventLogPublish(t *testing.T) {
+	l := NewEventLog(10)
+	assert.Equal(t, int64(0), l.LastSeq())
+	assert.Empty(t, l.Since(0))
+
+	l.Publish(EventUpdateAvailable, "org/model", "version %s is available", "v2")
+	l.Publish(EventUpdateCompleted, "org/model", "updated to version %s", "v2")
+
+	events := l.Since(0)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(1), events[0].Seq)
+	assert.Equal(t, EventUpdateAvailable, events[0].Type)
+	assert.Equal(t, "org/model", events[0].Model)
+	assert.Equal(t, "version v2 is available", events[0].Message)
+	assert.Equal(t, int64(2), l.LastSeq())
+
+	// Only events after the given sequence number
+	events = l.Since(1)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventUpdateCompleted, events[0].Type)
+}
+
+func TestEventLogWraps(t *testing.T) {
+	l := NewEventLog(3)
+	for i := 1; i <= 5; i++ {
+		l.Publish(EventUpdateStarted, "org/model", "event %d", i)
+	}
+
+	events := l.Since(0)
+	require.Len(t, events, 3)
+	assert.Equal(t, "event 3", events[0].Message)
+	assert.Equal(t, int64(5), l.LastSeq())
+}
@@ -0,0 +1,468 @@
+package daemon
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// How often the GC worker checks whether a collection is due
+const gcCheckInterval = time.Hour
+
+// Artifacts changed more recently are left alone, an operation may still be
+// writing them
+const gcGracePeriod = 24 * time.Hour
+
+// Unfinished downloads are kept longer, getting the model again resumes them
+const gcPartialGracePeriod = 7 * 24 * time.Hour
+
+// Kinds of artifacts garbage collection removes
+const (
+	GCCatalogTorrent   = "catalog_torrent"
+	GCPublisherCatalog = "publisher_catalog"
+	GCTorrentFile      = "torrent_file"
+	GCPartialDownload  = "partial_download"
+	GCModelUpdate      = "model_update"
+	GCTempFile         = "temp_file"
+)
+
+var infoHashPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// GCItem is an artifact nothing refers to anymore
+type GCItem struct {
+	Kind   string `json:"kind"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Reason string `json:"reason"`
+
+	root string // Empty parents up to root are removed with it
+}
+
+// GCReport lists the artifacts a collection removed, or would remove on a
+// dry run
+type GCReport struct {
+	DryRun bool     `json:"dry_run"`
+	Items  []GCItem `json:"items"`
+	Freed  int64    `json:"freed"` // Bytes
+	Errors []string `json:"errors,omitempty"`
+}
+
+// garbageCollector runs one collection at a time
+type garbageCollector struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// gcRefs is what the daemon still uses, artifacts it doesn't cover are
+// orphaned
+type gcRefs struct {
+	infoHashes   map[string]bool
+	models       map[string]bool
+	storagePaths map[string]bool // Cleaned directories of torrents and pending updates
+	publishers   map[string]bool // Subscribed publisher keys and our own
+}
+
+// gcRefs collects the torrents, models, updates and publishers in use
+func (d *Daemon) gcRefs() gcRefs {
+	refs := gcRefs{
+		infoHashes:   make(map[string]bool),
+		models:       make(map[string]bool),
+		storagePaths: make(map[string]bool),
+		publishers:   make(map[string]bool),
+	}
+	if d.torrentManager != nil {
+		for _, mt := range d.torrentManager.GetAllTorrents() {
+			refs.infoHashes[mt.InfoHash] = true
+			refs.storagePaths[filepath.Clean(mt.StoragePath)] = true
+		}
+	}
+	if d.state != nil {
+		// Torrents that failed to restore are still wanted
+		for _, infoHash := range d.state.TorrentInfoHashes() {
+			refs.infoHashes[infoHash] = true
+		}
+		for _, sub := range d.state.GetModelSubscriptions() {
+			refs.infoHashes[sub.InfoHash] = true
+			if sub.PendingInfoHash != "" {
+				refs.infoHashes[sub.PendingInfoHash] = true
+				refs.storagePaths[filepath.Clean(updateDir(sub.Model))] = true
+			}
+		}
+		for _, sub := range d.state.GetSubscriptions() {
+			refs.publishers[sub.PublicKey] = true
+		}
+	}
+	if d.registry != nil {
+		for _, manifest := range d.registry.GetAllManifests() {
+			refs.models[manifest.Name] = true
+			if infoHash := magnetInfoHash(manifest.MagnetURI); infoHash != "" {
+				refs.infoHashes[infoHash] = true
+			}
+		}
+	}
+	if d.dhtManager != nil {
+		if key := d.dhtManager.PublisherKey(); key != "" {
+			refs.publishers[key] = true
+		}
+		// Catalogs of our old keys still publish the rotation
+		for _, key := range d.dhtManager.RetiredKeys() {
+			refs.publishers[key] = true
+		}
+	}
+	return refs
+}
+
+// CollectGarbage removes the superseded catalog torrents, the catalogs of
+// publishers no longer followed, torrent files and downloads no model or
+// transfer uses, and leftover temporary files. A dry run only reports them.
+func (d *Daemon) CollectGarbage(dryRun bool) *GCReport {
+	d.gc.mu.Lock()
+	defer d.gc.mu.Unlock()
+
+	now := time.Now()
+	refs := d.gcRefs()
+	baseDir := storage.GetBaseDir()
+	publishersDir := filepath.Join(baseDir, "publishers")
+
+	// Catalogs of publishers and of the other catalog networks
+	catalogDirs := []string{filepath.Join(baseDir, "catalog")}
+	for _, dir := range []string{publishersDir, filepath.Join(baseDir, "networks")} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				catalogDirs = append(catalogDirs, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	var items []GCItem
+	items = append(items, staleCatalogTorrents(catalogDirs)...)
+	// Without the DHT our own publisher key is unknown
+	if d.dhtManager != nil && d.dhtManager.PublisherKey() != "" {
+		items = append(items, orphanedPublisherCatalogs(publishersDir, refs)...)
+	}
+	// Relays don't load model torrents, their files and downloads are kept
+	// for when the daemon stores models again
+	if !d.Relay() {
+		items = append(items, orphanedTorrentFiles(storage.GetTorrentsDir(), refs, now)...)
+		items = append(items, orphanedPartialDownloads(storage.GetModelsDir(), refs, now)...)
+		items = append(items, orphanedUpdates(filepath.Join(baseDir, "updates"), refs, now)...)
+	}
+	items = append(items, leftoverTempFiles(baseDir, storage.GetModelsDir(), storage.GetTorrentsDir(), now)...)
+
+	report := &GCReport{DryRun: dryRun, Items: []GCItem{}}
+	for _, item := range items {
+		if !dryRun {
+			if err := os.RemoveAll(item.Path); err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+			if item.root != "" {
+				removeEmptyParents(filepath.Dir(item.Path), item.root)
+			}
+		}
+		report.Items = append(report.Items, item)
+		report.Freed += item.Size
+	}
+	d.gc.last = now
+
+	if !dryRun && len(report.Items) > 0 {
+		fmt.Printf("[GC] Removed %d orphaned artifacts, freed %.2f MB\n", len(report.Items), float64(report.Freed)/(1024*1024))
+	}
+	for _, err := range report.Errors {
+		fmt.Printf("[GC] Failed to remove: %s\n", err)
+	}
+	return report
+}
+
+// staleCatalogTorrents returns the catalog_N.torrent files older than the
+// newest one of each catalog directory, which is the one seeded
+func staleCatalogTorrents(dirs []string) []GCItem {
+	var items []GCItem
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		sequences := make(map[string]int64)
+		var latest int64 = -1
+		for _, entry := range entries {
+			var seq int64
+			if _, err := fmt.Sscanf(entry.Name(), "catalog_%d.torrent", &seq); err != nil || entry.Name() != fmt.Sprintf("catalog_%d.torrent", seq) {
+				continue
+			}
+			sequences[entry.Name()] = seq
+			if seq > latest {
+				latest = seq
+			}
+		}
+		for name, seq := range sequences {
+			if seq == latest {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			items = append(items, GCItem{
+				Kind:   GCCatalogTorrent,
+				Path:   path,
+				Size:   fileSize(path),
+				Reason: fmt.Sprintf("superseded by catalog_%d.torrent", latest),
+			})
+		}
+	}
+	return items
+}
+
+// orphanedPublisherCatalogs returns the cached catalogs of publishers that
+// are no longer followed
+func orphanedPublisherCatalogs(dir string, refs gcRefs) []GCItem {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var items []GCItem
+	for _, entry := range entries {
+		if !entry.IsDir() || refs.publishers[entry.Name()] {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		size, _ := dirUsage(path)
+		items = append(items, GCItem{
+			Kind:   GCPublisherCatalog,
+			Path:   path,
+			Size:   size,
+			Reason: "publisher is no longer followed",
+		})
+	}
+	return items
+}
+
+// orphanedTorrentFiles returns the torrent files of models and transfers
+// that are gone. Downloads keep theirs under the infohash, published models
+// under the model name.
+func orphanedTorrentFiles(dir string, refs gcRefs, now time.Time) []GCItem {
+	var items []GCItem
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(path) != ".torrent" {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < gcGracePeriod {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		name := strings.TrimSuffix(filepath.ToSlash(rel), ".torrent")
+
+		var reason string
+		if infoHashPattern.MatchString(name) {
+			if refs.infoHashes[name] {
+				return nil
+			}
+			reason = "no model or transfer uses this torrent"
+		} else {
+			if refs.models[name] {
+				return nil
+			}
+			if mi, err := metainfo.LoadFromFile(path); err == nil && refs.infoHashes[mi.HashInfoBytes().HexString()] {
+				return nil
+			}
+			reason = fmt.Sprintf("model %s is gone", name)
+		}
+		items = append(items, GCItem{Kind: GCTorrentFile, Path: path, Size: info.Size(), Reason: reason, root: dir})
+		return nil
+	})
+	return items
+}
+
+// orphanedPartialDownloads returns the model directories of unfinished
+// downloads no transfer resumes
+func orphanedPartialDownloads(modelsDir string, refs gcRefs, now time.Time) []GCItem {
+	var items []GCItem
+	filepath.WalkDir(modelsDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() || path == modelsDir {
+			return nil
+		}
+		if !storage.IsPartial(path) {
+			return nil
+		}
+		if refs.storagePaths[filepath.Clean(path)] {
+			return filepath.SkipDir
+		}
+		size, newest := dirUsage(path)
+		if now.Sub(newest) >= gcPartialGracePeriod {
+			items = append(items, GCItem{
+				Kind:   GCPartialDownload,
+				Path:   path,
+				Size:   size,
+				Reason: fmt.Sprintf("unfinished download untouched since %s", newest.Format("2006-01-02")),
+				root:   modelsDir,
+			})
+		}
+		return filepath.SkipDir
+	})
+	return items
+}
+
+// orphanedUpdates returns the directories of model update downloads that no
+// subscription waits for
+func orphanedUpdates(updatesDir string, refs gcRefs, now time.Time) []GCItem {
+	var items []GCItem
+	filepath.WalkDir(updatesDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() || path == updatesDir {
+			return nil
+		}
+		path = filepath.Clean(path)
+		if refs.storagePaths[path] {
+			return filepath.SkipDir
+		}
+		// Organization directories hold the updates of several models
+		for used := range refs.storagePaths {
+			if strings.HasPrefix(used, path+string(filepath.Separator)) {
+				return nil
+			}
+		}
+		size, newest := dirUsage(path)
+		if now.Sub(newest) >= gcGracePeriod {
+			items = append(items, GCItem{
+				Kind:   GCModelUpdate,
+				Path:   path,
+				Size:   size,
+				Reason: "no subscription waits for this update",
+				root:   updatesDir,
+			})
+		}
+		return filepath.SkipDir
+	})
+	return items
+}
+
+// isTempFile reports whether name is a temporary file silmaril writes.
+// Names ending in .tmp only count outside models, which may ship such files.
+func isTempFile(name string, inModels bool) bool {
+	if strings.HasPrefix(name, ".silmaril-pull-") {
+		return true
+	}
+	return !inModels && strings.HasSuffix(name, ".tmp")
+}
+
+// leftoverTempFiles returns the temporary files of interrupted writes in the
+// data directories and the catalog downloads left in the system temp
+// directory
+func leftoverTempFiles(baseDir, modelsDir, torrentsDir string, now time.Time) []GCItem {
+	modelDirs := []string{modelsDir, storage.GetEncryptedDir()}
+	inModels := func(path string) bool {
+		for _, dir := range modelDirs {
+			if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	seen := make(map[string]bool)
+	var items []GCItem
+	for _, root := range []string{baseDir, modelsDir, torrentsDir} {
+		filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() || seen[path] || !isTempFile(entry.Name(), inModels(path)) {
+				return nil
+			}
+			seen[path] = true
+			info, err := entry.Info()
+			if err != nil || now.Sub(info.ModTime()) < gcGracePeriod {
+				return nil
+			}
+			items = append(items, GCItem{Kind: GCTempFile, Path: path, Size: info.Size(), Reason: "left by an interrupted write"})
+			return nil
+		})
+	}
+
+	entries, _ := os.ReadDir(os.TempDir())
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "silmaril-catalog-") {
+			continue
+		}
+		path := filepath.Join(os.TempDir(), entry.Name())
+		size, newest := dirUsage(path)
+		if now.Sub(newest) >= gcGracePeriod {
+			items = append(items, GCItem{Kind: GCTempFile, Path: path, Size: size, Reason: "left by an interrupted catalog download"})
+		}
+	}
+	return items
+}
+
+// dirUsage returns the size of the files under dir and when the newest of
+// them, or dir itself, was modified
+func dirUsage(dir string) (int64, time.Time) {
+	var size int64
+	var newest time.Time
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		if !entry.IsDir() {
+			size += info.Size()
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	return size, newest
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// gcInterval returns the configured time between collections, 0 if the GC
+// worker is disabled
+func (d *Daemon) gcInterval() time.Duration {
+	if d.config == nil {
+		return 0
+	}
+	return time.Duration(d.config.GetInt("storage.gc_interval_hours")) * time.Hour
+}
+
+// gcWorker collects garbage every storage.gc_interval_hours
+func (d *Daemon) gcWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(gcCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			interval := d.gcInterval()
+			d.gc.mu.Lock()
+			due := interval > 0 && time.Since(d.gc.last) >= interval
+			d.gc.mu.Unlock()
+			if due {
+				d.CollectGarbage(false)
+			}
+		}
+	}
+}
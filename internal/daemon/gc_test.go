@@ -0,0 +1,129 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeAgedFile(t *testing.T, path string, age time.Duration) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+	old := time.Now().Add(-age)
+	require.NoError(t, os.Chtimes(path, old, old))
+	require.NoError(t, os.Chtimes(filepath.Dir(path), old, old))
+}
+
+func gcPaths(items []GCItem) []string {
+	paths := make([]string, 0, len(items))
+	for _, item := range items {
+		paths = append(paths, item.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func newGCRefs() gcRefs {
+	return gcRefs{
+		infoHashes:   make(map[string]bool),
+		models:       make(map[string]bool),
+		storagePaths: make(map[string]bool),
+		publishers:   make(map[string]bool),
+	}
+}
+
+func TestStaleCatalogTorrents(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"catalog_1.torrent", "catalog_2.torrent", "catalog_10.torrent", "catalog.torrent", "catalog.json"} {
+		writeAgedFile(t, filepath.Join(dir, name), 0)
+	}
+
+	items := staleCatalogTorrents([]string{dir, filepath.Join(dir, "missing")})
+	assert.Equal(t, []string{filepath.Join(dir, "catalog_1.torrent"), filepath.Join(dir, "catalog_2.torrent")}, gcPaths(items))
+}
+
+func TestOrphanedTorrentFiles(t *testing.T) {
+	dir := t.TempDir()
+	used := "0123456789abcdef0123456789abcdef01234567"
+	unused := "89abcdef0123456789abcdef0123456789abcdef"
+	writeAgedFile(t, filepath.Join(dir, used+".torrent"), 48*time.Hour)
+	writeAgedFile(t, filepath.Join(dir, unused+".torrent"), 48*time.Hour)
+	writeAgedFile(t, filepath.Join(dir, "org", "kept.torrent"), 48*time.Hour)
+	writeAgedFile(t, filepath.Join(dir, "org", "removed.torrent"), 48*time.Hour)
+	// Just written, maybe by a download that is starting
+	writeAgedFile(t, filepath.Join(dir, "org", "new.torrent"), time.Minute)
+
+	refs := newGCRefs()
+	refs.infoHashes[used] = true
+	refs.models["org/kept"] = true
+
+	items := orphanedTorrentFiles(dir, refs, time.Now())
+	assert.Equal(t, []string{
+		filepath.Join(dir, unused+".torrent"),
+		filepath.Join(dir, "org", "removed.torrent"),
+	}, gcPaths(items))
+}
+
+func TestOrphanedPartialDownloads(t *testing.T) {
+	modelsDir := t.TempDir()
+	writeAgedFile(t, filepath.Join(modelsDir, "org", "abandoned", storage.PartialMarkerFile), 30*24*time.Hour)
+	writeAgedFile(t, filepath.Join(modelsDir, "org", "abandoned", "model.bin"), 30*24*time.Hour)
+	writeAgedFile(t, filepath.Join(modelsDir, "org", "downloading", storage.PartialMarkerFile), 30*24*time.Hour)
+	writeAgedFile(t, filepath.Join(modelsDir, "org", "recent", storage.PartialMarkerFile), 30*24*time.Hour)
+	writeAgedFile(t, filepath.Join(modelsDir, "org", "recent", "model.bin"), time.Hour)
+	writeAgedFile(t, filepath.Join(modelsDir, "org", "complete", "model.bin"), 30*24*time.Hour)
+
+	refs := newGCRefs()
+	refs.storagePaths[filepath.Join(modelsDir, "org", "downloading")] = true
+
+	items := orphanedPartialDownloads(modelsDir, refs, time.Now())
+	require.Len(t, items, 1)
+	assert.Equal(t, filepath.Join(modelsDir, "org", "abandoned"), items[0].Path)
+	assert.Equal(t, int64(8), items[0].Size)
+}
+
+func TestOrphanedUpdates(t *testing.T) {
+	updatesDir := t.TempDir()
+	writeAgedFile(t, filepath.Join(updatesDir, "org", "pending", "model.bin"), 48*time.Hour)
+	writeAgedFile(t, filepath.Join(updatesDir, "org", "dropped", "model.bin"), 48*time.Hour)
+
+	refs := newGCRefs()
+	refs.storagePaths[filepath.Join(updatesDir, "org", "pending")] = true
+
+	items := orphanedUpdates(updatesDir, refs, time.Now())
+	assert.Equal(t, []string{filepath.Join(updatesDir, "org", "dropped")}, gcPaths(items))
+}
+
+func TestLeftoverTempFiles(t *testing.T) {
+	baseDir := t.TempDir()
+	t.Setenv("SILMARIL_HOME", baseDir)
+	modelsDir := filepath.Join(baseDir, "models")
+	torrentsDir := filepath.Join(baseDir, "torrents")
+
+	writeAgedFile(t, filepath.Join(baseDir, "state.json.tmp"), 48*time.Hour)
+	writeAgedFile(t, filepath.Join(torrentsDir, "x.torrent.tmp"), 48*time.Hour)
+	writeAgedFile(t, filepath.Join(baseDir, "registry", "fresh.tmp"), time.Minute)
+	writeAgedFile(t, filepath.Join(modelsDir, "org", "model", ".silmaril-pull-123"), 48*time.Hour)
+	// Model files may be named anything
+	writeAgedFile(t, filepath.Join(modelsDir, "org", "model", "weights.tmp"), 48*time.Hour)
+
+	items := leftoverTempFiles(baseDir, modelsDir, torrentsDir, time.Now())
+	var paths []string
+	for _, path := range gcPaths(items) {
+		if filepath.Dir(path) != filepath.Clean(os.TempDir()) {
+			paths = append(paths, path)
+		}
+	}
+	assert.Equal(t, []string{
+		filepath.Join(modelsDir, "org", "model", ".silmaril-pull-123"),
+		filepath.Join(baseDir, "state.json.tmp"),
+		filepath.Join(torrentsDir, "x.torrent.tmp"),
+	}, paths)
+}
@@ -0,0 +1,132 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// How often the hashing worker looks for files without a checksum
+const hashCheckInterval = time.Minute
+
+// hashRate returns the configured bytes per second read to fill in missing
+// checksums, 0 if background hashing is disabled
+func (d *Daemon) hashRate() int64 {
+	if d.config == nil {
+		return 0
+	}
+	return int64(d.config.GetInt("storage.hash_rate_mb")) * 1024 * 1024
+}
+
+// unhashedFile is a model file whose manifest has no checksum for it
+type unhashedFile struct {
+	model string
+	path  string
+	size  int64
+}
+
+func (f unhashedFile) key() string {
+	return f.model + "/" + f.path
+}
+
+// nextUnhashed returns the first file without a checksum, skipping those in
+// skip. Signed manifests are left alone, changing them voids the signature.
+func (d *Daemon) nextUnhashed(skip map[string]bool) (unhashedFile, bool) {
+	manifests := d.registry.GetAllManifests()
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].Name < manifests[j].Name })
+
+	for _, manifest := range manifests {
+		if manifest.Signature != "" || len(manifest.Signatures) > 0 {
+			continue
+		}
+		for _, f := range manifest.Files {
+			file := unhashedFile{model: manifest.Name, path: f.Path, size: f.Size}
+			if f.SHA256 == "" && !skip[file.key()] {
+				return file, true
+			}
+		}
+	}
+	return unhashedFile{}, false
+}
+
+// hashFile checksums one model file and records it in the manifest
+func (d *Daemon) hashFile(file unhashedFile, rate int64) error {
+	modelPath, err := d.registry.Paths().ModelDir(file.model)
+	if err != nil {
+		return err
+	}
+	dir := storage.ResolveModelDir(modelPath)
+	path := filepath.Join(dir, filepath.FromSlash(file.path))
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() != file.size {
+		return fmt.Errorf("%s is %d bytes, the manifest says %d", file.path, info.Size(), file.size)
+	}
+
+	hash, err := models.HashFileRate(d.ctx, path, rate)
+	if err != nil {
+		return err
+	}
+	return d.registry.SetFileHash(file.model, file.path, file.size, hash)
+}
+
+// hashMissing checksums the files the scan left without one until there
+// are none left, hashing is disabled or the daemon stops. Files that can't
+// be hashed are added to failed and not tried again.
+func (d *Daemon) hashMissing(failed map[string]bool) {
+	for {
+		rate := d.hashRate()
+		if rate <= 0 {
+			return
+		}
+		file, ok := d.nextUnhashed(failed)
+		if !ok {
+			return
+		}
+
+		if err := d.hashFile(file, rate); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			// Files not selected for a partial download don't exist
+			if !os.IsNotExist(err) {
+				fmt.Printf("[Hashing] Failed to hash %s of %s: %v\n", file.path, file.model, err)
+			}
+			failed[file.key()] = true
+			continue
+		}
+
+		if manifest, err := d.registry.GetManifest(file.model); err == nil && manifest.HashesComplete() {
+			fmt.Printf("[Hashing] All files of %s are hashed\n", file.model)
+		}
+	}
+}
+
+// hashWorker fills in the checksums of files too large to hash while the
+// models directory is scanned, reading at a bounded rate
+func (d *Daemon) hashWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(hashCheckInterval)
+	defer ticker.Stop()
+
+	failed := make(map[string]bool)
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.hashMissing(failed)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashMissingFiles(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	d.ctx = context.Background()
+
+	modelDir := writeTestModel(t, "org/model")
+	require.NoError(t, models.WriteManifest(modelDir, &types.ModelManifest{
+		Name: "org/model",
+		Files: []types.ModelFile{
+			{Path: "model.safetensors", Size: 1024},
+			{Path: "unselected.safetensors", Size: 1024},
+		},
+	}))
+	signedDir := writeTestModel(t, "org/signed")
+	require.NoError(t, models.WriteManifest(signedDir, &types.ModelManifest{
+		Name:      "org/signed",
+		Signature: "sig",
+		Files:     []types.ModelFile{{Path: "model.safetensors", Size: 1024}},
+	}))
+
+	var err error
+	d.registry, err = newRegistry(nil)
+	require.NoError(t, err)
+
+	failed := make(map[string]bool)
+	file, ok := d.nextUnhashed(failed)
+	require.True(t, ok)
+	assert.Equal(t, "org/model", file.model)
+	assert.Equal(t, "model.safetensors", file.path)
+	require.NoError(t, d.hashFile(file, 0))
+
+	// Files that aren't on disk are skipped from then on
+	file, ok = d.nextUnhashed(failed)
+	require.True(t, ok)
+	assert.Equal(t, "unselected.safetensors", file.path)
+	assert.True(t, os.IsNotExist(d.hashFile(file, 0)))
+	failed[file.key()] = true
+
+	// Signed manifests are never changed
+	_, ok = d.nextUnhashed(failed)
+	assert.False(t, ok)
+
+	sum := sha256.Sum256(make([]byte, 1024))
+	manifest, err := d.registry.GetManifest("org/model")
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), manifest.Files[0].SHA256)
+	saved, err := models.ReadManifest(modelDir)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Files[0].SHA256, saved.Files[0].SHA256)
+}
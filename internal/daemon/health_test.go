@@ -0,0 +1,29 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckStorageWritable(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, HealthOK, checkStorageWritable([]string{dir}).Status)
+
+	// The probe file doesn't stay behind
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	check := checkStorageWritable([]string{dir, filepath.Join(dir, "missing")})
+	assert.Equal(t, HealthFailing, check.Status)
+	assert.Contains(t, check.Message, "missing")
+}
+
+func TestHealthProbeIgnoredByRegistry(t *testing.T) {
+	event := fsnotify.Event{Name: filepath.Join("models", healthProbePrefix+"123"), Op: fsnotify.Create}
+	assert.False(t, registryEventMatters(event))
+}
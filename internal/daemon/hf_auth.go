@@ -0,0 +1,23 @@
+package daemon
+
+import (
+	"path/filepath"
+
+	"github.com/silmaril/silmaril/internal/hfauth"
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// hfToken returns the HuggingFace token to send with a request to rawURL:
+// HF_TOKEN, or the token stored by 'silmaril auth hf login'. It is read for
+// every request, and only requests to the hub get it.
+func (d *Daemon) hfToken(rawURL string) string {
+	return hfauth.NewStore(d.keysDir()).TokenFor(rawURL, d.HFProxyUpstream())
+}
+
+// keysDir returns where the keys and credentials of this node are stored
+func (d *Daemon) keysDir() string {
+	if d.config != nil && d.config.Security.KeysDir != "" {
+		return d.config.Security.KeysDir
+	}
+	return filepath.Join(storage.GetBaseDir(), "keys")
+}
@@ -0,0 +1,159 @@
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// SetHFProxyHandler sets the handler of the HuggingFace Hub proxy. The proxy
+// only runs if it is enabled in the config.
+func (d *Daemon) SetHFProxyHandler(handler http.Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.hfProxyHandler = handler
+}
+
+// startHFProxyServer serves the HuggingFace Hub proxy on its own port. It
+// has no write timeout since model files take long to stream.
+func (d *Daemon) startHFProxyServer() {
+	d.mu.RLock()
+	handler := d.hfProxyHandler
+	d.mu.RUnlock()
+
+	if d.config == nil || !d.config.HFProxy.Enabled || handler == nil {
+		return
+	}
+
+	// Anyone reaching the proxy can make the daemon download models, so it
+	// only serves local clients unless configured otherwise
+	bindAddress := "127.0.0.1"
+	if d.config.HFProxy.BindAddress != "" {
+		bindAddress = d.config.HFProxy.BindAddress
+	}
+
+	d.hfProxyServer = &http.Server{
+		Addr:        fmt.Sprintf("%s:%d", bindAddress, d.config.HFProxy.Port),
+		Handler:     handler,
+		ReadTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := d.hfProxyServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("[HFProxy] Server error: %v\n", err)
+		}
+	}()
+
+	fmt.Printf("[HFProxy] Serving HuggingFace Hub proxy on %s:%d, set HF_ENDPOINT=http://localhost:%d\n",
+		bindAddress, d.config.HFProxy.Port, d.config.HFProxy.Port)
+}
+
+// HFProxyUpstream returns the hub the proxy falls back to
+func (d *Daemon) HFProxyUpstream() string {
+	if d.config != nil {
+		if upstream := d.config.GetString("hf_proxy.upstream"); upstream != "" {
+			return strings.TrimSuffix(upstream, "/")
+		}
+		if d.config.HFProxy.Upstream != "" {
+			return strings.TrimSuffix(d.config.HFProxy.Upstream, "/")
+		}
+	}
+	return "https://huggingface.co"
+}
+
+// HFProxyP2PTimeout returns how long the proxy waits for peers before it
+// falls back to the upstream hub
+func (d *Daemon) HFProxyP2PTimeout() time.Duration {
+	if d.config != nil {
+		if seconds := d.config.GetInt("hf_proxy.p2p_timeout_seconds"); seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if d.config.HFProxy.P2PTimeoutSeconds > 0 {
+			return time.Duration(d.config.HFProxy.P2PTimeoutSeconds) * time.Second
+		}
+	}
+	return 30 * time.Second
+}
+
+// FindCatalogModel returns the catalog entry of the model with exactly the
+// given name, or nil if the catalog has none
+func (d *Daemon) FindCatalogModel(name string) *types.ModelAnnouncement {
+	if d.dhtManager == nil {
+		return nil
+	}
+
+	models, err := d.dhtManager.DiscoverModels(name)
+	if err != nil {
+		return nil
+	}
+	for _, model := range models {
+		if model.Name == name && model.InfoHash != "" {
+			return model
+		}
+	}
+	return nil
+}
+
+// FindCatalogInfoHash returns a catalog entry of the torrent with the given
+// infohash, named name as it is downloaded under that name, or nil if the
+// catalog has none. Gated models asked for by infohash keep their license.
+func (d *Daemon) FindCatalogInfoHash(name, infoHash string) *types.ModelAnnouncement {
+	if d.dhtManager == nil || infoHash == "" {
+		return nil
+	}
+	idx, err := d.dhtManager.currentCatalogIndex()
+	if err != nil {
+		return nil
+	}
+	var found *types.ModelAnnouncement
+	for _, model := range idx.models {
+		if !strings.EqualFold(model.InfoHash, infoHash) {
+			continue
+		}
+		// Entries that ask for acceptance win over copies that don't
+		if found == nil || model.RequireLicenseAcceptance && !found.RequireLicenseAcceptance {
+			named := *model
+			named.Name = name
+			found = &named
+		}
+	}
+	return found
+}
+
+// FetchModel starts downloading a model from peers, or returns the torrent
+// already downloading or seeding it
+func (d *Daemon) FetchModel(name, infoHash string) (*ManagedTorrent, error) {
+	infoHash = strings.ToLower(infoHash)
+	if mt, ok := d.torrentManager.GetTorrent(infoHash); ok {
+		return mt, nil
+	}
+
+	modelPath, err := modelPathFor(name)
+	if err != nil {
+		return nil, err
+	}
+	mt, err := d.fetchModel(name, infoHash, modelPath)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[HFProxy] Fetching %s from peers (InfoHash: %s)\n", name, mt.InfoHash)
+	return mt, nil
+}
+
+// fetchModel starts downloading a model into storagePath, from its torrent
+// file if we have it or else by infohash, and tracks it as a download
+func (d *Daemon) fetchModel(name, infoHash, storagePath string) (*ManagedTorrent, error) {
+	mt, _, err := d.torrentManager.AddForDownload(infoHash, name, storagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Torrents added by infohash learn their size with the metadata
+	transfer := d.transferManager.CreateDownload(name, mt.InfoHash, mt.Torrent.Length())
+	transfer.Status = TransferStatusActive
+	return mt, nil
+}
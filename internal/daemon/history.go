@@ -0,0 +1,290 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/store"
+)
+
+// HistoryEntry is a transfer that ended, kept after the transfer itself is
+// cleaned up
+type HistoryEntry struct {
+	TransferID       string         `json:"transfer_id"`
+	Type             TransferType   `json:"type"`
+	ModelName        string         `json:"model_name"`
+	InfoHash         string         `json:"info_hash"`
+	Result           TransferStatus `json:"result"` // completed, cancelled or failed
+	Error            string         `json:"error,omitempty"`
+	StopReason       string         `json:"stop_reason,omitempty"`
+	StartedAt        time.Time      `json:"started_at"`
+	EndedAt          time.Time      `json:"ended_at"`
+	TotalBytes       int64          `json:"total_bytes"`
+	BytesTransferred int64          `json:"bytes_transferred"`
+	PeakPeers        int            `json:"peak_peers"`
+	Files            []string       `json:"files,omitempty"`
+}
+
+// historyEntry describes how transfer ended
+func historyEntry(transfer Transfer) HistoryEntry {
+	entry := HistoryEntry{
+		TransferID:       transfer.ID,
+		Type:             transfer.Type,
+		ModelName:        transfer.ModelName,
+		InfoHash:         transfer.InfoHash,
+		Result:           transfer.Status,
+		Error:            transfer.Error,
+		StopReason:       transfer.StopReason,
+		StartedAt:        transfer.StartedAt,
+		EndedAt:          time.Now(),
+		TotalBytes:       transfer.TotalBytes,
+		BytesTransferred: transfer.BytesTransferred,
+		PeakPeers:        transfer.PeakPeers,
+		Files:            transfer.Files,
+	}
+	if transfer.CompletedAt != nil {
+		entry.EndedAt = *transfer.CompletedAt
+	}
+	return entry
+}
+
+// HistoryFilter selects history entries, empty fields select all
+type HistoryFilter struct {
+	Model  string
+	Type   TransferType
+	Result TransferStatus
+	Since  time.Time
+	Limit  int // Most recent entries returned, all if 0
+}
+
+func (f HistoryFilter) matches(entry HistoryEntry) bool {
+	return (f.Model == "" || entry.ModelName == f.Model) &&
+		(f.Type == "" || entry.Type == f.Type) &&
+		(f.Result == "" || entry.Result == f.Result) &&
+		(f.Since.IsZero() || !entry.EndedAt.Before(f.Since))
+}
+
+// History keeps the transfers that ended, in the database or else in a
+// file of JSON lines
+type History struct {
+	mu    sync.Mutex
+	store *store.Store
+	path  string
+}
+
+// NewHistory creates a history kept in st, or in the file at path if st is nil
+func NewHistory(path string, st *store.Store) *History {
+	return &History{store: st, path: path}
+}
+
+// historyKey orders entries by the time they ended
+func historyKey(entry HistoryEntry) string {
+	return entry.EndedAt.UTC().Format("20060102T150405.000000000Z") + "/" + entry.TransferID
+}
+
+// Record adds an entry to the history
+func (h *History) Record(entry HistoryEntry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.store != nil {
+		return h.store.Put(store.History, historyKey(entry), entry)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// List returns the entries selected by filter, the most recent first
+func (h *History) List(filter HistoryFilter) ([]HistoryEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries, err := h.read()
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]HistoryEntry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		if filter.matches(entries[i]) {
+			selected = append(selected, entries[i])
+			if filter.Limit > 0 && len(selected) == filter.Limit {
+				break
+			}
+		}
+	}
+	return selected, nil
+}
+
+// Prune removes the entries that ended before cutoff and returns how many
+func (h *History) Prune(cutoff time.Time) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.store != nil {
+		var stale []string
+		err := h.store.Update(func(tx *store.Tx) error {
+			err := tx.ForEach(store.History, func(key string, data []byte) error {
+				var entry HistoryEntry
+				if err := json.Unmarshal(data, &entry); err != nil || entry.EndedAt.Before(cutoff) {
+					stale = append(stale, key)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, key := range stale {
+				if err := tx.Delete(store.History, key); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return len(stale), err
+	}
+
+	entries, err := h.read()
+	if err != nil {
+		return 0, err
+	}
+	var kept bytes.Buffer
+	pruned := 0
+	for _, entry := range entries {
+		if entry.EndedAt.Before(cutoff) {
+			pruned++
+			continue
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return 0, err
+		}
+		kept.Write(append(data, '\n'))
+	}
+	if pruned == 0 {
+		return 0, nil
+	}
+	if err := storage.WriteFileAtomic(h.path, kept.Bytes(), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write history: %w", err)
+	}
+	return pruned, nil
+}
+
+// read returns all entries, the oldest first. Callers hold h.mu.
+func (h *History) read() ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	if h.store != nil {
+		err := h.store.ForEach(store.History, func(key string, data []byte) error {
+			var entry HistoryEntry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return fmt.Errorf("failed to decode history entry %s: %w", key, err)
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+		return entries, err
+	}
+
+	file, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		// A crash can leave the last line torn, skip it
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].EndedAt.Before(entries[j].EndedAt) })
+	return entries, nil
+}
+
+// historyRetention returns how long the history is kept, 0 to keep it
+// forever
+func (d *Daemon) historyRetention() time.Duration {
+	if d.config == nil {
+		return 0
+	}
+	return time.Duration(d.config.GetInt("storage.history_retention_days")) * 24 * time.Hour
+}
+
+// pruneHistory drops the entries older than the retention
+func (d *Daemon) pruneHistory() {
+	retention := d.historyRetention()
+	if d.history == nil || retention <= 0 {
+		return
+	}
+	pruned, err := d.history.Prune(time.Now().Add(-retention))
+	if err != nil {
+		fmt.Printf("[History] Failed to prune history: %v\n", err)
+		return
+	}
+	if pruned > 0 {
+		fmt.Printf("[History] Pruned %d entries older than %v\n", pruned, retention)
+	}
+}
+
+// transferFinished records a transfer that ended in the history and tells
+// clients following the events how a download ended
+func (d *Daemon) transferFinished(transfer Transfer) {
+	d.recordHistory(transfer)
+	if transfer.Type != TransferTypeDownload || d.events == nil {
+		return
+	}
+	switch transfer.Status {
+	case TransferStatusCompleted:
+		d.events.Publish(EventDownloadCompleted, transfer.ModelName, "Downloaded %s (transfer %s)", transfer.ModelName, transfer.ID)
+		if d.downloadReady(transfer.InfoHash) {
+			d.checkDownload(transfer.ModelName, transfer.InfoHash)
+		}
+	case TransferStatusFailed:
+		d.events.Publish(EventDownloadFailed, transfer.ModelName, "Download of %s failed: %s", transfer.ModelName, transfer.Error)
+	case TransferStatusCancelled:
+		d.events.Publish(EventDownloadCancelled, transfer.ModelName, "Download of %s was cancelled", transfer.ModelName)
+	}
+}
+
+// recordHistory adds a transfer that ended to the history
+func (d *Daemon) recordHistory(transfer Transfer) {
+	if err := d.history.Record(historyEntry(transfer)); err != nil {
+		fmt.Printf("[History] Failed to record transfer %s: %v\n", transfer.ID, err)
+	}
+}
+
+// GetHistory returns the transfers that ended, the most recent first
+func (d *Daemon) GetHistory(filter HistoryFilter) ([]HistoryEntry, error) {
+	if d.history == nil {
+		return nil, nil
+	}
+	return d.history.List(filter)
+}
@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "db"))
+	require.NoError(t, err)
+	defer st.Close()
+
+	backends := map[string]*History{
+		"file":  NewHistory(filepath.Join(t.TempDir(), "history.jsonl"), nil),
+		"store": NewHistory("", st),
+	}
+	for name, history := range backends {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			entries := []HistoryEntry{
+				{TransferID: "1", Type: TransferTypeDownload, ModelName: "org/a", Result: TransferStatusCompleted, EndedAt: now.Add(-48 * time.Hour)},
+				{TransferID: "2", Type: TransferTypeDownload, ModelName: "org/b", Result: TransferStatusFailed, EndedAt: now.Add(-time.Hour)},
+				{TransferID: "3", Type: TransferTypeSeed, ModelName: "org/a", Result: TransferStatusCompleted, EndedAt: now},
+			}
+			for _, entry := range entries {
+				require.NoError(t, history.Record(entry))
+			}
+
+			all, err := history.List(HistoryFilter{})
+			require.NoError(t, err)
+			require.Len(t, all, 3)
+			assert.Equal(t, "3", all[0].TransferID, "the most recent first")
+
+			selected, err := history.List(HistoryFilter{Model: "org/a"})
+			require.NoError(t, err)
+			assert.Len(t, selected, 2)
+			selected, err = history.List(HistoryFilter{Result: TransferStatusFailed})
+			require.NoError(t, err)
+			require.Len(t, selected, 1)
+			assert.Equal(t, "2", selected[0].TransferID)
+			selected, err = history.List(HistoryFilter{Since: now.Add(-2 * time.Hour), Limit: 1})
+			require.NoError(t, err)
+			require.Len(t, selected, 1)
+			assert.Equal(t, "3", selected[0].TransferID)
+
+			pruned, err := history.Prune(now.Add(-24 * time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, 1, pruned)
+			all, err = history.List(HistoryFilter{})
+			require.NoError(t, err)
+			assert.Len(t, all, 2)
+		})
+	}
+}
+
+func TestHistorySkipsTornLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	history := NewHistory(path, nil)
+	require.NoError(t, history.Record(HistoryEntry{TransferID: "1", EndedAt: time.Now()}))
+
+	// A crash in the middle of a write
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"transfer_id": "2", "end`)
+	require.NoError(t, err)
+	file.Close()
+
+	entries, err := history.List(HistoryFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "1", entries[0].TransferID)
+}
+
+func TestTransferManagerReportsFinishedTransfers(t *testing.T) {
+	tm := NewTransferManager(nil, NewState(""))
+	history := NewHistory(filepath.Join(t.TempDir(), "history.jsonl"), nil)
+	tm.OnFinish = func(transfer Transfer) {
+		require.NoError(t, history.Record(historyEntry(transfer)))
+	}
+
+	cancelled := tm.CreateDownload("org/cancelled", "aaaa", 100)
+	require.NoError(t, tm.CancelTransfer(cancelled.ID))
+	// Cancelling again doesn't add another entry
+	require.NoError(t, tm.CancelTransfer(cancelled.ID))
+
+	failed := tm.CreateDownload("org/failed", "bbbb", 100)
+	tm.FailTransfer(failed.ID, errors.New("no torrent file"))
+
+	entries, err := history.List(HistoryFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	results := map[string]HistoryEntry{}
+	for _, entry := range entries {
+		results[entry.ModelName] = entry
+	}
+	assert.Equal(t, TransferStatusCancelled, results["org/cancelled"].Result)
+	assert.Equal(t, TransferStatusFailed, results["org/failed"].Result)
+	assert.Equal(t, "no torrent file", results["org/failed"].Error)
+	assert.Equal(t, int64(100), results["org/failed"].TotalBytes)
+
+	transfer, ok := tm.GetTransfer(failed.ID)
+	require.True(t, ok)
+	assert.Equal(t, TransferStatusFailed, transfer.Status)
+}
+
+func TestTransferFinishedEvents(t *testing.T) {
+	d := &Daemon{
+		events:  NewEventLog(10),
+		history: NewHistory(filepath.Join(t.TempDir(), "history.jsonl"), nil),
+	}
+
+	d.transferFinished(Transfer{ID: "1", Type: TransferTypeDownload, ModelName: "org/a", Status: TransferStatusCompleted})
+	d.transferFinished(Transfer{ID: "2", Type: TransferTypeDownload, ModelName: "org/b", Status: TransferStatusFailed, Error: "no peers"})
+	d.transferFinished(Transfer{ID: "3", Type: TransferTypeSeed, ModelName: "org/a", Status: TransferStatusCompleted})
+
+	events := d.events.Since(0)
+	require.Len(t, events, 2, "seeds ending are not announced")
+	assert.Equal(t, EventDownloadCompleted, events[0].Type)
+	assert.Equal(t, "org/a", events[0].Model)
+	assert.Equal(t, EventDownloadFailed, events[1].Type)
+	assert.Contains(t, events[1].Message, "no peers")
+
+	all, err := d.history.List(HistoryFilter{})
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+}
@@ -0,0 +1,225 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/models"
+)
+
+const (
+	defaultHookTimeout = 30 * time.Minute
+
+	// Output of a hook or converter kept for the daemon log
+	maxHookOutput = 64 * 1024
+)
+
+// Environment variables of the daemon every hook gets, others only when
+// listed in hooks.env
+var hookBaseEnv = []string{"PATH", "HOME", "USER", "LANG", "LC_ALL", "TZ", "TMPDIR", "SYSTEMROOT"}
+
+// hookRunner runs one hook at a time, so a batch of downloads doesn't start
+// a batch of conversions
+type hookRunner struct {
+	mu sync.Mutex
+}
+
+// hookCommands are the hooks and converter the daemon runs for models and
+// the daemon variables passed to them. They are read once at
+// startup and can only be set in the config file, so a running daemon can't
+// be told through the API to run anything else.
+type hookCommands struct {
+	OnDownloadComplete string // hooks.on_download_complete
+	Timeout            time.Duration
+	Env                []string // hooks.env
+	Converter          string   // convert.command
+}
+
+// hookCommandsFor returns the hooks set in cfg
+func hookCommandsFor(cfg *config.Config) hookCommands {
+	commands := hookCommands{Timeout: defaultHookTimeout}
+	if cfg == nil {
+		return commands
+	}
+	commands.OnDownloadComplete = config.ExpandPath(cfg.GetString("hooks.on_download_complete"))
+	if seconds := cfg.GetInt("hooks.timeout_seconds"); seconds > 0 {
+		commands.Timeout = time.Duration(seconds) * time.Second
+	}
+	commands.Env = cfg.GetStringSlice("hooks.env")
+	commands.Converter = config.ExpandPath(cfg.GetString("convert.command"))
+	return commands
+}
+
+// downloadHook is a run of hooks.on_download_complete for a model
+type downloadHook struct {
+	Script    string
+	Model     string
+	ModelPath string
+	InfoHash  string
+	Timeout   time.Duration
+	Env       []string // Names of daemon variables passed on
+}
+
+// env returns the environment of the hook, the model and the daemon
+// variables it may see
+func (h downloadHook) env() []string {
+	version := ""
+	manifestPath := filepath.Join(h.ModelPath, models.ManifestFileName)
+	if manifest, err := models.ReadManifest(h.ModelPath); err == nil {
+		version = manifest.Version
+	} else {
+		manifestPath = ""
+	}
+	return sandboxEnv(h.Env,
+		"SILMARIL_EVENT="+EventDownloadCompleted,
+		"SILMARIL_MODEL_NAME="+h.Model,
+		"SILMARIL_MODEL_PATH="+h.ModelPath,
+		"SILMARIL_MODEL_VERSION="+version,
+		"SILMARIL_MANIFEST="+manifestPath,
+		"SILMARIL_INFO_HASH="+h.InfoHash,
+	)
+}
+
+// run runs the hook in the model directory with the model name and path as
+// arguments, and returns its output
+func (h downloadHook) run(ctx context.Context) (string, error) {
+	return runSandboxed(ctx, h.Script, []string{h.Model, h.ModelPath}, h.ModelPath, h.env(), h.Timeout)
+}
+
+// sandboxEnv returns the environment of a script: the base variables and
+// the ones named in passEnv taken from the daemon, followed by vars
+func sandboxEnv(passEnv []string, vars ...string) []string {
+	var env []string
+	for _, name := range append(append([]string(nil), hookBaseEnv...), passEnv...) {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return append(env, vars...)
+}
+
+// runSandboxed runs a script in dir with only env as its environment, and
+// returns its output. The script is killed with the processes it started
+// when it runs past timeout or ctx is done.
+func runSandboxed(ctx context.Context, script string, args []string, dir string, env []string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var output limitedBuffer
+	cmd := exec.CommandContext(ctx, script, args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.WaitDelay = 5 * time.Second
+	killProcessGroup(cmd)
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %v", timeout)
+	}
+	return strings.TrimSpace(output.String()), err
+}
+
+// limitedBuffer keeps the first maxHookOutput bytes written to it
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxHookOutput - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// downloadHookFor returns the hook to run for a downloaded model, false if
+// hooks.on_download_complete isn't set
+func downloadHookFor(commands hookCommands, model string) (downloadHook, bool) {
+	if commands.OnDownloadComplete == "" {
+		return downloadHook{}, false
+	}
+	return downloadHook{
+		Script:  commands.OnDownloadComplete,
+		Model:   model,
+		Timeout: commands.Timeout,
+		Env:     commands.Env,
+	}, true
+}
+
+// startDownloadHook runs hooks.on_download_complete for a model in the
+// background once its files are in the models directory
+func (d *Daemon) startDownloadHook(model, infoHash string) {
+	hook, ok := downloadHookFor(d.commands, model)
+	if !ok {
+		return
+	}
+	modelPath, err := modelPathFor(model)
+	if err != nil {
+		return
+	}
+	hook.ModelPath = modelPath
+	hook.InfoHash = infoHash
+
+	d.workers.Add(1)
+	go func() {
+		defer d.workers.Done()
+		d.runDownloadHook(hook)
+	}()
+}
+
+// modelReady runs the download hook and converter for a downloaded model
+// whose files are ready in the models directory
+func (d *Daemon) modelReady(model, infoHash string) {
+	d.startDownloadHook(model, infoHash)
+	d.autoConvert(model, infoHash)
+}
+
+// downloadReady reports whether a finished download is ready for hooks.
+// Encrypted models are ready once decrypted and updates once installed.
+// Models pinned for the network aren't the user's and get no hooks.
+func (d *Daemon) downloadReady(infoHash string) bool {
+	if d.state != nil && d.state.IsPinned(infoHash) {
+		return false
+	}
+	if d.torrentManager == nil {
+		return true
+	}
+	mt, ok := d.torrentManager.GetTorrent(infoHash)
+	if !ok {
+		return true
+	}
+	if isUpdateDownload(mt) {
+		return false
+	}
+	_, pending := pendingDecryption(mt)
+	return !pending
+}
+
+// runDownloadHook runs a hook and publishes how it went
+func (d *Daemon) runDownloadHook(hook downloadHook) {
+	d.hooks.mu.Lock()
+	defer d.hooks.mu.Unlock()
+
+	fmt.Printf("[Hooks] Running %s for %s\n", hook.Script, hook.Model)
+	started := time.Now()
+	output, err := hook.run(d.ctx)
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" {
+			fmt.Printf("[Hooks] %s: %s\n", hook.Model, line)
+		}
+	}
+	if err != nil {
+		d.events.Publish(EventHookFailed, hook.Model, "%s failed: %v", filepath.Base(hook.Script), err)
+		return
+	}
+	d.events.Publish(EventHookCompleted, hook.Model, "%s finished in %v", filepath.Base(hook.Script), time.Since(started).Round(time.Second))
+}
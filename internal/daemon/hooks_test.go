@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeHookScript writes an executable shell script
+func writeHookScript(t *testing.T, script string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts are shell scripts")
+	}
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755))
+	return path
+}
+
+func TestDownloadHookEnv(t *testing.T) {
+	t.Setenv("HF_TOKEN", "secret")
+	t.Setenv("CUDA_VISIBLE_DEVICES", "0")
+	newRenameTestDaemon(t)
+	modelDir := writeTestModel(t, "org/model")
+	require.NoError(t, models.WriteManifest(modelDir, &types.ModelManifest{Name: "org/model", Version: "1.0"}))
+
+	script := writeHookScript(t, `echo "args=$1 $2"
+echo "pwd=$(pwd)"
+env | grep -E '^(SILMARIL_[A-Z_]*|HF_TOKEN|CUDA_VISIBLE_DEVICES|PATH)=' | sort
+`)
+	hook := downloadHook{
+		Script:    script,
+		Model:     "org/model",
+		ModelPath: modelDir,
+		InfoHash:  "abcd",
+		Timeout:   time.Minute,
+		Env:       []string{"CUDA_VISIBLE_DEVICES"},
+	}
+	output, err := hook.run(context.Background())
+	require.NoError(t, err)
+
+	lines := strings.Split(output, "\n")
+	assert.Contains(t, lines, "args=org/model "+modelDir)
+	assert.Contains(t, lines, "CUDA_VISIBLE_DEVICES=0")
+	assert.Contains(t, lines, "SILMARIL_EVENT=download.completed")
+	assert.Contains(t, lines, "SILMARIL_MODEL_NAME=org/model")
+	assert.Contains(t, lines, "SILMARIL_MODEL_PATH="+modelDir)
+	assert.Contains(t, lines, "SILMARIL_MODEL_VERSION=1.0")
+	assert.Contains(t, lines, "SILMARIL_MANIFEST="+filepath.Join(modelDir, models.ManifestFileName))
+	assert.Contains(t, lines, "SILMARIL_INFO_HASH=abcd")
+	assert.NotContains(t, output, "HF_TOKEN", "variables not listed in hooks.env are not passed on")
+	assert.Contains(t, output, "PATH=")
+
+	realDir, err := filepath.EvalSymlinks(modelDir)
+	require.NoError(t, err)
+	assert.Contains(t, lines, "pwd="+realDir)
+}
+
+func TestDownloadHookTimeout(t *testing.T) {
+	script := writeHookScript(t, "sleep 30 &\nwait\n")
+	hook := downloadHook{Script: script, Model: "org/model", ModelPath: t.TempDir(), Timeout: 200 * time.Millisecond}
+
+	started := time.Now()
+	_, err := hook.run(context.Background())
+	assert.EqualError(t, err, "timed out after 200ms")
+	assert.Less(t, time.Since(started), 10*time.Second, "the hook and its children are killed")
+}
+
+func TestRunDownloadHook(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	d.ctx = context.Background()
+	modelDir := writeTestModel(t, "org/model")
+
+	hook := downloadHook{Script: writeHookScript(t, "echo converted\n"), Model: "org/model", ModelPath: modelDir, Timeout: time.Minute}
+	d.runDownloadHook(hook)
+	hook.Script = writeHookScript(t, "echo 'out of memory' >&2\nexit 3\n")
+	d.runDownloadHook(hook)
+
+	events := d.events.Since(0)
+	require.Len(t, events, 2)
+	assert.Equal(t, EventHookCompleted, events[0].Type)
+	assert.Equal(t, EventHookFailed, events[1].Type)
+	assert.Equal(t, "hook.sh failed: exit status 3", events[1].Message)
+}
+
+func TestDownloadReady(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	assert.True(t, d.downloadReady("abcd"))
+
+	d.state.PinModel(&PinnedModel{Model: "org/model", InfoHash: "abcd"})
+	assert.False(t, d.downloadReady("abcd"), "pinned models get no hooks")
+}
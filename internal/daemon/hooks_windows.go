@@ -0,0 +1,9 @@
+//go:build windows
+
+package daemon
+
+import "os/exec"
+
+// killProcessGroup leaves cmd as it is on Windows, where only the hook
+// process itself is killed when the command is cancelled
+func killProcessGroup(cmd *exec.Cmd) {}
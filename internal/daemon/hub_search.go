@@ -0,0 +1,155 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/proxy"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+const (
+	// Results of a hub search without a limit
+	defaultHubSearchLimit = 20
+
+	hubSearchTimeout = 30 * time.Second
+)
+
+// HubModel is a model found on the HuggingFace Hub, with whether the
+// Silmaril network and this node have it too
+type HubModel struct {
+	ID           string   `json:"id"`
+	Downloads    int64    `json:"downloads"`
+	Likes        int64    `json:"likes"`
+	PipelineTag  string   `json:"pipeline_tag,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Gated        bool     `json:"gated,omitempty"`
+	LastModified string   `json:"last_modified,omitempty"`
+
+	// Set when the catalog has the model, it can be downloaded from peers
+	OnNetwork bool   `json:"on_network"`
+	InfoHash  string `json:"info_hash,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+	Seeders   int    `json:"seeders,omitempty"`
+
+	Local bool `json:"local"`
+}
+
+// hubSearchResult is a model in the response of the hub's model search
+type hubSearchResult struct {
+	ID           string      `json:"id"`
+	Downloads    int64       `json:"downloads"`
+	Likes        int64       `json:"likes"`
+	PipelineTag  string      `json:"pipeline_tag"`
+	Tags         []string    `json:"tags"`
+	Gated        interface{} `json:"gated"` // false, "auto" or "manual"
+	LastModified string      `json:"lastModified"`
+}
+
+// SearchHub searches the HuggingFace Hub the HF proxy uses for models
+// matching term, most downloaded first, and marks the ones the catalog or
+// the local store have. Requests go through the git proxy, like clones
+// from the hub, and carry the HuggingFace token if there is one.
+func (d *Daemon) SearchHub(term string, limit int) ([]HubModel, error) {
+	if limit <= 0 {
+		limit = defaultHubSearchLimit
+	}
+	query := url.Values{}
+	query.Set("search", term)
+	query.Set("sort", "downloads")
+	query.Set("direction", "-1")
+	query.Set("limit", strconv.Itoa(limit))
+
+	req, err := http.NewRequest(http.MethodGet, d.HFProxyUpstream()+"/api/models?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if token := d.hfToken(req.URL.String()); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	hubProxy, err := d.Proxy(proxy.Git)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := hubProxy.HTTPClient(hubSearchTimeout).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search the hub: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to search the hub: %s", resp.Status)
+	}
+
+	var results []hubSearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode hub results: %w", err)
+	}
+
+	hubModels := make([]HubModel, 0, len(results))
+	for _, result := range results {
+		gated := false
+		switch g := result.Gated.(type) {
+		case bool:
+			gated = g
+		case string:
+			gated = g != ""
+		}
+		hubModels = append(hubModels, HubModel{
+			ID:           result.ID,
+			Downloads:    result.Downloads,
+			Likes:        result.Likes,
+			PipelineTag:  result.PipelineTag,
+			Tags:         result.Tags,
+			Gated:        gated,
+			LastModified: result.LastModified,
+		})
+	}
+
+	var catalog []*types.ModelAnnouncement
+	if d.dhtManager != nil && len(hubModels) > 0 {
+		if catalog, err = d.dhtManager.DiscoverModels("*"); err != nil {
+			fmt.Printf("[Search] Failed to read the catalog: %v\n", err)
+		}
+	}
+	annotateHubModels(hubModels, catalog)
+	return hubModels, nil
+}
+
+// annotateHubModels marks the hub models the catalog has, with the latest
+// version announced, and the ones stored locally
+func annotateHubModels(hubModels []HubModel, catalog []*types.ModelAnnouncement) {
+	announced := make(map[string]*types.ModelAnnouncement, len(catalog))
+	for _, model := range catalog {
+		if model.InfoHash == "" {
+			continue
+		}
+		key := strings.ToLower(model.Name)
+		if latest, ok := announced[key]; !ok || model.Time > latest.Time {
+			announced[key] = model
+		}
+	}
+
+	for i := range hubModels {
+		hubModel := &hubModels[i]
+		if model, ok := announced[strings.ToLower(hubModel.ID)]; ok {
+			hubModel.OnNetwork = true
+			hubModel.InfoHash = model.InfoHash
+			hubModel.Version = model.Version
+			hubModel.Size = model.Size
+			hubModel.Seeders = model.Seeders
+		}
+		if modelPath, err := modelPathFor(hubModel.ID); err == nil {
+			if _, err := os.Lstat(modelPath); err == nil {
+				hubModel.Local = true
+			}
+		}
+	}
+}
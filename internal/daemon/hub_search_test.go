@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchHub(t *testing.T) {
+	t.Setenv("HF_TOKEN", "secret")
+	var query, auth string
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/models", r.URL.Path)
+		query = r.URL.RawQuery
+		auth = r.Header.Get("Authorization")
+		io.WriteString(w, `[
+			{"id": "org/model", "downloads": 100, "likes": 5, "pipeline_tag": "text-generation", "gated": false},
+			{"id": "org/gated", "downloads": 10, "gated": "manual"}
+		]`)
+	}))
+	defer hub.Close()
+
+	d := newRenameTestDaemon(t)
+	d.config = &config.Config{HFProxy: config.HFProxyConfig{Upstream: hub.URL}}
+	writeTestModel(t, "org/model")
+
+	results, err := d.SearchHub("llama 3", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "direction=-1&limit=20&search=llama+3&sort=downloads", query)
+	assert.Equal(t, "Bearer secret", auth)
+	require.Len(t, results, 2)
+	assert.Equal(t, HubModel{ID: "org/model", Downloads: 100, Likes: 5, PipelineTag: "text-generation", Local: true}, results[0])
+	assert.True(t, results[1].Gated)
+	assert.False(t, results[1].Local)
+
+	hub.Close()
+	_, err = d.SearchHub("llama", 5)
+	assert.Error(t, err)
+}
+
+func TestAnnotateHubModels(t *testing.T) {
+	newRenameTestDaemon(t)
+	hubModels := []HubModel{{ID: "Org/Model"}, {ID: "org/other"}}
+	annotateHubModels(hubModels, []*types.ModelAnnouncement{
+		{Name: "org/model", Version: "1.0", InfoHash: "aaaa", Time: 1},
+		{Name: "org/model", Version: "2.0", InfoHash: "bbbb", Size: 1024, Seeders: 3, Time: 2},
+		{Name: "org/other", Version: "1.0"},
+	})
+
+	assert.Equal(t, HubModel{ID: "Org/Model", OnNetwork: true, InfoHash: "bbbb", Version: "2.0", Size: 1024, Seeders: 3}, hubModels[0])
+	assert.False(t, hubModels[1].OnNetwork, "announcements without an infohash can't be downloaded")
+}
@@ -0,0 +1,101 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/anacrolix/torrent"
+)
+
+// Seed modes of a complete torrent
+const (
+	SeedModeNormal  = "normal"
+	SeedModeInitial = "initial"
+)
+
+// Peers a torrent keeps connected in initial seed mode. Uploading to fewer
+// peers at a time gets whole pieces into the swarm sooner, so peers can trade
+// them among themselves instead of all waiting on us.
+const initialSeedMaxConns = 8
+
+// distributedCopies returns how many complete copies of a torrent its peers
+// hold between them: the availability of the rarest piece plus the fraction of
+// pieces that are more common than that. availability holds the number of
+// peers having each piece.
+func distributedCopies(availability []int) float64 {
+	if len(availability) == 0 {
+		return 0
+	}
+
+	rarest := availability[0]
+	for _, n := range availability {
+		if n < rarest {
+			rarest = n
+		}
+	}
+	above := 0
+	for _, n := range availability {
+		if n > rarest {
+			above++
+		}
+	}
+	return float64(rarest) + float64(above)/float64(len(availability))
+}
+
+// pieceAvailability counts the connected peers having each piece of t
+func pieceAvailability(t *torrent.Torrent) []int {
+	availability := make([]int, t.NumPieces())
+	for _, pc := range t.PeerConns() {
+		pieces := pc.PeerPieces()
+		it := pieces.Iterator()
+		for it.HasNext() {
+			if i := int(it.Next()); i < len(availability) {
+				availability[i]++
+			}
+		}
+	}
+	return availability
+}
+
+// nextSeedMode decides the seed mode of a complete torrent. We seed initially
+// while no other seeder is connected and the peers don't hold a complete copy
+// between them yet; once they do, the swarm survives without us being special.
+func nextSeedMode(current string, peers, seeders int, copies float64) string {
+	switch {
+	case seeders > 0 || copies >= 1:
+		return SeedModeNormal
+	case peers > 0:
+		return SeedModeInitial
+	}
+	// Without peers there is nothing to learn, keep the current mode
+	if current == "" {
+		return SeedModeNormal
+	}
+	return current
+}
+
+// updateSeedModes switches complete torrents into initial seed mode while we
+// are their sole seeder and back once the swarm holds a full copy
+func (d *Daemon) updateSeedModes() {
+	if d.torrentManager == nil {
+		return
+	}
+	enabled := d.config == nil || d.config.GetBool("torrent.initial_seeding")
+
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		t := mt.Torrent
+		if !mt.Complete() {
+			continue
+		}
+
+		stats := t.Stats()
+		copies := distributedCopies(pieceAvailability(t))
+		mode := SeedModeNormal
+		if enabled {
+			mode = nextSeedMode(mt.SeedMode, stats.ActivePeers, stats.ConnectedSeeders, copies)
+		}
+		if changed := d.torrentManager.SetSeedMode(mt.InfoHash, mode, copies); changed {
+			fmt.Printf("[Seeding] %s: %s seed mode (distributed copies: %.2f, peers: %d)\n",
+				mt.Name, mode, copies, stats.ActivePeers)
+		}
+	}
+}
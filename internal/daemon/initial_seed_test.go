@@ -0,0 +1,29 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistributedCopies(t *testing.T) {
+	assert.Equal(t, 0.0, distributedCopies(nil))
+	assert.Equal(t, 0.0, distributedCopies([]int{0, 0, 0, 0}))
+	assert.Equal(t, 0.5, distributedCopies([]int{1, 1, 0, 0}))
+	assert.Equal(t, 1.0, distributedCopies([]int{1, 1, 1, 1}))
+	assert.Equal(t, 1.25, distributedCopies([]int{1, 2, 1, 1}))
+}
+
+func TestNextSeedMode(t *testing.T) {
+	// Sole seeder of peers without a full copy
+	assert.Equal(t, SeedModeInitial, nextSeedMode("", 3, 0, 0.5))
+	assert.Equal(t, SeedModeInitial, nextSeedMode(SeedModeNormal, 3, 0, 0.9))
+
+	// Another seeder or a full copy in the swarm
+	assert.Equal(t, SeedModeNormal, nextSeedMode(SeedModeInitial, 3, 1, 0.5))
+	assert.Equal(t, SeedModeNormal, nextSeedMode(SeedModeInitial, 3, 0, 1.0))
+
+	// No peers keeps the current mode
+	assert.Equal(t, SeedModeNormal, nextSeedMode("", 0, 0, 0))
+	assert.Equal(t, SeedModeInitial, nextSeedMode(SeedModeInitial, 0, 0, 0))
+}
@@ -0,0 +1,401 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/signing"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// ErrModelNotFound is returned for a name that is no local model or alias
+var ErrModelNotFound = errors.New("model not found")
+
+// Status of a manifest file on disk
+const (
+	FileOK           = "ok"
+	FileMissing      = "missing"
+	FileSizeMismatch = "size_mismatch"
+	FileExtra        = "extra" // On disk but not in the manifest
+)
+
+// Status of a manifest signature
+const (
+	SignatureUnsigned   = "unsigned"
+	SignatureVerified   = "verified"   // Signed with this node's key
+	SignatureUnverified = "unverified" // Signed with a key we don't have
+)
+
+// ModelInspection describes a local model, its files, torrent and seeding
+type ModelInspection struct {
+	Name      string                 `json:"name"`
+	Path      string                 `json:"path"` // Where the files are
+	Linked    bool                   `json:"linked,omitempty"`
+	Manifest  *types.ModelManifest   `json:"manifest"`
+	Files     []InspectedFile        `json:"files"`
+	Signature SignatureStatus        `json:"signature"`
+	Signers   *SignersStatus         `json:"signers,omitempty"`
+	Torrent   *TorrentInspection     `json:"torrent,omitempty"`
+	Seeding   *SeedingInspection     `json:"seeding,omitempty"`
+	Publisher *PublisherVerification `json:"publisher,omitempty"` // Namespace claim of its catalog publisher
+}
+
+// InspectedFile is a file of the manifest checked against the one on disk
+type InspectedFile struct {
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	DiskSize int64  `json:"disk_size,omitempty"`
+	SHA256   string `json:"sha256,omitempty"`
+	Status   string `json:"status"`
+}
+
+// SignatureStatus tells whether the manifest is signed and by whom
+type SignatureStatus struct {
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// SignersStatus tells which publisher keys signed the manifest and whether
+// they meet its signature policy
+type SignersStatus struct {
+	Status       string   `json:"status"` // SignatureVerified or SignatureUnverified
+	Signers      []string `json:"signers,omitempty"`
+	Organization string   `json:"organization,omitempty"`
+	PolicyID     string   `json:"policy_id,omitempty"`
+	Threshold    int      `json:"threshold,omitempty"`
+	Keys         int      `json:"keys,omitempty"`
+	Detail       string   `json:"detail,omitempty"`
+}
+
+// TorrentInspection are the piece stats of the torrent of a model. Pieces
+// are only counted complete while the torrent is running.
+type TorrentInspection struct {
+	InfoHash       string     `json:"info_hash"`
+	PieceLength    int64      `json:"piece_length"`
+	Pieces         int        `json:"pieces"`
+	PiecesComplete int        `json:"pieces_complete"`
+	Files          int        `json:"files"`
+	Size           int64      `json:"size"`
+	Private        bool       `json:"private,omitempty"`
+	CreatedBy      string     `json:"created_by,omitempty"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	Running        bool       `json:"running"`
+	Peers          int        `json:"peers"`
+}
+
+// SeedingInspection is the latest seed of a model and its seeding policy
+type SeedingInspection struct {
+	TransferID     string               `json:"transfer_id,omitempty"`
+	Status         TransferStatus       `json:"status,omitempty"`
+	Ratio          float64              `json:"ratio"`
+	SeedingSeconds int64                `json:"seeding_seconds"`
+	SeedMode       string               `json:"seed_mode,omitempty"`
+	StopReason     string               `json:"stop_reason,omitempty"`
+	Policy         *types.SeedingPolicy `json:"policy,omitempty"`
+}
+
+// InspectModel describes the local model name, which may be an alias
+func (d *Daemon) InspectModel(name string) (*ModelInspection, error) {
+	name, modelPath, manifest, err := d.localManifest(name)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := storage.ResolveModelDir(modelPath)
+	_, linked := storage.IsLinkedModel(modelPath)
+	inspection := &ModelInspection{
+		Name:      name,
+		Path:      dir,
+		Linked:    linked,
+		Manifest:  manifest,
+		Files:     inspectFiles(dir, manifest.Files),
+		Signature: signatureStatus(manifest),
+		Signers:   signersStatus(manifest, d.organizations()),
+	}
+
+	inspection.Torrent, err = d.inspectTorrent(name)
+	if err != nil && !errors.Is(err, ErrTorrentNotFound) {
+		return nil, err
+	}
+	inspection.Seeding = d.inspectSeeding(name, manifest)
+	if d.dhtManager != nil {
+		inspection.Publisher = d.dhtManager.CatalogPublisher(context.Background(), name)
+	}
+	return inspection, nil
+}
+
+// localManifest resolves name to a local model and reads its manifest,
+// from the model directory or else the registry
+func (d *Daemon) localManifest(name string) (string, string, *types.ModelManifest, error) {
+	name = d.ResolveModel(name)
+	modelPath, err := modelPathFor(name)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if _, err := os.Lstat(modelPath); err != nil {
+		return "", "", nil, fmt.Errorf("%w: %s", ErrModelNotFound, name)
+	}
+
+	manifest, err := models.ReadManifest(modelPath)
+	if err != nil && d.registry != nil {
+		manifest, err = d.registry.GetManifest(name)
+	}
+	if err != nil {
+		return "", "", nil, fmt.Errorf("no manifest for model %s: %w", name, err)
+	}
+	return name, modelPath, manifest, nil
+}
+
+// inspectFiles checks the files of the manifest in dir, followed by the
+// files in dir the manifest doesn't list
+func inspectFiles(dir string, files []types.ModelFile) []InspectedFile {
+	listed := make(map[string]bool, len(files))
+	inspected := make([]InspectedFile, 0, len(files))
+	for _, file := range files {
+		listed[file.Path] = true
+		f := InspectedFile{Path: file.Path, Size: file.Size, SHA256: file.SHA256, Status: FileOK}
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(file.Path)))
+		switch {
+		case err != nil:
+			f.Status = FileMissing
+		case info.Size() != file.Size:
+			f.Status = FileSizeMismatch
+			f.DiskSize = info.Size()
+		}
+		inspected = append(inspected, f)
+	}
+	sort.Slice(inspected, func(i, j int) bool { return inspected[i].Path < inspected[j].Path })
+
+	var extra []InspectedFile
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || storage.IsStateFile(info.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || listed[filepath.ToSlash(rel)] {
+			return nil
+		}
+		extra = append(extra, InspectedFile{Path: filepath.ToSlash(rel), DiskSize: info.Size(), Status: FileExtra})
+		return nil
+	})
+	return append(inspected, extra...)
+}
+
+// signatureStatus checks the signature of manifest against this node's key.
+// Manifests of other publishers can't be verified without theirs.
+func signatureStatus(manifest *types.ModelManifest) SignatureStatus {
+	if manifest.Signature == "" {
+		return SignatureStatus{Status: SignatureUnsigned}
+	}
+
+	keysDir, err := signing.DefaultKeysDir()
+	if err != nil {
+		return SignatureStatus{Status: SignatureUnverified, Detail: err.Error()}
+	}
+	publicKey, err := signing.LoadPublicKey(filepath.Join(keysDir, "public.pem"))
+	if err != nil {
+		return SignatureStatus{Status: SignatureUnverified, Detail: "no local key to verify it with"}
+	}
+	if err := signing.VerifyManifest(manifest, publicKey); err != nil {
+		return SignatureStatus{Status: SignatureUnverified, Detail: "not signed with this node's key"}
+	}
+	return SignatureStatus{Status: SignatureVerified, Detail: "signed with this node's key"}
+}
+
+// signersStatus checks the publisher signatures of manifest, nil if it has
+// neither signatures nor a signature policy
+func signersStatus(manifest *types.ModelManifest, organizations map[string]string) *SignersStatus {
+	if manifest.SignaturePolicy == nil && len(manifest.Signatures) == 0 {
+		return nil
+	}
+	status := &SignersStatus{Status: SignatureVerified}
+	if policy := manifest.SignaturePolicy; policy != nil {
+		status.Organization = policy.Organization
+		status.PolicyID = signing.PolicyID(policy)
+		status.Threshold = policy.Threshold
+		status.Keys = len(policy.Keys)
+	}
+	signers, err := signing.VerifySignatures(manifest)
+	status.Signers = signers
+	if err == nil {
+		err = checkManifestSignatures(manifest, organizations)
+	}
+	if err != nil {
+		status.Status = SignatureUnverified
+		status.Detail = err.Error()
+	}
+	return status
+}
+
+// inspectTorrent reads the torrent of the model, counting the complete
+// pieces if it is running
+func (d *Daemon) inspectTorrent(name string) (*TorrentInspection, error) {
+	data, infoHash, err := d.ExportTorrent(name)
+	if err != nil {
+		return nil, err
+	}
+	mi, err := metainfo.Load(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse torrent: %w", err)
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse torrent info: %w", err)
+	}
+
+	inspection := &TorrentInspection{
+		InfoHash:    infoHash,
+		PieceLength: info.PieceLength,
+		Pieces:      info.NumPieces(),
+		Files:       len(info.UpvertedFiles()),
+		Size:        info.TotalLength(),
+		Private:     info.Private != nil && *info.Private,
+		CreatedBy:   mi.CreatedBy,
+	}
+	if mi.CreationDate > 0 {
+		createdAt := time.Unix(mi.CreationDate, 0)
+		inspection.CreatedAt = &createdAt
+	}
+
+	if d.torrentManager != nil {
+		if mt, ok := d.torrentManager.GetTorrent(infoHash); ok && mt.Torrent.Info() != nil {
+			t := mt.Torrent
+			inspection.Running = true
+			inspection.Peers = t.Stats().ActivePeers
+			for i := 0; i < t.NumPieces(); i++ {
+				if t.PieceState(i).Complete {
+					inspection.PiecesComplete++
+				}
+			}
+		}
+	}
+	return inspection, nil
+}
+
+// inspectSeeding returns the latest seed of the model and its seeding
+// policy, nil if it has neither
+func (d *Daemon) inspectSeeding(name string, manifest *types.ModelManifest) *SeedingInspection {
+	var latest *Transfer
+	if d.transferManager != nil {
+		for _, transfer := range d.transferManager.GetAllTransfers() {
+			if transfer.Type != TransferTypeSeed || transfer.ModelName != name {
+				continue
+			}
+			if latest == nil || transfer.StartedAt.After(latest.StartedAt) {
+				latest = transfer
+			}
+		}
+	}
+	if latest == nil && manifest.Seeding == nil {
+		return nil
+	}
+
+	seeding := &SeedingInspection{Policy: manifest.Seeding}
+	if latest != nil {
+		seeding.TransferID = latest.ID
+		seeding.Status = latest.Status
+		seeding.Ratio = latest.Ratio
+		seeding.SeedingSeconds = latest.SeedingSeconds
+		seeding.SeedMode = latest.SeedMode
+		seeding.StopReason = latest.StopReason
+	}
+	return seeding
+}
+
+// ModelDiff are the differences between the files of two models
+type ModelDiff struct {
+	A         string            `json:"a"`
+	B         string            `json:"b"`
+	VersionA  string            `json:"version_a,omitempty"`
+	VersionB  string            `json:"version_b,omitempty"`
+	Added     []types.ModelFile `json:"added"`   // Only in B
+	Removed   []types.ModelFile `json:"removed"` // Only in A
+	Changed   []ChangedFile     `json:"changed"`
+	Unchanged int               `json:"unchanged"`
+	SizeDelta int64             `json:"size_delta"` // Size of B less the size of A
+}
+
+// ChangedFile is a file in both models with another size or hash
+type ChangedFile struct {
+	Path    string `json:"path"`
+	SizeA   int64  `json:"size_a"`
+	SizeB   int64  `json:"size_b"`
+	SHA256A string `json:"sha256_a,omitempty"`
+	SHA256B string `json:"sha256_b,omitempty"`
+}
+
+// DiffModels compares the files of the local models a and b, which may be
+// aliases
+func (d *Daemon) DiffModels(a, b string) (*ModelDiff, error) {
+	nameA, _, manifestA, err := d.localManifest(a)
+	if err != nil {
+		return nil, err
+	}
+	nameB, _, manifestB, err := d.localManifest(b)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := DiffManifests(manifestA, manifestB)
+	diff.A, diff.B = nameA, nameB
+	return diff, nil
+}
+
+// DiffManifests compares the file lists of two manifests. Files differ if
+// their sizes do, or their hashes when both manifests have one.
+func DiffManifests(a, b *types.ModelManifest) *ModelDiff {
+	diff := &ModelDiff{
+		A:        a.Name,
+		B:        b.Name,
+		VersionA: a.Version,
+		VersionB: b.Version,
+		Added:    []types.ModelFile{},
+		Removed:  []types.ModelFile{},
+		Changed:  []ChangedFile{},
+	}
+
+	filesA := make(map[string]types.ModelFile, len(a.Files))
+	for _, file := range a.Files {
+		filesA[file.Path] = file
+		diff.SizeDelta -= file.Size
+	}
+	for _, fileB := range b.Files {
+		diff.SizeDelta += fileB.Size
+		fileA, ok := filesA[fileB.Path]
+		if !ok {
+			diff.Added = append(diff.Added, fileB)
+			continue
+		}
+		delete(filesA, fileB.Path)
+
+		hashChanged := fileA.SHA256 != "" && fileB.SHA256 != "" && fileA.SHA256 != fileB.SHA256
+		if fileA.Size == fileB.Size && !hashChanged {
+			diff.Unchanged++
+			continue
+		}
+		diff.Changed = append(diff.Changed, ChangedFile{
+			Path:    fileB.Path,
+			SizeA:   fileA.Size,
+			SizeB:   fileB.Size,
+			SHA256A: fileA.SHA256,
+			SHA256B: fileB.SHA256,
+		})
+	}
+	for _, fileA := range filesA {
+		diff.Removed = append(diff.Removed, fileA)
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Path < diff.Added[j].Path })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Path < diff.Removed[j].Path })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Path < diff.Changed[j].Path })
+	return diff
+}
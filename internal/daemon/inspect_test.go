@@ -0,0 +1,120 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/signing"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectModel(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	d := newRenameTestDaemon(t)
+	modelDir := writeTestModel(t, "org/model")
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "config.json"), []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "notes.txt"), []byte("notes"), 0644))
+	ratio := 2.0
+	require.NoError(t, models.WriteManifest(modelDir, &types.ModelManifest{
+		Name:    "org/model",
+		Version: "1.0",
+		Files: []types.ModelFile{
+			{Path: "model.safetensors", Size: 1024, SHA256: "aaaa"},
+			{Path: "config.json", Size: 10},
+			{Path: "tokenizer.json", Size: 5},
+		},
+		Seeding: &types.SeedingPolicy{SeedRatio: &ratio},
+	}))
+	seed := d.transferManager.CreateSeed("org/model", "hash")
+	_, err := d.SetAlias("m", "org/model")
+	require.NoError(t, err)
+
+	inspection, err := d.InspectModel("m")
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", inspection.Name)
+	assert.Equal(t, "1.0", inspection.Manifest.Version)
+	assert.Equal(t, []InspectedFile{
+		{Path: "config.json", Size: 10, DiskSize: 2, Status: FileSizeMismatch},
+		{Path: "model.safetensors", Size: 1024, SHA256: "aaaa", Status: FileOK},
+		{Path: "tokenizer.json", Size: 5, Status: FileMissing},
+		{Path: "notes.txt", DiskSize: 5, Status: FileExtra},
+	}, inspection.Files)
+	assert.Equal(t, SignatureUnsigned, inspection.Signature.Status)
+	assert.Nil(t, inspection.Torrent, "the model has no torrent")
+	require.NotNil(t, inspection.Seeding)
+	assert.Equal(t, seed.ID, inspection.Seeding.TransferID)
+	assert.Equal(t, &ratio, inspection.Seeding.Policy.SeedRatio)
+
+	_, err = d.InspectModel("org/missing")
+	assert.ErrorIs(t, err, ErrModelNotFound)
+}
+
+func TestSignatureStatus(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	manifest := &types.ModelManifest{Name: "org/model", Version: "1.0"}
+	assert.Equal(t, SignatureUnsigned, signatureStatus(manifest).Status)
+
+	other, err := signing.GenerateKeyPair()
+	require.NoError(t, err)
+	require.NoError(t, signing.SignManifest(manifest, other.PrivateKey))
+	assert.Equal(t, SignatureUnverified, signatureStatus(manifest).Status, "there is no local key")
+
+	keys, err := signing.GetOrCreateKeys()
+	require.NoError(t, err)
+	assert.Equal(t, SignatureUnverified, signatureStatus(manifest).Status, "signed with another key")
+
+	require.NoError(t, signing.SignManifest(manifest, keys.PrivateKey))
+	assert.Equal(t, SignatureVerified, signatureStatus(manifest).Status)
+}
+
+func TestDiffManifests(t *testing.T) {
+	a := &types.ModelManifest{Name: "org/model", Version: "1.0", Files: []types.ModelFile{
+		{Path: "config.json", Size: 10, SHA256: "c1"},
+		{Path: "model.safetensors", Size: 100, SHA256: "m1"},
+		{Path: "old.bin", Size: 50},
+		{Path: "tokenizer.json", Size: 20},
+	}}
+	b := &types.ModelManifest{Name: "org/model", Version: "2.0", Files: []types.ModelFile{
+		{Path: "config.json", Size: 10, SHA256: "c2"},
+		{Path: "model.safetensors", Size: 120, SHA256: "m2"},
+		{Path: "new.bin", Size: 70},
+		{Path: "tokenizer.json", Size: 20, SHA256: "t"},
+	}}
+
+	diff := DiffManifests(a, b)
+	assert.Equal(t, "1.0", diff.VersionA)
+	assert.Equal(t, "2.0", diff.VersionB)
+	assert.Equal(t, []types.ModelFile{{Path: "new.bin", Size: 70}}, diff.Added)
+	assert.Equal(t, []types.ModelFile{{Path: "old.bin", Size: 50}}, diff.Removed)
+	assert.Equal(t, []ChangedFile{
+		{Path: "config.json", SizeA: 10, SizeB: 10, SHA256A: "c1", SHA256B: "c2"},
+		{Path: "model.safetensors", SizeA: 100, SizeB: 120, SHA256A: "m1", SHA256B: "m2"},
+	}, diff.Changed)
+	assert.Equal(t, 1, diff.Unchanged, "files hashed on one side only compare by size")
+	assert.Equal(t, int64(40), diff.SizeDelta)
+}
+
+func TestDiffModels(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	writeTestModel(t, "org/model-v1")
+	dirB := writeTestModel(t, "org/model-v2")
+	require.NoError(t, models.WriteManifest(dirB, &types.ModelManifest{
+		Name:  "org/model-v2",
+		Files: []types.ModelFile{{Path: "model.safetensors", Size: 1024}},
+	}))
+	_, err := d.SetAlias("new", "org/model-v2")
+	require.NoError(t, err)
+
+	diff, err := d.DiffModels("org/model-v1", "new")
+	require.NoError(t, err)
+	assert.Equal(t, "org/model-v1", diff.A)
+	assert.Equal(t, "org/model-v2", diff.B)
+	assert.Len(t, diff.Added, 1)
+
+	_, err = d.DiffModels("org/model-v1", "org/missing")
+	assert.ErrorIs(t, err, ErrModelNotFound)
+}
@@ -0,0 +1,337 @@
+package daemon
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/internal/signing"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// Where the rotations of our publisher key and the keys we rotated away from
+// are kept, in the keys directory
+const (
+	rotationsFile  = "rotations.json"
+	retiredKeysDir = "retired"
+)
+
+// KeyRotationResult is the outcome of rotating our publisher key
+type KeyRotationResult struct {
+	OldKey      string                `json:"old_key"`
+	NewKey      string                `json:"new_key"`
+	Rotation    discovery.KeyRotation `json:"rotation"`
+	Resigned    int                   `json:"resigned"`    // Local manifests signed again
+	Republished int                   `json:"republished"` // Models published again under the new key
+}
+
+// RotatePublisherKey replaces our publisher key with a new one, cross-signed
+// with the old key, and republishes our models under it. Subscribers of the
+// old key follow the rotation to the new one. The manifest signing key is
+// replaced too and local manifests signed with the old one are signed again.
+func (d *Daemon) RotatePublisherKey() (*KeyRotationResult, error) {
+	if d.dhtManager == nil {
+		return nil, fmt.Errorf("DHT not available")
+	}
+
+	rotation, republished, err := d.dhtManager.RotatePublisherKey()
+	if err != nil {
+		return nil, err
+	}
+	result := &KeyRotationResult{
+		OldKey:      rotation.Old,
+		NewKey:      rotation.New,
+		Rotation:    rotation,
+		Republished: republished,
+	}
+
+	result.Resigned, err = d.resignManifests()
+	if err != nil {
+		fmt.Printf("[Keys] %v\n", err)
+	}
+
+	d.events.Publish(EventKeyRotated, "", "publisher key rotated from %s to %s, %d manifests signed again", rotation.Old, rotation.New, result.Resigned)
+	return result, nil
+}
+
+// resignManifests replaces the manifest signing key and signs the local
+// manifests that were signed with the old one again with the new one
+func (d *Daemon) resignManifests() (int, error) {
+	oldKeys, newKeys, err := signing.RotateKeys()
+	if err != nil {
+		return 0, fmt.Errorf("failed to rotate manifest signing keys: %w", err)
+	}
+	if oldKeys == nil || d.registry == nil {
+		return 0, nil
+	}
+
+	resigned := 0
+	for _, manifest := range d.registry.GetAllManifests() {
+		// Manifests of other publishers keep their signature
+		if manifest.Signature == "" || signing.VerifyManifest(manifest, oldKeys.PublicKey) != nil {
+			continue
+		}
+		// Callers may hold the old manifest, sign a copy
+		updated := *manifest
+		if err := signing.SignManifest(&updated, newKeys.PrivateKey); err != nil {
+			fmt.Printf("[Keys] Failed to sign manifest of %s: %v\n", manifest.Name, err)
+			continue
+		}
+		if err := d.registry.SaveManifest(&updated); err != nil {
+			fmt.Printf("[Keys] Failed to save manifest of %s: %v\n", manifest.Name, err)
+			continue
+		}
+		resigned++
+	}
+	return resigned, nil
+}
+
+// keysDir returns the directory our publisher key is stored in
+func (dm *DHTManager) keysDir() string {
+	return filepath.Dir(dm.publisherKeyPath())
+}
+
+// retiredKeyPath returns where a key we rotated away from is stored
+func (dm *DHTManager) retiredKeyPath(publicKey string) string {
+	return filepath.Join(dm.keysDir(), retiredKeysDir, publicKey+".key")
+}
+
+// loadRotations returns the rotations of our publisher key, the oldest first
+func (dm *DHTManager) loadRotations() ([]discovery.KeyRotation, error) {
+	data, err := os.ReadFile(filepath.Join(dm.keysDir(), rotationsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key rotations: %w", err)
+	}
+	var rotations []discovery.KeyRotation
+	if err := json.Unmarshal(data, &rotations); err != nil {
+		return nil, fmt.Errorf("failed to parse key rotations: %w", err)
+	}
+	return rotations, nil
+}
+
+// saveRotations records the rotations of our publisher key
+func (dm *DHTManager) saveRotations(rotations []discovery.KeyRotation) error {
+	data, err := json.MarshalIndent(rotations, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode key rotations: %w", err)
+	}
+	if err := storage.WriteFileAtomic(filepath.Join(dm.keysDir(), rotationsFile), data, 0600); err != nil {
+		return fmt.Errorf("failed to save key rotations: %w", err)
+	}
+	return nil
+}
+
+// RetiredKeys returns the keys we rotated away from whose rotation is still
+// published
+func (dm *DHTManager) RetiredKeys() []string {
+	rotations, err := dm.loadRotations()
+	if err != nil {
+		return nil
+	}
+	var keys []string
+	for _, rotation := range rotations {
+		if !rotation.Expired(time.Now()) {
+			keys = append(keys, rotation.Old)
+		}
+	}
+	return keys
+}
+
+// RotatePublisherKey generates a new publisher key and rotates to it. The old
+// key is kept to publish the rotation in its catalog until the rotation
+// expires. It returns the rotation and the number of models published again
+// under the new key, which happens in the background.
+func (dm *DHTManager) RotatePublisherKey() (discovery.KeyRotation, int, error) {
+	dm.mu.RLock()
+	oldKey := dm.publisherKey
+	dm.mu.RUnlock()
+	if oldKey == nil {
+		return discovery.KeyRotation{}, 0, fmt.Errorf("publisher key not available")
+	}
+
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return discovery.KeyRotation{}, 0, fmt.Errorf("failed to generate publisher key: %w", err)
+	}
+	rotation := discovery.NewKeyRotation(oldKey, newKey)
+
+	// Keep the old key first, a failure past this point must not lose it
+	if err := discovery.SavePublisherKey(dm.retiredKeyPath(rotation.Old), oldKey); err != nil {
+		return discovery.KeyRotation{}, 0, err
+	}
+	rotations, err := dm.loadRotations()
+	if err != nil {
+		return discovery.KeyRotation{}, 0, err
+	}
+	if err := dm.saveRotations(append(rotations, rotation)); err != nil {
+		return discovery.KeyRotation{}, 0, err
+	}
+	if err := discovery.SavePublisherKey(dm.publisherKeyPath(), newKey); err != nil {
+		return discovery.KeyRotation{}, 0, err
+	}
+
+	dm.mu.Lock()
+	dm.publisherKey = newKey
+	dm.publisherID = rotation.New
+	if dm.publisherRef != nil {
+		dm.retiredRefs[rotation.Old] = dm.publisherRef
+		dm.publisherRef = nil
+	}
+	ready := dm.catalogRef != nil
+	republished := len(dm.announcements)
+	dm.mu.Unlock()
+
+	fmt.Printf("[DHT] Rotated publisher key from %s to %s\n", rotation.Old, rotation.New)
+
+	// Otherwise the rotation is published once the DHT is ready
+	if ready {
+		go func() {
+			dm.initOwnPublisherCatalog()
+			dm.publishRotations()
+		}()
+	}
+	return rotation, republished, nil
+}
+
+// publishRotations publishes the rotations of our publisher key in the
+// catalogs of the old keys, for their subscribers to follow, and in our own
+// and the public catalog, where the new key takes over the entries of the
+// old ones
+func (dm *DHTManager) publishRotations() {
+	rotations, err := dm.loadRotations()
+	if err != nil {
+		fmt.Printf("[DHT] %v\n", err)
+		return
+	}
+
+	dm.mu.RLock()
+	catalogRef := dm.catalogRef
+	own := dm.publisherRef
+	publisherKey := dm.publisherKey
+	announcements := make([]*types.ModelAnnouncement, 0, len(dm.announcements))
+	for _, ann := range dm.announcements {
+		announcements = append(announcements, ann)
+	}
+	dm.mu.RUnlock()
+
+	published := false
+	for _, rotation := range rotations {
+		if rotation.Expired(time.Now()) {
+			continue
+		}
+		published = true
+		if retired := dm.retiredCatalog(rotation.Old); retired != nil {
+			if err := retired.PublishRotation(rotation); err != nil {
+				fmt.Printf("[DHT] Failed to publish key rotation in catalog of %s: %v\n", rotation.Old, err)
+			}
+		}
+		for _, ref := range []*discovery.BEP44CatalogRef{own, catalogRef} {
+			if ref == nil {
+				continue
+			}
+			if err := ref.PublishRotation(rotation); err != nil {
+				fmt.Printf("[DHT] Failed to publish key rotation of %s: %v\n", rotation.Old, err)
+			}
+		}
+	}
+	if !published || catalogRef == nil {
+		return
+	}
+
+	// Renew our entries in the public catalog under the new key
+	for _, ann := range announcements {
+		if err := catalogRef.AddModelWithMetadata(ann.Name, ann.InfoHash, ann.Size, announcementMetadata(ann, publisherKey)); err != nil {
+			fmt.Printf("[DHT] Failed to republish %s under the new key: %v\n", ann.Name, err)
+		}
+	}
+}
+
+// republishRotations publishes the rotations of our publisher key in the
+// catalogs of the old keys again to keep them alive in the DHT, and stops
+// publishing the expired ones
+func (dm *DHTManager) republishRotations() {
+	rotations, err := dm.loadRotations()
+	if err != nil {
+		fmt.Printf("[DHT] %v\n", err)
+		return
+	}
+	for _, rotation := range rotations {
+		if rotation.Expired(time.Now()) {
+			dm.mu.Lock()
+			if ref, exists := dm.retiredRefs[rotation.Old]; exists {
+				ref.Close()
+				delete(dm.retiredRefs, rotation.Old)
+			}
+			dm.mu.Unlock()
+			continue
+		}
+		if retired := dm.retiredCatalog(rotation.Old); retired != nil {
+			if err := retired.PublishRotation(rotation); err != nil {
+				fmt.Printf("[DHT] Failed to republish key rotation in catalog of %s: %v\n", rotation.Old, err)
+			}
+		}
+	}
+}
+
+// retiredCatalog returns the catalog signed with a key we rotated away from,
+// nil if the key is gone
+func (dm *DHTManager) retiredCatalog(publicKey string) *discovery.BEP44CatalogRef {
+	dm.mu.RLock()
+	ref := dm.retiredRefs[publicKey]
+	dm.mu.RUnlock()
+	if ref != nil || dm.torrentClient == nil {
+		return ref
+	}
+
+	privateKey, err := discovery.LoadPublisherKey(dm.retiredKeyPath(publicKey))
+	if err != nil {
+		fmt.Printf("[DHT] Can't publish key rotation of %s: %v\n", publicKey, err)
+		return nil
+	}
+	ref, err = discovery.NewPublisherCatalogRef(dm.dhtServer, dm.torrentClient, privateKey, publisherCatalogDir(publicKey))
+	if err != nil {
+		fmt.Printf("[DHT] Failed to create publisher catalog of %s: %v\n", publicKey, err)
+		return nil
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	if existing, exists := dm.retiredRefs[publicKey]; exists {
+		ref.Close()
+		return existing
+	}
+	dm.retiredRefs[publicKey] = ref
+	return ref
+}
+
+// followRotation moves the subscription of a publisher that rotated its key
+// to the new key
+func (dm *DHTManager) followRotation(rotation discovery.KeyRotation) {
+	state := dm.state()
+	if state == nil || rotation.New == dm.PublisherKey() || !state.RotateSubscription(rotation.Old, rotation.New) {
+		return
+	}
+	if err := state.Save(); err != nil {
+		fmt.Printf("[DHT] Warning: failed to save subscriptions: %v\n", err)
+	}
+	fmt.Printf("[DHT] Publisher %s rotated its key to %s, following it\n", rotation.Old, rotation.New)
+
+	dm.mu.Lock()
+	if ref, exists := dm.subscriptions[rotation.Old]; exists {
+		ref.Close()
+		delete(dm.subscriptions, rotation.Old)
+	}
+	dm.mu.Unlock()
+
+	go dm.loadSubscription(rotation.New)
+}
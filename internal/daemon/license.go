@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// LicenseError is returned when downloading a model whose license has to be
+// accepted first
+type LicenseError struct {
+	Model      string
+	License    string
+	LicenseURL string
+}
+
+func (e *LicenseError) Error() string {
+	if e.LicenseURL != "" {
+		return fmt.Sprintf("model %s requires accepting its license %s (%s)", e.Model, e.License, e.LicenseURL)
+	}
+	return fmt.Sprintf("model %s requires accepting its license %s", e.Model, e.License)
+}
+
+// CheckLicense returns a *LicenseError if the catalog entry of a model
+// requires accepting its license and it wasn't. With accept the license is
+// accepted and the acceptance kept for later downloads.
+func (d *Daemon) CheckLicense(model *types.ModelAnnouncement, accept bool) error {
+	if model == nil || !model.RequireLicenseAcceptance {
+		return nil
+	}
+	if d.state.LicenseAccepted(model.Name, model.License, model.LicenseURL) {
+		return nil
+	}
+	if !accept {
+		return &LicenseError{Model: model.Name, License: model.License, LicenseURL: model.LicenseURL}
+	}
+
+	d.state.AcceptLicense(&LicenseAcceptance{
+		Model:      model.Name,
+		License:    model.License,
+		LicenseURL: model.LicenseURL,
+		AcceptedAt: time.Now(),
+	})
+	if err := d.state.Save(); err != nil {
+		fmt.Printf("[License] Warning: failed to save state: %v\n", err)
+	}
+	d.events.Publish(EventLicenseAccepted, model.Name, "accepted license %s", model.License)
+	d.releaseLicenseHolds(model.Name)
+	return nil
+}
+
+// manifestLicense returns the license terms of the manifest in dir as a
+// catalog entry of the model called name, nil if it has no manifest or the
+// license needn't be accepted. Manifests come from peers, so their terms
+// apply even if the catalog entry doesn't ask for acceptance.
+func manifestLicense(name, dir string) *types.ModelAnnouncement {
+	manifest, err := models.ReadManifest(dir)
+	if err != nil || !manifest.RequireLicenseAcceptance {
+		return nil
+	}
+	return &types.ModelAnnouncement{
+		Name:                     name,
+		License:                  manifest.License,
+		LicenseURL:               manifest.LicenseURL,
+		RequireLicenseAcceptance: true,
+	}
+}
+
+// unacceptedLicense returns a *LicenseError if the manifest in dir of the
+// model called name requires accepting its license and it wasn't
+func (d *Daemon) unacceptedLicense(name, dir string) error {
+	return d.CheckLicense(manifestLicense(name, dir), false)
+}
+
+// AcceptHeldLicense accepts the license in the manifest of a download held
+// in quarantine until it is, which releases the download once verified.
+// It reports whether such a download was found.
+func (d *Daemon) AcceptHeldLicense(name string) bool {
+	for _, q := range d.ListQuarantine() {
+		if q.Model != name || q.Status != QuarantineLicense {
+			continue
+		}
+		if model := manifestLicense(name, q.Path); model != nil {
+			return d.CheckLicense(model, true) == nil
+		}
+	}
+	return false
+}
+
+// releaseLicenseHolds verifies again the downloads of a model held in
+// quarantine until its license was accepted
+func (d *Daemon) releaseLicenseHolds(name string) {
+	for _, q := range d.ListQuarantine() {
+		if q.Model != name || q.Status != QuarantineLicense {
+			continue
+		}
+		mt, ok := d.torrentManager.GetTorrent(q.InfoHash)
+		if !ok {
+			continue
+		}
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			d.verifyQuarantined(mt, q)
+		}()
+	}
+}
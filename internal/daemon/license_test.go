@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLicense(t *testing.T) {
+	d := &Daemon{
+		state:  NewState(filepath.Join(t.TempDir(), "state.json")),
+		events: NewEventLog(0),
+	}
+
+	// Models without a gated license are always downloaded
+	assert.NoError(t, d.CheckLicense(nil, false))
+	assert.NoError(t, d.CheckLicense(&types.ModelAnnouncement{Name: "org/open", License: "mit"}, false))
+
+	gated := &types.ModelAnnouncement{
+		Name:                     "org/model",
+		License:                  "llama3",
+		LicenseURL:               "https://example.com/LICENSE",
+		RequireLicenseAcceptance: true,
+	}
+	err := d.CheckLicense(gated, false)
+	var licenseErr *LicenseError
+	require.ErrorAs(t, err, &licenseErr)
+	assert.Equal(t, "llama3", licenseErr.License)
+	assert.Equal(t, "https://example.com/LICENSE", licenseErr.LicenseURL)
+
+	// Accepting once covers later downloads
+	require.NoError(t, d.CheckLicense(gated, true))
+	assert.NoError(t, d.CheckLicense(gated, false))
+	require.Len(t, d.events.Since(0), 1)
+	assert.Equal(t, EventLicenseAccepted, d.events.Since(0)[0].Type)
+
+	// New terms have to be accepted again
+	changed := *gated
+	changed.LicenseURL = "https://example.com/LICENSE-v2"
+	assert.Error(t, d.CheckLicense(&changed, false))
+}
+
+func TestManifestLicense(t *testing.T) {
+	d := &Daemon{
+		state:  NewState(filepath.Join(t.TempDir(), "state.json")),
+		events: NewEventLog(0),
+	}
+
+	// Downloads without a manifest, or with an open license, aren't held
+	dir := t.TempDir()
+	assert.NoError(t, d.unacceptedLicense("org/model", dir))
+	require.NoError(t, models.WriteManifest(dir, &types.ModelManifest{Name: "org/model", License: "mit"}))
+	assert.NoError(t, d.unacceptedLicense("org/model", dir))
+
+	// A manifest from a peer asks for acceptance even if the catalog doesn't
+	require.NoError(t, models.WriteManifest(dir, &types.ModelManifest{
+		Name:                     "org/model",
+		License:                  "llama3",
+		LicenseURL:               "https://example.com/LICENSE",
+		RequireLicenseAcceptance: true,
+	}))
+	var licenseErr *LicenseError
+	require.ErrorAs(t, d.unacceptedLicense("org/renamed", dir), &licenseErr)
+	assert.Equal(t, "org/renamed", licenseErr.Model)
+	assert.Equal(t, "llama3", licenseErr.License)
+
+	require.NoError(t, d.CheckLicense(manifestLicense("org/renamed", dir), true))
+	assert.NoError(t, d.unacceptedLicense("org/renamed", dir))
+}
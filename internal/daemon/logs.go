@@ -0,0 +1,253 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Number of log lines kept in memory
+	DefaultLogBufferLines = 5000
+
+	// Size at which the daemon log file is rotated
+	maxLogFileSize = 10 * 1024 * 1024
+
+	// Number of rotated log files kept next to the current one
+	maxLogFileBackups = 3
+)
+
+// debugOutput controls whether [DEBUG] lines of captured output are kept
+var debugOutput atomic.Bool
+
+func init() {
+	debugOutput.Store(true)
+}
+
+// SetLogLevel sets the level of captured daemon output. At "info" level lines
+// starting with [DEBUG] are dropped, at "debug" level everything is kept.
+func SetLogLevel(level string) {
+	debugOutput.Store(level != "info")
+}
+
+// LogLine is a single line of daemon output
+type LogLine struct {
+	Seq  int64     `json:"seq"`
+	Time time.Time `json:"time"`
+	Text string    `json:"text"`
+}
+
+// LogBuffer keeps the most recent lines of daemon output in a ring buffer so
+// they can be read through the API without attaching to the process
+type LogBuffer struct {
+	mu      sync.RWMutex
+	lines   []LogLine
+	start   int
+	nextSeq int64
+	partial []byte
+}
+
+// NewLogBuffer creates a ring buffer holding up to size lines
+func NewLogBuffer(size int) *LogBuffer {
+	if size <= 0 {
+		size = DefaultLogBufferLines
+	}
+	return &LogBuffer{
+		lines:   make([]LogLine, 0, size),
+		nextSeq: 1,
+	}
+}
+
+// Write implements io.Writer, splitting the output into lines
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data := append(b.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		b.append(string(bytes.TrimRight(data[:i], "\r")))
+		data = data[i+1:]
+	}
+	b.partial = append([]byte(nil), data...)
+
+	return len(p), nil
+}
+
+// append adds a line, overwriting the oldest one when the buffer is full.
+// Callers must hold b.mu.
+func (b *LogBuffer) append(text string) {
+	line := LogLine{Seq: b.nextSeq, Time: time.Now(), Text: text}
+	b.nextSeq++
+
+	if len(b.lines) < cap(b.lines) {
+		b.lines = append(b.lines, line)
+		return
+	}
+	b.lines[b.start] = line
+	b.start = (b.start + 1) % len(b.lines)
+}
+
+// Since returns the buffered lines with a sequence number greater than seq
+func (b *LogBuffer) Since(seq int64) []LogLine {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var result []LogLine
+	for i := 0; i < len(b.lines); i++ {
+		line := b.lines[(b.start+i)%len(b.lines)]
+		if line.Seq > seq {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// SinceTime returns the buffered lines written after t
+func (b *LogBuffer) SinceTime(t time.Time) []LogLine {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var result []LogLine
+	for i := 0; i < len(b.lines); i++ {
+		line := b.lines[(b.start+i)%len(b.lines)]
+		if line.Time.After(t) {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// LastSeq returns the sequence number of the most recent line
+func (b *LogBuffer) LastSeq() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.nextSeq - 1
+}
+
+// RotatingFile is a log file that is rotated once it grows past a size limit
+type RotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	file    *os.File
+	size    int64
+}
+
+// NewRotatingFile opens or creates the log file at path
+func NewRotatingFile(path string, maxSize int64, backups int) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	r := &RotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// Write implements io.Writer
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size+int64(len(p)) > r.maxSize && r.size > 0 {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts daemon.log to daemon.log.1, daemon.log.1 to daemon.log.2 and
+// so on, dropping the oldest. Callers must hold r.mu.
+func (r *RotatingFile) rotate() error {
+	r.file.Close()
+
+	for i := r.backups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if r.backups > 0 {
+		os.Rename(r.path, r.path+".1")
+	} else {
+		os.Remove(r.path)
+	}
+
+	return r.open()
+}
+
+// Close closes the log file
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// CaptureOutput redirects the process stdout and stderr into a log buffer and
+// a rotating log file in logDir, while still passing it through to the
+// original stdout. The daemon logs with fmt.Printf, so this captures
+// everything it prints.
+func CaptureOutput(logDir string) (*LogBuffer, error) {
+	logFile, err := NewRotatingFile(filepath.Join(logDir, "daemon.log"), maxLogFileSize, maxLogFileBackups)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		logFile.Close()
+		return nil, fmt.Errorf("failed to create output pipe: %w", err)
+	}
+
+	buffer := NewLogBuffer(DefaultLogBufferLines)
+	original := os.Stdout
+	os.Stdout = writer
+	os.Stderr = writer
+
+	go func() {
+		// The original stdout may be closed when the daemon runs detached
+		out := io.MultiWriter(buffer, logFile)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if !debugOutput.Load() && strings.HasPrefix(scanner.Text(), "[DEBUG]") {
+				continue
+			}
+			line := append(scanner.Bytes(), '\n')
+			out.Write(line)
+			original.Write(line)
+		}
+	}()
+
+	return buffer, nil
+}
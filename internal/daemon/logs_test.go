@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogBufferWrite(t *testing.T) {
+	b := NewLogBuffer(10)
+
+	fmt.Fprintf(b, "first line\nsecond ")
+	fmt.Fprintf(b, "line\r\n")
+
+	lines := b.Since(0)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "first line", lines[0].Text)
+	assert.Equal(t, "second line", lines[1].Text)
+	assert.Equal(t, int64(1), lines[0].Seq)
+	assert.Equal(t, int64(2), b.LastSeq())
+
+	// Only lines after the given sequence number
+	lines = b.Since(1)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "second line", lines[0].Text)
+}
+
+func TestLogBufferWraps(t *testing.T) {
+	b := NewLogBuffer(3)
+	for i := 1; i <= 5; i++ {
+		fmt.Fprintf(b, "line %d\n", i)
+	}
+
+	lines := b.Since(0)
+	require.Len(t, lines, 3)
+	assert.Equal(t, "line 3", lines[0].Text)
+	assert.Equal(t, "line 5", lines[2].Text)
+	assert.Equal(t, int64(5), b.LastSeq())
+}
+
+func TestLogBufferSinceTime(t *testing.T) {
+	b := NewLogBuffer(10)
+	fmt.Fprintln(b, "old")
+	cutoff := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	fmt.Fprintln(b, "new")
+
+	lines := b.SinceTime(cutoff)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "new", lines[0].Text)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "daemon.log")
+
+	r, err := NewRotatingFile(path, 20, 2)
+	require.NoError(t, err)
+	defer r.Close()
+
+	for i := 0; i < 4; i++ {
+		_, err := fmt.Fprintf(r, "line %d of output\n", i)
+		require.NoError(t, err)
+	}
+
+	// Each write exceeds the limit, so every file holds one line and only two backups are kept
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "line 3 of output\n", string(data))
+
+	data, err = os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, "line 2 of output\n", string(data))
+
+	_, err = os.Stat(path + ".2")
+	assert.NoError(t, err)
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
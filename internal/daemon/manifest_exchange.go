@@ -0,0 +1,256 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/silmaril/silmaril/internal/encryption"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/signing"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// manifestStore serves and receives the manifests of the models the daemon
+// downloads and seeds, by the infohash of their torrent
+type manifestStore struct {
+	d *Daemon
+}
+
+// Manifest returns the manifest of a model being seeded. The local seeding
+// policy and storage backend are left out, they only apply to this daemon.
+func (s manifestStore) Manifest(infoHash string) ([]byte, bool) {
+	mt, ok := s.d.torrentManager.GetTorrent(infoHash)
+	if !ok {
+		return nil, false
+	}
+	manifest, err := models.ReadManifest(mt.StoragePath)
+	if err != nil {
+		return nil, false
+	}
+	manifest.Seeding = nil
+	manifest.Storage = ""
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// WantsManifest reports whether a torrent has its metadata but no manifest.
+// Manifests are only checked against the torrent once its files are known.
+func (s manifestStore) WantsManifest(infoHash string) bool {
+	mt, ok := s.d.torrentManager.GetTorrent(infoHash)
+	if !ok || mt.Torrent.Info() == nil {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(mt.StoragePath, models.ManifestFileName))
+	return os.IsNotExist(err)
+}
+
+// ReceiveManifest writes the manifest a peer sent next to the model files
+func (s manifestStore) ReceiveManifest(infoHash string, data []byte) error {
+	mt, ok := s.d.torrentManager.GetTorrent(infoHash)
+	if !ok {
+		return fmt.Errorf("torrent not found: %s", infoHash)
+	}
+
+	var manifest types.ModelManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	// The model is named by whoever downloads it. The name isn't signed, so
+	// it is set before the signatures are checked against the organization
+	// of the namespace.
+	manifest.Name = mt.Name
+	if s.d.verifyManifests() {
+		if err := validatePeerManifest(&manifest, mt.Torrent.Info(), infoHash); err != nil {
+			s.d.events.Publish(EventManifestRejected, mt.Name, "rejected the manifest a peer sent: %v", err)
+			return err
+		}
+		if err := checkManifestSignatures(&manifest, s.d.organizations()); err != nil {
+			s.d.events.Publish(EventManifestRejected, mt.Name, "rejected the manifest a peer sent: %v", err)
+			return err
+		}
+	}
+
+	manifest.Seeding = nil
+	manifest.Storage = ""
+	if err := os.MkdirAll(mt.StoragePath, 0755); err != nil {
+		return fmt.Errorf("failed to create model directory: %w", err)
+	}
+	if err := models.WriteManifest(mt.StoragePath, &manifest); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+
+	s.d.events.Publish(EventManifestReceived, mt.Name, "received manifest from a peer (license: %s)", manifest.License)
+
+	// A download held back for want of its organization's signatures is
+	// checked again now that it has a manifest
+	if q, err := readQuarantine(mt.StoragePath); err == nil && q.Status == QuarantineUnsigned {
+		s.d.workers.Add(1)
+		go func() {
+			defer s.d.workers.Done()
+			s.d.verifyQuarantined(mt, q)
+		}()
+	}
+	return nil
+}
+
+// validatePeerManifest checks that a manifest received from a peer describes
+// the files of the torrent it was sent for. Hidden files are listed in
+// manifests but never part of a torrent.
+func validatePeerManifest(manifest *types.ModelManifest, info *metainfo.Info, infoHash string) error {
+	if info == nil {
+		return fmt.Errorf("metadata of %s not known yet", infoHash)
+	}
+	if manifest.MagnetURI != "" && !strings.Contains(strings.ToLower(manifest.MagnetURI), strings.ToLower(infoHash)) {
+		return fmt.Errorf("manifest is for another torrent")
+	}
+
+	sizes := make(map[string]int64)
+	for _, f := range info.UpvertedFiles() {
+		sizes[f.DisplayPath(info)] = f.Length
+	}
+
+	matched := 0
+	for _, f := range manifest.Files {
+		if isHiddenPath(f.Path) {
+			continue
+		}
+		size, ok := sizes[f.Path]
+		if !ok {
+			return fmt.Errorf("manifest lists %s, which is not in the torrent", f.Path)
+		}
+		// Manifests of encrypted models describe the plaintext
+		want := f.Size
+		if manifest.Encryption != nil {
+			want = encryption.CiphertextSize(f.Size)
+		}
+		if size != want {
+			return fmt.Errorf("manifest size of %s is %d bytes, the torrent has %d", f.Path, want, size)
+		}
+		matched++
+	}
+	if matched == 0 {
+		return fmt.Errorf("manifest lists no files of the torrent")
+	}
+	return nil
+}
+
+// verifyManifests reports whether manifests are checked against the torrent
+// and the signature policies of organizations
+func (d *Daemon) verifyManifests() bool {
+	return d.config == nil || d.config.GetBool("security.verify_manifests")
+}
+
+// organizationModel reports whether name is in the namespace of a configured
+// organization whose signatures are checked. Their downloads are always
+// verified against the checksums the organization signed, so a signed
+// manifest can't be replayed onto another torrent with the same file names
+// and sizes.
+func (d *Daemon) organizationModel(name string) bool {
+	namespace, _, ok := strings.Cut(name, "/")
+	_, pinned := d.organizations()[strings.ToLower(namespace)]
+	return ok && pinned && d.verifyManifests()
+}
+
+// unsignedOrganizationModel returns why a download in the namespace of a
+// configured organization isn't signed by it: it has no manifest, or one not
+// signed under the organization's policy. It returns "" for other models,
+// signed ones, or if manifests aren't verified.
+func (d *Daemon) unsignedOrganizationModel(name, dir string) string {
+	if !d.verifyManifests() {
+		return ""
+	}
+	return unsignedOrganizationManifest(name, dir, d.organizations())
+}
+
+// unsignedOrganizationManifest returns why the manifest in dir of a model in
+// the namespace of one of organizations isn't signed under its policy, ""
+// for other models and signed ones
+func unsignedOrganizationManifest(name, dir string, organizations map[string]string) string {
+	namespace, _, ok := strings.Cut(name, "/")
+	if _, pinned := organizations[strings.ToLower(namespace)]; !ok || !pinned {
+		return ""
+	}
+	manifest, err := models.ReadManifest(dir)
+	if err != nil {
+		return fmt.Sprintf("no manifest signed by organization %s was received", namespace)
+	}
+	manifest.Name = name
+	if err := checkManifestSignatures(manifest, organizations); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// organizations returns the signature policy ID of each configured
+// organization
+func (d *Daemon) organizations() map[string]string {
+	if d.config == nil {
+		return nil
+	}
+	return d.config.Security.Organizations
+}
+
+// checkManifestSignatures verifies the publisher signatures of a manifest
+// against its signature policy. Manifests named after an organization of
+// organizations, or with a policy naming one, must be signed under the
+// policy configured for it, so no single member publishes for it.
+func checkManifestSignatures(manifest *types.ModelManifest, organizations map[string]string) error {
+	policy := manifest.SignaturePolicy
+	for _, org := range manifestOrganizations(manifest) {
+		want, ok := organizations[strings.ToLower(org)]
+		if !ok {
+			continue
+		}
+		if policy == nil {
+			return fmt.Errorf("manifest of organization %s has no signature policy", org)
+		}
+		if id := signing.PolicyID(policy); !strings.EqualFold(id, want) {
+			return fmt.Errorf("signature policy %s is not the one of organization %s", id, org)
+		}
+	}
+
+	if policy == nil && len(manifest.Signatures) == 0 {
+		return nil
+	}
+	if policy != nil {
+		// The checksums tie the manifest to the files it was signed for
+		for _, file := range manifest.Files {
+			if file.SHA256 == "" {
+				return fmt.Errorf("manifest signed under a policy has no checksum of %s", file.Path)
+			}
+		}
+	}
+	_, err := signing.VerifySignatures(manifest)
+	return err
+}
+
+// manifestOrganizations returns the organizations a manifest claims: the
+// namespace of its name and the one its signature policy names
+func manifestOrganizations(manifest *types.ModelManifest) []string {
+	var orgs []string
+	if namespace, _, ok := strings.Cut(manifest.Name, "/"); ok {
+		orgs = append(orgs, namespace)
+	}
+	if policy := manifest.SignaturePolicy; policy != nil && policy.Organization != "" {
+		orgs = append(orgs, policy.Organization)
+	}
+	return orgs
+}
+
+// isHiddenPath reports whether a file or one of its directories is hidden
+func isHiddenPath(p string) bool {
+	for _, part := range strings.Split(p, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package daemon
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/silmaril/silmaril/internal/encryption"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/signing"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePeerManifest(t *testing.T) {
+	infoHash := strings.Repeat("ab", 20)
+	info := &metainfo.Info{
+		Name: "model",
+		Files: []metainfo.FileInfo{
+			{Path: []string{"config.json"}, Length: 100},
+			{Path: []string{"weights", "model.safetensors"}, Length: 5000},
+		},
+	}
+
+	manifest := &types.ModelManifest{
+		Name: "org/model",
+		Files: []types.ModelFile{
+			{Path: "config.json", Size: 100},
+			{Path: "weights/model.safetensors", Size: 5000},
+			{Path: ".gitattributes", Size: 10}, // Never part of a torrent
+		},
+		MagnetURI: "magnet:?xt=urn:btih:" + strings.ToUpper(infoHash),
+	}
+	assert.NoError(t, validatePeerManifest(manifest, info, infoHash))
+
+	// Metadata is needed to check the manifest
+	assert.Error(t, validatePeerManifest(manifest, nil, infoHash))
+
+	// A manifest of another torrent
+	other := *manifest
+	other.MagnetURI = "magnet:?xt=urn:btih:" + strings.Repeat("cd", 20)
+	assert.Error(t, validatePeerManifest(&other, info, infoHash))
+
+	// Files of a different version of the model
+	resized := *manifest
+	resized.Files = []types.ModelFile{{Path: "config.json", Size: 101}}
+	assert.Error(t, validatePeerManifest(&resized, info, infoHash))
+
+	extra := *manifest
+	extra.Files = append([]types.ModelFile{{Path: "tokenizer.json", Size: 1}}, manifest.Files...)
+	assert.Error(t, validatePeerManifest(&extra, info, infoHash))
+
+	// A manifest must describe some of the torrent
+	empty := *manifest
+	empty.Files = nil
+	assert.Error(t, validatePeerManifest(&empty, info, infoHash))
+}
+
+func TestValidatePeerManifestSingleFile(t *testing.T) {
+	infoHash := strings.Repeat("ab", 20)
+	info := &metainfo.Info{Name: "model.gguf", Length: 2048}
+
+	manifest := &types.ModelManifest{Files: []types.ModelFile{{Path: "model.gguf", Size: 2048}}}
+	assert.NoError(t, validatePeerManifest(manifest, info, infoHash))
+
+	// Encrypted torrents hold the ciphertext of the files the manifest lists
+	encrypted := *manifest
+	encrypted.Encryption = &types.EncryptionInfo{Cipher: encryption.Cipher}
+	assert.Error(t, validatePeerManifest(&encrypted, info, infoHash))
+
+	info.Length = encryption.CiphertextSize(2048)
+	assert.NoError(t, validatePeerManifest(&encrypted, info, infoHash))
+}
+
+func TestIsHiddenPath(t *testing.T) {
+	assert.True(t, isHiddenPath(".gitattributes"))
+	assert.True(t, isHiddenPath(".cache/model.bin"))
+	assert.False(t, isHiddenPath("weights/model.safetensors"))
+}
+
+func TestCheckManifestSignatures(t *testing.T) {
+	var keys []ed25519.PrivateKey
+	var ids []string
+	for i := 0; i < 3; i++ {
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		keys = append(keys, privateKey)
+		ids = append(ids, hex.EncodeToString(privateKey.Public().(ed25519.PublicKey)))
+	}
+	policy := &types.SignaturePolicy{Organization: "acme", Threshold: 2, Keys: ids}
+	organizations := map[string]string{"acme": signing.PolicyID(policy)}
+
+	// Unsigned manifests of other organizations pass
+	assert.NoError(t, checkManifestSignatures(&types.ModelManifest{Name: "other/model"}, organizations))
+
+	// Manifests in the namespace of a pinned organization need its policy
+	assert.Error(t, checkManifestSignatures(&types.ModelManifest{Name: "acme/model"}, organizations))
+
+	manifest := &types.ModelManifest{Name: "acme/model", Version: "1.0", SignaturePolicy: policy}
+	require.NoError(t, signing.AddSignature(manifest, keys[0]))
+	assert.ErrorIs(t, checkManifestSignatures(manifest, organizations), signing.ErrThresholdNotMet)
+	require.NoError(t, signing.AddSignature(manifest, keys[1]))
+	assert.NoError(t, checkManifestSignatures(manifest, organizations))
+
+	// Without checksums it could be replayed onto other files of the same size
+	unhashed := &types.ModelManifest{Name: "acme/model", Version: "1.0", SignaturePolicy: policy,
+		Files: []types.ModelFile{{Path: "model.gguf", Size: 1024}}}
+	require.NoError(t, signing.AddSignature(unhashed, keys[0]))
+	require.NoError(t, signing.AddSignature(unhashed, keys[1]))
+	assert.ErrorContains(t, checkManifestSignatures(unhashed, organizations), "no checksum of model.gguf")
+
+	// A single member can't publish under the organization with a policy of their own
+	forged := &types.ModelManifest{Name: "acme/model", Version: "1.0", SignaturePolicy: &types.SignaturePolicy{Organization: "acme", Threshold: 1, Keys: ids[:1]}}
+	require.NoError(t, signing.AddSignature(forged, keys[0]))
+	assert.Error(t, checkManifestSignatures(forged, organizations))
+	forged.Name = "renamed/model"
+	assert.Error(t, checkManifestSignatures(forged, organizations))
+}
+
+func TestUnsignedOrganizationManifest(t *testing.T) {
+	var keys []ed25519.PrivateKey
+	var ids []string
+	for i := 0; i < 2; i++ {
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		keys = append(keys, privateKey)
+		ids = append(ids, hex.EncodeToString(privateKey.Public().(ed25519.PublicKey)))
+	}
+	policy := &types.SignaturePolicy{Organization: "acme", Threshold: 2, Keys: ids}
+	organizations := map[string]string{"acme": signing.PolicyID(policy)}
+	dir := t.TempDir()
+
+	// Downloads of an organization without a manifest are held back
+	assert.Equal(t, "no manifest signed by organization acme was received", unsignedOrganizationManifest("acme/model", dir, organizations))
+	assert.Empty(t, unsignedOrganizationManifest("other/model", dir, organizations))
+	assert.Empty(t, unsignedOrganizationManifest("model", dir, organizations))
+
+	// The name the manifest was sent with doesn't matter, the download's does
+	manifest := &types.ModelManifest{Name: "renamed/model", Version: "1.0"}
+	require.NoError(t, models.WriteManifest(dir, manifest))
+	assert.Equal(t, "manifest of organization acme has no signature policy", unsignedOrganizationManifest("acme/model", dir, organizations))
+
+	manifest.SignaturePolicy = policy
+	for _, key := range keys {
+		require.NoError(t, signing.AddSignature(manifest, key))
+	}
+	require.NoError(t, models.WriteManifest(dir, manifest))
+	assert.Empty(t, unsignedOrganizationManifest("acme/model", dir, organizations))
+}
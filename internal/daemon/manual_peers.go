@@ -0,0 +1,145 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/anacrolix/torrent"
+)
+
+// ErrInvalidPeer is returned for a peer address that is neither host nor
+// host:port
+var ErrInvalidPeer = errors.New("invalid peer address")
+
+// ErrManualPeerNotFound is returned for removing a peer that wasn't added to
+// the torrent by hand
+var ErrManualPeerNotFound = errors.New("peer was not added to the torrent")
+
+// ManualPeers are the peers added by hand to the torrent of a model
+type ManualPeers struct {
+	InfoHash string   `json:"info_hash"`
+	Name     string   `json:"name"`
+	Peers    []string `json:"peers"`           // All peers added by hand, as host:port
+	Added    int      `json:"added"`           // Addresses new to the torrent
+	Error    string   `json:"error,omitempty"` // Of resolving the peers
+}
+
+// normalizeManualPeers checks peer addresses and gives port to the ones
+// without a port
+func normalizeManualPeers(peers []string, port int) ([]string, error) {
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("%w: no peers given", ErrInvalidPeer)
+	}
+	result := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		host, peerPort, err := splitPeerHint(strings.TrimSpace(peer), port)
+		if err != nil || strings.ContainsAny(host, " /") {
+			return nil, fmt.Errorf("%w %q", ErrInvalidPeer, peer)
+		}
+		if peerPort == 0 {
+			return nil, fmt.Errorf("%w %q: give a port, this daemon isn't listening for peers", ErrInvalidPeer, peer)
+		}
+		result = append(result, net.JoinHostPort(host, strconv.Itoa(peerPort)))
+	}
+	return result, nil
+}
+
+// peerListenPort returns the port the torrent client listens on, which
+// other daemons are expected to use too
+func (d *Daemon) peerListenPort() int {
+	if d.config != nil && d.config.Network.ListenPort != 0 {
+		return d.config.Network.ListenPort
+	}
+	if d.torrentManager != nil {
+		return d.torrentManager.client.LocalPort()
+	}
+	return 0
+}
+
+// addManualPeers resolves peers and adds them to the torrent of mt. Host
+// names are resolved each time, so a peer whose address changes is found
+// again.
+func (d *Daemon) addManualPeers(mt *ManagedTorrent, peers []string) (int, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, peerHintTimeout)
+	defer cancel()
+
+	addrs, err := resolvePeerHints(ctx, net.DefaultResolver.LookupIPAddr, peers, d.peerListenPort(), localIPs())
+	return d.torrentManager.AddTorrentPeers(mt, addrs), err
+}
+
+// AddManualPeers adds peers to the torrent of a model and remembers them,
+// so they are added again after a restart. Two daemons that know each
+// other's address can transfer a model without the DHT.
+func (d *Daemon) AddManualPeers(infoHash string, peers []string) (*ManualPeers, error) {
+	if d.torrentManager == nil {
+		return nil, errors.New("torrent client is not running")
+	}
+	mt, exists := d.torrentManager.GetTorrent(infoHash)
+	if !exists {
+		return nil, fmt.Errorf("torrent not found: %s", infoHash)
+	}
+	peers, err := normalizeManualPeers(peers, d.peerListenPort())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ManualPeers{
+		InfoHash: mt.InfoHash,
+		Name:     mt.Name,
+		Peers:    d.state.AddManualPeers(mt.InfoHash, peers),
+	}
+	result.Added, err = d.addManualPeers(mt, peers)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	fmt.Printf("[ManualPeers] Added %s to %s\n", strings.Join(peers, ", "), mt.Name)
+	return result, nil
+}
+
+// RemoveManualPeer forgets a peer added to a torrent by hand. The torrent
+// keeps the peer until the daemon restarts, but it is not added again.
+func (d *Daemon) RemoveManualPeer(infoHash, peer string) error {
+	normalized, err := normalizeManualPeers([]string{peer}, d.peerListenPort())
+	if err != nil {
+		return err
+	}
+	if !d.state.RemoveManualPeer(infoHash, normalized[0]) {
+		return fmt.Errorf("%w: %s", ErrManualPeerNotFound, normalized[0])
+	}
+	fmt.Printf("[ManualPeers] Removed %s from %s\n", normalized[0], infoHash)
+	return nil
+}
+
+// restoreManualPeers adds the peers added by hand to the torrents loaded
+// since, after a restart or once a download starts
+func (d *Daemon) restoreManualPeers() {
+	if d.state == nil || d.torrentManager == nil {
+		return
+	}
+	for infoHash, peers := range d.state.GetAllManualPeers() {
+		mt, exists := d.torrentManager.GetTorrent(infoHash)
+		if !exists {
+			continue
+		}
+		if _, err := d.addManualPeers(mt, peers); err != nil {
+			fmt.Printf("[ManualPeers] %s: %v\n", mt.Name, err)
+		}
+	}
+}
+
+// AddTorrentPeers adds addrs as peers of one torrent and returns how many
+// it didn't know yet
+func (tm *TorrentManager) AddTorrentPeers(mt *ManagedTorrent, addrs []*net.TCPAddr) int {
+	if len(addrs) == 0 {
+		return 0
+	}
+	peers := make([]torrent.PeerInfo, len(addrs))
+	for i, addr := range addrs {
+		peers[i] = torrent.PeerInfo{Addr: addr, Source: peerSourceManual, Trusted: true}
+	}
+	return mt.Torrent.AddPeers(peers)
+}
@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeManualPeers(t *testing.T) {
+	peers, err := normalizeManualPeers([]string{"10.0.0.5", "lab-b:6881", " [fd00::2]:7000 ", "fd00::3"}, 42069)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.5:42069", "lab-b:6881", "[fd00::2]:7000", "[fd00::3]:42069"}, peers)
+
+	for _, peer := range []string{"", "10.0.0.5:0", "10.0.0.5:http", "http://10.0.0.5"} {
+		_, err := normalizeManualPeers([]string{peer}, 42069)
+		assert.ErrorIs(t, err, ErrInvalidPeer, peer)
+	}
+	_, err = normalizeManualPeers(nil, 42069)
+	assert.ErrorIs(t, err, ErrInvalidPeer)
+	_, err = normalizeManualPeers([]string{"10.0.0.5"}, 0)
+	assert.ErrorIs(t, err, ErrInvalidPeer)
+}
+
+func TestManualPeersUnknownTorrent(t *testing.T) {
+	tm, state, _ := setupTestTorrentManager(t)
+	defer tm.Stop()
+	d := &Daemon{torrentManager: tm, state: state}
+
+	_, err := d.AddManualPeers("0123456789abcdef0123456789abcdef01234567", []string{"10.0.0.5:6881"})
+	assert.ErrorContains(t, err, "torrent not found")
+	assert.Empty(t, state.GetAllManualPeers())
+
+	state.AddManualPeers("abc", []string{"10.0.0.5:6881"})
+	require.NoError(t, d.RemoveManualPeer("abc", "10.0.0.5:6881"))
+	assert.ErrorIs(t, d.RemoveManualPeer("abc", "10.0.0.5:6881"), ErrManualPeerNotFound)
+
+	// Peers of torrents not loaded are kept for when they are
+	state.AddManualPeers("def", []string{"10.0.0.6:6881"})
+	d.restoreManualPeers()
+	assert.Equal(t, []string{"10.0.0.6:6881"}, state.GetManualPeers("def"))
+}
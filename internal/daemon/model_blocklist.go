@@ -0,0 +1,358 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/blocklist"
+	"github.com/silmaril/silmaril/internal/proxy"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// ErrModelBlocked is returned when downloading or seeding a model on the
+// model blocklist
+var ErrModelBlocked = errors.New("model is blocklisted")
+
+// ErrNotBlocked is returned when removing an entry the local model
+// blocklist doesn't have
+var ErrNotBlocked = errors.New("not on the model blocklist")
+
+// Largest model blocklist feed accepted
+const maxModelBlocklistSize = 16 << 20
+
+// ModelBlocklistStatus lists the local entries of the model blocklist and
+// the feeds it follows
+type ModelBlocklistStatus struct {
+	Entries []*BlockedModel `json:"entries"`
+	Feeds   []BlocklistFeed `json:"feeds"`
+	Total   int             `json:"total"` // Entries of all lists
+}
+
+// BlocklistFeed is a remote list of models to refuse
+type BlocklistFeed struct {
+	URL          string     `json:"url"`
+	Entries      int        `json:"entries"`
+	Updated      *time.Time `json:"updated,omitempty"` // When the downloaded feed was saved
+	SkippedLines int        `json:"skipped_lines"`     // Lines that didn't parse
+	Error        string     `json:"error,omitempty"`   // Of the last download
+}
+
+// modelBlocklistLoader loads the local entries and feeds into the model
+// filter
+type modelBlocklistLoader struct {
+	mu    sync.Mutex // Serializes reloads
+	feeds map[string]BlocklistFeed
+}
+
+// modelBlocklistDir is where downloaded feeds are kept, so a restart
+// doesn't depend on downloading them again
+func modelBlocklistDir() string {
+	return filepath.Join(storage.GetBaseDir(), "daemon", "model_blocklists")
+}
+
+// feedPath returns where the feed at source is saved
+func feedPath(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return filepath.Join(modelBlocklistDir(), hex.EncodeToString(sum[:8])+".txt")
+}
+
+// modelBlocklistRefresh returns how old downloaded feeds may get
+func (d *Daemon) modelBlocklistRefresh() time.Duration {
+	if d.config != nil {
+		if hours := d.config.GetInt("security.model_blocklist_refresh_hours"); hours > 0 {
+			return time.Duration(hours) * time.Hour
+		}
+	}
+	return 24 * time.Hour
+}
+
+// blocklistFeeds returns the configured feeds
+func (d *Daemon) blocklistFeeds() []string {
+	if d.config == nil {
+		return nil
+	}
+	return d.config.GetStringSlice("security.model_blocklist_urls")
+}
+
+// ReloadModelBlocklist reads the local entries and the saved feeds into the
+// model filter, downloading the feeds again if download is set, and stops
+// the torrents of models it now blocks. A feed that fails to download keeps
+// its saved copy.
+func (d *Daemon) ReloadModelBlocklist(download bool) ModelBlocklistStatus {
+	d.modelBlocklist.mu.Lock()
+	defer d.modelBlocklist.mu.Unlock()
+
+	d.reloadModelBlocklist(download)
+	go d.enforceModelBlocklist()
+	return d.modelBlocklistStatus()
+}
+
+// reloadModelBlocklist loads the lists, the caller holds d.modelBlocklist.mu
+func (d *Daemon) reloadModelBlocklist(download bool) {
+	var entries []blocklist.Entry
+	for _, blocked := range d.state.GetBlockedModels() {
+		entries = append(entries, blocklist.Entry{Pattern: blocked.Pattern, Reason: blocked.Reason})
+	}
+
+	feeds := make(map[string]BlocklistFeed)
+	for _, source := range d.blocklistFeeds() {
+		feed := BlocklistFeed{URL: source}
+		if download {
+			if err := d.downloadBlocklistFeed(source); err != nil {
+				feed.Error = err.Error()
+				fmt.Printf("[Blocklist] %v\n", err)
+			} else {
+				fmt.Printf("[Blocklist] Updated feed %s\n", source)
+			}
+		} else if previous, exists := d.modelBlocklist.feeds[source]; exists {
+			feed.Error = previous.Error
+		}
+
+		listed, skipped, err := readBlocklistFeed(source)
+		if err != nil && feed.Error == "" {
+			feed.Error = err.Error()
+		}
+		if info, err := os.Stat(feedPath(source)); err == nil {
+			updated := info.ModTime()
+			feed.Updated = &updated
+		}
+		feed.Entries = len(listed)
+		feed.SkippedLines = skipped
+		feeds[source] = feed
+		entries = append(entries, listed...)
+	}
+	d.modelBlocklist.feeds = feeds
+
+	d.modelFilter.SetEntries(entries)
+	if d.dhtManager != nil {
+		d.dhtManager.resetCatalogIndex()
+	}
+	fmt.Printf("[Blocklist] %d models blocked by %d entries\n", d.modelFilter.Len(), len(entries))
+}
+
+// downloadBlocklistFeed fetches the feed at source, an HTTP(S) URL or a file
+// path, and saves it once it parses
+func (d *Daemon) downloadBlocklistFeed(source string) error {
+	var data []byte
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		p, err := proxy.FromConfig(d.config, proxy.Torrent)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(d.ctx, blocklistDownloadTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return fmt.Errorf("invalid blocklist feed URL: %w", err)
+		}
+		resp, err := p.HTTPClient(blocklistDownloadTimeout).Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to download blocklist feed %s: %w", source, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to download blocklist feed %s: %s", source, resp.Status)
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, maxModelBlocklistSize+1))
+		if err != nil {
+			return fmt.Errorf("failed to download blocklist feed %s: %w", source, err)
+		}
+	} else {
+		var err error
+		if data, err = os.ReadFile(source); err != nil {
+			return fmt.Errorf("failed to read blocklist feed: %w", err)
+		}
+	}
+	if len(data) > maxModelBlocklistSize {
+		return fmt.Errorf("blocklist feed %s is larger than %d MB", source, maxModelBlocklistSize>>20)
+	}
+
+	// Keep the saved copy if the new one is unusable
+	if _, _, err := blocklist.Parse(bytes.NewReader(data), source); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(modelBlocklistDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create blocklist directory: %w", err)
+	}
+	if err := storage.WriteFileAtomic(feedPath(source), data, 0644); err != nil {
+		return fmt.Errorf("failed to save blocklist feed: %w", err)
+	}
+	return nil
+}
+
+// readBlocklistFeed parses the saved copy of the feed at source, none if it
+// wasn't downloaded yet
+func readBlocklistFeed(source string) ([]blocklist.Entry, int, error) {
+	file, err := os.Open(feedPath(source))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("failed to open blocklist feed: %w", err)
+	}
+	defer file.Close()
+	return blocklist.Parse(file, source)
+}
+
+// ModelBlocklist returns the local entries of the model blocklist and the
+// state of its feeds
+func (d *Daemon) ModelBlocklist() ModelBlocklistStatus {
+	d.modelBlocklist.mu.Lock()
+	defer d.modelBlocklist.mu.Unlock()
+	return d.modelBlocklistStatus()
+}
+
+// modelBlocklistStatus describes the blocklist, the caller holds
+// d.modelBlocklist.mu
+func (d *Daemon) modelBlocklistStatus() ModelBlocklistStatus {
+	status := ModelBlocklistStatus{
+		Entries: d.state.GetBlockedModels(),
+		Feeds:   []BlocklistFeed{},
+		Total:   d.modelFilter.Len(),
+	}
+	for _, source := range d.blocklistFeeds() {
+		if feed, exists := d.modelBlocklist.feeds[source]; exists {
+			status.Feeds = append(status.Feeds, feed)
+		}
+	}
+	return status
+}
+
+// BlockModel adds an infohash or a pattern of model names to the local
+// blocklist and stops the torrents of the models it blocks
+func (d *Daemon) BlockModel(pattern, reason string) (*BlockedModel, error) {
+	pattern, err := blocklist.ParseEntry(pattern)
+	if err != nil {
+		return nil, err
+	}
+	blocked := &BlockedModel{Pattern: pattern, Reason: reason, AddedAt: time.Now()}
+	d.state.BlockModel(blocked)
+
+	d.modelBlocklist.mu.Lock()
+	d.reloadModelBlocklist(false)
+	d.modelBlocklist.mu.Unlock()
+
+	d.enforceModelBlocklist()
+	return blocked, nil
+}
+
+// UnblockModel removes an entry from the local blocklist. Models it blocked
+// aren't downloaded or seeded again on their own.
+func (d *Daemon) UnblockModel(pattern string) error {
+	normalized, err := blocklist.ParseEntry(pattern)
+	if err != nil {
+		return err
+	}
+	if !d.state.UnblockModel(normalized) {
+		return fmt.Errorf("%w: %s", ErrNotBlocked, pattern)
+	}
+
+	d.modelBlocklist.mu.Lock()
+	defer d.modelBlocklist.mu.Unlock()
+	d.reloadModelBlocklist(false)
+	return nil
+}
+
+// CheckModelBlocked returns ErrModelBlocked if the model with name or
+// infoHash is on the blocklist
+func (d *Daemon) CheckModelBlocked(name, infoHash string) error {
+	entry, blocked := d.modelFilter.Match(name, infoHash)
+	if !blocked {
+		return nil
+	}
+	if entry.Reason != "" {
+		return fmt.Errorf("%w by %s: %s", ErrModelBlocked, entry.Pattern, entry.Reason)
+	}
+	return fmt.Errorf("%w by %s", ErrModelBlocked, entry.Pattern)
+}
+
+// enforceModelBlocklist stops downloading and seeding the torrents of
+// blocked models. Their files are kept.
+func (d *Daemon) enforceModelBlocklist() {
+	if d.torrentManager == nil {
+		return
+	}
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		entry, blocked := d.modelFilter.Match(mt.Name, mt.InfoHash)
+		if !blocked {
+			continue
+		}
+		if err := d.torrentManager.RemoveTorrent(mt.InfoHash); err != nil {
+			continue
+		}
+		if d.dhtManager != nil {
+			d.dhtManager.RemoveTorrentFromDHT(mt.InfoHash)
+		}
+		if d.transferManager != nil {
+			for _, transfer := range d.transferManager.GetActiveTransfers() {
+				if transfer.InfoHash != mt.InfoHash {
+					continue
+				}
+				if transfer.Type == TransferTypeSeed {
+					d.transferManager.FinishSeeding(mt.InfoHash, "blocklisted")
+				} else {
+					d.transferManager.FailTransfer(transfer.ID, d.CheckModelBlocked(mt.Name, mt.InfoHash))
+				}
+			}
+		}
+		fmt.Printf("[Blocklist] Stopped %s, blocked by %s\n", mt.Name, entry.Pattern)
+		d.events.Publish(EventModelBlocked, mt.Name, "stopped torrent %s, blocked by %s", mt.InfoHash, entry.Pattern)
+	}
+}
+
+// filterBlockedModels drops the models on the blocklist
+func filterBlockedModels(models []*types.ModelAnnouncement, filter *blocklist.Filter) []*types.ModelAnnouncement {
+	if filter.Len() == 0 {
+		return models
+	}
+	results := models[:0]
+	for _, model := range models {
+		if _, blocked := filter.Match(model.Name, model.InfoHash); !blocked {
+			results = append(results, model)
+		}
+	}
+	return results
+}
+
+// refreshModelBlocklist downloads the feeds if one wasn't downloaded yet or
+// is older than security.model_blocklist_refresh_hours
+func (d *Daemon) refreshModelBlocklist() {
+	for _, source := range d.blocklistFeeds() {
+		info, err := os.Stat(feedPath(source))
+		if err != nil || time.Since(info.ModTime()) >= d.modelBlocklistRefresh() {
+			d.ReloadModelBlocklist(true)
+			return
+		}
+	}
+}
+
+// modelBlocklistWorker keeps the downloaded feeds up to date
+func (d *Daemon) modelBlocklistWorker() {
+	defer d.workers.Done()
+
+	d.refreshModelBlocklist()
+
+	ticker := time.NewTicker(blocklistCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.refreshModelBlocklist()
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/blocklist"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelBlocklist(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	d := &Daemon{state: NewState(statePath), modelFilter: blocklist.New()}
+	require.NoError(t, d.CheckModelBlocked("badorg/model", ""))
+
+	_, err := d.BlockModel("bad org", "")
+	assert.Error(t, err)
+
+	entry, err := d.BlockModel("BadOrg/*", "malware in pickle files")
+	require.NoError(t, err)
+	assert.Equal(t, "badorg/*", entry.Pattern)
+
+	hash := strings.Repeat("ab", 20)
+	_, err = d.BlockModel(hash, "")
+	require.NoError(t, err)
+
+	err = d.CheckModelBlocked("badorg/model", "")
+	assert.ErrorIs(t, err, ErrModelBlocked)
+	assert.Contains(t, err.Error(), "malware in pickle files")
+	assert.ErrorIs(t, d.CheckModelBlocked("org/model", hash), ErrModelBlocked)
+	assert.NoError(t, d.CheckModelBlocked("org/model", ""))
+
+	status := d.ModelBlocklist()
+	assert.Len(t, status.Entries, 2)
+	assert.Equal(t, 2, status.Total)
+	assert.Empty(t, status.Feeds)
+
+	models := filterBlockedModels([]*types.ModelAnnouncement{
+		{Name: "badorg/model"},
+		{Name: "org/model", InfoHash: hash},
+		{Name: "org/other"},
+	}, d.modelFilter)
+	require.Len(t, models, 1)
+	assert.Equal(t, "org/other", models[0].Name)
+
+	// Local entries survive a restart
+	require.NoError(t, d.state.Save())
+	restarted := &Daemon{state: NewState(statePath), modelFilter: blocklist.New()}
+	require.NoError(t, restarted.state.Load())
+	restarted.ReloadModelBlocklist(false)
+	assert.ErrorIs(t, restarted.CheckModelBlocked("badorg/model", ""), ErrModelBlocked)
+
+	require.NoError(t, d.UnblockModel("badorg/*"))
+	assert.NoError(t, d.CheckModelBlocked("badorg/model", ""))
+	assert.ErrorIs(t, d.UnblockModel("badorg/*"), ErrNotBlocked)
+}
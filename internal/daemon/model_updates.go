@@ -0,0 +1,317 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// SubscribeModel keeps a model up to date with the catalog. New versions are
+// downloaded next to the current one and replace it once complete; the old
+// version is kept as <model>@<version> unless autoRemove is set. Models that
+// are not local yet are downloaded right away.
+func (d *Daemon) SubscribeModel(name string, autoRemove bool) (*ModelSubscription, error) {
+	modelPath, err := modelPathFor(name)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &ModelSubscription{
+		Model:      name,
+		AutoRemove: autoRemove,
+		AddedAt:    time.Now(),
+	}
+
+	// The version we seed is the one we have
+	if d.torrentManager != nil {
+		for _, mt := range d.torrentManager.GetAllTorrents() {
+			if mt.Name == name && filepath.Clean(mt.StoragePath) == modelPath {
+				sub.InfoHash = strings.ToLower(mt.InfoHash)
+			}
+		}
+	}
+
+	// Keep the state of an existing subscription, only the options change
+	if !d.state.UpdateModelSubscription(name, func(existing *ModelSubscription) {
+		existing.AutoRemove = autoRemove
+		subCopy := *existing
+		sub = &subCopy
+	}) {
+		subCopy := *sub
+		d.state.AddModelSubscription(&subCopy)
+	}
+	if err := d.state.Save(); err != nil {
+		fmt.Printf("[Updates] Warning: failed to save subscriptions: %v\n", err)
+	}
+
+	go func(sub ModelSubscription) {
+		d.updateMu.Lock()
+		defer d.updateMu.Unlock()
+		d.checkModelUpdate(&sub)
+	}(*sub)
+
+	return sub, nil
+}
+
+// UnsubscribeModel stops updating a model. An update being downloaded is
+// cancelled, the model itself is kept.
+func (d *Daemon) UnsubscribeModel(name string) error {
+	d.updateMu.Lock()
+	defer d.updateMu.Unlock()
+
+	sub := d.state.RemoveModelSubscription(name)
+	if sub == nil {
+		return fmt.Errorf("not subscribed to model %s", name)
+	}
+	if err := d.state.Save(); err != nil {
+		fmt.Printf("[Updates] Warning: failed to save subscriptions: %v\n", err)
+	}
+
+	if sub.PendingInfoHash != "" && d.torrentManager != nil {
+		if mt, ok := d.torrentManager.GetTorrent(sub.PendingInfoHash); ok && mt.StoragePath == updateDir(name) {
+			d.torrentManager.RemoveTorrent(mt.InfoHash)
+			os.RemoveAll(mt.StoragePath)
+		}
+	}
+	return nil
+}
+
+// ModelSubscriptions returns all model subscriptions
+func (d *Daemon) ModelSubscriptions() []*ModelSubscription {
+	return d.state.GetModelSubscriptions()
+}
+
+// Events returns the daemon event log
+func (d *Daemon) Events() *EventLog {
+	return d.events
+}
+
+// modelUpdateWorker checks subscribed models for new versions every catalog
+// refresh and swaps in finished updates
+func (d *Daemon) modelUpdateWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	// Give the DHT time to fetch the catalog before the first check
+	nextCheck := time.Now().Add(2 * time.Minute)
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.updateMu.Lock()
+			check := time.Now().After(nextCheck)
+			for _, sub := range d.state.GetModelSubscriptions() {
+				if sub.PendingInfoHash != "" {
+					d.finishModelUpdate(sub)
+				} else if check {
+					d.checkModelUpdate(sub)
+				}
+			}
+			if check {
+				nextCheck = time.Now().Add(d.catalogRefreshInterval())
+			}
+			d.updateMu.Unlock()
+		}
+	}
+}
+
+// checkModelUpdate starts downloading the catalog version of a subscribed
+// model if it differs from the one we have. Callers must hold d.updateMu.
+func (d *Daemon) checkModelUpdate(sub *ModelSubscription) {
+	now := time.Now()
+	model := d.FindCatalogModel(sub.Model)
+	if model == nil {
+		d.state.UpdateModelSubscription(sub.Model, func(s *ModelSubscription) { s.LastChecked = &now })
+		return
+	}
+
+	infoHash := strings.ToLower(model.InfoHash)
+	if infoHash == sub.InfoHash || infoHash == sub.PendingInfoHash {
+		d.state.UpdateModelSubscription(sub.Model, func(s *ModelSubscription) { s.LastChecked = &now })
+		return
+	}
+
+	// New terms of a gated model have to be accepted with silmaril get
+	if err := d.CheckLicense(model, false); err != nil {
+		d.state.UpdateModelSubscription(sub.Model, func(s *ModelSubscription) {
+			s.LastChecked = &now
+			s.LastError = err.Error()
+		})
+		if sub.LastError != err.Error() {
+			d.events.Publish(EventUpdateFailed, sub.Model, "version %s not downloaded: %v", displayVersion(model.Version), err)
+		}
+		return
+	}
+
+	modelPath, err := modelPathFor(sub.Model)
+	if err != nil {
+		return
+	}
+
+	// Download straight into the models directory if the model isn't local,
+	// otherwise next to it so the current version stays usable
+	storagePath := modelPath
+	if _, err := os.Stat(modelPath); err == nil {
+		storagePath = updateDir(sub.Model)
+		d.events.Publish(EventUpdateAvailable, sub.Model, "version %s is available (infohash %s)", displayVersion(model.Version), infoHash)
+	}
+
+	mt, err := d.fetchModel(sub.Model, infoHash, storagePath)
+	if err != nil {
+		d.state.UpdateModelSubscription(sub.Model, func(s *ModelSubscription) {
+			s.LastChecked = &now
+			s.LastError = err.Error()
+		})
+		d.events.Publish(EventUpdateFailed, sub.Model, "failed to download version %s: %v", displayVersion(model.Version), err)
+		return
+	}
+
+	d.state.UpdateModelSubscription(sub.Model, func(s *ModelSubscription) {
+		s.LastChecked = &now
+		s.LastError = ""
+		s.PendingInfoHash = strings.ToLower(mt.InfoHash)
+		s.PendingVersion = model.Version
+	})
+	d.events.Publish(EventUpdateStarted, sub.Model, "downloading version %s", displayVersion(model.Version))
+}
+
+// finishModelUpdate replaces a subscribed model with its downloaded update.
+// Callers must hold d.updateMu.
+func (d *Daemon) finishModelUpdate(sub *ModelSubscription) {
+	mt, ok := d.torrentManager.GetTorrent(sub.PendingInfoHash)
+	if !ok {
+		// The download was cancelled, try again on the next check
+		d.state.UpdateModelSubscription(sub.Model, func(s *ModelSubscription) {
+			s.PendingInfoHash = ""
+			s.PendingVersion = ""
+		})
+		return
+	}
+	if !mt.Complete() {
+		return
+	}
+
+	modelPath, err := modelPathFor(sub.Model)
+	if err != nil {
+		return
+	}
+	// Updates downloaded next to the model are ready for hooks once swapped
+	// in, downloads into the models directory were when they completed
+	swapped := filepath.Clean(mt.StoragePath) != modelPath
+	if swapped {
+		if err := d.swapModelVersion(sub, mt, modelPath); err != nil {
+			d.state.UpdateModelSubscription(sub.Model, func(s *ModelSubscription) { s.LastError = err.Error() })
+			d.events.Publish(EventUpdateFailed, sub.Model, "failed to install version %s: %v", displayVersion(sub.PendingVersion), err)
+			return
+		}
+	}
+
+	now := time.Now()
+	d.state.UpdateModelSubscription(sub.Model, func(s *ModelSubscription) {
+		s.InfoHash = s.PendingInfoHash
+		s.Version = s.PendingVersion
+		s.PendingInfoHash = ""
+		s.PendingVersion = ""
+		s.LastUpdated = &now
+		s.LastError = ""
+	})
+	if err := d.state.Save(); err != nil {
+		fmt.Printf("[Updates] Warning: failed to save subscriptions: %v\n", err)
+	}
+	d.events.Publish(EventUpdateCompleted, sub.Model, "updated to version %s", displayVersion(sub.PendingVersion))
+	if swapped {
+		d.modelReady(sub.Model, strings.ToLower(mt.InfoHash))
+	}
+}
+
+// swapModelVersion moves the downloaded update of a model into the models
+// directory and seeds it from there. The old version is deleted or kept as
+// <model>@<version>.
+func (d *Daemon) swapModelVersion(sub *ModelSubscription, update *ManagedTorrent, modelPath string) error {
+	stagingPath := update.StoragePath
+
+	// Files can't move while torrents read them
+	if _, err := d.stopModelTorrents(sub.Model, modelPath); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(modelPath); err == nil {
+		if sub.AutoRemove {
+			trashPath, err := moveToTrash(modelPath, storage.GetModelsDir())
+			if err != nil {
+				return err
+			}
+			if err := os.RemoveAll(trashPath); err != nil {
+				fmt.Printf("[Updates] Failed to empty trash %s: %v\n", trashPath, err)
+			}
+			if sub.InfoHash != "" {
+				os.Remove(filepath.Join(storage.GetTorrentsDir(), sub.InfoHash+".torrent"))
+			}
+		} else {
+			keptPath := modelPath + "@" + versionLabel(sub.Version, sub.InfoHash)
+			if _, err := os.Stat(keptPath); err == nil {
+				keptPath += "-" + time.Now().Format("20060102150405")
+			}
+			if err := os.Rename(modelPath, keptPath); err != nil {
+				return fmt.Errorf("failed to keep old version: %w", err)
+			}
+			fmt.Printf("[Updates] Kept old version of %s at %s\n", sub.Model, keptPath)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(modelPath), 0755); err != nil {
+		return fmt.Errorf("failed to create model directory: %w", err)
+	}
+	if err := os.Rename(stagingPath, modelPath); err != nil {
+		return fmt.Errorf("failed to move update into place: %w", err)
+	}
+	removeEmptyParents(filepath.Dir(stagingPath), filepath.Join(storage.GetBaseDir(), "updates"))
+	if err := storage.ClearPartial(modelPath); err != nil {
+		return err
+	}
+
+	torrentPath := filepath.Join(storage.GetTorrentsDir(), update.InfoHash+".torrent")
+	if _, err := d.torrentManager.AddTorrentForSeeding(torrentPath, sub.Model, modelPath); err != nil {
+		return fmt.Errorf("failed to seed update: %w", err)
+	}
+	return nil
+}
+
+// modelPathFor returns the directory of a model, refusing invalid names such
+// as ones escaping the models directory
+func modelPathFor(name string) (string, error) {
+	return storage.ModelDir(name)
+}
+
+// updateDir is where the update of a local model is downloaded
+func updateDir(name string) string {
+	return filepath.Join(storage.GetBaseDir(), "updates", filepath.FromSlash(name))
+}
+
+// versionLabel names a kept old version after its version, or its infohash
+// if the version can't name a directory
+func versionLabel(version, infoHash string) string {
+	if version != "" && version != "unknown" && modelname.ValidVersion(version) {
+		return version
+	}
+	if len(infoHash) >= 8 {
+		return infoHash[:8]
+	}
+	return time.Now().Format("20060102")
+}
+
+func displayVersion(version string) string {
+	if version == "" {
+		return "unknown"
+	}
+	return version
+}
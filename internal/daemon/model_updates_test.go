@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelPathFor(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+
+	path, err := modelPathFor("org/model")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(storage.GetModelsDir(), "org", "model"), path)
+
+	for _, name := range []string{"", ".", "../escape", "org/../../escape"} {
+		_, err := modelPathFor(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestVersionLabel(t *testing.T) {
+	assert.Equal(t, "v1.2", versionLabel("v1.2", "0123456789abcdef"))
+	assert.Equal(t, "01234567", versionLabel("", "0123456789abcdef"))
+	assert.Equal(t, "01234567", versionLabel("unknown", "0123456789abcdef"))
+	assert.Equal(t, "01234567", versionLabel("v1/../x", "0123456789abcdef"))
+}
+
+func TestSubscribeModel(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	d := &Daemon{state: NewState(filepath.Join(t.TempDir(), "state.json")), events: NewEventLog(10)}
+
+	sub, err := d.SubscribeModel("org/model", false)
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", sub.Model)
+	assert.False(t, sub.AutoRemove)
+
+	// Subscribing again only changes the options
+	sub, err = d.SubscribeModel("org/model", true)
+	require.NoError(t, err)
+	assert.True(t, sub.AutoRemove)
+	require.Len(t, d.ModelSubscriptions(), 1)
+
+	// The first check runs in the background and finds no catalog
+	assert.Eventually(t, func() bool {
+		subs := d.ModelSubscriptions()
+		return len(subs) == 1 && subs[0].LastChecked != nil
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = d.SubscribeModel("../escape", false)
+	assert.Error(t, err)
+
+	require.NoError(t, d.UnsubscribeModel("org/model"))
+	assert.Empty(t, d.ModelSubscriptions())
+	assert.Error(t, d.UnsubscribeModel("org/model"))
+}
@@ -0,0 +1,300 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/internal/proxy"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+const (
+	// How long the proof of a domain is trusted, and how long until a
+	// domain without one is checked again
+	namespaceProofTTL = 6 * time.Hour
+	namespaceRetryTTL = 15 * time.Minute
+
+	// Time each lookup of a proof may take
+	namespaceLookupTimeout = 5 * time.Second
+
+	// Largest proof read from a web server
+	maxNamespaceProof = 64 << 10
+)
+
+// Ways a namespace proof is published
+const (
+	ProofDNS   = "dns"
+	ProofHTTPS = "https"
+)
+
+// PublisherVerification is the outcome of checking that the publisher of a
+// model owns the namespace of its name
+type PublisherVerification struct {
+	Key       string `json:"key"`
+	Namespace string `json:"namespace"`
+	Domain    string `json:"domain"`
+	Verified  bool   `json:"verified"`
+	Method    string `json:"method,omitempty"` // ProofDNS or ProofHTTPS
+	Detail    string `json:"detail,omitempty"`
+}
+
+// domainProof is the proof a domain published, by the way it was published
+type domainProof struct {
+	proofs  map[string]discovery.NamespaceProof
+	errors  []string
+	checked time.Time
+}
+
+// namespaceVerifier checks the namespace proofs of domains and caches them
+type namespaceVerifier struct {
+	mu      sync.Mutex
+	domains map[string]*domainProof
+
+	lookupTXT func(ctx context.Context, name string) ([]string, error)
+	fetch     func(ctx context.Context, url string) ([]byte, error)
+}
+
+// newNamespaceVerifier creates a verifier looking proofs up through p, which
+// may be nil to connect directly
+func newNamespaceVerifier(p *proxy.Proxy) *namespaceVerifier {
+	client := p.HTTPClient(namespaceLookupTimeout)
+	return &namespaceVerifier{
+		domains:   make(map[string]*domainProof),
+		lookupTXT: p.Resolver().LookupTXT,
+		fetch: func(ctx context.Context, url string) ([]byte, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+			}
+			return io.ReadAll(io.LimitReader(resp.Body, maxNamespaceProof))
+		},
+	}
+}
+
+// Verify checks that domain lists key for namespace, looking the proof up
+// unless it was checked recently
+func (v *namespaceVerifier) Verify(ctx context.Context, namespace, domain, key string) PublisherVerification {
+	result := PublisherVerification{Key: key, Namespace: namespace, Domain: domain}
+	proof := v.proof(ctx, domain)
+	for _, method := range []string{ProofDNS, ProofHTTPS} {
+		if proof.proofs[method].Proves(namespace, key) {
+			result.Verified = true
+			result.Method = method
+			return result
+		}
+	}
+	if len(proof.errors) > 0 {
+		result.Detail = strings.Join(proof.errors, "; ")
+	} else {
+		result.Detail = fmt.Sprintf("%s doesn't list this publisher for %s", domain, namespace)
+	}
+	return result
+}
+
+// Cached reports whether the cached proof of domain lists key for
+// namespace, without looking it up
+func (v *namespaceVerifier) Cached(namespace, domain, key string) bool {
+	v.mu.Lock()
+	proof, exists := v.domains[strings.ToLower(domain)]
+	v.mu.Unlock()
+	if !exists {
+		return false
+	}
+	for _, p := range proof.proofs {
+		if p.Proves(namespace, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// proof returns the proof of domain, looking it up if the cached one is stale
+func (v *namespaceVerifier) proof(ctx context.Context, domain string) *domainProof {
+	domain = strings.ToLower(domain)
+	v.mu.Lock()
+	cached, exists := v.domains[domain]
+	v.mu.Unlock()
+	if exists {
+		ttl := namespaceRetryTTL
+		if len(cached.proofs) > 0 {
+			ttl = namespaceProofTTL
+		}
+		if time.Since(cached.checked) < ttl {
+			return cached
+		}
+	}
+
+	proof := &domainProof{proofs: make(map[string]discovery.NamespaceProof), checked: time.Now()}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, namespaceLookupTimeout)
+	records, err := v.lookupTXT(lookupCtx, discovery.NamespaceTXTPrefix+domain)
+	cancel()
+	if err != nil {
+		proof.errors = append(proof.errors, fmt.Sprintf("no DNS proof: %v", err))
+	} else if p := discovery.ParseNamespaceTXT(records); len(p) > 0 {
+		proof.proofs[ProofDNS] = p
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, namespaceLookupTimeout)
+	data, err := v.fetch(fetchCtx, "https://"+domain+discovery.NamespaceWellKnownPath)
+	cancel()
+	if err == nil {
+		var p discovery.NamespaceProof
+		if p, err = discovery.ParseNamespaceWellKnown(data); err == nil && len(p) > 0 {
+			proof.proofs[ProofHTTPS] = p
+		}
+	}
+	if err != nil {
+		proof.errors = append(proof.errors, fmt.Sprintf("no HTTPS proof: %v", err))
+	}
+
+	v.mu.Lock()
+	v.domains[domain] = proof
+	v.mu.Unlock()
+	return proof
+}
+
+// namespaceDomain returns the domain proving we own the namespace of a model
+// name, "" if the config names none
+func (dm *DHTManager) namespaceDomain(name string) string {
+	if dm.config == nil {
+		return ""
+	}
+	namespace := discovery.ModelNamespace(name)
+	for claimed, domain := range dm.config.Security.Namespaces {
+		if strings.EqualFold(claimed, namespace) {
+			return domain
+		}
+	}
+	return ""
+}
+
+// trustedDomain returns the domain trusted to name the publishers of a
+// namespace, "" if the config names none. Domains proving we own a namespace
+// are trusted too.
+func (dm *DHTManager) trustedDomain(namespace string) string {
+	if dm.config == nil {
+		return ""
+	}
+	for _, domains := range []map[string]string{dm.config.Security.TrustedNamespaces, dm.config.Security.Namespaces} {
+		for claimed, domain := range domains {
+			if strings.EqualFold(claimed, namespace) {
+				return domain
+			}
+		}
+	}
+	return ""
+}
+
+// checkClaim checks the namespace claim of a catalog entry before the proof
+// of its domain is looked up: the domain must be the one trusted for the
+// namespace, and the publisher must have signed naming it for this torrent.
+// It returns why the claim fails, "" if the proof decides.
+func (dm *DHTManager) checkClaim(name, infoHash, publisher, domain, signature string) string {
+	namespace := discovery.ModelNamespace(name)
+	trusted := dm.trustedDomain(namespace)
+	if trusted == "" {
+		return fmt.Sprintf("no domain is trusted for %s, see security.trusted_namespaces", namespace)
+	}
+	if !strings.EqualFold(trusted, domain) {
+		return fmt.Sprintf("%s is trusted for %s, not %s", trusted, namespace, domain)
+	}
+	if !discovery.VerifyNamespaceClaim(publisher, signature, name, infoHash, domain) {
+		return "the publisher didn't sign the claim for this torrent"
+	}
+	return ""
+}
+
+// VerifyPublisher checks that publisher signed the claim of domain for the
+// model name with infoHash and owns its namespace at domain, nil if the
+// model names no domain or has no namespace
+func (dm *DHTManager) VerifyPublisher(ctx context.Context, name, infoHash, publisher, domain, signature string) *PublisherVerification {
+	namespace := discovery.ModelNamespace(name)
+	if dm.namespaces == nil || domain == "" || namespace == "" || publisher == "" {
+		return nil
+	}
+	if reason := dm.checkClaim(name, infoHash, publisher, domain, signature); reason != "" {
+		return &PublisherVerification{Key: publisher, Namespace: namespace, Domain: domain, Detail: reason}
+	}
+	result := dm.namespaces.Verify(ctx, namespace, domain, publisher)
+	return &result
+}
+
+// CatalogPublisher checks the namespace claim of the publisher of name in
+// the public catalog, nil if the catalog doesn't list it with a domain
+func (dm *DHTManager) CatalogPublisher(ctx context.Context, name string) *PublisherVerification {
+	dm.mu.RLock()
+	ref := dm.catalogRef
+	dm.mu.RUnlock()
+	if ref == nil {
+		return nil
+	}
+	entry, exists := ref.FindModel(name)
+	if !exists {
+		return nil
+	}
+	return dm.VerifyPublisher(ctx, name, entry.InfoHash, entry.Publisher, entry.Domain, entry.DomainSignature)
+}
+
+// claimed reports whether a model names a domain for its namespace in a
+// claim its publisher signed, and the domain is the one trusted for it
+func (dm *DHTManager) claimed(model *types.ModelAnnouncement) bool {
+	if model.Domain == "" || model.PublisherKey == "" || discovery.ModelNamespace(model.Name) == "" {
+		return false
+	}
+	return dm.checkClaim(model.Name, model.InfoHash, model.PublisherKey, model.Domain, model.DomainSignature) == ""
+}
+
+// annotateVerified marks the models whose publisher proved it owns their
+// namespace. Proofs are looked up once per domain, in parallel.
+func (dm *DHTManager) annotateVerified(ctx context.Context, models []*types.ModelAnnouncement) {
+	if dm.namespaces == nil {
+		return
+	}
+	byDomain := make(map[string][]*types.ModelAnnouncement)
+	for _, model := range models {
+		if dm.claimed(model) {
+			byDomain[strings.ToLower(model.Domain)] = append(byDomain[strings.ToLower(model.Domain)], model)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for domain, domainModels := range byDomain {
+		wg.Add(1)
+		go func(domain string, domainModels []*types.ModelAnnouncement) {
+			defer wg.Done()
+			for _, model := range domainModels {
+				result := dm.namespaces.Verify(ctx, discovery.ModelNamespace(model.Name), domain, model.PublisherKey)
+				model.VerifiedPublisher = result.Verified
+			}
+		}(domain, domainModels)
+	}
+	wg.Wait()
+}
+
+// annotateCachedVerified marks the models whose publisher proved it owns
+// their namespace in a proof checked before, without looking any up
+func (dm *DHTManager) annotateCachedVerified(models []*types.ModelAnnouncement) {
+	if dm.namespaces == nil {
+		return
+	}
+	for _, model := range models {
+		if dm.claimed(model) {
+			model.VerifiedPublisher = dm.namespaces.Cached(discovery.ModelNamespace(model.Name), model.Domain, model.PublisherKey)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package daemon
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceVerifier(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key := hex.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+	other := hex.EncodeToString(otherKey.Public().(ed25519.PublicKey))
+	infoHash := strings.Repeat("1", 40)
+	sign := func(k ed25519.PrivateKey, name, domain string) string {
+		return discovery.SignNamespaceClaim(k, name, infoHash, domain)
+	}
+
+	lookups := 0
+	verifier := newNamespaceVerifier(nil)
+	verifier.lookupTXT = func(ctx context.Context, name string) ([]string, error) {
+		lookups++
+		switch name {
+		case "_silmaril.mistral.ai":
+			return []string{discovery.NamespaceTXTRecord(key, "mistralai")}, nil
+		case "_silmaril.evil.example":
+			return []string{discovery.NamespaceTXTRecord(other, "mistralai")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	verifier.fetch = func(ctx context.Context, url string) ([]byte, error) {
+		if url == "https://example.org/.well-known/silmaril" {
+			return []byte(`{"publishers": [{"key": "` + other + `", "namespaces": ["example"]}]}`), nil
+		}
+		return nil, errors.New("status 404")
+	}
+
+	dm := &DHTManager{
+		config: &config.Config{Security: config.SecurityConfig{
+			Namespaces:        map[string]string{"MistralAI": "mistral.ai"},
+			TrustedNamespaces: map[string]string{"example": "example.org"},
+		}},
+		namespaces: verifier,
+	}
+	assert.Equal(t, "mistral.ai", dm.namespaceDomain("mistralai/Mistral-7B"))
+	assert.Equal(t, "", dm.namespaceDomain("other/model"))
+
+	result := dm.VerifyPublisher(context.Background(), "mistralai/Mistral-7B", infoHash, key, "mistral.ai", sign(privateKey, "mistralai/Mistral-7B", "mistral.ai"))
+	require.NotNil(t, result)
+	assert.True(t, result.Verified)
+	assert.Equal(t, ProofDNS, result.Method)
+
+	// Another key claiming the namespace isn't verified
+	result = dm.VerifyPublisher(context.Background(), "mistralai/Mistral-7B", infoHash, other, "mistral.ai", sign(otherKey, "mistralai/Mistral-7B", "mistral.ai"))
+	require.NotNil(t, result)
+	assert.False(t, result.Verified)
+	assert.Equal(t, 1, lookups, "the proof of a domain is cached")
+
+	// Nor is the key and domain of the publisher copied onto another torrent
+	result = dm.VerifyPublisher(context.Background(), "mistralai/Mistral-7B", strings.Repeat("2", 40), key, "mistral.ai", sign(privateKey, "mistralai/Mistral-7B", "mistral.ai"))
+	require.NotNil(t, result)
+	assert.False(t, result.Verified)
+	assert.Equal(t, "the publisher didn't sign the claim for this torrent", result.Detail)
+
+	// Nor a domain that isn't trusted for the namespace, whatever it lists
+	result = dm.VerifyPublisher(context.Background(), "mistralai/Mistral-7B", infoHash, other, "evil.example", sign(otherKey, "mistralai/Mistral-7B", "evil.example"))
+	require.NotNil(t, result)
+	assert.False(t, result.Verified)
+	assert.Equal(t, "mistral.ai is trusted for mistralai, not evil.example", result.Detail)
+	result = dm.VerifyPublisher(context.Background(), "unknown/model", infoHash, other, "evil.example", sign(otherKey, "unknown/model", "evil.example"))
+	require.NotNil(t, result)
+	assert.False(t, result.Verified)
+	assert.Equal(t, 1, lookups, "untrusted domains aren't looked up")
+
+	result = dm.VerifyPublisher(context.Background(), "example/model", infoHash, other, "example.org", sign(otherKey, "example/model", "example.org"))
+	require.NotNil(t, result)
+	assert.True(t, result.Verified)
+	assert.Equal(t, ProofHTTPS, result.Method)
+
+	assert.Nil(t, dm.VerifyPublisher(context.Background(), "model", infoHash, key, "mistral.ai", ""))
+
+	models := []*types.ModelAnnouncement{
+		{Name: "mistralai/Mistral-7B", InfoHash: infoHash, PublisherKey: key, Domain: "mistral.ai", DomainSignature: sign(privateKey, "mistralai/Mistral-7B", "mistral.ai")},
+		{Name: "mistralai/Impostor", InfoHash: infoHash, PublisherKey: other, Domain: "mistral.ai", DomainSignature: sign(otherKey, "mistralai/Impostor", "mistral.ai")},
+		{Name: "mistralai/Copied", InfoHash: infoHash, PublisherKey: key, Domain: "mistral.ai", DomainSignature: sign(privateKey, "mistralai/Mistral-7B", "mistral.ai")},
+		{Name: "mistralai/Elsewhere", InfoHash: infoHash, PublisherKey: other, Domain: "evil.example", DomainSignature: sign(otherKey, "mistralai/Elsewhere", "evil.example")},
+		{Name: "example/model", InfoHash: infoHash, PublisherKey: other, Domain: "example.org", DomainSignature: sign(otherKey, "example/model", "example.org")},
+		{Name: "example/unclaimed", InfoHash: infoHash, PublisherKey: other},
+	}
+	dm.annotateVerified(context.Background(), models)
+	assert.True(t, models[0].VerifiedPublisher)
+	assert.False(t, models[1].VerifiedPublisher)
+	assert.False(t, models[2].VerifiedPublisher)
+	assert.False(t, models[3].VerifiedPublisher)
+	assert.True(t, models[4].VerifiedPublisher)
+	assert.False(t, models[5].VerifiedPublisher)
+
+	assert.True(t, verifier.Cached("mistralai", "Mistral.AI", key))
+	assert.False(t, verifier.Cached("mistralai", "unknown.org", key))
+}
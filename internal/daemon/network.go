@@ -0,0 +1,188 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/anacrolix/torrent"
+	"github.com/silmaril/silmaril/internal/proxy"
+)
+
+// Address family preferences of network.ip_preference
+const (
+	IPPreferDual = "dual"        // Use both families, the default
+	IPPreferIPv4 = "prefer_ipv4" // Use both families, IPv4 first
+	IPPreferIPv6 = "prefer_ipv6" // Use both families, IPv6 first
+	IPv4Only     = "ipv4_only"
+	IPv6Only     = "ipv6_only"
+)
+
+// IPPreferences lists the valid values of network.ip_preference
+var IPPreferences = []string{IPPreferDual, IPPreferIPv4, IPPreferIPv6, IPv4Only, IPv6Only}
+
+// Address family names used in stats
+const (
+	familyIPv4 = "ipv4"
+	familyIPv6 = "ipv6"
+)
+
+// listenConfig is where the torrent client and DHT listen, per address family
+type listenConfig struct {
+	IPv4Host    string
+	IPv6Host    string
+	Port        int
+	DisableIPv4 bool
+	DisableIPv6 bool
+	PreferIPv6  bool
+}
+
+// parseListenConfig parses the network.listen_addrs setting. Without
+// addresses both families listen on all interfaces at port. Addresses bind a
+// family each, families without an address are disabled. The torrent client
+// listens on one port for all families, so the addresses must share it.
+func parseListenConfig(addrs []string, port int, preference string) (listenConfig, error) {
+	lc := listenConfig{Port: port}
+
+	if len(addrs) > 0 {
+		lc.DisableIPv4 = true
+		lc.DisableIPv6 = true
+		for i, addr := range addrs {
+			host, portStr, err := net.SplitHostPort(strings.TrimSpace(addr))
+			if err != nil {
+				return lc, fmt.Errorf("invalid listen address %q: %w", addr, err)
+			}
+			addrPort, err := strconv.Atoi(portStr)
+			if err != nil || addrPort < 0 || addrPort > 65535 {
+				return lc, fmt.Errorf("invalid port in listen address %q", addr)
+			}
+			if i == 0 {
+				lc.Port = addrPort
+			} else if addrPort != lc.Port {
+				return lc, fmt.Errorf("listen addresses must use the same port, got %d and %d", lc.Port, addrPort)
+			}
+
+			// An empty host listens on all interfaces of both families
+			if host == "" {
+				if !lc.DisableIPv4 || !lc.DisableIPv6 {
+					return lc, fmt.Errorf("listen address %q overlaps another address", addr)
+				}
+				lc.DisableIPv4 = false
+				lc.DisableIPv6 = false
+				continue
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return lc, fmt.Errorf("listen address %q must be an IP address", addr)
+			}
+			if ip.To4() != nil {
+				if !lc.DisableIPv4 {
+					return lc, fmt.Errorf("more than one IPv4 listen address: %q", addr)
+				}
+				lc.IPv4Host = host
+				lc.DisableIPv4 = false
+			} else {
+				if !lc.DisableIPv6 {
+					return lc, fmt.Errorf("more than one IPv6 listen address: %q", addr)
+				}
+				lc.IPv6Host = host
+				lc.DisableIPv6 = false
+			}
+		}
+	}
+
+	switch preference {
+	case "", IPPreferDual, IPPreferIPv4:
+	case IPPreferIPv6:
+		lc.PreferIPv6 = true
+	case IPv4Only:
+		lc.DisableIPv6 = true
+	case IPv6Only:
+		lc.DisableIPv4 = true
+		lc.PreferIPv6 = true
+	default:
+		return lc, fmt.Errorf("invalid IP preference %q, expected one of %s", preference, strings.Join(IPPreferences, ", "))
+	}
+	if lc.DisableIPv4 && lc.DisableIPv6 {
+		return lc, fmt.Errorf("listen addresses and IP preference %q disable both IPv4 and IPv6", preference)
+	}
+	return lc, nil
+}
+
+// PeerNetwork returns the TCP network of the preferred enabled family
+func (lc listenConfig) PeerNetwork() string {
+	if lc.DisableIPv4 || (lc.PreferIPv6 && !lc.DisableIPv6) {
+		return "tcp6"
+	}
+	return "tcp4"
+}
+
+// Host returns the host to listen on for a network such as "tcp4" or "udp6"
+func (lc listenConfig) Host(network string) string {
+	if strings.HasSuffix(network, "6") {
+		return lc.IPv6Host
+	}
+	return lc.IPv4Host
+}
+
+// UDPNetworks returns the UDP networks to listen on, the preferred one first
+func (lc listenConfig) UDPNetworks() []string {
+	var networks []string
+	if !lc.DisableIPv4 {
+		networks = append(networks, "udp4")
+	}
+	if !lc.DisableIPv6 {
+		networks = append(networks, "udp6")
+	}
+	if lc.PreferIPv6 && len(networks) == 2 {
+		networks[0], networks[1] = networks[1], networks[0]
+	}
+	return networks
+}
+
+// addrFamily returns the address family of a peer or node address, "" if it
+// has no IP address. IPv4-mapped IPv6 addresses count as IPv4.
+func addrFamily(addr fmt.Stringer) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+	return ipFamily(net.ParseIP(host))
+}
+
+func ipFamily(ip net.IP) string {
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return familyIPv4
+	default:
+		return familyIPv6
+	}
+}
+
+// Proxy returns the outbound proxy of a component, nil if it connects directly
+func (d *Daemon) Proxy(component string) (*proxy.Proxy, error) {
+	return proxy.FromConfig(d.config, component)
+}
+
+// applyTorrentProxy sends the HTTP and peer traffic of a torrent client
+// through a proxy. Proxies only carry outgoing TCP connections, so the client
+// stops listening for peers and skips UDP trackers instead of bypassing the
+// proxy. Peer connections are dialed through a dialer added to the client.
+func applyTorrentProxy(clientCfg *torrent.ClientConfig, p *proxy.Proxy) {
+	clientCfg.HTTPProxy = p.ProxyFunc()
+	clientCfg.WebTransport = p.Transport()
+	clientCfg.TrackerListenPacket = func(network, addr string) (net.PacketConn, error) {
+		return nil, errors.New("UDP trackers can't be used through a proxy")
+	}
+	clientCfg.DisableTCP = true
+	clientCfg.DisableUTP = true
+	clientCfg.AcceptPeerConnections = false
+}
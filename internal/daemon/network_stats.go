@@ -0,0 +1,160 @@
+package daemon
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/discovery"
+)
+
+// How often the daemon checks whether today's network stats were published
+const networkStatsInterval = time.Hour
+
+// Time allowed to publish our report or read the reports of a day
+const networkStatsTimeout = 30 * time.Second
+
+// NetworkStatsStatus is the stats of the network for a day and whether this
+// node takes part
+type NetworkStatsStatus struct {
+	discovery.NetworkStats
+	Sharing       bool   `json:"sharing"`                  // network.share_stats
+	LastPublished string `json:"last_published,omitempty"` // Day our report was last published
+	PublishError  string `json:"publish_error,omitempty"`  // Of the last attempt
+}
+
+// networkStatsReporter remembers what was published, so a report goes out
+// once a day
+type networkStatsReporter struct {
+	mu      sync.Mutex // Serializes publishing
+	secret  []byte     // Fallback nonce key without a publisher key
+	lastDay string
+	err     error
+}
+
+// StatsCatalog returns the catalog of daily network stats, nil before the
+// DHT runs
+func (dm *DHTManager) StatsCatalog() *discovery.StatsCatalog {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	if dm.statsCatalog == nil && dm.dhtServer != nil {
+		dm.statsCatalog = discovery.NewStatsCatalog(dm.dhtServer)
+	}
+	return dm.statsCatalog
+}
+
+// networkStatsNonce tells our reports of day apart from other nodes'. It is
+// keyed with our publisher key so it is stable across restarts, but reports
+// of different days can't be linked to each other or to the key.
+func (d *Daemon) networkStatsNonce(day string) string {
+	var key []byte
+	if d.dhtManager != nil && d.dhtManager.publisherKey != nil {
+		key = d.dhtManager.publisherKey.Seed()
+	} else {
+		if d.networkStats.secret == nil {
+			d.networkStats.secret = make([]byte, 32)
+			rand.Read(d.networkStats.secret)
+		}
+		key = d.networkStats.secret
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("silmaril-stats/" + day))
+	return hex.EncodeToString(mac.Sum(nil)[:4])
+}
+
+// networkReport returns what this node reports for day: the number of
+// models it seeds and the bytes it uploaded in total, nothing else
+func (d *Daemon) networkReport(day string) discovery.NetworkReport {
+	report := discovery.NetworkReport{Nonce: d.networkStatsNonce(day)}
+	if d.torrentManager != nil {
+		report.Models = len(d.torrentManager.GetSeedingModels())
+	}
+	report.UploadedMB = d.GetContributionStats(1).Uploaded >> 20
+	return report
+}
+
+// publishNetworkStats publishes today's report if network.share_stats is
+// enabled and it wasn't published yet
+func (d *Daemon) publishNetworkStats() {
+	if d.config == nil || !d.config.GetBool("network.share_stats") || d.dhtManager == nil {
+		return
+	}
+
+	d.networkStats.mu.Lock()
+	defer d.networkStats.mu.Unlock()
+
+	day := discovery.StatsDay(time.Now())
+	if d.networkStats.lastDay == day || d.dhtManager.GetNodeCount() == 0 {
+		return
+	}
+	catalog := d.dhtManager.StatsCatalog()
+	if catalog == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, networkStatsTimeout)
+	defer cancel()
+	report := d.networkReport(day)
+	if err := catalog.Publish(ctx, day, report); err != nil {
+		d.networkStats.err = err
+		fmt.Printf("[NetworkStats] %v\n", err)
+		return
+	}
+	d.networkStats.lastDay = day
+	d.networkStats.err = nil
+	fmt.Printf("[NetworkStats] Published the stats for %s: %d models seeded, %d MB uploaded\n", day, report.Models, report.UploadedMB)
+}
+
+// GetNetworkStats reads the stats published by the nodes of the network for
+// day, 2006-01-02 in UTC
+func (d *Daemon) GetNetworkStats(ctx context.Context, day string) (NetworkStatsStatus, error) {
+	status := NetworkStatsStatus{NetworkStats: discovery.NetworkStats{Day: day}}
+	if d.config != nil {
+		status.Sharing = d.config.GetBool("network.share_stats")
+	}
+	d.networkStats.mu.Lock()
+	status.LastPublished = d.networkStats.lastDay
+	if d.networkStats.err != nil {
+		status.PublishError = d.networkStats.err.Error()
+	}
+	d.networkStats.mu.Unlock()
+
+	if d.dhtManager == nil {
+		return status, fmt.Errorf("DHT is not running")
+	}
+	catalog := d.dhtManager.StatsCatalog()
+	if catalog == nil {
+		return status, fmt.Errorf("DHT is not running")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, networkStatsTimeout)
+	defer cancel()
+	stats, err := catalog.Read(ctx, day)
+	if err != nil {
+		return status, err
+	}
+	status.NetworkStats = stats
+	return status, nil
+}
+
+// networkStatsWorker publishes our report once a day while sharing is enabled
+func (d *Daemon) networkStatsWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(networkStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.publishNetworkStats()
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListenConfig(t *testing.T) {
+	// Without addresses both families listen on all interfaces
+	lc, err := parseListenConfig(nil, 6881, "")
+	require.NoError(t, err)
+	assert.Equal(t, listenConfig{Port: 6881}, lc)
+	assert.Equal(t, []string{"udp4", "udp6"}, lc.UDPNetworks())
+
+	lc, err = parseListenConfig([]string{"0.0.0.0:42069", "[::]:42069"}, 0, "prefer_ipv6")
+	require.NoError(t, err)
+	assert.Equal(t, 42069, lc.Port)
+	assert.Equal(t, "0.0.0.0", lc.Host("tcp4"))
+	assert.Equal(t, "::", lc.Host("udp6"))
+	assert.Equal(t, []string{"udp6", "udp4"}, lc.UDPNetworks())
+
+	// Families without an address are disabled
+	lc, err = parseListenConfig([]string{"[2001:db8::1]:42069"}, 0, "")
+	require.NoError(t, err)
+	assert.True(t, lc.DisableIPv4)
+	assert.False(t, lc.DisableIPv6)
+	assert.Equal(t, []string{"udp6"}, lc.UDPNetworks())
+
+	lc, err = parseListenConfig([]string{":42069"}, 0, "ipv4_only")
+	require.NoError(t, err)
+	assert.False(t, lc.DisableIPv4)
+	assert.True(t, lc.DisableIPv6)
+
+	for _, tc := range []struct {
+		addrs      []string
+		preference string
+	}{
+		{[]string{"0.0.0.0"}, ""},                         // No port
+		{[]string{"localhost:42069"}, ""},                 // Not an IP address
+		{[]string{"0.0.0.0:42069", "[::]:42070"}, ""},     // Different ports
+		{[]string{"0.0.0.0:42069", "10.0.0.1:42069"}, ""}, // Two IPv4 addresses
+		{[]string{":42069", "[::]:42069"}, ""},            // Overlapping addresses
+		{[]string{"[::]:42069"}, "ipv4_only"},             // No family left
+		{nil, "ipv6_first"},                               // Unknown preference
+	} {
+		_, err := parseListenConfig(tc.addrs, 0, tc.preference)
+		assert.Error(t, err, "%v %s", tc.addrs, tc.preference)
+	}
+}
+
+func TestAddrFamily(t *testing.T) {
+	assert.Equal(t, "ipv4", addrFamily(&net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 6881}))
+	assert.Equal(t, "ipv4", addrFamily(&net.UDPAddr{IP: net.ParseIP("::ffff:192.0.2.1"), Port: 6881}))
+	assert.Equal(t, "ipv6", addrFamily(&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 6881}))
+	assert.Equal(t, "", addrFamily(nil))
+}
@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// CheckOutputDir checks that a download may be stored in output, which must
+// be below one of the directories of storage.output_dirs. API clients
+// choose the directory, so without the setting they can't store downloads
+// anywhere but the models directory and the storage pools. Links are
+// followed, so none leads out of an output directory.
+func (d *Daemon) CheckOutputDir(output string) error {
+	resolved := resolveExisting(filepath.Clean(output))
+	if d.config != nil {
+		for _, dir := range d.config.Storage.OutputDirs {
+			if !filepath.IsAbs(dir) {
+				continue
+			}
+			root := resolveExisting(filepath.Clean(dir))
+			rel, err := filepath.Rel(root, resolved)
+			if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("output directory %s is not below a directory of storage.output_dirs", output)
+}
+
+// resolveExisting resolves the links of the part of path that exists
+func resolveExisting(path string) string {
+	rest := ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(path, rest)
+		}
+		rest = filepath.Join(filepath.Base(path), rest)
+		path = parent
+	}
+}
@@ -0,0 +1,187 @@
+package daemon
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/anacrolix/torrent"
+)
+
+// fileSelection holds the file patterns of a torrent, which change while
+// workers check its completion
+type fileSelection struct {
+	mu       sync.RWMutex
+	patterns []string
+}
+
+// Files returns the file patterns of a torrent, nil if all files are
+// downloaded and seeded
+func (s *fileSelection) Files() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.patterns
+}
+
+func (s *fileSelection) setFiles(patterns []string) {
+	s.mu.Lock()
+	s.patterns = patterns
+	s.mu.Unlock()
+}
+
+// matchFile reports whether a file of a torrent matches one of patterns. A
+// pattern is a glob matched against the file's path in the torrent or, if it
+// has no slash, against the file name. No patterns match every file.
+func matchFile(patterns []string, filePath string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		target := filePath
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(filePath)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyFileSelection downloads and seeds only the files of t matching
+// patterns, or all files without patterns. Pieces of other files are neither
+// requested nor advertised unless they overlap a selected file. It returns the
+// number of selected files.
+func applyFileSelection(t *torrent.Torrent, patterns []string) int {
+	if len(patterns) == 0 {
+		t.DownloadAll()
+		return len(t.Files())
+	}
+
+	// File priorities only count once the torrent wide priority is cleared
+	t.CancelPieces(0, t.NumPieces())
+	selected := 0
+	for _, f := range t.Files() {
+		if matchFile(patterns, f.DisplayPath()) {
+			f.Download()
+			selected++
+		} else {
+			f.SetPriority(torrent.PiecePriorityNone)
+		}
+	}
+	return selected
+}
+
+// WantedBytes returns how much of the selected files of a torrent is local
+// and their total size, both 0 until the metadata is known
+func (mt *ManagedTorrent) WantedBytes() (completed, total int64) {
+	t := mt.Torrent
+	if t.Info() == nil {
+		return 0, 0
+	}
+	patterns := mt.Files()
+	if len(patterns) == 0 {
+		return t.BytesCompleted(), t.Length()
+	}
+	for _, f := range t.Files() {
+		if matchFile(patterns, f.DisplayPath()) {
+			completed += f.BytesCompleted()
+			total += f.Length()
+		}
+	}
+	return completed, total
+}
+
+// FileProgress is how much of a file of a torrent is local
+type FileProgress struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Completed int64  `json:"completed"`
+}
+
+// FileProgress returns how much of each selected file of a torrent is
+// local, in the order of the torrent, none until the metadata is known
+func (tm *TorrentManager) FileProgress(infoHash string) ([]FileProgress, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	mt, exists := tm.torrents[infoHash]
+	if !exists {
+		return nil, fmt.Errorf("torrent not found: %s", infoHash)
+	}
+	files := make([]FileProgress, 0)
+	if mt.Torrent.Info() == nil {
+		return files, nil
+	}
+	patterns := mt.Files()
+	for _, f := range mt.Torrent.Files() {
+		if matchFile(patterns, f.DisplayPath()) {
+			files = append(files, FileProgress{
+				Path:      f.DisplayPath(),
+				Size:      f.Length(),
+				Completed: f.BytesCompleted(),
+			})
+		}
+	}
+	return files, nil
+}
+
+// Complete reports whether all selected files of a torrent are local
+func (mt *ManagedTorrent) Complete() bool {
+	completed, total := mt.WantedBytes()
+	return mt.Torrent.Info() != nil && completed == total
+}
+
+// SetFileSelection limits a torrent to the files matching patterns, an empty
+// selection downloads and seeds all files again. Torrents still fetching
+// their metadata apply the selection once it arrives.
+func (tm *TorrentManager) SetFileSelection(infoHash string, patterns []string) (int, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	mt, exists := tm.torrents[infoHash]
+	if !exists {
+		return 0, fmt.Errorf("torrent not found: %s", infoHash)
+	}
+
+	selected := 0
+	if mt.Torrent.Info() != nil {
+		for _, f := range mt.Torrent.Files() {
+			if matchFile(patterns, f.DisplayPath()) {
+				selected++
+			}
+		}
+		if selected == 0 {
+			return 0, fmt.Errorf("no files of %s match %s", mt.Name, strings.Join(patterns, ", "))
+		}
+		applyFileSelection(mt.Torrent, patterns)
+	}
+
+	mt.setFiles(patterns)
+	tm.state.SetTorrentFiles(infoHash, patterns)
+	return selected, nil
+}
+
+// SelectModelFiles limits seeding of a model to the files matching patterns.
+// Files that are no longer selected can be deleted to free disk space.
+func (d *Daemon) SelectModelFiles(name string, patterns []string) (int, error) {
+	if d.torrentManager == nil {
+		return 0, fmt.Errorf("torrent manager not available")
+	}
+
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		if mt.Name != name || isUpdateDownload(mt) {
+			continue
+		}
+		selected, err := d.torrentManager.SetFileSelection(mt.InfoHash, patterns)
+		if err != nil {
+			return 0, err
+		}
+		if err := d.state.Save(); err != nil {
+			fmt.Printf("[TorrentManager] Warning: failed to save state: %v\n", err)
+		}
+		return selected, nil
+	}
+	return 0, fmt.Errorf("model %s is not being seeded", name)
+}
@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	torrentclient "github.com/silmaril/silmaril/internal/torrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchFile(t *testing.T) {
+	assert.True(t, matchFile(nil, "model.Q8_0.gguf"))
+
+	patterns := []string{"*Q4_K_M.gguf", "*.json"}
+	assert.True(t, matchFile(patterns, "model.Q4_K_M.gguf"))
+	assert.True(t, matchFile(patterns, "config.json"))
+	assert.False(t, matchFile(patterns, "model.Q8_0.gguf"))
+
+	// Patterns without a slash match the file name in any directory
+	assert.True(t, matchFile(patterns, "quants/model.Q4_K_M.gguf"))
+
+	// Patterns with a slash match the path
+	assert.True(t, matchFile([]string{"quants/*"}, "quants/model.Q8_0.gguf"))
+	assert.False(t, matchFile([]string{"quants/*"}, "model.Q8_0.gguf"))
+}
+
+func TestTorrentManagerSetFileSelection(t *testing.T) {
+	tm, state, tmpDir := setupTestTorrentManager(t)
+	defer tm.Stop()
+
+	// A model with two quantizations
+	sourceDir := filepath.Join(tmpDir, "source")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "model.Q4_K_M.gguf"), bytes.Repeat([]byte("a"), 3*16384), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "model.Q8_0.gguf"), bytes.Repeat([]byte("b"), 70000), 0644))
+	torrentPath := filepath.Join(tmpDir, "model.torrent")
+	_, err := torrentclient.CreateTorrentFromDirectory(sourceDir, torrentPath, 16384, nil)
+	require.NoError(t, err)
+
+	mt, err := tm.AddTorrentForDownload(torrentPath, "org/model", filepath.Join(tmpDir, "download"))
+	require.NoError(t, err)
+
+	_, err = tm.SetFileSelection(mt.InfoHash, []string{"*.safetensors"})
+	assert.Error(t, err, "a selection must match a file")
+
+	selected, err := tm.SetFileSelection(mt.InfoHash, []string{"*Q4_K_M.gguf"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, selected)
+	assert.Equal(t, []string{"*Q4_K_M.gguf"}, mt.Files())
+
+	completed, total := mt.WantedBytes()
+	assert.Equal(t, int64(0), completed)
+	assert.Equal(t, int64(3*16384), total)
+	assert.False(t, mt.Complete())
+
+	files, err := tm.FileProgress(mt.InfoHash)
+	require.NoError(t, err)
+	assert.Equal(t, []FileProgress{{Path: "model.Q4_K_M.gguf", Size: 3 * 16384}}, files)
+
+	// The selection survives restarts
+	ts, ok := state.GetTorrentState(mt.InfoHash)
+	require.True(t, ok)
+	assert.Equal(t, []string{"*Q4_K_M.gguf"}, ts.Files)
+
+	// Seeding the selected files only needs them to be local, the pieces
+	// of this one end with the file
+	require.NoError(t, os.Remove(filepath.Join(sourceDir, "model.Q8_0.gguf")))
+	require.NoError(t, tm.RemoveTorrent(mt.InfoHash))
+	mt, err = tm.AddTorrentForSeeding(torrentPath, "org/model", sourceDir)
+	require.NoError(t, err)
+	_, err = tm.SetFileSelection(mt.InfoHash, []string{"*Q4_K_M.gguf"})
+	require.NoError(t, err)
+	assert.Eventually(t, mt.Complete, 10*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,243 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/peerfilter"
+	"github.com/silmaril/silmaril/internal/proxy"
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// How often the age of the downloaded blocklist is checked
+const blocklistCheckInterval = time.Hour
+
+// Time allowed to download a blocklist
+const blocklistDownloadTimeout = 5 * time.Minute
+
+// Largest blocklist accepted, common lists are a few MB compressed
+const maxBlocklistSize = 256 << 20
+
+// PeerFilterStatus describes the peer filter and what it rejected
+type PeerFilterStatus struct {
+	peerfilter.Stats
+	BlocklistURL     string     `json:"blocklist_url,omitempty"`
+	BlocklistUpdated *time.Time `json:"blocklist_updated,omitempty"` // When the downloaded list was saved
+	SkippedLines     int        `json:"skipped_lines"`               // Lines of the downloaded list that didn't parse
+	Error            string     `json:"error,omitempty"`             // Of the last reload
+}
+
+// peerFilterLoader loads the configured lists into the torrent client's
+// peer filter
+type peerFilterLoader struct {
+	mu      sync.Mutex // Serializes reloads
+	skipped int
+	err     error
+}
+
+// blocklistPath is where the downloaded blocklist is kept, so a restart
+// doesn't depend on downloading it again
+func blocklistPath() string {
+	return filepath.Join(storage.GetBaseDir(), "daemon", "blocklist.p2p")
+}
+
+// blocklistRefresh returns how old the downloaded blocklist may get
+func (d *Daemon) blocklistRefresh() time.Duration {
+	if d.config != nil {
+		if hours := d.config.GetInt("network.peer_blocklist_refresh_hours"); hours > 0 {
+			return time.Duration(hours) * time.Hour
+		}
+	}
+	return 24 * time.Hour
+}
+
+// ReloadPeerFilter reads the configured block and allow lists into the peer
+// filter. The blocklist source is fetched again if download is set, else
+// the saved copy is used. The previous lists stay in place if the
+// configured ones are invalid.
+func (d *Daemon) ReloadPeerFilter(download bool) (PeerFilterStatus, error) {
+	d.peerFilter.mu.Lock()
+	defer d.peerFilter.mu.Unlock()
+
+	err := d.reloadPeerFilter(download)
+	d.peerFilter.err = err
+	if err != nil {
+		fmt.Printf("[PeerFilter] %v\n", err)
+	}
+	return d.peerFilterStatus(), err
+}
+
+// reloadPeerFilter loads the lists, the caller holds d.peerFilter.mu
+func (d *Daemon) reloadPeerFilter(download bool) error {
+	if d.torrentManager == nil || d.config == nil {
+		return nil
+	}
+
+	blocked, err := peerfilter.ParseEntries(d.config.GetStringSlice("network.peer_blocklist"))
+	if err != nil {
+		return fmt.Errorf("invalid network.peer_blocklist: %w", err)
+	}
+	allowed, err := peerfilter.ParseEntries(d.config.GetStringSlice("network.peer_allowlist"))
+	if err != nil {
+		return fmt.Errorf("invalid network.peer_allowlist: %w", err)
+	}
+
+	// A failed download keeps the saved copy in use
+	var downloadErr error
+	source := d.config.GetString("network.peer_blocklist_url")
+	if source != "" {
+		if download {
+			if downloadErr = d.downloadBlocklist(source); downloadErr == nil {
+				fmt.Printf("[PeerFilter] Updated blocklist from %s\n", source)
+			}
+		}
+		listed, skipped, err := readBlocklist(blocklistPath())
+		if err != nil && downloadErr == nil {
+			downloadErr = err
+		}
+		blocked = append(blocked, listed...)
+		d.peerFilter.skipped = skipped
+	} else {
+		d.peerFilter.skipped = 0
+	}
+
+	filter := d.torrentManager.PeerFilter()
+	filter.SetLists(blocked, allowed)
+	stats := filter.Stats()
+	fmt.Printf("[PeerFilter] %d blocked and %d allowed ranges\n", stats.BlockedRanges, stats.AllowedRanges)
+	return downloadErr
+}
+
+// downloadBlocklist fetches the blocklist at source, an HTTP(S) URL or a
+// file path, and saves it once it parses
+func (d *Daemon) downloadBlocklist(source string) error {
+	var data []byte
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		p, err := proxy.FromConfig(d.config, proxy.Torrent)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(d.ctx, blocklistDownloadTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return fmt.Errorf("invalid blocklist URL: %w", err)
+		}
+		resp, err := p.HTTPClient(blocklistDownloadTimeout).Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to download blocklist: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to download blocklist: %s", resp.Status)
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, maxBlocklistSize+1))
+		if err != nil {
+			return fmt.Errorf("failed to download blocklist: %w", err)
+		}
+	} else {
+		var err error
+		if data, err = os.ReadFile(source); err != nil {
+			return fmt.Errorf("failed to read blocklist: %w", err)
+		}
+	}
+	if len(data) > maxBlocklistSize {
+		return fmt.Errorf("blocklist is larger than %d MB", maxBlocklistSize>>20)
+	}
+
+	// Keep the saved copy if the new one is unusable
+	ranges, _, err := peerfilter.ParseP2P(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if len(ranges) == 0 {
+		return fmt.Errorf("blocklist from %s has no ranges", source)
+	}
+	if err := storage.WriteFileAtomic(blocklistPath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to save blocklist: %w", err)
+	}
+	return nil
+}
+
+// readBlocklist parses the saved blocklist, none if it doesn't exist
+func readBlocklist(path string) ([]peerfilter.Range, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("failed to open blocklist: %w", err)
+	}
+	defer file.Close()
+	return peerfilter.ParseP2P(file)
+}
+
+// PeerFilterStatus returns the ranges of the peer filter and the addresses
+// it rejected
+func (d *Daemon) PeerFilterStatus() PeerFilterStatus {
+	d.peerFilter.mu.Lock()
+	defer d.peerFilter.mu.Unlock()
+	return d.peerFilterStatus()
+}
+
+// peerFilterStatus describes the peer filter, the caller holds d.peerFilter.mu
+func (d *Daemon) peerFilterStatus() PeerFilterStatus {
+	var status PeerFilterStatus
+	if d.torrentManager != nil {
+		status.Stats = d.torrentManager.PeerFilter().Stats()
+	}
+	if d.config != nil {
+		status.BlocklistURL = d.config.GetString("network.peer_blocklist_url")
+	}
+	if status.BlocklistURL != "" {
+		if info, err := os.Stat(blocklistPath()); err == nil {
+			updated := info.ModTime()
+			status.BlocklistUpdated = &updated
+		}
+		status.SkippedLines = d.peerFilter.skipped
+	}
+	if d.peerFilter.err != nil {
+		status.Error = d.peerFilter.err.Error()
+	}
+	return status
+}
+
+// refreshBlocklist downloads the blocklist if there is none yet or it is
+// older than network.peer_blocklist_refresh_hours
+func (d *Daemon) refreshBlocklist() {
+	if d.config == nil || d.config.GetString("network.peer_blocklist_url") == "" {
+		return
+	}
+	info, err := os.Stat(blocklistPath())
+	if err == nil && time.Since(info.ModTime()) < d.blocklistRefresh() {
+		return
+	}
+	d.ReloadPeerFilter(true)
+}
+
+// peerFilterWorker keeps the downloaded blocklist up to date
+func (d *Daemon) peerFilterWorker() {
+	defer d.workers.Done()
+
+	d.refreshBlocklist()
+
+	ticker := time.NewTicker(blocklistCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.refreshBlocklist()
+		}
+	}
+}
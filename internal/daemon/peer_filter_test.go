@@ -0,0 +1,37 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadBlocklist(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	require.NoError(t, os.MkdirAll(filepath.Dir(blocklistPath()), 0755))
+	d := &Daemon{ctx: context.Background()}
+
+	source := filepath.Join(t.TempDir(), "level1.p2p")
+	require.NoError(t, os.WriteFile(source, []byte("Bad actors:203.0.113.0-203.0.113.255\nnot a range\n"), 0644))
+	require.NoError(t, d.downloadBlocklist(source))
+
+	ranges, skipped, err := readBlocklist(blocklistPath())
+	require.NoError(t, err)
+	assert.Len(t, ranges, 1)
+	assert.Equal(t, 1, skipped)
+
+	// A list without ranges doesn't replace the saved one
+	require.NoError(t, os.WriteFile(source, []byte("<html>Not found</html>\n"), 0644))
+	assert.Error(t, d.downloadBlocklist(source))
+	ranges, _, err = readBlocklist(blocklistPath())
+	require.NoError(t, err)
+	assert.Len(t, ranges, 1)
+
+	ranges, _, err = readBlocklist(filepath.Join(t.TempDir(), "missing.p2p"))
+	assert.NoError(t, err)
+	assert.Empty(t, ranges)
+}
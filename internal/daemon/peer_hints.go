@@ -0,0 +1,216 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// How often peer hints are resolved again, pods of a StatefulSet come and go
+const peerHintInterval = time.Minute
+
+// Time allowed to resolve all peer hints
+const peerHintTimeout = 10 * time.Second
+
+// PeerHintsStatus is the state of the peers found through network.peer_hints
+type PeerHintsStatus struct {
+	Hints        []string   `json:"hints"`
+	Peers        []string   `json:"peers"` // Addresses the hints resolved to, without our own
+	LastResolved *time.Time `json:"last_resolved,omitempty"`
+	Error        string     `json:"error,omitempty"` // Of the last resolution
+}
+
+// peerHints remembers the last resolution of the peer hints
+type peerHints struct {
+	mu       sync.Mutex // Serializes resolutions
+	peers    []string
+	resolved time.Time
+	err      error
+}
+
+// lookupIPFunc resolves a host name to its addresses, like
+// net.Resolver.LookupIPAddr
+type lookupIPFunc func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+// PeerHintsStatus returns the peers found through the configured hints
+func (d *Daemon) PeerHintsStatus() PeerHintsStatus {
+	d.peerHints.mu.Lock()
+	defer d.peerHints.mu.Unlock()
+
+	status := PeerHintsStatus{Peers: d.peerHints.peers}
+	if d.config != nil {
+		status.Hints = d.config.GetStringSlice("network.peer_hints")
+	}
+	if !d.peerHints.resolved.IsZero() {
+		resolved := d.peerHints.resolved
+		status.LastResolved = &resolved
+	}
+	if d.peerHints.err != nil {
+		status.Error = d.peerHints.err.Error()
+	}
+	return status
+}
+
+// addHintedPeers resolves the peer hints and adds the daemons behind them
+// as peers of every torrent, so daemons in a cluster find each other
+// without the DHT
+func (d *Daemon) addHintedPeers() {
+	if d.config == nil || d.torrentManager == nil {
+		return
+	}
+	hints := d.config.GetStringSlice("network.peer_hints")
+
+	d.peerHints.mu.Lock()
+	defer d.peerHints.mu.Unlock()
+
+	if len(hints) == 0 {
+		d.peerHints.peers = nil
+		d.peerHints.err = nil
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, peerHintTimeout)
+	defer cancel()
+
+	addrs, err := resolvePeerHints(ctx, net.DefaultResolver.LookupIPAddr, hints, d.peerListenPort(), localIPs())
+	if err != nil {
+		fmt.Printf("[PeerHints] %v\n", err)
+	}
+
+	peers := make([]string, len(addrs))
+	for i, addr := range addrs {
+		peers[i] = addr.String()
+	}
+	if strings.Join(peers, ",") != strings.Join(d.peerHints.peers, ",") {
+		fmt.Printf("[PeerHints] Peers: %s\n", strings.Join(peers, ", "))
+	}
+	d.peerHints.peers = peers
+	d.peerHints.resolved = time.Now()
+	d.peerHints.err = err
+
+	d.torrentManager.AddPeers(addrs)
+}
+
+// peerHintWorker adds the peers behind the hints and the peers added by
+// hand to new torrents, and follows changes to their addresses
+func (d *Daemon) peerHintWorker() {
+	defer d.workers.Done()
+
+	d.addHintedPeers()
+	d.restoreManualPeers()
+
+	ticker := time.NewTicker(peerHintInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.addHintedPeers()
+			d.restoreManualPeers()
+		}
+	}
+}
+
+// AddPeers adds addrs as peers of every torrent of the client, catalogs
+// included. Peers already known are not added twice.
+func (tm *TorrentManager) AddPeers(addrs []*net.TCPAddr) int {
+	if len(addrs) == 0 {
+		return 0
+	}
+	peers := make([]torrent.PeerInfo, len(addrs))
+	for i, addr := range addrs {
+		peers[i] = torrent.PeerInfo{Addr: addr, Source: peerSourceHint, Trusted: true}
+	}
+
+	added := 0
+	for _, t := range tm.client.Torrents() {
+		added += t.AddPeers(peers)
+	}
+	return added
+}
+
+// resolvePeerHints resolves hints like "silmaril.ns.svc.cluster.local",
+// "10.0.0.5" or "peer:42069" to peer addresses. Hints without a port use
+// port. Addresses of this host on port are skipped, a headless service
+// resolves to our own pod too. Hints that fail to resolve are reported
+// without stopping the others.
+func resolvePeerHints(ctx context.Context, lookup lookupIPFunc, hints []string, port int, local map[string]bool) ([]*net.TCPAddr, error) {
+	seen := make(map[string]bool)
+	var addrs []*net.TCPAddr
+	var errs []error
+
+	for _, hint := range hints {
+		host, hintPort, err := splitPeerHint(hint, port)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		var ips []net.IPAddr
+		if ip := net.ParseIP(host); ip != nil {
+			ips = []net.IPAddr{{IP: ip}}
+		} else if ips, err = lookup(ctx, host); err != nil {
+			errs = append(errs, fmt.Errorf("failed to resolve peer hint %s: %w", hint, err))
+			continue
+		}
+
+		for _, ip := range ips {
+			if hintPort == port && local[ip.IP.String()] {
+				continue
+			}
+			addr := &net.TCPAddr{IP: ip.IP, Port: hintPort}
+			if !seen[addr.String()] {
+				seen[addr.String()] = true
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].String() < addrs[j].String() })
+	return addrs, errors.Join(errs...)
+}
+
+// splitPeerHint splits a hint into its host and port, port is used if the
+// hint has none
+func splitPeerHint(hint string, port int) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(hint)
+	if err != nil {
+		// No port, or an IPv6 address without brackets
+		host = strings.Trim(hint, "[]")
+		if host == "" {
+			return "", 0, fmt.Errorf("invalid peer hint %q", hint)
+		}
+		return host, port, nil
+	}
+
+	n, err := strconv.Atoi(portStr)
+	if err != nil || n < 1 || n > 65535 {
+		return "", 0, fmt.Errorf("invalid port in peer hint %q", hint)
+	}
+	return host, n, nil
+}
+
+// localIPs returns the addresses of this host's interfaces
+func localIPs() map[string]bool {
+	ips := make(map[string]bool)
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ips
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			ips[ipNet.IP.String()] = true
+		}
+	}
+	return ips
+}
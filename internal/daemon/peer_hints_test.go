@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePeerHints(t *testing.T) {
+	lookup := func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "silmaril.models.svc.cluster.local":
+			return []net.IPAddr{{IP: net.ParseIP("10.0.0.2")}, {IP: net.ParseIP("10.0.0.1")}, {IP: net.ParseIP("10.0.0.3")}}, nil
+		case "other":
+			return []net.IPAddr{{IP: net.ParseIP("10.0.1.1")}}, nil
+		}
+		return nil, fmt.Errorf("no such host")
+	}
+	// This pod is 10.0.0.1
+	local := map[string]bool{"10.0.0.1": true, "127.0.0.1": true}
+
+	addrs, err := resolvePeerHints(context.Background(), lookup, []string{
+		"silmaril.models.svc.cluster.local",
+		"other:7000",
+		"10.0.0.3",        // Already found through the service
+		"[fd00::1]:42069", // Our port, but not our address
+		"10.0.0.1:7000",   // Another daemon on this host
+		"missing",
+		"other:99999",
+	}, 42069, local)
+
+	var got []string
+	for _, addr := range addrs {
+		got = append(got, addr.String())
+	}
+	assert.Equal(t, []string{
+		"10.0.0.1:7000",
+		"10.0.0.2:42069",
+		"10.0.0.3:42069",
+		"10.0.1.1:7000",
+		"[fd00::1]:42069",
+	}, got)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing")
+	assert.Contains(t, err.Error(), "other:99999")
+}
+
+func TestSplitPeerHint(t *testing.T) {
+	host, port, err := splitPeerHint("fd00::1", 6881)
+	require.NoError(t, err)
+	assert.Equal(t, "fd00::1", host)
+	assert.Equal(t, 6881, port)
+
+	_, _, err = splitPeerHint("", 6881)
+	assert.Error(t, err)
+}
@@ -0,0 +1,229 @@
+package daemon
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/anacrolix/torrent"
+)
+
+// Source of the peers added for network.peer_hints, told apart from other
+// peers added directly
+const peerSourceHint torrent.PeerSource = "Sh"
+
+// Source of the peers added to a torrent by hand
+const peerSourceManual torrent.PeerSource = "Sm"
+
+// PeerSourceStats are the peers a torrent found through one source, such as
+// the DHT or peer exchange, and how many of them it could connect to
+type PeerSourceStats struct {
+	Source          string  `json:"source"`           // See peerSources
+	Found           int     `json:"found"`            // Distinct peers found this session
+	Connected       int     `json:"connected"`        // Of those, peers a connection was established with
+	Active          int     `json:"active"`           // Connected right now
+	SuccessRate     float64 `json:"success_rate"`     // Percentage of the found peers connected to
+	BytesDownloaded int64   `json:"bytes_downloaded"` // Useful data the peers sent
+}
+
+// peerSourceName returns the name of the source a peer was found through
+func peerSourceName(source torrent.PeerSource) string {
+	if name, ok := peerSources[source]; ok {
+		return name
+	}
+	return string(source)
+}
+
+// foundPeer is a peer address a torrent found, with the source it was found
+// through first and whether a connection to it was established
+type foundPeer struct {
+	source    string
+	connected bool
+}
+
+// torrentPeerSources are the peers a torrent found and what each source
+// sent
+type torrentPeerSources struct {
+	peers map[string]*foundPeer // By address
+	bytes map[string]int64      // By source
+}
+
+// peerSourceMeter counts the peers each torrent found per source, the ones
+// it connected to and the data they sent. It is fed by torrent client
+// callbacks, which run with the client locked, and by sampling the known
+// peers of each torrent, so it has a lock of its own.
+type peerSourceMeter struct {
+	mu       sync.Mutex
+	torrents map[string]*torrentPeerSources // By infohash
+}
+
+func newPeerSourceMeter() *peerSourceMeter {
+	return &peerSourceMeter{torrents: make(map[string]*torrentPeerSources)}
+}
+
+// Register counts the connections and useful data of peers by source
+func (m *peerSourceMeter) Register(cfg *torrent.ClientConfig) {
+	cfg.Callbacks.PeerConnAdded = append(cfg.Callbacks.PeerConnAdded, m.connected)
+	cfg.Callbacks.ReceivedUsefulData = append(cfg.Callbacks.ReceivedUsefulData, m.received)
+}
+
+func (m *peerSourceMeter) connected(pc *torrent.PeerConn) {
+	if pc.RemoteAddr == nil {
+		return
+	}
+	m.found(pc.Torrent().InfoHash().HexString(), pc.RemoteAddr.String(), peerSourceName(pc.Discovery), true)
+}
+
+func (m *peerSourceMeter) received(event torrent.ReceivedUsefulDataEvent) {
+	// Web seeds are counted by sourceMeter
+	if event.Peer.Network == "http" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sources := m.torrentLocked(event.Peer.Torrent().InfoHash().HexString())
+	sources.bytes[peerSourceName(event.Peer.Discovery)] += int64(len(event.Message.Piece))
+}
+
+// found records a peer of a torrent. A peer keeps the source it was found
+// through first, unless it is connected to through another.
+func (m *peerSourceMeter) found(infoHash, addr, source string, connected bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sources := m.torrentLocked(infoHash)
+	peer, ok := sources.peers[addr]
+	if !ok {
+		peer = &foundPeer{source: source}
+		sources.peers[addr] = peer
+	}
+	if connected {
+		if !peer.connected {
+			peer.source = source
+		}
+		peer.connected = true
+	}
+}
+
+func (m *peerSourceMeter) torrentLocked(infoHash string) *torrentPeerSources {
+	sources, ok := m.torrents[infoHash]
+	if !ok {
+		sources = &torrentPeerSources{peers: make(map[string]*foundPeer), bytes: make(map[string]int64)}
+		m.torrents[infoHash] = sources
+	}
+	return sources
+}
+
+// sample records the peers a torrent knows, connected or not
+func (m *peerSourceMeter) sample(t *torrent.Torrent) {
+	infoHash := t.InfoHash().HexString()
+	for _, peer := range t.KnownSwarm() {
+		if peer.Addr != nil {
+			m.found(infoHash, peer.Addr.String(), peerSourceName(peer.Source), false)
+		}
+	}
+}
+
+// stats returns the peers of a torrent by source, the sources that found
+// the most first. active holds the peers connected right now by source.
+func (m *peerSourceMeter) stats(infoHash string, active map[string]int) []PeerSourceStats {
+	m.mu.Lock()
+	bySource := make(map[string]*PeerSourceStats)
+	get := func(source string) *PeerSourceStats {
+		stats, ok := bySource[source]
+		if !ok {
+			stats = &PeerSourceStats{Source: source}
+			bySource[source] = stats
+		}
+		return stats
+	}
+	if sources, ok := m.torrents[infoHash]; ok {
+		for _, peer := range sources.peers {
+			stats := get(peer.source)
+			stats.Found++
+			if peer.connected {
+				stats.Connected++
+			}
+		}
+		for source, n := range sources.bytes {
+			get(source).BytesDownloaded = n
+		}
+	}
+	m.mu.Unlock()
+	for source, n := range active {
+		get(source).Active = n
+	}
+
+	return sortPeerSources(bySource)
+}
+
+// forget drops the peers of a torrent that was removed
+func (m *peerSourceMeter) forget(infoHash string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.torrents, infoHash)
+}
+
+// sortPeerSources computes the success rates and sorts the sources, the
+// ones that found the most peers first
+func sortPeerSources(bySource map[string]*PeerSourceStats) []PeerSourceStats {
+	result := make([]PeerSourceStats, 0, len(bySource))
+	for _, stats := range bySource {
+		stats.Found = max(stats.Found, stats.Connected, stats.Active)
+		if stats.Found > 0 {
+			stats.SuccessRate = float64(stats.Connected) * 100 / float64(stats.Found)
+		}
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Found != result[j].Found {
+			return result[i].Found > result[j].Found
+		}
+		return result[i].Source < result[j].Source
+	})
+	return result
+}
+
+// mergePeerSources adds up the peers of several torrents by source
+func mergePeerSources(torrents ...[]PeerSourceStats) []PeerSourceStats {
+	bySource := make(map[string]*PeerSourceStats)
+	for _, sources := range torrents {
+		for _, s := range sources {
+			total, ok := bySource[s.Source]
+			if !ok {
+				total = &PeerSourceStats{Source: s.Source}
+				bySource[s.Source] = total
+			}
+			total.Found += s.Found
+			total.Connected += s.Connected
+			total.Active += s.Active
+			total.BytesDownloaded += s.BytesDownloaded
+		}
+	}
+	return sortPeerSources(bySource)
+}
+
+// SamplePeerSources records the peers every torrent knows, so peers that
+// were found but never connected to count towards their source
+func (tm *TorrentManager) SamplePeerSources() {
+	for _, mt := range tm.GetAllTorrents() {
+		tm.peerSources.sample(mt.Torrent)
+	}
+}
+
+// peerSourceStats returns the peers of a torrent by source
+func (tm *TorrentManager) peerSourceStats(mt *ManagedTorrent) []PeerSourceStats {
+	tm.peerSources.sample(mt.Torrent)
+	active := make(map[string]int)
+	for _, pc := range mt.Torrent.PeerConns() {
+		active[peerSourceName(pc.Discovery)]++
+	}
+	return tm.peerSources.stats(mt.InfoHash, active)
+}
+
+// PeerSourceStats returns the peers of all torrents by source
+func (tm *TorrentManager) PeerSourceStats() []PeerSourceStats {
+	var torrents [][]PeerSourceStats
+	for _, mt := range tm.GetAllTorrents() {
+		torrents = append(torrents, tm.peerSourceStats(mt))
+	}
+	return mergePeerSources(torrents...)
+}
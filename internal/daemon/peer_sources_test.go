@@ -0,0 +1,49 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/anacrolix/torrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerSourceMeter(t *testing.T) {
+	m := newPeerSourceMeter()
+	m.found("abc", "10.0.0.1:6881", "dht", false)
+	m.found("abc", "10.0.0.2:6881", "dht", false)
+	m.found("abc", "10.0.0.3:6881", "pex", false)
+	m.found("abc", "10.0.0.4:6881", "pex", false)
+
+	// Peers keep the source that found them first, unless connected through
+	// another one
+	m.found("abc", "10.0.0.1:6881", "pex", false)
+	m.found("abc", "10.0.0.1:6881", "dht", true)
+	m.found("abc", "10.0.0.3:6881", "tracker", true)
+	m.found("abc", "10.0.0.9:50000", "incoming", true)
+	m.torrentLocked("abc").bytes["dht"] = 4096
+
+	sources := m.stats("abc", map[string]int{"dht": 1})
+	require.Len(t, sources, 4)
+	assert.Equal(t, PeerSourceStats{Source: "dht", Found: 2, Connected: 1, Active: 1, SuccessRate: 50, BytesDownloaded: 4096}, sources[0])
+	assert.Equal(t, PeerSourceStats{Source: "incoming", Found: 1, Connected: 1, SuccessRate: 100}, sources[1])
+	assert.Equal(t, PeerSourceStats{Source: "pex", Found: 1}, sources[2])
+	assert.Equal(t, "tracker", sources[3].Source)
+
+	m.forget("abc")
+	assert.Empty(t, m.stats("abc", nil))
+}
+
+func TestMergePeerSources(t *testing.T) {
+	merged := mergePeerSources(
+		[]PeerSourceStats{{Source: "dht", Found: 4, Connected: 1}, {Source: "pex", Found: 1, Connected: 1}},
+		[]PeerSourceStats{{Source: "dht", Found: 4, Connected: 3, BytesDownloaded: 100}},
+	)
+	require.Len(t, merged, 2)
+	assert.Equal(t, PeerSourceStats{Source: "dht", Found: 8, Connected: 4, SuccessRate: 50, BytesDownloaded: 100}, merged[0])
+	assert.Equal(t, 100.0, merged[1].SuccessRate)
+
+	assert.Equal(t, "hint", peerSourceName(peerSourceHint))
+	assert.Equal(t, "dht", peerSourceName(torrent.PeerSourceDhtAnnouncePeer))
+	assert.Equal(t, "Zz", peerSourceName("Zz"))
+}
@@ -0,0 +1,141 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anacrolix/torrent"
+)
+
+// ConnectedPeer is a peer connection of a torrent
+type ConnectedPeer struct {
+	Addr         string  `json:"addr"`
+	Client       string  `json:"client,omitempty"`
+	PeerID       string  `json:"peer_id,omitempty"`
+	Source       string  `json:"source"`  // How the peer was found, see peerSources
+	Network      string  `json:"network"` // tcp or utp
+	DownloadRate int64   `json:"download_rate"`
+	Progress     float64 `json:"progress"` // Percentage of the pieces the peer has
+	Seed         bool    `json:"seed"`
+	Encryption   bool    `json:"encryption"` // The peer prefers encrypted connections
+	Flags        string  `json:"flags"`
+}
+
+// TorrentPeers are the peers a torrent is connected to and how many more
+// are known but not connected
+type TorrentPeers struct {
+	InfoHash  string            `json:"info_hash"`
+	Name      string            `json:"name"`
+	Connected int               `json:"connected"`
+	Known     int               `json:"known"`
+	Peers     []ConnectedPeer   `json:"peers"`            // Fastest first
+	Sources   []PeerSourceStats `json:"sources"`          // Peers found by source, see PeerSourceStats
+	Manual    []string          `json:"manual,omitempty"` // Peers added by hand
+}
+
+// peerSources names the sources the torrent client reports peers from
+var peerSources = map[torrent.PeerSource]string{
+	torrent.PeerSourceTracker:         "tracker",
+	torrent.PeerSourceIncoming:        "incoming",
+	torrent.PeerSourceDhtGetPeers:     "dht",
+	torrent.PeerSourceDhtAnnouncePeer: "dht",
+	torrent.PeerSourcePex:             "pex",
+	torrent.PeerSourceDirect:          "direct",
+	torrent.PeerSourceUtHolepunch:     "holepunch",
+	peerSourceHint:                    "hint",
+	peerSourceManual:                  "manual",
+}
+
+// peerFlags summarizes a peer in a few letters: I incoming, D DHT, T
+// tracker, X PEX, H holepunch, P peer hint, M added by hand, U uTP, E
+// prefers encryption, S seed
+func peerFlags(peer ConnectedPeer) string {
+	var flags strings.Builder
+	switch peer.Source {
+	case "incoming":
+		flags.WriteByte('I')
+	case "dht":
+		flags.WriteByte('D')
+	case "tracker":
+		flags.WriteByte('T')
+	case "pex":
+		flags.WriteByte('X')
+	case "holepunch":
+		flags.WriteByte('H')
+	case "hint":
+		flags.WriteByte('P')
+	case "manual":
+		flags.WriteByte('M')
+	}
+	if peer.Network == "utp" {
+		flags.WriteByte('U')
+	}
+	if peer.Encryption {
+		flags.WriteByte('E')
+	}
+	if peer.Seed {
+		flags.WriteByte('S')
+	}
+	return flags.String()
+}
+
+// GetPeers returns the peers a torrent is connected to. Upload rates are
+// not listed per peer, the torrent client only counts what peers send us.
+func (tm *TorrentManager) GetPeers(infoHash string) (*TorrentPeers, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	mt, exists := tm.torrents[infoHash]
+	if !exists {
+		return nil, fmt.Errorf("torrent not found: %s", infoHash)
+	}
+
+	result := &TorrentPeers{
+		InfoHash: mt.InfoHash,
+		Name:     mt.Name,
+		Known:    len(mt.Torrent.KnownSwarm()),
+		Peers:    []ConnectedPeer{},
+	}
+	pieces := mt.Torrent.NumPieces()
+	for _, pc := range mt.Torrent.PeerConns() {
+		peer := ConnectedPeer{
+			DownloadRate: int64(pc.DownloadRate()),
+			Source:       peerSourceName(pc.Discovery),
+			Network:      "tcp",
+			Encryption:   pc.PeerPrefersEncryption,
+		}
+		if strings.HasPrefix(pc.Network, "udp") || strings.HasPrefix(pc.Network, "utp") {
+			peer.Network = "utp"
+		}
+		if pc.RemoteAddr != nil {
+			peer.Addr = pc.RemoteAddr.String()
+		}
+		if client, ok := pc.PeerClientName.Load().(string); ok {
+			peer.Client = client
+		}
+		if pc.PeerID != ([20]byte{}) {
+			peer.PeerID = fmt.Sprintf("%x", pc.PeerID[:])
+		}
+		if pieces > 0 {
+			have := int(pc.PeerPieces().GetCardinality())
+			peer.Progress = float64(have) * 100 / float64(pieces)
+			peer.Seed = have >= pieces
+		}
+		peer.Flags = peerFlags(peer)
+		result.Peers = append(result.Peers, peer)
+	}
+	result.Connected = len(result.Peers)
+	result.Sources = tm.peerSourceStats(mt)
+	if tm.state != nil {
+		result.Manual = tm.state.GetManualPeers(mt.InfoHash)
+	}
+
+	sort.Slice(result.Peers, func(i, j int) bool {
+		if result.Peers[i].DownloadRate != result.Peers[j].DownloadRate {
+			return result.Peers[i].DownloadRate > result.Peers[j].DownloadRate
+		}
+		return result.Peers[i].Addr < result.Peers[j].Addr
+	})
+	return result, nil
+}
@@ -0,0 +1,15 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerFlags(t *testing.T) {
+	assert.Equal(t, "IUES", peerFlags(ConnectedPeer{Source: "incoming", Network: "utp", Encryption: true, Seed: true}))
+	assert.Equal(t, "D", peerFlags(ConnectedPeer{Source: "dht", Network: "tcp"}))
+	assert.Equal(t, "P", peerFlags(ConnectedPeer{Source: "hint", Network: "tcp"}))
+	assert.Equal(t, "MS", peerFlags(ConnectedPeer{Source: "manual", Network: "tcp", Seed: true}))
+	assert.Equal(t, "", peerFlags(ConnectedPeer{Source: "direct", Network: "tcp"}))
+}
@@ -0,0 +1,154 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// ErrAlreadyRunning is returned when another daemon holds the PID file lock
+var ErrAlreadyRunning = errors.New("daemon is already running")
+
+// PIDFile is a locked file holding the PID of the running daemon. The lock is
+// held for the lifetime of the daemon, so at most one daemon can use a base
+// directory at a time and a PID file left behind by a crashed daemon is never
+// mistaken for a running one.
+type PIDFile struct {
+	path string
+	file *os.File
+}
+
+// DefaultPIDFilePath returns the PID file location in the daemon directory
+func DefaultPIDFilePath() string {
+	return filepath.Join(storage.GetBaseDir(), "daemon", "daemon.pid")
+}
+
+// AcquirePIDFile locks the PID file at path and writes the current PID to it.
+// It returns ErrAlreadyRunning if another process holds the lock.
+func AcquirePIDFile(path string) (*PIDFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create PID file directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PID file: %w", err)
+	}
+
+	if err := lockFile(file); err != nil {
+		pid, _ := readPID(file)
+		file.Close()
+		if pid > 0 {
+			return nil, fmt.Errorf("%w (PID %d)", ErrAlreadyRunning, pid)
+		}
+		return nil, ErrAlreadyRunning
+	}
+
+	// We hold the lock, so any PID still in the file belongs to a daemon that
+	// exited without cleaning up
+	if pid, err := readPID(file); err == nil && pid != os.Getpid() {
+		fmt.Printf("[Daemon] Recovered stale PID file left by PID %d\n", pid)
+	}
+
+	if err := writePID(file, os.Getpid()); err != nil {
+		unlockFile(file)
+		file.Close()
+		return nil, err
+	}
+
+	return &PIDFile{path: path, file: file}, nil
+}
+
+// Path returns the location of the PID file
+func (p *PIDFile) Path() string {
+	return p.path
+}
+
+// Release removes the PID file and drops the lock
+func (p *PIDFile) Release() error {
+	if p.file == nil {
+		return nil
+	}
+
+	// Remove while still holding the lock so a new daemon never sees our PID.
+	// Windows does not allow removing open files, so retry after closing.
+	err := os.Remove(p.path)
+	unlockFile(p.file)
+	p.file.Close()
+	p.file = nil
+	if err != nil && !os.IsNotExist(err) {
+		err = os.Remove(p.path)
+	}
+
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove PID file: %w", err)
+	}
+	return nil
+}
+
+// RunningPID returns the PID of the daemon holding the PID file at path, or 0
+// if no daemon holds it. A PID file without a lock holder is stale.
+func RunningPID(path string) (int, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to open PID file: %w", err)
+	}
+	defer file.Close()
+
+	if err := lockFile(file); err == nil {
+		unlockFile(file)
+		return 0, nil
+	}
+
+	pid, err := readPID(file)
+	if err != nil {
+		return 0, err
+	}
+	return pid, nil
+}
+
+// TerminateProcess asks the process with the given PID to shut down
+func TerminateProcess(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("failed to find process %d: %w", pid, err)
+	}
+	if err := terminate(process); err != nil {
+		return fmt.Errorf("failed to signal process %d: %w", pid, err)
+	}
+	return nil
+}
+
+func readPID(file *os.File) (int, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to read PID file: %w", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PID file: %w", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid PID file contents: %w", err)
+	}
+	return pid, nil
+}
+
+func writePID(file *os.File, pid int) error {
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	if _, err := file.WriteAt([]byte(fmt.Sprintf("%d\n", pid)), 0); err != nil {
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	return file.Sync()
+}
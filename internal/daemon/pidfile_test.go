@@ -0,0 +1,60 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPIDFileSingleInstance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon", "daemon.pid")
+
+	pidFile, err := AcquirePIDFile(path)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid()), strings.TrimSpace(string(data)))
+
+	pid, err := RunningPID(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+
+	// A second daemon must not be able to take the lock
+	_, err = AcquirePIDFile(path)
+	assert.ErrorIs(t, err, ErrAlreadyRunning)
+
+	require.NoError(t, pidFile.Release())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	pid, err = RunningPID(path)
+	require.NoError(t, err)
+	assert.Zero(t, pid)
+}
+
+func TestPIDFileStaleRecovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.pid")
+
+	// A PID file left behind by a crashed daemon is not locked
+	require.NoError(t, os.WriteFile(path, []byte("999999\n"), 0644))
+
+	pid, err := RunningPID(path)
+	require.NoError(t, err)
+	assert.Zero(t, pid)
+
+	pidFile, err := AcquirePIDFile(path)
+	require.NoError(t, err)
+	defer pidFile.Release()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid()), strings.TrimSpace(string(data)))
+}
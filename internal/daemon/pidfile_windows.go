@@ -0,0 +1,41 @@
+//go:build windows
+
+package daemon
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile emulates the PID file lock on Windows by checking whether the
+// process recorded in file is still alive
+func lockFile(file *os.File) error {
+	pid, err := readPID(file)
+	if err != nil || pid == os.Getpid() {
+		return nil
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+	process.Release()
+	return ErrAlreadyRunning
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
+
+func terminate(process *os.Process) error {
+	return process.Kill()
+}
+
+// DetachedProcAttr returns process attributes that start a child without a
+// console, so it keeps running after the starting terminal goes away
+func DetachedProcAttr() *syscall.SysProcAttr {
+	const detachedProcess = 0x00000008
+	return &syscall.SysProcAttr{
+		CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP,
+		HideWindow:    true,
+	}
+}
@@ -0,0 +1,134 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/anacrolix/torrent/metainfo"
+	torrentStorage "github.com/anacrolix/torrent/storage"
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// pieceStore records which pieces of each torrent were verified, so a
+// restarted daemon only hashes the pieces it knows nothing about. Pieces are
+// recorded in a database in the model directory, or in the daemon's own if
+// the directory is read-only, as it is for models seeded in place.
+type pieceStore struct {
+	dir string // Of the daemon's database
+
+	mu       sync.Mutex
+	daemonDB torrentStorage.PieceCompletion // Opened when first used
+
+	// Torrents whose recorded pieces are not trusted until hashed again,
+	// and the pieces hashed since
+	recheck map[metainfo.Hash]bool
+	checked map[metainfo.PieceKey]bool
+}
+
+func newPieceStore(dir string) *pieceStore {
+	return &pieceStore{
+		dir:     dir,
+		recheck: make(map[metainfo.Hash]bool),
+		checked: make(map[metainfo.PieceKey]bool),
+	}
+}
+
+// piecesDir returns the directory of the daemon's piece completion database
+func piecesDir() string {
+	return filepath.Join(storage.GetBaseDir(), "daemon", "pieces")
+}
+
+// forDir returns the piece completion of the torrents with files in dir,
+// kept in a database in dir if it can be written
+func (s *pieceStore) forDir(dir string) torrentStorage.PieceCompletion {
+	if err := os.MkdirAll(dir, 0755); err == nil {
+		if completion, err := torrentStorage.NewDefaultPieceCompletionForDir(dir); err == nil {
+			return storeCompletion{PieceCompletion: completion, store: s}
+		}
+	}
+	return s.shared()
+}
+
+// shared returns the piece completion kept in the daemon's database. The
+// database stays open until the store is closed, if it can't be opened
+// pieces are only remembered until the daemon stops.
+func (s *pieceStore) shared() torrentStorage.PieceCompletion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.daemonDB == nil {
+		completion, err := torrentStorage.NewBoltPieceCompletion(s.dir)
+		if err != nil {
+			fmt.Printf("[Storage] Verified pieces kept in memory: %v\n", err)
+			completion = torrentStorage.NewMapPieceCompletion()
+		}
+		s.daemonDB = completion
+	}
+	return storeCompletion{PieceCompletion: s.daemonDB, store: s, shared: true}
+}
+
+// Recheck makes the pieces recorded for infoHash unknown until they are
+// hashed again, so the torrent is verified in full when added
+func (s *pieceStore) Recheck(infoHash metainfo.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recheck[infoHash] = true
+}
+
+// trusted reports whether the recorded completion of a piece can be used
+func (s *pieceStore) trusted(pk metainfo.PieceKey) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.recheck[pk.InfoHash] || s.checked[pk]
+}
+
+// hashed records that a piece was hashed since the daemon started
+func (s *pieceStore) hashed(pk metainfo.PieceKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.recheck[pk.InfoHash] {
+		s.checked[pk] = true
+	}
+}
+
+// Close closes the daemon's database if it was opened
+func (s *pieceStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.daemonDB == nil {
+		return nil
+	}
+	err := s.daemonDB.Close()
+	s.daemonDB = nil
+	return err
+}
+
+// storeCompletion is a piece completion handed to the storage of torrents,
+// hiding the pieces of torrents being rechecked until they are hashed
+type storeCompletion struct {
+	torrentStorage.PieceCompletion
+	store  *pieceStore
+	shared bool // Closed with the store, not with a torrent
+}
+
+func (c storeCompletion) Get(pk metainfo.PieceKey) (torrentStorage.Completion, error) {
+	if !c.store.trusted(pk) {
+		return torrentStorage.Completion{}, nil
+	}
+	return c.PieceCompletion.Get(pk)
+}
+
+func (c storeCompletion) Set(pk metainfo.PieceKey, complete bool) error {
+	c.store.hashed(pk)
+	return c.PieceCompletion.Set(pk, complete)
+}
+
+func (c storeCompletion) Close() error {
+	if c.shared {
+		return nil
+	}
+	return c.PieceCompletion.Close()
+}
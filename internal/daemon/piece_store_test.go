@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+	torrentStorage "github.com/anacrolix/torrent/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPieceStoreRemembersPieces(t *testing.T) {
+	storeDir, modelDir := t.TempDir(), t.TempDir()
+	pk := metainfo.PieceKey{InfoHash: metainfo.HashBytes([]byte("model")), Index: 3}
+
+	pieces := newPieceStore(storeDir)
+	require.NoError(t, pieces.forDir(modelDir).Set(pk, true))
+	require.NoError(t, pieces.Close())
+
+	// A restarted daemon trusts the pieces verified before
+	pieces = newPieceStore(storeDir)
+	defer pieces.Close()
+	completion, err := pieces.forDir(modelDir).Get(pk)
+	require.NoError(t, err)
+	assert.Equal(t, torrentStorage.Completion{Complete: true, Ok: true}, completion)
+}
+
+func TestPieceStoreUnwritableDir(t *testing.T) {
+	// A directory that can't be created, like a read-only one, records its
+	// pieces in the daemon's database
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+	modelDir := filepath.Join(file, "model")
+	storeDir := t.TempDir()
+	pk := metainfo.PieceKey{InfoHash: metainfo.HashBytes([]byte("model")), Index: 0}
+
+	pieces := newPieceStore(storeDir)
+	require.NoError(t, pieces.forDir(modelDir).Set(pk, true))
+	require.NoError(t, pieces.Close())
+
+	pieces = newPieceStore(storeDir)
+	defer pieces.Close()
+	completion, err := pieces.forDir(modelDir).Get(pk)
+	require.NoError(t, err)
+	assert.True(t, completion.Complete)
+
+	// Closing the storage of a torrent leaves the database open
+	require.NoError(t, pieces.forDir(modelDir).Close())
+	_, err = pieces.shared().Get(pk)
+	assert.NoError(t, err)
+}
+
+func TestPieceStoreRecheck(t *testing.T) {
+	infoHash := metainfo.HashBytes([]byte("model"))
+	other := metainfo.HashBytes([]byte("other"))
+	pieces := newPieceStore(t.TempDir())
+	defer pieces.Close()
+
+	completion := pieces.shared()
+	for _, ih := range []metainfo.Hash{infoHash, other} {
+		require.NoError(t, completion.Set(metainfo.PieceKey{InfoHash: ih, Index: 0}, true))
+		require.NoError(t, completion.Set(metainfo.PieceKey{InfoHash: ih, Index: 1}, true))
+	}
+
+	// Pieces of a rechecked torrent are unknown until hashed again
+	pieces.Recheck(infoHash)
+	got, err := completion.Get(metainfo.PieceKey{InfoHash: infoHash, Index: 0})
+	require.NoError(t, err)
+	assert.False(t, got.Ok)
+
+	require.NoError(t, completion.Set(metainfo.PieceKey{InfoHash: infoHash, Index: 0}, false))
+	got, err = completion.Get(metainfo.PieceKey{InfoHash: infoHash, Index: 0})
+	require.NoError(t, err)
+	assert.Equal(t, torrentStorage.Completion{Complete: false, Ok: true}, got)
+
+	got, err = completion.Get(metainfo.PieceKey{InfoHash: infoHash, Index: 1})
+	require.NoError(t, err)
+	assert.False(t, got.Ok)
+
+	// Other torrents are not affected
+	got, err = completion.Get(metainfo.PieceKey{InfoHash: other, Index: 1})
+	require.NoError(t, err)
+	assert.True(t, got.Complete)
+}
@@ -0,0 +1,310 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// How often the pinning worker looks whether a check is due
+const pinningCheckInterval = time.Minute
+
+// Status of a pinned model
+const (
+	PinDownloading = "downloading"
+	PinSeeding     = "seeding"
+	PinStopped     = "stopped" // Its torrent isn't running
+	PinRemoved     = "removed" // Removed by hand, not pinned again
+)
+
+// pinner runs one pinning check at a time
+type pinner struct {
+	mu        sync.Mutex
+	last      time.Time
+	lastError string
+}
+
+// PinningPolicy is the community pinning config in effect
+type PinningPolicy struct {
+	MinSeeders    int           `json:"min_seeders"`
+	MaxDiskBytes  int64         `json:"max_disk_bytes"`
+	MaxModelBytes int64         `json:"max_model_bytes,omitempty"`
+	MaxDownloads  int           `json:"max_downloads"`
+	Models        []string      `json:"models,omitempty"`
+	Interval      time.Duration `json:"-"`
+}
+
+// PinningReport is what community pinning holds for the network
+type PinningReport struct {
+	Enabled   bool                `json:"enabled"`
+	Policy    PinningPolicy       `json:"policy"`
+	UsedBytes int64               `json:"used_bytes"`
+	LastCheck *time.Time          `json:"last_check,omitempty"`
+	LastError string              `json:"last_error,omitempty"`
+	Models    []PinnedModelStatus `json:"models"`
+}
+
+// PinnedModelStatus is a pinned model and how its torrent is doing
+type PinnedModelStatus struct {
+	PinnedModel
+	Status   string  `json:"status"`
+	Progress float64 `json:"progress"`
+	Uploaded int64   `json:"uploaded"`
+	Peers    int     `json:"peers"`
+}
+
+// pinningPolicy returns the pinning config and whether pinning is enabled
+func (d *Daemon) pinningPolicy() (PinningPolicy, bool) {
+	policy := PinningPolicy{MinSeeders: 3, MaxDownloads: 1, Interval: time.Hour}
+	if d.config == nil {
+		return policy, false
+	}
+	const gb = 1024 * 1024 * 1024
+	if n := d.config.GetInt("pinning.min_seeders"); n > 0 {
+		policy.MinSeeders = n
+	}
+	if n := d.config.GetInt("pinning.max_downloads"); n > 0 {
+		policy.MaxDownloads = n
+	}
+	if n := d.config.GetInt("pinning.interval_minutes"); n > 0 {
+		policy.Interval = time.Duration(n) * time.Minute
+	}
+	policy.MaxDiskBytes = int64(d.config.GetInt("pinning.max_disk_gb")) * gb
+	policy.MaxModelBytes = int64(d.config.GetInt("pinning.max_model_size_gb")) * gb
+	policy.Models = d.config.GetStringSlice("pinning.models")
+	return policy, d.config.GetBool("pinning.enabled")
+}
+
+// pinningWorker pins catalog models every pinning.interval_minutes while
+// pinning is enabled
+func (d *Daemon) pinningWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(pinningCheckInterval)
+	defer ticker.Stop()
+
+	// Give the DHT time to fetch the catalog before the first check
+	nextCheck := time.Now().Add(2 * time.Minute)
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.refreshPins()
+			policy, enabled := d.pinningPolicy()
+			if enabled && time.Now().After(nextCheck) {
+				d.checkPinning(policy)
+				nextCheck = time.Now().Add(policy.Interval)
+			}
+		}
+	}
+}
+
+// checkPinning probes the swarms of the catalog models and starts
+// downloading the ones too few peers seed, as far as the policy allows
+func (d *Daemon) checkPinning(policy PinningPolicy) {
+	d.pinning.mu.Lock()
+	defer d.pinning.mu.Unlock()
+	d.pinning.last = time.Now()
+
+	if d.dhtManager == nil || d.torrentManager == nil {
+		return
+	}
+	catalog, err := d.dhtManager.SearchModels(types.SearchFilter{Pattern: "*", CheckHealth: true})
+	if err != nil {
+		d.pinning.lastError = err.Error()
+		fmt.Printf("[Pinning] Failed to read the catalog: %v\n", err)
+		return
+	}
+	d.pinning.lastError = ""
+
+	for _, model := range d.pinCandidates(policy, catalog) {
+		modelPath, err := modelPathFor(model.Name)
+		if err != nil {
+			continue
+		}
+		mt, err := d.fetchModel(model.Name, strings.ToLower(model.InfoHash), modelPath)
+		if err != nil {
+			d.pinning.lastError = fmt.Sprintf("%s: %v", model.Name, err)
+			fmt.Printf("[Pinning] Failed to download %s: %v\n", model.Name, err)
+			continue
+		}
+		d.state.PinModel(&PinnedModel{
+			Model:    model.Name,
+			InfoHash: strings.ToLower(mt.InfoHash),
+			Version:  model.Version,
+			Size:     model.Size,
+			Seeders:  model.Seeders,
+			PinnedAt: time.Now(),
+		})
+		d.events.Publish(EventModelPinned, model.Name, "pinning version %s, %d seeders", displayVersion(model.Version), model.Seeders)
+	}
+	if err := d.state.Save(); err != nil {
+		fmt.Printf("[Pinning] Warning: failed to save state: %v\n", err)
+	}
+}
+
+// pinCandidates returns the catalog models to pin next, fewest seeders
+// first. Models nobody seeds can't be downloaded, and models that are local,
+// were pinned before, need their license accepted or don't fit the disk
+// budget are skipped.
+func (d *Daemon) pinCandidates(policy PinningPolicy, catalog []*types.ModelAnnouncement) []*types.ModelAnnouncement {
+	pinned := make(map[string]bool)
+	var used int64
+	downloading := 0
+	for _, pin := range d.state.GetPinnedModels() {
+		pinned[pin.Model] = true
+		if pin.RemovedAt != nil {
+			continue
+		}
+		used += pin.Size
+		// Cancelled downloads don't take a download slot
+		if pin.CompletedAt == nil && d.torrentManager != nil {
+			if _, ok := d.torrentManager.GetTorrent(pin.InfoHash); ok {
+				downloading++
+			}
+		}
+	}
+
+	var candidates []*types.ModelAnnouncement
+	for _, model := range catalog {
+		if model.InfoHash == "" || pinned[model.Name] || !matchPinPatterns(policy.Models, model.Name) {
+			continue
+		}
+		if model.Seeders < 1 || model.Seeders >= policy.MinSeeders {
+			continue
+		}
+		if model.Size <= 0 || (policy.MaxModelBytes > 0 && model.Size > policy.MaxModelBytes) {
+			continue
+		}
+		if d.CheckLicense(model, false) != nil {
+			continue
+		}
+		modelPath, err := modelPathFor(model.Name)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Lstat(modelPath); err == nil {
+			continue
+		}
+		candidates = append(candidates, model)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Seeders != candidates[j].Seeders {
+			return candidates[i].Seeders < candidates[j].Seeders
+		}
+		return candidates[i].Size < candidates[j].Size
+	})
+
+	var selected []*types.ModelAnnouncement
+	seen := make(map[string]bool)
+	for _, model := range candidates {
+		if downloading >= policy.MaxDownloads {
+			break
+		}
+		// The catalog lists every version, pin one per model
+		if seen[model.Name] || used+model.Size > policy.MaxDiskBytes {
+			continue
+		}
+		seen[model.Name] = true
+		used += model.Size
+		downloading++
+		selected = append(selected, model)
+	}
+	return selected
+}
+
+// matchPinPatterns reports whether name matches one of the patterns, or
+// any name if there are none
+func matchPinPatterns(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshPins marks pinned models that finished downloading, and the ones
+// removed by hand so they aren't pinned again
+func (d *Daemon) refreshPins() {
+	if d.torrentManager == nil {
+		return
+	}
+	for _, pin := range d.state.GetPinnedModels() {
+		if pin.RemovedAt != nil {
+			continue
+		}
+		if mt, ok := d.torrentManager.GetTorrent(pin.InfoHash); ok {
+			if pin.CompletedAt == nil && mt.Complete() {
+				now := time.Now()
+				d.state.UpdatePinnedModel(pin.Model, func(p *PinnedModel) { p.CompletedAt = &now })
+				d.events.Publish(EventModelPinned, pin.Model, "downloaded, seeding it for the network")
+			}
+			continue
+		}
+		if modelPath, err := modelPathFor(pin.Model); err == nil {
+			if _, err := os.Lstat(modelPath); os.IsNotExist(err) {
+				now := time.Now()
+				d.state.UpdatePinnedModel(pin.Model, func(p *PinnedModel) { p.RemovedAt = &now })
+				fmt.Printf("[Pinning] %s was removed, not pinning it again\n", pin.Model)
+			}
+		}
+	}
+}
+
+// PinningReport returns the pinning policy and the models pinned for the
+// network, newest first
+func (d *Daemon) PinningReport() *PinningReport {
+	policy, enabled := d.pinningPolicy()
+	report := &PinningReport{
+		Enabled: enabled,
+		Policy:  policy,
+		Models:  []PinnedModelStatus{},
+	}
+
+	d.pinning.mu.Lock()
+	if !d.pinning.last.IsZero() {
+		last := d.pinning.last
+		report.LastCheck = &last
+	}
+	report.LastError = d.pinning.lastError
+	d.pinning.mu.Unlock()
+
+	for _, pin := range d.state.GetPinnedModels() {
+		status := PinnedModelStatus{PinnedModel: *pin, Status: PinStopped}
+		if pin.RemovedAt != nil {
+			status.Status = PinRemoved
+		} else {
+			report.UsedBytes += pin.Size
+			if d.torrentManager != nil {
+				if mt, ok := d.torrentManager.GetTorrent(pin.InfoHash); ok {
+					status.Status = PinDownloading
+					if mt.Complete() {
+						status.Status = PinSeeding
+					}
+					if completed, total := mt.WantedBytes(); total > 0 {
+						status.Progress = float64(completed) / float64(total) * 100
+					}
+					status.Uploaded = mt.Uploaded()
+					status.Peers = mt.Torrent.Stats().ActivePeers
+				}
+			}
+		}
+		report.Models = append(report.Models, status)
+	}
+	sort.Slice(report.Models, func(i, j int) bool {
+		return report.Models[i].PinnedAt.After(report.Models[j].PinnedAt)
+	})
+	return report
+}
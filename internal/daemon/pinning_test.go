@@ -0,0 +1,87 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinCandidates(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	writeTestModel(t, "org/local")
+	removedAt := time.Now()
+	d.state.PinModel(&PinnedModel{Model: "org/removed", InfoHash: "rrrr", Size: 100, RemovedAt: &removedAt})
+	d.state.PinModel(&PinnedModel{Model: "org/pinned", InfoHash: "pppp", Size: 300})
+
+	catalog := []*types.ModelAnnouncement{
+		{Name: "org/two", InfoHash: "2222", Size: 200, Seeders: 2},
+		{Name: "org/one", InfoHash: "1111", Size: 500, Seeders: 1},
+		{Name: "org/one-small", InfoHash: "1112", Size: 100, Seeders: 1},
+		{Name: "org/local", InfoHash: "aaaa", Size: 100, Seeders: 1},
+		{Name: "org/removed", InfoHash: "rrrr", Size: 100, Seeders: 1},
+		{Name: "org/pinned", InfoHash: "pppp", Size: 300, Seeders: 1},
+		{Name: "org/unseeded", InfoHash: "0000", Size: 100, Seeders: 0},
+		{Name: "org/healthy", InfoHash: "3333", Size: 100, Seeders: 3},
+		{Name: "org/huge", InfoHash: "hhhh", Size: 5000, Seeders: 1},
+		{Name: "org/gated", InfoHash: "gggg", Size: 100, Seeders: 1, RequireLicenseAcceptance: true, License: "llama3"},
+		{Name: "other/one", InfoHash: "oooo", Size: 100, Seeders: 1},
+	}
+	policy := PinningPolicy{
+		MinSeeders:    3,
+		MaxDiskBytes:  1100,
+		MaxModelBytes: 1000,
+		MaxDownloads:  3,
+		Models:        []string{"org/*"},
+	}
+
+	names := func(models []*types.ModelAnnouncement) []string {
+		var names []string
+		for _, m := range models {
+			names = append(names, m.Name)
+		}
+		return names
+	}
+
+	// Fewest seeders first, then smallest, until the disk budget is spent.
+	// Models pinned already take 300 of it.
+	assert.Equal(t, []string{"org/one-small", "org/one", "org/two"}, names(d.pinCandidates(policy, catalog)))
+
+	policy.MaxDiskBytes = 700
+	assert.Equal(t, []string{"org/one-small", "org/two"}, names(d.pinCandidates(policy, catalog)))
+
+	policy.MaxDownloads = 1
+	assert.Equal(t, []string{"org/one-small"}, names(d.pinCandidates(policy, catalog)))
+
+	policy.Models = nil
+	policy.MaxDownloads = 5
+	assert.Contains(t, names(d.pinCandidates(policy, catalog)), "other/one")
+}
+
+func TestPinningReport(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	completedAt := time.Now()
+	d.state.PinModel(&PinnedModel{Model: "org/old", InfoHash: "AAAA", Size: 100, PinnedAt: time.Now().Add(-time.Hour), CompletedAt: &completedAt})
+	d.state.PinModel(&PinnedModel{Model: "org/gone", InfoHash: "bbbb", Size: 200, PinnedAt: time.Now(), RemovedAt: &completedAt})
+
+	report := d.PinningReport()
+	assert.False(t, report.Enabled)
+	assert.Equal(t, int64(100), report.UsedBytes, "removed models don't count")
+	require.Len(t, report.Models, 2)
+	assert.Equal(t, "org/gone", report.Models[0].Model)
+	assert.Equal(t, PinRemoved, report.Models[0].Status)
+	assert.Equal(t, PinStopped, report.Models[1].Status)
+
+	// Pinned models are seeded without limits
+	assert.True(t, d.state.IsPinned("aaaa"))
+	assert.False(t, d.state.IsPinned("bbbb"))
+}
+
+func TestMatchPinPatterns(t *testing.T) {
+	assert.True(t, matchPinPatterns(nil, "org/model"))
+	assert.True(t, matchPinPatterns([]string{"meta-llama/*"}, "meta-llama/Llama-3.1-8B"))
+	assert.True(t, matchPinPatterns([]string{"x/*", "org/model"}, "org/model"))
+	assert.False(t, matchPinPatterns([]string{"meta-llama/*"}, "org/model"))
+}
@@ -0,0 +1,297 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// PoolMove is a model moved, or to be moved, to another storage pool
+type PoolMove struct {
+	Model string `json:"model"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Size  int64  `json:"size"`
+	Error string `json:"error,omitempty"`
+}
+
+// PoolUsage describes a storage pool and the local models in it
+type PoolUsage struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	MinSize int64  `json:"min_size,omitempty"`
+	MaxSize int64  `json:"max_size,omitempty"`
+	Free    int64  `json:"free"` // -1 if unknown
+	Models  int    `json:"models"`
+	Size    int64  `json:"size"`
+}
+
+// RebalanceReport lists the storage pools and the models moved between them
+type RebalanceReport struct {
+	DryRun bool        `json:"dry_run"`
+	Pools  []PoolUsage `json:"pools"`
+	Moves  []PoolMove  `json:"moves"`
+	Moved  int64       `json:"moved"` // Bytes
+}
+
+// storagePools returns the storage pools configured in cfg, in name order.
+// Pools without an absolute path, or inside the models directory where they
+// would be taken for models, are left out.
+func storagePools(cfg *config.Config) []storage.Pool {
+	if cfg == nil || len(cfg.Storage.Pools) == 0 {
+		return nil
+	}
+
+	modelsDir := storage.GetModelsDir()
+	pools := make([]storage.Pool, 0, len(cfg.Storage.Pools))
+	for name, pool := range cfg.Storage.Pools {
+		path := filepath.Clean(pool.Path)
+		if !filepath.IsAbs(path) {
+			fmt.Printf("[Storage] Ignoring pool %s, its path %q is not absolute\n", name, pool.Path)
+			continue
+		}
+		if _, inside := storage.RelocatePath(path, modelsDir, modelsDir); inside {
+			fmt.Printf("[Storage] Ignoring pool %s, %s is inside the models directory\n", name, path)
+			continue
+		}
+		pools = append(pools, storage.Pool{
+			Name:    name,
+			Path:    path,
+			MinSize: int64(pool.MinModelSizeGB) << 30,
+			MaxSize: int64(pool.MaxModelSizeGB) << 30,
+		})
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+	return pools
+}
+
+// PoolPathFor returns the directory a new download of the model called name,
+// of size bytes, is kept in, or "" to keep it in the models directory.
+// Models already in the models directory, such as resumed downloads, stay
+// where they are.
+func (d *Daemon) PoolPathFor(name string, size int64) string {
+	modelPath, err := modelPathFor(name)
+	if err != nil {
+		return ""
+	}
+	if _, err := os.Lstat(modelPath); err == nil {
+		return ""
+	}
+	pool := storage.PlaceModel(storagePools(d.config), size)
+	if pool == nil {
+		return ""
+	}
+	poolPath, err := pool.ModelDir(name)
+	if err != nil {
+		return ""
+	}
+	return poolPath
+}
+
+// RebalanceStorage moves the local models kept in a pool whose size limits
+// don't take them, as after the pools were changed or for models downloaded
+// before their size was known, to the pool they belong in. Models stay in
+// their pool while it takes them. With dryRun the moves are only listed.
+// Models imported in place or downloaded to a directory of the user's
+// choosing are left alone.
+func (d *Daemon) RebalanceStorage(dryRun bool) *RebalanceReport {
+	pools := storagePools(d.config)
+	report := &RebalanceReport{DryRun: dryRun, Moves: []PoolMove{}}
+
+	usage := make(map[string]*PoolUsage)
+	report.Pools = append(report.Pools, PoolUsage{Name: storage.DefaultPool, Path: storage.GetModelsDir()})
+	for _, pool := range pools {
+		report.Pools = append(report.Pools, PoolUsage{Name: pool.Name, Path: pool.Path, MinSize: pool.MinSize, MaxSize: pool.MaxSize})
+	}
+	for i := range report.Pools {
+		usage[report.Pools[i].Name] = &report.Pools[i]
+	}
+
+	var names []string
+	if d.registry != nil {
+		names = d.registry.ListModels()
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		modelPath, err := modelPathFor(name)
+		if err != nil {
+			continue
+		}
+		current, managed := storage.PoolOf(pools, name, modelPath)
+		if !managed {
+			continue
+		}
+		size := d.modelSize(name, modelPath)
+
+		target := current
+		if current == nil || !current.Takes(size) {
+			target = storage.PlaceModel(pools, size)
+		}
+		from, to := poolName(current), poolName(target)
+		if from == to {
+			usage[from].Models++
+			usage[from].Size += size
+			continue
+		}
+
+		move := PoolMove{Model: name, From: from, To: to, Size: size}
+		if !dryRun {
+			if err := d.moveModelToPool(name, modelPath, current, target); err != nil {
+				move.Error = err.Error()
+				to = from
+			} else {
+				report.Moved += size
+				d.reloadRegistryModels(name)
+				fmt.Printf("[Storage] Moved %s from pool %s to %s\n", name, from, to)
+			}
+		}
+		usage[to].Models++
+		usage[to].Size += size
+		report.Moves = append(report.Moves, move)
+	}
+
+	for i := range report.Pools {
+		free, err := storage.FreeSpace(report.Pools[i].Path)
+		if err != nil {
+			free = -1
+		}
+		report.Pools[i].Free = free
+	}
+	return report
+}
+
+// modelSize returns the size of the local model called name, from its
+// manifest or else from its files
+func (d *Daemon) modelSize(name, modelPath string) int64 {
+	if manifest, err := d.registry.GetManifest(name); err == nil && manifest.TotalSize > 0 {
+		return manifest.TotalSize
+	}
+	return storage.GetDirSize(storage.ResolveModelDir(modelPath))
+}
+
+// poolName names pool, nil is the models directory
+func poolName(pool *storage.Pool) string {
+	if pool == nil {
+		return storage.DefaultPool
+	}
+	return pool.Name
+}
+
+// moveModelToPool moves the files of a local model from one pool to another,
+// nil being the models directory. The torrents seeding the model are stopped
+// while its files move and seeded again from the same model directory.
+func (d *Daemon) moveModelToPool(name, modelPath string, from, to *storage.Pool) error {
+	d.aliasMu.Lock()
+	defer d.aliasMu.Unlock()
+
+	if err := d.checkRenamable(name, modelPath); err != nil {
+		return err
+	}
+
+	var detached []*DetachedTorrent
+	var storagePaths []string
+	if d.torrentManager != nil {
+		for _, mt := range d.torrentManager.GetAllTorrents() {
+			storagePath := filepath.Clean(mt.StoragePath)
+			if storagePath != modelPath {
+				continue
+			}
+			dt, err := d.torrentManager.DetachTorrent(mt.InfoHash)
+			if err != nil {
+				d.reattachTorrents(detached, storagePaths, name)
+				return fmt.Errorf("failed to stop torrent %s: %w", mt.InfoHash, err)
+			}
+			detached = append(detached, dt)
+			storagePaths = append(storagePaths, storagePath)
+		}
+	}
+	defer d.reattachTorrents(detached, storagePaths, name)
+
+	// Models moving into the models directory are put together next to it,
+	// where they are not taken for a model until complete
+	src := storage.ResolveModelDir(modelPath)
+	stagingDir := filepath.Join(storage.GetBaseDir(), "staging")
+	dst, err := modelname.Join(stagingDir, name)
+	if to != nil {
+		dst, err = to.ModelDir(name)
+	}
+	if err != nil {
+		return err
+	}
+	if err := placeModelFiles(modelPath, src, dst, to != nil); err != nil {
+		return err
+	}
+	if from != nil {
+		removeEmptyParents(filepath.Dir(src), from.Path)
+	}
+	if to == nil {
+		removeEmptyParents(filepath.Dir(dst), stagingDir)
+	}
+	return nil
+}
+
+// placeModelFiles moves the files of the model at modelPath from src to dst,
+// renaming them within a filesystem and copying them otherwise, then puts a
+// link to dst at modelPath, or without link dst itself. The model's files
+// are complete in one place or the other if the move fails, and a copy left
+// by an interrupted move is picked up by the next one.
+func placeModelFiles(modelPath, src, dst string, link bool) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(dst), err)
+	}
+	copied := false
+	if err := os.Rename(src, dst); err != nil {
+		if err := storage.CopyDir(src, dst); err != nil {
+			return fmt.Errorf("failed to copy model: %w", err)
+		}
+		copied = true
+	}
+	undo := func() {
+		if copied {
+			os.RemoveAll(dst)
+		} else if err := os.Rename(dst, src); err != nil {
+			fmt.Printf("[Storage] Failed to move %s back to %s: %v\n", dst, src, err)
+		}
+	}
+
+	// Whatever is left at modelPath, the old link or the copied directory,
+	// goes to the trash until the new model directory is in place
+	old := ""
+	if _, err := os.Lstat(modelPath); err == nil {
+		var err error
+		if old, err = moveToTrash(modelPath, storage.GetModelsDir()); err != nil {
+			undo()
+			return err
+		}
+	}
+
+	var err error
+	if link {
+		err = os.Symlink(dst, modelPath)
+	} else {
+		err = os.Rename(dst, modelPath)
+	}
+	if err != nil {
+		if old != "" {
+			os.Rename(old, modelPath)
+		}
+		undo()
+		return fmt.Errorf("failed to place model: %w", err)
+	}
+
+	if old != "" {
+		os.RemoveAll(old)
+	}
+	if copied && src != modelPath {
+		if err := os.RemoveAll(src); err != nil {
+			fmt.Printf("[Storage] Failed to remove %s: %v\n", src, err)
+		}
+	}
+	return nil
+}
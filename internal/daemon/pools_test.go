@@ -0,0 +1,129 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPoolTestDaemon(t *testing.T, pools map[string]config.PoolConfig) *Daemon {
+	d := newRenameTestDaemon(t)
+	d.config = &config.Config{}
+	d.config.Storage.Pools = pools
+	registry, err := newRegistry(nil)
+	require.NoError(t, err)
+	d.registry = registry
+	return d
+}
+
+func TestRebalanceStorage(t *testing.T) {
+	poolDir := t.TempDir()
+	d := newPoolTestDaemon(t, map[string]config.PoolConfig{"big": {Path: poolDir}})
+	modelDir := writeTestModel(t, "org/model")
+	d.reloadRegistryModels("org/model")
+
+	report := d.RebalanceStorage(true)
+	require.Len(t, report.Moves, 1)
+	assert.Equal(t, "org/model", report.Moves[0].Model)
+	assert.Equal(t, storage.DefaultPool, report.Moves[0].From)
+	assert.Equal(t, "big", report.Moves[0].To)
+	require.Len(t, report.Pools, 2)
+	assert.Equal(t, storage.DefaultPool, report.Pools[0].Name)
+	_, linked := storage.IsLinkedModel(modelDir)
+	assert.False(t, linked, "dry run moved the model")
+
+	report = d.RebalanceStorage(false)
+	require.Len(t, report.Moves, 1)
+	assert.Empty(t, report.Moves[0].Error)
+	assert.Equal(t, report.Moves[0].Size, report.Moved)
+	target, linked := storage.IsLinkedModel(modelDir)
+	require.True(t, linked)
+	assert.FileExists(t, filepath.Join(poolDir, "org", "model", "model.safetensors"))
+	assert.Equal(t, filepath.Join(poolDir, "org", "model"), target)
+	assert.Equal(t, 1, report.Pools[1].Models)
+	_, err := d.registry.GetManifest("org/model")
+	assert.NoError(t, err)
+
+	// Models stay in their pool while it takes them
+	assert.Empty(t, d.RebalanceStorage(false).Moves)
+
+	// and go back to the models directory once no pool does
+	d.config.Storage.Pools["big"] = config.PoolConfig{Path: poolDir, MinModelSizeGB: 1}
+	report = d.RebalanceStorage(false)
+	require.Len(t, report.Moves, 1)
+	assert.Empty(t, report.Moves[0].Error)
+	assert.Equal(t, storage.DefaultPool, report.Moves[0].To)
+	_, linked = storage.IsLinkedModel(modelDir)
+	assert.False(t, linked)
+	assert.FileExists(t, filepath.Join(modelDir, "model.safetensors"))
+	assert.NoDirExists(t, filepath.Join(poolDir, "org"))
+}
+
+func TestRebalanceStorageLeavesImportedModels(t *testing.T) {
+	d := newPoolTestDaemon(t, map[string]config.PoolConfig{"big": {Path: t.TempDir()}})
+
+	srcDir := filepath.Join(t.TempDir(), "model")
+	require.NoError(t, os.MkdirAll(srcDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "model.safetensors"), make([]byte, 1024), 0644))
+	_, err := storage.ImportDir(srcDir, filepath.Join(storage.GetModelsDir(), "org", "model"), storage.ImportInPlace)
+	require.NoError(t, err)
+	d.reloadRegistryModels("org/model")
+
+	assert.Empty(t, d.RebalanceStorage(false).Moves)
+	assert.FileExists(t, filepath.Join(srcDir, "model.safetensors"))
+}
+
+func TestPoolPathFor(t *testing.T) {
+	poolDir := t.TempDir()
+	d := newPoolTestDaemon(t, map[string]config.PoolConfig{"big": {Path: poolDir, MinModelSizeGB: 1}})
+
+	assert.Empty(t, d.PoolPathFor("org/small", 1024))
+	assert.Equal(t, filepath.Join(poolDir, "org", "large"), d.PoolPathFor("org/large", 1<<30))
+
+	// Pools inside the models directory are ignored
+	d.config.Storage.Pools["big"] = config.PoolConfig{Path: filepath.Join(storage.GetModelsDir(), "pool")}
+	assert.Empty(t, d.PoolPathFor("org/large", 1<<30))
+}
+
+func TestRemovePooledModelPurgesFiles(t *testing.T) {
+	poolDir := t.TempDir()
+	d := newPoolTestDaemon(t, map[string]config.PoolConfig{"big": {Path: poolDir}})
+	writeTestModel(t, "org/model")
+	d.reloadRegistryModels("org/model")
+	require.Len(t, d.RebalanceStorage(false).Moves, 1)
+
+	result, err := d.RemoveModel("org/model", true)
+	require.NoError(t, err)
+	assert.True(t, result.Purged)
+	assert.Positive(t, result.FreedBytes)
+	assert.NoDirExists(t, filepath.Join(poolDir, "org"))
+	assert.DirExists(t, poolDir)
+
+	// The files go through the trash of the pool, which may be on another disk
+	entries, err := os.ReadDir(trashDir(poolDir))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRemoveModelImportedFromPoolKeepsFiles(t *testing.T) {
+	poolDir := t.TempDir()
+	d := newPoolTestDaemon(t, map[string]config.PoolConfig{"big": {Path: poolDir}})
+
+	// Only the pool directory of the model itself is deleted with it
+	srcDir := filepath.Join(poolDir, "checkpoints", "run-1")
+	require.NoError(t, os.MkdirAll(srcDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "model.safetensors"), make([]byte, 2048), 0644))
+	_, err := storage.ImportDir(srcDir, filepath.Join(storage.GetModelsDir(), "org", "model"), storage.ImportInPlace)
+	require.NoError(t, err)
+
+	result, err := d.RemoveModel("org/model", true)
+	require.NoError(t, err)
+	assert.True(t, result.Purged)
+	assert.Zero(t, result.FreedBytes)
+	assert.FileExists(t, filepath.Join(srcDir, "model.safetensors"))
+}
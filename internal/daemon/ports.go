@@ -0,0 +1,108 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Standard DHT port, tried before a random one when network.dht_port is 0
+const defaultDHTPort = 6881
+
+// Ports are the ports the daemon listens on
+type Ports struct {
+	API     int    `json:"api,omitempty"`      // REST API, 0 with daemon.socket_only
+	Socket  string `json:"socket,omitempty"`   // daemon.socket
+	Peer    int    `json:"peer,omitempty"`     // BitTorrent peers, over TCP and uTP
+	DHT     []int  `json:"dht,omitempty"`      // One DHT node per address family
+	HFProxy int    `json:"hf_proxy,omitempty"` // HuggingFace Hub proxy
+}
+
+// SavedPorts are the ports the daemon picked itself. They are kept in the
+// state and listened on again after a restart, so peers and DHT nodes that
+// know the daemon, and port mappings of the router, keep working.
+type SavedPorts struct {
+	Peer int `json:"peer,omitempty"`
+	DHT  int `json:"dht,omitempty"`
+}
+
+// isAddrInUse reports whether err is from listening on a port another
+// program has
+func isAddrInUse(err error) bool {
+	// Windows reports WSAEADDRINUSE, which syscall.EADDRINUSE isn't
+	return errors.Is(err, syscall.EADDRINUSE) || strings.Contains(err.Error(), "Only one usage of each socket address")
+}
+
+// portConflictError explains what to do about setting asking for a port
+// another program has
+func portConflictError(setting string, port int, err error) error {
+	return fmt.Errorf("%s %d is in use by another program, possibly another silmaril daemon: set %s to a free port, or to 0 to pick one and keep it across restarts: %w", setting, port, setting, err)
+}
+
+// portFree reports whether both TCP and UDP can listen on port of host
+func portFree(host string, port int) bool {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// peerPort returns the port the torrent client listens on: the configured
+// one, or else the one picked last time while it is free. 0 picks a random
+// port.
+func peerPort(configured int, host string, saved SavedPorts) int {
+	if configured > 0 || saved.Peer == 0 {
+		return configured
+	}
+	if !portFree(host, saved.Peer) {
+		fmt.Printf("[TorrentManager] Port %d used last time is taken, picking another\n", saved.Peer)
+		return 0
+	}
+	return saved.Peer
+}
+
+// dhtPorts returns the ports the DHT tries in order: the configured one
+// alone, or else the one used last time, the standard port and a random one
+func dhtPorts(configured, saved int) []int {
+	if configured > 0 {
+		return []int{configured}
+	}
+	var ports []int
+	if saved > 0 {
+		ports = append(ports, saved)
+	}
+	if saved != defaultDHTPort {
+		ports = append(ports, defaultDHTPort)
+	}
+	return append(ports, 0)
+}
+
+// Ports returns the ports the daemon listens on
+func (d *Daemon) Ports() Ports {
+	// Set before the API serves requests, and never changed
+	ports := Ports{API: d.apiPort}
+	if d.config != nil {
+		ports.Socket = d.config.Daemon.Socket
+		if d.hfProxyServer != nil {
+			ports.HFProxy = d.config.HFProxy.Port
+		}
+	}
+	if d.torrentManager != nil && d.torrentManager.client != nil {
+		ports.Peer = d.torrentManager.client.LocalPort()
+	}
+	if d.dhtManager != nil {
+		ports.DHT = d.dhtManager.Ports()
+	}
+	return ports
+}
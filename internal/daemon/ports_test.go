@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	taken := listener.Addr().(*net.TCPAddr).Port
+
+	assert.Equal(t, 50000, peerPort(50000, "127.0.0.1", SavedPorts{Peer: 40000}), "the configured port wins")
+	assert.Equal(t, 0, peerPort(0, "127.0.0.1", SavedPorts{}))
+	assert.Equal(t, 0, peerPort(0, "127.0.0.1", SavedPorts{Peer: taken}), "a taken port is given up")
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	free := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+	if portFree("127.0.0.1", free) {
+		assert.Equal(t, free, peerPort(0, "127.0.0.1", SavedPorts{Peer: free}))
+	}
+}
+
+func TestDHTPorts(t *testing.T) {
+	assert.Equal(t, []int{7000}, dhtPorts(7000, 6890), "a configured port is the only one tried")
+	assert.Equal(t, []int{defaultDHTPort, 0}, dhtPorts(0, 0))
+	assert.Equal(t, []int{6890, defaultDHTPort, 0}, dhtPorts(0, 6890))
+	assert.Equal(t, []int{defaultDHTPort, 0}, dhtPorts(0, defaultDHTPort))
+}
+
+func TestListenDHT(t *testing.T) {
+	taken, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer taken.Close()
+	port := taken.LocalAddr().(*net.UDPAddr).Port
+
+	// A taken port falls through to the next
+	conn, err := listenDHT("udp4", "127.0.0.1", []int{port, 0})
+	require.NoError(t, err)
+	assert.NotEqual(t, port, conn.LocalAddr().(*net.UDPAddr).Port)
+	conn.Close()
+
+	_, err = listenDHT("udp4", "127.0.0.1", []int{port})
+	require.Error(t, err)
+	assert.True(t, isAddrInUse(err))
+}
+
+func TestPortConflictError(t *testing.T) {
+	err := portConflictError("network.dht_port", 6881, fmt.Errorf("listen: %w", syscall.EADDRINUSE))
+	assert.ErrorContains(t, err, "network.dht_port 6881 is in use")
+	assert.ErrorContains(t, err, "set network.dht_port to a free port")
+	assert.True(t, errors.Is(err, syscall.EADDRINUSE))
+	assert.False(t, isAddrInUse(errors.New("permission denied")))
+}
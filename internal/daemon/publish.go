@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/silmaril/silmaril/internal/publish"
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// publishSeeder seeds published models with the torrent manager and tracks
+// them as seed transfers
+type publishSeeder struct {
+	d *Daemon
+}
+
+// Seed adds the torrent of a published model and starts seeding it
+func (s publishSeeder) Seed(torrentPath, name, path string) (string, string, error) {
+	mt, err := s.d.torrentManager.AddTorrentForSeeding(torrentPath, name, path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to add torrent: %w", err)
+	}
+	if err := s.d.torrentManager.StartSeeding(mt.InfoHash); err != nil {
+		return "", "", fmt.Errorf("failed to start seeding: %w", err)
+	}
+	transfer := s.d.transferManager.CreateSeed(name, mt.InfoHash)
+	return mt.InfoHash, transfer.ID, nil
+}
+
+// newPublisher creates the publisher of the daemon, which reports finished
+// jobs as events
+func (d *Daemon) newPublisher() *publish.Publisher {
+	p := publish.New(publish.GitCloner{Token: d.hfToken}, publishSeeder{d: d}, d.dhtManager)
+	p.OnFinish = func(job publish.Job) {
+		d.saveJob(job)
+		if job.Status == publish.StatusFailed {
+			d.events.Publish(EventPublishFailed, job.Model, "publishing failed at %s", job.Error)
+			return
+		}
+		d.reloadRegistryModels(job.Model)
+		d.events.Publish(EventPublishCompleted, job.Model, "published (infohash %s)", job.InfoHash)
+	}
+	// Clone progress outlives the daemon, for restarted clones to continue
+	p.OnProgress = d.saveJob
+	return p
+}
+
+// ResumableClone returns the progress of an interrupted clone of repoURL
+// left in modelPath, which cloning the repository again continues. It
+// reports false if modelPath holds no partial clone, or a job publishing
+// the model is still running.
+func (d *Daemon) ResumableClone(model, repoURL, modelPath string) (publish.CloneProgress, bool) {
+	if !storage.IsPartial(modelPath) {
+		return nil, false
+	}
+	// Partial downloads have no .git directory
+	if _, err := os.Stat(filepath.Join(modelPath, ".git")); err != nil {
+		return nil, false
+	}
+
+	progress := publish.CloneProgress{}
+	for _, job := range d.publisher.Jobs() {
+		if job.Model != model {
+			continue
+		}
+		if job.CompletedAt == nil {
+			return nil, false
+		}
+		// The most recent clone of the repository has the latest progress
+		if job.RepoURL == repoURL && job.Clone != nil {
+			progress = job.Clone
+			break
+		}
+	}
+	return progress, true
+}
+
+// Publish runs the publish pipeline for req and returns the finished job
+func (d *Daemon) Publish(req publish.Request) (publish.Job, error) {
+	return d.publisher.Run(d.ctx, req)
+}
+
+// StartPublish runs the publish pipeline for req in the background
+func (d *Daemon) StartPublish(req publish.Request) publish.Job {
+	job := d.publisher.Start(d.ctx, req)
+	d.saveJob(job)
+	return job
+}
+
+// StartPublishBatch runs the publish pipelines for reqs in the background,
+// workers at a time
+func (d *Daemon) StartPublishBatch(reqs []publish.Request, workers int) []publish.Job {
+	jobs := d.publisher.StartBatch(d.ctx, reqs, workers)
+	for _, job := range jobs {
+		d.saveJob(job)
+	}
+	return jobs
+}
+
+// PublishJobs returns the publish jobs, the most recent first
+func (d *Daemon) PublishJobs() []publish.Job {
+	return d.publisher.Jobs()
+}
+
+// PublishJob returns the publish job with the given ID
+func (d *Daemon) PublishJob(id string) (publish.Job, bool) {
+	return d.publisher.Job(id)
+}
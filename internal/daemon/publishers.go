@@ -0,0 +1,359 @@
+package daemon
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// SubscriptionStatus describes a followed publisher and its cached catalog
+type SubscriptionStatus struct {
+	Subscription
+	Loaded bool `json:"loaded"`
+	Models int  `json:"models"`
+}
+
+// publisherKeyPath returns where our publisher signing key is stored
+func (dm *DHTManager) publisherKeyPath() string {
+	if dm.config != nil && dm.config.Security.KeysDir != "" {
+		return filepath.Join(dm.config.Security.KeysDir, "publisher.key")
+	}
+	return filepath.Join(storage.GetBaseDir(), "keys", "publisher.key")
+}
+
+// publisherCatalogDir returns where the catalog of a publisher is cached
+func publisherCatalogDir(publicKey string) string {
+	return filepath.Join(storage.GetBaseDir(), "publishers", publicKey)
+}
+
+// state returns the daemon state shared through the torrent manager
+func (dm *DHTManager) state() *State {
+	if dm.torrentManager == nil {
+		return nil
+	}
+	return dm.torrentManager.state
+}
+
+// PublisherKey returns our hex encoded publisher public key
+func (dm *DHTManager) PublisherKey() string {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.publisherID
+}
+
+// PublisherModelCount returns the number of models in our own publisher catalog
+func (dm *DHTManager) PublisherModelCount() int {
+	dm.mu.RLock()
+	ref := dm.publisherRef
+	dm.mu.RUnlock()
+
+	if ref == nil {
+		return 0
+	}
+	return ref.ModelCount()
+}
+
+// initPublisherCatalogs creates our own publisher catalog and loads the
+// catalogs of all subscribed publishers. It must run after DHT bootstrap.
+func (dm *DHTManager) initPublisherCatalogs() {
+	dm.initOwnPublisherCatalog()
+	dm.publishRotations()
+
+	if state := dm.state(); state != nil {
+		for _, sub := range state.GetSubscriptions() {
+			dm.loadSubscription(sub.PublicKey)
+		}
+	}
+}
+
+// initOwnPublisherCatalog creates the catalog signed with our publisher key
+// and adds the models we share to it
+func (dm *DHTManager) initOwnPublisherCatalog() {
+	dm.mu.RLock()
+	privateKey := dm.publisherKey
+	dm.mu.RUnlock()
+
+	if privateKey == nil || dm.torrentClient == nil {
+		return
+	}
+
+	publicKey := hex.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+	ref, err := discovery.NewPublisherCatalogRef(dm.dhtServer, dm.torrentClient, privateKey, publisherCatalogDir(publicKey))
+	if err != nil {
+		fmt.Printf("[DHT] Failed to create publisher catalog: %v\n", err)
+		return
+	}
+
+	dm.mu.Lock()
+	// The key may have been rotated while the catalog was created
+	if dm.publisherID != publicKey {
+		dm.mu.Unlock()
+		ref.Close()
+		return
+	}
+	dm.publisherRef = ref
+	announcements := make([]*types.ModelAnnouncement, 0, len(dm.announcements))
+	for _, ann := range dm.announcements {
+		announcements = append(announcements, ann)
+	}
+	dm.mu.Unlock()
+
+	fmt.Printf("[DHT] Publisher catalog ready: %s\n", publicKey)
+	for _, ann := range announcements {
+		dm.addToPublisherCatalog(ann)
+	}
+}
+
+// addToPublisherCatalog adds a shared model to our own publisher catalog
+func (dm *DHTManager) addToPublisherCatalog(ann *types.ModelAnnouncement) {
+	dm.mu.RLock()
+	ref := dm.publisherRef
+	publisherKey := dm.publisherKey
+	dm.mu.RUnlock()
+
+	if ref == nil {
+		// Added when the publisher catalog is initialized
+		return
+	}
+
+	if err := ref.AddModelWithMetadata(ann.Name, ann.InfoHash, ann.Size, announcementMetadata(ann, publisherKey)); err != nil {
+		fmt.Printf("[DHT] Failed to add %s to publisher catalog: %v\n", ann.Name, err)
+	}
+}
+
+// loadSubscription fetches the catalog of a subscribed publisher
+func (dm *DHTManager) loadSubscription(publicKey string) {
+	key, err := discovery.ParsePublisherKey(publicKey)
+	if err != nil {
+		fmt.Printf("[DHT] Skipping subscription: %v\n", err)
+		return
+	}
+
+	dm.mu.RLock()
+	_, loaded := dm.subscriptions[publicKey]
+	dm.mu.RUnlock()
+	if loaded || dm.torrentClient == nil {
+		return
+	}
+
+	fmt.Printf("[DHT] Loading subscribed publisher catalog: %s\n", publicKey)
+	ref, err := discovery.NewSubscribedCatalogRef(dm.dhtServer, dm.torrentClient, key, publisherCatalogDir(publicKey))
+	if err != nil {
+		fmt.Printf("[DHT] Failed to load publisher catalog %s: %v\n", publicKey, err)
+		return
+	}
+
+	dm.mu.Lock()
+	// The subscription may have been removed while fetching
+	if state := dm.state(); state != nil {
+		stillSubscribed := false
+		for _, sub := range state.GetSubscriptions() {
+			if sub.PublicKey == publicKey {
+				stillSubscribed = true
+				break
+			}
+		}
+		if !stillSubscribed {
+			dm.mu.Unlock()
+			ref.Close()
+			return
+		}
+	}
+	dm.subscriptions[publicKey] = ref
+	dm.mu.Unlock()
+
+	if rotation := ref.Rotation(); rotation != nil {
+		dm.followRotation(*rotation)
+	}
+}
+
+// Subscribe follows a publisher's catalog
+func (dm *DHTManager) Subscribe(publicKey, name string) (*Subscription, error) {
+	publicKey = strings.ToLower(strings.TrimSpace(publicKey))
+	if _, err := discovery.ParsePublisherKey(publicKey); err != nil {
+		return nil, err
+	}
+	if publicKey == dm.PublisherKey() {
+		return nil, fmt.Errorf("cannot subscribe to your own publisher key")
+	}
+
+	state := dm.state()
+	if state == nil {
+		return nil, fmt.Errorf("daemon state not available")
+	}
+
+	sub := &Subscription{
+		PublicKey: publicKey,
+		Name:      name,
+		AddedAt:   time.Now(),
+	}
+	state.AddSubscription(sub)
+	if err := state.Save(); err != nil {
+		fmt.Printf("[DHT] Warning: failed to save subscriptions: %v\n", err)
+	}
+
+	// Fetch the catalog now if the DHT is ready, otherwise it is loaded after bootstrap
+	dm.mu.RLock()
+	ready := dm.catalogRef != nil
+	dm.mu.RUnlock()
+	if ready {
+		go dm.loadSubscription(publicKey)
+	}
+
+	return sub, nil
+}
+
+// Unsubscribe stops following a publisher's catalog
+func (dm *DHTManager) Unsubscribe(publicKey string) error {
+	publicKey = strings.ToLower(strings.TrimSpace(publicKey))
+
+	state := dm.state()
+	if state == nil || !state.RemoveSubscription(publicKey) {
+		return fmt.Errorf("not subscribed to %s", publicKey)
+	}
+	if err := state.Save(); err != nil {
+		fmt.Printf("[DHT] Warning: failed to save subscriptions: %v\n", err)
+	}
+
+	dm.mu.Lock()
+	if ref, exists := dm.subscriptions[publicKey]; exists {
+		ref.Close()
+		delete(dm.subscriptions, publicKey)
+	}
+	dm.mu.Unlock()
+
+	return nil
+}
+
+// ListSubscriptions returns all followed publishers
+func (dm *DHTManager) ListSubscriptions() []SubscriptionStatus {
+	state := dm.state()
+	if state == nil {
+		return nil
+	}
+
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	var result []SubscriptionStatus
+	for _, sub := range state.GetSubscriptions() {
+		status := SubscriptionStatus{Subscription: *sub}
+		if ref, exists := dm.subscriptions[sub.PublicKey]; exists {
+			status.Loaded = true
+			status.Models = ref.ModelCount()
+		}
+		result = append(result, status)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].AddedAt.Before(result[j].AddedAt)
+	})
+	return result
+}
+
+// mergeSubscribedResults adds matching models from subscribed publisher
+// catalogs to the public results, attributing them to their publisher
+func (dm *DHTManager) mergeSubscribedResults(results []*types.ModelAnnouncement, filter types.SearchFilter) []*types.ModelAnnouncement {
+	dm.mu.RLock()
+	refs := make(map[string]*discovery.BEP44CatalogRef, len(dm.subscriptions))
+	for key, ref := range dm.subscriptions {
+		refs[key] = ref
+	}
+	dm.mu.RUnlock()
+
+	if len(refs) == 0 {
+		return results
+	}
+
+	names := make(map[string]string)
+	if state := dm.state(); state != nil {
+		for _, sub := range state.GetSubscriptions() {
+			names[sub.PublicKey] = sub.Name
+		}
+	}
+
+	byHash := make(map[string]*types.ModelAnnouncement, len(results))
+	for _, ann := range results {
+		byHash[ann.InfoHash] = ann
+	}
+
+	for key, ref := range refs {
+		models, err := ref.SearchLocalModels(filter)
+		if err != nil {
+			fmt.Printf("[DHT] Failed to search publisher catalog %s: %v\n", key, err)
+			continue
+		}
+		for _, ann := range models {
+			if existing, exists := byHash[ann.InfoHash]; exists {
+				ann = existing
+			} else {
+				byHash[ann.InfoHash] = ann
+				results = append(results, ann)
+			}
+			ann.Publisher = key
+			ann.PublisherName = names[key]
+		}
+	}
+
+	return results
+}
+
+// refreshPublisherCatalogs republishes our own catalog and refreshes subscriptions
+func (dm *DHTManager) refreshPublisherCatalogs() {
+	dm.mu.RLock()
+	own := dm.publisherRef
+	refs := make([]*discovery.BEP44CatalogRef, 0, len(dm.subscriptions))
+	for _, ref := range dm.subscriptions {
+		refs = append(refs, ref)
+	}
+	dm.mu.RUnlock()
+
+	if own != nil && own.ModelCount() > 0 {
+		published, err := own.Cleanup(dm.liveInfoHashes())
+		if err != nil {
+			fmt.Printf("[DHT] Publisher catalog cleanup failed: %v\n", err)
+		}
+		if !published {
+			if err := own.RepublishCatalog(); err != nil {
+				fmt.Printf("[DHT] Failed to republish publisher catalog: %v\n", err)
+			}
+		}
+	}
+
+	// Keep publishing our key rotations for subscribers of the old keys
+	dm.republishRotations()
+
+	for _, ref := range refs {
+		if err := ref.RefreshCatalog(); err != nil {
+			fmt.Printf("[DHT] Failed to refresh publisher catalog %s: %v\n", ref.PublicKey(), err)
+			continue
+		}
+		if rotation := ref.Rotation(); rotation != nil {
+			dm.followRotation(*rotation)
+		}
+	}
+}
+
+// closePublisherCatalogs stops all publisher catalog references
+func (dm *DHTManager) closePublisherCatalogs() {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	if dm.publisherRef != nil {
+		dm.publisherRef.Close()
+	}
+	for _, ref := range dm.retiredRefs {
+		ref.Close()
+	}
+	for _, ref := range dm.subscriptions {
+		ref.Close()
+	}
+}
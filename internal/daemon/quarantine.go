@@ -0,0 +1,246 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// States of a download held in quarantine
+const (
+	QuarantineVerifying = "verifying" // Its files are being checked
+	QuarantineFailed    = "failed"    // Its files don't match its manifest
+	QuarantineUnsigned  = "unsigned"  // Its organization didn't sign its manifest
+	QuarantineLicense   = "license"   // Its manifest has a license that wasn't accepted
+)
+
+// ErrNotQuarantined is returned for models not held in quarantine
+var ErrNotQuarantined = errors.New("model is not in quarantine")
+
+// QuarantinedModel is a finished download held back from the local models
+// and from seeding until its files are verified
+type QuarantinedModel struct {
+	Model    string    `json:"model"`
+	InfoHash string    `json:"info_hash"`
+	Status   string    `json:"status"`
+	Reason   string    `json:"reason,omitempty"`
+	Files    []string  `json:"files,omitempty"` // Files that don't match their checksum
+	Since    time.Time `json:"since"`
+	Path     string    `json:"path,omitempty"`
+}
+
+// quarantineDownload holds a finished download in quarantine and verifies it
+// in the background. It reports false if downloads aren't verified, or the
+// download couldn't be quarantined, and are listed right away. Downloads in
+// the namespace of a configured organization are always verified, and
+// downloads whose license wasn't accepted are always held.
+func (d *Daemon) quarantineDownload(mt *ManagedTorrent) bool {
+	if !d.verifyDownloads() && !d.organizationModel(mt.Name) && d.unacceptedLicense(mt.Name, mt.StoragePath) == nil {
+		return false
+	}
+	q := QuarantinedModel{
+		Model:    mt.Name,
+		InfoHash: strings.ToLower(mt.InfoHash),
+		Status:   QuarantineVerifying,
+		Since:    time.Now(),
+	}
+	if err := writeQuarantine(mt.StoragePath, q); err != nil {
+		fmt.Printf("[Quarantine] %v\n", err)
+		return false
+	}
+	// Peers get the files once they are known to be the published ones
+	mt.Torrent.DisallowDataUpload()
+
+	d.workers.Add(1)
+	go func() {
+		defer d.workers.Done()
+		d.verifyQuarantined(mt, q)
+	}()
+	return true
+}
+
+// rejectDownload fails the transfers of a download dropped because its
+// metadata was refused
+func (d *Daemon) rejectDownload(mt *ManagedTorrent, err error) {
+	if d.transferManager == nil {
+		return
+	}
+	for _, transfer := range d.transferManager.GetActiveTransfers() {
+		if transfer.Type == TransferTypeDownload && strings.EqualFold(transfer.InfoHash, mt.InfoHash) {
+			d.transferManager.FailTransfer(transfer.ID, err)
+		}
+	}
+}
+
+// verifyQuarantined checks a quarantined download against its manifest and
+// releases it if it matches. Downloads that don't stay in quarantine until
+// they are removed or released by force.
+func (d *Daemon) verifyQuarantined(mt *ManagedTorrent, q QuarantinedModel) {
+	if reason := d.unsignedOrganizationModel(q.Model, mt.StoragePath); reason != "" {
+		q.Status = QuarantineUnsigned
+		q.Reason = reason
+		if !storage.IsQuarantined(mt.StoragePath) {
+			return
+		}
+		if err := writeQuarantine(mt.StoragePath, q); err != nil {
+			fmt.Printf("[Quarantine] %v\n", err)
+		}
+		d.events.Publish(EventManifestRejected, q.Model, "holding the download in quarantine: %s", reason)
+		fmt.Printf("[Quarantine] Holding %s: %s\n", q.Model, q.Reason)
+		return
+	}
+	if err := d.unacceptedLicense(q.Model, mt.StoragePath); err != nil {
+		q.Status = QuarantineLicense
+		q.Reason = err.Error()
+		if !storage.IsQuarantined(mt.StoragePath) {
+			return
+		}
+		if err := writeQuarantine(mt.StoragePath, q); err != nil {
+			fmt.Printf("[Quarantine] %v\n", err)
+		}
+		fmt.Printf("[Quarantine] Holding %s: %s\n", q.Model, q.Reason)
+		return
+	}
+	if !d.verifyDownloads() && !d.organizationModel(q.Model) {
+		d.releaseVerified(mt, q)
+		return
+	}
+
+	mismatched, err := d.downloadMatchesManifest(q.Model, mt.StoragePath)
+	if err != nil || len(mismatched) > 0 {
+		q.Status = QuarantineFailed
+		q.Files = mismatched
+		q.Reason = "files don't match the checksums in the manifest"
+		if err != nil {
+			q.Reason = fmt.Sprintf("failed to verify: %v", err)
+		}
+		// Unless it was released by force meanwhile
+		if !storage.IsQuarantined(mt.StoragePath) {
+			return
+		}
+		if err := writeQuarantine(mt.StoragePath, q); err != nil {
+			fmt.Printf("[Quarantine] %v\n", err)
+		}
+		fmt.Printf("[Quarantine] Holding %s: %s\n", q.Model, q.Reason)
+		return
+	}
+	d.releaseVerified(mt, q)
+}
+
+// releaseVerified releases a quarantined download that passed verification
+// and hands it to the hooks
+func (d *Daemon) releaseVerified(mt *ManagedTorrent, q QuarantinedModel) {
+	if err := d.releaseQuarantine(mt); err != nil {
+		fmt.Printf("[Quarantine] %v\n", err)
+		return
+	}
+	fmt.Printf("[Quarantine] Released %s\n", q.Model)
+	if d.downloadReady(q.InfoHash) {
+		d.modelReady(q.Model, q.InfoHash)
+	}
+}
+
+// releaseQuarantine lists a quarantined download as a local model and seeds
+// it again
+func (d *Daemon) releaseQuarantine(mt *ManagedTorrent) error {
+	if err := storage.ClearQuarantine(mt.StoragePath); err != nil {
+		return err
+	}
+	mt.Torrent.AllowDataUpload()
+	d.reloadRegistryModels(mt.Name)
+	return nil
+}
+
+// resumeQuarantine verifies again the downloads whose verification a
+// restart interrupted, and keeps the others from seeding
+func (d *Daemon) resumeQuarantine() {
+	for _, q := range d.ListQuarantine() {
+		mt, ok := d.torrentManager.GetTorrent(q.InfoHash)
+		if !ok {
+			continue
+		}
+		mt.Torrent.DisallowDataUpload()
+		if q.Status == QuarantineVerifying {
+			d.workers.Add(1)
+			go func() {
+				defer d.workers.Done()
+				d.verifyQuarantined(mt, q)
+			}()
+		}
+	}
+}
+
+// ListQuarantine returns the downloads held in quarantine, the oldest first
+func (d *Daemon) ListQuarantine() []QuarantinedModel {
+	if d.torrentManager == nil {
+		return nil
+	}
+	var quarantined []QuarantinedModel
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		if mt.StoragePath == "" {
+			continue
+		}
+		q, err := readQuarantine(mt.StoragePath)
+		if err != nil {
+			continue
+		}
+		// Renamed models keep their note
+		q.Model = mt.Name
+		q.InfoHash = strings.ToLower(mt.InfoHash)
+		q.Path = mt.StoragePath
+		quarantined = append(quarantined, q)
+	}
+	sort.Slice(quarantined, func(i, j int) bool { return quarantined[i].Since.Before(quarantined[j].Since) })
+	return quarantined
+}
+
+// ReleaseQuarantine lists a quarantined model as a local model without
+// waiting for, or despite, its verification
+func (d *Daemon) ReleaseQuarantine(model string) (QuarantinedModel, error) {
+	for _, q := range d.ListQuarantine() {
+		if q.Model != model {
+			continue
+		}
+		mt, ok := d.torrentManager.GetTorrent(q.InfoHash)
+		if !ok {
+			break
+		}
+		if err := d.releaseQuarantine(mt); err != nil {
+			return QuarantinedModel{}, err
+		}
+		d.events.Publish(EventQuarantineReleased, model, "released from quarantine by force (%s)", q.Status)
+		if d.downloadReady(q.InfoHash) {
+			d.modelReady(q.Model, q.InfoHash)
+		}
+		return q, nil
+	}
+	return QuarantinedModel{}, fmt.Errorf("%w: %s", ErrNotQuarantined, model)
+}
+
+// writeQuarantine holds the download in dir in quarantine with q as its note
+func writeQuarantine(dir string, q QuarantinedModel) error {
+	q.Path = ""
+	data, err := json.Marshal(q)
+	if err != nil {
+		return fmt.Errorf("failed to encode quarantine note: %w", err)
+	}
+	return storage.MarkQuarantined(dir, data)
+}
+
+// readQuarantine returns the note of the quarantine of dir
+func readQuarantine(dir string) (QuarantinedModel, error) {
+	data, err := storage.ReadQuarantine(dir)
+	if err != nil {
+		return QuarantinedModel{}, err
+	}
+	var q QuarantinedModel
+	if err := json.Unmarshal(data, &q); err != nil {
+		return QuarantinedModel{}, fmt.Errorf("failed to parse quarantine note: %w", err)
+	}
+	return q, nil
+}
@@ -0,0 +1,43 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuarantineNote(t *testing.T) {
+	dir := t.TempDir()
+	_, err := readQuarantine(dir)
+	assert.Error(t, err)
+
+	q := QuarantinedModel{
+		Model:    "org/model",
+		InfoHash: "abc",
+		Status:   QuarantineFailed,
+		Reason:   "files don't match the checksums in the manifest",
+		Files:    []string{"model.safetensors"},
+		Since:    time.Now().UTC().Truncate(time.Second),
+		Path:     dir,
+	}
+	require.NoError(t, writeQuarantine(dir, q))
+	assert.True(t, storage.IsQuarantined(dir))
+
+	read, err := readQuarantine(dir)
+	require.NoError(t, err)
+	q.Path = "" // Not kept in the note, the directory may move
+	assert.Equal(t, q, read)
+
+	require.NoError(t, storage.ClearQuarantine(dir))
+	assert.False(t, storage.IsQuarantined(dir))
+}
+
+func TestReleaseQuarantineNotQuarantined(t *testing.T) {
+	d := &Daemon{}
+	assert.Empty(t, d.ListQuarantine())
+	_, err := d.ReleaseQuarantine("org/model")
+	assert.ErrorIs(t, err, ErrNotQuarantined)
+}
@@ -0,0 +1,239 @@
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/store"
+)
+
+// How long changes to the models directory settle before it is scanned
+// again. Downloads and imports create many files in a row.
+const registryRescanDelay = 2 * time.Second
+
+// How often the models directory is scanned when it can't be watched
+const registryPollInterval = time.Minute
+
+// registryWatch keeps the directories the registry found models in watched
+type registryWatch struct {
+	mu      sync.Mutex
+	watcher *fsnotify.Watcher // Nil while the directories aren't watched
+	watched map[string]bool
+}
+
+// sync watches dirs and stops watching the directories no longer among them
+func (w *registryWatch) sync(dirs []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.watcher == nil {
+		return
+	}
+	wanted := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		wanted[dir] = true
+		if w.watched[dir] {
+			continue
+		}
+		if err := w.watcher.Add(dir); err != nil {
+			fmt.Printf("[Registry] Failed to watch %s: %v\n", dir, err)
+			continue
+		}
+		w.watched[dir] = true
+	}
+	for dir := range w.watched {
+		if !wanted[dir] {
+			// Removed directories are dropped by the watcher already
+			w.watcher.Remove(dir)
+			delete(w.watched, dir)
+		}
+	}
+}
+
+// set starts or stops watching with watcher
+func (w *registryWatch) set(watcher *fsnotify.Watcher) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.watcher = watcher
+	w.watched = make(map[string]bool)
+}
+
+// newRegistry creates the registry of the local models, shared by the API
+// handlers instead of scanning the models directory for every request. It
+// caches the manifests in st if there is a database.
+func newRegistry(st *store.Store) (*models.Registry, error) {
+	paths, err := storage.NewPaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize paths: %w", err)
+	}
+	if st != nil {
+		return models.NewRegistryWithStore(paths, st)
+	}
+	return models.NewRegistry(paths)
+}
+
+// GetRegistry returns the registry of the local models. It follows changes
+// to the models directory on its own; RefreshRegistry rescans it at once.
+func (d *Daemon) GetRegistry() *models.Registry {
+	return d.registry
+}
+
+// RefreshRegistry scans the models directory again and returns the number
+// of models found
+func (d *Daemon) RefreshRegistry() (int, error) {
+	if err := d.registry.ScanModels(); err != nil {
+		return 0, fmt.Errorf("failed to scan models: %w", err)
+	}
+	d.registryWatch.sync(d.registry.Dirs())
+	return len(d.registry.ListModels()), nil
+}
+
+// refreshRegistry is RefreshRegistry for workers, which only log failures
+func (d *Daemon) refreshRegistry() {
+	if _, err := d.RefreshRegistry(); err != nil {
+		fmt.Printf("[Registry] %v\n", err)
+	}
+}
+
+// reloadRegistryModels updates the registry after the daemon added, removed
+// or renamed models, or changed aliases
+func (d *Daemon) reloadRegistryModels(names ...string) {
+	if d.registry == nil {
+		return
+	}
+	for _, name := range names {
+		// Models that are gone are dropped
+		d.registry.LoadModel(name)
+	}
+	if err := d.registry.ReloadAliases(); err != nil {
+		fmt.Printf("[Registry] Failed to reload aliases: %v\n", err)
+	}
+}
+
+// registryEventMatters reports whether a change in a watched directory may
+// change the models found. Writes to model files, as downloads make plenty
+// of, don't.
+func registryEventMatters(event fsnotify.Event) bool {
+	if strings.HasPrefix(filepath.Base(event.Name), healthProbePrefix) {
+		return false
+	}
+	if event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		return true
+	}
+	if event.Has(fsnotify.Write) {
+		switch filepath.Base(event.Name) {
+		case models.ManifestFileName, models.HFConfigFile:
+			return true
+		}
+	}
+	return false
+}
+
+// registryWorker rescans the models directory when models are added,
+// removed or their manifests change, falling back to polling if the
+// directory can't be watched
+func (d *Daemon) registryWorker() {
+	defer d.workers.Done()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Printf("[Registry] Can't watch the models directory, scanning it every %v: %v\n", registryPollInterval, err)
+		d.pollRegistry()
+		return
+	}
+	d.registryWatch.set(watcher)
+	defer func() {
+		d.registryWatch.set(nil)
+		watcher.Close()
+	}()
+	d.registryWatch.sync(d.registry.Dirs())
+
+	// Paths changed since the last update, nil after a watcher error asks
+	// for a full scan
+	changed := make(map[string]bool)
+	var rescan <-chan time.Time
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if registryEventMatters(event) {
+				if changed != nil {
+					changed[event.Name] = true
+				}
+				rescan = time.After(registryRescanDelay)
+			}
+		case <-rescan:
+			rescan = nil
+			if changed == nil {
+				d.refreshRegistry()
+			} else {
+				paths := make([]string, 0, len(changed))
+				for path := range changed {
+					paths = append(paths, path)
+				}
+				d.updateRegistry(paths)
+			}
+			changed = make(map[string]bool)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been lost, scan everything to be sure
+			fmt.Printf("[Registry] Watcher error: %v\n", err)
+			changed = nil
+			rescan = time.After(registryRescanDelay)
+		}
+	}
+}
+
+// updateRegistry scans again the parts of the models directory that
+// changed, reports the models that appeared or disappeared, and shares new
+// models if storage.auto_share_new_models is set
+func (d *Daemon) updateRegistry(changed []string) {
+	added, removed, err := d.registry.Update(changed)
+	if err != nil {
+		fmt.Printf("[Registry] Failed to update models: %v\n", err)
+		return
+	}
+	d.registryWatch.sync(d.registry.Dirs())
+
+	for _, name := range removed {
+		fmt.Printf("[Registry] Model %s was removed\n", name)
+		d.events.Publish(EventModelRemoved, name, "removed from the models directory")
+	}
+	for _, name := range added {
+		fmt.Printf("[Registry] Model %s was added\n", name)
+		d.events.Publish(EventModelAdded, name, "added to the models directory")
+		if d.config != nil && d.config.GetBool("storage.auto_share_new_models") {
+			if err := d.autoShareModel(name); err != nil {
+				fmt.Printf("[Registry] Not sharing %s: %v\n", name, err)
+			}
+		}
+	}
+}
+
+// pollRegistry scans the models directory periodically until the daemon
+// stops
+func (d *Daemon) pollRegistry() {
+	ticker := time.NewTicker(registryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.refreshRegistry()
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryEventMatters(t *testing.T) {
+	assert.True(t, registryEventMatters(fsnotify.Event{Name: "/models/org/model", Op: fsnotify.Create}))
+	assert.True(t, registryEventMatters(fsnotify.Event{Name: "/models/org/model/.silmaril.partial", Op: fsnotify.Remove}))
+	assert.True(t, registryEventMatters(fsnotify.Event{Name: "/models/org/model/.silmaril.json", Op: fsnotify.Write}))
+	assert.False(t, registryEventMatters(fsnotify.Event{Name: "/models/org/model/model.safetensors", Op: fsnotify.Write}))
+	assert.False(t, registryEventMatters(fsnotify.Event{Name: "/models/org/model", Op: fsnotify.Chmod}))
+}
+
+func TestRegistryWorkerFollowsModelsDirectory(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	var err error
+	d.registry, err = newRegistry(nil)
+	require.NoError(t, err)
+
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	d.workers.Add(1)
+	go d.registryWorker()
+	defer func() {
+		d.cancel()
+		d.workers.Wait()
+	}()
+
+	// Wait for the worker to watch the models directory
+	require.Eventually(t, func() bool {
+		d.registryWatch.mu.Lock()
+		defer d.registryWatch.mu.Unlock()
+		return d.registryWatch.watched[storage.GetModelsDir()]
+	}, 5*time.Second, 10*time.Millisecond)
+
+	modelDir := writeTestModel(t, "org/model")
+	require.Eventually(t, func() bool {
+		_, err := d.registry.GetManifest("org/model")
+		return err == nil
+	}, 10*time.Second, 50*time.Millisecond)
+
+	require.NoError(t, os.RemoveAll(modelDir))
+	require.Eventually(t, func() bool {
+		_, err := d.registry.GetManifest("org/model")
+		return err != nil
+	}, 10*time.Second, 50*time.Millisecond)
+
+	var types []string
+	for _, event := range d.events.Since(0) {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{EventModelAdded, EventModelRemoved}, types)
+}
+
+func TestRenameUpdatesRegistry(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	writeTestModel(t, "org/model")
+	var err error
+	d.registry, err = newRegistry(nil)
+	require.NoError(t, err)
+
+	_, err = d.RenameModel("org/model", "org/renamed", true)
+	require.NoError(t, err)
+
+	_, err = d.registry.GetManifest("org/renamed")
+	assert.NoError(t, err)
+	// The old name is now an alias
+	manifest, err := d.registry.GetManifest("org/model")
+	require.NoError(t, err)
+	assert.Equal(t, "org/renamed", manifest.Name)
+}
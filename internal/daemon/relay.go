@@ -0,0 +1,38 @@
+package daemon
+
+import (
+	"errors"
+
+	"github.com/silmaril/silmaril/internal/config"
+)
+
+// Modes of the daemon, set in daemon.mode
+const (
+	ModeFull  = "full"  // Stores, downloads and seeds models
+	ModeRelay = "relay" // Only seeds catalogs and republishes DHT references
+)
+
+// ErrRelayMode is returned for requests that need model data on a relay
+var ErrRelayMode = errors.New("the daemon runs in relay mode and doesn't store models")
+
+// Mode returns the mode the daemon runs in. It only changes on a restart.
+func (d *Daemon) Mode() string {
+	if d.mode == "" {
+		return ModeFull
+	}
+	return d.mode
+}
+
+// Relay reports whether the daemon runs as a relay, keeping the discovery
+// layer alive without storing models
+func (d *Daemon) Relay() bool {
+	return d.Mode() == ModeRelay
+}
+
+// configMode returns the mode cfg sets, full unless it sets relay
+func configMode(cfg *config.Config) string {
+	if cfg.Daemon.Mode == ModeRelay {
+		return ModeRelay
+	}
+	return ModeFull
+}
@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/store"
+)
+
+// RelocateState points the paths recorded in the state file in daemonDir and
+// the database in dbDir from the base directory from to to, once the base
+// directory was migrated. It returns the number of paths changed. The daemon
+// must not be running.
+func RelocateState(daemonDir, dbDir, from, to string) (int, error) {
+	changed, err := relocateStateFile(filepath.Join(daemonDir, "state.json"), from, to)
+	if err != nil {
+		return changed, err
+	}
+
+	if _, err := os.Stat(filepath.Join(dbDir, store.FileName)); os.IsNotExist(err) {
+		return changed, nil
+	}
+	st, err := store.Open(dbDir)
+	if err != nil {
+		return changed, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer st.Close()
+
+	err = st.Update(func(tx *store.Tx) error {
+		var torrents []TorrentState
+		found, err := tx.Get(store.State, stateTorrents, &torrents)
+		if err != nil {
+			return err
+		}
+		if n := relocateTorrents(torrents, from, to); found && n > 0 {
+			changed += n
+			if err := tx.Put(store.State, stateTorrents, torrents); err != nil {
+				return err
+			}
+		}
+
+		// Catalogs are cached by their directory
+		catalogs := make(map[string]json.RawMessage)
+		err = tx.ForEach(store.Catalogs, func(key string, data []byte) error {
+			if _, ok := storage.RelocatePath(key, from, to); ok {
+				catalogs[key] = append(json.RawMessage(nil), data...)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for key, data := range catalogs {
+			newKey, _ := storage.RelocatePath(key, from, to)
+			if err := tx.Put(store.Catalogs, newKey, data); err != nil {
+				return err
+			}
+			if err := tx.Delete(store.Catalogs, key); err != nil {
+				return err
+			}
+			changed++
+		}
+		return nil
+	})
+	if err != nil {
+		return changed, fmt.Errorf("failed to relocate paths in the database: %w", err)
+	}
+	return changed, nil
+}
+
+// relocateStateFile rewrites the paths in the state file at path, if there is
+// one
+func relocateStateFile(path, from, to string) (int, error) {
+	state, err := readStateFile(path)
+	if err != nil || state == nil {
+		return 0, err
+	}
+	changed := relocateTorrents(state.ActiveTorrents, from, to)
+	if changed == 0 {
+		return 0, nil
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if err := storage.WriteFileAtomic(path, data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write state file: %w", err)
+	}
+	return changed, nil
+}
+
+// relocateTorrents points the storage paths of torrents below from to to
+func relocateTorrents(torrents []TorrentState, from, to string) int {
+	changed := 0
+	for i, t := range torrents {
+		if relocated, ok := storage.RelocatePath(t.StoragePath, from, to); ok && relocated != t.StoragePath {
+			torrents[i].StoragePath = relocated
+			changed++
+		}
+	}
+	return changed
+}
@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelocateStateFile(t *testing.T) {
+	daemonDir := t.TempDir()
+	s := NewState(filepath.Join(daemonDir, "state.json"))
+	s.AddTorrent("aaaa", "org/moved", time.Now(), true)
+	s.AddTorrent("bbbb", "org/outside", time.Now(), true)
+	s.AddTorrent("cccc", "org/default", time.Now(), true)
+	s.SetTorrentStoragePath("aaaa", "/old/base/updates/org/moved")
+	s.SetTorrentStoragePath("bbbb", "/elsewhere/org/outside")
+	require.NoError(t, s.Save())
+
+	changed, err := RelocateState(daemonDir, t.TempDir(), "/old/base", "/new/base")
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+
+	loaded := NewState(filepath.Join(daemonDir, "state.json"))
+	require.NoError(t, loaded.Load())
+	paths := make(map[string]string)
+	for _, torrent := range loaded.ActiveTorrents {
+		paths[torrent.InfoHash] = torrent.StoragePath
+	}
+	assert.Equal(t, "/new/base/updates/org/moved", paths["aaaa"])
+	assert.Equal(t, "/elsewhere/org/outside", paths["bbbb"])
+	assert.Empty(t, paths["cccc"])
+}
+
+func TestRelocateStateDatabase(t *testing.T) {
+	dbDir := t.TempDir()
+	st, err := store.Open(dbDir)
+	require.NoError(t, err)
+
+	s := NewState("")
+	s.UseStore(st)
+	s.AddTorrent("aaaa", "org/moved", time.Now(), true)
+	s.SetTorrentStoragePath("aaaa", "/old/base/updates/org/moved")
+	require.NoError(t, s.Save())
+	require.NoError(t, st.Put(store.Catalogs, "/old/base/catalog", map[string]string{"version": "1"}))
+	require.NoError(t, st.Close())
+
+	changed, err := RelocateState(t.TempDir(), dbDir, "/old/base", "/new/base")
+	require.NoError(t, err)
+	assert.Equal(t, 2, changed)
+
+	st, err = store.Open(dbDir)
+	require.NoError(t, err)
+	defer st.Close()
+
+	var torrents []TorrentState
+	found, err := st.Get(store.State, stateTorrents, &torrents)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "/new/base/updates/org/moved", torrents[0].StoragePath)
+
+	var catalog map[string]string
+	found, err = st.Get(store.Catalogs, "/new/base/catalog", &catalog)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "1", catalog["version"])
+	found, err = st.Get(store.Catalogs, "/old/base/catalog", &catalog)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
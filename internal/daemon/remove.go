@@ -0,0 +1,213 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// ErrModelInUse is returned when a model cannot be removed because a
+// download still writes to it
+var ErrModelInUse = errors.New("model is in use by an active download")
+
+// RemoveResult describes what RemoveModel did
+type RemoveResult struct {
+	StoppedTorrents []string `json:"stopped_torrents"`
+	Purged          bool     `json:"purged"`
+	FreedBytes      int64    `json:"freed_bytes"`
+}
+
+// RemoveModel stops seeding a local model and drops its torrents. With purge
+// the model directory and its torrent files are deleted as well. The
+// directory is first moved into the trash directory next to it, so a failed
+// or interrupted removal never leaves a half-deleted model behind.
+func (d *Daemon) RemoveModel(name string, purge bool) (*RemoveResult, error) {
+	modelsDir := storage.GetModelsDir()
+	modelPath, err := modelPathFor(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.transferManager != nil {
+		for _, t := range d.transferManager.GetIncompleteTransfers() {
+			if t.Type == TransferTypeDownload && t.ModelName == name && t.Status != TransferStatusFailed {
+				return nil, fmt.Errorf("%w (transfer %s), cancel it first", ErrModelInUse, t.ID)
+			}
+		}
+	}
+
+	result := &RemoveResult{}
+
+	stopped, err := d.stopModelTorrents(name, modelPath)
+	result.StoppedTorrents = stopped
+	if err != nil {
+		return result, err
+	}
+
+	if !purge {
+		return result, nil
+	}
+	defer d.reloadRegistryModels(name)
+
+	for _, infoHash := range result.StoppedTorrents {
+		torrentPath := filepath.Join(storage.GetTorrentsDir(), infoHash+".torrent")
+		if err := os.Remove(torrentPath); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("[Daemon] Failed to delete torrent file %s: %v\n", torrentPath, err)
+		}
+	}
+
+	// Encrypted models are seeded from their encrypted files
+	encryptedPath := encryptedModelPath(name)
+	if _, err := os.Stat(encryptedPath); err == nil {
+		result.FreedBytes += storage.GetDirSize(encryptedPath)
+		if err := os.RemoveAll(encryptedPath); err != nil {
+			return result, fmt.Errorf("failed to delete encrypted files: %w", err)
+		}
+		removeEmptyParents(filepath.Dir(encryptedPath), storage.GetEncryptedDir())
+	}
+
+	if _, err := os.Stat(modelPath); os.IsNotExist(err) {
+		result.Purged = true
+		d.removeModelAliases(name)
+		return result, nil
+	}
+
+	// Models published in place or downloaded to another directory only lose
+	// their link, the files stay where they are. Models in a storage pool are
+	// deleted with their files.
+	if target, ok := storage.IsLinkedModel(modelPath); ok {
+		pool, _ := storage.PoolOf(storagePools(d.config), name, modelPath)
+		if err := os.Remove(modelPath); err != nil {
+			return result, fmt.Errorf("failed to unlink model: %w", err)
+		}
+		removeEmptyParents(filepath.Dir(modelPath), modelsDir)
+		result.Purged = true
+		d.removeModelAliases(name)
+		if pool == nil {
+			fmt.Printf("[Daemon] Unlinked model %s, files in %s were kept\n", name, target)
+			return result, nil
+		}
+
+		size := storage.GetDirSize(target)
+		trashPath, err := moveToTrash(target, pool.Path)
+		if err != nil {
+			return result, fmt.Errorf("failed to delete model files in pool %s: %w", pool.Name, err)
+		}
+		removeEmptyParents(filepath.Dir(target), pool.Path)
+		if err := os.RemoveAll(trashPath); err != nil {
+			fmt.Printf("[Daemon] Failed to empty trash %s: %v\n", trashPath, err)
+		}
+		result.FreedBytes += size
+		fmt.Printf("[Daemon] Deleted model %s from pool %s (%d bytes)\n", name, pool.Name, size)
+		return result, nil
+	}
+
+	size := storage.GetDirSize(modelPath)
+	trashPath, err := moveToTrash(modelPath, modelsDir)
+	if err != nil {
+		return result, err
+	}
+
+	// Drop empty organization directories left behind
+	removeEmptyParents(filepath.Dir(modelPath), modelsDir)
+
+	if err := os.RemoveAll(trashPath); err != nil {
+		// The model is already gone from the models directory, the trash is
+		// emptied again on the next start
+		fmt.Printf("[Daemon] Failed to empty trash %s: %v\n", trashPath, err)
+	}
+
+	result.Purged = true
+	result.FreedBytes += size
+	d.removeModelAliases(name)
+	fmt.Printf("[Daemon] Deleted model %s (%d bytes)\n", name, size)
+	return result, nil
+}
+
+// stopModelTorrents stops seeding and announcing every torrent serving the
+// model and returns their infohashes
+func (d *Daemon) stopModelTorrents(name, modelPath string) ([]string, error) {
+	var stopped []string
+	if d.torrentManager == nil {
+		return stopped, nil
+	}
+
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		if mt.Name != name && filepath.Clean(mt.StoragePath) != modelPath {
+			continue
+		}
+		if err := d.torrentManager.RemoveTorrent(mt.InfoHash); err != nil {
+			return stopped, fmt.Errorf("failed to stop torrent %s: %w", mt.InfoHash, err)
+		}
+		if d.dhtManager != nil {
+			d.dhtManager.RemoveTorrentFromDHT(mt.InfoHash)
+		}
+		if d.state != nil {
+			d.state.ForgetManualPeers(mt.InfoHash)
+		}
+		stopped = append(stopped, mt.InfoHash)
+	}
+	return stopped, nil
+}
+
+// trashName is the directory model directories wait in to be deleted. The
+// models directory and every storage pool have their own, so moving a model
+// there is a rename on the same filesystem.
+const trashName = ".silmaril-trash"
+
+// trashDir returns the trash directory of root, the models directory or a
+// storage pool
+func trashDir(root string) string {
+	return filepath.Join(root, trashName)
+}
+
+// moveToTrash renames path into the trash directory of root, the models
+// directory or storage pool holding it, and returns its new path
+func moveToTrash(path, root string) (string, error) {
+	if err := os.MkdirAll(trashDir(root), 0755); err != nil {
+		return "", fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	target := filepath.Join(trashDir(root), fmt.Sprintf("%s-%d", filepath.Base(path), time.Now().UnixNano()))
+	if err := os.Rename(path, target); err != nil {
+		return "", fmt.Errorf("failed to move model to trash: %w", err)
+	}
+	return target, nil
+}
+
+// emptyTrash deletes model directories left in the trash of the models
+// directory and the storage pools by an interrupted removal
+func (d *Daemon) emptyTrash() {
+	roots := []string{storage.GetModelsDir()}
+	for _, pool := range storagePools(d.config) {
+		roots = append(roots, pool.Path)
+	}
+	for _, root := range roots {
+		entries, err := os.ReadDir(trashDir(root))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(trashDir(root), entry.Name())
+			if err := os.RemoveAll(path); err != nil {
+				fmt.Printf("[Daemon] Failed to empty trash %s: %v\n", path, err)
+			}
+		}
+	}
+}
+
+// removeEmptyParents removes empty directories from dir up to, but not
+// including, root
+func removeEmptyParents(dir, root string) {
+	for dir != root && strings.HasPrefix(dir, root) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
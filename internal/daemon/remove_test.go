@@ -0,0 +1,74 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveModel(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	d := &Daemon{transferManager: NewTransferManager(nil, NewState(""))}
+
+	modelDir := filepath.Join(storage.GetModelsDir(), "org", "model")
+	require.NoError(t, os.MkdirAll(modelDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "model.safetensors"), make([]byte, 2048), 0644))
+
+	// Without purge the files stay on disk
+	result, err := d.RemoveModel("org/model", false)
+	require.NoError(t, err)
+	assert.False(t, result.Purged)
+	assert.DirExists(t, modelDir)
+
+	// Models with an active download are refused
+	transfer := d.transferManager.CreateDownload("org/model", "hash", 4096)
+	_, err = d.RemoveModel("org/model", true)
+	assert.ErrorIs(t, err, ErrModelInUse)
+	assert.DirExists(t, modelDir)
+
+	require.NoError(t, d.transferManager.CancelTransfer(transfer.ID))
+	result, err = d.RemoveModel("org/model", true)
+	require.NoError(t, err)
+	assert.True(t, result.Purged)
+	assert.Equal(t, int64(2048), result.FreedBytes)
+	assert.NoDirExists(t, modelDir)
+	assert.NoDirExists(t, filepath.Join(storage.GetModelsDir(), "org"))
+
+	entries, err := os.ReadDir(trashDir(storage.GetModelsDir()))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRemoveModelInvalidName(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	d := &Daemon{}
+
+	for _, name := range []string{"", "..", "../outside", "org/../../outside"} {
+		_, err := d.RemoveModel(name, true)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRemoveLinkedModelKeepsFiles(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	d := &Daemon{}
+
+	srcDir := filepath.Join(t.TempDir(), "model")
+	require.NoError(t, os.MkdirAll(srcDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "model.safetensors"), make([]byte, 2048), 0644))
+
+	modelDir := filepath.Join(storage.GetModelsDir(), "org", "model")
+	_, err := storage.ImportDir(srcDir, modelDir, storage.ImportInPlace)
+	require.NoError(t, err)
+
+	result, err := d.RemoveModel("org/model", true)
+	require.NoError(t, err)
+	assert.True(t, result.Purged)
+	assert.Zero(t, result.FreedBytes)
+	assert.NoFileExists(t, modelDir)
+	assert.FileExists(t, filepath.Join(srcDir, "model.safetensors"))
+}
@@ -0,0 +1,192 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// RenameResult describes what RenameModel did
+type RenameResult struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Torrents []string `json:"torrents"`        // Torrents seeded under the new name
+	Alias    bool     `json:"alias,omitempty"` // The old name is an alias of the new one
+}
+
+// RenameModel renames a local model. Its torrents are stopped while the
+// model directory, encrypted files and torrent file move, then seeded again
+// under the new name. The manifest, transfers and aliases follow the model,
+// and with keepAlias the old name stays an alias of the new one.
+func (d *Daemon) RenameModel(from, to string, keepAlias bool) (*RenameResult, error) {
+	d.aliasMu.Lock()
+	defer d.aliasMu.Unlock()
+
+	from = d.ResolveModel(from)
+	if from == to {
+		return nil, fmt.Errorf("model is already named %s", to)
+	}
+	fromPath, err := modelPathFor(from)
+	if err != nil {
+		return nil, err
+	}
+	toPath, err := modelPathFor(to)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Lstat(fromPath); err != nil {
+		return nil, fmt.Errorf("model %s not found", from)
+	}
+	if _, err := os.Lstat(toPath); err == nil {
+		return nil, fmt.Errorf("model %s already exists", to)
+	}
+	if err := d.checkRenamable(from, fromPath); err != nil {
+		return nil, err
+	}
+
+	aliases, err := loadAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop the torrents serving the model from the client while their files
+	// move, they are added again from the new place
+	encryptedFrom, encryptedTo := encryptedModelPath(from), encryptedModelPath(to)
+	moved := map[string]string{fromPath: toPath, encryptedFrom: encryptedTo}
+	var detached []*DetachedTorrent
+	var storagePaths []string
+	if d.torrentManager != nil {
+		for _, mt := range d.torrentManager.GetAllTorrents() {
+			storagePath := filepath.Clean(mt.StoragePath)
+			if mt.Name != from && moved[storagePath] == "" {
+				continue
+			}
+			dt, err := d.torrentManager.DetachTorrent(mt.InfoHash)
+			if err != nil {
+				d.reattachTorrents(detached, storagePaths, from)
+				return nil, fmt.Errorf("failed to stop torrent %s: %w", mt.InfoHash, err)
+			}
+			detached = append(detached, dt)
+			storagePaths = append(storagePaths, storagePath)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+		d.reattachTorrents(detached, storagePaths, from)
+		return nil, fmt.Errorf("failed to create model directory: %w", err)
+	}
+	// Models published in place only have their link renamed
+	if err := os.Rename(fromPath, toPath); err != nil {
+		d.reattachTorrents(detached, storagePaths, from)
+		return nil, fmt.Errorf("failed to move model: %w", err)
+	}
+	removeEmptyParents(filepath.Dir(fromPath), storage.GetModelsDir())
+
+	if _, err := os.Stat(encryptedFrom); err == nil {
+		if err := moveDir(encryptedFrom, encryptedTo); err != nil {
+			fmt.Printf("[Daemon] Failed to move encrypted files of %s: %v\n", from, err)
+			moved[encryptedFrom] = encryptedFrom
+		} else {
+			removeEmptyParents(filepath.Dir(encryptedFrom), storage.GetEncryptedDir())
+		}
+	}
+
+	// Published models keep their torrent file under their name
+	torrentFrom := filepath.Join(storage.GetTorrentsDir(), from+".torrent")
+	if _, err := os.Stat(torrentFrom); err == nil {
+		torrentTo := filepath.Join(storage.GetTorrentsDir(), to+".torrent")
+		if err := os.MkdirAll(filepath.Dir(torrentTo), 0755); err == nil {
+			err = os.Rename(torrentFrom, torrentTo)
+		}
+		if err != nil {
+			fmt.Printf("[Daemon] Failed to move torrent file of %s: %v\n", from, err)
+		}
+	}
+
+	for _, dir := range []string{toPath, moved[encryptedFrom]} {
+		if manifest, err := models.ReadManifest(dir); err == nil {
+			manifest.Name = to
+			if err := models.WriteManifest(dir, manifest); err != nil {
+				fmt.Printf("[Daemon] Failed to update manifest in %s: %v\n", dir, err)
+			}
+		}
+	}
+
+	result := &RenameResult{From: from, To: to, Torrents: []string{}}
+	var seedErr error
+	for i, dt := range detached {
+		storagePath := storagePaths[i]
+		if target, ok := moved[storagePath]; ok {
+			storagePath = target
+		}
+		if _, err := d.torrentManager.ReattachTorrent(dt, to, storagePath); err != nil {
+			seedErr = fmt.Errorf("failed to seed torrent %s again: %w", dt.State.InfoHash, err)
+			continue
+		}
+		result.Torrents = append(result.Torrents, dt.State.InfoHash)
+	}
+	if d.transferManager != nil {
+		d.transferManager.RenameModel(from, to)
+	}
+	if err := d.state.Save(); err != nil {
+		fmt.Printf("[Daemon] Warning: failed to save state: %v\n", err)
+	}
+
+	aliases.Rename(from, to)
+	if keepAlias {
+		if err := aliases.Set(from, to); err == nil {
+			result.Alias = true
+		}
+	}
+	if err := aliases.Save(); err != nil {
+		fmt.Printf("[Daemon] Failed to update aliases of %s: %v\n", to, err)
+		result.Alias = false
+	}
+
+	d.reloadRegistryModels(from, to)
+	d.events.Publish(EventModelRenamed, to, "renamed from %s", from)
+	fmt.Printf("[Daemon] Renamed model %s to %s\n", from, to)
+	return result, seedErr
+}
+
+// checkRenamable reports why a model can't be renamed: it is downloading or
+// kept up to date under its name
+func (d *Daemon) checkRenamable(name, modelPath string) error {
+	if storage.IsPartial(modelPath) {
+		return fmt.Errorf("%w, wait for the download to finish", ErrModelInUse)
+	}
+	if d.transferManager != nil {
+		for _, t := range d.transferManager.GetIncompleteTransfers() {
+			if t.Type == TransferTypeDownload && t.ModelName == name && t.Status != TransferStatusFailed {
+				return fmt.Errorf("%w (transfer %s), cancel it first", ErrModelInUse, t.ID)
+			}
+		}
+	}
+	for _, sub := range d.state.GetModelSubscriptions() {
+		if sub.Model == name {
+			return fmt.Errorf("model %s is subscribed to updates under its name, unsubscribe first", name)
+		}
+	}
+	return nil
+}
+
+// reattachTorrents seeds detached torrents again from where they were, after
+// a rename failed
+func (d *Daemon) reattachTorrents(detached []*DetachedTorrent, storagePaths []string, name string) {
+	for i, dt := range detached {
+		if _, err := d.torrentManager.ReattachTorrent(dt, name, storagePaths[i]); err != nil {
+			fmt.Printf("[Daemon] Failed to seed torrent %s again: %v\n", dt.State.InfoHash, err)
+		}
+	}
+}
+
+// moveDir renames the directory from to to, creating the parents of to
+func moveDir(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
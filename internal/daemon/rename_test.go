@@ -0,0 +1,117 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRenameTestDaemon(t *testing.T) *Daemon {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	state := NewState(filepath.Join(t.TempDir(), "state.json"))
+	return &Daemon{
+		state:           state,
+		events:          NewEventLog(10),
+		transferManager: NewTransferManager(nil, state),
+	}
+}
+
+func writeTestModel(t *testing.T, name string) string {
+	modelDir := filepath.Join(storage.GetModelsDir(), name)
+	require.NoError(t, os.MkdirAll(modelDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "model.safetensors"), make([]byte, 1024), 0644))
+	require.NoError(t, models.WriteManifest(modelDir, &types.ModelManifest{Name: name}))
+	return modelDir
+}
+
+func TestAliases(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	writeTestModel(t, "org/model")
+
+	model, err := d.SetAlias("m", "org/model")
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", model)
+	assert.Equal(t, "org/model", d.ResolveModel("m"))
+	assert.Equal(t, "org/other", d.ResolveModel("org/other"))
+
+	// Aliases need an existing model and can't shadow one
+	_, err = d.SetAlias("x", "org/missing")
+	assert.Error(t, err)
+	_, err = d.SetAlias("org/model", "m")
+	assert.Error(t, err)
+	_, err = d.SetAlias("../outside", "org/model")
+	assert.Error(t, err)
+
+	aliases, err := d.ListAliases()
+	require.NoError(t, err)
+	assert.Equal(t, []models.Alias{{Alias: "m", Model: "org/model"}}, aliases)
+
+	require.NoError(t, d.RemoveAlias("m"))
+	assert.ErrorIs(t, d.RemoveAlias("m"), ErrAliasNotFound)
+
+	// Purging a model drops its aliases
+	_, err = d.SetAlias("m", "org/model")
+	require.NoError(t, err)
+	_, err = d.RemoveModel("org/model", true)
+	require.NoError(t, err)
+	aliases, err = d.ListAliases()
+	require.NoError(t, err)
+	assert.Empty(t, aliases)
+}
+
+func TestRenameModel(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	modelDir := writeTestModel(t, "org/model")
+	torrentFile := filepath.Join(storage.GetTorrentsDir(), "org", "model.torrent")
+	require.NoError(t, os.MkdirAll(filepath.Dir(torrentFile), 0755))
+	require.NoError(t, os.WriteFile(torrentFile, []byte("torrent"), 0644))
+	_, err := d.SetAlias("m", "org/model")
+	require.NoError(t, err)
+	seed := d.transferManager.CreateSeed("org/model", "hash")
+
+	result, err := d.RenameModel("m", "team/model", true)
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", result.From)
+	assert.True(t, result.Alias)
+
+	newDir := filepath.Join(storage.GetModelsDir(), "team", "model")
+	assert.NoDirExists(t, modelDir)
+	assert.NoDirExists(t, filepath.Join(storage.GetModelsDir(), "org"))
+	assert.FileExists(t, filepath.Join(newDir, "model.safetensors"))
+	assert.FileExists(t, filepath.Join(storage.GetTorrentsDir(), "team", "model.torrent"))
+
+	manifest, err := models.ReadManifest(newDir)
+	require.NoError(t, err)
+	assert.Equal(t, "team/model", manifest.Name)
+
+	transfer, ok := d.transferManager.GetTransfer(seed.ID)
+	require.True(t, ok)
+	assert.Equal(t, "team/model", transfer.ModelName)
+
+	// Existing aliases and the old name point to the new name
+	assert.Equal(t, "team/model", d.ResolveModel("m"))
+	assert.Equal(t, "team/model", d.ResolveModel("org/model"))
+
+	// Taken names and missing models are refused
+	writeTestModel(t, "org/other")
+	_, err = d.RenameModel("org/other", "team/model", false)
+	assert.Error(t, err)
+	_, err = d.RenameModel("org/missing", "org/new", false)
+	assert.Error(t, err)
+}
+
+func TestRenameModelInUse(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	modelDir := writeTestModel(t, "org/model")
+
+	d.transferManager.CreateDownload("org/model", "hash", 4096)
+	_, err := d.RenameModel("org/model", "org/new", false)
+	assert.ErrorIs(t, err, ErrModelInUse)
+	assert.DirExists(t, modelDir)
+}
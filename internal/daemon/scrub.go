@@ -0,0 +1,212 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often the scrub worker looks for models due for a scrub
+const scrubCheckInterval = 10 * time.Minute
+
+// Pause between pieces, so a scrub doesn't starve uploads of disk bandwidth
+const scrubPieceDelay = 20 * time.Millisecond
+
+// ScrubStatus is the scrub state of a seeded model
+type ScrubStatus struct {
+	Model         string     `json:"model"`
+	InfoHash      string     `json:"info_hash"`
+	LastScrubbed  *time.Time `json:"last_scrubbed,omitempty"`
+	CorruptPieces int        `json:"corrupt_pieces"` // Found by the last scrub
+	Scrubbing     bool       `json:"scrubbing"`
+}
+
+// scrubber runs one scrub at a time
+type scrubber struct {
+	mu      sync.Mutex
+	running string // Infohash being scrubbed
+}
+
+// start marks infoHash as being scrubbed, it returns false if a scrub is
+// already running
+func (s *scrubber) start(infoHash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running != "" {
+		return false
+	}
+	s.running = infoHash
+	return true
+}
+
+func (s *scrubber) finish() {
+	s.mu.Lock()
+	s.running = ""
+	s.mu.Unlock()
+}
+
+func (s *scrubber) current() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// scrubDue reports whether data last verified at last is due for a scrub
+func scrubDue(last time.Time, interval time.Duration, now time.Time) bool {
+	return interval > 0 && !now.Before(last.Add(interval))
+}
+
+// scrubInterval returns the configured time between scrubs of a model, 0 if
+// scrubbing is disabled
+func (d *Daemon) scrubInterval() time.Duration {
+	if d.config == nil {
+		return 0
+	}
+	return time.Duration(d.config.GetInt("storage.scrub_interval_hours")) * time.Hour
+}
+
+// scrubWorker periodically re-verifies the data of seeded models against
+// their piece hashes, so bit rot isn't served to peers
+func (d *Daemon) scrubWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(scrubCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			if mt := d.nextScrub(); mt != nil {
+				d.scrubTorrent(mt)
+			}
+		}
+	}
+}
+
+// nextScrub returns the complete torrent that was verified longest ago if it
+// is due for a scrub. Torrents never scrubbed count from when they were
+// added, as adding them verified their data.
+func (d *Daemon) nextScrub() *ManagedTorrent {
+	interval := d.scrubInterval()
+	if d.torrentManager == nil || interval == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var next *ManagedTorrent
+	var nextLast time.Time
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		if !mt.Complete() {
+			continue
+		}
+		last := mt.AddedAt
+		if ts, ok := d.state.GetTorrentState(mt.InfoHash); ok && ts.LastScrubbed != nil {
+			last = *ts.LastScrubbed
+		}
+		if scrubDue(last, interval, now) && (next == nil || last.Before(nextLast)) {
+			next, nextLast = mt, last
+		}
+	}
+	return next
+}
+
+// ScrubModel starts re-verifying the data of a seeded model now
+func (d *Daemon) ScrubModel(name string) error {
+	if d.torrentManager == nil {
+		return fmt.Errorf("torrent manager not available")
+	}
+
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		if mt.Name != name || isUpdateDownload(mt) {
+			continue
+		}
+		if !mt.Complete() {
+			return fmt.Errorf("model %s is still downloading", name)
+		}
+		if d.scrubs.current() != "" {
+			return fmt.Errorf("a scrub is already running")
+		}
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			d.scrubTorrent(mt)
+		}()
+		return nil
+	}
+	return fmt.Errorf("model %s is not being seeded", name)
+}
+
+// ScrubStatuses returns the scrub state of all seeded models
+func (d *Daemon) ScrubStatuses() []ScrubStatus {
+	statuses := make([]ScrubStatus, 0)
+	if d.torrentManager == nil {
+		return statuses
+	}
+
+	running := d.scrubs.current()
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		if isUpdateDownload(mt) {
+			continue
+		}
+		status := ScrubStatus{
+			Model:     mt.Name,
+			InfoHash:  mt.InfoHash,
+			Scrubbing: strings.EqualFold(mt.InfoHash, running),
+		}
+		if ts, ok := d.state.GetTorrentState(mt.InfoHash); ok {
+			status.LastScrubbed = ts.LastScrubbed
+			status.CorruptPieces = ts.CorruptPieces
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// scrubTorrent re-hashes every complete piece of a torrent. Pieces that no
+// longer match their hash are marked missing, which makes the torrent
+// download them again from peers.
+func (d *Daemon) scrubTorrent(mt *ManagedTorrent) {
+	if !d.scrubs.start(mt.InfoHash) {
+		return
+	}
+	defer d.scrubs.finish()
+
+	t := mt.Torrent
+	fmt.Printf("[Scrub] Verifying %s (%d pieces)\n", mt.Name, t.NumPieces())
+	started := time.Now()
+
+	corrupt := 0
+	for i := 0; i < t.NumPieces(); i++ {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-t.Closed():
+			// Removed while scrubbing, its data is gone or no longer served
+			return
+		case <-time.After(scrubPieceDelay):
+		}
+
+		if !t.PieceState(i).Complete {
+			continue
+		}
+		t.Piece(i).VerifyData()
+		if !t.PieceState(i).Complete {
+			corrupt++
+		}
+	}
+
+	d.state.SetTorrentScrubbed(mt.InfoHash, time.Now(), corrupt)
+	if err := d.state.Save(); err != nil {
+		fmt.Printf("[Scrub] Warning: failed to save state: %v\n", err)
+	}
+
+	if corrupt > 0 {
+		d.events.Publish(EventScrubCorrupt, mt.Name, "%d corrupt pieces found, downloading them again", corrupt)
+		return
+	}
+	fmt.Printf("[Scrub] %s verified in %v, no corrupt pieces\n", mt.Name, time.Since(started).Round(time.Second))
+}
@@ -0,0 +1,40 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrubDue(t *testing.T) {
+	now := time.Now()
+	week := 7 * 24 * time.Hour
+
+	assert.True(t, scrubDue(now.Add(-8*24*time.Hour), week, now))
+	assert.True(t, scrubDue(now.Add(-week), week, now))
+	assert.False(t, scrubDue(now.Add(-time.Hour), week, now))
+
+	// A zero interval disables scrubbing
+	assert.False(t, scrubDue(time.Time{}, 0, now))
+}
+
+func TestScrubberOneAtATime(t *testing.T) {
+	var s scrubber
+
+	assert.True(t, s.start("abcd"))
+	assert.Equal(t, "abcd", s.current())
+	assert.False(t, s.start("ef01"))
+
+	s.finish()
+	assert.Equal(t, "", s.current())
+	assert.True(t, s.start("ef01"))
+}
+
+func TestScrubWithoutTorrentManager(t *testing.T) {
+	d := &Daemon{state: NewState("")}
+
+	assert.Nil(t, d.nextScrub())
+	assert.Empty(t, d.ScrubStatuses())
+	assert.Error(t, d.ScrubModel("org/model"))
+}
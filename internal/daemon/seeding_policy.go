@@ -0,0 +1,193 @@
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// How often seeding policies are checked
+const seedingPolicyInterval = 1 * time.Minute
+
+// SeedingLimits are the seeding limits in effect for a torrent, 0 means
+// unlimited
+type SeedingLimits struct {
+	SeedRatio        float64 `json:"seed_ratio,omitempty"`
+	SeedTime         int64   `json:"seed_time,omitempty"`            // Seconds
+	StopIfNoPeersFor int64   `json:"stop_if_no_peers_for,omitempty"` // Seconds
+}
+
+// seedingLimits merges a model's seeding policy into the global limits
+func seedingLimits(global SeedingLimits, policy *types.SeedingPolicy) SeedingLimits {
+	limits := global
+	if policy == nil {
+		return limits
+	}
+	if policy.SeedRatio != nil {
+		limits.SeedRatio = *policy.SeedRatio
+	}
+	if policy.SeedTime != nil {
+		limits.SeedTime = int64(*policy.SeedTime)
+	}
+	if policy.StopIfNoPeersFor != nil {
+		limits.StopIfNoPeersFor = int64(*policy.StopIfNoPeersFor)
+	}
+	return limits
+}
+
+// seedingStopReason returns why a torrent should stop seeding under limits,
+// or "" if it should keep seeding
+func seedingStopReason(limits SeedingLimits, ratio float64, seeded, idle time.Duration) string {
+	switch {
+	case limits.SeedRatio > 0 && ratio >= limits.SeedRatio:
+		return fmt.Sprintf("seed ratio %.2f reached", limits.SeedRatio)
+	case limits.SeedTime > 0 && seeded >= time.Duration(limits.SeedTime)*time.Second:
+		return fmt.Sprintf("seed time %v reached", time.Duration(limits.SeedTime)*time.Second)
+	case limits.StopIfNoPeersFor > 0 && idle >= time.Duration(limits.StopIfNoPeersFor)*time.Second:
+		return fmt.Sprintf("no peers for %v", time.Duration(limits.StopIfNoPeersFor)*time.Second)
+	}
+	return ""
+}
+
+// globalSeedingLimits returns the seeding limits of the torrent settings
+func (d *Daemon) globalSeedingLimits() SeedingLimits {
+	if d.config == nil {
+		return SeedingLimits{}
+	}
+	return SeedingLimits{
+		SeedRatio:        d.config.GetFloat64("torrent.seed_ratio"),
+		SeedTime:         int64(d.config.GetInt("torrent.seed_time")),
+		StopIfNoPeersFor: int64(d.config.GetInt("torrent.stop_if_no_peers_for")),
+	}
+}
+
+// seedingPolicyWorker stops seeding models that reached their seeding limits
+// and switches models we are the sole seeder of into initial seed mode
+func (d *Daemon) seedingPolicyWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(seedingPolicyInterval)
+	defer ticker.Stop()
+
+	// When each torrent last had a peer, only known to this worker
+	lastPeer := make(map[string]time.Time)
+	lastCheck := time.Now()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.updateSeedModes()
+			d.enforceSeedingPolicies(lastPeer, now.Sub(lastCheck))
+			lastCheck = now
+		}
+	}
+}
+
+// enforceSeedingPolicies updates the seeding progress of all complete
+// torrents and stops seeding those that reached their limits. elapsed is the
+// time since the previous check.
+func (d *Daemon) enforceSeedingPolicies(lastPeer map[string]time.Time, elapsed time.Duration) {
+	if d.torrentManager == nil {
+		return
+	}
+
+	now := time.Now()
+	global := d.globalSeedingLimits()
+	seen := make(map[string]bool)
+
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		t := mt.Torrent
+		if !mt.Complete() || isUpdateDownload(mt) {
+			continue
+		}
+		seen[mt.InfoHash] = true
+
+		stats := t.Stats()
+		if stats.ActivePeers > 0 || lastPeer[mt.InfoHash].IsZero() {
+			lastPeer[mt.InfoHash] = now
+		}
+
+		// Partial seeds count against the size of their selected files
+		var ratio float64
+		if _, length := mt.WantedBytes(); length > 0 {
+			ratio = float64(mt.Uploaded()) / float64(length)
+		}
+		seeded := d.state.AddTorrentSeedingTime(mt.InfoHash, elapsed)
+
+		var policy *types.SeedingPolicy
+		if manifest, err := models.ReadManifest(mt.StoragePath); err == nil {
+			policy = manifest.Seeding
+		}
+		limits := seedingLimits(global, policy)
+		if d.transferManager != nil {
+			d.transferManager.UpdateSeeding(mt.Name, mt.InfoHash, ratio, seeded, limits)
+		}
+
+		if reason := seedingStopReason(limits, ratio, seeded, now.Sub(lastPeer[mt.InfoHash])); reason != "" {
+			// Models pinned for the network are seeded for as long as they are
+			if d.state.IsPinned(mt.InfoHash) {
+				continue
+			}
+			// Peers that can only get some pieces from us would be stranded
+			if d.swarmCoordinatorEnabled() && d.neededBySwarm(mt.InfoHash) {
+				continue
+			}
+			d.stopSeeding(mt, reason)
+			delete(lastPeer, mt.InfoHash)
+		}
+	}
+
+	// Forget torrents that are gone
+	for infoHash := range lastPeer {
+		if !seen[infoHash] {
+			delete(lastPeer, infoHash)
+		}
+	}
+}
+
+// stopSeeding drops a torrent whose seeding limits were reached, freeing its
+// connections, and records why in its transfers and the model manifest. The
+// model files are kept; sharing the model again resumes seeding.
+func (d *Daemon) stopSeeding(mt *ManagedTorrent, reason string) {
+	if err := d.torrentManager.RemoveTorrent(mt.InfoHash); err != nil {
+		fmt.Printf("[Seeding] Failed to stop seeding %s: %v\n", mt.Name, err)
+		return
+	}
+	if d.dhtManager != nil {
+		d.dhtManager.RemoveTorrentFromDHT(mt.InfoHash)
+	}
+	if d.transferManager != nil {
+		d.transferManager.FinishSeeding(mt.InfoHash, reason)
+	}
+
+	if manifest, err := models.ReadManifest(mt.StoragePath); err == nil {
+		if manifest.Seeding == nil {
+			manifest.Seeding = &types.SeedingPolicy{}
+		}
+		now := time.Now()
+		manifest.Seeding.StoppedAt = &now
+		manifest.Seeding.StopReason = reason
+		if err := models.WriteManifest(mt.StoragePath, manifest); err != nil {
+			fmt.Printf("[Seeding] Failed to update manifest of %s: %v\n", mt.Name, err)
+		}
+	}
+
+	if err := d.state.Save(); err != nil {
+		fmt.Printf("[Seeding] Warning: failed to save state: %v\n", err)
+	}
+	d.events.Publish(EventSeedingStopped, mt.Name, "stopped seeding: %s", reason)
+}
+
+// isUpdateDownload reports whether a torrent is a model update waiting to
+// replace the current version, which must not be stopped before the swap
+func isUpdateDownload(mt *ManagedTorrent) bool {
+	updatesDir := filepath.Join(storage.GetBaseDir(), "updates")
+	return strings.HasPrefix(filepath.Clean(mt.StoragePath), updatesDir+string(filepath.Separator))
+}
@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeedingLimits(t *testing.T) {
+	global := SeedingLimits{SeedRatio: 2, SeedTime: 3600}
+
+	// Without a policy the global limits apply
+	assert.Equal(t, global, seedingLimits(global, nil))
+
+	// Set limits override, 0 lifts a global limit
+	ratio := 0.0
+	idle := 600
+	limits := seedingLimits(global, &types.SeedingPolicy{SeedRatio: &ratio, StopIfNoPeersFor: &idle})
+	assert.Equal(t, SeedingLimits{SeedRatio: 0, SeedTime: 3600, StopIfNoPeersFor: 600}, limits)
+}
+
+func TestSeedingStopReason(t *testing.T) {
+	limits := SeedingLimits{SeedRatio: 1.5, SeedTime: 3600, StopIfNoPeersFor: 600}
+
+	assert.Empty(t, seedingStopReason(limits, 1.0, 30*time.Minute, 5*time.Minute))
+	assert.Equal(t, "seed ratio 1.50 reached", seedingStopReason(limits, 1.5, 0, 0))
+	assert.Equal(t, "seed time 1h0m0s reached", seedingStopReason(limits, 0, time.Hour, 0))
+	assert.Equal(t, "no peers for 10m0s", seedingStopReason(limits, 0, 0, 10*time.Minute))
+
+	// Unlimited never stops
+	assert.Empty(t, seedingStopReason(SeedingLimits{}, 100, 1000*time.Hour, 1000*time.Hour))
+}
+
+func TestIsUpdateDownload(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+
+	assert.True(t, isUpdateDownload(&ManagedTorrent{StoragePath: updateDir("org/model")}))
+	assert.False(t, isUpdateDownload(&ManagedTorrent{StoragePath: filepath.Join(storage.GetModelsDir(), "org", "model")}))
+	assert.False(t, isUpdateDownload(&ManagedTorrent{StoragePath: filepath.Join(storage.GetBaseDir(), "updates-old")}))
+}
@@ -0,0 +1,18 @@
+package daemon
+
+import "errors"
+
+// ServiceName is the name the daemon is registered under as a Windows service
+const ServiceName = "silmaril"
+
+// ErrServiceUnsupported is returned when registering the daemon as a service
+// on a system without a service manager silmaril talks to
+var ErrServiceUnsupported = errors.New("installing the daemon as a service is only supported on Windows, run 'silmaril daemon start --foreground' under systemd or launchd instead")
+
+// ServiceOptions describe how the daemon is registered as a service
+type ServiceOptions struct {
+	Executable string   // The silmaril executable
+	Args       []string // Arguments that start the daemon in the foreground
+	Account    string   // Account the service runs as, empty for LocalSystem
+	Password   string   // Password of Account
+}
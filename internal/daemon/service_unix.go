@@ -0,0 +1,24 @@
+//go:build !windows
+
+package daemon
+
+// RunningAsService reports whether the process was started by the Windows
+// service manager, never elsewhere
+func RunningAsService() bool {
+	return false
+}
+
+// RunService is only supported on Windows
+func RunService(d *Daemon, port int) error {
+	return ErrServiceUnsupported
+}
+
+// InstallService is only supported on Windows
+func InstallService(opts ServiceOptions) error {
+	return ErrServiceUnsupported
+}
+
+// UninstallService is only supported on Windows
+func UninstallService() error {
+	return ErrServiceUnsupported
+}
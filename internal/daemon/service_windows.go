@@ -0,0 +1,118 @@
+//go:build windows
+
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// RunningAsService reports whether the process was started by the Windows
+// service manager
+func RunningAsService() bool {
+	isService, err := svc.IsWindowsService()
+	return err == nil && isService
+}
+
+// RunService starts d on port and serves the service manager until it asks
+// the daemon to stop or the daemon is shut down through the API
+func RunService(d *Daemon, port int) error {
+	return svc.Run(ServiceName, &serviceHandler{daemon: d, port: port})
+}
+
+type serviceHandler struct {
+	daemon *Daemon
+	port   int
+}
+
+func (h *serviceHandler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	if err := h.daemon.Start(h.port); err != nil {
+		fmt.Printf("Failed to start daemon: %v\n", err)
+		return true, 1
+	}
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				// Leaving swarms may take the whole grace period
+				waitHint := h.daemon.shutdownGracePeriod() + 10*time.Second
+				status <- svc.Status{State: svc.StopPending, WaitHint: uint32(waitHint.Milliseconds())}
+				h.daemon.Shutdown()
+				return false, 0
+			}
+		case <-h.daemon.Done():
+			return false, 0
+		}
+	}
+}
+
+// InstallService registers the daemon as a Windows service that starts
+// with the system and is restarted when it fails, and starts it
+func InstallService(opts ServiceOptions) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(ServiceName); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s is already installed", ServiceName)
+	}
+
+	s, err := m.CreateService(ServiceName, opts.Executable, mgr.Config{
+		DisplayName:      "Silmaril",
+		Description:      "Shares and downloads AI models peer-to-peer",
+		StartType:        mgr.StartAutomatic,
+		DelayedAutoStart: true,
+		ServiceStartName: opts.Account,
+		Password:         opts.Password,
+	}, opts.Args...)
+	if err != nil {
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+	defer s.Close()
+
+	recovery := []mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 10 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+	}
+	if err := s.SetRecoveryActions(recovery, uint32((24 * time.Hour).Seconds())); err != nil {
+		return fmt.Errorf("failed to set recovery actions: %w", err)
+	}
+	if err := s.Start(); err != nil {
+		return fmt.Errorf("service installed but failed to start: %w", err)
+	}
+	return nil
+}
+
+// UninstallService stops the daemon service and removes it
+func UninstallService() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(ServiceName)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", ServiceName)
+	}
+	defer s.Close()
+
+	// A service that isn't running can't be stopped, it is removed anyway
+	s.Control(svc.Stop)
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("failed to remove service: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"context"
+	"net"
+)
+
+// socketConnKey marks the context of requests that came in on the local
+// socket, see ViaSocket
+type socketConnKey struct{}
+
+// socketListener marks the connections accepted on the local socket
+type socketListener struct {
+	net.Listener
+}
+
+type socketConn struct {
+	net.Conn
+}
+
+func (l socketListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return socketConn{conn}, nil
+}
+
+// socketConnContext is the ConnContext of the API server, recording in the
+// context of a request whether it came in on the local socket
+func socketConnContext(ctx context.Context, conn net.Conn) context.Context {
+	if _, ok := conn.(socketConn); ok {
+		return context.WithValue(ctx, socketConnKey{}, true)
+	}
+	return ctx
+}
+
+// ViaSocket reports whether a request came in on the local socket. Only the
+// user the daemon runs as may connect to it, so such requests are trusted
+// like the holder of an admin token.
+func ViaSocket(ctx context.Context) bool {
+	via, _ := ctx.Value(socketConnKey{}).(bool)
+	return via
+}
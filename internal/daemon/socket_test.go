@@ -0,0 +1,73 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenSocket(t *testing.T) {
+	// Socket paths are short, TempDir names can be too long on macOS
+	dir, err := os.MkdirTemp("", "slm")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "run", "daemon.sock")
+
+	listener, err := listenSocket(path)
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "only the owner may connect")
+
+	// Requests on the socket are told apart from those on the port
+	server := &http.Server{
+		ConnContext: socketConnContext,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(strconv.FormatBool(ViaSocket(r.Context()))))
+		}),
+	}
+	go server.Serve(socketListener{listener})
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", path)
+		},
+	}}
+	resp, err := client.Get("http://daemon/")
+	require.NoError(t, err)
+	body := make([]byte, 8)
+	n, _ := resp.Body.Read(body)
+	resp.Body.Close()
+	assert.Equal(t, "true", string(body[:n]))
+	assert.False(t, ViaSocket(context.Background()))
+
+	// A running daemon keeps its socket
+	_, err = listenSocket(path)
+	assert.ErrorContains(t, err, "in use")
+
+	// A socket left behind by a crashed daemon is replaced
+	require.NoError(t, server.Close())
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	listener, err = listenSocket(path)
+	require.NoError(t, err)
+	listener.Close()
+
+	// Files that aren't sockets are left alone
+	notSocket := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(notSocket, nil, 0644))
+	_, err = listenSocket(notSocket)
+	assert.ErrorContains(t, err, "not a socket")
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package daemon
+
+import (
+	"net"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// listenSocket listens on the named pipe at path, such as \\.\pipe\silmaril.
+// The default security of a pipe only lets its owner, administrators and
+// the system connect to it for writing, see ViaSocket.
+func listenSocket(path string) (net.Listener, error) {
+	return winio.ListenPipe(path, &winio.PipeConfig{InputBufferSize: 64 << 10, OutputBufferSize: 64 << 10})
+}
@@ -0,0 +1,322 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/silmaril/silmaril/internal/models"
+	torrentclient "github.com/silmaril/silmaril/internal/torrent"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// How often downloads look for mirrors in manifests that arrived from peers
+const downloadSourceInterval = 10 * time.Second
+
+// Kinds of sources a download fetches its files from
+const (
+	SourceSwarm  = "swarm"  // BitTorrent peers
+	SourceMirror = "mirror" // HTTP servers holding the model directory
+	SourceIPFS   = "ipfs"   // IPFS gateways serving the files by CID
+)
+
+// downloadSource is a web seed fetching the files of a download over HTTP
+type downloadSource struct {
+	Kind string
+	URL  string // Base URL, the paths or CIDs of files are appended
+}
+
+// SourceThroughput is what a source contributed to a download
+type SourceThroughput struct {
+	Kind            string  `json:"kind"`
+	URL             string  `json:"url,omitempty"`
+	BytesDownloaded int64   `json:"bytes_downloaded"` // Useful data, this session
+	Share           float64 `json:"share"`            // Percentage of the useful data
+}
+
+// downloadSources returns the web seeds holding the files of a torrent
+// described by manifest: the mirrors it lists, the HuggingFace repository it
+// was cloned from at the cloned commit, and the IPFS gateways if it has the
+// CID of every file. Encrypted and derived models have other files than
+// their upstream repository, and encrypted models other files than the CIDs.
+func downloadSources(manifest *types.ModelManifest, info *metainfo.Info, gateways []string) []downloadSource {
+	if manifest.Encryption != nil {
+		return nil
+	}
+
+	var sources []downloadSource
+	add := func(kind, base string) {
+		u, err := url.Parse(base)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return
+		}
+		// Web seeds only append file paths to URLs ending in a slash
+		source := downloadSource{Kind: kind, URL: strings.TrimSuffix(base, "/") + "/"}
+		if !slices.Contains(sources, source) {
+			sources = append(sources, source)
+		}
+	}
+
+	for _, mirror := range manifest.Mirrors {
+		add(SourceMirror, mirror)
+	}
+	if mirror, ok := huggingFaceMirror(manifest.Provenance); ok {
+		add(SourceMirror, mirror)
+	}
+	if hasAllCIDs(manifest, info) {
+		for _, gateway := range gateways {
+			add(SourceIPFS, strings.TrimSuffix(gateway, "/")+"/ipfs/")
+		}
+	}
+	return sources
+}
+
+// huggingFaceMirror returns the URL serving the files of a HuggingFace
+// repository at the commit a model was cloned at
+func huggingFaceMirror(p *types.Provenance) (string, bool) {
+	if p == nil || p.Derived() || p.UpstreamURL == "" || p.UpstreamRevision == "" {
+		return "", false
+	}
+	u, err := url.Parse(p.UpstreamURL)
+	if err != nil || (u.Host != "huggingface.co" && u.Host != "hf.co") {
+		return "", false
+	}
+	// Only models, datasets and spaces have a prefix
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 {
+		return "", false
+	}
+	repo := parts[0] + "/" + strings.TrimSuffix(parts[1], ".git")
+	return fmt.Sprintf("https://%s/%s/resolve/%s/", u.Host, repo, p.UpstreamRevision), true
+}
+
+// hasAllCIDs reports whether the manifest has the IPFS CID of every file of
+// a torrent. Gateways are only used then, requests for files without a CID
+// would fail and slow down the requests for the others.
+func hasAllCIDs(manifest *types.ModelManifest, info *metainfo.Info) bool {
+	if len(manifest.IPFSCIDs) == 0 {
+		return false
+	}
+	for _, file := range info.UpvertedFiles() {
+		if manifest.IPFSCIDs[file.DisplayPath(info)] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// attachSources adds the mirrors, and the configured IPFS gateways, holding
+// the files of downloads as web seeds, unless web seeds are disabled
+func (tm *TorrentManager) attachSources() {
+	if !tm.config.GetBool("torrent.web_seeds") {
+		return
+	}
+	tm.addSources(tm.config.GetStringSlice("torrent.ipfs_gateways"))
+}
+
+// addSources adds the mirrors and IPFS gateways holding the files of
+// downloads as web seeds. The torrent client requests pieces from web seeds
+// like from peers, keeping more requests with the sources that deliver
+// faster, and checks every piece against the torrent whichever source sent
+// it. Manifests of downloads by infohash arrive from peers after the
+// metadata, so this runs periodically.
+func (tm *TorrentManager) addSources(gateways []string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	for _, mt := range tm.torrents {
+		info := mt.Torrent.Info()
+		if mt.Seeding || info == nil || mt.Complete() {
+			continue
+		}
+		manifest, err := models.ReadManifest(mt.StoragePath)
+		if err != nil {
+			continue
+		}
+		for _, source := range downloadSources(manifest, info, gateways) {
+			if slices.Contains(mt.sources, source) {
+				continue
+			}
+			escaper := torrentclient.MirrorPathEscaper
+			if source.Kind == SourceIPFS {
+				escaper = torrentclient.IPFSPathEscaper(manifest.IPFSCIDs)
+			}
+			mt.Torrent.AddWebSeeds([]string{source.URL}, torrent.WebSeedPathEscaper(escaper))
+			mt.sources = append(mt.sources, source)
+			fmt.Printf("[TorrentManager] Downloading %s from %s %s too\n", mt.Name, source.Kind, source.URL)
+		}
+	}
+}
+
+// sourceMeter counts the data web seeds send to each torrent. It is fed by
+// a torrent client callback, which runs with the client locked, so it has a
+// lock of its own.
+type sourceMeter struct {
+	mu    sync.Mutex
+	bytes map[string]map[string]int64 // Infohash -> web seed host -> bytes
+}
+
+func newSourceMeter() *sourceMeter {
+	return &sourceMeter{bytes: make(map[string]map[string]int64)}
+}
+
+// Register counts the useful data received from web seeds
+func (m *sourceMeter) Register(cfg *torrent.ClientConfig) {
+	cfg.Callbacks.ReceivedUsefulData = append(cfg.Callbacks.ReceivedUsefulData, m.received)
+}
+
+func (m *sourceMeter) received(event torrent.ReceivedUsefulDataEvent) {
+	// Web seeds are the peers reached over HTTP, named by their host
+	if event.Peer.Network != "http" || event.Peer.RemoteAddr == nil {
+		return
+	}
+	m.add(event.Peer.Torrent().InfoHash().HexString(), event.Peer.RemoteAddr.String(), int64(len(event.Message.Piece)))
+}
+
+func (m *sourceMeter) add(infoHash, host string, n int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hosts, ok := m.bytes[infoHash]
+	if !ok {
+		hosts = make(map[string]int64)
+		m.bytes[infoHash] = hosts
+	}
+	hosts[host] += n
+}
+
+// get returns the bytes each web seed host sent to a torrent
+func (m *sourceMeter) get(infoHash string) map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hosts := make(map[string]int64, len(m.bytes[infoHash]))
+	for host, n := range m.bytes[infoHash] {
+		hosts[host] = n
+	}
+	return hosts
+}
+
+// forget drops the counts of a torrent that was removed
+func (m *sourceMeter) forget(infoHash string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.bytes, infoHash)
+}
+
+// sourceThroughput splits the useful data a torrent received between the
+// swarm and its web seeds, the sources that sent the most first
+func sourceThroughput(sources []downloadSource, useful int64, byHost map[string]int64) []SourceThroughput {
+	result := make([]SourceThroughput, 0, len(sources)+1)
+	swarm := useful
+	for _, source := range sources {
+		var received int64
+		if u, err := url.Parse(source.URL); err == nil {
+			received = byHost[u.Host]
+		}
+		swarm -= received
+		result = append(result, SourceThroughput{Kind: source.Kind, URL: source.URL, BytesDownloaded: received})
+	}
+	result = append(result, SourceThroughput{Kind: SourceSwarm, BytesDownloaded: max(swarm, 0)})
+
+	for i := range result {
+		if useful > 0 {
+			result[i].Share = float64(result[i].BytesDownloaded) * 100 / float64(useful)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].BytesDownloaded > result[j].BytesDownloaded
+	})
+	return result
+}
+
+func (d *Daemon) downloadSourceWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(downloadSourceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			if d.torrentManager != nil {
+				d.torrentManager.attachSources()
+			}
+		}
+	}
+}
+
+// verifyDownload checks the files of a finished download against the
+// checksums in its manifest and returns those that don't match. Pieces were
+// checked against the torrent as they arrived, this also catches torrents
+// whose files aren't the ones the manifest describes. Files without a
+// checksum, and files left out of the download, are skipped.
+func verifyDownload(ctx context.Context, dir string, manifest *types.ModelManifest) ([]string, error) {
+	var mismatched []string
+	for _, file := range manifest.Files {
+		if file.SHA256 == "" {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		hash, err := models.HashFileRate(ctx, path, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", file.Path, err)
+		}
+		if !strings.EqualFold(hash, file.SHA256) {
+			mismatched = append(mismatched, file.Path)
+		}
+	}
+	return mismatched, nil
+}
+
+// checkDownload hands a finished download to the hooks and converter.
+// Downloads being verified, or waiting for the signatures of their
+// organization, are handed over once they leave quarantine.
+func (d *Daemon) checkDownload(model, infoHash string) {
+	if d.verifyDownloads() || d.organizationModel(model) {
+		return
+	}
+	d.modelReady(model, infoHash)
+}
+
+// verifyDownloads reports whether finished downloads are verified against
+// their manifest before they are listed
+func (d *Daemon) verifyDownloads() bool {
+	return d.config != nil && d.config.GetBool("torrent.verify_downloads")
+}
+
+// downloadMatchesManifest verifies the model downloaded to dir against the
+// checksums in its manifest and publishes the outcome. It returns the files
+// that don't match, or why the model couldn't be verified. Models without a
+// manifest have nothing to be checked against.
+func (d *Daemon) downloadMatchesManifest(model, dir string) ([]string, error) {
+	manifest, err := models.ReadManifest(dir)
+	if err != nil {
+		return nil, nil
+	}
+
+	mismatched, err := verifyDownload(d.ctx, dir, manifest)
+	if err != nil {
+		fmt.Printf("[Download] Failed to verify %s: %v\n", model, err)
+		return nil, err
+	}
+	if len(mismatched) > 0 {
+		fmt.Printf("[Download] %s: %d files don't match their manifest checksum\n", model, len(mismatched))
+		d.events.Publish(EventDownloadCorrupt, model, "files don't match the checksums in the manifest: %s", strings.Join(mismatched, ", "))
+		return mismatched, nil
+	}
+	d.events.Publish(EventDownloadVerified, model, "files match the checksums in the manifest")
+	return nil, nil
+}
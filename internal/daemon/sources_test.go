@@ -0,0 +1,151 @@
+package daemon
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/silmaril/silmaril/internal/models"
+	torrentclient "github.com/silmaril/silmaril/internal/torrent"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadSources(t *testing.T) {
+	info := &metainfo.Info{Files: []metainfo.FileInfo{
+		{Path: []string{"config.json"}, Length: 10},
+		{Path: []string{"model.safetensors"}, Length: 100},
+	}}
+	manifest := &types.ModelManifest{
+		Mirrors: []string{"https://mirror.example.com/org/model", "ftp://old.example.com/model/", "https://mirror.example.com/org/model/"},
+		Provenance: &types.Provenance{
+			UpstreamURL:      "https://huggingface.co/org/model",
+			UpstreamRevision: "0123456789abcdef0123456789abcdef01234567",
+		},
+		IPFSCIDs: map[string]string{"config.json": "bafy1", "model.safetensors": "bafy2"},
+	}
+
+	sources := downloadSources(manifest, info, []string{"https://ipfs.io", "https://dweb.link/"})
+	assert.Equal(t, []downloadSource{
+		{Kind: SourceMirror, URL: "https://mirror.example.com/org/model/"},
+		{Kind: SourceMirror, URL: "https://huggingface.co/org/model/resolve/0123456789abcdef0123456789abcdef01234567/"},
+		{Kind: SourceIPFS, URL: "https://ipfs.io/ipfs/"},
+		{Kind: SourceIPFS, URL: "https://dweb.link/ipfs/"},
+	}, sources)
+
+	// Gateways are only used with the CID of every file
+	delete(manifest.IPFSCIDs, "config.json")
+	assert.Len(t, downloadSources(manifest, info, []string{"https://ipfs.io"}), 2)
+
+	// Derived models aren't the files of their upstream repository
+	manifest.Provenance.Transformation = "gguf:Q4_K_M"
+	assert.Len(t, downloadSources(manifest, info, nil), 1)
+
+	// Encrypted torrents hold other files than any mirror
+	manifest.Encryption = &types.EncryptionInfo{}
+	assert.Empty(t, downloadSources(manifest, info, nil))
+}
+
+func TestHuggingFaceMirror(t *testing.T) {
+	mirror, ok := huggingFaceMirror(&types.Provenance{UpstreamURL: "https://huggingface.co/org/model.git", UpstreamRevision: "abc"})
+	require.True(t, ok)
+	assert.Equal(t, "https://huggingface.co/org/model/resolve/abc/", mirror)
+
+	_, ok = huggingFaceMirror(&types.Provenance{UpstreamURL: "https://huggingface.co/datasets/org/data", UpstreamRevision: "abc"})
+	assert.False(t, ok, "datasets are served from another path")
+	_, ok = huggingFaceMirror(&types.Provenance{UpstreamURL: "https://github.com/org/model", UpstreamRevision: "abc"})
+	assert.False(t, ok)
+	_, ok = huggingFaceMirror(&types.Provenance{UpstreamURL: "https://huggingface.co/org/model"})
+	assert.False(t, ok, "branches move, only commits are the published files")
+	_, ok = huggingFaceMirror(nil)
+	assert.False(t, ok)
+}
+
+func TestSourceThroughput(t *testing.T) {
+	sources := []downloadSource{
+		{Kind: SourceMirror, URL: "https://mirror.example.com/org/model/"},
+		{Kind: SourceIPFS, URL: "https://ipfs.io/ipfs/"},
+	}
+	result := sourceThroughput(sources, 1000, map[string]int64{"mirror.example.com": 600, "ipfs.io": 100})
+	assert.Equal(t, []SourceThroughput{
+		{Kind: SourceMirror, URL: "https://mirror.example.com/org/model/", BytesDownloaded: 600, Share: 60},
+		{Kind: SourceSwarm, BytesDownloaded: 300, Share: 30},
+		{Kind: SourceIPFS, URL: "https://ipfs.io/ipfs/", BytesDownloaded: 100, Share: 10},
+	}, result)
+
+	// Nothing received yet
+	result = sourceThroughput(nil, 0, nil)
+	assert.Equal(t, []SourceThroughput{{Kind: SourceSwarm}}, result)
+}
+
+func TestVerifyDownload(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "model.safetensors"), []byte("weights"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte("{}"), 0644))
+	hash, err := models.HashFileRate(context.Background(), filepath.Join(dir, "model.safetensors"), 0)
+	require.NoError(t, err)
+
+	manifest := &types.ModelManifest{Files: []types.ModelFile{
+		{Path: "model.safetensors", Size: 7, SHA256: hash},
+		{Path: "config.json", Size: 2},                  // No checksum
+		{Path: "tokenizer.json", Size: 5, SHA256: hash}, // Not downloaded
+	}}
+	mismatched, err := verifyDownload(context.Background(), dir, manifest)
+	require.NoError(t, err)
+	assert.Empty(t, mismatched)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "model.safetensors"), []byte("changed"), 0644))
+	mismatched, err = verifyDownload(context.Background(), dir, manifest)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"model.safetensors"}, mismatched)
+}
+
+func TestDownloadFromMirror(t *testing.T) {
+	tm, _, tmpDir := setupTestTorrentManager(t)
+	defer tm.Stop()
+	t.Setenv("SILMARIL_HOME", tmpDir)
+
+	// A model on an HTTP server and nobody seeding it
+	source := filepath.Join(tmpDir, "mirror")
+	require.NoError(t, os.MkdirAll(filepath.Join(source, "weights"), 0755))
+	weights := make([]byte, 3<<20)
+	_, err := rand.Read(weights)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(source, "weights", "model v2.safetensors"), weights, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "config.json"), []byte(`{"model_type":"llama"}`), 0644))
+	server := httptest.NewServer(http.FileServer(http.Dir(source)))
+	defer server.Close()
+
+	torrentPath := filepath.Join(tmpDir, "model.torrent")
+	_, err = torrentclient.CreateTorrentFromDirectory(source, torrentPath, 1<<20, nil)
+	require.NoError(t, err)
+
+	// The manifest lists the mirror
+	dest := filepath.Join(tmpDir, "models", "org", "model")
+	require.NoError(t, os.MkdirAll(dest, 0755))
+	require.NoError(t, models.WriteManifest(dest, &types.ModelManifest{Name: "org/model", Mirrors: []string{server.URL}}))
+
+	mt, err := tm.AddTorrentForDownload(torrentPath, "org/model", dest)
+	require.NoError(t, err)
+	tm.addSources(nil)
+	require.Eventually(t, mt.Complete, 30*time.Second, 100*time.Millisecond)
+
+	got, err := os.ReadFile(filepath.Join(dest, "weights", "model v2.safetensors"))
+	require.NoError(t, err)
+	assert.Equal(t, weights, got)
+
+	stats, err := tm.GetThroughput(mt.InfoHash)
+	require.NoError(t, err)
+	require.Len(t, stats.Sources, 2)
+	assert.Equal(t, SourceMirror, stats.Sources[0].Kind)
+	assert.Equal(t, server.URL+"/", stats.Sources[0].URL)
+	assert.Equal(t, int64(len(weights))+22, stats.Sources[0].BytesDownloaded)
+	assert.Equal(t, float64(100), stats.Sources[0].Share)
+}
@@ -1,23 +1,104 @@
 package daemon
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/store"
 )
 
 type State struct {
 	mu              sync.RWMutex
 	filePath        string
+	store           *store.Store // Replaces the state file if set
+	saveMu          sync.Mutex    // Serializes saves, which share a temporary file
+	saveStatusMu    sync.Mutex
+	saveStatus      SaveStatus
+	changed         chan struct{} // Signals changes not saved yet
 	StartTime       time.Time                  `json:"start_time"`
 	ActiveTorrents  []TorrentState             `json:"active_torrents"`
 	Transfers       map[string]*Transfer       `json:"transfers"`
 	Statistics      Statistics                 `json:"statistics"`
+	Subscriptions   map[string]*Subscription   `json:"subscriptions,omitempty"`
+	ModelSubscriptions map[string]*ModelSubscription `json:"model_subscriptions,omitempty"`
+	LicenseAcceptances map[string]*LicenseAcceptance `json:"license_acceptances,omitempty"`
+	PinnedModels    map[string]*PinnedModel    `json:"pinned_models,omitempty"`
+	APITokens       map[string]*APIToken       `json:"api_tokens,omitempty"`
+	BlockedModels   map[string]*BlockedModel   `json:"blocked_models,omitempty"`
+	Ports           SavedPorts                 `json:"ports"`
+	ManualPeers     map[string][]string        `json:"manual_peers,omitempty"` // Peer addresses by infohash
+	Contribution    Contribution               `json:"contribution"`
 	LastSave        time.Time                  `json:"last_save"`
 }
 
+// Subscription is a followed publisher catalog
+type Subscription struct {
+	PublicKey string    `json:"public_key"`
+	Name      string    `json:"name,omitempty"`
+	AddedAt   time.Time `json:"added_at"`
+
+	// Keys the publisher rotated away from, the oldest first
+	PreviousKeys []string `json:"previous_keys,omitempty"`
+}
+
+// ModelSubscription keeps a local model up to date with the catalog
+type ModelSubscription struct {
+	Model      string    `json:"model"`
+	AutoRemove bool      `json:"auto_remove"` // Delete the old version after an update
+	AddedAt    time.Time `json:"added_at"`
+
+	// Version we have
+	InfoHash string `json:"info_hash,omitempty"`
+	Version  string `json:"version,omitempty"`
+
+	// Version being downloaded
+	PendingInfoHash string `json:"pending_info_hash,omitempty"`
+	PendingVersion  string `json:"pending_version,omitempty"`
+
+	LastChecked *time.Time `json:"last_checked,omitempty"`
+	LastUpdated *time.Time `json:"last_updated,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// LicenseAcceptance records that the user accepted the license of a model
+type LicenseAcceptance struct {
+	Model      string    `json:"model"`
+	License    string    `json:"license"`
+	LicenseURL string    `json:"license_url,omitempty"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+// PinnedModel is a catalog model the daemon downloads and seeds for the
+// network because too few peers seed it
+type PinnedModel struct {
+	Model       string     `json:"model"`
+	InfoHash    string     `json:"info_hash"`
+	Version     string     `json:"version,omitempty"`
+	Size        int64      `json:"size"`
+	Seeders     int        `json:"seeders"` // When it was pinned
+	PinnedAt    time.Time  `json:"pinned_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	// Set once the model was removed by hand, it isn't pinned again
+	RemovedAt *time.Time `json:"removed_at,omitempty"`
+}
+
+// BlockedModel is an entry of the local model blocklist, an infohash or a
+// pattern of model names
+type BlockedModel struct {
+	Pattern string    `json:"pattern"`
+	Reason  string    `json:"reason,omitempty"`
+	AddedAt time.Time `json:"added_at"`
+}
+
 type TorrentState struct {
 	InfoHash      string     `json:"info_hash"`
 	Name          string     `json:"name"`
@@ -26,6 +107,11 @@ type TorrentState struct {
 	Seeding       bool       `json:"seeding"`
 	BytesDown     int64      `json:"bytes_downloaded"`
 	BytesUp       int64      `json:"bytes_uploaded"`
+	StoragePath   string     `json:"storage_path,omitempty"` // Set if not in the models directory
+	SeedingSeconds int64     `json:"seeding_seconds,omitempty"` // Time seeded with all pieces
+	LastScrubbed  *time.Time `json:"last_scrubbed,omitempty"`   // Last re-verification of the data
+	CorruptPieces int        `json:"corrupt_pieces,omitempty"`  // Pieces the last scrub found corrupt
+	Files         []string   `json:"files,omitempty"`           // Patterns of the files seeded, all if empty
 }
 
 type Statistics struct {
@@ -44,27 +130,48 @@ func NewState(filePath string) *State {
 		ActiveTorrents: make([]TorrentState, 0),
 		Transfers:      make(map[string]*Transfer),
 		Statistics:     Statistics{},
+		Subscriptions:  make(map[string]*Subscription),
+		ModelSubscriptions: make(map[string]*ModelSubscription),
+		changed:        make(chan struct{}, 1),
 	}
 }
 
+// Changed signals changes to the state since it was last saved. Frequent
+// counters such as transferred bytes don't signal, they are saved
+// periodically.
+func (s *State) Changed() <-chan struct{} {
+	return s.changed
+}
+
+// markChanged signals a change to the state
+func (s *State) markChanged() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// UseStore keeps the state in st instead of the state file. The state file
+// is imported into st by the next Load.
+func (s *State) UseStore(st *store.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = st
+}
+
 func (s *State) Load() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	data, err := os.ReadFile(s.filePath)
+	loadedState, err := s.read()
 	if err != nil {
-		if os.IsNotExist(err) {
-			// No previous state, start fresh
-			s.Statistics.DaemonStartCount = 1
-			s.Statistics.LastStartTime = time.Now()
-			return nil
-		}
-		return fmt.Errorf("failed to read state file: %w", err)
+		return err
 	}
-
-	var loadedState State
-	if err := json.Unmarshal(data, &loadedState); err != nil {
-		return fmt.Errorf("failed to unmarshal state: %w", err)
+	if loadedState == nil {
+		// No previous state, start fresh
+		s.Statistics.DaemonStartCount = 1
+		s.Statistics.LastStartTime = time.Now()
+		return nil
 	}
 
 	// Preserve current start time
@@ -74,6 +181,29 @@ func (s *State) Load() error {
 	s.ActiveTorrents = loadedState.ActiveTorrents
 	s.Transfers = loadedState.Transfers
 	s.Statistics = loadedState.Statistics
+	s.Contribution = loadedState.Contribution
+	if loadedState.Subscriptions != nil {
+		s.Subscriptions = loadedState.Subscriptions
+	}
+	if loadedState.ModelSubscriptions != nil {
+		s.ModelSubscriptions = loadedState.ModelSubscriptions
+	}
+	if loadedState.LicenseAcceptances != nil {
+		s.LicenseAcceptances = loadedState.LicenseAcceptances
+	}
+	if loadedState.PinnedModels != nil {
+		s.PinnedModels = loadedState.PinnedModels
+	}
+	if loadedState.APITokens != nil {
+		s.APITokens = loadedState.APITokens
+	}
+	if loadedState.BlockedModels != nil {
+		s.BlockedModels = loadedState.BlockedModels
+	}
+	s.Ports = loadedState.Ports
+	if loadedState.ManualPeers != nil {
+		s.ManualPeers = loadedState.ManualPeers
+	}
 	
 	// Update statistics
 	s.StartTime = currentStartTime
@@ -86,32 +216,226 @@ func (s *State) Load() error {
 	return nil
 }
 
-func (s *State) Save() error {
+// read returns the saved state, nil if there is none
+func (s *State) read() (*State, error) {
+	if s.store != nil {
+		return s.readStore()
+	}
+
+	loaded, err := readStateFile(s.filePath)
+	if err == nil && loaded != nil {
+		return loaded, nil
+	}
+	
+	// A damaged state file, or none because a save was interrupted between
+	// its renames, leaves the previous state in the backup
+	backup, backupErr := readStateFile(s.filePath + ".bak")
+	if backupErr != nil || backup == nil {
+		return nil, err
+	}
+	if err != nil {
+		fmt.Printf("[State] %v, loading the backup\n", err)
+	}
+	return backup, nil
+}
+
+// readStateFile reads and validates the state file at path, it returns nil
+// if there is none
+func readStateFile(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var loadedState State
+	if err := json.Unmarshal(data, &loadedState); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+	if err := loadedState.validate(); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+	return &loadedState, nil
+}
+
+// validate checks a loaded state for damage that decoding doesn't catch
+func (s *State) validate() error {
+	for id, transfer := range s.Transfers {
+		if transfer == nil || transfer.ID != id {
+			return fmt.Errorf("transfer %s is damaged", id)
+		}
+	}
+	for _, torrent := range s.ActiveTorrents {
+		if torrent.InfoHash == "" {
+			return fmt.Errorf("torrent %s has no infohash", torrent.Name)
+		}
+	}
+	return nil
+}
+
+// Sections of the state in the state bucket of the store
+const (
+	stateTorrents           = "torrents"
+	stateStatistics         = "statistics"
+	stateSubscriptions      = "subscriptions"
+	stateModelSubscriptions = "model_subscriptions"
+	stateLicenseAcceptances = "license_acceptances"
+	statePinnedModels       = "pinned_models"
+	stateAPITokens          = "api_tokens"
+	stateBlockedModels      = "blocked_models"
+	statePorts              = "ports"
+	stateManualPeers        = "manual_peers"
+	stateContribution       = "contribution"
+)
+
+// readStore returns the state saved in the store. A state file left from
+// before the store is imported first.
+func (s *State) readStore() (*State, error) {
+	_, err := store.ImportFile(s.filePath, func(data []byte) error {
+		var imported State
+		if err := json.Unmarshal(data, &imported); err != nil {
+			return err
+		}
+		fmt.Printf("[State] Importing %s into the database\n", s.filePath)
+		return s.store.Update(imported.write)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := &State{Transfers: make(map[string]*Transfer)}
+	found := false
+	err = s.store.View(func(tx *store.Tx) error {
+		var err error
+		if found, err = tx.Get(store.State, stateStatistics, &loaded.Statistics); err != nil || !found {
+			return err
+		}
+		sections := map[string]interface{}{
+			stateTorrents:           &loaded.ActiveTorrents,
+			stateSubscriptions:      &loaded.Subscriptions,
+			stateModelSubscriptions: &loaded.ModelSubscriptions,
+			stateLicenseAcceptances: &loaded.LicenseAcceptances,
+			statePinnedModels:       &loaded.PinnedModels,
+			stateAPITokens:          &loaded.APITokens,
+			stateBlockedModels:      &loaded.BlockedModels,
+			statePorts:              &loaded.Ports,
+			stateManualPeers:        &loaded.ManualPeers,
+			stateContribution:       &loaded.Contribution,
+		}
+		for key, v := range sections {
+			if _, err := tx.Get(store.State, key, v); err != nil {
+				return err
+			}
+		}
+		return tx.ForEach(store.Transfers, func(id string, data []byte) error {
+			var transfer Transfer
+			if err := json.Unmarshal(data, &transfer); err != nil {
+				return fmt.Errorf("failed to decode transfer %s: %w", id, err)
+			}
+			loaded.Transfers[id] = &transfer
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state from database: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+	if err := loaded.validate(); err != nil {
+		return nil, fmt.Errorf("invalid state in database: %w", err)
+	}
+	return loaded, nil
+}
+
+// write saves the state in tx, the caller holds s.mu
+func (s *State) write(tx *store.Tx) error {
+	sections := map[string]interface{}{
+		stateTorrents:           s.ActiveTorrents,
+		stateStatistics:         s.Statistics,
+		stateSubscriptions:      s.Subscriptions,
+		stateModelSubscriptions: s.ModelSubscriptions,
+		stateLicenseAcceptances: s.LicenseAcceptances,
+		statePinnedModels:       s.PinnedModels,
+		stateAPITokens:          s.APITokens,
+		stateBlockedModels:      s.BlockedModels,
+		statePorts:              s.Ports,
+		stateManualPeers:        s.ManualPeers,
+		stateContribution:       s.Contribution,
+	}
+	for key, v := range sections {
+		if err := tx.Put(store.State, key, v); err != nil {
+			return err
+		}
+	}
+	transfers := make(map[string]interface{}, len(s.Transfers))
+	for id, transfer := range s.Transfers {
+		transfers[id] = transfer
+	}
+	return tx.Replace(store.Transfers, transfers)
+}
+
+// SaveStatus is the outcome of saving the state
+type SaveStatus struct {
+	LastAttempt time.Time `json:"last_attempt"`
+	LastSuccess time.Time `json:"last_success"`
+	LastError   string    `json:"last_error,omitempty"` // Error of the last attempt, if it failed
+}
+
+// SaveStatus returns the outcome of saving the state. It doesn't wait for a
+// save in progress.
+func (s *State) SaveStatus() SaveStatus {
+	s.saveStatusMu.Lock()
+	defer s.saveStatusMu.Unlock()
+	return s.saveStatus
+}
+
+func (s *State) recordSave(err error) {
+	s.saveStatusMu.Lock()
+	defer s.saveStatusMu.Unlock()
+	
+	s.saveStatus.LastAttempt = time.Now()
+	if err != nil {
+		s.saveStatus.LastError = err.Error()
+		return
+	}
+	s.saveStatus.LastSuccess = s.saveStatus.LastAttempt
+	s.saveStatus.LastError = ""
+}
+
+// Save writes the state to the database, or else to the state file. The
+// file is replaced atomically and the previous one kept as a backup.
+func (s *State) Save() (err error) {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	defer func() { s.recordSave(err) }()
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	s.LastSave = time.Now()
 	
+	if s.store != nil {
+		if err := s.store.Update(s.write); err != nil {
+			return fmt.Errorf("failed to save state to database: %w", err)
+		}
+		return nil
+	}
+	
 	data, err := json.MarshalIndent(s, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	// Write to temporary file first
-	tempFile := s.filePath + ".tmp"
-	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+	if err := storage.WriteFileAtomic(s.filePath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write state file: %w", err)
 	}
-
-	// Rename to final location (atomic operation)
-	if err := os.Rename(tempFile, s.filePath); err != nil {
-		return fmt.Errorf("failed to rename state file: %w", err)
-	}
-
 	return nil
 }
 
 func (s *State) AddTorrent(infoHash, name string, addedAt time.Time, seeding bool) {
+	defer s.markChanged()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -134,7 +458,23 @@ func (s *State) AddTorrent(infoHash, name string, addedAt time.Time, seeding boo
 	})
 }
 
+// PutTorrent records the state of a torrent, replacing the state it had
+func (s *State) PutTorrent(torrent TorrentState) {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, t := range s.ActiveTorrents {
+		if t.InfoHash == torrent.InfoHash {
+			s.ActiveTorrents[i] = torrent
+			return
+		}
+	}
+	s.ActiveTorrents = append(s.ActiveTorrents, torrent)
+}
+
 func (s *State) RemoveTorrent(infoHash string) {
+	defer s.markChanged()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -147,7 +487,23 @@ func (s *State) RemoveTorrent(infoHash string) {
 	}
 }
 
+// SetTorrentStoragePath records where the files of a torrent are, for
+// torrents kept outside the models directory
+func (s *State) SetTorrentStoragePath(infoHash, storagePath string) {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, t := range s.ActiveTorrents {
+		if t.InfoHash == infoHash {
+			s.ActiveTorrents[i].StoragePath = storagePath
+			return
+		}
+	}
+}
+
 func (s *State) SetTorrentSeeding(infoHash string, seeding bool) {
+	defer s.markChanged()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -176,7 +532,95 @@ func (s *State) UpdateTorrentStats(infoHash string, bytesDown, bytesUp int64) {
 	}
 }
 
+// AddContribution adds what a torrent transferred to the per model and daily
+// contribution
+func (s *State) AddContribution(sample ContributionSample, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Contribution.add(sample, now)
+}
+
+// GetContribution summarizes the contribution with the last days of daily
+// totals, all of them if days is 0
+func (s *State) GetContribution(days int) ContributionStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.Contribution.stats(days)
+}
+
+// AddTorrentSeedingTime adds to the time a torrent was seeded and returns the
+// total, or 0 if the torrent is unknown
+func (s *State) AddTorrentSeedingTime(infoHash string, d time.Duration) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, t := range s.ActiveTorrents {
+		if t.InfoHash == infoHash {
+			s.ActiveTorrents[i].SeedingSeconds += int64(d.Round(time.Second) / time.Second)
+			return time.Duration(s.ActiveTorrents[i].SeedingSeconds) * time.Second
+		}
+	}
+	return 0
+}
+
+// SetTorrentScrubbed records the result of re-verifying a torrent's data
+func (s *State) SetTorrentScrubbed(infoHash string, at time.Time, corrupt int) {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, t := range s.ActiveTorrents {
+		if t.InfoHash == infoHash {
+			s.ActiveTorrents[i].LastScrubbed = &at
+			s.ActiveTorrents[i].CorruptPieces = corrupt
+			return
+		}
+	}
+}
+
+// SetTorrentFiles records the file patterns a torrent downloads and seeds
+func (s *State) SetTorrentFiles(infoHash string, patterns []string) {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, t := range s.ActiveTorrents {
+		if t.InfoHash == infoHash {
+			s.ActiveTorrents[i].Files = patterns
+			return
+		}
+	}
+}
+
+// TorrentInfoHashes returns the infohashes of all torrents in the state
+func (s *State) TorrentInfoHashes() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infoHashes := make([]string, 0, len(s.ActiveTorrents))
+	for _, t := range s.ActiveTorrents {
+		infoHashes = append(infoHashes, t.InfoHash)
+	}
+	return infoHashes
+}
+
+// GetTorrentState returns a copy of the state of a torrent
+func (s *State) GetTorrentState(infoHash string) (TorrentState, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.ActiveTorrents {
+		if t.InfoHash == infoHash {
+			return t, true
+		}
+	}
+	return TorrentState{}, false
+}
+
 func (s *State) SetTorrentCompleted(infoHash string) {
+	defer s.markChanged()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -191,6 +635,7 @@ func (s *State) SetTorrentCompleted(infoHash string) {
 }
 
 func (s *State) AddTransfer(transfer *Transfer) {
+	defer s.markChanged()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -205,6 +650,7 @@ func (s *State) UpdateTransfers(transfers map[string]*Transfer) {
 }
 
 func (s *State) UpdateTransferStatus(id string, status TransferStatus) {
+	defer s.markChanged()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -218,6 +664,7 @@ func (s *State) UpdateTransferStatus(id string, status TransferStatus) {
 }
 
 func (s *State) RemoveTransfer(id string) {
+	defer s.markChanged()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -244,8 +691,407 @@ func (s *State) GetStatistics() Statistics {
 }
 
 func (s *State) IncrementModelsShared() {
+	defer s.markChanged()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
 	s.Statistics.TotalModelsShared++
-}
\ No newline at end of file
+}
+
+func (s *State) AddSubscription(sub *Subscription) {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if s.Subscriptions == nil {
+		s.Subscriptions = make(map[string]*Subscription)
+	}
+	s.Subscriptions[sub.PublicKey] = sub
+}
+
+func (s *State) RemoveSubscription(publicKey string) bool {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if _, exists := s.Subscriptions[publicKey]; !exists {
+		return false
+	}
+	delete(s.Subscriptions, publicKey)
+	return true
+}
+
+// RotateSubscription moves the subscription of a publisher key to the key
+// it was rotated to. It reports whether the old key was subscribed.
+func (s *State) RotateSubscription(oldKey, newKey string) bool {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, exists := s.Subscriptions[oldKey]
+	if !exists {
+		return false
+	}
+	delete(s.Subscriptions, oldKey)
+	if _, exists := s.Subscriptions[newKey]; exists {
+		return true
+	}
+	rotated := *sub
+	rotated.PublicKey = newKey
+	rotated.PreviousKeys = append(append([]string(nil), sub.PreviousKeys...), oldKey)
+	s.Subscriptions[newKey] = &rotated
+	return true
+}
+
+func (s *State) GetSubscriptions() []*Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	subs := make([]*Subscription, 0, len(s.Subscriptions))
+	for _, sub := range s.Subscriptions {
+		subCopy := *sub
+		subs = append(subs, &subCopy)
+	}
+	return subs
+}
+
+// AddModelSubscription records a model subscription, replacing an existing
+// one for the same model
+func (s *State) AddModelSubscription(sub *ModelSubscription) {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ModelSubscriptions == nil {
+		s.ModelSubscriptions = make(map[string]*ModelSubscription)
+	}
+	s.ModelSubscriptions[sub.Model] = sub
+}
+
+// RemoveModelSubscription removes a model subscription and returns it, or
+// nil if there was none
+func (s *State) RemoveModelSubscription(model string) *ModelSubscription {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, exists := s.ModelSubscriptions[model]
+	if !exists {
+		return nil
+	}
+	delete(s.ModelSubscriptions, model)
+	return sub
+}
+
+// UpdateModelSubscription changes a model subscription in place and reports
+// whether it exists
+func (s *State) UpdateModelSubscription(model string, update func(sub *ModelSubscription)) bool {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, exists := s.ModelSubscriptions[model]
+	if !exists {
+		return false
+	}
+	update(sub)
+	return true
+}
+
+// GetModelSubscriptions returns copies of all model subscriptions
+func (s *State) GetModelSubscriptions() []*ModelSubscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subs := make([]*ModelSubscription, 0, len(s.ModelSubscriptions))
+	for _, sub := range s.ModelSubscriptions {
+		subCopy := *sub
+		subs = append(subs, &subCopy)
+	}
+	return subs
+}
+
+// AcceptLicense records the acceptance of a model license, replacing an
+// earlier acceptance of other terms
+func (s *State) AcceptLicense(acceptance *LicenseAcceptance) {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.LicenseAcceptances == nil {
+		s.LicenseAcceptances = make(map[string]*LicenseAcceptance)
+	}
+	s.LicenseAcceptances[acceptance.Model] = acceptance
+}
+
+// LicenseAccepted reports whether the given license terms of a model were
+// accepted. Changed terms have to be accepted again.
+func (s *State) LicenseAccepted(model, license, licenseURL string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	acceptance, exists := s.LicenseAcceptances[model]
+	return exists && acceptance.License == license && acceptance.LicenseURL == licenseURL
+}
+
+// UpdateAPITokens changes the API tokens in place. update must leave them
+// unchanged if it fails.
+func (s *State) UpdateAPITokens(update func(tokens map[string]*APIToken) error) error {
+	s.mu.Lock()
+	if s.APITokens == nil {
+		s.APITokens = make(map[string]*APIToken)
+	}
+	err := update(s.APITokens)
+	s.mu.Unlock()
+
+	if err == nil {
+		s.markChanged()
+	}
+	return err
+}
+
+// HasAPITokens reports whether any API token exists
+func (s *State) HasAPITokens() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.APITokens) > 0
+}
+
+// ListAPITokens returns copies of the API tokens sorted by name
+func (s *State) ListAPITokens() []APIToken {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := make([]APIToken, 0, len(s.APITokens))
+	for _, token := range s.APITokens {
+		tokens = append(tokens, *token)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Name < tokens[j].Name })
+	return tokens
+}
+
+// FindAPIToken returns a copy of the API token with a hash
+func (s *State) FindAPIToken(hash string) (APIToken, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, token := range s.APITokens {
+		if subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hash)) == 1 {
+			return *token, true
+		}
+	}
+	return APIToken{}, false
+}
+
+// PinModel records a model pinned for the network, replacing an earlier pin
+// of the same model
+func (s *State) PinModel(pin *PinnedModel) {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.PinnedModels == nil {
+		s.PinnedModels = make(map[string]*PinnedModel)
+	}
+	s.PinnedModels[pin.Model] = pin
+}
+
+// UpdatePinnedModel changes a pinned model in place and reports whether it
+// is pinned
+func (s *State) UpdatePinnedModel(model string, update func(pin *PinnedModel)) bool {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pin, exists := s.PinnedModels[model]
+	if !exists {
+		return false
+	}
+	update(pin)
+	return true
+}
+
+// GetPinnedModels returns copies of all pinned models
+func (s *State) GetPinnedModels() []*PinnedModel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pins := make([]*PinnedModel, 0, len(s.PinnedModels))
+	for _, pin := range s.PinnedModels {
+		pinCopy := *pin
+		pins = append(pins, &pinCopy)
+	}
+	return pins
+}
+
+// IsPinned reports whether the torrent of a model pinned for the network is
+// the one with infoHash
+func (s *State) IsPinned(infoHash string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, pin := range s.PinnedModels {
+		if pin.RemovedAt == nil && strings.EqualFold(pin.InfoHash, infoHash) {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockModel adds an entry to the local model blocklist, replacing the
+// reason of an existing one
+func (s *State) BlockModel(blocked *BlockedModel) {
+	defer s.markChanged()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.BlockedModels == nil {
+		s.BlockedModels = make(map[string]*BlockedModel)
+	}
+	s.BlockedModels[blocked.Pattern] = blocked
+}
+
+// UnblockModel removes an entry from the local model blocklist and reports
+// whether it was there
+func (s *State) UnblockModel(pattern string) bool {
+	s.mu.Lock()
+	_, exists := s.BlockedModels[pattern]
+	delete(s.BlockedModels, pattern)
+	s.mu.Unlock()
+
+	if exists {
+		s.markChanged()
+	}
+	return exists
+}
+
+// GetBlockedModels returns copies of the entries of the local model
+// blocklist, oldest first
+func (s *State) GetBlockedModels() []*BlockedModel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	blocked := make([]*BlockedModel, 0, len(s.BlockedModels))
+	for _, entry := range s.BlockedModels {
+		entryCopy := *entry
+		blocked = append(blocked, &entryCopy)
+	}
+	sort.Slice(blocked, func(i, j int) bool {
+		return blocked[i].AddedAt.Before(blocked[j].AddedAt)
+	})
+	return blocked
+}
+
+// SavedPorts returns the ports the daemon picked itself last time
+func (s *State) SavedPorts() SavedPorts {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Ports
+}
+
+// SavePeerPort remembers the port the torrent client listens on
+func (s *State) SavePeerPort(port int) {
+	s.mu.Lock()
+	changed := port > 0 && s.Ports.Peer != port
+	if changed {
+		s.Ports.Peer = port
+	}
+	s.mu.Unlock()
+
+	if changed {
+		s.markChanged()
+	}
+}
+
+// SaveDHTPort remembers the port the DHT listens on
+func (s *State) SaveDHTPort(port int) {
+	s.mu.Lock()
+	changed := port > 0 && s.Ports.DHT != port
+	if changed {
+		s.Ports.DHT = port
+	}
+	s.mu.Unlock()
+
+	if changed {
+		s.markChanged()
+	}
+}
+
+// AddManualPeers remembers peer addresses added to a torrent by hand and
+// returns all of them. Addresses already known are not added twice.
+func (s *State) AddManualPeers(infoHash string, peers []string) []string {
+	s.mu.Lock()
+	if s.ManualPeers == nil {
+		s.ManualPeers = make(map[string][]string)
+	}
+	known := s.ManualPeers[infoHash]
+	changed := false
+	for _, peer := range peers {
+		if !slices.Contains(known, peer) {
+			known = append(known, peer)
+			changed = true
+		}
+	}
+	s.ManualPeers[infoHash] = known
+	result := slices.Clone(known)
+	s.mu.Unlock()
+
+	if changed {
+		s.markChanged()
+	}
+	return result
+}
+
+// RemoveManualPeer forgets a peer address added to a torrent by hand and
+// reports whether it was there
+func (s *State) RemoveManualPeer(infoHash, peer string) bool {
+	s.mu.Lock()
+	known := s.ManualPeers[infoHash]
+	i := slices.Index(known, peer)
+	if i >= 0 {
+		known = slices.Delete(known, i, i+1)
+		if len(known) == 0 {
+			delete(s.ManualPeers, infoHash)
+		} else {
+			s.ManualPeers[infoHash] = known
+		}
+	}
+	s.mu.Unlock()
+
+	if i >= 0 {
+		s.markChanged()
+	}
+	return i >= 0
+}
+
+// ForgetManualPeers drops the peers added by hand to a torrent that was
+// removed
+func (s *State) ForgetManualPeers(infoHash string) {
+	s.mu.Lock()
+	_, exists := s.ManualPeers[infoHash]
+	delete(s.ManualPeers, infoHash)
+	s.mu.Unlock()
+
+	if exists {
+		s.markChanged()
+	}
+}
+
+// GetManualPeers returns the peer addresses added by hand to a torrent
+func (s *State) GetManualPeers(infoHash string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.ManualPeers[infoHash])
+}
+
+// GetAllManualPeers returns the peer addresses added by hand, by infohash
+func (s *State) GetAllManualPeers() map[string][]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string][]string, len(s.ManualPeers))
+	for infoHash, peers := range s.ManualPeers {
+		result[infoHash] = slices.Clone(peers)
+	}
+	return result
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/silmaril/silmaril/internal/store"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -279,4 +280,295 @@ func TestStateConcurrency(t *testing.T) {
 	// Verify final state
 	assert.Equal(t, 10, s.Statistics.TotalModelsShared)
 	assert.Len(t, s.ActiveTorrents, 10)
-}
\ No newline at end of file
+}
+func TestStateSubscriptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+
+	s := NewState(stateFile)
+	s.AddSubscription(&Subscription{PublicKey: "abcd", Name: "acme", AddedAt: time.Now()})
+	require.NoError(t, s.Save())
+
+	// Subscriptions survive a restart
+	s2 := NewState(stateFile)
+	require.NoError(t, s2.Load())
+	subs := s2.GetSubscriptions()
+	require.Len(t, subs, 1)
+	assert.Equal(t, "abcd", subs[0].PublicKey)
+	assert.Equal(t, "acme", subs[0].Name)
+
+	// Returned subscriptions are copies
+	subs[0].Name = "changed"
+	assert.Equal(t, "acme", s2.GetSubscriptions()[0].Name)
+
+	assert.True(t, s2.RemoveSubscription("abcd"))
+	assert.False(t, s2.RemoveSubscription("abcd"))
+	assert.Empty(t, s2.GetSubscriptions())
+}
+
+func TestStateRotateSubscription(t *testing.T) {
+	s := NewState(filepath.Join(t.TempDir(), "state.json"))
+	s.AddSubscription(&Subscription{PublicKey: "aa", Name: "acme", AddedAt: time.Now()})
+
+	assert.False(t, s.RotateSubscription("ff", "bb"))
+	assert.True(t, s.RotateSubscription("aa", "bb"))
+	assert.True(t, s.RotateSubscription("bb", "cc"))
+	subs := s.GetSubscriptions()
+	require.Len(t, subs, 1)
+	assert.Equal(t, "cc", subs[0].PublicKey)
+	assert.Equal(t, "acme", subs[0].Name)
+	assert.Equal(t, []string{"aa", "bb"}, subs[0].PreviousKeys)
+
+	// Subscribed to both keys, the old subscription goes
+	s.AddSubscription(&Subscription{PublicKey: "dd", AddedAt: time.Now()})
+	assert.True(t, s.RotateSubscription("dd", "cc"))
+	subs = s.GetSubscriptions()
+	require.Len(t, subs, 1)
+	assert.Equal(t, []string{"aa", "bb"}, subs[0].PreviousKeys)
+}
+
+func TestStateModelSubscriptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+
+	s := NewState(stateFile)
+	s.AddModelSubscription(&ModelSubscription{Model: "org/model", AutoRemove: true, AddedAt: time.Now()})
+	assert.True(t, s.UpdateModelSubscription("org/model", func(sub *ModelSubscription) {
+		sub.InfoHash = "abcd"
+		sub.Version = "v1"
+	}))
+	assert.False(t, s.UpdateModelSubscription("org/other", func(sub *ModelSubscription) {}))
+	require.NoError(t, s.Save())
+
+	// Subscriptions survive a restart
+	s2 := NewState(stateFile)
+	require.NoError(t, s2.Load())
+	subs := s2.GetModelSubscriptions()
+	require.Len(t, subs, 1)
+	assert.Equal(t, "org/model", subs[0].Model)
+	assert.True(t, subs[0].AutoRemove)
+	assert.Equal(t, "abcd", subs[0].InfoHash)
+	assert.Equal(t, "v1", subs[0].Version)
+
+	// Returned subscriptions are copies
+	subs[0].Version = "changed"
+	assert.Equal(t, "v1", s2.GetModelSubscriptions()[0].Version)
+
+	removed := s2.RemoveModelSubscription("org/model")
+	require.NotNil(t, removed)
+	assert.Equal(t, "abcd", removed.InfoHash)
+	assert.Nil(t, s2.RemoveModelSubscription("org/model"))
+	assert.Empty(t, s2.GetModelSubscriptions())
+}
+
+func TestStateTorrentStoragePath(t *testing.T) {
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+
+	s := NewState(stateFile)
+	s.AddTorrent("abcd", "org/model", time.Now(), false)
+	s.SetTorrentStoragePath("abcd", "/data/updates/org/model")
+	require.NoError(t, s.Save())
+
+	s2 := NewState(stateFile)
+	require.NoError(t, s2.Load())
+	require.Len(t, s2.ActiveTorrents, 1)
+	assert.Equal(t, "/data/updates/org/model", s2.ActiveTorrents[0].StoragePath)
+}
+
+func TestStateTorrentSeedingTime(t *testing.T) {
+	s := NewState("")
+	s.AddTorrent("abcd", "org/model", time.Now(), true)
+
+	assert.Equal(t, time.Minute, s.AddTorrentSeedingTime("abcd", time.Minute))
+	assert.Equal(t, 2*time.Minute, s.AddTorrentSeedingTime("abcd", time.Minute))
+	assert.Equal(t, int64(120), s.ActiveTorrents[0].SeedingSeconds)
+
+	// Unknown torrents are ignored
+	assert.Equal(t, time.Duration(0), s.AddTorrentSeedingTime("unknown", time.Minute))
+}
+
+func TestStateTorrentScrubbed(t *testing.T) {
+	s := NewState("")
+	s.AddTorrent("abcd", "org/model", time.Now(), true)
+
+	ts, ok := s.GetTorrentState("abcd")
+	require.True(t, ok)
+	assert.Nil(t, ts.LastScrubbed)
+
+	scrubbed := time.Now()
+	s.SetTorrentScrubbed("abcd", scrubbed, 3)
+	ts, ok = s.GetTorrentState("abcd")
+	require.True(t, ok)
+	require.NotNil(t, ts.LastScrubbed)
+	assert.True(t, scrubbed.Equal(*ts.LastScrubbed))
+	assert.Equal(t, 3, ts.CorruptPieces)
+
+	_, ok = s.GetTorrentState("unknown")
+	assert.False(t, ok)
+}
+
+func TestStateTorrentFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := NewState(path)
+	s.AddTorrent("abcd", "org/model", time.Now(), true)
+	s.SetTorrentFiles("abcd", []string{"*Q4_K_M.gguf"})
+	require.NoError(t, s.Save())
+
+	s2 := NewState(path)
+	require.NoError(t, s2.Load())
+	require.Len(t, s2.ActiveTorrents, 1)
+	assert.Equal(t, []string{"*Q4_K_M.gguf"}, s2.ActiveTorrents[0].Files)
+}
+
+func TestStateLicenseAcceptances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := NewState(path)
+	assert.False(t, s.LicenseAccepted("org/model", "llama3", "https://example.com/LICENSE"))
+
+	s.AcceptLicense(&LicenseAcceptance{
+		Model:      "org/model",
+		License:    "llama3",
+		LicenseURL: "https://example.com/LICENSE",
+		AcceptedAt: time.Now(),
+	})
+	require.NoError(t, s.Save())
+
+	s2 := NewState(path)
+	require.NoError(t, s2.Load())
+	assert.True(t, s2.LicenseAccepted("org/model", "llama3", "https://example.com/LICENSE"))
+
+	// Changed terms have to be accepted again
+	assert.False(t, s2.LicenseAccepted("org/model", "llama3", "https://example.com/LICENSE-v2"))
+	assert.False(t, s2.LicenseAccepted("org/other", "llama3", "https://example.com/LICENSE"))
+}
+
+func TestStateSavedPorts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := NewState(path)
+	assert.Equal(t, SavedPorts{}, s.SavedPorts())
+
+	s.SavePeerPort(42069)
+	s.SaveDHTPort(6882)
+	s.SaveDHTPort(0)
+	require.NoError(t, s.Save())
+
+	s2 := NewState(path)
+	require.NoError(t, s2.Load())
+	assert.Equal(t, SavedPorts{Peer: 42069, DHT: 6882}, s2.SavedPorts())
+}
+
+func TestStateManualPeers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := NewState(path)
+
+	assert.Equal(t, []string{"10.0.0.5:6881"}, s.AddManualPeers("abc", []string{"10.0.0.5:6881"}))
+	assert.Equal(t, []string{"10.0.0.5:6881", "lab-b:6881"}, s.AddManualPeers("abc", []string{"lab-b:6881", "10.0.0.5:6881"}))
+	s.AddManualPeers("def", []string{"10.0.0.6:6881"})
+	require.NoError(t, s.Save())
+
+	s2 := NewState(path)
+	require.NoError(t, s2.Load())
+	assert.Equal(t, map[string][]string{
+		"abc": {"10.0.0.5:6881", "lab-b:6881"},
+		"def": {"10.0.0.6:6881"},
+	}, s2.GetAllManualPeers())
+
+	assert.True(t, s2.RemoveManualPeer("def", "10.0.0.6:6881"))
+	assert.False(t, s2.RemoveManualPeer("def", "10.0.0.6:6881"))
+	s2.ForgetManualPeers("abc")
+	assert.Empty(t, s2.GetAllManualPeers())
+}
+
+func TestStateStore(t *testing.T) {
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+
+	// A state file from before the database
+	s := NewState(stateFile)
+	s.AddTorrent("test-hash", "test-model", time.Now(), true)
+	s.AddTransfer(&Transfer{ID: "t1", Type: TransferTypeDownload, Status: TransferStatusActive, ModelName: "test-model", StartedAt: time.Now()})
+	s.AddSubscription(&Subscription{PublicKey: "key", AddedAt: time.Now()})
+	s.Statistics.TotalUploaded = 500
+	require.NoError(t, s.Save())
+
+	st, err := store.Open(filepath.Join(tmpDir, "db"))
+	require.NoError(t, err)
+	defer st.Close()
+
+	s2 := NewState(stateFile)
+	s2.UseStore(st)
+	require.NoError(t, s2.Load())
+	assert.NoFileExists(t, stateFile, "the state file is imported once")
+	assert.FileExists(t, stateFile+".migrated")
+	require.Len(t, s2.ActiveTorrents, 1)
+	assert.Equal(t, "test-model", s2.ActiveTorrents[0].Name)
+	assert.Contains(t, s2.Transfers, "t1")
+	assert.Len(t, s2.GetSubscriptions(), 1)
+	assert.Equal(t, int64(500), s2.Statistics.TotalUploaded)
+
+	// Later saves and loads go through the database
+	s2.RemoveTransfer("t1")
+	s2.Statistics.TotalUploaded = 700
+	require.NoError(t, s2.Save())
+	assert.NoFileExists(t, stateFile)
+
+	s3 := NewState(stateFile)
+	s3.UseStore(st)
+	require.NoError(t, s3.Load())
+	assert.Empty(t, s3.Transfers)
+	assert.Equal(t, int64(700), s3.Statistics.TotalUploaded)
+	assert.Equal(t, s2.Statistics.DaemonStartCount+1, s3.Statistics.DaemonStartCount)
+}
+
+func TestStateSaveKeepsBackup(t *testing.T) {
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+
+	s := NewState(stateFile)
+	s.AddTorrent("hash-1", "model-1", time.Now(), true)
+	require.NoError(t, s.Save())
+	s.AddTorrent("hash-2", "model-2", time.Now(), true)
+	require.NoError(t, s.Save())
+	assert.FileExists(t, stateFile+".bak")
+	assert.NoFileExists(t, stateFile+".tmp")
+
+	// A state file torn by a crash falls back to the previous save
+	require.NoError(t, os.WriteFile(stateFile, []byte(`{"active_torrents": [{"info_`), 0644))
+	s2 := NewState(stateFile)
+	require.NoError(t, s2.Load())
+	require.Len(t, s2.ActiveTorrents, 1)
+	assert.Equal(t, "hash-1", s2.ActiveTorrents[0].InfoHash)
+
+	// As does a save interrupted between its renames
+	require.NoError(t, os.Remove(stateFile))
+	s3 := NewState(stateFile)
+	require.NoError(t, s3.Load())
+	assert.Len(t, s3.ActiveTorrents, 1)
+
+	// Without a backup the damage is reported
+	require.NoError(t, os.WriteFile(stateFile, []byte(`{"transfers": {"t1": {"id": "t2"}}}`), 0644))
+	require.NoError(t, os.Remove(stateFile+".bak"))
+	assert.ErrorContains(t, NewState(stateFile).Load(), "damaged")
+}
+
+func TestStateSignalsChanges(t *testing.T) {
+	s := NewState(filepath.Join(t.TempDir(), "state.json"))
+
+	// Transfer counters are saved periodically, not on every update
+	s.AddTorrent("hash", "model", time.Now(), true)
+	<-s.Changed()
+	s.UpdateTorrentStats("hash", 10, 20)
+	select {
+	case <-s.Changed():
+		t.Fatal("counter updates should not signal a change")
+	default:
+	}
+
+	s.AcceptLicense(&LicenseAcceptance{Model: "model", License: "mit"})
+	select {
+	case <-s.Changed():
+	default:
+		t.Fatal("accepting a license should signal a change")
+	}
+}
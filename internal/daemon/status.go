@@ -0,0 +1,201 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/version"
+)
+
+// HealthDegraded is the status of a component that works but needs
+// attention, such as storage running out of space
+const HealthDegraded = "degraded"
+
+const (
+	// Free space below which storage is reported as degraded
+	lowFreeSpace = 1 << 30
+
+	// Age of a published catalog reference after which it may have expired
+	// from the DHT, BEP44 items are kept for about two hours
+	staleCatalogPublish = 2 * time.Hour
+)
+
+// Components of the daemon in the order the status panel shows them
+var StatusComponents = []string{"torrent_client", "dht", "catalog", "storage", "state"}
+
+// ComponentStatus is the health of a subsystem of the daemon. Message sums
+// up the details for people, Details holds them for programs.
+type ComponentStatus struct {
+	Status  string                 `json:"status"`
+	Message string                 `json:"message,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Uptime is how long the daemon has been running
+type Uptime struct {
+	StartedAt time.Time `json:"started_at"`
+	Seconds   int64     `json:"seconds"`
+}
+
+// Components returns the status of each subsystem of the daemon
+func (d *Daemon) Components() map[string]ComponentStatus {
+	return map[string]ComponentStatus{
+		"torrent_client": d.torrentClientStatus(),
+		"dht":            d.dhtStatus(),
+		"catalog":        d.catalogStatus(time.Now()),
+		"storage":        d.storageStatus(),
+		"state":          stateStatus(d.state.SaveStatus(), d.state.store != nil),
+	}
+}
+
+func (d *Daemon) torrentClientStatus() ComponentStatus {
+	check := d.checkTorrentClient()
+	status := ComponentStatus{Status: check.Status, Message: check.Message}
+	if check.Status == HealthOK {
+		conns := d.ConnectionStats()
+		status.Details = map[string]interface{}{
+			"torrents":        len(d.torrentManager.GetAllTorrents()),
+			"peers":           d.torrentManager.GetTotalPeers(),
+			"listen_addrs":    d.torrentManager.GetListenAddrs(),
+			"connections":     conns.Connected,
+			"half_open":       conns.HalfOpen,
+			"max_connections": conns.Limits.MaxConnections,
+		}
+	}
+	return status
+}
+
+func (d *Daemon) dhtStatus() ComponentStatus {
+	check := d.checkDHT()
+	status := ComponentStatus{Status: check.Status, Message: check.Message}
+	if d.dhtManager != nil && check.Status != HealthDisabled {
+		bootstrap := d.dhtManager.GetBootstrapState()
+		status.Details = map[string]interface{}{
+			"nodes":        d.dhtManager.GetNodeCount(),
+			"bootstrapped": bootstrap.Status == BootstrapReady,
+			"last_success": bootstrap.LastSuccess,
+		}
+	}
+	return status
+}
+
+func (d *Daemon) catalogStatus(now time.Time) ComponentStatus {
+	if d.config != nil && !d.config.Network.DHTEnabled {
+		return ComponentStatus{Status: HealthDisabled, Message: "the catalog is published in the DHT, which is disabled"}
+	}
+	if d.dhtManager == nil || d.dhtManager.GetCatalogRef() == nil {
+		return ComponentStatus{Status: HealthFailing, Message: "catalog not available"}
+	}
+	ref := d.dhtManager.GetCatalogRef()
+	return catalogComponentStatus(ref.Reference(), ref.ModelCount(), ref.PublishStatus(), now)
+}
+
+// catalogComponentStatus reports the local catalog and how publishing it
+// went. Publishing failing since the last success makes the catalog
+// degraded, as does a last success older than the DHT keeps the reference.
+func catalogComponentStatus(ref *discovery.CatalogReference, models int, publish discovery.PublishStatus, now time.Time) ComponentStatus {
+	details := map[string]interface{}{"models": models}
+	message := fmt.Sprintf("%d models", models)
+	if ref != nil {
+		updated := time.Unix(ref.Updated, 0)
+		details["infohash"] = ref.InfoHash
+		details["sequence"] = ref.Sequence
+		details["updated_at"] = updated
+		details["age_seconds"] = int64(now.Sub(updated).Seconds())
+		message += fmt.Sprintf(", seq %d, updated %s ago", ref.Sequence, formatAge(now.Sub(updated)))
+	}
+	if !publish.LastAttempt.IsZero() {
+		details["last_publish_attempt"] = publish.LastAttempt
+	}
+	if !publish.LastSuccess.IsZero() {
+		details["last_publish"] = publish.LastSuccess
+	}
+	if publish.LastError != "" {
+		details["last_publish_error"] = publish.LastError
+	}
+
+	status := HealthOK
+	switch {
+	case publish.LastError != "":
+		status = HealthDegraded
+		message += ", publishing failed: " + publish.LastError
+	case publish.LastSuccess.IsZero():
+		message += ", not published yet"
+	case now.Sub(publish.LastSuccess) > staleCatalogPublish:
+		status = HealthDegraded
+		message += fmt.Sprintf(", last published %s ago", formatAge(now.Sub(publish.LastSuccess)))
+	default:
+		message += fmt.Sprintf(", published %s ago", formatAge(now.Sub(publish.LastSuccess)))
+	}
+	return ComponentStatus{Status: status, Message: message, Details: details}
+}
+
+func (d *Daemon) storageStatus() ComponentStatus {
+	check := checkStorageWritable(d.storageDirs())
+	if check.Status != HealthOK {
+		return ComponentStatus{Status: check.Status, Message: check.Message}
+	}
+	paths, err := storage.NewPaths()
+	if err != nil {
+		return ComponentStatus{Status: HealthFailing, Message: err.Error()}
+	}
+	free, err := storage.FreeSpace(paths.ModelsDir())
+	if err != nil {
+		return ComponentStatus{Status: HealthOK, Message: fmt.Sprintf("free space unknown: %v", err)}
+	}
+	return storageComponentStatus(paths.ModelsDir(), free)
+}
+
+func storageComponentStatus(dir string, free int64) ComponentStatus {
+	status := ComponentStatus{
+		Status:  HealthOK,
+		Message: fmt.Sprintf("%s free", formatBytes(free)),
+		Details: map[string]interface{}{"path": dir, "free_bytes": free},
+	}
+	if free < lowFreeSpace {
+		status.Status = HealthDegraded
+		status.Message = fmt.Sprintf("only %s free", formatBytes(free))
+	}
+	return status
+}
+
+// stateStatus reports how saving the state went, in the database if
+// database is set or else the state file
+func stateStatus(save SaveStatus, database bool) ComponentStatus {
+	backend := "file"
+	if database {
+		backend = "database"
+	}
+	details := map[string]interface{}{"backend": backend}
+	if !save.LastSuccess.IsZero() {
+		details["last_save"] = save.LastSuccess
+	}
+	switch {
+	case save.LastError != "":
+		details["last_error"] = save.LastError
+		return ComponentStatus{Status: HealthFailing, Message: "saving failed: " + save.LastError, Details: details}
+	case save.LastSuccess.IsZero():
+		return ComponentStatus{Status: HealthOK, Message: "not saved yet", Details: details}
+	}
+	return ComponentStatus{
+		Status:  HealthOK,
+		Message: fmt.Sprintf("saved %s ago", formatAge(time.Since(save.LastSuccess))),
+		Details: details,
+	}
+}
+
+// BuildInfo returns the build of the daemon
+func (d *Daemon) BuildInfo() version.Info {
+	return version.Get()
+}
+
+// formatAge formats a duration rounded for display, e.g. 42s, 3m or 2h5m
+func formatAge(age time.Duration) string {
+	if age < time.Minute {
+		return age.Round(time.Second).String()
+	}
+	return strings.TrimSuffix(age.Round(time.Minute).String(), "0s")
+}
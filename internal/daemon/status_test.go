@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogComponentStatus(t *testing.T) {
+	now := time.Now()
+	ref := &discovery.CatalogReference{InfoHash: "abc", Sequence: 7, Updated: now.Add(-5 * time.Minute).Unix()}
+
+	status := catalogComponentStatus(ref, 3, discovery.PublishStatus{}, now)
+	assert.Equal(t, HealthOK, status.Status)
+	assert.Contains(t, status.Message, "seq 7, updated 5m ago")
+	assert.Contains(t, status.Message, "not published yet")
+	assert.Equal(t, int64(300), status.Details["age_seconds"])
+
+	published := discovery.PublishStatus{LastAttempt: now.Add(-time.Minute), LastSuccess: now.Add(-time.Minute)}
+	status = catalogComponentStatus(ref, 3, published, now)
+	assert.Equal(t, HealthOK, status.Status)
+	assert.Contains(t, status.Message, "published 1m ago")
+
+	// A failed publish since the last success needs attention
+	failed := discovery.PublishStatus{LastAttempt: now, LastSuccess: now.Add(-time.Hour), LastError: "traversal put failed"}
+	status = catalogComponentStatus(ref, 3, failed, now)
+	assert.Equal(t, HealthDegraded, status.Status)
+	assert.Equal(t, "traversal put failed", status.Details["last_publish_error"])
+
+	// As does a reference the DHT may have dropped
+	stale := discovery.PublishStatus{LastAttempt: now.Add(-3 * time.Hour), LastSuccess: now.Add(-3 * time.Hour)}
+	assert.Equal(t, HealthDegraded, catalogComponentStatus(ref, 3, stale, now).Status)
+
+	status = catalogComponentStatus(nil, 0, discovery.PublishStatus{}, now)
+	assert.Equal(t, HealthOK, status.Status)
+	assert.NotContains(t, status.Details, "sequence")
+}
+
+func TestStorageComponentStatus(t *testing.T) {
+	assert.Equal(t, HealthOK, storageComponentStatus("/models", 10<<30).Status)
+
+	status := storageComponentStatus("/models", 100<<20)
+	assert.Equal(t, HealthDegraded, status.Status)
+	assert.Contains(t, status.Message, "only")
+	assert.Equal(t, int64(100<<20), status.Details["free_bytes"])
+}
+
+func TestStateSaveStatus(t *testing.T) {
+	s := NewState(filepath.Join(t.TempDir(), "state.json"))
+	assert.Equal(t, "not saved yet", stateStatus(s.SaveStatus(), false).Message)
+
+	require.NoError(t, s.Save())
+	save := s.SaveStatus()
+	assert.False(t, save.LastSuccess.IsZero())
+	assert.Empty(t, save.LastError)
+	assert.Equal(t, HealthOK, stateStatus(save, false).Status)
+
+	// A failed save is reported until the next one succeeds
+	s.filePath = filepath.Join(t.TempDir(), "missing", "state.json")
+	require.Error(t, s.Save())
+	save = s.SaveStatus()
+	assert.NotEmpty(t, save.LastError)
+	assert.True(t, save.LastAttempt.After(save.LastSuccess) || save.LastAttempt.Equal(save.LastSuccess))
+	status := stateStatus(save, false)
+	assert.Equal(t, HealthFailing, status.Status)
+	assert.Contains(t, status.Details, "last_save")
+}
@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/internal/publish"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/store"
+)
+
+// dbDir returns the database directory configured in cfg
+func dbDir(cfg *config.Config) string {
+	if cfg != nil && cfg.Storage.DBDir != "" {
+		return cfg.Storage.DBDir
+	}
+	if cfg != nil && cfg.Storage.BaseDir != "" {
+		return filepath.Join(cfg.Storage.BaseDir, "db")
+	}
+	return filepath.Join(storage.GetBaseDir(), "db")
+}
+
+// openStore opens the database of the daemon. Without one the daemon keeps
+// its state in JSON files.
+func openStore(cfg *config.Config) *store.Store {
+	st, err := store.Open(dbDir(cfg))
+	if err != nil {
+		fmt.Printf("Warning: could not open database, keeping state in files: %v\n", err)
+		return nil
+	}
+	fmt.Printf("[Store] Using database %s\n", st.Path())
+	return st
+}
+
+// catalogCache keeps the catalogs in the database
+type catalogCache struct {
+	store *store.Store
+}
+
+func (c catalogCache) LoadCatalog(dir string, catalog *discovery.ModelCatalog) (bool, error) {
+	return c.store.Get(store.Catalogs, dir, catalog)
+}
+
+func (c catalogCache) SaveCatalog(dir string, catalog *discovery.ModelCatalog) error {
+	return c.store.Put(store.Catalogs, dir, catalog)
+}
+
+// saveJob keeps a publish job in the database, so it is listed after a
+// restart
+func (d *Daemon) saveJob(job publish.Job) {
+	if d.store == nil {
+		return
+	}
+	if err := d.store.Put(store.Jobs, job.ID, job); err != nil {
+		fmt.Printf("[Store] Failed to save publish job %s: %v\n", job.ID, err)
+	}
+}
+
+// restoreJobs gives the publisher the jobs of previous runs. Jobs that were
+// still running when the daemon stopped failed with it.
+func (d *Daemon) restoreJobs() error {
+	if d.store == nil {
+		return nil
+	}
+	var jobs []publish.Job
+	var interrupted []publish.Job
+	err := d.store.ForEach(store.Jobs, func(id string, data []byte) error {
+		var job publish.Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return fmt.Errorf("failed to decode publish job %s: %w", id, err)
+		}
+		if job.Status == publish.StatusQueued || job.Status == publish.StatusRunning {
+			job.Status = publish.StatusFailed
+			job.Error = fmt.Sprintf("%s: interrupted by a daemon restart", job.Stage)
+			stoppedAt := time.Now()
+			job.CompletedAt = &stoppedAt
+			interrupted = append(interrupted, job)
+		}
+		jobs = append(jobs, job)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, job := range interrupted {
+		d.saveJob(job)
+	}
+	d.publisher.Restore(jobs)
+	return nil
+}
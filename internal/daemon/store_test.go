@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/publish"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreJobs(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	var err error
+	d.store, err = store.Open(filepath.Join(t.TempDir(), "db"))
+	require.NoError(t, err)
+	defer d.store.Close()
+
+	d.saveJob(publish.Job{ID: "done", Model: "org/done", Status: publish.StatusCompleted, Stage: publish.StageDone, StartedAt: time.Now()})
+	d.saveJob(publish.Job{ID: "running", Model: "org/running", Status: publish.StatusRunning, Stage: publish.StageTorrent, StartedAt: time.Now()})
+
+	// A new daemon lists the jobs of the previous one
+	d.publisher = publish.New(nil, nil, nil)
+	require.NoError(t, d.restoreJobs())
+	require.Len(t, d.PublishJobs(), 2)
+
+	job, ok := d.PublishJob("done")
+	require.True(t, ok)
+	assert.Equal(t, publish.StatusCompleted, job.Status)
+
+	job, ok = d.PublishJob("running")
+	require.True(t, ok)
+	assert.Equal(t, publish.StatusFailed, job.Status)
+	assert.Contains(t, job.Error, "interrupted")
+	assert.NotNil(t, job.CompletedAt)
+
+	// The interrupted job stays failed in the database
+	var saved publish.Job
+	found, err := d.store.Get(store.Jobs, "running", &saved)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, publish.StatusFailed, saved.Status)
+}
+
+func TestResumableClone(t *testing.T) {
+	d := newRenameTestDaemon(t)
+	var err error
+	d.store, err = store.Open(filepath.Join(t.TempDir(), "db"))
+	require.NoError(t, err)
+	defer d.store.Close()
+
+	const repoURL = "https://huggingface.co/org/model"
+	progress := publish.CloneProgress{"model.safetensors": {Size: 100, Downloaded: 40}}
+	d.saveJob(publish.Job{ID: "old", Model: "org/model", RepoURL: repoURL, Status: publish.StatusFailed, Stage: publish.StageClone, StartedAt: time.Now().Add(-time.Hour), CompletedAt: &time.Time{}})
+	d.saveJob(publish.Job{ID: "clone", Model: "org/model", RepoURL: repoURL, Status: publish.StatusRunning, Stage: publish.StageClone, Clone: progress, StartedAt: time.Now()})
+	d.publisher = publish.New(nil, nil, nil)
+	require.NoError(t, d.restoreJobs())
+
+	modelPath := filepath.Join(t.TempDir(), "org", "model")
+	_, ok := d.ResumableClone("org/model", repoURL, modelPath)
+	assert.False(t, ok, "nothing was cloned")
+
+	// The clone interrupted by the restart continues from its progress
+	require.NoError(t, os.MkdirAll(filepath.Join(modelPath, ".git"), 0755))
+	require.NoError(t, storage.MarkPartial(modelPath))
+	resume, ok := d.ResumableClone("org/model", repoURL, modelPath)
+	require.True(t, ok)
+	assert.Equal(t, progress, resume)
+
+	// A partial download isn't a clone
+	require.NoError(t, os.RemoveAll(filepath.Join(modelPath, ".git")))
+	_, ok = d.ResumableClone("org/model", repoURL, modelPath)
+	assert.False(t, ok)
+}
@@ -0,0 +1,189 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// How often piece availability is measured
+const swarmCoordinatorInterval = 30 * time.Second
+
+// Pieces held by at most this many connected peers are rare. Downloading
+// them first keeps them in the swarm when their holders leave.
+const rarePieceThreshold = 1
+
+// PieceAvailability describes how well the pieces of a model are spread
+// among the peers we are connected to
+type PieceAvailability struct {
+	Peers             int       `json:"peers"`
+	Pieces            int       `json:"pieces"`
+	DistributedCopies float64   `json:"distributed_copies"`
+	MinAvailability   int       `json:"min_availability"` // Peers holding the rarest piece
+	RarePieces        int       `json:"rare_pieces"`      // Held by at most one peer
+	UniquePieces      int       `json:"unique_pieces"`    // Held by us and no connected peer
+	BoostedPieces     int       `json:"boosted_pieces"`   // Rare pieces we download first
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// ModelAvailability is the piece availability of a model's swarm
+type ModelAvailability struct {
+	Model    string `json:"model"`
+	InfoHash string `json:"info_hash"`
+	PieceAvailability
+}
+
+// measureAvailability summarizes the number of peers having each piece.
+// have reports whether we have a piece ourselves.
+func measureAvailability(availability []int, peers int, have func(piece int) bool) PieceAvailability {
+	a := PieceAvailability{
+		Peers:             peers,
+		Pieces:            len(availability),
+		DistributedCopies: distributedCopies(availability),
+		UpdatedAt:         time.Now(),
+	}
+	for i, n := range availability {
+		if i == 0 || n < a.MinAvailability {
+			a.MinAvailability = n
+		}
+		if n <= rarePieceThreshold {
+			a.RarePieces++
+		}
+		if n == 0 && peers > 0 && have(i) {
+			a.UniquePieces++
+		}
+	}
+	return a
+}
+
+// rarePiecesToBoost returns the pieces we still want that are rare among our
+// peers but can be downloaded, rarest first
+func rarePiecesToBoost(availability []int, want func(piece int) bool) []int {
+	var pieces []int
+	for i, n := range availability {
+		if n > 0 && n <= rarePieceThreshold && want(i) {
+			pieces = append(pieces, i)
+		}
+	}
+	sort.SliceStable(pieces, func(i, j int) bool {
+		return availability[pieces[i]] < availability[pieces[j]]
+	})
+	return pieces
+}
+
+// swarmCoordinatorEnabled reports whether the daemon rebalances scarce pieces
+func (d *Daemon) swarmCoordinatorEnabled() bool {
+	return d.config != nil && d.config.GetBool("torrent.swarm_coordinator")
+}
+
+// swarmCoordinatorWorker measures the piece availability of every swarm we
+// are in. In coordinator mode rare pieces are downloaded first, and models
+// holding pieces no peer has keep seeding past their seeding limits.
+func (d *Daemon) swarmCoordinatorWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(swarmCoordinatorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.coordinateSwarms()
+		}
+	}
+}
+
+// coordinateSwarms updates the availability of all torrents and the
+// priorities of their rare pieces
+func (d *Daemon) coordinateSwarms() {
+	if d.torrentManager == nil {
+		return
+	}
+	enabled := d.swarmCoordinatorEnabled()
+
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		t := mt.Torrent
+		if t.Info() == nil {
+			continue
+		}
+
+		availability := pieceAvailability(t)
+		have := func(piece int) bool { return t.PieceState(piece).Complete }
+		a := measureAvailability(availability, len(t.PeerConns()), have)
+
+		var boost []int
+		if enabled && !mt.Complete() {
+			boost = rarePiecesToBoost(availability, func(piece int) bool {
+				state := t.PieceState(piece)
+				return !state.Complete && state.Priority != torrent.PiecePriorityNone
+			})
+		}
+		d.boostPieces(mt, boost)
+		a.BoostedPieces = len(boost)
+
+		d.torrentManager.SetAvailability(mt.InfoHash, a)
+	}
+}
+
+// boostPieces raises the priority of pieces and returns the previously
+// boosted ones to normal. Only the coordinator worker calls it.
+func (d *Daemon) boostPieces(mt *ManagedTorrent, pieces []int) {
+	boosted := make(map[int]bool, len(pieces))
+	for _, i := range pieces {
+		boosted[i] = true
+		if !mt.boosted[i] {
+			mt.Torrent.Piece(i).SetPriority(torrent.PiecePriorityHigh)
+		}
+	}
+
+	// Files not selected keep their pieces unwanted
+	normal := torrent.PiecePriorityNormal
+	if len(mt.Files()) > 0 {
+		normal = torrent.PiecePriorityNone
+	}
+	for i := range mt.boosted {
+		if !boosted[i] {
+			mt.Torrent.Piece(i).SetPriority(normal)
+		}
+	}
+
+	if len(pieces) > 0 && len(mt.boosted) == 0 {
+		fmt.Printf("[Swarm] %s: downloading %d rare pieces first\n", mt.Name, len(pieces))
+	}
+	mt.boosted = boosted
+}
+
+// neededBySwarm reports whether connected peers depend on our copy of a
+// torrent for some of its pieces
+func (d *Daemon) neededBySwarm(infoHash string) bool {
+	a, ok := d.torrentManager.Availability(infoHash)
+	return ok && a.UniquePieces > 0
+}
+
+// SwarmAvailability returns the piece availability of every model
+func (d *Daemon) SwarmAvailability() []ModelAvailability {
+	models := make([]ModelAvailability, 0)
+	if d.torrentManager == nil {
+		return models
+	}
+
+	for _, mt := range d.torrentManager.GetAllTorrents() {
+		a, ok := d.torrentManager.Availability(mt.InfoHash)
+		if !ok {
+			continue
+		}
+		models = append(models, ModelAvailability{
+			Model:             mt.Name,
+			InfoHash:          mt.InfoHash,
+			PieceAvailability: a,
+		})
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].Model < models[j].Model
+	})
+	return models
+}
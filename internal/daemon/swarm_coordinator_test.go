@@ -0,0 +1,37 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeasureAvailability(t *testing.T) {
+	have := func(piece int) bool { return piece != 3 }
+
+	a := measureAvailability([]int{2, 1, 0, 0, 3}, 3, have)
+	assert.Equal(t, 3, a.Peers)
+	assert.Equal(t, 5, a.Pieces)
+	assert.Equal(t, 0, a.MinAvailability)
+	assert.Equal(t, 3, a.RarePieces)
+	assert.Equal(t, 1, a.UniquePieces, "piece 3 is held by nobody")
+	assert.Equal(t, 0.6, a.DistributedCopies)
+
+	// Without peers nothing depends on us
+	a = measureAvailability([]int{0, 0}, 0, have)
+	assert.Equal(t, 0, a.UniquePieces)
+}
+
+func TestRarePiecesToBoost(t *testing.T) {
+	want := func(piece int) bool { return piece != 1 }
+
+	// Pieces nobody has can't be downloaded, common ones need no help
+	assert.Equal(t, []int{2}, rarePiecesToBoost([]int{0, 1, 1, 2}, want))
+	assert.Empty(t, rarePiecesToBoost([]int{2, 3}, want))
+}
+
+func TestSwarmAvailabilityWithoutTorrentManager(t *testing.T) {
+	d := &Daemon{}
+	assert.Empty(t, d.SwarmAvailability())
+	assert.False(t, d.swarmCoordinatorEnabled())
+}
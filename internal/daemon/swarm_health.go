@@ -0,0 +1,179 @@
+package daemon
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/dht/v2"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+const (
+	// How long a swarm probe result stays valid
+	swarmHealthTTL = 5 * time.Minute
+
+	// How long a single swarm probe walks the DHT
+	swarmProbeTimeout = 10 * time.Second
+
+	// Maximum number of swarms probed at the same time
+	maxConcurrentProbes = 8
+)
+
+// SwarmHealthCache caches swarm probe results per infohash
+type SwarmHealthCache struct {
+	mu      sync.RWMutex
+	entries map[string]*types.SwarmHealth
+	ttl     time.Duration
+}
+
+// NewSwarmHealthCache creates a cache whose entries expire after ttl
+func NewSwarmHealthCache(ttl time.Duration) *SwarmHealthCache {
+	return &SwarmHealthCache{
+		entries: make(map[string]*types.SwarmHealth),
+		ttl:     ttl,
+	}
+}
+
+// Get returns a cached result if it has not expired
+func (c *SwarmHealthCache) Get(infoHash string) (*types.SwarmHealth, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	health, ok := c.entries[infoHash]
+	if !ok || time.Since(health.CheckedAt) > c.ttl {
+		return nil, false
+	}
+	return health, true
+}
+
+// Set stores a probe result
+func (c *SwarmHealthCache) Set(infoHash string, health *types.SwarmHealth) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[infoHash] = health
+}
+
+// Len returns the number of cached results, including expired ones
+func (c *SwarmHealthCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// GetSwarmHealth returns the health of a swarm, probing the DHT if there is no
+// fresh cached result
+func (dm *DHTManager) GetSwarmHealth(infoHash string) *types.SwarmHealth {
+	if health, ok := dm.healthCache.Get(infoHash); ok {
+		return health
+	}
+
+	health := dm.probeSwarm(infoHash, swarmProbeTimeout)
+	dm.healthCache.Set(infoHash, health)
+	return health
+}
+
+// annotateHealth probes the swarms of the given models concurrently and fills
+// in their seeder, leecher and availability fields
+func (dm *DHTManager) annotateHealth(models []*types.ModelAnnouncement) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentProbes)
+
+	for _, model := range models {
+		wg.Add(1)
+		go func(m *types.ModelAnnouncement) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			health := dm.GetSwarmHealth(m.InfoHash)
+			m.Seeders = health.Seeders
+			m.Leechers = health.Leechers
+			m.Availability = health.Availability
+		}(model)
+	}
+	wg.Wait()
+}
+
+// probeSwarm walks the DHT for an infohash, collecting peers and BEP 33 scrape
+// bloom filters to estimate the number of seeders and leechers
+func (dm *DHTManager) probeSwarm(infoHash string, timeout time.Duration) *types.SwarmHealth {
+	health := &types.SwarmHealth{CheckedAt: time.Now()}
+	peers := make(map[string]bool)
+	var seedEstimate, peerEstimate float64
+
+	if dm.dhtServer == nil {
+		setSwarmCounts(health, 0, 0, 0)
+		return health
+	}
+
+	var ih metainfo.Hash
+	if err := ih.FromHexString(infoHash); err != nil {
+		fmt.Printf("[DHT] Invalid infohash %s: %v\n", infoHash, err)
+		setSwarmCounts(health, 0, 0, 0)
+		return health
+	}
+
+	announce, err := dm.dhtServer.AnnounceTraversal(ih, dht.Scrape())
+	if err != nil {
+		fmt.Printf("[DHT] Failed to start swarm probe for %s: %v\n", infoHash, err)
+		setSwarmCounts(health, 0, 0, 0)
+		return health
+	}
+	defer announce.Close()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+probe:
+	for {
+		select {
+		case pv, ok := <-announce.Peers:
+			if !ok {
+				break probe
+			}
+			for _, peer := range pv.Peers {
+				peers[peer.String()] = true
+			}
+			// Nodes return bloom filters of the peers they track; the largest
+			// estimate is the best lower bound for the whole swarm
+			if pv.Return.BFsd != nil {
+				if n := pv.Return.BFsd.EstimateCount(); n > seedEstimate {
+					seedEstimate = n
+				}
+			}
+			if pv.Return.BFpe != nil {
+				if n := pv.Return.BFpe.EstimateCount(); n > peerEstimate {
+					peerEstimate = n
+				}
+			}
+		case <-timer.C:
+			break probe
+		case <-dm.ctx.Done():
+			break probe
+		}
+	}
+
+	setSwarmCounts(health, len(peers), seedEstimate, peerEstimate)
+	return health
+}
+
+// setSwarmCounts fills in the peer counts of a probe result. Scrape estimates
+// are preferred; without them every peer found is assumed to be a seeder since
+// get_peers responses do not say whether a peer has the complete data.
+func setSwarmCounts(health *types.SwarmHealth, peers int, seedEstimate, peerEstimate float64) {
+	if seedEstimate > 0 || peerEstimate > 0 {
+		health.Seeders = int(seedEstimate + 0.5)
+		health.Leechers = int(peerEstimate + 0.5)
+		health.Scraped = true
+	} else {
+		health.Seeders = peers
+	}
+
+	health.Peers = peers
+	if total := health.Seeders + health.Leechers; total > health.Peers {
+		health.Peers = total
+	}
+	health.Availability = types.EstimateAvailability(health.Seeders, health.Peers)
+}
@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSwarmHealthCache(t *testing.T) {
+	cache := NewSwarmHealthCache(time.Minute)
+
+	_, ok := cache.Get("abc")
+	assert.False(t, ok)
+
+	cache.Set("abc", &types.SwarmHealth{Seeders: 3, CheckedAt: time.Now()})
+	health, ok := cache.Get("abc")
+	assert.True(t, ok)
+	assert.Equal(t, 3, health.Seeders)
+
+	// Expired entries are not returned
+	cache.Set("old", &types.SwarmHealth{Seeders: 1, CheckedAt: time.Now().Add(-2 * time.Minute)})
+	_, ok = cache.Get("old")
+	assert.False(t, ok)
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestSetSwarmCounts(t *testing.T) {
+	t.Run("without scrape data", func(t *testing.T) {
+		health := &types.SwarmHealth{}
+		setSwarmCounts(health, 3, 0, 0)
+
+		assert.Equal(t, 3, health.Seeders)
+		assert.Equal(t, 0, health.Leechers)
+		assert.Equal(t, 3, health.Peers)
+		assert.False(t, health.Scraped)
+		assert.Equal(t, types.AvailabilityMedium, health.Availability)
+	})
+
+	t.Run("with scrape estimates", func(t *testing.T) {
+		health := &types.SwarmHealth{}
+		setSwarmCounts(health, 2, 6.4, 3.6)
+
+		assert.Equal(t, 6, health.Seeders)
+		assert.Equal(t, 4, health.Leechers)
+		assert.Equal(t, 10, health.Peers)
+		assert.True(t, health.Scraped)
+		assert.Equal(t, types.AvailabilityHigh, health.Availability)
+	})
+
+	t.Run("empty swarm", func(t *testing.T) {
+		health := &types.SwarmHealth{}
+		setSwarmCounts(health, 0, 0, 0)
+		assert.Equal(t, types.AvailabilityUnavailable, health.Availability)
+	})
+}
+
+func TestFilterBySeeders(t *testing.T) {
+	models := []*types.ModelAnnouncement{
+		{Name: "a", Seeders: 0},
+		{Name: "b", Seeders: 2},
+		{Name: "c", Seeders: 5},
+	}
+
+	results := filterBySeeders(models, 2)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "b", results[0].Name)
+	assert.Equal(t, "c", results[1].Name)
+}
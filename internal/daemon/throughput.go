@@ -0,0 +1,150 @@
+package daemon
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// How often the transfer rates of torrents are sampled
+const throughputSampleInterval = 2 * time.Second
+
+// Time constant of the smoothed rates. Older samples fade out, so a rate
+// follows the last few seconds instead of the whole session.
+const throughputWindow = 10 * time.Second
+
+// Samples kept for speed graphs, five minutes at the sample interval
+const throughputHistorySize = 150
+
+// ThroughputSample is the smoothed rate of a torrent at one point in time
+type ThroughputSample struct {
+	Time         time.Time `json:"time"`
+	DownloadRate int64     `json:"download_rate"`
+	UploadRate   int64     `json:"upload_rate"`
+}
+
+// PeerThroughput is what a connected peer contributes to a torrent
+type PeerThroughput struct {
+	Addr         string  `json:"addr"`
+	Client       string  `json:"client,omitempty"`
+	DownloadRate int64   `json:"download_rate"` // Useful data received from the peer
+	Progress     float64 `json:"progress"`      // Percentage of the pieces the peer has
+}
+
+// TransferStats are the live rates of a transfer's torrent
+type TransferStats struct {
+	TransferID          string             `json:"transfer_id"`
+	InfoHash            string             `json:"info_hash"`
+	DownloadRate        int64              `json:"download_rate"` // Smoothed over throughputWindow
+	UploadRate          int64              `json:"upload_rate"`
+	AverageDownloadRate int64              `json:"average_download_rate"` // Since the torrent was added
+	AverageUploadRate   int64              `json:"average_upload_rate"`
+	BytesRemaining      int64              `json:"bytes_remaining"`
+	ETA                 *time.Duration     `json:"eta,omitempty"`
+	Peers               []PeerThroughput   `json:"peers"`
+	Sources             []SourceThroughput `json:"sources"` // Swarm and web seeds
+	History             []ThroughputSample `json:"history"` // Oldest first
+	Window              time.Duration      `json:"window"`
+	SampleInterval      time.Duration      `json:"sample_interval"`
+}
+
+// throughputMeter turns the byte counters of a torrent into rates smoothed
+// with an exponential moving average, and keeps a short history of them
+type throughputMeter struct {
+	mu       sync.Mutex
+	last     time.Time
+	lastDown int64
+	lastUp   int64
+	down     float64
+	up       float64
+	history  []ThroughputSample // Ring of throughputHistorySize samples
+	next     int                // Where the next sample goes once the ring is full
+}
+
+// sample records the byte counters of a torrent at now
+func (m *throughputMeter) sample(now time.Time, down, up int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The first sample and counters that went back, because the torrent
+	// was added again, only set the baseline
+	if m.last.IsZero() || down < m.lastDown || up < m.lastUp {
+		m.last, m.lastDown, m.lastUp = now, down, up
+		return
+	}
+	elapsed := now.Sub(m.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	// Weigh the rate since the last sample by how long it covers, so
+	// irregular sampling doesn't skew the average
+	alpha := 1 - math.Exp(-elapsed/throughputWindow.Seconds())
+	m.down += alpha * (float64(down-m.lastDown)/elapsed - m.down)
+	m.up += alpha * (float64(up-m.lastUp)/elapsed - m.up)
+	m.last, m.lastDown, m.lastUp = now, down, up
+
+	s := ThroughputSample{Time: now, DownloadRate: int64(m.down), UploadRate: int64(m.up)}
+	if len(m.history) < throughputHistorySize {
+		m.history = append(m.history, s)
+		return
+	}
+	m.history[m.next] = s
+	m.next = (m.next + 1) % throughputHistorySize
+}
+
+// rates returns the smoothed download and upload rates in bytes per second
+func (m *throughputMeter) rates() (down, up int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(m.down), int64(m.up)
+}
+
+// samples returns the history, oldest first
+func (m *throughputMeter) samples() []ThroughputSample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	samples := make([]ThroughputSample, 0, len(m.history))
+	samples = append(samples, m.history[m.next:]...)
+	return append(samples, m.history[:m.next]...)
+}
+
+// estimateETA returns how long the remaining bytes take at rate, nil if
+// nothing is left or nothing arrives
+func estimateETA(remaining, rate int64) *time.Duration {
+	if remaining <= 0 || rate <= 0 {
+		return nil
+	}
+	eta := time.Duration(remaining/rate) * time.Second
+	return &eta
+}
+
+// sortPeerThroughput orders peers by what they contribute, the fastest first
+func sortPeerThroughput(peers []PeerThroughput) {
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].DownloadRate != peers[j].DownloadRate {
+			return peers[i].DownloadRate > peers[j].DownloadRate
+		}
+		return peers[i].Addr < peers[j].Addr
+	})
+}
+
+func (d *Daemon) throughputWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(throughputSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			if d.torrentManager != nil {
+				d.torrentManager.SampleThroughput()
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThroughputMeterSmoothsRates(t *testing.T) {
+	var m throughputMeter
+	start := time.Now()
+
+	// The first sample only sets the baseline
+	m.sample(start, 0, 0)
+	down, up := m.rates()
+	assert.Zero(t, down)
+	assert.Zero(t, up)
+	assert.Empty(t, m.samples())
+
+	// A steady 1 MB/s download converges to its rate
+	var bytes int64
+	for i := 1; i <= 30; i++ {
+		bytes += 2 << 20
+		m.sample(start.Add(time.Duration(i)*throughputSampleInterval), bytes, 0)
+	}
+	down, _ = m.rates()
+	assert.InDelta(t, 1<<20, down, 1<<20/100)
+
+	// When the download stops the rate fades within a few windows instead
+	// of staying at the session average
+	now := start.Add(30 * throughputSampleInterval)
+	for i := 1; i <= 15; i++ {
+		m.sample(now.Add(time.Duration(i)*throughputSampleInterval), bytes, 0)
+	}
+	down, _ = m.rates()
+	assert.Less(t, down, int64(1<<20/10))
+}
+
+func TestThroughputMeterHistory(t *testing.T) {
+	var m throughputMeter
+	start := time.Now()
+
+	for i := 0; i <= throughputHistorySize+10; i++ {
+		m.sample(start.Add(time.Duration(i)*time.Second), int64(i)*1000, 0)
+	}
+
+	samples := m.samples()
+	require.Len(t, samples, throughputHistorySize)
+	for i := 1; i < len(samples); i++ {
+		assert.True(t, samples[i].Time.After(samples[i-1].Time), "samples are oldest first")
+	}
+	assert.Equal(t, start.Add(time.Duration(throughputHistorySize+10)*time.Second), samples[len(samples)-1].Time)
+}
+
+func TestThroughputMeterCounterReset(t *testing.T) {
+	var m throughputMeter
+	start := time.Now()
+	m.sample(start, 0, 0)
+	m.sample(start.Add(time.Second), 10000, 5000)
+	before, _ := m.rates()
+
+	// Counters of a torrent added again start from zero, which is not a
+	// negative rate
+	m.sample(start.Add(2*time.Second), 0, 0)
+	down, up := m.rates()
+	assert.Equal(t, before, down)
+	assert.GreaterOrEqual(t, up, int64(0))
+}
+
+func TestEstimateETA(t *testing.T) {
+	eta := estimateETA(100<<20, 1<<20)
+	require.NotNil(t, eta)
+	assert.Equal(t, 100*time.Second, *eta)
+
+	assert.Nil(t, estimateETA(0, 1<<20))
+	assert.Nil(t, estimateETA(100<<20, 0))
+}
+
+func TestSortPeerThroughput(t *testing.T) {
+	peers := []PeerThroughput{
+		{Addr: "b", DownloadRate: 10},
+		{Addr: "c", DownloadRate: 30},
+		{Addr: "a", DownloadRate: 10},
+	}
+	sortPeerThroughput(peers)
+	assert.Equal(t, []string{"c", "a", "b"}, []string{peers[0].Addr, peers[1].Addr, peers[2].Addr})
+}
+
+func TestGetTransferStatsWithoutTorrent(t *testing.T) {
+	tm := NewTransferManager(nil, NewState(""))
+	transfer := tm.CreateDownload("org/model", "hash", 1024)
+
+	_, err := tm.GetTransferStats(transfer.ID)
+	assert.ErrorIs(t, err, ErrNoTransferStats)
+
+	_, err = tm.GetTransferStats("missing")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNoTransferStats)
+}
@@ -0,0 +1,193 @@
+package daemon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// ErrTorrentNotFound is returned for a model without a torrent file
+var ErrTorrentNotFound = errors.New("torrent file not found")
+
+// ErrTorrentLoaded is returned when importing a torrent the daemon already has
+var ErrTorrentLoaded = errors.New("torrent is already loaded")
+
+// Largest torrent file accepted for import
+const maxTorrentFileSize = 64 << 20
+
+// TorrentImport describes a torrent file that was imported
+type TorrentImport struct {
+	Name       string `json:"name"`
+	InfoHash   string `json:"info_hash"`
+	Files      int    `json:"files"`
+	Size       int64  `json:"size"`
+	Status     string `json:"status"` // imported, seeding or downloading
+	TransferID string `json:"transfer_id,omitempty"`
+}
+
+// ExportTorrent returns the torrent file of a local model and its infohash.
+// It comes from the running torrent if there is one, else from the torrents
+// directory.
+func (d *Daemon) ExportTorrent(name string) ([]byte, string, error) {
+	name = d.ResolveModel(name)
+
+	if d.torrentManager != nil {
+		for _, mt := range d.torrentManager.GetAllTorrents() {
+			if mt.Name != name || mt.Torrent.Info() == nil {
+				continue
+			}
+			mi := mt.Torrent.Metainfo()
+			var buf bytes.Buffer
+			if err := mi.Write(&buf); err != nil {
+				return nil, "", fmt.Errorf("failed to encode torrent: %w", err)
+			}
+			return buf.Bytes(), mt.InfoHash, nil
+		}
+	}
+
+	// Published models keep their torrent under their name, downloaded
+	// ones under their infohash
+	candidates := []string{filepath.Join(storage.GetTorrentsDir(), filepath.FromSlash(name)+".torrent")}
+	if d.registry != nil {
+		if manifest, err := d.registry.GetManifest(name); err == nil {
+			if infoHash := magnetInfoHash(manifest.MagnetURI); infoHash != "" {
+				candidates = append(candidates, filepath.Join(storage.GetTorrentsDir(), infoHash+".torrent"))
+			}
+		}
+	}
+	for _, path := range candidates {
+		mi, err := metainfo.LoadFromFile(path)
+		if err != nil {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := mi.Write(&buf); err != nil {
+			return nil, "", fmt.Errorf("failed to encode torrent: %w", err)
+		}
+		return buf.Bytes(), mi.HashInfoBytes().HexString(), nil
+	}
+	return nil, "", fmt.Errorf("%w for model %s", ErrTorrentNotFound, name)
+}
+
+// magnetInfoHash returns the v1 infohash of a magnet URI, "" if it has none
+func magnetInfoHash(uri string) string {
+	if uri == "" {
+		return ""
+	}
+	m, err := metainfo.ParseMagnetUri(uri)
+	if err != nil {
+		return ""
+	}
+	return m.InfoHash.HexString()
+}
+
+// ImportTorrent adds a torrent file as the model name, the torrent's name if
+// empty. Files already in the model directory, for example copied from
+// another machine, are registered as a local model and seeded if seed is
+// set. Otherwise the files are downloaded from peers.
+func (d *Daemon) ImportTorrent(data []byte, name string, seed bool) (*TorrentImport, error) {
+	if len(data) > maxTorrentFileSize {
+		return nil, fmt.Errorf("torrent file is larger than %d MB", maxTorrentFileSize>>20)
+	}
+	mi, err := metainfo.Load(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid torrent file: %w", err)
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return nil, fmt.Errorf("invalid torrent file: %w", err)
+	}
+	if name == "" {
+		name = info.BestName()
+	}
+	modelPath, err := modelPathFor(name)
+	if err != nil {
+		return nil, err
+	}
+
+	infoHash := mi.HashInfoBytes().HexString()
+	if mt, ok := d.torrentManager.GetTorrent(infoHash); ok {
+		return nil, fmt.Errorf("%w as %s", ErrTorrentLoaded, mt.Name)
+	}
+
+	// Kept where downloads keep theirs, so the model is restored on restart
+	var buf bytes.Buffer
+	if err := mi.Write(&buf); err != nil {
+		return nil, fmt.Errorf("failed to encode torrent: %w", err)
+	}
+	torrentPath := filepath.Join(storage.GetTorrentsDir(), infoHash+".torrent")
+	if err := storage.WriteFileAtomic(torrentPath, buf.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("failed to save torrent file: %w", err)
+	}
+
+	result := &TorrentImport{
+		Name:     name,
+		InfoHash: infoHash,
+		Files:    len(info.UpvertedFiles()),
+		Size:     info.TotalLength(),
+	}
+
+	if missing := missingTorrentFiles(&info, modelPath); len(missing) > 0 {
+		if _, err := d.fetchModel(name, infoHash, modelPath); err != nil {
+			return nil, fmt.Errorf("failed to start download: %w", err)
+		}
+		if transfer, ok := d.transferManager.FindTransfer(infoHash, TransferTypeDownload); ok {
+			result.TransferID = transfer.ID
+		}
+		result.Status = "downloading"
+		fmt.Printf("[Import] %s is missing %d files, downloading them from peers\n", name, len(missing))
+		return result, nil
+	}
+
+	// Describe the files unless they came with their manifest
+	manifest, err := models.ReadManifest(modelPath)
+	if err != nil {
+		if manifest, err = models.GenerateManifest(modelPath, name, nil); err != nil {
+			return nil, fmt.Errorf("failed to describe model: %w", err)
+		}
+	}
+	manifest.Name = name
+	manifest.MagnetURI = mi.Magnet(nil, &info).String()
+	if err := d.registry.SaveManifest(manifest); err != nil {
+		return nil, fmt.Errorf("failed to save manifest: %w", err)
+	}
+	result.Status = "imported"
+
+	if seed {
+		mt, err := d.torrentManager.AddTorrentForSeeding(torrentPath, name, modelPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seed: %w", err)
+		}
+		transfer := d.transferManager.CreateSeed(name, mt.InfoHash)
+		result.TransferID = transfer.ID
+		result.Status = "seeding"
+	}
+	fmt.Printf("[Import] Imported %s (InfoHash: %s, %s)\n", name, infoHash, result.Status)
+	return result, nil
+}
+
+// missingTorrentFiles returns the files of a torrent that aren't in dir
+// with their full size
+func missingTorrentFiles(info *metainfo.Info, dir string) []string {
+	var missing []string
+	for _, f := range info.UpvertedFiles() {
+		path := f.DisplayPath(info)
+		file := filepath.Join(dir, filepath.FromSlash(path))
+		if !strings.HasPrefix(file, filepath.Clean(dir)+string(filepath.Separator)) {
+			missing = append(missing, path)
+			continue
+		}
+		stat, err := os.Stat(file)
+		if err != nil || stat.Size() != f.Length {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,40 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingTorrentFiles(t *testing.T) {
+	dir := t.TempDir()
+	info := &metainfo.Info{
+		Name: "model",
+		Files: []metainfo.FileInfo{
+			{Path: []string{"config.json"}, Length: 10},
+			{Path: []string{"weights", "model.safetensors"}, Length: 100},
+			{Path: []string{"tokenizer.json"}, Length: 20},
+			{Path: []string{"..", "outside"}, Length: 1},
+		},
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), make([]byte, 10), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "weights"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "weights", "model.safetensors"), make([]byte, 100), 0644))
+	// A truncated copy counts as missing
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tokenizer.json"), make([]byte, 5), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(dir), "outside"), make([]byte, 1), 0644))
+
+	assert.Equal(t, []string{"tokenizer.json", "../outside"}, missingTorrentFiles(info, dir))
+}
+
+func TestMagnetInfoHash(t *testing.T) {
+	assert.Equal(t, "0123456789abcdef0123456789abcdef01234567",
+		magnetInfoHash("magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=model"))
+	assert.Equal(t, "", magnetInfoHash(""))
+	assert.Equal(t, "", magnetInfoHash("not a magnet"))
+}
@@ -2,38 +2,115 @@ package daemon
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/anacrolix/torrent"
 	"github.com/anacrolix/torrent/metainfo"
-	torrentStorage "github.com/anacrolix/torrent/storage"
+	"github.com/silmaril/silmaril/internal/blocklist"
 	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/discovery"
+	"github.com/silmaril/silmaril/internal/modelname"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/peerfilter"
+	"github.com/silmaril/silmaril/internal/proxy"
 	"github.com/silmaril/silmaril/internal/storage"
 	torrentclient "github.com/silmaril/silmaril/internal/torrent"
+	"golang.org/x/time/rate"
 )
 
 type TorrentManager struct {
-	mu       sync.RWMutex
-	client   *torrent.Client
-	config   *config.Config
-	state    *State
-	torrents map[string]*ManagedTorrent
+	mu        sync.RWMutex
+	client    *torrent.Client
+	config    *config.Config
+	state     *State
+	torrents  map[string]*ManagedTorrent
+	gossip    *discovery.CatalogGossip
+	manifests *discovery.ManifestExchange
+
+	// Rate limiters shared with the torrent client, adjusted in place when
+	// the configured limits change
+	uploadLimiter   *rate.Limiter
+	downloadLimiter *rate.Limiter
+
+	// Consulted by the torrent client before talking to a peer, the lists
+	// are loaded by the daemon, see ReloadPeerFilter
+	peerFilter *peerfilter.Filter
+
+	// Models refused for download and seeding, the lists are loaded by the
+	// daemon, see ReloadModelBlocklist
+	modelFilter *blocklist.Filter
+
+	// Backend storing the data of torrents, unless their model sets its own
+	storage StorageBackend
+	pieces  *pieceStore // Pieces verified in this and previous sessions
+
+	// Swarms of the same files as downloads, by the infohash of the
+	// download, see AddEquivalents
+	equivalents map[string]*equivalentSwarms
+
+	// Data received from the web seeds of downloads, see attachSources
+	sourceBytes *sourceMeter
+
+	// Peers of each torrent by how they were found, see PeerSourceStats
+	peerSources *peerSourceMeter
+
+	// Upload limit of each torrent, see scheduleUploads
+	uploadShares *uploadShaper
+
+	// Holds finished downloads back until they are verified, reporting
+	// whether it did, see SetQuarantine
+	quarantine func(mt *ManagedTorrent) bool
+
+	// Told about downloads dropped once their metadata arrived, see
+	// SetRejected
+	rejected func(mt *ManagedTorrent, err error)
 }
 
 type ManagedTorrent struct {
 	InfoHash    string
 	Name        string
+	StoragePath string // Directory holding the torrent's files
 	Torrent     *torrent.Torrent
 	AddedAt     time.Time
 	CompletedAt *time.Time
-	BytesDown   int64
+	BytesDown   int64 // Transferred in previous sessions
 	BytesUp     int64
 	Seeding     bool
+
+	// Set once all pieces are local, see updateSeedModes
+	SeedMode          string
+	DistributedCopies float64
+	normalMaxConns    int // Connection limit to restore after initial seeding
+	maxConns          int // Share of network.max_connections, see balanceConnections
+
+	// Measured by the swarm coordinator, see coordinateSwarms
+	availability *PieceAvailability
+	boosted      map[int]bool // Rare pieces with raised priority
+
+	// Smoothed transfer rates, sampled by the throughput worker
+	throughput throughputMeter
+
+	// Session bytes already added to the contribution, see SampleContribution
+	accountedDown int64
+	accountedUp   int64
+
+	// Mirrors and IPFS gateways fetching the files, see attachSources
+	sources []downloadSource
+
+	fileSelection // Files to download and seed, see SetFileSelection
 }
 
 func NewTorrentManager(cfg *config.Config, state *State) (*TorrentManager, error) {
+	pieces := newPieceStore(piecesDir())
+	backend, err := newStorageBackend(cfg.GetString("torrent.storage_backend"), pieces)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create a persistent torrent client
 	clientCfg := torrent.NewDefaultClientConfig()
 	// Don't set a global DataDir - we'll use custom storage for each torrent
@@ -42,31 +119,103 @@ func NewTorrentManager(cfg *config.Config, state *State) (*TorrentManager, error
 	clientCfg.DisableWebtorrent = false
 	// Enable PEX for better peer discovery
 	clientCfg.DisablePEX = false
-	clientCfg.ListenPort = cfg.GetInt("network.listen_port")
 	clientCfg.Seed = true
+	applyConnectionLimits(clientCfg, connectionLimits(cfg))
+	
+	// Listen on the configured addresses of each address family
+	listen, err := parseListenConfig(cfg.GetStringSlice("network.listen_addrs"), cfg.GetInt("network.listen_port"), cfg.GetString("network.ip_preference"))
+	if err != nil {
+		return nil, err
+	}
+	clientCfg.ListenHost = listen.Host
+	clientCfg.ListenPort = peerPort(listen.Port, listen.Host(listen.PeerNetwork()), state.SavedPorts())
+	clientCfg.DisableIPv4 = listen.DisableIPv4
+	clientCfg.DisableIPv4Peers = listen.DisableIPv4
+	clientCfg.DisableIPv6 = listen.DisableIPv6
+
+	// Send tracker, web seed and peer traffic through the configured proxy
+	torrentProxy, err := proxy.FromConfig(cfg, proxy.Torrent)
+	if err != nil {
+		return nil, err
+	}
+	if torrentProxy != nil {
+		applyTorrentProxy(clientCfg, torrentProxy)
+	}
 	
-	// Set rate limits
+	// Set rate limits. The limiters always exist so the limits can be
+	// changed while the client is running.
+	uploadLimiter := rate.NewLimiter(rate.Inf, 0)
+	downloadLimiter := rate.NewLimiter(rate.Inf, 0)
 	if uploadLimit := cfg.GetInt("network.upload_rate_limit"); uploadLimit > 0 {
-		clientCfg.UploadRateLimiter = torrentclient.NewRateLimiter(int64(uploadLimit))
+		uploadLimiter = torrentclient.NewRateLimiter(int64(uploadLimit))
 	}
 	if downloadLimit := cfg.GetInt("network.download_rate_limit"); downloadLimit > 0 {
-		clientCfg.DownloadRateLimiter = torrentclient.NewRateLimiter(int64(downloadLimit))
+		downloadLimiter = torrentclient.NewRateLimiter(int64(downloadLimit))
 	}
+	clientCfg.UploadRateLimiter = uploadLimiter
+	clientCfg.DownloadRateLimiter = downloadLimiter
+
+	// Reject peers on the blocklist or missing from the allowlist
+	peerFilter := peerfilter.New()
+	clientCfg.IPBlocklist = peerFilter
+
+	// Exchange catalogs with other Silmaril peers over a BitTorrent extension
+	gossip := discovery.NewCatalogGossip()
+	gossip.Register(clientCfg)
+
+	// Send model manifests to peers downloading by infohash
+	manifests := discovery.NewManifestExchange()
+	manifests.Register(clientCfg)
+
+	// Count what the web seeds of downloads send
+	sourceBytes := newSourceMeter()
+	sourceBytes.Register(clientCfg)
+
+	// Count the peers found and connected to through each source
+	peerSources := newPeerSourceMeter()
+	peerSources.Register(clientCfg)
 
 	client, err := torrent.NewClient(clientCfg)
 	if err != nil {
+		gossip.Close()
+		if listen.Port > 0 && isAddrInUse(err) {
+			return nil, portConflictError("network.listen_port", listen.Port, err)
+		}
 		return nil, fmt.Errorf("failed to create torrent client: %w", err)
 	}
+	// Listen on the same port after a restart
+	state.SavePeerPort(client.LocalPort())
+	if torrentProxy != nil {
+		client.AddDialer(torrent.NetworkDialer{Network: "tcp", Dialer: torrentProxy})
+		fmt.Printf("[TorrentManager] Using proxy %s for trackers and peers\n", torrentProxy)
+	}
 
 	tm := &TorrentManager{
-		client:   client,
-		config:   cfg,
-		state:    state,
-		torrents: make(map[string]*ManagedTorrent),
+		client:    client,
+		config:    cfg,
+		state:     state,
+		torrents:  make(map[string]*ManagedTorrent),
+		gossip:    gossip,
+		manifests: manifests,
+
+		uploadLimiter:   uploadLimiter,
+		downloadLimiter: downloadLimiter,
+		peerFilter:      peerFilter,
+
+		storage: backend,
+		pieces:  pieces,
+
+		sourceBytes:  sourceBytes,
+		peerSources:  peerSources,
+		uploadShares: newUploadShaper(),
 	}
+	fmt.Printf("[TorrentManager] Storage backend: %s\n", backend.Name())
 
-	// Restore previous torrents from state
-	if err := tm.restoreTorrents(); err != nil {
+	// Restore previous torrents from state. Relays don't seed models, their
+	// torrents stay in the state for when the daemon stores models again.
+	if configMode(cfg) == ModeRelay {
+		fmt.Printf("[TorrentManager] Relay mode, not restoring %d model torrents\n", len(state.ActiveTorrents))
+	} else if err := tm.restoreTorrents(); err != nil {
 		fmt.Printf("Warning: could not restore torrents: %v\n", err)
 	}
 
@@ -76,6 +225,7 @@ func NewTorrentManager(cfg *config.Config, state *State) (*TorrentManager, error
 func (tm *TorrentManager) restoreTorrents() error {
 	torrentsDir := storage.GetTorrentsDir()
 	modelsDir := storage.GetModelsDir()
+	recheck := tm.config.GetBool("torrent.recheck_on_start")
 	
 	// Load all torrents that were active in the previous session
 	for _, torrentInfo := range tm.state.ActiveTorrents {
@@ -90,15 +240,19 @@ func (tm *TorrentManager) restoreTorrents() error {
 
 		// Determine storage path based on torrent name
 		storagePath := filepath.Join(modelsDir, torrentInfo.Name)
+		if torrentInfo.StoragePath != "" {
+			storagePath = torrentInfo.StoragePath
+		}
+
+		// Pieces verified in a previous session are trusted unless asked
+		// to verify everything again
+		if recheck {
+			fmt.Printf("[TorrentManager] Rechecking %s\n", torrentInfo.Name)
+			tm.pieces.Recheck(mi.HashInfoBytes())
+		}
 		
 		// Create custom storage pointing to the specific directory
-		customStorage := torrentStorage.NewFileOpts(torrentStorage.NewFileClientOpts{
-			ClientBaseDir: storagePath,
-			TorrentDirMaker: func(baseDir string, info *metainfo.Info, infoHash metainfo.Hash) string {
-				// Return the base dir itself
-				return baseDir
-			},
-		})
+		customStorage := tm.storageFor(storagePath)
 
 		// Add torrent with custom storage
 		t, _ := tm.client.AddTorrentOpt(torrent.AddTorrentOpts{
@@ -112,20 +266,24 @@ func (tm *TorrentManager) restoreTorrents() error {
 			continue
 		}
 
-		// Start downloading/seeding
-		t.DownloadAll()
+		// Start downloading/seeding the selected files
+		applyFileSelection(t, torrentInfo.Files)
 		
 		mt := &ManagedTorrent{
-			InfoHash: torrentInfo.InfoHash,
-			Name:     torrentInfo.Name,
-			Torrent:  t,
-			AddedAt:  torrentInfo.AddedAt,
-			Seeding:  torrentInfo.Seeding,
+			InfoHash:    torrentInfo.InfoHash,
+			Name:        torrentInfo.Name,
+			StoragePath: storagePath,
+			Torrent:     t,
+			AddedAt:     torrentInfo.AddedAt,
+			BytesDown:   torrentInfo.BytesDown,
+			BytesUp:     torrentInfo.BytesUp,
+			Seeding:     torrentInfo.Seeding,
 		}
 		
 		if torrentInfo.CompletedAt != nil {
 			mt.CompletedAt = torrentInfo.CompletedAt
 		}
+		mt.setFiles(torrentInfo.Files)
 		
 		tm.torrents[torrentInfo.InfoHash] = mt
 		fmt.Printf("Restored torrent: %s (seeding: %v)\n", torrentInfo.Name, torrentInfo.Seeding)
@@ -147,15 +305,19 @@ func (tm *TorrentManager) AddTorrentForSeeding(torrentPath string, name string,
 	if err != nil {
 		return nil, fmt.Errorf("failed to load torrent metainfo: %w", err)
 	}
+	if err := tm.checkBlocked(name, mi.HashInfoBytes().HexString()); err != nil {
+		return nil, err
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load torrent metainfo: %w", err)
+	}
+	if err := checkTorrentFiles(&info); err != nil {
+		return nil, err
+	}
 
 	// Create custom storage pointing to the specific directory
-	customStorage := torrentStorage.NewFileOpts(torrentStorage.NewFileClientOpts{
-		ClientBaseDir: storagePath,
-		TorrentDirMaker: func(baseDir string, info *metainfo.Info, infoHash metainfo.Hash) string {
-			// Return the base dir itself since files are already in the right place
-			return baseDir
-		},
-	})
+	customStorage := tm.storageFor(storagePath)
 
 	// Add torrent with custom storage
 	t, isNew := tm.client.AddTorrentOpt(torrent.AddTorrentOpts{
@@ -175,17 +337,19 @@ func (tm *TorrentManager) AddTorrentForSeeding(torrentPath string, name string,
 	t.DownloadAll()
 
 	mt := &ManagedTorrent{
-		InfoHash: t.InfoHash().String(),
-		Name:     name,
-		Torrent:  t,
-		AddedAt:  time.Now(),
-		Seeding:  true, // Explicitly mark as seeding
+		InfoHash:    t.InfoHash().String(),
+		Name:        name,
+		StoragePath: storagePath,
+		Torrent:     t,
+		AddedAt:     time.Now(),
+		Seeding:     true, // Explicitly mark as seeding
 	}
 
 	tm.torrents[mt.InfoHash] = mt
 	
 	// Update state
 	tm.state.AddTorrent(mt.InfoHash, name, mt.AddedAt, true)
+	tm.recordStoragePath(mt)
 	
 	fmt.Printf("[TorrentManager] Torrent added for seeding: %s (InfoHash: %s)\n", name, mt.InfoHash)
 	return mt, nil
@@ -204,15 +368,12 @@ func (tm *TorrentManager) AddTorrentForDownload(torrentPath string, name string,
 	if err != nil {
 		return nil, fmt.Errorf("failed to load torrent metainfo: %w", err)
 	}
+	if err := tm.checkBlocked(name, mi.HashInfoBytes().HexString()); err != nil {
+		return nil, err
+	}
 
 	// Create custom storage pointing to the specific directory
-	customStorage := torrentStorage.NewFileOpts(torrentStorage.NewFileClientOpts{
-		ClientBaseDir: storagePath,
-		TorrentDirMaker: func(baseDir string, info *metainfo.Info, infoHash metainfo.Hash) string {
-			// Return the base dir itself to download files directly there
-			return baseDir
-		},
-	})
+	customStorage := tm.storageFor(storagePath)
 
 	// Add torrent with custom storage
 	t, isNew := tm.client.AddTorrentOpt(torrent.AddTorrentOpts{
@@ -227,26 +388,273 @@ func (tm *TorrentManager) AddTorrentForDownload(torrentPath string, name string,
 
 	fmt.Printf("[TorrentManager] Torrent added to client (new: %v)\n", isNew)
 
+	// Hide the model from the local model list until all pieces are in
+	if err := storage.MarkPartial(storagePath); err != nil {
+		fmt.Printf("[TorrentManager] Warning: %v\n", err)
+	}
+
 	// Start downloading
 	t.DownloadAll()
 
 	mt := &ManagedTorrent{
-		InfoHash: t.InfoHash().String(),
-		Name:     name,
-		Torrent:  t,
-		AddedAt:  time.Now(),
-		Seeding:  false, // Explicitly mark as downloading
+		InfoHash:    t.InfoHash().String(),
+		Name:        name,
+		StoragePath: storagePath,
+		Torrent:     t,
+		AddedAt:     time.Now(),
+		Seeding:     false, // Explicitly mark as downloading
 	}
 
 	tm.torrents[mt.InfoHash] = mt
 	
 	// Update state
 	tm.state.AddTorrent(mt.InfoHash, name, mt.AddedAt, false)
+	tm.recordStoragePath(mt)
 	
 	fmt.Printf("[TorrentManager] Torrent added for download: %s (InfoHash: %s)\n", name, mt.InfoHash)
 	return mt, nil
 }
 
+// AddMagnetForDownload starts downloading a torrent known only by its
+// infohash. The metadata is fetched from peers; once it arrives the torrent
+// file is saved to the torrents directory and all files are downloaded.
+func (tm *TorrentManager) AddMagnetForDownload(infoHash string, name string, storagePath string) (*ManagedTorrent, error) {
+	var hash metainfo.Hash
+	if err := hash.FromHexString(infoHash); err != nil {
+		return nil, fmt.Errorf("invalid infohash %s: %w", infoHash, err)
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if mt, exists := tm.torrents[hash.HexString()]; exists {
+		return mt, nil
+	}
+	if err := tm.checkBlocked(name, hash.HexString()); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("[TorrentManager] Adding magnet for download: %s to %s\n", name, storagePath)
+
+	customStorage := tm.storageFor(storagePath)
+
+	t, _ := tm.client.AddTorrentOpt(torrent.AddTorrentOpts{
+		InfoHash: hash,
+		Storage:  customStorage,
+	})
+	if t == nil {
+		return nil, fmt.Errorf("failed to add torrent to client")
+	}
+
+	if err := storage.MarkPartial(storagePath); err != nil {
+		fmt.Printf("[TorrentManager] Warning: %v\n", err)
+	}
+
+	go func() {
+		select {
+		case <-t.GotInfo():
+		case <-t.Closed():
+			return
+		}
+		if err := checkTorrentFiles(t.Info()); err != nil {
+			tm.rejectDownload(t.InfoHash().String(), err)
+			return
+		}
+
+		// Keep the metainfo so the download can be restored after a restart
+		torrentPath := filepath.Join(storage.GetTorrentsDir(), t.InfoHash().HexString()+".torrent")
+		if err := saveMetainfo(t.Metainfo(), torrentPath); err != nil {
+			fmt.Printf("[TorrentManager] Warning: %v\n", err)
+		}
+		mt := tm.GetManagedTorrent(t.InfoHash().String())
+		if mt == nil {
+			return
+		}
+		applyFileSelection(t, mt.Files())
+		tm.manifests.RequestManifest(t)
+		_, total := mt.WantedBytes()
+		fmt.Printf("[TorrentManager] Got metadata for %s, downloading %d bytes\n", name, total)
+	}()
+
+	mt := &ManagedTorrent{
+		InfoHash:    t.InfoHash().String(),
+		Name:        name,
+		StoragePath: storagePath,
+		Torrent:     t,
+		AddedAt:     time.Now(),
+		Seeding:     false,
+	}
+
+	tm.torrents[mt.InfoHash] = mt
+	tm.state.AddTorrent(mt.InfoHash, name, mt.AddedAt, false)
+	tm.recordStoragePath(mt)
+
+	return mt, nil
+}
+
+// checkTorrentFiles refuses torrents with hidden files. Silmaril keeps its
+// manifest and the partial and quarantine markers next to the model files,
+// which such a torrent could plant or clear, and models are published
+// without hidden files.
+func checkTorrentFiles(info *metainfo.Info) error {
+	for _, f := range info.UpvertedFiles() {
+		if path := f.DisplayPath(info); isHiddenPath(path) {
+			return fmt.Errorf("torrent has the hidden file %s, refusing to download it", path)
+		}
+	}
+	return nil
+}
+
+// rejectDownload drops a download whose metadata was refused
+func (tm *TorrentManager) rejectDownload(infoHash string, err error) {
+	mt := tm.GetManagedTorrent(infoHash)
+	if mt == nil || tm.RemoveTorrent(infoHash) != nil {
+		return
+	}
+	fmt.Printf("[TorrentManager] Dropped %s: %v\n", mt.Name, err)
+	tm.mu.RLock()
+	rejected := tm.rejected
+	tm.mu.RUnlock()
+	if rejected != nil {
+		rejected(mt, err)
+	}
+}
+
+// AddForDownload starts downloading a torrent from its torrent file in the
+// torrents directory, or by infohash from peers if there is none. It reports
+// whether the metadata has to be fetched.
+func (tm *TorrentManager) AddForDownload(infoHash string, name string, storagePath string) (*ManagedTorrent, bool, error) {
+	// The name becomes the model's path in the registry
+	if err := modelname.Validate(name); err != nil {
+		return nil, false, err
+	}
+	// And the infohash the name of its torrent file
+	var hash metainfo.Hash
+	if err := hash.FromHexString(infoHash); err != nil {
+		return nil, false, fmt.Errorf("invalid infohash %s: %w", infoHash, err)
+	}
+	infoHash = hash.HexString()
+	torrentPath := filepath.Join(storage.GetTorrentsDir(), infoHash+".torrent")
+	if _, err := os.Stat(torrentPath); err == nil {
+		mt, err := tm.AddTorrentForDownload(torrentPath, name, storagePath)
+		return mt, false, err
+	}
+	mt, err := tm.AddMagnetForDownload(infoHash, name, storagePath)
+	return mt, true, err
+}
+
+// saveMetainfo writes mi as a torrent file to path
+func saveMetainfo(mi metainfo.MetaInfo, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create torrents directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to save torrent file: %w", err)
+	}
+	if err := mi.Write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to save torrent file: %w", err)
+	}
+	return f.Close()
+}
+
+// recordStoragePath remembers the storage path of torrents outside the
+// models directory, so they are restored to the same place
+func (tm *TorrentManager) recordStoragePath(mt *ManagedTorrent) {
+	storagePath := ""
+	if modelPath, err := modelPathFor(mt.Name); err != nil || filepath.Clean(mt.StoragePath) != modelPath {
+		storagePath = mt.StoragePath
+	}
+	tm.state.SetTorrentStoragePath(mt.InfoHash, storagePath)
+}
+
+// DetachedTorrent is a torrent dropped from the client while its files
+// move, with what is needed to add it again
+type DetachedTorrent struct {
+	State    TorrentState
+	MetaInfo metainfo.MetaInfo
+}
+
+// DetachTorrent drops a torrent from the client, so its files can be moved,
+// see ReattachTorrent
+func (tm *TorrentManager) DetachTorrent(infoHash string) (*DetachedTorrent, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	mt, exists := tm.torrents[infoHash]
+	if !exists {
+		return nil, fmt.Errorf("torrent not found: %s", infoHash)
+	}
+	if mt.Torrent.Info() == nil {
+		return nil, fmt.Errorf("torrent %s has no metadata yet", infoHash)
+	}
+
+	state, ok := tm.state.GetTorrentState(infoHash)
+	if !ok {
+		state = TorrentState{
+			InfoHash:    infoHash,
+			Name:        mt.Name,
+			AddedAt:     mt.AddedAt,
+			CompletedAt: mt.CompletedAt,
+			Seeding:     mt.Seeding,
+			Files:       mt.Files(),
+		}
+	}
+	dt := &DetachedTorrent{State: state, MetaInfo: mt.Torrent.Metainfo()}
+
+	tm.dropEquivalentsLocked(infoHash)
+	tm.sourceBytes.forget(infoHash)
+	tm.peerSources.forget(infoHash)
+	tm.uploadShares.forget(infoHash)
+	mt.Torrent.Drop()
+	delete(tm.torrents, infoHash)
+	tm.state.RemoveTorrent(infoHash)
+	return dt, nil
+}
+
+// ReattachTorrent adds a detached torrent again under a new name, with its
+// files in storagePath. Its statistics, file selection and whether it seeds
+// are kept.
+func (tm *TorrentManager) ReattachTorrent(dt *DetachedTorrent, name, storagePath string) (*ManagedTorrent, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	customStorage := tm.storageFor(storagePath)
+	t, _ := tm.client.AddTorrentOpt(torrent.AddTorrentOpts{
+		InfoHash:  dt.MetaInfo.HashInfoBytes(),
+		Storage:   customStorage,
+		InfoBytes: dt.MetaInfo.InfoBytes,
+	})
+	if t == nil {
+		return nil, fmt.Errorf("failed to add torrent to client")
+	}
+	applyFileSelection(t, dt.State.Files)
+	if !dt.State.Seeding {
+		t.DisallowDataDownload()
+		t.DisallowDataUpload()
+	}
+
+	mt := &ManagedTorrent{
+		InfoHash:    dt.State.InfoHash,
+		Name:        name,
+		StoragePath: storagePath,
+		Torrent:     t,
+		AddedAt:     dt.State.AddedAt,
+		CompletedAt: dt.State.CompletedAt,
+		BytesDown:   dt.State.BytesDown,
+		BytesUp:     dt.State.BytesUp,
+		Seeding:     dt.State.Seeding,
+	}
+	mt.setFiles(dt.State.Files)
+	tm.torrents[mt.InfoHash] = mt
+
+	state := dt.State
+	state.Name = name
+	tm.state.PutTorrent(state)
+	tm.recordStoragePath(mt)
+	return mt, nil
+}
 
 func (tm *TorrentManager) RemoveTorrent(infoHash string) error {
 	tm.mu.Lock()
@@ -257,6 +665,10 @@ func (tm *TorrentManager) RemoveTorrent(infoHash string) error {
 		return fmt.Errorf("torrent not found: %s", infoHash)
 	}
 
+	tm.dropEquivalentsLocked(infoHash)
+	tm.sourceBytes.forget(infoHash)
+	tm.peerSources.forget(infoHash)
+	tm.uploadShares.forget(infoHash)
 	mt.Torrent.Drop()
 	delete(tm.torrents, infoHash)
 	
@@ -266,6 +678,75 @@ func (tm *TorrentManager) RemoveTorrent(infoHash string) error {
 	return nil
 }
 
+// SetQuarantine sets the function holding finished downloads back until they
+// are verified
+func (tm *TorrentManager) SetQuarantine(quarantine func(mt *ManagedTorrent) bool) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.quarantine = quarantine
+}
+
+// SetRejected sets the function told about downloads dropped because their
+// metadata was refused
+func (tm *TorrentManager) SetRejected(rejected func(mt *ManagedTorrent, err error)) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.rejected = rejected
+}
+
+// ClearCompletedDownloads removes the partial download marker from models
+// whose torrents have all pieces, so they show up as local models once they
+// leave quarantine. Updates wait outside the models directory until they
+// are installed.
+func (tm *TorrentManager) ClearCompletedDownloads() {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	for _, mt := range tm.torrents {
+		if mt.StoragePath == "" || !mt.Complete() {
+			continue
+		}
+		if !storage.IsPartial(mt.StoragePath) {
+			continue
+		}
+		// Encrypted models are listed once they are decrypted
+		if manifest, err := models.ReadManifest(mt.StoragePath); err == nil && manifest.Encryption != nil {
+			continue
+		}
+		quarantined := tm.quarantine != nil && !isUpdateDownload(mt) && tm.quarantine(mt)
+		if err := storage.ClearPartial(mt.StoragePath); err != nil {
+			fmt.Printf("[TorrentManager] Warning: %v\n", err)
+			continue
+		}
+		if quarantined {
+			fmt.Printf("[TorrentManager] Download complete, verifying in quarantine: %s\n", mt.Name)
+			continue
+		}
+		fmt.Printf("[TorrentManager] Download complete: %s\n", mt.Name)
+	}
+}
+
+// PeerFilter returns the filter deciding which peers the client talks to
+func (tm *TorrentManager) PeerFilter() *peerfilter.Filter {
+	return tm.peerFilter
+}
+
+// SetModelFilter sets the filter of models refused for download and seeding
+func (tm *TorrentManager) SetModelFilter(filter *blocklist.Filter) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.modelFilter = filter
+}
+
+// checkBlocked returns ErrModelBlocked if the model filter blocks the model,
+// the caller holds tm.mu
+func (tm *TorrentManager) checkBlocked(name, infoHash string) error {
+	if entry, blocked := tm.modelFilter.Match(name, infoHash); blocked {
+		return fmt.Errorf("%w by %s: %s", ErrModelBlocked, entry.Pattern, name)
+	}
+	return nil
+}
+
 func (tm *TorrentManager) GetTorrent(infoHash string) (*ManagedTorrent, bool) {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
@@ -337,21 +818,186 @@ func (tm *TorrentManager) GetStats(infoHash string) (map[string]interface{}, err
 	stats := mt.Torrent.Stats()
 	peers := mt.Torrent.KnownSwarm()
 	
+	// Torrents added by infohash have no length until their metadata arrives
+	var progress int64
+	completed, total := mt.WantedBytes()
+	if total > 0 {
+		progress = completed * 100 / total
+	}
+	elapsed := int64(time.Since(mt.AddedAt).Seconds())
+	if elapsed < 1 {
+		elapsed = 1
+	}
+	downloadRate, uploadRate := mt.throughput.rates()
+	
 	return map[string]interface{}{
-		"name":             mt.Name,
-		"info_hash":        mt.InfoHash,
-		"seeding":          mt.Seeding,
-		"bytes_downloaded": stats.BytesReadData.Int64(),
-		"bytes_uploaded":   stats.BytesWrittenData.Int64(),
-		"peers":            len(peers),
-		"seeders":          stats.ConnectedSeeders,
-		"leechers":         len(peers) - stats.ConnectedSeeders,
-		"progress":         mt.Torrent.BytesCompleted() * 100 / mt.Torrent.Length(),
-		"download_rate":    stats.BytesReadData.Int64() / int64(time.Since(mt.AddedAt).Seconds()),
-		"upload_rate":      stats.BytesWrittenData.Int64() / int64(time.Since(mt.AddedAt).Seconds()),
+		"name":                  mt.Name,
+		"info_hash":             mt.InfoHash,
+		"seeding":               mt.Seeding,
+		"bytes_downloaded":      stats.BytesReadData.Int64(),
+		"bytes_uploaded":        stats.BytesWrittenData.Int64(),
+		"peers":                 len(peers),
+		"seeders":               stats.ConnectedSeeders,
+		"leechers":              len(peers) - stats.ConnectedSeeders,
+		"progress":              progress,
+		"download_rate":         downloadRate,
+		"upload_rate":           uploadRate,
+		"average_download_rate": stats.BytesReadData.Int64() / elapsed,
+		"average_upload_rate":   stats.BytesWrittenData.Int64() / elapsed,
+		"bytes_remaining":       total - completed,
+		"seed_mode":             mt.SeedMode,
+		"distributed_copies":    mt.DistributedCopies,
+		"total_bytes":           total,
+		"files":                 mt.Files(),
+		"availability":          mt.availability,
 	}, nil
 }
 
+// SampleThroughput feeds the byte counters of all torrents to their
+// throughput meters
+func (tm *TorrentManager) SampleThroughput() {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	now := time.Now()
+	for _, mt := range tm.torrents {
+		stats := mt.Torrent.Stats()
+		mt.throughput.sample(now, stats.BytesReadData.Int64(), stats.BytesWrittenData.Int64())
+	}
+}
+
+// SampleContribution returns what each torrent transferred since it was last
+// sampled, with the peers connected to it
+func (tm *TorrentManager) SampleContribution() []ContributionSample {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	samples := make([]ContributionSample, 0, len(tm.torrents))
+	for _, mt := range tm.torrents {
+		stats := mt.Torrent.Stats()
+		down, up := stats.BytesReadData.Int64(), stats.BytesWrittenData.Int64()
+		sample := ContributionSample{
+			Model:      mt.Name,
+			Downloaded: down - mt.accountedDown,
+			Uploaded:   up - mt.accountedUp,
+		}
+		mt.accountedDown, mt.accountedUp = down, up
+		for _, pc := range mt.Torrent.PeerConns() {
+			if peer, ok := peerHash(pc.RemoteAddr); ok {
+				sample.Peers = append(sample.Peers, peer)
+			}
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+// GetThroughput returns the smoothed rates of a torrent, its recent history
+// and what each connected peer contributes
+func (tm *TorrentManager) GetThroughput(infoHash string) (*TransferStats, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	mt, exists := tm.torrents[infoHash]
+	if !exists {
+		return nil, fmt.Errorf("torrent not found: %s", infoHash)
+	}
+
+	stats := mt.Torrent.Stats()
+	elapsed := int64(time.Since(mt.AddedAt).Seconds())
+	if elapsed < 1 {
+		elapsed = 1
+	}
+	completed, total := mt.WantedBytes()
+	result := &TransferStats{
+		InfoHash:            mt.InfoHash,
+		AverageDownloadRate: stats.BytesReadData.Int64() / elapsed,
+		AverageUploadRate:   stats.BytesWrittenData.Int64() / elapsed,
+		BytesRemaining:      total - completed,
+		Peers:               []PeerThroughput{},
+		Sources:             sourceThroughput(mt.sources, stats.BytesReadUsefulData.Int64(), tm.sourceBytes.get(mt.InfoHash)),
+		History:             mt.throughput.samples(),
+		Window:              throughputWindow,
+		SampleInterval:      throughputSampleInterval,
+	}
+	result.DownloadRate, result.UploadRate = mt.throughput.rates()
+	result.ETA = estimateETA(result.BytesRemaining, result.DownloadRate)
+
+	// The torrent client only counts what peers send us, not what we
+	// send each of them
+	pieces := mt.Torrent.NumPieces()
+	for _, pc := range mt.Torrent.PeerConns() {
+		peer := PeerThroughput{DownloadRate: int64(pc.DownloadRate())}
+		if pc.RemoteAddr != nil {
+			peer.Addr = pc.RemoteAddr.String()
+		}
+		if client, ok := pc.PeerClientName.Load().(string); ok {
+			peer.Client = client
+		}
+		if pieces > 0 {
+			peer.Progress = float64(pc.PeerPieces().GetCardinality()) * 100 / float64(pieces)
+		}
+		result.Peers = append(result.Peers, peer)
+	}
+	sortPeerThroughput(result.Peers)
+	return result, nil
+}
+
+// SetSeedMode switches a torrent between normal and initial seed mode and
+// records the distributed copies among its peers. It reports whether the mode
+// changed.
+func (tm *TorrentManager) SetSeedMode(infoHash, mode string, copies float64) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	mt, exists := tm.torrents[infoHash]
+	if !exists {
+		return false
+	}
+	mt.DistributedCopies = copies
+	if mt.SeedMode == mode {
+		return false
+	}
+
+	previous := mt.SeedMode
+	mt.SeedMode = mode
+	switch {
+	case mode == SeedModeInitial:
+		mt.normalMaxConns = mt.Torrent.SetMaxEstablishedConns(initialSeedMaxConns)
+	case previous == SeedModeInitial && mt.normalMaxConns > 0:
+		mt.Torrent.SetMaxEstablishedConns(mt.normalMaxConns)
+	}
+	return previous != "" || mode != SeedModeNormal
+}
+
+// SetAvailability records the piece availability of a torrent's swarm
+func (tm *TorrentManager) SetAvailability(infoHash string, a PieceAvailability) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if mt, exists := tm.torrents[infoHash]; exists {
+		mt.availability = &a
+	}
+}
+
+// Availability returns the last measured piece availability of a torrent
+func (tm *TorrentManager) Availability(infoHash string) (PieceAvailability, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	mt, exists := tm.torrents[infoHash]
+	if !exists || mt.availability == nil {
+		return PieceAvailability{}, false
+	}
+	return *mt.availability, true
+}
+
+// Uploaded returns the bytes uploaded for a torrent across all sessions
+func (mt *ManagedTorrent) Uploaded() int64 {
+	stats := mt.Torrent.Stats()
+	return mt.BytesUp + stats.BytesWrittenData.Int64()
+}
+
 func (tm *TorrentManager) GetTotalPeers() int {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
@@ -364,6 +1010,50 @@ func (tm *TorrentManager) GetTotalPeers() int {
 	return totalPeers
 }
 
+// GetPeersByFamily returns the number of connected peers per address family
+func (tm *TorrentManager) GetPeersByFamily() map[string]int {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	counts := map[string]int{familyIPv4: 0, familyIPv6: 0}
+	for _, mt := range tm.torrents {
+		for _, pc := range mt.Torrent.PeerConns() {
+			if family := addrFamily(pc.RemoteAddr); family != "" {
+				counts[family]++
+			}
+		}
+	}
+	return counts
+}
+
+// GetListenAddrs returns the addresses the torrent client listens on. TCP
+// and uTP listen on the same addresses.
+func (tm *TorrentManager) GetListenAddrs() []string {
+	var addrs []string
+	for _, addr := range tm.client.ListenAddrs() {
+		if !slices.Contains(addrs, addr.String()) {
+			addrs = append(addrs, addr.String())
+		}
+	}
+	return addrs
+}
+
+// SetRateLimits changes the upload and download limits of the running client
+// in bytes per second, 0 means unlimited
+func (tm *TorrentManager) SetRateLimits(upload, download int64) {
+	setLimit(tm.uploadLimiter, upload)
+	setLimit(tm.downloadLimiter, download)
+}
+
+func setLimit(limiter *rate.Limiter, bytesPerSecond int64) {
+	if bytesPerSecond <= 0 {
+		limiter.SetLimit(rate.Inf)
+		return
+	}
+	limiter.SetBurst(int(bytesPerSecond))
+	limiter.SetLimit(rate.Limit(bytesPerSecond))
+}
+
 func (tm *TorrentManager) Stop() {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
@@ -371,15 +1061,19 @@ func (tm *TorrentManager) Stop() {
 	// Save final stats for all torrents
 	for _, mt := range tm.torrents {
 		stats := mt.Torrent.Stats()
-		mt.BytesDown = stats.BytesReadData.Int64()
-		mt.BytesUp = stats.BytesWrittenData.Int64()
+		mt.BytesDown += stats.BytesReadData.Int64()
+		mt.BytesUp += stats.BytesWrittenData.Int64()
 		
 		// Update state with final stats
 		tm.state.UpdateTorrentStats(mt.InfoHash, mt.BytesDown, mt.BytesUp)
 	}
 
 	// Close the torrent client
+	tm.gossip.Close()
 	tm.client.Close()
+	if err := tm.pieces.Close(); err != nil {
+		fmt.Printf("[Storage] Failed to close piece completion database: %v\n", err)
+	}
 }
 
 // GetClient returns the underlying torrent client (for DHT manager)
@@ -387,6 +1081,16 @@ func (tm *TorrentManager) GetClient() *torrent.Client {
 	return tm.client
 }
 
+// GetCatalogGossip returns the peer-to-peer catalog gossip handler
+func (tm *TorrentManager) GetCatalogGossip() *discovery.CatalogGossip {
+	return tm.gossip
+}
+
+// GetManifestExchange returns the peer-to-peer manifest exchange handler
+func (tm *TorrentManager) GetManifestExchange() *discovery.ManifestExchange {
+	return tm.manifests
+}
+
 // GetSeedingModels returns a list of currently seeded models
 func (tm *TorrentManager) GetSeedingModels() []*ManagedTorrent {
 	tm.mu.RLock()
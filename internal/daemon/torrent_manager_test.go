@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/anacrolix/torrent/metainfo"
 	"github.com/silmaril/silmaril/internal/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -204,4 +205,52 @@ func TestTorrentManagerStateIntegration(t *testing.T) {
 	
 	// When adding a torrent, it should update state
 	// (This would require a valid torrent file to test properly)
-}
\ No newline at end of file
+}
+func TestTorrentManagerNetworkStats(t *testing.T) {
+	tm, _, _ := setupTestTorrentManager(t)
+	defer tm.Stop()
+	
+	assert.NotEmpty(t, tm.GetListenAddrs())
+	assert.Equal(t, map[string]int{"ipv4": 0, "ipv6": 0}, tm.GetPeersByFamily())
+}
+
+func TestTorrentManagerProxy(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Storage: config.StorageConfig{BaseDir: tmpDir},
+		Proxy:   config.ProxyConfig{URL: "socks5://127.0.0.1:1080"},
+	}
+
+	tm, err := NewTorrentManager(cfg, NewState(filepath.Join(tmpDir, "state.json")))
+	require.NoError(t, err)
+	defer tm.Stop()
+
+	// Peers can't reach us through the proxy, so nothing listens
+	assert.Empty(t, tm.client.Listeners())
+
+	cfg.Proxy.Torrent = "ftp://127.0.0.1:21"
+	_, err = NewTorrentManager(cfg, NewState(filepath.Join(tmpDir, "state2.json")))
+	assert.ErrorContains(t, err, "torrent proxy")
+}
+
+func TestCheckTorrentFiles(t *testing.T) {
+	info := &metainfo.Info{
+		Name: "model",
+		Files: []metainfo.FileInfo{
+			{Path: []string{"config.json"}, Length: 10},
+			{Path: []string{"weights", "model.safetensors"}, Length: 100},
+		},
+	}
+	assert.NoError(t, checkTorrentFiles(info))
+
+	// Torrents could otherwise plant a manifest or clear the quarantine
+	for _, path := range [][]string{
+		{".silmaril.json"},
+		{".silmaril.quarantine"},
+		{"weights", ".hidden"},
+	} {
+		info.Files = append(info.Files[:2:2], metainfo.FileInfo{Path: path, Length: 1})
+		assert.Error(t, checkTorrentFiles(info), path)
+	}
+	assert.Error(t, checkTorrentFiles(&metainfo.Info{Name: ".silmaril.partial", Length: 1}))
+}
@@ -0,0 +1,126 @@
+package daemon
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/anacrolix/torrent/metainfo"
+	torrentStorage "github.com/anacrolix/torrent/storage"
+	"github.com/silmaril/silmaril/internal/models"
+)
+
+// Storage backends for the data of torrents
+const (
+	StorageFile   = "file"   // Reads and writes files, the default
+	StorageMMap   = "mmap"   // Maps files into memory, for read-heavy seeding
+	StorageCached = "cached" // Files, with piece completion only in the daemon's store
+)
+
+// StorageBackends lists the backends torrent.storage_backend and the
+// storage of a model can be set to
+var StorageBackends = []string{StorageFile, StorageMMap, StorageCached}
+
+// StorageBackend stores the data of torrents. Whatever the backend, the files
+// of a torrent are kept in its model directory, laid out as in the torrent.
+type StorageBackend interface {
+	Name() string
+	// Open returns the storage of a torrent with its files in dir
+	Open(dir string) torrentStorage.ClientImpl
+}
+
+// newStorageBackend returns the backend called name, recording verified
+// pieces in pieces
+func newStorageBackend(name string, pieces *pieceStore) (StorageBackend, error) {
+	switch name {
+	case StorageFile, "":
+		return fileBackend{pieces: pieces}, nil
+	case StorageMMap:
+		return mmapBackend{pieces: pieces}, nil
+	case StorageCached:
+		return cachedBackend{pieces: pieces}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q, expected one of %v", name, StorageBackends)
+	}
+}
+
+// ValidStorageBackend reports whether name is a storage backend
+func ValidStorageBackend(name string) bool {
+	return slices.Contains(StorageBackends, name)
+}
+
+// torrentDir keeps the files of a torrent directly in the directory given to
+// the storage, not in a directory of its own
+func torrentDir(baseDir string, info *metainfo.Info, infoHash metainfo.Hash) string {
+	return baseDir
+}
+
+// fileBackend reads and writes the files of a torrent, with piece completion
+// in a database in the model directory
+type fileBackend struct {
+	pieces *pieceStore
+}
+
+func (fileBackend) Name() string { return StorageFile }
+
+func (b fileBackend) Open(dir string) torrentStorage.ClientImpl {
+	return torrentStorage.NewFileOpts(torrentStorage.NewFileClientOpts{
+		ClientBaseDir:   dir,
+		TorrentDirMaker: torrentDir,
+		PieceCompletion: b.pieces.forDir(dir),
+	})
+}
+
+// mmapBackend maps the files of a torrent into memory, so pieces uploaded to
+// many peers are served from the page cache without a read per request. The
+// model directory must be writable.
+type mmapBackend struct {
+	pieces *pieceStore
+}
+
+func (mmapBackend) Name() string { return StorageMMap }
+
+func (b mmapBackend) Open(dir string) torrentStorage.ClientImpl {
+	return torrentStorage.NewMMapWithCompletion(dir, b.pieces.forDir(dir))
+}
+
+// cachedBackend reads and writes the files of a torrent like fileBackend, but
+// keeps piece completion in the daemon's database instead of one in the
+// model directory, leaving nothing but the model's files there
+type cachedBackend struct {
+	pieces *pieceStore
+}
+
+func (cachedBackend) Name() string { return StorageCached }
+
+func (b cachedBackend) Open(dir string) torrentStorage.ClientImpl {
+	return torrentStorage.NewFileOpts(torrentStorage.NewFileClientOpts{
+		ClientBaseDir:   dir,
+		TorrentDirMaker: torrentDir,
+		PieceCompletion: b.pieces.shared(),
+	})
+}
+
+// storageFor returns the storage of a torrent with its files in dir, reading
+// what it uploads through its upload limiter
+func (tm *TorrentManager) storageFor(dir string) torrentStorage.ClientImpl {
+	impl := tm.backendFor(dir).Open(dir)
+	if tm.uploadShares == nil {
+		return impl
+	}
+	return shapedStorage{ClientImpl: impl, shaper: tm.uploadShares}
+}
+
+// backendFor returns the backend set in the manifest of the model in dir, or
+// else the daemon's
+func (tm *TorrentManager) backendFor(dir string) StorageBackend {
+	manifest, err := models.ReadManifest(dir)
+	if err != nil || manifest.Storage == "" || manifest.Storage == tm.storage.Name() {
+		return tm.storage
+	}
+	backend, err := newStorageBackend(manifest.Storage, tm.pieces)
+	if err != nil {
+		fmt.Printf("[Storage] %s: %v, using %s\n", dir, err, tm.storage.Name())
+		return tm.storage
+	}
+	return backend
+}
@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+	torrentStorage "github.com/anacrolix/torrent/storage"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePieces writes data through a backend and marks its pieces complete
+func writePieces(t *testing.T, backend StorageBackend, dir string, info *metainfo.Info, data []byte) metainfo.Hash {
+	t.Helper()
+	infoHash := metainfo.HashBytes([]byte(dir))
+	ts, err := backend.Open(dir).OpenTorrent(context.Background(), info, infoHash)
+	require.NoError(t, err)
+
+	for i := 0; i < info.NumPieces(); i++ {
+		p := info.Piece(i)
+		piece := ts.Piece(p)
+		_, err := piece.WriteAt(data[p.Offset():p.Offset()+p.Length()], 0)
+		require.NoError(t, err)
+		require.NoError(t, piece.MarkComplete())
+	}
+	if ts.Flush != nil {
+		require.NoError(t, ts.Flush())
+	}
+	require.NoError(t, ts.Close())
+	return infoHash
+}
+
+func TestStorageBackends(t *testing.T) {
+	data := []byte("weights of a small model, two pieces")
+	info := &metainfo.Info{
+		PieceLength: 20,
+		Files:       []metainfo.FileInfo{{Path: []string{"model.bin"}, Length: int64(len(data))}},
+		Pieces:      make([]byte, 2*20),
+	}
+	pieces := newPieceStore(t.TempDir())
+	defer pieces.Close()
+
+	for _, name := range StorageBackends {
+		t.Run(name, func(t *testing.T) {
+			backend, err := newStorageBackend(name, pieces)
+			require.NoError(t, err)
+			assert.Equal(t, name, backend.Name())
+
+			dir := t.TempDir()
+			infoHash := writePieces(t, backend, dir, info, data)
+
+			// Files are laid out directly in the model directory
+			written, err := os.ReadFile(filepath.Join(dir, "model.bin"))
+			require.NoError(t, err)
+			assert.Equal(t, data, written)
+
+			ts, err := backend.Open(dir).OpenTorrent(context.Background(), info, infoHash)
+			require.NoError(t, err)
+			defer ts.Close()
+			for i := 0; i < info.NumPieces(); i++ {
+				assert.True(t, ts.Piece(info.Piece(i)).Completion().Complete, "piece %d", i)
+			}
+		})
+	}
+}
+
+func TestCachedBackendKeepsCompletion(t *testing.T) {
+	data := []byte("model")
+	info := &metainfo.Info{
+		PieceLength: 8,
+		Files:       []metainfo.FileInfo{{Path: []string{"model.bin"}, Length: int64(len(data))}},
+		Pieces:      make([]byte, 20),
+	}
+	storeDir, modelDir := t.TempDir(), t.TempDir()
+
+	pieces := newPieceStore(storeDir)
+	infoHash := writePieces(t, cachedBackend{pieces: pieces}, modelDir, info, data)
+	require.NoError(t, pieces.Close())
+
+	// Nothing is written to the model directory but its files
+	entries, err := os.ReadDir(modelDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// The verified pieces are remembered by the next daemon
+	pieces = newPieceStore(storeDir)
+	defer pieces.Close()
+	completion, err := pieces.shared().Get(metainfo.PieceKey{InfoHash: infoHash, Index: 0})
+	require.NoError(t, err)
+	assert.Equal(t, torrentStorage.Completion{Complete: true, Ok: true}, completion)
+}
+
+func TestModelStorageBackend(t *testing.T) {
+	pieces := newPieceStore(t.TempDir())
+	tm := &TorrentManager{storage: fileBackend{pieces: pieces}, pieces: pieces}
+	defer tm.pieces.Close()
+
+	dir := t.TempDir()
+	assert.Equal(t, StorageFile, tm.backendFor(dir).Name())
+
+	require.NoError(t, models.WriteManifest(dir, &types.ModelManifest{Name: "org/model", Storage: StorageMMap}))
+	assert.Equal(t, StorageMMap, tm.backendFor(dir).Name())
+
+	// An unknown backend falls back to the daemon's
+	require.NoError(t, models.WriteManifest(dir, &types.ModelManifest{Name: "org/model", Storage: "tape"}))
+	assert.Equal(t, StorageFile, tm.backendFor(dir).Name())
+
+	_, err := newStorageBackend("tape", tm.pieces)
+	assert.Error(t, err)
+	assert.False(t, ValidStorageBackend("tape"))
+}
@@ -1,11 +1,15 @@
 package daemon
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/silmaril/silmaril/internal/storage"
 )
 
 type TransferType string
@@ -39,12 +43,39 @@ type Transfer struct {
 	DownloadRate int64          `json:"download_rate"`
 	UploadRate   int64          `json:"upload_rate"`
 	Peers        int            `json:"peers"`
+	PeakPeers    int            `json:"peak_peers,omitempty"` // Most peers at once
 	Seeders      int            `json:"seeders"`
 	ETA          *time.Duration `json:"eta,omitempty"`
 	StartedAt    time.Time      `json:"started_at"`
 	CompletedAt  *time.Time     `json:"completed_at,omitempty"`
 	LastActivity time.Time      `json:"last_activity"`
 	Error        string         `json:"error,omitempty"`
+
+	// Seeding progress against the seeding policy, set once all pieces are local
+	Ratio          float64        `json:"ratio,omitempty"`
+	SeedingSeconds int64          `json:"seeding_seconds,omitempty"`
+	SeedingLimits  *SeedingLimits `json:"seeding_limits,omitempty"`
+	StopReason     string         `json:"stop_reason,omitempty"` // Why seeding stopped
+
+	// Seed mode, "initial" while we are the sole seeder, and the number of
+	// complete copies the peers hold between them
+	SeedMode          string  `json:"seed_mode,omitempty"`
+	DistributedCopies float64 `json:"distributed_copies,omitempty"`
+
+	// Patterns of the files transferred, all files if empty
+	Files []string `json:"files,omitempty"`
+
+	// How well the pieces are spread among connected peers
+	Availability *PieceAvailability `json:"availability,omitempty"`
+}
+
+// IsLive reports whether the transfer is still running or waiting to
+func (t *Transfer) IsLive() bool {
+	switch t.Status {
+	case TransferStatusPending, TransferStatusActive, TransferStatusPaused:
+		return true
+	}
+	return false
 }
 
 type TransferManager struct {
@@ -52,6 +83,11 @@ type TransferManager struct {
 	torrentManager *TorrentManager
 	state          *State
 	transfers      map[string]*Transfer
+
+	// OnFinish is called with each transfer once it completed, was cancelled
+	// or failed. It runs under the lock of the manager, so it must not call
+	// back into it.
+	OnFinish func(transfer Transfer)
 }
 
 func NewTransferManager(tm *TorrentManager, state *State) *TransferManager {
@@ -66,6 +102,10 @@ func (tm *TransferManager) CreateDownload(modelName, infoHash string, totalBytes
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
+	return tm.createDownload(modelName, infoHash, totalBytes)
+}
+
+func (tm *TransferManager) createDownload(modelName, infoHash string, totalBytes int64) *Transfer {
 	transfer := &Transfer{
 		ID:           uuid.New().String(),
 		Type:         TransferTypeDownload,
@@ -83,6 +123,33 @@ func (tm *TransferManager) CreateDownload(modelName, infoHash string, totalBytes
 	return transfer
 }
 
+// CreateDownloadOnce creates the download transfer of a torrent unless the
+// torrent is already downloading, or downloaded and its model still there.
+// That transfer is returned instead, with false.
+func (tm *TransferManager) CreateDownloadOnce(modelName, infoHash string) (*Transfer, bool) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if existing := tm.findTransfer(infoHash, TransferTypeDownload); existing != nil {
+		if existing.IsLive() || (existing.Status == TransferStatusCompleted && modelDownloaded(existing.ModelName)) {
+			return existing, false
+		}
+	}
+	return tm.createDownload(modelName, infoHash, 0), true
+}
+
+// modelDownloaded reports whether the model is complete on disk
+func modelDownloaded(modelName string) bool {
+	modelPath, err := modelPathFor(modelName)
+	if err != nil {
+		return false
+	}
+	if _, err := os.Stat(modelPath); err != nil {
+		return false
+	}
+	return !storage.IsPartial(modelPath)
+}
+
 func (tm *TransferManager) CreateUpload(modelName, infoHash string) *Transfer {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
@@ -170,18 +237,34 @@ func (tm *TransferManager) UpdateStats() {
 		}
 		if v, ok := stats["peers"].(int); ok {
 			transfer.Peers = v
+			if v > transfer.PeakPeers {
+				transfer.PeakPeers = v
+			}
 		}
 		if v, ok := stats["seeders"].(int); ok {
 			transfer.Seeders = v
 		}
+		if v, ok := stats["seed_mode"].(string); ok {
+			transfer.SeedMode = v
+		}
+		if v, ok := stats["distributed_copies"].(float64); ok {
+			transfer.DistributedCopies = v
+		}
+		if v, ok := stats["files"].([]string); ok {
+			transfer.Files = v
+		}
+		if v, ok := stats["availability"].(*PieceAvailability); ok && v != nil {
+			transfer.Availability = v
+		}
+		if v, ok := stats["total_bytes"].(int64); ok && v > 0 {
+			transfer.TotalBytes = v
+		}
 		transfer.LastActivity = time.Now()
 
-		// Calculate ETA for downloads
-		if transfer.Type == TransferTypeDownload && transfer.DownloadRate > 0 {
-			remainingBytes := transfer.TotalBytes - transfer.BytesTransferred
-			etaSeconds := remainingBytes / transfer.DownloadRate
-			eta := time.Duration(etaSeconds) * time.Second
-			transfer.ETA = &eta
+		// Estimate the time left of downloads from the smoothed rate
+		if transfer.Type == TransferTypeDownload {
+			remaining, _ := stats["bytes_remaining"].(int64)
+			transfer.ETA = estimateETA(remaining, transfer.DownloadRate)
 		}
 
 		// Check if download is complete
@@ -190,11 +273,17 @@ func (tm *TransferManager) UpdateStats() {
 			now := time.Now()
 			transfer.CompletedAt = &now
 			transfer.ETA = nil
+			tm.finish(transfer)
 		}
 	}
 
 	// Update state
 	tm.state.UpdateTransfers(tm.transfers)
+	
+	// Show finished downloads as local models
+	if tm.torrentManager != nil {
+		tm.torrentManager.ClearCompletedDownloads()
+	}
 }
 
 func (tm *TransferManager) GetTransfer(id string) (*Transfer, bool) {
@@ -205,6 +294,50 @@ func (tm *TransferManager) GetTransfer(id string) (*Transfer, bool) {
 	return transfer, exists
 }
 
+// ErrNoTransferStats is returned for transfers without a running torrent
+var ErrNoTransferStats = errors.New("transfer has no running torrent")
+
+// GetTransferStats returns the smoothed rates, ETA, peers and rate history of
+// a transfer's torrent
+func (tm *TransferManager) GetTransferStats(id string) (*TransferStats, error) {
+	transfer, exists := tm.GetTransfer(id)
+	if !exists {
+		return nil, fmt.Errorf("transfer %s not found", id)
+	}
+	if tm.torrentManager == nil {
+		return nil, ErrNoTransferStats
+	}
+	
+	stats, err := tm.torrentManager.GetThroughput(transfer.InfoHash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoTransferStats, err)
+	}
+	stats.TransferID = transfer.ID
+	
+	// Seeds have nothing left to download
+	if transfer.Type != TransferTypeDownload {
+		stats.ETA = nil
+	}
+	return stats, nil
+}
+
+// GetTransferFiles returns how much of each file of a transfer is local
+func (tm *TransferManager) GetTransferFiles(id string) ([]FileProgress, error) {
+	transfer, exists := tm.GetTransfer(id)
+	if !exists {
+		return nil, fmt.Errorf("transfer %s not found", id)
+	}
+	if tm.torrentManager == nil {
+		return nil, ErrNoTransferStats
+	}
+	
+	files, err := tm.torrentManager.FileProgress(transfer.InfoHash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoTransferStats, err)
+	}
+	return files, nil
+}
+
 func (tm *TransferManager) GetAllTransfers() []*Transfer {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
@@ -294,26 +427,84 @@ func (tm *TransferManager) ResumeTransfer(id string) error {
 	return nil
 }
 
+// CancelTransfer stops a transfer and drops its torrent. Files that were
+// already downloaded stay on disk but the model is not listed until a new
+// download completes it.
 func (tm *TransferManager) CancelTransfer(id string) error {
+	_, err := tm.cancelTransfer(id, false)
+	return err
+}
+
+// PurgeTransfer cancels a download and deletes its partially downloaded
+// files. It returns the number of bytes freed.
+func (tm *TransferManager) PurgeTransfer(id string) (int64, error) {
+	return tm.cancelTransfer(id, true)
+}
+
+func (tm *TransferManager) cancelTransfer(id string, purge bool) (int64, error) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
 	transfer, exists := tm.transfers[id]
 	if !exists {
-		return fmt.Errorf("transfer not found: %s", id)
+		return 0, fmt.Errorf("transfer not found: %s", id)
+	}
+
+	// Work out what to delete before anything is changed
+	var purgePath string
+	if purge {
+		if transfer.Type != TransferTypeDownload {
+			return 0, fmt.Errorf("only downloads can be purged")
+		}
+		if transfer.Status == TransferStatusCompleted {
+			return 0, fmt.Errorf("download already completed, remove the model instead")
+		}
+		modelPath, err := modelPathFor(transfer.ModelName)
+		if err != nil {
+			return 0, err
+		}
+		purgePath = modelPath
+		if tm.torrentManager != nil {
+			if mt, exists := tm.torrentManager.GetTorrent(transfer.InfoHash); exists && mt.StoragePath != "" {
+				purgePath = mt.StoragePath
+			}
+		}
+		// Never delete a directory that does not hold an unfinished download
+		if _, err := os.Stat(purgePath); err == nil && !storage.IsPartial(purgePath) {
+			return 0, fmt.Errorf("%s is not a partial download, not deleting it", purgePath)
+		}
 	}
 
+	// Transfers that ended already are in the history
+	live := transfer.IsLive()
 	transfer.Status = TransferStatusCancelled
+	transfer.ETA = nil
+	now := time.Now()
+	transfer.CompletedAt = &now
 	tm.state.UpdateTransferStatus(id, TransferStatusCancelled)
+	if live {
+		tm.finish(transfer)
+	}
 	
-	// Remove from torrent manager (if available)
+	// Drop the torrent so it stops downloading and releases its files
 	if tm.torrentManager != nil {
-		if err := tm.torrentManager.RemoveTorrent(transfer.InfoHash); err != nil {
-			return fmt.Errorf("failed to remove torrent: %w", err)
+		if _, exists := tm.torrentManager.GetTorrent(transfer.InfoHash); exists {
+			if err := tm.torrentManager.RemoveTorrent(transfer.InfoHash); err != nil {
+				return 0, fmt.Errorf("failed to remove torrent: %w", err)
+			}
 		}
 	}
+
+	if purgePath == "" {
+		return 0, nil
+	}
+	freed := storage.GetDirSize(purgePath)
+	if err := os.RemoveAll(purgePath); err != nil {
+		return 0, fmt.Errorf("failed to delete partial download: %w", err)
+	}
+	fmt.Printf("[TransferManager] Deleted partial download %s (%d bytes)\n", purgePath, freed)
 	
-	return nil
+	return freed, nil
 }
 
 func (tm *TransferManager) GetActiveCount() int {
@@ -329,6 +520,48 @@ func (tm *TransferManager) GetActiveCount() int {
 	return count
 }
 
+// RenameModel moves the transfers of a model to its new name
+func (tm *TransferManager) RenameModel(from, to string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	for _, t := range tm.transfers {
+		if t.ModelName == from {
+			t.ModelName = to
+		}
+	}
+	tm.state.UpdateTransfers(tm.transfers)
+}
+
+// FindTransfer returns the newest transfer of a torrent that wasn't
+// cancelled or failed
+func (tm *TransferManager) FindTransfer(infoHash string, transferType TransferType) (*Transfer, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	t := tm.findTransfer(infoHash, transferType)
+	return t, t != nil
+}
+
+func (tm *TransferManager) findTransfer(infoHash string, transferType TransferType) *Transfer {
+	if infoHash == "" {
+		return nil
+	}
+	var found *Transfer
+	for _, t := range tm.transfers {
+		if t.Type != transferType || !strings.EqualFold(t.InfoHash, infoHash) {
+			continue
+		}
+		if t.Status == TransferStatusCancelled || t.Status == TransferStatusFailed {
+			continue
+		}
+		if found == nil || t.StartedAt.After(found.StartedAt) {
+			found = t
+		}
+	}
+	return found
+}
+
 func (tm *TransferManager) GetTransferByInfoHash(infoHash string) (*Transfer, bool) {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
@@ -341,6 +574,93 @@ func (tm *TransferManager) GetTransferByInfoHash(infoHash string) (*Transfer, bo
 	return nil, false
 }
 
+// UpdateSeeding records the seeding progress of a torrent on its transfers. A
+// seed transfer is created for torrents seeded without one, such as those
+// restored at startup.
+func (tm *TransferManager) UpdateSeeding(modelName, infoHash string, ratio float64, seedingTime time.Duration, limits SeedingLimits) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	found := false
+	for _, t := range tm.transfers {
+		if t.InfoHash != infoHash || t.Status == TransferStatusCancelled {
+			continue
+		}
+		found = true
+		t.Ratio = ratio
+		t.SeedingSeconds = int64(seedingTime / time.Second)
+		limitsCopy := limits
+		t.SeedingLimits = &limitsCopy
+	}
+	if found {
+		return
+	}
+
+	transfer := &Transfer{
+		ID:             uuid.New().String(),
+		Type:           TransferTypeSeed,
+		Status:         TransferStatusActive,
+		ModelName:      modelName,
+		InfoHash:       infoHash,
+		Progress:       100,
+		StartedAt:      time.Now(),
+		LastActivity:   time.Now(),
+		Ratio:          ratio,
+		SeedingSeconds: int64(seedingTime / time.Second),
+		SeedingLimits:  &limits,
+	}
+	tm.transfers[transfer.ID] = transfer
+	tm.state.AddTransfer(transfer)
+}
+
+// FinishSeeding records on the transfers of a torrent that the seeding policy
+// stopped seeding it, completing those still active
+func (tm *TransferManager) FinishSeeding(infoHash, reason string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	now := time.Now()
+	for _, t := range tm.transfers {
+		if t.InfoHash != infoHash || t.Status == TransferStatusCancelled {
+			continue
+		}
+		t.StopReason = reason
+		t.UploadRate = 0
+		t.DownloadRate = 0
+		t.Peers = 0
+		if t.Status == TransferStatusActive {
+			t.Status = TransferStatusCompleted
+			t.CompletedAt = &now
+			tm.finish(t)
+		}
+	}
+}
+
+// FailTransfer ends a transfer that can't go on because of err
+func (tm *TransferManager) FailTransfer(id string, err error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	transfer, exists := tm.transfers[id]
+	if !exists || !transfer.IsLive() {
+		return
+	}
+	transfer.Status = TransferStatusFailed
+	transfer.Error = err.Error()
+	transfer.ETA = nil
+	now := time.Now()
+	transfer.CompletedAt = &now
+	tm.state.UpdateTransferStatus(id, TransferStatusFailed)
+	tm.finish(transfer)
+}
+
+// finish reports a transfer that ended, callers hold tm.mu
+func (tm *TransferManager) finish(transfer *Transfer) {
+	if tm.OnFinish != nil {
+		tm.OnFinish(*transfer)
+	}
+}
+
 func (tm *TransferManager) CleanupOldTransfers(olderThan time.Duration) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
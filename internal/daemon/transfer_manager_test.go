@@ -2,9 +2,12 @@ package daemon
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/silmaril/silmaril/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -39,6 +42,51 @@ func TestTransferManagerCreateDownload(t *testing.T) {
 	assert.Equal(t, transfer, retrieved)
 }
 
+func TestTransferManagerCreateDownloadOnce(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	tm := NewTransferManager(nil, NewState(""))
+
+	first, created := tm.CreateDownloadOnce("org/model", "abcd")
+	require.True(t, created)
+
+	// The same torrent, whatever the case of its hash, keeps its transfer
+	again, created := tm.CreateDownloadOnce("org/model", "ABCD")
+	assert.False(t, created)
+	assert.Equal(t, first.ID, again.ID)
+	assert.Len(t, tm.GetAllTransfers(), 1)
+
+	// Downloads that didn't finish don't stop a new one
+	require.NoError(t, tm.CancelTransfer(first.ID))
+	second, created := tm.CreateDownloadOnce("org/model", "abcd")
+	require.True(t, created)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	// Nor do finished ones whose model was removed
+	second.Status = TransferStatusCompleted
+	_, created = tm.CreateDownloadOnce("org/model", "abcd")
+	assert.True(t, created)
+
+	found, ok := tm.FindTransfer("abcd", TransferTypeDownload)
+	require.True(t, ok)
+	assert.True(t, found.IsLive())
+	_, ok = tm.FindTransfer("abcd", TransferTypeSeed)
+	assert.False(t, ok)
+}
+
+func TestTransferManagerCreateDownloadOnceDownloaded(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	tm := NewTransferManager(nil, NewState(""))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(storage.GetModelsDir(), "org", "model"), 0755))
+	transfer, created := tm.CreateDownloadOnce("org/model", "abcd")
+	require.True(t, created)
+	transfer.Status = TransferStatusCompleted
+
+	existing, created := tm.CreateDownloadOnce("org/model", "abcd")
+	assert.False(t, created)
+	assert.Equal(t, transfer.ID, existing.ID)
+}
+
 func TestTransferManagerCreateUpload(t *testing.T) {
 	state := NewState("")
 	tm := NewTransferManager(nil, state)
@@ -377,4 +425,61 @@ func TestTransferManagerConcurrency(t *testing.T) {
 	// Verify we have all transfers
 	all := tm.GetAllTransfers()
 	assert.Len(t, all, 20)
-}
\ No newline at end of file
+}
+func TestTransferManagerPurgeTransfer(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	state := NewState("")
+	tm := NewTransferManager(nil, state)
+
+	// Partially downloaded model
+	modelDir := filepath.Join(storage.GetModelsDir(), "org", "model")
+	require.NoError(t, storage.MarkPartial(modelDir))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "model.safetensors"), make([]byte, 1024), 0644))
+
+	transfer := tm.CreateDownload("org/model", "hash", 4096)
+	freed, err := tm.PurgeTransfer(transfer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), freed)
+	assert.Equal(t, TransferStatusCancelled, transfer.Status)
+	assert.NoDirExists(t, modelDir)
+
+	// Complete models are never deleted
+	completeDir := filepath.Join(storage.GetModelsDir(), "org", "complete")
+	require.NoError(t, os.MkdirAll(completeDir, 0755))
+	transfer = tm.CreateDownload("org/complete", "hash2", 4096)
+	_, err = tm.PurgeTransfer(transfer.ID)
+	assert.Error(t, err)
+	assert.DirExists(t, completeDir)
+	assert.Equal(t, TransferStatusPending, transfer.Status)
+
+	// Seeds cannot be purged
+	seed := tm.CreateSeed("org/complete", "hash3")
+	_, err = tm.PurgeTransfer(seed.ID)
+	assert.Error(t, err)
+}
+
+func TestTransferManagerSeeding(t *testing.T) {
+	state := NewState("")
+	tm := NewTransferManager(nil, state)
+	limits := SeedingLimits{SeedRatio: 2}
+
+	// Torrents seeded without a transfer get a seed transfer
+	tm.UpdateSeeding("test-model", "seed-hash", 0.5, 90*time.Second, limits)
+	transfer, exists := tm.GetTransferByInfoHash("seed-hash")
+	require.True(t, exists)
+	assert.Equal(t, TransferTypeSeed, transfer.Type)
+	assert.Equal(t, TransferStatusActive, transfer.Status)
+	assert.Equal(t, 0.5, transfer.Ratio)
+	assert.Equal(t, int64(90), transfer.SeedingSeconds)
+	assert.Equal(t, &limits, transfer.SeedingLimits)
+
+	// Existing transfers are updated
+	tm.UpdateSeeding("test-model", "seed-hash", 2, 120*time.Second, limits)
+	assert.Len(t, tm.GetAllTransfers(), 1)
+	assert.Equal(t, 2.0, transfer.Ratio)
+
+	tm.FinishSeeding("seed-hash", "seed ratio 2.00 reached")
+	assert.Equal(t, TransferStatusCompleted, transfer.Status)
+	assert.NotNil(t, transfer.CompletedAt)
+	assert.Equal(t, "seed ratio 2.00 reached", transfer.StopReason)
+}
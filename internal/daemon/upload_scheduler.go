@@ -0,0 +1,341 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	g "github.com/anacrolix/generics"
+	"github.com/anacrolix/torrent/metainfo"
+	torrentStorage "github.com/anacrolix/torrent/storage"
+	"github.com/silmaril/silmaril/internal/models"
+	"golang.org/x/time/rate"
+)
+
+// How often the upload bandwidth is divided between torrents
+const uploadScheduleInterval = 5 * time.Second
+
+// Upload rate every torrent gets, so a swarm that was quiet can pick up
+// before the next allocation gives it more
+const minUploadShare = 64 << 10
+
+// Seed priorities of a model, weighing its share of the upload bandwidth
+const (
+	SeedPriorityLow    = "low"
+	SeedPriorityNormal = "normal"
+	SeedPriorityHigh   = "high"
+)
+
+// SeedPriorities lists the priorities a model can be seeded with
+var SeedPriorities = []string{SeedPriorityLow, SeedPriorityNormal, SeedPriorityHigh}
+
+var seedPriorityWeights = map[string]float64{
+	SeedPriorityLow:    1,
+	SeedPriorityNormal: 2,
+	SeedPriorityHigh:   4,
+}
+
+// ValidSeedPriority reports whether priority is a seed priority
+func ValidSeedPriority(priority string) bool {
+	_, ok := seedPriorityWeights[priority]
+	return ok
+}
+
+// UploadShare is the part of the upload bandwidth a torrent was given
+type UploadShare struct {
+	Model      string  `json:"model"`
+	InfoHash   string  `json:"info_hash"`
+	Priority   string  `json:"priority"`
+	Weight     float64 `json:"weight"`
+	Peers      int     `json:"peers"`
+	UploadRate int64   `json:"upload_rate"` // Smoothed over throughputWindow
+	Limit      int64   `json:"limit"`       // Bytes per second, 0 = unlimited
+}
+
+// UploadSchedule is how the upload bandwidth is divided between torrents
+type UploadSchedule struct {
+	Enabled   bool          `json:"enabled"`
+	Total     int64         `json:"total"` // network.upload_rate_limit
+	Shares    []UploadShare `json:"shares"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// uploadScheduler keeps the last division of the upload bandwidth
+type uploadScheduler struct {
+	mu   sync.Mutex
+	last UploadSchedule
+}
+
+// shareDemand is what a torrent asks of a limit divided between torrents,
+// such as the upload bandwidth
+type shareDemand struct {
+	weight float64
+	demand int64 // What it can use, such as bytes per second, -1 if more than it got
+}
+
+// estimateUploadDemand guesses what a torrent could upload from its rate and
+// current limit: nothing without peers, more than it got if it used most of
+// its limit, and otherwise a little more than it uploads now
+func estimateUploadDemand(peers int, uploadRate, limit int64) int64 {
+	switch {
+	case peers == 0:
+		return 0
+	case limit <= 0 || uploadRate >= limit*9/10:
+		return -1
+	}
+	return uploadRate + uploadRate/4 + minUploadShare
+}
+
+// allocateFairShares divides total between torrents by weighted max-min
+// fairness: torrents needing less than their weighted share get what they
+// need, and what is left is split between the others by weight
+func allocateFairShares(total int64, demands []shareDemand) []int64 {
+	shares := make([]int64, len(demands))
+	var pending []int
+	for i, d := range demands {
+		if d.demand != 0 && d.weight > 0 {
+			pending = append(pending, i)
+		}
+	}
+
+	remaining := total
+	for len(pending) > 0 {
+		var weights float64
+		for _, i := range pending {
+			weights += demands[i].weight
+		}
+		available := float64(remaining)
+
+		var unsatisfied []int
+		for _, i := range pending {
+			fair := available * demands[i].weight / weights
+			if demands[i].demand > 0 && float64(demands[i].demand) <= fair {
+				shares[i] = demands[i].demand
+				remaining -= demands[i].demand
+				continue
+			}
+			unsatisfied = append(unsatisfied, i)
+		}
+
+		// Nobody needed less than their share, split the rest by weight
+		if len(unsatisfied) == len(pending) {
+			for _, i := range pending {
+				shares[i] = int64(available * demands[i].weight / weights)
+			}
+			break
+		}
+		pending = unsatisfied
+	}
+	return shares
+}
+
+// uploadShaper holds the upload limiter of each torrent, which the storage
+// of the torrent reads the pieces peers ask for through, see shapedStorage
+type uploadShaper struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newUploadShaper() *uploadShaper {
+	return &uploadShaper{limiters: make(map[string]*rate.Limiter)}
+}
+
+// limiter returns the limiter of a torrent, unlimited until it gets a share
+func (s *uploadShaper) limiter(infoHash string) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	limiter, ok := s.limiters[infoHash]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Inf, 0)
+		s.limiters[infoHash] = limiter
+	}
+	return limiter
+}
+
+// limit returns the upload limit of a torrent, 0 if it is unlimited
+func (s *uploadShaper) limit(infoHash string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	limiter, ok := s.limiters[infoHash]
+	if !ok || limiter.Limit() == rate.Inf {
+		return 0
+	}
+	return int64(limiter.Limit())
+}
+
+// forget drops the limiter of a torrent that was removed
+func (s *uploadShaper) forget(infoHash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.limiters, infoHash)
+}
+
+// shapedStorage reads the pieces of each torrent through its upload limiter.
+// Hashing reads pieces with WriteTo and isn't limited.
+type shapedStorage struct {
+	torrentStorage.ClientImpl
+	shaper *uploadShaper
+}
+
+func (s shapedStorage) OpenTorrent(ctx context.Context, info *metainfo.Info, infoHash metainfo.Hash) (torrentStorage.TorrentImpl, error) {
+	impl, err := s.ClientImpl.OpenTorrent(ctx, info, infoHash)
+	if err != nil {
+		return impl, err
+	}
+	limiter := s.shaper.limiter(infoHash.HexString())
+	if piece := impl.Piece; piece != nil {
+		impl.Piece = func(p metainfo.Piece) torrentStorage.PieceImpl {
+			return shapedPiece{PieceImpl: piece(p), limiter: limiter, length: p.Length()}
+		}
+	}
+	if piece := impl.PieceWithHash; piece != nil {
+		impl.PieceWithHash = func(p metainfo.Piece, pieceHash g.Option[[]byte]) torrentStorage.PieceImpl {
+			return shapedPiece{PieceImpl: piece(p, pieceHash), limiter: limiter, length: p.Length()}
+		}
+	}
+	return impl, nil
+}
+
+// shapedPiece waits for the upload limiter of its torrent before reading
+type shapedPiece struct {
+	torrentStorage.PieceImpl
+	limiter *rate.Limiter
+	length  int64
+}
+
+func (p shapedPiece) ReadAt(b []byte, off int64) (int, error) {
+	// Reads may be larger than the burst of the limiter
+	for n := len(b); n > 0; {
+		step := n
+		if burst := p.limiter.Burst(); p.limiter.Limit() != rate.Inf && burst > 0 && step > burst {
+			step = burst
+		}
+		if err := p.limiter.WaitN(context.Background(), step); err != nil {
+			break
+		}
+		n -= step
+	}
+	return p.PieceImpl.ReadAt(b, off)
+}
+
+func (p shapedPiece) WriteTo(w io.Writer) (int64, error) {
+	if writerTo, ok := p.PieceImpl.(io.WriterTo); ok {
+		return writerTo.WriteTo(w)
+	}
+	return io.CopyN(w, io.NewSectionReader(p.PieceImpl, 0, p.length), p.length)
+}
+
+// SetUploadShare limits the upload rate of a torrent, 0 lifts the limit
+func (tm *TorrentManager) SetUploadShare(infoHash string, bytesPerSecond int64) {
+	setLimit(tm.uploadShares.limiter(infoHash), bytesPerSecond)
+}
+
+// UploadShare returns the upload limit of a torrent, 0 if it has none
+func (tm *TorrentManager) UploadShare(infoHash string) int64 {
+	return tm.uploadShares.limit(infoHash)
+}
+
+// seedPriority returns the priority of a torrent's model: the one it was
+// shared with, low for models pinned for the network and normal otherwise
+func (d *Daemon) seedPriority(mt *ManagedTorrent) string {
+	if manifest, err := models.ReadManifest(mt.StoragePath); err == nil && manifest.Seeding != nil && ValidSeedPriority(manifest.Seeding.Priority) {
+		return manifest.Seeding.Priority
+	}
+	if d.state != nil && d.state.IsPinned(mt.InfoHash) {
+		return SeedPriorityLow
+	}
+	return SeedPriorityNormal
+}
+
+// scheduleUploads divides network.upload_rate_limit between the torrents by
+// their priority and what they can upload, so one busy swarm can't starve
+// the others. Without a limit, or with network.upload_fairness off, the
+// torrents share the bandwidth as the torrent client sees fit.
+func (d *Daemon) scheduleUploads() {
+	if d.torrentManager == nil {
+		return
+	}
+	var total int64
+	enabled := false
+	if d.config != nil {
+		total = int64(d.config.GetInt("network.upload_rate_limit"))
+		enabled = d.config.GetBool("network.upload_fairness") && total > 0
+	}
+
+	torrents := d.torrentManager.GetAllTorrents()
+	schedule := UploadSchedule{
+		Enabled:   enabled,
+		Total:     total,
+		Shares:    make([]UploadShare, 0, len(torrents)),
+		UpdatedAt: time.Now(),
+	}
+	demands := make([]shareDemand, 0, len(torrents))
+	for _, mt := range torrents {
+		priority := d.seedPriority(mt)
+		_, uploadRate := mt.throughput.rates()
+		share := UploadShare{
+			Model:      mt.Name,
+			InfoHash:   mt.InfoHash,
+			Priority:   priority,
+			Weight:     seedPriorityWeights[priority],
+			Peers:      mt.Torrent.Stats().ActivePeers,
+			UploadRate: uploadRate,
+		}
+		schedule.Shares = append(schedule.Shares, share)
+		demands = append(demands, shareDemand{
+			weight: share.Weight,
+			demand: estimateUploadDemand(share.Peers, uploadRate, d.torrentManager.UploadShare(mt.InfoHash)),
+		})
+	}
+
+	var allocated []int64
+	if enabled {
+		allocated = allocateFairShares(total, demands)
+	}
+	for i := range schedule.Shares {
+		share := &schedule.Shares[i]
+		if enabled {
+			share.Limit = max(allocated[i], minUploadShare)
+		}
+		d.torrentManager.SetUploadShare(share.InfoHash, share.Limit)
+	}
+	sort.Slice(schedule.Shares, func(i, j int) bool {
+		if schedule.Shares[i].UploadRate != schedule.Shares[j].UploadRate {
+			return schedule.Shares[i].UploadRate > schedule.Shares[j].UploadRate
+		}
+		return schedule.Shares[i].Model < schedule.Shares[j].Model
+	})
+
+	d.uploadSchedule.mu.Lock()
+	d.uploadSchedule.last = schedule
+	d.uploadSchedule.mu.Unlock()
+}
+
+// UploadSchedule returns how the upload bandwidth was last divided
+func (d *Daemon) UploadSchedule() UploadSchedule {
+	d.uploadSchedule.mu.Lock()
+	defer d.uploadSchedule.mu.Unlock()
+	schedule := d.uploadSchedule.last
+	schedule.Shares = append([]UploadShare{}, schedule.Shares...)
+	return schedule
+}
+
+// uploadSchedulerWorker divides the upload bandwidth again as swarms get
+// busy or quiet
+func (d *Daemon) uploadSchedulerWorker() {
+	defer d.workers.Done()
+
+	ticker := time.NewTicker(uploadScheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.scheduleUploads()
+		}
+	}
+}
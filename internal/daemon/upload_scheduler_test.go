@@ -0,0 +1,69 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocateFairShares(t *testing.T) {
+	const total = 1000
+
+	// A hot swarm doesn't starve the others: all want more, split by weight
+	shares := allocateFairShares(total, []shareDemand{
+		{weight: 2, demand: -1},
+		{weight: 2, demand: -1},
+		{weight: 4, demand: -1},
+	})
+	assert.Equal(t, []int64{250, 250, 500}, shares)
+
+	// What a quiet swarm doesn't need goes to the busy ones
+	shares = allocateFairShares(total, []shareDemand{
+		{weight: 2, demand: -1},
+		{weight: 2, demand: 100},
+		{weight: 1, demand: -1},
+	})
+	assert.Equal(t, []int64{600, 100, 300}, shares)
+
+	// Swarms without peers get nothing
+	shares = allocateFairShares(total, []shareDemand{
+		{weight: 2, demand: 0},
+		{weight: 2, demand: -1},
+	})
+	assert.Equal(t, []int64{0, 1000}, shares)
+
+	// Everyone satisfied leaves bandwidth unallocated
+	shares = allocateFairShares(total, []shareDemand{
+		{weight: 2, demand: 200},
+		{weight: 1, demand: 300},
+	})
+	assert.Equal(t, []int64{200, 300}, shares)
+
+	assert.Empty(t, allocateFairShares(total, nil))
+}
+
+func TestEstimateUploadDemand(t *testing.T) {
+	assert.Equal(t, int64(0), estimateUploadDemand(0, 5000, 10000))
+	assert.Equal(t, int64(-1), estimateUploadDemand(3, 5000, 0), "unlimited torrents may want more")
+	assert.Equal(t, int64(-1), estimateUploadDemand(3, 9500, 10000), "torrents near their limit want more")
+	assert.Equal(t, int64(4000+1000+minUploadShare), estimateUploadDemand(3, 4000, 10000))
+}
+
+func TestUploadShaper(t *testing.T) {
+	tm := &TorrentManager{uploadShares: newUploadShaper()}
+	assert.Equal(t, int64(0), tm.UploadShare("abc"))
+
+	tm.SetUploadShare("abc", 128<<10)
+	assert.Equal(t, int64(128<<10), tm.UploadShare("abc"))
+	assert.Equal(t, 128<<10, tm.uploadShares.limiter("abc").Burst())
+
+	tm.SetUploadShare("abc", 0)
+	assert.Equal(t, int64(0), tm.UploadShare("abc"))
+
+	tm.SetUploadShare("abc", 128<<10)
+	tm.uploadShares.forget("abc")
+	assert.Equal(t, int64(0), tm.UploadShare("abc"))
+
+	assert.True(t, ValidSeedPriority(SeedPriorityHigh))
+	assert.False(t, ValidSeedPriority("urgent"))
+}
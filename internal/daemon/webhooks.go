@@ -0,0 +1,183 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/silmaril/silmaril/internal/proxy"
+)
+
+const (
+	// How often webhook workers look for new events
+	webhookPollInterval = time.Second
+
+	webhookTimeout    = 10 * time.Second
+	webhookAttempts   = 3
+	webhookRetryDelay = 5 * time.Second
+)
+
+// Events sent to webhooks that don't list their own: finished downloads,
+// failed verifications, publishes and catalog updates
+var defaultWebhookEvents = []string{
+	EventDownloadCompleted,
+	EventScrubCorrupt,
+	EventDownloadCorrupt,
+	EventDecryptionFailed,
+	EventManifestRejected,
+	EventPublishCompleted,
+	EventPublishFailed,
+	EventCatalogUpdated,
+}
+
+// webhook is an endpoint events are posted to
+type webhook struct {
+	Name    string
+	URL     string
+	Headers map[string]string
+	Events  []string
+}
+
+// webhookPayload is the JSON body posted for an event. Text repeats the
+// event as a line chat services like Slack show.
+type webhookPayload struct {
+	Event
+	Text string `json:"text"`
+}
+
+// configuredWebhooks returns the webhooks configured in cfg in name order,
+// the ones without a URL are left out
+func configuredWebhooks(cfg *config.Config) []webhook {
+	if cfg == nil {
+		return nil
+	}
+	var hooks []webhook
+	for name, hookCfg := range cfg.Webhooks {
+		if hookCfg.URL == "" {
+			continue
+		}
+		events := hookCfg.Events
+		if len(events) == 0 {
+			events = defaultWebhookEvents
+		}
+		hooks = append(hooks, webhook{Name: name, URL: hookCfg.URL, Headers: hookCfg.Headers, Events: events})
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].Name < hooks[j].Name })
+	return hooks
+}
+
+// wants reports whether the webhook takes events of eventType
+func (w webhook) wants(eventType string) bool {
+	for _, pattern := range w.Events {
+		if ok, _ := path.Match(pattern, eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// startWebhooks starts a worker for each configured webhook. Webhooks are
+// read when the daemon starts, changes need a restart.
+func (d *Daemon) startWebhooks() {
+	seq := d.events.LastSeq()
+	for _, hook := range configuredWebhooks(d.config) {
+		fmt.Printf("[Webhooks] Posting %v events to %s\n", hook.Events, hook.Name)
+		d.workers.Add(1)
+		go d.webhookWorker(hook, seq)
+	}
+}
+
+// webhookWorker posts the events published after seq to a webhook, in
+// order. Events the webhook falls too far behind on drop out of
+// the event log and are not sent.
+func (d *Daemon) webhookWorker(hook webhook, seq int64) {
+	defer d.workers.Done()
+
+	var webhookProxy *proxy.Proxy
+	if d.config != nil {
+		var err error
+		if webhookProxy, err = proxy.New(d.config.Proxy.URL, d.config.Proxy.NoProxy); err != nil {
+			fmt.Printf("[Webhooks] Not posting to %s: %v\n", hook.Name, err)
+			return
+		}
+	}
+	client := webhookProxy.HTTPClient(webhookTimeout)
+
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, event := range d.events.Since(seq) {
+				seq = event.Seq
+				if !hook.wants(event.Type) {
+					continue
+				}
+				if err := d.deliverWebhook(client, hook, event); err != nil {
+					fmt.Printf("[Webhooks] Failed to post %s event to %s: %v\n", event.Type, hook.Name, err)
+				}
+			}
+		}
+	}
+}
+
+// deliverWebhook posts an event to a webhook, trying again after a delay if
+// the request fails
+func (d *Daemon) deliverWebhook(client *http.Client, hook webhook, event Event) error {
+	text := fmt.Sprintf("[silmaril] %s: %s", event.Type, event.Message)
+	if event.Model != "" {
+		text = fmt.Sprintf("[silmaril] %s %s: %s", event.Type, event.Model, event.Message)
+	}
+	body, err := json.Marshal(webhookPayload{Event: event, Text: text})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = postWebhook(d.ctx, client, hook, event.Type, body)
+		if err == nil || attempt == webhookAttempts {
+			return err
+		}
+		select {
+		case <-d.ctx.Done():
+			return err
+		case <-time.After(webhookRetryDelay):
+		}
+	}
+}
+
+// postWebhook sends one request to a webhook
+func postWebhook(ctx context.Context, client *http.Client, hook webhook, eventType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "silmaril")
+	req.Header.Set("X-Silmaril-Event", eventType)
+	for name, value := range hook.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfiguredWebhooks(t *testing.T) {
+	hooks := configuredWebhooks(&config.Config{Webhooks: map[string]config.WebhookConfig{
+		"slack": {URL: "https://hooks.slack.com/services/x"},
+		"ci":    {URL: "https://ci.example.com/hook", Events: []string{"publish.*"}},
+		"off":   {},
+	}})
+	require.Len(t, hooks, 2)
+	assert.Equal(t, "ci", hooks[0].Name)
+	assert.Equal(t, "slack", hooks[1].Name)
+
+	assert.True(t, hooks[0].wants(EventPublishCompleted))
+	assert.True(t, hooks[0].wants(EventPublishFailed))
+	assert.False(t, hooks[0].wants(EventDownloadCompleted))
+
+	assert.True(t, hooks[1].wants(EventDownloadCompleted))
+	assert.True(t, hooks[1].wants(EventScrubCorrupt))
+	assert.True(t, hooks[1].wants(EventCatalogUpdated))
+	assert.False(t, hooks[1].wants(EventDownloadCancelled))
+
+	assert.Empty(t, configuredWebhooks(nil))
+}
+
+func TestWebhookWorker(t *testing.T) {
+	t.Setenv("CI_TOKEN", "secret")
+	received := make(chan *http.Request, 10)
+	payloads := make(chan webhookPayload, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		received <- r
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	d := newRenameTestDaemon(t)
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	d.events.Publish(EventDownloadCompleted, "org/old", "published before the daemon started")
+	seq := d.events.LastSeq()
+
+	d.workers.Add(1)
+	go d.webhookWorker(webhook{
+		Name:    "ci",
+		URL:     server.URL,
+		Headers: map[string]string{"authorization": "Bearer ${CI_TOKEN}"},
+		Events:  []string{EventDownloadCompleted},
+	}, seq)
+	defer func() {
+		d.cancel()
+		d.workers.Wait()
+	}()
+
+	d.events.Publish(EventDownloadCancelled, "org/model", "cancelled")
+	d.events.Publish(EventDownloadCompleted, "org/model", "downloaded 2 files")
+
+	select {
+	case r := <-received:
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, EventDownloadCompleted, r.Header.Get("X-Silmaril-Event"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+	payload := <-payloads
+	assert.Equal(t, EventDownloadCompleted, payload.Type)
+	assert.Equal(t, "org/model", payload.Model)
+	assert.Equal(t, "[silmaril] download.completed org/model: downloaded 2 files", payload.Text)
+
+	// Neither the earlier event nor the filtered one is sent
+	select {
+	case r := <-received:
+		t.Fatalf("unexpected webhook request for %s", r.Header.Get("X-Silmaril-Event"))
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
+
+func TestDeliverWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d := &Daemon{ctx: ctx}
+	// The cancelled context stops retries, and the request itself
+	err := d.deliverWebhook(http.DefaultClient, webhook{URL: server.URL}, Event{Type: EventPublishFailed})
+	assert.Error(t, err)
+
+	err = postWebhook(context.Background(), http.DefaultClient, webhook{URL: server.URL}, EventPublishFailed, []byte("{}"))
+	assert.EqualError(t, err, "webhook returned 400 Bad Request")
+}
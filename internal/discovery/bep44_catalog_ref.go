@@ -4,8 +4,10 @@ import (
 	"context"
 	"crypto/ed25519"
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -21,7 +23,7 @@ type BEP44CatalogRef struct {
 	mu     sync.Mutex
 	server *dht.Server
 	
-	// Deterministic key derived from well-known seed
+	// Signing key for the catalog, nil for read-only catalogs of other publishers
 	privateKey ed25519.PrivateKey
 	publicKey  [32]byte
 	
@@ -29,6 +31,16 @@ type BEP44CatalogRef struct {
 	sequence int64
 	ref      *CatalogReference
 	
+	// Rotation of the key to a new key, published with the reference
+	rotation *KeyRotation
+	
+	// Concurrent writes by other publishers that were merged, and the outcome
+	// of publishing. They have their own lock since ref.mu is held for the
+	// whole duration of a publish.
+	conflictsMu sync.Mutex
+	conflicts   int
+	publish     PublishStatus
+	
 	// Catalog torrent manager
 	catalogTorrent *CatalogTorrent
 	
@@ -36,25 +48,89 @@ type BEP44CatalogRef struct {
 	cancel context.CancelFunc
 }
 
-// NewBEP44CatalogRef creates a new BEP44 catalog reference manager
+// NewBEP44CatalogRef creates a new BEP44 catalog reference manager for the
+// public catalog
 func NewBEP44CatalogRef(server *dht.Server, torrentClient *torrent.Client) (*BEP44CatalogRef, error) {
 	fmt.Printf("[BEP44Ref] Creating catalog reference with well-known seed: %s\n", WellKnownSeed)
 	
-	// Generate deterministic key from well-known seed
-	seed := sha256.Sum256([]byte(WellKnownSeed))
-	privateKey := ed25519.NewKeyFromSeed(seed[:])
+	// Create catalog torrent manager
+	catalogTorrent, err := NewCatalogTorrent(torrentClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create catalog torrent: %w", err)
+	}
+	
+	return newSeedCatalogRef(server, catalogTorrent, WellKnownSeed), nil
+}
+
+// NewNetworkCatalogRef creates a reference to the shared catalog of another
+// network, which every node knowing its seed can write to like the public
+// catalog
+func NewNetworkCatalogRef(server *dht.Server, torrentClient *torrent.Client, seed, catalogDir string) (*BEP44CatalogRef, error) {
+	fmt.Printf("[BEP44Ref] Creating network catalog reference: %s\n", SeedCatalogKey(seed))
+	
+	catalogTorrent, err := NewCatalogTorrentInDir(torrentClient, catalogDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create catalog torrent: %w", err)
+	}
+	
+	return newSeedCatalogRef(server, catalogTorrent, seed), nil
+}
+
+// newSeedCatalogRef creates a reference to a catalog signed with a key
+// derived from a seed
+func newSeedCatalogRef(server *dht.Server, catalogTorrent *CatalogTorrent, seed string) *BEP44CatalogRef {
+	privateKey := seedCatalogKey(seed)
 	
 	var publicKey [32]byte
 	copy(publicKey[:], privateKey.Public().(ed25519.PublicKey))
 	
 	fmt.Printf("[BEP44Ref] Catalog reference public key: %x\n", publicKey[:16])
 	
-	// Create catalog torrent manager
-	catalogTorrent, err := NewCatalogTorrent(torrentClient)
+	return newBEP44CatalogRef(server, catalogTorrent, privateKey, publicKey)
+}
+
+// seedCatalogKey derives the signing key of a shared catalog from its seed
+func seedCatalogKey(seed string) ed25519.PrivateKey {
+	digest := sha256.Sum256([]byte(seed))
+	return ed25519.NewKeyFromSeed(digest[:])
+}
+
+// SeedCatalogKey returns the hex encoded public key the shared catalog of a
+// seed is published under
+func SeedCatalogKey(seed string) string {
+	return hex.EncodeToString(seedCatalogKey(seed).Public().(ed25519.PublicKey))
+}
+
+// NewPublisherCatalogRef creates a catalog reference signed with a publisher's
+// own key, so that only the key owner can update it
+func NewPublisherCatalogRef(server *dht.Server, torrentClient *torrent.Client, privateKey ed25519.PrivateKey, catalogDir string) (*BEP44CatalogRef, error) {
+	var publicKey [32]byte
+	copy(publicKey[:], privateKey.Public().(ed25519.PublicKey))
+	
+	fmt.Printf("[BEP44Ref] Creating publisher catalog reference: %x\n", publicKey[:])
+	
+	catalogTorrent, err := NewCatalogTorrentInDir(torrentClient, catalogDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create catalog torrent: %w", err)
 	}
 	
+	return newBEP44CatalogRef(server, catalogTorrent, privateKey, publicKey), nil
+}
+
+// NewSubscribedCatalogRef creates a read-only reference to another publisher's catalog
+func NewSubscribedCatalogRef(server *dht.Server, torrentClient *torrent.Client, publicKey [32]byte, catalogDir string) (*BEP44CatalogRef, error) {
+	fmt.Printf("[BEP44Ref] Creating subscribed catalog reference: %x\n", publicKey[:])
+	
+	catalogTorrent, err := NewCatalogTorrentInDir(torrentClient, catalogDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create catalog torrent: %w", err)
+	}
+	
+	return newBEP44CatalogRef(server, catalogTorrent, nil, publicKey), nil
+}
+
+// newBEP44CatalogRef creates the reference manager and fetches the latest published reference
+func newBEP44CatalogRef(server *dht.Server, catalogTorrent *CatalogTorrent, privateKey ed25519.PrivateKey, publicKey [32]byte) *BEP44CatalogRef {
 	ctx, cancel := context.WithCancel(context.Background())
 	
 	ref := &BEP44CatalogRef{
@@ -71,11 +147,25 @@ func NewBEP44CatalogRef(server *dht.Server, torrentClient *torrent.Client) (*BEP
 		fmt.Printf("[BEP44Ref] No existing catalog reference found: %v\n", err)
 	}
 	
-	return ref, nil
+	return ref
+}
+
+// PublicKey returns the hex encoded public key the catalog is published under
+func (ref *BEP44CatalogRef) PublicKey() string {
+	return hex.EncodeToString(ref.publicKey[:])
+}
+
+// ReadOnly reports whether this is another publisher's catalog
+func (ref *BEP44CatalogRef) ReadOnly() bool {
+	return ref.privateKey == nil
 }
 
 // PublishCatalogRef publishes the catalog reference to BEP44 using proper traversal
 func (ref *BEP44CatalogRef) PublishCatalogRef(catalogInfoHash string) error {
+	if ref.ReadOnly() {
+		return fmt.Errorf("cannot publish to a read-only catalog")
+	}
+	
 	fmt.Printf("[BEP44Ref] Publishing catalog reference: %s\n", catalogInfoHash)
 	
 	// Update sequence and reference
@@ -84,6 +174,7 @@ func (ref *BEP44CatalogRef) PublishCatalogRef(catalogInfoHash string) error {
 		InfoHash: catalogInfoHash,
 		Sequence: ref.sequence,
 		Updated:  time.Now().Unix(),
+		Rotation: ref.rotation,
 	}
 	
 	// Serialize to JSON (compact)
@@ -110,8 +201,9 @@ func (ref *BEP44CatalogRef) PublishCatalogRef(catalogInfoHash string) error {
 			data, _ = json.Marshal(ref.ref)
 		}
 		
-		// Create and sign the BEP44 item
-		item, err := bep44.NewItem(data, nil, ref.sequence, 0, ref.privateKey)
+		// Create and sign the BEP44 item. The observed sequence is used as CAS
+		// value so nodes reject our write if another publisher got in between.
+		item, err := bep44.NewItem(data, nil, ref.sequence, seq, ref.privateKey)
 		if err != nil {
 			fmt.Printf("[BEP44Ref] Error creating BEP44 item: %v\n", err)
 			return bep44.Put{}
@@ -133,19 +225,119 @@ func (ref *BEP44CatalogRef) PublishCatalogRef(catalogInfoHash string) error {
 	// Give the value a moment to settle
 	time.Sleep(1 * time.Second)
 	
-	// Verify we can fetch it back
-	fmt.Println("[BEP44Ref] Verifying catalog reference was stored...")
-	if err := ref.fetchCatalogRef(); err != nil {
-		fmt.Printf("[BEP44Ref] Warning: Could not verify catalog storage: %v\n", err)
-	} else {
-		fmt.Println("[BEP44Ref] Catalog reference verified successfully")
+	return nil
+}
+
+// publishMerged publishes a catalog torrent and verifies that our write won.
+// If another publisher wrote a different catalog concurrently, their catalog
+// is merged into ours and the merged catalog is published again, so models
+// are not lost to racing writers. Callers must hold ref.mu.
+func (ref *BEP44CatalogRef) publishMerged(catalogInfoHash string) (err error) {
+	defer func() { ref.recordPublish(err) }()
+	
+	for attempt := 1; ; attempt++ {
+		if err := ref.PublishCatalogRef(catalogInfoHash); err != nil {
+			return err
+		}
+		
+		// Verify we can fetch it back
+		fmt.Println("[BEP44Ref] Verifying catalog reference was stored...")
+		remote, seq, err := ref.getRemoteRef()
+		if err != nil {
+			fmt.Printf("[BEP44Ref] Warning: Could not verify catalog storage: %v\n", err)
+			return nil
+		}
+		if remote.InfoHash == catalogInfoHash {
+			fmt.Println("[BEP44Ref] Catalog reference verified successfully")
+			ref.catalogTorrent.MarkPublished()
+			return nil
+		}
+		
+		ref.conflictsMu.Lock()
+		ref.conflicts++
+		ref.conflictsMu.Unlock()
+		fmt.Printf("[BEP44Ref] Conflicting catalog write detected (seq: %d, catalog: %s), merging (attempt %d/%d)\n",
+			seq, remote.InfoHash, attempt, maxPublishAttempts)
+		
+		if seq > ref.sequence {
+			ref.sequence = seq
+		}
+		ref.ref = remote
+		
+		if _, err := ref.catalogTorrent.FetchAndMergeCatalog(remote.InfoHash); err != nil {
+			return fmt.Errorf("failed to merge conflicting catalog %s: %w", remote.InfoHash, err)
+		}
+		
+		// Their catalog already contains everything we have
+		if !ref.catalogTorrent.HasUnpublishedChanges() {
+			fmt.Println("[BEP44Ref] Conflicting catalog already includes our models")
+			return nil
+		}
+		
+		if attempt >= maxPublishAttempts {
+			return fmt.Errorf("catalog write conflict not resolved after %d attempts", attempt)
+		}
+		
+		// Back off with jitter so racing publishers do not collide again
+		backoff := time.Duration(attempt)*publishRetryDelay + time.Duration(rand.Int63n(int64(publishRetryDelay)))
+		select {
+		case <-time.After(backoff):
+		case <-ref.ctx.Done():
+			return ref.ctx.Err()
+		}
+		
+		newCatalogHash, err := ref.catalogTorrent.Rebuild()
+		if err != nil {
+			return fmt.Errorf("failed to rebuild catalog torrent: %w", err)
+		}
+		catalogInfoHash = newCatalogHash
 	}
+}
+
+// PublishStatus is the outcome of publishing the catalog reference
+type PublishStatus struct {
+	LastAttempt time.Time `json:"last_attempt"`
+	LastSuccess time.Time `json:"last_success"`
+	LastError   string    `json:"last_error,omitempty"` // Error of the last attempt, if it failed
+}
+
+// recordPublish records the outcome of a publish
+func (ref *BEP44CatalogRef) recordPublish(err error) {
+	ref.conflictsMu.Lock()
+	defer ref.conflictsMu.Unlock()
 	
-	return nil
+	ref.publish.LastAttempt = time.Now()
+	if err != nil {
+		ref.publish.LastError = err.Error()
+		return
+	}
+	ref.publish.LastSuccess = ref.publish.LastAttempt
+	ref.publish.LastError = ""
 }
 
-// fetchCatalogRef fetches the catalog reference from BEP44 using proper traversal
-func (ref *BEP44CatalogRef) fetchCatalogRef() error {
+// PublishStatus returns the outcome of publishing the catalog reference
+func (ref *BEP44CatalogRef) PublishStatus() PublishStatus {
+	ref.conflictsMu.Lock()
+	defer ref.conflictsMu.Unlock()
+	return ref.publish
+}
+
+// Reference returns the reference of the local catalog torrent, nil if
+// there is none yet
+func (ref *BEP44CatalogRef) Reference() *CatalogReference {
+	return ref.catalogTorrent.GetCatalogReference()
+}
+
+// Conflicts returns the number of concurrent catalog writes that had to be merged
+func (ref *BEP44CatalogRef) Conflicts() int {
+	ref.conflictsMu.Lock()
+	defer ref.conflictsMu.Unlock()
+	return ref.conflicts
+}
+
+// getRemoteRef fetches the current catalog reference and its sequence number
+// from BEP44 without loading the catalog
+func (ref *BEP44CatalogRef) getRemoteRef() (*CatalogReference, int64, error) {
 	target := bep44.MakeMutableTarget(ref.publicKey, nil)
 	
 	fmt.Printf("[BEP44Ref] Fetching catalog reference from DHT (target: %x)\n", target[:8])
@@ -162,7 +354,7 @@ func (ref *BEP44CatalogRef) fetchCatalogRef() error {
 			fmt.Printf("[BEP44Ref] Get traversal failed after contacting %d nodes: %v\n", 
 				stats.NumAddrsTried, err)
 		}
-		return fmt.Errorf("catalog reference not found in DHT: %w", err)
+		return nil, 0, fmt.Errorf("catalog reference not found in DHT: %w", err)
 	}
 	
 	fmt.Printf("[BEP44Ref] Get traversal complete - contacted %d nodes, got %d responses\n",
@@ -170,7 +362,7 @@ func (ref *BEP44CatalogRef) fetchCatalogRef() error {
 	
 	// Parse the retrieved value
 	if len(result.V) == 0 {
-		return fmt.Errorf("empty catalog reference value")
+		return nil, 0, fmt.Errorf("empty catalog reference value")
 	}
 	
 	// The value from BEP44 is the raw bytes we stored
@@ -200,16 +392,35 @@ func (ref *BEP44CatalogRef) fetchCatalogRef() error {
 	// Parse the JSON catalog reference
 	var catalogRef CatalogReference
 	if err := json.Unmarshal(jsonData, &catalogRef); err != nil {
-		return fmt.Errorf("failed to parse catalog reference from %q: %w", string(jsonData), err)
+		return nil, 0, fmt.Errorf("failed to parse catalog reference from %q: %w", string(jsonData), err)
 	}
 	
 	fmt.Printf("[BEP44Ref] Found catalog reference: %s (seq: %d)\n", 
 		catalogRef.InfoHash, result.Seq)
 	
+	return &catalogRef, result.Seq, nil
+}
+
+// fetchCatalogRef fetches the catalog reference from BEP44 using proper traversal
+func (ref *BEP44CatalogRef) fetchCatalogRef() error {
+	catalogRef, seq, err := ref.getRemoteRef()
+	if err != nil {
+		return err
+	}
+	
 	// Update our state if newer or equal (to refresh our knowledge)
-	if result.Seq >= ref.sequence {
-		ref.ref = &catalogRef
-		ref.sequence = result.Seq
+	if seq >= ref.sequence {
+		ref.ref = catalogRef
+		ref.sequence = seq
+		
+		// Merge into catalogs we write to, so local additions that were not
+		// published yet survive
+		if !ref.ReadOnly() {
+			if _, err := ref.catalogTorrent.FetchAndMergeCatalog(catalogRef.InfoHash); err != nil {
+				fmt.Printf("[BEP44Ref] Warning: failed to fetch catalog torrent: %v\n", err)
+			}
+			return nil
+		}
 		
 		// Fetch the catalog torrent
 		if err := ref.catalogTorrent.LoadOrFetchCatalog(catalogRef.InfoHash); err != nil {
@@ -241,30 +452,53 @@ func (ref *BEP44CatalogRef) RefreshCatalog() error {
 
 // RepublishCatalog republishes the current catalog reference to keep it alive in DHT
 func (ref *BEP44CatalogRef) RepublishCatalog() error {
+	ref.mu.Lock()
+	defer ref.mu.Unlock()
+	
+	// Publish merged changes that are not in the DHT yet
+	if ref.catalogTorrent.HasUnpublishedChanges() && ref.catalogTorrent.ModelCount() > 0 {
+		fmt.Println("[BEP44Ref] Local catalog has unpublished changes, publishing merged catalog")
+		return ref.rebuildAndPublish()
+	}
+	
 	// If we don't have a catalog, nothing to republish
 	if ref.ref == nil || ref.ref.InfoHash == "" {
 		return fmt.Errorf("no catalog to republish")
 	}
 	
 	// Republish the current catalog reference to keep it alive
-	return ref.PublishCatalogRef(ref.ref.InfoHash)
+	return ref.publishMerged(ref.ref.InfoHash)
 }
 
 // AddModel adds a model and publishes the new catalog
 func (ref *BEP44CatalogRef) AddModel(name, infoHash string, size int64) error {
+	return ref.AddModelWithMetadata(name, infoHash, size, ModelMetadata{})
+}
+
+// AddModelWithMetadata adds a model with searchable metadata and publishes the new catalog
+func (ref *BEP44CatalogRef) AddModelWithMetadata(name, infoHash string, size int64, meta ModelMetadata) error {
+	if ref.ReadOnly() {
+		return fmt.Errorf("cannot add models to a read-only catalog")
+	}
+	
 	// Lock to prevent concurrent catalog updates
 	ref.mu.Lock()
 	defer ref.mu.Unlock()
 	
 	fmt.Printf("[BEP44Ref] AddModel acquiring lock for: %s\n", name)
 	
-	// Check if model already exists in our local catalog
+	// Check if model already exists in our local catalog, unless its entry
+	// is taken over after a key rotation
 	models, _ := ref.catalogTorrent.GetModels("")
 	for _, model := range models {
-		if model.InfoHash == infoHash {
-			fmt.Printf("[BEP44Ref] Model %s already in catalog, skipping add\n", name)
-			return nil
+		if model.InfoHash != infoHash {
+			continue
 		}
+		if entry, ok := ref.catalogTorrent.FindModel(model.Name); ok && entry.Publisher != meta.Publisher && ref.catalogTorrent.PublishedBy(entry, meta.Publisher) {
+			continue
+		}
+		fmt.Printf("[BEP44Ref] Model %s already in catalog, skipping add\n", name)
+		return nil
 	}
 	
 	// First fetch latest catalog to avoid conflicts
@@ -273,13 +507,13 @@ func (ref *BEP44CatalogRef) AddModel(name, infoHash string, size int64) error {
 	}
 	
 	// Add model to catalog torrent
-	newCatalogHash, err := ref.catalogTorrent.AddModel(name, infoHash, size)
+	newCatalogHash, err := ref.catalogTorrent.AddModelWithMetadata(name, infoHash, size, meta)
 	if err != nil {
 		return fmt.Errorf("failed to add model to catalog: %w", err)
 	}
 	
 	// Publish new catalog reference
-	if err := ref.PublishCatalogRef(newCatalogHash); err != nil {
+	if err := ref.publishMerged(newCatalogHash); err != nil {
 		return fmt.Errorf("failed to publish catalog reference: %w", err)
 	}
 	
@@ -294,6 +528,146 @@ func (ref *BEP44CatalogRef) AddModel(name, infoHash string, size int64) error {
 	return nil
 }
 
+// MergePeerCatalog merges a catalog received from a peer and, if it added
+// anything, publishes the merged catalog
+func (ref *BEP44CatalogRef) MergePeerCatalog(other *ModelCatalog) (bool, error) {
+	if ref.ReadOnly() {
+		return false, fmt.Errorf("cannot merge into a read-only catalog")
+	}
+	
+	ref.mu.Lock()
+	defer ref.mu.Unlock()
+	
+	if !ref.catalogTorrent.MergeCatalog(other) {
+		return false, nil
+	}
+	
+	return true, ref.rebuildAndPublish()
+}
+
+// Unpublish withdraws a model from the catalog using a tombstone signed by
+// its publisher and publishes the new catalog
+func (ref *BEP44CatalogRef) Unpublish(name string, tombstone Tombstone) error {
+	if ref.ReadOnly() {
+		return fmt.Errorf("cannot unpublish from a read-only catalog")
+	}
+	
+	ref.mu.Lock()
+	defer ref.mu.Unlock()
+	
+	// Fetch the latest catalog so the withdrawal is applied on top of it
+	if err := ref.fetchCatalogRef(); err != nil {
+		fmt.Printf("[BEP44Ref] Could not fetch latest catalog (will use local): %v\n", err)
+	}
+	
+	if err := ref.catalogTorrent.ApplyTombstone(name, tombstone); err != nil {
+		return fmt.Errorf("failed to withdraw model: %w", err)
+	}
+	
+	return ref.rebuildAndPublish()
+}
+
+// Cleanup renews the entries of models that are still shared, removes expired
+// entries and tombstones, and publishes the catalog if anything changed.
+// Returns whether a new catalog was published.
+func (ref *BEP44CatalogRef) Cleanup(live map[string]bool) (bool, error) {
+	if ref.ReadOnly() {
+		return false, fmt.Errorf("cannot clean up a read-only catalog")
+	}
+	
+	ref.mu.Lock()
+	defer ref.mu.Unlock()
+	
+	renewed := ref.catalogTorrent.RenewModels(live)
+	removed, pruned := ref.catalogTorrent.Prune(time.Now())
+	for _, name := range removed {
+		fmt.Printf("[BEP44Ref] Removed expired model from catalog: %s\n", name)
+	}
+	
+	if renewed == 0 && !pruned {
+		return false, nil
+	}
+	
+	fmt.Printf("[BEP44Ref] Catalog cleanup: renewed %d, removed %d\n", renewed, len(removed))
+	if err := ref.rebuildAndPublish(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// rebuildAndPublish seeds a new catalog torrent and publishes its reference.
+// Callers must hold ref.mu.
+func (ref *BEP44CatalogRef) rebuildAndPublish() error {
+	newCatalogHash, err := ref.catalogTorrent.Rebuild()
+	if err != nil {
+		return fmt.Errorf("failed to rebuild catalog torrent: %w", err)
+	}
+	
+	if err := ref.publishMerged(newCatalogHash); err != nil {
+		return fmt.Errorf("failed to publish catalog reference: %w", err)
+	}
+	return nil
+}
+
+// PublishRotation records in the catalog that a publisher rotated its key and
+// publishes the catalog. Catalogs signed with the old key also publish the
+// rotation with their reference, for subscribers to follow it.
+func (ref *BEP44CatalogRef) PublishRotation(rotation KeyRotation) error {
+	if ref.ReadOnly() {
+		return fmt.Errorf("cannot publish to a read-only catalog")
+	}
+	
+	ref.mu.Lock()
+	defer ref.mu.Unlock()
+	
+	if rotation.Old == ref.PublicKey() {
+		ref.rotation = &rotation
+	}
+	if err := ref.catalogTorrent.AddRotation(rotation); err != nil {
+		return err
+	}
+	if ref.catalogTorrent.ModelCount() == 0 && ref.rotation != nil {
+		// Nothing to seed, the reference only points to the new key
+		infoHash := ""
+		if ref.ref != nil {
+			infoHash = ref.ref.InfoHash
+		}
+		return ref.publishMerged(infoHash)
+	}
+	return ref.rebuildAndPublish()
+}
+
+// Rotation returns the verified rotation of the key of the catalog to a new
+// key, published with the latest reference, nil if there is none
+func (ref *BEP44CatalogRef) Rotation() *KeyRotation {
+	current := ref.ref
+	if current == nil || current.Rotation == nil || current.Rotation.Old != ref.PublicKey() {
+		return nil
+	}
+	if err := current.Rotation.Verify(); err != nil {
+		fmt.Printf("[BEP44Ref] Ignoring key rotation of %s: %v\n", ref.PublicKey(), err)
+		return nil
+	}
+	rotation := *current.Rotation
+	return &rotation
+}
+
+// PublishedBy reports whether a catalog entry was published with key, or
+// with a key rotated to it
+func (ref *BEP44CatalogRef) PublishedBy(entry ModelEntry, key string) bool {
+	return ref.catalogTorrent.PublishedBy(entry, key)
+}
+
+// FindModel returns the catalog entry of a model
+func (ref *BEP44CatalogRef) FindModel(name string) (ModelEntry, bool) {
+	return ref.catalogTorrent.FindModel(name)
+}
+
+// IsWithdrawn reports whether the publisher withdrew this version of a model
+func (ref *BEP44CatalogRef) IsWithdrawn(name, infoHash string) bool {
+	return ref.catalogTorrent.IsWithdrawn(name, infoHash)
+}
+
 // GetModels searches for models
 func (ref *BEP44CatalogRef) GetModels(pattern string) ([]*types.ModelAnnouncement, error) {
 	// Try to fetch latest catalog
@@ -304,6 +678,26 @@ func (ref *BEP44CatalogRef) GetModels(pattern string) ([]*types.ModelAnnouncemen
 	return ref.catalogTorrent.GetModels(pattern)
 }
 
+// SearchModels searches for models matching the given filters
+func (ref *BEP44CatalogRef) SearchModels(filter types.SearchFilter) ([]*types.ModelAnnouncement, error) {
+	// Try to fetch latest catalog
+	if err := ref.fetchCatalogRef(); err != nil {
+		fmt.Printf("[BEP44Ref] Could not fetch latest catalog: %v\n", err)
+	}
+	
+	return ref.catalogTorrent.SearchModels(filter)
+}
+
+// SearchLocalModels searches the locally cached catalog without contacting the DHT
+func (ref *BEP44CatalogRef) SearchLocalModels(filter types.SearchFilter) ([]*types.ModelAnnouncement, error) {
+	return ref.catalogTorrent.SearchModels(filter)
+}
+
+// ModelCount returns the number of models in the locally cached catalog
+func (ref *BEP44CatalogRef) ModelCount() int {
+	return ref.catalogTorrent.ModelCount()
+}
+
 // Close shuts down the catalog reference manager
 func (ref *BEP44CatalogRef) Close() {
 	ref.cancel()
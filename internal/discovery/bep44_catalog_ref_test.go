@@ -94,6 +94,18 @@ func TestDeterministicKeyGeneration(t *testing.T) {
 	assert.Equal(t, publicKey1, publicKey2)
 }
 
+func TestSeedCatalogKey(t *testing.T) {
+	// The public catalog keeps the key derived from the well-known seed
+	seed := sha256.Sum256([]byte(WellKnownSeed))
+	expected := ed25519.NewKeyFromSeed(seed[:])
+	assert.Equal(t, expected, seedCatalogKey(WellKnownSeed))
+
+	// Other networks get catalogs of their own
+	assert.Len(t, SeedCatalogKey("acme-models"), 64)
+	assert.NotEqual(t, SeedCatalogKey(WellKnownSeed), SeedCatalogKey("acme-models"))
+	assert.Equal(t, SeedCatalogKey("acme-models"), SeedCatalogKey("acme-models"))
+}
+
 func TestPublishCatalogRef(t *testing.T) {
 	ref, dhtServer, client, tmpDir := setupTestBEP44CatalogRef(t)
 	defer os.RemoveAll(tmpDir)
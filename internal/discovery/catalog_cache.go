@@ -0,0 +1,30 @@
+package discovery
+
+import "sync"
+
+// CatalogCache keeps a copy of the catalogs apart from the files they are
+// seeded from, which a crash can leave torn. Catalogs are keyed by their
+// directory.
+type CatalogCache interface {
+	LoadCatalog(dir string, catalog *ModelCatalog) (bool, error)
+	SaveCatalog(dir string, catalog *ModelCatalog) error
+}
+
+var (
+	catalogCacheMu sync.RWMutex
+	catalogCache   CatalogCache
+)
+
+// SetCatalogCache makes catalogs loaded and saved from then on use cache,
+// nil stops using one
+func SetCatalogCache(cache CatalogCache) {
+	catalogCacheMu.Lock()
+	defer catalogCacheMu.Unlock()
+	catalogCache = cache
+}
+
+func getCatalogCache() CatalogCache {
+	catalogCacheMu.RLock()
+	defer catalogCacheMu.RUnlock()
+	return catalogCache
+}
@@ -0,0 +1,327 @@
+package discovery
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	pp "github.com/anacrolix/torrent/peer_protocol"
+)
+
+const (
+	// BEP 10 extension name used by Silmaril peers to exchange catalogs
+	CatalogGossipExtension pp.ExtensionName = "silmaril_catalog"
+
+	// Maximum size of a gossip message, larger catalogs are ignored
+	maxGossipPayload = 1 << 20
+
+	// Maximum number of models accepted from a single peer catalog
+	maxGossipModels = 10000
+
+	// Minimum time between sending our full catalog to the same peer
+	gossipResendInterval = 10 * time.Minute
+
+	// How often offers are broadcast to all connected Silmaril peers
+	gossipOfferInterval = 5 * time.Minute
+
+	// Delay before replying to an extended handshake, the torrent client only
+	// records the peer's extension IDs after the handshake callback returns
+	gossipHandshakeDelay = time.Second
+)
+
+// Gossip message types
+const (
+	gossipOffer   = "offer"   // Summary of our catalog
+	gossipRequest = "request" // Ask the peer for its full catalog
+	gossipCatalog = "catalog" // Full catalog
+)
+
+// gossipMessage is the JSON payload of a catalog gossip extension message
+type gossipMessage struct {
+	Type     string        `json:"type"`
+	Sequence int64         `json:"seq,omitempty"`
+	Count    int           `json:"n,omitempty"`
+	Digest   string        `json:"digest,omitempty"`
+	Catalog  *ModelCatalog `json:"catalog,omitempty"`
+}
+
+// gossipPeer tracks the gossip state of a connected Silmaril peer
+type gossipPeer struct {
+	lastCatalogSent time.Time
+}
+
+// CatalogGossip exchanges and merges catalogs directly with connected peers
+// over a BitTorrent extension, so discovery keeps working when the BEP44
+// catalog reference is stale or unreachable
+type CatalogGossip struct {
+	mu    sync.Mutex
+	ref   *BEP44CatalogRef
+	peers map[*torrent.PeerConn]*gossipPeer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewCatalogGossip creates a catalog gossip handler. Register must be called
+// with the torrent client config before the client is created.
+func NewCatalogGossip() *CatalogGossip {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &CatalogGossip{
+		peers:  make(map[*torrent.PeerConn]*gossipPeer),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	go g.periodicOffers()
+	return g
+}
+
+// Register adds the gossip extension and its callbacks to a torrent client config
+func (g *CatalogGossip) Register(cfg *torrent.ClientConfig) {
+	cfg.Callbacks.PeerConnAdded = append(cfg.Callbacks.PeerConnAdded, g.onPeerConnAdded)
+	cfg.Callbacks.PeerConnReadExtensionMessage = append(cfg.Callbacks.PeerConnReadExtensionMessage, g.onExtensionMessage)
+
+	prevHandshake := cfg.Callbacks.ReadExtendedHandshake
+	cfg.Callbacks.ReadExtendedHandshake = func(pc *torrent.PeerConn, msg *pp.ExtendedHandshakeMessage) {
+		if prevHandshake != nil {
+			prevHandshake(pc, msg)
+		}
+		g.onExtendedHandshake(pc, msg)
+	}
+
+	prevClosed := cfg.Callbacks.PeerConnClosed
+	cfg.Callbacks.PeerConnClosed = func(pc *torrent.PeerConn) {
+		if prevClosed != nil {
+			prevClosed(pc)
+		}
+		g.mu.Lock()
+		delete(g.peers, pc)
+		g.mu.Unlock()
+	}
+}
+
+// SetCatalogRef sets the catalog that is gossiped and merged into
+func (g *CatalogGossip) SetCatalogRef(ref *BEP44CatalogRef) {
+	g.mu.Lock()
+	g.ref = ref
+	g.mu.Unlock()
+}
+
+// PeerCount returns the number of connected peers that support catalog gossip
+func (g *CatalogGossip) PeerCount() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.peers)
+}
+
+// Broadcast sends an offer of our current catalog to every gossip peer
+func (g *CatalogGossip) Broadcast() {
+	g.mu.Lock()
+	peers := make([]*torrent.PeerConn, 0, len(g.peers))
+	for pc := range g.peers {
+		peers = append(peers, pc)
+	}
+	g.mu.Unlock()
+
+	for _, pc := range peers {
+		g.sendOffer(pc)
+	}
+}
+
+// Close stops the periodic offers
+func (g *CatalogGossip) Close() {
+	g.cancel()
+}
+
+func (g *CatalogGossip) periodicOffers() {
+	ticker := time.NewTicker(gossipOfferInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.ctx.Done():
+			return
+		case <-ticker.C:
+			g.Broadcast()
+		}
+	}
+}
+
+// onPeerConnAdded advertises the gossip extension in our extended handshake
+func (g *CatalogGossip) onPeerConnAdded(pc *torrent.PeerConn) {
+	// The map points to the client default, copy it before adding our protocol
+	protocols := *pc.LocalLtepProtocolMap
+	protocols.Index = slices.Clone(protocols.Index)
+	protocols.AddUserProtocol(CatalogGossipExtension)
+	pc.LocalLtepProtocolMap = &protocols
+}
+
+// onExtendedHandshake starts gossiping with peers that support the extension
+func (g *CatalogGossip) onExtendedHandshake(pc *torrent.PeerConn, msg *pp.ExtendedHandshakeMessage) {
+	if _, ok := msg.M[CatalogGossipExtension]; !ok {
+		return
+	}
+
+	g.mu.Lock()
+	if _, exists := g.peers[pc]; !exists {
+		g.peers[pc] = &gossipPeer{}
+	}
+	g.mu.Unlock()
+
+	// Callbacks run with client locks held, so reply asynchronously
+	go func() {
+		time.Sleep(gossipHandshakeDelay)
+		g.sendOffer(pc)
+	}()
+}
+
+// onExtensionMessage dispatches gossip extension messages
+func (g *CatalogGossip) onExtensionMessage(event torrent.PeerConnReadExtensionMessageEvent) {
+	name, _, err := event.PeerConn.LocalLtepProtocolMap.LookupId(event.ExtensionNumber)
+	if err != nil || name != CatalogGossipExtension {
+		return
+	}
+	if len(event.Payload) > maxGossipPayload {
+		fmt.Printf("[CatalogGossip] Ignoring oversized message from %s (%d bytes)\n", event.PeerConn.RemoteAddr, len(event.Payload))
+		return
+	}
+
+	// The payload is only valid during the callback
+	payload := slices.Clone(event.Payload)
+	go g.handleMessage(event.PeerConn, payload)
+}
+
+func (g *CatalogGossip) handleMessage(pc *torrent.PeerConn, payload []byte) {
+	var msg gossipMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		fmt.Printf("[CatalogGossip] Invalid message from %s: %v\n", pc.RemoteAddr, err)
+		return
+	}
+
+	g.mu.Lock()
+	ref := g.ref
+	g.mu.Unlock()
+	if ref == nil {
+		return
+	}
+
+	switch msg.Type {
+	case gossipOffer:
+		if msg.Digest != ref.catalogTorrent.Digest() {
+			g.send(pc, gossipMessage{Type: gossipRequest})
+		}
+	case gossipRequest:
+		g.sendCatalog(pc)
+	case gossipCatalog:
+		if msg.Catalog == nil {
+			return
+		}
+		if err := validateGossipCatalog(msg.Catalog); err != nil {
+			fmt.Printf("[CatalogGossip] Rejected catalog from %s: %v\n", pc.RemoteAddr, err)
+			return
+		}
+		changed, err := ref.MergePeerCatalog(msg.Catalog)
+		if err != nil {
+			fmt.Printf("[CatalogGossip] Failed to merge catalog from %s: %v\n", pc.RemoteAddr, err)
+			return
+		}
+		if changed {
+			fmt.Printf("[CatalogGossip] Merged catalog from %s (%d models)\n", pc.RemoteAddr, len(msg.Catalog.Models))
+		}
+	}
+}
+
+func (g *CatalogGossip) sendOffer(pc *torrent.PeerConn) {
+	g.mu.Lock()
+	ref := g.ref
+	g.mu.Unlock()
+	if ref == nil {
+		return
+	}
+
+	snapshot := ref.catalogTorrent.Snapshot()
+	g.send(pc, gossipMessage{
+		Type:     gossipOffer,
+		Sequence: snapshot.Sequence,
+		Count:    len(snapshot.Models),
+		Digest:   ref.catalogTorrent.Digest(),
+	})
+}
+
+func (g *CatalogGossip) sendCatalog(pc *torrent.PeerConn) {
+	g.mu.Lock()
+	ref := g.ref
+	peer, ok := g.peers[pc]
+	if !ok || ref == nil || time.Since(peer.lastCatalogSent) < gossipResendInterval {
+		g.mu.Unlock()
+		return
+	}
+	peer.lastCatalogSent = time.Now()
+	g.mu.Unlock()
+
+	g.send(pc, gossipMessage{Type: gossipCatalog, Catalog: ref.catalogTorrent.Snapshot()})
+}
+
+func (g *CatalogGossip) send(pc *torrent.PeerConn, msg gossipMessage) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		fmt.Printf("[CatalogGossip] Failed to encode %s message: %v\n", msg.Type, err)
+		return
+	}
+	if len(payload) > maxGossipPayload {
+		fmt.Printf("[CatalogGossip] Catalog too large to gossip (%d bytes)\n", len(payload))
+		return
+	}
+	if err := pc.WriteExtendedMessage(CatalogGossipExtension, payload); err != nil {
+		fmt.Printf("[CatalogGossip] Failed to send %s to %s: %v\n", msg.Type, pc.RemoteAddr, err)
+	}
+}
+
+// validateGossipCatalog performs basic sanity checks on a catalog received from a peer
+func validateGossipCatalog(catalog *ModelCatalog) error {
+	if len(catalog.Models) > maxGossipModels {
+		return fmt.Errorf("too many models: %d", len(catalog.Models))
+	}
+	for name, entry := range catalog.Models {
+		if name == "" {
+			return fmt.Errorf("model with empty name")
+		}
+		if len(entry.InfoHash) != 40 {
+			return fmt.Errorf("invalid infohash for %s", name)
+		}
+		if _, err := hex.DecodeString(entry.InfoHash); err != nil {
+			return fmt.Errorf("invalid infohash for %s: %w", name, err)
+		}
+		if len(entry.Domain) > maxDomainLength {
+			return fmt.Errorf("invalid domain for %s", name)
+		}
+		if len(entry.DomainSignature) > 2*ed25519.SignatureSize {
+			return fmt.Errorf("invalid domain signature for %s", name)
+		}
+	}
+	if len(catalog.Tombstones) > maxGossipModels {
+		return fmt.Errorf("too many tombstones: %d", len(catalog.Tombstones))
+	}
+	for name, tombstone := range catalog.Tombstones {
+		if err := tombstone.Verify(name); err != nil {
+			return err
+		}
+	}
+	if len(catalog.Rotations) > maxGossipModels {
+		return fmt.Errorf("too many key rotations: %d", len(catalog.Rotations))
+	}
+	for old, rotation := range catalog.Rotations {
+		if rotation.Old != old {
+			return fmt.Errorf("key rotation of %s filed under %s", rotation.Old, old)
+		}
+		if err := rotation.Verify(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
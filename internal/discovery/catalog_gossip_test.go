@@ -0,0 +1,91 @@
+package discovery
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/anacrolix/torrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogDigest(t *testing.T) {
+	ct, client, tmpDir := setupTestCatalogTorrent(t)
+	defer os.RemoveAll(tmpDir)
+	defer client.Close()
+
+	ct.catalog.Models = map[string]ModelEntry{
+		"org/a": {InfoHash: strings.Repeat("a", 40), Added: 1},
+		"org/b": {InfoHash: strings.Repeat("b", 40), Added: 2},
+	}
+	digest := ct.Digest()
+
+	// The digest ignores the sequence number
+	ct.catalog.Sequence += 10
+	assert.Equal(t, digest, ct.Digest())
+
+	// But changes with the catalog contents
+	ct.catalog.Models["org/c"] = ModelEntry{InfoHash: strings.Repeat("c", 40), Added: 3}
+	assert.NotEqual(t, digest, ct.Digest())
+}
+
+func TestCatalogSnapshotIsCopy(t *testing.T) {
+	ct, client, tmpDir := setupTestCatalogTorrent(t)
+	defer os.RemoveAll(tmpDir)
+	defer client.Close()
+
+	ct.catalog.Models = map[string]ModelEntry{
+		"org/a": {InfoHash: strings.Repeat("a", 40), Added: 1},
+	}
+
+	snapshot := ct.Snapshot()
+	snapshot.Models["org/b"] = ModelEntry{InfoHash: strings.Repeat("b", 40)}
+
+	assert.Len(t, ct.catalog.Models, 1)
+	assert.Len(t, snapshot.Models, 2)
+}
+
+func TestValidateGossipCatalog(t *testing.T) {
+	valid := &ModelCatalog{Models: map[string]ModelEntry{
+		"org/model": {InfoHash: strings.Repeat("ab", 20)},
+	}}
+	assert.NoError(t, validateGossipCatalog(valid))
+
+	badHash := &ModelCatalog{Models: map[string]ModelEntry{
+		"org/model": {InfoHash: "not-a-hash"},
+	}}
+	assert.Error(t, validateGossipCatalog(badHash))
+
+	nonHex := &ModelCatalog{Models: map[string]ModelEntry{
+		"org/model": {InfoHash: strings.Repeat("zz", 20)},
+	}}
+	assert.Error(t, validateGossipCatalog(nonHex))
+
+	emptyName := &ModelCatalog{Models: map[string]ModelEntry{
+		"": {InfoHash: strings.Repeat("ab", 20)},
+	}}
+	assert.Error(t, validateGossipCatalog(emptyName))
+
+	privateKey, _ := newTestPublisher(t)
+	tombstone := NewTombstone("org/model", strings.Repeat("ab", 20), privateKey)
+	withTombstone := &ModelCatalog{Tombstones: map[string]Tombstone{"org/model": tombstone}}
+	assert.NoError(t, validateGossipCatalog(withTombstone))
+
+	badTombstone := &ModelCatalog{Tombstones: map[string]Tombstone{"org/other": tombstone}}
+	assert.Error(t, validateGossipCatalog(badTombstone))
+}
+
+func TestCatalogGossipRegister(t *testing.T) {
+	gossip := NewCatalogGossip()
+	defer gossip.Close()
+
+	cfg := torrent.NewDefaultClientConfig()
+	gossip.Register(cfg)
+
+	assert.Len(t, cfg.Callbacks.PeerConnAdded, 1)
+	assert.Len(t, cfg.Callbacks.PeerConnReadExtensionMessage, 1)
+	require.NotNil(t, cfg.Callbacks.ReadExtendedHandshake)
+	require.NotNil(t, cfg.Callbacks.PeerConnClosed)
+	assert.Equal(t, 0, gossip.PeerCount())
+}
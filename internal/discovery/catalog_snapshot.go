@@ -0,0 +1,67 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SnapshotFormat identifies catalog snapshot files
+const SnapshotFormat = "silmaril-catalog-snapshot/v1"
+
+// CatalogSnapshot is a catalog exported to a file, which communities can
+// serve over HTTP so new nodes find models before they reach the DHT
+type CatalogSnapshot struct {
+	Format   string        `json:"format"`
+	Exported time.Time     `json:"exported"`
+	Digest   string        `json:"digest"` // Of the catalog, as offered in gossip
+	Catalog  *ModelCatalog `json:"catalog"`
+}
+
+// ExportSnapshot returns a snapshot of the catalog without its expired
+// entries
+func (ref *BEP44CatalogRef) ExportSnapshot() *CatalogSnapshot {
+	catalog := ref.catalogTorrent.Snapshot()
+	now := time.Now()
+	for name, entry := range catalog.Models {
+		if entry.Expired(now) {
+			delete(catalog.Models, name)
+		}
+	}
+	return &CatalogSnapshot{
+		Format:   SnapshotFormat,
+		Exported: now.UTC().Truncate(time.Second),
+		Digest:   catalogDigest(catalog),
+		Catalog:  catalog,
+	}
+}
+
+// ImportSnapshot merges a snapshot into the catalog like a catalog gossiped
+// by a peer, and publishes the merged catalog if it added anything
+func (ref *BEP44CatalogRef) ImportSnapshot(snapshot *CatalogSnapshot) (bool, error) {
+	return ref.MergePeerCatalog(snapshot.Catalog)
+}
+
+// ParseSnapshot reads a catalog snapshot and checks it as a catalog received
+// from a peer. Entries, tombstones and rotations are only as trustworthy as
+// whoever serves the snapshot, tombstones and rotations are verified when
+// merged.
+func ParseSnapshot(data []byte) (*CatalogSnapshot, error) {
+	var snapshot CatalogSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid catalog snapshot: %w", err)
+	}
+	if snapshot.Format != SnapshotFormat {
+		return nil, fmt.Errorf("not a catalog snapshot: format %q, want %q", snapshot.Format, SnapshotFormat)
+	}
+	if snapshot.Catalog == nil {
+		return nil, fmt.Errorf("catalog snapshot has no catalog")
+	}
+	if err := validateGossipCatalog(snapshot.Catalog); err != nil {
+		return nil, fmt.Errorf("invalid catalog snapshot: %w", err)
+	}
+	if snapshot.Digest != "" && snapshot.Digest != catalogDigest(snapshot.Catalog) {
+		return nil, fmt.Errorf("catalog snapshot doesn't match its digest, it may be truncated or changed")
+	}
+	return &snapshot, nil
+}
@@ -0,0 +1,52 @@
+package discovery
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogSnapshot(t *testing.T) {
+	ct, client, tmpDir := setupTestCatalogTorrent(t)
+	defer os.RemoveAll(tmpDir)
+	defer client.Close()
+
+	now := time.Now().Unix()
+	ct.catalog.Models = map[string]ModelEntry{
+		"org/a":   {InfoHash: strings.Repeat("a", 40), Added: now},
+		"org/old": {InfoHash: strings.Repeat("b", 40), Added: now - int64(2*CatalogEntryTTL/time.Second)},
+	}
+	ref := &BEP44CatalogRef{catalogTorrent: ct}
+
+	// Expired entries are left out
+	snapshot := ref.ExportSnapshot()
+	assert.Equal(t, SnapshotFormat, snapshot.Format)
+	assert.Contains(t, snapshot.Catalog.Models, "org/a")
+	assert.NotContains(t, snapshot.Catalog.Models, "org/old")
+	assert.Len(t, ct.catalog.Models, 2)
+
+	data, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+	parsed, err := ParseSnapshot(data)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.Digest, parsed.Digest)
+	assert.Len(t, parsed.Catalog.Models, 1)
+
+	// A changed snapshot no longer matches its digest
+	snapshot.Catalog.Models["org/c"] = ModelEntry{InfoHash: strings.Repeat("c", 40), Added: now}
+	data, err = json.Marshal(snapshot)
+	require.NoError(t, err)
+	_, err = ParseSnapshot(data)
+	assert.Error(t, err)
+
+	// Other files and invalid catalogs are refused
+	_, err = ParseSnapshot([]byte(`{"v":1,"m":{}}`))
+	assert.Error(t, err)
+	_, err = ParseSnapshot([]byte(`{"format":"` + SnapshotFormat + `","catalog":{"m":{"org/x":{"h":"nothex"}}}}`))
+	assert.Error(t, err)
+}
@@ -1,11 +1,15 @@
 package discovery
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
@@ -30,6 +34,12 @@ type CatalogTorrent struct {
 	catalog     *ModelCatalog
 	infoHash    string
 	
+	// Digest of the catalog as last published in the DHT, and the last
+	// published catalog merged into ours
+	publishedDigest string
+	mergedInfoHash  string
+	torrentDigest   string
+	
 	// Torrent client for downloading/seeding
 	client      *torrent.Client
 	torrent     *torrent.Torrent
@@ -42,6 +52,9 @@ type CatalogReference struct {
 	Updated   int64  `json:"updated"`
 	Size      int64  `json:"size,omitempty"`
 	Seeders   int    `json:"seeders,omitempty"`
+	
+	// Set once the publisher moved to a new key, subscribers follow it
+	Rotation  *KeyRotation `json:"rot,omitempty"`
 }
 
 // NewCatalogTorrent creates a new catalog torrent manager
@@ -51,8 +64,12 @@ func NewCatalogTorrent(torrentClient *torrent.Client) (*CatalogTorrent, error) {
 		return nil, fmt.Errorf("failed to get paths: %w", err)
 	}
 	
+	return NewCatalogTorrentInDir(torrentClient, filepath.Join(paths.BaseDir(), "catalog"))
+}
+
+// NewCatalogTorrentInDir creates a catalog torrent manager storing its catalog in catalogDir
+func NewCatalogTorrentInDir(torrentClient *torrent.Client, catalogDir string) (*CatalogTorrent, error) {
 	// Create catalog directory
-	catalogDir := filepath.Join(paths.BaseDir(), "catalog")
 	if err := os.MkdirAll(catalogDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create catalog dir: %w", err)
 	}
@@ -118,6 +135,63 @@ func (ct *CatalogTorrent) LoadOrFetchCatalog(infoHash string) error {
 		return nil
 	}
 	
+	catalog, t, err := ct.fetchCatalog(infoHash, ct.catalogDir)
+	if err != nil {
+		return err
+	}
+	
+	// Update our catalog
+	ct.catalog = catalog
+	ct.infoHash = infoHash
+	ct.torrent = t
+	ct.publishedDigest = catalogDigest(catalog)
+	
+	// Save to local file
+	if err := ct.saveCatalog(); err != nil {
+		fmt.Printf("[CatalogTorrent] Warning: failed to save catalog locally: %v\n", err)
+	}
+	
+	return nil
+}
+
+// FetchAndMergeCatalog downloads a published catalog and merges it into ours,
+// keeping local entries that were not published yet instead of replacing
+// them. Returns whether the local catalog changed.
+func (ct *CatalogTorrent) FetchAndMergeCatalog(infoHash string) (bool, error) {
+	ct.mu.Lock()
+	if ct.infoHash == infoHash || ct.mergedInfoHash == infoHash {
+		ct.mu.Unlock()
+		return false, nil
+	}
+	ct.mu.Unlock()
+	
+	// Download into a scratch directory so our own catalog files are not overwritten
+	dir, err := os.MkdirTemp("", "silmaril-catalog-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create download dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	
+	remote, t, err := ct.fetchCatalog(infoHash, dir)
+	if err != nil {
+		return false, err
+	}
+	// We seed our merged catalog, not the fetched one
+	t.Drop()
+	
+	changed := ct.MergeCatalog(remote)
+	
+	ct.mu.Lock()
+	ct.mergedInfoHash = infoHash
+	ct.publishedDigest = catalogDigest(remote)
+	ct.mu.Unlock()
+	
+	return changed, nil
+}
+
+// fetchCatalog downloads the catalog torrent with the given infohash into dir
+// and parses it
+func (ct *CatalogTorrent) fetchCatalog(infoHash, dir string) (*ModelCatalog, *torrent.Torrent, error) {
 	fmt.Printf("[CatalogTorrent] Fetching catalog torrent: %s\n", infoHash)
 	
 	// Add magnet link with custom storage for catalog
@@ -126,12 +200,12 @@ func (ct *CatalogTorrent) LoadOrFetchCatalog(infoHash string) error {
 	// Parse the infohash to get the metainfo.Hash (not needed since spec handles it)
 	// Just validate the infohash format
 	if len(infoHash) != 40 {
-		return fmt.Errorf("invalid infohash length: %d", len(infoHash))
+		return nil, nil, fmt.Errorf("invalid infohash length: %d", len(infoHash))
 	}
 	
 	// Create storage that puts files directly in the catalog directory
 	catalogStorage := torrentStorage.NewFileOpts(torrentStorage.NewFileClientOpts{
-		ClientBaseDir: ct.catalogDir,
+		ClientBaseDir: dir,
 		TorrentDirMaker: func(baseDir string, info *metainfo.Info, infoHash metainfo.Hash) string {
 			// Return the base dir itself, not a subdirectory
 			return baseDir
@@ -141,13 +215,13 @@ func (ct *CatalogTorrent) LoadOrFetchCatalog(infoHash string) error {
 	// Add torrent with custom storage
 	spec, err := torrent.TorrentSpecFromMagnetUri(magnetURI)
 	if err != nil {
-		return fmt.Errorf("failed to parse magnet URI: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse magnet URI: %w", err)
 	}
 	spec.Storage = catalogStorage
 	
 	t, _, err := ct.client.AddTorrentSpec(spec)
 	if err != nil {
-		return fmt.Errorf("failed to add catalog torrent: %w", err)
+		return nil, nil, fmt.Errorf("failed to add catalog torrent: %w", err)
 	}
 	
 	// First check if there are any peers for this torrent in DHT
@@ -169,7 +243,7 @@ func (ct *CatalogTorrent) LoadOrFetchCatalog(infoHash string) error {
 	if stats.TotalPeers == 0 {
 		fmt.Println("[CatalogTorrent] No peers found in DHT for catalog torrent")
 		t.Drop() // Remove from torrent client
-		return fmt.Errorf("no seeders for catalog torrent")
+		return nil, nil, fmt.Errorf("no seeders for catalog torrent")
 	}
 	
 	// Wait for info with timeout
@@ -196,9 +270,9 @@ func (ct *CatalogTorrent) LoadOrFetchCatalog(infoHash string) error {
 				stats.ActivePeers, stats.TotalPeers)
 			t.Drop()
 			if stats.TotalPeers == 0 {
-				return fmt.Errorf("no seeders for catalog torrent")
+				return nil, nil, fmt.Errorf("no seeders for catalog torrent")
 			}
-			return fmt.Errorf("timeout waiting for catalog torrent metadata")
+			return nil, nil, fmt.Errorf("timeout waiting for catalog torrent metadata")
 		}
 	}
 	
@@ -218,7 +292,7 @@ downloadPhase:
 			fmt.Printf("[CatalogTorrent] Download timeout. Peers: %d, Seeders: %d\n", 
 				stats.ActivePeers, stats.ConnectedSeeders)
 			t.Drop()
-			return fmt.Errorf("timeout downloading catalog")
+			return nil, nil, fmt.Errorf("timeout downloading catalog")
 			
 		case <-downloadTicker.C:
 			if t.BytesCompleted() == t.Info().TotalLength() {
@@ -232,30 +306,24 @@ downloadPhase:
 						reader.SetResponsive()
 						data, err := io.ReadAll(reader)
 						if err != nil {
-							return fmt.Errorf("failed to read catalog from torrent: %w", err)
+							return nil, nil, fmt.Errorf("failed to read catalog from torrent: %w", err)
 						}
 						
 						// Parse catalog
 						var catalog ModelCatalog
 						if err := json.Unmarshal(data, &catalog); err != nil {
-							return fmt.Errorf("failed to parse catalog: %w", err)
+							return nil, nil, fmt.Errorf("failed to parse catalog: %w", err)
 						}
 						
-						// Update our catalog
-						ct.catalog = &catalog
-						ct.infoHash = infoHash
-						ct.torrent = t
-						
-						// Save to local file
-						if err := ct.saveCatalog(); err != nil {
-							fmt.Printf("[CatalogTorrent] Warning: failed to save catalog locally: %v\n", err)
+						if catalog.Models == nil {
+							catalog.Models = make(map[string]ModelEntry)
 						}
 						
 						fmt.Printf("[CatalogTorrent] Loaded catalog with %d models\n", len(catalog.Models))
-						return nil
+						return &catalog, t, nil
 					}
 				}
-				return fmt.Errorf("catalog.json not found in torrent")
+				return nil, nil, fmt.Errorf("catalog.json not found in torrent")
 			}
 			
 			// Progress update
@@ -268,25 +336,65 @@ downloadPhase:
 
 // AddModel adds a model to the catalog and creates a new torrent
 func (ct *CatalogTorrent) AddModel(name, infoHash string, size int64) (string, error) {
+	return ct.AddModelWithMetadata(name, infoHash, size, ModelMetadata{})
+}
+
+// AddModelWithMetadata adds a model with searchable metadata to the catalog and creates a new torrent
+func (ct *CatalogTorrent) AddModelWithMetadata(name, infoHash string, size int64, meta ModelMetadata) (string, error) {
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
 	
 	fmt.Printf("[CatalogTorrent] Adding model to catalog: %s\n", name)
 	
 	// Check if model already exists with same infohash
+	now := time.Now().Unix()
+	added, seen := now, int64(0)
 	if existing, exists := ct.catalog.Models[name]; exists && existing.InfoHash == infoHash {
-		fmt.Printf("[CatalogTorrent] Model %s already in catalog with same infohash, returning existing\n", name)
-		return ct.infoHash, nil
+		if slices.Equal(existing.Equivalents, meta.Equivalents) && existing.Domain == meta.Domain && existing.DomainSignature == meta.DomainSignature {
+			if meta.Publisher == existing.Publisher || !rotatesTo(existing.Publisher, meta.Publisher, ct.catalog.Rotations) {
+				fmt.Printf("[CatalogTorrent] Model %s already in catalog with same infohash, returning existing\n", name)
+				return ct.infoHash, nil
+			}
+			// A publisher that rotated its key takes over its entries
+			existing.Publisher = meta.Publisher
+			existing.DomainSignature = meta.DomainSignature
+			existing.Seen = now
+			ct.catalog.Models[name] = existing
+			return ct.saveAndRebuild()
+		}
+		// Declaring equivalent torrents or a domain renews the entry of the
+		// same torrent
+		added, seen = existing.Added, now
 	}
 	
 	// Add or update model in catalog
 	ct.catalog.Models[name] = ModelEntry{
-		InfoHash: infoHash,
-		Size:     size,
-		Tags:     extractTags(name),
-		Added:    time.Now().Unix(),
+		InfoHash:    infoHash,
+		Size:        size,
+		Tags:        buildEntryTags(name, meta.Tags),
+		Description: meta.Description,
+		License:     meta.License,
+		Parameters:  meta.Parameters,
+		Added:       added,
+		Seen:        seen,
+		Publisher:   meta.Publisher,
+		LicenseURL:  meta.LicenseURL,
+		RequireLicenseAcceptance: meta.RequireLicenseAcceptance,
+		Provenance:  meta.Provenance,
+		Equivalents: meta.Equivalents,
+		Domain:      meta.Domain,
+		DomainSignature: meta.DomainSignature,
 	}
 	
+	// Sharing the model again revokes an earlier withdrawal
+	delete(ct.catalog.Tombstones, name)
+	
+	return ct.saveAndRebuild()
+}
+
+// saveAndRebuild saves a changed catalog and seeds a new catalog torrent of
+// it. Callers must hold ct.mu.
+func (ct *CatalogTorrent) saveAndRebuild() (string, error) {
 	// Update catalog metadata
 	ct.catalog.Sequence++
 	ct.catalog.Updated = time.Now().Unix()
@@ -296,9 +404,23 @@ func (ct *CatalogTorrent) AddModel(name, infoHash string, size int64) (string, e
 		return "", fmt.Errorf("failed to save catalog: %w", err)
 	}
 	
+	return ct.rebuildTorrent()
+}
+
+// Rebuild creates and seeds a new catalog torrent from the current catalog
+func (ct *CatalogTorrent) Rebuild() (string, error) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	
+	return ct.rebuildTorrent()
+}
+
+// rebuildTorrent creates a torrent of the catalog directory and starts seeding
+// it in place of the previous one. Callers must hold ct.mu.
+func (ct *CatalogTorrent) rebuildTorrent() (string, error) {
 	// Create torrent of catalog directory
 	catalogTorrentPath := filepath.Join(ct.catalogDir, fmt.Sprintf("catalog_%d.torrent", ct.catalog.Sequence))
-	newInfoHash, err := torrentCreator.CreateTorrentFromDirectory(ct.catalogDir, catalogTorrentPath, 256*1024) // 256KB pieces for small catalog
+	newInfoHash, err := torrentCreator.CreateTorrentFromDirectory(ct.catalogDir, catalogTorrentPath, 256*1024, nil) // 256KB pieces for small catalog
 	if err != nil {
 		return "", fmt.Errorf("failed to create catalog torrent: %w", err)
 	}
@@ -351,6 +473,7 @@ func (ct *CatalogTorrent) AddModel(name, infoHash string, size int64) (string, e
 	ct.torrent = newTorrent
 	ct.infoHash = newInfoHash
 	ct.torrentFile = catalogTorrentPath
+	ct.torrentDigest = catalogDigest(ct.catalog)
 	
 	fmt.Printf("[CatalogTorrent] Created new catalog torrent: %s\n", newInfoHash)
 	fmt.Printf("[CatalogTorrent] Catalog now contains %d models\n", len(ct.catalog.Models))
@@ -367,14 +490,30 @@ func (ct *CatalogTorrent) GetModels(pattern string) ([]*types.ModelAnnouncement,
 		return nil, nil
 	}
 	
+	now := time.Now()
 	var results []*types.ModelAnnouncement
 	for name, model := range ct.catalog.Models {
-		if pattern == "" || pattern == "*" || matchesPattern(name, pattern) {
+		// Expired entries are hidden until the next cleanup pass removes them
+		if model.Expired(now) {
+			continue
+		}
+		if pattern == "" || pattern == "*" || matchesEntry(name, model, pattern) {
 			results = append(results, &types.ModelAnnouncement{
-				Name:     name,
-				InfoHash: model.InfoHash,
-				Size:     model.Size,
-				Time:     model.Added,
+				Name:        name,
+				InfoHash:    model.InfoHash,
+				Size:        model.Size,
+				Time:        model.Added,
+				Description: model.Description,
+				Tags:        model.Tags,
+				License:     model.License,
+				Parameters:  model.Parameters,
+				LicenseURL:  model.LicenseURL,
+				RequireLicenseAcceptance: model.RequireLicenseAcceptance,
+				Provenance:  model.Provenance,
+				Equivalents: model.Equivalents,
+				PublisherKey: model.Publisher,
+				Domain:      model.Domain,
+				DomainSignature: model.DomainSignature,
 			})
 		}
 	}
@@ -382,6 +521,23 @@ func (ct *CatalogTorrent) GetModels(pattern string) ([]*types.ModelAnnouncement,
 	return results, nil
 }
 
+// SearchModels returns models matching the pattern, tag, size and license filters
+func (ct *CatalogTorrent) SearchModels(filter types.SearchFilter) ([]*types.ModelAnnouncement, error) {
+	models, err := ct.GetModels(filter.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	
+	var results []*types.ModelAnnouncement
+	for _, model := range models {
+		if filter.Matches(model) {
+			results = append(results, model)
+		}
+	}
+	
+	return results, nil
+}
+
 // GetCatalogReference returns the current catalog reference for BEP44
 func (ct *CatalogTorrent) GetCatalogReference() *CatalogReference {
 	ct.mu.RLock()
@@ -414,8 +570,52 @@ func (ct *CatalogTorrent) MergeCatalog(other *ModelCatalog) bool {
 	defer ct.mu.Unlock()
 	
 	changed := false
+	
+	// Take key rotations first, tombstones may be signed with the new keys
+	for old, rotation := range other.Rotations {
+		if _, exists := ct.catalog.Rotations[old]; exists || rotation.Old != old {
+			continue
+		}
+		if err := rotation.Verify(); err != nil {
+			fmt.Printf("[CatalogTorrent] Ignoring key rotation: %v\n", err)
+			continue
+		}
+		ct.addRotation(rotation)
+		changed = true
+	}
+	
+	// Take tombstones first so withdrawn models are not merged back in
+	for name, tombstone := range other.Tombstones {
+		if existing, exists := ct.catalog.Tombstones[name]; exists && existing.Removed >= tombstone.Removed {
+			continue
+		}
+		if err := tombstone.Verify(name); err != nil {
+			fmt.Printf("[CatalogTorrent] Ignoring tombstone: %v\n", err)
+			continue
+		}
+		if ct.applyTombstone(name, tombstone) {
+			changed = true
+		}
+	}
+	
+	now := time.Now()
+	latest := now.Add(maxClockSkew).Unix()
 	for name, entry := range other.Models {
-		if existing, exists := ct.catalog.Models[name]; !exists || entry.Added > existing.Added {
+		if entry.Expired(now) {
+			continue
+		}
+		// A peer dating its entry far ahead would keep it from ever being
+		// replaced by the publisher's renewals
+		if entry.Added > latest {
+			entry.Added = latest
+		}
+		if entry.Seen > latest {
+			entry.Seen = latest
+		}
+		if tombstone, exists := ct.catalog.Tombstones[name]; exists && ct.covers(tombstone, entry) {
+			continue
+		}
+		if existing, exists := ct.catalog.Models[name]; !exists || ct.replaces(entry, existing) {
 			ct.catalog.Models[name] = entry
 			changed = true
 			fmt.Printf("[CatalogTorrent] Merged model: %s\n", name)
@@ -423,6 +623,7 @@ func (ct *CatalogTorrent) MergeCatalog(other *ModelCatalog) bool {
 	}
 	
 	if changed {
+		ct.catalog.Sequence++
 		ct.catalog.Updated = time.Now().Unix()
 		ct.saveCatalog()
 	}
@@ -430,15 +631,317 @@ func (ct *CatalogTorrent) MergeCatalog(other *ModelCatalog) bool {
 	return changed
 }
 
+// Snapshot returns a copy of the current catalog
+func (ct *CatalogTorrent) Snapshot() *ModelCatalog {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	
+	snapshot := *ct.catalog
+	snapshot.Models = make(map[string]ModelEntry, len(ct.catalog.Models))
+	for name, entry := range ct.catalog.Models {
+		snapshot.Models[name] = entry
+	}
+	if len(ct.catalog.Tombstones) > 0 {
+		snapshot.Tombstones = make(map[string]Tombstone, len(ct.catalog.Tombstones))
+		for name, tombstone := range ct.catalog.Tombstones {
+			snapshot.Tombstones[name] = tombstone
+		}
+	}
+	if len(ct.catalog.Rotations) > 0 {
+		snapshot.Rotations = make(map[string]KeyRotation, len(ct.catalog.Rotations))
+		for old, rotation := range ct.catalog.Rotations {
+			snapshot.Rotations[old] = rotation
+		}
+	}
+	return &snapshot
+}
+
+// Digest returns a hash of the catalog contents, independent of sequence
+// numbers, so peers can cheaply tell whether their catalogs differ
+func (ct *CatalogTorrent) Digest() string {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	
+	return catalogDigest(ct.catalog)
+}
+
+// HasUnpublishedChanges reports whether the local catalog differs from the
+// catalog last published in the DHT, e.g. after merging a concurrent write
+func (ct *CatalogTorrent) HasUnpublishedChanges() bool {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	
+	return catalogDigest(ct.catalog) != ct.publishedDigest
+}
+
+// MarkPublished records that the current catalog torrent was published
+func (ct *CatalogTorrent) MarkPublished() {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	
+	ct.publishedDigest = ct.torrentDigest
+}
+
+// catalogDigest hashes the contents of a catalog
+func catalogDigest(catalog *ModelCatalog) string {
+	names := make([]string, 0, len(catalog.Models))
+	for name := range catalog.Models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	h := sha256.New()
+	for _, name := range names {
+		entry := catalog.Models[name]
+		fmt.Fprintf(h, "%s\x00%s\x00%d\n", name, entry.InfoHash, entry.LastSeen())
+	}
+	
+	tombstoned := make([]string, 0, len(catalog.Tombstones))
+	for name := range catalog.Tombstones {
+		tombstoned = append(tombstoned, name)
+	}
+	sort.Strings(tombstoned)
+	for _, name := range tombstoned {
+		tombstone := catalog.Tombstones[name]
+		fmt.Fprintf(h, "x\x00%s\x00%s\x00%d\n", name, tombstone.InfoHash, tombstone.Removed)
+	}
+	
+	rotated := make([]string, 0, len(catalog.Rotations))
+	for old := range catalog.Rotations {
+		rotated = append(rotated, old)
+	}
+	sort.Strings(rotated)
+	for _, old := range rotated {
+		fmt.Fprintf(h, "kr\x00%s\x00%s\n", old, catalog.Rotations[old].New)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ModelCount returns the number of models in the catalog
+func (ct *CatalogTorrent) ModelCount() int {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	
+	return len(ct.catalog.Models)
+}
+
+// FindModel returns the catalog entry of a model
+func (ct *CatalogTorrent) FindModel(name string) (ModelEntry, bool) {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	
+	entry, exists := ct.catalog.Models[name]
+	return entry, exists
+}
+
+// IsWithdrawn reports whether the publisher withdrew this version of a model
+func (ct *CatalogTorrent) IsWithdrawn(name, infoHash string) bool {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	
+	tombstone, exists := ct.catalog.Tombstones[name]
+	return exists && tombstone.InfoHash == infoHash
+}
+
+// ApplyTombstone withdraws a model from the catalog. The tombstone must be
+// signed by the publisher of the catalog entry.
+func (ct *CatalogTorrent) ApplyTombstone(name string, tombstone Tombstone) error {
+	if err := tombstone.Verify(name); err != nil {
+		return err
+	}
+	
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	
+	if entry, exists := ct.catalog.Models[name]; exists && entry.InfoHash == tombstone.InfoHash && !ct.covers(tombstone, entry) {
+		return fmt.Errorf("model %s was not published with this key", name)
+	}
+	
+	ct.applyTombstone(name, tombstone)
+	ct.catalog.Sequence++
+	ct.catalog.Updated = time.Now().Unix()
+	return ct.saveCatalog()
+}
+
+// applyTombstone stores a verified tombstone and removes the entry it covers.
+// Callers must hold ct.mu.
+func (ct *CatalogTorrent) applyTombstone(name string, tombstone Tombstone) bool {
+	if ct.catalog.Tombstones == nil {
+		ct.catalog.Tombstones = make(map[string]Tombstone)
+	}
+	ct.catalog.Tombstones[name] = tombstone
+	
+	if entry, exists := ct.catalog.Models[name]; exists && ct.covers(tombstone, entry) {
+		delete(ct.catalog.Models, name)
+		fmt.Printf("[CatalogTorrent] Removed withdrawn model: %s\n", name)
+	}
+	return true
+}
+
+// replaces reports whether a peer's entry replaces ours: it is newer and
+// from the same publisher, or from the key the publisher rotated to. Callers
+// must hold ct.mu.
+func (ct *CatalogTorrent) replaces(entry, existing ModelEntry) bool {
+	if entry.LastSeen() <= existing.LastSeen() {
+		return false
+	}
+	return entry.Publisher == existing.Publisher || rotatesTo(existing.Publisher, entry.Publisher, ct.catalog.Rotations)
+}
+
+// covers reports whether a tombstone removes a catalog entry, also when the
+// entry was published with a key that was rotated to the tombstone's key.
+// Callers must hold ct.mu.
+func (ct *CatalogTorrent) covers(tombstone Tombstone, entry ModelEntry) bool {
+	if tombstone.Covers(entry) {
+		return true
+	}
+	if !rotatesTo(entry.Publisher, tombstone.Publisher, ct.catalog.Rotations) {
+		return false
+	}
+	entry.Publisher = tombstone.Publisher
+	return tombstone.Covers(entry)
+}
+
+// AddRotation records that a publisher rotated its key, so entries of the
+// old key can be renewed and withdrawn with the new key
+func (ct *CatalogTorrent) AddRotation(rotation KeyRotation) error {
+	if err := rotation.Verify(); err != nil {
+		return err
+	}
+	
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	
+	if existing, exists := ct.catalog.Rotations[rotation.Old]; exists {
+		if existing.New == rotation.New {
+			return nil
+		}
+		return fmt.Errorf("key %s was already rotated to %s", rotation.Old, existing.New)
+	}
+	ct.addRotation(rotation)
+	ct.catalog.Sequence++
+	ct.catalog.Updated = time.Now().Unix()
+	return ct.saveCatalog()
+}
+
+// addRotation stores a verified key rotation. A key is only rotated once, so
+// a stolen old key can't redirect its entries again. Callers must hold ct.mu.
+func (ct *CatalogTorrent) addRotation(rotation KeyRotation) {
+	if ct.catalog.Rotations == nil {
+		ct.catalog.Rotations = make(map[string]KeyRotation)
+	}
+	ct.catalog.Rotations[rotation.Old] = rotation
+	fmt.Printf("[CatalogTorrent] Publisher key %s rotated to %s\n", rotation.Old, rotation.New)
+}
+
+// PublishedBy reports whether an entry was published with key, or with a
+// key that was rotated to it
+func (ct *CatalogTorrent) PublishedBy(entry ModelEntry, key string) bool {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	
+	return rotatesTo(entry.Publisher, key, ct.catalog.Rotations)
+}
+
+// RenewModels marks the entries of models that are still being shared as
+// seen. Entries are only renewed once per CatalogRenewInterval so the catalog
+// is not republished on every refresh. Returns the number of renewed entries.
+func (ct *CatalogTorrent) RenewModels(infoHashes map[string]bool) int {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	
+	now := time.Now()
+	renewed := 0
+	for name, entry := range ct.catalog.Models {
+		if !infoHashes[entry.InfoHash] {
+			continue
+		}
+		if now.Sub(time.Unix(entry.LastSeen(), 0)) < CatalogRenewInterval {
+			continue
+		}
+		entry.Seen = now.Unix()
+		ct.catalog.Models[name] = entry
+		renewed++
+	}
+	
+	if renewed > 0 {
+		ct.catalog.Sequence++
+		ct.catalog.Updated = now.Unix()
+		if err := ct.saveCatalog(); err != nil {
+			fmt.Printf("[CatalogTorrent] Warning: failed to save catalog: %v\n", err)
+		}
+	}
+	return renewed
+}
+
+// Prune removes entries that were not renewed within the TTL and tombstones
+// past their retention period. Returns the names of the removed models and
+// whether the catalog changed.
+func (ct *CatalogTorrent) Prune(now time.Time) ([]string, bool) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	
+	var removed []string
+	for name, entry := range ct.catalog.Models {
+		if entry.Expired(now) {
+			delete(ct.catalog.Models, name)
+			removed = append(removed, name)
+		}
+	}
+	
+	changed := len(removed) > 0
+	for name, tombstone := range ct.catalog.Tombstones {
+		if tombstone.Expired(now) {
+			delete(ct.catalog.Tombstones, name)
+			changed = true
+		}
+	}
+	for old, rotation := range ct.catalog.Rotations {
+		if rotation.Expired(now) {
+			delete(ct.catalog.Rotations, old)
+			changed = true
+		}
+	}
+	
+	if changed {
+		ct.catalog.Sequence++
+		ct.catalog.Updated = now.Unix()
+		if err := ct.saveCatalog(); err != nil {
+			fmt.Printf("[CatalogTorrent] Warning: failed to save catalog: %v\n", err)
+		}
+	}
+	sort.Strings(removed)
+	return removed, changed
+}
+
 // Helper functions
 
 func (ct *CatalogTorrent) loadCatalog() error {
+	cache := getCatalogCache()
 	data, err := os.ReadFile(ct.catalogFile)
-	if err != nil {
+	if err == nil {
+		err = json.Unmarshal(data, &ct.catalog)
+	}
+	if cache == nil {
 		return err
 	}
+	if err != nil {
+		// The file may be missing or torn by a crash, the cache is not
+		var cached ModelCatalog
+		found, cacheErr := cache.LoadCatalog(ct.catalogDir, &cached)
+		if cacheErr != nil || !found {
+			return err
+		}
+		ct.catalog = &cached
+		return nil
+	}
 	
-	return json.Unmarshal(data, &ct.catalog)
+	// Catalogs saved before there was a cache go into it
+	var cached ModelCatalog
+	if found, err := cache.LoadCatalog(ct.catalogDir, &cached); err == nil && !found {
+		return cache.SaveCatalog(ct.catalogDir, ct.catalog)
+	}
+	return nil
 }
 
 func (ct *CatalogTorrent) saveCatalog() error {
@@ -447,6 +950,11 @@ func (ct *CatalogTorrent) saveCatalog() error {
 		return err
 	}
 	
+	if cache := getCatalogCache(); cache != nil {
+		if err := cache.SaveCatalog(ct.catalogDir, ct.catalog); err != nil {
+			return err
+		}
+	}
 	return os.WriteFile(ct.catalogFile, data, 0644)
 }
 
@@ -3,10 +3,12 @@ package discovery
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/anacrolix/torrent"
+	"github.com/silmaril/silmaril/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -83,6 +85,25 @@ func TestCatalogTorrentAddModel(t *testing.T) {
 	assert.NotEmpty(t, model.Tags)
 }
 
+func TestCatalogTorrentProvenance(t *testing.T) {
+	ct, client, tmpDir := setupTestCatalogTorrent(t)
+	defer os.RemoveAll(tmpDir)
+	defer client.Close()
+
+	provenance := &types.Provenance{
+		UpstreamURL:      "https://huggingface.co/org/model",
+		UpstreamRevision: "0123456789abcdef",
+	}
+	_, err := ct.AddModelWithMetadata("org/model", "abc123", 1000, ModelMetadata{Provenance: provenance})
+	require.NoError(t, err)
+
+	// Discovered models say where their weights came from
+	models, err := ct.GetModels("org/model")
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	assert.Equal(t, provenance, models[0].Provenance)
+}
+
 func TestAddMultipleModels(t *testing.T) {
 	ct, client, tmpDir := setupTestCatalogTorrent(t)
 	defer os.RemoveAll(tmpDir)
@@ -207,6 +228,40 @@ func TestMergeCatalog(t *testing.T) {
 	assert.Equal(t, "hash3", ct.catalog.Models["model3"].InfoHash)
 }
 
+func TestMergeCatalogFromPeers(t *testing.T) {
+	ct, client, tmpDir := setupTestCatalogTorrent(t)
+	defer os.RemoveAll(tmpDir)
+	defer client.Close()
+
+	oldKey, publisher := newTestPublisher(t)
+	newKey, rotated := newTestPublisher(t)
+	_, other := newTestPublisher(t)
+	now := time.Now().Unix()
+	ct.catalog.Models = map[string]ModelEntry{
+		"org/model": {InfoHash: "hash1", Added: now - 1000, Publisher: publisher},
+	}
+
+	// Another publisher can't take over the entry, however new its entry is
+	assert.False(t, ct.MergeCatalog(&ModelCatalog{Models: map[string]ModelEntry{
+		"org/model": {InfoHash: "hash2", Added: now, Publisher: other},
+	}}))
+	assert.Equal(t, "hash1", ct.catalog.Models["org/model"].InfoHash)
+
+	// The key the publisher rotated to renews the entry
+	ct.catalog.Rotations = map[string]KeyRotation{publisher: NewKeyRotation(oldKey, newKey)}
+	assert.True(t, ct.MergeCatalog(&ModelCatalog{Models: map[string]ModelEntry{
+		"org/model": {InfoHash: "hash3", Added: now, Publisher: rotated},
+	}}))
+	assert.Equal(t, "hash3", ct.catalog.Models["org/model"].InfoHash)
+
+	// Entries dated in the future count as dated now
+	assert.True(t, ct.MergeCatalog(&ModelCatalog{Models: map[string]ModelEntry{
+		"org/model": {InfoHash: "hash4", Added: now, Seen: now + 365*24*3600, Publisher: rotated},
+	}}))
+	assert.LessOrEqual(t, ct.catalog.Models["org/model"].Seen, time.Now().Add(maxClockSkew).Unix())
+	assert.Equal(t, "hash4", ct.catalog.Models["org/model"].InfoHash)
+}
+
 func TestGetCatalogReference(t *testing.T) {
 	ct, client, tmpDir := setupTestCatalogTorrent(t)
 	defer os.RemoveAll(tmpDir)
@@ -332,4 +387,45 @@ func TestMatchesPattern(t *testing.T) {
 			assert.Equal(t, tt.expected, result)
 		})
 	}
-}
\ No newline at end of file
+}
+func TestCatalogUnpublishedChanges(t *testing.T) {
+	ct, client, tmpDir := setupTestCatalogTorrent(t)
+	defer os.RemoveAll(tmpDir)
+	defer client.Close()
+
+	ct.catalog.Models = make(map[string]ModelEntry)
+	_, err := ct.AddModel("org/model", strings.Repeat("a", 40), 1000)
+	require.NoError(t, err)
+	assert.True(t, ct.HasUnpublishedChanges())
+
+	ct.MarkPublished()
+	assert.False(t, ct.HasUnpublishedChanges())
+
+	// Merging a concurrent write leaves changes to publish
+	other := &ModelCatalog{Models: map[string]ModelEntry{
+		"org/other": {InfoHash: strings.Repeat("b", 40), Added: time.Now().Unix()},
+	}}
+	assert.True(t, ct.MergeCatalog(other))
+	assert.True(t, ct.HasUnpublishedChanges())
+
+	// A rebuilt and published catalog contains both writes
+	_, err = ct.Rebuild()
+	require.NoError(t, err)
+	ct.MarkPublished()
+	assert.False(t, ct.HasUnpublishedChanges())
+	assert.Equal(t, 2, ct.ModelCount())
+}
+
+func TestFetchAndMergeCatalogSkipsOwnCatalog(t *testing.T) {
+	ct, client, tmpDir := setupTestCatalogTorrent(t)
+	defer os.RemoveAll(tmpDir)
+	defer client.Close()
+
+	infoHash, err := ct.AddModel("org/model", strings.Repeat("a", 40), 1000)
+	require.NoError(t, err)
+
+	// Our own published catalog is not downloaded again
+	changed, err := ct.FetchAndMergeCatalog(infoHash)
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
@@ -2,6 +2,9 @@ package discovery
 
 import (
 	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/pkg/types"
 )
 
 const (
@@ -10,6 +13,25 @@ const (
 	
 	// Maximum size for BEP 44 value (1000 bytes)
 	MaxValueSize = 1000
+	
+	// Catalog entries that are not re-announced within this period are removed
+	CatalogEntryTTL = 7 * 24 * time.Hour
+	
+	// How often publishers renew the entries of models they still share
+	CatalogRenewInterval = 24 * time.Hour
+	
+	// How far in the future entries of peers may be dated, for clocks that
+	// are off. Later dates are taken as now plus this.
+	maxClockSkew = 10 * time.Minute
+	
+	// How long tombstones are kept so removals reach peers with stale catalogs
+	TombstoneRetention = CatalogEntryTTL
+	
+	// How often a catalog write is retried after merging a concurrent write
+	maxPublishAttempts = 5
+	
+	// Base delay between catalog write attempts, randomized per attempt
+	publishRetryDelay = 2 * time.Second
 )
 
 // ModelCatalog is the catalog of all discoverable models
@@ -18,14 +40,82 @@ type ModelCatalog struct {
 	Sequence int64                  `json:"seq"`
 	Updated  int64                  `json:"t"`
 	Models   map[string]ModelEntry  `json:"m"`
+	
+	// Models withdrawn by their publisher, by name
+	Tombstones map[string]Tombstone `json:"x,omitempty"`
+	
+	// Publisher keys replaced by a new key, by the old key
+	Rotations map[string]KeyRotation `json:"kr,omitempty"`
 }
 
 // ModelEntry is a single model in the catalog
 type ModelEntry struct {
-	InfoHash string   `json:"h"`
-	Size     int64    `json:"s,omitempty"`
-	Tags     []string `json:"t,omitempty"`
-	Added    int64    `json:"a"`
+	InfoHash    string   `json:"h"`
+	Size        int64    `json:"s,omitempty"`
+	Tags        []string `json:"t,omitempty"`
+	Description string   `json:"d,omitempty"`
+	License     string   `json:"l,omitempty"`
+	Parameters  int64    `json:"p,omitempty"`
+	Added       int64    `json:"a"`
+	Seen        int64    `json:"r,omitempty"` // Last time the publisher renewed the entry
+	Publisher   string   `json:"k,omitempty"` // Hex publisher key allowed to withdraw the entry
+	LicenseURL  string   `json:"lu,omitempty"`
+	RequireLicenseAcceptance bool `json:"la,omitempty"` // Downloaders must accept the license first
+	Provenance  *types.Provenance `json:"pv,omitempty"` // Where the weights came from
+	Equivalents []string `json:"eq,omitempty"` // Infohashes of torrents of the same files
+	Domain      string   `json:"dn,omitempty"` // Domain proving the publisher owns the namespace
+	DomainSignature string `json:"ds,omitempty"` // Publisher's signature of the name, infohash and domain
+}
+
+// LastSeen returns the last time the entry was announced or renewed
+func (e ModelEntry) LastSeen() int64 {
+	if e.Seen > e.Added {
+		return e.Seen
+	}
+	return e.Added
+}
+
+// Expired reports whether the entry was not renewed within the catalog TTL
+func (e ModelEntry) Expired(now time.Time) bool {
+	return now.Sub(time.Unix(e.LastSeen(), 0)) > CatalogEntryTTL
+}
+
+// ModelMetadata is optional searchable metadata published with a catalog entry
+type ModelMetadata struct {
+	Description string
+	Tags        []string
+	License     string
+	Parameters  int64
+	Publisher   string // Hex publisher key, lets the publisher withdraw the entry later
+	LicenseURL  string
+	RequireLicenseAcceptance bool
+	Provenance  *types.Provenance
+	Equivalents []string
+	Domain      string // Domain proving the publisher owns the namespace of the model
+	DomainSignature string // See SignNamespaceClaim
+}
+
+// buildEntryTags merges name-derived tags with model card tags
+func buildEntryTags(name string, extra []string) []string {
+	tags := extractTags(name)
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		seen[tag] = true
+	}
+	for _, tag := range extra {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// matchesEntry checks if a catalog entry matches a search pattern on its
+// name, description or tags
+func matchesEntry(name string, entry ModelEntry, pattern string) bool {
+	return types.MatchesSearch(pattern, name, entry.Description, entry.Tags)
 }
 
 // extractTags extracts searchable tags from a model name
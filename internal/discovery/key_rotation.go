@@ -0,0 +1,95 @@
+package discovery
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Rotations are kept in the catalog until entries and tombstones of the old
+// key are gone from it
+const RotationRetention = CatalogEntryTTL + TombstoneRetention
+
+// KeyRotation records that a publisher replaced its key. The old key signs
+// it, vouching for the new key, and the new key signs it, proving it is
+// held. Subscribers of the old key follow it to the new one, and catalog
+// entries of the old key may be renewed and withdrawn with the new one.
+type KeyRotation struct {
+	Old     string `json:"o"` // Hex encoded ed25519 public keys
+	New     string `json:"n"`
+	Rotated int64  `json:"a"`
+	OldSig  string `json:"so"`
+	NewSig  string `json:"sn"`
+}
+
+// NewKeyRotation creates a rotation from oldKey to newKey signed with both
+func NewKeyRotation(oldKey, newKey ed25519.PrivateKey) KeyRotation {
+	r := KeyRotation{
+		Old:     hex.EncodeToString(oldKey.Public().(ed25519.PublicKey)),
+		New:     hex.EncodeToString(newKey.Public().(ed25519.PublicKey)),
+		Rotated: time.Now().Unix(),
+	}
+	r.OldSig = hex.EncodeToString(ed25519.Sign(oldKey, r.message()))
+	r.NewSig = hex.EncodeToString(ed25519.Sign(newKey, r.message()))
+	return r
+}
+
+// Verify checks both signatures of the rotation
+func (r KeyRotation) Verify() error {
+	if r.Old == r.New {
+		return fmt.Errorf("key rotation to the same key %s", r.Old)
+	}
+	for _, key := range []struct{ publicKey, signature string }{{r.Old, r.OldSig}, {r.New, r.NewSig}} {
+		publicKey, err := hex.DecodeString(key.publicKey)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid key in rotation: %q", key.publicKey)
+		}
+		signature, err := hex.DecodeString(key.signature)
+		if err != nil {
+			return fmt.Errorf("invalid key rotation signature: %w", err)
+		}
+		if !ed25519.Verify(publicKey, r.message(), signature) {
+			return fmt.Errorf("key rotation signature mismatch for %s", key.publicKey)
+		}
+	}
+	return nil
+}
+
+// Expired reports whether the rotation is past its retention in the catalog
+func (r KeyRotation) Expired(now time.Time) bool {
+	return now.Sub(time.Unix(r.Rotated, 0)) > RotationRetention
+}
+
+func (r KeyRotation) message() []byte {
+	return []byte(fmt.Sprintf("silmaril-rotate\x00%s\x00%s\x00%d", r.Old, r.New, r.Rotated))
+}
+
+// RotatedKeys returns key followed by the keys it was rotated to, in order.
+// rotations are verified rotations by their old key.
+func RotatedKeys(key string, rotations map[string]KeyRotation) []string {
+	keys := []string{key}
+	seen := map[string]bool{key: true}
+	for {
+		r, ok := rotations[key]
+		if !ok || seen[r.New] {
+			return keys
+		}
+		key = r.New
+		seen[key] = true
+		keys = append(keys, key)
+	}
+}
+
+// rotatesTo reports whether from is to, or was rotated to it
+func rotatesTo(from, to string, rotations map[string]KeyRotation) bool {
+	if from == "" || to == "" {
+		return false
+	}
+	for _, key := range RotatedKeys(from, rotations) {
+		if key == to {
+			return true
+		}
+	}
+	return false
+}
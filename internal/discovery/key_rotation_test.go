@@ -0,0 +1,112 @@
+package discovery
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRotationVerify(t *testing.T) {
+	oldKey, oldPublisher := newTestPublisher(t)
+	newKey, newPublisher := newTestPublisher(t)
+
+	rotation := NewKeyRotation(oldKey, newKey)
+	assert.Equal(t, oldPublisher, rotation.Old)
+	assert.Equal(t, newPublisher, rotation.New)
+	require.NoError(t, rotation.Verify())
+
+	// Both keys must sign it
+	otherKey, otherPublisher := newTestPublisher(t)
+	forged := NewKeyRotation(otherKey, newKey)
+	forged.Old = oldPublisher
+	assert.Error(t, forged.Verify())
+	redirected := rotation
+	redirected.New = otherPublisher
+	assert.Error(t, redirected.Verify())
+
+	assert.Error(t, NewKeyRotation(oldKey, oldKey).Verify())
+
+	assert.False(t, rotation.Expired(time.Now()))
+	assert.True(t, rotation.Expired(time.Now().Add(RotationRetention+time.Hour)))
+}
+
+func TestRotatedKeys(t *testing.T) {
+	a, keyA := newTestPublisher(t)
+	b, keyB := newTestPublisher(t)
+	c, keyC := newTestPublisher(t)
+	rotations := map[string]KeyRotation{
+		keyA: NewKeyRotation(a, b),
+		keyB: NewKeyRotation(b, c),
+	}
+
+	assert.Equal(t, []string{keyA, keyB, keyC}, RotatedKeys(keyA, rotations))
+	assert.Equal(t, []string{keyC}, RotatedKeys(keyC, rotations))
+	assert.True(t, rotatesTo(keyA, keyC, rotations))
+	assert.False(t, rotatesTo(keyC, keyA, rotations))
+
+	// Cycles end the chain
+	rotations[keyC] = NewKeyRotation(c, a)
+	assert.Equal(t, []string{keyA, keyB, keyC}, RotatedKeys(keyA, rotations))
+}
+
+func TestCatalogKeyRotation(t *testing.T) {
+	ct, client, tmpDir := setupTestCatalogTorrent(t)
+	defer os.RemoveAll(tmpDir)
+	defer client.Close()
+
+	oldKey, oldPublisher := newTestPublisher(t)
+	newKey, newPublisher := newTestPublisher(t)
+	infoHash := strings.Repeat("a", 40)
+	ct.catalog.Models = map[string]ModelEntry{
+		"org/model": {InfoHash: infoHash, Added: time.Now().Unix() - 10, Publisher: oldPublisher},
+	}
+	entry, _ := ct.FindModel("org/model")
+
+	// Before the rotation the new key can't withdraw entries of the old one
+	assert.False(t, ct.PublishedBy(entry, newPublisher))
+	assert.Error(t, ct.ApplyTombstone("org/model", NewTombstone("org/model", infoHash, newKey)))
+
+	rotation := NewKeyRotation(oldKey, newKey)
+	require.NoError(t, ct.AddRotation(rotation))
+	assert.True(t, ct.PublishedBy(entry, newPublisher))
+	assert.Contains(t, ct.Snapshot().Rotations, oldPublisher)
+
+	// A key is only rotated once
+	otherKey, _ := newTestPublisher(t)
+	require.NoError(t, ct.AddRotation(rotation))
+	assert.Error(t, ct.AddRotation(NewKeyRotation(oldKey, otherKey)))
+
+	// The new key takes over the entry, keeping its metadata
+	ct.catalog.Models["org/model"] = ModelEntry{InfoHash: infoHash, Added: entry.Added, Description: "A model", Publisher: oldPublisher}
+	_, err := ct.AddModelWithMetadata("org/model", infoHash, 0, ModelMetadata{Publisher: newPublisher})
+	require.NoError(t, err)
+	entry, _ = ct.FindModel("org/model")
+	assert.Equal(t, newPublisher, entry.Publisher)
+	assert.Equal(t, "A model", entry.Description)
+
+	require.NoError(t, ct.ApplyTombstone("org/model", NewTombstone("org/model", infoHash, newKey)))
+	assert.Empty(t, ct.catalog.Models)
+}
+
+func TestMergeCatalogRotations(t *testing.T) {
+	ct, client, tmpDir := setupTestCatalogTorrent(t)
+	defer os.RemoveAll(tmpDir)
+	defer client.Close()
+
+	oldKey, oldPublisher := newTestPublisher(t)
+	newKey, newPublisher := newTestPublisher(t)
+
+	// Forged rotations and rotations filed under another key are ignored
+	forged := NewKeyRotation(oldKey, newKey)
+	forged.NewSig = forged.OldSig
+	ct.MergeCatalog(&ModelCatalog{Rotations: map[string]KeyRotation{oldPublisher: forged}})
+	ct.MergeCatalog(&ModelCatalog{Rotations: map[string]KeyRotation{newPublisher: NewKeyRotation(oldKey, newKey)}})
+	assert.Empty(t, ct.catalog.Rotations)
+
+	assert.True(t, ct.MergeCatalog(&ModelCatalog{Rotations: map[string]KeyRotation{oldPublisher: NewKeyRotation(oldKey, newKey)}}))
+	assert.Equal(t, newPublisher, ct.catalog.Rotations[oldPublisher].New)
+}
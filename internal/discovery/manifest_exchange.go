@@ -0,0 +1,199 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	pp "github.com/anacrolix/torrent/peer_protocol"
+)
+
+const (
+	// BEP 10 extension name used by Silmaril peers to exchange model manifests
+	ManifestExchangeExtension pp.ExtensionName = "silmaril_manifest"
+
+	// Maximum size of a manifest message, larger manifests are not exchanged
+	maxManifestPayload = 1 << 20
+)
+
+// Manifest exchange message types
+const (
+	manifestRequest = "request"  // Ask the peer for the manifest of the torrent
+	manifestReply   = "manifest" // The manifest of the torrent
+)
+
+// manifestMessage is the JSON payload of a manifest exchange extension message
+type manifestMessage struct {
+	Type     string          `json:"type"`
+	Manifest json.RawMessage `json:"manifest,omitempty"`
+}
+
+// ManifestStore provides and accepts the manifests of torrents by infohash
+type ManifestStore interface {
+	// Manifest returns the manifest of a torrent to send to peers, false if
+	// we don't have one
+	Manifest(infoHash string) ([]byte, bool)
+	// WantsManifest reports whether a torrent still lacks its manifest
+	WantsManifest(infoHash string) bool
+	// ReceiveManifest validates and keeps the manifest a peer sent for a torrent
+	ReceiveManifest(infoHash string, manifest []byte) error
+}
+
+// ManifestExchange sends the manifest of a model to the peers downloading it
+// over a BitTorrent extension, so models fetched by infohash get their
+// license, inference hints and signature along with their files
+type ManifestExchange struct {
+	mu    sync.Mutex
+	store ManifestStore
+
+	// Serializes received manifests, so only the first one is kept
+	receiveMu sync.Mutex
+}
+
+// NewManifestExchange creates a manifest exchange handler. Register must be
+// called with the torrent client config before the client is created.
+func NewManifestExchange() *ManifestExchange {
+	return &ManifestExchange{}
+}
+
+// Register adds the manifest extension and its callbacks to a torrent client config
+func (e *ManifestExchange) Register(cfg *torrent.ClientConfig) {
+	cfg.Callbacks.PeerConnAdded = append(cfg.Callbacks.PeerConnAdded, e.onPeerConnAdded)
+	cfg.Callbacks.PeerConnReadExtensionMessage = append(cfg.Callbacks.PeerConnReadExtensionMessage, e.onExtensionMessage)
+
+	prevHandshake := cfg.Callbacks.ReadExtendedHandshake
+	cfg.Callbacks.ReadExtendedHandshake = func(pc *torrent.PeerConn, msg *pp.ExtendedHandshakeMessage) {
+		if prevHandshake != nil {
+			prevHandshake(pc, msg)
+		}
+		e.onExtendedHandshake(pc, msg)
+	}
+}
+
+// SetStore sets where manifests are read from and written to
+func (e *ManifestExchange) SetStore(store ManifestStore) {
+	e.mu.Lock()
+	e.store = store
+	e.mu.Unlock()
+}
+
+func (e *ManifestExchange) getStore() ManifestStore {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.store
+}
+
+// RequestManifest asks every connected peer of t for its manifest. Peers are
+// asked on connecting, this covers torrents whose metadata only arrived later.
+func (e *ManifestExchange) RequestManifest(t *torrent.Torrent) {
+	payload, err := json.Marshal(manifestMessage{Type: manifestRequest})
+	if err != nil {
+		return
+	}
+	for _, pc := range t.PeerConns() {
+		// Fails for peers without the extension, which have nothing to send
+		_ = pc.WriteExtendedMessage(ManifestExchangeExtension, payload)
+	}
+}
+
+// onPeerConnAdded advertises the manifest extension in our extended handshake
+func (e *ManifestExchange) onPeerConnAdded(pc *torrent.PeerConn) {
+	// The map may be the client default, copy it before adding our protocol
+	protocols := *pc.LocalLtepProtocolMap
+	protocols.Index = slices.Clone(protocols.Index)
+	protocols.AddUserProtocol(ManifestExchangeExtension)
+	pc.LocalLtepProtocolMap = &protocols
+}
+
+// onExtendedHandshake asks peers supporting the extension for the manifest
+// of a torrent we don't have the manifest of
+func (e *ManifestExchange) onExtendedHandshake(pc *torrent.PeerConn, msg *pp.ExtendedHandshakeMessage) {
+	if _, ok := msg.M[ManifestExchangeExtension]; !ok {
+		return
+	}
+
+	// Callbacks run with client locks held, so request asynchronously
+	go func() {
+		time.Sleep(gossipHandshakeDelay)
+		store := e.getStore()
+		t := pc.Torrent()
+		if store == nil || t == nil {
+			return
+		}
+		if store.WantsManifest(t.InfoHash().HexString()) {
+			e.send(pc, manifestMessage{Type: manifestRequest})
+		}
+	}()
+}
+
+// onExtensionMessage dispatches manifest extension messages
+func (e *ManifestExchange) onExtensionMessage(event torrent.PeerConnReadExtensionMessageEvent) {
+	name, _, err := event.PeerConn.LocalLtepProtocolMap.LookupId(event.ExtensionNumber)
+	if err != nil || name != ManifestExchangeExtension {
+		return
+	}
+	if len(event.Payload) > maxManifestPayload {
+		fmt.Printf("[ManifestExchange] Ignoring oversized message from %s (%d bytes)\n", event.PeerConn.RemoteAddr, len(event.Payload))
+		return
+	}
+
+	// The payload is only valid during the callback
+	payload := slices.Clone(event.Payload)
+	go e.handleMessage(event.PeerConn, payload)
+}
+
+func (e *ManifestExchange) handleMessage(pc *torrent.PeerConn, payload []byte) {
+	var msg manifestMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		fmt.Printf("[ManifestExchange] Invalid message from %s: %v\n", pc.RemoteAddr, err)
+		return
+	}
+
+	store := e.getStore()
+	t := pc.Torrent()
+	if store == nil || t == nil {
+		return
+	}
+	infoHash := t.InfoHash().HexString()
+
+	switch msg.Type {
+	case manifestRequest:
+		if manifest, ok := store.Manifest(infoHash); ok {
+			e.send(pc, manifestMessage{Type: manifestReply, Manifest: manifest})
+		}
+	case manifestReply:
+		if len(msg.Manifest) == 0 {
+			return
+		}
+		e.receiveMu.Lock()
+		defer e.receiveMu.Unlock()
+
+		// Several peers may answer our requests, keep the first valid manifest
+		if !store.WantsManifest(infoHash) {
+			return
+		}
+		if err := store.ReceiveManifest(infoHash, msg.Manifest); err != nil {
+			fmt.Printf("[ManifestExchange] Rejected manifest from %s: %v\n", pc.RemoteAddr, err)
+			return
+		}
+		fmt.Printf("[ManifestExchange] Received manifest of %s from %s\n", infoHash, pc.RemoteAddr)
+	}
+}
+
+func (e *ManifestExchange) send(pc *torrent.PeerConn, msg manifestMessage) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		fmt.Printf("[ManifestExchange] Failed to encode %s message: %v\n", msg.Type, err)
+		return
+	}
+	if len(payload) > maxManifestPayload {
+		fmt.Printf("[ManifestExchange] Manifest too large to send (%d bytes)\n", len(payload))
+		return
+	}
+	if err := pc.WriteExtendedMessage(ManifestExchangeExtension, payload); err != nil {
+		fmt.Printf("[ManifestExchange] Failed to send %s to %s: %v\n", msg.Type, pc.RemoteAddr, err)
+	}
+}
@@ -0,0 +1,100 @@
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testManifestStore holds manifests in memory
+type testManifestStore struct {
+	mu        sync.Mutex
+	manifests map[string][]byte
+}
+
+func (s *testManifestStore) Manifest(infoHash string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	manifest, ok := s.manifests[infoHash]
+	return manifest, ok
+}
+
+func (s *testManifestStore) WantsManifest(infoHash string) bool {
+	_, ok := s.Manifest(infoHash)
+	return !ok
+}
+
+func (s *testManifestStore) ReceiveManifest(infoHash string, manifest []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.manifests[infoHash] = manifest
+	return nil
+}
+
+func TestManifestExchangeRegister(t *testing.T) {
+	exchange := NewManifestExchange()
+
+	cfg := torrent.NewDefaultClientConfig()
+	exchange.Register(cfg)
+
+	assert.Len(t, cfg.Callbacks.PeerConnAdded, 1)
+	assert.Len(t, cfg.Callbacks.PeerConnReadExtensionMessage, 1)
+	require.NotNil(t, cfg.Callbacks.ReadExtendedHandshake)
+}
+
+func TestManifestExchangeBetweenPeers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping peer test in short mode")
+	}
+
+	newClient := func(dataDir string, store ManifestStore) *torrent.Client {
+		cfg := torrent.NewDefaultClientConfig()
+		cfg.DataDir = dataDir
+		cfg.ListenHost = torrent.LoopbackListenHost
+		cfg.ListenPort = 0
+		cfg.NoDHT = true
+		cfg.DisableTrackers = true
+		cfg.Seed = true
+		exchange := NewManifestExchange()
+		exchange.Register(cfg)
+		exchange.SetStore(store)
+		client, err := torrent.NewClient(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+
+	// Peers only accept connections for torrents with data to share
+	dataDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "model.bin"), []byte("weights"), 0644))
+	info := metainfo.Info{PieceLength: 16384}
+	require.NoError(t, info.BuildFromFilePath(filepath.Join(dataDir, "model.bin")))
+	infoBytes, err := bencode.Marshal(info)
+	require.NoError(t, err)
+	infoHash := metainfo.HashBytes(infoBytes)
+
+	manifest := []byte(`{"name":"org/model","license":"mit"}`)
+	seederStore := &testManifestStore{manifests: map[string][]byte{infoHash.HexString(): manifest}}
+	leecherStore := &testManifestStore{manifests: map[string][]byte{}}
+	seeder := newClient(dataDir, seederStore)
+	leecher := newClient(t.TempDir(), leecherStore)
+
+	_, _, err = seeder.AddTorrentSpec(&torrent.TorrentSpec{InfoHash: infoHash, InfoBytes: infoBytes})
+	require.NoError(t, err)
+	lt, _, err := leecher.AddTorrentSpec(&torrent.TorrentSpec{InfoHash: infoHash})
+	require.NoError(t, err)
+	lt.AddClientPeer(seeder)
+
+	assert.Eventually(t, func() bool {
+		received, ok := leecherStore.Manifest(infoHash.HexString())
+		return ok && string(received) == string(manifest)
+	}, 10*time.Second, 100*time.Millisecond)
+}
@@ -0,0 +1,158 @@
+package discovery
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// A publisher proves it owns a namespace, the organization part of model
+// names like mistralai/*, by listing its publisher key for the namespace at a
+// domain it controls. Catalog entries name the domain, nodes check it before
+// marking the publisher verified. The proof is either DNS TXT records at
+// _silmaril.<domain>:
+//
+//	v=silmaril1 key=<publisher key> ns=mistralai,mistral-community
+//
+// or a JSON document at https://<domain>/.well-known/silmaril:
+//
+//	{"publishers": [{"key": "<publisher key>", "namespaces": ["mistralai"]}]}
+//
+// Catalog entries are written by anyone, so an entry naming a domain carries
+// the publisher's signature of the model name, infohash and domain. Without
+// it the key and domain of a real publisher could be copied onto any torrent.
+const (
+	// Prefix of the name holding the TXT records of a domain
+	NamespaceTXTPrefix = "_silmaril."
+
+	// Path of the proof on the web server of a domain
+	NamespaceWellKnownPath = "/.well-known/silmaril"
+
+	// Version tag of TXT records, records without it are ignored
+	namespaceTXTVersion = "v=silmaril1"
+
+	// Longest domain name
+	maxDomainLength = 253
+)
+
+// NamespaceProof is the publisher keys a domain lists for each namespace
+type NamespaceProof map[string][]string
+
+// Proves reports whether the proof lists publisher for namespace. Keys and
+// namespaces are compared case-insensitively.
+func (p NamespaceProof) Proves(namespace, publisher string) bool {
+	for _, key := range p[strings.ToLower(namespace)] {
+		if strings.EqualFold(key, publisher) {
+			return true
+		}
+	}
+	return false
+}
+
+// add lists key for namespace
+func (p NamespaceProof) add(namespace, key string) {
+	namespace, key = strings.ToLower(strings.TrimSpace(namespace)), strings.ToLower(strings.TrimSpace(key))
+	if namespace == "" || key == "" {
+		return
+	}
+	p[namespace] = append(p[namespace], key)
+}
+
+// wellKnownProof is the document at NamespaceWellKnownPath
+type wellKnownProof struct {
+	Publishers []struct {
+		Key        string   `json:"key"`
+		Namespaces []string `json:"namespaces"`
+	} `json:"publishers"`
+}
+
+// ParseNamespaceTXT reads the proof in the TXT records of a domain, records
+// of other services are skipped
+func ParseNamespaceTXT(records []string) NamespaceProof {
+	proof := make(NamespaceProof)
+	for _, record := range records {
+		fields := strings.Fields(record)
+		if len(fields) == 0 || fields[0] != namespaceTXTVersion {
+			continue
+		}
+		var key string
+		var namespaces []string
+		for _, field := range fields[1:] {
+			name, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			switch name {
+			case "key":
+				key = value
+			case "ns":
+				namespaces = strings.Split(value, ",")
+			}
+		}
+		for _, namespace := range namespaces {
+			proof.add(namespace, key)
+		}
+	}
+	return proof
+}
+
+// ParseNamespaceWellKnown reads the proof served at NamespaceWellKnownPath
+func ParseNamespaceWellKnown(data []byte) (NamespaceProof, error) {
+	var doc wellKnownProof
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid namespace proof: %w", err)
+	}
+	proof := make(NamespaceProof)
+	for _, publisher := range doc.Publishers {
+		for _, namespace := range publisher.Namespaces {
+			proof.add(namespace, publisher.Key)
+		}
+	}
+	return proof, nil
+}
+
+// namespaceClaimMessage is what a publisher signs to name the domain proving
+// it owns the namespace of a model
+func namespaceClaimMessage(name, infoHash, domain string) []byte {
+	return []byte("silmaril-namespace-claim\x00" + name + "\x00" + strings.ToLower(infoHash) + "\x00" + strings.ToLower(domain))
+}
+
+// SignNamespaceClaim returns the hex signature of key naming domain for the
+// model name with infoHash, "" without a key or domain
+func SignNamespaceClaim(key ed25519.PrivateKey, name, infoHash, domain string) string {
+	if key == nil || domain == "" {
+		return ""
+	}
+	return hex.EncodeToString(ed25519.Sign(key, namespaceClaimMessage(name, infoHash, domain)))
+}
+
+// VerifyNamespaceClaim reports whether signature is the signature of the
+// hex publisher key naming domain for the model name with infoHash
+func VerifyNamespaceClaim(publisher, signature, name, infoHash, domain string) bool {
+	publicKey, err := hex.DecodeString(publisher)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(publicKey, namespaceClaimMessage(name, infoHash, domain), sig)
+}
+
+// NamespaceTXTRecord returns the TXT record proving publisher owns namespaces
+func NamespaceTXTRecord(publisher string, namespaces ...string) string {
+	return fmt.Sprintf("%s key=%s ns=%s", namespaceTXTVersion, publisher, strings.Join(namespaces, ","))
+}
+
+// ModelNamespace returns the namespace of a model name, the part before the
+// first slash, or "" for names without one
+func ModelNamespace(name string) string {
+	namespace, _, ok := strings.Cut(name, "/")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(namespace)
+}
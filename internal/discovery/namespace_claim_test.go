@@ -0,0 +1,69 @@
+package discovery
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNamespaceTXT(t *testing.T) {
+	key := strings.Repeat("ab", 32)
+	proof := ParseNamespaceTXT([]string{
+		"google-site-verification=xyz",
+		NamespaceTXTRecord(key, "mistralai", "Mistral-Community"),
+		"v=silmaril1 ns=nokey",
+		"v=silmaril2 key=" + key + " ns=future",
+	})
+
+	assert.True(t, proof.Proves("mistralai", key))
+	assert.True(t, proof.Proves("MistralAI", strings.ToUpper(key)))
+	assert.True(t, proof.Proves("mistral-community", key))
+	assert.False(t, proof.Proves("mistralai", strings.Repeat("cd", 32)))
+	assert.False(t, proof.Proves("nokey", key))
+	assert.False(t, proof.Proves("future", key))
+}
+
+func TestParseNamespaceWellKnown(t *testing.T) {
+	key := strings.Repeat("ab", 32)
+	proof, err := ParseNamespaceWellKnown([]byte(`{"publishers": [
+		{"key": "` + key + `", "namespaces": ["mistralai"]},
+		{"key": "", "namespaces": ["empty"]}
+	]}`))
+	require.NoError(t, err)
+	assert.True(t, proof.Proves("mistralai", key))
+	assert.False(t, proof.Proves("empty", ""))
+
+	_, err = ParseNamespaceWellKnown([]byte("<html>"))
+	assert.Error(t, err)
+}
+
+func TestModelNamespace(t *testing.T) {
+	assert.Equal(t, "mistralai", ModelNamespace("MistralAI/Mistral-7B"))
+	assert.Equal(t, "org", ModelNamespace("org/model/variant"))
+	assert.Equal(t, "", ModelNamespace("model"))
+}
+
+func TestNamespaceClaim(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key := hex.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+	infoHash := strings.Repeat("ab", 20)
+
+	signature := SignNamespaceClaim(privateKey, "mistralai/Mistral-7B", infoHash, "mistral.ai")
+	assert.True(t, VerifyNamespaceClaim(key, signature, "mistralai/Mistral-7B", strings.ToUpper(infoHash), "Mistral.AI"))
+
+	// The claim only holds for the model, torrent and domain it was signed for
+	assert.False(t, VerifyNamespaceClaim(key, signature, "mistralai/Impostor", infoHash, "mistral.ai"))
+	assert.False(t, VerifyNamespaceClaim(key, signature, "mistralai/Mistral-7B", strings.Repeat("cd", 20), "mistral.ai"))
+	assert.False(t, VerifyNamespaceClaim(key, signature, "mistralai/Mistral-7B", infoHash, "evil.example"))
+	assert.False(t, VerifyNamespaceClaim(strings.Repeat("ab", 32), signature, "mistralai/Mistral-7B", infoHash, "mistral.ai"))
+	assert.False(t, VerifyNamespaceClaim(key, "", "mistralai/Mistral-7B", infoHash, "mistral.ai"))
+
+	assert.Empty(t, SignNamespaceClaim(privateKey, "model", infoHash, ""))
+	assert.Empty(t, SignNamespaceClaim(nil, "model", infoHash, "mistral.ai"))
+}
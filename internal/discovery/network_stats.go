@@ -0,0 +1,263 @@
+package discovery
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/dht/v2"
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/dht/v2/exts/getput"
+	"github.com/anacrolix/torrent/bencode"
+)
+
+const (
+	// Well-known seed of the key anyone signs network stats with
+	StatsSeed = "silmaril-stats-v1"
+
+	// BEP44 slots the reports of a day are spread over. A slot holds about
+	// 25 reports, so this bounds the nodes counted per day.
+	StatsSlots = 32
+
+	// Slots tried before giving up on publishing a report
+	maxStatsPublishAttempts = 4
+
+	// Slots read at once
+	statsReadConcurrency = 8
+)
+
+// NetworkReport is what one node reports for one day. It holds nothing that
+// identifies the node: the nonce only tells reports of the same node on the
+// same day apart from the others, so republishing doesn't count it twice.
+type NetworkReport struct {
+	Nonce      string `json:"n"`
+	Models     int    `json:"m"` // Models seeded
+	UploadedMB int64  `json:"u"` // Uploaded in total, in MiB
+}
+
+// statsSlot is the value stored in one slot
+type statsSlot struct {
+	Reports []NetworkReport `json:"r"`
+}
+
+// NetworkStats sums the reports published for a day
+type NetworkStats struct {
+	Day          string `json:"day"` // 2006-01-02 in UTC
+	Nodes        int    `json:"nodes"`
+	ModelsSeeded int    `json:"models_seeded"`
+	UploadedMB   int64  `json:"uploaded_mb"`
+	SlotsRead    int    `json:"slots_read"` // Slots that answered, empty ones included
+	SlotsFound   int    `json:"slots_found"`
+}
+
+// StatsCatalog publishes and reads the daily network stats. The signing key
+// is derived from StatsSeed like the discovery catalog's, so every node can
+// write the slots.
+type StatsCatalog struct {
+	server     *dht.Server
+	privateKey ed25519.PrivateKey
+	publicKey  [32]byte
+}
+
+// NewStatsCatalog creates the stats catalog on a DHT server
+func NewStatsCatalog(server *dht.Server) *StatsCatalog {
+	seed := sha256.Sum256([]byte(StatsSeed))
+	privateKey := ed25519.NewKeyFromSeed(seed[:])
+
+	var publicKey [32]byte
+	copy(publicKey[:], privateKey.Public().(ed25519.PublicKey))
+
+	return &StatsCatalog{server: server, privateKey: privateKey, publicKey: publicKey}
+}
+
+// StatsDay returns the day t falls on in UTC, as used for the slots
+func StatsDay(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// statsSalt returns the BEP44 salt of a slot of a day
+func statsSalt(day string, slot int) []byte {
+	return []byte(fmt.Sprintf("stats/%s/%02d", day, slot))
+}
+
+// Publish adds report to a random slot of day, replacing the earlier report
+// with the same nonce. A slot that is full or was written concurrently is
+// left for another one.
+func (sc *StatsCatalog) Publish(ctx context.Context, day string, report NetworkReport) error {
+	slots := rand.Perm(StatsSlots)
+	var lastErr error
+	for _, slot := range slots[:maxStatsPublishAttempts] {
+		err := sc.publishSlot(ctx, day, slot, report)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		lastErr = err
+	}
+	return fmt.Errorf("failed to publish network stats: %w", lastErr)
+}
+
+// publishSlot adds report to one slot and checks that it was stored
+func (sc *StatsCatalog) publishSlot(ctx context.Context, day string, slot int, report NetworkReport) error {
+	salt := statsSalt(day, slot)
+	current, seq, err := sc.getSlot(ctx, salt)
+	if err != nil {
+		return err
+	}
+	merged, ok := mergeReport(current, report)
+	if !ok {
+		return fmt.Errorf("slot %d is full", slot)
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("failed to serialize network stats: %w", err)
+	}
+
+	target := bep44.MakeMutableTarget(sc.publicKey, salt)
+	_, err = getput.Put(ctx, target, sc.server, salt, func(int64) bep44.Put {
+		// The observed sequence is the CAS value, so a concurrent write
+		// isn't overwritten
+		item, err := bep44.NewItem(string(data), salt, seq+1, seq, sc.privateKey)
+		if err != nil {
+			return bep44.Put{}
+		}
+		return item.ToPut()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store slot %d: %w", slot, err)
+	}
+
+	stored, _, err := sc.getSlot(ctx, salt)
+	if err != nil {
+		return err
+	}
+	for _, r := range stored.Reports {
+		if r == report {
+			return nil
+		}
+	}
+	return fmt.Errorf("slot %d was written concurrently", slot)
+}
+
+// getSlot returns the value of a slot and its sequence number, an empty
+// slot with sequence 0 if none was published
+func (sc *StatsCatalog) getSlot(ctx context.Context, salt []byte) (statsSlot, int64, error) {
+	target := bep44.MakeMutableTarget(sc.publicKey, salt)
+	result, _, err := getput.Get(ctx, target, sc.server, nil, salt)
+	if err != nil {
+		if ctx.Err() == nil {
+			// Nothing published yet
+			return statsSlot{}, 0, nil
+		}
+		return statsSlot{}, 0, err
+	}
+	slot, err := decodeSlot(result.V)
+	if err != nil {
+		// Garbage written by someone else is replaced
+		return statsSlot{}, result.Seq, nil
+	}
+	return slot, result.Seq, nil
+}
+
+// Read sums the reports published for day. Slots are read concurrently; the
+// ones that don't answer before ctx is done are left out.
+func (sc *StatsCatalog) Read(ctx context.Context, day string) (NetworkStats, error) {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		slots []statsSlot
+		read  int
+	)
+	sem := make(chan struct{}, statsReadConcurrency)
+	for i := 0; i < StatsSlots; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+			slot, _, err := sc.getSlot(ctx, statsSalt(day, i))
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			read++
+			if len(slot.Reports) > 0 {
+				slots = append(slots, slot)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if read == 0 && ctx.Err() != nil {
+		return NetworkStats{}, errors.New("no stats slot answered in time")
+	}
+	stats := sumSlots(day, slots)
+	stats.SlotsRead = read
+	return stats, nil
+}
+
+// mergeReport adds report to slot, replacing one with the same nonce. It
+// fails if the slot would no longer fit in a BEP44 value.
+func mergeReport(slot statsSlot, report NetworkReport) (statsSlot, bool) {
+	merged := statsSlot{Reports: make([]NetworkReport, 0, len(slot.Reports)+1)}
+	for _, r := range slot.Reports {
+		if r.Nonce != report.Nonce {
+			merged.Reports = append(merged.Reports, r)
+		}
+	}
+	merged.Reports = append(merged.Reports, report)
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return slot, false
+	}
+	// Leave room for the bencode length prefix
+	if len(data)+5 > MaxValueSize {
+		return slot, false
+	}
+	return merged, true
+}
+
+// decodeSlot parses a slot value, a bencoded string holding JSON
+func decodeSlot(v bencode.Bytes) (statsSlot, error) {
+	var data string
+	if err := bencode.Unmarshal(v, &data); err != nil {
+		return statsSlot{}, fmt.Errorf("invalid stats value: %w", err)
+	}
+	var slot statsSlot
+	if err := json.Unmarshal([]byte(data), &slot); err != nil {
+		return statsSlot{}, fmt.Errorf("invalid stats value: %w", err)
+	}
+	return slot, nil
+}
+
+// sumSlots adds up the reports of slots, counting a nonce once
+func sumSlots(day string, slots []statsSlot) NetworkStats {
+	stats := NetworkStats{Day: day}
+	seen := make(map[string]bool)
+	for _, slot := range slots {
+		stats.SlotsFound++
+		for _, r := range slot.Reports {
+			if r.Nonce == "" || seen[r.Nonce] || r.Models < 0 || r.UploadedMB < 0 {
+				continue
+			}
+			seen[r.Nonce] = true
+			stats.Nodes++
+			stats.ModelsSeeded += r.Models
+			stats.UploadedMB += r.UploadedMB
+		}
+	}
+	return stats
+}
@@ -0,0 +1,88 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsSalt(t *testing.T) {
+	day := StatsDay(time.Date(2026, 3, 1, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*3600)))
+	assert.Equal(t, "2026-03-02", day)
+	assert.Equal(t, "stats/2026-03-02/07", string(statsSalt(day, 7)))
+	assert.LessOrEqual(t, len(statsSalt(day, StatsSlots-1)), 64, "BEP44 salts are at most 64 bytes")
+}
+
+func TestMergeReport(t *testing.T) {
+	slot, ok := mergeReport(statsSlot{}, NetworkReport{Nonce: "a", Models: 2, UploadedMB: 10})
+	require.True(t, ok)
+	slot, ok = mergeReport(slot, NetworkReport{Nonce: "b", Models: 1})
+	require.True(t, ok)
+
+	// A report of the same node replaces its earlier one
+	slot, ok = mergeReport(slot, NetworkReport{Nonce: "a", Models: 3, UploadedMB: 20})
+	require.True(t, ok)
+	assert.Equal(t, []NetworkReport{
+		{Nonce: "b", Models: 1},
+		{Nonce: "a", Models: 3, UploadedMB: 20},
+	}, slot.Reports)
+
+	// Until the slot is full
+	full := 0
+	for i := 0; ; i++ {
+		next, ok := mergeReport(slot, NetworkReport{Nonce: fmt.Sprintf("%08x", i), Models: 100, UploadedMB: 1 << 20})
+		if !ok {
+			break
+		}
+		slot = next
+		full++
+	}
+	assert.Greater(t, full, 15)
+	data, err := bencode.Marshal(mustJSON(t, slot))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(data), MaxValueSize)
+}
+
+func TestDecodeSlot(t *testing.T) {
+	want := statsSlot{Reports: []NetworkReport{{Nonce: "a", Models: 2, UploadedMB: 10}}}
+	data, err := bencode.Marshal(mustJSON(t, want))
+	require.NoError(t, err)
+
+	slot, err := decodeSlot(data)
+	require.NoError(t, err)
+	assert.Equal(t, want, slot)
+
+	_, err = decodeSlot(bencode.Bytes("i42e"))
+	assert.Error(t, err)
+	_, err = decodeSlot(bencode.Bytes("3:abc"))
+	assert.Error(t, err)
+}
+
+func TestSumSlots(t *testing.T) {
+	stats := sumSlots("2026-03-02", []statsSlot{
+		{Reports: []NetworkReport{{Nonce: "a", Models: 2, UploadedMB: 10}, {Nonce: "b", Models: 1, UploadedMB: 5}}},
+		// A node that landed in two slots is counted once
+		{Reports: []NetworkReport{{Nonce: "a", Models: 2, UploadedMB: 10}, {Nonce: "c", Models: 4}}},
+		// Invalid reports are ignored
+		{Reports: []NetworkReport{{Nonce: "", Models: 9}, {Nonce: "d", Models: -1}}},
+	})
+	assert.Equal(t, NetworkStats{
+		Day:          "2026-03-02",
+		Nodes:        3,
+		ModelsSeeded: 7,
+		UploadedMB:   15,
+		SlotsFound:   3,
+	}, stats)
+}
+
+func mustJSON(t *testing.T, slot statsSlot) string {
+	t.Helper()
+	data, err := json.Marshal(slot)
+	require.NoError(t, err)
+	return string(data)
+}
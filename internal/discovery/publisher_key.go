@@ -0,0 +1,73 @@
+package discovery
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// LoadOrCreatePublisherKey loads the publisher's ed25519 key from path, or
+// generates and saves a new one if it does not exist yet. The file holds the
+// hex encoded key seed.
+func LoadOrCreatePublisherKey(path string) (ed25519.PrivateKey, error) {
+	privateKey, err := LoadPublisherKey(path)
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return privateKey, err
+	}
+
+	_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate publisher key: %w", err)
+	}
+
+	if err := SavePublisherKey(path, privateKey); err != nil {
+		return nil, err
+	}
+
+	return privateKey, nil
+}
+
+// LoadPublisherKey loads a publisher key saved with SavePublisherKey
+func LoadPublisherKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read publisher key: %w", err)
+	}
+	seed, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid publisher key in %s", path)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// SavePublisherKey writes a publisher key to path, readable only by us
+func SavePublisherKey(path string, privateKey ed25519.PrivateKey) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create keys directory: %w", err)
+	}
+	if err := storage.WriteFileAtomic(path, []byte(hex.EncodeToString(privateKey.Seed())), 0600); err != nil {
+		return fmt.Errorf("failed to save publisher key: %w", err)
+	}
+	return nil
+}
+
+// ParsePublisherKey parses a hex encoded ed25519 public key
+func ParsePublisherKey(s string) ([32]byte, error) {
+	var key [32]byte
+	decoded, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return key, fmt.Errorf("invalid publisher key: %w", err)
+	}
+	if len(decoded) != ed25519.PublicKeySize {
+		return key, fmt.Errorf("invalid publisher key: expected %d bytes, got %d", ed25519.PublicKeySize, len(decoded))
+	}
+	copy(key[:], decoded)
+	return key, nil
+}
@@ -0,0 +1,51 @@
+package discovery
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrCreatePublisherKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "publisher.key")
+
+	key, err := LoadOrCreatePublisherKey(path)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Loading again returns the same key
+	loaded, err := LoadOrCreatePublisherKey(path)
+	require.NoError(t, err)
+	assert.True(t, key.Equal(loaded))
+}
+
+func TestLoadOrCreatePublisherKeyInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "publisher.key")
+	require.NoError(t, os.WriteFile(path, []byte("not a key"), 0600))
+
+	_, err := LoadOrCreatePublisherKey(path)
+	assert.Error(t, err)
+}
+
+func TestParsePublisherKey(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	parsed, err := ParsePublisherKey(hex.EncodeToString(publicKey))
+	require.NoError(t, err)
+	assert.Equal(t, []byte(publicKey), parsed[:])
+
+	_, err = ParsePublisherKey("xyz")
+	assert.Error(t, err)
+
+	_, err = ParsePublisherKey("abcd")
+	assert.Error(t, err)
+}
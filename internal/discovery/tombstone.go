@@ -0,0 +1,64 @@
+package discovery
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Tombstone records that a publisher withdrew a model from the catalog. It is
+// signed with the publisher key of the catalog entry, so only the original
+// publisher can remove a model, and it is kept in the catalog long enough for
+// the removal to reach peers still holding the old entry.
+type Tombstone struct {
+	InfoHash  string `json:"h"`
+	Removed   int64  `json:"a"`
+	Publisher string `json:"k"`
+	Signature string `json:"sig"`
+}
+
+// NewTombstone creates a signed tombstone for a model
+func NewTombstone(name, infoHash string, privateKey ed25519.PrivateKey) Tombstone {
+	t := Tombstone{
+		InfoHash:  infoHash,
+		Removed:   time.Now().Unix(),
+		Publisher: hex.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+	}
+	t.Signature = hex.EncodeToString(ed25519.Sign(privateKey, t.message(name)))
+	return t
+}
+
+// Verify checks the tombstone signature for the given model name
+func (t Tombstone) Verify(name string) error {
+	publicKey, err := hex.DecodeString(t.Publisher)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid tombstone publisher for %s", name)
+	}
+	signature, err := hex.DecodeString(t.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid tombstone signature for %s: %w", name, err)
+	}
+	if !ed25519.Verify(publicKey, t.message(name), signature) {
+		return fmt.Errorf("tombstone signature mismatch for %s", name)
+	}
+	return nil
+}
+
+// Covers reports whether the tombstone removes a catalog entry. Entries
+// without a publisher can not be withdrawn and only expire.
+func (t Tombstone) Covers(entry ModelEntry) bool {
+	return entry.Publisher != "" &&
+		entry.Publisher == t.Publisher &&
+		entry.InfoHash == t.InfoHash &&
+		entry.Added <= t.Removed
+}
+
+// Expired reports whether the tombstone is past its retention period
+func (t Tombstone) Expired(now time.Time) bool {
+	return now.Sub(time.Unix(t.Removed, 0)) > TombstoneRetention
+}
+
+func (t Tombstone) message(name string) []byte {
+	return []byte(fmt.Sprintf("silmaril-unpublish\x00%s\x00%s\x00%d", name, t.InfoHash, t.Removed))
+}
@@ -0,0 +1,172 @@
+package discovery
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPublisher(t *testing.T) (ed25519.PrivateKey, string) {
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return privateKey, hex.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+}
+
+func TestTombstoneVerify(t *testing.T) {
+	privateKey, publisher := newTestPublisher(t)
+	infoHash := strings.Repeat("a", 40)
+
+	tombstone := NewTombstone("org/model", infoHash, privateKey)
+	assert.Equal(t, publisher, tombstone.Publisher)
+	assert.NoError(t, tombstone.Verify("org/model"))
+
+	// The signature is bound to the model name and infohash
+	assert.Error(t, tombstone.Verify("org/other"))
+	tampered := tombstone
+	tampered.InfoHash = strings.Repeat("b", 40)
+	assert.Error(t, tampered.Verify("org/model"))
+
+	// A different key can not sign for the publisher
+	otherKey, _ := newTestPublisher(t)
+	forged := NewTombstone("org/model", infoHash, otherKey)
+	forged.Publisher = publisher
+	assert.Error(t, forged.Verify("org/model"))
+}
+
+func TestTombstoneCovers(t *testing.T) {
+	privateKey, publisher := newTestPublisher(t)
+	infoHash := strings.Repeat("a", 40)
+	tombstone := NewTombstone("org/model", infoHash, privateKey)
+
+	entry := ModelEntry{InfoHash: infoHash, Added: tombstone.Removed - 10, Publisher: publisher}
+	assert.True(t, tombstone.Covers(entry))
+
+	// Re-published after the withdrawal
+	republished := entry
+	republished.Added = tombstone.Removed + 10
+	assert.False(t, tombstone.Covers(republished))
+
+	// Entries of other publishers or without a publisher are not covered
+	_, otherPublisher := newTestPublisher(t)
+	other := entry
+	other.Publisher = otherPublisher
+	assert.False(t, tombstone.Covers(other))
+	anonymous := entry
+	anonymous.Publisher = ""
+	assert.False(t, tombstone.Covers(anonymous))
+}
+
+func TestCatalogApplyTombstone(t *testing.T) {
+	ct, client, tmpDir := setupTestCatalogTorrent(t)
+	defer os.RemoveAll(tmpDir)
+	defer client.Close()
+
+	privateKey, publisher := newTestPublisher(t)
+	infoHash := strings.Repeat("a", 40)
+	ct.catalog.Models = map[string]ModelEntry{
+		"org/model": {InfoHash: infoHash, Added: time.Now().Unix() - 10, Publisher: publisher},
+	}
+
+	// Only the publisher of the entry can withdraw it
+	otherKey, _ := newTestPublisher(t)
+	assert.Error(t, ct.ApplyTombstone("org/model", NewTombstone("org/model", infoHash, otherKey)))
+	assert.Len(t, ct.catalog.Models, 1)
+
+	require.NoError(t, ct.ApplyTombstone("org/model", NewTombstone("org/model", infoHash, privateKey)))
+	assert.Empty(t, ct.catalog.Models)
+	assert.True(t, ct.IsWithdrawn("org/model", infoHash))
+
+	// A stale peer catalog can not bring the entry back
+	stale := &ModelCatalog{Models: map[string]ModelEntry{
+		"org/model": {InfoHash: infoHash, Added: time.Now().Unix() - 10, Publisher: publisher},
+	}}
+	assert.False(t, ct.MergeCatalog(stale))
+	assert.Empty(t, ct.catalog.Models)
+}
+
+func TestMergeCatalogTombstones(t *testing.T) {
+	ct, client, tmpDir := setupTestCatalogTorrent(t)
+	defer os.RemoveAll(tmpDir)
+	defer client.Close()
+
+	privateKey, publisher := newTestPublisher(t)
+	infoHash := strings.Repeat("a", 40)
+	ct.catalog.Models = map[string]ModelEntry{
+		"org/model": {InfoHash: infoHash, Added: time.Now().Unix() - 10, Publisher: publisher},
+	}
+
+	// Forged tombstones are ignored
+	otherKey, _ := newTestPublisher(t)
+	forged := NewTombstone("org/model", infoHash, otherKey)
+	forged.Publisher = publisher
+	ct.MergeCatalog(&ModelCatalog{Tombstones: map[string]Tombstone{"org/model": forged}})
+	assert.Len(t, ct.catalog.Models, 1)
+
+	// Valid tombstones from peers remove the entry
+	tombstone := NewTombstone("org/model", infoHash, privateKey)
+	assert.True(t, ct.MergeCatalog(&ModelCatalog{Tombstones: map[string]Tombstone{"org/model": tombstone}}))
+	assert.Empty(t, ct.catalog.Models)
+	assert.Contains(t, ct.Snapshot().Tombstones, "org/model")
+}
+
+func TestCatalogPrune(t *testing.T) {
+	ct, client, tmpDir := setupTestCatalogTorrent(t)
+	defer os.RemoveAll(tmpDir)
+	defer client.Close()
+
+	now := time.Now()
+	old := now.Add(-CatalogEntryTTL - time.Hour).Unix()
+	ct.catalog.Models = map[string]ModelEntry{
+		"org/fresh":   {InfoHash: strings.Repeat("a", 40), Added: now.Unix()},
+		"org/renewed": {InfoHash: strings.Repeat("b", 40), Added: old, Seen: now.Unix()},
+		"org/stale":   {InfoHash: strings.Repeat("c", 40), Added: old},
+	}
+	ct.catalog.Tombstones = map[string]Tombstone{
+		"org/gone": {InfoHash: strings.Repeat("d", 40), Removed: old},
+	}
+
+	// Expired entries are hidden from searches before they are pruned
+	models, err := ct.GetModels("")
+	require.NoError(t, err)
+	assert.Len(t, models, 2)
+
+	removed, changed := ct.Prune(now)
+	assert.True(t, changed)
+	assert.Equal(t, []string{"org/stale"}, removed)
+	assert.Len(t, ct.catalog.Models, 2)
+	assert.Empty(t, ct.catalog.Tombstones)
+
+	_, changed = ct.Prune(now)
+	assert.False(t, changed)
+}
+
+func TestCatalogRenewModels(t *testing.T) {
+	ct, client, tmpDir := setupTestCatalogTorrent(t)
+	defer os.RemoveAll(tmpDir)
+	defer client.Close()
+
+	now := time.Now().Unix()
+	old := time.Now().Add(-2 * CatalogRenewInterval).Unix()
+	ct.catalog.Models = map[string]ModelEntry{
+		"org/old":    {InfoHash: strings.Repeat("a", 40), Added: old},
+		"org/recent": {InfoHash: strings.Repeat("b", 40), Added: now},
+		"org/gone":   {InfoHash: strings.Repeat("c", 40), Added: old},
+	}
+
+	live := map[string]bool{strings.Repeat("a", 40): true, strings.Repeat("b", 40): true}
+	assert.Equal(t, 1, ct.RenewModels(live))
+	assert.GreaterOrEqual(t, ct.catalog.Models["org/old"].Seen, now)
+	assert.Zero(t, ct.catalog.Models["org/recent"].Seen)
+	assert.Zero(t, ct.catalog.Models["org/gone"].Seen)
+
+	// Renewed entries are picked up by peers through the digest
+	digest := ct.Digest()
+	assert.Equal(t, 0, ct.RenewModels(live))
+	assert.Equal(t, digest, ct.Digest())
+}
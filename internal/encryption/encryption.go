@@ -0,0 +1,274 @@
+// Package encryption encrypts model files for gated distribution. Files are
+// encrypted with AES-256-GCM in fixed size chunks, so they can be encrypted
+// and decrypted as a stream and truncation or reordering of chunks is
+// detected.
+package encryption
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Cipher is the name of the encryption scheme recorded in manifests
+	Cipher = "aes-256-gcm-stream"
+
+	// KeySize is the size of a model key in bytes
+	KeySize = 32
+
+	// Size of the plaintext in each encrypted chunk
+	chunkSize = 64 * 1024
+
+	// Random part of the chunk nonces, stored in the file header. The rest
+	// of a nonce is the chunk counter and a flag marking the last chunk.
+	noncePrefixSize = 7
+
+	// Size of the authentication tag added to each chunk
+	aesGCMOverhead = 16
+)
+
+// Magic bytes starting every encrypted file
+var magic = []byte("SILMENC1")
+
+// headerSize is the size of the header of an encrypted file
+var headerSize = int64(len(magic) + noncePrefixSize)
+
+// GenerateKey returns a new random model key
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return key, nil
+}
+
+// KeyID identifies a key without revealing it
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParseKey parses a hex encoded model key
+func ParseKey(s string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid key: expected %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// CiphertextSize returns the size of an encrypted file of size bytes
+func CiphertextSize(size int64) int64 {
+	chunks := (size + chunkSize - 1) / chunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return headerSize + size + chunks*aesGCMOverhead
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid key size %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of the chunk with the given counter
+func chunkNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// readChunk reads up to len(buf) bytes and reports whether the stream ends
+// after them
+func readChunk(r *bufio.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return n, true, nil
+	}
+	if err != nil {
+		return n, false, err
+	}
+	if _, err := r.Peek(1); err == io.EOF {
+		return n, true, nil
+	} else if err != nil {
+		return n, false, err
+	}
+	return n, false, nil
+}
+
+// Encrypt encrypts src to dst with key
+func Encrypt(dst io.Writer, src io.Reader, key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if _, err := dst.Write(append(append([]byte{}, magic...), prefix...)); err != nil {
+		return err
+	}
+
+	r := bufio.NewReaderSize(src, chunkSize)
+	buf := make([]byte, chunkSize)
+	out := make([]byte, 0, chunkSize+aead.Overhead())
+	for counter := uint32(0); ; counter++ {
+		n, last, err := readChunk(r, buf)
+		if err != nil {
+			return err
+		}
+		out = aead.Seal(out[:0], chunkNonce(prefix, counter, last), buf[:n], nil)
+		if _, err := dst.Write(out); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		if counter == ^uint32(0) {
+			return errors.New("file too large to encrypt")
+		}
+	}
+}
+
+// Decrypt decrypts src, encrypted by Encrypt with key, to dst. It fails if
+// the data was modified, truncated or encrypted with another key.
+func Decrypt(dst io.Writer, src io.Reader, key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	if string(header[:len(magic)]) != string(magic) {
+		return errors.New("not an encrypted model file")
+	}
+	prefix := header[len(magic):]
+
+	r := bufio.NewReaderSize(src, chunkSize+aead.Overhead())
+	buf := make([]byte, chunkSize+aead.Overhead())
+	out := make([]byte, 0, chunkSize)
+	for counter := uint32(0); ; counter++ {
+		n, last, err := readChunk(r, buf)
+		if err != nil {
+			return err
+		}
+		out, err = aead.Open(out[:0], chunkNonce(prefix, counter, last), buf[:n], nil)
+		if err != nil {
+			return fmt.Errorf("chunk %d failed authentication, wrong key or corrupt data", counter)
+		}
+		if _, err := dst.Write(out); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// EncryptFile encrypts the file at srcPath to dstPath
+func EncryptFile(srcPath, dstPath string, key []byte) error {
+	return transformFile(srcPath, dstPath, func(dst io.Writer, src io.Reader) error {
+		return Encrypt(dst, src, key)
+	})
+}
+
+// DecryptFile decrypts the file at srcPath to dstPath. dst also receives the
+// plaintext, for example to hash it; it may be nil.
+func DecryptFile(srcPath, dstPath string, key []byte, dst io.Writer) error {
+	return transformFile(srcPath, dstPath, func(out io.Writer, src io.Reader) error {
+		if dst != nil {
+			out = io.MultiWriter(out, dst)
+		}
+		return Decrypt(out, src, key)
+	})
+}
+
+// transformFile writes the transformed contents of srcPath to a temporary
+// file that replaces dstPath once complete
+func transformFile(srcPath, dstPath string, transform func(io.Writer, io.Reader) error) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dstPath), "."+filepath.Base(dstPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := transform(w, src); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%s: %w", filepath.Base(srcPath), err)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dstPath)
+}
+
+// SaveKey stores key in the key store dir, named by its ID
+func SaveKey(dir string, key []byte) (string, error) {
+	id := KeyID(key)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create key store: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, id+".key"), []byte(hex.EncodeToString(key)), 0600); err != nil {
+		return "", fmt.Errorf("failed to save key: %w", err)
+	}
+	return id, nil
+}
+
+// LoadKey loads the key with the given ID from the key store dir
+func LoadKey(dir, id string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.Base(id)+".key"))
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseKey(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if KeyID(key) != id {
+		return nil, fmt.Errorf("key %s does not match its ID", id)
+	}
+	return key, nil
+}
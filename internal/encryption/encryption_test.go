@@ -0,0 +1,122 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	// Sizes around the chunk boundaries
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3 * chunkSize} {
+		plaintext := make([]byte, size)
+		_, err := rand.Read(plaintext)
+		require.NoError(t, err)
+
+		var ciphertext bytes.Buffer
+		require.NoError(t, Encrypt(&ciphertext, bytes.NewReader(plaintext), key))
+		assert.Equal(t, CiphertextSize(int64(size)), int64(ciphertext.Len()), "size %d", size)
+
+		var decrypted bytes.Buffer
+		require.NoError(t, Decrypt(&decrypted, bytes.NewReader(ciphertext.Bytes()), key), "size %d", size)
+		assert.True(t, bytes.Equal(plaintext, decrypted.Bytes()), "size %d", size)
+	}
+}
+
+func TestDecryptDetectsTampering(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+	plaintext := bytes.Repeat([]byte("weights"), chunkSize/2)
+
+	var ciphertext bytes.Buffer
+	require.NoError(t, Encrypt(&ciphertext, bytes.NewReader(plaintext), key))
+	data := ciphertext.Bytes()
+
+	// Wrong key
+	otherKey, err := GenerateKey()
+	require.NoError(t, err)
+	assert.Error(t, Decrypt(&bytes.Buffer{}, bytes.NewReader(data), otherKey))
+
+	// Modified data
+	modified := bytes.Clone(data)
+	modified[len(modified)/2] ^= 1
+	assert.Error(t, Decrypt(&bytes.Buffer{}, bytes.NewReader(modified), key))
+
+	// Truncated after a full chunk, which would otherwise look complete
+	truncated := data[:headerSize+chunkSize+aesGCMOverhead]
+	assert.Error(t, Decrypt(&bytes.Buffer{}, bytes.NewReader(truncated), key))
+
+	// Not encrypted at all
+	assert.Error(t, Decrypt(&bytes.Buffer{}, bytes.NewReader(plaintext), key))
+}
+
+func TestEncryptDecryptFile(t *testing.T) {
+	dir := t.TempDir()
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	src := filepath.Join(dir, "model.safetensors")
+	require.NoError(t, os.WriteFile(src, []byte("model weights"), 0644))
+
+	encrypted := filepath.Join(dir, "encrypted", "model.safetensors")
+	require.NoError(t, EncryptFile(src, encrypted, key))
+
+	var plaintext bytes.Buffer
+	decrypted := filepath.Join(dir, "decrypted", "model.safetensors")
+	require.NoError(t, DecryptFile(encrypted, decrypted, key, &plaintext))
+
+	data, err := os.ReadFile(decrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "model weights", string(data))
+	assert.Equal(t, "model weights", plaintext.String())
+
+	// A failed decryption leaves no file behind
+	otherKey, err := GenerateKey()
+	require.NoError(t, err)
+	failed := filepath.Join(dir, "failed", "model.safetensors")
+	assert.Error(t, DecryptFile(encrypted, failed, otherKey, nil))
+	entries, err := os.ReadDir(filepath.Dir(failed))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestKeyStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	id, err := SaveKey(dir, key)
+	require.NoError(t, err)
+	assert.Equal(t, KeyID(key), id)
+
+	loaded, err := LoadKey(dir, id)
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	_, err = LoadKey(dir, "0000000000000000")
+	assert.Error(t, err)
+}
+
+func TestParseKey(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	parsed, err := ParseKey(" " + strings.ToUpper(hex.EncodeToString(key)) + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = ParseKey("abcd")
+	assert.Error(t, err)
+	_, err = ParseKey("not hex")
+	assert.Error(t, err)
+}
@@ -0,0 +1,251 @@
+// Package hfauth stores the HuggingFace access token used to clone and
+// search gated and private models. The token is kept in the keys directory,
+// encrypted with a key held by the OS keyring where one is available, and
+// readable only by its owner otherwise. HF_TOKEN overrides the stored token.
+package hfauth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/silmaril/silmaril/internal/encryption"
+)
+
+const (
+	// File in the keys directory holding the token
+	tokenFile = "hf_token.json"
+	// Keyring entry holding the key the token is encrypted with
+	keyringAccount = "hf-token-key"
+)
+
+// Sources of the token in use
+const (
+	SourceEnv    = "HF_TOKEN"
+	SourceStored = "stored"
+)
+
+// ErrNotLoggedIn is returned when no token is stored
+var ErrNotLoggedIn = errors.New("not logged in to HuggingFace, run 'silmaril auth hf login'")
+
+// storedToken is the token file
+type storedToken struct {
+	Encrypted bool   `json:"encrypted"`
+	Token     string `json:"token"` // Base64 ciphertext if encrypted
+}
+
+// Store keeps the token of a keys directory
+type Store struct {
+	dir     string
+	keyring Keyring
+}
+
+// NewStore returns the token store of keysDir, using the OS keyring if
+// there is one
+func NewStore(keysDir string) *Store {
+	return &Store{dir: keysDir, keyring: SystemKeyring()}
+}
+
+// newStoreWithKeyring returns a token store using keyring, nil for none
+func newStoreWithKeyring(keysDir string, keyring Keyring) *Store {
+	return &Store{dir: keysDir, keyring: keyring}
+}
+
+func (s *Store) path() string {
+	return filepath.Join(s.dir, tokenFile)
+}
+
+// Save stores token, replacing the stored one. It reports whether the
+// token was encrypted with a key in the OS keyring.
+func (s *Store) Save(token string) (bool, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return false, errors.New("token is empty")
+	}
+	stored := storedToken{Token: token}
+	if s.keyring != nil {
+		if ciphertext, err := s.encrypt(token); err == nil {
+			stored = storedToken{Encrypted: true, Token: ciphertext}
+		} else {
+			fmt.Printf("[HFAuth] Storing the token unencrypted, the OS keyring failed: %v\n", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return false, fmt.Errorf("failed to create keys directory: %w", err)
+	}
+	if err := os.WriteFile(s.path(), data, 0600); err != nil {
+		return false, fmt.Errorf("failed to save token: %w", err)
+	}
+	return stored.Encrypted, nil
+}
+
+// encrypt encrypts token with a new key, which is stored in the keyring
+func (s *Store) encrypt(token string) (string, error) {
+	key, err := encryption.GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	var ciphertext bytes.Buffer
+	if err := encryption.Encrypt(&ciphertext, strings.NewReader(token), key); err != nil {
+		return "", err
+	}
+	if err := s.keyring.Set(keyringAccount, hex.EncodeToString(key)); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext.Bytes()), nil
+}
+
+// Load returns the stored token, ErrNotLoggedIn if there is none
+func (s *Store) Load() (string, error) {
+	data, err := os.ReadFile(s.path())
+	if os.IsNotExist(err) {
+		return "", ErrNotLoggedIn
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+	var stored storedToken
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return "", fmt.Errorf("failed to parse token file: %w", err)
+	}
+	if !stored.Encrypted {
+		return stored.Token, nil
+	}
+
+	if s.keyring == nil {
+		return "", errors.New("the token is encrypted with a key in the OS keyring, which is not available")
+	}
+	hexKey, err := s.keyring.Get(keyringAccount)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the token key from the OS keyring: %w", err)
+	}
+	key, err := encryption.ParseKey(hexKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(stored.Token)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode token: %w", err)
+	}
+	var token bytes.Buffer
+	if err := encryption.Decrypt(&token, bytes.NewReader(ciphertext), key); err != nil {
+		return "", fmt.Errorf("failed to decrypt token: %w", err)
+	}
+	return token.String(), nil
+}
+
+// Delete removes the stored token and its key
+func (s *Store) Delete() error {
+	err := os.Remove(s.path())
+	if os.IsNotExist(err) {
+		return ErrNotLoggedIn
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove token: %w", err)
+	}
+	if s.keyring != nil {
+		s.keyring.Delete(keyringAccount)
+	}
+	return nil
+}
+
+// Token returns the token in use and where it came from: HF_TOKEN if it is
+// set, the stored token otherwise. It is read on every call, so logging in
+// and out applies to the next request.
+func (s *Store) Token() (string, string, error) {
+	if token := os.Getenv("HF_TOKEN"); token != "" {
+		return token, SourceEnv, nil
+	}
+	token, err := s.Load()
+	if err != nil {
+		return "", "", err
+	}
+	return token, SourceStored, nil
+}
+
+// TokenFor returns the token to send with a request to rawURL, none unless
+// the request goes to the HuggingFace Hub or one of hubs, so the token never
+// reaches other hosts
+func (s *Store) TokenFor(rawURL string, hubs ...string) string {
+	if !isHub(rawURL, hubs) {
+		return ""
+	}
+	token, _, err := s.Token()
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// isHub reports whether rawURL is on the HuggingFace Hub or one of hubs
+func isHub(rawURL string, hubs []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "huggingface.co" || host == "hf.co" {
+		return true
+	}
+	for _, hub := range hubs {
+		if h, err := url.Parse(hub); err == nil && strings.EqualFold(h.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// User is the account a token belongs to
+type User struct {
+	Name     string `json:"name"`
+	Fullname string `json:"fullname"`
+	Orgs     []struct {
+		Name string `json:"name"`
+	} `json:"orgs"`
+	Auth struct {
+		AccessToken struct {
+			DisplayName string `json:"displayName"`
+			Role        string `json:"role"`
+		} `json:"accessToken"`
+	} `json:"auth"`
+}
+
+// Whoami returns the account of token from the hub at endpoint
+func Whoami(client *http.Client, endpoint, token string) (*User, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(endpoint, "/")+"/api/whoami-v2", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the hub: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, errors.New("the hub rejected the token, it is invalid or was revoked")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to check the token: %s", resp.Status)
+	}
+
+	var user User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to decode account: %w", err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,130 @@
+package hfauth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryKeyring keeps secrets in memory
+type memoryKeyring map[string]string
+
+func (k memoryKeyring) Get(account string) (string, error) {
+	secret, ok := k[account]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return secret, nil
+}
+
+func (k memoryKeyring) Set(account, secret string) error {
+	k[account] = secret
+	return nil
+}
+
+func (k memoryKeyring) Delete(account string) error {
+	delete(k, account)
+	return nil
+}
+
+func TestStoreEncrypted(t *testing.T) {
+	t.Setenv("HF_TOKEN", "")
+	dir := t.TempDir()
+	keyring := memoryKeyring{}
+	store := newStoreWithKeyring(dir, keyring)
+
+	_, err := store.Load()
+	assert.ErrorIs(t, err, ErrNotLoggedIn)
+
+	encrypted, err := store.Save("hf_secret\n")
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+
+	// The file doesn't hold the token and only its owner can read it
+	data, err := os.ReadFile(filepath.Join(dir, tokenFile))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "hf_secret")
+	info, err := os.Stat(filepath.Join(dir, tokenFile))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	token, source, err := store.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "hf_secret", token)
+	assert.Equal(t, SourceStored, source)
+
+	// Without the key the token can't be read
+	_, err = newStoreWithKeyring(dir, memoryKeyring{}).Load()
+	assert.Error(t, err)
+	_, err = newStoreWithKeyring(dir, nil).Load()
+	assert.Error(t, err)
+
+	require.NoError(t, store.Delete())
+	assert.Empty(t, keyring)
+	_, err = store.Load()
+	assert.ErrorIs(t, err, ErrNotLoggedIn)
+	assert.ErrorIs(t, store.Delete(), ErrNotLoggedIn)
+}
+
+func TestStoreWithoutKeyring(t *testing.T) {
+	t.Setenv("HF_TOKEN", "")
+	store := newStoreWithKeyring(t.TempDir(), nil)
+
+	encrypted, err := store.Save("hf_secret")
+	require.NoError(t, err)
+	assert.False(t, encrypted)
+	token, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "hf_secret", token)
+
+	_, err = store.Save("  ")
+	assert.Error(t, err)
+}
+
+func TestTokenFor(t *testing.T) {
+	t.Setenv("HF_TOKEN", "")
+	store := newStoreWithKeyring(t.TempDir(), nil)
+	assert.Empty(t, store.TokenFor("https://huggingface.co/org/model"), "not logged in")
+
+	_, err := store.Save("hf_secret")
+	require.NoError(t, err)
+	assert.Equal(t, "hf_secret", store.TokenFor("https://huggingface.co/org/model"))
+	assert.Equal(t, "hf_secret", store.TokenFor("https://hf.co/api/models"))
+	assert.Equal(t, "hf_secret", store.TokenFor("https://hf-mirror.example.com/org/model", "https://hf-mirror.example.com"))
+	assert.Empty(t, store.TokenFor("https://github.com/org/model"), "tokens only go to the hub")
+	assert.Empty(t, store.TokenFor("https://huggingface.co.evil.example/org/model"))
+
+	// HF_TOKEN overrides the stored token
+	t.Setenv("HF_TOKEN", "hf_env")
+	token, source, err := store.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "hf_env", token)
+	assert.Equal(t, SourceEnv, source)
+}
+
+func TestWhoami(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/whoami-v2" || r.Header.Get("Authorization") != "Bearer hf_secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"name":"alice","orgs":[{"name":"acme"}],"auth":{"accessToken":{"displayName":"laptop","role":"read"}}}`))
+	}))
+	defer server.Close()
+
+	user, err := Whoami(server.Client(), server.URL+"/", "hf_secret")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Name)
+	require.Len(t, user.Orgs, 1)
+	assert.Equal(t, "acme", user.Orgs[0].Name)
+	assert.Equal(t, "read", user.Auth.AccessToken.Role)
+
+	_, err = Whoami(server.Client(), server.URL, "hf_wrong")
+	assert.ErrorContains(t, err, "rejected")
+}
@@ -0,0 +1,89 @@
+package hfauth
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Service the keyring entries of Silmaril are filed under
+const keyringService = "silmaril"
+
+// Keyring holds secrets for the user, such as the macOS Keychain or the
+// Secret Service of Linux desktops
+type Keyring interface {
+	Get(account string) (string, error)
+	Set(account, secret string) error
+	Delete(account string) error
+}
+
+// SystemKeyring returns the keyring of the OS, through its command line
+// tool, nil if there is none
+func SystemKeyring() Keyring {
+	switch runtime.GOOS {
+	case "darwin":
+		if path, err := exec.LookPath("security"); err == nil {
+			return keychain{path: path}
+		}
+	case "linux", "freebsd", "openbsd":
+		if path, err := exec.LookPath("secret-tool"); err == nil {
+			return secretService{path: path}
+		}
+	}
+	return nil
+}
+
+// keychain is the macOS Keychain
+type keychain struct {
+	path string
+}
+
+func (k keychain) Get(account string) (string, error) {
+	return run(k.path, "", "find-generic-password", "-s", keyringService, "-a", account, "-w")
+}
+
+func (k keychain) Set(account, secret string) error {
+	_, err := run(k.path, "", "add-generic-password", "-U", "-s", keyringService, "-a", account, "-w", secret)
+	return err
+}
+
+func (k keychain) Delete(account string) error {
+	_, err := run(k.path, "", "delete-generic-password", "-s", keyringService, "-a", account)
+	return err
+}
+
+// secretService is the Secret Service of Linux desktops, such as GNOME
+// Keyring and KWallet
+type secretService struct {
+	path string
+}
+
+func (s secretService) Get(account string) (string, error) {
+	return run(s.path, "", "lookup", "service", keyringService, "account", account)
+}
+
+func (s secretService) Set(account, secret string) error {
+	// The secret is read from stdin, keeping it off the command line
+	_, err := run(s.path, secret, "store", "--label", "Silmaril "+account, "service", keyringService, "account", account)
+	return err
+}
+
+func (s secretService) Delete(account string) error {
+	_, err := run(s.path, "", "clear", "service", keyringService, "account", account)
+	return err
+}
+
+// run runs a keyring tool and returns its output
+func run(path, stdin string, args ...string) (string, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s %s: %v: %s", path, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
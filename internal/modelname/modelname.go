@@ -0,0 +1,130 @@
+// Package modelname parses and checks the names of models. A name is model
+// or org/model, optionally followed by a version, and doubles as the path of
+// the model below the models directory, so every name handed to the storage
+// layer is checked here first.
+package modelname
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalid is returned for a name that is not a valid model name
+var ErrInvalid = errors.New("invalid model name")
+
+// Longest organization or model part and version, the organization and
+// model limits are the ones of HuggingFace repository names
+const (
+	maxPartLength    = 96
+	maxVersionLength = 128
+)
+
+var (
+	// Letters, digits, '.', '_' and '-', starting with a letter or digit, so
+	// a part is never "." or ".."
+	partPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+	// Versions only ever end a path segment, so they may start with a dot
+	versionPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// Name is a parsed model name
+type Name struct {
+	Org     string // Empty for names without an organization
+	Model   string
+	Version string // Of an old version kept next to the model, empty for the model itself
+}
+
+// Parse parses model, org/model and either followed by a version after '@'
+// or ':'. Old versions of a model are kept in org/model@version, so
+// org/model:version names the same model; ':' can't be part of a path on
+// every platform.
+func Parse(s string) (Name, error) {
+	invalid := func(reason string) (Name, error) {
+		return Name{}, fmt.Errorf("%w %q: %s", ErrInvalid, s, reason)
+	}
+	if s == "" {
+		return invalid("the name is empty")
+	}
+
+	var name Name
+	base := s
+	if i := strings.IndexAny(s, "@:"); i >= 0 {
+		base, name.Version = s[:i], s[i+1:]
+		if !ValidVersion(name.Version) {
+			return invalid("versions are letters, digits, '.', '_' and '-'")
+		}
+	}
+
+	parts := strings.Split(base, "/")
+	if len(parts) > 2 {
+		return invalid("names are model or org/model")
+	}
+	for _, part := range parts {
+		if part == "" {
+			return invalid("names are model or org/model")
+		}
+		if len(part) > maxPartLength {
+			return invalid(fmt.Sprintf("%s is longer than %d characters", part, maxPartLength))
+		}
+		if !partPattern.MatchString(part) {
+			return invalid("names are letters, digits, '.', '_' and '-', starting with a letter or digit")
+		}
+	}
+	if len(parts) == 2 {
+		name.Org = parts[0]
+	}
+	name.Model = parts[len(parts)-1]
+	return name, nil
+}
+
+// String returns the canonical form of the name, org/model@version
+func (n Name) String() string {
+	s := n.Model
+	if n.Org != "" {
+		s = n.Org + "/" + s
+	}
+	if n.Version != "" {
+		s += "@" + n.Version
+	}
+	return s
+}
+
+// Canonical checks a name as given by a user and returns its canonical form
+func Canonical(s string) (string, error) {
+	name, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return name.String(), nil
+}
+
+// Validate checks that s is a valid name in its canonical form, as names are
+// kept once they entered the daemon
+func Validate(s string) error {
+	name, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	if canonical := name.String(); canonical != s {
+		return fmt.Errorf("%w %q: use %s", ErrInvalid, s, canonical)
+	}
+	return nil
+}
+
+// Join returns the directory of the model named s below dir. The name is
+// validated first, so the directory is always inside dir.
+func Join(dir, s string) (string, error) {
+	if err := Validate(s); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.FromSlash(s)), nil
+}
+
+// ValidVersion reports whether v can be the version part of a name
+func ValidVersion(v string) bool {
+	return len(v) <= maxVersionLength && versionPattern.MatchString(v)
+}
@@ -0,0 +1,60 @@
+package modelname
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	name, err := Parse("meta-llama/Llama-3.1-8B")
+	require.NoError(t, err)
+	assert.Equal(t, Name{Org: "meta-llama", Model: "Llama-3.1-8B"}, name)
+
+	name, err = Parse("test-model")
+	require.NoError(t, err)
+	assert.Equal(t, Name{Model: "test-model"}, name)
+	assert.Equal(t, "test-model", name.String())
+
+	// Both version separators name the same kept version
+	for _, s := range []string{"org/model:v1.2", "org/model@v1.2"} {
+		name, err = Parse(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, Name{Org: "org", Model: "model", Version: "v1.2"}, name)
+		assert.Equal(t, "org/model@v1.2", name.String())
+	}
+
+	for _, s := range []string{
+		"", "/", "org/", "/model", "org//model", "a/b/c",
+		"..", "../etc/passwd", "org/..", "org/.hidden", "org/model name",
+		`org\model`, "org/model@", "org/model@v1/x", "org/model:v1:v2",
+		"org/" + strings.Repeat("m", 97),
+	} {
+		_, err := Parse(s)
+		assert.ErrorIs(t, err, ErrInvalid, s)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate("org/model"))
+	assert.NoError(t, Validate("org/model@0123abcd"))
+	assert.ErrorContains(t, Validate("org/model:v1"), "use org/model@v1")
+	assert.ErrorIs(t, Validate("org/../model"), ErrInvalid)
+
+	canonical, err := Canonical("org/model:v1")
+	require.NoError(t, err)
+	assert.Equal(t, "org/model@v1", canonical)
+}
+
+func TestJoin(t *testing.T) {
+	dir := t.TempDir()
+	path, err := Join(dir, "org/model")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "org", "model"), path)
+
+	_, err = Join(dir, "../outside")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
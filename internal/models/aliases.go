@@ -0,0 +1,147 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// AliasesFileName is the file in the registry directory holding the aliases
+const AliasesFileName = "aliases.json"
+
+// Aliases maps alternative names of local models to their names, so a model
+// can be referred to by a short name or by the name it had before a rename
+type Aliases struct {
+	path    string
+	aliases map[string]string
+}
+
+// Alias is an alternative name of a local model
+type Alias struct {
+	Alias string `json:"alias"`
+	Model string `json:"model"`
+}
+
+// LoadAliases reads the aliases kept in registryDir. A missing file holds no
+// aliases.
+func LoadAliases(registryDir string) (*Aliases, error) {
+	a := &Aliases{
+		path:    filepath.Join(registryDir, AliasesFileName),
+		aliases: make(map[string]string),
+	}
+	data, err := os.ReadFile(a.path)
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aliases: %w", err)
+	}
+	if err := json.Unmarshal(data, &a.aliases); err != nil {
+		return nil, fmt.Errorf("failed to parse aliases: %w", err)
+	}
+	return a, nil
+}
+
+// Save writes the aliases to the registry directory
+func (a *Aliases) Save() error {
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return fmt.Errorf("failed to create registry directory: %w", err)
+	}
+	data, err := json.MarshalIndent(a.aliases, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal aliases: %w", err)
+	}
+
+	// Write to a temporary file first, so a crash never leaves a truncated
+	// alias file behind
+	tmp := a.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write aliases: %w", err)
+	}
+	if err := os.Rename(tmp, a.path); err != nil {
+		return fmt.Errorf("failed to write aliases: %w", err)
+	}
+	return nil
+}
+
+// Resolve returns the model an alias points to, or name itself if it is not
+// an alias
+func (a *Aliases) Resolve(name string) string {
+	if model, ok := a.aliases[name]; ok {
+		return model
+	}
+	return name
+}
+
+// Set points alias to model, replacing what it pointed to before. Aliases
+// point to models, not to other aliases.
+func (a *Aliases) Set(alias, model string) error {
+	if alias == "" || model == "" {
+		return fmt.Errorf("alias and model name must not be empty")
+	}
+	model = a.Resolve(model)
+	if alias == model {
+		return fmt.Errorf("alias %s can't point to itself", alias)
+	}
+	a.aliases[alias] = model
+	return nil
+}
+
+// Remove deletes an alias and reports whether it existed
+func (a *Aliases) Remove(alias string) bool {
+	if _, ok := a.aliases[alias]; !ok {
+		return false
+	}
+	delete(a.aliases, alias)
+	return true
+}
+
+// RemoveModel deletes the aliases of a model and returns them
+func (a *Aliases) RemoveModel(model string) []string {
+	var removed []string
+	for alias, target := range a.aliases {
+		if target == model {
+			delete(a.aliases, alias)
+			removed = append(removed, alias)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// Rename points the aliases of model from to model to. An alias named to
+// is dropped, the model takes its place.
+func (a *Aliases) Rename(from, to string) {
+	delete(a.aliases, to)
+	for alias, target := range a.aliases {
+		if target == from {
+			a.aliases[alias] = to
+		}
+	}
+}
+
+// Of returns the aliases of a model, sorted
+func (a *Aliases) Of(model string) []string {
+	var aliases []string
+	for alias, target := range a.aliases {
+		if target == model {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// List returns all aliases sorted by alias
+func (a *Aliases) List() []Alias {
+	list := make([]Alias, 0, len(a.aliases))
+	for alias, model := range a.aliases {
+		list = append(list, Alias{Alias: alias, Model: model})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Alias < list[j].Alias
+	})
+	return list
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliases(t *testing.T) {
+	dir := t.TempDir()
+
+	aliases, err := LoadAliases(dir)
+	require.NoError(t, err)
+	assert.Empty(t, aliases.List())
+
+	require.NoError(t, aliases.Set("llama", "meta-llama/Llama-3.1-8B"))
+	// Aliases of aliases point to the model itself
+	require.NoError(t, aliases.Set("l", "llama"))
+	assert.Error(t, aliases.Set("org/model", "org/model"))
+	assert.Error(t, aliases.Set("", "org/model"))
+
+	assert.Equal(t, "meta-llama/Llama-3.1-8B", aliases.Resolve("l"))
+	assert.Equal(t, "org/other", aliases.Resolve("org/other"))
+	assert.Equal(t, []string{"l", "llama"}, aliases.Of("meta-llama/Llama-3.1-8B"))
+	require.NoError(t, aliases.Save())
+
+	loaded, err := LoadAliases(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []Alias{
+		{Alias: "l", Model: "meta-llama/Llama-3.1-8B"},
+		{Alias: "llama", Model: "meta-llama/Llama-3.1-8B"},
+	}, loaded.List())
+
+	// A rename retargets the aliases and replaces an alias with the new name
+	loaded.Rename("meta-llama/Llama-3.1-8B", "llama")
+	assert.Equal(t, []Alias{{Alias: "l", Model: "llama"}}, loaded.List())
+
+	assert.True(t, loaded.Remove("l"))
+	assert.False(t, loaded.Remove("l"))
+
+	require.NoError(t, loaded.Set("a", "org/model"))
+	require.NoError(t, loaded.Set("b", "org/model"))
+	assert.Equal(t, []string{"a", "b"}, loaded.RemoveModel("org/model"))
+	assert.Empty(t, loaded.List())
+}
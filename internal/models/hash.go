@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+)
+
+// Size of the reads of HashFileRate
+const hashChunkSize = 1024 * 1024
+
+// HashFileRate returns the SHA256 of the file at path, reading at most rate
+// bytes per second so that hashing in the background leaves the disk to
+// transfers. It stops when ctx is done.
+func HashFileRate(ctx context.Context, path string, rate int64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	buf := make([]byte, hashChunkSize)
+	start := time.Now()
+	var read int64
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			hasher.Write(buf[:n])
+			read += int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		var wait time.Duration
+		if rate > 0 {
+			due := time.Duration(float64(read) / float64(rate) * float64(time.Second))
+			wait = due - time.Since(start)
+		}
+		if wait <= 0 {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			continue
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
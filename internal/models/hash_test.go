@@ -0,0 +1,40 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashFileRate(t *testing.T) {
+	data := make([]byte, 3*hashChunkSize/2)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	path := filepath.Join(t.TempDir(), "model.bin")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	sum := sha256.Sum256(data)
+
+	hash, err := HashFileRate(context.Background(), path, 0)
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), hash)
+
+	// Reading 1.5 MB at 5 MB/s takes about 300ms
+	start := time.Now()
+	hash, err = HashFileRate(context.Background(), path, 5*1024*1024)
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), hash)
+	assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = HashFileRate(ctx, path, 1024)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,248 @@
+package models
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// HFRevision is the ref linked models are published under in the
+// HuggingFace cache
+const HFRevision = "main"
+
+// commitHashPattern matches git commit hashes, which the HF cache uses to
+// name snapshots
+var commitHashPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// IsHFCommitHash reports whether revision is a commit hash rather than a
+// branch or tag name
+func IsHFCommitHash(revision string) bool {
+	return commitHashPattern.MatchString(revision)
+}
+
+// HFCacheDir returns the HuggingFace Hub cache directory, honoring the same
+// environment variables as the huggingface_hub library
+func HFCacheDir() (string, error) {
+	if dir := os.Getenv("HF_HUB_CACHE"); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("HF_HOME"); dir != "" {
+		return filepath.Join(dir, "hub"), nil
+	}
+
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		cacheHome = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(cacheHome, "huggingface", "hub"), nil
+}
+
+// HFRepoDir returns the cache directory of a model repository, e.g.
+// models--meta-llama--Llama-3.1-8B
+func HFRepoDir(cacheDir, modelName string) string {
+	return filepath.Join(cacheDir, "models--"+strings.ReplaceAll(modelName, "/", "--"))
+}
+
+// HFLinkOptions control how model files are exported to the HF cache
+type HFLinkOptions struct {
+	// Hardlink the files instead of symlinking them. Hardlinks survive the
+	// model being moved but need the cache on the same filesystem.
+	Hardlink bool
+
+	// Revision names the snapshot, a commit hash is derived from the model
+	// files if empty
+	Revision string
+}
+
+// HFLinkResult describes a model exported to the HF cache
+type HFLinkResult struct {
+	RepoDir     string
+	SnapshotDir string
+	Revision    string
+	Files       int
+}
+
+// LinkToHFCache exports the model in modelDir into the HuggingFace cache
+// layout below cacheDir. The cache only holds links into modelDir, so tools
+// like transformers and vLLM can load the model without a second copy:
+//
+//	models--org--name/
+//	  blobs/<id>                  link to the file in the Silmaril store
+//	  refs/main                   snapshot revision
+//	  snapshots/<revision>/<path> symlink to ../../blobs/<id>
+func LinkToHFCache(cacheDir, modelName, modelDir string, opts HFLinkOptions) (*HFLinkResult, error) {
+	if !strings.Contains(modelName, "/") {
+		return nil, fmt.Errorf("model name %q must have the form org/name", modelName)
+	}
+
+	modelDir, err := filepath.Abs(modelDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve model directory: %w", err)
+	}
+	files, err := hfCacheFiles(modelDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("model directory %s has no files", modelDir)
+	}
+
+	revision := opts.Revision
+	if revision == "" {
+		revision = hfRevision(modelName, modelDir, files)
+	}
+	if !commitHashPattern.MatchString(revision) {
+		return nil, fmt.Errorf("revision %q is not a commit hash", revision)
+	}
+
+	repoDir := HFRepoDir(cacheDir, modelName)
+	blobsDir := filepath.Join(repoDir, "blobs")
+	snapshotDir := filepath.Join(repoDir, "snapshots", revision)
+
+	// Rebuild the snapshot so files removed from the model disappear
+	if err := os.RemoveAll(snapshotDir); err != nil {
+		return nil, fmt.Errorf("failed to clear snapshot: %w", err)
+	}
+	for _, dir := range []string{blobsDir, snapshotDir, filepath.Join(repoDir, "refs")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cache directory: %w", err)
+		}
+	}
+
+	for _, relPath := range files {
+		src := filepath.Join(modelDir, filepath.FromSlash(relPath))
+		blob := filepath.Join(blobsDir, HFBlobID(modelName, relPath))
+
+		if err := os.Remove(blob); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to replace blob: %w", err)
+		}
+		if opts.Hardlink {
+			err = os.Link(src, blob)
+		} else {
+			err = os.Symlink(src, blob)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to link %s: %w", relPath, err)
+		}
+
+		// Snapshot entries point at their blob relative to the repo, like the
+		// ones huggingface_hub creates
+		link := filepath.Join(snapshotDir, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+		}
+		target, err := filepath.Rel(filepath.Dir(link), blob)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Symlink(target, link); err != nil {
+			return nil, fmt.Errorf("failed to link %s: %w", relPath, err)
+		}
+	}
+
+	refPath := filepath.Join(repoDir, "refs", HFRevision)
+	if err := os.WriteFile(refPath, []byte(revision), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write ref: %w", err)
+	}
+
+	return &HFLinkResult{
+		RepoDir:     repoDir,
+		SnapshotDir: snapshotDir,
+		Revision:    revision,
+		Files:       len(files),
+	}, nil
+}
+
+// UnlinkFromHFCache removes a model exported with LinkToHFCache. Files in the
+// Silmaril store are not touched.
+func UnlinkFromHFCache(cacheDir, modelName string) error {
+	repoDir := HFRepoDir(cacheDir, modelName)
+	if _, err := os.Stat(repoDir); os.IsNotExist(err) {
+		return fmt.Errorf("model %s is not in the HuggingFace cache", modelName)
+	}
+	if err := os.RemoveAll(repoDir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", repoDir, err)
+	}
+	return nil
+}
+
+// HFSnapshot returns the revision and files of the model in modelDir, as they
+// appear in the HF cache and the HF proxy
+func HFSnapshot(modelName, modelDir string) (string, []string, error) {
+	files, err := hfCacheFiles(modelDir)
+	if err != nil {
+		return "", nil, err
+	}
+	return hfRevision(modelName, modelDir, files), files, nil
+}
+
+// hfCacheFiles lists the model files to export, skipping the hidden files
+// Silmaril and git keep next to them
+func hfCacheFiles(modelDir string) ([]string, error) {
+	root, err := filepath.EvalSymlinks(modelDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve model directory: %w", err)
+	}
+
+	var files []string
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && path != root {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list model files: %w", err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// hfRevision derives a stable commit hash for a model from its file list, so
+// linking the same model again reuses its snapshot
+func hfRevision(modelName, modelDir string, files []string) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "silmaril:%s\n", modelName)
+	for _, relPath := range files {
+		var size int64
+		if info, err := os.Stat(filepath.Join(modelDir, filepath.FromSlash(relPath))); err == nil {
+			size = info.Size()
+		}
+		fmt.Fprintf(h, "%s %d\n", relPath, size)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HFBlobID names the blob of a model file. The hub names blobs after their
+// content hash; hashing large weights just to name a link is too slow, so the
+// ID is derived from the file path instead.
+func HFBlobID(modelName, relPath string) string {
+	sum := sha256.Sum256([]byte(modelName + "/" + relPath))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkToHFCache(t *testing.T) {
+	modelDir := filepath.Join(t.TempDir(), "org", "model")
+	require.NoError(t, os.MkdirAll(filepath.Join(modelDir, "tokenizer"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, HFConfigFile), []byte(`{"model_type": "llama"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "tokenizer", "vocab.json"), []byte(`{}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, ManifestFileName), []byte(`{}`), 0644))
+	cacheDir := t.TempDir()
+
+	result, err := LinkToHFCache(cacheDir, "org/model", modelDir, HFLinkOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cacheDir, "models--org--model"), result.RepoDir)
+	assert.Equal(t, 2, result.Files)
+
+	// Files are readable through the snapshot, hidden files are not exported
+	data, err := os.ReadFile(filepath.Join(result.SnapshotDir, HFConfigFile))
+	require.NoError(t, err)
+	assert.Equal(t, `{"model_type": "llama"}`, string(data))
+	assert.FileExists(t, filepath.Join(result.SnapshotDir, "tokenizer", "vocab.json"))
+	assert.NoFileExists(t, filepath.Join(result.SnapshotDir, ManifestFileName))
+
+	ref, err := os.ReadFile(filepath.Join(result.RepoDir, "refs", "main"))
+	require.NoError(t, err)
+	assert.Equal(t, result.Revision, string(ref))
+
+	// Linking again reuses the snapshot
+	again, err := LinkToHFCache(cacheDir, "org/model", modelDir, HFLinkOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, result.Revision, again.Revision)
+
+	require.NoError(t, UnlinkFromHFCache(cacheDir, "org/model"))
+	assert.NoDirExists(t, result.RepoDir)
+	assert.FileExists(t, filepath.Join(modelDir, HFConfigFile))
+	assert.Error(t, UnlinkFromHFCache(cacheDir, "org/model"))
+}
+
+func TestLinkToHFCacheHardlink(t *testing.T) {
+	modelDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, HFConfigFile), []byte(`{}`), 0644))
+	cacheDir := t.TempDir()
+
+	revision := "0123456789abcdef0123456789abcdef01234567"
+	result, err := LinkToHFCache(cacheDir, "org/model", modelDir, HFLinkOptions{Hardlink: true, Revision: revision})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(result.RepoDir, "snapshots", revision), result.SnapshotDir)
+
+	srcInfo, err := os.Stat(filepath.Join(modelDir, HFConfigFile))
+	require.NoError(t, err)
+	linkInfo, err := os.Stat(filepath.Join(result.SnapshotDir, HFConfigFile))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(srcInfo, linkInfo))
+
+	_, err = LinkToHFCache(cacheDir, "org/model", modelDir, HFLinkOptions{Revision: "main"})
+	assert.Error(t, err)
+	_, err = LinkToHFCache(cacheDir, "model", modelDir, HFLinkOptions{})
+	assert.Error(t, err)
+}
+
+func TestHFCacheDir(t *testing.T) {
+	t.Setenv("HF_HUB_CACHE", "")
+	t.Setenv("HF_HOME", "/data/hf")
+	dir, err := HFCacheDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/data/hf", "hub"), dir)
+
+	t.Setenv("HF_HUB_CACHE", "/data/hub")
+	dir, err = HFCacheDir()
+	require.NoError(t, err)
+	assert.Equal(t, "/data/hub", dir)
+}
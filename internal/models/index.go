@@ -0,0 +1,152 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/store"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// Versions kept per model, the oldest are dropped first
+const maxModelVersions = 100
+
+// modelRecord is the manifest of a model cached in the database, with the
+// state of the manifest file it was read from
+type modelRecord struct {
+	Manifest *types.ModelManifest `json:"manifest"`
+	ModTime  int64                `json:"mod_time"` // Of the manifest file, in nanoseconds
+	Size     int64                `json:"size"`
+}
+
+// ModelVersion is a version a local model had
+type ModelVersion struct {
+	Version   string    `json:"version"`
+	MagnetURI string    `json:"magnet_uri,omitempty"`
+	SeenAt    time.Time `json:"seen_at"`
+}
+
+// NewRegistryWithStore creates a registry caching the manifests of the
+// models in st, so scans only parse the manifests that changed, and keeping
+// the versions each model had
+func NewRegistryWithStore(paths *storage.Paths, st *store.Store) (*Registry, error) {
+	r := &Registry{
+		models: make(map[string]*types.ModelManifest),
+		paths:  paths,
+		store:  st,
+	}
+	return r, r.init()
+}
+
+// cachedManifest returns the manifest of the model name read from the file
+// at path, from the database if the file didn't change since it was cached
+func (r *Registry) cachedManifest(name, path string) (*types.ModelManifest, error) {
+	if r.store == nil {
+		return r.loadManifest(path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var record modelRecord
+	found, err := r.store.Get(store.Models, name, &record)
+	if err == nil && found && record.Manifest != nil &&
+		record.ModTime == info.ModTime().UnixNano() && record.Size == info.Size() {
+		return record.Manifest, nil
+	}
+	return r.loadManifest(path)
+}
+
+// index saves the manifests of the models named to the database, dropping
+// those no longer in the registry, and records versions not seen before.
+// A full index replaces all models. Callers must not hold r.mu.
+func (r *Registry) index(full bool, names ...string) error {
+	if r.store == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	if full {
+		names = names[:0]
+		for name := range r.models {
+			names = append(names, name)
+		}
+	}
+	records := make(map[string]interface{}, len(names))
+	var gone []string
+	for _, name := range names {
+		manifest, ok := r.models[name]
+		if !ok {
+			gone = append(gone, name)
+			continue
+		}
+		record := modelRecord{Manifest: manifest}
+		if dir, err := r.paths.ModelDir(name); err == nil {
+			if info, err := os.Stat(filepath.Join(dir, ManifestFileName)); err == nil {
+				record.ModTime = info.ModTime().UnixNano()
+				record.Size = info.Size()
+			}
+		}
+		records[name] = record
+	}
+	r.mu.RUnlock()
+
+	return r.store.Update(func(tx *store.Tx) error {
+		if full {
+			if err := tx.Replace(store.Models, records); err != nil {
+				return err
+			}
+		} else {
+			for name, record := range records {
+				if err := tx.Put(store.Models, name, record); err != nil {
+					return err
+				}
+			}
+			for _, name := range gone {
+				if err := tx.Delete(store.Models, name); err != nil {
+					return err
+				}
+			}
+		}
+		for name, record := range records {
+			if err := recordVersion(tx, name, record.(modelRecord).Manifest); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// recordVersion adds the version of manifest to the versions of the model
+// name, unless it is the latest already
+func recordVersion(tx *store.Tx, name string, manifest *types.ModelManifest) error {
+	var versions []ModelVersion
+	if _, err := tx.Get(store.Versions, name, &versions); err != nil {
+		return err
+	}
+	if n := len(versions); n > 0 && versions[n-1].Version == manifest.Version && versions[n-1].MagnetURI == manifest.MagnetURI {
+		return nil
+	}
+	versions = append(versions, ModelVersion{
+		Version:   manifest.Version,
+		MagnetURI: manifest.MagnetURI,
+		SeenAt:    time.Now(),
+	})
+	if len(versions) > maxModelVersions {
+		versions = versions[len(versions)-maxModelVersions:]
+	}
+	return tx.Put(store.Versions, name, versions)
+}
+
+// Versions returns the versions the model name had, the oldest first. They
+// are only kept by registries with a database.
+func (r *Registry) Versions(name string) ([]ModelVersion, error) {
+	if r.store == nil {
+		return nil, nil
+	}
+	var versions []ModelVersion
+	_, err := r.store.Get(store.Versions, name, &versions)
+	return versions, err
+}
@@ -1,10 +1,38 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
 	"github.com/silmaril/silmaril/pkg/types"
 )
 
 // Re-export types for backward compatibility
 type ModelManifest = types.ModelManifest
 type InferenceHints = types.InferenceHints
-type ModelFile = types.ModelFile
\ No newline at end of file
+type ModelFile = types.ModelFile
+
+// ReadManifest reads the manifest of the model in modelPath
+func ReadManifest(modelPath string) (*types.ModelManifest, error) {
+	data, err := os.ReadFile(filepath.Join(modelPath, ManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest types.ModelManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// WriteManifest writes the manifest of the model in modelPath
+func WriteManifest(modelPath string, manifest *types.ModelManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	return os.WriteFile(filepath.Join(modelPath, ManifestFileName), data, 0644)
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/silmaril/silmaril/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Maximum length of a description extracted from a model card body
+const maxCardDescriptionLength = 500
+
+// modelCardFiles are the file names checked for a model card, in order
+var modelCardFiles = []string{"README.md", "readme.md", "README.MD", "MODEL_CARD.md"}
+
+// ModelCard holds the metadata extracted from a HuggingFace-style model card
+type ModelCard struct {
+	Description string
+	License     string
+	Tags        []string
+	Languages   []string
+	PipelineTag string
+}
+
+// modelCardFrontMatter mirrors the YAML front matter used by HuggingFace model cards
+type modelCardFrontMatter struct {
+	License     string   `yaml:"license"`
+	Tags        yamlList `yaml:"tags"`
+	Language    yamlList `yaml:"language"`
+	Languages   yamlList `yaml:"languages"`
+	PipelineTag string   `yaml:"pipeline_tag"`
+	Description string   `yaml:"description"`
+}
+
+// yamlList accepts either a single scalar or a sequence of scalars
+type yamlList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (l *yamlList) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Value != "" {
+			*l = []string{node.Value}
+		}
+		return nil
+	case yaml.SequenceNode:
+		var values []string
+		if err := node.Decode(&values); err != nil {
+			return err
+		}
+		*l = values
+		return nil
+	default:
+		return nil
+	}
+}
+
+// LoadModelCard looks for a model card in the model directory and parses it
+func LoadModelCard(modelPath string) (*ModelCard, error) {
+	for _, name := range modelCardFiles {
+		cardPath := filepath.Join(modelPath, name)
+		if _, err := os.Stat(cardPath); err == nil {
+			return ParseModelCard(cardPath)
+		}
+	}
+	return nil, fmt.Errorf("no model card found in %s", modelPath)
+}
+
+// ParseModelCard parses a README.md model card with optional YAML front matter
+func ParseModelCard(path string) (*ModelCard, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	frontMatter, body := splitFrontMatter(string(data))
+
+	card := &ModelCard{}
+	if frontMatter != "" {
+		var fm modelCardFrontMatter
+		if err := yaml.Unmarshal([]byte(frontMatter), &fm); err != nil {
+			return nil, fmt.Errorf("failed to parse model card front matter: %w", err)
+		}
+		card.License = fm.License
+		card.Tags = normalizeList(fm.Tags)
+		card.Languages = normalizeList(append(fm.Language, fm.Languages...))
+		card.PipelineTag = strings.TrimSpace(fm.PipelineTag)
+		card.Description = strings.TrimSpace(fm.Description)
+	}
+
+	if card.Description == "" {
+		card.Description = extractDescription(body)
+	}
+
+	return card, nil
+}
+
+// ApplyTo copies the model card metadata into a manifest without
+// overwriting values that were set explicitly
+func (mc *ModelCard) ApplyTo(manifest *types.ModelManifest) {
+	if mc.Description != "" && (manifest.Description == "" || strings.HasPrefix(manifest.Description, "Model imported from")) {
+		manifest.Description = mc.Description
+	}
+	if mc.License != "" && (manifest.License == "" || manifest.License == "Unknown") {
+		manifest.License = mc.License
+	}
+	if len(manifest.Tags) == 0 {
+		manifest.Tags = mc.Tags
+	}
+	if len(manifest.Languages) == 0 {
+		manifest.Languages = mc.Languages
+	}
+	if manifest.PipelineTag == "" {
+		manifest.PipelineTag = mc.PipelineTag
+	}
+}
+
+// splitFrontMatter separates a leading "---" delimited YAML block from the markdown body
+func splitFrontMatter(content string) (string, string) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	if !strings.HasPrefix(content, "---") {
+		return "", content
+	}
+
+	lines := strings.Split(content, "\n")
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			return strings.Join(lines[1:i], "\n"), strings.Join(lines[i+1:], "\n")
+		}
+	}
+
+	// Unterminated front matter, treat everything as body
+	return "", content
+}
+
+// extractDescription returns the first prose paragraph of a markdown body
+func extractDescription(body string) string {
+	var paragraph []string
+	inCodeBlock := false
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "```") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock {
+			continue
+		}
+
+		// Headings, images, tables, lists and HTML are not prose
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") ||
+			strings.HasPrefix(line, "|") || strings.HasPrefix(line, "<") ||
+			strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
+			if len(paragraph) > 0 {
+				break
+			}
+			continue
+		}
+
+		paragraph = append(paragraph, line)
+	}
+
+	description := strings.Join(paragraph, " ")
+	if len(description) > maxCardDescriptionLength {
+		description = description[:maxCardDescriptionLength-3] + "..."
+	}
+	return description
+}
+
+// normalizeList trims, de-duplicates and drops empty values
+func normalizeList(values []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[strings.ToLower(v)] {
+			continue
+		}
+		seen[strings.ToLower(v)] = true
+		result = append(result, v)
+	}
+	return result
+}
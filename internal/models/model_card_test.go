@@ -0,0 +1,114 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModelCardFrontMatter(t *testing.T) {
+	tempDir := t.TempDir()
+	readme := `---
+license: apache-2.0
+language:
+  - en
+  - fr
+tags:
+  - text-generation
+  - llama
+  - Llama
+pipeline_tag: text-generation
+---
+
+# My Model
+
+This model is a fine-tuned llama
+for chat use cases.
+
+## Usage
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "README.md"), []byte(readme), 0644))
+
+	card, err := LoadModelCard(tempDir)
+	require.NoError(t, err)
+
+	assert.Equal(t, "apache-2.0", card.License)
+	assert.Equal(t, []string{"en", "fr"}, card.Languages)
+	assert.Equal(t, []string{"text-generation", "llama"}, card.Tags)
+	assert.Equal(t, "text-generation", card.PipelineTag)
+	assert.Equal(t, "This model is a fine-tuned llama for chat use cases.", card.Description)
+}
+
+func TestParseModelCardScalarLanguage(t *testing.T) {
+	tempDir := t.TempDir()
+	readme := "---\nlanguage: en\ndescription: An explicit description\n---\nBody text.\n"
+	path := filepath.Join(tempDir, "README.md")
+	require.NoError(t, os.WriteFile(path, []byte(readme), 0644))
+
+	card, err := ParseModelCard(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"en"}, card.Languages)
+	assert.Equal(t, "An explicit description", card.Description)
+}
+
+func TestParseModelCardWithoutFrontMatter(t *testing.T) {
+	tempDir := t.TempDir()
+	readme := "# Title\n\n![badge](img.png)\n\n```\ncode\n```\n\nFirst paragraph.\n\nSecond paragraph.\n"
+	path := filepath.Join(tempDir, "README.md")
+	require.NoError(t, os.WriteFile(path, []byte(readme), 0644))
+
+	card, err := ParseModelCard(path)
+	require.NoError(t, err)
+
+	assert.Empty(t, card.Tags)
+	assert.Equal(t, "First paragraph.", card.Description)
+}
+
+func TestLoadModelCardMissing(t *testing.T) {
+	_, err := LoadModelCard(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestModelCardApplyTo(t *testing.T) {
+	card := &ModelCard{
+		Description: "From the card",
+		License:     "mit",
+		Tags:        []string{"chat"},
+		Languages:   []string{"en"},
+		PipelineTag: "text-generation",
+	}
+
+	t.Run("fills defaults", func(t *testing.T) {
+		manifest := &types.ModelManifest{
+			Name:        "org/model",
+			Description: "Model imported from org/model",
+			License:     "Unknown",
+		}
+		card.ApplyTo(manifest)
+
+		assert.Equal(t, "From the card", manifest.Description)
+		assert.Equal(t, "mit", manifest.License)
+		assert.Equal(t, []string{"chat"}, manifest.Tags)
+		assert.Equal(t, []string{"en"}, manifest.Languages)
+		assert.Equal(t, "text-generation", manifest.PipelineTag)
+		assert.Equal(t, []string{"chat", "en", "text-generation"}, manifest.Keywords())
+	})
+
+	t.Run("keeps explicit values", func(t *testing.T) {
+		manifest := &types.ModelManifest{
+			Description: "Explicit",
+			License:     "apache-2.0",
+			Tags:        []string{"custom"},
+		}
+		card.ApplyTo(manifest)
+
+		assert.Equal(t, "Explicit", manifest.Description)
+		assert.Equal(t, "apache-2.0", manifest.License)
+		assert.Equal(t, []string{"custom"}, manifest.Tags)
+	})
+}
@@ -8,11 +8,14 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/silmaril/silmaril/internal/modelname"
 	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/store"
 	"github.com/silmaril/silmaril/pkg/types"
 )
 
@@ -23,9 +26,14 @@ const (
 
 // Registry manages model manifests dynamically
 type Registry struct {
-	mu       sync.RWMutex
-	models   map[string]*types.ModelManifest
-	paths    *storage.Paths
+	mu        sync.RWMutex
+	scanMu    sync.Mutex // Serializes scans, reads go on while one runs
+	models    map[string]*types.ModelManifest
+	paths     *storage.Paths
+	aliases   *Aliases
+	dirs      map[string]bool // Directories the scans looked for models in
+	scannedAt time.Time
+	store     *store.Store // Caches manifests between scans, may be nil
 }
 
 // NewRegistry creates a new registry instance and scans for models
@@ -34,81 +42,331 @@ func NewRegistry(paths *storage.Paths) (*Registry, error) {
 		models: make(map[string]*types.ModelManifest),
 		paths:  paths,
 	}
-	
+	return r, r.init()
+}
+
+// init creates the directories and scans for existing models
+func (r *Registry) init() error {
 	// Initialize directories
-	if err := paths.Initialize(); err != nil {
-		return nil, fmt.Errorf("failed to initialize paths: %w", err)
+	if err := r.paths.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize paths: %w", err)
 	}
 	
 	// Scan for existing models
 	if err := r.ScanModels(); err != nil {
-		return nil, fmt.Errorf("failed to scan models: %w", err)
+		return fmt.Errorf("failed to scan models: %w", err)
 	}
 	
-	return r, nil
+	return nil
+}
+
+// Paths returns the storage paths of the registry
+func (r *Registry) Paths() *storage.Paths {
+	return r.paths
 }
 
-// ScanModels scans the models directory and builds the registry
+// ScanModels scans the models directory and builds the registry. The models
+// found replace those of the previous scan at once, so models whose
+// directory is gone are dropped and readers never see a partial scan.
 func (r *Registry) ScanModels() error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	r.scanMu.Lock()
+	defer r.scanMu.Unlock()
+	
+	aliases, err := LoadAliases(r.paths.RegistryDir())
+	if err != nil {
+		return err
+	}
 	
+	found := make(map[string]*types.ModelManifest)
 	modelsDir := r.paths.ModelsDir()
+	dirs := map[string]bool{modelsDir: true}
 	
 	// Check if models directory exists
-	if _, err := os.Stat(modelsDir); os.IsNotExist(err) {
-		// No models directory yet, that's ok
-		return nil
+	if _, err := os.Stat(modelsDir); err == nil {
+		if err := r.scan(modelsDir, modelsDir, found, dirs); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	
+	r.mu.Lock()
+	r.models = found
+	r.aliases = aliases
+	r.dirs = dirs
+	r.scannedAt = time.Now()
+	r.mu.Unlock()
+	return r.index(true)
+}
+
+// Update scans again only the parts of the models directory that changed,
+// given as the paths of changed files and directories. It returns the names
+// of the models that appeared and disappeared.
+func (r *Registry) Update(changed []string) (added, removed []string, err error) {
+	r.scanMu.Lock()
+	defer r.scanMu.Unlock()
+	
+	modelsDir := r.paths.ModelsDir()
+	roots := r.updateRoots(modelsDir, changed)
+	
+	found := make(map[string]*types.ModelManifest)
+	dirs := make(map[string]bool)
+	for _, root := range roots {
+		if _, err := os.Stat(root); err != nil {
+			continue // Gone, its models are dropped below
+		}
+		if err := r.scan(root, modelsDir, found, dirs); err != nil {
+			return nil, nil, err
+		}
+	}
+	
+	r.mu.Lock()
+	for _, root := range roots {
+		for name := range r.models {
+			// A name without a directory can't be on disk anymore
+			dir, err := r.paths.ModelDir(name)
+			if _, ok := found[name]; !ok && (err != nil || withinDir(dir, root)) {
+				delete(r.models, name)
+				removed = append(removed, name)
+			}
+		}
+		for dir := range r.dirs {
+			if withinDir(dir, root) {
+				delete(r.dirs, dir)
+			}
+		}
+	}
+	for name, manifest := range found {
+		if _, ok := r.models[name]; !ok {
+			added = append(added, name)
+		}
+		r.models[name] = manifest
+	}
+	for dir := range dirs {
+		r.dirs[dir] = true
+	}
+	r.dirs[modelsDir] = true
+	r.mu.Unlock()
+	
+	names := append([]string(nil), removed...)
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed, r.index(false, names...)
+}
+
+// updateRoots returns the directories to scan again for changes to paths:
+// the model a changed path is part of, or else the changed directory, or
+// the directory of a changed file. Callers must hold r.scanMu.
+func (r *Registry) updateRoots(modelsDir string, paths []string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	var roots []string
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		if !withinDir(path, modelsDir) {
+			continue
+		}
+		switch filepath.Base(path) {
+		case ManifestFileName, HFConfigFile, storage.PartialMarkerFile, storage.QuarantineMarkerFile:
+			// What makes a directory a model
+			path = filepath.Dir(path)
+		default:
+			info, err := os.Lstat(path)
+			if err == nil && info.Mode().IsRegular() {
+				path = filepath.Dir(path)
+			} else if err != nil && !r.dirs[path] {
+				// A removed file
+				path = filepath.Dir(path)
+			}
+		}
+		
+		// Changes within a model rescan the model
+		for dir := path; dir != modelsDir && withinDir(dir, modelsDir); dir = filepath.Dir(dir) {
+			name := filepath.ToSlash(strings.TrimPrefix(dir, modelsDir+string(filepath.Separator)))
+			if _, ok := r.models[name]; ok {
+				path = dir
+				break
+			}
+		}
+		roots = append(roots, path)
 	}
 	
-	// Walk through the models directory
-	err := filepath.Walk(modelsDir, func(path string, info os.FileInfo, err error) error {
+	// Directories within others are scanned with them
+	sort.Strings(roots)
+	var outer []string
+	for _, root := range roots {
+		if len(outer) > 0 && withinDir(root, outer[len(outer)-1]) {
+			continue
+		}
+		outer = append(outer, root)
+	}
+	return outer
+}
+
+// withinDir reports whether path is dir or inside it
+func withinDir(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// scan walks root within the models directory, adds the models in it to
+// found and the directories it looked in to dirs
+func (r *Registry) scan(root, modelsDir string, found map[string]*types.ModelManifest, dirs map[string]bool) error {
+	// Walk through the directory
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip problematic paths
 		}
 		
+		// Models published in place are links to their original directory.
+		// Walk does not follow links, so look for a model behind them here.
+		if info.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Stat(path); err == nil && target.IsDir() {
+				r.addModel(path, modelsDir, found)
+			}
+			return nil
+		}
+		
 		// Skip if not a directory
 		if !info.IsDir() {
 			return nil
 		}
+		// Hidden directories, such as the trash, hold no models
+		if path != modelsDir && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		dirs[path] = true
 		
 		// Skip the root models directory itself
 		if path == modelsDir {
 			return nil
 		}
 		
-		// Check for Silmaril manifest
-		manifestPath := filepath.Join(path, ManifestFileName)
-		if manifest, err := r.loadManifest(manifestPath); err == nil {
-			// Found a Silmaril-managed model
-			modelName := strings.TrimPrefix(path, modelsDir+string(filepath.Separator))
-			modelName = filepath.ToSlash(modelName) // Convert to forward slashes
-			manifest.Name = modelName // Ensure name matches directory
-			r.models[modelName] = manifest
-			return filepath.SkipDir // Don't recurse into this model's subdirectories
+		// Skip models that are still being downloaded, whose download was
+		// cancelled or isn't verified yet
+		if storage.IsPartial(path) || storage.IsQuarantined(path) {
+			return filepath.SkipDir
 		}
 		
-		// Check if this looks like a HuggingFace model (has config.json)
-		configPath := filepath.Join(path, HFConfigFile)
-		if _, err := os.Stat(configPath); err == nil {
-			// Found a potential model without Silmaril manifest
-			modelName := strings.TrimPrefix(path, modelsDir+string(filepath.Separator))
-			modelName = filepath.ToSlash(modelName)
-			
-			// Generate a manifest for this model
-			manifest, err := r.generateManifest(path, modelName)
-			if err == nil {
-				r.models[modelName] = manifest
-				// Save the generated manifest
-				r.saveManifestToDisk(manifest)
-			}
-			return filepath.SkipDir
+		if r.addModel(path, modelsDir, found) {
+			return filepath.SkipDir // Don't recurse into this model's subdirectories
 		}
 		
 		return nil
 	})
+}
+
+// addModel adds the model in the directory at path to found, if it holds one
+func (r *Registry) addModel(path, modelsDir string, found map[string]*types.ModelManifest) bool {
+	modelName := strings.TrimPrefix(path, modelsDir+string(filepath.Separator))
+	modelName = filepath.ToSlash(modelName) // Convert to forward slashes
+	
+	// Models in directories whose path is no valid name couldn't be
+	// addressed, so they are left out
+	invalidName := modelname.Validate(modelName)
+	
+	// Check for Silmaril manifest
+	manifestPath := filepath.Join(path, ManifestFileName)
+	if manifest, err := r.cachedManifest(modelName, manifestPath); err == nil {
+		if invalidName != nil {
+			fmt.Printf("[Registry] Skipping %s: %v\n", path, invalidName)
+			return true
+		}
+		// Found a Silmaril-managed model
+		manifest.Name = modelName // Ensure name matches directory
+		found[modelName] = manifest
+		return true
+	}
+	
+	// Check if this looks like a HuggingFace model (has config.json)
+	configPath := filepath.Join(path, HFConfigFile)
+	if _, err := os.Stat(configPath); err == nil {
+		if invalidName != nil {
+			fmt.Printf("[Registry] Skipping %s: %v\n", path, invalidName)
+			return true
+		}
+		// Found a potential model without Silmaril manifest, generate a
+		// manifest for it
+		manifest, err := r.generateManifest(path, modelName, nil)
+		if err == nil {
+			found[modelName] = manifest
+			// Save the generated manifest
+			r.saveManifestToDisk(manifest)
+		}
+		return true
+	}
+	
+	return false
+}
+
+// LoadModel reads the model in the directory of name into the registry,
+// without scanning the other models. A directory that no longer holds a
+// model drops it from the registry.
+func (r *Registry) LoadModel(name string) (*types.ModelManifest, error) {
+	modelsDir := r.paths.ModelsDir()
+	path, err := r.paths.ModelDir(name)
+	if err != nil {
+		return nil, err
+	}
+	
+	found := make(map[string]*types.ModelManifest)
+	if info, err := os.Stat(path); err == nil && info.IsDir() && !storage.IsPartial(path) && !storage.IsQuarantined(path) {
+		r.addModel(path, modelsDir, found)
+	}
+	
+	r.mu.Lock()
+	manifest, ok := found[name]
+	if !ok {
+		delete(r.models, name)
+	} else {
+		r.models[name] = manifest
+	}
+	r.mu.Unlock()
+	
+	if err := r.index(false, name); err != nil {
+		return nil, fmt.Errorf("failed to save model %s to the database: %w", name, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("model %s not found in registry", name)
+	}
+	return manifest, nil
+}
+
+// ReloadAliases reads the aliases again after they changed
+func (r *Registry) ReloadAliases() error {
+	aliases, err := LoadAliases(r.paths.RegistryDir())
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.aliases = aliases
+	r.mu.Unlock()
+	return nil
+}
+
+// Dirs returns the directories the last scan looked for models in: the
+// models directory, those grouping models and those of the models. Changes
+// to them are what a new scan would pick up.
+func (r *Registry) Dirs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	
-	return err
+	dirs := make([]string, 0, len(r.dirs))
+	for dir := range r.dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// ScannedAt returns when the models directory was last scanned
+func (r *Registry) ScannedAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.scannedAt
 }
 
 // loadManifest loads a Silmaril manifest from disk
@@ -126,8 +384,15 @@ func (r *Registry) loadManifest(path string) (*types.ModelManifest, error) {
 	return &manifest, nil
 }
 
+// GenerateManifest creates a manifest describing the model in modelPath from
+// its config, model card and files. The files left out by ignore, or by the
+// ignore file of the model if it is nil, are not listed.
+func GenerateManifest(modelPath, modelName string, ignore *storage.Ignore) (*types.ModelManifest, error) {
+	return (&Registry{}).generateManifest(modelPath, modelName, ignore)
+}
+
 // generateManifest creates a manifest for a model without one
-func (r *Registry) generateManifest(modelPath, modelName string) (*types.ModelManifest, error) {
+func (r *Registry) generateManifest(modelPath, modelName string, ignore *storage.Ignore) (*types.ModelManifest, error) {
 	manifest := &types.ModelManifest{
 		Name:        modelName,
 		Version:     "unknown",
@@ -167,18 +432,41 @@ func (r *Registry) generateManifest(modelPath, modelName string) (*types.ModelMa
 		}
 	}
 	
+	// Pull description, tags and languages from the model card if present
+	if card, err := LoadModelCard(modelPath); err == nil {
+		card.ApplyTo(manifest)
+	}
+	
 	// Scan files in the model directory
 	var totalSize int64
 	var files []types.ModelFile
 	
-	err := filepath.Walk(modelPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
+	root := storage.ResolveModelDir(modelPath)
+	if ignore == nil {
+		var err error
+		if ignore, err = storage.LoadIgnore(root, nil, nil); err != nil {
+			return nil, err
+		}
+	}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == root {
 			return nil
 		}
 		
-		relPath, _ := filepath.Rel(modelPath, path)
+		relPath, _ := filepath.Rel(root, path)
 		relPath = filepath.ToSlash(relPath)
 		
+		// Only the files that are published are part of the model
+		if ignore.Ignored(relPath, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		
 		// Calculate file hash (expensive for large files, so we'll do it lazily)
 		hash := ""
 		if info.Size() < 100*1024*1024 { // Only hash files < 100MB for now
@@ -223,20 +511,50 @@ func (r *Registry) hashFile(path string) (string, error) {
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
-// GetManifest retrieves a model manifest
+// GetManifest retrieves a model manifest by its name or an alias
 func (r *Registry) GetManifest(name string) (*types.ModelManifest, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	
-	manifest, ok := r.models[name]
+	manifest, ok := r.models[r.resolve(name)]
 	if !ok {
 		return nil, fmt.Errorf("model %s not found in registry", name)
 	}
 	return manifest, nil
 }
 
+// Resolve returns the name of the model name refers to, which is name itself
+// unless it is an alias. A model named like an alias takes precedence.
+func (r *Registry) Resolve(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resolve(name)
+}
+
+// resolve is Resolve for callers holding r.mu
+func (r *Registry) resolve(name string) string {
+	if _, ok := r.models[name]; ok || r.aliases == nil {
+		return name
+	}
+	return r.aliases.Resolve(name)
+}
+
+// Aliases returns the aliases of a model
+func (r *Registry) Aliases(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.aliases == nil {
+		return nil
+	}
+	return r.aliases.Of(name)
+}
+
 // SaveManifest saves a model manifest
 func (r *Registry) SaveManifest(manifest *types.ModelManifest) error {
+	if err := modelname.Validate(manifest.Name); err != nil {
+		return err
+	}
+	
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	
@@ -247,9 +565,39 @@ func (r *Registry) SaveManifest(manifest *types.ModelManifest) error {
 	return r.saveManifestToDisk(manifest)
 }
 
+// SetFileHash records the checksum of a model file and saves the manifest.
+// The file must still have the size it was hashed at.
+func (r *Registry) SetFileHash(name, path string, size int64, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	manifest, ok := r.models[name]
+	if !ok {
+		return fmt.Errorf("model %s not found in registry", name)
+	}
+	for i, f := range manifest.Files {
+		if f.Path != path {
+			continue
+		}
+		if f.Size != size {
+			return fmt.Errorf("%s changed size from %d to %d bytes", path, f.Size, size)
+		}
+		// Callers may hold the old manifest, update a copy
+		updated := *manifest
+		updated.Files = append([]types.ModelFile(nil), manifest.Files...)
+		updated.Files[i].SHA256 = hash
+		r.models[name] = &updated
+		return r.saveManifestToDisk(&updated)
+	}
+	return fmt.Errorf("model %s has no file %s", name, path)
+}
+
 // saveManifestToDisk saves a manifest to the model's directory
 func (r *Registry) saveManifestToDisk(manifest *types.ModelManifest) error {
-	modelPath := r.paths.ModelPath(manifest.Name)
+	modelPath, err := r.paths.ModelDir(manifest.Name)
+	if err != nil {
+		return err
+	}
 	
 	// Ensure model directory exists
 	if err := os.MkdirAll(modelPath, 0755); err != nil {
@@ -257,14 +605,7 @@ func (r *Registry) saveManifestToDisk(manifest *types.ModelManifest) error {
 	}
 	
 	// Save manifest to model directory
-	manifestPath := filepath.Join(modelPath, ManifestFileName)
-	
-	data, err := json.MarshalIndent(manifest, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal manifest: %w", err)
-	}
-	
-	return os.WriteFile(manifestPath, data, 0644)
+	return WriteManifest(modelPath, manifest)
 }
 
 // ListModels returns all model names in the registry
@@ -291,6 +632,26 @@ func (r *Registry) GetAllManifests() []*types.ModelManifest {
 	return manifests
 }
 
+// SearchModels returns manifests matching a search pattern and carrying all of the given tags.
+// The pattern is matched against the name, description and model card keywords.
+func (r *Registry) SearchModels(pattern string, tags []string) []*types.ModelManifest {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	var results []*types.ModelManifest
+	for _, manifest := range r.models {
+		keywords := manifest.Keywords()
+		if !types.MatchesSearch(pattern, manifest.Name, manifest.Description, keywords) {
+			continue
+		}
+		if !types.HasAllTags(keywords, tags) {
+			continue
+		}
+		results = append(results, manifest)
+	}
+	return results
+}
+
 // DeleteModel removes a model from the registry (but not from disk)
 func (r *Registry) DeleteModel(name string) error {
 	r.mu.Lock()
@@ -306,7 +667,10 @@ func (r *Registry) DeleteModel(name string) error {
 
 // RefreshModel re-scans a specific model and updates its manifest
 func (r *Registry) RefreshModel(name string) error {
-	modelPath := r.paths.ModelPath(name)
+	modelPath, err := r.paths.ModelDir(name)
+	if err != nil {
+		return err
+	}
 	
 	// Check if model directory exists
 	if _, err := os.Stat(modelPath); os.IsNotExist(err) {
@@ -314,7 +678,7 @@ func (r *Registry) RefreshModel(name string) error {
 	}
 	
 	// Always regenerate the manifest to pick up file changes
-	manifest, err := r.generateManifest(modelPath, name)
+	manifest, err := r.generateManifest(modelPath, name, nil)
 	if err != nil {
 		return fmt.Errorf("failed to generate manifest: %w", err)
 	}
@@ -335,6 +699,9 @@ func (r *Registry) RefreshModel(name string) error {
 		if oldManifest.MagnetURI != "" {
 			manifest.MagnetURI = oldManifest.MagnetURI
 		}
+		if len(oldManifest.Tags) > 0 {
+			manifest.Tags = oldManifest.Tags
+		}
 	}
 	
 	// Update registry
@@ -376,11 +743,5 @@ func (r *Registry) UpdateManifest(name string, updates map[string]interface{}) e
 
 // Rescan triggers a full rescan of the models directory
 func (r *Registry) Rescan() error {
-	// Clear existing models
-	r.mu.Lock()
-	r.models = make(map[string]*types.ModelManifest)
-	r.mu.Unlock()
-	
-	// Scan again
 	return r.ScanModels()
 }
\ No newline at end of file
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/store"
 	"github.com/silmaril/silmaril/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -141,7 +142,7 @@ func TestSaveManifest(t *testing.T) {
 	assert.Equal(t, manifest.Version, retrieved.Version)
 	
 	// Check it's saved to disk
-	manifestPath := filepath.Join(paths.ModelPath("new-org/new-model"), ManifestFileName)
+	manifestPath := filepath.Join(modelPath(t, paths, "new-org/new-model"), ManifestFileName)
 	assert.FileExists(t, manifestPath)
 	
 	// Load from disk and verify
@@ -184,7 +185,7 @@ func TestGenerateManifest(t *testing.T) {
 	}
 	
 	// Generate manifest
-	manifest, err := registry.generateManifest(modelDir, "generated/model")
+	manifest, err := registry.generateManifest(modelDir, "generated/model", nil)
 	require.NoError(t, err)
 	
 	assert.Equal(t, "generated/model", manifest.Name)
@@ -201,6 +202,36 @@ func TestGenerateManifest(t *testing.T) {
 	assert.Equal(t, files, fileMap)
 }
 
+func TestGenerateManifestIgnoresFiles(t *testing.T) {
+	modelDir := t.TempDir()
+	for _, name := range []string{"config.json", "model.safetensors", "train.log", "checkpoints/step-100/model.safetensors", ".git/HEAD"} {
+		path := filepath.Join(modelDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, storage.IgnoreFile), []byte("# Training leftovers\n*.log\ncheckpoints/\n"), 0644))
+	
+	manifest, err := GenerateManifest(modelDir, "org/model", nil)
+	require.NoError(t, err)
+	var listed []string
+	for _, f := range manifest.Files {
+		listed = append(listed, f.Path)
+	}
+	assert.ElementsMatch(t, []string{"config.json", "model.safetensors"}, listed)
+	assert.Equal(t, int64(len("config.json")+len("model.safetensors")), manifest.TotalSize)
+	
+	// Patterns given when publishing take precedence over the ignore file
+	ignore, err := storage.LoadIgnore(modelDir, []string{"model.safetensors"}, []string{"train.log"})
+	require.NoError(t, err)
+	manifest, err = GenerateManifest(modelDir, "org/model", ignore)
+	require.NoError(t, err)
+	listed = nil
+	for _, f := range manifest.Files {
+		listed = append(listed, f.Path)
+	}
+	assert.ElementsMatch(t, []string{"config.json", "train.log"}, listed)
+}
+
 func TestRefreshModel(t *testing.T) {
 	tmpDir := t.TempDir()
 	os.Setenv("SILMARIL_HOME", tmpDir)
@@ -245,8 +276,9 @@ func TestRefreshModel(t *testing.T) {
 	// Check updated manifest
 	updated, err := registry.GetManifest("refresh/model")
 	require.NoError(t, err)
-	// Files should now include: .silmaril.json, config.json, model.bin, weights.bin
-	assert.Len(t, updated.Files, 4)
+	// Files should now include: config.json, model.bin, weights.bin, but not
+	// the hidden .silmaril.json, which isn't published
+	assert.Len(t, updated.Files, 3)
 	// Check that weights.bin was added
 	var hasWeights bool
 	for _, f := range updated.Files {
@@ -419,4 +451,311 @@ func TestConcurrentAccess(t *testing.T) {
 	// Verify all models exist
 	models := registry.ListModels()
 	assert.Len(t, models, 10)
-}
\ No newline at end of file
+}
+func TestScanModelsSkipsPartialDownloads(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.Setenv("SILMARIL_HOME", tmpDir)
+	defer os.Unsetenv("SILMARIL_HOME")
+	
+	paths, err := storage.NewPaths()
+	require.NoError(t, err)
+	
+	// A download that has fetched config.json but not the weights yet
+	modelDir := filepath.Join(paths.ModelsDir(), "test-org/partial-model")
+	require.NoError(t, os.MkdirAll(modelDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, HFConfigFile), []byte(`{"model_type": "llama"}`), 0644))
+	require.NoError(t, storage.MarkPartial(modelDir))
+	
+	registry, err := NewRegistry(paths)
+	require.NoError(t, err)
+	assert.Empty(t, registry.ListModels())
+	assert.NoFileExists(t, filepath.Join(modelDir, ManifestFileName))
+	
+	// Completed downloads wait in quarantine until they are verified
+	require.NoError(t, storage.MarkQuarantined(modelDir, nil))
+	require.NoError(t, storage.ClearPartial(modelDir))
+	require.NoError(t, registry.ScanModels())
+	assert.Empty(t, registry.ListModels())
+
+	// Once released the model is listed
+	require.NoError(t, storage.ClearQuarantine(modelDir))
+	require.NoError(t, registry.ScanModels())
+	assert.Equal(t, []string{"test-org/partial-model"}, registry.ListModels())
+}
+
+func TestScanModelsFollowsLinkedModels(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.Setenv("SILMARIL_HOME", tmpDir)
+	defer os.Unsetenv("SILMARIL_HOME")
+	
+	paths, err := storage.NewPaths()
+	require.NoError(t, err)
+	
+	// A model published in place from outside the models directory
+	srcDir := filepath.Join(t.TempDir(), "my-model")
+	require.NoError(t, os.MkdirAll(srcDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, HFConfigFile), []byte(`{"model_type": "llama"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "model.safetensors"), make([]byte, 512), 0644))
+	_, err = storage.ImportDir(srcDir, modelPath(t, paths, "test-org/linked-model"), storage.ImportInPlace)
+	require.NoError(t, err)
+	
+	registry, err := NewRegistry(paths)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test-org/linked-model"}, registry.ListModels())
+	
+	manifest, err := registry.GetManifest("test-org/linked-model")
+	require.NoError(t, err)
+	assert.Equal(t, "llama", manifest.ModelType)
+	assert.Len(t, manifest.Files, 2)
+	assert.Equal(t, int64(512+len(`{"model_type": "llama"}`)), manifest.TotalSize)
+}
+
+func TestReadWriteManifest(t *testing.T) {
+	modelPath := t.TempDir()
+
+	_, err := ReadManifest(modelPath)
+	assert.True(t, os.IsNotExist(err))
+
+	seedTime := 3600
+	manifest := &types.ModelManifest{
+		Name:    "org/model",
+		Version: "v1",
+		Seeding: &types.SeedingPolicy{SeedTime: &seedTime},
+	}
+	require.NoError(t, WriteManifest(modelPath, manifest))
+
+	loaded, err := ReadManifest(modelPath)
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", loaded.Name)
+	require.NotNil(t, loaded.Seeding)
+	require.NotNil(t, loaded.Seeding.SeedTime)
+	assert.Equal(t, 3600, *loaded.Seeding.SeedTime)
+	assert.Nil(t, loaded.Seeding.SeedRatio)
+}
+
+func TestGetManifestResolvesAliases(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	paths, err := storage.NewPaths()
+	require.NoError(t, err)
+	
+	for _, name := range []string{"org/model", "llama"} {
+		modelDir := modelPath(t, paths, name)
+		require.NoError(t, os.MkdirAll(modelDir, 0755))
+		require.NoError(t, WriteManifest(modelDir, &types.ModelManifest{Name: name}))
+	}
+	
+	aliases, err := LoadAliases(paths.RegistryDir())
+	require.NoError(t, err)
+	require.NoError(t, aliases.Set("m", "org/model"))
+	require.NoError(t, aliases.Set("llama", "org/model"))
+	require.NoError(t, aliases.Save())
+	
+	registry, err := NewRegistry(paths)
+	require.NoError(t, err)
+	
+	manifest, err := registry.GetManifest("m")
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", manifest.Name)
+	assert.Equal(t, []string{"llama", "m"}, registry.Aliases("org/model"))
+	
+	// A model named like an alias wins over the alias
+	assert.Equal(t, "llama", registry.Resolve("llama"))
+}
+
+func TestScanModelsDropsRemovedModels(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	paths, err := storage.NewPaths()
+	require.NoError(t, err)
+	
+	for _, name := range []string{"org/kept", "org/removed"} {
+		modelDir := modelPath(t, paths, name)
+		require.NoError(t, os.MkdirAll(modelDir, 0755))
+		require.NoError(t, WriteManifest(modelDir, &types.ModelManifest{Name: name}))
+	}
+	
+	registry, err := NewRegistry(paths)
+	require.NoError(t, err)
+	assert.Len(t, registry.ListModels(), 2)
+	assert.Contains(t, registry.Dirs(), paths.ModelsDir())
+	assert.Contains(t, registry.Dirs(), modelPath(t, paths, "org/kept"))
+	scannedAt := registry.ScannedAt()
+	
+	require.NoError(t, os.RemoveAll(modelPath(t, paths, "org/removed")))
+	require.NoError(t, registry.ScanModels())
+	assert.Equal(t, []string{"org/kept"}, registry.ListModels())
+	assert.NotContains(t, registry.Dirs(), modelPath(t, paths, "org/removed"))
+	assert.False(t, registry.ScannedAt().Before(scannedAt))
+}
+
+func TestLoadModel(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	paths, err := storage.NewPaths()
+	require.NoError(t, err)
+	
+	registry, err := NewRegistry(paths)
+	require.NoError(t, err)
+	
+	// Models added after the scan are read on their own
+	modelDir := modelPath(t, paths, "org/model")
+	require.NoError(t, os.MkdirAll(modelDir, 0755))
+	require.NoError(t, WriteManifest(modelDir, &types.ModelManifest{Name: "org/model", Version: "v1"}))
+	manifest, err := registry.LoadModel("org/model")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", manifest.Version)
+	_, err = registry.GetManifest("org/model")
+	assert.NoError(t, err)
+	
+	// Partial downloads aren't models yet
+	require.NoError(t, storage.MarkPartial(modelDir))
+	_, err = registry.LoadModel("org/model")
+	assert.Error(t, err)
+	_, err = registry.GetManifest("org/model")
+	assert.Error(t, err)
+	
+	_, err = registry.LoadModel("../outside")
+	assert.Error(t, err)
+}
+
+func TestRegistryUpdate(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	paths, err := storage.NewPaths()
+	require.NoError(t, err)
+	
+	kept := modelPath(t, paths, "org/kept")
+	require.NoError(t, os.MkdirAll(kept, 0755))
+	require.NoError(t, WriteManifest(kept, &types.ModelManifest{Name: "org/kept"}))
+	
+	registry, err := NewRegistry(paths)
+	require.NoError(t, err)
+	
+	// A model copied in
+	modelDir := modelPath(t, paths, "org/model")
+	require.NoError(t, os.MkdirAll(modelDir, 0755))
+	require.NoError(t, WriteManifest(modelDir, &types.ModelManifest{Name: "org/model", Version: "v1"}))
+	added, removed, err := registry.Update([]string{filepath.Dir(modelDir)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"org/model"}, added)
+	assert.Empty(t, removed)
+	assert.Contains(t, registry.Dirs(), modelDir)
+	
+	// Changes to files of a model reload the model only
+	require.NoError(t, WriteManifest(modelDir, &types.ModelManifest{Name: "org/model", Version: "v2"}))
+	added, removed, err = registry.Update([]string{filepath.Join(modelDir, ManifestFileName), filepath.Join(modelDir, "weights.bin")})
+	require.NoError(t, err)
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+	manifest, err := registry.GetManifest("org/model")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", manifest.Version)
+	
+	// A model deleted by hand
+	require.NoError(t, os.RemoveAll(modelDir))
+	added, removed, err = registry.Update([]string{modelDir})
+	require.NoError(t, err)
+	assert.Empty(t, added)
+	assert.Equal(t, []string{"org/model"}, removed)
+	assert.Equal(t, []string{"org/kept"}, registry.ListModels())
+	assert.NotContains(t, registry.Dirs(), modelDir)
+	
+	// Paths outside the models directory are ignored
+	added, removed, err = registry.Update([]string{t.TempDir()})
+	require.NoError(t, err)
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+}
+
+func TestSetFileHash(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	paths, err := storage.NewPaths()
+	require.NoError(t, err)
+	
+	modelDir := modelPath(t, paths, "org/model")
+	require.NoError(t, os.MkdirAll(modelDir, 0755))
+	require.NoError(t, WriteManifest(modelDir, &types.ModelManifest{
+		Name: "org/model",
+		Files: []types.ModelFile{
+			{Path: "config.json", Size: 10, SHA256: "abc"},
+			{Path: "model.safetensors", Size: 1024},
+		},
+	}))
+	registry, err := NewRegistry(paths)
+	require.NoError(t, err)
+	
+	before, err := registry.GetManifest("org/model")
+	require.NoError(t, err)
+	assert.False(t, before.HashesComplete())
+	assert.Equal(t, 1, before.HashedFiles())
+	
+	// A file that changed size since the scan keeps no checksum
+	assert.Error(t, registry.SetFileHash("org/model", "model.safetensors", 2048, "def"))
+	assert.Error(t, registry.SetFileHash("org/model", "missing.bin", 1024, "def"))
+	
+	require.NoError(t, registry.SetFileHash("org/model", "model.safetensors", 1024, "def"))
+	after, err := registry.GetManifest("org/model")
+	require.NoError(t, err)
+	assert.True(t, after.HashesComplete())
+	assert.False(t, before.HashesComplete(), "held manifests are not changed")
+	
+	// The checksum is saved with the manifest
+	saved, err := ReadManifest(modelDir)
+	require.NoError(t, err)
+	assert.Equal(t, "def", saved.Files[1].SHA256)
+}
+
+func TestRegistryWithStore(t *testing.T) {
+	t.Setenv("SILMARIL_HOME", t.TempDir())
+	paths, err := storage.NewPaths()
+	require.NoError(t, err)
+	st, err := store.Open(paths.DBDir())
+	require.NoError(t, err)
+	defer st.Close()
+	
+	modelDir := modelPath(t, paths, "org/model")
+	require.NoError(t, os.MkdirAll(modelDir, 0755))
+	require.NoError(t, WriteManifest(modelDir, &types.ModelManifest{Name: "org/model", Version: "v1"}))
+	
+	registry, err := NewRegistryWithStore(paths, st)
+	require.NoError(t, err)
+	var record modelRecord
+	found, err := st.Get(store.Models, "org/model", &record)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "v1", record.Manifest.Version)
+	
+	// Unchanged manifests are read from the database
+	record.Manifest.Description = "cached"
+	require.NoError(t, st.Put(store.Models, "org/model", record))
+	require.NoError(t, registry.ScanModels())
+	manifest, err := registry.GetManifest("org/model")
+	require.NoError(t, err)
+	assert.Equal(t, "cached", manifest.Description)
+	
+	// Changed ones are read again, and their new version is recorded
+	require.NoError(t, WriteManifest(modelDir, &types.ModelManifest{Name: "org/model", Version: "v2", Description: "changed"}))
+	require.NoError(t, os.Chtimes(filepath.Join(modelDir, ManifestFileName), time.Now(), time.Now().Add(time.Second)))
+	require.NoError(t, registry.ScanModels())
+	manifest, err = registry.GetManifest("org/model")
+	require.NoError(t, err)
+	assert.Equal(t, "changed", manifest.Description)
+	
+	versions, err := registry.Versions("org/model")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "v1", versions[0].Version)
+	assert.Equal(t, "v2", versions[1].Version)
+	
+	// Removed models leave the database
+	require.NoError(t, os.RemoveAll(modelDir))
+	require.NoError(t, registry.ScanModels())
+	found, err = st.Get(store.Models, "org/model", &record)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+// modelPath returns the directory of the model named name below paths
+func modelPath(t *testing.T, paths *storage.Paths, name string) string {
+	t.Helper()
+	dir, err := paths.ModelDir(name)
+	require.NoError(t, err)
+	return dir
+}
@@ -0,0 +1,425 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// Media types of model artifacts. The layout follows ORAS, so `oras pull`
+// restores the model files as well.
+const (
+	ManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ArtifactType      = "application/vnd.silmaril.model.v1"
+	ConfigMediaType   = "application/vnd.silmaril.manifest.v1+json"
+	FileMediaType     = "application/vnd.silmaril.model.file.v1"
+
+	// AnnotationTitle holds the path of a file layer in the model
+	AnnotationTitle = "org.opencontainers.image.title"
+)
+
+// Descriptor points at a blob in a registry
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI image manifest carrying a model. The config blob is the
+// Silmaril manifest, each model file is a layer.
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// Progress reports each model file pushed or pulled. skipped is set for files
+// the other side already had.
+type Progress func(file string, size int64, skipped bool)
+
+// PushModel pushes the model in modelDir to ref and returns the digest of the
+// pushed manifest. Blobs the registry already has are not uploaded again.
+func (c *Client) PushModel(ctx context.Context, ref Reference, modelDir string, manifest *types.ModelManifest, progress Progress) (string, error) {
+	if ref.Tag == "" {
+		return "", fmt.Errorf("reference %s needs a tag to push to", ref)
+	}
+
+	files, err := modelFiles(modelDir)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("model directory %s has no files", modelDir)
+	}
+
+	configData, err := json.Marshal(manifest)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal model manifest: %w", err)
+	}
+	config := Descriptor{MediaType: ConfigMediaType, Digest: digestBytes(configData), Size: int64(len(configData))}
+	if _, err := c.pushBlob(ctx, ref, config, func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(configData)), nil
+	}); err != nil {
+		return "", err
+	}
+
+	root := storage.ResolveModelDir(modelDir)
+	layers := make([]Descriptor, 0, len(files))
+	for _, relPath := range files {
+		filePath := filepath.Join(root, filepath.FromSlash(relPath))
+		digest, size, err := digestFile(filePath)
+		if err != nil {
+			return "", err
+		}
+		layer := Descriptor{
+			MediaType:   FileMediaType,
+			Digest:      digest,
+			Size:        size,
+			Annotations: map[string]string{AnnotationTitle: relPath},
+		}
+
+		uploaded, err := c.pushBlob(ctx, ref, layer, func() (io.ReadCloser, error) {
+			return os.Open(filePath)
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to push %s: %w", relPath, err)
+		}
+		if progress != nil {
+			progress(relPath, size, !uploaded)
+		}
+		layers = append(layers, layer)
+	}
+
+	ociManifest := Manifest{
+		SchemaVersion: 2,
+		MediaType:     ManifestMediaType,
+		ArtifactType:  ArtifactType,
+		Config:        config,
+		Layers:        layers,
+		Annotations: map[string]string{
+			"org.opencontainers.image.title":   manifest.Name,
+			"org.opencontainers.image.version": manifest.Version,
+		},
+	}
+	if manifest.License != "" {
+		ociManifest.Annotations["org.opencontainers.image.licenses"] = manifest.License
+	}
+	if manifest.Description != "" {
+		ociManifest.Annotations["org.opencontainers.image.description"] = manifest.Description
+	}
+
+	manifestData, err := json.Marshal(ociManifest)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal OCI manifest: %w", err)
+	}
+
+	header := http.Header{"Content-Type": {ManifestMediaType}}
+	resp, err := c.do(ctx, ref, true, http.MethodPut, c.url(ref, "manifests/%s", ref.Tag), header, func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(manifestData)), nil
+	}, int64(len(manifestData)))
+	if err != nil {
+		return "", fmt.Errorf("failed to push manifest: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", responseError("push manifest", resp)
+	}
+
+	return digestBytes(manifestData), nil
+}
+
+// PullModel downloads the model at ref into destDir and returns its
+// manifest. Files already in destDir with the right digest are kept, so an
+// interrupted pull resumes where it stopped.
+func (c *Client) PullModel(ctx context.Context, ref Reference, destDir string, progress Progress) (*types.ModelManifest, error) {
+	ociManifest, err := c.FetchManifest(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := c.fetchModelManifest(ctx, ref, ociManifest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate every path before writing anything
+	for _, layer := range ociManifest.Layers {
+		if _, err := layerPath(destDir, layer); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create model directory: %w", err)
+	}
+	for _, layer := range ociManifest.Layers {
+		relPath := layer.Annotations[AnnotationTitle]
+		filePath, _ := layerPath(destDir, layer)
+
+		if digest, size, err := digestFile(filePath); err == nil && digest == layer.Digest && size == layer.Size {
+			if progress != nil {
+				progress(relPath, layer.Size, true)
+			}
+			continue
+		}
+		if progress != nil {
+			progress(relPath, layer.Size, false)
+		}
+		if err := c.fetchBlobToFile(ctx, ref, layer, filePath); err != nil {
+			return nil, fmt.Errorf("failed to pull %s: %w", relPath, err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// FetchModelManifest returns the Silmaril manifest of the model at ref
+// without pulling its files
+func (c *Client) FetchModelManifest(ctx context.Context, ref Reference) (*types.ModelManifest, error) {
+	ociManifest, err := c.FetchManifest(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.fetchModelManifest(ctx, ref, ociManifest)
+}
+
+// fetchModelManifest fetches and decodes the config blob of ociManifest
+func (c *Client) fetchModelManifest(ctx context.Context, ref Reference, ociManifest *Manifest) (*types.ModelManifest, error) {
+	configData, err := c.fetchBlobBytes(ctx, ref, ociManifest.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch model manifest: %w", err)
+	}
+	var manifest types.ModelManifest
+	if err := json.Unmarshal(configData, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode model manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// FetchManifest fetches the manifest at ref and checks it carries a model
+func (c *Client) FetchManifest(ctx context.Context, ref Reference) (*Manifest, error) {
+	header := http.Header{"Accept": {ManifestMediaType}}
+	resp, err := c.do(ctx, ref, false, http.MethodGet, c.url(ref, "manifests/%s", ref.manifestRef()), header, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("fetch manifest of "+ref.String(), resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if ref.Digest != "" && digestBytes(data) != ref.Digest {
+		return nil, fmt.Errorf("manifest of %s does not match its digest", ref)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	if manifest.Config.MediaType != ConfigMediaType {
+		return nil, fmt.Errorf("%s is not a Silmaril model (config type %q)", ref, manifest.Config.MediaType)
+	}
+	return &manifest, nil
+}
+
+// pushBlob uploads a blob unless the registry already has it, and reports
+// whether it was uploaded
+func (c *Client) pushBlob(ctx context.Context, ref Reference, desc Descriptor, body newBody) (bool, error) {
+	resp, err := c.do(ctx, ref, true, http.MethodHead, c.url(ref, "blobs/%s", desc.Digest), nil, nil, 0)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return false, nil
+	}
+
+	resp, err = c.do(ctx, ref, true, http.MethodPost, c.url(ref, "blobs/uploads/"), nil, nil, 0)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return false, responseError("start upload", resp)
+	}
+
+	// The upload location may be relative to the registry
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return false, fmt.Errorf("registry returned an invalid upload location: %w", err)
+	}
+	query := location.Query()
+	query.Set("digest", desc.Digest)
+	location.RawQuery = query.Encode()
+
+	header := http.Header{"Content-Type": {"application/octet-stream"}}
+	resp, err = c.do(ctx, ref, true, http.MethodPut, location.String(), header, body, desc.Size)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return false, responseError("upload blob", resp)
+	}
+	return true, nil
+}
+
+// fetchBlob opens a blob for reading
+func (c *Client) fetchBlob(ctx context.Context, ref Reference, desc Descriptor) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, ref, false, http.MethodGet, c.url(ref, "blobs/%s", desc.Digest), nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError("fetch blob", resp)
+	}
+	return resp.Body, nil
+}
+
+// fetchBlobBytes fetches a small blob and verifies its digest
+func (c *Client) fetchBlobBytes(ctx context.Context, ref Reference, desc Descriptor) ([]byte, error) {
+	body, err := c.fetchBlob(ctx, ref, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, desc.Size+1))
+	if err != nil {
+		return nil, err
+	}
+	if digestBytes(data) != desc.Digest {
+		return nil, fmt.Errorf("blob %s does not match its digest", desc.Digest)
+	}
+	return data, nil
+}
+
+// fetchBlobToFile downloads a blob into a temporary file next to filePath and
+// moves it into place once its digest is verified
+func (c *Client) fetchBlobToFile(ctx context.Context, ref Reference, desc Descriptor, filePath string) error {
+	body, err := c.fetchBlob(ctx, ref, desc)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".silmaril-pull-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(body, desc.Size+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if written != desc.Size || "sha256:"+hex.EncodeToString(hasher.Sum(nil)) != desc.Digest {
+		return fmt.Errorf("blob %s does not match its digest", desc.Digest)
+	}
+
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("failed to move file into place: %w", err)
+	}
+	return nil
+}
+
+// layerPath returns where a file layer goes in destDir, refusing paths that
+// would escape it
+func layerPath(destDir string, layer Descriptor) (string, error) {
+	relPath := layer.Annotations[AnnotationTitle]
+	if relPath == "" {
+		return "", fmt.Errorf("layer %s has no file name", layer.Digest)
+	}
+	if !digestPattern.MatchString(layer.Digest) {
+		return "", fmt.Errorf("layer %s has an unsupported digest", relPath)
+	}
+	clean := path.Clean(relPath)
+	if path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(relPath, "\\") {
+		return "", fmt.Errorf("layer has an invalid file name %q", relPath)
+	}
+	return filepath.Join(destDir, filepath.FromSlash(clean)), nil
+}
+
+// modelFiles lists the files to push, skipping the hidden files Silmaril and
+// git keep next to the model
+func modelFiles(modelDir string) ([]string, error) {
+	root := storage.ResolveModelDir(modelDir)
+
+	var files []string
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && p != root {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list model files: %w", err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// digestFile returns the OCI digest and size of a file
+func digestFile(filePath string) (string, int64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to hash %s: %w", filePath, err)
+	}
+	return "sha256:" + hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
+
+func digestBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,251 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRegistry is an in-memory OCI distribution registry that hands out
+// bearer tokens like Docker Hub and GHCR do
+type fakeRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	uploads   int
+	server    *httptest.Server
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	r := &fakeRegistry{
+		blobs:     make(map[string][]byte),
+		manifests: make(map[string][]byte),
+	}
+	r.server = httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *fakeRegistry) ref(t *testing.T, repoAndTag string) Reference {
+	u, err := url.Parse(r.server.URL)
+	require.NoError(t, err)
+	ref, err := ParseReference(u.Host + "/" + repoAndTag)
+	require.NoError(t, err)
+	return ref
+}
+
+func (r *fakeRegistry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req.URL.Path == "/token" {
+		fmt.Fprintf(w, `{"token": "secret-%s"}`, req.URL.Query().Get("scope"))
+		return
+	}
+
+	// Pushing needs a token with push access
+	auth := req.Header.Get("Authorization")
+	push := req.Method != http.MethodGet && req.Method != http.MethodHead
+	if !strings.HasPrefix(auth, "Bearer secret-") || (push && !strings.HasSuffix(auth, ",push")) {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake",scope="ignored"`, r.server.URL))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Uploads go to a session URL outside /v2/<repo>/, like on real registries
+	if req.URL.Path == "/upload/session" {
+		data, _ := io.ReadAll(req.Body)
+		if digestBytes(data) != req.URL.Query().Get("digest") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.blobs[req.URL.Query().Get("digest")] = data
+		r.uploads++
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/v2/"), "/", 4)
+	if len(parts) != 4 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	kind, id := parts[2], parts[3]
+
+	switch {
+	case kind == "blobs" && id == "uploads/" && req.Method == http.MethodPost:
+		w.Header().Set("Location", "/upload/session")
+		w.WriteHeader(http.StatusAccepted)
+	case kind == "blobs" && req.Method == http.MethodHead:
+		if _, ok := r.blobs[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case kind == "blobs" && req.Method == http.MethodGet:
+		data, ok := r.blobs[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case kind == "manifests" && req.Method == http.MethodPut:
+		data, _ := io.ReadAll(req.Body)
+		r.manifests[id] = data
+		r.manifests[digestBytes(data)] = data
+		w.WriteHeader(http.StatusCreated)
+	case kind == "manifests" && req.Method == http.MethodGet:
+		data, ok := r.manifests[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors": [{"code": "MANIFEST_UNKNOWN", "message": "manifest unknown"}]}`)
+			return
+		}
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func writeModel(t *testing.T, dir string) {
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "onnx"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"model_type": "llama"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "model.safetensors"), []byte("weights"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "onnx", "model.onnx"), []byte("onnx"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".silmaril.json"), []byte(`{}`), 0644))
+}
+
+func TestPushPullModel(t *testing.T) {
+	registry := newFakeRegistry(t)
+	ref := registry.ref(t, "models/llama:v1")
+
+	modelDir := t.TempDir()
+	writeModel(t, modelDir)
+	manifest := &types.ModelManifest{Name: "org/llama", Version: "v1", License: "apache-2.0"}
+
+	client := NewClient()
+	client.PlainHTTP = true
+
+	var pushed []string
+	digest, err := client.PushModel(context.Background(), ref, modelDir, manifest, func(file string, size int64, skipped bool) {
+		assert.False(t, skipped)
+		pushed = append(pushed, file)
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(digest, "sha256:"))
+	assert.Equal(t, []string{"config.json", "model.safetensors", "onnx/model.onnx"}, pushed)
+	assert.Equal(t, 4, registry.uploads) // the Silmaril manifest and three files
+
+	// Pushing again only updates the manifest
+	_, err = client.PushModel(context.Background(), ref, modelDir, manifest, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, registry.uploads)
+
+	ociManifest, err := client.FetchManifest(context.Background(), ref)
+	require.NoError(t, err)
+	assert.Equal(t, ArtifactType, ociManifest.ArtifactType)
+	assert.Len(t, ociManifest.Layers, 3)
+	assert.Equal(t, "onnx/model.onnx", ociManifest.Layers[2].Annotations[AnnotationTitle])
+
+	// Pull by digest with a fresh client, which has to authenticate again
+	destDir := filepath.Join(t.TempDir(), "llama")
+	pullClient := NewClient()
+	pullClient.PlainHTTP = true
+	byDigest := ref
+	byDigest.Tag, byDigest.Digest = "", digest
+	pulled, err := pullClient.PullModel(context.Background(), byDigest, destDir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "org/llama", pulled.Name)
+	assert.Equal(t, "apache-2.0", pulled.License)
+
+	data, err := os.ReadFile(filepath.Join(destDir, "onnx", "model.onnx"))
+	require.NoError(t, err)
+	assert.Equal(t, "onnx", string(data))
+	assert.NoFileExists(t, filepath.Join(destDir, ".silmaril.json"))
+
+	// Pulling again keeps the files that are already there
+	var skipped int
+	_, err = pullClient.PullModel(context.Background(), ref, destDir, func(file string, size int64, wasSkipped bool) {
+		if wasSkipped {
+			skipped++
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, skipped)
+}
+
+func TestPullModelRejectsUnsafePaths(t *testing.T) {
+	for _, title := range []string{"../escape", "/etc/passwd", "", "a/../../b"} {
+		_, err := layerPath("/models/llama", Descriptor{
+			Digest:      "sha256:" + strings.Repeat("0", 64),
+			Annotations: map[string]string{AnnotationTitle: title},
+		})
+		assert.Error(t, err, title)
+	}
+
+	path, err := layerPath("/models/llama", Descriptor{
+		Digest:      "sha256:" + strings.Repeat("0", 64),
+		Annotations: map[string]string{AnnotationTitle: "onnx/model.onnx"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/models/llama", "onnx", "model.onnx"), path)
+}
+
+func TestPullMissingManifest(t *testing.T) {
+	registry := newFakeRegistry(t)
+	client := NewClient()
+	client.PlainHTTP = true
+
+	_, err := client.PullModel(context.Background(), registry.ref(t, "models/llama:missing"), t.TempDir(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MANIFEST_UNKNOWN")
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:org/llama:pull"`)
+	assert.Equal(t, "Bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://ghcr.io/token",
+		"service": "ghcr.io",
+		"scope":   "repository:org/llama:pull",
+	}, params)
+}
+
+func TestCredentials(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	t.Setenv("SILMARIL_OCI_USERNAME", "")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{
+		"auths": {
+			"ghcr.io": {"auth": "dXNlcjpwYXNz"},
+			"https://index.docker.io/v1/": {"auth": "aHViOnNlY3JldA=="}
+		}
+	}`), 0644))
+
+	username, password := Credentials("ghcr.io")
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "pass", password)
+
+	username, password = Credentials("docker.io")
+	assert.Equal(t, "hub", username)
+	assert.Equal(t, "secret", password)
+
+	username, _ = Credentials("quay.io")
+	assert.Empty(t, username)
+
+	t.Setenv("SILMARIL_OCI_USERNAME", "env")
+	t.Setenv("SILMARIL_OCI_PASSWORD", "token")
+	username, password = Credentials("ghcr.io")
+	assert.Equal(t, "env", username)
+	assert.Equal(t, "token", password)
+}
@@ -0,0 +1,250 @@
+package oci
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Client talks to OCI distribution registries. It authenticates with
+// anonymous or basic-auth bearer tokens, which covers Docker Hub, GHCR,
+// Harbor, ECR (with a token as password) and self-hosted registries.
+type Client struct {
+	// PlainHTTP talks to the registry without TLS, for local registries
+	PlainHTTP bool
+
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]string // scope -> Authorization header
+}
+
+// NewClient creates a registry client
+func NewClient() *Client {
+	return &Client{
+		httpClient: &http.Client{},
+		tokens:     make(map[string]string),
+	}
+}
+
+// newBody returns a fresh request body, so requests can be sent again after
+// authenticating
+type newBody func() (io.ReadCloser, error)
+
+// do sends a request to the registry, authenticating and retrying once if the
+// registry asks for credentials. push requests push access to the repository.
+func (c *Client) do(ctx context.Context, ref Reference, push bool, method, rawURL string, header http.Header, body newBody, size int64) (*http.Response, error) {
+	scope := "repository:" + ref.Repository + ":pull"
+	if push {
+		scope += ",push"
+	}
+	cacheKey := ref.apiHost() + "|" + scope
+
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if body != nil {
+			rc, err := body()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = rc
+			req.ContentLength = size
+		}
+		c.mu.Lock()
+		auth := c.tokens[cacheKey]
+		c.mu.Unlock()
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		return c.httpClient.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	auth, err := c.authenticate(ctx, ref, challenge, scope)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.tokens[cacheKey] = auth
+	c.mu.Unlock()
+	return send()
+}
+
+// authenticate answers a WWW-Authenticate challenge and returns the
+// Authorization header to send
+func (c *Client) authenticate(ctx context.Context, ref Reference, challenge, scope string) (string, error) {
+	username, password := Credentials(ref.Registry)
+	scheme, params := parseChallenge(challenge)
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if username == "" {
+			return "", fmt.Errorf("registry %s requires credentials", ref.Registry)
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil
+
+	case "bearer":
+		realm, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return "", fmt.Errorf("registry %s sent an invalid token realm", ref.Registry)
+		}
+		query := realm.Query()
+		if service := params["service"]; service != "" {
+			query.Set("service", service)
+		}
+		query.Set("scope", scope)
+		realm.RawQuery = query.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("failed to get registry token: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("registry %s denied access to %s: %s", ref.Registry, ref.Repository, resp.Status)
+		}
+
+		var token struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return "", fmt.Errorf("failed to decode registry token: %w", err)
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		if token.Token == "" {
+			return "", fmt.Errorf("registry %s returned an empty token", ref.Registry)
+		}
+		return "Bearer " + token.Token, nil
+	}
+
+	return "", fmt.Errorf("registry %s requires unsupported authentication %q", ref.Registry, scheme)
+}
+
+// parseChallenge parses `Bearer realm="...",service="...",scope="..."`
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key != "" {
+			params[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+	return scheme, params
+}
+
+// Credentials returns the username and password for a registry. They are
+// read from SILMARIL_OCI_USERNAME and SILMARIL_OCI_PASSWORD, then from the
+// auths that `docker login` stores in ~/.docker/config.json. Credential
+// helpers are not supported.
+func Credentials(registry string) (string, string) {
+	if username := os.Getenv("SILMARIL_OCI_USERNAME"); username != "" {
+		return username, os.Getenv("SILMARIL_OCI_PASSWORD")
+	}
+
+	configDir := os.Getenv("DOCKER_CONFIG")
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", ""
+		}
+		configDir = filepath.Join(home, ".docker")
+	}
+	data, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	if err != nil {
+		return "", ""
+	}
+
+	var dockerConfig struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &dockerConfig); err != nil {
+		return "", ""
+	}
+
+	keys := []string{registry, "https://" + registry}
+	if registry == dockerHubRegistry {
+		keys = append(keys, "https://index.docker.io/v1/")
+	}
+	for _, key := range keys {
+		entry, ok := dockerConfig.Auths[key]
+		if !ok {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			continue
+		}
+		if username, password, found := strings.Cut(string(decoded), ":"); found {
+			return username, password
+		}
+	}
+	return "", ""
+}
+
+// url returns the URL of a registry API path
+func (c *Client) url(ref Reference, format string, args ...interface{}) string {
+	scheme := "https"
+	if c.PlainHTTP {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/", scheme, ref.apiHost(), ref.Repository) + fmt.Sprintf(format, args...)
+}
+
+// responseError turns an unexpected registry response into an error
+func responseError(action string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var registryErr struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &registryErr) == nil && len(registryErr.Errors) > 0 {
+		e := registryErr.Errors[0]
+		return fmt.Errorf("failed to %s: %s: %s", action, e.Code, e.Message)
+	}
+	return fmt.Errorf("failed to %s: %s", action, resp.Status)
+}
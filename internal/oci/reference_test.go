@@ -0,0 +1,48 @@
+package oci
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+
+	tests := []struct {
+		input string
+		want  Reference
+	}{
+		{"ghcr.io/org/llama:3.1", Reference{Registry: "ghcr.io", Repository: "org/llama", Tag: "3.1"}},
+		{"localhost:5000/models/llama", Reference{Registry: "localhost:5000", Repository: "models/llama", Tag: "latest"}},
+		{"localhost/llama:v1", Reference{Registry: "localhost", Repository: "llama", Tag: "v1"}},
+		{"org/llama:v1", Reference{Registry: "docker.io", Repository: "org/llama", Tag: "v1"}},
+		{"llama", Reference{Registry: "docker.io", Repository: "library/llama", Tag: "latest"}},
+		{"ghcr.io/org/llama@" + digest, Reference{Registry: "ghcr.io", Repository: "org/llama", Digest: digest}},
+		{"ghcr.io/org/llama:v1@" + digest, Reference{Registry: "ghcr.io", Repository: "org/llama", Tag: "v1", Digest: digest}},
+	}
+	for _, tt := range tests {
+		ref, err := ParseReference(tt.input)
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.want, ref, tt.input)
+	}
+
+	assert.Equal(t, "ghcr.io/org/llama:3.1", Reference{Registry: "ghcr.io", Repository: "org/llama", Tag: "3.1"}.String())
+	assert.Equal(t, "registry-1.docker.io", Reference{Registry: "docker.io"}.apiHost())
+}
+
+func TestParseReferenceInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"ghcr.io/Org/Llama:v1",
+		"ghcr.io/org/llama:",
+		"ghcr.io/org/llama@sha256:1234",
+		"ghcr.io/org//llama",
+		"ghcr.io/org/llama:v1:v2",
+	} {
+		_, err := ParseReference(input)
+		assert.Error(t, err, input)
+	}
+}
@@ -0,0 +1,269 @@
+// Package peerfilter decides which IP addresses the torrent client may
+// exchange data with, from static lists of addresses and ranges and from
+// blocklists in the PeerGuardian P2P format.
+package peerfilter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/anacrolix/torrent/iplist"
+)
+
+// Range is an inclusive range of addresses of one family
+type Range struct {
+	First       netip.Addr
+	Last        netip.Addr
+	Description string
+}
+
+// Contains reports whether addr is in the range
+func (r Range) Contains(addr netip.Addr) bool {
+	return r.First.Compare(addr) <= 0 && addr.Compare(r.Last) <= 0
+}
+
+// List is a sorted set of ranges that don't overlap
+type List []Range
+
+// NewList sorts ranges and merges the ones that overlap or touch
+func NewList(ranges []Range) List {
+	sorted := make([]Range, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].First.Compare(sorted[j].First) < 0
+	})
+
+	list := make(List, 0, len(sorted))
+	for _, r := range sorted {
+		if n := len(list); n > 0 {
+			last := &list[n-1]
+			next := last.Last.Next()
+			if last.Last.BitLen() == r.First.BitLen() && (r.First.Compare(last.Last) <= 0 || (next.IsValid() && next == r.First)) {
+				if r.Last.Compare(last.Last) > 0 {
+					last.Last = r.Last
+				}
+				continue
+			}
+		}
+		list = append(list, r)
+	}
+	return list
+}
+
+// Lookup returns the range holding addr
+func (l List) Lookup(addr netip.Addr) (Range, bool) {
+	// The first range starting after addr, the one before may hold it
+	i := sort.Search(len(l), func(i int) bool {
+		return l[i].First.Compare(addr) > 0
+	})
+	if i == 0 || !l[i-1].Contains(addr) {
+		return Range{}, false
+	}
+	return l[i-1], true
+}
+
+// ParseEntry parses an address, a CIDR prefix or a range of two addresses
+// separated by a hyphen
+func ParseEntry(entry string) (Range, error) {
+	entry = strings.TrimSpace(entry)
+	if first, last, ok := strings.Cut(entry, "-"); ok {
+		from, err := netip.ParseAddr(strings.TrimSpace(first))
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid range %q: %w", entry, err)
+		}
+		to, err := netip.ParseAddr(strings.TrimSpace(last))
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid range %q: %w", entry, err)
+		}
+		from, to = from.Unmap(), to.Unmap()
+		if from.BitLen() != to.BitLen() || to.Less(from) {
+			return Range{}, fmt.Errorf("invalid range %q", entry)
+		}
+		return Range{First: from, Last: to, Description: entry}, nil
+	}
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid prefix %q: %w", entry, err)
+		}
+		prefix = prefix.Masked()
+		return Range{First: prefix.Addr(), Last: lastAddr(prefix), Description: entry}, nil
+	}
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid address %q: %w", entry, err)
+	}
+	addr = addr.Unmap()
+	return Range{First: addr, Last: addr, Description: entry}, nil
+}
+
+// ParseEntries parses a static list of addresses, prefixes and ranges
+func ParseEntries(entries []string) ([]Range, error) {
+	ranges := make([]Range, 0, len(entries))
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		r, err := ParseEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// lastAddr returns the last address of a masked prefix
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(b)*8; bit++ {
+		b[bit/8] |= 1 << (7 - bit%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// ParseP2P reads a blocklist in the PeerGuardian P2P format, "description:
+// first-last" per line, optionally gzipped. Lines with a plain address,
+// prefix or range are accepted too. It returns the ranges and the number of
+// lines it couldn't parse, which blocklists collected from many sources
+// tend to have.
+func ParseP2P(r io.Reader) ([]Range, int, error) {
+	buffered := bufio.NewReader(r)
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decompress blocklist: %w", err)
+		}
+		defer gz.Close()
+		buffered = bufio.NewReader(gz)
+	}
+
+	var ranges []Range
+	skipped := 0
+	scanner := bufio.NewScanner(buffered)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		if r, ok, err := iplist.ParseBlocklistP2PLine(line); err == nil && ok {
+			first, firstOK := netip.AddrFromSlice(r.First)
+			last, lastOK := netip.AddrFromSlice(r.Last)
+			first, last = first.Unmap(), last.Unmap()
+			if firstOK && lastOK && first.BitLen() == last.BitLen() && !last.Less(first) {
+				ranges = append(ranges, Range{First: first, Last: last, Description: r.Description})
+				continue
+			}
+		}
+		if r, err := ParseEntry(string(line)); err == nil {
+			ranges = append(ranges, r)
+			continue
+		}
+		skipped++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, skipped, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+	return ranges, skipped, nil
+}
+
+// Stats counts the ranges of a filter and the addresses it rejected
+type Stats struct {
+	BlockedRanges      int   `json:"blocked_ranges"`
+	AllowedRanges      int   `json:"allowed_ranges"`
+	RejectedBlocked    int64 `json:"rejected_blocked"`     // On the blocklist
+	RejectedNotAllowed int64 `json:"rejected_not_allowed"` // Missing from the allowlist
+}
+
+// Filter is handed to the torrent client as its IP blocklist. The client
+// asks it before connecting to a peer, accepting a connection and talking
+// to a DHT node. The lists can be replaced while the client runs.
+type Filter struct {
+	mu      sync.RWMutex
+	blocked List
+	allowed List // Only these addresses are allowed if set
+
+	rejectedBlocked    atomic.Int64
+	rejectedNotAllowed atomic.Int64
+}
+
+// New returns a filter that allows every address
+func New() *Filter {
+	return &Filter{}
+}
+
+// SetLists replaces the blocked and allowed ranges. An empty allowlist
+// allows every address that isn't blocked.
+func (f *Filter) SetLists(blocked, allowed []Range) {
+	blockedList, allowedList := NewList(blocked), NewList(allowed)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blocked, f.allowed = blockedList, allowedList
+}
+
+// Check returns why addr is rejected, "" if it is allowed
+func (f *Filter) Check(addr netip.Addr) string {
+	addr = addr.Unmap()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if r, blocked := f.blocked.Lookup(addr); blocked {
+		f.rejectedBlocked.Add(1)
+		if r.Description == "" {
+			return "blocklist"
+		}
+		return "blocklist: " + r.Description
+	}
+	if len(f.allowed) > 0 {
+		if _, allowed := f.allowed.Lookup(addr); !allowed {
+			f.rejectedNotAllowed.Add(1)
+			return "not on the allowlist"
+		}
+	}
+	return ""
+}
+
+// Lookup implements iplist.Ranger, an address is in a range if it is rejected
+func (f *Filter) Lookup(ip net.IP) (iplist.Range, bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return iplist.Range{}, false
+	}
+	reason := f.Check(addr)
+	if reason == "" {
+		return iplist.Range{}, false
+	}
+	return iplist.Range{First: ip, Last: ip, Description: reason}, true
+}
+
+// NumRanges implements iplist.Ranger
+func (f *Filter) NumRanges() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.blocked)
+}
+
+// Stats returns the number of ranges and rejected addresses
+func (f *Filter) Stats() Stats {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return Stats{
+		BlockedRanges:      len(f.blocked),
+		AllowedRanges:      len(f.allowed),
+		RejectedBlocked:    f.rejectedBlocked.Load(),
+		RejectedNotAllowed: f.rejectedNotAllowed.Load(),
+	}
+}
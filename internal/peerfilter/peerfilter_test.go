@@ -0,0 +1,103 @@
+package peerfilter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEntry(t *testing.T) {
+	r, err := ParseEntry("10.1.0.0/16")
+	require.NoError(t, err)
+	assert.Equal(t, netip.MustParseAddr("10.1.0.0"), r.First)
+	assert.Equal(t, netip.MustParseAddr("10.1.255.255"), r.Last)
+
+	r, err = ParseEntry("192.0.2.10 - 192.0.2.20")
+	require.NoError(t, err)
+	assert.Equal(t, netip.MustParseAddr("192.0.2.20"), r.Last)
+
+	r, err = ParseEntry("2001:db8::/32")
+	require.NoError(t, err)
+	assert.Equal(t, netip.MustParseAddr("2001:db8:ffff:ffff:ffff:ffff:ffff:ffff"), r.Last)
+
+	r, err = ParseEntry("::ffff:198.51.100.1")
+	require.NoError(t, err)
+	assert.True(t, r.First.Is4(), "mapped addresses are IPv4")
+
+	for _, entry := range []string{"10.0.0.300", "10.0.0.0/33", "10.0.0.5-10.0.0.1", "10.0.0.1-2001:db8::1"} {
+		_, err := ParseEntry(entry)
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestList(t *testing.T) {
+	ranges, err := ParseEntries([]string{"10.0.0.0/24", "10.0.0.128-10.0.1.10", "10.0.1.11", "10.0.5.0/24", "2001:db8::/64"})
+	require.NoError(t, err)
+	list := NewList(ranges)
+	require.Len(t, list, 3, "overlapping and touching ranges are merged")
+
+	for addr, want := range map[string]bool{
+		"10.0.0.1":      true,
+		"10.0.1.11":     true,
+		"10.0.1.12":     false,
+		"10.0.5.200":    true,
+		"9.255.255.255": false,
+		"2001:db8::1":   true,
+		"2001:db9::1":   false,
+	} {
+		_, found := list.Lookup(netip.MustParseAddr(addr))
+		assert.Equal(t, want, found, addr)
+	}
+}
+
+func TestParseP2P(t *testing.T) {
+	text := `# Comment
+Bad actors:203.0.113.0-203.0.113.255
+Some org:198.51.100.7-198.51.100.7
+garbage line
+192.0.2.0/28
+`
+	ranges, skipped, err := ParseP2P(strings.NewReader(text))
+	require.NoError(t, err)
+	assert.Len(t, ranges, 3)
+	assert.Equal(t, 1, skipped)
+	assert.Equal(t, "Bad actors", ranges[0].Description)
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(text))
+	require.NoError(t, w.Close())
+	ranges, _, err = ParseP2P(&gz)
+	require.NoError(t, err)
+	assert.Len(t, ranges, 3, "gzipped lists are decompressed")
+}
+
+func TestFilter(t *testing.T) {
+	f := New()
+	_, blocked := f.Lookup(net.ParseIP("203.0.113.5"))
+	assert.False(t, blocked, "an empty filter allows everything")
+
+	blockedRanges, err := ParseEntries([]string{"10.0.0.66"})
+	require.NoError(t, err)
+	allowedRanges, err := ParseEntries([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	f.SetLists(blockedRanges, allowedRanges)
+
+	_, blocked = f.Lookup(net.ParseIP("10.1.2.3"))
+	assert.False(t, blocked)
+	r, blocked := f.Lookup(net.ParseIP("10.0.0.66"))
+	assert.True(t, blocked, "the blocklist wins over the allowlist")
+	assert.Contains(t, r.Description, "blocklist")
+	_, blocked = f.Lookup(net.ParseIP("203.0.113.5"))
+	assert.True(t, blocked, "addresses missing from the allowlist are rejected")
+	_, blocked = f.Lookup(net.ParseIP("::ffff:10.1.2.3"))
+	assert.False(t, blocked, "mapped addresses match IPv4 ranges")
+
+	assert.Equal(t, Stats{BlockedRanges: 1, AllowedRanges: 1, RejectedBlocked: 1, RejectedNotAllowed: 1}, f.Stats())
+}
@@ -0,0 +1,332 @@
+// Package proxy routes outbound connections through a SOCKS5 or HTTP proxy.
+// Each component of the daemon can use the global proxy, its own proxy or
+// no proxy, and hosts on the no-proxy list are always reached directly.
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/config"
+	xproxy "golang.org/x/net/proxy"
+)
+
+// Components with their own proxy setting
+const (
+	Torrent = "torrent" // Trackers, web seeds and outgoing peer connections
+	DHT     = "dht"     // Resolution of DHT bootstrap nodes
+	Git     = "git"     // Git and HuggingFace mirroring
+	API     = "api"     // CLI requests to the daemon
+)
+
+// Direct disables the proxy of a component
+const Direct = "direct"
+
+// Components lists the components with a proxy setting
+var Components = []string{Torrent, DHT, Git, API}
+
+// Proxy dials through a proxy server. A nil Proxy dials directly.
+type Proxy struct {
+	url     *url.URL
+	noProxy []string
+	direct  *net.Dialer
+	socks   xproxy.ContextDialer // Set for SOCKS5 proxies
+}
+
+// New returns a proxy for a socks5://, socks5h:// or http:// URL, nil if
+// rawURL is empty or "direct". Hosts matching noProxy are dialed directly.
+func New(rawURL string, noProxy []string) (*Proxy, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" || rawURL == Direct {
+		return nil, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", rawURL, err)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("proxy URL %q must include a host and port", rawURL)
+	}
+
+	p := &Proxy{
+		url:    u,
+		direct: &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+	}
+	for _, entry := range noProxy {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			p.noProxy = append(p.noProxy, entry)
+		}
+	}
+
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		var auth *xproxy.Auth
+		if u.User != nil {
+			password, _ := u.User.Password()
+			auth = &xproxy.Auth{User: u.User.Username(), Password: password}
+		}
+		d, err := xproxy.SOCKS5("tcp", u.Host, auth, p.direct)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", rawURL, err)
+		}
+		p.socks = d.(xproxy.ContextDialer)
+	case "http":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, expected socks5, socks5h or http", u.Scheme)
+	}
+	return p, nil
+}
+
+// FromConfig returns the proxy of a component, nil if it connects directly.
+// Components without their own setting use proxy.url.
+func FromConfig(cfg *config.Config, component string) (*Proxy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	rawURL := cfg.Proxy.URL
+	var override string
+	switch component {
+	case Torrent:
+		override = cfg.Proxy.Torrent
+	case DHT:
+		override = cfg.Proxy.DHT
+	case Git:
+		override = cfg.Proxy.Git
+	case API:
+		override = cfg.Proxy.API
+	default:
+		return nil, fmt.Errorf("unknown proxy component %q", component)
+	}
+	if override != "" {
+		rawURL = override
+	}
+
+	p, err := New(rawURL, cfg.Proxy.NoProxy)
+	if err != nil {
+		return nil, fmt.Errorf("%s proxy: %w", component, err)
+	}
+	return p, nil
+}
+
+// String returns the proxy URL without credentials
+func (p *Proxy) String() string {
+	if p == nil {
+		return Direct
+	}
+	return p.url.Redacted()
+}
+
+// URL returns the proxy URL, nil for a direct connection
+func (p *Proxy) URL() *url.URL {
+	if p == nil {
+		return nil
+	}
+	return p.url
+}
+
+// Bypass reports whether a host or host:port is on the no-proxy list.
+// Entries are "*", IP addresses, CIDR ranges and domains, which also match
+// their subdomains.
+func (p *Proxy) Bypass(addr string) bool {
+	if p == nil {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	ip := net.ParseIP(host)
+
+	for _, entry := range p.noProxy {
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if entryIP := net.ParseIP(strings.Trim(entry, "[]")); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		domain := strings.TrimPrefix(entry, ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// DialContext connects to addr through the proxy, or directly if addr is on
+// the no-proxy list. Only TCP goes through the proxy.
+func (p *Proxy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if p == nil || p.Bypass(addr) {
+		var d net.Dialer
+		if p != nil {
+			d = *p.direct
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("network %s can't be used through proxy %s", network, p)
+	}
+
+	if p.socks != nil {
+		// Plain socks5 resolves names locally, socks5h leaves it to the proxy
+		if p.url.Scheme == "socks5" {
+			resolved, err := resolveAddr(ctx, addr)
+			if err != nil {
+				return nil, err
+			}
+			addr = resolved
+		}
+		conn, err := p.socks.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("proxy %s: %w", p, err)
+		}
+		return conn, nil
+	}
+	return p.dialConnect(ctx, addr)
+}
+
+// resolveAddr replaces the host name of addr with one of its IP addresses
+func resolveAddr(ctx context.Context, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return addr, nil
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("no addresses found for %s", host)
+	}
+	return net.JoinHostPort(ips[0].IP.String(), port), nil
+}
+
+// dialConnect opens a tunnel to addr with an HTTP CONNECT request
+func (p *Proxy) dialConnect(ctx context.Context, addr string) (net.Conn, error) {
+	conn, err := p.direct.DialContext(ctx, "tcp", p.url.Host)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", p, err)
+	}
+
+	// Abort the handshake when the context ends
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if p.url.User != nil {
+		password, _ := p.url.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(p.url.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %w", p, err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("proxy %s: %w", p, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused connection to %s: %s", p, addr, resp.Status)
+	}
+
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a connection with data already read into a buffer
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// ProxyFunc returns the proxy for a request, for use as http.Transport.Proxy
+func (p *Proxy) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if p == nil || p.Bypass(req.URL.Host) {
+			return nil, nil
+		}
+		return p.url, nil
+	}
+}
+
+// Transport returns an HTTP transport sending requests through the proxy
+func (p *Proxy) Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = p.ProxyFunc()
+	return transport
+}
+
+// HTTPClient returns an HTTP client sending requests through the proxy
+func (p *Proxy) HTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: p.Transport(), Timeout: timeout}
+}
+
+// Resolver returns a DNS resolver that queries name servers over TCP
+// through the proxy, name servers on the no-proxy list are queried directly
+func (p *Proxy) Resolver() *net.Resolver {
+	if p == nil {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if p.Bypass(addr) {
+				return p.direct.DialContext(ctx, network, addr)
+			}
+			return p.DialContext(ctx, "tcp", addr)
+		},
+	}
+}
+
+// Env returns the proxy environment variables for subprocesses such as git
+func (p *Proxy) Env() []string {
+	if p == nil {
+		return nil
+	}
+	u := p.url.String()
+	noProxy := strings.Join(p.noProxy, ",")
+	return []string{
+		"HTTP_PROXY=" + u, "http_proxy=" + u,
+		"HTTPS_PROXY=" + u, "https_proxy=" + u,
+		"ALL_PROXY=" + u, "all_proxy=" + u,
+		"NO_PROXY=" + noProxy, "no_proxy=" + noProxy,
+	}
+}
@@ -0,0 +1,95 @@
+package publish
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/silmaril/silmaril/internal/models"
+)
+
+// Found is a model directory found under a directory tree
+type Found struct {
+	Name string `json:"name"` // Name inferred from the directory tree
+	Path string `json:"path"`
+}
+
+// IsModelDir reports whether dir holds a model: a HuggingFace config or
+// GGUF weights
+func IsModelDir(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if name == models.HFConfigFile || strings.EqualFold(filepath.Ext(name), ".gguf") {
+			return true
+		}
+	}
+	return false
+}
+
+// FindModels returns the model directories under root, sorted by name. The
+// directories inside a model belong to it and are not searched. Without
+// recursive only root itself is considered.
+func FindModels(root string, recursive bool) ([]Found, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", root, err)
+	}
+
+	if !recursive {
+		if !IsModelDir(root) {
+			return nil, nil
+		}
+		return []Found{{Name: InferName(root, root), Path: root}}, nil
+	}
+
+	var found []Found
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if IsModelDir(path) {
+			found = append(found, Found{Name: InferName(root, path), Path: path})
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", root, err)
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Name < found[j].Name
+	})
+	return found, nil
+}
+
+// InferName names the model in dir after its place in the tree under root:
+// root/org/model and root/a/org/model become org/model, root/model becomes
+// <root>/model, and a model at root is named after root and its parent.
+func InferName(root, dir string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return filepath.Base(filepath.Dir(dir)) + "/" + filepath.Base(dir)
+	}
+
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) == 1 {
+		return filepath.Base(root) + "/" + parts[0]
+	}
+	return strings.Join(parts[len(parts)-2:], "/")
+}
@@ -0,0 +1,60 @@
+package publish
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles creates files with their parent directories under root
+func writeFiles(t *testing.T, root string, files ...string) {
+	for _, file := range files {
+		path := filepath.Join(root, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("{}"), 0644))
+	}
+}
+
+func TestFindModels(t *testing.T) {
+	zoo := filepath.Join(t.TempDir(), "zoo")
+	writeFiles(t, zoo,
+		"meta-llama/Llama-3-8B/config.json",
+		"meta-llama/Llama-3-8B/model.safetensors",
+		"meta-llama/Llama-3-8B/original/config.json", // Part of the model above
+		"TheBloke/Mistral-GGUF/mistral.Q4_K_M.gguf",
+		"tinyllama/config.json",
+		"archive/2023/org/old/config.json",
+		"docs/README.md",
+		".cache/org/cached/config.json",
+	)
+
+	found, err := FindModels(zoo, true)
+	require.NoError(t, err)
+	assert.Equal(t, []Found{
+		{Name: "TheBloke/Mistral-GGUF", Path: filepath.Join(zoo, "TheBloke/Mistral-GGUF")},
+		{Name: "meta-llama/Llama-3-8B", Path: filepath.Join(zoo, "meta-llama/Llama-3-8B")},
+		{Name: "org/old", Path: filepath.Join(zoo, "archive/2023/org/old")},
+		{Name: "zoo/tinyllama", Path: filepath.Join(zoo, "tinyllama")},
+	}, found)
+
+	// Without recursive only the directory itself can be a model
+	found, err = FindModels(zoo, false)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	found, err = FindModels(filepath.Join(zoo, "meta-llama/Llama-3-8B"), false)
+	require.NoError(t, err)
+	assert.Equal(t, []Found{{Name: "meta-llama/Llama-3-8B", Path: filepath.Join(zoo, "meta-llama/Llama-3-8B")}}, found)
+}
+
+func TestIsModelDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.False(t, IsModelDir(dir))
+	assert.False(t, IsModelDir(filepath.Join(dir, "missing")))
+
+	writeFiles(t, dir, "weights.GGUF")
+	assert.True(t, IsModelDir(dir))
+}
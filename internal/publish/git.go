@@ -0,0 +1,549 @@
+package publish
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/silmaril/silmaril/internal/proxy"
+	"github.com/silmaril/silmaril/internal/storage"
+)
+
+// GitCloner clones git repositories, including their Git LFS files, and
+// removes the .git directory afterwards
+type GitCloner struct {
+	// Token returns the HuggingFace token to clone a repository with, nil
+	// uses HF_TOKEN for HuggingFace repositories
+	Token func(repoURL string) string
+}
+
+// token returns the token to clone repoURL with, if any
+func (c GitCloner) token(repoURL string) string {
+	if c.Token != nil {
+		return c.Token(repoURL)
+	}
+	if strings.Contains(repoURL, "huggingface.co") {
+		return os.Getenv("HF_TOKEN")
+	}
+	return ""
+}
+
+// Clone clones repo into path and returns the commit it was cloned at. Once
+// the git objects are fetched the directory is marked partial and keeps its
+// .git directory until the LFS files are downloaded too, so a clone with
+// repo.Resume set continues from there instead of starting over.
+func (c GitCloner) Clone(ctx context.Context, repo *Repo, path string) (string, error) {
+	cloneOptions := &git.CloneOptions{
+		URL:          repo.URL,
+		Progress:     os.Stdout,
+		ProxyOptions: gitProxyOptions(repo.Proxy, repo.URL),
+	}
+
+	// Set branch if not main/master
+	if repo.Branch != "" && repo.Branch != "main" && repo.Branch != "master" {
+		cloneOptions.ReferenceName = plumbing.NewBranchReferenceName(repo.Branch)
+	}
+
+	// Set depth for shallow clone
+	if repo.Depth > 0 {
+		cloneOptions.Depth = repo.Depth
+	}
+
+	// Tags are cloned like branches. Servers don't hand out arbitrary
+	// commits, so pinning one clones the history of every branch to check
+	// it out from.
+	if isCommitHash(repo.Revision) {
+		cloneOptions.ReferenceName = ""
+		cloneOptions.Depth = 0
+	} else if repo.Revision != "" {
+		cloneOptions.ReferenceName = plumbing.NewTagReferenceName(repo.Revision)
+		cloneOptions.SingleBranch = true
+	}
+
+	// HuggingFace needs a token for private and gated repositories
+	if token := c.token(repo.URL); token != "" {
+		cloneOptions.Auth = &githttp.BasicAuth{
+			Username: "hf",
+			Password: token,
+		}
+	}
+
+	gitRepo := reopenClone(repo, path)
+	if gitRepo == nil {
+		// Whatever an interrupted clone left behind is of no use
+		if storage.IsPartial(path) {
+			if err := os.RemoveAll(path); err != nil {
+				return "", fmt.Errorf("failed to remove partial clone: %w", err)
+			}
+		}
+
+		var err error
+		gitRepo, err = git.PlainCloneContext(ctx, path, false, cloneOptions)
+		if err != nil {
+			switch {
+			case errors.Is(err, transport.ErrAuthenticationRequired):
+				return "", fmt.Errorf("authentication required for %s, log in with 'silmaril auth hf login' or set HF_TOKEN for gated models: %w", repo.URL, err)
+			case errors.Is(err, transport.ErrRepositoryNotFound):
+				return "", fmt.Errorf("repository %s not found: %w", repo.URL, err)
+			case errors.Is(err, plumbing.ErrReferenceNotFound) && repo.Revision != "":
+				return "", fmt.Errorf("tag %s not found in %s: %w", repo.Revision, repo.URL, err)
+			}
+			return "", fmt.Errorf("failed to clone repository: %w", err)
+		}
+		if isCommitHash(repo.Revision) {
+			if err := checkoutCommit(gitRepo, repo.Revision); err != nil {
+				os.RemoveAll(path)
+				return "", err
+			}
+		}
+		fmt.Printf("[Publish] Repository cloned successfully to %s\n", path)
+	} else {
+		fmt.Printf("[Publish] Continuing partial clone in %s\n", path)
+	}
+	if err := storage.MarkPartial(path); err != nil {
+		return "", err
+	}
+
+	// The commit is recorded as the provenance of the model, read it before
+	// the .git directory goes
+	var revision string
+	if head, err := gitRepo.Head(); err == nil {
+		revision = head.Hash().String()
+	}
+
+	// Files left as LFS pointers would be published in place of the
+	// weights, the partial clone is kept for sharing the repository again
+	if !repo.SkipLFS {
+		fmt.Printf("[Publish] Checking for LFS files...\n")
+		if err := downloadLFSFiles(ctx, gitRepo, path, cloneOptions.Auth, repo); err != nil {
+			return "", fmt.Errorf("failed to download LFS files, share the repository again to continue: %w", err)
+		}
+		fmt.Printf("[Publish] LFS files downloaded successfully\n")
+	}
+
+	// Remove .git directory to save space
+	if err := os.RemoveAll(filepath.Join(path, ".git")); err != nil {
+		fmt.Printf("[Publish] Warning: failed to remove .git directory: %v\n", err)
+	}
+	if err := storage.ClearPartial(path); err != nil {
+		return "", err
+	}
+	return revision, nil
+}
+
+// reopenClone returns the clone of repo an interrupted clone left in path,
+// nil if cloning doesn't resume or there is none
+func reopenClone(repo *Repo, path string) *git.Repository {
+	if repo.Resume == nil || !storage.IsPartial(path) {
+		return nil
+	}
+	gitRepo, err := git.PlainOpen(path)
+	if err != nil {
+		return nil
+	}
+	remote, err := gitRepo.Remote(git.DefaultRemoteName)
+	if err != nil || len(remote.Config().URLs) == 0 || remote.Config().URLs[0] != repo.URL {
+		return nil
+	}
+	// A clone pinned to another revision holds other files
+	if repo.Revision != "" {
+		head, err := gitRepo.Head()
+		if err != nil {
+			return nil
+		}
+		pinned, err := gitRepo.ResolveRevision(plumbing.Revision(repo.Revision))
+		if err != nil || *pinned != head.Hash() {
+			return nil
+		}
+	}
+	return gitRepo
+}
+
+// isCommitHash reports whether a revision is a commit hash, full or
+// abbreviated, rather than a tag
+func isCommitHash(revision string) bool {
+	if len(revision) < 7 || len(revision) > 40 {
+		return false
+	}
+	for _, c := range revision {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// checkoutCommit checks out the commit a revision names in a fresh clone
+func checkoutCommit(gitRepo *git.Repository, revision string) error {
+	hash, err := gitRepo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return fmt.Errorf("commit %s not found in repository: %w", revision, err)
+	}
+	worktree, err := gitRepo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open worktree: %w", err)
+	}
+	if err := worktree.Checkout(&git.CheckoutOptions{Hash: *hash}); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", revision, err)
+	}
+	return nil
+}
+
+// RepoModelName extracts the model name from a repository URL
+func RepoModelName(repoURL string) string {
+	// Handle HuggingFace URLs
+	if strings.Contains(repoURL, "huggingface.co") {
+		parts := strings.Split(repoURL, "/")
+		if len(parts) >= 5 {
+			// Format: https://huggingface.co/owner/model
+			return parts[3] + "/" + parts[4]
+		}
+	}
+
+	// Handle GitHub URLs
+	if strings.Contains(repoURL, "github.com") {
+		parts := strings.Split(repoURL, "/")
+		if len(parts) >= 5 {
+			// Format: https://github.com/owner/repo
+			owner := parts[3]
+			repo := strings.TrimSuffix(parts[4], ".git")
+			return owner + "/" + repo
+		}
+	}
+
+	// For other git URLs, try to extract a reasonable name
+	parts := strings.Split(repoURL, "/")
+	if len(parts) >= 2 {
+		modelName := strings.TrimSuffix(parts[len(parts)-1], ".git")
+		if len(parts) >= 3 {
+			return parts[len(parts)-2] + "/" + modelName
+		}
+		return "unknown/" + modelName
+	}
+
+	return ""
+}
+
+// gitProxyOptions returns the proxy to clone a repository through, none if
+// the repository host is on the no-proxy list
+func gitProxyOptions(p *proxy.Proxy, repoURL string) transport.ProxyOptions {
+	if p == nil {
+		return transport.ProxyOptions{}
+	}
+	if u, err := url.Parse(repoURL); err == nil && p.Bypass(u.Host) {
+		return transport.ProxyOptions{}
+	}
+	proxyURL := *p.URL()
+	opts := transport.ProxyOptions{}
+	if proxyURL.User != nil {
+		opts.Username = proxyURL.User.Username()
+		opts.Password, _ = proxyURL.User.Password()
+		proxyURL.User = nil
+	}
+	opts.URL = proxyURL.String()
+	return opts
+}
+
+// downloadLFSFiles downloads Git LFS files in the repository. git-lfs keeps
+// the objects it fetched in the .git directory, the manual download keeps
+// partially downloaded files next to their pointers, so either continues
+// where an interrupted clone stopped.
+func downloadLFSFiles(ctx context.Context, repo *git.Repository, repoPath string, auth transport.AuthMethod, cloned *Repo) error {
+	p := cloned.Proxy
+	// First, check if git-lfs is installed
+	if _, err := exec.LookPath("git-lfs"); err != nil {
+		fmt.Printf("[LFS] git-lfs not found in PATH, trying to download LFS files manually\n")
+		return downloadLFSFilesManually(ctx, repo, repoPath, auth, cloned)
+	}
+
+	// Use git-lfs to pull files
+	fmt.Printf("[LFS] Using git-lfs to pull LFS files\n")
+	cmd := exec.CommandContext(ctx, "git", "lfs", "pull")
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(), p.Env()...)
+
+	// Set up authentication if provided
+	if auth != nil {
+		if basicAuth, ok := auth.(*githttp.BasicAuth); ok {
+			cmd.Env = append(cmd.Env,
+				fmt.Sprintf("GIT_ASKPASS=echo"),
+				fmt.Sprintf("GIT_USERNAME=%s", basicAuth.Username),
+				fmt.Sprintf("GIT_PASSWORD=%s", basicAuth.Password),
+			)
+		}
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git lfs pull failed: %v, output: %s", err, output)
+	}
+
+	fmt.Printf("[LFS] git-lfs pull output: %s\n", output)
+	return nil
+}
+
+// downloadLFSFilesManually downloads LFS files without git-lfs command,
+// continuing the downloads cloned.Resume has progress for
+func downloadLFSFilesManually(ctx context.Context, repo *git.Repository, repoPath string, auth transport.AuthMethod, cloned *Repo) error {
+	// Find all LFS pointer files
+	lfsFiles := make(map[string]*LFSPointer)
+
+	err := filepath.Walk(repoPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip directories and .git folder
+		if info.IsDir() || strings.Contains(path, ".git") {
+			return nil
+		}
+
+		// Check if file is an LFS pointer
+		if pointer := parseLFSPointer(path); pointer != nil {
+			lfsFiles[path] = pointer
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to walk repository: %v", err)
+	}
+
+	if len(lfsFiles) == 0 {
+		fmt.Printf("[LFS] No LFS files found\n")
+		return nil
+	}
+
+	fmt.Printf("[LFS] Found %d LFS pointer files\n", len(lfsFiles))
+
+	// Get the remote URL
+	remotes, err := repo.Remotes()
+	if err != nil || len(remotes) == 0 {
+		return fmt.Errorf("no remotes configured")
+	}
+
+	remoteURL := remotes[0].Config().URLs[0]
+	lfsURL := getLFSEndpoint(remoteURL)
+
+	// Download each LFS file
+	var failed int
+	var firstErr error
+	for filePath, pointer := range lfsFiles {
+		name, err := filepath.Rel(repoPath, filePath)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		offset := cloned.Resume.offset(name, pointer.Size)
+		if offset > 0 {
+			fmt.Printf("[LFS] Continuing %s at %.2f of %.2f MB\n", filePath, float64(offset)/(1024*1024), float64(pointer.Size)/(1024*1024))
+		} else {
+			fmt.Printf("[LFS] Downloading %s (%.2f MB)\n", filePath, float64(pointer.Size)/(1024*1024))
+		}
+
+		progress := func(downloaded int64) {
+			if cloned.Progress != nil {
+				cloned.Progress(name, downloaded, pointer.Size)
+			}
+		}
+		if err := downloadLFSObject(ctx, lfsURL, pointer, filePath, offset, auth, cloned.Proxy, progress); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Printf("[LFS] Failed to download %s: %v\n", filePath, err)
+			// Continue with other files
+			failed++
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed, first: %w", failed, len(lfsFiles), firstErr)
+	}
+	return nil
+}
+
+// LFSPointer represents a Git LFS pointer file
+type LFSPointer struct {
+	OID  string
+	Size int64
+}
+
+// parseLFSPointer checks if a file is an LFS pointer and parses it
+func parseLFSPointer(filePath string) *LFSPointer {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	// LFS pointer files are small (< 1KB)
+	info, err := file.Stat()
+	if err != nil || info.Size() > 1024 {
+		return nil
+	}
+
+	scanner := bufio.NewScanner(file)
+	var pointer LFSPointer
+	hasVersion := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "version https://git-lfs.github.com/spec/v1" {
+			hasVersion = true
+		} else if strings.HasPrefix(line, "oid sha256:") {
+			pointer.OID = strings.TrimPrefix(line, "oid sha256:")
+		} else if strings.HasPrefix(line, "size ") {
+			fmt.Sscanf(line, "size %d", &pointer.Size)
+		}
+	}
+
+	if hasVersion && pointer.OID != "" && pointer.Size > 0 {
+		return &pointer
+	}
+
+	return nil
+}
+
+// getLFSEndpoint converts a git remote URL to an LFS endpoint
+func getLFSEndpoint(remoteURL string) string {
+	// For GitHub
+	if strings.Contains(remoteURL, "github.com") {
+		// Convert git@github.com:user/repo.git to https://github.com/user/repo.git
+		if strings.HasPrefix(remoteURL, "git@") {
+			remoteURL = strings.Replace(remoteURL, "git@github.com:", "https://github.com/", 1)
+		}
+		remoteURL = strings.TrimSuffix(remoteURL, ".git")
+		return remoteURL + ".git/info/lfs"
+	}
+
+	// For HuggingFace
+	if strings.Contains(remoteURL, "huggingface.co") {
+		remoteURL = strings.TrimSuffix(remoteURL, ".git")
+		return remoteURL + ".git/info/lfs"
+	}
+
+	// Generic Git LFS endpoint
+	return strings.TrimSuffix(remoteURL, "/") + "/info/lfs"
+}
+
+// downloadLFSObject downloads a single LFS object. The object is written
+// to a temporary file next to the pointer; with an offset, the first offset
+// bytes of an earlier download in that file are kept and the rest is
+// requested with a range request. progress is called with the bytes of the
+// object on disk as they are written.
+func downloadLFSObject(ctx context.Context, lfsEndpoint string, pointer *LFSPointer, filePath string, offset int64, auth transport.AuthMethod, p *proxy.Proxy, progress func(downloaded int64)) error {
+	tmpFile := filePath + ".tmp"
+	if info, err := os.Stat(tmpFile); err != nil || info.Size() < offset {
+		offset = 0
+	}
+
+	// Construct download URL
+	// For simplicity, try direct download first (works for public repos)
+	downloadURL := fmt.Sprintf("%s/objects/%s", lfsEndpoint, pointer.OID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	// Add authentication if provided
+	if auth != nil {
+		if basicAuth, ok := auth.(*githttp.BasicAuth); ok {
+			req.SetBasicAuth(basicAuth.Username, basicAuth.Password)
+		}
+	}
+
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+
+	client := &http.Client{Transport: p.Transport()}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// The server ignored the range and sends the whole object
+		offset = 0
+	default:
+		// Try batch API for authenticated downloads
+		return downloadLFSObjectBatch(lfsEndpoint, pointer, filePath, auth)
+	}
+
+	// Download to temporary file first
+	out, err := os.OpenFile(tmpFile, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if err := out.Truncate(offset); err != nil {
+		return err
+	}
+
+	// Download and verify SHA256, over what was downloaded before too
+	hasher := sha256.New()
+	if _, err := io.CopyN(hasher, out, offset); err != nil {
+		return err
+	}
+	writer := io.MultiWriter(out, hasher, &progressWriter{written: offset, report: progress})
+
+	// The partial file is kept for the next clone to continue
+	_, err = io.Copy(writer, resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// Verify OID
+	computedOID := hex.EncodeToString(hasher.Sum(nil))
+	if computedOID != pointer.OID {
+		os.Remove(tmpFile)
+		return fmt.Errorf("OID mismatch: expected %s, got %s", pointer.OID, computedOID)
+	}
+
+	// Replace pointer file with actual content
+	return os.Rename(tmpFile, filePath)
+}
+
+// progressWriter reports the bytes written through it, on top of those
+// written before
+type progressWriter struct {
+	written int64
+	report  func(written int64)
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	w.written += int64(len(b))
+	w.report(w.written)
+	return len(b), nil
+}
+
+// downloadLFSObjectBatch uses the batch API for authenticated downloads
+func downloadLFSObjectBatch(lfsEndpoint string, pointer *LFSPointer, filePath string, auth transport.AuthMethod) error {
+	// This is a simplified version - full implementation would use the batch API
+	// For now, return an error suggesting to use git-lfs
+	return fmt.Errorf("authenticated LFS download requires git-lfs command")
+}
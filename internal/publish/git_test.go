@@ -0,0 +1,158 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLFSServer serves content as the LFS object with its OID, honouring
+// range requests, and records the ranges requested
+func newLFSServer(t *testing.T, content []byte) (*httptest.Server, *LFSPointer, *[]string) {
+	sum := sha256.Sum256(content)
+	pointer := &LFSPointer{OID: hex.EncodeToString(sum[:]), Size: int64(len(content))}
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/objects/"+pointer.OID {
+			http.NotFound(w, r)
+			return
+		}
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server, pointer, &ranges
+}
+
+func TestDownloadLFSObjectResume(t *testing.T) {
+	content := make([]byte, 1<<20)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+	server, pointer, ranges := newLFSServer(t, content)
+
+	// An interrupted download left the first half
+	filePath := filepath.Join(t.TempDir(), "model.safetensors")
+	require.NoError(t, os.WriteFile(filePath, []byte("version https://git-lfs.github.com/spec/v1\n"), 0644))
+	half := int64(len(content) / 2)
+	require.NoError(t, os.WriteFile(filePath+".tmp", content[:half], 0644))
+
+	var downloaded int64
+	err = downloadLFSObject(context.Background(), server.URL, pointer, filePath, half, nil, nil, func(n int64) { downloaded = n })
+	require.NoError(t, err)
+
+	got, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+	assert.NoFileExists(t, filePath+".tmp")
+	assert.Equal(t, pointer.Size, downloaded)
+	assert.Equal(t, []string{"bytes=524288-"}, *ranges, "only the rest is requested")
+}
+
+func TestDownloadLFSObjectRestart(t *testing.T) {
+	content := []byte(strings.Repeat("weights", 1000))
+	server, pointer, ranges := newLFSServer(t, content)
+	filePath := filepath.Join(t.TempDir(), "model.safetensors")
+
+	// The partial file is shorter than the progress recorded, so it starts over
+	require.NoError(t, os.WriteFile(filePath+".tmp", content[:10], 0644))
+	err := downloadLFSObject(context.Background(), server.URL, pointer, filePath, 100, nil, nil, func(int64) {})
+	require.NoError(t, err)
+	got, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+	assert.Equal(t, []string{""}, *ranges)
+
+	// A corrupt partial file fails the checksum and is dropped
+	require.NoError(t, os.WriteFile(filePath+".tmp", []byte("corrupt!!!"), 0644))
+	err = downloadLFSObject(context.Background(), server.URL, pointer, filePath, 10, nil, nil, func(int64) {})
+	assert.ErrorContains(t, err, "OID mismatch")
+	assert.NoFileExists(t, filePath+".tmp")
+}
+
+func TestCloneProgressOffset(t *testing.T) {
+	progress := CloneProgress{"model.safetensors": {Size: 100, Downloaded: 40}}
+	assert.Equal(t, int64(40), progress.offset("model.safetensors", 100))
+	assert.Equal(t, int64(0), progress.offset("model.safetensors", 200), "the file changed upstream")
+	assert.Equal(t, int64(0), progress.offset("other.bin", 100))
+	assert.Equal(t, int64(0), CloneProgress(nil).offset("model.safetensors", 100))
+}
+
+// newTestRepo creates a repository with a tagged first commit and a second
+// commit changing the model card, and returns it with the first commit
+func newTestRepo(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	commit := func(card string) plumbing.Hash {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte(card), 0644))
+		_, err := worktree.Add("README.md")
+		require.NoError(t, err)
+		hash, err := worktree.Commit(card, &git.CommitOptions{Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}})
+		require.NoError(t, err)
+		return hash
+	}
+	first := commit("# v1\n")
+	_, err = repo.CreateTag("v1.0", first, nil)
+	require.NoError(t, err)
+	commit("# v2\n")
+	return dir, first.String()
+}
+
+func TestCloneRevision(t *testing.T) {
+	source, first := newTestRepo(t)
+
+	for _, revision := range []string{"v1.0", first[:10], first} {
+		t.Run(revision, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "model")
+			cloned, err := GitCloner{}.Clone(context.Background(), &Repo{URL: source, Revision: revision, Depth: 1, SkipLFS: true}, path)
+			require.NoError(t, err)
+			assert.Equal(t, first, cloned)
+
+			card, err := os.ReadFile(filepath.Join(path, "README.md"))
+			require.NoError(t, err)
+			assert.Equal(t, "# v1\n", string(card))
+			assert.NoDirExists(t, filepath.Join(path, ".git"))
+		})
+	}
+
+	// Without a revision the branch head is cloned
+	path := filepath.Join(t.TempDir(), "model")
+	cloned, err := GitCloner{}.Clone(context.Background(), &Repo{URL: source, SkipLFS: true}, path)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, cloned)
+
+	_, err = GitCloner{}.Clone(context.Background(), &Repo{URL: source, Revision: "0000000000", SkipLFS: true}, filepath.Join(t.TempDir(), "model"))
+	assert.ErrorContains(t, err, "commit 0000000000 not found")
+}
+
+func TestPinVersion(t *testing.T) {
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+	manifest := &types.ModelManifest{Version: "main"}
+	pinVersion(manifest, &Repo{}, commit)
+	assert.Equal(t, "main", manifest.Version, "unpinned clones keep the branch")
+
+	pinVersion(manifest, &Repo{Revision: "v1.0"}, commit)
+	assert.Equal(t, "v1.0", manifest.Version)
+
+	pinVersion(manifest, &Repo{Revision: "0123456"}, commit)
+	assert.Equal(t, commit, manifest.Version, "abbreviated commits are recorded in full")
+}
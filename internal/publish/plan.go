@@ -0,0 +1,104 @@
+package publish
+
+import (
+	"fmt"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/torrent"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// Plan is what publishing a model would do, worked out without writing
+// anything
+type Plan struct {
+	Model        string                   `json:"model"`
+	Path         string                   `json:"path"` // Directory the torrent is created from
+	Files        []PlannedFile            `json:"files"`
+	Excluded     []string                 `json:"excluded,omitempty"` // Files left out of the torrent
+	TotalSize    int64                    `json:"total_size"`
+	PieceLength  int64                    `json:"piece_length"`
+	Pieces       int                      `json:"pieces"`
+	InfoHash     string                   `json:"info_hash"`
+	Manifest     *types.ModelManifest     `json:"manifest"`
+	Announcement *types.ModelAnnouncement `json:"announcement,omitempty"` // Catalog entry, unless announcing is skipped
+}
+
+// PlannedFile is a file of the torrent a plan would create
+type PlannedFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// PlanRequest works out what publishing req would do: the files of the
+// torrent, its infohash, the manifest and the announcement. The files are
+// read from the imported directory, or Path, and hashed, but nothing is
+// written. Repositories can't be planned before they are cloned.
+func PlanRequest(req Request) (*Plan, error) {
+	if req.Repo != nil {
+		return nil, fmt.Errorf("repositories must be cloned before they can be planned")
+	}
+
+	dir := req.Path
+	if req.Import != nil {
+		dir = req.Import.From
+	}
+	if req.SeedPath != "" && req.SeedPath != req.Path {
+		dir = req.SeedPath
+	}
+
+	ignore, err := req.ignore(dir)
+	if err != nil {
+		return nil, err
+	}
+	files, excluded, err := torrent.DirectoryFiles(dir, ignore)
+	if err != nil {
+		return nil, err
+	}
+	info, err := torrent.BuildInfo(dir, req.PieceLength, ignore)
+	if err != nil {
+		return nil, err
+	}
+	infoHash, err := torrent.InfoHash(info)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work on a copy, the manifest of the request is saved when publishing
+	var manifest *types.ModelManifest
+	if req.Manifest != nil {
+		copied := *req.Manifest
+		copied.Files = append([]types.ModelFile(nil), req.Manifest.Files...)
+		manifest = &copied
+		dropIgnored(manifest, ignore)
+	} else {
+		manifest, err = models.GenerateManifest(dir, req.Name, ignore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate manifest: %w", err)
+		}
+	}
+	if req.Edit != nil {
+		req.Edit(manifest)
+	}
+
+	plan := &Plan{
+		Model:       req.Name,
+		Path:        dir,
+		Files:       make([]PlannedFile, 0, len(files)),
+		Excluded:    excluded,
+		TotalSize:   info.TotalLength(),
+		PieceLength: info.PieceLength,
+		Pieces:      info.NumPieces(),
+		InfoHash:    infoHash,
+		Manifest:    manifest,
+	}
+	for _, file := range files {
+		plan.Files = append(plan.Files, PlannedFile{Path: file.Path[0], Size: file.Length})
+	}
+	if manifest.TotalSize == 0 {
+		manifest.TotalSize = plan.TotalSize
+	}
+	if !req.SkipAnnounce {
+		plan.Announcement = newAnnouncement(req, manifest, infoHash)
+	}
+	return plan, nil
+}
@@ -0,0 +1,66 @@
+package publish
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanRequest(t *testing.T) {
+	req := newTestRequest(t)
+	require.NoError(t, os.WriteFile(filepath.Join(req.Path, ".cache"), []byte("x"), 0644))
+
+	plan, err := PlanRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", plan.Model)
+	assert.Equal(t, []PlannedFile{{Path: "model.gguf", Size: int64(len("weights"))}}, plan.Files)
+	assert.Equal(t, []string{".cache"}, plan.Excluded)
+	assert.Equal(t, int64(len("weights")), plan.TotalSize)
+	assert.Equal(t, int64(4*1024*1024), plan.PieceLength)
+	assert.Equal(t, 1, plan.Pieces)
+	assert.Equal(t, "mit", plan.Manifest.License)
+	require.NotNil(t, plan.Announcement)
+	assert.Equal(t, plan.InfoHash, plan.Announcement.InfoHash)
+
+	// Nothing is written
+	assert.NoFileExists(t, req.TorrentPath)
+	assert.NoFileExists(t, filepath.Join(req.Path, ".silmaril.json"))
+	assert.Empty(t, req.Manifest.TotalSize)
+
+	// Publishing creates the torrent that was planned
+	p := newTestPublisher(nil, &fakeSeeder{}, &fakeAnnouncer{})
+	_, err = p.Run(context.Background(), req)
+	require.NoError(t, err)
+	mi, err := metainfo.LoadFromFile(req.TorrentPath)
+	require.NoError(t, err)
+	assert.Equal(t, plan.InfoHash, mi.HashInfoBytes().HexString())
+}
+
+func TestPlanRequestImport(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, "config.json")
+	req := Request{
+		Name:         "org/imported",
+		Path:         filepath.Join(t.TempDir(), "models", "org", "imported"),
+		Import:       &Import{From: src, Mode: "copy"},
+		Edit:         func(manifest *types.ModelManifest) { manifest.License = "apache-2.0" },
+		SkipAnnounce: true,
+	}
+
+	plan, err := PlanRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, src, plan.Path)
+	assert.Equal(t, "apache-2.0", plan.Manifest.License)
+	assert.Nil(t, plan.Announcement)
+	assert.NoDirExists(t, req.Path)
+
+	req.Repo = &Repo{URL: "https://huggingface.co/org/imported"}
+	_, err = PlanRequest(req)
+	assert.Error(t, err)
+}
@@ -0,0 +1,675 @@
+// Package publish turns a model directory, or a repository cloned into one,
+// into a seeded and announced torrent. Publishing runs as a job through the
+// stages clone or import, manifest, torrent, seed and announce, and a failed
+// job records the stage and error so clients can report it.
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/internal/proxy"
+	"github.com/silmaril/silmaril/internal/storage"
+	"github.com/silmaril/silmaril/internal/torrent"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// Stage is a step of the publish pipeline
+type Stage string
+
+// Stages in the order they run
+const (
+	StageClone    Stage = "clone"    // Clone the repository into the model directory
+	StageImport   Stage = "import"   // Place a directory in the models directory
+	StageManifest Stage = "manifest" // Describe the model and save its manifest
+	StageTorrent  Stage = "torrent"  // Create the torrent of the seeded directory
+	StageSeed     Stage = "seed"     // Add the torrent to the client and seed it
+	StageAnnounce Stage = "announce" // Announce the model on the DHT
+	StageDone     Stage = "done"
+)
+
+// Status of a publish job
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+const (
+	// Attempts to announce a model before the job fails
+	announceAttempts = 3
+	// Delay before the second announce attempt, doubled for each further one
+	announceRetryDelay = 5 * time.Second
+	// Number of finished jobs kept for clients to look up
+	maxFinishedJobs = 100
+	// How often the clone progress of a running job is reported
+	progressInterval = 5 * time.Second
+)
+
+// Cloner fetches a repository into a directory. It returns the commit the
+// repository was cloned at, empty if unknown.
+type Cloner interface {
+	Clone(ctx context.Context, repo *Repo, path string) (revision string, err error)
+}
+
+// Seeder seeds a torrent. It returns the infohash and the ID of the transfer
+// tracking the seed.
+type Seeder interface {
+	Seed(torrentPath, name, path string) (infoHash, transferID string, err error)
+}
+
+// Announcer makes a model discoverable by other peers
+type Announcer interface {
+	AnnounceModel(announcement *types.ModelAnnouncement) error
+}
+
+// Repo is a repository cloned into the model directory before publishing
+type Repo struct {
+	URL      string
+	Branch   string
+	Revision string // Tag or commit to clone instead of the head of Branch
+	Depth    int
+	SkipLFS  bool
+	Proxy    *proxy.Proxy // Proxy to clone through, nil to connect directly
+
+	// Progress of an interrupted clone of the repository into the model
+	// directory, which cloning continues. Nil clones from scratch.
+	Resume CloneProgress
+	// Progress is called as large files are downloaded, if set
+	Progress func(file string, downloaded, size int64)
+}
+
+// CloneProgress is how much of each large file of a repository was
+// downloaded, by path within the repository
+type CloneProgress map[string]FileProgress
+
+// FileProgress is how much of a file was downloaded
+type FileProgress struct {
+	Size       int64 `json:"size"`
+	Downloaded int64 `json:"downloaded"`
+}
+
+// Bytes returns the bytes downloaded and the size of all files
+func (c CloneProgress) Bytes() (downloaded, size int64) {
+	for _, file := range c {
+		downloaded += file.Downloaded
+		size += file.Size
+	}
+	return downloaded, size
+}
+
+// offset returns where the download of a file of size bytes continues
+func (c CloneProgress) offset(file string, size int64) int64 {
+	if progress, ok := c[file]; ok && progress.Size == size {
+		return progress.Downloaded
+	}
+	return 0
+}
+
+// Import is a directory placed in the models directory before publishing
+type Import struct {
+	From string
+	Mode string // storage.ImportCopy, ImportLink or ImportInPlace
+}
+
+// Request describes a model to publish
+type Request struct {
+	Name        string
+	Path        string // Model directory, Repo is cloned or Import placed into it
+	SeedPath    string // Directory seeded, Path if empty
+	TorrentPath string
+	Repo        *Repo   // Repository to clone first
+	Import      *Import // Directory to import first
+
+	// Manifest of the model. Without one a manifest is generated from the
+	// model files, and for cloned repositories the model card and license
+	// files complete it.
+	Manifest *types.ModelManifest
+	// Edit changes the manifest before it is saved, if set
+	Edit func(manifest *types.ModelManifest)
+
+	// Patterns of files left out of, or kept in, the published model on top
+	// of the ignore file of the model directory
+	Exclude []string
+	Include []string
+
+	Version      string // Version announced
+	PieceLength  int64  // Piece length of the torrent, the default if 0
+	SkipAnnounce bool   // Seed without announcing on the DHT
+}
+
+// ignore returns the files of the model in dir that are not published
+func (req Request) ignore(dir string) (*storage.Ignore, error) {
+	return storage.LoadIgnore(storage.ResolveModelDir(dir), req.Exclude, req.Include)
+}
+
+// Job is a publish request making its way through the pipeline
+type Job struct {
+	ID               string `json:"id"`
+	Model            string `json:"model"`
+	RepoURL          string `json:"repo_url,omitempty"`
+	Status           Status `json:"status"`
+	Stage            Stage  `json:"stage"`
+	Error            string `json:"error,omitempty"`
+	InfoHash         string `json:"info_hash,omitempty"`
+	TransferID       string `json:"transfer_id,omitempty"`
+	AnnounceAttempts int    `json:"announce_attempts,omitempty"`
+	// Large files of a cloned repository and how much of each is local,
+	// kept so sharing the repository again continues an interrupted clone
+	Clone       CloneProgress `json:"clone,omitempty"`
+	StartedAt   time.Time     `json:"started_at"`
+	CompletedAt *time.Time    `json:"completed_at,omitempty"`
+}
+
+// StageError is the error of the stage a job failed in
+type StageError struct {
+	Stage Stage
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// FailedStage returns the stage err happened in, "" if it is not a StageError
+func FailedStage(err error) Stage {
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		return stageErr.Stage
+	}
+	return ""
+}
+
+// Publisher runs publish jobs and keeps track of them
+type Publisher struct {
+	cloner    Cloner
+	seeder    Seeder
+	announcer Announcer
+
+	// OnFinish is called with each job once it completed or failed
+	OnFinish func(job Job)
+	// OnProgress is called with jobs cloning a repository as its large files
+	// are downloaded, at most every progressInterval per job
+	OnProgress func(job Job)
+
+	mu         sync.RWMutex
+	jobs       map[string]*Job
+	retryDelay time.Duration
+}
+
+// New creates a publisher cloning, seeding and announcing models with the
+// given services
+func New(cloner Cloner, seeder Seeder, announcer Announcer) *Publisher {
+	return &Publisher{
+		cloner:     cloner,
+		seeder:     seeder,
+		announcer:  announcer,
+		jobs:       make(map[string]*Job),
+		retryDelay: announceRetryDelay,
+	}
+}
+
+// Start runs the pipeline for req in the background and returns its job
+func (p *Publisher) Start(ctx context.Context, req Request) Job {
+	job := p.newJob(req, StatusRunning)
+	snapshot := p.snapshot(job)
+	go p.run(ctx, job, req)
+	return snapshot
+}
+
+// StartBatch queues the pipelines for reqs and runs them in the background,
+// at most workers at a time. It returns the queued jobs in the order of reqs.
+func (p *Publisher) StartBatch(ctx context.Context, reqs []Request, workers int) []Job {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(reqs) {
+		workers = len(reqs)
+	}
+
+	jobs := make([]*Job, len(reqs))
+	snapshots := make([]Job, len(reqs))
+	queue := make(chan int, len(reqs))
+	for i, req := range reqs {
+		jobs[i] = p.newJob(req, StatusQueued)
+		snapshots[i] = p.snapshot(jobs[i])
+		queue <- i
+	}
+	close(queue)
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range queue {
+				p.update(jobs[i], func(job *Job) { job.Status = StatusRunning })
+				p.run(ctx, jobs[i], reqs[i])
+			}
+		}()
+	}
+	return snapshots
+}
+
+// Run runs the pipeline for req and returns the finished job. The error is a
+// *StageError naming the stage that failed.
+func (p *Publisher) Run(ctx context.Context, req Request) (Job, error) {
+	job := p.newJob(req, StatusRunning)
+	err := p.run(ctx, job, req)
+	return p.snapshot(job), err
+}
+
+// Job returns the job with the given ID
+func (p *Publisher) Job(id string) (Job, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	job, ok := p.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return job.copy(), true
+}
+
+// Jobs returns all jobs, the most recent first
+func (p *Publisher) Jobs() []Job {
+	p.mu.RLock()
+	jobs := make([]Job, 0, len(p.jobs))
+	for _, job := range p.jobs {
+		jobs = append(jobs, job.copy())
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].StartedAt.After(jobs[j].StartedAt)
+	})
+	return jobs
+}
+
+// Restore adds jobs of a previous run, keeping the jobs known already
+func (p *Publisher) Restore(jobs []Job) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range jobs {
+		if _, ok := p.jobs[jobs[i].ID]; !ok {
+			job := jobs[i]
+			p.jobs[job.ID] = &job
+		}
+	}
+}
+
+func (p *Publisher) newJob(req Request, status Status) *Job {
+	job := &Job{
+		ID:        uuid.New().String(),
+		Model:     req.Name,
+		Status:    status,
+		Stage:     StageManifest,
+		StartedAt: time.Now(),
+	}
+	if req.Repo != nil {
+		job.RepoURL = req.Repo.URL
+		job.Stage = StageClone
+		job.Clone = maps.Clone(req.Repo.Resume)
+	} else if req.Import != nil {
+		job.Stage = StageImport
+	}
+
+	p.mu.Lock()
+	p.jobs[job.ID] = job
+	p.pruneLocked()
+	p.mu.Unlock()
+	return job
+}
+
+// pruneLocked forgets the oldest finished jobs beyond maxFinishedJobs
+func (p *Publisher) pruneLocked() {
+	var finished []*Job
+	for _, job := range p.jobs {
+		if job.CompletedAt != nil {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].StartedAt.Before(finished[j].StartedAt)
+	})
+	for _, job := range finished[:len(finished)-maxFinishedJobs] {
+		delete(p.jobs, job.ID)
+	}
+}
+
+func (p *Publisher) snapshot(job *Job) Job {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return job.copy()
+}
+
+// copy returns the job with a clone progress of its own, which keeps
+// changing while the job runs
+func (j *Job) copy() Job {
+	job := *j
+	job.Clone = maps.Clone(j.Clone)
+	return job
+}
+
+// update changes a job under the lock
+func (p *Publisher) update(job *Job, change func(job *Job)) {
+	p.mu.Lock()
+	change(job)
+	p.mu.Unlock()
+}
+
+// run takes a job through the stages, stopping at the first that fails
+func (p *Publisher) run(ctx context.Context, job *Job, req Request) error {
+	if req.SeedPath == "" {
+		req.SeedPath = req.Path
+	}
+
+	var manifest *types.ModelManifest
+	var revision string
+	stages := []struct {
+		stage Stage
+		run   func() error
+	}{
+		{StageClone, func() (err error) {
+			revision, err = p.clone(ctx, job, req)
+			return err
+		}},
+		{StageImport, func() error { return importDir(req) }},
+		{StageManifest, func() (err error) {
+			manifest, err = buildManifest(req, revision)
+			return err
+		}},
+		{StageTorrent, func() error { return createTorrent(req) }},
+		{StageSeed, func() error { return p.seed(job, req) }},
+		{StageAnnounce, func() error { return p.announce(ctx, job, req, manifest) }},
+	}
+
+	var err error
+	for _, s := range stages {
+		if (s.stage == StageClone && req.Repo == nil) || (s.stage == StageImport && req.Import == nil) {
+			continue
+		}
+		p.update(job, func(job *Job) { job.Stage = s.stage })
+		if err = ctx.Err(); err == nil {
+			err = s.run()
+		}
+		if err != nil {
+			err = &StageError{Stage: s.stage, Err: err}
+			break
+		}
+	}
+
+	now := time.Now()
+	p.update(job, func(job *Job) {
+		job.CompletedAt = &now
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = StatusCompleted
+		job.Stage = StageDone
+	})
+	if err != nil {
+		fmt.Printf("[Publish] Failed to publish %s: %v\n", req.Name, err)
+	} else {
+		fmt.Printf("[Publish] Published %s\n", req.Name)
+	}
+
+	if p.OnFinish != nil {
+		p.OnFinish(p.snapshot(job))
+	}
+	return err
+}
+
+// clone fetches the repository, removing what was cloned if it fails before
+// the clone was marked partial. Partial clones are kept, with the progress
+// of their large files on the job, for cloning the repository again to
+// continue. It returns the commit the repository was cloned at.
+func (p *Publisher) clone(ctx context.Context, job *Job, req Request) (string, error) {
+	if p.cloner == nil {
+		return "", errors.New("cloning repositories is not supported")
+	}
+	if err := os.MkdirAll(filepath.Dir(req.Path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create model directory: %w", err)
+	}
+	if req.Repo.Resume != nil {
+		fmt.Printf("[Publish] Continuing clone of repository: %s to %s\n", req.Repo.URL, req.Path)
+	} else {
+		fmt.Printf("[Publish] Cloning repository: %s to %s\n", req.Repo.URL, req.Path)
+	}
+
+	repo := *req.Repo
+	var reported time.Time
+	repo.Progress = func(file string, downloaded, size int64) {
+		p.update(job, func(job *Job) {
+			if job.Clone == nil {
+				job.Clone = make(CloneProgress)
+			}
+			job.Clone[file] = FileProgress{Size: size, Downloaded: downloaded}
+		})
+		if p.OnProgress != nil && (downloaded == size || time.Since(reported) >= progressInterval) {
+			reported = time.Now()
+			p.OnProgress(p.snapshot(job))
+		}
+	}
+
+	revision, err := p.cloner.Clone(ctx, &repo, req.Path)
+	if err != nil {
+		if !storage.IsPartial(req.Path) {
+			os.RemoveAll(req.Path)
+		}
+		return "", err
+	}
+	return revision, nil
+}
+
+// importDir places the imported directory at the model path
+func importDir(req Request) error {
+	fmt.Printf("[Publish] Importing %s to %s (mode: %s)\n", req.Import.From, req.Path, req.Import.Mode)
+	if _, err := storage.ImportDir(req.Import.From, req.Path, req.Import.Mode); err != nil {
+		return fmt.Errorf("failed to import model: %w", err)
+	}
+	return nil
+}
+
+// buildManifest completes the manifest of the request and saves it with the
+// model, and with the seeded directory for peers to fetch. revision is the
+// commit a cloned repository was at.
+func buildManifest(req Request, revision string) (*types.ModelManifest, error) {
+	ignore, err := req.ignore(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	manifest := req.Manifest
+	if manifest == nil {
+		generated, err := models.GenerateManifest(req.Path, req.Name, ignore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate manifest: %w", err)
+		}
+		manifest = generated
+	} else {
+		dropIgnored(manifest, ignore)
+	}
+	if req.Repo != nil {
+		describeModel(manifest, req.Path)
+		recordUpstream(manifest, req.Repo.URL, revision)
+		pinVersion(manifest, req.Repo, revision)
+	}
+	if req.Edit != nil {
+		req.Edit(manifest)
+	}
+	if manifest.TotalSize == 0 {
+		manifest.TotalSize = dirSize(req.Path, ignore)
+	}
+
+	if err := models.WriteManifest(req.Path, manifest); err != nil {
+		return nil, fmt.Errorf("failed to save manifest: %w", err)
+	}
+	if req.SeedPath != req.Path {
+		if err := models.WriteManifest(req.SeedPath, manifest); err != nil {
+			return nil, fmt.Errorf("failed to save manifest: %w", err)
+		}
+	}
+	return manifest, nil
+}
+
+// describeModel fills in the manifest from the model card and license files
+func describeModel(manifest *types.ModelManifest, path string) {
+	if card, err := models.LoadModelCard(path); err == nil {
+		card.ApplyTo(manifest)
+	}
+
+	if manifest.License != "Unknown" {
+		return
+	}
+	for _, name := range []string{"LICENSE", "LICENSE.txt", "LICENSE.md", "LICENCE", "LICENCE.txt", "LICENCE.md"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err == nil {
+			manifest.License = "See LICENSE file"
+			return
+		}
+	}
+}
+
+// recordUpstream notes in the provenance of the manifest the repository the
+// model was cloned from, leaving out any credentials in the URL
+func recordUpstream(manifest *types.ModelManifest, repoURL, revision string) {
+	if manifest.Provenance == nil {
+		manifest.Provenance = &types.Provenance{}
+	}
+	manifest.Provenance.UpstreamURL = repoURL
+	if u, err := url.Parse(repoURL); err == nil && u.User != nil {
+		u.User = nil
+		manifest.Provenance.UpstreamURL = u.String()
+	}
+	manifest.Provenance.UpstreamRevision = revision
+}
+
+// pinVersion versions models cloned at a pinned revision by it, and those
+// pinned to a commit by its full hash, so the version names the files
+func pinVersion(manifest *types.ModelManifest, repo *Repo, revision string) {
+	switch {
+	case repo.Revision == "":
+	case isCommitHash(repo.Revision) && revision != "":
+		manifest.Version = revision
+	default:
+		manifest.Version = repo.Revision
+	}
+}
+
+// dirSize returns the size of the files under path that are published
+func dirSize(path string, ignore *storage.Ignore) int64 {
+	files, _, _ := torrent.DirectoryFiles(path, ignore)
+	var total int64
+	for _, file := range files {
+		total += file.Length
+	}
+	return total
+}
+
+// dropIgnored removes the files that are not published from a manifest
+// listing the files of the model
+func dropIgnored(manifest *types.ModelManifest, ignore *storage.Ignore) {
+	files := manifest.Files[:0]
+	for _, file := range manifest.Files {
+		if ignore.Ignored(file.Path, false) {
+			manifest.TotalSize -= file.Size
+			continue
+		}
+		files = append(files, file)
+	}
+	manifest.Files = files
+}
+
+func createTorrent(req Request) error {
+	if err := os.MkdirAll(filepath.Dir(req.TorrentPath), 0755); err != nil {
+		return fmt.Errorf("failed to create torrent directory: %w", err)
+	}
+	ignore, err := req.ignore(req.Path)
+	if err != nil {
+		return err
+	}
+	infoHash, err := torrent.CreateTorrentFromDirectory(req.SeedPath, req.TorrentPath, req.PieceLength, ignore)
+	if err != nil {
+		return fmt.Errorf("failed to create torrent: %w", err)
+	}
+	fmt.Printf("[Publish] Torrent created: %s (InfoHash: %s)\n", req.TorrentPath, infoHash)
+	return nil
+}
+
+func (p *Publisher) seed(job *Job, req Request) error {
+	infoHash, transferID, err := p.seeder.Seed(req.TorrentPath, req.Name, req.SeedPath)
+	if err != nil {
+		return err
+	}
+	p.update(job, func(job *Job) {
+		job.InfoHash = infoHash
+		job.TransferID = transferID
+	})
+	return nil
+}
+
+// announce announces the model on the DHT, retrying with growing delays
+func (p *Publisher) announce(ctx context.Context, job *Job, req Request, manifest *types.ModelManifest) error {
+	if req.SkipAnnounce || p.announcer == nil {
+		return nil
+	}
+
+	announcement := newAnnouncement(req, manifest, p.snapshot(job).InfoHash)
+
+	delay := p.retryDelay
+	var err error
+	for attempt := 1; attempt <= announceAttempts; attempt++ {
+		p.update(job, func(job *Job) { job.AnnounceAttempts = attempt })
+		if err = p.announcer.AnnounceModel(announcement); err == nil {
+			return nil
+		}
+		if attempt == announceAttempts {
+			break
+		}
+		fmt.Printf("[Publish] Announcing %s failed, retrying in %v: %v\n", req.Name, delay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return fmt.Errorf("failed after %d attempts: %w", announceAttempts, err)
+}
+
+// newAnnouncement returns the DHT announcement of a published model
+func newAnnouncement(req Request, manifest *types.ModelManifest, infoHash string) *types.ModelAnnouncement {
+	return &types.ModelAnnouncement{
+		Name:                     req.Name,
+		InfoHash:                 infoHash,
+		Size:                     manifest.TotalSize,
+		Version:                  req.Version,
+		Description:              manifest.Description,
+		Tags:                     manifest.Keywords(),
+		License:                  manifest.License,
+		Parameters:               manifest.Parameters,
+		LicenseURL:               manifest.LicenseURL,
+		RequireLicenseAcceptance: manifest.RequireLicenseAcceptance,
+		Provenance:               manifest.Provenance,
+		Equivalents:              manifest.Equivalents,
+	}
+}
@@ -0,0 +1,199 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/pkg/types"
+)
+
+// Most keys a signature policy may list
+const MaxPolicyKeys = 16
+
+// ErrThresholdNotMet is returned for manifests signed by fewer keys than
+// their policy requires
+var ErrThresholdNotMet = errors.New("manifest is not signed by enough keys of its policy")
+
+// ValidatePolicy checks that a policy lists valid, distinct keys and a
+// threshold between 1 and their number
+func ValidatePolicy(policy *types.SignaturePolicy) error {
+	if len(policy.Keys) == 0 || len(policy.Keys) > MaxPolicyKeys {
+		return fmt.Errorf("signature policy must list 1 to %d keys, got %d", MaxPolicyKeys, len(policy.Keys))
+	}
+	if policy.Threshold < 1 || policy.Threshold > len(policy.Keys) {
+		return fmt.Errorf("signature policy threshold must be between 1 and %d, got %d", len(policy.Keys), policy.Threshold)
+	}
+	seen := make(map[string]bool, len(policy.Keys))
+	for _, key := range policy.Keys {
+		if _, err := parseKey(key); err != nil {
+			return err
+		}
+		if seen[strings.ToLower(key)] {
+			return fmt.Errorf("signature policy lists key %s twice", key)
+		}
+		seen[strings.ToLower(key)] = true
+	}
+	return nil
+}
+
+// PolicyID identifies a policy by its threshold and keys, whatever their
+// order. It names the organization the policy stands for.
+func PolicyID(policy *types.SignaturePolicy) string {
+	keys := make([]string, len(policy.Keys))
+	for i, key := range policy.Keys {
+		keys[i] = strings.ToLower(key)
+	}
+	sort.Strings(keys)
+	sum := sha256.Sum256([]byte(strconv.Itoa(policy.Threshold) + "\x00" + strings.Join(keys, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// AddSignature signs the manifest with a publisher key, replacing an earlier
+// signature of the key. Signatures made before the manifest changed no
+// longer match and are dropped. With a policy only its keys can sign.
+func AddSignature(manifest *models.ModelManifest, privateKey ed25519.PrivateKey) error {
+	keyID := hex.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+	if policy := manifest.SignaturePolicy; policy != nil {
+		if err := ValidatePolicy(policy); err != nil {
+			return err
+		}
+		if !inPolicy(policy, keyID) {
+			return fmt.Errorf("key %s is not one of the keys of the signature policy", keyID)
+		}
+	}
+
+	message, err := signedMessage(manifest)
+	if err != nil {
+		return err
+	}
+	signatures := []types.ManifestSignature{{
+		KeyID:     keyID,
+		Signature: hex.EncodeToString(ed25519.Sign(privateKey, message)),
+		SignedAt:  time.Now().UTC().Truncate(time.Second),
+	}}
+	for _, sig := range manifest.Signatures {
+		if !strings.EqualFold(sig.KeyID, keyID) && verifySignature(sig, message) {
+			signatures = append(signatures, sig)
+		}
+	}
+	sort.Slice(signatures, func(i, j int) bool { return signatures[i].KeyID < signatures[j].KeyID })
+	manifest.Signatures = signatures
+	return nil
+}
+
+// MergeSignatures adds the signatures of another copy of the manifest, such
+// as one an organization member signed, that match this one. It returns
+// the number of signatures added.
+func MergeSignatures(manifest, other *models.ModelManifest) (int, error) {
+	message, err := signedMessage(manifest)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for _, sig := range other.Signatures {
+		if !verifySignature(sig, message) {
+			continue
+		}
+		if slices.ContainsFunc(manifest.Signatures, func(s types.ManifestSignature) bool { return strings.EqualFold(s.KeyID, sig.KeyID) }) {
+			continue
+		}
+		manifest.Signatures = append(manifest.Signatures, sig)
+		added++
+	}
+	sort.Slice(manifest.Signatures, func(i, j int) bool { return manifest.Signatures[i].KeyID < manifest.Signatures[j].KeyID })
+	return added, nil
+}
+
+// VerifySignatures checks the signatures of a manifest and returns the keys
+// that signed it. With a policy only signatures of its keys count and there
+// must be at least its threshold of them, ErrThresholdNotMet otherwise.
+// Without one every signature must match.
+func VerifySignatures(manifest *models.ModelManifest) ([]string, error) {
+	message, err := signedMessage(manifest)
+	if err != nil {
+		return nil, err
+	}
+	policy := manifest.SignaturePolicy
+	if policy != nil {
+		if err := ValidatePolicy(policy); err != nil {
+			return nil, err
+		}
+	}
+
+	var signers []string
+	for _, sig := range manifest.Signatures {
+		keyID := strings.ToLower(sig.KeyID)
+		if slices.Contains(signers, keyID) {
+			continue
+		}
+		if !verifySignature(sig, message) {
+			if policy == nil {
+				return nil, fmt.Errorf("signature of %s doesn't match the manifest", sig.KeyID)
+			}
+			continue
+		}
+		if policy == nil || inPolicy(policy, keyID) {
+			signers = append(signers, keyID)
+		}
+	}
+
+	if policy != nil && len(signers) < policy.Threshold {
+		return signers, fmt.Errorf("%w: %d of %d required", ErrThresholdNotMet, len(signers), policy.Threshold)
+	}
+	return signers, nil
+}
+
+// signedMessage is what the keys sign: the manifest without its signatures
+// and local settings. The name is left out too, downloaders name models
+// themselves.
+func signedMessage(manifest *models.ModelManifest) ([]byte, error) {
+	signed := *manifest
+	signed.Name = ""
+	signed.Signature = ""
+	signed.Signatures = nil
+	signed.Seeding = nil
+	signed.Storage = ""
+	data, err := json.Marshal(&signed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return append([]byte("silmaril-manifest\x00"), sum[:]...), nil
+}
+
+// verifySignature reports whether sig is a valid signature of message
+func verifySignature(sig types.ManifestSignature, message []byte) bool {
+	publicKey, err := parseKey(sig.KeyID)
+	if err != nil {
+		return false
+	}
+	signature, err := hex.DecodeString(sig.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(publicKey, message, signature)
+}
+
+// inPolicy reports whether a key is one of the keys of the policy
+func inPolicy(policy *types.SignaturePolicy, keyID string) bool {
+	return slices.ContainsFunc(policy.Keys, func(key string) bool { return strings.EqualFold(key, keyID) })
+}
+
+// parseKey decodes a hex encoded ed25519 public key
+func parseKey(key string) (ed25519.PublicKey, error) {
+	publicKey, err := hex.DecodeString(key)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid publisher key %q", key)
+	}
+	return publicKey, nil
+}
@@ -0,0 +1,116 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/models"
+	"github.com/silmaril/silmaril/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generatePublisherKeys(t *testing.T, n int) ([]ed25519.PrivateKey, []string) {
+	keys := make([]ed25519.PrivateKey, n)
+	ids := make([]string, n)
+	for i := range keys {
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		keys[i] = privateKey
+		ids[i] = hex.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+	}
+	return keys, ids
+}
+
+func TestThresholdSignatures(t *testing.T) {
+	keys, ids := generatePublisherKeys(t, 4)
+	manifest := &models.ModelManifest{
+		Name:  "acme/model",
+		Files: []types.ModelFile{{Path: "model.safetensors", Size: 1024, SHA256: "abc123"}},
+		SignaturePolicy: &types.SignaturePolicy{
+			Organization: "acme",
+			Threshold:    2,
+			Keys:         ids[:3],
+		},
+	}
+
+	// Keys outside the policy can't sign
+	assert.Error(t, AddSignature(manifest, keys[3]))
+
+	require.NoError(t, AddSignature(manifest, keys[0]))
+	signers, err := VerifySignatures(manifest)
+	assert.ErrorIs(t, err, ErrThresholdNotMet)
+	assert.Equal(t, []string{ids[0]}, signers)
+
+	// Signing again replaces the signature of the key
+	require.NoError(t, AddSignature(manifest, keys[0]))
+	assert.Len(t, manifest.Signatures, 1)
+
+	// A member signs a copy named differently, which is merged back
+	copied := *manifest
+	copied.Name = "renamed/model"
+	require.NoError(t, AddSignature(&copied, keys[1]))
+	added, err := MergeSignatures(manifest, &copied)
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	signers, err = VerifySignatures(manifest)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, ids[:2], signers)
+
+	// Changing the manifest invalidates the signatures
+	changed := *manifest
+	changed.Files = []types.ModelFile{{Path: "model.safetensors", Size: 1024, SHA256: "def456"}}
+	_, err = VerifySignatures(&changed)
+	assert.ErrorIs(t, err, ErrThresholdNotMet)
+
+	// and signing the changed manifest drops them
+	require.NoError(t, AddSignature(&changed, keys[2]))
+	assert.Len(t, changed.Signatures, 1)
+
+	// Lowering the threshold changes the policy, and so the signed content
+	changed.SignaturePolicy = &types.SignaturePolicy{Organization: "acme", Threshold: 1, Keys: ids[:3]}
+	_, err = VerifySignatures(&changed)
+	assert.ErrorIs(t, err, ErrThresholdNotMet)
+}
+
+func TestVerifySignaturesWithoutPolicy(t *testing.T) {
+	keys, ids := generatePublisherKeys(t, 2)
+	manifest := &models.ModelManifest{Name: "org/model", Version: "1.0"}
+	require.NoError(t, AddSignature(manifest, keys[0]))
+	require.NoError(t, AddSignature(manifest, keys[1]))
+
+	signers, err := VerifySignatures(manifest)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, ids, signers)
+
+	// Without a policy every signature must match
+	manifest.Signatures[0].Signature = manifest.Signatures[1].Signature
+	_, err = VerifySignatures(manifest)
+	assert.Error(t, err)
+}
+
+func TestValidatePolicy(t *testing.T) {
+	_, ids := generatePublisherKeys(t, 3)
+
+	assert.NoError(t, ValidatePolicy(&types.SignaturePolicy{Threshold: 2, Keys: ids}))
+	assert.Error(t, ValidatePolicy(&types.SignaturePolicy{Threshold: 0, Keys: ids}))
+	assert.Error(t, ValidatePolicy(&types.SignaturePolicy{Threshold: 4, Keys: ids}))
+	assert.Error(t, ValidatePolicy(&types.SignaturePolicy{Threshold: 1, Keys: []string{ids[0], ids[0]}}))
+	assert.Error(t, ValidatePolicy(&types.SignaturePolicy{Threshold: 1, Keys: []string{"not-a-key"}}))
+	assert.Error(t, ValidatePolicy(&types.SignaturePolicy{Threshold: 1}))
+}
+
+func TestPolicyID(t *testing.T) {
+	_, ids := generatePublisherKeys(t, 3)
+	policy := &types.SignaturePolicy{Organization: "acme", Threshold: 2, Keys: ids}
+
+	// The order of the keys and the organization name don't matter
+	reordered := &types.SignaturePolicy{Organization: "other", Threshold: 2, Keys: []string{ids[2], ids[0], ids[1]}}
+	assert.Equal(t, PolicyID(policy), PolicyID(reordered))
+
+	lowered := &types.SignaturePolicy{Organization: "acme", Threshold: 1, Keys: ids}
+	assert.NotEqual(t, PolicyID(policy), PolicyID(lowered))
+}
@@ -9,9 +9,11 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/silmaril/silmaril/internal/models"
 )
@@ -186,14 +188,22 @@ func VerifyManifest(manifest *models.ModelManifest, publicKey *rsa.PublicKey) er
 	return nil
 }
 
+// DefaultKeysDir returns the directory holding the signing keys of this node
+func DefaultKeysDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".silmaril", "keys"), nil
+}
+
 // GetOrCreateKeys gets existing keys or creates new ones
 func GetOrCreateKeys() (*KeyPair, error) {
-	home, err := os.UserHomeDir()
+	keysDir, err := DefaultKeysDir()
 	if err != nil {
 		return nil, err
 	}
 	
-	keysDir := filepath.Join(home, ".silmaril", "keys")
 	os.MkdirAll(keysDir, 0700) // Secure permissions
 	
 	privateKeyPath := filepath.Join(keysDir, "private.pem")
@@ -225,4 +235,44 @@ func GetOrCreateKeys() (*KeyPair, error) {
 	
 	fmt.Printf("Keys saved to: %s\n", keysDir)
 	return keyPair, nil
-}
\ No newline at end of file
+}
+
+// RotateKeys replaces the signing keys with new ones. The old keys are moved
+// to the retired directory next to them and returned, nil if there were none,
+// so manifests signed with them can be signed again.
+func RotateKeys() (oldKeys, newKeys *KeyPair, err error) {
+	keysDir, err := DefaultKeysDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := os.MkdirAll(keysDir, 0700); err != nil {
+		return nil, nil, fmt.Errorf("failed to create keys directory: %w", err)
+	}
+	
+	privateKeyPath := filepath.Join(keysDir, "private.pem")
+	publicKeyPath := filepath.Join(keysDir, "public.pem")
+	
+	if privateKey, err := LoadPrivateKey(privateKeyPath); err == nil {
+		oldKeys = &KeyPair{PrivateKey: privateKey, PublicKey: &privateKey.PublicKey}
+		
+		retiredDir := filepath.Join(keysDir, "retired")
+		if err := os.MkdirAll(retiredDir, 0700); err != nil {
+			return nil, nil, fmt.Errorf("failed to create retired keys directory: %w", err)
+		}
+		prefix := time.Now().UTC().Format("20060102T150405") + "-"
+		if err := oldKeys.SaveKeyPair(filepath.Join(retiredDir, prefix+"private.pem"), filepath.Join(retiredDir, prefix+"public.pem")); err != nil {
+			return nil, nil, fmt.Errorf("failed to retire signing keys: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+	
+	newKeys, err = GenerateKeyPair()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := newKeys.SaveKeyPair(privateKeyPath, publicKeyPath); err != nil {
+		return nil, nil, err
+	}
+	return oldKeys, newKeys, nil
+}
@@ -274,6 +274,35 @@ func TestGetOrCreateKeys(t *testing.T) {
 	assert.Equal(t, keyPair1.PrivateKey.D, keyPair2.PrivateKey.D)
 }
 
+func TestRotateKeys(t *testing.T) {
+	tempHome := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempHome)
+	defer os.Setenv("HOME", originalHome)
+	
+	// Without keys there is nothing to retire
+	oldKeys, firstKeys, err := RotateKeys()
+	require.NoError(t, err)
+	assert.Nil(t, oldKeys)
+	
+	oldKeys, newKeys, err := RotateKeys()
+	require.NoError(t, err)
+	require.NotNil(t, oldKeys)
+	assert.Equal(t, firstKeys.PrivateKey.N, oldKeys.PrivateKey.N)
+	assert.NotEqual(t, oldKeys.PrivateKey.N, newKeys.PrivateKey.N)
+	
+	// The new keys are the current ones, the old ones are retired
+	current, err := GetOrCreateKeys()
+	require.NoError(t, err)
+	assert.Equal(t, newKeys.PrivateKey.N, current.PrivateKey.N)
+	retired, err := filepath.Glob(filepath.Join(tempHome, ".silmaril", "keys", "retired", "*-private.pem"))
+	require.NoError(t, err)
+	require.Len(t, retired, 1)
+	retiredKey, err := LoadPrivateKey(retired[0])
+	require.NoError(t, err)
+	assert.Equal(t, oldKeys.PrivateKey.N, retiredKey.N)
+}
+
 func TestSignatureConsistency(t *testing.T) {
 	keyPair, err := GenerateKeyPair()
 	require.NoError(t, err)
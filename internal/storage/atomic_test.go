@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	require.NoError(t, WriteFileAtomic(path, []byte("one"), 0644))
+	assert.NoFileExists(t, path+".bak")
+
+	require.NoError(t, WriteFileAtomic(path, []byte("two"), 0644))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "two", string(data))
+	backup, err := os.ReadFile(path + ".bak")
+	require.NoError(t, err)
+	assert.Equal(t, "one", string(backup))
+	assert.NoFileExists(t, path+".tmp")
+
+	// A directory in the way fails the write and leaves no temporary file
+	assert.Error(t, WriteFileAtomic(filepath.Join(path, "sub"), []byte("x"), 0644))
+}
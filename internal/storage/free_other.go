@@ -0,0 +1,11 @@
+//go:build !linux
+
+package storage
+
+import "errors"
+
+// freeSpace is only implemented on Linux, elsewhere models are placed in the
+// first pool that takes them
+func freeSpace(path string) (int64, error) {
+	return 0, errors.New("free space is not known on this platform")
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// IgnoreFile lists the files of a model directory that are not published, in
+// .gitignore syntax
+const IgnoreFile = ".silmarilignore"
+
+// Hidden files and directories, such as .git, are never published unless
+// they are included again
+const hiddenPattern = ".*"
+
+// Ignore decides which files of a model directory are published
+type Ignore struct {
+	matcher gitignore.Matcher
+}
+
+// LoadIgnore reads the ignore file of the model directory dir, if there is
+// one. The exclude and include patterns of a single publish take precedence
+// over it, and include patterns over exclude patterns.
+func LoadIgnore(dir string, exclude, include []string) (*Ignore, error) {
+	patterns := []gitignore.Pattern{gitignore.ParsePattern(hiddenPattern, nil)}
+
+	file, err := os.Open(filepath.Join(dir, IgnoreFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFile, err)
+	}
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimRight(scanner.Text(), " \t\r")
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			patterns = append(patterns, gitignore.ParsePattern(line, nil))
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", IgnoreFile, err)
+		}
+	}
+
+	for _, pattern := range exclude {
+		patterns = append(patterns, gitignore.ParsePattern(pattern, nil))
+	}
+	for _, pattern := range include {
+		patterns = append(patterns, gitignore.ParsePattern("!"+pattern, nil))
+	}
+	return &Ignore{matcher: gitignore.NewMatcher(patterns)}, nil
+}
+
+// Ignored reports whether the file or directory at the slash separated path,
+// relative to the model directory, is left out. Files in an ignored
+// directory are left out, as with .gitignore.
+func (i *Ignore) Ignored(relPath string, isDir bool) bool {
+	parts := strings.Split(relPath, "/")
+	for n := 1; n < len(parts); n++ {
+		if i.matcher.Match(parts[:n], true) {
+			return true
+		}
+	}
+	return i.matcher.Match(parts, isDir)
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadIgnore(t *testing.T) {
+	dir := t.TempDir()
+	rules := "# Training leftovers\n*.log\ncheckpoints/\n/optimizer.pt\n!keep.log\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, IgnoreFile), []byte(rules), 0644))
+
+	ignore, err := LoadIgnore(dir, []string{"*.bin"}, []string{"pytorch_model.bin"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"config.json", false, false},
+		{"train.log", false, true},
+		{"logs/run/train.log", false, true},
+		{"keep.log", false, false},
+		{"checkpoints", true, true},
+		{"checkpoints/step-100/model.safetensors", false, true},
+		{"optimizer.pt", false, true},
+		{"sub/optimizer.pt", false, false},
+		{"model.bin", false, true},
+		{"pytorch_model.bin", false, false},
+		{".git", true, true},
+		{".git/HEAD", false, true},
+		{".gitattributes", false, true},
+		{IgnoreFile, false, true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.ignored, ignore.Ignored(tt.path, tt.isDir), tt.path)
+	}
+}
+
+func TestLoadIgnoreWithoutFile(t *testing.T) {
+	ignore, err := LoadIgnore(t.TempDir(), nil, []string{".well-known"})
+	require.NoError(t, err)
+	assert.False(t, ignore.Ignored("model.safetensors", false))
+	assert.True(t, ignore.Ignored(".cache/model.safetensors", false))
+	assert.False(t, ignore.Ignored(".well-known", false))
+}
+
+func TestImportDirKeepsIgnoreFile(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, IgnoreFile), []byte("*.log\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, ".silmaril.json"), []byte("{}"), 0644))
+
+	dst := filepath.Join(t.TempDir(), "model")
+	_, err := ImportDir(src, dst, ImportCopy)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dst, IgnoreFile))
+	assert.NoFileExists(t, filepath.Join(dst, ".silmaril.json"))
+}
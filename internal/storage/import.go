@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Import modes for publishing a model directory that lives outside the models
+// directory
+const (
+	// ImportCopy copies the files into the models directory
+	ImportCopy = "copy"
+
+	// ImportLink clones the files into the models directory with reflinks or
+	// hardlinks, falling back to a copy where the filesystem supports neither
+	ImportLink = "link"
+
+	// ImportInPlace links the original directory into the models directory, so
+	// the model is seeded from where it already is
+	ImportInPlace = "inplace"
+)
+
+// ImportStats counts how the files of an imported model were placed
+type ImportStats struct {
+	Reflinked  int `json:"reflinked"`
+	Hardlinked int `json:"hardlinked"`
+	Copied     int `json:"copied"`
+}
+
+// ValidImportMode reports whether mode is a known import mode
+func ValidImportMode(mode string) bool {
+	return mode == ImportCopy || mode == ImportLink || mode == ImportInPlace
+}
+
+// ImportDir places the model directory src at dst using the given import
+// mode. An empty mode copies.
+func ImportDir(src, dst, mode string) (ImportStats, error) {
+	var stats ImportStats
+
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return stats, fmt.Errorf("failed to resolve %s: %w", src, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return stats, fmt.Errorf("failed to create model directory: %w", err)
+	}
+
+	switch mode {
+	case ImportInPlace:
+		if err := os.Symlink(src, dst); err != nil {
+			return stats, fmt.Errorf("failed to link model directory: %w", err)
+		}
+		return stats, nil
+	case ImportLink, ImportCopy, "":
+	default:
+		return stats, fmt.Errorf("unknown import mode %q", mode)
+	}
+
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(dst, relPath)
+
+		if info.IsDir() {
+			return os.MkdirAll(dstPath, info.Mode().Perm()|0700)
+		}
+
+		// Manifests and piece completion databases belong to the directory
+		// they were written in
+		if IsStateFile(info.Name()) {
+			return nil
+		}
+
+		// Files linked by an earlier import already share their data, writing
+		// them again would truncate the original
+		if dstInfo, err := os.Stat(dstPath); err == nil && os.SameFile(info, dstInfo) {
+			return nil
+		}
+
+		if mode == ImportLink {
+			// Links cannot replace an existing file
+			if err := os.Remove(dstPath); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if err := reflinkFile(path, dstPath); err == nil {
+				stats.Reflinked++
+				return nil
+			}
+			if err := os.Link(path, dstPath); err == nil {
+				stats.Hardlinked++
+				return nil
+			}
+		}
+
+		if err := copyFile(path, dstPath, info.Mode().Perm()); err != nil {
+			return err
+		}
+		stats.Copied++
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed to import model: %w", err)
+	}
+	return stats, nil
+}
+
+// IsLinkedModel reports whether the model directory at path was imported in
+// place, and returns the original directory if so
+func IsLinkedModel(path string) (string, bool) {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return "", false
+	}
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", false
+	}
+	return target, true
+}
+
+// ErrModelExists is returned when a model directory is already taken by
+// another model
+var ErrModelExists = errors.New("model already exists")
+
+// ErrOutputNotEmpty is returned when a download would be stored in a
+// directory that already holds files
+var ErrOutputNotEmpty = errors.New("output directory is not empty")
+
+// LinkOutputDir links the directory output into the models directory at
+// modelPath, so a model downloaded to a directory of the user's choosing is
+// registered and seeded from there. Linking the same directory again is
+// fine, for resumed downloads, other directories must be empty so the
+// download overwrites nothing. It reports whether the link was created.
+func LinkOutputDir(output, modelPath string) (bool, error) {
+	if !filepath.IsAbs(output) {
+		return false, fmt.Errorf("output directory %s is not an absolute path", output)
+	}
+	if err := os.MkdirAll(output, 0755); err != nil {
+		return false, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(output)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve %s: %w", output, err)
+	}
+
+	if target, ok := IsLinkedModel(modelPath); ok {
+		if target == resolved {
+			return false, nil
+		}
+		return false, fmt.Errorf("%w in %s", ErrModelExists, target)
+	}
+	if _, err := os.Lstat(modelPath); err == nil {
+		return false, fmt.Errorf("%w in %s", ErrModelExists, modelPath)
+	}
+	entries, err := os.ReadDir(resolved)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", output, err)
+	}
+	if len(entries) > 0 {
+		return false, fmt.Errorf("%w: %s", ErrOutputNotEmpty, output)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(modelPath), 0755); err != nil {
+		return false, fmt.Errorf("failed to create model directory: %w", err)
+	}
+	if err := os.Symlink(resolved, modelPath); err != nil {
+		return false, fmt.Errorf("failed to link output directory: %w", err)
+	}
+	return true, nil
+}
+
+// ResolveModelDir returns the directory holding the files of the model at
+// path, following the link of models imported in place
+func ResolveModelDir(path string) string {
+	if target, ok := IsLinkedModel(path); ok {
+		return target
+	}
+	return path
+}
+
+// IsStateFile reports whether name is a file Silmaril or the torrent client
+// keeps next to the model files. The ignore file belongs to the model.
+func IsStateFile(name string) bool {
+	if name == IgnoreFile {
+		return false
+	}
+	return strings.HasPrefix(name, ".silmaril") || strings.HasPrefix(name, ".torrent.db") || strings.HasPrefix(name, ".torrent.bolt.db")
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MigrationFile marks a base directory taking part in an unfinished
+// migration. It is written to both the old and the new base directory.
+const MigrationFile = ".silmaril-migrate.json"
+
+// Migration records the move of a base directory to another location
+type Migration struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// MigrateProgress reports the progress of a migration after each file
+type MigrateProgress struct {
+	Path       string // Relative to the base directory
+	Files      int
+	TotalFiles int
+	Bytes      int64
+	TotalBytes int64
+}
+
+// ReadMigration returns the unfinished migration the base directory dir
+// takes part in, nil if there is none
+func ReadMigration(dir string) (*Migration, error) {
+	data, err := os.ReadFile(filepath.Join(dir, MigrationFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read migration: %w", err)
+	}
+	var m Migration
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to read migration %s: %w", filepath.Join(dir, MigrationFile), err)
+	}
+	return &m, nil
+}
+
+// MigrateBaseDir moves everything in the base directory from to the new base
+// directory to, which must be empty or hold an unfinished migration from the
+// same directory. Files are moved one at a time, renamed if both directories
+// are on the same filesystem and copied otherwise, the original is removed
+// once its copy is on disk. An interrupted migration is resumed by calling
+// MigrateBaseDir again. Links to files below from are pointed at to.
+//
+// Both directories keep a MigrationFile until FinishMigration is called, after
+// the paths recorded elsewhere were rewritten.
+func MigrateBaseDir(from, to string, progress func(MigrateProgress)) error {
+	if !filepath.IsAbs(from) || !filepath.IsAbs(to) {
+		return fmt.Errorf("migration needs absolute paths, got %s and %s", from, to)
+	}
+	from, to = filepath.Clean(from), filepath.Clean(to)
+	if from == to {
+		return fmt.Errorf("%s is already the base directory", to)
+	}
+	if isBelow(to, from) || isBelow(from, to) {
+		return fmt.Errorf("cannot migrate %s to %s, one is inside the other", from, to)
+	}
+
+	m, err := startMigration(from, to)
+	if err != nil {
+		return err
+	}
+
+	files, total, err := migrationFiles(from)
+	if err != nil {
+		return err
+	}
+
+	done := MigrateProgress{TotalFiles: len(files), TotalBytes: total}
+	for _, relPath := range files {
+		size, err := moveEntry(m, relPath)
+		if err != nil {
+			return fmt.Errorf("failed to move %s: %w", relPath, err)
+		}
+		done.Path = relPath
+		done.Files++
+		done.Bytes += size
+		if progress != nil {
+			progress(done)
+		}
+	}
+
+	return removeEmptyDirs(from)
+}
+
+// FinishMigration removes the marks of a migration from both directories, and
+// the old base directory if nothing is left in it
+func FinishMigration(m *Migration) error {
+	for _, dir := range []string{m.From, m.To} {
+		for _, name := range []string{MigrationFile, MigrationFile + ".bak"} {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to finish migration: %w", err)
+			}
+		}
+	}
+	os.Remove(m.From)
+	return nil
+}
+
+// RelocatePath returns path moved from the base directory from to to, and
+// whether it was below from
+func RelocatePath(path, from, to string) (string, bool) {
+	if path == "" || !filepath.IsAbs(path) {
+		return path, false
+	}
+	relPath, err := filepath.Rel(from, path)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return path, false
+	}
+	return filepath.Join(to, relPath), true
+}
+
+// startMigration marks both directories, or checks the marks of the
+// migration being resumed
+func startMigration(from, to string) (*Migration, error) {
+	existing, err := ReadMigration(to)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if filepath.Clean(existing.From) != from {
+			return nil, fmt.Errorf("%s holds an unfinished migration from %s", to, existing.From)
+		}
+		return existing, writeMigration(from, existing)
+	}
+
+	if entries, err := os.ReadDir(to); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", to)
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", to, err)
+	}
+	if err := os.MkdirAll(to, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", to, err)
+	}
+
+	m := &Migration{From: from, To: to, StartedAt: time.Now()}
+	if err := writeMigration(to, m); err != nil {
+		return nil, err
+	}
+	return m, writeMigration(from, m)
+}
+
+func writeMigration(dir string, m *Migration) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(filepath.Join(dir, MigrationFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to record migration in %s: %w", dir, err)
+	}
+	return nil
+}
+
+// migrationFiles lists the files and links left to move below from, and
+// their total size
+func migrationFiles(from string) ([]string, int64, error) {
+	var files []string
+	var total int64
+	err := filepath.Walk(from, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		if relPath == MigrationFile || strings.HasPrefix(relPath, MigrationFile+".") {
+			return nil
+		}
+		files = append(files, relPath)
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list %s: %w", from, err)
+	}
+	sort.Strings(files)
+	return files, total, nil
+}
+
+// moveEntry moves the file or link at relPath and returns its size
+func moveEntry(m *Migration, relPath string) (int64, error) {
+	src := filepath.Join(m.From, relPath)
+	dst := filepath.Join(m.To, relPath)
+
+	info, err := os.Lstat(src)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return 0, err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		return 0, moveLink(m, src, dst)
+	}
+	// Sockets and pipes hold no data and are left behind
+	if !info.Mode().IsRegular() {
+		return 0, nil
+	}
+
+	// Within a filesystem the file is moved as is
+	if err := os.Rename(src, dst); err == nil {
+		return info.Size(), nil
+	}
+
+	// A copy left by an interrupted migration only lacks the removal of the
+	// original, copies are renamed into place once complete
+	if dstInfo, err := os.Stat(dst); err != nil || dstInfo.Size() != info.Size() {
+		if err := copyFileSynced(src, dst, info); err != nil {
+			return 0, err
+		}
+	}
+	return info.Size(), os.Remove(src)
+}
+
+// moveLink recreates the link src at dst, pointing it at the new base
+// directory if it pointed into the old one. Models imported in place stay
+// linked to their original directory.
+func moveLink(m *Migration, src, dst string) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	if relocated, ok := RelocatePath(target, m.From, m.To); ok {
+		target = relocated
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Symlink(target, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFileSynced copies src to dst through a temporary file synced to disk,
+// keeping the mode and modification time of src
+func copyFileSynced(src, dst string, info os.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".migrating"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(dst))
+}
+
+// removeEmptyDirs removes the directories below root left empty by the
+// migration, deepest first
+func removeEmptyDirs(root string) error {
+	var dirs []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path != root {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", root, err)
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		// Directories still holding files fail with ErrExist
+		if err := os.Remove(dirs[i]); err != nil && !errors.Is(err, os.ErrExist) && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", dirs[i], err)
+		}
+	}
+	return nil
+}
+
+// isBelow reports whether path is dir or inside it
+func isBelow(path, dir string) bool {
+	_, ok := RelocatePath(path, dir, dir)
+	return ok
+}
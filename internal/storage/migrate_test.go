@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateBaseDir(t *testing.T) {
+	from := filepath.Join(t.TempDir(), "old")
+	to := filepath.Join(t.TempDir(), "new")
+	original := t.TempDir()
+
+	modelDir := filepath.Join(from, "models", "org", "model")
+	require.NoError(t, os.MkdirAll(modelDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "model.safetensors"), []byte("weights"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(from, "daemon"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(from, "daemon", "state.json"), []byte("{}"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(from, "torrents"), 0755))
+
+	// A model imported in place and a link into the base directory
+	require.NoError(t, os.Symlink(original, filepath.Join(from, "models", "org", "linked")))
+	require.NoError(t, os.Symlink(modelDir, filepath.Join(from, "models", "org", "alias")))
+
+	var last MigrateProgress
+	require.NoError(t, MigrateBaseDir(from, to, func(p MigrateProgress) { last = p }))
+	assert.Equal(t, 4, last.Files)
+	assert.Equal(t, 4, last.TotalFiles)
+	assert.Equal(t, int64(len("weights")+len("{}")), last.Bytes)
+
+	data, err := os.ReadFile(filepath.Join(to, "models", "org", "model", "model.safetensors"))
+	require.NoError(t, err)
+	assert.Equal(t, "weights", string(data))
+	assert.FileExists(t, filepath.Join(to, "daemon", "state.json"))
+
+	target, err := os.Readlink(filepath.Join(to, "models", "org", "linked"))
+	require.NoError(t, err)
+	assert.Equal(t, original, target)
+	target, err = os.Readlink(filepath.Join(to, "models", "org", "alias"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(to, "models", "org", "model"), target)
+
+	// Only the marks of the migration are left until it is finished
+	entries, err := os.ReadDir(from)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, MigrationFile, entries[0].Name())
+
+	m, err := ReadMigration(to)
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, from, m.From)
+	assert.Equal(t, to, m.To)
+
+	require.NoError(t, FinishMigration(m))
+	assert.NoDirExists(t, from)
+	assert.NoFileExists(t, filepath.Join(to, MigrationFile))
+}
+
+func TestMigrateBaseDirResume(t *testing.T) {
+	from := filepath.Join(t.TempDir(), "old")
+	to := filepath.Join(t.TempDir(), "new")
+	require.NoError(t, os.MkdirAll(filepath.Join(from, "models"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(from, "models", "a.bin"), []byte("aaaa"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(from, "models", "b.bin"), []byte("bbbb"), 0644))
+
+	// Interrupted after copying a.bin but before removing the original
+	m := &Migration{From: from, To: to}
+	require.NoError(t, os.MkdirAll(filepath.Join(to, "models"), 0755))
+	require.NoError(t, writeMigration(to, m))
+	require.NoError(t, writeMigration(from, m))
+	require.NoError(t, os.WriteFile(filepath.Join(to, "models", "a.bin"), []byte("aaaa"), 0644))
+
+	require.NoError(t, MigrateBaseDir(from, to, nil))
+	assert.FileExists(t, filepath.Join(to, "models", "a.bin"))
+	assert.FileExists(t, filepath.Join(to, "models", "b.bin"))
+	assert.NoFileExists(t, filepath.Join(from, "models", "a.bin"))
+	assert.NoFileExists(t, filepath.Join(from, "models", "b.bin"))
+
+	// A migration from another directory is not taken over
+	assert.Error(t, MigrateBaseDir(t.TempDir(), to, nil))
+}
+
+func TestMigrateBaseDirRejects(t *testing.T) {
+	from := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(from, "file"), []byte("x"), 0644))
+
+	notEmpty := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(notEmpty, "other"), []byte("x"), 0644))
+
+	assert.Error(t, MigrateBaseDir(from, notEmpty, nil))
+	assert.Error(t, MigrateBaseDir(from, filepath.Join(from, "inside"), nil))
+	assert.Error(t, MigrateBaseDir(from, from, nil))
+	assert.Error(t, MigrateBaseDir(from, "relative", nil))
+	assert.FileExists(t, filepath.Join(from, "file"))
+}
+
+func TestRelocatePath(t *testing.T) {
+	path, ok := RelocatePath("/old/base/models/org/model", "/old/base", "/new")
+	assert.True(t, ok)
+	assert.Equal(t, "/new/models/org/model", path)
+
+	path, ok = RelocatePath("/old/basement/model", "/old/base", "/new")
+	assert.False(t, ok)
+	assert.Equal(t, "/old/basement/model", path)
+
+	_, ok = RelocatePath("relative/model", "/old/base", "/new")
+	assert.False(t, ok)
+}
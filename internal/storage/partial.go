@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// PartialMarkerFile marks a model directory whose download has not finished.
+// Directories carrying it are not listed as local models.
+const PartialMarkerFile = ".silmaril.partial"
+
+// MarkPartial marks the model directory dir as incompletely downloaded
+func MarkPartial(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create model directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, PartialMarkerFile), nil, 0644); err != nil {
+		return fmt.Errorf("failed to mark partial download: %w", err)
+	}
+	return nil
+}
+
+// ClearPartial removes the partial download marker from dir
+func ClearPartial(dir string) error {
+	err := os.Remove(filepath.Join(dir, PartialMarkerFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear partial download marker: %w", err)
+	}
+	return nil
+}
+
+// IsPartial reports whether dir holds an unfinished download
+func IsPartial(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, PartialMarkerFile))
+	return err == nil
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+
+	"github.com/silmaril/silmaril/internal/modelname"
 )
 
 // Paths manages all storage locations for Silmaril
@@ -127,9 +129,10 @@ func (p *Paths) ModelsDir() string {
 	return p.modelsDir
 }
 
-// ModelPath returns the path for a specific model
-func (p *Paths) ModelPath(modelName string) string {
-	return filepath.Join(p.modelsDir, modelName)
+// ModelDir returns the path of a model after checking its name, so the path
+// never leaves the models directory
+func (p *Paths) ModelDir(modelName string) (string, error) {
+	return modelname.Join(p.modelsDir, modelName)
 }
 
 // TorrentsDir returns the torrents directory
@@ -223,14 +226,32 @@ func GetModelsDir() string {
 	return filepath.Join(baseDir, "models")
 }
 
+// ModelDir returns the path of a model in the models directory after
+// checking its name
+func ModelDir(modelName string) (string, error) {
+	return modelname.Join(GetModelsDir(), modelName)
+}
+
 // GetTorrentsDir returns the torrents directory
 func GetTorrentsDir() string {
 	baseDir := GetBaseDir()
 	return filepath.Join(baseDir, "torrents")
 }
 
+// GetEncryptedDir returns the directory holding the encrypted files seeded
+// for encrypted models
+func GetEncryptedDir() string {
+	baseDir := GetBaseDir()
+	return filepath.Join(baseDir, "encrypted")
+}
+
 // GetRegistryDir returns the registry directory
 func GetRegistryDir() string {
 	baseDir := GetBaseDir()
 	return filepath.Join(baseDir, "registry")
-}
\ No newline at end of file
+}
+
+// GetDirSize returns the total size of the files below path
+func GetDirSize(path string) int64 {
+	return getDirSize(path)
+}
@@ -236,7 +236,8 @@ func TestModelPath(t *testing.T) {
 	
 	for _, tt := range tests {
 		t.Run(tt.modelName, func(t *testing.T) {
-			result := paths.ModelPath(tt.modelName)
+			result, err := paths.ModelDir(tt.modelName)
+			require.NoError(t, err)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -349,4 +350,111 @@ func BenchmarkGetDirSize(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_ = getDirSize(tempDir)
 	}
-}
\ No newline at end of file
+}
+func TestPartialMarker(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "org", "model")
+	assert.False(t, IsPartial(dir))
+
+	require.NoError(t, MarkPartial(dir))
+	assert.True(t, IsPartial(dir))
+
+	require.NoError(t, ClearPartial(dir))
+	assert.False(t, IsPartial(dir))
+
+	// Clearing twice is fine
+	require.NoError(t, ClearPartial(dir))
+}
+
+func TestImportDir(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "model")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "config.json"), []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "sub", "weights.bin"), []byte("weights"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, ".torrent.db"), nil, 0644))
+
+	modelsDir := t.TempDir()
+
+	// Copies are independent of the source
+	dst := filepath.Join(modelsDir, "org", "copy")
+	stats, err := ImportDir(src, dst, ImportCopy)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Copied)
+	data, err := os.ReadFile(filepath.Join(dst, "sub", "weights.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, "weights", string(data))
+	assert.NoFileExists(t, filepath.Join(dst, ".torrent.db"))
+	_, linked := IsLinkedModel(dst)
+	assert.False(t, linked)
+
+	// Links share the data of the source files
+	dst = filepath.Join(modelsDir, "org", "link")
+	stats, err = ImportDir(src, dst, ImportLink)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Reflinked+stats.Hardlinked+stats.Copied)
+	if stats.Hardlinked > 0 {
+		srcInfo, err := os.Stat(filepath.Join(src, "config.json"))
+		require.NoError(t, err)
+		dstInfo, err := os.Stat(filepath.Join(dst, "config.json"))
+		require.NoError(t, err)
+		assert.True(t, os.SameFile(srcInfo, dstInfo))
+	}
+
+	// Importing again leaves linked files intact
+	_, err = ImportDir(src, dst, ImportCopy)
+	require.NoError(t, err)
+	data, err = os.ReadFile(filepath.Join(src, "sub", "weights.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, "weights", string(data))
+
+	// In place imports link the directory itself
+	if runtime.GOOS != "windows" {
+		dst = filepath.Join(modelsDir, "org", "inplace")
+		_, err = ImportDir(src, dst, ImportInPlace)
+		require.NoError(t, err)
+		target, linked := IsLinkedModel(dst)
+		assert.True(t, linked)
+		resolved, err := filepath.EvalSymlinks(src)
+		require.NoError(t, err)
+		assert.Equal(t, resolved, target)
+		assert.Equal(t, resolved, ResolveModelDir(dst))
+	}
+
+	_, err = ImportDir(src, filepath.Join(modelsDir, "org", "bad"), "move")
+	assert.Error(t, err)
+}
+
+func TestLinkOutputDir(t *testing.T) {
+	modelsDir := t.TempDir()
+	modelPath := filepath.Join(modelsDir, "org", "model")
+	output, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	output = filepath.Join(output, "scratch")
+
+	created, err := LinkOutputDir(output, modelPath)
+	require.NoError(t, err)
+	assert.True(t, created)
+	target, ok := IsLinkedModel(modelPath)
+	require.True(t, ok)
+	assert.Equal(t, output, target)
+
+	// A resumed download links the same directory again
+	created, err = LinkOutputDir(output, modelPath)
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	// Another directory or a model already in place is not replaced
+	_, err = LinkOutputDir(t.TempDir(), modelPath)
+	assert.ErrorIs(t, err, ErrModelExists)
+	require.NoError(t, os.MkdirAll(filepath.Join(modelsDir, "org", "local"), 0755))
+	_, err = LinkOutputDir(output, filepath.Join(modelsDir, "org", "local"))
+	assert.ErrorIs(t, err, ErrModelExists)
+
+	_, err = LinkOutputDir("relative", filepath.Join(modelsDir, "org", "other"))
+	assert.Error(t, err)
+
+	// Nor are files of a directory in use overwritten
+	used := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(used, "notes.txt"), []byte("keep"), 0644))
+	_, err = LinkOutputDir(used, filepath.Join(modelsDir, "org", "other"))
+	assert.ErrorIs(t, err, ErrOutputNotEmpty)
+}
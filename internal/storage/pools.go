@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/silmaril/silmaril/internal/modelname"
+)
+
+// DefaultPool names the models directory among the storage pools
+const DefaultPool = "default"
+
+// Pool is a directory models are kept in besides the models directory, for
+// example on a faster or a bigger disk. Models in a pool are linked into the
+// models directory, so they are found and seeded like any other.
+type Pool struct {
+	Name    string
+	Path    string
+	MinSize int64 // Bytes, smaller models go elsewhere
+	MaxSize int64 // Bytes, no limit if 0
+}
+
+// Takes reports whether the size limits of the pool take a model of size
+// bytes
+func (p Pool) Takes(size int64) bool {
+	return size >= p.MinSize && (p.MaxSize == 0 || size <= p.MaxSize)
+}
+
+// ModelDir returns where the model called name is kept in the pool after
+// checking its name
+func (p Pool) ModelDir(name string) (string, error) {
+	return modelname.Join(p.Path, name)
+}
+
+// PlaceModel returns the pool a model of size bytes belongs in: of the pools
+// whose limits take it and with room for it, the one with the most free
+// space. Ties go to the pool listed first. Models of unknown size and models
+// no pool takes are kept in the models directory, PlaceModel returns nil for
+// them.
+func PlaceModel(pools []Pool, size int64) *Pool {
+	if size <= 0 {
+		return nil
+	}
+
+	var best *Pool
+	var bestFree int64
+	for i := range pools {
+		pool := &pools[i]
+		if !pool.Takes(size) {
+			continue
+		}
+		// Pools whose free space can't be told are only picked if no other
+		// pool takes the model
+		free, err := FreeSpace(pool.Path)
+		if err != nil {
+			free = -1
+		} else if free < size {
+			continue
+		}
+		if best == nil || free > bestFree {
+			best, bestFree = pool, free
+		}
+	}
+	return best
+}
+
+// PoolOf returns the pool holding the model called name whose directory is
+// at modelPath, the default pool for models in the models directory itself.
+// Only a link to the model's own directory in a pool counts, models linked
+// from anywhere else, such as models imported in place from a directory
+// that happens to be in a pool, are in no pool.
+func PoolOf(pools []Pool, name, modelPath string) (*Pool, bool) {
+	target, linked := IsLinkedModel(modelPath)
+	if !linked {
+		return nil, true
+	}
+	for i := range pools {
+		path, err := filepath.EvalSymlinks(pools[i].Path)
+		if err != nil {
+			continue
+		}
+		dir, err := modelname.Join(path, name)
+		if err == nil && target == dir {
+			return &pools[i], true
+		}
+	}
+	return nil, false
+}
+
+// FreeSpace returns the bytes available to unprivileged users on the
+// filesystem of path. Paths that don't exist yet are looked up by their
+// closest existing parent.
+func FreeSpace(path string) (int64, error) {
+	for {
+		if _, err := os.Stat(path); err == nil || filepath.Dir(path) == path {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+	return freeSpace(path)
+}
+
+// CopyDir copies the files and links below src to dst, for moving a model
+// directory to another filesystem. Files are synced to disk and keep their
+// modification time, files a previous copy completed are skipped.
+func CopyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relPath)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			if done, err := os.Stat(target); err == nil && done.Size() == info.Size() && done.ModTime().Equal(info.ModTime()) {
+				return nil
+			}
+			return copyFileSynced(path, target, info)
+		}
+		return nil
+	})
+}
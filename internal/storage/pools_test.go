@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolTakes(t *testing.T) {
+	pool := Pool{MinSize: 10, MaxSize: 100}
+	assert.False(t, pool.Takes(9))
+	assert.True(t, pool.Takes(10))
+	assert.True(t, pool.Takes(100))
+	assert.False(t, pool.Takes(101))
+
+	assert.True(t, Pool{}.Takes(1<<40), "no upper limit")
+}
+
+func TestPlaceModel(t *testing.T) {
+	pools := []Pool{
+		{Name: "fast", Path: t.TempDir(), MaxSize: 1 << 20},
+		{Name: "big", Path: t.TempDir(), MinSize: 1 << 20},
+	}
+
+	pool := PlaceModel(pools, 1024)
+	require.NotNil(t, pool)
+	assert.Equal(t, "fast", pool.Name)
+
+	pool = PlaceModel(pools, 2<<20)
+	require.NotNil(t, pool)
+	assert.Equal(t, "big", pool.Name)
+
+	// Unknown sizes and models no pool has room for stay in the models
+	// directory
+	assert.Nil(t, PlaceModel(pools, 0))
+	assert.Nil(t, PlaceModel(pools, 1<<62))
+	assert.Nil(t, PlaceModel(nil, 1024))
+}
+
+func TestPoolOf(t *testing.T) {
+	pools := []Pool{{Name: "big", Path: t.TempDir()}}
+	modelsDir := t.TempDir()
+
+	plain := filepath.Join(modelsDir, "plain")
+	require.NoError(t, os.MkdirAll(plain, 0755))
+	pool, managed := PoolOf(pools, "org/plain", plain)
+	assert.Nil(t, pool)
+	assert.True(t, managed)
+
+	pooled := filepath.Join(modelsDir, "pooled")
+	pooledDir, err := pools[0].ModelDir("org/pooled")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(pooledDir, 0755))
+	require.NoError(t, os.Symlink(pooledDir, pooled))
+	pool, managed = PoolOf(pools, "org/pooled", pooled)
+	require.NotNil(t, pool)
+	assert.Equal(t, "big", pool.Name)
+	assert.True(t, managed)
+
+	// Models imported in place are in no pool
+	imported := filepath.Join(modelsDir, "imported")
+	require.NoError(t, os.Symlink(t.TempDir(), imported))
+	pool, managed = PoolOf(pools, "org/imported", imported)
+	assert.Nil(t, pool)
+	assert.False(t, managed)
+
+	// Even when they are imported from a directory in a pool
+	pool, managed = PoolOf(pools, "org/other", pooled)
+	assert.Nil(t, pool)
+	assert.False(t, managed)
+	inPool := filepath.Join(pools[0].Path, "datasets", "mine")
+	require.NoError(t, os.MkdirAll(inPool, 0755))
+	require.NoError(t, os.Symlink(inPool, filepath.Join(modelsDir, "mine")))
+	pool, managed = PoolOf(pools, "org/mine", filepath.Join(modelsDir, "mine"))
+	assert.Nil(t, pool)
+	assert.False(t, managed)
+}
+
+func TestCopyDir(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "copy")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "sub", "a.bin"), []byte("aaaa"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "b.bin"), []byte("bbbb"), 0644))
+	require.NoError(t, os.Symlink("sub/a.bin", filepath.Join(src, "link")))
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(filepath.Join(src, "b.bin"), mtime, mtime))
+
+	// A file completed by an interrupted copy is kept
+	require.NoError(t, os.MkdirAll(dst, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "b.bin"), []byte("done"), 0644))
+	require.NoError(t, os.Chtimes(filepath.Join(dst, "b.bin"), mtime, mtime))
+
+	require.NoError(t, CopyDir(src, dst))
+	data, err := os.ReadFile(filepath.Join(dst, "sub", "a.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, "aaaa", string(data))
+	data, err = os.ReadFile(filepath.Join(dst, "b.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, "done", string(data))
+	link, err := os.Readlink(filepath.Join(dst, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "sub/a.bin", link)
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// QuarantineMarkerFile marks a model directory whose download finished but
+// wasn't verified yet, or failed verification. It holds a note on why.
+// Directories carrying it are not listed as local models.
+const QuarantineMarkerFile = ".silmaril.quarantine"
+
+// MarkQuarantined holds the model directory dir in quarantine, replacing the
+// note of an earlier quarantine
+func MarkQuarantined(dir string, note []byte) error {
+	if err := os.WriteFile(filepath.Join(dir, QuarantineMarkerFile), note, 0644); err != nil {
+		return fmt.Errorf("failed to quarantine download: %w", err)
+	}
+	return nil
+}
+
+// ReadQuarantine returns the note of the quarantine of dir
+func ReadQuarantine(dir string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(dir, QuarantineMarkerFile))
+}
+
+// ClearQuarantine releases dir from quarantine
+func ClearQuarantine(dir string) error {
+	err := os.Remove(filepath.Join(dir, QuarantineMarkerFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear quarantine marker: %w", err)
+	}
+	return nil
+}
+
+// IsQuarantined reports whether dir holds a download held in quarantine
+func IsQuarantined(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, QuarantineMarkerFile))
+	return err == nil
+}
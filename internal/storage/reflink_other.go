@@ -0,0 +1,11 @@
+//go:build !linux
+
+package storage
+
+import "errors"
+
+// reflinkFile is only implemented on Linux, elsewhere ImportLink falls back
+// to hardlinks
+func reflinkFile(src, dst string) error {
+	return errors.New("reflinks are not supported on this platform")
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var schemaVersionKey = []byte("schema_version")
+
+// migrations bring the schema up to date, migrations[i] moves it from
+// version i to i+1. Only append to it.
+var migrations = []func(tx *bolt.Tx) error{
+	// 1: the buckets
+	func(tx *bolt.Tx) error {
+		for _, name := range []string{Models, Versions, Transfers, Jobs, Catalogs, State} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// 2: the transfer history
+	func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(History))
+		return err
+	},
+}
+
+// SchemaVersion returns the version of the schema of the database
+func (s *Store) SchemaVersion() (int, error) {
+	version := 0
+	err := s.db.View(func(tx *bolt.Tx) error {
+		version = schemaVersion(tx)
+		return nil
+	})
+	return version, err
+}
+
+func schemaVersion(tx *bolt.Tx) int {
+	b := tx.Bucket([]byte(meta))
+	if b == nil {
+		return 0
+	}
+	if data := b.Get(schemaVersionKey); len(data) == 8 {
+		return int(binary.BigEndian.Uint64(data))
+	}
+	return 0
+}
+
+// migrate runs the migrations the database hasn't had, each in its own
+// transaction
+func (s *Store) migrate() error {
+	for {
+		done := false
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			version := schemaVersion(tx)
+			if version > len(migrations) {
+				return fmt.Errorf("database schema version %d is newer than this silmaril supports (%d)", version, len(migrations))
+			}
+			if version == len(migrations) {
+				done = true
+				return nil
+			}
+			if err := migrations[version](tx); err != nil {
+				return fmt.Errorf("failed to migrate database to version %d: %w", version+1, err)
+			}
+			b, err := tx.CreateBucketIfNotExists([]byte(meta))
+			if err != nil {
+				return err
+			}
+			data := make([]byte, 8)
+			binary.BigEndian.PutUint64(data, uint64(version+1))
+			return b.Put(schemaVersionKey, data)
+		})
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// ImportFile moves the JSON file at path into the database. It calls load
+// with the content of the file and, once load succeeded, renames the file
+// with a .migrated suffix so it is imported only once. It returns false if
+// there is no file.
+func ImportFile(path string, load func(data []byte) error) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := load(data); err != nil {
+		return false, fmt.Errorf("failed to import %s: %w", path, err)
+	}
+	if err := os.Rename(path, path+".migrated"); err != nil {
+		return true, fmt.Errorf("failed to rename imported %s: %w", path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,222 @@
+// Package store is the embedded database of the daemon. It is a bbolt file
+// in the db directory holding the models, transfers, jobs and catalogs the
+// daemon used to keep in JSON files. Writes happen in transactions, so a
+// crash never leaves it half written.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// FileName is the name of the database in the db directory
+const FileName = "silmaril.db"
+
+// Buckets of the database
+const (
+	Models    = "models"    // Manifests of the local models by name
+	Versions  = "versions"  // Versions each local model had
+	Transfers = "transfers" // Transfers by ID
+	Jobs      = "jobs"      // Publish jobs by ID
+	Catalogs  = "catalogs"  // Catalogs by directory
+	State     = "state"     // Daemon state by section
+	History   = "history"   // Finished transfers by end time
+	meta      = "meta"
+)
+
+// How long Open waits for another process to release the database
+const openTimeout = time.Second
+
+// ErrLocked is returned by Open when another daemon has the database open
+var ErrLocked = errors.New("database is in use by another process")
+
+// Store is the database of the daemon
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens the database in dir, creating it if needed, and brings its
+// schema up to date
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	db, err := bolt.Open(filepath.Join(dir, FileName), 0600, &bolt.Options{Timeout: openTimeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, ErrLocked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Path returns the file of the database
+func (s *Store) Path() string {
+	return s.db.Path()
+}
+
+// Put stores v as JSON under key in bucket
+func (s *Store) Put(bucket, key string, v interface{}) error {
+	return s.Update(func(tx *Tx) error {
+		return tx.Put(bucket, key, v)
+	})
+}
+
+// Get decodes the value under key in bucket into v. It returns false if
+// there is none.
+func (s *Store) Get(bucket, key string, v interface{}) (bool, error) {
+	found := false
+	err := s.View(func(tx *Tx) error {
+		var err error
+		found, err = tx.Get(bucket, key, v)
+		return err
+	})
+	return found, err
+}
+
+// Delete removes key from bucket
+func (s *Store) Delete(bucket, key string) error {
+	return s.Update(func(tx *Tx) error {
+		return tx.Delete(bucket, key)
+	})
+}
+
+// ForEach calls fn with each key of bucket and its JSON value, in key order
+func (s *Store) ForEach(bucket string, fn func(key string, data []byte) error) error {
+	return s.View(func(tx *Tx) error {
+		return tx.ForEach(bucket, fn)
+	})
+}
+
+// Replace makes values the content of bucket
+func (s *Store) Replace(bucket string, values map[string]interface{}) error {
+	return s.Update(func(tx *Tx) error {
+		return tx.Replace(bucket, values)
+	})
+}
+
+// Update runs fn in a read-write transaction, committed if fn returns nil
+func (s *Store) Update(fn func(tx *Tx) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return fn(&Tx{tx: tx})
+	})
+}
+
+// View runs fn in a read-only transaction
+func (s *Store) View(fn func(tx *Tx) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return fn(&Tx{tx: tx})
+	})
+}
+
+// Tx is a transaction of the database
+type Tx struct {
+	tx *bolt.Tx
+}
+
+func (t *Tx) bucket(name string) (*bolt.Bucket, error) {
+	b := t.tx.Bucket([]byte(name))
+	if b == nil {
+		return nil, fmt.Errorf("no bucket %s", name)
+	}
+	return b, nil
+}
+
+// Put stores v as JSON under key in bucket
+func (t *Tx) Put(bucket, key string, v interface{}) error {
+	b, err := t.bucket(bucket)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", bucket, key, err)
+	}
+	return b.Put([]byte(key), data)
+}
+
+// Get decodes the value under key in bucket into v. It returns false if
+// there is none.
+func (t *Tx) Get(bucket, key string, v interface{}) (bool, error) {
+	b, err := t.bucket(bucket)
+	if err != nil {
+		return false, err
+	}
+	data := b.Get([]byte(key))
+	if data == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to decode %s/%s: %w", bucket, key, err)
+	}
+	return true, nil
+}
+
+// Delete removes key from bucket
+func (t *Tx) Delete(bucket, key string) error {
+	b, err := t.bucket(bucket)
+	if err != nil {
+		return err
+	}
+	return b.Delete([]byte(key))
+}
+
+// ForEach calls fn with each key of bucket and its JSON value, in key order.
+// data is only valid during the call.
+func (t *Tx) ForEach(bucket string, fn func(key string, data []byte) error) error {
+	b, err := t.bucket(bucket)
+	if err != nil {
+		return err
+	}
+	return b.ForEach(func(k, v []byte) error {
+		return fn(string(k), v)
+	})
+}
+
+// Replace makes values the content of bucket, removing the other keys
+func (t *Tx) Replace(bucket string, values map[string]interface{}) error {
+	b, err := t.bucket(bucket)
+	if err != nil {
+		return err
+	}
+	var stale [][]byte
+	err = b.ForEach(func(k, _ []byte) error {
+		if _, ok := values[string(k)]; !ok {
+			stale = append(stale, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range stale {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	for key, v := range values {
+		if err := t.Put(bucket, key, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testValue struct {
+	Name string `json:"name"`
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	st, err := Open(dir)
+	require.NoError(t, err)
+
+	version, err := st.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, len(migrations), version)
+
+	require.NoError(t, st.Put(Models, "org/a", testValue{Name: "a"}))
+	require.NoError(t, st.Put(Models, "org/b", testValue{Name: "b"}))
+
+	var v testValue
+	found, err := st.Get(Models, "org/a", &v)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "a", v.Name)
+
+	found, err = st.Get(Models, "org/missing", &v)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = st.Get("nonexistent", "x", &v)
+	assert.Error(t, err)
+
+	// Replace drops the keys not given
+	require.NoError(t, st.Replace(Models, map[string]interface{}{"org/b": testValue{Name: "b2"}, "org/c": testValue{Name: "c"}}))
+	var keys []string
+	require.NoError(t, st.ForEach(Models, func(key string, data []byte) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, []string{"org/b", "org/c"}, keys)
+
+	require.NoError(t, st.Delete(Models, "org/b"))
+	found, err = st.Get(Models, "org/b", &v)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// A failed transaction changes nothing
+	err = st.Update(func(tx *Tx) error {
+		require.NoError(t, tx.Put(Jobs, "job", testValue{Name: "job"}))
+		return assert.AnError
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+	found, err = st.Get(Jobs, "job", &v)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// The data survives reopening
+	require.NoError(t, st.Close())
+	st, err = Open(dir)
+	require.NoError(t, err)
+	defer st.Close()
+	found, err = st.Get(Models, "org/c", &v)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, filepath.Join(dir, FileName), st.Path())
+}
+
+func TestOpenLocked(t *testing.T) {
+	dir := t.TempDir()
+	st, err := Open(dir)
+	require.NoError(t, err)
+	defer st.Close()
+
+	_, err = Open(dir)
+	assert.ErrorIs(t, err, ErrLocked)
+}
+
+func TestImportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	imported, err := ImportFile(path, func(data []byte) error { return nil })
+	require.NoError(t, err)
+	assert.False(t, imported)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"name":"x"}`), 0644))
+
+	// A failed import leaves the file to try again
+	_, err = ImportFile(path, func(data []byte) error { return assert.AnError })
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.FileExists(t, path)
+
+	var content string
+	imported, err = ImportFile(path, func(data []byte) error {
+		content = string(data)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, imported)
+	assert.Equal(t, `{"name":"x"}`, content)
+	assert.NoFileExists(t, path)
+	assert.FileExists(t, path+".migrated")
+}
@@ -7,73 +7,48 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"time"
+	"sort"
 
 	"github.com/anacrolix/torrent/bencode"
 	"github.com/anacrolix/torrent/metainfo"
+	"github.com/silmaril/silmaril/internal/storage"
 )
 
-// CreateTorrentFromDirectory creates a .torrent file from a directory
-func CreateTorrentFromDirectory(sourceDir string, outputPath string, pieceLength int64) (string, error) {
-	fmt.Printf("[TorrentCreator] Creating torrent from directory: %s\n", sourceDir)
-	fmt.Printf("[TorrentCreator] Output path: %s\n", outputPath)
-	
-	// Set default piece length if not specified
-	if pieceLength <= 0 {
-		pieceLength = 4 * 1024 * 1024 // 4MB default
-	}
-	fmt.Printf("[TorrentCreator] Using piece length: %d bytes\n", pieceLength)
+// DefaultPieceLength is the piece length of torrents created without one,
+// doubled for models too large to fit in maxCanonicalPieces pieces
+const DefaultPieceLength = 4 * 1024 * 1024
 
-	// Create metainfo builder
-	info := metainfo.Info{
-		PieceLength: pieceLength,
-	}
-
-	// Build file list
-	err := filepath.Walk(sourceDir, func(path string, fi os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if fi.IsDir() {
-			return nil
-		}
-		
-		// Skip hidden files and special files
-		if filepath.Base(path)[0] == '.' {
-			return nil
-		}
-		
-		// Skip the silmaril manifest file itself
-		if filepath.Base(path) == ".silmaril.json" {
-			return nil
-		}
-
-		relPath, err := filepath.Rel(sourceDir, path)
-		if err != nil {
-			return fmt.Errorf("failed to get relative path: %w", err)
-		}
+const (
+	// Pieces of a torrent created with the canonical piece length, at most
+	maxCanonicalPieces = 16384
+	// Largest canonical piece length
+	maxCanonicalPieceLength = 64 * 1024 * 1024
+)
 
-		info.Files = append(info.Files, metainfo.FileInfo{
-			Path:   []string{filepath.ToSlash(relPath)},
-			Length: fi.Size(),
-		})
-		
-		return nil
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to walk directory: %w", err)
+// CanonicalPieceLength returns the piece length of torrents of totalSize
+// bytes created without one. Everyone publishing the same files gets the
+// same piece length, and so the same infohash.
+func CanonicalPieceLength(totalSize int64) int64 {
+	pieceLength := int64(DefaultPieceLength)
+	for pieceLength < maxCanonicalPieceLength && totalSize > pieceLength*maxCanonicalPieces {
+		pieceLength *= 2
 	}
-	fmt.Printf("[TorrentCreator] Found %d files to include\n", len(info.Files))
+	return pieceLength
+}
 
-	// Calculate pieces
-	fmt.Printf("[TorrentCreator] Generating pieces...\n")
-	err = info.GeneratePieces(func(fi metainfo.FileInfo) (io.ReadCloser, error) {
-		path := filepath.Join(sourceDir, filepath.FromSlash(fi.Path[0]))
-		return os.Open(path)
-	})
+// CreateTorrentFromDirectory creates a .torrent file from a directory. A nil
+// ignore leaves out the files ignored by the ignore file of the directory.
+// Torrents of the same files with the same piece length are identical, so
+// independent publishers of a model end up in one swarm.
+func CreateTorrentFromDirectory(sourceDir string, outputPath string, pieceLength int64, ignore *storage.Ignore) (string, error) {
+	fmt.Printf("[TorrentCreator] Creating torrent from directory: %s\n", sourceDir)
+	fmt.Printf("[TorrentCreator] Output path: %s\n", outputPath)
+
+	info, err := BuildInfo(sourceDir, pieceLength, ignore)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate pieces: %w", err)
+		return "", err
 	}
+	fmt.Printf("[TorrentCreator] Using piece length: %d bytes\n", info.PieceLength)
 
 	// Create the metainfo
 	mi := metainfo.MetaInfo{
@@ -86,8 +61,7 @@ func CreateTorrentFromDirectory(sourceDir string, outputPath string, pieceLength
 		return "", fmt.Errorf("failed to marshal info: %w", err)
 	}
 
-	// Set creation date and metadata
-	mi.CreationDate = time.Now().Unix()
+	// No creation date, the torrent file only depends on the files
 	mi.CreatedBy = "Silmaril P2P"
 	mi.Comment = "Distributed via Silmaril P2P network"
 	
@@ -105,14 +79,126 @@ func CreateTorrentFromDirectory(sourceDir string, outputPath string, pieceLength
 		return "", fmt.Errorf("failed to write torrent file: %w", err)
 	}
 
-	// Calculate info hash (BitTorrent uses SHA1)
-	h := sha1.New()
-	h.Write(mi.InfoBytes)
-	infoHash := hex.EncodeToString(h.Sum(nil))
+	infoHash := hashInfoBytes(mi.InfoBytes)
 	
 	fmt.Printf("[TorrentCreator] Torrent created successfully\n")
 	fmt.Printf("[TorrentCreator] InfoHash: %s\n", infoHash)
 	fmt.Printf("[TorrentCreator] Torrent file: %s\n", outputPath)
 
 	return infoHash, nil
-}
\ No newline at end of file
+}
+
+// DirectoryFiles lists the files under sourceDir a torrent includes, sorted
+// by path, and the files and directories it leaves out, as slash separated
+// relative paths. A nil ignore uses the ignore file of sourceDir.
+func DirectoryFiles(sourceDir string, ignore *storage.Ignore) ([]metainfo.FileInfo, []string, error) {
+	// Models published in place are links to their original directory
+	if resolved, err := filepath.EvalSymlinks(sourceDir); err == nil {
+		sourceDir = resolved
+	}
+	if ignore == nil {
+		var err error
+		if ignore, err = storage.LoadIgnore(sourceDir, nil, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var files []metainfo.FileInfo
+	var excluded []string
+	err := filepath.Walk(sourceDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == sourceDir {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		// Skip ignored files and hidden ones, such as the silmaril manifest
+		if ignore.Ignored(relPath, fi.IsDir()) {
+			if fi.IsDir() {
+				excluded = append(excluded, relPath+"/")
+				return filepath.SkipDir
+			}
+			excluded = append(excluded, relPath)
+			return nil
+		}
+		if fi.IsDir() {
+			return nil
+		}
+
+		files = append(files, metainfo.FileInfo{
+			Path:   []string{relPath},
+			Length: fi.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	// Walk sorts the entries of each directory, which does not sort paths:
+	// "a/b" comes before "a.txt"
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path[0] < files[j].Path[0]
+	})
+	return files, excluded, nil
+}
+
+// BuildInfo creates the info of a torrent of sourceDir, hashing its files
+// into pieces. A piece length of 0 uses the canonical one for the size of
+// the files. Nothing is written.
+func BuildInfo(sourceDir string, pieceLength int64, ignore *storage.Ignore) (*metainfo.Info, error) {
+	if resolved, err := filepath.EvalSymlinks(sourceDir); err == nil {
+		sourceDir = resolved
+	}
+
+	files, _, err := DirectoryFiles(sourceDir, ignore)
+	if err != nil {
+		return nil, err
+	}
+	if pieceLength <= 0 {
+		var totalSize int64
+		for _, file := range files {
+			totalSize += file.Length
+		}
+		pieceLength = CanonicalPieceLength(totalSize)
+	}
+	info := &metainfo.Info{
+		PieceLength: pieceLength,
+		Files:       files,
+	}
+	fmt.Printf("[TorrentCreator] Found %d files to include\n", len(info.Files))
+
+	// Calculate pieces
+	fmt.Printf("[TorrentCreator] Generating pieces...\n")
+	err = info.GeneratePieces(func(fi metainfo.FileInfo) (io.ReadCloser, error) {
+		path := filepath.Join(sourceDir, filepath.FromSlash(fi.Path[0]))
+		return os.Open(path)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate pieces: %w", err)
+	}
+	return info, nil
+}
+
+// InfoHash returns the infohash of a torrent with the given info
+func InfoHash(info *metainfo.Info) (string, error) {
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal info: %w", err)
+	}
+	return hashInfoBytes(infoBytes), nil
+}
+
+// hashInfoBytes returns the infohash of bencoded info, BitTorrent uses SHA1
+func hashInfoBytes(infoBytes []byte) string {
+	h := sha1.New()
+	h.Write(infoBytes)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,91 @@
+package torrent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildInfoMatchesCreatedTorrent(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "weights"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"model_type":"llama"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "weights", "model.safetensors"), make([]byte, 3000), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".silmaril.json"), []byte("{}"), 0644))
+
+	files, excluded, err := DirectoryFiles(dir, nil)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, []string{"config.json"}, files[0].Path)
+	assert.Equal(t, []string{"weights/model.safetensors"}, files[1].Path)
+	assert.Equal(t, []string{".silmaril.json"}, excluded)
+
+	info, err := BuildInfo(dir, 1024, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), info.PieceLength)
+	assert.Equal(t, int64(3000+len(`{"model_type":"llama"}`)), info.TotalLength())
+	assert.Equal(t, 3, info.NumPieces())
+
+	infoHash, err := InfoHash(info)
+	require.NoError(t, err)
+
+	// The infohash is the one of the torrent file created from the directory
+	torrentPath := filepath.Join(t.TempDir(), "model.torrent")
+	created, err := CreateTorrentFromDirectory(dir, torrentPath, 1024, nil)
+	require.NoError(t, err)
+	assert.Equal(t, created, infoHash)
+
+	mi, err := metainfo.LoadFromFile(torrentPath)
+	require.NoError(t, err)
+	assert.Equal(t, infoHash, mi.HashInfoBytes().HexString())
+}
+
+func TestCreatedTorrentsAreReproducible(t *testing.T) {
+	// The same files written in a different order, at different times
+	writeModel := func(dir string, names []string) {
+		for _, name := range names {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+			require.NoError(t, os.WriteFile(path, []byte(name), 0644))
+		}
+	}
+	first, second := t.TempDir(), t.TempDir()
+	writeModel(first, []string{"a.txt", "a/b.safetensors", "config.json"})
+	writeModel(second, []string{"config.json", "a/b.safetensors", "a.txt"})
+	require.NoError(t, os.Chtimes(filepath.Join(second, "a.txt"), time.Unix(0, 0), time.Unix(0, 0)))
+
+	files, _, err := DirectoryFiles(first, nil)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.Equal(t, []string{"a.txt"}, files[0].Path)
+	assert.Equal(t, []string{"a/b.safetensors"}, files[1].Path)
+
+	firstTorrent := filepath.Join(t.TempDir(), "first.torrent")
+	secondTorrent := filepath.Join(t.TempDir(), "second.torrent")
+	firstHash, err := CreateTorrentFromDirectory(first, firstTorrent, 0, nil)
+	require.NoError(t, err)
+	secondHash, err := CreateTorrentFromDirectory(second, secondTorrent, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, firstHash, secondHash)
+
+	// The torrent files are identical too
+	firstData, err := os.ReadFile(firstTorrent)
+	require.NoError(t, err)
+	secondData, err := os.ReadFile(secondTorrent)
+	require.NoError(t, err)
+	assert.Equal(t, firstData, secondData)
+}
+
+func TestCanonicalPieceLength(t *testing.T) {
+	const gib = int64(1024 * 1024 * 1024)
+	assert.Equal(t, int64(DefaultPieceLength), CanonicalPieceLength(0))
+	assert.Equal(t, int64(DefaultPieceLength), CanonicalPieceLength(64*gib))
+	assert.Equal(t, int64(8*1024*1024), CanonicalPieceLength(64*gib+1))
+	assert.Equal(t, int64(32*1024*1024), CanonicalPieceLength(400*gib))
+	assert.Equal(t, int64(maxCanonicalPieceLength), CanonicalPieceLength(4096*gib))
+}
@@ -0,0 +1,60 @@
+package torrent
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// SameFiles reports whether two torrents hold the same files, with the same
+// paths and sizes, in whatever order and with whatever piece length
+func SameFiles(a, b *metainfo.Info) bool {
+	aFiles, bFiles := a.UpvertedFiles(), b.UpvertedFiles()
+	if len(aFiles) != len(bFiles) {
+		return false
+	}
+	sizes := make(map[string]int64, len(aFiles))
+	for _, file := range aFiles {
+		sizes[file.DisplayPath(a)] = file.Length
+	}
+	for _, file := range bFiles {
+		size, ok := sizes[file.DisplayPath(b)]
+		if !ok || size != file.Length {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyEquivalent checks that the torrent with info holds the files of the
+// local torrent own, stored in dir: it lists the same files, and hashing the
+// local files into its pieces gives its piece hashes. Torrents of the same
+// files have other infohashes when their piece length or file order differ.
+func VerifyEquivalent(dir string, own, info *metainfo.Info) error {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	if !SameFiles(own, info) {
+		return fmt.Errorf("the torrent has other files")
+	}
+
+	// Hash the local files in the order and piece length of the torrent
+	rehashed := &metainfo.Info{
+		PieceLength: info.PieceLength,
+		Files:       info.UpvertedFiles(),
+	}
+	err := rehashed.GeneratePieces(func(fi metainfo.FileInfo) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, filepath.FromSlash(fi.DisplayPath(info))))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to hash files: %w", err)
+	}
+	if !bytes.Equal(rehashed.Pieces, info.Pieces) {
+		return fmt.Errorf("the files differ from the torrent's")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package torrent
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeEquivalentModel(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "weights"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"model_type":"llama"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "weights", "model.safetensors"), make([]byte, 5000), 0644))
+	return dir
+}
+
+func TestVerifyEquivalent(t *testing.T) {
+	dir := writeEquivalentModel(t)
+
+	// The same files with another piece length and in another order
+	other, err := BuildInfo(dir, 2048, nil)
+	require.NoError(t, err)
+	other.Files[0], other.Files[1] = other.Files[1], other.Files[0]
+	require.NoError(t, other.GeneratePieces(func(fi metainfo.FileInfo) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, filepath.FromSlash(fi.Path[0])))
+	}))
+	own, err := BuildInfo(dir, 1024, nil)
+	require.NoError(t, err)
+	assert.True(t, SameFiles(own, other))
+	assert.NoError(t, VerifyEquivalent(dir, own, other))
+
+	// Files of other sizes
+	changed := writeEquivalentModel(t)
+	require.NoError(t, os.WriteFile(filepath.Join(changed, "config.json"), []byte(`{"model_type":"mistral"}`), 0644))
+	changedInfo, err := BuildInfo(changed, 2048, nil)
+	require.NoError(t, err)
+	assert.False(t, SameFiles(own, changedInfo))
+
+	// Files of the same size with other content
+	require.NoError(t, os.WriteFile(filepath.Join(changed, "config.json"), []byte(`{"model_type":"gemma"}`), 0644))
+	changedInfo, err = BuildInfo(changed, 2048, nil)
+	require.NoError(t, err)
+	assert.True(t, SameFiles(own, changedInfo))
+	assert.EqualError(t, VerifyEquivalent(dir, own, changedInfo), "the files differ from the torrent's")
+
+	// Torrents with an extra file
+	require.NoError(t, os.WriteFile(filepath.Join(changed, "README.md"), []byte("# Model"), 0644))
+	changedInfo, err = BuildInfo(changed, 2048, nil)
+	require.NoError(t, err)
+	assert.EqualError(t, VerifyEquivalent(dir, own, changedInfo), "the torrent has other files")
+}
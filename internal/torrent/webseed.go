@@ -0,0 +1,43 @@
+package torrent
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/anacrolix/torrent/webseed"
+)
+
+// filePath returns the path of a file within its model from the path
+// components the torrent client passes web seeds: the torrent name followed
+// by the path of the file. Model torrents have no name, their files are
+// relative to the model directory, so the name is dropped. Single file
+// torrents only have a name, which is the file.
+func filePath(pathComps []string) string {
+	if len(pathComps) > 1 {
+		pathComps = pathComps[1:]
+	}
+	return strings.Join(pathComps, "/")
+}
+
+// MirrorPathEscaper maps the files of a model torrent to URLs below the base
+// URL of an HTTP mirror holding the model directory, such as
+// https://huggingface.co/org/model/resolve/<commit>/
+func MirrorPathEscaper(pathComps []string) string {
+	segments := strings.Split(filePath(pathComps), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// IPFSPathEscaper maps the files of a model torrent to their CIDs, for web
+// seeds at the /ipfs/ path of an IPFS gateway. Files without a CID keep
+// their path, which the gateway doesn't serve.
+func IPFSPathEscaper(cids map[string]string) webseed.PathEscaper {
+	return func(pathComps []string) string {
+		if cid, ok := cids[filePath(pathComps)]; ok {
+			return url.PathEscape(cid)
+		}
+		return MirrorPathEscaper(pathComps)
+	}
+}
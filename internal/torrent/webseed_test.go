@@ -0,0 +1,25 @@
+package torrent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirrorPathEscaper(t *testing.T) {
+	// Model torrents have no name and a single path component per file
+	assert.Equal(t, "weights/model%20v2.safetensors", MirrorPathEscaper([]string{"", "weights/model v2.safetensors"}))
+	assert.Equal(t, "config.json", MirrorPathEscaper([]string{"", "config.json"}))
+	// Torrents made elsewhere have a name and a component per directory
+	assert.Equal(t, "weights/a%23b.bin", MirrorPathEscaper([]string{"model", "weights", "a#b.bin"}))
+	// Single file torrents
+	assert.Equal(t, "model.gguf", MirrorPathEscaper([]string{"model.gguf"}))
+}
+
+func TestIPFSPathEscaper(t *testing.T) {
+	escape := IPFSPathEscaper(map[string]string{
+		"weights/model.safetensors": "bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi",
+	})
+	assert.Equal(t, "bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi", escape([]string{"", "weights/model.safetensors"}))
+	assert.Equal(t, "config.json", escape([]string{"", "config.json"}), "files without a CID keep their path")
+}
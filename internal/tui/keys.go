@@ -0,0 +1,71 @@
+package tui
+
+import "unicode/utf8"
+
+// Key is a key pressed in the terminal, the character typed or the name of
+// a special key
+type Key string
+
+// Special keys
+const (
+	KeyUp        Key = "up"
+	KeyDown      Key = "down"
+	KeyLeft      Key = "left"
+	KeyRight     Key = "right"
+	KeyEnter     Key = "enter"
+	KeyTab       Key = "tab"
+	KeyBackspace Key = "backspace"
+	KeyEscape    Key = "esc"
+	KeyCtrlC     Key = "ctrl+c"
+)
+
+// parseKeys splits what the terminal sent in raw mode into keys. Escape
+// sequences of keys without a name are dropped.
+func parseKeys(data []byte) []Key {
+	var keys []Key
+	for len(data) > 0 {
+		switch b := data[0]; {
+		case b == 0x1b:
+			if len(data) >= 3 && (data[1] == '[' || data[1] == 'O') {
+				switch data[2] {
+				case 'A':
+					keys = append(keys, KeyUp)
+				case 'B':
+					keys = append(keys, KeyDown)
+				case 'C':
+					keys = append(keys, KeyRight)
+				case 'D':
+					keys = append(keys, KeyLeft)
+				}
+				// Skip the parameters of longer sequences, like F-keys
+				n := 2
+				for n < len(data) && (data[n] < 0x40 || data[n] > 0x7e) {
+					n++
+				}
+				data = data[min(n+1, len(data)):]
+				continue
+			}
+			keys = append(keys, KeyEscape)
+			data = data[1:]
+		case b == '\r' || b == '\n':
+			keys = append(keys, KeyEnter)
+			data = data[1:]
+		case b == '\t':
+			keys = append(keys, KeyTab)
+			data = data[1:]
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, KeyBackspace)
+			data = data[1:]
+		case b == 0x03:
+			keys = append(keys, KeyCtrlC)
+			data = data[1:]
+		case b < 0x20:
+			data = data[1:]
+		default:
+			r, size := utf8.DecodeRune(data)
+			keys = append(keys, Key(string(r)))
+			data = data[size:]
+		}
+	}
+	return keys
+}
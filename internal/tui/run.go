@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Run shows the interface on the terminal of in and out until the user
+// quits, refreshing it every interval. The terminal is put back as it was
+// on return.
+func Run(c Client, in *os.File, out io.Writer, interval time.Duration) error {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("the terminal UI needs a terminal")
+	}
+	saved, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	defer term.Restore(fd, saved)
+
+	// Draw on the alternate screen, so the shell comes back as it was
+	fmt.Fprint(out, "\033[?1049h\033[?25l")
+	defer fmt.Fprint(out, "\033[?25h\033[?1049l")
+
+	// Keys are read until the process exits, reads can't be interrupted
+	keys := make(chan []Key)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- parseKeys(buf[:n])
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m := New(c)
+	m.Refresh()
+	for {
+		if width, height, err := term.GetSize(fd); err == nil {
+			m.SetSize(width, height)
+		}
+		draw(out, m.View())
+
+		select {
+		case pressed, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range pressed {
+				m.HandleKey(k)
+			}
+		case <-ticker.C:
+			m.Refresh()
+		}
+		if m.Quitting() {
+			return nil
+		}
+	}
+}
+
+// draw overwrites the screen with view, line by line so it doesn't flicker
+func draw(out io.Writer, view string) {
+	var b strings.Builder
+	b.WriteString("\033[H")
+	for i, line := range strings.Split(view, "\n") {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line)
+		b.WriteString("\033[K")
+	}
+	b.WriteString("\033[J")
+	io.WriteString(out, b.String())
+}
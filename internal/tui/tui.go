@@ -0,0 +1,601 @@
+// Package tui is the terminal user interface of Silmaril. It shows the
+// models, transfers, peers and DHT status of the daemon, refreshed through the
+// API client, with keys to pause, resume and cancel transfers and to download
+// models found on the network.
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/silmaril/silmaril/internal/api/apierror"
+)
+
+// Client is the part of the daemon API client the interface uses
+type Client interface {
+	GetStatus() (map[string]interface{}, error)
+	ListModels() ([]map[string]interface{}, error)
+	ListTransfers(status string) ([]map[string]interface{}, error)
+	GetTorrentPeers(infoHash string) (map[string]interface{}, error)
+	PauseTransfer(id string) error
+	ResumeTransfer(id string) error
+	CancelTransfer(id string) error
+	DiscoverModels(pattern string) ([]map[string]interface{}, error)
+	DownloadModel(modelName, infoHash string, seed bool, files []string, acceptLicense, force bool, output string) (map[string]interface{}, error)
+}
+
+// Tab is a screen of the interface
+type Tab int
+
+const (
+	TabModels Tab = iota
+	TabTransfers
+	TabPeers
+	TabDiscover
+)
+
+var tabNames = [...]string{"Models", "Transfers", "Peers", "Discover"}
+
+// Lines taken by the header, tabs, column titles and footer around the
+// list of a tab
+const chromeLines = 7
+
+// Model is the state of the interface. As in the Elm architecture, keys and
+// refreshes update it and View draws it from scratch.
+type Model struct {
+	client Client
+
+	tab    Tab
+	cursor [len(tabNames)]int
+	offset [len(tabNames)]int // First row shown, to keep the cursor in view
+
+	status     map[string]interface{}
+	models     []map[string]interface{}
+	transfers  []map[string]interface{}
+	peers      map[string]interface{}
+	peersOf    string // Model of the transfer whose peers are shown
+	discovered []map[string]interface{}
+
+	query     string
+	searching bool // Typing a search query
+
+	// A question waiting for y, like whether to cancel a transfer
+	confirm *confirmation
+
+	message string
+	err     error // Of the last refresh
+	width   int
+	height  int
+	quit    bool
+}
+
+type confirmation struct {
+	prompt string
+	action func()
+}
+
+// New returns the interface for the daemon behind c
+func New(c Client) *Model {
+	return &Model{client: c, width: 80, height: 24}
+}
+
+// SetSize sets the size of the terminal in characters
+func (m *Model) SetSize(width, height int) {
+	m.width, m.height = width, height
+}
+
+// Quitting reports whether the user asked to quit
+func (m *Model) Quitting() bool {
+	return m.quit
+}
+
+// Refresh reloads the daemon status, models and transfers, and the peers
+// while they are shown
+func (m *Model) Refresh() {
+	status, err := m.client.GetStatus()
+	if err != nil {
+		m.err = err
+		return
+	}
+	m.err = nil
+	m.status = status
+
+	if models, err := m.client.ListModels(); err == nil {
+		sortByField(models, "name")
+		m.models = models
+	}
+	if transfers, err := m.client.ListTransfers(""); err == nil {
+		sortByField(transfers, "model_name")
+		m.transfers = transfers
+	}
+	if m.tab == TabPeers {
+		m.refreshPeers()
+	}
+	m.clampCursor()
+}
+
+// refreshPeers loads the peers of the transfer selected in the transfers
+// tab
+func (m *Model) refreshPeers() {
+	transfer := m.selected(TabTransfers)
+	m.peers, m.peersOf = nil, ""
+	if transfer == nil {
+		return
+	}
+	infoHash, _ := transfer["info_hash"].(string)
+	peers, err := m.client.GetTorrentPeers(infoHash)
+	if err != nil {
+		m.message = fmt.Sprintf("Failed to get peers: %v", err)
+		return
+	}
+	m.peers = peers
+	m.peersOf, _ = transfer["model_name"].(string)
+}
+
+// HandleKey updates the interface for a key pressed by the user, running
+// the action it stands for
+func (m *Model) HandleKey(k Key) {
+	if k == KeyCtrlC {
+		m.quit = true
+		return
+	}
+	if m.confirm != nil {
+		confirm := m.confirm
+		m.confirm = nil
+		m.message = ""
+		if k == "y" || k == "Y" {
+			confirm.action()
+			m.Refresh()
+		}
+		return
+	}
+	if m.searching {
+		m.editQuery(k)
+		return
+	}
+
+	switch k {
+	case "q":
+		m.quit = true
+		return
+	case KeyTab, KeyRight:
+		m.switchTab((m.tab + 1) % Tab(len(tabNames)))
+		return
+	case KeyLeft:
+		m.switchTab((m.tab + Tab(len(tabNames)) - 1) % Tab(len(tabNames)))
+		return
+	case "1", "2", "3", "4":
+		m.switchTab(Tab(k[0] - '1'))
+		return
+	case KeyUp, "k":
+		m.cursor[m.tab]--
+		m.clampCursor()
+		return
+	case KeyDown, "j":
+		m.cursor[m.tab]++
+		m.clampCursor()
+		return
+	}
+
+	switch m.tab {
+	case TabTransfers:
+		m.transferKey(k)
+	case TabDiscover:
+		m.discoverKey(k)
+	}
+}
+
+func (m *Model) switchTab(tab Tab) {
+	m.tab = tab
+	m.message = ""
+	if tab == TabPeers {
+		m.refreshPeers()
+	}
+	m.clampCursor()
+}
+
+// transferKey runs the action of a key on the selected transfer
+func (m *Model) transferKey(k Key) {
+	transfer := m.selected(TabTransfers)
+	if transfer == nil {
+		return
+	}
+	id, _ := transfer["id"].(string)
+	name, _ := transfer["model_name"].(string)
+
+	switch k {
+	case "p":
+		m.report(m.client.PauseTransfer(id), "Paused "+name)
+	case "r":
+		m.report(m.client.ResumeTransfer(id), "Resumed "+name)
+	case "c":
+		m.confirm = &confirmation{
+			prompt: fmt.Sprintf("Cancel the transfer of %s? (y/n)", name),
+			action: func() { m.report(m.client.CancelTransfer(id), "Cancelled "+name) },
+		}
+		return
+	case KeyEnter:
+		m.switchTab(TabPeers)
+		return
+	default:
+		return
+	}
+	m.Refresh()
+}
+
+// discoverKey searches the network and downloads the selected model
+func (m *Model) discoverKey(k Key) {
+	switch k {
+	case "/", "s":
+		m.searching = true
+		m.message = ""
+	case KeyEnter, "d":
+		m.download(false)
+	case "a":
+		m.download(true)
+	}
+}
+
+// editQuery types the search query, enter searches
+func (m *Model) editQuery(k Key) {
+	switch k {
+	case KeyEnter:
+		m.searching = false
+		m.search()
+	case KeyEscape:
+		m.searching = false
+	case KeyBackspace:
+		if _, size := utf8.DecodeLastRuneInString(m.query); size > 0 {
+			m.query = m.query[:len(m.query)-size]
+		}
+	default:
+		if utf8.RuneCountInString(string(k)) == 1 {
+			m.query += string(k)
+		}
+	}
+}
+
+func (m *Model) search() {
+	models, err := m.client.DiscoverModels(m.query)
+	if err != nil {
+		m.message = fmt.Sprintf("Search failed: %v", err)
+		return
+	}
+	sortByField(models, "name")
+	m.discovered = models
+	m.cursor[TabDiscover], m.offset[TabDiscover] = 0, 0
+	m.message = fmt.Sprintf("Found %d models", len(models))
+}
+
+// download starts downloading the selected discovered model and seeding it
+// afterwards. Models whose license must be accepted are only downloaded with
+// acceptLicense, which the message asks for.
+func (m *Model) download(acceptLicense bool) {
+	model := m.selected(TabDiscover)
+	if model == nil {
+		return
+	}
+	name, _ := model["name"].(string)
+	infoHash, _ := model["info_hash"].(string)
+
+	_, err := m.client.DownloadModel(name, infoHash, true, nil, acceptLicense, false, "")
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) && apiErr.Code == apierror.CodeLicenseRequired {
+		license, _ := apiErr.Details["license"].(string)
+		m.message = fmt.Sprintf("%s requires accepting its license %s, press a to accept it and download", name, license)
+		return
+	}
+	m.report(err, "Downloading "+name)
+	if err == nil {
+		m.Refresh()
+	}
+}
+
+// report shows the outcome of an action in the message line
+func (m *Model) report(err error, done string) {
+	if err != nil {
+		m.message = "Error: " + err.Error()
+		return
+	}
+	m.message = done
+}
+
+// rows returns the rows listed in a tab
+func (m *Model) rows(tab Tab) []map[string]interface{} {
+	switch tab {
+	case TabModels:
+		return m.models
+	case TabTransfers:
+		return m.transfers
+	case TabPeers:
+		return peerList(m.peers)
+	case TabDiscover:
+		return m.discovered
+	}
+	return nil
+}
+
+// selected returns the row under the cursor of a tab, nil if it has none
+func (m *Model) selected(tab Tab) map[string]interface{} {
+	rows := m.rows(tab)
+	if len(rows) == 0 {
+		return nil
+	}
+	return rows[m.cursor[tab]]
+}
+
+// clampCursor keeps the cursor of the current tab on a row and in view
+func (m *Model) clampCursor() {
+	for tab := range tabNames {
+		n := len(m.rows(Tab(tab)))
+		if m.cursor[tab] >= n {
+			m.cursor[tab] = n - 1
+		}
+		if m.cursor[tab] < 0 {
+			m.cursor[tab] = 0
+		}
+	}
+
+	visible := m.listHeight()
+	tab := m.tab
+	if m.cursor[tab] < m.offset[tab] {
+		m.offset[tab] = m.cursor[tab]
+	}
+	if m.cursor[tab] >= m.offset[tab]+visible {
+		m.offset[tab] = m.cursor[tab] - visible + 1
+	}
+}
+
+// listHeight returns how many rows of a list fit on the screen
+func (m *Model) listHeight() int {
+	if h := m.height - chromeLines - 1; h > 1 {
+		return h
+	}
+	return 1
+}
+
+// View draws the whole screen
+func (m *Model) View() string {
+	var lines []string
+	lines = append(lines, m.header(), m.tabs(), "")
+
+	title, rows := m.body()
+	lines = append(lines, strings.Split(title, "\n")...)
+	visible := m.listHeight()
+	start := m.offset[m.tab]
+	for i := start; i < len(rows) && i < start+visible; i++ {
+		line := truncate("  "+rows[i], m.width)
+		if i == m.cursor[m.tab] && m.tab != TabPeers {
+			line = "\033[7m" + pad(line, m.width) + "\033[0m"
+		}
+		lines = append(lines, line)
+	}
+	if len(rows) == 0 {
+		lines = append(lines, "  "+m.empty())
+	}
+
+	// The footer sits at the bottom of the terminal
+	for len(lines) < m.height-2 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, truncate(m.footerMessage(), m.width), truncate(m.help(), m.width))
+
+	for i := range lines {
+		if !strings.HasPrefix(lines[i], "\033") {
+			lines[i] = truncate(lines[i], m.width)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// header sums up the daemon: DHT, peers and transfers
+func (m *Model) header() string {
+	if m.err != nil {
+		return "Silmaril | daemon unreachable: " + m.err.Error()
+	}
+	if m.status == nil {
+		return "Silmaril | connecting..."
+	}
+	nodes, _ := m.status["dht_nodes"].(float64)
+	peers, _ := m.status["total_peers"].(float64)
+	active, _ := m.status["active_transfers"].(float64)
+	uptime, _ := m.status["uptime"].(string)
+	header := fmt.Sprintf("Silmaril | DHT %d nodes", int(nodes))
+	if bootstrap, ok := m.status["dht_bootstrap"].(map[string]interface{}); ok {
+		if state, _ := bootstrap["status"].(string); state != "" {
+			header += " (" + state + ")"
+		}
+	}
+	header += fmt.Sprintf(" | %d peers | %d active transfers", int(peers), int(active))
+	if uptime != "" {
+		header += " | up " + shortDuration(uptime)
+	}
+	return header
+}
+
+func (m *Model) tabs() string {
+	var b strings.Builder
+	for i, name := range tabNames {
+		label := fmt.Sprintf(" %d %s ", i+1, name)
+		if Tab(i) == m.tab {
+			label = "\033[7m" + label + "\033[0m"
+		}
+		b.WriteString(label)
+		b.WriteString(" ")
+	}
+	return b.String()
+}
+
+// body returns the column titles of the current tab and its rows
+func (m *Model) body() (string, []string) {
+	var rows []string
+	switch m.tab {
+	case TabModels:
+		for _, model := range m.models {
+			name, _ := model["name"].(string)
+			license, _ := model["license"].(string)
+			rows = append(rows, fmt.Sprintf("%-48s %10s  %s", name, formatBytes(modelSize(model)), license))
+		}
+		return fmt.Sprintf("  %-48s %10s  %s", "NAME", "SIZE", "LICENSE"), rows
+
+	case TabTransfers:
+		for _, t := range m.transfers {
+			name, _ := t["model_name"].(string)
+			kind, _ := t["type"].(string)
+			status, _ := t["status"].(string)
+			progress, _ := t["progress"].(float64)
+			down, _ := t["download_rate"].(float64)
+			up, _ := t["upload_rate"].(float64)
+			peers, _ := t["peers"].(float64)
+			rows = append(rows, fmt.Sprintf("%-40s %-8s %-10s %5.1f%% %10s/s %10s/s %5d",
+				name, kind, status, progress, formatBytes(int64(down)), formatBytes(int64(up)), int(peers)))
+		}
+		return fmt.Sprintf("  %-40s %-8s %-10s %6s %12s %12s %5s", "MODEL", "TYPE", "STATUS", "DONE", "DOWN", "UP", "PEERS"), rows
+
+	case TabPeers:
+		for _, peer := range peerList(m.peers) {
+			addr, _ := peer["addr"].(string)
+			flags, _ := peer["flags"].(string)
+			rate, _ := peer["download_rate"].(float64)
+			progress, _ := peer["progress"].(float64)
+			client, _ := peer["client"].(string)
+			rows = append(rows, fmt.Sprintf("%-40s %-6s %10s/s %4.0f%%  %s", addr, flags, formatBytes(int64(rate)), progress, client))
+		}
+		title := "  No transfer selected"
+		if m.peersOf != "" {
+			connected, _ := m.peers["connected"].(float64)
+			known, _ := m.peers["known"].(float64)
+			title = fmt.Sprintf("  Peers of %s: %d connected, %d known\n  %-40s %-6s %12s %5s  %s",
+				m.peersOf, int(connected), int(known), "ADDRESS", "FLAGS", "DOWN", "HAVE", "CLIENT")
+		}
+		return title, rows
+
+	case TabDiscover:
+		for _, model := range m.discovered {
+			name, _ := model["name"].(string)
+			version, _ := model["version"].(string)
+			license, _ := model["license"].(string)
+			rows = append(rows, fmt.Sprintf("%-48s %-10s %10s  %s", name, version, formatBytes(modelSize(model)), license))
+		}
+		cursor := ""
+		if m.searching {
+			cursor = "_"
+		}
+		return fmt.Sprintf("  Search: %s%s\n  %-48s %-10s %10s  %s", m.query, cursor, "NAME", "VERSION", "SIZE", "LICENSE"), rows
+	}
+	return "", nil
+}
+
+// empty explains an empty tab
+func (m *Model) empty() string {
+	switch m.tab {
+	case TabModels:
+		return "No local models."
+	case TabTransfers:
+		return "No transfers."
+	case TabPeers:
+		return "No connected peers."
+	case TabDiscover:
+		if m.discovered == nil {
+			return "Press / to search the network."
+		}
+		return "No models found."
+	}
+	return ""
+}
+
+func (m *Model) footerMessage() string {
+	if m.confirm != nil {
+		return m.confirm.prompt
+	}
+	return m.message
+}
+
+// help lists the keys of the current tab
+func (m *Model) help() string {
+	if m.searching {
+		return "type a name or tag · enter search · esc stop typing"
+	}
+	keys := "q quit · tab/1-4 switch · ↑↓ select"
+	switch m.tab {
+	case TabTransfers:
+		keys += " · p pause · r resume · c cancel · enter peers"
+	case TabDiscover:
+		keys += " · / search · enter download · a accept license and download"
+	}
+	return keys
+}
+
+// peerList returns the peers of a torrent peers response
+func peerList(peers map[string]interface{}) []map[string]interface{} {
+	list, _ := peers["peers"].([]interface{})
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, p := range list {
+		if peer, ok := p.(map[string]interface{}); ok {
+			result = append(result, peer)
+		}
+	}
+	return result
+}
+
+func modelSize(model map[string]interface{}) int64 {
+	if size, ok := model["total_size"].(float64); ok {
+		return int64(size)
+	}
+	size, _ := model["size"].(float64)
+	return int64(size)
+}
+
+func sortByField(rows []map[string]interface{}, field string) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, _ := rows[i][field].(string)
+		b, _ := rows[j][field].(string)
+		return a < b
+	})
+}
+
+// truncate cuts s to width characters
+func truncate(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// pad fills s with spaces to width characters, for highlighting whole rows
+func pad(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// shortDuration drops the fractions of seconds from a duration
+func shortDuration(d string) string {
+	if i := strings.Index(d, "."); i >= 0 {
+		if j := strings.IndexAny(d[i:], "smhµn"); j >= 0 && d[i+j] == 's' {
+			return d[:i] + "s"
+		}
+	}
+	return d
+}
+
+// formatBytes formats a byte count with a unit of the right size
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		value /= unit
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+	return fmt.Sprintf("%.1f TB", value/unit)
+}
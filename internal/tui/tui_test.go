@@ -0,0 +1,186 @@
+package tui
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/silmaril/silmaril/internal/api/apierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient is a daemon with a model, two transfers and a peer
+type fakeClient struct {
+	calls         []string
+	needsLicense  bool
+	discoverQuery string
+}
+
+func (f *fakeClient) GetStatus() (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"dht_nodes":        float64(42),
+		"total_peers":      float64(3),
+		"active_transfers": float64(1),
+		"uptime":           "1h2m3.456s",
+		"dht_bootstrap":    map[string]interface{}{"status": "ready"},
+	}, nil
+}
+
+func (f *fakeClient) ListModels() ([]map[string]interface{}, error) {
+	return []map[string]interface{}{
+		{"name": "org/model", "total_size": float64(2 << 30), "license": "apache-2.0"},
+	}, nil
+}
+
+func (f *fakeClient) ListTransfers(status string) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{
+		{"id": "t2", "model_name": "org/seeded", "type": "seed", "status": "active", "info_hash": "bbbb"},
+		{"id": "t1", "model_name": "org/downloading", "type": "download", "status": "active", "progress": 42.0, "info_hash": "aaaa"},
+	}, nil
+}
+
+func (f *fakeClient) GetTorrentPeers(infoHash string) (map[string]interface{}, error) {
+	f.calls = append(f.calls, "peers "+infoHash)
+	return map[string]interface{}{
+		"connected": float64(1),
+		"known":     float64(5),
+		"peers":     []interface{}{map[string]interface{}{"addr": "10.0.0.1:6881", "flags": "DE", "client": "silmaril"}},
+	}, nil
+}
+
+func (f *fakeClient) PauseTransfer(id string) error {
+	f.calls = append(f.calls, "pause "+id)
+	return nil
+}
+
+func (f *fakeClient) ResumeTransfer(id string) error {
+	f.calls = append(f.calls, "resume "+id)
+	return nil
+}
+
+func (f *fakeClient) CancelTransfer(id string) error {
+	f.calls = append(f.calls, "cancel "+id)
+	return nil
+}
+
+func (f *fakeClient) DiscoverModels(pattern string) ([]map[string]interface{}, error) {
+	f.discoverQuery = pattern
+	return []map[string]interface{}{
+		{"name": "org/found", "version": "1.0", "size": float64(1 << 20), "info_hash": "cccc"},
+	}, nil
+}
+
+func (f *fakeClient) DownloadModel(modelName, infoHash string, seed bool, files []string, acceptLicense, force bool, output string) (map[string]interface{}, error) {
+	if f.needsLicense && !acceptLicense {
+		return nil, apierror.New(http.StatusForbidden, "license required").
+			WithCode(apierror.CodeLicenseRequired).
+			WithDetail("license", "llama3")
+	}
+	f.calls = append(f.calls, "download "+modelName+" "+infoHash)
+	return map[string]interface{}{"transfer_id": "t3"}, nil
+}
+
+func pressKeys(m *Model, keys ...Key) {
+	for _, k := range keys {
+		m.HandleKey(k)
+	}
+}
+
+func TestViewShowsDaemon(t *testing.T) {
+	m := New(&fakeClient{})
+	m.SetSize(120, 20)
+	m.Refresh()
+
+	view := m.View()
+	lines := strings.Split(view, "\n")
+	assert.Len(t, lines, 20, "the footer is at the bottom")
+	assert.Contains(t, lines[0], "DHT 42 nodes (ready)")
+	assert.Contains(t, lines[0], "3 peers")
+	assert.Contains(t, lines[0], "up 1h2m3s")
+	assert.Contains(t, view, "org/model")
+	assert.Contains(t, view, "2.0 GB")
+
+	// Transfers are sorted by model
+	pressKeys(m, "2")
+	view = m.View()
+	assert.Less(t, strings.Index(view, "org/downloading"), strings.Index(view, "org/seeded"))
+	assert.Contains(t, view, "42.0%")
+}
+
+func TestTransferKeys(t *testing.T) {
+	f := &fakeClient{}
+	m := New(f)
+	m.Refresh()
+
+	pressKeys(m, "2", KeyDown, "p", "r")
+	assert.Equal(t, []string{"pause t2", "resume t2"}, f.calls)
+	assert.Equal(t, "Resumed org/seeded", m.message)
+
+	// Cancelling asks first
+	f.calls = nil
+	pressKeys(m, KeyUp, "c")
+	assert.Contains(t, m.View(), "Cancel the transfer of org/downloading? (y/n)")
+	pressKeys(m, "n")
+	assert.Empty(t, f.calls)
+	pressKeys(m, "c", "y")
+	assert.Equal(t, []string{"cancel t1"}, f.calls)
+
+	// Enter shows the peers of the transfer
+	f.calls = nil
+	pressKeys(m, KeyEnter)
+	assert.Equal(t, TabPeers, m.tab)
+	assert.Equal(t, []string{"peers aaaa"}, f.calls)
+	view := m.View()
+	assert.Contains(t, view, "Peers of org/downloading: 1 connected, 5 known")
+	assert.Contains(t, view, "10.0.0.1:6881")
+}
+
+func TestDiscoverAndDownload(t *testing.T) {
+	f := &fakeClient{needsLicense: true}
+	m := New(f)
+	m.Refresh()
+
+	pressKeys(m, "4", "/", "l", "l", "x", KeyBackspace, "a", KeyEnter)
+	assert.Equal(t, "lla", f.discoverQuery)
+	assert.False(t, m.searching)
+	require.Len(t, m.discovered, 1)
+	assert.Contains(t, m.View(), "org/found")
+
+	// Licenses are accepted with a
+	pressKeys(m, KeyEnter)
+	assert.Empty(t, f.calls)
+	assert.Contains(t, m.message, "license llama3")
+	pressKeys(m, "a")
+	assert.Equal(t, []string{"download org/found cccc"}, f.calls)
+	assert.Equal(t, "Downloading org/found", m.message)
+
+	// Keys typed into the search don't quit
+	pressKeys(m, "/", "q")
+	assert.False(t, m.Quitting())
+	pressKeys(m, KeyEscape, "q")
+	assert.True(t, m.Quitting())
+}
+
+func TestCursorStaysInView(t *testing.T) {
+	m := New(&fakeClient{})
+	m.SetSize(80, 10)
+	for i := 0; i < 20; i++ {
+		m.discovered = append(m.discovered, map[string]interface{}{"name": strings.Repeat("m", i+1)})
+	}
+	m.tab = TabDiscover
+	for i := 0; i < 30; i++ {
+		m.HandleKey(KeyDown)
+	}
+	assert.Equal(t, 19, m.cursor[TabDiscover])
+	assert.Equal(t, 19-m.listHeight()+1, m.offset[TabDiscover])
+	assert.Contains(t, m.View(), strings.Repeat("m", 20))
+}
+
+func TestParseKeys(t *testing.T) {
+	assert.Equal(t, []Key{KeyUp, KeyDown, "q", KeyEnter, KeyTab, KeyBackspace, KeyEscape, "é", KeyCtrlC},
+		parseKeys([]byte("\x1b[A\x1b[Bq\r\t\x7f\x1bé\x03")))
+
+	// Unnamed sequences like F5 are skipped
+	assert.Equal(t, []Key{"x"}, parseKeys([]byte("\x1b[15~x")))
+}
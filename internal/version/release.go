@@ -0,0 +1,57 @@
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ReleasesURL is where the latest release is looked up, the GitHub API of
+// the Silmaril repository
+const ReleasesURL = "https://api.github.com/repos/im-knots/silmaril/releases/latest"
+
+// Release is a published release
+type Release struct {
+	Tag         string    `json:"tag_name"`
+	URL         string    `json:"html_url"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// LatestRelease returns the latest release from the GitHub API at url
+func LatestRelease(client *http.Client, url string) (*Release, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the latest release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("no release published yet")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to look up the latest release: %s", resp.Status)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to decode release: %w", err)
+	}
+	if _, ok := Parse(release.Tag); !ok {
+		return nil, fmt.Errorf("latest release %q is not a semantic version", release.Tag)
+	}
+	return &release, nil
+}
+
+// Newer reports whether release is newer than the running version. Builds
+// without a semantic version, such as dev builds, are never outdated.
+func (r *Release) Newer(current string) bool {
+	latest, _ := Parse(r.Tag)
+	running, ok := Parse(current)
+	return ok && latest.Compare(running) > 0
+}
@@ -0,0 +1,139 @@
+// Package version describes the build of Silmaril. Version and Commit are
+// set at link time:
+//
+//	go build -ldflags "-X github.com/silmaril/silmaril/internal/version.Version=v0.3.0 \
+//	  -X github.com/silmaril/silmaril/internal/version.Commit=$(git rev-parse HEAD)"
+//
+// Builds without them report the VCS revision Go recorded, if any.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// Info is the build of the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // Built from a tree with uncommitted changes
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build of the running binary
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+// String returns the version and the short commit, e.g. "v0.3.0 (1a2b3c4)"
+func (i Info) String() string {
+	if i.Commit == "" {
+		return i.Version
+	}
+	commit := i.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	if i.Modified {
+		commit += "-dirty"
+	}
+	return i.Version + " (" + commit + ")"
+}
+
+// Semver is a semantic version, without pre-release and build metadata
+type Semver struct {
+	Major, Minor, Patch int
+}
+
+// Parse parses a version like v1.2.3, 1.2 or v1.2.3-4-gabcdef from git
+// describe. It reports false for versions that aren't semantic, such as dev.
+func Parse(v string) (Semver, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Semver{}, false
+	}
+	var nums [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Semver{}, false
+		}
+		nums[i] = n
+	}
+	return Semver{Major: nums[0], Minor: nums[1], Patch: nums[2]}, true
+}
+
+// Compare returns -1, 0 or 1 as s is older than, the same as or newer than o
+func (s Semver) Compare(o Semver) int {
+	switch {
+	case s.Major != o.Major:
+		return cmpInt(s.Major, o.Major)
+	case s.Minor != o.Minor:
+		return cmpInt(s.Minor, o.Minor)
+	}
+	return cmpInt(s.Patch, o.Patch)
+}
+
+func (s Semver) String() string {
+	return fmt.Sprintf("v%d.%d.%d", s.Major, s.Minor, s.Patch)
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// How well a CLI and a daemon of two versions work together
+const (
+	Compatible   = "compatible"   // Same major and minor version
+	MinorSkew    = "minor_skew"   // Features of the newer side may be missing
+	Incompatible = "incompatible" // Different major versions
+	Unknown      = "unknown"      // A version isn't semantic, e.g. a dev build
+)
+
+// Compatibility compares the versions of a CLI and a daemon. Breaking API
+// changes are left to API versions, which clients negotiate separately, so
+// only a different major version makes them incompatible.
+func Compatibility(cli, daemon string) string {
+	a, okA := Parse(cli)
+	b, okB := Parse(daemon)
+	switch {
+	case !okA || !okB:
+		return Unknown
+	case a.Major != b.Major:
+		return Incompatible
+	case a.Minor != b.Minor:
+		return MinorSkew
+	}
+	return Compatible
+}
@@ -0,0 +1,55 @@
+package version
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	v, ok := Parse("v1.2.3")
+	require.True(t, ok)
+	assert.Equal(t, Semver{1, 2, 3}, v)
+
+	v, ok = Parse("0.4.1-5-g1a2b3c4-dirty")
+	require.True(t, ok)
+	assert.Equal(t, Semver{0, 4, 1}, v)
+
+	v, ok = Parse("v2.0")
+	require.True(t, ok)
+	assert.Equal(t, "v2.0.0", v.String())
+
+	for _, s := range []string{"dev", "", "v1", "1.2.3.4", "v1.x.0", "1a2b3c4"} {
+		_, ok := Parse(s)
+		assert.False(t, ok, s)
+	}
+}
+
+func TestCompatibility(t *testing.T) {
+	assert.Equal(t, Compatible, Compatibility("v0.3.0", "v0.3.2"))
+	assert.Equal(t, MinorSkew, Compatibility("v0.4.0", "v0.3.2"))
+	assert.Equal(t, Incompatible, Compatibility("v2.0.0", "v1.9.0"))
+	assert.Equal(t, Unknown, Compatibility("dev", "v1.0.0"))
+}
+
+func TestLatestRelease(t *testing.T) {
+	tag := "v0.4.0"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name":"` + tag + `","html_url":"https://example.com/releases/` + tag + `","published_at":"2026-10-01T12:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	release, err := LatestRelease(server.Client(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.4.0", release.Tag)
+	assert.True(t, release.Newer("v0.3.9"))
+	assert.False(t, release.Newer("v0.4.0"))
+	assert.False(t, release.Newer("dev"), "dev builds are never outdated")
+
+	tag = "nightly"
+	_, err = LatestRelease(server.Client(), server.URL)
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MatchesSearch reports whether a model matches a free-text search pattern.
+// The pattern is matched case-insensitively against the name, the description
+// and each tag, so searches work on model card metadata and not just names.
+func MatchesSearch(pattern, name, description string, tags []string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+
+	needle := strings.ToLower(pattern)
+	if strings.Contains(strings.ToLower(name), needle) {
+		return true
+	}
+	if description != "" && strings.Contains(strings.ToLower(description), needle) {
+		return true
+	}
+	for _, tag := range tags {
+		if strings.Contains(strings.ToLower(tag), needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAllTags reports whether every wanted tag is present (case-insensitive)
+func HasAllTags(tags []string, wanted []string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, t := range tags {
+			if strings.EqualFold(t, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchFilter narrows catalog search results
+type SearchFilter struct {
+	Pattern     string   // Free-text pattern matched against name, description and tags
+	Tags        []string // All of these tags must be present
+	MaxSize     int64    // Maximum model size in bytes, 0 for no limit
+	License     string   // Exact license identifier (case-insensitive)
+	MinSeeders  int      // Minimum number of seeders, requires probing the swarm
+	CheckHealth bool     // Probe the swarm and annotate results with seeder statistics
+	Network     string   // Only models listed in this catalog network, checked by the daemon
+}
+
+// Matches reports whether an announcement satisfies the pattern, tag, size and
+// license filters. MinSeeders is not checked here since seeder counts are only
+// known after the swarm has been probed.
+func (f SearchFilter) Matches(ann *ModelAnnouncement) bool {
+	if !MatchesSearch(f.Pattern, ann.Name, ann.Description, ann.Tags) {
+		return false
+	}
+	if !HasAllTags(ann.Tags, f.Tags) {
+		return false
+	}
+	if f.MaxSize > 0 && ann.Size > f.MaxSize {
+		return false
+	}
+	if f.License != "" && !strings.EqualFold(ann.License, f.License) {
+		return false
+	}
+	return true
+}
+
+// sizeUnits maps size suffixes to their multiplier in bytes
+var sizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"K":   1 << 10,
+	"KB":  1 << 10,
+	"KIB": 1 << 10,
+	"M":   1 << 20,
+	"MB":  1 << 20,
+	"MIB": 1 << 20,
+	"G":   1 << 30,
+	"GB":  1 << 30,
+	"GIB": 1 << 30,
+	"T":   1 << 40,
+	"TB":  1 << 40,
+	"TIB": 1 << 40,
+}
+
+// ParseSize parses a human readable size such as "20GB", "1.5G" or "512MB"
+// into bytes. A bare number is interpreted as bytes.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+
+	i := len(s)
+	for i > 0 && (s[i-1] < '0' || s[i-1] > '9') {
+		i--
+	}
+	number := strings.TrimSpace(s[:i])
+	unit := strings.ToUpper(strings.TrimSpace(s[i:]))
+
+	multiplier, ok := sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit %q", s[i:])
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return int64(value * float64(multiplier)), nil
+}
@@ -16,6 +16,16 @@ type ModelManifest struct {
 	License     string    `json:"license"`
 	CreatedAt   time.Time `json:"created_at"`
 	
+	// License terms, downloaders must accept them first if required
+	LicenseURL               string `json:"license_url,omitempty"`
+	LicenseText              string `json:"license_text,omitempty"`
+	RequireLicenseAcceptance bool   `json:"require_license_acceptance,omitempty"`
+	
+	// Model card metadata (extracted from README.md front matter)
+	Tags        []string  `json:"tags,omitempty"`
+	Languages   []string  `json:"languages,omitempty"`
+	PipelineTag string    `json:"pipeline_tag,omitempty"` // text-generation, etc
+	
 	// Model architecture and parameters
 	Architecture   string                 `json:"architecture"`
 	ModelType      string                 `json:"model_type"` // llm, diffusion, etc
@@ -32,8 +42,68 @@ type ModelManifest struct {
 	MagnetURI      string                `json:"magnet_uri"` // BitTorrent v2 only
 	IPFSCIDs       map[string]string     `json:"ipfs_cids,omitempty"` // filename -> CID
 	
+	// HTTP base URLs serving the files by path, downloads fetch from them
+	// alongside the swarm
+	Mirrors        []string              `json:"mirrors,omitempty"`
+	
+	// Set when the torrent holds encrypted files, Files describes the plaintext
+	Encryption     *EncryptionInfo       `json:"encryption,omitempty"`
+	
+	// Where the weights came from: the upstream repository, and the model
+	// they were derived from
+	Provenance     *Provenance           `json:"provenance,omitempty"`
+	
+	// Infohashes of other torrents of exactly the same files, such as the
+	// same revision published by someone else. Downloads use their swarms too.
+	Equivalents    []string              `json:"equivalents,omitempty"`
+	
+	// Local seeding policy, not part of the signed manifest
+	Seeding        *SeedingPolicy        `json:"seeding,omitempty"`
+	
+	// Local storage backend of the model's torrent, not part of the signed
+	// manifest. Empty uses the daemon's.
+	Storage        string                `json:"storage,omitempty"`
+	
 	// Signature for verification
 	Signature      string                `json:"signature,omitempty"`
+	
+	// Signatures of publisher keys, and the policy saying how many of which
+	// keys must sign, so an organization publishes only once enough of its
+	// members signed
+	SignaturePolicy *SignaturePolicy     `json:"signature_policy,omitempty"`
+	Signatures     []ManifestSignature   `json:"signatures,omitempty"`
+}
+
+// SignaturePolicy requires Threshold of Keys to sign a manifest, such as 2
+// of the 3 publisher keys of an organization
+type SignaturePolicy struct {
+	Organization string   `json:"organization,omitempty"`
+	Threshold    int      `json:"threshold"`
+	Keys         []string `json:"keys"` // Hex encoded ed25519 publisher keys
+}
+
+// ManifestSignature is the signature of a manifest by one publisher key
+type ManifestSignature struct {
+	KeyID     string    `json:"key_id"` // Hex encoded ed25519 public key
+	Signature string    `json:"sig"`    // Hex encoded
+	SignedAt  time.Time `json:"signed_at"`
+}
+
+// Provenance records where the weights of a model came from: the upstream
+// repository they were mirrored from, and for derived models the model they
+// were derived from and how
+type Provenance struct {
+	UpstreamURL      string `json:"upstream_url,omitempty"`
+	UpstreamRevision string `json:"upstream_revision,omitempty"` // Commit the upstream repository was at
+	ParentModel      string `json:"parent_model,omitempty"`
+	ParentVersion    string `json:"parent_version,omitempty"`
+	ParentInfoHash   string `json:"parent_info_hash,omitempty"`
+	Transformation   string `json:"transformation,omitempty"` // e.g. "gguf:Q4_K_M"
+}
+
+// Derived reports whether the model was derived from another model
+func (p *Provenance) Derived() bool {
+	return p != nil && p.Transformation != ""
 }
 
 // InferenceHints provides hints for running inference
@@ -45,6 +115,29 @@ type InferenceHints struct {
 	TokenizerType   string   `json:"tokenizer_type,omitempty"`
 }
 
+// SeedingPolicy limits how long a model is seeded. Unset limits fall back to
+// the torrent settings of the daemon, 0 means unlimited.
+type SeedingPolicy struct {
+	SeedRatio        *float64 `json:"seed_ratio,omitempty"`
+	SeedTime         *int     `json:"seed_time,omitempty"`            // Seconds
+	StopIfNoPeersFor *int     `json:"stop_if_no_peers_for,omitempty"` // Seconds
+
+	// low, normal or high, weighing the model's share of the upload rate
+	Priority string `json:"priority,omitempty"`
+
+	// Set when the daemon stopped seeding because a limit was reached
+	StoppedAt  *time.Time `json:"stopped_at,omitempty"`
+	StopReason string     `json:"stop_reason,omitempty"`
+}
+
+// EncryptionInfo describes how the files of an encrypted model are encrypted
+// and where to get the key to decrypt them
+type EncryptionInfo struct {
+	Cipher string `json:"cipher"`
+	KeyID  string `json:"key_id"`            // Identifies the key, not the key itself
+	KeyURL string `json:"key_url,omitempty"` // Access controlled endpoint serving the key
+}
+
 // ModelFile represents a single file in a model
 type ModelFile struct {
 	Path     string `json:"path"`
@@ -52,11 +145,14 @@ type ModelFile struct {
 	SHA256   string `json:"sha256"`
 }
 
-// ComputeHash returns the SHA256 hash of the manifest (excluding signature)
+// ComputeHash returns the SHA256 hash of the manifest (excluding signatures)
 func (m *ModelManifest) ComputeHash() (string, error) {
-	// Create a copy without the signature
+	// Create a copy without the signature and local settings
 	manifestCopy := *m
 	manifestCopy.Signature = ""
+	manifestCopy.Signatures = nil
+	manifestCopy.Seeding = nil
+	manifestCopy.Storage = ""
 	
 	data, err := json.Marshal(manifestCopy)
 	if err != nil {
@@ -67,14 +163,118 @@ func (m *ModelManifest) ComputeHash() (string, error) {
 	return hex.EncodeToString(hash[:]), nil
 }
 
+// HashedFiles returns the number of files whose checksum is known. Large
+// files are hashed in the background after the model is found.
+func (m *ModelManifest) HashedFiles() int {
+	hashed := 0
+	for _, f := range m.Files {
+		if f.SHA256 != "" {
+			hashed++
+		}
+	}
+	return hashed
+}
+
+// HashesComplete reports whether every file of the model has its checksum
+func (m *ModelManifest) HashesComplete() bool {
+	return m.HashedFiles() == len(m.Files)
+}
+
+// Keywords returns the searchable model card terms (tags, languages and pipeline tag)
+func (m *ModelManifest) Keywords() []string {
+	keywords := make([]string, 0, len(m.Tags)+len(m.Languages)+1)
+	keywords = append(keywords, m.Tags...)
+	keywords = append(keywords, m.Languages...)
+	if m.PipelineTag != "" {
+		keywords = append(keywords, m.PipelineTag)
+	}
+	return keywords
+}
+
 // ModelAnnouncement represents a model announcement in DHT
 type ModelAnnouncement struct {
-	Name     string `json:"name"`
-	Version  string `json:"version"`
-	Magnet   string `json:"magnet"`
-	InfoHash string `json:"info_hash"`
-	Size     int64  `json:"size"`
-	Time     int64  `json:"time"`
+	Name         string   `json:"name"`
+	Version      string   `json:"version"`
+	Magnet       string   `json:"magnet"`
+	InfoHash     string   `json:"info_hash"`
+	Size         int64    `json:"size"`
+	Time         int64    `json:"time"`
+	Description  string   `json:"description,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	License      string   `json:"license,omitempty"`
+	Parameters   int64    `json:"parameters,omitempty"`
+	
+	// Set for models whose license must be accepted before downloading
+	LicenseURL               string `json:"license_url,omitempty"`
+	RequireLicenseAcceptance bool   `json:"require_license_acceptance,omitempty"`
+	
+	// Where the weights came from, if the publisher recorded it
+	Provenance *Provenance `json:"provenance,omitempty"`
+	
+	// Infohashes of other torrents of the same files
+	Equivalents []string `json:"equivalents,omitempty"`
+	
+	// Key of the publisher that announced the model, the domain proving it
+	// owns the namespace of the model with the publisher's signature of it,
+	// and whether the daemon checked the proof
+	PublisherKey      string `json:"publisher_key,omitempty"`
+	Domain            string `json:"domain,omitempty"`
+	DomainSignature   string `json:"domain_signature,omitempty"`
+	VerifiedPublisher bool   `json:"verified_publisher,omitempty"`
+	
+	// Catalog networks listing the model, "public" for the public catalog
+	Networks []string `json:"networks,omitempty"`
+	
+	// Publisher attribution, only set for models from subscribed publisher catalogs
+	Publisher     string  `json:"publisher,omitempty"`
+	PublisherName string  `json:"publisher_name,omitempty"`
+	
+	// Swarm health, only set when the swarm was probed
+	Seeders      int      `json:"seeders,omitempty"`
+	Leechers     int      `json:"leechers,omitempty"`
+	Availability string   `json:"availability,omitempty"`
+}
+
+// InNetwork reports whether the model is listed in the named catalog network
+func (a *ModelAnnouncement) InNetwork(network string) bool {
+	for _, name := range a.Networks {
+		if name == network {
+			return true
+		}
+	}
+	return false
+}
+
+// SwarmHealth is the result of probing the DHT for the swarm of a torrent
+type SwarmHealth struct {
+	Seeders      int       `json:"seeders"`
+	Leechers     int       `json:"leechers"`
+	Peers        int       `json:"peers"`
+	Scraped      bool      `json:"scraped"` // Counts come from BEP 33 scrape estimates
+	Availability string    `json:"availability"`
+	CheckedAt    time.Time `json:"checked_at"`
+}
+
+// Swarm availability estimates
+const (
+	AvailabilityHigh        = "high"
+	AvailabilityMedium      = "medium"
+	AvailabilityLow         = "low"
+	AvailabilityUnavailable = "unavailable"
+)
+
+// EstimateAvailability rates how likely a model can be fully downloaded
+func EstimateAvailability(seeders, peers int) string {
+	switch {
+	case seeders >= 5:
+		return AvailabilityHigh
+	case seeders >= 2:
+		return AvailabilityMedium
+	case seeders >= 1 || peers > 0:
+		return AvailabilityLow
+	default:
+		return AvailabilityUnavailable
+	}
 }
 
 // ProgressUpdate represents download/upload progress
@@ -46,6 +46,14 @@ func TestModelManifestComputeHash(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, hash1, hash3)
 
+	// Hash should ignore the local seeding policy and storage backend
+	ratio := 2.0
+	manifest.Seeding = &SeedingPolicy{SeedRatio: &ratio}
+	manifest.Storage = "mmap"
+	hash5, err := manifest.ComputeHash()
+	require.NoError(t, err)
+	assert.Equal(t, hash1, hash5)
+
 	// Hash should change when other fields change
 	manifest.Name = "different-model"
 	hash4, err := manifest.ComputeHash()
@@ -256,4 +264,77 @@ func TestModelFileValidation(t *testing.T) {
 	
 	// Check path is not empty
 	assert.NotEmpty(t, file.Path)
-}
\ No newline at end of file
+}
+func TestMatchesSearch(t *testing.T) {
+	tags := []string{"text-generation", "en"}
+
+	assert.True(t, MatchesSearch("", "org/model", "", nil))
+	assert.True(t, MatchesSearch("*", "org/model", "", nil))
+	assert.True(t, MatchesSearch("MODEL", "org/model", "", nil))
+	assert.True(t, MatchesSearch("chat", "org/model", "A chat model", nil))
+	assert.True(t, MatchesSearch("generation", "org/model", "", tags))
+	assert.False(t, MatchesSearch("vision", "org/model", "A chat model", tags))
+}
+
+func TestHasAllTags(t *testing.T) {
+	tags := []string{"text-generation", "en"}
+
+	assert.True(t, HasAllTags(tags, nil))
+	assert.True(t, HasAllTags(tags, []string{"EN"}))
+	assert.True(t, HasAllTags(tags, []string{"en", "text-generation"}))
+	assert.False(t, HasAllTags(tags, []string{"en", "fr"}))
+}
+
+func TestSearchFilterMatches(t *testing.T) {
+	ann := &ModelAnnouncement{
+		Name:        "meta-llama/Llama-2-7b",
+		Description: "A chat model",
+		Size:        13 * 1024 * 1024 * 1024,
+		Tags:        []string{"llama", "7b"},
+		License:     "Apache-2.0",
+	}
+
+	assert.True(t, SearchFilter{}.Matches(ann))
+	assert.True(t, SearchFilter{Pattern: "chat", Tags: []string{"LLAMA"}}.Matches(ann))
+	assert.True(t, SearchFilter{MaxSize: 20 * 1024 * 1024 * 1024, License: "apache-2.0"}.Matches(ann))
+	assert.False(t, SearchFilter{MaxSize: 10 * 1024 * 1024 * 1024}.Matches(ann))
+	assert.False(t, SearchFilter{License: "mit"}.Matches(ann))
+	assert.False(t, SearchFilter{Tags: []string{"mistral"}}.Matches(ann))
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{"1024", 1024, false},
+		{"20GB", 20 * 1024 * 1024 * 1024, false},
+		{"1.5G", 1536 * 1024 * 1024, false},
+		{"512 mb", 512 * 1024 * 1024, false},
+		{"2TiB", 2 * 1024 * 1024 * 1024 * 1024, false},
+		{"", 0, true},
+		{"GB", 0, true},
+		{"10XB", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			size, err := ParseSize(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, size)
+		})
+	}
+}
+
+func TestEstimateAvailability(t *testing.T) {
+	assert.Equal(t, AvailabilityHigh, EstimateAvailability(5, 5))
+	assert.Equal(t, AvailabilityMedium, EstimateAvailability(2, 3))
+	assert.Equal(t, AvailabilityLow, EstimateAvailability(1, 1))
+	assert.Equal(t, AvailabilityLow, EstimateAvailability(0, 2))
+	assert.Equal(t, AvailabilityUnavailable, EstimateAvailability(0, 0))
+}